@@ -2,20 +2,39 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"path/filepath"
+	"strings"
 	"time"
 
 	"noodexx/internal/api"
 	"noodexx/internal/auth"
+	"noodexx/internal/cluster"
 	"noodexx/internal/config"
+	"noodexx/internal/embedpool"
+	"noodexx/internal/eval"
+	"noodexx/internal/events"
+	"noodexx/internal/extractors"
+	"noodexx/internal/fairqueue"
 	"noodexx/internal/ingest"
 	"noodexx/internal/llm"
 	"noodexx/internal/logging"
+	"noodexx/internal/metaquery"
 	"noodexx/internal/rag"
+	"noodexx/internal/readiness"
+	"noodexx/internal/replay"
+	"noodexx/internal/scheduler"
 	"noodexx/internal/skills"
 	"noodexx/internal/store"
+	"noodexx/internal/telemetry"
+	"noodexx/internal/transcripts"
+	"noodexx/internal/warmup"
 	"noodexx/internal/watcher"
+	"noodexx/internal/websearch"
+	"noodexx/internal/wirelog"
 )
 
 // storeAdapter adapts store.Store to rag.Store interface
@@ -23,8 +42,13 @@ type storeAdapter struct {
 	store *store.Store
 }
 
-func (sa *storeAdapter) Search(ctx context.Context, queryVec []float32, topK int) ([]rag.Chunk, error) {
-	storeChunks, err := sa.store.Search(ctx, queryVec, topK)
+func (sa *storeAdapter) SearchLibrary(ctx context.Context, userID int64, queryVec []float32, opts rag.SearchOptions, topK int) ([]rag.Chunk, error) {
+	storeChunks, err := sa.store.SearchLibrary(ctx, userID, queryVec, store.SearchOptions{
+		Query:      opts.Query,
+		QueryModel: opts.QueryModel,
+		Sources:    opts.Sources,
+		Mode:       opts.Mode,
+	}, topK)
 	if err != nil {
 		return nil, err
 	}
@@ -33,9 +57,10 @@ func (sa *storeAdapter) Search(ctx context.Context, queryVec []float32, topK int
 	ragChunks := make([]rag.Chunk, len(storeChunks))
 	for i, sc := range storeChunks {
 		ragChunks[i] = rag.Chunk{
-			Source: sc.Source,
-			Text:   sc.Text,
-			Score:  0, // Score calculated by store
+			Source:    sc.Source,
+			Text:      sc.Text,
+			Score:     sc.Score,
+			Embedding: sc.Embedding,
 		}
 	}
 	return ragChunks, nil
@@ -266,7 +291,58 @@ func (wsa *watcherStoreAdapter) GetWatchedFolders(ctx context.Context) ([]watche
 
 func (wsa *watcherStoreAdapter) DeleteSource(ctx context.Context, source string) error {
 	// Use local-default user (ID=1) for backward compatibility
-	return wsa.store.DeleteChunksBySource(ctx, 1, source)
+	return wsa.store.DeleteDocument(ctx, 1, source)
+}
+
+func (wsa *watcherStoreAdapter) RecordIngestFailure(ctx context.Context, userID int64, folder, path, errMsg string) (int, error) {
+	return wsa.store.RecordIngestFailure(ctx, userID, folder, path, errMsg)
+}
+
+func (wsa *watcherStoreAdapter) GetIngestFailureAttempts(ctx context.Context, path string) (int, error) {
+	return wsa.store.GetIngestFailureAttempts(ctx, path)
+}
+
+func (wsa *watcherStoreAdapter) ClearIngestFailure(ctx context.Context, path string) error {
+	return wsa.store.ClearIngestFailure(ctx, path)
+}
+
+// ingestStoreAdapter adapts store.Store to ingest.Store interface
+type ingestStoreAdapter struct {
+	store *store.Store
+}
+
+func (isa *ingestStoreAdapter) SaveChunk(ctx context.Context, userID int64, source, text string, embedding []float32, tags []string, summary string) error {
+	return isa.store.SaveChunk(ctx, userID, source, text, embedding, tags, summary)
+}
+
+func (isa *ingestStoreAdapter) DeleteChunksBySource(ctx context.Context, userID int64, source string) error {
+	return isa.store.DeleteChunksBySource(ctx, userID, source)
+}
+
+func (isa *ingestStoreAdapter) SaveSummary(ctx context.Context, userID int64, source, summary, model string) error {
+	return isa.store.SaveSummary(ctx, userID, source, summary, model)
+}
+
+func (isa *ingestStoreAdapter) GetSourceTexts(ctx context.Context, userID int64, source string) ([]string, error) {
+	return isa.store.GetSourceTexts(ctx, userID, source)
+}
+
+func (isa *ingestStoreAdapter) GetDefaultVisibility(ctx context.Context, userID int64) (string, error) {
+	return isa.store.GetDefaultVisibility(ctx, userID)
+}
+
+func (isa *ingestStoreAdapter) GetDocumentContent(ctx context.Context, userID int64, source string) (*ingest.DocumentContent, error) {
+	content, err := isa.store.GetDocumentContent(ctx, userID, source)
+	if err != nil || content == nil {
+		return nil, err
+	}
+	return &ingest.DocumentContent{Content: content.Content, Chunker: content.Chunker}, nil
+}
+
+func (isa *ingestStoreAdapter) WithTx(ctx context.Context, fn func(tx ingest.StoreTx) error) error {
+	return isa.store.WithTx(ctx, func(tx store.StoreTx) error {
+		return fn(tx)
+	})
 }
 
 // apiStoreAdapter adapts store.Store to api.Store interface
@@ -289,16 +365,17 @@ func (asa *apiStoreAdapter) Search(ctx context.Context, queryVec []float32, topK
 	apiChunks := make([]api.Chunk, len(storeChunks))
 	for i, sc := range storeChunks {
 		apiChunks[i] = api.Chunk{
+			ID:     sc.ID,
 			Source: sc.Source,
 			Text:   sc.Text,
-			Score:  0, // Score calculated by store
+			Score:  sc.Score,
 		}
 	}
 	return apiChunks, nil
 }
 
-func (asa *apiStoreAdapter) SearchByUser(ctx context.Context, userID int64, queryVec []float32, topK int) ([]api.Chunk, error) {
-	storeChunks, err := asa.store.SearchByUser(ctx, userID, queryVec, topK)
+func (asa *apiStoreAdapter) SearchByUser(ctx context.Context, userID int64, queryVec []float32, queryModel string, topK int) ([]api.Chunk, error) {
+	storeChunks, err := asa.store.SearchByUser(ctx, userID, queryVec, queryModel, topK)
 	if err != nil {
 		return nil, err
 	}
@@ -307,14 +384,110 @@ func (asa *apiStoreAdapter) SearchByUser(ctx context.Context, userID int64, quer
 	apiChunks := make([]api.Chunk, len(storeChunks))
 	for i, sc := range storeChunks {
 		apiChunks[i] = api.Chunk{
+			ID:        sc.ID,
+			Source:    sc.Source,
+			Text:      sc.Text,
+			Score:     sc.Score,
+			Embedding: sc.Embedding,
+		}
+	}
+	return apiChunks, nil
+}
+
+func (asa *apiStoreAdapter) SearchUserSources(ctx context.Context, userID int64, queryVec []float32, queryModel string, sources []string, topK int) ([]api.Chunk, error) {
+	storeChunks, err := asa.store.SearchUserSources(ctx, userID, queryVec, queryModel, sources, topK)
+	if err != nil {
+		return nil, err
+	}
+
+	apiChunks := make([]api.Chunk, len(storeChunks))
+	for i, sc := range storeChunks {
+		apiChunks[i] = api.Chunk{
+			ID:        sc.ID,
+			Source:    sc.Source,
+			Text:      sc.Text,
+			Score:     sc.Score,
+			Embedding: sc.Embedding,
+		}
+	}
+	return apiChunks, nil
+}
+
+func (asa *apiStoreAdapter) SearchLibrary(ctx context.Context, userID int64, queryVec []float32, opts api.SearchOptions, topK int) ([]api.Chunk, error) {
+	storeChunks, err := asa.store.SearchLibrary(ctx, userID, queryVec, store.SearchOptions{
+		Query:      opts.Query,
+		QueryModel: opts.QueryModel,
+		Sources:    opts.Sources,
+		Mode:       opts.Mode,
+	}, topK)
+	if err != nil {
+		return nil, err
+	}
+
+	apiChunks := make([]api.Chunk, len(storeChunks))
+	for i, sc := range storeChunks {
+		apiChunks[i] = api.Chunk{
+			ID:        sc.ID,
+			Source:    sc.Source,
+			Text:      sc.Text,
+			Score:     sc.Score,
+			Embedding: sc.Embedding,
+		}
+	}
+	return apiChunks, nil
+}
+
+func (asa *apiStoreAdapter) GetSourceChunks(ctx context.Context, userID int64, source string) ([]api.Chunk, error) {
+	storeChunks, err := asa.store.GetSourceChunks(ctx, userID, source)
+	if err != nil {
+		return nil, err
+	}
+
+	apiChunks := make([]api.Chunk, len(storeChunks))
+	for i, sc := range storeChunks {
+		apiChunks[i] = api.Chunk{
+			ID:     sc.ID,
 			Source: sc.Source,
 			Text:   sc.Text,
-			Score:  0, // Score calculated by store
 		}
 	}
 	return apiChunks, nil
 }
 
+func (asa *apiStoreAdapter) GetChunkedDocuments(ctx context.Context, userID int64) ([]api.ChunkedDocument, error) {
+	docs, err := asa.store.GetChunkedDocuments(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	apiDocs := make([]api.ChunkedDocument, len(docs))
+	for i, doc := range docs {
+		apiDocs[i] = api.ChunkedDocument{Source: doc.Source, Stored: doc.Stored, Chunker: doc.Chunker}
+	}
+	return apiDocs, nil
+}
+
+func (asa *apiStoreAdapter) GetDocumentSummaries(ctx context.Context, userID int64, model string) ([]api.DocumentSummary, error) {
+	states, err := asa.store.GetSummaryStates(ctx, userID, false)
+	if err != nil {
+		return nil, err
+	}
+
+	summaries := make([]api.DocumentSummary, len(states))
+	for i, st := range states {
+		summaries[i] = api.DocumentSummary{
+			Source:  st.Source,
+			Summary: st.Summary,
+			Model:   st.Model,
+			Stale:   st.StaleReason(model),
+		}
+		if !st.GeneratedAt.IsZero() {
+			generatedAt := st.GeneratedAt
+			summaries[i].GeneratedAt = &generatedAt
+		}
+	}
+	return summaries, nil
+}
+
 func (asa *apiStoreAdapter) Library(ctx context.Context) ([]api.LibraryEntry, error) {
 	storeLibrary, err := asa.store.Library(ctx)
 	if err != nil {
@@ -325,11 +498,15 @@ func (asa *apiStoreAdapter) Library(ctx context.Context) ([]api.LibraryEntry, er
 	apiLibrary := make([]api.LibraryEntry, len(storeLibrary))
 	for i, sle := range storeLibrary {
 		apiLibrary[i] = api.LibraryEntry{
+			DocumentID: sle.DocumentID,
 			Source:     sle.Source,
+			Title:      sle.Title,
 			ChunkCount: sle.ChunkCount,
 			Summary:    sle.Summary,
 			Tags:       sle.Tags,
 			CreatedAt:  sle.CreatedAt,
+			Warning:    sle.Warning,
+			Pinned:     sle.Pinned,
 		}
 	}
 	return apiLibrary, nil
@@ -345,19 +522,233 @@ func (asa *apiStoreAdapter) LibraryByUser(ctx context.Context, userID int64) ([]
 	apiLibrary := make([]api.LibraryEntry, len(storeLibrary))
 	for i, sle := range storeLibrary {
 		apiLibrary[i] = api.LibraryEntry{
+			DocumentID: sle.DocumentID,
 			Source:     sle.Source,
+			Title:      sle.Title,
 			ChunkCount: sle.ChunkCount,
 			Summary:    sle.Summary,
 			Tags:       sle.Tags,
 			CreatedAt:  sle.CreatedAt,
+			Warning:    sle.Warning,
+			Pinned:     sle.Pinned,
 		}
 	}
 	return apiLibrary, nil
 }
 
+func (asa *apiStoreAdapter) ForEachLibraryEntry(ctx context.Context, userID int64, fn func(api.LibraryEntry) bool) error {
+	return asa.store.ForEachLibraryEntry(ctx, userID, func(sle store.LibraryEntry) bool {
+		return fn(api.LibraryEntry{
+			DocumentID: sle.DocumentID,
+			Source:     sle.Source,
+			Title:      sle.Title,
+			ChunkCount: sle.ChunkCount,
+			Summary:    sle.Summary,
+			Tags:       sle.Tags,
+			CreatedAt:  sle.CreatedAt,
+			Warning:    sle.Warning,
+			Pinned:     sle.Pinned,
+		})
+	})
+}
+
 func (asa *apiStoreAdapter) DeleteSource(ctx context.Context, source string) error {
 	// Use local-default user (ID=1) for backward compatibility
-	return asa.store.DeleteChunksBySource(ctx, 1, source)
+	return asa.store.DeleteDocument(ctx, 1, source)
+}
+
+func (asa *apiStoreAdapter) GetDocument(ctx context.Context, userID, documentID int64) (*api.Document, error) {
+	doc, err := asa.store.GetDocument(ctx, userID, documentID)
+	if err != nil || doc == nil {
+		return nil, err
+	}
+	return &api.Document{
+		ID:         doc.ID,
+		Source:     doc.Source,
+		Title:      doc.Title,
+		Visibility: doc.Visibility,
+		CreatedAt:  doc.CreatedAt,
+		UpdatedAt:  doc.UpdatedAt,
+	}, nil
+}
+
+func (asa *apiStoreAdapter) RenameDocument(ctx context.Context, userID, documentID int64, title string) error {
+	return asa.store.RenameDocument(ctx, userID, documentID, title)
+}
+
+func (asa *apiStoreAdapter) PinDocument(ctx context.Context, userID, documentID int64) (bool, error) {
+	return asa.store.PinDocument(ctx, userID, documentID)
+}
+
+func (asa *apiStoreAdapter) UnpinDocument(ctx context.Context, userID, documentID int64) error {
+	return asa.store.UnpinDocument(ctx, userID, documentID)
+}
+
+func (asa *apiStoreAdapter) AddQuickNote(ctx context.Context, userID int64, source, text string, dedupSince time.Time) (int64, error) {
+	note := &store.QuickNote{UserID: userID, Source: source, Text: text}
+	added, err := asa.store.AddQuickNote(ctx, note, dedupSince)
+	if err != nil || !added {
+		return 0, err
+	}
+	return note.ID, nil
+}
+
+func (asa *apiStoreAdapter) GetQuickNotes(ctx context.Context, userID int64, source string) ([]api.QuickNote, error) {
+	notes, err := asa.store.GetQuickNotes(ctx, userID, source)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]api.QuickNote, len(notes))
+	for i, note := range notes {
+		result[i] = api.QuickNote{ID: note.ID, Text: note.Text, CreatedAt: note.CreatedAt}
+	}
+	return result, nil
+}
+
+func (asa *apiStoreAdapter) DeleteQuickNote(ctx context.Context, userID, noteID int64) error {
+	return asa.store.DeleteQuickNote(ctx, userID, noteID)
+}
+
+func (asa *apiStoreAdapter) GetDefaultVisibility(ctx context.Context, userID int64) (string, error) {
+	return asa.store.GetDefaultVisibility(ctx, userID)
+}
+
+func (asa *apiStoreAdapter) SetDefaultVisibility(ctx context.Context, userID int64, visibility string) error {
+	return asa.store.SetDefaultVisibility(ctx, userID, visibility)
+}
+
+func (asa *apiStoreAdapter) CountChunkVisibility(ctx context.Context, chunkIDs []int64) (map[string]int, error) {
+	return asa.store.CountChunkVisibility(ctx, chunkIDs)
+}
+
+func (asa *apiStoreAdapter) SetDocumentsVisibility(ctx context.Context, userID int64, documentIDs []int64, visibility string) (int, error) {
+	return asa.store.SetDocumentsVisibility(ctx, userID, documentIDs, visibility)
+}
+
+func (asa *apiStoreAdapter) GetCostThreshold(ctx context.Context, userID int64) (*float64, error) {
+	return asa.store.GetCostThreshold(ctx, userID)
+}
+
+func (asa *apiStoreAdapter) SetCostThreshold(ctx context.Context, userID int64, threshold *float64) error {
+	return asa.store.SetCostThreshold(ctx, userID, threshold)
+}
+
+func (asa *apiStoreAdapter) SaveCostEstimate(ctx context.Context, e api.CostEstimate) error {
+	return asa.store.SaveCostEstimate(ctx, store.CostEstimate{
+		UserID:           e.UserID,
+		SessionID:        e.SessionID,
+		Provider:         e.Provider,
+		Model:            e.Model,
+		PromptTokens:     e.PromptTokens,
+		CompletionTokens: e.CompletionTokens,
+		CostUSD:          e.CostUSD,
+		ThresholdUSD:     e.ThresholdUSD,
+		Outcome:          e.Outcome,
+	})
+}
+
+func (asa *apiStoreAdapter) GetCostEstimates(ctx context.Context, userID int64, limit int) ([]api.CostEstimate, error) {
+	estimates, err := asa.store.GetCostEstimates(ctx, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	apiEstimates := make([]api.CostEstimate, len(estimates))
+	for i, e := range estimates {
+		apiEstimates[i] = api.CostEstimate{
+			ID:               e.ID,
+			UserID:           e.UserID,
+			SessionID:        e.SessionID,
+			Provider:         e.Provider,
+			Model:            e.Model,
+			PromptTokens:     e.PromptTokens,
+			CompletionTokens: e.CompletionTokens,
+			CostUSD:          e.CostUSD,
+			ThresholdUSD:     e.ThresholdUSD,
+			Outcome:          e.Outcome,
+			CreatedAt:        e.CreatedAt,
+		}
+	}
+	return apiEstimates, nil
+}
+
+func (asa *apiStoreAdapter) SetDocumentLinks(ctx context.Context, userID int64, source string, targets []string) error {
+	return asa.store.SetDocumentLinks(ctx, userID, source, targets)
+}
+
+func (asa *apiStoreAdapter) GetDocumentLinks(ctx context.Context, userID int64, source string) ([]string, error) {
+	return asa.store.GetDocumentLinks(ctx, userID, source)
+}
+
+func (asa *apiStoreAdapter) GetDocumentEncoding(ctx context.Context, userID int64, source string) (*api.DocumentEncoding, error) {
+	e, err := asa.store.GetDocumentEncoding(ctx, userID, source)
+	if err != nil || e == nil {
+		return nil, err
+	}
+	return &api.DocumentEncoding{Source: e.Source, Encoding: e.Encoding, Changes: e.Changes, UpdatedAt: e.UpdatedAt}, nil
+}
+
+func (asa *apiStoreAdapter) RecordChunkAccess(ctx context.Context, access api.ChunkAccess, chunkIDs []int64, tag string) (int, error) {
+	return asa.store.RecordChunkAccess(ctx, store.ChunkAccess{
+		UserID:       access.UserID,
+		SessionID:    access.SessionID,
+		Query:        access.Query,
+		Provider:     access.Provider,
+		ProviderMode: access.ProviderMode,
+	}, chunkIDs, tag)
+}
+
+func (asa *apiStoreAdapter) GetChunkAccessLog(ctx context.Context, ownerID int64, source string, since time.Time, limit int) ([]api.ChunkAccess, error) {
+	entries, err := asa.store.GetChunkAccessLog(ctx, ownerID, source, since, limit)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]api.ChunkAccess, len(entries))
+	for i, e := range entries {
+		result[i] = api.ChunkAccess{
+			UserID:       e.UserID,
+			Username:     e.Username,
+			ChunkID:      e.ChunkID,
+			Source:       e.Source,
+			SessionID:    e.SessionID,
+			Query:        e.Query,
+			Provider:     e.Provider,
+			ProviderMode: e.ProviderMode,
+			AccessedAt:   e.AccessedAt,
+		}
+	}
+	return result, nil
+}
+
+func (asa *apiStoreAdapter) RecordRetrievals(ctx context.Context, hits []api.RetrievalHit) error {
+	storeHits := make([]store.RetrievalHit, len(hits))
+	for i, h := range hits {
+		storeHits[i] = store.RetrievalHit{ChunkID: h.ChunkID, Score: h.Score, Selected: h.Selected}
+	}
+	return asa.store.RecordRetrievals(ctx, storeHits)
+}
+
+func (asa *apiStoreAdapter) GetRetrievalStats(ctx context.Context, userID int64, since time.Time) ([]api.SourceRetrieval, error) {
+	stats, err := asa.store.GetRetrievalStats(ctx, userID, since)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]api.SourceRetrieval, len(stats))
+	for i, st := range stats {
+		result[i] = api.SourceRetrieval{
+			Source:     st.Source,
+			Chunks:     st.Chunks,
+			CreatedAt:  st.CreatedAt,
+			Candidates: st.Candidates,
+			Selected:   st.Selected,
+			BestScore:  st.BestScore,
+			AvgScore:   st.AvgScore,
+		}
+		if !st.LastRetrieved.IsZero() {
+			lastRetrieved := st.LastRetrieved
+			result[i].LastRetrieved = &lastRetrieved
+		}
+	}
+	return result, nil
 }
 
 func (asa *apiStoreAdapter) SaveMessage(ctx context.Context, sessionID, role, content string) error {
@@ -368,6 +759,121 @@ func (asa *apiStoreAdapter) SaveChatMessage(ctx context.Context, userID int64, s
 	return asa.store.SaveChatMessage(ctx, userID, sessionID, role, content, providerMode)
 }
 
+func (asa *apiStoreAdapter) SaveChatMessageWithCitations(ctx context.Context, userID int64, sessionID, role, content, providerMode string, citations []string) error {
+	return asa.store.SaveChatMessageWithCitations(ctx, userID, sessionID, role, content, providerMode, citations)
+}
+
+func (asa *apiStoreAdapter) SaveChatMessageWithProvenance(ctx context.Context, userID int64, sessionID, role, content, providerMode string, citations []string, provenance *api.MessageProvenance) (int64, error) {
+	var storeProvenance *store.MessageProvenance
+	if provenance != nil {
+		storeProvenance = &store.MessageProvenance{
+			Provider:         provenance.Provider,
+			Model:            provenance.Model,
+			Params:           string(provenance.Params),
+			PromptHash:       provenance.PromptHash,
+			ChunkIDs:         provenance.ChunkIDs,
+			PromptTokens:     provenance.PromptTokens,
+			CompletionTokens: provenance.CompletionTokens,
+			FirstTokenMS:     provenance.FirstTokenMS,
+			GenerationMS:     provenance.GenerationMS,
+			Privacy:          encodePrivacy(provenance.Privacy),
+		}
+	}
+	return asa.store.SaveChatMessageWithProvenance(ctx, userID, sessionID, role, content, providerMode, citations, storeProvenance)
+}
+
+// encodePrivacy stores a privacy summary as JSON; empty when there is none
+func encodePrivacy(p *api.PrivacySummary) string {
+	if p == nil {
+		return ""
+	}
+	data, err := json.Marshal(p)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// decodePrivacy reads a stored privacy summary; nil when none was recorded
+func decodePrivacy(s string) *api.PrivacySummary {
+	if s == "" {
+		return nil
+	}
+	var p api.PrivacySummary
+	if err := json.Unmarshal([]byte(s), &p); err != nil {
+		return nil
+	}
+	return &p
+}
+
+func (asa *apiStoreAdapter) GetMessageProvenance(ctx context.Context, userID, messageID int64) (*api.MessageProvenance, error) {
+	p, err := asa.store.GetMessageProvenance(ctx, userID, messageID)
+	if err != nil || p == nil {
+		return nil, err
+	}
+	return &api.MessageProvenance{
+		MessageID:        p.MessageID,
+		Provider:         p.Provider,
+		Model:            p.Model,
+		Params:           json.RawMessage(p.Params),
+		PromptHash:       p.PromptHash,
+		ChunkIDs:         p.ChunkIDs,
+		PromptTokens:     p.PromptTokens,
+		CompletionTokens: p.CompletionTokens,
+		FirstTokenMS:     p.FirstTokenMS,
+		GenerationMS:     p.GenerationMS,
+		Privacy:          decodePrivacy(p.Privacy),
+		CreatedAt:        p.CreatedAt,
+	}, nil
+}
+
+func (asa *apiStoreAdapter) GetResponseStats(ctx context.Context, userID int64, since time.Time) ([]api.ResponseStats, error) {
+	stats, err := asa.store.GetResponseStats(ctx, userID, since)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]api.ResponseStats, len(stats))
+	for i, st := range stats {
+		result[i] = api.ResponseStats{
+			Provider:         st.Provider,
+			Model:            st.Model,
+			Responses:        st.Responses,
+			AvgFirstTokenMS:  st.AvgFirstTokenMS,
+			TokensPerSecond:  st.TokensPerSecond,
+			PromptTokens:     st.PromptTokens,
+			CompletionTokens: st.CompletionTokens,
+		}
+	}
+	return result, nil
+}
+
+func (asa *apiStoreAdapter) SaveMessageArtifacts(ctx context.Context, messageID int64, artifacts []api.MessageArtifact) error {
+	storeArtifacts := make([]store.MessageArtifact, len(artifacts))
+	for i, a := range artifacts {
+		storeArtifacts[i] = store.MessageArtifact{Kind: a.Kind, Language: a.Language, Content: a.Content}
+	}
+	return asa.store.SaveMessageArtifacts(ctx, messageID, storeArtifacts)
+}
+
+func (asa *apiStoreAdapter) GetMessageArtifacts(ctx context.Context, userID, messageID int64) ([]api.MessageArtifact, error) {
+	artifacts, err := asa.store.GetMessageArtifacts(ctx, userID, messageID)
+	if err != nil || artifacts == nil {
+		return nil, err
+	}
+	apiArtifacts := make([]api.MessageArtifact, len(artifacts))
+	for i, a := range artifacts {
+		apiArtifacts[i] = api.MessageArtifact{
+			MessageID: a.MessageID,
+			N:         a.N,
+			Kind:      a.Kind,
+			Language:  a.Language,
+			Content:   a.Content,
+			CreatedAt: a.CreatedAt,
+		}
+	}
+	return apiArtifacts, nil
+}
+
 func (asa *apiStoreAdapter) GetSessionHistory(ctx context.Context, sessionID string) ([]api.ChatMessage, error) {
 	storeMessages, err := asa.store.GetSessionHistory(ctx, sessionID)
 	if err != nil {
@@ -404,6 +910,8 @@ func (asa *apiStoreAdapter) GetSessionMessages(ctx context.Context, userID int64
 			Role:         sm.Role,
 			Content:      sm.Content,
 			ProviderMode: sm.ProviderMode,
+			Citations:    sm.Citations,
+			Privacy:      decodePrivacy(sm.Privacy),
 			CreatedAt:    sm.CreatedAt,
 		}
 	}
@@ -423,11 +931,28 @@ func (asa *apiStoreAdapter) ListSessions(ctx context.Context) ([]api.Session, er
 			ID:            ss.ID,
 			LastMessageAt: ss.LastMessageAt,
 			MessageCount:  ss.MessageCount,
+			ContinuedFrom: ss.ContinuedFrom,
+			ImportedFrom:  ss.ImportedFrom,
 		}
 	}
 	return apiSessions, nil
 }
 
+func (asa *apiStoreAdapter) ImportChatSession(ctx context.Context, userID int64, session *api.ImportedSession) (bool, error) {
+	messages := make([]store.ImportedMessage, len(session.Messages))
+	for i, msg := range session.Messages {
+		messages[i] = store.ImportedMessage{Role: msg.Role, Content: msg.Content, CreatedAt: msg.CreatedAt}
+	}
+	return asa.store.ImportChatSession(ctx, userID, &store.ImportedSession{
+		SessionID:  session.SessionID,
+		Format:     session.Format,
+		ExternalID: session.ExternalID,
+		Title:      session.Title,
+		CreatedAt:  session.CreatedAt,
+		Messages:   messages,
+	})
+}
+
 func (asa *apiStoreAdapter) GetUserSessions(ctx context.Context, userID int64) ([]api.Session, error) {
 	storeSessions, err := asa.store.GetUserSessions(ctx, userID)
 	if err != nil {
@@ -441,6 +966,7 @@ func (asa *apiStoreAdapter) GetUserSessions(ctx context.Context, userID int64) (
 			ID:            ss.ID,
 			LastMessageAt: ss.LastMessageAt,
 			MessageCount:  ss.MessageCount,
+			ContinuedFrom: ss.ContinuedFrom,
 		}
 	}
 	return apiSessions, nil
@@ -450,6 +976,148 @@ func (asa *apiStoreAdapter) GetSessionOwner(ctx context.Context, sessionID strin
 	return asa.store.GetSessionOwner(ctx, sessionID)
 }
 
+func (asa *apiStoreAdapter) CreateSessionLink(ctx context.Context, userID int64, link *api.SessionLink) error {
+	return asa.store.CreateSessionLink(ctx, &store.SessionLink{
+		SessionID:     link.SessionID,
+		UserID:        userID,
+		ContinuedFrom: link.ContinuedFrom,
+		Summary:       link.Summary,
+		Model:         link.Model,
+	})
+}
+
+func (asa *apiStoreAdapter) GetSessionLink(ctx context.Context, userID int64, sessionID string) (*api.SessionLink, error) {
+	link, err := asa.store.GetSessionLink(ctx, userID, sessionID)
+	if err != nil || link == nil {
+		return nil, err
+	}
+	return &api.SessionLink{
+		SessionID:     link.SessionID,
+		ContinuedFrom: link.ContinuedFrom,
+		Summary:       link.Summary,
+		Model:         link.Model,
+		CreatedAt:     link.CreatedAt,
+	}, nil
+}
+
+func (asa *apiStoreAdapter) GetSessionMode(ctx context.Context, userID int64, sessionID string) (string, error) {
+	return asa.store.GetSessionMode(ctx, userID, sessionID)
+}
+
+func (asa *apiStoreAdapter) SetSessionMode(ctx context.Context, userID int64, sessionID, mode string) error {
+	return asa.store.SetSessionMode(ctx, userID, sessionID, mode)
+}
+
+func (asa *apiStoreAdapter) GetSessionSummary(ctx context.Context, userID int64, sessionID string) (*api.SessionSummary, error) {
+	summary, err := asa.store.GetSessionSummary(ctx, userID, sessionID)
+	if err != nil || summary == nil {
+		return nil, err
+	}
+	return &api.SessionSummary{
+		SessionID:        summary.SessionID,
+		Summary:          summary.Summary,
+		ThroughMessageID: summary.ThroughMessageID,
+		Model:            summary.Model,
+		UpdatedAt:        summary.UpdatedAt,
+	}, nil
+}
+
+func (asa *apiStoreAdapter) SaveSessionSummary(ctx context.Context, userID int64, summary *api.SessionSummary) error {
+	return asa.store.SaveSessionSummary(ctx, &store.SessionSummary{
+		SessionID:        summary.SessionID,
+		UserID:           userID,
+		Summary:          summary.Summary,
+		ThroughMessageID: summary.ThroughMessageID,
+		Model:            summary.Model,
+	})
+}
+
+func (asa *apiStoreAdapter) GetSessionsToCompact(ctx context.Context, idleBefore time.Time, keep int) ([]api.SessionToCompact, error) {
+	sessions, err := asa.store.GetSessionsToCompact(ctx, idleBefore, keep)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]api.SessionToCompact, len(sessions))
+	for i, session := range sessions {
+		result[i] = api.SessionToCompact{SessionID: session.SessionID, UserID: session.UserID}
+	}
+	return result, nil
+}
+
+func (asa *apiStoreAdapter) CreateSessionShare(ctx context.Context, share *api.SessionShare) (int64, error) {
+	return asa.store.CreateSessionShare(ctx, &store.SessionShare{
+		Token:        share.Token,
+		SessionID:    share.SessionID,
+		UserID:       share.UserID,
+		PasswordHash: share.PasswordHash,
+		ExpiresAt:    share.ExpiresAt,
+	})
+}
+
+func (asa *apiStoreAdapter) GetSessionShareByToken(ctx context.Context, token string) (*api.SessionShare, error) {
+	storeShare, err := asa.store.GetSessionShareByToken(ctx, token)
+	if err != nil || storeShare == nil {
+		return nil, err
+	}
+	share := toAPISessionShare(*storeShare)
+	return &share, nil
+}
+
+func (asa *apiStoreAdapter) GetSessionShares(ctx context.Context, userID int64, sessionID string) ([]api.SessionShare, error) {
+	storeShares, err := asa.store.GetSessionShares(ctx, userID, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	apiShares := make([]api.SessionShare, len(storeShares))
+	for i, s := range storeShares {
+		apiShares[i] = toAPISessionShare(s)
+	}
+	return apiShares, nil
+}
+
+func (asa *apiStoreAdapter) RevokeSessionShare(ctx context.Context, userID, shareID int64) error {
+	return asa.store.RevokeSessionShare(ctx, userID, shareID)
+}
+
+func (asa *apiStoreAdapter) RecordSessionShareView(ctx context.Context, shareID int64, ipAddress, userAgent string) error {
+	return asa.store.RecordSessionShareView(ctx, shareID, ipAddress, userAgent)
+}
+
+func (asa *apiStoreAdapter) GetSessionShareViews(ctx context.Context, userID, shareID int64, limit int) ([]api.SessionShareView, error) {
+	storeViews, err := asa.store.GetSessionShareViews(ctx, userID, shareID, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	apiViews := make([]api.SessionShareView, len(storeViews))
+	for i, v := range storeViews {
+		apiViews[i] = api.SessionShareView{
+			ID:        v.ID,
+			ShareID:   v.ShareID,
+			IPAddress: v.IPAddress,
+			UserAgent: v.UserAgent,
+			ViewedAt:  v.ViewedAt,
+		}
+	}
+	return apiViews, nil
+}
+
+// toAPISessionShare converts a store.SessionShare to an api.SessionShare
+func toAPISessionShare(s store.SessionShare) api.SessionShare {
+	return api.SessionShare{
+		ID:           s.ID,
+		Token:        s.Token,
+		SessionID:    s.SessionID,
+		UserID:       s.UserID,
+		PasswordHash: s.PasswordHash,
+		ExpiresAt:    s.ExpiresAt,
+		RevokedAt:    s.RevokedAt,
+		ViewCount:    s.ViewCount,
+		CreatedAt:    s.CreatedAt,
+	}
+}
+
 func (asa *apiStoreAdapter) AddAuditEntry(ctx context.Context, opType, details, userCtx string) error {
 	return asa.store.AddAuditEntry(ctx, opType, details, userCtx)
 }
@@ -485,15 +1153,16 @@ func (asa *apiStoreAdapter) GetUserByUsername(ctx context.Context, username stri
 		email = user.Email.String
 	}
 	return &api.User{
-		ID:                 user.ID,
-		Username:           user.Username,
-		PasswordHash:       user.PasswordHash,
-		Email:              email,
-		IsAdmin:            user.IsAdmin,
-		MustChangePassword: user.MustChangePassword,
-		CreatedAt:          user.CreatedAt,
-		LastLogin:          user.LastLogin,
-		DarkMode:           user.DarkMode,
+		ID:                  user.ID,
+		Username:            user.Username,
+		PasswordHash:        user.PasswordHash,
+		Email:               email,
+		IsAdmin:             user.IsAdmin,
+		MustChangePassword:  user.MustChangePassword,
+		CreatedAt:           user.CreatedAt,
+		LastLogin:           user.LastLogin,
+		DarkMode:            user.DarkMode,
+		OnboardingCompleted: user.OnboardingCompleted,
 	}, nil
 }
 
@@ -507,15 +1176,16 @@ func (asa *apiStoreAdapter) GetUserByID(ctx context.Context, userID int64) (*api
 		email = user.Email.String
 	}
 	return &api.User{
-		ID:                 user.ID,
-		Username:           user.Username,
-		PasswordHash:       user.PasswordHash,
-		Email:              email,
-		IsAdmin:            user.IsAdmin,
-		MustChangePassword: user.MustChangePassword,
-		CreatedAt:          user.CreatedAt,
-		LastLogin:          user.LastLogin,
-		DarkMode:           user.DarkMode,
+		ID:                  user.ID,
+		Username:            user.Username,
+		PasswordHash:        user.PasswordHash,
+		Email:               email,
+		IsAdmin:             user.IsAdmin,
+		MustChangePassword:  user.MustChangePassword,
+		CreatedAt:           user.CreatedAt,
+		LastLogin:           user.LastLogin,
+		DarkMode:            user.DarkMode,
+		OnboardingCompleted: user.OnboardingCompleted,
 	}, nil
 }
 
@@ -531,6 +1201,14 @@ func (asa *apiStoreAdapter) UpdateUserDarkMode(ctx context.Context, userID int64
 	return asa.store.UpdateUserDarkMode(ctx, userID, darkMode)
 }
 
+func (asa *apiStoreAdapter) GetUserPreferences(ctx context.Context, userID int64) (map[string]string, error) {
+	return asa.store.GetUserPreferences(ctx, userID)
+}
+
+func (asa *apiStoreAdapter) SetUserPreferences(ctx context.Context, userID int64, prefs map[string]string) error {
+	return asa.store.SetUserPreferences(ctx, userID, prefs)
+}
+
 func (asa *apiStoreAdapter) ListUsers(ctx context.Context) ([]api.User, error) {
 	storeUsers, err := asa.store.ListUsers(ctx)
 	if err != nil {
@@ -562,6 +1240,34 @@ func (asa *apiStoreAdapter) DeleteUser(ctx context.Context, userID int64) error
 	return asa.store.DeleteUser(ctx, userID)
 }
 
+func (asa *apiStoreAdapter) CompleteOnboarding(ctx context.Context, userID int64) error {
+	return asa.store.CompleteOnboarding(ctx, userID)
+}
+
+func (asa *apiStoreAdapter) DeleteUserSessionTokens(ctx context.Context, userID int64, keepToken string) error {
+	return asa.store.DeleteUserSessionTokens(ctx, userID, keepToken)
+}
+
+func (asa *apiStoreAdapter) SetTemporaryPassword(ctx context.Context, userID int64, newPassword string) error {
+	return asa.store.SetTemporaryPassword(ctx, userID, newPassword)
+}
+
+func (asa *apiStoreAdapter) IsRecentPassword(ctx context.Context, userID int64, password string, n int) (bool, error) {
+	return asa.store.IsRecentPassword(ctx, userID, password, n)
+}
+
+func (asa *apiStoreAdapter) IsAccountLocked(ctx context.Context, username string, threshold int, window time.Duration) (bool, time.Time) {
+	return asa.store.IsAccountLocked(ctx, username, threshold, window)
+}
+
+func (asa *apiStoreAdapter) CountFailedLogins(ctx context.Context, username string, since time.Time) (int, error) {
+	return asa.store.CountFailedLogins(ctx, username, since)
+}
+
+func (asa *apiStoreAdapter) ClearFailedLogins(ctx context.Context, username string) error {
+	return asa.store.ClearFailedLogins(ctx, username)
+}
+
 // Skills management methods
 func (asa *apiStoreAdapter) GetUserSkills(ctx context.Context, userID int64) ([]api.Skill, error) {
 	storeSkills, err := asa.store.GetUserSkills(ctx, userID)
@@ -572,13 +1278,321 @@ func (asa *apiStoreAdapter) GetUserSkills(ctx context.Context, userID int64) ([]
 	apiSkills := make([]api.Skill, len(storeSkills))
 	for i, ss := range storeSkills {
 		apiSkills[i] = api.Skill{
-			Name: ss.Name,
-			Path: ss.Path,
+			ID:      ss.ID,
+			UserID:  ss.UserID,
+			Name:    ss.Name,
+			Path:    ss.Path,
+			Enabled: ss.Enabled,
+			Global:  ss.Global,
 		}
 	}
 	return apiSkills, nil
 }
 
+func (asa *apiStoreAdapter) UpdateSkillEnabled(ctx context.Context, userID, skillID int64, enabled bool) error {
+	return asa.store.UpdateSkillEnabled(ctx, userID, skillID, enabled)
+}
+
+func (asa *apiStoreAdapter) CreateGlobalSkill(ctx context.Context, adminID int64, name, path string, enabled bool) (int64, error) {
+	return asa.store.CreateGlobalSkill(ctx, adminID, name, path, enabled)
+}
+
+func (asa *apiStoreAdapter) GetGlobalSkills(ctx context.Context) ([]api.GlobalSkill, error) {
+	storeSkills, err := asa.store.GetGlobalSkills(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	apiSkills := make([]api.GlobalSkill, len(storeSkills))
+	for i, ss := range storeSkills {
+		apiSkills[i] = api.GlobalSkill{
+			ID:        ss.ID,
+			Name:      ss.Name,
+			Path:      ss.Path,
+			Enabled:   ss.Enabled,
+			AddedBy:   ss.UserID,
+			CreatedAt: ss.CreatedAt,
+		}
+	}
+	return apiSkills, nil
+}
+
+func (asa *apiStoreAdapter) UpdateGlobalSkill(ctx context.Context, skillID int64, enabled bool) (bool, error) {
+	return asa.store.UpdateGlobalSkill(ctx, skillID, enabled)
+}
+
+func (asa *apiStoreAdapter) DeleteGlobalSkill(ctx context.Context, skillID int64) (bool, error) {
+	return asa.store.DeleteGlobalSkill(ctx, skillID)
+}
+
+func (asa *apiStoreAdapter) RecordSkillRun(ctx context.Context, run *api.SkillRun) (int64, error) {
+	return asa.store.RecordSkillRun(ctx, &store.SkillRun{
+		SkillID:        run.SkillID,
+		UserID:         run.UserID,
+		Status:         run.Status,
+		Input:          run.Input,
+		Output:         run.Output,
+		Error:          run.Error,
+		Stderr:         run.Stderr,
+		ExitCode:       run.ExitCode,
+		DurationMS:     run.DurationMS,
+		ArtifactSource: run.ArtifactSource,
+		RerunOf:        run.RerunOf,
+	})
+}
+
+func (asa *apiStoreAdapter) SetSkillRunArtifact(ctx context.Context, userID, runID int64, source string) error {
+	return asa.store.SetSkillRunArtifact(ctx, userID, runID, source)
+}
+
+func (asa *apiStoreAdapter) GetSkillRuns(ctx context.Context, userID, skillID int64, limit, offset int) ([]api.SkillRun, int, error) {
+	storeRuns, total, err := asa.store.GetSkillRuns(ctx, userID, skillID, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	apiRuns := make([]api.SkillRun, len(storeRuns))
+	for i, r := range storeRuns {
+		apiRuns[i] = toAPISkillRun(r)
+	}
+	return apiRuns, total, nil
+}
+
+func (asa *apiStoreAdapter) GetSkillRun(ctx context.Context, userID, runID int64) (*api.SkillRun, error) {
+	storeRun, err := asa.store.GetSkillRun(ctx, userID, runID)
+	if err != nil || storeRun == nil {
+		return nil, err
+	}
+	run := toAPISkillRun(*storeRun)
+	return &run, nil
+}
+
+// toAPISkillRun converts a store.SkillRun to an api.SkillRun
+func toAPISkillRun(r store.SkillRun) api.SkillRun {
+	return api.SkillRun{
+		ID:             r.ID,
+		SkillID:        r.SkillID,
+		UserID:         r.UserID,
+		Status:         r.Status,
+		Input:          r.Input,
+		Output:         r.Output,
+		Error:          r.Error,
+		Stderr:         r.Stderr,
+		ExitCode:       r.ExitCode,
+		DurationMS:     r.DurationMS,
+		ArtifactSource: r.ArtifactSource,
+		RerunOf:        r.RerunOf,
+		CreatedAt:      r.CreatedAt,
+	}
+}
+
+// Retrieval evaluation methods
+// Cases, configs, metrics and results are stored as JSON
+func (asa *apiStoreAdapter) CreateEvalSet(ctx context.Context, userID int64, name string, cases []eval.Case) (int64, error) {
+	casesJSON, err := json.Marshal(cases)
+	if err != nil {
+		return 0, err
+	}
+	return asa.store.CreateEvalSet(ctx, userID, name, string(casesJSON))
+}
+
+func (asa *apiStoreAdapter) UpdateEvalSet(ctx context.Context, userID, setID int64, name string, cases []eval.Case) error {
+	casesJSON, err := json.Marshal(cases)
+	if err != nil {
+		return err
+	}
+	return asa.store.UpdateEvalSet(ctx, userID, setID, name, string(casesJSON))
+}
+
+func (asa *apiStoreAdapter) GetEvalSets(ctx context.Context, userID int64) ([]api.EvalSet, error) {
+	storeSets, err := asa.store.GetEvalSets(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	sets := make([]api.EvalSet, len(storeSets))
+	for i, ss := range storeSets {
+		if sets[i], err = toAPIEvalSet(ss); err != nil {
+			return nil, err
+		}
+	}
+	return sets, nil
+}
+
+func (asa *apiStoreAdapter) GetEvalSet(ctx context.Context, userID, setID int64) (*api.EvalSet, error) {
+	storeSet, err := asa.store.GetEvalSet(ctx, userID, setID)
+	if err != nil || storeSet == nil {
+		return nil, err
+	}
+	set, err := toAPIEvalSet(*storeSet)
+	if err != nil {
+		return nil, err
+	}
+	return &set, nil
+}
+
+func (asa *apiStoreAdapter) DeleteEvalSet(ctx context.Context, userID, setID int64) error {
+	return asa.store.DeleteEvalSet(ctx, userID, setID)
+}
+
+func (asa *apiStoreAdapter) CreateSavedSearch(ctx context.Context, userID int64, name, query, mode string) (int64, error) {
+	return asa.store.CreateSavedSearch(ctx, userID, name, query, mode)
+}
+
+func (asa *apiStoreAdapter) GetSavedSearches(ctx context.Context, userID int64) ([]api.SavedSearch, error) {
+	storeSearches, err := asa.store.GetSavedSearches(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return toAPISavedSearches(storeSearches), nil
+}
+
+func (asa *apiStoreAdapter) GetSavedSearch(ctx context.Context, userID, id int64) (*api.SavedSearch, error) {
+	storeSearch, err := asa.store.GetSavedSearch(ctx, userID, id)
+	if err != nil || storeSearch == nil {
+		return nil, err
+	}
+	search := toAPISavedSearches([]store.SavedSearch{*storeSearch})[0]
+	return &search, nil
+}
+
+func (asa *apiStoreAdapter) GetStaleSavedSearches(ctx context.Context, model string, limit int) ([]api.SavedSearch, error) {
+	storeSearches, err := asa.store.GetStaleSavedSearches(ctx, model, limit)
+	if err != nil {
+		return nil, err
+	}
+	return toAPISavedSearches(storeSearches), nil
+}
+
+func (asa *apiStoreAdapter) SetSavedSearchEmbedding(ctx context.Context, id int64, embedding []float32, model string) error {
+	return asa.store.SetSavedSearchEmbedding(ctx, id, embedding, model)
+}
+
+func (asa *apiStoreAdapter) DeleteSavedSearch(ctx context.Context, userID, id int64) (bool, error) {
+	return asa.store.DeleteSavedSearch(ctx, userID, id)
+}
+
+// toAPISavedSearches converts store saved searches to their API form
+func toAPISavedSearches(storeSearches []store.SavedSearch) []api.SavedSearch {
+	searches := make([]api.SavedSearch, len(storeSearches))
+	for i, ss := range storeSearches {
+		searches[i] = api.SavedSearch{
+			ID:             ss.ID,
+			UserID:         ss.UserID,
+			Name:           ss.Name,
+			Query:          ss.Query,
+			Mode:           ss.Mode,
+			Embedding:      ss.Embedding,
+			EmbeddingModel: ss.EmbeddingModel,
+			EmbeddedAt:     ss.EmbeddedAt,
+			CreatedAt:      ss.CreatedAt,
+		}
+	}
+	return searches
+}
+
+func (asa *apiStoreAdapter) RecordEvalRun(ctx context.Context, userID int64, run *api.EvalRun) (int64, error) {
+	configJSON, err := json.Marshal(run.Config)
+	if err != nil {
+		return 0, err
+	}
+	metricsJSON, err := json.Marshal(run.Metrics)
+	if err != nil {
+		return 0, err
+	}
+	resultsJSON, err := json.Marshal(run.Results)
+	if err != nil {
+		return 0, err
+	}
+	return asa.store.RecordEvalRun(ctx, &store.EvalRun{
+		SetID:      run.SetID,
+		UserID:     userID,
+		Config:     string(configJSON),
+		Metrics:    string(metricsJSON),
+		Results:    string(resultsJSON),
+		DurationMS: run.DurationMS,
+	})
+}
+
+func (asa *apiStoreAdapter) GetEvalRuns(ctx context.Context, userID, setID int64, limit int) ([]api.EvalRun, error) {
+	storeRuns, err := asa.store.GetEvalRuns(ctx, userID, setID, limit)
+	if err != nil {
+		return nil, err
+	}
+	runs := make([]api.EvalRun, len(storeRuns))
+	for i, sr := range storeRuns {
+		if runs[i], err = toAPIEvalRun(sr); err != nil {
+			return nil, err
+		}
+	}
+	return runs, nil
+}
+
+func (asa *apiStoreAdapter) GetEvalRun(ctx context.Context, userID, runID int64) (*api.EvalRun, error) {
+	storeRun, err := asa.store.GetEvalRun(ctx, userID, runID)
+	if err != nil || storeRun == nil {
+		return nil, err
+	}
+	run, err := toAPIEvalRun(*storeRun)
+	if err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
+
+// toAPIEvalSet converts a store.EvalSet to an api.EvalSet
+func toAPIEvalSet(s store.EvalSet) (api.EvalSet, error) {
+	set := api.EvalSet{ID: s.ID, Name: s.Name, CreatedAt: s.CreatedAt, UpdatedAt: s.UpdatedAt}
+	if err := json.Unmarshal([]byte(s.Cases), &set.Cases); err != nil {
+		return set, fmt.Errorf("invalid cases of eval set %d: %w", s.ID, err)
+	}
+	return set, nil
+}
+
+// toAPIEvalRun converts a store.EvalRun to an api.EvalRun; results are left
+// empty when the run was listed without them
+func toAPIEvalRun(r store.EvalRun) (api.EvalRun, error) {
+	run := api.EvalRun{ID: r.ID, SetID: r.SetID, DurationMS: r.DurationMS, CreatedAt: r.CreatedAt}
+	if err := json.Unmarshal([]byte(r.Config), &run.Config); err != nil {
+		return run, fmt.Errorf("invalid config of eval run %d: %w", r.ID, err)
+	}
+	if err := json.Unmarshal([]byte(r.Metrics), &run.Metrics); err != nil {
+		return run, fmt.Errorf("invalid metrics of eval run %d: %w", r.ID, err)
+	}
+	if r.Results != "" {
+		if err := json.Unmarshal([]byte(r.Results), &run.Results); err != nil {
+			return run, fmt.Errorf("invalid results of eval run %d: %w", r.ID, err)
+		}
+	}
+	return run, nil
+}
+
+// Feature flag override methods
+func (asa *apiStoreAdapter) GetFlagOverrides(ctx context.Context) ([]api.FlagOverride, error) {
+	storeOverrides, err := asa.store.GetFlagOverrides(ctx)
+	if err != nil {
+		return nil, err
+	}
+	overrides := make([]api.FlagOverride, len(storeOverrides))
+	for i, o := range storeOverrides {
+		overrides[i] = api.FlagOverride{
+			UserID:    o.UserID,
+			Username:  o.Username,
+			Flag:      o.Flag,
+			Enabled:   o.Enabled,
+			UpdatedAt: o.UpdatedAt,
+		}
+	}
+	return overrides, nil
+}
+
+func (asa *apiStoreAdapter) SetFlagOverride(ctx context.Context, userID int64, flag string, enabled bool) error {
+	return asa.store.SetFlagOverride(ctx, userID, flag, enabled)
+}
+
+func (asa *apiStoreAdapter) DeleteFlagOverride(ctx context.Context, userID int64, flag string) error {
+	return asa.store.DeleteFlagOverride(ctx, userID, flag)
+}
+
 // Watched folders management methods
 func (asa *apiStoreAdapter) GetWatchedFoldersByUser(ctx context.Context, userID int64) ([]api.WatchedFolder, error) {
 	storeWatchedFolders, err := asa.store.GetWatchedFoldersByUser(ctx, userID)
@@ -597,6 +1611,458 @@ func (asa *apiStoreAdapter) GetWatchedFoldersByUser(ctx context.Context, userID
 	return apiWatchedFolders, nil
 }
 
+func (asa *apiStoreAdapter) CreateExtractionSchema(ctx context.Context, userID int64, name, description string, schema json.RawMessage) (int64, error) {
+	return asa.store.CreateExtractionSchema(ctx, userID, name, description, string(schema))
+}
+
+func (asa *apiStoreAdapter) GetExtractionSchemas(ctx context.Context, userID int64) ([]api.ExtractionSchema, error) {
+	storeSchemas, err := asa.store.GetExtractionSchemas(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	schemas := make([]api.ExtractionSchema, len(storeSchemas))
+	for i, es := range storeSchemas {
+		schemas[i] = toAPIExtractionSchema(es)
+	}
+	return schemas, nil
+}
+
+func (asa *apiStoreAdapter) GetExtractionSchema(ctx context.Context, userID, schemaID int64) (*api.ExtractionSchema, error) {
+	storeSchema, err := asa.store.GetExtractionSchema(ctx, userID, schemaID)
+	if err != nil || storeSchema == nil {
+		return nil, err
+	}
+	schema := toAPIExtractionSchema(*storeSchema)
+	return &schema, nil
+}
+
+func (asa *apiStoreAdapter) DeleteExtractionSchema(ctx context.Context, userID, schemaID int64) error {
+	return asa.store.DeleteExtractionSchema(ctx, userID, schemaID)
+}
+
+func (asa *apiStoreAdapter) SaveStructuredRecord(ctx context.Context, userID int64, record *api.StructuredRecord) (int64, error) {
+	data := []byte("{}")
+	if record.Data != nil {
+		var err error
+		if data, err = json.Marshal(record.Data); err != nil {
+			return 0, err
+		}
+	}
+	var violations string
+	if len(record.Violations) > 0 {
+		violationsJSON, err := json.Marshal(record.Violations)
+		if err != nil {
+			return 0, err
+		}
+		violations = string(violationsJSON)
+	}
+	return asa.store.SaveStructuredRecord(ctx, &store.StructuredRecord{
+		SchemaID:   record.SchemaID,
+		UserID:     userID,
+		Source:     record.Source,
+		Data:       string(data),
+		Status:     record.Status,
+		Violations: violations,
+		Error:      record.Error,
+		Model:      record.Model,
+	})
+}
+
+func (asa *apiStoreAdapter) GetStructuredRecords(ctx context.Context, userID int64, filter api.RecordFilter) ([]api.StructuredRecord, int, error) {
+	storeRecords, total, err := asa.store.GetStructuredRecords(ctx, userID, store.RecordFilter{
+		SchemaID: filter.SchemaID,
+		Status:   filter.Status,
+		Source:   filter.Source,
+		Fields:   filter.Fields,
+		Limit:    filter.Limit,
+		Offset:   filter.Offset,
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	records := make([]api.StructuredRecord, len(storeRecords))
+	for i, r := range storeRecords {
+		records[i] = api.StructuredRecord{
+			ID:          r.ID,
+			SchemaID:    r.SchemaID,
+			Source:      r.Source,
+			Status:      r.Status,
+			Error:       r.Error,
+			Model:       r.Model,
+			ExtractedAt: r.ExtractedAt,
+		}
+		if err := json.Unmarshal([]byte(r.Data), &records[i].Data); err != nil {
+			return nil, 0, fmt.Errorf("invalid data in structured record %d: %w", r.ID, err)
+		}
+		if r.Violations != "" {
+			if err := json.Unmarshal([]byte(r.Violations), &records[i].Violations); err != nil {
+				return nil, 0, fmt.Errorf("invalid violations in structured record %d: %w", r.ID, err)
+			}
+		}
+	}
+	return records, total, nil
+}
+
+func (asa *apiStoreAdapter) GetMetadataRows(ctx context.Context, userID int64, entity string) ([]metaquery.Row, error) {
+	switch entity {
+	case metaquery.Documents:
+		facts, err := asa.store.GetDocumentFacts(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		rows := make([]metaquery.Row, len(facts))
+		for i, f := range facts {
+			rows[i] = metaquery.Row{
+				"source":     f.Source,
+				"type":       documentType(f.Source),
+				"tags":       f.Tags,
+				"visibility": f.Visibility,
+				"chunks":     float64(f.Chunks),
+				"size":       float64(f.Size),
+				"created_at": f.CreatedAt,
+			}
+		}
+		return rows, nil
+
+	case metaquery.Chunks:
+		facts, err := asa.store.GetChunkFacts(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		rows := make([]metaquery.Row, len(facts))
+		for i, f := range facts {
+			rows[i] = metaquery.Row{
+				"source":     f.Source,
+				"type":       documentType(f.Source),
+				"tags":       f.Tags,
+				"visibility": f.Visibility,
+				"length":     float64(f.Length),
+				"created_at": f.CreatedAt,
+			}
+		}
+		return rows, nil
+
+	case metaquery.Sessions:
+		facts, err := asa.store.GetSessionFacts(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		rows := make([]metaquery.Row, len(facts))
+		for i, f := range facts {
+			rows[i] = metaquery.Row{
+				"title":           f.Title,
+				"messages":        float64(f.Messages),
+				"created_at":      f.CreatedAt,
+				"last_message_at": f.LastMessageAt,
+			}
+		}
+		return rows, nil
+	}
+	return nil, fmt.Errorf("unknown metadata entity: %s", entity)
+}
+
+// documentType is a document's lowercased file extension, e.g. "pdf"
+func documentType(source string) string {
+	return strings.ToLower(strings.TrimPrefix(filepath.Ext(source), "."))
+}
+
+func toAPIExtractionSchema(es store.ExtractionSchema) api.ExtractionSchema {
+	return api.ExtractionSchema{
+		ID:          es.ID,
+		Name:        es.Name,
+		Description: es.Description,
+		Schema:      json.RawMessage(es.Schema),
+		CreatedAt:   es.CreatedAt,
+	}
+}
+
+func (asa *apiStoreAdapter) CreateNotification(ctx context.Context, userID int64, notificationType, message, payload string) (*api.Notification, error) {
+	n, err := asa.store.CreateNotification(ctx, userID, notificationType, message, payload)
+	if err != nil {
+		return nil, err
+	}
+	notification := toAPINotification(*n)
+	return &notification, nil
+}
+
+func (asa *apiStoreAdapter) GetNotifications(ctx context.Context, userID int64, unreadOnly bool, limit int) ([]api.Notification, error) {
+	storeNotifications, err := asa.store.GetNotifications(ctx, userID, unreadOnly, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	notifications := make([]api.Notification, len(storeNotifications))
+	for i, n := range storeNotifications {
+		notifications[i] = toAPINotification(n)
+	}
+	return notifications, nil
+}
+
+func (asa *apiStoreAdapter) CountUnreadNotifications(ctx context.Context, userID int64) (int, error) {
+	return asa.store.CountUnreadNotifications(ctx, userID)
+}
+
+func (asa *apiStoreAdapter) MarkNotificationsRead(ctx context.Context, userID int64, ids []int64) (int64, error) {
+	return asa.store.MarkNotificationsRead(ctx, userID, ids)
+}
+
+func toAPINotification(n store.Notification) api.Notification {
+	notification := api.Notification{
+		ID:        n.ID,
+		Type:      n.Type,
+		Message:   n.Message,
+		Read:      n.Read,
+		CreatedAt: n.CreatedAt,
+	}
+	if n.Payload != "" {
+		notification.Payload = json.RawMessage(n.Payload)
+	}
+	return notification
+}
+
+func (asa *apiStoreAdapter) GetIngestFailures(ctx context.Context, userID int64, folder string) ([]api.IngestFailure, error) {
+	storeFailures, err := asa.store.GetIngestFailures(ctx, userID, folder)
+	if err != nil {
+		return nil, err
+	}
+
+	failures := make([]api.IngestFailure, len(storeFailures))
+	for i, f := range storeFailures {
+		failures[i] = api.IngestFailure{
+			Path:          f.Path,
+			Error:         f.Error,
+			Attempts:      f.Attempts,
+			FirstFailedAt: f.FirstFailedAt,
+			LastAttemptAt: f.LastAttemptAt,
+		}
+	}
+	return failures, nil
+}
+
+// Retrieval ranking methods
+func (asa *apiStoreAdapter) GetRankingWeights(ctx context.Context, userID int64) (*api.RankingWeights, error) {
+	weights, err := asa.store.GetRankingWeights(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &api.RankingWeights{
+		RecencyHalfLifeDays: weights.RecencyHalfLifeDays,
+		TagWeights:          weights.TagWeights,
+		SourceWeights:       weights.SourceWeights,
+	}, nil
+}
+
+func (asa *apiStoreAdapter) SaveRankingWeights(ctx context.Context, userID int64, weights api.RankingWeights) error {
+	return asa.store.SaveRankingWeights(ctx, userID, weights.RecencyHalfLifeDays, weights.TagWeights, weights.SourceWeights)
+}
+
+// Generation defaults methods
+func (asa *apiStoreAdapter) GetGenerationDefaults(ctx context.Context, userID int64) (*api.GenerationOptions, error) {
+	defaults, err := asa.store.GetGenerationDefaults(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &api.GenerationOptions{
+		Temperature: defaults.Temperature,
+		TopP:        defaults.TopP,
+		MaxTokens:   defaults.MaxTokens,
+	}, nil
+}
+
+func (asa *apiStoreAdapter) SaveGenerationDefaults(ctx context.Context, userID int64, opts api.GenerationOptions) error {
+	return asa.store.SaveGenerationDefaults(ctx, store.GenerationDefaults{
+		UserID:      userID,
+		Temperature: opts.Temperature,
+		TopP:        opts.TopP,
+		MaxTokens:   opts.MaxTokens,
+	})
+}
+
+func (asa *apiStoreAdapter) GetAnswerStyle(ctx context.Context, userID int64) (*api.AnswerStyle, error) {
+	style, err := asa.store.GetAnswerStyle(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &api.AnswerStyle{
+		Language:      style.Language,
+		Tone:          style.Tone,
+		CitationStyle: style.CitationStyle,
+	}, nil
+}
+
+func (asa *apiStoreAdapter) SaveAnswerStyle(ctx context.Context, userID int64, style api.AnswerStyle) error {
+	return asa.store.SaveAnswerStyle(ctx, store.AnswerStyle{
+		UserID:        userID,
+		Language:      style.Language,
+		Tone:          style.Tone,
+		CitationStyle: style.CitationStyle,
+	})
+}
+
+func (asa *apiStoreAdapter) GetStorageStats(ctx context.Context, userID int64, allUsers bool, limit int) (*api.StorageStats, error) {
+	stats, err := asa.store.GetStorageStats(ctx, userID, allUsers, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	// Convert store.StorageStats to api.StorageStats
+	apiStats := &api.StorageStats{
+		Total:   apiStorageUsage(stats.Total),
+		Sources: stats.Sources,
+		Largest: make([]api.SourceStorage, len(stats.Largest)),
+		Users:   make([]api.UserStorage, len(stats.Users)),
+	}
+	for i, src := range stats.Largest {
+		apiStats.Largest[i] = api.SourceStorage{
+			Source:       src.Source,
+			UserID:       src.UserID,
+			Username:     src.Username,
+			StorageUsage: apiStorageUsage(src.StorageUsage),
+		}
+	}
+	for i, user := range stats.Users {
+		apiStats.Users[i] = api.UserStorage{
+			UserID:       user.UserID,
+			Username:     user.Username,
+			Sources:      user.Sources,
+			StorageUsage: apiStorageUsage(user.StorageUsage),
+		}
+	}
+	return apiStats, nil
+}
+
+func (asa *apiStoreAdapter) GetEmbeddingGroups(ctx context.Context) ([]api.EmbeddingGroup, error) {
+	groups, err := asa.store.GetEmbeddingGroups(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	apiGroups := make([]api.EmbeddingGroup, len(groups))
+	for i, g := range groups {
+		apiGroups[i] = api.EmbeddingGroup{
+			Model:     g.Model,
+			Dimension: g.Dimension,
+			Chunks:    g.Chunks,
+			Sources:   g.Sources,
+		}
+	}
+	return apiGroups, nil
+}
+
+func (asa *apiStoreAdapter) GetUserActivity(ctx context.Context) ([]api.UserActivity, error) {
+	activity, err := asa.store.GetUserActivity(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	apiActivity := make([]api.UserActivity, len(activity))
+	for i, a := range activity {
+		apiActivity[i] = api.UserActivity{
+			UserID:                a.UserID,
+			Username:              a.Username,
+			Email:                 a.Email,
+			IsAdmin:               a.IsAdmin,
+			CreatedAt:             a.CreatedAt,
+			LastLogin:             a.LastLogin,
+			LastChat:              a.LastChat,
+			Messages:              a.Messages,
+			Documents:             a.Documents,
+			StorageBytes:          a.StorageBytes,
+			CloudPromptTokens:     a.CloudPromptTokens,
+			CloudCompletionTokens: a.CloudCompletionTokens,
+			EstimatedCostUSD:      a.EstimatedCostUSD,
+			ConfirmationsAsked:    a.ConfirmationsAsked,
+		}
+	}
+	return apiActivity, nil
+}
+
+// apiStorageUsage converts store.StorageUsage to api.StorageUsage
+func apiStorageUsage(u store.StorageUsage) api.StorageUsage {
+	return api.StorageUsage{
+		Chunks:         u.Chunks,
+		TextBytes:      u.TextBytes,
+		EmbeddingBytes: u.EmbeddingBytes,
+		MetadataBytes:  u.MetadataBytes,
+		TotalBytes:     u.TotalBytes(),
+	}
+}
+
+func (asa *apiStoreAdapter) QuickSearch(ctx context.Context, userID int64, query string, limits api.QuickSearchLimits) ([]api.QuickSearchResult, error) {
+	storeResults, err := asa.store.QuickSearch(ctx, userID, query, store.QuickSearchLimits{
+		Documents: limits.Documents,
+		Tags:      limits.Tags,
+		Sessions:  limits.Sessions,
+		Messages:  limits.Messages,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Convert store.QuickSearchResult to api.QuickSearchResult
+	apiResults := make([]api.QuickSearchResult, len(storeResults))
+	for i, sr := range storeResults {
+		apiResults[i] = api.QuickSearchResult{
+			Type:      sr.Type,
+			Title:     sr.Title,
+			Snippet:   sr.Snippet,
+			Target:    sr.Target,
+			SessionID: sr.SessionID,
+			UpdatedAt: sr.UpdatedAt,
+		}
+	}
+	return apiResults, nil
+}
+
+func (asa *apiStoreAdapter) GetSearchDictionary(ctx context.Context) (*api.SearchDictionary, error) {
+	dict, err := asa.store.GetSearchDictionary(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &api.SearchDictionary{
+		StopWords: dict.StopWords,
+		Protected: dict.Protected,
+		Synonyms:  dict.Synonyms,
+	}, nil
+}
+
+func (asa *apiStoreAdapter) SetSearchDictionary(ctx context.Context, dict api.SearchDictionary) error {
+	return asa.store.SetSearchDictionary(ctx, store.SearchDictionary{
+		StopWords: dict.StopWords,
+		Protected: dict.Protected,
+		Synonyms:  dict.Synonyms,
+	})
+}
+
+func (asa *apiStoreAdapter) WithTx(ctx context.Context, fn func(tx api.StoreTx) error) error {
+	return asa.store.WithTx(ctx, func(tx store.StoreTx) error {
+		return fn(&apiTxAdapter{tx: tx})
+	})
+}
+
+// apiTxAdapter adapts store.StoreTx to api.StoreTx interface
+type apiTxAdapter struct {
+	tx store.StoreTx
+}
+
+func (ata *apiTxAdapter) DeleteSource(ctx context.Context, source string) error {
+	// Use local-default user (ID=1) for backward compatibility
+	return ata.tx.DeleteDocument(ctx, 1, source)
+}
+
+func (ata *apiTxAdapter) AddAuditEntry(ctx context.Context, opType, details, userCtx string) error {
+	return ata.tx.AddAuditEntry(ctx, opType, details, userCtx)
+}
+
+func (ata *apiTxAdapter) DeleteUser(ctx context.Context, userID int64) error {
+	return ata.tx.DeleteUser(ctx, userID)
+}
+
 // apiProviderAdapter adapts llm.Provider to api.LLMProvider interface
 type apiProviderAdapter struct {
 	provider llm.Provider
@@ -618,6 +2084,22 @@ func (apa *apiProviderAdapter) Stream(ctx context.Context, messages []api.Messag
 	return apa.provider.Stream(ctx, llmMessages, w)
 }
 
+func (apa *apiProviderAdapter) StreamWithOptions(ctx context.Context, messages []api.Message, opts api.GenerationOptions, w io.Writer) (string, error) {
+	// Convert api.Message to llm.Message
+	llmMessages := make([]llm.Message, len(messages))
+	for i, msg := range messages {
+		llmMessages[i] = llm.Message{
+			Role:    msg.Role,
+			Content: msg.Content,
+		}
+	}
+	return apa.provider.StreamWithOptions(ctx, llmMessages, llm.GenerationOptions{
+		Temperature: opts.Temperature,
+		TopP:        opts.TopP,
+		MaxTokens:   opts.MaxTokens,
+	}, w)
+}
+
 func (apa *apiProviderAdapter) Name() string {
 	return apa.provider.Name()
 }
@@ -631,8 +2113,13 @@ type apiSearcherAdapter struct {
 	searcher *rag.Searcher
 }
 
-func (asa *apiSearcherAdapter) Search(ctx context.Context, queryVec []float32, topK int) ([]api.Chunk, error) {
-	ragChunks, err := asa.searcher.Search(ctx, queryVec, topK)
+func (asa *apiSearcherAdapter) Search(ctx context.Context, userID int64, queryVec []float32, opts api.SearchOptions, topK int) ([]api.Chunk, error) {
+	ragChunks, err := asa.searcher.Search(ctx, userID, queryVec, rag.SearchOptions{
+		Query:      opts.Query,
+		QueryModel: opts.QueryModel,
+		Sources:    opts.Sources,
+		Mode:       opts.Mode,
+	}, topK)
 	if err != nil {
 		return nil, err
 	}
@@ -672,14 +2159,16 @@ func (asla *apiSkillsLoaderAdapter) LoadAll() ([]*api.Skill, error) {
 		}
 
 		apiSkills[i] = &api.Skill{
-			Name:        s.Name,
-			Version:     s.Version,
-			Description: s.Description,
-			Executable:  s.Executable,
-			Triggers:    triggers,
-			Timeout:     s.Timeout,
-			RequiresNet: s.RequiresNet,
-			Path:        s.Path,
+			Name:         s.Name,
+			Version:      s.Version,
+			Description:  s.Description,
+			Executable:   s.Executable,
+			Triggers:     triggers,
+			Timeout:      s.Timeout,
+			RequiresNet:  s.RequiresNet,
+			Path:         s.Path,
+			InputSchema:  schemaToJSON(s.InputSchema),
+			OutputSchema: schemaToJSON(s.OutputSchema),
 		}
 	}
 	return apiSkills, nil
@@ -703,15 +2192,18 @@ func (asla *apiSkillsLoaderAdapter) LoadForUser(ctx context.Context, userID int6
 		}
 
 		apiSkills[i] = &api.Skill{
-			UserID:      s.UserID,
-			Name:        s.Name,
-			Version:     s.Version,
-			Description: s.Description,
-			Executable:  s.Executable,
-			Triggers:    triggers,
-			Timeout:     s.Timeout,
-			RequiresNet: s.RequiresNet,
-			Path:        s.Path,
+			ID:           s.ID,
+			UserID:       s.UserID,
+			Name:         s.Name,
+			Version:      s.Version,
+			Description:  s.Description,
+			Executable:   s.Executable,
+			Triggers:     triggers,
+			Timeout:      s.Timeout,
+			RequiresNet:  s.RequiresNet,
+			Path:         s.Path,
+			InputSchema:  schemaToJSON(s.InputSchema),
+			OutputSchema: schemaToJSON(s.OutputSchema),
 		}
 	}
 	return apiSkills, nil
@@ -733,14 +2225,18 @@ func (asea *apiSkillsExecutorAdapter) Execute(ctx context.Context, skill *api.Sk
 	}
 
 	skillsSkill := &skills.Skill{
-		Name:        skill.Name,
-		Version:     skill.Version,
-		Description: skill.Description,
-		Executable:  skill.Executable,
-		Triggers:    triggers,
-		Timeout:     skill.Timeout,
-		RequiresNet: skill.RequiresNet,
-		Path:        skill.Path,
+		ID:           skill.ID,
+		UserID:       skill.UserID,
+		Name:         skill.Name,
+		Version:      skill.Version,
+		Description:  skill.Description,
+		Executable:   skill.Executable,
+		Triggers:     triggers,
+		Timeout:      skill.Timeout,
+		RequiresNet:  skill.RequiresNet,
+		Path:         skill.Path,
+		InputSchema:  schemaFromJSON(skill.InputSchema),
+		OutputSchema: schemaFromJSON(skill.OutputSchema),
 	}
 
 	// Convert api.SkillInput to skills.Input
@@ -751,17 +2247,80 @@ func (asea *apiSkillsExecutorAdapter) Execute(ctx context.Context, skill *api.Sk
 	}
 
 	// Execute
-	output, err := asea.executor.Execute(ctx, skillsSkill, skillsInput)
+	run, err := asea.executor.Run(ctx, skillsSkill, skillsInput)
+
+	// Convert skills.Run to api.SkillOutput, keeping execution details even on failure
+	output := &api.SkillOutput{
+		Stderr:     run.Stderr,
+		ExitCode:   run.ExitCode,
+		DurationMS: run.Duration.Milliseconds(),
+	}
+	if run.Output != nil {
+		output.Result = run.Output.Result
+		output.Error = run.Output.Error
+		output.Metadata = run.Output.Metadata
+	}
+
+	var validationErr *skills.ValidationError
+	if errors.As(err, &validationErr) {
+		violations := make([]api.SkillViolation, len(validationErr.Violations))
+		for i, v := range validationErr.Violations {
+			violations[i] = api.SkillViolation{Path: v.Path, Message: v.Message}
+		}
+		return output, &api.SkillValidationError{
+			Skill:      validationErr.Skill,
+			Stage:      validationErr.Stage,
+			Violations: violations,
+		}
+	}
+	var limitErr *skills.LimitError
+	if errors.As(err, &limitErr) {
+		return output, &api.SkillLimitError{
+			Skill:  limitErr.Skill,
+			Limit:  limitErr.Limit,
+			Detail: limitErr.Detail,
+		}
+	}
+	return output, err
+}
+
+// SkillStats reports the executor's load and the limits executions hit
+func (asea *apiSkillsExecutorAdapter) SkillStats() api.SkillExecutorStats {
+	stats := asea.executor.Stats()
+	return api.SkillExecutorStats{
+		Running:        stats.Running,
+		Waiting:        stats.Waiting,
+		Runs:           stats.Runs,
+		Queued:         stats.Queued,
+		QueueTimeouts:  stats.QueueTimeouts,
+		RuntimeCapped:  stats.RuntimeCapped,
+		Timeouts:       stats.Timeouts,
+		OutputTooLarge: stats.OutputTooLarge,
+	}
+}
+
+// schemaToJSON encodes a skill schema for the api package
+func schemaToJSON(schema *skills.Schema) json.RawMessage {
+	if schema == nil {
+		return nil
+	}
+	data, err := json.Marshal(schema)
 	if err != nil {
-		return nil, err
+		return nil
 	}
+	return data
+}
 
-	// Convert skills.Output to api.SkillOutput
-	return &api.SkillOutput{
-		Result:   output.Result,
-		Error:    output.Error,
-		Metadata: output.Metadata,
-	}, nil
+// schemaFromJSON decodes a skill schema passed back from the api package
+func schemaFromJSON(data json.RawMessage) *skills.Schema {
+	if len(data) == 0 {
+		return nil
+	}
+	var schema skills.Schema
+	if err := json.Unmarshal(data, &schema); err != nil {
+		return nil
+	}
+	return &schema
 }
 
 // apiLoggerAdapter adapts logging.Logger to api.Logger interface
@@ -821,7 +2380,8 @@ func (aapa *apiAuthProviderAdapter) RefreshToken(ctx context.Context, token stri
 
 // authStoreAdapter adapts store.Store to auth.Store interface
 type authStoreAdapter struct {
-	store *store.Store
+	store  *store.Store
+	events *events.Bus // Told about accounts auth providers create
 }
 
 func (asa *authStoreAdapter) GetUserByUsername(ctx context.Context, username string) (*auth.User, error) {
@@ -829,6 +2389,19 @@ func (asa *authStoreAdapter) GetUserByUsername(ctx context.Context, username str
 	if err != nil {
 		return nil, err
 	}
+	return toAuthUser(user), nil
+}
+
+func (asa *authStoreAdapter) GetUserByID(ctx context.Context, userID int64) (*auth.User, error) {
+	user, err := asa.store.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return toAuthUser(user), nil
+}
+
+// toAuthUser converts a store user to the auth package's view of it
+func toAuthUser(user *store.User) *auth.User {
 	email := ""
 	if user.Email.Valid {
 		email = user.Email.String
@@ -840,14 +2413,14 @@ func (asa *authStoreAdapter) GetUserByUsername(ctx context.Context, username str
 		Email:              email,
 		IsAdmin:            user.IsAdmin,
 		MustChangePassword: user.MustChangePassword,
-	}, nil
+	}
 }
 
 func (asa *authStoreAdapter) UpdateLastLogin(ctx context.Context, userID int64) error {
 	return asa.store.UpdateLastLogin(ctx, userID)
 }
 
-func (asa *authStoreAdapter) CreateSessionToken(ctx context.Context, token string, userID int64, expiresAt interface{}) error {
+func (asa *authStoreAdapter) CreateSessionToken(ctx context.Context, token string, userID int64, expiresAt interface{}, rememberMe bool) error {
 	// Convert interface{} to time.Time
 	var expiresAtTime time.Time
 	switch v := expiresAt.(type) {
@@ -856,7 +2429,7 @@ func (asa *authStoreAdapter) CreateSessionToken(ctx context.Context, token strin
 	default:
 		return fmt.Errorf("invalid expiresAt type: %T", expiresAt)
 	}
-	return asa.store.CreateSessionToken(ctx, token, userID, expiresAtTime)
+	return asa.store.CreateSessionToken(ctx, token, userID, expiresAtTime, rememberMe)
 }
 
 func (asa *authStoreAdapter) GetSessionToken(ctx context.Context, token string) (*auth.SessionToken, error) {
@@ -868,18 +2441,32 @@ func (asa *authStoreAdapter) GetSessionToken(ctx context.Context, token string)
 		return nil, nil
 	}
 	return &auth.SessionToken{
-		Token:     sessionToken.Token,
-		UserID:    sessionToken.UserID,
-		ExpiresAt: sessionToken.ExpiresAt,
+		Token:      sessionToken.Token,
+		UserID:     sessionToken.UserID,
+		CreatedAt:  sessionToken.CreatedAt,
+		ExpiresAt:  sessionToken.ExpiresAt,
+		RememberMe: sessionToken.RememberMe,
 	}, nil
 }
 
+func (asa *authStoreAdapter) ExtendSessionToken(ctx context.Context, token string, expiresAt interface{}) error {
+	expiresAtTime, ok := expiresAt.(time.Time)
+	if !ok {
+		return fmt.Errorf("invalid expiresAt type: %T", expiresAt)
+	}
+	return asa.store.ExtendSessionToken(ctx, token, expiresAtTime)
+}
+
 func (asa *authStoreAdapter) DeleteSessionToken(ctx context.Context, token string) error {
 	return asa.store.DeleteSessionToken(ctx, token)
 }
 
-func (asa *authStoreAdapter) IsAccountLocked(ctx context.Context, username string) (bool, interface{}) {
-	locked, until := asa.store.IsAccountLocked(ctx, username)
+func (asa *authStoreAdapter) DeleteUserSessionTokens(ctx context.Context, userID int64, keepToken string) error {
+	return asa.store.DeleteUserSessionTokens(ctx, userID, keepToken)
+}
+
+func (asa *authStoreAdapter) IsAccountLocked(ctx context.Context, username string, threshold int, window time.Duration) (bool, interface{}) {
+	locked, until := asa.store.IsAccountLocked(ctx, username, threshold, window)
 	return locked, until
 }
 
@@ -891,6 +2478,25 @@ func (asa *authStoreAdapter) ClearFailedLogins(ctx context.Context, username str
 	return asa.store.ClearFailedLogins(ctx, username)
 }
 
+// CreateUser lets external auth providers create accounts (see auth.UserCreator)
+func (asa *authStoreAdapter) CreateUser(ctx context.Context, username, password, email string, isAdmin, mustChangePassword bool) (int64, error) {
+	userID, err := asa.store.CreateUser(ctx, username, password, email, isAdmin, mustChangePassword)
+	if err != nil {
+		return 0, err
+	}
+	asa.events.Publish(ctx, events.Event{
+		Topic:  events.UserCreated,
+		UserID: userID,
+		Data:   map[string]interface{}{"username": username},
+	})
+	return userID, nil
+}
+
+// SetUserAdmin lets directory auth providers sync admin rights (see auth.AdminUpdater)
+func (asa *authStoreAdapter) SetUserAdmin(ctx context.Context, userID int64, isAdmin bool) error {
+	return asa.store.SetUserAdmin(ctx, userID, isAdmin)
+}
+
 // apiProviderManagerAdapter adapts provider.DualProviderManager to api.ProviderManager interface
 type apiProviderManagerAdapter struct {
 	manager interface {
@@ -899,6 +2505,9 @@ type apiProviderManagerAdapter struct {
 		GetCloudProvider() llm.Provider
 		IsLocalMode() bool
 		GetProviderName() string
+		GetActiveModel() string
+		GetActiveEmbedModel() string
+		GetModels(local bool) (chatModel, embedModel string)
 		Reload(cfg *config.Config) error
 	}
 }
@@ -936,6 +2545,18 @@ func (apma *apiProviderManagerAdapter) GetProviderName() string {
 	return apma.manager.GetProviderName()
 }
 
+func (apma *apiProviderManagerAdapter) GetActiveModel() string {
+	return apma.manager.GetActiveModel()
+}
+
+func (apma *apiProviderManagerAdapter) GetActiveEmbedModel() string {
+	return apma.manager.GetActiveEmbedModel()
+}
+
+func (apma *apiProviderManagerAdapter) GetModels(local bool) (chatModel, embedModel string) {
+	return apma.manager.GetModels(local)
+}
+
 func (apma *apiProviderManagerAdapter) Reload(cfg interface{}) error {
 	// Convert interface{} to *config.Config
 	configCfg, ok := cfg.(*config.Config)
@@ -950,6 +2571,8 @@ type apiRAGEnforcerAdapter struct {
 	enforcer interface {
 		ShouldPerformRAG() bool
 		GetRAGStatus() string
+		ShouldPerformRAGFor(local bool) bool
+		GetRAGStatusFor(local bool) string
 		Reload(cfg interface{})
 	}
 }
@@ -962,6 +2585,728 @@ func (area *apiRAGEnforcerAdapter) GetRAGStatus() string {
 	return area.enforcer.GetRAGStatus()
 }
 
+func (area *apiRAGEnforcerAdapter) ShouldPerformRAGFor(local bool) bool {
+	return area.enforcer.ShouldPerformRAGFor(local)
+}
+
+func (area *apiRAGEnforcerAdapter) GetRAGStatusFor(local bool) string {
+	return area.enforcer.GetRAGStatusFor(local)
+}
+
 func (area *apiRAGEnforcerAdapter) Reload(cfg interface{}) {
 	area.enforcer.Reload(cfg)
 }
+
+// apiWireLogAdapter adapts wirelog.Log to api.WireLog interface
+type apiWireLogAdapter struct {
+	log *wirelog.Log
+}
+
+func (wla *apiWireLogAdapter) Query(filter api.WireLogFilter) ([]api.WireLogEntry, error) {
+	entries, err := wla.log.Query(wirelog.Filter{
+		Provider:   filter.Provider,
+		Model:      filter.Model,
+		Operation:  filter.Operation,
+		UserID:     filter.UserID,
+		ErrorsOnly: filter.ErrorsOnly,
+		Since:      filter.Since,
+		Limit:      filter.Limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Convert wirelog.Entry to api.WireLogEntry
+	apiEntries := make([]api.WireLogEntry, len(entries))
+	for i, e := range entries {
+		apiEntries[i] = api.WireLogEntry{
+			Time:           e.Time,
+			Provider:       e.Provider,
+			Model:          e.Model,
+			Operation:      e.Operation,
+			UserID:         e.UserID,
+			Messages:       e.Messages,
+			PromptHash:     e.PromptHash,
+			PromptChars:    e.PromptChars,
+			PromptTokens:   e.PromptTokens,
+			ResponseChars:  e.ResponseChars,
+			ResponseTokens: e.ResponseTokens,
+			Dimensions:     e.Dimensions,
+			LatencyMS:      e.LatencyMS,
+			Error:          e.Error,
+			Prompt:         e.Prompt,
+			Response:       e.Response,
+		}
+	}
+	return apiEntries, nil
+}
+
+// apiWebSearchAdapter adapts websearch.Client to api.WebSearcher interface
+type apiWebSearchAdapter struct {
+	client *websearch.Client
+}
+
+func (wsa *apiWebSearchAdapter) Search(ctx context.Context, query string) ([]api.WebResult, error) {
+	results, err := wsa.client.Search(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	// Convert websearch.Result to api.WebResult
+	apiResults := make([]api.WebResult, len(results))
+	for i, r := range results {
+		apiResults[i] = api.WebResult{
+			Title: r.Title,
+			URL:   r.URL,
+			Text:  r.Text,
+		}
+	}
+	return apiResults, nil
+}
+
+// apiRerankerAdapter adapts the local provider's reranker to api.Reranker interface
+// The reranker is looked up on each call so it follows provider reloads
+type apiRerankerAdapter struct {
+	manager interface {
+		GetReranker() llm.Reranker
+	}
+}
+
+func (ra *apiRerankerAdapter) Rerank(ctx context.Context, query string, documents []string) ([]float64, error) {
+	reranker := ra.manager.GetReranker()
+	if reranker == nil {
+		return nil, nil
+	}
+	return reranker.Rerank(ctx, query, documents)
+}
+
+// pooledProviderAdapter embeds through an embedding pool and streams through the provider
+// It implements ingest.BatchEmbedder so ingestion spreads chunks across the pool
+type pooledProviderAdapter struct {
+	providerAdapter
+	pool *embedpool.Pool
+}
+
+func (ppa *pooledProviderAdapter) Embed(ctx context.Context, text string) ([]float32, error) {
+	return ppa.pool.Embed(ctx, text)
+}
+
+func (ppa *pooledProviderAdapter) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	return ppa.pool.EmbedBatch(ctx, texts)
+}
+
+// apiEmbeddingPoolAdapter adapts embedpool.Pool to api.EmbeddingPool interface
+type apiEmbeddingPoolAdapter struct {
+	pool *embedpool.Pool
+}
+
+func (epa *apiEmbeddingPoolAdapter) Stats() []api.EmbeddingWorkerStats {
+	stats := epa.pool.Stats()
+
+	// Convert embedpool.WorkerStats to api.EmbeddingWorkerStats
+	apiStats := make([]api.EmbeddingWorkerStats, len(stats))
+	for i, s := range stats {
+		apiStats[i] = api.EmbeddingWorkerStats{
+			Endpoint:            s.Name,
+			Healthy:             s.Healthy,
+			ConsecutiveFailures: s.ConsecutiveFailures,
+			InFlight:            s.InFlight,
+			Completed:           s.Completed,
+			Failed:              s.Failed,
+			AvgLatencyMS:        s.AvgLatencyMS,
+		}
+	}
+	return apiStats
+}
+
+// apiExtractorsAdapter adapts extractors.Registry to api.ExtractorRegistry interface
+type apiExtractorsAdapter struct {
+	registry *extractors.Registry
+}
+
+func (ea *apiExtractorsAdapter) Extractors() []api.ExtractorInfo {
+	loaded := ea.registry.Extractors()
+	infos := make([]api.ExtractorInfo, len(loaded))
+	for i, ex := range loaded {
+		infos[i] = api.ExtractorInfo{
+			Name:           ex.Name,
+			Version:        ex.Version,
+			Description:    ex.Description,
+			MIMETypes:      ex.MIMETypes,
+			Extensions:     ex.Extensions,
+			TimeoutSeconds: int(ex.Timeout.Seconds()),
+		}
+	}
+	return infos
+}
+
+// apiProviderQueueAdapter adapts fairqueue.Queue to api.ProviderQueue interface
+type apiProviderQueueAdapter struct {
+	queue *fairqueue.Queue
+}
+
+func (pqa *apiProviderQueueAdapter) Join(userID int64) api.QueueTicket {
+	return pqa.queue.Join(userID)
+}
+
+func (pqa *apiProviderQueueAdapter) Stats() api.ProviderQueueStats {
+	stats := pqa.queue.Stats()
+	return api.ProviderQueueStats{
+		Active:     stats.Active,
+		Waiting:    stats.Waiting,
+		Users:      stats.Users,
+		Admitted:   stats.Admitted,
+		Queued:     stats.Queued,
+		Abandoned:  stats.Abandoned,
+		AvgWaitMS:  stats.AvgWaitMS,
+		MaxWaitMS:  stats.MaxWaitMS,
+		LastWaitMS: stats.LastWaitMS,
+	}
+}
+
+// apiModelWarmerAdapter adapts warmup.Warmer to api.ModelWarmer interface
+type apiModelWarmerAdapter struct {
+	warmer *warmup.Warmer
+}
+
+func (mwa *apiModelWarmerAdapter) Touch() {
+	mwa.warmer.Touch()
+}
+
+func (mwa *apiModelWarmerAdapter) Status() api.ModelWarmupStatus {
+	status := mwa.warmer.Status()
+	apiStatus := api.ModelWarmupStatus{
+		State:         string(status.State),
+		KeepAlive:     status.KeepAlive,
+		Paused:        status.Paused,
+		LastLatencyMS: status.LastLatency.Milliseconds(),
+		LastError:     status.LastError,
+		Pings:         status.Pings,
+	}
+	if !status.LastLoaded.IsZero() {
+		apiStatus.LastLoaded = &status.LastLoaded
+	}
+	if !status.LastActivity.IsZero() {
+		apiStatus.LastActivity = &status.LastActivity
+	}
+	return apiStatus
+}
+
+// apiTelemetryAdapter adapts telemetry.Reporter to api.TelemetryReporter interface
+type apiTelemetryAdapter struct {
+	reporter *telemetry.Reporter
+}
+
+func (ta *apiTelemetryAdapter) Preview(ctx context.Context) ([]byte, error) {
+	return ta.reporter.Preview(ctx)
+}
+
+func (ta *apiTelemetryAdapter) Status() api.TelemetryStatus {
+	status := ta.reporter.Status()
+	apiStatus := api.TelemetryStatus{
+		Enabled:     status.Enabled,
+		DisabledBy:  status.DisabledBy,
+		Endpoint:    status.Endpoint,
+		LastError:   status.LastError,
+		ReportsSent: status.ReportsSent,
+	}
+	if !status.LastSent.IsZero() {
+		apiStatus.LastSent = &status.LastSent
+	}
+	return apiStatus
+}
+
+// apiWatcherAdapter adapts watcher.Watcher to api.WatcherControl interface
+type apiWatcherAdapter struct {
+	watcher *watcher.Watcher
+}
+
+func (wa *apiWatcherAdapter) Pause() {
+	wa.watcher.Pause()
+}
+
+func (wa *apiWatcherAdapter) Resume() {
+	wa.watcher.Resume()
+}
+
+func (wa *apiWatcherAdapter) Status() api.WatcherStatus {
+	status := wa.watcher.Status()
+	apiStatus := api.WatcherStatus{
+		Paused:    status.Paused,
+		Pending:   status.Pending,
+		Settling:  status.Settling,
+		Ingesting: status.Ingesting,
+		Ingested:  status.Ingested,
+		Failed:    status.Failed,
+	}
+	if !status.LastIngest.IsZero() {
+		apiStatus.LastIngest = &status.LastIngest
+	}
+	return apiStatus
+}
+
+// schedulerStoreAdapter adapts store.Store to scheduler.Store interface
+type schedulerStoreAdapter struct {
+	store *store.Store
+}
+
+func (ssa *schedulerStoreAdapter) GetJobStates(ctx context.Context) ([]scheduler.State, error) {
+	storeStates, err := ssa.store.GetJobStates(ctx)
+	if err != nil {
+		return nil, err
+	}
+	states := make([]scheduler.State, len(storeStates))
+	for i, js := range storeStates {
+		states[i] = scheduler.State{
+			Name:         js.Name,
+			Schedule:     js.Schedule,
+			Paused:       js.Paused,
+			LastDuration: time.Duration(js.LastDurationMS) * time.Millisecond,
+			LastError:    js.LastError,
+			Runs:         js.Runs,
+			Failures:     js.Failures,
+		}
+		if js.LastRunAt != nil {
+			states[i].LastRun = *js.LastRunAt
+		}
+		if js.NextRunAt != nil {
+			states[i].NextRun = *js.NextRunAt
+		}
+	}
+	return states, nil
+}
+
+func (ssa *schedulerStoreAdapter) SaveJobState(ctx context.Context, state scheduler.State) error {
+	js := store.JobState{
+		Name:           state.Name,
+		Schedule:       state.Schedule,
+		Paused:         state.Paused,
+		LastDurationMS: state.LastDuration.Milliseconds(),
+		LastError:      state.LastError,
+		Runs:           state.Runs,
+		Failures:       state.Failures,
+	}
+	if !state.LastRun.IsZero() {
+		js.LastRunAt = &state.LastRun
+	}
+	if !state.NextRun.IsZero() {
+		js.NextRunAt = &state.NextRun
+	}
+	return ssa.store.SaveJobState(ctx, js)
+}
+
+// apiJobSchedulerAdapter adapts scheduler.Scheduler to api.JobScheduler interface
+type apiJobSchedulerAdapter struct {
+	scheduler *scheduler.Scheduler
+}
+
+func (jsa *apiJobSchedulerAdapter) Jobs(ctx context.Context) []api.ScheduledJob {
+	// Pick up runs and pauses saved by other instances
+	jsa.scheduler.Refresh(ctx)
+	statuses := jsa.scheduler.Jobs()
+	jobs := make([]api.ScheduledJob, len(statuses))
+	for i, status := range statuses {
+		jobs[i] = toAPIScheduledJob(status)
+	}
+	return jobs
+}
+
+// toAPIScheduledJob converts a scheduler job status to its api equivalent
+func toAPIScheduledJob(status scheduler.Status) api.ScheduledJob {
+	job := api.ScheduledJob{
+		Name:           status.Name,
+		Description:    status.Description,
+		Schedule:       status.Schedule,
+		Paused:         status.Paused,
+		Running:        status.Running,
+		LastDurationMS: status.LastDuration.Milliseconds(),
+		LastError:      status.LastError,
+		Runs:           status.Runs,
+		Failures:       status.Failures,
+	}
+	if !status.LastRun.IsZero() {
+		lastRun := status.LastRun
+		job.LastRun = &lastRun
+	}
+	if !status.NextRun.IsZero() {
+		nextRun := status.NextRun
+		job.NextRun = &nextRun
+	}
+	return job
+}
+
+func (jsa *apiJobSchedulerAdapter) RunJob(name string) error {
+	return toAPIJobError(jsa.scheduler.RunNow(name))
+}
+
+func (jsa *apiJobSchedulerAdapter) SetJobPaused(ctx context.Context, name string, paused bool) error {
+	return toAPIJobError(jsa.scheduler.SetPaused(ctx, name, paused))
+}
+
+// toAPIJobError converts scheduler errors to their api equivalents
+func toAPIJobError(err error) error {
+	switch {
+	case errors.Is(err, scheduler.ErrNotFound):
+		return api.ErrJobNotFound
+	case errors.Is(err, scheduler.ErrRunning):
+		return api.ErrJobRunning
+	}
+	return err
+}
+
+// clusterStoreAdapter adapts store.Store to cluster.Store interface
+type clusterStoreAdapter struct {
+	store *store.Store
+}
+
+func (csa *clusterStoreAdapter) AcquireLock(ctx context.Context, name, owner string, ttl time.Duration) (bool, error) {
+	return csa.store.AcquireLock(ctx, name, owner, ttl)
+}
+
+func (csa *clusterStoreAdapter) ReleaseLock(ctx context.Context, name, owner string) error {
+	return csa.store.ReleaseLock(ctx, name, owner)
+}
+
+func (csa *clusterStoreAdapter) PublishEvent(ctx context.Context, origin, kind, payload string) (int64, error) {
+	return csa.store.PublishClusterEvent(ctx, origin, kind, payload)
+}
+
+func (csa *clusterStoreAdapter) GetEventsAfter(ctx context.Context, afterID int64, limit int) ([]cluster.Event, error) {
+	storeEvents, err := csa.store.GetClusterEventsAfter(ctx, afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	events := make([]cluster.Event, len(storeEvents))
+	for i, e := range storeEvents {
+		events[i] = cluster.Event{
+			ID:      e.ID,
+			Origin:  e.Origin,
+			Kind:    e.Kind,
+			Payload: e.Payload,
+		}
+	}
+	return events, nil
+}
+
+func (csa *clusterStoreAdapter) LatestEventID(ctx context.Context) (int64, error) {
+	return csa.store.LatestClusterEventID(ctx)
+}
+
+func (csa *clusterStoreAdapter) EnqueueTask(ctx context.Context, kind, payload string) (int64, error) {
+	return csa.store.EnqueueClusterTask(ctx, kind, payload)
+}
+
+func (csa *clusterStoreAdapter) ClaimTask(ctx context.Context, owner string, kinds []string, lease time.Duration) (*cluster.Task, error) {
+	task, err := csa.store.ClaimClusterTask(ctx, owner, kinds, lease)
+	if err != nil || task == nil {
+		return nil, err
+	}
+	return toClusterTask(task), nil
+}
+
+func (csa *clusterStoreAdapter) RenewTask(ctx context.Context, id int64, owner string, lease time.Duration) error {
+	return csa.store.RenewClusterTask(ctx, id, owner, lease)
+}
+
+func (csa *clusterStoreAdapter) FinishTask(ctx context.Context, id int64, owner, result, errMsg string) error {
+	return csa.store.FinishClusterTask(ctx, id, owner, result, errMsg)
+}
+
+func (csa *clusterStoreAdapter) GetTask(ctx context.Context, id int64) (*cluster.Task, error) {
+	task, err := csa.store.GetClusterTask(ctx, id)
+	if err != nil || task == nil {
+		return nil, err
+	}
+	return toClusterTask(task), nil
+}
+
+func (csa *clusterStoreAdapter) Prune(ctx context.Context, eventsBefore, tasksBefore time.Time) (int64, error) {
+	return csa.store.PruneCluster(ctx, eventsBefore, tasksBefore)
+}
+
+// toClusterTask converts a store task to its cluster equivalent
+func toClusterTask(t *store.ClusterTask) *cluster.Task {
+	return &cluster.Task{
+		ID:        t.ID,
+		Kind:      t.Kind,
+		Payload:   t.Payload,
+		Status:    t.Status,
+		ClaimedBy: t.ClaimedBy,
+		Attempts:  t.Attempts,
+		Result:    t.Result,
+		Error:     t.Error,
+	}
+}
+
+// folderRetryTask is the cluster task kind of a retry of a quarantined watched file
+const folderRetryTask = "folder_retry"
+
+// clusterFolderRetrier adapts a cluster.Node to api.FolderRetrier, sending
+// retries to the instance running the folder watcher and waiting for the outcome
+type clusterFolderRetrier struct {
+	node    *cluster.Node
+	watcher *watcher.Watcher
+}
+
+// folderRetry is the payload of a folder retry task
+type folderRetry struct {
+	UserID int64  `json:"user_id"`
+	Path   string `json:"path"`
+}
+
+func (cfr *clusterFolderRetrier) Retry(ctx context.Context, userID int64, path string) error {
+	payload, err := json.Marshal(folderRetry{UserID: userID, Path: path})
+	if err != nil {
+		return err
+	}
+	id, err := cfr.node.Submit(ctx, folderRetryTask, string(payload))
+	if err != nil {
+		return err
+	}
+	_, err = cfr.node.Wait(ctx, id)
+	return err
+}
+
+func (cfr *clusterFolderRetrier) QuarantineLimit() int {
+	return cfr.watcher.QuarantineLimit()
+}
+
+// work retries files for other instances while this one runs the watcher
+func (cfr *clusterFolderRetrier) work(ctx context.Context) {
+	cfr.node.Work(ctx, folderRetryTask, func(ctx context.Context, payload string) (string, error) {
+		var retry folderRetry
+		if err := json.Unmarshal([]byte(payload), &retry); err != nil {
+			return "", fmt.Errorf("invalid folder retry: %w", err)
+		}
+		return "", cfr.watcher.Retry(ctx, retry.UserID, retry.Path)
+	})
+}
+
+// apiStartupGateAdapter adapts readiness.Gate to api.StartupGate interface
+type apiStartupGateAdapter struct {
+	gate *readiness.Gate
+}
+
+func (a *apiStartupGateAdapter) Ready() bool {
+	return a.gate.Ready()
+}
+
+func (a *apiStartupGateAdapter) Status() []api.DependencyStatus {
+	results := a.gate.Status()
+	status := make([]api.DependencyStatus, len(results))
+	for i, r := range results {
+		status[i] = api.DependencyStatus{
+			Name:     r.Name,
+			Required: r.Required,
+			OK:       r.OK,
+			Attempts: r.Attempts,
+			Error:    r.Error,
+		}
+	}
+	return status
+}
+
+// transcriptStoreAdapter adapts store.Store to transcripts.Store interface
+type transcriptStoreAdapter struct {
+	store *store.Store
+}
+
+func (tsa *transcriptStoreAdapter) SaveTranscript(ctx context.Context, e transcripts.Entry) error {
+	return tsa.store.SaveTranscript(ctx, store.Transcript{
+		UserID:    e.UserID,
+		Provider:  e.Provider,
+		Model:     e.Model,
+		Operation: e.Operation,
+		KeyID:     e.KeyID,
+		Payload:   e.Sealed,
+		CreatedAt: e.Time,
+	})
+}
+
+// replayStoreAdapter adapts store.Store to replay.Store interface
+type replayStoreAdapter struct {
+	store *store.Store
+}
+
+func (rsa *replayStoreAdapter) SaveFailedRequest(ctx context.Context, e replay.Envelope) (int64, error) {
+	request, err := json.Marshal(e.Request)
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode failed request: %w", err)
+	}
+	return rsa.store.SaveFailedRequest(ctx, store.FailedRequest{
+		UserID:    e.UserID,
+		Provider:  e.Provider,
+		Model:     e.Model,
+		Operation: e.Operation,
+		Local:     e.Local,
+		Request:   string(request),
+		Error:     e.Error,
+		CreatedAt: e.Time,
+	})
+}
+
+// apiReplayAdapter adapts replay.Log to api.ReplayLog interface
+type apiReplayAdapter struct {
+	store         *store.Store
+	log           *replay.Log
+	providers     replay.Providers
+	retentionDays int
+}
+
+func (ara *apiReplayAdapter) FailedRequests(ctx context.Context, limit int) ([]api.FailedRequest, error) {
+	records, err := ara.store.GetFailedRequests(ctx, limit)
+	if err != nil {
+		return nil, err
+	}
+	requests := make([]api.FailedRequest, len(records))
+	for i, r := range records {
+		requests[i] = toAPIFailedRequest(r, envelopeRequest(r))
+	}
+	return requests, nil
+}
+
+func (ara *apiReplayAdapter) Replay(ctx context.Context, id int64, live bool) (*api.ReplayResult, error) {
+	record, err := ara.store.GetFailedRequest(ctx, id)
+	if err != nil || record == nil {
+		return nil, err
+	}
+	request := envelopeRequest(*record)
+	result := ara.log.Replay(ctx, ara.providers, replay.Envelope{
+		ID:        record.ID,
+		Time:      record.CreatedAt,
+		UserID:    record.UserID,
+		Provider:  record.Provider,
+		Model:     record.Model,
+		Operation: record.Operation,
+		Local:     record.Local,
+		Request:   request,
+		Error:     record.Error,
+	}, live)
+
+	mode := "dry_run"
+	if live {
+		mode = "live"
+	}
+	return &api.ReplayResult{
+		Request:       toAPIFailedRequest(*record, request),
+		Mode:          mode,
+		Provider:      result.Provider,
+		Model:         result.Model,
+		Response:      result.Response,
+		Dimensions:    result.Dimensions,
+		OriginalError: record.Error,
+		Error:         result.Error,
+		Outcome:       result.Outcome,
+		Diff:          result.Diff,
+		LatencyMS:     result.LatencyMS,
+	}, nil
+}
+
+func (ara *apiReplayAdapter) RetentionDays() int {
+	return ara.retentionDays
+}
+
+// envelopeRequest decodes the request kept with a failed request. A request
+// that cannot be decoded is replayed empty, and fails again
+func envelopeRequest(r store.FailedRequest) replay.Request {
+	var request replay.Request
+	json.Unmarshal([]byte(r.Request), &request)
+	return request
+}
+
+// toAPIFailedRequest converts a stored failed request and its decoded contents
+func toAPIFailedRequest(r store.FailedRequest, request replay.Request) api.FailedRequest {
+	messages := make([]api.Message, len(request.Messages))
+	for i, msg := range request.Messages {
+		messages[i] = api.Message{Role: msg.Role, Content: msg.Content}
+	}
+	return api.FailedRequest{
+		ID:          r.ID,
+		Time:        r.CreatedAt,
+		UserID:      r.UserID,
+		Provider:    r.Provider,
+		Model:       r.Model,
+		Operation:   r.Operation,
+		Local:       r.Local,
+		Messages:    messages,
+		Input:       request.Input,
+		Temperature: request.Temperature,
+		TopP:        request.TopP,
+		MaxTokens:   request.MaxTokens,
+		Error:       r.Error,
+	}
+}
+
+// apiTranscriptsAdapter adapts transcripts.Archive to api.TranscriptArchive interface
+type apiTranscriptsAdapter struct {
+	store         *store.Store
+	archive       *transcripts.Archive
+	retentionDays int
+	embeddings    bool
+}
+
+func (ata *apiTranscriptsAdapter) Transcripts(ctx context.Context, filter api.TranscriptFilter, decrypt bool) ([]api.Transcript, error) {
+	records, err := ata.store.GetTranscripts(ctx, store.TranscriptFilter{
+		UserID:   filter.UserID,
+		Provider: filter.Provider,
+		Since:    filter.Since,
+		Until:    filter.Until,
+		Limit:    filter.Limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Convert store.Transcript to api.Transcript, opening payloads on request
+	apiTranscripts := make([]api.Transcript, len(records))
+	for i, r := range records {
+		apiTranscripts[i] = api.Transcript{
+			ID:        r.ID,
+			Time:      r.CreatedAt,
+			UserID:    r.UserID,
+			Provider:  r.Provider,
+			Model:     r.Model,
+			Operation: r.Operation,
+		}
+		if !decrypt {
+			continue
+		}
+		payload, err := ata.archive.Open(transcripts.Entry{
+			ID:        r.ID,
+			Time:      r.CreatedAt,
+			UserID:    r.UserID,
+			Provider:  r.Provider,
+			Model:     r.Model,
+			Operation: r.Operation,
+			KeyID:     r.KeyID,
+			Sealed:    r.Payload,
+		})
+		if err != nil {
+			apiTranscripts[i].DecryptError = err.Error()
+			continue
+		}
+		messages := make([]api.Message, len(payload.Messages))
+		for j, msg := range payload.Messages {
+			messages[j] = api.Message{Role: msg.Role, Content: msg.Content}
+		}
+		apiTranscripts[i].Payload = &api.TranscriptPayload{
+			Messages:    messages,
+			Input:       payload.Input,
+			Temperature: payload.Temperature,
+			TopP:        payload.TopP,
+			MaxTokens:   payload.MaxTokens,
+			Response:    payload.Response,
+			Dimensions:  payload.Dimensions,
+			Error:       payload.Error,
+		}
+	}
+	return apiTranscripts, nil
+}
+
+func (ata *apiTranscriptsAdapter) RetentionDays() int {
+	return ata.retentionDays
+}
+
+func (ata *apiTranscriptsAdapter) IncludesEmbeddings() bool {
+	return ata.embeddings
+}
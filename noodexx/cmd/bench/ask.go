@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultQuestions are asked when no -questions file is given
+var defaultQuestions = []string{
+	"Summarize the main points of my notes.",
+	"What are the open action items?",
+	"Which documents mention deadlines?",
+	"Explain the most important decision that was made.",
+	"List the people mentioned and their roles.",
+}
+
+// benchAsk sends requests questions to the server at baseURL, concurrency at a
+// time, timing the first byte and the whole of each answer. Each question
+// starts a new session, so answers do not grow with session history
+func benchAsk(baseURL, username, password string, questions []string, requests, concurrency int, timeout time.Duration) (*AskResult, error) {
+	baseURL = strings.TrimRight(baseURL, "/")
+	jar, _ := cookiejar.New(nil)
+	client := &http.Client{Jar: jar, Timeout: timeout}
+	if username != "" {
+		if err := login(client, baseURL, username, password); err != nil {
+			return nil, err
+		}
+	}
+
+	var (
+		mu         sync.Mutex
+		firstBytes []time.Duration
+		totals     []time.Duration
+		errs       int
+		lastErr    error
+	)
+	jobs := make(chan string)
+	var wg sync.WaitGroup
+	start := time.Now()
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for q := range jobs {
+				firstByte, total, err := ask(client, baseURL, q)
+				mu.Lock()
+				if err != nil {
+					errs++
+					lastErr = err
+				} else {
+					firstBytes = append(firstBytes, firstByte)
+					totals = append(totals, total)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for i := 0; i < requests; i++ {
+		jobs <- questions[i%len(questions)]
+	}
+	close(jobs)
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	if errs == requests {
+		return nil, fmt.Errorf("every question failed, the last with: %w", lastErr)
+	}
+	result := &AskResult{
+		Requests:          requests,
+		Errors:            errs,
+		Seconds:           elapsed.Seconds(),
+		RequestsPerSecond: float64(requests-errs) / elapsed.Seconds(),
+		FirstByte:         summarize(firstBytes),
+		Total:             summarize(totals),
+	}
+	if lastErr != nil {
+		result.LastError = lastErr.Error()
+	}
+	return result, nil
+}
+
+// login signs in, leaving the session cookie in the client's jar
+func login(client *http.Client, baseURL, username, password string) error {
+	body, _ := json.Marshal(map[string]string{"username": username, "password": password})
+	resp, err := client.Post(baseURL+"/api/login", "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to sign in: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to sign in as %s: %s", username, resp.Status)
+	}
+	return nil
+}
+
+// keepAliveComment is sent ahead of an answer while the model loads
+const keepAliveComment = ": keep-alive\n\n"
+
+// ask sends one question and reads its streamed answer, returning the time to
+// its first byte and to its end
+func ask(client *http.Client, baseURL, question string) (time.Duration, time.Duration, error) {
+	body, _ := json.Marshal(map[string]string{"query": question})
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, baseURL+"/api/ask", bytes.NewReader(body))
+	if err != nil {
+		return 0, 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return 0, 0, fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	// Keep-alive comments sent while the model loads are not part of the answer
+	var firstByte time.Duration
+	var head []byte
+	buf := make([]byte, 4096)
+	for {
+		n, err := resp.Body.Read(buf)
+		if firstByte == 0 {
+			head = append(head, buf[:n]...)
+			for bytes.HasPrefix(head, []byte(keepAliveComment)) {
+				head = head[len(keepAliveComment):]
+			}
+			if len(head) > 0 && !bytes.HasPrefix([]byte(keepAliveComment), head) {
+				firstByte = time.Since(start)
+			}
+		} else if len(head) < 64 {
+			head = append(head, buf[:min(n, 64-len(head))]...)
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+	total := time.Since(start)
+	if firstByte == 0 {
+		return 0, 0, fmt.Errorf("empty answer")
+	}
+	// Provider failures after the stream started are written into the answer
+	if strings.HasPrefix(string(head), "Error: ") {
+		return 0, 0, fmt.Errorf("provider failed: %s", head)
+	}
+	return firstByte, total, nil
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"time"
+
+	"noodexx/internal/store"
+)
+
+// benchStore ingests a synthetic library into the database at path, or a
+// temporary one, then times searches over it
+func benchStore(report *Report, path string) error {
+	cfg := report.Config
+	if path == "" {
+		dir, err := os.MkdirTemp("", "noodexx-bench-")
+		if err != nil {
+			return fmt.Errorf("failed to create temporary directory: %w", err)
+		}
+		defer os.RemoveAll(dir)
+		path = filepath.Join(dir, "bench.db")
+	}
+
+	st, err := store.NewStore(path, "multi")
+	if err != nil {
+		return fmt.Errorf("failed to open store: %w", err)
+	}
+	defer st.Close()
+	st.SetEmbeddingModel("bench")
+
+	ctx := context.Background()
+	// Each run gets its own user so an existing database is not searched as well
+	username := fmt.Sprintf("bench-%d", time.Now().UnixNano())
+	userID, err := st.CreateUser(ctx, username, "bench-password-1", username+"@example.com", false, false)
+	if err != nil {
+		return fmt.Errorf("failed to create benchmark user: %w", err)
+	}
+
+	r := rand.New(rand.NewSource(cfg.Seed))
+	start := time.Now()
+	for doc := 0; doc*cfg.ChunksPerDoc < cfg.Chunks; doc++ {
+		first := doc * cfg.ChunksPerDoc
+		last := min(first+cfg.ChunksPerDoc, cfg.Chunks)
+		source := fmt.Sprintf("bench/doc-%d.md", doc)
+		// Documents are saved in one transaction each, as the ingester does
+		err := st.WithTx(ctx, func(tx store.StoreTx) error {
+			for i := first; i < last; i++ {
+				text := fmt.Sprintf("Synthetic chunk %d of document %d.", i, doc)
+				if err := tx.SaveChunk(ctx, userID, source, text, randomEmbedding(r, cfg.Dims), nil, ""); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to ingest %s: %w", source, err)
+		}
+	}
+	elapsed := time.Since(start)
+	report.Ingest = &IngestResult{
+		Chunks:          cfg.Chunks,
+		Seconds:         elapsed.Seconds(),
+		ChunksPerSecond: float64(cfg.Chunks) / elapsed.Seconds(),
+	}
+
+	if cfg.VectorIndex || cfg.ANN {
+		if _, err := st.OpenVectorIndex(path+".vecindex", store.ANNOptions{Probes: cfg.ANNProbes}); err != nil {
+			return fmt.Errorf("failed to open vector index: %w", err)
+		}
+		if _, err := st.RepairVectorIndex(ctx); err != nil {
+			return fmt.Errorf("failed to build vector index: %w", err)
+		}
+	}
+	if cfg.ANN {
+		if _, err := st.RebuildANNIndex(ctx); err != nil {
+			return fmt.Errorf("failed to build ANN index: %w", err)
+		}
+	}
+
+	latencies := make([]time.Duration, 0, cfg.Queries)
+	start = time.Now()
+	for i := 0; i < cfg.Queries; i++ {
+		query := randomEmbedding(r, cfg.Dims)
+		t := time.Now()
+		if _, err := st.SearchByUser(ctx, userID, query, "bench", cfg.TopK); err != nil {
+			return fmt.Errorf("search failed: %w", err)
+		}
+		latencies = append(latencies, time.Since(t))
+	}
+	report.Search = &SearchResult{
+		Latency:          summarize(latencies),
+		QueriesPerSecond: float64(cfg.Queries) / time.Since(start).Seconds(),
+	}
+	return nil
+}
+
+// randomEmbedding returns a random vector of the given size
+func randomEmbedding(r *rand.Rand, dims int) []float32 {
+	v := make([]float32, dims)
+	for i := range v {
+		v[i] = r.Float32()*2 - 1
+	}
+	return v
+}
@@ -0,0 +1,130 @@
+// Command bench measures noodexx performance: ingest throughput and search
+// latency of the store over a synthetic library, and the throughput of
+// concurrent questions to a running server. Its JSON report can be compared
+// with one from an earlier release to spot regressions.
+//
+//	bench -chunks 20000 -dims 384 -out v1.1.json
+//	bench -chunks 0 -server http://localhost:8080 -username bench -password secret
+//	bench -baseline v1.0.json -max-regression 15
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:]))
+}
+
+// run parses flags, runs the benchmarks asked for and reports them; it returns
+// the exit code, 1 when a benchmark fails or a regression exceeds the limit
+func run(args []string) int {
+	fs := flag.NewFlagSet("bench", flag.ContinueOnError)
+	label := fs.String("label", "", "Label recorded in the report, such as the release being measured")
+	dbPath := fs.String("db", "", "Database to populate (default: a temporary one, removed afterwards)")
+	chunks := fs.Int("chunks", 10000, "Synthetic chunks to ingest; 0 skips the store benchmarks")
+	dims := fs.Int("dims", 384, "Embedding dimensions of synthetic chunks")
+	perDoc := fs.Int("chunks-per-doc", 20, "Chunks per synthetic document, each saved in one transaction")
+	queries := fs.Int("queries", 200, "Searches to time")
+	topK := fs.Int("top-k", 10, "Results per search")
+	vectorIndex := fs.Bool("vector-index", false, "Search through the in-memory vector index, as the server does when it is configured")
+	ann := fs.Bool("ann", false, "Search through the vector index's ANN lists, as the server does once a library reaches database.ann_min_chunks; implies -vector-index")
+	annProbes := fs.Int("ann-probes", 16, "ANN lists read per search with -ann")
+	seed := fs.Int64("seed", 1, "Seed for synthetic embeddings, so runs are comparable")
+	server := fs.String("server", "", "Base URL of a running server to send questions to, e.g. http://localhost:8080")
+	username := fs.String("username", "", "User to sign in as (not needed in single-user mode)")
+	password := fs.String("password", "", "Password of -username")
+	requests := fs.Int("requests", 50, "Questions to send to -server")
+	concurrency := fs.Int("concurrency", 4, "Questions in flight at once")
+	questionsFile := fs.String("questions", "", "File of questions to ask, one per line (default: a built-in set)")
+	timeout := fs.Duration("timeout", 2*time.Minute, "Time limit for one answer")
+	out := fs.String("out", "", "Write the report as JSON to this file")
+	baseline := fs.String("baseline", "", "Earlier JSON report to compare with")
+	maxRegression := fs.Float64("max-regression", 0, "Fail when a metric is this many percent worse than -baseline (0: never fail)")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *chunks < 0 || *dims <= 0 || *perDoc <= 0 || *queries <= 0 || *topK <= 0 || *annProbes <= 0 || *requests <= 0 || *concurrency <= 0 {
+		fmt.Fprintln(os.Stderr, "bench: counts must be positive")
+		return 2
+	}
+
+	report := newReport(*label)
+	report.Config = Config{
+		Chunks:       *chunks,
+		Dims:         *dims,
+		ChunksPerDoc: *perDoc,
+		Queries:      *queries,
+		TopK:         *topK,
+		VectorIndex:  *vectorIndex,
+		ANN:          *ann,
+		ANNProbes:    *annProbes,
+		Seed:         *seed,
+		Server:       *server,
+		Requests:     *requests,
+		Concurrency:  *concurrency,
+	}
+
+	if *chunks > 0 {
+		fmt.Fprintf(os.Stderr, "Ingesting %d chunks of %d dimensions...\n", *chunks, *dims)
+		if err := benchStore(report, *dbPath); err != nil {
+			fmt.Fprintf(os.Stderr, "bench: %v\n", err)
+			return 1
+		}
+	}
+
+	if *server != "" {
+		questions := defaultQuestions
+		if *questionsFile != "" {
+			data, err := os.ReadFile(*questionsFile)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "bench: %v\n", err)
+				return 1
+			}
+			questions = nil
+			for _, line := range strings.Split(string(data), "\n") {
+				if line = strings.TrimSpace(line); line != "" {
+					questions = append(questions, line)
+				}
+			}
+			if len(questions) == 0 {
+				fmt.Fprintf(os.Stderr, "bench: no questions in %s\n", *questionsFile)
+				return 1
+			}
+		}
+		fmt.Fprintf(os.Stderr, "Asking %s %d questions, %d at a time...\n", *server, *requests, *concurrency)
+		result, err := benchAsk(*server, *username, *password, questions, *requests, *concurrency, *timeout)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "bench: %v\n", err)
+			return 1
+		}
+		report.Ask = result
+	}
+
+	report.Print(os.Stdout)
+
+	if *out != "" {
+		if err := report.Save(*out); err != nil {
+			fmt.Fprintf(os.Stderr, "bench: %v\n", err)
+			return 1
+		}
+	}
+
+	if *baseline != "" {
+		old, err := loadReport(*baseline)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "bench: %v\n", err)
+			return 1
+		}
+		worst := compareReports(os.Stdout, old, report)
+		if *maxRegression > 0 && worst > *maxRegression {
+			fmt.Fprintf(os.Stderr, "bench: regression of %.1f%% exceeds %.1f%%\n", worst, *maxRegression)
+			return 1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"runtime"
+	"sort"
+	"time"
+)
+
+// reportVersion is the version of the report format; reports of another
+// version are not compared
+const reportVersion = 1
+
+// Report is the result of one benchmark run. Store and server sections are
+// nil when they were not run
+type Report struct {
+	Version   int           `json:"version"`
+	Label     string        `json:"label,omitempty"`
+	StartedAt time.Time     `json:"started_at"`
+	GoVersion string        `json:"go_version"`
+	Platform  string        `json:"platform"`
+	CPUs      int           `json:"cpus"`
+	Config    Config        `json:"config"`
+	Ingest    *IngestResult `json:"ingest,omitempty"`
+	Search    *SearchResult `json:"search,omitempty"`
+	Ask       *AskResult    `json:"ask,omitempty"`
+}
+
+// Config records the settings a report was produced with, since only runs
+// with the same settings are comparable
+type Config struct {
+	Chunks       int    `json:"chunks"`
+	Dims         int    `json:"dims"`
+	ChunksPerDoc int    `json:"chunks_per_doc"`
+	Queries      int    `json:"queries"`
+	TopK         int    `json:"top_k"`
+	VectorIndex  bool   `json:"vector_index"`
+	ANN          bool   `json:"ann"`
+	ANNProbes    int    `json:"ann_probes"`
+	Seed         int64  `json:"seed"`
+	Server       string `json:"server,omitempty"`
+	Requests     int    `json:"requests"`
+	Concurrency  int    `json:"concurrency"`
+}
+
+// IngestResult is the throughput of saving the synthetic library
+type IngestResult struct {
+	Chunks          int     `json:"chunks"`
+	Seconds         float64 `json:"seconds"`
+	ChunksPerSecond float64 `json:"chunks_per_second"`
+}
+
+// SearchResult is the latency distribution of searches over the library
+type SearchResult struct {
+	Latency          Latency `json:"latency"`
+	QueriesPerSecond float64 `json:"queries_per_second"`
+}
+
+// AskResult is the throughput of concurrent questions to a running server
+type AskResult struct {
+	Requests          int     `json:"requests"`
+	Errors            int     `json:"errors"`
+	LastError         string  `json:"last_error,omitempty"`
+	Seconds           float64 `json:"seconds"`
+	RequestsPerSecond float64 `json:"requests_per_second"`
+	FirstByte         Latency `json:"first_byte"` // From sending the question to the first byte of the answer
+	Total             Latency `json:"total"`      // From sending the question to the end of the answer
+}
+
+// Latency summarizes a latency distribution, in milliseconds
+type Latency struct {
+	Count  int     `json:"count"`
+	MeanMS float64 `json:"mean_ms"`
+	P50MS  float64 `json:"p50_ms"`
+	P90MS  float64 `json:"p90_ms"`
+	P99MS  float64 `json:"p99_ms"`
+	MaxMS  float64 `json:"max_ms"`
+}
+
+// newReport starts a report describing this machine
+func newReport(label string) *Report {
+	return &Report{
+		Version:   reportVersion,
+		Label:     label,
+		StartedAt: time.Now().UTC(),
+		GoVersion: runtime.Version(),
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+		CPUs:      runtime.NumCPU(),
+	}
+}
+
+// summarize computes the distribution of latencies; percentiles are nearest-rank
+func summarize(latencies []time.Duration) Latency {
+	l := Latency{Count: len(latencies)}
+	if len(latencies) == 0 {
+		return l
+	}
+	sorted := append([]time.Duration(nil), latencies...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	ms := func(d time.Duration) float64 { return float64(d.Microseconds()) / 1000 }
+	rank := func(p float64) float64 {
+		i := int(math.Ceil(p*float64(len(sorted)))) - 1
+		return ms(sorted[max(i, 0)])
+	}
+	var sum time.Duration
+	for _, d := range sorted {
+		sum += d
+	}
+	l.MeanMS = ms(sum / time.Duration(len(sorted)))
+	l.P50MS, l.P90MS, l.P99MS = rank(0.50), rank(0.90), rank(0.99)
+	l.MaxMS = ms(sorted[len(sorted)-1])
+	return l
+}
+
+// Print writes a readable summary of the report
+func (r *Report) Print(w io.Writer) {
+	fmt.Fprintf(w, "noodexx benchmark %s (%s, %s, %d CPUs)\n", r.Label, r.GoVersion, r.Platform, r.CPUs)
+	if r.Ingest != nil {
+		fmt.Fprintf(w, "Ingest: %d chunks of %d dimensions in %.2fs (%.0f chunks/s)\n", r.Ingest.Chunks, r.Config.Dims, r.Ingest.Seconds, r.Ingest.ChunksPerSecond)
+	}
+	if r.Search != nil {
+		fmt.Fprintf(w, "Search: %s (%.1f queries/s, top %d)\n", r.Search.Latency, r.Search.QueriesPerSecond, r.Config.TopK)
+	}
+	if r.Ask != nil {
+		fmt.Fprintf(w, "Ask: %d questions, %d failed, %d at a time in %.2fs (%.2f answers/s)\n", r.Ask.Requests, r.Ask.Errors, r.Config.Concurrency, r.Ask.Seconds, r.Ask.RequestsPerSecond)
+		fmt.Fprintf(w, "  First byte: %s\n", r.Ask.FirstByte)
+		fmt.Fprintf(w, "  Answer:     %s\n", r.Ask.Total)
+		if r.Ask.LastError != "" {
+			fmt.Fprintf(w, "  Last error: %s\n", r.Ask.LastError)
+		}
+	}
+}
+
+// String formats the distribution on one line
+func (l Latency) String() string {
+	return fmt.Sprintf("mean %.2fms, p50 %.2fms, p90 %.2fms, p99 %.2fms, max %.2fms over %d", l.MeanMS, l.P50MS, l.P90MS, l.P99MS, l.MaxMS, l.Count)
+}
+
+// Save writes the report as indented JSON
+func (r *Report) Save(path string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode report: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+	return nil
+}
+
+// loadReport reads a report saved by an earlier run
+func loadReport(path string) (*Report, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read baseline: %w", err)
+	}
+	var r Report
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("failed to parse baseline %s: %w", path, err)
+	}
+	if r.Version != reportVersion {
+		return nil, fmt.Errorf("baseline %s has report version %d, expected %d", path, r.Version, reportVersion)
+	}
+	return &r, nil
+}
+
+// metric is one figure compared between reports
+type metric struct {
+	name           string
+	old, new       float64
+	higherIsBetter bool
+}
+
+// compareReports writes how each metric measured in both reports changed and
+// returns the worst regression, in percent (0 if nothing got worse)
+func compareReports(w io.Writer, old, cur *Report) float64 {
+	label := old.Label
+	if label == "" {
+		label = old.StartedAt.Format(time.RFC3339)
+	}
+	fmt.Fprintf(w, "\nCompared with %s:\n", label)
+	if old.Config != cur.Config {
+		fmt.Fprintln(w, "  Warning: the runs used different settings, so the figures may not be comparable")
+	}
+
+	var metrics []metric
+	if old.Ingest != nil && cur.Ingest != nil {
+		metrics = append(metrics, metric{"ingest chunks/s", old.Ingest.ChunksPerSecond, cur.Ingest.ChunksPerSecond, true})
+	}
+	if old.Search != nil && cur.Search != nil {
+		metrics = append(metrics,
+			metric{"search p50 ms", old.Search.Latency.P50MS, cur.Search.Latency.P50MS, false},
+			metric{"search p99 ms", old.Search.Latency.P99MS, cur.Search.Latency.P99MS, false},
+		)
+	}
+	if old.Ask != nil && cur.Ask != nil {
+		metrics = append(metrics,
+			metric{"ask answers/s", old.Ask.RequestsPerSecond, cur.Ask.RequestsPerSecond, true},
+			metric{"ask first byte p50 ms", old.Ask.FirstByte.P50MS, cur.Ask.FirstByte.P50MS, false},
+			metric{"ask answer p99 ms", old.Ask.Total.P99MS, cur.Ask.Total.P99MS, false},
+		)
+	}
+	if len(metrics) == 0 {
+		fmt.Fprintln(w, "  Nothing measured in both runs")
+		return 0
+	}
+
+	worst := 0.0
+	for _, m := range metrics {
+		if m.old == 0 {
+			continue
+		}
+		change := (m.new - m.old) / m.old * 100
+		regression := change
+		if m.higherIsBetter {
+			regression = -change
+		}
+		verdict := "better"
+		if regression > 0 {
+			verdict = "worse"
+			worst = max(worst, regression)
+		}
+		fmt.Fprintf(w, "  %-22s %10.2f -> %10.2f  %+7.1f%% (%s)\n", m.name, m.old, m.new, change, verdict)
+	}
+	return worst
+}
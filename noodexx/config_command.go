@@ -0,0 +1,51 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"noodexx/internal/config"
+)
+
+// configUsage describes the config subcommands
+const configUsage = `Usage:
+  noodexx config validate [file]   Check a config file (default config.json) and list every problem
+  noodexx config schema            Print the JSON Schema of config.json for editor completion`
+
+// runConfigCommand runs a config subcommand and returns the process exit code:
+// 0 on success, 1 when the config file has problems and 2 for a usage error
+func runConfigCommand(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprintln(stderr, configUsage)
+		return 2
+	}
+
+	switch args[0] {
+	case "schema":
+		data, err := json.MarshalIndent(config.Schema(), "", "  ")
+		if err != nil {
+			fmt.Fprintf(stderr, "Failed to build schema: %v\n", err)
+			return 1
+		}
+		fmt.Fprintln(stdout, string(data))
+		return 0
+	case "validate":
+		path := "config.json"
+		if len(args) > 1 {
+			path = args[1]
+		}
+		problems := config.CheckFile(path)
+		if len(problems) == 0 {
+			fmt.Fprintf(stdout, "%s is valid\n", path)
+			return 0
+		}
+		fmt.Fprintf(stderr, "%s has %d problem(s):\n", path, len(problems))
+		for _, problem := range problems {
+			fmt.Fprintf(stderr, "  - %v\n", problem)
+		}
+		return 1
+	default:
+		fmt.Fprintf(stderr, "Unknown config command %q\n\n%s\n", args[0], configUsage)
+		return 2
+	}
+}
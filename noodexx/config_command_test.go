@@ -0,0 +1,37 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestRunConfigCommand tests the config validate and schema subcommands
+func TestRunConfigCommand(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"server": {"port": 8080, "bind_adress": "0.0.0.0"}}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	if code := runConfigCommand([]string{"validate", path}, &stdout, &stderr); code != 1 {
+		t.Errorf("Expected exit code 1, got %d", code)
+	}
+	if !strings.Contains(stderr.String(), `server.bind_adress: unknown setting (did you mean "bind_address"?)`) {
+		t.Errorf("Expected the misspelled setting to be reported, got %q", stderr.String())
+	}
+
+	stdout.Reset()
+	if code := runConfigCommand([]string{"schema"}, &stdout, &stderr); code != 0 {
+		t.Errorf("Expected exit code 0, got %d", code)
+	}
+	if !strings.Contains(stdout.String(), `"$schema"`) || !strings.Contains(stdout.String(), `"bind_address"`) {
+		t.Errorf("Expected a JSON Schema, got %q", stdout.String())
+	}
+
+	if code := runConfigCommand([]string{"lint"}, &stdout, &stderr); code != 2 {
+		t.Errorf("Expected exit code 2 for an unknown command, got %d", code)
+	}
+}
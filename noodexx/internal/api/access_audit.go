@@ -0,0 +1,209 @@
+package api
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"noodexx/internal/auth"
+	"noodexx/internal/validate"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// sensitiveTag marks documents whose chunks are logged every time they are
+// included in a prompt
+const sensitiveTag = "sensitive"
+
+const (
+	defaultAccessLogDays  = 30
+	defaultAccessLogLimit = 200
+)
+
+// ChunkAccess is a logged inclusion of a sensitive chunk in a prompt
+type ChunkAccess struct {
+	UserID       int64     `json:"user_id"` // User whose request included the chunk
+	Username     string    `json:"username"`
+	ChunkID      int64     `json:"chunk_id"`
+	Source       string    `json:"source"`
+	SessionID    string    `json:"-"`             // The requesting user's session, not shown to owners
+	Query        string    `json:"query"`         // Question or operation that retrieved the chunk
+	Provider     string    `json:"provider"`      // Provider the prompt was sent to
+	ProviderMode string    `json:"provider_mode"` // "local" or "cloud"
+	AccessedAt   time.Time `json:"accessed_at"`
+}
+
+// SensitiveSourceAccess summarizes the logged accesses to one sensitive document
+type SensitiveSourceAccess struct {
+	Source       string    `json:"source"`
+	Accesses     int       `json:"accesses"`
+	CloudAccess  int       `json:"cloud_accesses"` // Accesses whose prompt went to a cloud provider
+	Users        int       `json:"users"`          // Distinct users whose requests included it
+	LastAccessed time.Time `json:"last_accessed"`
+}
+
+// recordSensitiveAccess logs the chunks tagged sensitive among those about to be
+// sent to provider. Attachment and web chunks have no ID and are not logged;
+// failures are logged but do not stop the request
+func (s *Server) recordSensitiveAccess(ctx context.Context, logger Logger, userID int64, sessionID, query string, provider LLMProvider, chunks []Chunk) {
+	var ids []int64
+	for _, chunk := range chunks {
+		if chunk.ID != 0 {
+			ids = append(ids, chunk.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return
+	}
+
+	mode := "local"
+	if !provider.IsLocal() {
+		mode = "cloud"
+	}
+	access := ChunkAccess{UserID: userID, SessionID: sessionID, Query: query, Provider: provider.Name(), ProviderMode: mode}
+	logged, err := s.store.RecordChunkAccess(ctx, access, ids, sensitiveTag)
+	if err != nil {
+		logger.Warn("failed to record sensitive chunk access", "error", err.Error())
+		return
+	}
+	if logged > 0 {
+		logger.Debug("sensitive chunks included in prompt", "chunks", logged, "provider_mode", mode)
+	}
+}
+
+// summarizeAccess groups access log entries by source, most recently accessed first
+func summarizeAccess(entries []ChunkAccess) []SensitiveSourceAccess {
+	bySource := make(map[string]*SensitiveSourceAccess)
+	users := make(map[string]map[int64]bool)
+	for _, e := range entries {
+		summary, ok := bySource[e.Source]
+		if !ok {
+			summary = &SensitiveSourceAccess{Source: e.Source}
+			bySource[e.Source] = summary
+			users[e.Source] = make(map[int64]bool)
+		}
+		summary.Accesses++
+		if e.ProviderMode == "cloud" {
+			summary.CloudAccess++
+		}
+		users[e.Source][e.UserID] = true
+		if e.AccessedAt.After(summary.LastAccessed) {
+			summary.LastAccessed = e.AccessedAt
+		}
+	}
+
+	result := make([]SensitiveSourceAccess, 0, len(bySource))
+	for source, summary := range bySource {
+		summary.Users = len(users[source])
+		result = append(result, *summary)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if !result[i].LastAccessed.Equal(result[j].LastAccessed) {
+			return result[i].LastAccessed.After(result[j].LastAccessed)
+		}
+		return result[i].Source < result[j].Source
+	})
+	return result
+}
+
+// handleAccessLog handles GET /api/access-log - when the current user's
+// documents tagged sensitive were included in prompts: by whom, for which
+// question and sent to which provider. ?source narrows it to one document,
+// ?days sets the window and ?format=csv downloads every entry in it
+func (s *Server) handleAccessLog(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	requestID := newRequestID(w)
+
+	logger := s.logger.WithContext("request_id", requestID).
+		WithContext("method", r.Method).
+		WithContext("path", r.URL.Path)
+
+	logger.Debug("processing access log request")
+
+	ctx := r.Context()
+
+	userID, err := auth.GetUserID(ctx)
+	if err != nil {
+		logger.Error("request failed", "operation", "get_user_id", "error", err.Error())
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
+		return
+	}
+
+	query := r.URL.Query()
+	source := query.Get("source")
+	format := query.Get("format")
+	if format == "" {
+		format = "json"
+	}
+	days, limit := defaultAccessLogDays, defaultAccessLogLimit
+
+	v := validate.New()
+	v.Check("format", validate.OneOf("Format", format, "json", "csv"))
+	if d := query.Get("days"); d != "" {
+		n, err := validate.IntRange("days", d, 1, 3650)
+		v.Check("days", err)
+		days = n
+	}
+	if l := query.Get("limit"); l != "" {
+		n, err := validate.IntRange("limit", l, 1, 1000)
+		v.Check("limit", err)
+		limit = n
+	}
+	if err := v.Err(); err != nil {
+		writeValidationError(w, logger, err)
+		return
+	}
+
+	// The summary covers the whole window; the JSON entries are the latest only
+	since := time.Now().AddDate(0, 0, -days)
+	entries, err := s.store.GetChunkAccessLog(ctx, userID, source, since, 0)
+	if err != nil {
+		logger.Error("request failed", "operation", "get_chunk_access_log", "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to load the access log")
+		return
+	}
+	if entries == nil {
+		entries = []ChunkAccess{}
+	}
+
+	if format == "csv" {
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="sensitive-access.csv"`)
+		cw := csv.NewWriter(w)
+		cw.Write([]string{"accessed_at", "user_id", "username", "source", "chunk_id", "query", "provider", "provider_mode"})
+		for _, e := range entries {
+			cw.Write([]string{
+				csvTime(e.AccessedAt),
+				strconv.FormatInt(e.UserID, 10),
+				e.Username,
+				e.Source,
+				strconv.FormatInt(e.ChunkID, 10),
+				e.Query,
+				e.Provider,
+				e.ProviderMode,
+			})
+		}
+		cw.Flush()
+		if err := cw.Error(); err != nil {
+			logger.Warn("failed to write access log export", "error", err.Error())
+		}
+	} else {
+		latest := entries
+		if len(latest) > limit {
+			latest = latest[:limit]
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"tag":     sensitiveTag,
+			"days":    days,
+			"total":   len(entries),
+			"sources": summarizeAccess(entries),
+			"entries": latest,
+		})
+	}
+
+	latency := time.Since(start).Milliseconds()
+	logger.Debug("request completed", "status", http.StatusOK, "latency_ms", latency, "entries", len(entries))
+}
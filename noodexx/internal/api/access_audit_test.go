@@ -0,0 +1,110 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"noodexx/internal/auth"
+	"strings"
+	"testing"
+	"time"
+)
+
+// accessLogStore treats chunk 7 as sensitive and keeps the accesses recorded
+type accessLogStore struct {
+	mockStoreForAsk
+	entries []ChunkAccess
+}
+
+func (m *accessLogStore) RecordChunkAccess(ctx context.Context, access ChunkAccess, chunkIDs []int64, tag string) (int, error) {
+	logged := 0
+	for _, id := range chunkIDs {
+		if id == 7 && tag == sensitiveTag {
+			access.ChunkID, access.Source, access.AccessedAt = id, "salaries.csv", time.Now()
+			m.entries = append([]ChunkAccess{access}, m.entries...)
+			logged++
+		}
+	}
+	return logged, nil
+}
+
+func (m *accessLogStore) GetChunkAccessLog(ctx context.Context, ownerID int64, source string, since time.Time, limit int) ([]ChunkAccess, error) {
+	var result []ChunkAccess
+	for _, e := range m.entries {
+		if source == "" || e.Source == source {
+			result = append(result, e)
+		}
+	}
+	return result, nil
+}
+
+// TestSensitiveAccessLog tests that answering with a sensitive chunk logs it with
+// the question and provider, and that the owner's report summarizes it
+func TestSensitiveAccessLog(t *testing.T) {
+	store := &accessLogStore{}
+	store.searchByUserFunc = func(ctx context.Context, userID int64, queryVec []float32, topK int) ([]Chunk, error) {
+		return []Chunk{
+			{ID: 7, Source: "salaries.csv", Text: "Alice earns a lot"},
+			{ID: 8, Source: "handbook.md", Text: "Leave policy"},
+		}, nil
+	}
+	server := &Server{
+		store:           store,
+		logger:          &mockLoggerForAsk{},
+		providerManager: &mockProviderManagerForAsk{provider: &mockProviderForAsk{name: "openai"}, providerName: "OpenAI"},
+		ragEnforcer:     &mockRAGEnforcerForAsk{shouldPerformRAG: true, ragStatus: "RAG Enabled"},
+	}
+
+	for _, query := range []string{"Who earns the most?", "What is the leave policy?"} {
+		body, _ := json.Marshal(map[string]string{"query": query, "session_id": "s1"})
+		req := httptest.NewRequest(http.MethodPost, "/api/ask", bytes.NewReader(body))
+		req = req.WithContext(context.WithValue(req.Context(), auth.UserIDKey, int64(2)))
+		server.handleAsk(httptest.NewRecorder(), req)
+	}
+
+	if len(store.entries) != 2 {
+		t.Fatalf("Expected 2 logged accesses, got %d", len(store.entries))
+	}
+	e := store.entries[1]
+	if e.UserID != 2 || e.SessionID != "s1" || e.Query != "Who earns the most?" || e.Provider != "openai" || e.ProviderMode != "cloud" {
+		t.Errorf("Unexpected access %+v", e)
+	}
+
+	get := func(query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/access-log"+query, nil)
+		req = req.WithContext(context.WithValue(req.Context(), auth.UserIDKey, int64(1)))
+		w := httptest.NewRecorder()
+		server.handleAccessLog(w, req)
+		return w
+	}
+
+	w := get("?limit=1")
+	var resp struct {
+		Total   int                      `json:"total"`
+		Sources []SensitiveSourceAccess  `json:"sources"`
+		Entries []map[string]interface{} `json:"entries"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode report: %v", err)
+	}
+	if resp.Total != 2 || len(resp.Entries) != 1 {
+		t.Errorf("Expected 2 accesses with the latest listed, got %s", w.Body.String())
+	}
+	if len(resp.Sources) != 1 || resp.Sources[0].Accesses != 2 || resp.Sources[0].CloudAccess != 2 || resp.Sources[0].Users != 1 {
+		t.Errorf("Unexpected summary %+v", resp.Sources)
+	}
+	if _, ok := resp.Entries[0]["session_id"]; ok {
+		t.Error("Expected the requesting user's session to be left out of the report")
+	}
+
+	w = get("?format=csv")
+	if !strings.HasPrefix(w.Header().Get("Content-Type"), "text/csv") || !strings.Contains(w.Body.String(), "Who earns the most?") {
+		t.Errorf("Unexpected CSV export %q", w.Body.String())
+	}
+
+	if w := get("?days=0"); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for days=0, got %d", w.Code)
+	}
+}
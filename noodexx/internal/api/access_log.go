@@ -0,0 +1,201 @@
+package api
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"net/http"
+	"noodexx/internal/auth"
+	"sort"
+	"sync"
+	"time"
+)
+
+// routeSampleSize is how many of a route's most recent latencies its
+// percentiles are computed from
+const routeSampleSize = 1024
+
+// accessRecorder captures the status and size of a response for the access log
+type accessRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (ar *accessRecorder) WriteHeader(status int) {
+	if ar.status == 0 {
+		ar.status = status
+	}
+	ar.ResponseWriter.WriteHeader(status)
+}
+
+func (ar *accessRecorder) Write(p []byte) (int, error) {
+	if ar.status == 0 {
+		ar.status = http.StatusOK
+	}
+	n, err := ar.ResponseWriter.Write(p)
+	ar.bytes += int64(n)
+	return n, err
+}
+
+// Flush passes flushes through for streamed responses
+func (ar *accessRecorder) Flush() {
+	if f, ok := ar.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack passes connection takeovers through for WebSocket upgrades
+func (ar *accessRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(ar.ResponseWriter).Hijack()
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (ar *accessRecorder) Unwrap() http.ResponseWriter {
+	return ar.ResponseWriter
+}
+
+// accessLog logs every request to a route and records its latency under the
+// route pattern, so requests for different IDs count as one route
+func (s *Server) accessLog(pattern string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &accessRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r)
+		latency := time.Since(start)
+
+		status := rec.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		if s.perf != nil {
+			s.perf.record(pattern, latency, status)
+		}
+
+		if s.logger == nil {
+			return
+		}
+		fields := map[string]interface{}{
+			"method":     r.Method,
+			"route":      pattern,
+			"status":     status,
+			"bytes":      rec.bytes,
+			"latency_ms": latency.Milliseconds(),
+		}
+		if userID, err := auth.GetUserID(r.Context()); err == nil {
+			fields["user_id"] = userID
+		}
+		if requestID := w.Header().Get(requestIDHeader); requestID != "" {
+			fields["request_id"] = requestID
+		}
+		s.logger.WithFields(fields).Info("http request")
+	})
+}
+
+// RoutePerf is the latency summary of one route
+type RoutePerf struct {
+	Route  string  `json:"route"`
+	Count  int64   `json:"count"`  // Requests since startup
+	Errors int64   `json:"errors"` // Responses with a 5xx status
+	P50MS  float64 `json:"p50_ms"`
+	P95MS  float64 `json:"p95_ms"`
+	P99MS  float64 `json:"p99_ms"`
+	MaxMS  float64 `json:"max_ms"`
+	Window int     `json:"window"` // Recent requests the percentiles cover
+}
+
+// routeLatencies keeps a route's request counts and its most recent latencies
+type routeLatencies struct {
+	count   int64
+	errors  int64
+	samples []time.Duration // Ring buffer of recent latencies
+	next    int
+}
+
+// routeStats rolls up request latencies per route in memory
+type routeStats struct {
+	mu     sync.Mutex
+	size   int
+	routes map[string]*routeLatencies
+}
+
+// newRouteStats creates route stats keeping size latencies per route
+func newRouteStats(size int) *routeStats {
+	return &routeStats{size: size, routes: make(map[string]*routeLatencies)}
+}
+
+// record adds a request to a route
+func (rs *routeStats) record(route string, latency time.Duration, status int) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	stats, ok := rs.routes[route]
+	if !ok {
+		stats = &routeLatencies{}
+		rs.routes[route] = stats
+	}
+	stats.count++
+	if status >= 500 {
+		stats.errors++
+	}
+	if len(stats.samples) < rs.size {
+		stats.samples = append(stats.samples, latency)
+	} else {
+		stats.samples[stats.next] = latency
+		stats.next = (stats.next + 1) % rs.size
+	}
+}
+
+// snapshot summarizes every route, slowest p95 first
+func (rs *routeStats) snapshot() []RoutePerf {
+	rs.mu.Lock()
+	result := make([]RoutePerf, 0, len(rs.routes))
+	sorted := make(map[string][]time.Duration, len(rs.routes))
+	for route, stats := range rs.routes {
+		result = append(result, RoutePerf{Route: route, Count: stats.count, Errors: stats.errors, Window: len(stats.samples)})
+		sorted[route] = append([]time.Duration(nil), stats.samples...)
+	}
+	rs.mu.Unlock()
+
+	for i := range result {
+		samples := sorted[result[i].Route]
+		sort.Slice(samples, func(a, b int) bool { return samples[a] < samples[b] })
+		result[i].P50MS = percentileMS(samples, 50)
+		result[i].P95MS = percentileMS(samples, 95)
+		result[i].P99MS = percentileMS(samples, 99)
+		result[i].MaxMS = percentileMS(samples, 100)
+	}
+	sort.Slice(result, func(a, b int) bool {
+		if result[a].P95MS != result[b].P95MS {
+			return result[a].P95MS > result[b].P95MS
+		}
+		return result[a].Route < result[b].Route
+	})
+	return result
+}
+
+// percentileMS returns the nearest-rank percentile p of sorted latencies in
+// milliseconds
+func percentileMS(sorted []time.Duration, p int) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := (p*len(sorted) + 99) / 100 // ceil(p/100 * n)
+	rank = max(rank, 1)
+	return float64(sorted[rank-1].Microseconds()) / 1000
+}
+
+// handleAdminPerf handles GET /api/admin/perf - request counts and latency
+// percentiles per route since startup, slowest first
+func (s *Server) handleAdminPerf(w http.ResponseWriter, r *http.Request) {
+	routes := []RoutePerf{}
+	if s.perf != nil {
+		routes = s.perf.snapshot()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"routes":  routes,
+	})
+}
@@ -0,0 +1,94 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// recordingLogger keeps the fields of the access log lines it receives
+type recordingLogger struct {
+	mockLoggerForAsk
+	fields []map[string]interface{}
+}
+
+func (m *recordingLogger) WithFields(fields map[string]interface{}) Logger {
+	m.fields = append(m.fields, fields)
+	return m
+}
+
+// TestAccessLog tests that requests are logged and rolled up by route pattern
+func TestAccessLog(t *testing.T) {
+	logger := &recordingLogger{}
+	server := &Server{logger: logger, perf: newRouteStats(routeSampleSize)}
+
+	mux := http.NewServeMux()
+	rt := newRouter(mux)
+	rt.observe = server.accessLog
+	rt.handle("GET /api/documents/{id}", func(w http.ResponseWriter, r *http.Request) {
+		newRequestID(w)
+		if r.PathValue("id") == "missing" {
+			writeError(w, http.StatusInternalServerError, CodeInternal, "Failed")
+			return
+		}
+		w.Write([]byte("hello"))
+	})
+
+	for _, id := range []string{"1", "2", "missing"} {
+		mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/documents/"+id, nil))
+	}
+
+	if len(logger.fields) != 3 {
+		t.Fatalf("Expected 3 access log lines, got %d", len(logger.fields))
+	}
+	first := logger.fields[0]
+	if first["route"] != "GET /api/documents/{id}" || first["status"] != http.StatusOK || first["bytes"] != int64(5) || first["request_id"] == nil {
+		t.Errorf("Unexpected access log fields %v", first)
+	}
+	if logger.fields[2]["status"] != http.StatusInternalServerError {
+		t.Errorf("Expected the error status to be logged, got %v", logger.fields[2]["status"])
+	}
+
+	routes := server.perf.snapshot()
+	if len(routes) != 1 || routes[0].Count != 3 || routes[0].Errors != 1 || routes[0].Window != 3 {
+		t.Errorf("Expected one route with 3 requests and 1 error, got %+v", routes)
+	}
+
+	w := httptest.NewRecorder()
+	server.handleAdminPerf(w, httptest.NewRequest(http.MethodGet, "/api/admin/perf", nil))
+	var resp struct {
+		Routes []RoutePerf `json:"routes"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || len(resp.Routes) != 1 {
+		t.Errorf("Expected the route in the perf report, got %s", w.Body.String())
+	}
+}
+
+// TestRouteStatsPercentiles tests nearest-rank percentiles over the recent window
+func TestRouteStatsPercentiles(t *testing.T) {
+	stats := newRouteStats(100)
+	for i := 1; i <= 100; i++ {
+		stats.record("GET /api/search", time.Duration(i)*time.Millisecond, http.StatusOK)
+	}
+	stats.record("GET /api/library", 500*time.Millisecond, http.StatusOK)
+
+	routes := stats.snapshot()
+	if routes[0].Route != "GET /api/library" {
+		t.Errorf("Expected the slowest route first, got %+v", routes)
+	}
+	search := routes[1]
+	if search.P50MS != 50 || search.P95MS != 95 || search.P99MS != 99 || search.MaxMS != 100 {
+		t.Errorf("Unexpected percentiles %+v", search)
+	}
+
+	// Older latencies leave the window
+	for i := 0; i < 100; i++ {
+		stats.record("GET /api/search", time.Millisecond, http.StatusOK)
+	}
+	search = stats.snapshot()[1]
+	if search.MaxMS != 1 || search.Count != 200 || search.Window != 100 {
+		t.Errorf("Expected only recent latencies to count, got %+v", search)
+	}
+}
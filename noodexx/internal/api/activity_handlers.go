@@ -0,0 +1,231 @@
+package api
+
+import (
+	"cmp"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"noodexx/internal/validate"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// userActivitySorts are the columns the activity overview can be sorted by
+var userActivitySorts = []string{"username", "created_at", "last_active", "last_chat", "messages", "documents", "storage", "cloud_tokens"}
+
+// UserActivityRow is one account in the activity overview
+type UserActivityRow struct {
+	UserID                int64      `json:"user_id"`
+	Username              string     `json:"username"`
+	Email                 string     `json:"email,omitempty"`
+	IsAdmin               bool       `json:"is_admin"`
+	CreatedAt             time.Time  `json:"created_at"`
+	LastLogin             *time.Time `json:"last_login,omitempty"`
+	LastChat              *time.Time `json:"last_chat,omitempty"`
+	LastActive            *time.Time `json:"last_active,omitempty"` // Later of the last sign-in and the last chat
+	Messages              int        `json:"messages"`
+	Documents             int        `json:"documents"`
+	StorageBytes          int64      `json:"storage_bytes"`
+	CloudPromptTokens     int        `json:"cloud_prompt_tokens"`
+	CloudCompletionTokens int        `json:"cloud_completion_tokens"`
+	CloudTokens           int        `json:"cloud_tokens"`
+	EstimatedCostUSD      float64    `json:"estimated_cost_usd"`  // Of requests sent to paid providers with known prices
+	ConfirmationsAsked    int        `json:"confirmations_asked"` // Requests held back until the user confirmed their cost
+}
+
+// newUserActivityRow derives the overview columns of an account
+func newUserActivityRow(a UserActivity) UserActivityRow {
+	row := UserActivityRow{
+		UserID:                a.UserID,
+		Username:              a.Username,
+		Email:                 a.Email,
+		IsAdmin:               a.IsAdmin,
+		CreatedAt:             a.CreatedAt,
+		LastLogin:             optionalTime(a.LastLogin),
+		LastChat:              optionalTime(a.LastChat),
+		Messages:              a.Messages,
+		Documents:             a.Documents,
+		StorageBytes:          a.StorageBytes,
+		CloudPromptTokens:     a.CloudPromptTokens,
+		CloudCompletionTokens: a.CloudCompletionTokens,
+		CloudTokens:           a.CloudPromptTokens + a.CloudCompletionTokens,
+		EstimatedCostUSD:      a.EstimatedCostUSD,
+		ConfirmationsAsked:    a.ConfirmationsAsked,
+	}
+	lastActive := a.LastLogin
+	if a.LastChat.After(lastActive) {
+		lastActive = a.LastChat
+	}
+	row.LastActive = optionalTime(lastActive)
+	return row
+}
+
+// optionalTime returns nil for the zero time so it is left out of JSON
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// sortUserActivity orders rows by one of userActivitySorts, ties by user ID
+// Accounts without a time sort as oldest
+func sortUserActivity(rows []UserActivityRow, by string, desc bool) {
+	timeOf := func(t *time.Time) time.Time {
+		if t == nil {
+			return time.Time{}
+		}
+		return *t
+	}
+	// compare returns a negative number when a sorts before b in ascending order
+	compare := func(a, b UserActivityRow) int {
+		switch by {
+		case "username":
+			return strings.Compare(strings.ToLower(a.Username), strings.ToLower(b.Username))
+		case "created_at":
+			return a.CreatedAt.Compare(b.CreatedAt)
+		case "last_chat":
+			return timeOf(a.LastChat).Compare(timeOf(b.LastChat))
+		case "messages":
+			return cmp.Compare(a.Messages, b.Messages)
+		case "documents":
+			return cmp.Compare(a.Documents, b.Documents)
+		case "storage":
+			return cmp.Compare(a.StorageBytes, b.StorageBytes)
+		case "cloud_tokens":
+			return cmp.Compare(a.CloudTokens, b.CloudTokens)
+		default:
+			return timeOf(a.LastActive).Compare(timeOf(b.LastActive))
+		}
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		c := compare(rows[i], rows[j])
+		if c == 0 {
+			return rows[i].UserID < rows[j].UserID
+		}
+		if desc {
+			return c > 0
+		}
+		return c < 0
+	})
+}
+
+// handleUserActivity handles GET /api/admin/users/activity - every account with
+// its last sign-in and chat, library size and cloud token usage (admin only)
+// ?sort picks the column and ?order asc or desc, by default the most recently
+// active first. ?inactive_days=N keeps accounts with no activity in the last N
+// days, and ?format=csv downloads the overview
+func (s *Server) handleUserActivity(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	requestID := newRequestID(w)
+
+	logger := s.logger.WithContext("request_id", requestID).
+		WithContext("method", r.Method).
+		WithContext("path", r.URL.Path)
+
+	logger.Debug("processing user activity request")
+
+	query := r.URL.Query()
+	sortBy := query.Get("sort")
+	if sortBy == "" {
+		sortBy = "last_active"
+	}
+	order := query.Get("order")
+	if order == "" {
+		order = "desc"
+		if sortBy == "username" {
+			order = "asc"
+		}
+	}
+	format := query.Get("format")
+	if format == "" {
+		format = "json"
+	}
+	inactiveDays := 0
+
+	v := validate.New()
+	v.Check("sort", validate.OneOf("Sort", sortBy, userActivitySorts...))
+	v.Check("order", validate.OneOf("Order", order, "asc", "desc"))
+	v.Check("format", validate.OneOf("Format", format, "json", "csv"))
+	if days := query.Get("inactive_days"); days != "" {
+		n, err := validate.IntRange("inactive_days", days, 1, 3650)
+		v.Check("inactive_days", err)
+		inactiveDays = n
+	}
+	if err := v.Err(); err != nil {
+		writeValidationError(w, logger, err)
+		return
+	}
+
+	activity, err := s.store.GetUserActivity(r.Context())
+	if err != nil {
+		logger.Error("request failed", "operation", "get_user_activity", "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to load user activity")
+		return
+	}
+
+	cutoff := time.Now().AddDate(0, 0, -inactiveDays)
+	rows := make([]UserActivityRow, 0, len(activity))
+	for _, a := range activity {
+		row := newUserActivityRow(a)
+		if inactiveDays > 0 && row.LastActive != nil && row.LastActive.After(cutoff) {
+			continue
+		}
+		rows = append(rows, row)
+	}
+	sortUserActivity(rows, sortBy, order == "desc")
+
+	if format == "csv" {
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="user-activity.csv"`)
+		cw := csv.NewWriter(w)
+		cw.Write([]string{"user_id", "username", "email", "is_admin", "created_at", "last_login", "last_chat", "last_active",
+			"messages", "documents", "storage_bytes", "cloud_prompt_tokens", "cloud_completion_tokens", "cloud_tokens",
+			"estimated_cost_usd", "confirmations_asked"})
+		for _, row := range rows {
+			cw.Write([]string{
+				strconv.FormatInt(row.UserID, 10),
+				row.Username,
+				row.Email,
+				strconv.FormatBool(row.IsAdmin),
+				csvTime(row.CreatedAt),
+				csvOptionalTime(row.LastLogin),
+				csvOptionalTime(row.LastChat),
+				csvOptionalTime(row.LastActive),
+				strconv.Itoa(row.Messages),
+				strconv.Itoa(row.Documents),
+				strconv.FormatInt(row.StorageBytes, 10),
+				strconv.Itoa(row.CloudPromptTokens),
+				strconv.Itoa(row.CloudCompletionTokens),
+				strconv.Itoa(row.CloudTokens),
+				strconv.FormatFloat(row.EstimatedCostUSD, 'f', 4, 64),
+				strconv.Itoa(row.ConfirmationsAsked),
+			})
+		}
+		cw.Flush()
+		if err := cw.Error(); err != nil {
+			logger.Warn("failed to write user activity export", "error", err.Error())
+		}
+	} else {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"sort":    sortBy,
+			"order":   order,
+			"users":   rows,
+		})
+	}
+
+	latency := time.Since(start).Milliseconds()
+	logger.Debug("request completed", "status", http.StatusOK, "latency_ms", latency, "users", len(rows), "sort", sortBy, "order", order)
+}
+
+// csvOptionalTime formats an optional time for a CSV cell
+func csvOptionalTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return csvTime(*t)
+}
@@ -0,0 +1,141 @@
+package api
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// userActivityStore returns fixed user activity
+type userActivityStore struct {
+	*mockStoreForAsk
+	activity []UserActivity
+}
+
+func (m *userActivityStore) GetUserActivity(ctx context.Context) ([]UserActivity, error) {
+	return m.activity, nil
+}
+
+func newUserActivityServer() *Server {
+	now := time.Now()
+	return &Server{
+		store: &userActivityStore{
+			mockStoreForAsk: &mockStoreForAsk{},
+			activity: []UserActivity{
+				{UserID: 1, Username: "admin", IsAdmin: true, CreatedAt: now.AddDate(-1, 0, 0), LastLogin: now.Add(-time.Hour), Documents: 2, StorageBytes: 4000},
+				{UserID: 2, Username: "Bob", CreatedAt: now.AddDate(0, -6, 0), LastLogin: now.AddDate(0, 0, -90), LastChat: now.AddDate(0, 0, -60), Messages: 40, CloudPromptTokens: 9000, CloudCompletionTokens: 1000},
+				{UserID: 3, Username: "carol", CreatedAt: now.AddDate(0, -1, 0)},
+			},
+		},
+		logger: &mockLoggerForAsk{},
+	}
+}
+
+// TestHandleUserActivity tests the default order and the derived columns
+func TestHandleUserActivity(t *testing.T) {
+	w := httptest.NewRecorder()
+	newUserActivityServer().handleUserActivity(w, httptest.NewRequest(http.MethodGet, "/api/admin/users/activity", nil))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Users []UserActivityRow `json:"users"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	var order []int64
+	for _, row := range resp.Users {
+		order = append(order, row.UserID)
+	}
+	if len(order) != 3 || order[0] != 1 || order[1] != 2 || order[2] != 3 {
+		t.Fatalf("Expected the most recently active first and never active last, got %v", order)
+	}
+
+	bob := resp.Users[1]
+	if bob.CloudTokens != 10000 {
+		t.Errorf("Expected 10000 cloud tokens, got %d", bob.CloudTokens)
+	}
+	if bob.LastActive == nil || !bob.LastActive.Equal(*bob.LastChat) {
+		t.Errorf("Expected bob's last chat as his last activity, got %v", bob.LastActive)
+	}
+	if carol := resp.Users[2]; carol.LastActive != nil || carol.LastLogin != nil {
+		t.Errorf("Expected no activity times for carol, got %+v", carol)
+	}
+}
+
+// TestHandleUserActivity_Sort tests sorting and the inactivity filter
+func TestHandleUserActivity_Sort(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"sort=username", "admin,Bob,carol"},
+		{"sort=username&order=desc", "carol,Bob,admin"},
+		{"sort=cloud_tokens", "Bob,admin,carol"},
+		{"sort=storage&order=asc", "Bob,carol,admin"},
+		{"sort=created_at", "carol,Bob,admin"},
+		{"inactive_days=30", "Bob,carol"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			newUserActivityServer().handleUserActivity(w, httptest.NewRequest(http.MethodGet, "/api/admin/users/activity?"+tt.query, nil))
+
+			var resp struct {
+				Users []UserActivityRow `json:"users"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			var names []string
+			for _, row := range resp.Users {
+				names = append(names, row.Username)
+			}
+			if got := strings.Join(names, ","); got != tt.want {
+				t.Errorf("Expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+// TestHandleUserActivity_Invalid tests rejected query parameters
+func TestHandleUserActivity_Invalid(t *testing.T) {
+	for _, query := range []string{"sort=password", "order=up", "format=xml", "inactive_days=0"} {
+		w := httptest.NewRecorder()
+		newUserActivityServer().handleUserActivity(w, httptest.NewRequest(http.MethodGet, "/api/admin/users/activity?"+query, nil))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", query, w.Code)
+		}
+	}
+}
+
+// TestHandleUserActivity_CSV tests the CSV export
+func TestHandleUserActivity_CSV(t *testing.T) {
+	w := httptest.NewRecorder()
+	newUserActivityServer().handleUserActivity(w, httptest.NewRequest(http.MethodGet, "/api/admin/users/activity?format=csv&sort=username", nil))
+
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/csv") {
+		t.Fatalf("Expected CSV, got %q", ct)
+	}
+	records, err := csv.NewReader(w.Body).ReadAll()
+	if err != nil {
+		t.Fatalf("Failed to read CSV: %v", err)
+	}
+	if len(records) != 4 || records[0][13] != "cloud_tokens" {
+		t.Fatalf("Expected a header and 3 users, got %v", records)
+	}
+	if bob := records[2]; bob[1] != "Bob" || bob[8] != "40" || bob[13] != "10000" {
+		t.Errorf("Expected Bob's messages and tokens, got %v", bob)
+	}
+	if carol := records[3]; carol[5] != "" || carol[7] != "" {
+		t.Errorf("Expected empty activity times for carol, got %v", carol)
+	}
+}
@@ -68,6 +68,10 @@ func (m *mockStoreForAdmin) DeleteUser(ctx context.Context, userID int64) error
 	return nil
 }
 
+func (m *mockStoreForAdmin) WithTx(ctx context.Context, fn func(tx StoreTx) error) error {
+	return fn(m)
+}
+
 func TestHandleGetUsers(t *testing.T) {
 	tests := []struct {
 		name           string
@@ -123,8 +127,7 @@ func TestHandleGetUsers(t *testing.T) {
 			ctx := context.WithValue(req.Context(), auth.UserIDKey, tt.userID)
 			req = req.WithContext(ctx)
 
-			w := httptest.NewRecorder()
-			server.handleGetUsers(w, req)
+			w := serveRoute(server, req)
 
 			if w.Code != tt.expectedStatus {
 				t.Errorf("expected status %d, got %d", tt.expectedStatus, w.Code)
@@ -154,7 +157,7 @@ func TestHandleCreateUser(t *testing.T) {
 			requestBody: map[string]interface{}{
 				"username": "newuser",
 				"email":    "newuser@example.com",
-				"password": "password123",
+				"password": "staple-battery-42",
 				"is_admin": false,
 			},
 			expectedStatus: http.StatusOK,
@@ -181,7 +184,7 @@ func TestHandleCreateUser(t *testing.T) {
 			isAdmin: false,
 			requestBody: map[string]interface{}{
 				"username": "newuser",
-				"password": "password123",
+				"password": "staple-battery-42",
 			},
 			expectedStatus: http.StatusForbidden,
 			checkResponse:  nil,
@@ -191,7 +194,7 @@ func TestHandleCreateUser(t *testing.T) {
 			userID:  1,
 			isAdmin: true,
 			requestBody: map[string]interface{}{
-				"password": "password123",
+				"password": "staple-battery-42",
 			},
 			expectedStatus: http.StatusBadRequest,
 			checkResponse:  nil,
@@ -253,8 +256,7 @@ func TestHandleCreateUser(t *testing.T) {
 			ctx := context.WithValue(req.Context(), auth.UserIDKey, tt.userID)
 			req = req.WithContext(ctx)
 
-			w := httptest.NewRecorder()
-			server.handleCreateUser(w, req)
+			w := serveRoute(server, req)
 
 			if w.Code != tt.expectedStatus {
 				t.Errorf("expected status %d, got %d", tt.expectedStatus, w.Code)
@@ -344,8 +346,7 @@ func TestHandleDeleteUser(t *testing.T) {
 			ctx := context.WithValue(req.Context(), auth.UserIDKey, tt.userID)
 			req = req.WithContext(ctx)
 
-			w := httptest.NewRecorder()
-			server.handleDeleteUser(w, req)
+			w := serveRoute(server, req)
 
 			if w.Code != tt.expectedStatus {
 				t.Errorf("expected status %d, got %d", tt.expectedStatus, w.Code)
@@ -434,8 +435,7 @@ func TestHandleResetUserPassword(t *testing.T) {
 			ctx := context.WithValue(req.Context(), auth.UserIDKey, tt.userID)
 			req = req.WithContext(ctx)
 
-			w := httptest.NewRecorder()
-			server.handleResetUserPassword(w, req)
+			w := serveRoute(server, req)
 
 			if w.Code != tt.expectedStatus {
 				t.Errorf("expected status %d, got %d", tt.expectedStatus, w.Code)
@@ -0,0 +1,138 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"noodexx/internal/auth"
+	"noodexx/internal/rag"
+	"strings"
+	"time"
+)
+
+// normalizeAnswerStyle trims the style's fields and checks them
+func normalizeAnswerStyle(style AnswerStyle) (AnswerStyle, error) {
+	style = AnswerStyle{
+		Language:      strings.TrimSpace(style.Language),
+		Tone:          strings.TrimSpace(style.Tone),
+		CitationStyle: strings.TrimSpace(style.CitationStyle),
+	}
+	return style, rag.AnswerStyle(style).Validate()
+}
+
+// resolveAnswerStyle applies a request's overrides on top of the user's saved answer style
+func (s *Server) resolveAnswerStyle(ctx context.Context, logger Logger, userID int64, override AnswerStyle) (rag.AnswerStyle, error) {
+	override, err := normalizeAnswerStyle(override)
+	if err != nil {
+		return rag.AnswerStyle{}, err
+	}
+
+	var style rag.AnswerStyle
+	saved, err := s.store.GetAnswerStyle(ctx, userID)
+	if err != nil {
+		logger.Warn("failed to load answer style, using model defaults", "error", err.Error())
+	} else if saved != nil {
+		style = rag.AnswerStyle(*saved)
+	}
+	return style.Merge(rag.AnswerStyle(override)), nil
+}
+
+// handleAnswerStyle handles GET /api/answer-style
+// Returns the current user's answer style
+func (s *Server) handleAnswerStyle(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	requestID := newRequestID(w)
+
+	// Create logger with request context
+	logger := s.logger.WithContext("request_id", requestID).
+		WithContext("method", r.Method).
+		WithContext("path", r.URL.Path)
+
+	logger.Debug("processing answer style request")
+
+	ctx := r.Context()
+
+	// Extract user_id from context
+	userID, err := auth.GetUserID(ctx)
+	if err != nil {
+		logger.Error("request failed", "operation", "get_user_id", "error", err.Error())
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
+		return
+	}
+
+	style, err := s.store.GetAnswerStyle(ctx, userID)
+	if err != nil {
+		logger.Error("request failed", "operation", "get_answer_style", "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to get answer style")
+		return
+	}
+	if style == nil {
+		style = &AnswerStyle{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"style":   style,
+	})
+
+	latency := time.Since(start).Milliseconds()
+	logger.Debug("request completed", "status", http.StatusOK, "latency_ms", latency)
+}
+
+// handleUpdateAnswerStyle handles POST /api/answer-style
+// Replaces the current user's answer style
+func (s *Server) handleUpdateAnswerStyle(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	requestID := newRequestID(w)
+
+	// Create logger with request context
+	logger := s.logger.WithContext("request_id", requestID).
+		WithContext("method", r.Method).
+		WithContext("path", r.URL.Path)
+
+	logger.Debug("processing update answer style request")
+
+	ctx := r.Context()
+
+	// Extract user_id from context
+	userID, err := auth.GetUserID(ctx)
+	if err != nil {
+		logger.Error("request failed", "operation", "get_user_id", "error", err.Error())
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req AnswerStyle
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logger.Error("request failed", "operation", "parse_request", "error", err.Error())
+		writeError(w, http.StatusBadRequest, CodeBadRequest, "Invalid request")
+		return
+	}
+
+	style, err := normalizeAnswerStyle(req)
+	if err != nil {
+		logger.Error("request failed", "operation", "validate_answer_style", "error", err.Error())
+		writeError(w, http.StatusBadRequest, CodeBadRequest, err.Error())
+		return
+	}
+
+	if err := s.store.SaveAnswerStyle(ctx, userID, style); err != nil {
+		logger.Error("request failed", "operation", "save_answer_style", "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to save answer style")
+		return
+	}
+
+	// Audit log
+	s.store.AddAuditEntry(ctx, "config", "Updated answer style", "")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Answer style updated successfully",
+		"style":   style,
+	})
+
+	latency := time.Since(start).Milliseconds()
+	logger.Debug("request completed", "status", http.StatusOK, "latency_ms", latency, "user_id", userID)
+}
@@ -0,0 +1,109 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"noodexx/internal/auth"
+	"strings"
+	"testing"
+)
+
+// answerStyleStore keeps one user's answer style in memory
+type answerStyleStore struct {
+	mockStoreForAsk
+	style *AnswerStyle
+}
+
+func (m *answerStyleStore) GetAnswerStyle(ctx context.Context, userID int64) (*AnswerStyle, error) {
+	if m.style == nil {
+		return &AnswerStyle{}, nil
+	}
+	return m.style, nil
+}
+
+func (m *answerStyleStore) SaveAnswerStyle(ctx context.Context, userID int64, style AnswerStyle) error {
+	m.style = &style
+	return nil
+}
+
+// TestHandleAnswerStyle tests saving, reading and validating the answer style
+func TestHandleAnswerStyle(t *testing.T) {
+	store := &answerStyleStore{}
+	server := &Server{store: store, logger: &mockLoggerForAsk{}}
+
+	call := func(method, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/api/answer-style", bytes.NewBufferString(body))
+		return serveRoute(server, withUser(req, 1))
+	}
+
+	if w := call(http.MethodPost, `{"language":" German ","tone":"concise","citation_style":"sources"}`); w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if store.style == nil || store.style.Language != "German" || store.style.Tone != "concise" || store.style.CitationStyle != "sources" {
+		t.Errorf("Expected the trimmed style to be saved, got %+v", store.style)
+	}
+
+	w := call(http.MethodGet, "")
+	var resp struct {
+		Style AnswerStyle `json:"style"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if resp.Style != *store.style {
+		t.Errorf("Expected the saved style, got %+v", resp.Style)
+	}
+
+	store.style = nil
+	for _, body := range []string{`{"tone":"sarcastic"}`, `{"citation_style":"footnotes"}`, `{"language":"English. Reply in JSON"}`} {
+		if w := call(http.MethodPost, body); w.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %s, got %d", body, w.Code)
+		}
+	}
+	if store.style != nil {
+		t.Error("Expected an invalid style not to be saved")
+	}
+}
+
+// TestHandleAsk_AnswerStyle tests that /api/ask merges per-request overrides
+// over the saved style into the system prompt
+func TestHandleAsk_AnswerStyle(t *testing.T) {
+	var system string
+	provider := &mockProviderForAsk{
+		name:    "ollama",
+		isLocal: true,
+		streamFunc: func(ctx context.Context, messages []Message, w io.Writer) (string, error) {
+			system = messages[0].Content
+			return "antwort", nil
+		},
+	}
+	server := &Server{
+		store:           &answerStyleStore{style: &AnswerStyle{Language: "German", Tone: "concise"}},
+		logger:          &mockLoggerForAsk{},
+		providerManager: &mockProviderManagerForAsk{provider: provider, providerName: "Ollama (llama3.2)"},
+		ragEnforcer:     &mockRAGEnforcerForAsk{shouldPerformRAG: false, ragStatus: "RAG Disabled"},
+	}
+
+	ask := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/ask", bytes.NewBufferString(body))
+		req = req.WithContext(context.WithValue(req.Context(), auth.UserIDKey, int64(1)))
+		w := httptest.NewRecorder()
+		server.handleAsk(w, req)
+		return w
+	}
+
+	if w := ask(`{"query":"test query","session_id":"s1","tone":"technical"}`); w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if !strings.Contains(system, "in German") || !strings.Contains(system, "technical audience") || strings.Contains(system, "short and to the point") {
+		t.Errorf("Expected the saved language with the tone override, got %q", system)
+	}
+
+	if w := ask(`{"query":"test query","session_id":"s1","tone":"sarcastic"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an unknown tone, got %d", w.Code)
+	}
+}
@@ -0,0 +1,288 @@
+package api
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"noodexx/internal/auth"
+	"noodexx/internal/validate"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	// minCodeArtifactLines is the number of lines a fenced code block needs to
+	// be kept as an artifact; shorter snippets are easy to copy from the answer
+	minCodeArtifactLines = 5
+	// minTableArtifactRows is the number of data rows a table needs to be kept
+	minTableArtifactRows = 2
+)
+
+// tableSeparator matches the line under a markdown table's header, e.g. |---|:--:|
+var tableSeparator = regexp.MustCompile(`^\|?\s*:?-+:?\s*(\|\s*:?-+:?\s*)*\|?$`)
+
+// artifactExtensions maps code block languages to download file extensions
+var artifactExtensions = map[string]string{
+	"bash": "sh", "sh": "sh", "shell": "sh", "zsh": "sh",
+	"go": "go", "python": "py", "py": "py", "javascript": "js", "js": "js",
+	"typescript": "ts", "ts": "ts", "java": "java", "c": "c", "cpp": "cpp",
+	"csharp": "cs", "rust": "rs", "ruby": "rb", "php": "php", "sql": "sql",
+	"html": "html", "css": "css", "json": "json", "yaml": "yaml", "yml": "yaml",
+	"toml": "toml", "xml": "xml", "markdown": "md", "md": "md", "csv": "csv",
+}
+
+// extractArtifacts finds the fenced code blocks and markdown tables in an
+// answer that are large enough to be worth copying or downloading on their own,
+// in the order they appear. Tables inside code blocks are part of the block,
+// and a block the answer never closes is left out
+func extractArtifacts(response string) []MessageArtifact {
+	var artifacts []MessageArtifact
+	lines := strings.Split(strings.ReplaceAll(response, "\r\n", "\n"), "\n")
+
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+
+		if fence := codeFence(line); fence != "" {
+			language := ""
+			if info := strings.Fields(line[len(fence):]); len(info) > 0 {
+				language = strings.ToLower(info[0])
+			}
+			end := -1
+			for j := i + 1; j < len(lines); j++ {
+				closing := strings.TrimSpace(lines[j])
+				if strings.HasPrefix(closing, fence) && strings.Trim(closing, fence[:1]) == "" {
+					end = j
+					break
+				}
+			}
+			if end < 0 {
+				break
+			}
+			if end-i-1 >= minCodeArtifactLines {
+				artifacts = append(artifacts, MessageArtifact{
+					Kind:     "code",
+					Language: language,
+					Content:  strings.Join(lines[i+1:end], "\n"),
+				})
+			}
+			i = end
+			continue
+		}
+
+		if strings.HasPrefix(line, "|") && i+1 < len(lines) && tableSeparator.MatchString(strings.TrimSpace(lines[i+1])) {
+			end := i + 2
+			for end < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[end]), "|") {
+				end++
+			}
+			if end-i-2 >= minTableArtifactRows {
+				rows := make([]string, 0, end-i)
+				for _, row := range lines[i:end] {
+					rows = append(rows, strings.TrimSpace(row))
+				}
+				artifacts = append(artifacts, MessageArtifact{Kind: "table", Content: strings.Join(rows, "\n")})
+			}
+			i = end - 1
+		}
+	}
+	return artifacts
+}
+
+// codeFence returns the fence opening a code block on line, or "" if it does not open one
+func codeFence(line string) string {
+	for _, c := range []string{"`", "~"} {
+		n := len(line) - len(strings.TrimLeft(line, c))
+		if n >= 3 {
+			return strings.Repeat(c, n)
+		}
+	}
+	return ""
+}
+
+// tableCells splits the rows of a markdown table into cells, dropping the
+// separator line. A pipe escaped as \| stays in its cell
+func tableCells(table string) [][]string {
+	var records [][]string
+	for i, row := range strings.Split(table, "\n") {
+		if i == 1 && tableSeparator.MatchString(row) {
+			continue
+		}
+		row = strings.TrimSuffix(strings.TrimPrefix(row, "|"), "|")
+		var cells []string
+		var cell strings.Builder
+		for j := 0; j < len(row); j++ {
+			switch {
+			case row[j] == '\\' && j+1 < len(row) && row[j+1] == '|':
+				cell.WriteByte('|')
+				j++
+			case row[j] == '|':
+				cells = append(cells, strings.TrimSpace(cell.String()))
+				cell.Reset()
+			default:
+				cell.WriteByte(row[j])
+			}
+		}
+		records = append(records, append(cells, strings.TrimSpace(cell.String())))
+	}
+	return records
+}
+
+// saveArtifacts stores the artifacts of an assistant message; failures are
+// logged but do not affect the answer, which has already been sent
+func (s *Server) saveArtifacts(ctx context.Context, logger Logger, messageID int64, response string) {
+	artifacts := extractArtifacts(response)
+	if len(artifacts) == 0 {
+		return
+	}
+	if err := s.store.SaveMessageArtifacts(ctx, messageID, artifacts); err != nil {
+		logger.Warn("failed to save message artifacts", "message_id", messageID, "error", err.Error())
+	}
+}
+
+// handleMessageArtifacts lists the code blocks and tables extracted from one of
+// the user's assistant messages
+// Path: GET /api/message/:id/artifacts
+func (s *Server) handleMessageArtifacts(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	requestID := newRequestID(w)
+
+	logger := s.logger.WithContext("request_id", requestID).
+		WithContext("method", r.Method).
+		WithContext("path", r.URL.Path)
+
+	logger.Debug("processing message artifacts request")
+
+	ctx := r.Context()
+
+	userID, err := auth.GetUserID(ctx)
+	if err != nil {
+		logger.Error("request failed", "operation", "get_user_id", "error", err.Error())
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
+		return
+	}
+
+	messageID, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeBadRequest, "Invalid message ID")
+		return
+	}
+
+	artifacts, err := s.store.GetMessageArtifacts(ctx, userID, messageID)
+	if err != nil {
+		logger.Error("request failed", "operation", "get_message_artifacts", "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to get message artifacts")
+		return
+	}
+	if artifacts == nil {
+		artifacts = []MessageArtifact{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":   true,
+		"artifacts": artifacts,
+	})
+
+	latency := time.Since(start).Milliseconds()
+	logger.Debug("request completed", "message_id", messageID, "artifacts", len(artifacts), "latency_ms", latency)
+}
+
+// handleMessageArtifact returns the raw content of one artifact of one of the
+// user's messages, for copying. Code is served as plain text and tables as
+// markdown, or as CSV with ?format=csv; ?download=1 serves it as a file
+// Path: GET /api/message/:id/artifacts/:n
+func (s *Server) handleMessageArtifact(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	requestID := newRequestID(w)
+
+	logger := s.logger.WithContext("request_id", requestID).
+		WithContext("method", r.Method).
+		WithContext("path", r.URL.Path)
+
+	logger.Debug("processing message artifact request")
+
+	ctx := r.Context()
+
+	userID, err := auth.GetUserID(ctx)
+	if err != nil {
+		logger.Error("request failed", "operation", "get_user_id", "error", err.Error())
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
+		return
+	}
+
+	messageID, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeBadRequest, "Invalid message ID")
+		return
+	}
+	n, err := pathID(r, "n")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeBadRequest, "Invalid artifact number")
+		return
+	}
+
+	query := r.URL.Query()
+	format := query.Get("format")
+	if format == "" {
+		format = "raw"
+	}
+	v := validate.New()
+	v.Check("format", validate.OneOf("Format", format, "raw", "csv"))
+	if err := v.Err(); err != nil {
+		writeValidationError(w, logger, err)
+		return
+	}
+
+	artifacts, err := s.store.GetMessageArtifacts(ctx, userID, messageID)
+	if err != nil {
+		logger.Error("request failed", "operation", "get_message_artifacts", "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to get message artifact")
+		return
+	}
+	var artifact *MessageArtifact
+	for i := range artifacts {
+		if int64(artifacts[i].N) == n {
+			artifact = &artifacts[i]
+		}
+	}
+	if artifact == nil {
+		writeError(w, http.StatusNotFound, CodeNotFound, "Artifact not found")
+		return
+	}
+	if format == "csv" && artifact.Kind != "table" {
+		writeError(w, http.StatusBadRequest, CodeBadRequest, "Only tables can be served as CSV")
+		return
+	}
+
+	// Artifacts are model output, so they are never served as something a browser would run
+	ext := "md"
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	switch {
+	case format == "csv":
+		ext = "csv"
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	case artifact.Kind == "code":
+		ext = "txt"
+		if e, ok := artifactExtensions[artifact.Language]; ok {
+			ext = e
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	default:
+		w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+	}
+	if d := query.Get("download"); d == "1" || d == "true" {
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fmt.Sprintf("message-%d-artifact-%d.%s", messageID, n, ext)))
+	}
+
+	if format == "csv" {
+		cw := csv.NewWriter(w)
+		cw.WriteAll(tableCells(artifact.Content))
+	} else {
+		fmt.Fprint(w, artifact.Content)
+	}
+
+	latency := time.Since(start).Milliseconds()
+	logger.Debug("request completed", "message_id", messageID, "artifact", n, "format", format, "latency_ms", latency)
+}
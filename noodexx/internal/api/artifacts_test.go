@@ -0,0 +1,143 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"noodexx/internal/auth"
+	"strings"
+	"testing"
+)
+
+// artifactStore keeps the artifacts saved for one message
+type artifactStore struct {
+	mockStoreForAsk
+	messageID int64
+	artifacts []MessageArtifact
+}
+
+func (m *artifactStore) SaveMessageArtifacts(ctx context.Context, messageID int64, artifacts []MessageArtifact) error {
+	m.messageID = messageID
+	m.artifacts = nil
+	for i, a := range artifacts {
+		a.MessageID, a.N = messageID, i+1
+		m.artifacts = append(m.artifacts, a)
+	}
+	return nil
+}
+
+func (m *artifactStore) GetMessageArtifacts(ctx context.Context, userID, messageID int64) ([]MessageArtifact, error) {
+	if userID != 1 || messageID != m.messageID {
+		return nil, nil
+	}
+	return m.artifacts, nil
+}
+
+// TestExtractArtifacts tests that large code blocks and tables are extracted
+// in order, and that small ones, tables inside code and unclosed blocks are not
+func TestExtractArtifacts(t *testing.T) {
+	response := strings.Join([]string{
+		"Run this:",
+		"```bash",
+		"make build",
+		"```",
+		"Then the program:",
+		"```Go",
+		"package main",
+		"",
+		"| not | a table |",
+		"|---|---|",
+		"| 1 | 2 |",
+		"| 3 | 4 |",
+		"```",
+		"Compared:",
+		"| Name | Notes |",
+		"|:-----|------:|",
+		"| a    | x \\| y |",
+		"| b    | z |",
+		"",
+		"| Short | table |",
+		"|---|---|",
+		"| only | one row |",
+		"~~~",
+		"never",
+		"closed",
+	}, "\n")
+
+	artifacts := extractArtifacts(response)
+	if len(artifacts) != 2 {
+		t.Fatalf("Expected 2 artifacts, got %+v", artifacts)
+	}
+	if a := artifacts[0]; a.Kind != "code" || a.Language != "go" || !strings.HasPrefix(a.Content, "package main\n") || strings.Contains(a.Content, "```") {
+		t.Errorf("Unexpected code artifact %+v", a)
+	}
+	if a := artifacts[1]; a.Kind != "table" || !strings.HasPrefix(a.Content, "| Name | Notes |") || !strings.HasSuffix(a.Content, "| b    | z |") {
+		t.Errorf("Unexpected table artifact %+v", a)
+	}
+
+	cells := tableCells(artifacts[1].Content)
+	if len(cells) != 3 || cells[0][0] != "Name" || cells[1][1] != "x | y" {
+		t.Errorf("Unexpected table cells %q", cells)
+	}
+}
+
+// TestMessageArtifactEndpoints tests listing a message's artifacts and serving
+// one raw, as CSV and as a download
+func TestMessageArtifactEndpoints(t *testing.T) {
+	store := &artifactStore{}
+	store.SaveMessageArtifacts(context.Background(), 42, []MessageArtifact{
+		{Kind: "code", Language: "python", Content: "print('hi')"},
+		{Kind: "table", Content: "| a | b |\n|---|---|\n| 1 | 2 |"},
+	})
+	server := &Server{store: store, logger: &mockLoggerForAsk{}}
+
+	get := func(handler http.HandlerFunc, path string, values map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		for k, v := range values {
+			req.SetPathValue(k, v)
+		}
+		req = req.WithContext(context.WithValue(req.Context(), auth.UserIDKey, int64(1)))
+		w := httptest.NewRecorder()
+		handler(w, req)
+		return w
+	}
+
+	w := get(server.handleMessageArtifacts, "/api/message/42/artifacts", map[string]string{"id": "42"})
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"language":"python"`) {
+		t.Errorf("Expected the artifacts listed, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = get(server.handleMessageArtifact, "/api/message/42/artifacts/1", map[string]string{"id": "42", "n": "1"})
+	if w.Code != http.StatusOK || w.Body.String() != "print('hi')" || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/plain") {
+		t.Errorf("Expected the raw code, got %d %q %s", w.Code, w.Header().Get("Content-Type"), w.Body.String())
+	}
+	if w.Header().Get("Content-Disposition") != "" {
+		t.Error("Expected no download without ?download")
+	}
+
+	w = get(server.handleMessageArtifact, "/api/message/42/artifacts/1?download=1", map[string]string{"id": "42", "n": "1"})
+	if got := w.Header().Get("Content-Disposition"); got != `attachment; filename="message-42-artifact-1.py"` {
+		t.Errorf("Unexpected Content-Disposition %q", got)
+	}
+
+	w = get(server.handleMessageArtifact, "/api/message/42/artifacts/2?format=csv", map[string]string{"id": "42", "n": "2"})
+	if w.Code != http.StatusOK || w.Body.String() != "a,b\n1,2\n" || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/csv") {
+		t.Errorf("Expected the table as CSV, got %d %q", w.Code, w.Body.String())
+	}
+
+	for _, tc := range []struct {
+		path   string
+		values map[string]string
+		status int
+	}{
+		{"/api/message/42/artifacts/3", map[string]string{"id": "42", "n": "3"}, http.StatusNotFound},
+		{"/api/message/7/artifacts/1", map[string]string{"id": "7", "n": "1"}, http.StatusNotFound},
+		{"/api/message/42/artifacts/1?format=csv", map[string]string{"id": "42", "n": "1"}, http.StatusBadRequest},
+		{"/api/message/42/artifacts/1?format=pdf", map[string]string{"id": "42", "n": "1"}, http.StatusBadRequest},
+		{"/api/message/42/artifacts/x", map[string]string{"id": "42", "n": "x"}, http.StatusBadRequest},
+	} {
+		if w := get(server.handleMessageArtifact, tc.path, tc.values); w.Code != tc.status {
+			t.Errorf("%s: expected %d, got %d", tc.path, tc.status, w.Code)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package api
+
+import (
+	"net/http"
+	"time"
+)
+
+// askProgress sends status events for the stages before an answer, so the chat
+// can show what the assistant is doing while retrieval runs. Clients opt in
+// with "progress": true; the response then starts before retrieval, so
+// failures after that point are sent as error events instead of a status code.
+// Events are not buffered for resume
+type askProgress struct {
+	w       http.ResponseWriter
+	sse     *SSEWriter // The request's event writer, shared with the queue and the answer
+	start   time.Time
+	enabled bool
+	events  bool // The answer is framed as events
+	started bool
+}
+
+func newAskProgress(w http.ResponseWriter, sse *SSEWriter, start time.Time, enabled, events bool) *askProgress {
+	return &askProgress{w: w, sse: sse, start: start, enabled: enabled, events: events}
+}
+
+// begin commits the answer stream headers; the caller sets any other headers first
+func (p *askProgress) begin() {
+	if !p.enabled || p.started {
+		return
+	}
+	setAnswerHeaders(p.w, p.events)
+	p.w.WriteHeader(http.StatusOK)
+	p.started = true
+}
+
+// status sends a stage with the time since the request started and any
+// stage-specific counts and timings
+func (p *askProgress) status(stage string, fields map[string]interface{}) {
+	if !p.started {
+		return
+	}
+	data := map[string]interface{}{
+		"stage":      stage,
+		"elapsed_ms": time.Since(p.start).Milliseconds(),
+	}
+	for k, v := range fields {
+		data[k] = v
+	}
+	p.sse.Event("status", data)
+}
+
+// command acknowledges a slash command
+func (p *askProgress) command(ack commandAck) {
+	if !p.started {
+		return
+	}
+	p.sse.Event("command", ack)
+}
+
+// trailer sends an event after the answer, separated from it by a blank line
+// unless the answer is framed as events. The request's event writer sends it
+// through the answer's client stream by then, so it follows the answer; it is
+// not buffered for resume
+func (p *askProgress) trailer(event string, data interface{}) {
+	if !p.started {
+		return
+	}
+	if !p.events {
+		p.sse.write("\n\n")
+	}
+	p.sse.Event(event, data)
+}
+
+// fail reports an error as a response when nothing was sent yet, and as an
+// error event once the stream has started
+func (p *askProgress) fail(status int, code ErrorCode, message string) {
+	p.failDetails(status, code, message, nil)
+}
+
+// failDetails is fail for errors carrying details, such as a cost estimate
+func (p *askProgress) failDetails(status int, code ErrorCode, message string, details interface{}) {
+	if !p.started {
+		writeErrorDetails(p.w, status, code, message, details)
+		return
+	}
+	data := map[string]interface{}{
+		"code":    code,
+		"message": message,
+	}
+	if details != nil {
+		data["details"] = details
+	}
+	p.sse.Event("error", data)
+}
+
+// setAnswerHeaders marks a response as a streamed answer: a server-sent event
+// stream when the answer is framed as events, and plain text otherwise, with
+// any queue, status and trailing events framed as events around the text
+func setAnswerHeaders(w http.ResponseWriter, events bool) {
+	if events {
+		w.Header().Set("Content-Type", "text/event-stream")
+	} else {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+	}
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+}
@@ -0,0 +1,149 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"noodexx/internal/auth"
+	"strings"
+	"testing"
+)
+
+// parseStatusEvents returns the status events at the start of a streamed
+// answer and the text after them
+func parseStatusEvents(t *testing.T, body string) ([]map[string]interface{}, string) {
+	t.Helper()
+	var events []map[string]interface{}
+	for strings.HasPrefix(body, "event: status\ndata: ") {
+		end := strings.Index(body, "\n\n")
+		var data map[string]interface{}
+		if err := json.Unmarshal([]byte(strings.TrimPrefix(body[:end], "event: status\ndata: ")), &data); err != nil {
+			t.Fatalf("Malformed status event %q: %v", body[:end], err)
+		}
+		events = append(events, data)
+		body = body[end+2:]
+	}
+	return events, body
+}
+
+// TestHandleAsk_ProgressEvents tests the status events sent ahead of the answer
+func TestHandleAsk_ProgressEvents(t *testing.T) {
+	server := &Server{
+		store:           &mockStoreForAsk{},
+		logger:          &mockLoggerForAsk{},
+		providerManager: &mockProviderManagerForAsk{provider: &mockProviderForAsk{name: "ollama", isLocal: true}, providerName: "Ollama (llama3.2)"},
+		ragEnforcer:     &mockRAGEnforcerForAsk{shouldPerformRAG: true, ragStatus: "RAG Enabled (Local)"},
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/ask", bytes.NewBufferString(`{"query":"test query","session_id":"s1","progress":true}`))
+	req = req.WithContext(context.WithValue(req.Context(), auth.UserIDKey, int64(1)))
+	w := httptest.NewRecorder()
+	server.handleAsk(w, req)
+
+	// The answer is plain text, so the response is not labelled an event stream
+	if ct := w.Header().Get("Content-Type"); ct != "text/plain; charset=utf-8" {
+		t.Errorf("Expected a plain text answer, got %q", ct)
+	}
+	if name := w.Header().Get("X-Provider-Name"); name != "Ollama (llama3.2)" {
+		t.Errorf("Expected the provider header with progress events, got %q", name)
+	}
+
+	events, answer := parseStatusEvents(t, w.Body.String())
+	var stages []string
+	for _, event := range events {
+		stages = append(stages, event["stage"].(string))
+		if _, ok := event["elapsed_ms"]; !ok {
+			t.Errorf("Expected elapsed_ms in the %s event", event["stage"])
+		}
+	}
+	if strings.Join(stages, ",") != "retrieving,sources,generating" {
+		t.Fatalf("Expected retrieving, sources and generating events, got %v", stages)
+	}
+	if events[1]["sources"] != float64(2) || events[1]["library"] != float64(2) {
+		t.Errorf("Expected 2 library sources, got %v", events[1])
+	}
+	for _, key := range []string{"embed_ms", "search_ms", "web_ms"} {
+		if _, ok := events[1][key]; !ok {
+			t.Errorf("Expected %s in the sources event, got %v", key, events[1])
+		}
+	}
+	// The rendered answer, its stats and privacy summary trail it
+	if answer, _, _ := strings.Cut(answer, "\n\nevent: "); answer != "test response" {
+		t.Errorf("Expected the answer after the events, got %q", answer)
+	}
+}
+
+// TestHandleAsk_ProgressSkipsRetrieving tests that no retrieving event is sent
+// when nothing is searched
+func TestHandleAsk_ProgressSkipsRetrieving(t *testing.T) {
+	server := &Server{
+		store:           &mockStoreForAsk{},
+		logger:          &mockLoggerForAsk{},
+		providerManager: &mockProviderManagerForAsk{provider: &mockProviderForAsk{name: "ollama", isLocal: true}, providerName: "Ollama (llama3.2)"},
+		ragEnforcer:     &mockRAGEnforcerForAsk{shouldPerformRAG: false, ragStatus: "RAG Disabled"},
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/ask", bytes.NewBufferString(`{"query":"test query","progress":true}`))
+	req = req.WithContext(context.WithValue(req.Context(), auth.UserIDKey, int64(1)))
+	w := httptest.NewRecorder()
+	server.handleAsk(w, req)
+
+	events, _ := parseStatusEvents(t, w.Body.String())
+	if len(events) != 2 || events[0]["stage"] != "sources" || events[0]["sources"] != float64(0) {
+		t.Errorf("Expected sources and generating events only, got %v", events)
+	}
+}
+
+// TestHandleAsk_ProgressError tests that a failure after the stream started is
+// sent as an error event
+func TestHandleAsk_ProgressError(t *testing.T) {
+	provider := &mockProviderForAsk{
+		name:    "ollama",
+		isLocal: true,
+		embedFunc: func(ctx context.Context, text string) ([]float32, error) {
+			return nil, errors.New("model not loaded")
+		},
+	}
+	server := &Server{
+		store:           &mockStoreForAsk{},
+		logger:          &mockLoggerForAsk{},
+		providerManager: &mockProviderManagerForAsk{provider: provider, providerName: "Ollama (llama3.2)"},
+		ragEnforcer:     &mockRAGEnforcerForAsk{shouldPerformRAG: true, ragStatus: "RAG Enabled (Local)"},
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/ask", bytes.NewBufferString(`{"query":"test query","progress":true}`))
+	req = req.WithContext(context.WithValue(req.Context(), auth.UserIDKey, int64(1)))
+	w := httptest.NewRecorder()
+	server.handleAsk(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected the stream's 200 status, got %d", w.Code)
+	}
+	_, rest := parseStatusEvents(t, w.Body.String())
+	if !strings.HasPrefix(rest, "event: error\ndata: ") || !strings.Contains(rest, `"message":"Embedding failed"`) {
+		t.Errorf("Expected an error event after the retrieving event, got %q", rest)
+	}
+}
+
+// TestHandleAsk_NoProgressEvents tests that clients that do not ask for
+// progress get the answer alone
+func TestHandleAsk_NoProgressEvents(t *testing.T) {
+	server := &Server{
+		store:           &mockStoreForAsk{},
+		logger:          &mockLoggerForAsk{},
+		providerManager: &mockProviderManagerForAsk{provider: &mockProviderForAsk{name: "ollama", isLocal: true}, providerName: "Ollama (llama3.2)"},
+		ragEnforcer:     &mockRAGEnforcerForAsk{shouldPerformRAG: true, ragStatus: "RAG Enabled (Local)"},
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/ask", bytes.NewBufferString(`{"query":"test query"}`))
+	req = req.WithContext(context.WithValue(req.Context(), auth.UserIDKey, int64(1)))
+	w := httptest.NewRecorder()
+	server.handleAsk(w, req)
+
+	if w.Body.String() != "test response" {
+		t.Errorf("Expected only the answer, got %q", w.Body.String())
+	}
+}
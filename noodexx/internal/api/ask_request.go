@@ -0,0 +1,242 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"noodexx/internal/flags"
+	"noodexx/internal/rag"
+	"strings"
+)
+
+// askUpload is a file attached to one question
+type askUpload struct {
+	name string
+	data []byte
+}
+
+// parseAskRequest reads a question from a JSON or multipart body, with the file
+// a multipart body attaches. It returns false once the error was written
+func parseAskRequest(w http.ResponseWriter, r *http.Request, logger Logger) (*askRequest, *askUpload, bool) {
+	var req askRequest
+	if !strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			logger.Error("request failed", "operation", "parse_request", "error", err.Error())
+			writeError(w, http.StatusBadRequest, CodeBadRequest, "Invalid request")
+			return nil, nil, false
+		}
+		return &req, nil, true
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxAttachmentSize+(1<<20))
+	if err := r.ParseMultipartForm(maxAttachmentSize); err != nil {
+		logger.Error("request failed", "operation", "parse_multipart", "error", err.Error())
+		writeError(w, http.StatusBadRequest, CodeBadRequest, "Invalid request")
+		return nil, nil, false
+	}
+	req.Query = r.FormValue("query")
+	req.SessionID = r.FormValue("session_id")
+	req.Source = r.FormValue("source")
+	req.Progress = r.FormValue("progress") == "true"
+	req.Events = r.FormValue("events") == "true"
+	req.SessionSources = r.FormValue("session_sources") == "true"
+	req.Confirm = r.FormValue("confirm") == "true"
+	if v := r.FormValue("web_search"); v != "" {
+		webSearch := v == "true"
+		req.WebSearch = &webSearch
+	}
+	var err error
+	if req.GenerationOptions, err = parseGenerationForm(r); err != nil {
+		logger.Error("request failed", "operation", "parse_generation_options", "error", err.Error())
+		writeError(w, http.StatusBadRequest, CodeBadRequest, err.Error())
+		return nil, nil, false
+	}
+	req.AnswerStyle = AnswerStyle{
+		Language:      r.FormValue("language"),
+		Tone:          r.FormValue("tone"),
+		CitationStyle: r.FormValue("citation_style"),
+	}
+
+	file, header, err := r.FormFile("file")
+	if err == http.ErrMissingFile {
+		return &req, nil, true
+	}
+	if err != nil {
+		logger.Error("request failed", "operation", "get_attachment", "error", err.Error())
+		writeError(w, http.StatusBadRequest, CodeBadRequest, "Invalid attachment")
+		return nil, nil, false
+	}
+	defer file.Close()
+	if header.Size > maxAttachmentSize {
+		logger.Error("request failed", "operation", "check_attachment_size", "error", "attachment too large", "size", header.Size)
+		writeError(w, http.StatusRequestEntityTooLarge, CodeBodyTooLarge, "Attachment too large")
+		return nil, nil, false
+	}
+	data, err := io.ReadAll(file)
+	if err != nil {
+		logger.Error("request failed", "operation", "read_attachment", "error", err.Error())
+		writeError(w, http.StatusBadRequest, CodeBadRequest, "Invalid attachment")
+		return nil, nil, false
+	}
+	return &req, &askUpload{name: header.Filename, data: data}, true
+}
+
+// askSession is what a question's session contributes to its answer
+type askSession struct {
+	exists       bool
+	mode         string // Provider mode the session started in, empty before its first question
+	link         *SessionLink
+	history      *sessionHistory
+	citedSources []string // Sources the session cited, when retrieval is held to them
+}
+
+// openAskSession finds the session a question belongs to, starting a new one
+// when the request names none, and checks the user owns it. It returns false
+// once the error was written
+func (s *Server) openAskSession(ctx context.Context, w http.ResponseWriter, logger Logger, userID int64, req *askRequest) (*askSession, bool) {
+	if req.SessionID == "" {
+		req.SessionID = generateSessionID()
+	}
+
+	sess := &askSession{}
+	owner, err := s.store.GetSessionOwner(ctx, req.SessionID)
+	if err != nil || owner == 0 {
+		return sess, true
+	}
+	if owner != userID {
+		logger.Error("request failed", "operation", "verify_session_owner", "error", "unauthorized access to session")
+		writeError(w, http.StatusForbidden, CodeForbidden, "Forbidden: session belongs to another user")
+		return nil, false
+	}
+	sess.exists = true
+
+	// Sessions stay in the provider mode they started in when the global mode
+	// changes
+	if sess.mode, err = s.store.GetSessionMode(ctx, userID, req.SessionID); err != nil {
+		logger.Warn("failed to get session mode", "error", err.Error())
+		sess.mode = s.globalMode()
+	}
+	return sess, true
+}
+
+// selectAskMode picks the provider a question goes to and the RAG policy it is
+// answered under: the session's mode, unless /mode switches this message. It
+// returns false once the error was written
+func (s *Server) selectAskMode(ctx context.Context, w http.ResponseWriter, logger Logger, sess *askSession, commands []slashCommand) (*askMode, bool) {
+	commandMode := commandModeOf(commands)
+	mode, status, err := s.sessionAskMode(ctx, commandMode, sess.mode)
+	if err == nil {
+		return mode, true
+	}
+	logger.Error("request failed", "operation", "get_active_provider", "error", err.Error())
+	switch {
+	case status == http.StatusForbidden:
+		writeError(w, status, CodeForbidden, err.Error())
+	case commandMode != "" || s.sessionModeDiffers(sess.mode):
+		writeError(w, status, CodeProviderUnavailable, err.Error())
+	default:
+		writeError(w, status, CodeProviderUnavailable, "Provider not configured. Please configure the AI provider in Settings.")
+	}
+	return nil, false
+}
+
+// loadSessionContext reads what of the session may reach the provider. Prompts
+// carry the session's rolling summary and recent messages, and the summary of a
+// session it continues, under the same RAG policy as library content.
+// Follow-up questions may be held to the sources the session already cited; a
+// new session has none, so nothing is searched. It returns false once the
+// error was written
+func (s *Server) loadSessionContext(ctx context.Context, w http.ResponseWriter, logger Logger, userID int64, req *askRequest, sess *askSession, mode *askMode) bool {
+	var err error
+	if sess.exists && mode.rag {
+		if sess.link, err = s.store.GetSessionLink(ctx, userID, req.SessionID); err != nil {
+			logger.Warn("failed to get session link", "error", err.Error())
+		}
+		if sess.history, err = s.sessionHistory(ctx, userID, req.SessionID); err != nil {
+			logger.Warn("failed to get session history", "error", err.Error())
+		}
+	}
+
+	if req.SessionSources && sess.exists {
+		cited, err := s.sessionCitedSources(ctx, userID, req.SessionID)
+		if err != nil {
+			logger.Error("request failed", "operation", "get_cited_sources", "error", err.Error())
+			writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to get the session's sources")
+			return false
+		}
+		for _, c := range cited {
+			sess.citedSources = append(sess.citedSources, c.Source)
+		}
+	}
+	return true
+}
+
+// addAskAttachment chunks and embeds an attached file in memory; it is only
+// used by this session. It returns the attachment's ID, and false once the
+// error was written
+func (s *Server) addAskAttachment(ctx context.Context, w http.ResponseWriter, logger Logger, provider LLMProvider, userID int64, sessionID string, upload *askUpload) (string, bool) {
+	if s.attachments == nil {
+		logger.Error("request failed", "operation", "add_attachment", "error", "attachments not available")
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Attachments are not available")
+		return "", false
+	}
+	att, err := newAttachment(ctx, provider, userID, sessionID, upload.name, upload.data)
+	if err != nil {
+		logger.Error("request failed", "operation", "process_attachment", "filename", upload.name, "error", err.Error())
+		writeError(w, http.StatusBadRequest, CodeBadRequest, fmt.Sprintf("Attachment could not be processed: %v", err))
+		return "", false
+	}
+	s.attachments.Add(att)
+	s.store.AddAuditEntry(ctx, "attach", fmt.Sprintf("Attachment: %s (%d chunks)", att.Filename, len(att.Chunks)), sessionID)
+	return att.ID, true
+}
+
+// readAskDocument reads every chunk of the document a question is about, which
+// is answered from all of it instead of searching; the RAG policy still decides
+// whether library content may reach the provider. It returns false once the
+// error was written
+func (s *Server) readAskDocument(ctx context.Context, w http.ResponseWriter, logger Logger, userID int64, source string, mode *askMode) ([]Chunk, bool) {
+	if !s.featureEnabled(ctx, flags.DocumentQA) {
+		writeError(w, http.StatusForbidden, CodeForbidden, "Document questions are not enabled")
+		return nil, false
+	}
+	if !mode.rag {
+		writeError(w, http.StatusForbidden, CodeForbidden, "Document questions are not allowed by the RAG policy of the current provider")
+		return nil, false
+	}
+	chunks, err := s.store.GetSourceChunks(ctx, userID, source)
+	if err != nil {
+		logger.Error("request failed", "operation", "get_source_chunks", "source", source, "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to read document")
+		return nil, false
+	}
+	if len(chunks) == 0 {
+		writeError(w, http.StatusNotFound, CodeNotFound, "Document not found")
+		return nil, false
+	}
+	return chunks, true
+}
+
+// askMessages builds the provider call for a question answered from retrieved
+// chunks, after the session's history
+func askMessages(query string, chunks []Chunk, sess *askSession, style rag.AnswerStyle) []Message {
+	ragChunks := make([]rag.Chunk, len(chunks))
+	for i, chunk := range chunks {
+		ragChunks[i] = rag.Chunk{
+			Source: chunk.Source,
+			Text:   chunk.Text,
+			Score:  chunk.Score,
+		}
+	}
+
+	promptBuilder := rag.NewPromptBuilder()
+	prompt := promptBuilder.BuildPrompt(query, ragChunks)
+	systemPrompt := "You are a helpful assistant."
+	if sess.link != nil {
+		systemPrompt = continuedSessionPrompt(sess.link)
+	}
+	systemPrompt = promptBuilder.WithStyle(style).SystemPrompt(systemPrompt)
+	return append(historyPrompt(systemPrompt, sess.history), Message{Role: "user", Content: prompt})
+}
@@ -0,0 +1,109 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// retrieveAskContext finds the context a question is answered from, in prompt
+// order: chunks of the session's attachments and of the library, skill output
+// from its slash commands and live web results. documentChunks counts the
+// chunks of a document asked about, which is read instead of searched. Status
+// events report the search as it runs. It returns the number of web results
+// among the chunks, and false once a failure was reported to the client
+func (s *Server) retrieveAskContext(ctx context.Context, logger Logger, progress *askProgress, userID int64, req *askRequest, mode *askMode, cmd commandResult, citedSources []string, documentChunks int) (chunks []Chunk, webResults int, ok bool) {
+	// Conditionally perform RAG based on policy
+	// Attachments were explicitly supplied by the user, so they are searched regardless of policy
+	performRAG := req.Source == "" && mode.rag
+	var sessionAttachments []*attachment
+	if req.Source == "" {
+		sessionAttachments = s.attachments.ForSession(userID, req.SessionID)
+	}
+	webSearch := req.Source == "" && s.shouldWebSearch(ctx, req.WebSearch, mode.local)
+	if performRAG || len(sessionAttachments) > 0 || webSearch {
+		progress.status("retrieving", nil)
+	}
+	var embedTime, searchTime, webTime time.Duration
+	attachmentResults, libraryResults := 0, 0
+	if performRAG || len(sessionAttachments) > 0 {
+		// Embed query; keyword search of the library alone does not need it
+		var queryVec []float32
+		if len(sessionAttachments) > 0 || s.embedsQuery("") {
+			embedStart := time.Now()
+			var err error
+			queryVec, err = mode.provider.Embed(ctx, req.Query)
+			embedTime = time.Since(embedStart)
+			if err != nil {
+				logger.Error("request failed", "operation", "embed_query", "error", err.Error())
+				progress.fail(http.StatusInternalServerError, CodeInternal, "Embedding failed")
+				return nil, 0, false
+			}
+		}
+
+		searchStart := time.Now()
+		if len(sessionAttachments) > 0 {
+			chunks = s.attachments.Search(userID, req.SessionID, queryVec, attachmentTopK)
+			attachmentResults = len(chunks)
+		}
+
+		if performRAG {
+			logger.Debug("performing RAG search")
+
+			// Search for relevant chunks (user-scoped)
+			// Scoped searches cover only their sources, none when there are none
+			opts := SearchOptions{Query: req.Query, QueryModel: mode.embedModel}
+			switch {
+			case cmd.scoped:
+				sources := cmd.sources
+				if req.SessionSources {
+					sources = intersectSources(sources, citedSources)
+				}
+				opts.Sources = append([]string{}, sources...)
+			case req.SessionSources:
+				opts.Sources = append([]string{}, citedSources...)
+			}
+			libraryChunks, err := s.store.SearchLibrary(ctx, userID, queryVec, opts, s.libraryCandidates(ctx))
+			if err != nil {
+				logger.Error("request failed", "operation", "search_chunks", "error", err.Error())
+				progress.fail(http.StatusInternalServerError, CodeInternal, "Search failed")
+				return nil, 0, false
+			}
+			candidates := libraryChunks
+			libraryChunks = s.selectLibraryChunks(ctx, logger, req.Query, libraryChunks, librarySearchTopK)
+			s.recordRetrievals(ctx, logger, candidates, libraryChunks)
+			libraryResults = len(libraryChunks)
+			chunks = append(chunks, libraryChunks...)
+		} else {
+			logger.Debug("skipping RAG search per policy")
+		}
+		searchTime = time.Since(searchStart)
+	} else {
+		logger.Debug("skipping RAG search per policy")
+	}
+
+	// Skill output from /skill was asked for with the question, like an attachment
+	chunks = append(chunks, cmd.context...)
+
+	// Add live web results when the user is in cloud mode or explicitly opted in
+	if webSearch {
+		logger.Debug("performing web search")
+		webStart := time.Now()
+		webChunks := s.searchWeb(ctx, logger, userID, req.SessionID, req.Query)
+		webTime = time.Since(webStart)
+		webResults = len(webChunks)
+		chunks = append(chunks, webChunks...)
+	}
+	progress.status("sources", map[string]interface{}{
+		"sources":         len(chunks),
+		"library":         libraryResults,
+		"attachments":     attachmentResults,
+		"web":             webResults,
+		"document_chunks": documentChunks,
+		"embed_ms":        embedTime.Milliseconds(),
+		"search_ms":       searchTime.Milliseconds(),
+		"web_ms":          webTime.Milliseconds(),
+	})
+
+	return chunks, webResults, true
+}
@@ -0,0 +1,289 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"noodexx/internal/markdown"
+	"noodexx/internal/rag"
+	"time"
+)
+
+// confirmAskCost estimates what a paid provider will charge for a question,
+// and holds back questions over the user's limit until they are sent again with
+// confirm. Local providers are free. It returns false once the confirmation
+// request was sent
+func (s *Server) confirmAskCost(ctx context.Context, logger Logger, progress *askProgress, userID int64, req *askRequest, mode *askMode, messages []Message, docChunks []Chunk, genOpts GenerationOptions) bool {
+	if mode.provider.IsLocal() {
+		return true
+	}
+	promptTokens, calls := estimateMessages(messages), 1
+	if len(docChunks) > 0 {
+		promptTokens, calls = s.estimateDocumentQA(req.Query, docChunks)
+	}
+	estimate := s.estimateCost(mode.model, promptTokens, calls, genOpts)
+	estimate.SessionID = req.SessionID
+	estimate.Provider = mode.provider.Name()
+	if !s.checkCost(ctx, logger, userID, &estimate, req.Confirm) {
+		progress.failDetails(http.StatusPreconditionRequired, CodeConfirmationRequired, confirmationMessage(estimate), estimate)
+		return false
+	}
+	return true
+}
+
+// saveQuestion saves the user's message once the question is going to be
+// answered, so one sent again after confirming its cost is saved once, and
+// audits it. A new session keeps the global mode from its first question
+func (s *Server) saveQuestion(ctx context.Context, logger Logger, userID int64, req *askRequest, sess *askSession) {
+	// User messages don't have a provider mode, use empty string
+	if err := s.store.SaveChatMessage(ctx, userID, req.SessionID, "user", req.Query, ""); err != nil {
+		logger.Warn("failed to save user message", "error", err.Error())
+	} else {
+		s.pushSessionMessage(userID, req.SessionID, "user", req.Query, 0)
+		if sess.mode == "" {
+			if err := s.store.SetSessionMode(ctx, userID, req.SessionID, s.globalMode()); err != nil {
+				logger.Warn("failed to set session mode", "error", err.Error())
+			}
+		}
+	}
+	s.store.AddAuditEntry(ctx, "query", req.Query, req.SessionID)
+}
+
+// askStream sends one answer to the client once its prompt is ready. The answer
+// goes out through a client stream so a slow connection cannot hold up the
+// provider, framed as token events when the client asked for events, and is
+// buffered so a client whose connection drops can resume it
+type askStream struct {
+	sse       *SSEWriter
+	events    bool            // The answer is framed as events
+	out       io.Writer       // Where the answer text goes, through the resume buffer when there is one
+	buf       *streamBuffer   // nil when answers cannot be resumed
+	ctx       context.Context // Generation context; outlives the request when the answer can be resumed
+	timer     *streamTimer    // Receives the provider's output and times the answer
+	queueWait time.Duration
+	cleanup   []func() // Run in reverse order by close
+}
+
+// openAskStream waits for a generation slot, sending queue events meanwhile, then
+// starts the answer's stream. It fails only when the client left while queued.
+// The caller must close the stream before the handler returns
+func (s *Server) openAskStream(ctx context.Context, w http.ResponseWriter, logger Logger, progress *askProgress, requestID string, userID int64, sessionID string, events bool) (*askStream, error) {
+	st := &askStream{sse: progress.sse, events: events, ctx: ctx}
+
+	// Wait for a generation slot so one user's requests cannot starve others
+	// Queued clients receive queue events ahead of the answer; they are not buffered for resume
+	if s.providerQueue != nil {
+		ticket := s.providerQueue.Join(userID)
+		st.cleanup = append(st.cleanup, ticket.Release)
+		wait, err := s.waitForProvider(ctx, st.sse, ticket)
+		if err != nil {
+			logger.Warn("client left the provider queue", "error", err.Error(), "queue_wait_ms", wait.Milliseconds())
+			st.close()
+			return nil, err
+		}
+		st.queueWait = wait
+	}
+	progress.status("generating", map[string]interface{}{"queue_ms": st.queueWait.Milliseconds()})
+
+	// Send the answer to the client from its own goroutine, so a slow connection
+	// cannot hold up the provider stream
+	client := newClientStream(w, clientStreamBuffer, clientWriteTimeout, writeDeadline(ctx))
+	st.cleanup = append(st.cleanup, func() {
+		client.Close()
+		if dropped := client.Dropped(); dropped > 0 {
+			logger.Warn("client too slow, answer not fully streamed", "dropped_bytes", dropped)
+		}
+	})
+
+	// Answers framed as events send each chunk of text as a token event, with
+	// heartbeats throughout instead of keep-alive comments before the first token
+	st.sse.Redirect(client)
+	var answer io.Writer = client
+	keepAliveInterval := s.streamKeepAlive
+	if events {
+		answer = newSSETokenWriter(st.sse, 0)
+		client.SetNotice(formatSSEEvent("", sseEventDropped, []byte(`{"message":"The connection was too slow to stream the rest of this answer. Resume it from the last event ID."}`)))
+		st.cleanup = append(st.cleanup, st.sse.StartHeartbeat(s.sseHeartbeat()))
+		keepAliveInterval = 0
+	}
+
+	// Buffer the answer so a client whose connection drops can resume it via
+	// /api/ask/{request_id}/stream; generation continues if the client goes away
+	st.out = answer
+	if s.streams != nil {
+		st.buf = s.streams.Start(requestID, userID, sessionID)
+		st.cleanup = append(st.cleanup, st.buf.Finish)
+		if events {
+			st.buf.SetEvents()
+		}
+		st.out = newResumableWriter(answer, st.buf)
+		st.ctx = context.WithoutCancel(ctx)
+		w.Header().Set("X-Request-ID", requestID)
+	}
+
+	// Keep the connection alive while the model loads; the comments stop at the
+	// answer's first byte and are not part of the answer
+	keepAlive := startKeepAlive(st.out, client, keepAliveInterval)
+	st.cleanup = append(st.cleanup, keepAlive.Stop)
+
+	// Time the answer from sending the prompt, for its stats
+	st.timer = newStreamTimer(keepAlive)
+	return st, nil
+}
+
+// fail ends the answer with the provider's error: an error event, buffered for
+// resume, when the answer is framed as events, and otherwise text the client
+// displays in place of the answer
+func (st *askStream) fail(err error) {
+	if st.events {
+		failure := map[string]interface{}{
+			"code":    CodeUpstreamFailed,
+			"message": "Failed to get response from AI provider. " + err.Error(),
+		}
+		st.sse.Event(sseEventError, failure)
+		if st.buf != nil {
+			st.buf.FinishWith(sseEventError, failure)
+		}
+		return
+	}
+	fmt.Fprintf(st.out, "Error: Failed to get response from AI provider. %s", err.Error())
+}
+
+// finish ends an answer framed as events with its done event, buffered for resume
+func (st *askStream) finish(done askDone) {
+	if !st.events {
+		return
+	}
+	st.sse.Event(sseEventDone, done)
+	if st.buf != nil {
+		st.buf.FinishWith(sseEventDone, done)
+	}
+}
+
+// close stops the stream's keep-alives and heartbeats, sends what the client has
+// not received yet and gives up the generation slot
+func (st *askStream) close() {
+	for i := len(st.cleanup) - 1; i >= 0; i-- {
+		st.cleanup[i]()
+	}
+}
+
+// generateAnswer streams the provider's answer to a question. A question about one
+// document is answered by map-reduce over all of its chunks, with progress
+// events that are not buffered for resume; it returns the messages of the final
+// provider call
+func (s *Server) generateAnswer(st *askStream, provider LLMProvider, genOpts GenerationOptions, style rag.AnswerStyle, query string, messages []Message, docChunks []Chunk) ([]Message, string, error) {
+	if len(docChunks) > 0 {
+		return s.answerFromDocument(st.ctx, st.sse, st.timer, provider, genOpts, style, query, docChunks)
+	}
+	response, err := provider.StreamWithOptions(st.ctx, messages, genOpts, st.timer)
+	return messages, response, err
+}
+
+// askAnswer is what an answer was generated from, recorded in its provenance
+type askAnswer struct {
+	requestID      string
+	userID         int64
+	sessionID      string
+	source         string // Document asked about, empty when the library was searched
+	provider       LLMProvider
+	mode           *askMode
+	genOpts        GenerationOptions
+	style          rag.AnswerStyle
+	commands       []slashCommand
+	messages       []Message // Messages of the final provider call
+	chunks         []Chunk   // Context in prompt order; every chunk of the document asked about
+	webResults     int
+	sessionSources bool     // Retrieval was held to the sources the session cited
+	citedSources   []string // Sources the session cited
+	sessionLink    *SessionLink
+	history        *sessionHistory
+	sessionExists  bool
+}
+
+// writeAnswer sends what follows a generated answer: the rendered answer, its
+// stats and privacy summary when progress events are on, and the done event
+// when it is framed as events. It saves the answer to the session with its
+// provenance
+func (s *Server) writeAnswer(logger Logger, progress *askProgress, st *askStream, a *askAnswer, response string) {
+	ctx := st.ctx
+
+	// Save assistant message with user_id and provider mode
+	providerMode := "local"
+	if !a.mode.local {
+		providerMode = "cloud"
+	}
+	// Record the sources in prompt order so [n] markers in the response can be resolved later
+	citations := make([]string, len(a.chunks))
+	for i, chunk := range a.chunks {
+		citations[i] = chunk.Source
+	}
+	if a.source != "" {
+		// The whole document is cited as [1]
+		citations = []string{a.source}
+	}
+	// Record how the answer was produced so it can be reproduced and audited
+	params := map[string]interface{}{
+		"rag_status":  a.mode.ragStatus,
+		"web_results": a.webResults,
+		"generation":  a.genOpts,
+	}
+	if len(a.commands) > 0 {
+		typed := make([]string, len(a.commands))
+		for i, c := range a.commands {
+			typed[i] = c.String()
+		}
+		params["commands"] = typed
+	}
+	if a.style != (rag.AnswerStyle{}) {
+		params["answer_style"] = AnswerStyle(a.style)
+	}
+	if a.source != "" {
+		params["source"] = a.source
+		params["document_chunks"] = len(a.chunks)
+	}
+	if a.sessionSources && a.source == "" {
+		params["session_sources"] = len(a.citedSources)
+	}
+	if a.sessionLink != nil && a.source == "" {
+		params["continued_from"] = a.sessionLink.ContinuedFrom
+	}
+	if a.history != nil && a.source == "" {
+		params["history_messages"] = len(a.history.Messages)
+		params["history_summary"] = a.history.Summary != ""
+	}
+	provenance := newMessageProvenance(a.provider, a.mode.model, params, a.messages, a.chunks, response)
+	stats := st.timer.record(provenance)
+	// Record what reached the provider and what the RAG policy kept back
+	var redactions []string
+	if !a.mode.rag {
+		redactions = append(redactions, redactLibrary)
+		if a.sessionExists {
+			redactions = append(redactions, redactHistory)
+		}
+	}
+	withHistory := a.source == "" && (a.sessionLink != nil || (a.history != nil && (a.history.Summary != "" || len(a.history.Messages) > 0)))
+	provenance.Privacy = s.privacySummary(ctx, logger, a.mode, a.chunks, a.webResults, withHistory, redactions)
+	// The chat shows the answer as plain text while it streams, then the rendered markdown
+	progress.trailer("rendered", map[string]string{"html": markdown.Render(response)})
+	progress.trailer("stats", stats)
+	progress.trailer("privacy", provenance.Privacy)
+	messageID, err := s.store.SaveChatMessageWithProvenance(ctx, a.userID, a.sessionID, "assistant", response, providerMode, citations, provenance)
+	if err != nil {
+		logger.Warn("failed to save assistant message", "error", err.Error())
+	} else {
+		// Keep large code blocks and tables so they can be copied or downloaded on their own
+		s.saveArtifacts(ctx, logger, messageID, response)
+		s.pushSessionMessage(a.userID, a.sessionID, "assistant", response, messageID)
+	}
+	st.finish(askDone{
+		RequestID: a.requestID,
+		SessionID: a.sessionID,
+		MessageID: messageID,
+		Provider:  a.provider.Name(),
+		Model:     a.mode.model,
+		Mode:      providerMode,
+		Bytes:     len(response),
+	})
+}
@@ -0,0 +1,135 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"noodexx/internal/auth"
+	"noodexx/internal/ingest"
+	"noodexx/internal/validate"
+	"time"
+)
+
+// handleAttachments handles GET /api/attachments?session_id=... - list the files attached to a chat session
+func (s *Server) handleAttachments(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	requestID := newRequestID(w)
+
+	// Create logger with request context
+	logger := s.logger.WithContext("request_id", requestID).
+		WithContext("method", r.Method).
+		WithContext("path", r.URL.Path)
+
+	logger.Debug("processing attachments request")
+
+	// Extract user_id from context
+	userID, err := auth.GetUserID(r.Context())
+	if err != nil {
+		logger.Error("request failed", "operation", "get_user_id", "error", err.Error())
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
+		return
+	}
+
+	sessionID := r.URL.Query().Get("session_id")
+	if sessionID == "" {
+		logger.Error("request failed", "operation", "validate_request", "error", "session_id is required")
+		writeError(w, http.StatusBadRequest, CodeBadRequest, "session_id is required")
+		return
+	}
+
+	attachments := make([]map[string]interface{}, 0)
+	for _, att := range s.attachments.ForSession(userID, sessionID) {
+		attachments = append(attachments, map[string]interface{}{
+			"id":         att.ID,
+			"filename":   att.Filename,
+			"chunks":     len(att.Chunks),
+			"saved":      att.Saved,
+			"created_at": att.CreatedAt,
+			"expires_at": att.CreatedAt.Add(attachmentTTL),
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":     true,
+		"attachments": attachments,
+	})
+
+	latency := time.Since(start).Milliseconds()
+	logger.Debug("request completed", "status", http.StatusOK, "latency_ms", latency, "count", len(attachments))
+}
+
+// handleSaveAttachment handles POST /api/attachments/save
+// Ingests a chat attachment into the user's library so it is available to every session
+func (s *Server) handleSaveAttachment(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	requestID := newRequestID(w)
+
+	// Create logger with request context
+	logger := s.logger.WithContext("request_id", requestID).
+		WithContext("method", r.Method).
+		WithContext("path", r.URL.Path)
+
+	logger.Debug("processing save attachment request")
+
+	ctx := r.Context()
+
+	// Extract user_id from context
+	userID, err := auth.GetUserID(ctx)
+	if err != nil {
+		logger.Error("request failed", "operation", "get_user_id", "error", err.Error())
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
+		return
+	}
+
+	// Parse request
+	var req struct {
+		ID         string   `json:"id"`
+		Tags       []string `json:"tags"`
+		Visibility string   `json:"visibility"` // Empty for the user's default
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logger.Error("request failed", "operation", "parse_request", "error", err.Error())
+		writeError(w, http.StatusBadRequest, CodeBadRequest, "Invalid request")
+		return
+	}
+	v := validate.New()
+	v.Check("visibility", validate.Visibility(req.Visibility))
+	if err := v.Err(); err != nil {
+		writeValidationError(w, logger, err)
+		return
+	}
+
+	var att *attachment
+	var found bool
+	if s.attachments != nil {
+		att, found = s.attachments.Get(userID, req.ID)
+	}
+	if !found {
+		logger.Error("request failed", "operation", "get_attachment", "error", "attachment not found or expired", "attachment_id", req.ID)
+		writeError(w, http.StatusNotFound, CodeNotFound, "Attachment not found or expired")
+		return
+	}
+
+	if err := s.ingester.IngestText(ingest.WithVisibility(ctx, req.Visibility), userID, att.Filename, att.Text, req.Tags); err != nil {
+		logger.Error("request failed", "operation", "ingest_attachment", "filename", att.Filename, "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, fmt.Sprintf("Ingestion failed: %v", err))
+		return
+	}
+	s.attachments.MarkSaved(att.ID)
+
+	// Audit log
+	s.store.AddAuditEntry(ctx, "ingest", fmt.Sprintf("Attachment: %s", att.Filename), att.SessionID)
+
+	// Notify the user
+	s.NotifyUser(ctx, userID, "ingestion", fmt.Sprintf("%s ingested successfully", att.Filename), map[string]interface{}{"source": att.Filename})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"source":  att.Filename,
+	})
+
+	latency := time.Since(start).Milliseconds()
+	logger.Debug("request completed", "status", http.StatusOK, "latency_ms", latency, "source", att.Filename)
+}
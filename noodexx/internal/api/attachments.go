@@ -0,0 +1,221 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"noodexx/internal/rag"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-shiori/go-readability"
+)
+
+// Chat attachment limits
+const (
+	maxAttachmentSize        = 10 << 20      // Largest file accepted as a chat attachment
+	maxAttachmentChunks      = 200           // Chunks embedded per attachment; the rest are dropped
+	maxAttachmentsPerSession = 5             // Oldest attachments are evicted beyond this
+	attachmentTopK           = 3             // Attachment chunks added to each prompt
+	attachmentTTL            = 2 * time.Hour // How long an attachment stays available to its session
+	attachmentSourcePrefix   = "attachment:" // Citation source prefix for attachment chunks
+	attachmentChunkSize      = 500           // Matches the ingestion chunker
+	attachmentChunkOverlap   = 50            // Matches the ingestion chunker
+)
+
+// attachmentChunk is an in-memory chunk of an attached file
+type attachmentChunk struct {
+	Text      string
+	Embedding []float32
+}
+
+// attachment is a file attached to a chat session
+// Attachments are never written to the database; they live in memory until they
+// expire or the user saves them to the library
+type attachment struct {
+	ID        string
+	UserID    int64
+	SessionID string
+	Filename  string
+	Text      string
+	Chunks    []attachmentChunk
+	CreatedAt time.Time
+	Saved     bool
+}
+
+// attachmentStore holds transient chat attachments keyed by ID
+type attachmentStore struct {
+	mu    sync.Mutex
+	items map[string]*attachment
+	ttl   time.Duration
+	now   func() time.Time
+}
+
+// newAttachmentStore creates an empty attachment store with the given TTL
+func newAttachmentStore(ttl time.Duration) *attachmentStore {
+	return &attachmentStore{
+		items: make(map[string]*attachment),
+		ttl:   ttl,
+		now:   time.Now,
+	}
+}
+
+// Add stores an attachment, evicting expired entries and the oldest attachments
+// of the same session beyond maxAttachmentsPerSession
+func (a *attachmentStore) Add(att *attachment) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	now := a.now()
+	a.pruneLocked(now)
+	if att.CreatedAt.IsZero() {
+		att.CreatedAt = now
+	}
+	a.items[att.ID] = att
+
+	session := a.sessionLocked(att.UserID, att.SessionID)
+	for len(session) > maxAttachmentsPerSession {
+		delete(a.items, session[0].ID)
+		session = session[1:]
+	}
+}
+
+// Get returns an attachment owned by userID
+func (a *attachmentStore) Get(userID int64, id string) (*attachment, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.pruneLocked(a.now())
+	att, ok := a.items[id]
+	if !ok || att.UserID != userID {
+		return nil, false
+	}
+	return att, true
+}
+
+// ForSession returns the user's attachments for a session, oldest first
+func (a *attachmentStore) ForSession(userID int64, sessionID string) []*attachment {
+	if a == nil {
+		return nil
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.pruneLocked(a.now())
+	return a.sessionLocked(userID, sessionID)
+}
+
+// MarkSaved records that an attachment has been saved to the library
+func (a *attachmentStore) MarkSaved(id string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if att, ok := a.items[id]; ok {
+		att.Saved = true
+	}
+}
+
+// Search ranks the chunks of a session's attachments against queryVec and returns the
+// topK best as Chunks whose source is the attachment filename prefixed with attachmentSourcePrefix
+func (a *attachmentStore) Search(userID int64, sessionID string, queryVec []float32, topK int) []Chunk {
+	var results []Chunk
+	for _, att := range a.ForSession(userID, sessionID) {
+		for _, chunk := range att.Chunks {
+			results = append(results, Chunk{
+				Source: attachmentSourcePrefix + att.Filename,
+				Text:   chunk.Text,
+				Score:  rag.CosineSimilarity(queryVec, chunk.Embedding),
+			})
+		}
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+	if len(results) > topK {
+		results = results[:topK]
+	}
+	return results
+}
+
+// sessionLocked returns a session's attachments oldest first; a.mu must be held
+func (a *attachmentStore) sessionLocked(userID int64, sessionID string) []*attachment {
+	var session []*attachment
+	for _, att := range a.items {
+		if att.UserID == userID && att.SessionID == sessionID {
+			session = append(session, att)
+		}
+	}
+	sort.Slice(session, func(i, j int) bool {
+		return session[i].CreatedAt.Before(session[j].CreatedAt)
+	})
+	return session
+}
+
+// pruneLocked drops expired attachments; a.mu must be held
+func (a *attachmentStore) pruneLocked(now time.Time) {
+	for id, att := range a.items {
+		if now.Sub(att.CreatedAt) > a.ttl {
+			delete(a.items, id)
+		}
+	}
+}
+
+// extractAttachmentText converts an uploaded file to plain text
+// HTML is reduced to its readable content; other files must be valid UTF-8 text
+func extractAttachmentText(filename string, data []byte) (string, error) {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".html", ".htm":
+		article, err := readability.FromReader(bytes.NewReader(data), nil)
+		if err != nil {
+			return "", fmt.Errorf("failed to parse HTML: %w", err)
+		}
+		return article.TextContent, nil
+	case ".pdf":
+		return "", fmt.Errorf("PDF attachments are not supported yet")
+	}
+
+	if !utf8.Valid(data) {
+		return "", fmt.Errorf("file does not contain text")
+	}
+	return string(data), nil
+}
+
+// newAttachment extracts, chunks and embeds an uploaded file for use in a single session
+func newAttachment(ctx context.Context, provider LLMProvider, userID int64, sessionID, filename string, data []byte) (*attachment, error) {
+	text, err := extractAttachmentText(filename, data)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(text) == "" {
+		return nil, fmt.Errorf("file is empty")
+	}
+
+	pieces := rag.NewChunker(attachmentChunkSize, attachmentChunkOverlap).ChunkText(text)
+	if len(pieces) > maxAttachmentChunks {
+		pieces = pieces[:maxAttachmentChunks]
+	}
+
+	chunks := make([]attachmentChunk, 0, len(pieces))
+	for _, piece := range pieces {
+		embedding, err := provider.Embed(ctx, piece)
+		if err != nil {
+			return nil, fmt.Errorf("failed to embed attachment: %w", err)
+		}
+		chunks = append(chunks, attachmentChunk{Text: piece, Embedding: embedding})
+	}
+
+	return &attachment{
+		ID:        generateAttachmentID(),
+		UserID:    userID,
+		SessionID: sessionID,
+		Filename:  filepath.Base(filename),
+		Text:      text,
+		Chunks:    chunks,
+	}, nil
+}
@@ -0,0 +1,141 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"noodexx/internal/auth"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestAttachmentStoreScopingAndExpiry tests that attachments are scoped to a user's session and expire
+func TestAttachmentStoreScopingAndExpiry(t *testing.T) {
+	now := time.Now()
+	store := newAttachmentStore(time.Hour)
+	store.now = func() time.Time { return now }
+
+	store.Add(&attachment{ID: "a1", UserID: 1, SessionID: "s1", Filename: "notes.txt", Chunks: []attachmentChunk{
+		{Text: "relevant", Embedding: []float32{1, 0}},
+		{Text: "unrelated", Embedding: []float32{0, 1}},
+	}})
+	store.Add(&attachment{ID: "a2", UserID: 2, SessionID: "s1", Filename: "other.txt", Chunks: []attachmentChunk{
+		{Text: "other user", Embedding: []float32{1, 0}},
+	}})
+
+	results := store.Search(1, "s1", []float32{1, 0}, 1)
+	if len(results) != 1 || results[0].Text != "relevant" || results[0].Source != "attachment:notes.txt" {
+		t.Errorf("Expected the best matching chunk of user 1's attachment, got %+v", results)
+	}
+	if got := store.ForSession(1, "s2"); len(got) != 0 {
+		t.Errorf("Expected no attachments for another session, got %d", len(got))
+	}
+	if _, ok := store.Get(2, "a1"); ok {
+		t.Error("Expected attachment to be hidden from other users")
+	}
+
+	now = now.Add(2 * time.Hour)
+	if _, ok := store.Get(1, "a1"); ok {
+		t.Error("Expected attachment to expire")
+	}
+}
+
+// TestAttachmentStoreEvictsOldest tests the per-session attachment cap
+func TestAttachmentStoreEvictsOldest(t *testing.T) {
+	now := time.Now()
+	store := newAttachmentStore(time.Hour)
+	store.now = func() time.Time { return now }
+
+	for i := 0; i <= maxAttachmentsPerSession; i++ {
+		now = now.Add(time.Second)
+		store.Add(&attachment{ID: string(rune('a' + i)), UserID: 1, SessionID: "s1"})
+	}
+
+	session := store.ForSession(1, "s1")
+	if len(session) != maxAttachmentsPerSession {
+		t.Fatalf("Expected %d attachments, got %d", maxAttachmentsPerSession, len(session))
+	}
+	if session[0].ID != "b" {
+		t.Errorf("Expected the oldest attachment to be evicted, first is %s", session[0].ID)
+	}
+}
+
+// TestHandleAsk_MultipartAttachment tests that an attached file is used as context without being ingested
+func TestHandleAsk_MultipartAttachment(t *testing.T) {
+	var prompt string
+
+	provider := &mockProviderForAsk{
+		name:    "openai",
+		isLocal: false,
+		streamFunc: func(ctx context.Context, messages []Message, w io.Writer) (string, error) {
+			prompt = messages[len(messages)-1].Content
+			w.Write([]byte("answer"))
+			return "answer", nil
+		},
+	}
+
+	store := &attachmentAskStore{}
+
+	server := &Server{
+		store:           store,
+		logger:          &mockLoggerForAsk{},
+		providerManager: &mockProviderManagerForAsk{provider: provider, providerName: "OpenAI"},
+		ragEnforcer:     &mockRAGEnforcerForAsk{shouldPerformRAG: false, ragStatus: "RAG Disabled (Cloud Policy)"},
+		attachments:     newAttachmentStore(attachmentTTL),
+	}
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	mw.WriteField("query", "What is the launch date?")
+	mw.WriteField("session_id", "attach-session")
+	fw, _ := mw.CreateFormFile("file", "plan.md")
+	fw.Write([]byte("The launch date is March 3rd."))
+	mw.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/ask", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	req = req.WithContext(context.WithValue(req.Context(), auth.UserIDKey, int64(1)))
+
+	w := httptest.NewRecorder()
+	server.handleAsk(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if w.Header().Get("X-Attachment-ID") == "" {
+		t.Error("Expected X-Attachment-ID header")
+	}
+	if store.searched {
+		t.Error("Expected library search to be skipped under no_rag policy")
+	}
+	if !strings.Contains(prompt, "March 3rd") {
+		t.Errorf("Expected attachment text in prompt, got %q", prompt)
+	}
+	if len(store.citations) != 1 || store.citations[0] != "attachment:plan.md" {
+		t.Errorf("Expected attachment citation, got %v", store.citations)
+	}
+	if got := server.attachments.ForSession(1, "attach-session"); len(got) != 1 {
+		t.Errorf("Expected attachment to be kept for the session, got %d", len(got))
+	}
+}
+
+// attachmentAskStore records library searches and the citations saved with assistant messages
+type attachmentAskStore struct {
+	mockStore
+	searched  bool
+	citations []string
+}
+
+func (m *attachmentAskStore) SearchLibrary(ctx context.Context, userID int64, queryVec []float32, opts SearchOptions, topK int) ([]Chunk, error) {
+	m.searched = true
+	return nil, nil
+}
+
+func (m *attachmentAskStore) SaveChatMessageWithProvenance(ctx context.Context, userID int64, sessionID, role, content, providerMode string, citations []string, provenance *MessageProvenance) (int64, error) {
+	m.citations = citations
+	return 1, nil
+}
@@ -8,6 +8,8 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"noodexx/internal/auth"
+	"noodexx/internal/pwpolicy"
+	"strings"
 	"testing"
 	"time"
 )
@@ -50,9 +52,12 @@ func (m *mockAuthProvider) RefreshToken(ctx context.Context, token string) (stri
 }
 
 type mockStoreForAuth struct {
+	baseMockStore
 	getUserByUsernameFunc func(ctx context.Context, username string) (*User, error)
 	createUserFunc        func(ctx context.Context, username, password, email string, isAdmin, mustChangePassword bool) (int64, error)
 	updatePasswordFunc    func(ctx context.Context, userID int64, newPassword string) error
+	revokeSessionsFunc    func(ctx context.Context, userID int64, keepToken string) error
+	recentPasswordFunc    func(ctx context.Context, userID int64, password string, n int) (bool, error)
 }
 
 func (m *mockStoreForAuth) GetUserByUsername(ctx context.Context, username string) (*User, error) {
@@ -83,38 +88,6 @@ func (m *mockStoreForAuth) UpdatePassword(ctx context.Context, userID int64, new
 	return nil
 }
 
-func (m *mockStoreForAuth) UpdateUserDarkMode(ctx context.Context, userID int64, darkMode bool) error {
-	return nil
-}
-
-// Stub methods for Store interface (not used in auth tests)
-func (m *mockStoreForAuth) SaveChunk(ctx context.Context, source, text string, embedding []float32, tags []string, summary string) error {
-	return nil
-}
-func (m *mockStoreForAuth) Search(ctx context.Context, queryVec []float32, topK int) ([]Chunk, error) {
-	return nil, nil
-}
-func (m *mockStoreForAuth) Library(ctx context.Context) ([]LibraryEntry, error) {
-	return nil, nil
-}
-func (m *mockStoreForAuth) DeleteSource(ctx context.Context, source string) error {
-	return nil
-}
-func (m *mockStoreForAuth) SaveMessage(ctx context.Context, sessionID, role, content string) error {
-	return nil
-}
-func (m *mockStoreForAuth) GetSessionHistory(ctx context.Context, sessionID string) ([]ChatMessage, error) {
-	return nil, nil
-}
-func (m *mockStoreForAuth) ListSessions(ctx context.Context) ([]Session, error) {
-	return nil, nil
-}
-func (m *mockStoreForAuth) AddAuditEntry(ctx context.Context, opType, details, userCtx string) error {
-	return nil
-}
-func (m *mockStoreForAuth) GetAuditLog(ctx context.Context, opType string, from, to time.Time) ([]AuditEntry, error) {
-	return nil, nil
-}
 func (m *mockStoreForAuth) GetUserByID(ctx context.Context, userID int64) (*User, error) {
 	return &User{
 		ID:       userID,
@@ -122,35 +95,18 @@ func (m *mockStoreForAuth) GetUserByID(ctx context.Context, userID int64) (*User
 		IsAdmin:  false,
 	}, nil
 }
-func (m *mockStoreForAuth) ListUsers(ctx context.Context) ([]User, error) {
-	return nil, nil
-}
-func (m *mockStoreForAuth) DeleteUser(ctx context.Context, userID int64) error {
-	return nil
-}
-func (m *mockStoreForAuth) SearchByUser(ctx context.Context, userID int64, queryVec []float32, topK int) ([]Chunk, error) {
-	return nil, nil
-}
-func (m *mockStoreForAuth) LibraryByUser(ctx context.Context, userID int64) ([]LibraryEntry, error) {
-	return nil, nil
-}
-func (m *mockStoreForAuth) SaveChatMessage(ctx context.Context, userID int64, sessionID, role, content, providerMode string) error {
+func (m *mockStoreForAuth) DeleteUserSessionTokens(ctx context.Context, userID int64, keepToken string) error {
+	if m.revokeSessionsFunc != nil {
+		return m.revokeSessionsFunc(ctx, userID, keepToken)
+	}
 	return nil
 }
-func (m *mockStoreForAuth) GetUserSessions(ctx context.Context, userID int64) ([]Session, error) {
-	return nil, nil
-}
-func (m *mockStoreForAuth) GetSessionOwner(ctx context.Context, sessionID string) (int64, error) {
-	return 0, nil
-}
-func (m *mockStoreForAuth) GetSessionMessages(ctx context.Context, userID int64, sessionID string) ([]ChatMessage, error) {
-	return nil, nil
-}
-func (m *mockStoreForAuth) GetUserSkills(ctx context.Context, userID int64) ([]Skill, error) {
-	return nil, nil
-}
-func (m *mockStoreForAuth) GetWatchedFoldersByUser(ctx context.Context, userID int64) ([]WatchedFolder, error) {
-	return nil, nil
+
+func (m *mockStoreForAuth) IsRecentPassword(ctx context.Context, userID int64, password string, n int) (bool, error) {
+	if m.recentPasswordFunc != nil {
+		return m.recentPasswordFunc(ctx, userID, password, n)
+	}
+	return false, nil
 }
 
 // mockLogger is defined in server_test.go
@@ -231,6 +187,55 @@ func TestHandleLogin_Success(t *testing.T) {
 	}
 }
 
+func TestHandleLogin_RememberMe(t *testing.T) {
+	var remembered bool
+	mockAuth := &mockAuthProvider{
+		loginFunc: func(ctx context.Context, username, password string) (string, error) {
+			remembered = auth.RememberMe(ctx)
+			return "test-token-123", nil
+		},
+	}
+	mockStore := &mockStoreForAuth{
+		getUserByUsernameFunc: func(ctx context.Context, username string) (*User, error) {
+			return &User{ID: 1, Username: "testuser"}, nil
+		},
+	}
+	server := &Server{
+		authProvider: mockAuth,
+		store:        mockStore,
+		logger:       &mockLogger{},
+	}
+
+	for _, tc := range []struct {
+		rememberMe bool
+		maxAge     int
+	}{
+		{false, 0},
+		{true, 30 * 24 * 60 * 60},
+	} {
+		body, _ := json.Marshal(map[string]interface{}{
+			"username":    "testuser",
+			"password":    "testpass",
+			"remember_me": tc.rememberMe,
+		})
+		req := httptest.NewRequest(http.MethodPost, "/api/login", bytes.NewReader(body))
+		w := httptest.NewRecorder()
+
+		server.handleLogin(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d", w.Code)
+		}
+		if remembered != tc.rememberMe {
+			t.Errorf("remember_me=%v: provider saw remember me %v", tc.rememberMe, remembered)
+		}
+		cookies := w.Result().Cookies()
+		if len(cookies) != 1 || cookies[0].MaxAge != tc.maxAge {
+			t.Errorf("remember_me=%v: expected cookie max age %d, got %v", tc.rememberMe, tc.maxAge, cookies)
+		}
+	}
+}
+
 func TestHandleLogin_MustChangePassword(t *testing.T) {
 	mockAuth := &mockAuthProvider{
 		loginFunc: func(ctx context.Context, username, password string) (string, error) {
@@ -439,8 +444,8 @@ func TestHandleRegister_Success(t *testing.T) {
 	reqBody := map[string]string{
 		"username":         "newuser",
 		"email":            "new@example.com",
-		"password":         "password123",
-		"confirm_password": "password123",
+		"password":         "staple-battery-42",
+		"confirm_password": "staple-battery-42",
 	}
 	body, _ := json.Marshal(reqBody)
 
@@ -470,7 +475,7 @@ func TestHandleRegister_PasswordMismatch(t *testing.T) {
 	reqBody := map[string]string{
 		"username":         "newuser",
 		"email":            "new@example.com",
-		"password":         "password123",
+		"password":         "staple-battery-42",
 		"confirm_password": "different",
 	}
 	body, _ := json.Marshal(reqBody)
@@ -513,8 +518,8 @@ func TestHandleRegister_InvalidUsername(t *testing.T) {
 			reqBody := map[string]string{
 				"username":         tc.username,
 				"email":            "new@example.com",
-				"password":         "password123",
-				"confirm_password": "password123",
+				"password":         "staple-battery-42",
+				"confirm_password": "staple-battery-42",
 			}
 			body, _ := json.Marshal(reqBody)
 
@@ -539,8 +544,8 @@ func TestHandleRegister_InvalidEmail(t *testing.T) {
 	reqBody := map[string]string{
 		"username":         "newuser",
 		"email":            "invalid-email",
-		"password":         "password123",
-		"confirm_password": "password123",
+		"password":         "staple-battery-42",
+		"confirm_password": "staple-battery-42",
 	}
 	body, _ := json.Marshal(reqBody)
 
@@ -569,8 +574,8 @@ func TestHandleRegister_DuplicateUser(t *testing.T) {
 	reqBody := map[string]string{
 		"username":         "existinguser",
 		"email":            "existing@example.com",
-		"password":         "password123",
-		"confirm_password": "password123",
+		"password":         "staple-battery-42",
+		"confirm_password": "staple-battery-42",
 	}
 	body, _ := json.Marshal(reqBody)
 
@@ -628,6 +633,52 @@ func TestHandleChangePassword_Success(t *testing.T) {
 	}
 }
 
+func TestHandleChangePassword_RotatesSession(t *testing.T) {
+	var keptToken string
+	mockStore := &mockStoreForAuth{
+		revokeSessionsFunc: func(ctx context.Context, userID int64, keepToken string) error {
+			keptToken = keepToken
+			return nil
+		},
+	}
+	mockAuth := &mockAuthProvider{
+		refreshTokenFunc: func(ctx context.Context, token string) (string, error) {
+			if token != "old-token" {
+				t.Errorf("Expected old-token to be rotated, got %s", token)
+			}
+			return "rotated-token", nil
+		},
+	}
+	server := &Server{
+		authProvider: mockAuth,
+		store:        mockStore,
+		logger:       &mockLogger{},
+	}
+
+	body, _ := json.Marshal(map[string]string{
+		"new_password":     "newpassword123",
+		"confirm_password": "newpassword123",
+	})
+	req := httptest.NewRequest(http.MethodPost, "/api/change-password", bytes.NewReader(body))
+	req.AddCookie(&http.Cookie{Name: "session_token", Value: "old-token"})
+	ctx := auth.WithRememberMe(context.WithValue(req.Context(), auth.UserIDKey, int64(1)))
+	req = req.WithContext(ctx)
+	w := httptest.NewRecorder()
+
+	server.handleChangePassword(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	if keptToken != "rotated-token" {
+		t.Errorf("Expected every session but rotated-token to be revoked, kept %q", keptToken)
+	}
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Value != "rotated-token" || cookies[0].MaxAge != 30*24*60*60 {
+		t.Errorf("Expected a remember-me cookie with the rotated token, got %v", cookies)
+	}
+}
+
 func TestHandleChangePassword_PasswordMismatch(t *testing.T) {
 	server := &Server{
 		store:  &mockStoreForAuth{},
@@ -678,6 +729,83 @@ func TestHandleChangePassword_PasswordTooShort(t *testing.T) {
 	}
 }
 
+func TestHandleChangePassword_PasswordPolicy(t *testing.T) {
+	mockStore := &mockStoreForAuth{
+		recentPasswordFunc: func(ctx context.Context, userID int64, password string, n int) (bool, error) {
+			if n != 5 {
+				t.Errorf("Expected the last 5 passwords to be checked, got %d", n)
+			}
+			return password == "Old-Passphrase-1", nil
+		},
+	}
+	server := &Server{
+		store:  mockStore,
+		logger: &mockLogger{},
+	}
+	policy, err := pwpolicy.New(pwpolicy.Options{MinLength: 12, MinClasses: 3, HistoryCount: 5})
+	if err != nil {
+		t.Fatalf("pwpolicy.New failed: %v", err)
+	}
+	server.SetPasswordPolicy(policy)
+
+	tests := []struct {
+		name       string
+		password   string
+		wantStatus int
+		wantError  string
+	}{
+		{"too short", "Short-1", http.StatusBadRequest, "at least 12"},
+		{"too few classes", "lowercaseonlyhere", http.StatusBadRequest, "mix at least 3"},
+		{"recently used", "Old-Passphrase-1", http.StatusBadRequest, "used recently"},
+		{"accepted", "New-Passphrase-2", http.StatusOK, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, _ := json.Marshal(map[string]string{
+				"new_password":     tt.password,
+				"confirm_password": tt.password,
+			})
+			req := httptest.NewRequest(http.MethodPost, "/api/change-password", bytes.NewReader(body))
+			req = req.WithContext(context.WithValue(req.Context(), auth.UserIDKey, int64(1)))
+			w := httptest.NewRecorder()
+
+			server.handleChangePassword(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("Expected status %d, got %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+			if tt.wantError != "" && !strings.Contains(w.Body.String(), tt.wantError) {
+				t.Errorf("Expected error containing %q, got %s", tt.wantError, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandleRegister_CommonPassword(t *testing.T) {
+	server := &Server{
+		store:  &mockStoreForAuth{},
+		logger: &mockLogger{},
+	}
+
+	body, _ := json.Marshal(map[string]string{
+		"username":         "newuser",
+		"email":            "new@example.com",
+		"password":         "Password123",
+		"confirm_password": "Password123",
+	})
+	req := httptest.NewRequest(http.MethodPost, "/api/register", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+
+	server.handleRegister(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status 400, got %d", w.Code)
+	}
+	if !strings.Contains(w.Body.String(), "too common") {
+		t.Errorf("Expected a common password error, got %s", w.Body.String())
+	}
+}
+
 func TestHandleChangePassword_Unauthorized(t *testing.T) {
 	server := &Server{
 		store:  &mockStoreForAuth{},
@@ -0,0 +1,113 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"noodexx/internal/validate"
+	"strings"
+)
+
+// Body limits of routes that are not covered by the configured limits
+const (
+	credentialsBodyLimit = 16 << 10 // Login and registration
+	multipartOverhead    = 1 << 20  // Form fields and boundaries around an uploaded file
+)
+
+// BodyLimits caps the size of request bodies per route
+type BodyLimits struct {
+	Default int64            // Routes without their own limit; 0 leaves them unlimited
+	Routes  map[string]int64 // Exact paths, or path prefixes when the key ends in "/"
+}
+
+// DefaultBodyLimits returns the limits for every route given the general body
+// limit and the largest uploaded document
+func DefaultBodyLimits(maxBody, maxUpload int64) BodyLimits {
+	return BodyLimits{
+		Default: maxBody,
+		Routes: map[string]int64{
+			"/api/login":        credentialsBodyLimit,
+			"/api/register":     credentialsBodyLimit,
+			"/api/ingest/text":  maxUpload,
+			"/api/ingest/file":  maxUpload + multipartOverhead,
+			"/api/ask":          maxAttachmentSize + multipartOverhead,
+			"/api/import":       importArchiveLimit + multipartOverhead,
+			"/api/import/chats": importArchiveLimit + multipartOverhead,
+		},
+	}
+}
+
+// limitFor returns the limit of path: an exact match, else the longest matching prefix, else the default
+func (l BodyLimits) limitFor(path string) int64 {
+	return routeSetting(l.Routes, l.Default, path)
+}
+
+// routeSetting returns the setting of path in routes: an exact match, else the
+// longest matching prefix ending in "/", else def
+func routeSetting[T any](routes map[string]T, def T, path string) T {
+	if setting, ok := routes[path]; ok {
+		return setting
+	}
+	setting, matched := def, ""
+	for prefix, v := range routes {
+		if strings.HasSuffix(prefix, "/") && strings.HasPrefix(path, prefix) && len(prefix) > len(matched) {
+			setting, matched = v, prefix
+		}
+	}
+	return setting
+}
+
+// BodyLimitMiddleware rejects request bodies larger than their route's limit
+// Bodies that declare a larger Content-Length are refused with 413 up front; other
+// bodies are cut off at the limit, which handlers report as 413 when decoding
+func BodyLimitMiddleware(limits BodyLimits) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			limit := limits.limitFor(r.URL.Path)
+			if limit > 0 && r.Body != nil && r.Body != http.NoBody {
+				if r.ContentLength > limit {
+					writeBodyTooLarge(w, limit)
+					return
+				}
+				r.Body = http.MaxBytesReader(w, r.Body, limit)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// writeBodyTooLarge writes a 413 error payload
+func writeBodyTooLarge(w http.ResponseWriter, limit int64) {
+	writeError(w, http.StatusRequestEntityTooLarge, CodeBodyTooLarge, fmt.Sprintf("Request body too large (limit %d KB)", limit>>10))
+}
+
+// decodeJSON decodes the request body into dst, writing the error response itself
+// It returns false if the body was too large (413) or not valid JSON (400)
+func decodeJSON(w http.ResponseWriter, r *http.Request, logger Logger, dst interface{}) bool {
+	err := json.NewDecoder(r.Body).Decode(dst)
+	if err == nil {
+		return true
+	}
+
+	logger.Error("request failed", "operation", "parse_request", "error", err.Error())
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		writeBodyTooLarge(w, tooLarge.Limit)
+		return false
+	}
+	writeError(w, http.StatusBadRequest, CodeBadRequest, "Invalid request")
+	return false
+}
+
+// writeValidationError writes the field errors of a request as a 400 response
+// whose details list every failing field; the message is the first of them
+func writeValidationError(w http.ResponseWriter, logger Logger, err error) {
+	var fields validate.Errors
+	if !errors.As(err, &fields) {
+		fields = validate.Errors{{Message: err.Error()}}
+	}
+
+	logger.Warn("request failed", "operation", "validate", "error", fields.Error())
+	writeErrorDetails(w, http.StatusBadRequest, CodeValidationFailed, fields[0].Message, fields)
+}
@@ -0,0 +1,117 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"noodexx/internal/validate"
+	"strings"
+	"testing"
+)
+
+// TestBodyLimitsLimitFor tests exact, prefix and default limit lookup
+func TestBodyLimitsLimitFor(t *testing.T) {
+	limits := BodyLimits{
+		Default: 100,
+		Routes: map[string]int64{
+			"/api/login":      10,
+			"/api/admin/":     20,
+			"/api/admin/big/": 30,
+		},
+	}
+
+	tests := []struct {
+		path string
+		want int64
+	}{
+		{"/api/login", 10},
+		{"/api/admin/users", 20},
+		{"/api/admin/big/upload", 30},
+		{"/api/ask", 100},
+	}
+	for _, tt := range tests {
+		if got := limits.limitFor(tt.path); got != tt.want {
+			t.Errorf("limitFor(%q) = %d, want %d", tt.path, got, tt.want)
+		}
+	}
+}
+
+// TestBodyLimitMiddleware tests that oversized bodies are refused with 413
+func TestBodyLimitMiddleware(t *testing.T) {
+	limits := BodyLimits{Default: 1 << 10, Routes: map[string]int64{"/api/login": 16}}
+	var reached bool
+	handler := BodyLimitMiddleware(limits)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reached = true
+		var req map[string]string
+		decodeJSON(w, r, &mockLoggerForAsk{}, &req)
+	}))
+
+	t.Run("declared length over limit", func(t *testing.T) {
+		reached = false
+		req := httptest.NewRequest(http.MethodPost, "/api/login", strings.NewReader(`{"username":"alice","password":"secret"}`))
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+
+		if w.Code != http.StatusRequestEntityTooLarge {
+			t.Fatalf("Expected status 413, got %d", w.Code)
+		}
+		if reached {
+			t.Error("Expected the handler not to run")
+		}
+	})
+
+	t.Run("undeclared length over limit", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/api/login", io.NopCloser(strings.NewReader(`{"username":"alice","password":"secret"}`)))
+		req.ContentLength = -1
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+
+		if w.Code != http.StatusRequestEntityTooLarge {
+			t.Fatalf("Expected status 413, got %d", w.Code)
+		}
+		var response map[string]interface{}
+		if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		if response["success"] != false || response["error"] == "" {
+			t.Errorf("Unexpected error payload %v", response)
+		}
+	})
+
+	t.Run("body within limit", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/api/ask", strings.NewReader(`{"query":"hello"}`))
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d", w.Code)
+		}
+	})
+}
+
+// TestWriteValidationError tests the field error payload
+func TestWriteValidationError(t *testing.T) {
+	v := validate.New()
+	v.Required("username", "Username", "")
+	v.Check("email", validate.Email("nope"))
+
+	w := httptest.NewRecorder()
+	writeValidationError(w, &mockLoggerForAsk{}, v.Err())
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status 400, got %d", w.Code)
+	}
+	var response struct {
+		Success bool                  `json:"success"`
+		Code    ErrorCode             `json:"code"`
+		Error   string                `json:"error"`
+		Details []validate.FieldError `json:"details"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if response.Success || response.Code != CodeValidationFailed || response.Error != "Username is required" || len(response.Details) != 2 || response.Details[1].Field != "email" {
+		t.Errorf("Unexpected validation payload %+v", response)
+	}
+}
@@ -0,0 +1,181 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// clientStreamBuffer caps the output waiting for a slow client. Beyond it the
+	// rest of the answer is not streamed and the client receives a notice instead
+	clientStreamBuffer = 256 << 10
+	// clientWriteTimeout is how long one write to the client may block before the
+	// client is treated as gone
+	clientWriteTimeout = 30 * time.Second
+)
+
+// droppedNotice replaces the output a slow client fell too far behind to receive
+const droppedNotice = "\n\n[The rest of this answer was not streamed because the connection was too slow. The full answer is saved in this chat.]"
+
+// errClientGone is returned by writes to a client stream once the client has
+// stopped accepting output
+var errClientGone = errors.New("client connection closed")
+
+// clientStream decouples provider reads from writes to a client. Writes queue
+// output and return at once; a goroutine sends the queued output to the client,
+// coalescing whatever arrived while the previous write was in progress, so a
+// slow connection never blocks the provider stream. Output beyond
+// clientStreamBuffer is dropped and replaced by a notice when the stream closes.
+// Every write to the client has its own deadline, so one stuck client cannot hold
+// the handler past clientWriteTimeout either
+type clientStream struct {
+	w        http.ResponseWriter
+	rc       *http.ResponseController
+	limit    int
+	timeout  time.Duration
+	deadline time.Time // Deadline of the whole response, restored between writes; zero for none
+
+	mu      sync.Mutex
+	pending []byte
+	notice  string // Sent in place of dropped output, droppedNotice unless set
+	dropped int    // Bytes not sent because the client fell behind
+	closed  bool   // No more output will be written
+	gone    bool   // The client stopped accepting output
+	wake    chan struct{}
+	done    chan struct{}
+}
+
+// newClientStream starts sending output written to the stream to w, within
+// the response's deadline if it has one. The caller must Close the stream
+// before the handler returns
+func newClientStream(w http.ResponseWriter, limit int, timeout time.Duration, deadline time.Time) *clientStream {
+	cs := &clientStream{
+		w:        w,
+		rc:       http.NewResponseController(w),
+		limit:    limit,
+		timeout:  timeout,
+		deadline: deadline,
+		notice:   droppedNotice,
+		wake:     make(chan struct{}, 1),
+		done:     make(chan struct{}),
+	}
+	go cs.run()
+	return cs
+}
+
+// Header returns the header of the underlying response
+func (cs *clientStream) Header() http.Header {
+	return cs.w.Header()
+}
+
+// WriteHeader is a no-op: the status is sent with the first output
+func (cs *clientStream) WriteHeader(statusCode int) {}
+
+// Flush is a no-op: queued output is flushed as soon as it is sent
+func (cs *clientStream) Flush() {}
+
+// Write queues p for the client without blocking. It fails only once the client
+// has gone away, like a write to the connection would
+func (cs *clientStream) Write(p []byte) (int, error) {
+	cs.mu.Lock()
+	switch {
+	case cs.gone:
+		cs.mu.Unlock()
+		return 0, errClientGone
+	case cs.dropped > 0:
+		cs.dropped += len(p)
+	case len(cs.pending)+len(p) > cs.limit:
+		// The client is too far behind to catch up; stop streaming to it
+		cs.dropped = len(cs.pending) + len(p)
+		cs.pending = nil
+	default:
+		cs.pending = append(cs.pending, p...)
+	}
+	cs.mu.Unlock()
+
+	cs.signal()
+	return len(p), nil
+}
+
+// Close sends any queued output, or the notice for output that was dropped, and
+// waits until the client has it or its write deadline passes
+func (cs *clientStream) Close() {
+	cs.mu.Lock()
+	cs.closed = true
+	cs.mu.Unlock()
+
+	cs.signal()
+	<-cs.done
+}
+
+// SetNotice replaces the notice sent in place of dropped output
+func (cs *clientStream) SetNotice(notice string) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	cs.notice = notice
+}
+
+// Dropped returns how many bytes of output the client did not receive
+func (cs *clientStream) Dropped() int {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	return cs.dropped
+}
+
+// signal wakes the sending goroutine without blocking
+func (cs *clientStream) signal() {
+	select {
+	case cs.wake <- struct{}{}:
+	default:
+	}
+}
+
+// run sends queued output until the stream is closed or the client goes away
+func (cs *clientStream) run() {
+	defer close(cs.done)
+	for range cs.wake {
+		cs.mu.Lock()
+		out, dropped, closed, notice := cs.pending, cs.dropped, cs.closed, cs.notice
+		cs.pending = nil
+		cs.mu.Unlock()
+
+		if len(out) > 0 && !cs.send(out) {
+			return
+		}
+		if closed {
+			if dropped > 0 {
+				cs.send([]byte(notice))
+			}
+			return
+		}
+	}
+}
+
+// send writes p to the client and flushes it, reporting whether the client took it
+func (cs *clientStream) send(p []byte) bool {
+	// Deadlines are not supported by every writer, such as test recorders. The
+	// response's own deadline is restored after the write so waiting for the
+	// provider between writes never counts against the client
+	writeDeadline := time.Now().Add(cs.timeout)
+	if !cs.deadline.IsZero() && cs.deadline.Before(writeDeadline) {
+		writeDeadline = cs.deadline
+	}
+	cs.rc.SetWriteDeadline(writeDeadline)
+	defer cs.rc.SetWriteDeadline(cs.deadline)
+	_, err := cs.w.Write(p)
+	if err == nil {
+		if err = cs.rc.Flush(); errors.Is(err, http.ErrNotSupported) {
+			err = nil
+		}
+	}
+	if err != nil {
+		cs.mu.Lock()
+		cs.gone = true
+		cs.pending = nil
+		cs.mu.Unlock()
+		return false
+	}
+	return true
+}
@@ -0,0 +1,105 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// gatedWriter blocks every write until its gate is opened, like a stalled connection
+type gatedWriter struct {
+	header http.Header
+	gate   chan struct{}
+	mu     sync.Mutex
+	body   strings.Builder
+	err    error
+}
+
+func (g *gatedWriter) Header() http.Header        { return g.header }
+func (g *gatedWriter) WriteHeader(statusCode int) {}
+
+func (g *gatedWriter) Write(p []byte) (int, error) {
+	<-g.gate
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.err != nil {
+		return 0, g.err
+	}
+	g.body.Write(p)
+	return len(p), nil
+}
+
+// TestClientStream tests that output reaches the client in order
+func TestClientStream(t *testing.T) {
+	w := httptest.NewRecorder()
+	cs := newClientStream(w, clientStreamBuffer, clientWriteTimeout, time.Time{})
+	for _, token := range []string{"The ", "answer ", "is ", "42."} {
+		if _, err := cs.Write([]byte(token)); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+	}
+	cs.Close()
+
+	if got := w.Body.String(); got != "The answer is 42." {
+		t.Errorf("Expected the whole answer, got %q", got)
+	}
+	if cs.Dropped() != 0 {
+		t.Errorf("Expected nothing dropped, got %d", cs.Dropped())
+	}
+}
+
+// TestClientStream_SlowClient tests that a stalled client does not block writes
+// and receives a notice in place of the output it fell behind on
+func TestClientStream_SlowClient(t *testing.T) {
+	w := &gatedWriter{header: http.Header{}, gate: make(chan struct{})}
+	cs := newClientStream(w, 16, clientWriteTimeout, time.Time{})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		cs.Write([]byte("first "))
+		cs.Write([]byte(strings.Repeat("x", 64)))
+		cs.Write([]byte("last"))
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected writes not to wait for the client")
+	}
+
+	close(w.gate)
+	cs.Close()
+
+	body := w.body.String()
+	if !strings.HasSuffix(body, droppedNotice) || strings.Contains(body, "last") {
+		t.Errorf("Expected the rest of the answer to be replaced by the notice, got %q", body)
+	}
+	if cs.Dropped() < 68 {
+		t.Errorf("Expected the overflowing output to be counted as dropped, got %d", cs.Dropped())
+	}
+}
+
+// TestClientStream_ClientGone tests that writes fail once the client stops
+// accepting output
+func TestClientStream_ClientGone(t *testing.T) {
+	w := &gatedWriter{header: http.Header{}, gate: make(chan struct{}), err: errors.New("broken pipe")}
+	close(w.gate)
+	cs := newClientStream(w, clientStreamBuffer, clientWriteTimeout, time.Time{})
+	defer cs.Close()
+
+	cs.Write([]byte("hello"))
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, err := cs.Write([]byte(".")); errors.Is(err, errClientGone) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected writes to fail after the client went away")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -28,7 +28,7 @@ func TestHandleConfig_ValidationErrors(t *testing.T) {
 				"cloud_rag_policy":         {"no_rag"},
 			},
 			expectedStatus: http.StatusBadRequest,
-			expectedError:  "Local provider validation failed: local provider must be Ollama",
+			expectedError:  "Local provider type must be one of ollama, builtin",
 		},
 		{
 			name: "Invalid Ollama endpoint (not localhost)",
@@ -70,7 +70,7 @@ func TestHandleConfig_ValidationErrors(t *testing.T) {
 				"cloud_rag_policy":    {"no_rag"},
 			},
 			expectedStatus: http.StatusBadRequest,
-			expectedError:  "Cloud provider validation failed: invalid cloud provider type: invalid",
+			expectedError:  "Cloud provider type must be one of openai, anthropic",
 		},
 		{
 			name: "Invalid RAG policy",
@@ -78,7 +78,7 @@ func TestHandleConfig_ValidationErrors(t *testing.T) {
 				"cloud_rag_policy": {"invalid_policy"},
 			},
 			expectedStatus: http.StatusBadRequest,
-			expectedError:  "RAG policy validation failed: invalid RAG policy: invalid_policy",
+			expectedError:  "Cloud RAG policy must be one of no_rag, allow_rag",
 		},
 		{
 			name: "Valid local provider configuration",
@@ -117,7 +117,7 @@ func TestHandleConfig_ValidationErrors(t *testing.T) {
 
 			// Write a minimal valid config
 			initialConfig := &config.Config{
-				Provider: config.ProviderConfig{
+				LocalProvider: config.ProviderConfig{
 					Type:             "ollama",
 					OllamaEndpoint:   "http://localhost:11434",
 					OllamaEmbedModel: "nomic-embed-text",
@@ -0,0 +1,285 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"noodexx/internal/auth"
+	"noodexx/internal/validate"
+	"strings"
+	"time"
+)
+
+// defaultCompletionTokens is the answer length assumed by a zero CostPolicy
+// when the request sets no max_tokens
+const defaultCompletionTokens = 1024
+
+// costEstimateHistory is how many recent estimates /api/cost-settings returns
+const costEstimateHistory = 20
+
+// Outcomes of a cost estimate
+const (
+	costSent                 = "sent"                  // Under the threshold, or no threshold
+	costConfirmed            = "confirmed"             // Over the threshold and sent with "confirm": true
+	costConfirmationRequired = "confirmation_required" // Over the threshold and held back
+)
+
+// ModelPrice is what a cloud model costs in US dollars per million tokens
+type ModelPrice struct {
+	InputPerMillion  float64 `json:"input_per_million"`
+	OutputPerMillion float64 `json:"output_per_million"`
+}
+
+// CostPolicy decides when a question to a paid provider needs confirming.
+// The zero policy never asks but still logs estimates
+type CostPolicy struct {
+	ConfirmAboveUSD  float64               // Server threshold for users without their own; 0 never asks
+	CompletionTokens int                   // Answer length assumed when the request sets no max_tokens
+	Prices           map[string]ModelPrice // By model name
+}
+
+// CostEstimate is the estimated size and cost of a request to a paid provider
+type CostEstimate struct {
+	ID               int64     `json:"id,omitempty"`
+	UserID           int64     `json:"-"`
+	SessionID        string    `json:"session_id"`
+	Provider         string    `json:"provider"`
+	Model            string    `json:"model"`
+	PromptTokens     int       `json:"prompt_tokens"`
+	CompletionTokens int       `json:"completion_tokens"`
+	CostUSD          *float64  `json:"cost_usd"`      // Nil when the model has no configured price
+	ThresholdUSD     float64   `json:"threshold_usd"` // Threshold in force; 0 never asks
+	Outcome          string    `json:"outcome,omitempty"`
+	CreatedAt        time.Time `json:"created_at,omitempty"`
+}
+
+// SetCostPolicy sets the prices of cloud models and the estimated cost above
+// which /api/ask asks for confirmation
+func (s *Server) SetCostPolicy(policy CostPolicy) {
+	s.costPolicy = policy
+}
+
+// priceFor returns the price of a model, matching names case-insensitively so
+// "GPT-4o" in settings finds "gpt-4o" in the config
+func (p CostPolicy) priceFor(model string) (ModelPrice, bool) {
+	if price, ok := p.Prices[model]; ok {
+		return price, true
+	}
+	for name, price := range p.Prices {
+		if strings.EqualFold(name, model) {
+			return price, true
+		}
+	}
+	return ModelPrice{}, false
+}
+
+// costThreshold returns the estimated cost above which the user must confirm
+// a request: their own if set, otherwise the server's
+func (s *Server) costThreshold(ctx context.Context, userID int64) float64 {
+	threshold, err := s.store.GetCostThreshold(ctx, userID)
+	if err != nil || threshold == nil {
+		return s.costPolicy.ConfirmAboveUSD
+	}
+	return *threshold
+}
+
+// estimateCost estimates a request from the tokens of its prompts and the
+// number of model calls, each of which may generate up to max_tokens
+func (s *Server) estimateCost(model string, promptTokens, calls int, opts GenerationOptions) CostEstimate {
+	completion := s.costPolicy.CompletionTokens
+	if completion <= 0 {
+		completion = defaultCompletionTokens
+	}
+	if opts.MaxTokens > 0 {
+		completion = opts.MaxTokens
+	}
+	e := CostEstimate{
+		Model:            model,
+		PromptTokens:     promptTokens,
+		CompletionTokens: completion * calls,
+	}
+	if price, ok := s.costPolicy.priceFor(model); ok {
+		cost := (float64(e.PromptTokens)*price.InputPerMillion + float64(e.CompletionTokens)*price.OutputPerMillion) / 1e6
+		cost = math.Round(cost*1e6) / 1e6
+		e.CostUSD = &cost
+	}
+	return e
+}
+
+// estimateMessages returns the prompt tokens of messages sent in one call
+func estimateMessages(messages []Message) int {
+	tokens := 0
+	for _, msg := range messages {
+		tokens += estimateTokens(msg.Content)
+	}
+	return tokens
+}
+
+// estimateDocumentQA returns the prompt tokens and model calls of answering
+// over a whole document: one call per part of the document and one for the
+// answer, each carrying the question
+func (s *Server) estimateDocumentQA(query string, chunks []Chunk) (int, int) {
+	texts := make([]string, len(chunks))
+	for i, chunk := range chunks {
+		texts[i] = chunk.Text
+	}
+	parts := packByBudget(texts, s.docQABudgetTokens())
+	tokens := 0
+	for _, part := range parts {
+		tokens += estimateTokens(part) + estimateTokens(query)
+	}
+	// The answer prompt carries notes no longer than the budget
+	notes := tokens - len(parts)*estimateTokens(query)
+	if budget := s.docQABudgetTokens(); notes > budget {
+		notes = budget
+	}
+	return tokens + notes + estimateTokens(query), len(parts) + 1
+}
+
+// checkCost logs the estimate of a request to a paid provider and reports
+// whether it may be sent: it is held back when it costs more than the user's
+// threshold and was not confirmed. Requests to models without a price are
+// always sent
+func (s *Server) checkCost(ctx context.Context, logger Logger, userID int64, e *CostEstimate, confirmed bool) bool {
+	e.UserID = userID
+	e.ThresholdUSD = s.costThreshold(ctx, userID)
+	over := e.CostUSD != nil && e.ThresholdUSD > 0 && *e.CostUSD > e.ThresholdUSD
+	switch {
+	case over && !confirmed:
+		e.Outcome = costConfirmationRequired
+	case over:
+		e.Outcome = costConfirmed
+	default:
+		e.Outcome = costSent
+	}
+	if err := s.store.SaveCostEstimate(ctx, *e); err != nil {
+		logger.Warn("failed to save cost estimate", "error", err.Error())
+	}
+	logger.Info("estimated request cost", "model", e.Model, "prompt_tokens", e.PromptTokens,
+		"completion_tokens", e.CompletionTokens, "cost_usd", e.CostUSD, "threshold_usd", e.ThresholdUSD, "outcome", e.Outcome)
+	return e.Outcome != costConfirmationRequired
+}
+
+// confirmationMessage explains a request held back for its cost
+func confirmationMessage(e CostEstimate) string {
+	return fmt.Sprintf("This question is estimated to cost $%.4f with %s (about %d prompt and %d answer tokens), above your limit of $%.2f. Send it again with confirmation to go ahead.",
+		*e.CostUSD, e.Model, e.PromptTokens, e.CompletionTokens, e.ThresholdUSD)
+}
+
+// handleCostSettings handles GET /api/cost-settings - the user's confirmation
+// threshold, the server's, and their recent estimates
+func (s *Server) handleCostSettings(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	requestID := newRequestID(w)
+
+	// Create logger with request context
+	logger := s.logger.WithContext("request_id", requestID).
+		WithContext("method", r.Method).
+		WithContext("path", r.URL.Path)
+
+	logger.Debug("processing cost settings request")
+
+	ctx := r.Context()
+
+	// Extract user_id from context
+	userID, err := auth.GetUserID(ctx)
+	if err != nil {
+		logger.Error("request failed", "operation", "get_user_id", "error", err.Error())
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
+		return
+	}
+
+	threshold, err := s.store.GetCostThreshold(ctx, userID)
+	if err != nil {
+		logger.Error("request failed", "operation", "get_cost_threshold", "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to get cost settings")
+		return
+	}
+	estimates, err := s.store.GetCostEstimates(ctx, userID, costEstimateHistory)
+	if err != nil {
+		logger.Error("request failed", "operation", "get_cost_estimates", "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to get cost settings")
+		return
+	}
+	if estimates == nil {
+		estimates = []CostEstimate{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":           true,
+		"confirm_above_usd": threshold,
+		"server_default":    s.costPolicy.ConfirmAboveUSD,
+		"estimates":         estimates,
+	})
+
+	latency := time.Since(start).Milliseconds()
+	logger.Debug("request completed", "status", http.StatusOK, "latency_ms", latency)
+}
+
+// handleUpdateCostSettings handles POST /api/cost-settings
+// {"confirm_above_usd": 0.5} sets the user's confirmation threshold, 0 never
+// asks and null returns to the server's
+func (s *Server) handleUpdateCostSettings(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	requestID := newRequestID(w)
+
+	// Create logger with request context
+	logger := s.logger.WithContext("request_id", requestID).
+		WithContext("method", r.Method).
+		WithContext("path", r.URL.Path)
+
+	logger.Debug("processing update cost settings request")
+
+	ctx := r.Context()
+
+	// Extract user_id from context
+	userID, err := auth.GetUserID(ctx)
+	if err != nil {
+		logger.Error("request failed", "operation", "get_user_id", "error", err.Error())
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req struct {
+		ConfirmAboveUSD *float64 `json:"confirm_above_usd"`
+	}
+	if !decodeJSON(w, r, logger, &req) {
+		return
+	}
+
+	v := validate.New()
+	if req.ConfirmAboveUSD != nil && *req.ConfirmAboveUSD < 0 {
+		v.Check("confirm_above_usd", errors.New("Cost limit cannot be negative"))
+	}
+	if err := v.Err(); err != nil {
+		writeValidationError(w, logger, err)
+		return
+	}
+
+	if err := s.store.SetCostThreshold(ctx, userID, req.ConfirmAboveUSD); err != nil {
+		logger.Error("request failed", "operation", "set_cost_threshold", "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to save cost settings")
+		return
+	}
+
+	// Audit log
+	detail := "Cost confirmation limit reset to the server default"
+	if req.ConfirmAboveUSD != nil {
+		detail = fmt.Sprintf("Cost confirmation limit set to $%.2f", *req.ConfirmAboveUSD)
+	}
+	s.store.AddAuditEntry(ctx, "config", detail, "")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":           true,
+		"message":           "Cost settings updated successfully",
+		"confirm_above_usd": req.ConfirmAboveUSD,
+	})
+
+	latency := time.Since(start).Milliseconds()
+	logger.Debug("request completed", "status", http.StatusOK, "latency_ms", latency)
+}
@@ -0,0 +1,162 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"noodexx/internal/auth"
+	"strings"
+	"testing"
+)
+
+// costStore records the cost estimates and user messages saved, with a
+// per-user threshold
+type costStore struct {
+	mockStoreForAsk
+	threshold *float64
+	estimates []CostEstimate
+	saved     []string
+}
+
+func (m *costStore) GetCostThreshold(ctx context.Context, userID int64) (*float64, error) {
+	return m.threshold, nil
+}
+
+func (m *costStore) SetCostThreshold(ctx context.Context, userID int64, threshold *float64) error {
+	m.threshold = threshold
+	return nil
+}
+
+func (m *costStore) SaveCostEstimate(ctx context.Context, e CostEstimate) error {
+	m.estimates = append(m.estimates, e)
+	return nil
+}
+
+func (m *costStore) SaveChatMessage(ctx context.Context, userID int64, sessionID, role, content, providerMode string) error {
+	m.saved = append(m.saved, role)
+	return nil
+}
+
+// modelProviderManager reports the active model, as the real provider manager does
+type modelProviderManager struct {
+	mockProviderManagerForAsk
+	model string
+}
+
+func (m *modelProviderManager) GetActiveModel() string {
+	return m.model
+}
+
+func TestAskCostConfirmation(t *testing.T) {
+	store := &costStore{}
+	streamed := 0
+	provider := &mockProviderForAsk{name: "openai", isLocal: false, streamFunc: func(ctx context.Context, messages []Message, w io.Writer) (string, error) {
+		streamed++
+		return "answer", nil
+	}}
+	server := &Server{
+		store:           store,
+		logger:          &mockLoggerForAsk{},
+		providerManager: &modelProviderManager{mockProviderManagerForAsk{provider: provider, providerName: "OpenAI (gpt-4o)"}, "gpt-4o"},
+		ragEnforcer:     &mockRAGEnforcerForAsk{shouldPerformRAG: true, ragStatus: "RAG Enabled"},
+	}
+	// $1 per thousand prompt tokens, so the long question below costs more than $0.10
+	server.SetCostPolicy(CostPolicy{
+		ConfirmAboveUSD:  0.10,
+		CompletionTokens: 100,
+		Prices:           map[string]ModelPrice{"GPT-4o": {InputPerMillion: 1000, OutputPerMillion: 0}},
+	})
+	ask := func(query string, confirm bool) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(map[string]interface{}{"query": query, "session_id": "s1", "confirm": confirm})
+		req := httptest.NewRequest(http.MethodPost, "/api/ask", bytes.NewReader(body))
+		req = req.WithContext(context.WithValue(req.Context(), auth.UserIDKey, int64(1)))
+		w := httptest.NewRecorder()
+		server.handleAsk(w, req)
+		return w
+	}
+	long := strings.Repeat("word ", 200)
+
+	w := ask(long, false)
+	var resp ErrorResponse
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if w.Code != http.StatusPreconditionRequired || resp.Code != CodeConfirmationRequired {
+		t.Fatalf("Expected 428 confirmation_required, got %d %s", w.Code, w.Body.String())
+	}
+	details, _ := resp.Details.(map[string]interface{})
+	if details["model"] != "gpt-4o" || details["cost_usd"] == nil || details["threshold_usd"] != 0.1 {
+		t.Errorf("Expected the estimate in details, got %v", resp.Details)
+	}
+	if streamed != 0 || len(store.saved) != 0 {
+		t.Errorf("Expected nothing sent or saved before confirming, got %d streams and %v", streamed, store.saved)
+	}
+
+	// Confirming sends it, and saves the question once
+	w = ask(long, true)
+	if w.Code != http.StatusOK || streamed != 1 {
+		t.Fatalf("Expected the confirmed question answered, got %d %s", w.Code, w.Body.String())
+	}
+	if len(store.saved) != 1 || store.saved[0] != "user" {
+		t.Errorf("Expected the question saved once, got %v", store.saved)
+	}
+
+	// A short question is under the limit, and a user limit of 0 never asks
+	if w := ask("Hi?", false); w.Code != http.StatusOK {
+		t.Errorf("Expected a cheap question sent, got %d", w.Code)
+	}
+	never := 0.0
+	store.threshold = &never
+	if w := ask(long, false); w.Code != http.StatusOK {
+		t.Errorf("Expected no confirmation with a limit of 0, got %d", w.Code)
+	}
+
+	outcomes := []string{}
+	for _, e := range store.estimates {
+		outcomes = append(outcomes, e.Outcome)
+	}
+	if strings.Join(outcomes, ",") != "confirmation_required,confirmed,sent,sent" {
+		t.Errorf("Expected every estimate logged, got %v", outcomes)
+	}
+
+	// Local providers cost nothing and are not estimated
+	provider.isLocal = true
+	ask(long, false)
+	if len(store.estimates) != 4 {
+		t.Errorf("Expected no estimate for a local provider, got %d", len(store.estimates))
+	}
+}
+
+func TestCostSettings(t *testing.T) {
+	store := &costStore{}
+	server := &Server{store: store, logger: &mockLoggerForAsk{}}
+	server.SetCostPolicy(CostPolicy{ConfirmAboveUSD: 0.25})
+	do := func(method, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/api/cost-settings", strings.NewReader(body))
+		return serveRoute(server, withUser(req, 1))
+	}
+
+	if w := do(http.MethodPost, `{"confirm_above_usd": -1}`); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a negative limit, got %d", w.Code)
+	}
+	if w := do(http.MethodPost, `{"confirm_above_usd": 0.5}`); w.Code != http.StatusOK || store.threshold == nil || *store.threshold != 0.5 {
+		t.Errorf("Expected the limit saved, got %d %v", w.Code, store.threshold)
+	}
+
+	w := do(http.MethodGet, "")
+	var resp struct {
+		ConfirmAboveUSD *float64       `json:"confirm_above_usd"`
+		ServerDefault   float64        `json:"server_default"`
+		Estimates       []CostEstimate `json:"estimates"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if resp.ConfirmAboveUSD == nil || *resp.ConfirmAboveUSD != 0.5 || resp.ServerDefault != 0.25 || resp.Estimates == nil {
+		t.Errorf("Unexpected settings: %s", w.Body.String())
+	}
+
+	// null returns to the server's limit
+	if w := do(http.MethodPost, `{"confirm_above_usd": null}`); w.Code != http.StatusOK || store.threshold != nil {
+		t.Errorf("Expected the limit cleared, got %d %v", w.Code, store.threshold)
+	}
+}
@@ -0,0 +1,168 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"noodexx/internal/auth"
+	"noodexx/internal/validate"
+	"sort"
+	"time"
+)
+
+const (
+	// defaultDeadContentDays is the window the dead content report looks back over
+	defaultDeadContentDays = 30
+	// defaultLowScore is the search similarity below which a source's best match
+	// is considered a weak answer to the questions that found it
+	defaultLowScore = 0.35
+)
+
+// Dead content reasons, in the order the report lists them
+const (
+	reasonNeverRetrieved = "never_retrieved" // No search returned any of its chunks
+	reasonNeverSelected  = "never_selected"  // Searches returned it, but no prompt included it
+	reasonLowScore       = "low_score"       // Included, but its best match scored below the threshold
+)
+
+// RetrievalHit is a library chunk returned by a search for a question
+type RetrievalHit struct {
+	ChunkID  int64
+	Score    float64 // Search similarity, before any reranking
+	Selected bool    // Whether the chunk was included in the prompt
+}
+
+// SourceRetrieval summarizes how often one of a user's sources was retrieved
+// over a window
+type SourceRetrieval struct {
+	Source        string     `json:"source"`
+	Chunks        int        `json:"chunks"`
+	CreatedAt     time.Time  `json:"created_at"`
+	Candidates    int        `json:"candidates"` // Questions whose search returned one of its chunks
+	Selected      int        `json:"selected"`   // Questions whose prompt included one of its chunks
+	BestScore     float64    `json:"best_score"`
+	AvgScore      float64    `json:"avg_score"`
+	LastRetrieved *time.Time `json:"last_retrieved,omitempty"` // Nil when it was never retrieved
+}
+
+// DeadContent is a source the report suggests deleting or re-chunking
+type DeadContent struct {
+	SourceRetrieval
+	Reason     string `json:"reason"`     // One of the dead content reasons
+	Suggestion string `json:"suggestion"` // "delete" or "rechunk"
+}
+
+// recordRetrievals counts the sources of a question's library search results,
+// noting which made it into the prompt. Scores are the search similarities, as
+// reranker scores are not comparable across questions; failures are logged but
+// do not stop the request
+func (s *Server) recordRetrievals(ctx context.Context, logger Logger, candidates, selected []Chunk) {
+	inPrompt := make(map[int64]bool, len(selected))
+	for _, chunk := range selected {
+		inPrompt[chunk.ID] = true
+	}
+	hits := make([]RetrievalHit, 0, len(candidates))
+	for _, chunk := range candidates {
+		if chunk.ID != 0 {
+			hits = append(hits, RetrievalHit{ChunkID: chunk.ID, Score: chunk.Score, Selected: inPrompt[chunk.ID]})
+		}
+	}
+	if err := s.store.RecordRetrievals(ctx, hits); err != nil {
+		logger.Warn("failed to record retrieval statistics", "error", err.Error())
+	}
+}
+
+// findDeadContent picks the sources that did not earn their place in the
+// library since a time. Sources added after it have not had a full window to
+// be found and are left out
+func findDeadContent(stats []SourceRetrieval, since time.Time, minScore float64) []DeadContent {
+	var dead []DeadContent
+	for _, st := range stats {
+		if st.CreatedAt.After(since) {
+			continue
+		}
+		switch {
+		case st.Candidates == 0:
+			dead = append(dead, DeadContent{SourceRetrieval: st, Reason: reasonNeverRetrieved, Suggestion: "delete"})
+		case st.Selected == 0:
+			dead = append(dead, DeadContent{SourceRetrieval: st, Reason: reasonNeverSelected, Suggestion: "rechunk"})
+		case st.BestScore < minScore:
+			dead = append(dead, DeadContent{SourceRetrieval: st, Reason: reasonLowScore, Suggestion: "rechunk"})
+		}
+	}
+
+	rank := map[string]int{reasonNeverRetrieved: 0, reasonNeverSelected: 1, reasonLowScore: 2}
+	sort.SliceStable(dead, func(i, j int) bool {
+		if rank[dead[i].Reason] != rank[dead[j].Reason] {
+			return rank[dead[i].Reason] < rank[dead[j].Reason]
+		}
+		return dead[i].CreatedAt.Before(dead[j].CreatedAt)
+	})
+	return dead
+}
+
+// handleDeadContent handles GET /api/library/dead-content - the user's sources
+// that no question retrieved, that searches found but never put in a prompt, or
+// whose best match scored below ?min_score, over the last ?days
+func (s *Server) handleDeadContent(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	requestID := newRequestID(w)
+
+	logger := s.logger.WithContext("request_id", requestID).
+		WithContext("method", r.Method).
+		WithContext("path", r.URL.Path)
+
+	logger.Debug("processing dead content request")
+
+	ctx := r.Context()
+
+	userID, err := auth.GetUserID(ctx)
+	if err != nil {
+		logger.Error("request failed", "operation", "get_user_id", "error", err.Error())
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
+		return
+	}
+
+	query := r.URL.Query()
+	days, minScore := defaultDeadContentDays, defaultLowScore
+
+	v := validate.New()
+	if d := query.Get("days"); d != "" {
+		n, err := validate.IntRange("days", d, 1, 365)
+		v.Check("days", err)
+		days = n
+	}
+	if m := query.Get("min_score"); m != "" {
+		f, err := validate.FloatRange("min_score", m, 0, 1)
+		v.Check("min_score", err)
+		minScore = f
+	}
+	if err := v.Err(); err != nil {
+		writeValidationError(w, logger, err)
+		return
+	}
+
+	since := time.Now().AddDate(0, 0, -days)
+	stats, err := s.store.GetRetrievalStats(ctx, userID, since)
+	if err != nil {
+		logger.Error("request failed", "operation", "get_retrieval_stats", "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to load retrieval statistics")
+		return
+	}
+	dead := findDeadContent(stats, since, minScore)
+	if dead == nil {
+		dead = []DeadContent{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":    true,
+		"days":       days,
+		"min_score":  minScore,
+		"sources":    len(stats),
+		"candidates": dead,
+	})
+
+	latency := time.Since(start).Milliseconds()
+	logger.Debug("request completed", "status", http.StatusOK, "latency_ms", latency, "sources", len(stats), "candidates", len(dead))
+}
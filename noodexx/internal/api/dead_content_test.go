@@ -0,0 +1,123 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"noodexx/internal/auth"
+	"testing"
+	"time"
+)
+
+// retrievalStatsStore keeps the retrievals recorded and reports fixed statistics
+type retrievalStatsStore struct {
+	mockStoreForAsk
+	hits  [][]RetrievalHit
+	stats []SourceRetrieval
+}
+
+func (m *retrievalStatsStore) RecordRetrievals(ctx context.Context, hits []RetrievalHit) error {
+	m.hits = append(m.hits, hits)
+	return nil
+}
+
+func (m *retrievalStatsStore) GetRetrievalStats(ctx context.Context, userID int64, since time.Time) ([]SourceRetrieval, error) {
+	return m.stats, nil
+}
+
+// TestRecordRetrievals tests that asking records every library candidate with
+// its search score and marks those included in the prompt
+func TestRecordRetrievals(t *testing.T) {
+	store := &retrievalStatsStore{}
+	store.searchByUserFunc = func(ctx context.Context, userID int64, queryVec []float32, topK int) ([]Chunk, error) {
+		chunks := make([]Chunk, 7)
+		for i := range chunks {
+			chunks[i] = Chunk{ID: int64(i + 1), Source: "guide.md", Text: "Part", Score: 0.9 - float64(i)/10}
+		}
+		return chunks, nil
+	}
+	server := &Server{
+		store:           store,
+		logger:          &mockLoggerForAsk{},
+		providerManager: &mockProviderManagerForAsk{provider: &mockProviderForAsk{name: "ollama", isLocal: true}, providerName: "Ollama"},
+		ragEnforcer:     &mockRAGEnforcerForAsk{shouldPerformRAG: true, ragStatus: "RAG Enabled"},
+	}
+
+	body, _ := json.Marshal(map[string]string{"query": "How do I start?", "session_id": "s1"})
+	req := httptest.NewRequest(http.MethodPost, "/api/ask", bytes.NewReader(body))
+	req = req.WithContext(context.WithValue(req.Context(), auth.UserIDKey, int64(1)))
+	server.handleAsk(httptest.NewRecorder(), req)
+
+	if len(store.hits) != 1 || len(store.hits[0]) != 7 {
+		t.Fatalf("Expected one question with 7 candidates recorded, got %+v", store.hits)
+	}
+	selected := 0
+	for _, h := range store.hits[0] {
+		if h.Selected {
+			selected++
+		}
+	}
+	if selected != librarySearchTopK {
+		t.Errorf("Expected %d candidates marked as selected, got %d", librarySearchTopK, selected)
+	}
+	if h := store.hits[0][0]; h.ChunkID != 1 || h.Score != 0.9 || !h.Selected {
+		t.Errorf("Unexpected first hit %+v", h)
+	}
+}
+
+// TestDeadContentReport tests that old sources are flagged by how they fared
+// in searches, and that sources newer than the window are left out
+func TestDeadContentReport(t *testing.T) {
+	old := time.Now().AddDate(0, 0, -60)
+	store := &retrievalStatsStore{stats: []SourceRetrieval{
+		{Source: "useful.md", CreatedAt: old, Candidates: 9, Selected: 6, BestScore: 0.8},
+		{Source: "weak.md", CreatedAt: old, Candidates: 4, Selected: 2, BestScore: 0.2},
+		{Source: "ignored.md", CreatedAt: old, Candidates: 3, BestScore: 0.5},
+		{Source: "unused.md", CreatedAt: old},
+		{Source: "new.md", CreatedAt: time.Now()},
+	}}
+	server := &Server{store: store, logger: &mockLoggerForAsk{}}
+
+	get := func(query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/library/dead-content"+query, nil)
+		req = req.WithContext(context.WithValue(req.Context(), auth.UserIDKey, int64(1)))
+		w := httptest.NewRecorder()
+		server.handleDeadContent(w, req)
+		return w
+	}
+
+	w := get("")
+	var resp struct {
+		Sources    int           `json:"sources"`
+		Candidates []DeadContent `json:"candidates"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode report: %v", err)
+	}
+	want := []struct{ source, reason, suggestion string }{
+		{"unused.md", reasonNeverRetrieved, "delete"},
+		{"ignored.md", reasonNeverSelected, "rechunk"},
+		{"weak.md", reasonLowScore, "rechunk"},
+	}
+	if resp.Sources != 5 || len(resp.Candidates) != len(want) {
+		t.Fatalf("Expected 3 candidates among 5 sources, got %s", w.Body.String())
+	}
+	for i, c := range resp.Candidates {
+		if c.Source != want[i].source || c.Reason != want[i].reason || c.Suggestion != want[i].suggestion {
+			t.Errorf("Candidate %d: expected %v, got %+v", i, want[i], c)
+		}
+	}
+
+	// A lower threshold accepts the weak match
+	if err := json.Unmarshal(get("?min_score=0.1").Body.Bytes(), &resp); err != nil || len(resp.Candidates) != 2 {
+		t.Errorf("Expected 2 candidates with min_score=0.1, got %+v", resp.Candidates)
+	}
+
+	for _, query := range []string{"?days=0", "?min_score=2"} {
+		if w := get(query); w.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %s, got %d", query, w.Code)
+		}
+	}
+}
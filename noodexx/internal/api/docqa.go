@@ -0,0 +1,162 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"noodexx/internal/rag"
+	"strings"
+)
+
+// defaultDocQABudget matches the guardrails.doc_qa_token_budget default
+const defaultDocQABudget = 3000
+
+// docQANothing is what the model answers for a part of the document with nothing relevant
+const docQANothing = "NONE"
+
+// docQA answers a question over every chunk of one document with map-reduce
+// prompting: each part of the document that fits the token budget is condensed
+// into notes for the question, notes are combined until they fit the budget, and
+// the final answer is written from them
+type docQA struct {
+	provider LLMProvider
+	opts     GenerationOptions
+	style    rag.AnswerStyle // Shapes the final answer only
+	budget   int
+	source   string
+	query    string
+	progress func(stage string, done, total int)
+}
+
+// docQABudgetTokens returns how many tokens of document text or notes one
+// document question call may include
+func (s *Server) docQABudgetTokens() int {
+	if s.docQABudget <= 0 {
+		return defaultDocQABudget
+	}
+	return s.docQABudget
+}
+
+// answerFromDocument answers query from every chunk of a document, sending
+// progress events to sse and streaming the final answer to out
+// It returns the messages of the final call and the answer
+func (s *Server) answerFromDocument(ctx context.Context, sse *SSEWriter, out io.Writer, provider LLMProvider, opts GenerationOptions, style rag.AnswerStyle, query string, chunks []Chunk) ([]Message, string, error) {
+	qa := &docQA{
+		provider: provider,
+		opts:     opts,
+		style:    style,
+		budget:   s.docQABudgetTokens(),
+		source:   chunks[0].Source,
+		query:    query,
+		progress: func(stage string, done, total int) {
+			writeProgressEvent(sse, stage, done, total)
+		},
+	}
+	return qa.run(ctx, chunks, out)
+}
+
+// run performs the map, reduce and answer steps
+func (qa *docQA) run(ctx context.Context, chunks []Chunk, out io.Writer) ([]Message, string, error) {
+	texts := make([]string, len(chunks))
+	for i, chunk := range chunks {
+		texts[i] = chunk.Text
+	}
+
+	// Map: condense each part of the document into notes for the question
+	parts := packByBudget(texts, qa.budget)
+	var notes []string
+	for i, part := range parts {
+		prompt := fmt.Sprintf("You are reading part %d of %d of the document %q.\n\n"+
+			"Question: %s\n\n"+
+			"Document part:\n%s\n\n"+
+			"Write concise notes with every fact from this part that helps answer the question, "+
+			"keeping names, numbers and quotes exact. If nothing in this part is relevant, reply with only %s.",
+			i+1, len(parts), qa.source, qa.query, part, docQANothing)
+		note, err := qa.generate(ctx, prompt)
+		if err != nil {
+			return nil, "", fmt.Errorf("part %d of %d: %w", i+1, len(parts), err)
+		}
+		if note = strings.TrimSpace(note); note != "" && note != docQANothing {
+			notes = append(notes, note)
+		}
+		qa.progress("map", i+1, len(parts))
+	}
+
+	// Reduce: combine notes until they fit in one prompt
+	for len(notes) > 1 && estimateTokens(strings.Join(notes, "\n\n")) > qa.budget {
+		groups := packByBudget(notes, qa.budget)
+		if len(groups) == len(notes) {
+			// Every note is too large to pair with another; answer from what fits
+			break
+		}
+		combined := make([]string, 0, len(groups))
+		for i, group := range groups {
+			prompt := fmt.Sprintf("Question: %s\n\n"+
+				"Notes taken from consecutive parts of the document %q:\n%s\n\n"+
+				"Merge these notes into one concise set of notes for the question, "+
+				"removing repetition but keeping every relevant fact, name, number and quote.",
+				qa.query, qa.source, group)
+			note, err := qa.generate(ctx, prompt)
+			if err != nil {
+				return nil, "", fmt.Errorf("combining notes: %w", err)
+			}
+			combined = append(combined, strings.TrimSpace(note))
+			qa.progress("reduce", i+1, len(groups))
+		}
+		notes = combined
+	}
+
+	// Answer from the notes, streaming to the client
+	notesText := strings.Join(notes, "\n\n")
+	if notesText == "" {
+		notesText = "(No part of the document is relevant to the question.)"
+	}
+	messages := []Message{
+		{Role: "system", Content: rag.NewPromptBuilder().WithStyle(qa.style).SystemPrompt("You are a helpful assistant. Answer from the notes on the document; cite it as [1]. Say so when the notes do not contain the answer.")},
+		{Role: "user", Content: fmt.Sprintf("Notes on [1] %s:\n%s\n\nQuestion: %s", qa.source, notesText, qa.query)},
+	}
+	qa.progress("answer", 0, 1)
+	response, err := qa.provider.StreamWithOptions(ctx, messages, qa.opts, out)
+	return messages, response, err
+}
+
+// generate runs one intermediate prompt without streaming it to the client
+func (qa *docQA) generate(ctx context.Context, prompt string) (string, error) {
+	var buf bytes.Buffer
+	messages := []Message{
+		{Role: "system", Content: "You are a careful research assistant."},
+		{Role: "user", Content: prompt},
+	}
+	return qa.provider.StreamWithOptions(ctx, messages, qa.opts, &buf)
+}
+
+// packByBudget joins consecutive texts into groups of at most budget tokens
+// A text larger than the budget forms a group of its own
+func packByBudget(texts []string, budget int) []string {
+	var groups []string
+	var current []string
+	tokens := 0
+	for _, text := range texts {
+		n := estimateTokens(text)
+		if len(current) > 0 && tokens+n > budget {
+			groups = append(groups, strings.Join(current, "\n\n"))
+			current, tokens = nil, 0
+		}
+		current = append(current, text)
+		tokens += n
+	}
+	if len(current) > 0 {
+		groups = append(groups, strings.Join(current, "\n\n"))
+	}
+	return groups
+}
+
+// writeProgressEvent sends a document question progress event to the client
+func writeProgressEvent(sse *SSEWriter, stage string, done, total int) {
+	sse.Event("progress", struct {
+		Stage string `json:"stage"`
+		Done  int    `json:"done"`
+		Total int    `json:"total"`
+	}{stage, done, total})
+}
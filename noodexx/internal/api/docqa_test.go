@@ -0,0 +1,123 @@
+package api
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// mockStoreForDocQA holds one document of six 100-token chunks
+type mockStoreForDocQA struct {
+	mockStore
+	citations []string
+}
+
+func (m *mockStoreForDocQA) GetSourceChunks(ctx context.Context, userID int64, source string) ([]Chunk, error) {
+	if source != "book.md" {
+		return nil, nil
+	}
+	chunks := make([]Chunk, 6)
+	for i := range chunks {
+		chunks[i] = Chunk{ID: int64(i + 1), Source: source, Text: strings.Repeat("x", 400)}
+	}
+	return chunks, nil
+}
+
+func (m *mockStoreForDocQA) SaveChatMessageWithProvenance(ctx context.Context, userID int64, sessionID, role, content, providerMode string, citations []string, provenance *MessageProvenance) (int64, error) {
+	m.citations = citations
+	return 1, nil
+}
+
+func TestPackByBudget(t *testing.T) {
+	texts := []string{strings.Repeat("a", 40), strings.Repeat("b", 40), strings.Repeat("c", 40), strings.Repeat("d", 200)}
+	groups := packByBudget(texts, 25)
+	if len(groups) != 3 {
+		t.Fatalf("Expected 3 groups, got %d: %v", len(groups), groups)
+	}
+	if !strings.HasPrefix(groups[0], "aaa") || !strings.Contains(groups[0], "bbb") || !strings.HasPrefix(groups[1], "ccc") {
+		t.Errorf("Expected consecutive texts to be packed together, got %v", groups)
+	}
+	// A text over the budget is kept whole
+	if groups[2] != texts[3] {
+		t.Errorf("Expected the oversized text in its own group, got %q", groups[2])
+	}
+}
+
+func TestHandleAsk_DocumentQuestion(t *testing.T) {
+	var mapCalls, reduceCalls int
+	var finalPrompt string
+	provider := &mockProviderForAsk{
+		name:    "ollama",
+		isLocal: true,
+		streamFunc: func(ctx context.Context, messages []Message, w io.Writer) (string, error) {
+			prompt := messages[len(messages)-1].Content
+			var response string
+			switch {
+			case strings.Contains(prompt, "Document part:"):
+				// Notes too long to answer from together, so they are combined
+				mapCalls++
+				response = "note " + strings.Repeat("n", 400)
+			case strings.Contains(prompt, "Merge these notes"):
+				reduceCalls++
+				response = "merged"
+			default:
+				finalPrompt = prompt
+				response = "chapter three is about x"
+			}
+			w.Write([]byte(response))
+			return response, nil
+		},
+	}
+	store := &mockStoreForDocQA{}
+	rag := &mockRAGEnforcerForAsk{shouldPerformRAG: true}
+	server := &Server{
+		store:           store,
+		logger:          &mockLogger{},
+		providerManager: &mockProviderManagerForAsk{provider: provider, providerName: "Ollama"},
+		ragEnforcer:     rag,
+	}
+	server.SetDocQABudget(250)
+
+	ask := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/ask", strings.NewReader(body))
+		return serveRoute(server, withUser(req, 1))
+	}
+
+	w := ask(`{"query":"What is chapter 3 about?","source":"book.md"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if mapCalls != 3 || reduceCalls != 2 {
+		t.Errorf("Expected 3 map and 2 reduce calls, got %d and %d", mapCalls, reduceCalls)
+	}
+	body := w.Body.String()
+	if !strings.Contains(body, `data: {"stage":"map","done":3,"total":3}`) || !strings.Contains(body, `"stage":"reduce"`) {
+		t.Errorf("Expected map and reduce progress events, got %q", body)
+	}
+	if !strings.HasSuffix(body, "chapter three is about x") {
+		t.Errorf("Expected the answer after the progress events, got %q", body)
+	}
+	if !strings.Contains(finalPrompt, "merged\n\nmerged") || !strings.Contains(finalPrompt, "What is chapter 3 about?") {
+		t.Errorf("Expected the answer prompt to hold the combined notes, got %q", finalPrompt)
+	}
+	if w.Header().Get("X-Document-Chunks") != "6" {
+		t.Errorf("Expected X-Document-Chunks 6, got %q", w.Header().Get("X-Document-Chunks"))
+	}
+	if len(store.citations) != 1 || store.citations[0] != "book.md" {
+		t.Errorf("Expected the document to be cited, got %v", store.citations)
+	}
+
+	// Unknown documents are not found
+	if w := ask(`{"query":"q","source":"missing.md"}`); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for an unknown document, got %d", w.Code)
+	}
+
+	// The RAG policy keeps documents away from providers it excludes
+	rag.shouldPerformRAG = false
+	if w := ask(`{"query":"q","source":"book.md"}`); w.Code != http.StatusForbidden {
+		t.Errorf("Expected status 403 under a no-RAG policy, got %d", w.Code)
+	}
+}
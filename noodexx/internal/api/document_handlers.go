@@ -0,0 +1,202 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"noodexx/internal/auth"
+	"strings"
+	"time"
+)
+
+// maxDocumentTitleLen caps the length of a document's display title
+const maxDocumentTitleLen = 200
+
+// handleRenameDocument handles PATCH /api/documents/{id} - set the display title
+// of one of the user's documents. The source it was ingested from, and the
+// chunks and citations that refer to it, are unchanged; an empty title shows
+// the source again
+func (s *Server) handleRenameDocument(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	requestID := newRequestID(w)
+
+	logger := s.logger.WithContext("request_id", requestID).
+		WithContext("method", r.Method).
+		WithContext("path", r.URL.Path)
+
+	logger.Debug("processing rename document request")
+
+	ctx := r.Context()
+
+	userID, err := auth.GetUserID(ctx)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
+		return
+	}
+
+	documentID, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeBadRequest, "Invalid document ID")
+		return
+	}
+
+	var req struct {
+		Title string `json:"title"`
+	}
+	if !decodeJSON(w, r, logger, &req) {
+		return
+	}
+	title := strings.TrimSpace(req.Title)
+	if len(title) > maxDocumentTitleLen {
+		writeError(w, http.StatusBadRequest, CodeBadRequest, fmt.Sprintf("Title must be at most %d characters", maxDocumentTitleLen))
+		return
+	}
+
+	doc, err := s.store.GetDocument(ctx, userID, documentID)
+	if err != nil {
+		logger.Error("request failed", "operation", "get_document", "document_id", documentID, "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to get document")
+		return
+	}
+	if doc == nil {
+		writeError(w, http.StatusNotFound, CodeNotFound, "Document not found")
+		return
+	}
+
+	if err := s.store.RenameDocument(ctx, userID, documentID, title); err != nil {
+		logger.Error("request failed", "operation", "rename_document", "document_id", documentID, "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to rename document")
+		return
+	}
+	s.store.AddAuditEntry(ctx, "rename", fmt.Sprintf("Source: %s, title: %q", doc.Source, title), "")
+	doc.Title = title
+
+	w.Header().Set("HX-Trigger", `{"toast": {"variant": "success", "message": "Document renamed"}}`)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":  true,
+		"document": doc,
+	})
+
+	latency := time.Since(start).Milliseconds()
+	logger.Debug("request completed", "status", http.StatusOK, "latency_ms", latency, "document_id", documentID)
+}
+
+// handlePinDocument handles PUT /api/documents/{id}/pin - pin a document the
+// user can see, so it is listed first in their library and its chunks are
+// boosted in their searches
+func (s *Server) handlePinDocument(w http.ResponseWriter, r *http.Request) {
+	s.setDocumentPin(w, r, true)
+}
+
+// handleUnpinDocument handles DELETE /api/documents/{id}/pin - remove the
+// user's pin from a document
+func (s *Server) handleUnpinDocument(w http.ResponseWriter, r *http.Request) {
+	s.setDocumentPin(w, r, false)
+}
+
+// setDocumentPin pins or unpins the document in the request path for the user
+func (s *Server) setDocumentPin(w http.ResponseWriter, r *http.Request, pin bool) {
+	start := time.Now()
+	requestID := newRequestID(w)
+
+	logger := s.logger.WithContext("request_id", requestID).
+		WithContext("method", r.Method).
+		WithContext("path", r.URL.Path)
+
+	logger.Debug("processing document pin request", "pin", pin)
+
+	ctx := r.Context()
+
+	userID, err := auth.GetUserID(ctx)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
+		return
+	}
+
+	documentID, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeBadRequest, "Invalid document ID")
+		return
+	}
+
+	operation, message := "unpin", "Document unpinned"
+	if pin {
+		operation, message = "pin", "Document pinned"
+		found, err := s.store.PinDocument(ctx, userID, documentID)
+		if err != nil {
+			logger.Error("request failed", "operation", "pin_document", "document_id", documentID, "error", err.Error())
+			writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to pin document")
+			return
+		}
+		if !found {
+			writeError(w, http.StatusNotFound, CodeNotFound, "Document not found")
+			return
+		}
+	} else if err := s.store.UnpinDocument(ctx, userID, documentID); err != nil {
+		logger.Error("request failed", "operation", "unpin_document", "document_id", documentID, "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to unpin document")
+		return
+	}
+	s.store.AddAuditEntry(ctx, operation, fmt.Sprintf("Document: %d", documentID), "")
+
+	w.Header().Set("HX-Trigger", fmt.Sprintf(`{"toast": {"variant": "success", "message": %q}}`, message))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":     true,
+		"document_id": documentID,
+		"pinned":      pin,
+	})
+
+	latency := time.Since(start).Milliseconds()
+	logger.Debug("request completed", "status", http.StatusOK, "latency_ms", latency, "document_id", documentID)
+}
+
+// handleDocumentEncoding handles GET /api/library/encoding?source= - the
+// encoding one of the user's documents was decoded from at its latest
+// ingestion, and what normalizing its text changed
+func (s *Server) handleDocumentEncoding(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	requestID := newRequestID(w)
+
+	logger := s.logger.WithContext("request_id", requestID).
+		WithContext("method", r.Method).
+		WithContext("path", r.URL.Path)
+
+	ctx := r.Context()
+
+	userID, err := auth.GetUserID(ctx)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
+		return
+	}
+
+	source := r.URL.Query().Get("source")
+	if source == "" {
+		writeError(w, http.StatusBadRequest, CodeBadRequest, "source is required")
+		return
+	}
+
+	encoding, err := s.store.GetDocumentEncoding(ctx, userID, source)
+	if err != nil {
+		logger.Error("request failed", "operation", "get_document_encoding", "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to get document encoding")
+		return
+	}
+	if encoding == nil {
+		writeError(w, http.StatusNotFound, CodeNotFound, "No encoding recorded for this document")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":    true,
+		"source":     encoding.Source,
+		"encoding":   encoding.Encoding,
+		"changes":    encoding.Changes,
+		"updated_at": encoding.UpdatedAt,
+	})
+
+	latency := time.Since(start).Milliseconds()
+	logger.Debug("request completed", "status", http.StatusOK, "latency_ms", latency)
+}
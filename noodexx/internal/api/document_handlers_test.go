@@ -0,0 +1,229 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"noodexx/internal/auth"
+	"strings"
+	"testing"
+)
+
+// documentStore holds one user's documents for rename and pin tests
+type documentStore struct {
+	*mockStoreForAsk
+	docs map[int64]*Document
+	pins map[int64]bool
+}
+
+func (m *documentStore) GetDocument(ctx context.Context, userID, documentID int64) (*Document, error) {
+	if userID != 1 {
+		return nil, nil
+	}
+	doc, ok := m.docs[documentID]
+	if !ok {
+		return nil, nil
+	}
+	d := *doc
+	return &d, nil
+}
+
+func (m *documentStore) RenameDocument(ctx context.Context, userID, documentID int64, title string) error {
+	m.docs[documentID].Title = title
+	return nil
+}
+
+func (m *documentStore) PinDocument(ctx context.Context, userID, documentID int64) (bool, error) {
+	if _, ok := m.docs[documentID]; !ok || userID != 1 {
+		return false, nil
+	}
+	m.pins[documentID] = true
+	return true, nil
+}
+
+func (m *documentStore) UnpinDocument(ctx context.Context, userID, documentID int64) error {
+	delete(m.pins, documentID)
+	return nil
+}
+
+func (m *documentStore) LibraryByUser(ctx context.Context, userID int64) ([]LibraryEntry, error) {
+	var library []LibraryEntry
+	for _, id := range []int64{7, 8, 9} {
+		if doc, ok := m.docs[id]; ok {
+			library = append(library, LibraryEntry{DocumentID: id, Source: doc.Source, Title: doc.Source, Pinned: m.pins[id]})
+		}
+	}
+	return library, nil
+}
+
+func renameDocumentRequest(userID int64, id, body string) *http.Request {
+	req := httptest.NewRequest(http.MethodPatch, "/api/documents/"+id, strings.NewReader(body))
+	req.SetPathValue("id", id)
+	return req.WithContext(context.WithValue(req.Context(), auth.UserIDKey, userID))
+}
+
+// TestHandleRenameDocument tests renaming, ownership and title validation
+func TestHandleRenameDocument(t *testing.T) {
+	store := &documentStore{
+		mockStoreForAsk: &mockStoreForAsk{},
+		docs:            map[int64]*Document{7: {ID: 7, Source: "notes.txt"}},
+	}
+	srv := &Server{store: store, logger: &mockLoggerForAsk{}}
+
+	w := httptest.NewRecorder()
+	srv.handleRenameDocument(w, renameDocumentRequest(1, "7", `{"title": "  Meeting notes "}`))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Document Document `json:"document"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if resp.Document.Title != "Meeting notes" || resp.Document.Source != "notes.txt" {
+		t.Errorf("Expected the trimmed title and unchanged source, got %+v", resp.Document)
+	}
+	if store.docs[7].Title != "Meeting notes" {
+		t.Errorf("Expected the store to be updated, got %q", store.docs[7].Title)
+	}
+
+	w = httptest.NewRecorder()
+	srv.handleRenameDocument(w, renameDocumentRequest(2, "7", `{"title": "Mine now"}`))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for another user's document, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	srv.handleRenameDocument(w, renameDocumentRequest(1, "7", `{"title": "`+strings.Repeat("x", maxDocumentTitleLen+1)+`"}`))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a long title, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	srv.handleRenameDocument(w, renameDocumentRequest(1, "abc", `{"title": "x"}`))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an invalid ID, got %d", w.Code)
+	}
+}
+
+func pinDocumentRequest(method string, userID int64, id string) *http.Request {
+	req := httptest.NewRequest(method, "/api/documents/"+id+"/pin", nil)
+	req.SetPathValue("id", id)
+	return req.WithContext(context.WithValue(req.Context(), auth.UserIDKey, userID))
+}
+
+// TestHandlePinDocument tests pinning and unpinning documents, and that the
+// library lists pinned documents in their own section
+func TestHandlePinDocument(t *testing.T) {
+	store := &documentStore{
+		mockStoreForAsk: &mockStoreForAsk{},
+		docs: map[int64]*Document{
+			7: {ID: 7, Source: "notes.txt"},
+			8: {ID: 8, Source: "handbook.md"},
+			9: {ID: 9, Source: "faq.md"},
+		},
+		pins: map[int64]bool{},
+	}
+	srv := &Server{store: store, logger: &mockLoggerForAsk{}}
+
+	w := httptest.NewRecorder()
+	srv.handlePinDocument(w, pinDocumentRequest(http.MethodPut, 1, "8"))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if !store.pins[8] {
+		t.Error("Expected the document to be pinned")
+	}
+
+	w = httptest.NewRecorder()
+	srv.handlePinDocument(w, pinDocumentRequest(http.MethodPut, 2, "7"))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for a document the user cannot see, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	srv.handlePinDocument(w, pinDocumentRequest(http.MethodPut, 1, "abc"))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an invalid ID, got %d", w.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/library", nil)
+	req.Header.Set("Accept", "application/json")
+	req = req.WithContext(context.WithValue(req.Context(), auth.UserIDKey, int64(1)))
+	w = httptest.NewRecorder()
+	srv.handleLibrary(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var library struct {
+		Pinned    []LibraryEntry `json:"pinned"`
+		Documents []LibraryEntry `json:"documents"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &library); err != nil {
+		t.Fatalf("Failed to decode library: %v", err)
+	}
+	if len(library.Pinned) != 1 || library.Pinned[0].DocumentID != 8 {
+		t.Errorf("Expected the handbook in the pinned section, got %+v", library.Pinned)
+	}
+	if len(library.Documents) != 2 || library.Documents[0].DocumentID != 7 || library.Documents[1].DocumentID != 9 {
+		t.Errorf("Expected the other documents in order, got %+v", library.Documents)
+	}
+
+	w = httptest.NewRecorder()
+	srv.handleUnpinDocument(w, pinDocumentRequest(http.MethodDelete, 1, "8"))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if store.pins[8] {
+		t.Error("Expected the document to be unpinned")
+	}
+}
+
+// encodingStore records one document's encoding
+type encodingStore struct {
+	mockStoreForAsk
+	encoding *DocumentEncoding
+}
+
+func (m *encodingStore) GetDocumentEncoding(ctx context.Context, userID int64, source string) (*DocumentEncoding, error) {
+	if m.encoding != nil && m.encoding.Source == source {
+		return m.encoding, nil
+	}
+	return nil, nil
+}
+
+// TestHandleDocumentEncoding tests reading back the encoding a document was decoded from
+func TestHandleDocumentEncoding(t *testing.T) {
+	server := &Server{
+		store:  &encodingStore{encoding: &DocumentEncoding{Source: "notes.txt", Encoding: "windows-1252", Changes: "2 line endings"}},
+		logger: &mockLoggerForAsk{},
+	}
+	get := func(query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/library/encoding"+query, nil)
+		req = req.WithContext(context.WithValue(req.Context(), auth.UserIDKey, int64(1)))
+		w := httptest.NewRecorder()
+		server.handleDocumentEncoding(w, req)
+		return w
+	}
+
+	w := get("?source=notes.txt")
+	var resp struct {
+		Encoding string `json:"encoding"`
+		Changes  string `json:"changes"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("Expected the encoding, got %d %s", w.Code, w.Body.String())
+	}
+	if resp.Encoding != "windows-1252" || resp.Changes != "2 line endings" {
+		t.Errorf("Unexpected encoding %+v", resp)
+	}
+
+	if w := get("?source=other.txt"); w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 without a recorded encoding, got %d", w.Code)
+	}
+	if w := get(""); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 without a source, got %d", w.Code)
+	}
+}
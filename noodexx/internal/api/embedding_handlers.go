@@ -0,0 +1,106 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// Why a group of chunks is left out of search
+const (
+	embeddingMismatchModel     = "model"     // Embedded by a model other than the query model
+	embeddingMismatchDimension = "dimension" // Vectors cannot be compared with the query's
+)
+
+// EmbeddingReportGroup is an EmbeddingGroup with whether search uses it
+type EmbeddingReportGroup struct {
+	EmbeddingGroup
+	Searchable bool   `json:"searchable"`
+	Mismatch   string `json:"mismatch,omitempty"` // "model" or "dimension" when not searchable
+}
+
+// EmbeddingReport shows which models embedded the library and which chunks
+// questions cannot reach with the current query model
+type EmbeddingReport struct {
+	QueryModel     string                 `json:"query_model"`
+	QueryDimension int                    `json:"query_dimension"` // 0 until the query model has embedded a chunk
+	TotalChunks    int                    `json:"total_chunks"`
+	Searchable     int                    `json:"searchable_chunks"`
+	Excluded       int                    `json:"excluded_chunks"`
+	Groups         []EmbeddingReportGroup `json:"groups"`
+}
+
+// activeEmbedModel returns the embedding model of the active provider, if the manager reports it
+func (s *Server) activeEmbedModel() string {
+	if reporter, ok := s.providerManager.(ActiveEmbedModelReporter); ok {
+		return reporter.GetActiveEmbedModel()
+	}
+	return ""
+}
+
+// newEmbeddingReport classifies groups the way search filters chunks: the query
+// model's chunks and untagged chunks are searched when their dimension matches
+// the query model's. groups must be ordered largest first; the query model's
+// largest group sets the query dimension
+func newEmbeddingReport(queryModel string, groups []EmbeddingGroup) EmbeddingReport {
+	report := EmbeddingReport{QueryModel: queryModel, Groups: make([]EmbeddingReportGroup, len(groups))}
+	for _, g := range groups {
+		if g.Model == queryModel && g.Dimension > 0 {
+			report.QueryDimension = g.Dimension
+			break
+		}
+	}
+
+	for i, g := range groups {
+		rg := EmbeddingReportGroup{EmbeddingGroup: g}
+		switch {
+		case queryModel != "" && g.Model != "" && g.Model != queryModel:
+			rg.Mismatch = embeddingMismatchModel
+		case g.Dimension == 0 || (report.QueryDimension > 0 && g.Dimension != report.QueryDimension):
+			rg.Mismatch = embeddingMismatchDimension
+		default:
+			rg.Searchable = true
+		}
+
+		report.TotalChunks += g.Chunks
+		if rg.Searchable {
+			report.Searchable += g.Chunks
+		} else {
+			report.Excluded += g.Chunks
+		}
+		report.Groups[i] = rg
+	}
+	return report
+}
+
+// handleAdminEmbeddings handles GET /api/admin/embeddings - the embedding models
+// and dimensions across the library, and the chunks search skips because they
+// were embedded by a different model than questions are
+func (s *Server) handleAdminEmbeddings(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	requestID := newRequestID(w)
+
+	// Create logger with request context
+	logger := s.logger.WithContext("request_id", requestID).
+		WithContext("method", r.Method).
+		WithContext("path", r.URL.Path)
+
+	logger.Debug("processing embedding report request")
+
+	groups, err := s.store.GetEmbeddingGroups(r.Context())
+	if err != nil {
+		logger.Error("request failed", "operation", "get_embedding_groups", "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to load embedding report")
+		return
+	}
+	report := newEmbeddingReport(s.activeEmbedModel(), groups)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"report":  report,
+	})
+
+	latency := time.Since(start).Milliseconds()
+	logger.Debug("request completed", "groups", len(groups), "excluded_chunks", report.Excluded, "latency_ms", latency)
+}
@@ -0,0 +1,95 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// embeddingGroupsStore returns fixed embedding groups
+type embeddingGroupsStore struct {
+	*mockStoreForAsk
+	groups []EmbeddingGroup
+}
+
+func (m *embeddingGroupsStore) GetEmbeddingGroups(ctx context.Context) ([]EmbeddingGroup, error) {
+	return m.groups, nil
+}
+
+// embedModelProviderManager reports a fixed embedding model
+type embedModelProviderManager struct {
+	mockProviderManagerForAsk
+	embedModel string
+}
+
+func (m *embedModelProviderManager) GetActiveEmbedModel() string {
+	return m.embedModel
+}
+
+// TestNewEmbeddingReport tests that groups are classified the way search filters chunks
+func TestNewEmbeddingReport(t *testing.T) {
+	groups := []EmbeddingGroup{
+		{Model: "nomic-embed-text", Dimension: 768, Chunks: 40, Sources: 4},
+		{Model: "", Dimension: 768, Chunks: 10, Sources: 2},
+		{Model: "text-embedding-3-small", Dimension: 1536, Chunks: 5, Sources: 1},
+		{Model: "", Dimension: 384, Chunks: 3, Sources: 1},
+	}
+
+	report := newEmbeddingReport("nomic-embed-text", groups)
+	if report.QueryDimension != 768 {
+		t.Errorf("Expected query dimension 768, got %d", report.QueryDimension)
+	}
+	if report.TotalChunks != 58 || report.Searchable != 50 || report.Excluded != 8 {
+		t.Errorf("Expected 58 chunks with 50 searchable and 8 excluded, got %+v", report)
+	}
+	wantMismatch := []string{"", "", embeddingMismatchModel, embeddingMismatchDimension}
+	for i, g := range report.Groups {
+		if g.Mismatch != wantMismatch[i] || g.Searchable != (wantMismatch[i] == "") {
+			t.Errorf("Group %d (%s/%d): expected mismatch %q, got %q (searchable=%v)", i, g.Model, g.Dimension, wantMismatch[i], g.Mismatch, g.Searchable)
+		}
+	}
+
+	// A query model that has not embedded anything yet cannot set the dimension
+	report = newEmbeddingReport("mxbai-embed-large", groups)
+	if report.QueryDimension != 0 || report.Searchable != 13 {
+		t.Errorf("Expected only untagged chunks searchable, got %+v", report)
+	}
+}
+
+// TestHandleAdminEmbeddings tests the embedding report endpoint
+func TestHandleAdminEmbeddings(t *testing.T) {
+	store := &embeddingGroupsStore{
+		mockStoreForAsk: &mockStoreForAsk{},
+		groups: []EmbeddingGroup{
+			{Model: "nomic-embed-text", Dimension: 768, Chunks: 12, Sources: 3},
+			{Model: "text-embedding-3-small", Dimension: 1536, Chunks: 4, Sources: 1},
+		},
+	}
+	server := &Server{
+		store:           store,
+		providerManager: &embedModelProviderManager{embedModel: "nomic-embed-text"},
+		logger:          &mockLoggerForAsk{},
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/admin/embeddings", nil)
+	w := httptest.NewRecorder()
+	server.handleAdminEmbeddings(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	var response struct {
+		Report EmbeddingReport `json:"report"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if response.Report.QueryModel != "nomic-embed-text" || response.Report.Excluded != 4 || len(response.Report.Groups) != 2 {
+		t.Errorf("Unexpected report %+v", response.Report)
+	}
+	if response.Report.Groups[1].Mismatch != embeddingMismatchModel {
+		t.Errorf("Expected the OpenAI group to be a model mismatch, got %+v", response.Report.Groups[1])
+	}
+}
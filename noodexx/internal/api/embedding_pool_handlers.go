@@ -0,0 +1,43 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// handleEmbeddingPool handles GET /api/admin/embedding-pool - health and throughput of
+// the ingestion embedding workers (admin only)
+func (s *Server) handleEmbeddingPool(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	requestID := newRequestID(w)
+
+	// Create logger with request context
+	logger := s.logger.WithContext("request_id", requestID).
+		WithContext("method", r.Method).
+		WithContext("path", r.URL.Path)
+
+	logger.Debug("processing embedding pool request")
+
+	workers := []EmbeddingWorkerStats{}
+	healthy := 0
+	if s.embeddingPool != nil {
+		workers = s.embeddingPool.Stats()
+		for _, worker := range workers {
+			if worker.Healthy {
+				healthy++
+			}
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"enabled": s.embeddingPool != nil,
+		"healthy": healthy,
+		"workers": workers,
+	})
+
+	latency := time.Since(start).Milliseconds()
+	logger.Debug("request completed", "latency_ms", latency)
+}
@@ -124,8 +124,8 @@ func TestErrorHandling_InvalidLocalProviderConfiguration(t *testing.T) {
 				"local_ollama_chat_model":  {"llama3.2"},
 				"cloud_rag_policy":         {"no_rag"},
 			},
-			expectedError: "local provider must be Ollama",
-			errorShouldBe: "Clear that local provider must be Ollama",
+			expectedError: "Local provider type must be one of ollama, builtin",
+			errorShouldBe: "Clear that local provider must be Ollama or builtin",
 		},
 		{
 			name: "Invalid Ollama endpoint (not localhost)",
@@ -181,7 +181,7 @@ func TestErrorHandling_InvalidLocalProviderConfiguration(t *testing.T) {
 
 			// Write a minimal valid config
 			initialConfig := &config.Config{
-				Provider: config.ProviderConfig{
+				LocalProvider: config.ProviderConfig{
 					Type:             "ollama",
 					OllamaEndpoint:   "http://localhost:11434",
 					OllamaEmbedModel: "nomic-embed-text",
@@ -300,7 +300,7 @@ func TestErrorHandling_InvalidCloudProviderConfiguration(t *testing.T) {
 				"cloud_provider_type": {"invalid"},
 				"cloud_rag_policy":    {"no_rag"},
 			},
-			expectedError: "invalid cloud provider type",
+			expectedError: "Cloud provider type must be one of openai, anthropic",
 			errorShouldBe: "Clear that the provider type is invalid",
 		},
 	}
@@ -316,7 +316,7 @@ func TestErrorHandling_InvalidCloudProviderConfiguration(t *testing.T) {
 
 			// Write a minimal valid config
 			initialConfig := &config.Config{
-				Provider: config.ProviderConfig{
+				LocalProvider: config.ProviderConfig{
 					Type:             "ollama",
 					OllamaEndpoint:   "http://localhost:11434",
 					OllamaEmbedModel: "nomic-embed-text",
@@ -391,7 +391,7 @@ func TestErrorHandling_InvalidRAGPolicy(t *testing.T) {
 
 	// Write a minimal valid config
 	initialConfig := &config.Config{
-		Provider: config.ProviderConfig{
+		LocalProvider: config.ProviderConfig{
 			Type:             "ollama",
 			OllamaEndpoint:   "http://localhost:11434",
 			OllamaEmbedModel: "nomic-embed-text",
@@ -447,7 +447,7 @@ func TestErrorHandling_InvalidRAGPolicy(t *testing.T) {
 
 	// Verify error message is clear and actionable
 	body := w.Body.String()
-	expectedError := "invalid RAG policy"
+	expectedError := "Cloud RAG policy must be one of"
 	if !strings.Contains(body, expectedError) {
 		t.Errorf("Expected error message to contain %q, got %q", expectedError, body)
 	}
@@ -469,7 +469,7 @@ func TestErrorHandling_ErrorRecovery(t *testing.T) {
 
 	// Write a valid config
 	validConfig := &config.Config{
-		Provider: config.ProviderConfig{
+		LocalProvider: config.ProviderConfig{
 			Type:             "ollama",
 			OllamaEndpoint:   "http://localhost:11434",
 			OllamaEmbedModel: "nomic-embed-text",
@@ -530,8 +530,8 @@ func TestErrorHandling_ErrorRecovery(t *testing.T) {
 	}
 
 	// Verify the config wasn't corrupted by the failed save attempt
-	if loadedConfig.Provider.Type != "ollama" {
-		t.Errorf("Expected provider type 'ollama', got '%s'. Config was corrupted by failed save.", loadedConfig.Provider.Type)
+	if loadedConfig.LocalProvider.Type != "ollama" {
+		t.Errorf("Expected provider type 'ollama', got '%s'. Config was corrupted by failed save.", loadedConfig.LocalProvider.Type)
 	}
 
 	// Step 3: Verify that a subsequent valid configuration can be saved
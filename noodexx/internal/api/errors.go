@@ -0,0 +1,117 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// ErrorCode is a stable, machine-readable identifier of an API error
+// Clients branch on codes rather than messages; codes are never renamed, only added
+type ErrorCode string
+
+// Error codes returned by the API
+const (
+	CodeBadRequest             ErrorCode = "bad_request"              // 400: malformed request or parameters
+	CodeValidationFailed       ErrorCode = "validation_failed"        // 400: fields broke input rules; details lists them
+	CodeProviderUnavailable    ErrorCode = "provider_unavailable"     // 400: the requested AI provider is not configured
+	CodeUnauthorized           ErrorCode = "unauthorized"             // 401: not signed in, or the session expired
+	CodeInvalidCredentials     ErrorCode = "invalid_credentials"      // 401: wrong username or password
+	CodeForbidden              ErrorCode = "forbidden"                // 403: signed in but not allowed
+	CodePasswordChangeRequired ErrorCode = "password_change_required" // 403: the user must change a temporary password first (set by auth.AuthMiddleware)
+	CodeNotFound               ErrorCode = "not_found"                // 404: the resource does not exist or is not visible
+	CodeMethodNotAllowed       ErrorCode = "method_not_allowed"       // 405: wrong HTTP method for the route
+	CodeConflict               ErrorCode = "conflict"                 // 409: the resource already exists
+	CodeGone                   ErrorCode = "gone"                     // 410: the resource expired or was revoked
+	CodeBodyTooLarge           ErrorCode = "body_too_large"           // 413: request body or upload over its limit
+	CodeUnprocessable          ErrorCode = "unprocessable"            // 422: well-formed but unusable input
+	CodeAccountLocked          ErrorCode = "account_locked"           // 423: too many failed logins
+	CodeConfirmationRequired   ErrorCode = "confirmation_required"    // 428: the request's estimated cost needs confirming; details has the estimate, resend with "confirm": true
+	CodeRateLimited            ErrorCode = "rate_limited"             // 429: too many requests; retry after the Retry-After header
+	CodeInternal               ErrorCode = "internal_error"           // 500: the server failed; the request may be retried
+	CodeNotImplemented         ErrorCode = "not_implemented"          // 501: the feature is not available in this build
+	CodeUpstreamFailed         ErrorCode = "upstream_failed"          // 502: a provider or skill returned a bad result
+	CodeStarting               ErrorCode = "starting"                 // 503: still waiting for dependencies at startup; retry after the Retry-After header
+)
+
+// requestIDHeader carries the request ID that errors and logs refer to
+const requestIDHeader = "X-Request-ID"
+
+// ErrorResponse is the body of every API error
+type ErrorResponse struct {
+	Success   bool        `json:"success"` // Always false
+	Code      ErrorCode   `json:"code"`
+	Message   string      `json:"message"`
+	Error     string      `json:"error"` // Same as Message, for clients written before codes existed
+	Details   interface{} `json:"details,omitempty"`
+	RequestID string      `json:"request_id"`
+}
+
+// newRequestID creates a request ID and returns it in the X-Request-ID header,
+// so a client can quote it when reporting an error
+func newRequestID(w http.ResponseWriter) string {
+	requestID := generateRequestID()
+	w.Header().Set(requestIDHeader, requestID)
+	return requestID
+}
+
+// writeError writes an error response with the given status and code
+func writeError(w http.ResponseWriter, status int, code ErrorCode, message string) {
+	writeErrorDetails(w, status, code, message, nil)
+}
+
+// writeErrorDetails writes an error response carrying extra details, such as
+// the fields that failed validation
+// The request ID is the one the handler started with, or a new one for handlers without
+func writeErrorDetails(w http.ResponseWriter, status int, code ErrorCode, message string, details interface{}) {
+	requestID := w.Header().Get(requestIDHeader)
+	if requestID == "" {
+		requestID = newRequestID(w)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Del("Content-Length")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(ErrorResponse{
+		Success:   false,
+		Code:      code,
+		Message:   message,
+		Error:     message,
+		Details:   details,
+		RequestID: requestID,
+	})
+}
+
+// codeForStatus returns the general code of an HTTP status, for errors whose
+// status is decided by a helper
+func codeForStatus(status int) ErrorCode {
+	switch status {
+	case http.StatusBadRequest:
+		return CodeBadRequest
+	case http.StatusUnauthorized:
+		return CodeUnauthorized
+	case http.StatusForbidden:
+		return CodeForbidden
+	case http.StatusNotFound:
+		return CodeNotFound
+	case http.StatusMethodNotAllowed:
+		return CodeMethodNotAllowed
+	case http.StatusConflict:
+		return CodeConflict
+	case http.StatusGone:
+		return CodeGone
+	case http.StatusRequestEntityTooLarge:
+		return CodeBodyTooLarge
+	case http.StatusUnprocessableEntity:
+		return CodeUnprocessable
+	case http.StatusPreconditionRequired:
+		return CodeConfirmationRequired
+	case http.StatusTooManyRequests:
+		return CodeRateLimited
+	case http.StatusNotImplemented:
+		return CodeNotImplemented
+	case http.StatusBadGateway:
+		return CodeUpstreamFailed
+	default:
+		return CodeInternal
+	}
+}
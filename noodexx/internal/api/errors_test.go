@@ -0,0 +1,70 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// decodeErrorResponse decodes an error response, failing the test if the body is not one
+func decodeErrorResponse(t *testing.T, w *httptest.ResponseRecorder) ErrorResponse {
+	t.Helper()
+	var resp ErrorResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Expected a JSON error response, got %q: %v", w.Body.String(), err)
+	}
+	return resp
+}
+
+// TestWriteError tests the error envelope and its request ID
+func TestWriteError(t *testing.T) {
+	t.Run("uses the handler's request ID", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		requestID := newRequestID(w)
+		writeError(w, http.StatusNotFound, CodeNotFound, "Session not found")
+
+		if w.Code != http.StatusNotFound {
+			t.Fatalf("Expected status 404, got %d", w.Code)
+		}
+		if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+			t.Errorf("Expected JSON content type, got %q", ct)
+		}
+		resp := decodeErrorResponse(t, w)
+		if resp.Success || resp.Code != CodeNotFound || resp.Message != "Session not found" || resp.Error != resp.Message {
+			t.Errorf("Unexpected error response %+v", resp)
+		}
+		if resp.RequestID != requestID {
+			t.Errorf("Expected request ID %q, got %q", requestID, resp.RequestID)
+		}
+	})
+
+	t.Run("creates a request ID when the handler has none", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		writeErrorDetails(w, http.StatusBadGateway, CodeUpstreamFailed, "Skill output was invalid", map[string]interface{}{"run_id": 7})
+
+		resp := decodeErrorResponse(t, w)
+		if resp.RequestID == "" || resp.RequestID != w.Header().Get(requestIDHeader) {
+			t.Errorf("Expected request ID in body and header, got %q and %q", resp.RequestID, w.Header().Get(requestIDHeader))
+		}
+		details, ok := resp.Details.(map[string]interface{})
+		if !ok || details["run_id"] != float64(7) {
+			t.Errorf("Expected details with run_id, got %v", resp.Details)
+		}
+	})
+}
+
+// TestCodeForStatus tests the fallback codes of statuses decided by helpers
+func TestCodeForStatus(t *testing.T) {
+	tests := map[int]ErrorCode{
+		http.StatusForbidden:          CodeForbidden,
+		http.StatusNotFound:           CodeNotFound,
+		http.StatusBadGateway:         CodeUpstreamFailed,
+		http.StatusServiceUnavailable: CodeInternal,
+	}
+	for status, want := range tests {
+		if got := codeForStatus(status); got != want {
+			t.Errorf("codeForStatus(%d) = %s, want %s", status, got, want)
+		}
+	}
+}
@@ -0,0 +1,499 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"noodexx/internal/auth"
+	"noodexx/internal/eval"
+	"noodexx/internal/flags"
+	"noodexx/internal/rag"
+	"strings"
+	"time"
+)
+
+// Evaluation limits
+const (
+	maxEvalCases   = 200 // Cases in one golden set
+	maxEvalTopK    = 50  // Passages retrieved per question
+	evalRunsListed = 50  // Runs returned for a set, newest first
+)
+
+// evalSetRequest is the body of POST /api/eval/sets and PUT /api/eval/sets/{id}
+type evalSetRequest struct {
+	Name  string      `json:"name"`
+	Cases []eval.Case `json:"cases"`
+}
+
+// validate trims the set and reports the first problem with it
+func (req *evalSetRequest) validate() error {
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if len(req.Cases) == 0 || len(req.Cases) > maxEvalCases {
+		return fmt.Errorf("a set needs between 1 and %d cases", maxEvalCases)
+	}
+	for i := range req.Cases {
+		c := &req.Cases[i]
+		c.Question = strings.TrimSpace(c.Question)
+		if c.Question == "" {
+			return fmt.Errorf("case %d has no question", i+1)
+		}
+		sources := c.ExpectedSources[:0]
+		for _, source := range c.ExpectedSources {
+			if source = strings.TrimSpace(source); source != "" {
+				sources = append(sources, source)
+			}
+		}
+		if len(sources) == 0 {
+			return fmt.Errorf("case %d has no expected sources", i+1)
+		}
+		c.ExpectedSources = sources
+	}
+	return nil
+}
+
+// handleGetEvalSets handles GET /api/eval/sets - the user's golden sets
+func (s *Server) handleGetEvalSets(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	requestID := newRequestID(w)
+
+	logger := s.logger.WithContext("request_id", requestID).
+		WithContext("method", r.Method).
+		WithContext("path", r.URL.Path)
+
+	logger.Debug("processing get eval sets request")
+
+	ctx := r.Context()
+
+	userID, err := auth.GetUserID(ctx)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
+		return
+	}
+
+	sets, err := s.store.GetEvalSets(ctx, userID)
+	if err != nil {
+		logger.Error("request failed", "operation", "get_eval_sets", "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to get evaluation sets")
+		return
+	}
+	if sets == nil {
+		sets = []EvalSet{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"sets":    sets,
+	})
+
+	latency := time.Since(start).Milliseconds()
+	logger.Debug("request completed", "status", http.StatusOK, "latency_ms", latency, "count", len(sets))
+}
+
+// handleCreateEvalSet handles POST /api/eval/sets - create a golden set
+func (s *Server) handleCreateEvalSet(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	requestID := newRequestID(w)
+
+	logger := s.logger.WithContext("request_id", requestID).
+		WithContext("method", r.Method).
+		WithContext("path", r.URL.Path)
+
+	logger.Debug("processing create eval set request")
+
+	ctx := r.Context()
+
+	userID, err := auth.GetUserID(ctx)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req evalSetRequest
+	if !decodeJSON(w, r, logger, &req) {
+		return
+	}
+	if err := req.validate(); err != nil {
+		writeError(w, http.StatusBadRequest, CodeBadRequest, err.Error())
+		return
+	}
+
+	setID, err := s.store.CreateEvalSet(ctx, userID, req.Name, req.Cases)
+	if err != nil {
+		logger.Error("request failed", "operation", "create_eval_set", "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to create evaluation set")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"id":      setID,
+	})
+
+	latency := time.Since(start).Milliseconds()
+	logger.Debug("request completed", "status", http.StatusCreated, "latency_ms", latency, "set_id", setID, "cases", len(req.Cases))
+}
+
+// handleUpdateEvalSet handles PUT /api/eval/sets/{id} - replace a golden set's
+// name and cases; earlier runs are kept for comparison
+func (s *Server) handleUpdateEvalSet(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	requestID := newRequestID(w)
+
+	logger := s.logger.WithContext("request_id", requestID).
+		WithContext("method", r.Method).
+		WithContext("path", r.URL.Path)
+
+	logger.Debug("processing update eval set request")
+
+	ctx := r.Context()
+
+	userID, err := auth.GetUserID(ctx)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
+		return
+	}
+
+	set, ok := s.userEvalSet(w, r, logger, userID)
+	if !ok {
+		return
+	}
+
+	var req evalSetRequest
+	if !decodeJSON(w, r, logger, &req) {
+		return
+	}
+	if err := req.validate(); err != nil {
+		writeError(w, http.StatusBadRequest, CodeBadRequest, err.Error())
+		return
+	}
+
+	if err := s.store.UpdateEvalSet(ctx, userID, set.ID, req.Name, req.Cases); err != nil {
+		logger.Error("request failed", "operation", "update_eval_set", "set_id", set.ID, "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to update evaluation set")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+	})
+
+	latency := time.Since(start).Milliseconds()
+	logger.Debug("request completed", "status", http.StatusOK, "latency_ms", latency, "set_id", set.ID)
+}
+
+// handleDeleteEvalSet handles DELETE /api/eval/sets/{id} - delete a golden set and its runs
+func (s *Server) handleDeleteEvalSet(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	requestID := newRequestID(w)
+
+	logger := s.logger.WithContext("request_id", requestID).
+		WithContext("method", r.Method).
+		WithContext("path", r.URL.Path)
+
+	logger.Debug("processing delete eval set request")
+
+	ctx := r.Context()
+
+	userID, err := auth.GetUserID(ctx)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
+		return
+	}
+
+	set, ok := s.userEvalSet(w, r, logger, userID)
+	if !ok {
+		return
+	}
+
+	if err := s.store.DeleteEvalSet(ctx, userID, set.ID); err != nil {
+		logger.Error("request failed", "operation", "delete_eval_set", "set_id", set.ID, "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to delete evaluation set")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+	})
+
+	latency := time.Since(start).Milliseconds()
+	logger.Debug("request completed", "status", http.StatusOK, "latency_ms", latency, "set_id", set.ID)
+}
+
+// handleRunEvalSet handles POST /api/eval/sets/{id}/run - evaluate retrieval
+// with the current provider, reranker and library, optionally generating
+// answers, and record the run
+func (s *Server) handleRunEvalSet(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	requestID := newRequestID(w)
+
+	logger := s.logger.WithContext("request_id", requestID).
+		WithContext("method", r.Method).
+		WithContext("path", r.URL.Path)
+
+	logger.Debug("processing run eval set request")
+
+	ctx := r.Context()
+
+	userID, err := auth.GetUserID(ctx)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
+		return
+	}
+
+	if !s.featureEnabled(ctx, flags.RetrievalEval) {
+		writeError(w, http.StatusForbidden, CodeForbidden, "Retrieval evaluation is not enabled")
+		return
+	}
+
+	set, ok := s.userEvalSet(w, r, logger, userID)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		TopK     int  `json:"top_k"`    // Defaults to the number of chunks added to a prompt
+		Generate bool `json:"generate"` // Also answer each question from its passages
+	}
+	if !decodeJSON(w, r, logger, &req) {
+		return
+	}
+	if req.TopK == 0 {
+		req.TopK = librarySearchTopK
+	}
+	if req.TopK < 1 || req.TopK > maxEvalTopK {
+		writeError(w, http.StatusBadRequest, CodeBadRequest, fmt.Sprintf("top_k must be between 1 and %d", maxEvalTopK))
+		return
+	}
+
+	// Generation sends library content to the provider, so the RAG policy applies
+	if req.Generate && !s.ragEnforcer.ShouldPerformRAG() {
+		writeError(w, http.StatusForbidden, CodeForbidden, "Generating answers from the library is not allowed by the RAG policy of the current provider")
+		return
+	}
+
+	provider, err := s.providerManager.GetActiveProvider()
+	if err != nil {
+		logger.Error("request failed", "operation", "get_active_provider", "error", err.Error())
+		writeError(w, http.StatusBadRequest, CodeProviderUnavailable, "Provider not configured. Please configure the AI provider in Settings.")
+		return
+	}
+
+	retriever := &libraryRetriever{server: s, logger: logger, provider: provider, userID: userID}
+	var generator eval.Generator
+	if req.Generate {
+		opts, err := s.resolveGenerationOptions(ctx, logger, userID, GenerationOptions{})
+		if err != nil {
+			writeError(w, http.StatusBadRequest, CodeBadRequest, err.Error())
+			return
+		}
+		generator = &libraryGenerator{provider: provider, opts: opts}
+	}
+
+	results, metrics := eval.Run(ctx, set.Cases, req.TopK, retriever, generator)
+	run := &EvalRun{
+		SetID: set.ID,
+		Config: map[string]interface{}{
+			"top_k":      req.TopK,
+			"candidates": max(s.libraryCandidates(ctx), req.TopK),
+			"reranker":   s.activeReranker(ctx) != nil,
+			"provider":   s.providerManager.GetProviderName(),
+			"model":      s.activeModel(),
+			"generate":   req.Generate,
+		},
+		Metrics:    metrics,
+		Results:    results,
+		DurationMS: time.Since(start).Milliseconds(),
+	}
+
+	run.ID, err = s.store.RecordEvalRun(ctx, userID, run)
+	if err != nil {
+		logger.Error("request failed", "operation", "record_eval_run", "set_id", set.ID, "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to record evaluation run")
+		return
+	}
+	run.CreatedAt = time.Now()
+
+	s.store.AddAuditEntry(ctx, "eval", fmt.Sprintf("Evaluated %q: hit rate %.2f, MRR %.2f", set.Name, metrics.HitRate, metrics.MRR), "")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"run":     run,
+	})
+
+	latency := time.Since(start).Milliseconds()
+	logger.Debug("request completed", "status", http.StatusOK, "latency_ms", latency, "set_id", set.ID, "run_id", run.ID, "hit_rate", metrics.HitRate, "mrr", metrics.MRR)
+}
+
+// handleGetEvalRuns handles GET /api/eval/sets/{id}/runs - the set's recent runs,
+// newest first, without per-case results
+func (s *Server) handleGetEvalRuns(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	requestID := newRequestID(w)
+
+	logger := s.logger.WithContext("request_id", requestID).
+		WithContext("method", r.Method).
+		WithContext("path", r.URL.Path)
+
+	logger.Debug("processing get eval runs request")
+
+	ctx := r.Context()
+
+	userID, err := auth.GetUserID(ctx)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
+		return
+	}
+
+	set, ok := s.userEvalSet(w, r, logger, userID)
+	if !ok {
+		return
+	}
+
+	runs, err := s.store.GetEvalRuns(ctx, userID, set.ID, evalRunsListed)
+	if err != nil {
+		logger.Error("request failed", "operation", "get_eval_runs", "set_id", set.ID, "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to get evaluation runs")
+		return
+	}
+	if runs == nil {
+		runs = []EvalRun{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"runs":    runs,
+	})
+
+	latency := time.Since(start).Milliseconds()
+	logger.Debug("request completed", "status", http.StatusOK, "latency_ms", latency, "set_id", set.ID, "count", len(runs))
+}
+
+// handleGetEvalRun handles GET /api/eval/runs/{id} - one run with its per-case results
+func (s *Server) handleGetEvalRun(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	requestID := newRequestID(w)
+
+	logger := s.logger.WithContext("request_id", requestID).
+		WithContext("method", r.Method).
+		WithContext("path", r.URL.Path)
+
+	logger.Debug("processing get eval run request")
+
+	ctx := r.Context()
+
+	userID, err := auth.GetUserID(ctx)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
+		return
+	}
+
+	runID, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeBadRequest, "Invalid run ID")
+		return
+	}
+
+	run, err := s.store.GetEvalRun(ctx, userID, runID)
+	if err != nil {
+		logger.Error("request failed", "operation", "get_eval_run", "run_id", runID, "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to get evaluation run")
+		return
+	}
+	if run == nil {
+		writeError(w, http.StatusNotFound, CodeNotFound, "Evaluation run not found")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"run":     run,
+	})
+
+	latency := time.Since(start).Milliseconds()
+	logger.Debug("request completed", "status", http.StatusOK, "latency_ms", latency, "run_id", runID)
+}
+
+// userEvalSet finds the golden set named by the request's {id}, writing an
+// error response when it is not one of the user's
+func (s *Server) userEvalSet(w http.ResponseWriter, r *http.Request, logger Logger, userID int64) (*EvalSet, bool) {
+	setID, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeBadRequest, "Invalid set ID")
+		return nil, false
+	}
+
+	set, err := s.store.GetEvalSet(r.Context(), userID, setID)
+	if err != nil {
+		logger.Error("request failed", "operation", "get_eval_set", "set_id", setID, "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to get evaluation set")
+		return nil, false
+	}
+	if set == nil {
+		writeError(w, http.StatusNotFound, CodeNotFound, "Evaluation set not found")
+		return nil, false
+	}
+	return set, true
+}
+
+// libraryRetriever retrieves from the user's library the way /api/ask does:
+// embed the question, search, and rerank when a reranker is configured
+type libraryRetriever struct {
+	server   *Server
+	logger   Logger
+	provider LLMProvider
+	userID   int64
+}
+
+func (lr *libraryRetriever) Retrieve(ctx context.Context, question string, k int) ([]eval.Passage, error) {
+	queryVec, err := lr.provider.Embed(ctx, question)
+	if err != nil {
+		return nil, fmt.Errorf("embedding failed: %w", err)
+	}
+
+	opts := SearchOptions{Query: question, QueryModel: lr.server.activeEmbedModel()}
+	chunks, err := lr.server.store.SearchLibrary(ctx, lr.userID, queryVec, opts, max(lr.server.libraryCandidates(ctx), k))
+	if err != nil {
+		return nil, fmt.Errorf("search failed: %w", err)
+	}
+	chunks = lr.server.selectLibraryChunks(ctx, lr.logger, question, chunks, k)
+
+	passages := make([]eval.Passage, len(chunks))
+	for i, chunk := range chunks {
+		passages[i] = eval.Passage{Source: chunk.Source, Text: chunk.Text}
+	}
+	return passages, nil
+}
+
+// libraryGenerator answers from retrieved passages with the /api/ask prompt
+type libraryGenerator struct {
+	provider LLMProvider
+	opts     GenerationOptions
+}
+
+func (lg *libraryGenerator) Generate(ctx context.Context, question string, passages []eval.Passage) (string, error) {
+	ragChunks := make([]rag.Chunk, len(passages))
+	for i, passage := range passages {
+		ragChunks[i] = rag.Chunk{Source: passage.Source, Text: passage.Text}
+	}
+	prompt := rag.NewPromptBuilder().BuildPrompt(question, ragChunks)
+
+	var buf bytes.Buffer
+	messages := []Message{
+		{Role: "system", Content: "You are a helpful assistant."},
+		{Role: "user", Content: prompt},
+	}
+	return lg.provider.StreamWithOptions(ctx, messages, lg.opts, &buf)
+}
@@ -0,0 +1,148 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"noodexx/internal/eval"
+	"strings"
+	"testing"
+)
+
+// mockStoreForEval holds golden set 1 and searches a library where the question
+// "alpha" finds a.md first and "beta" finds only unrelated documents
+type mockStoreForEval struct {
+	mockStore
+	created []eval.Case
+	run     *EvalRun
+	query   string
+}
+
+func (m *mockStoreForEval) CreateEvalSet(ctx context.Context, userID int64, name string, cases []eval.Case) (int64, error) {
+	m.created = cases
+	return 7, nil
+}
+
+func (m *mockStoreForEval) GetEvalSet(ctx context.Context, userID, setID int64) (*EvalSet, error) {
+	if setID != 1 {
+		return nil, nil
+	}
+	return &EvalSet{ID: 1, Name: "golden", Cases: []eval.Case{
+		{Question: "alpha", ExpectedSources: []string{"a.md"}},
+		{Question: "beta", ExpectedSources: []string{"b.md"}},
+	}}, nil
+}
+
+func (m *mockStoreForEval) SearchLibrary(ctx context.Context, userID int64, queryVec []float32, opts SearchOptions, topK int) ([]Chunk, error) {
+	if m.query == "alpha" {
+		return []Chunk{{Source: "a.md", Text: "a"}, {Source: "c.md", Text: "c"}}, nil
+	}
+	return []Chunk{{Source: "c.md", Text: "c"}, {Source: "d.md", Text: "d"}}, nil
+}
+
+func (m *mockStoreForEval) RecordEvalRun(ctx context.Context, userID int64, run *EvalRun) (int64, error) {
+	m.run = run
+	return 3, nil
+}
+
+func TestHandleCreateEvalSet_Validation(t *testing.T) {
+	store := &mockStoreForEval{}
+	server := &Server{store: store, logger: &mockLogger{}}
+
+	create := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/eval/sets", strings.NewReader(body))
+		return serveRoute(server, withUser(req, 1))
+	}
+
+	for _, body := range []string{
+		`{"name":"","cases":[{"question":"q","expected_sources":["a.md"]}]}`,
+		`{"name":"golden","cases":[]}`,
+		`{"name":"golden","cases":[{"question":" ","expected_sources":["a.md"]}]}`,
+		`{"name":"golden","cases":[{"question":"q","expected_sources":[" "]}]}`,
+	} {
+		if w := create(body); w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for %s, got %d", body, w.Code)
+		}
+	}
+
+	w := create(`{"name":"golden","cases":[{"question":" q ","expected_sources":["a.md",""]}]}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+	if len(store.created) != 1 || store.created[0].Question != "q" || len(store.created[0].ExpectedSources) != 1 {
+		t.Errorf("Expected the cases to be trimmed, got %+v", store.created)
+	}
+}
+
+func TestHandleRunEvalSet(t *testing.T) {
+	store := &mockStoreForEval{}
+	var generated int
+	provider := &mockProviderForAsk{
+		name:    "ollama",
+		isLocal: true,
+		embedFunc: func(ctx context.Context, text string) ([]float32, error) {
+			store.query = text
+			return []float32{1}, nil
+		},
+		streamFunc: func(ctx context.Context, messages []Message, w io.Writer) (string, error) {
+			generated++
+			return "answer", nil
+		},
+	}
+	rag := &mockRAGEnforcerForAsk{shouldPerformRAG: true}
+	server := &Server{
+		store:           store,
+		logger:          &mockLogger{},
+		providerManager: &mockProviderManagerForAsk{provider: provider, providerName: "Ollama"},
+		ragEnforcer:     rag,
+	}
+
+	run := func(path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		return serveRoute(server, withUser(req, 1))
+	}
+
+	w := run("/api/eval/sets/1/run", `{"top_k":2}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Run EvalRun `json:"run"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	m := resp.Run.Metrics
+	if resp.Run.ID != 3 || m.Cases != 2 || m.Hits != 1 || m.HitRate != 0.5 || m.MRR != 0.5 {
+		t.Errorf("Expected one hit at rank 1 out of two cases, got %+v", resp.Run)
+	}
+	if store.run == nil || store.run.Config["top_k"] != 2 || len(store.run.Results) != 2 {
+		t.Errorf("Expected the run to be recorded with its config and results, got %+v", store.run)
+	}
+	if generated != 0 {
+		t.Errorf("Expected no answers without generate, got %d", generated)
+	}
+
+	// Generation answers every case
+	if w := run("/api/eval/sets/1/run", `{"generate":true}`); w.Code != http.StatusOK || generated != 2 {
+		t.Errorf("Expected 2 answers, got status %d and %d answers", w.Code, generated)
+	}
+	if store.run.Results[0].Answer != "answer" {
+		t.Errorf("Expected answers in the results, got %+v", store.run.Results[0])
+	}
+
+	// The RAG policy keeps library content away from providers it excludes
+	rag.shouldPerformRAG = false
+	if w := run("/api/eval/sets/1/run", `{"generate":true}`); w.Code != http.StatusForbidden {
+		t.Errorf("Expected status 403 under a no-RAG policy, got %d", w.Code)
+	}
+
+	if w := run("/api/eval/sets/1/run", `{"top_k":500}`); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for top_k over the limit, got %d", w.Code)
+	}
+	if w := run("/api/eval/sets/9/run", `{}`); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for an unknown set, got %d", w.Code)
+	}
+}
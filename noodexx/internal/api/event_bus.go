@@ -0,0 +1,100 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"noodexx/internal/events"
+)
+
+// legacyEventNames maps the event names skills used before the event bus to
+// the topics they now subscribe to
+var legacyEventNames = map[string]events.Topic{
+	"ingest_complete": events.IngestionCompleted,
+}
+
+// SetEventBus connects the server to the internal event bus: it publishes
+// deletions and new accounts, pushes events to their user's WebSocket clients,
+// notifies admins of provider failovers and runs event-triggered skills
+func (s *Server) SetEventBus(bus *events.Bus) {
+	s.bus = bus
+	bus.Subscribe(events.IngestionCompleted, "websocket", s.pushEvent)
+	bus.Subscribe(events.DocumentDeleted, "websocket", s.pushEvent)
+	bus.SubscribeAsync(events.ProviderFailover, "notifications", s.notifyFailover)
+	for _, topic := range events.Topics {
+		bus.SubscribeAsync(topic, "skill triggers", s.runEventSkills)
+	}
+}
+
+// publish sends an event about a user to the event bus, if there is one
+func (s *Server) publish(ctx context.Context, topic events.Topic, userID int64, data map[string]interface{}) {
+	s.bus.Publish(ctx, events.Event{Topic: topic, UserID: userID, Data: data})
+}
+
+// pushEvent sends an event to its user's open tabs, so pages such as the
+// library refresh after changes made elsewhere, like by the folder watcher
+func (s *Server) pushEvent(ctx context.Context, e events.Event) {
+	if e.UserID == 0 {
+		return
+	}
+	s.pushToUser(e.UserID, topicIngestion, map[string]interface{}{
+		"type":  "event",
+		"topic": e.Topic,
+		"data":  e.Data,
+	})
+}
+
+// notifyFailover tells every admin that a provider endpoint failed
+func (s *Server) notifyFailover(ctx context.Context, e events.Event) {
+	users, err := s.store.ListUsers(ctx)
+	if err != nil {
+		s.logger.Warn("failed to list admins for a failover notification", "error", err.Error())
+		return
+	}
+	message := fmt.Sprintf("Endpoint %v failed and its work moved to the other endpoints: %v", e.Data["endpoint"], e.Data["error"])
+	for _, user := range users {
+		if user.IsAdmin {
+			s.NotifyUser(ctx, user.ID, "failover", message, e.Data)
+		}
+	}
+}
+
+// runEventSkills runs the skills of the event's user that have an event
+// trigger listing its topic. The skill gets the topic and data as its context
+func (s *Server) runEventSkills(ctx context.Context, e events.Event) {
+	if e.UserID == 0 || s.skillsLoader == nil || s.skillsExecutor == nil {
+		return
+	}
+	skills, err := s.skillsLoader.LoadForUser(ctx, e.UserID)
+	if err != nil {
+		s.logger.Warn("failed to load skills for an event", "topic", string(e.Topic), "user_id", e.UserID, "error", err.Error())
+		return
+	}
+	for _, skill := range skills {
+		if skill.UserID != e.UserID || !triggeredBy(skill, e.Topic) {
+			continue
+		}
+		input := SkillInput{Context: map[string]interface{}{"event": string(e.Topic), "data": e.Data}}
+		run := s.runSkill(ctx, e.UserID, skill, input, 0, false)
+		if run.Err != nil {
+			s.logger.Warn("event-triggered skill failed", "skill", skill.Name, "topic", string(e.Topic), "error", run.Err.Error())
+		}
+	}
+}
+
+// triggeredBy reports whether one of a skill's event triggers lists the topic
+// in its "events" parameter
+func triggeredBy(skill *Skill, topic events.Topic) bool {
+	for _, trigger := range skill.Triggers {
+		if trigger.Type != "event" {
+			continue
+		}
+		names, _ := trigger.Parameters["events"].([]interface{})
+		for _, name := range names {
+			name, _ := name.(string)
+			if events.Topic(name) == topic || legacyEventNames[name] == topic {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,101 @@
+package api
+
+import (
+	"context"
+	"noodexx/internal/events"
+	"sync"
+	"testing"
+)
+
+// eventSkillsLoader returns the same skills for every user
+type eventSkillsLoader struct {
+	skills []*Skill
+}
+
+func (m *eventSkillsLoader) LoadAll() ([]*Skill, error) {
+	return m.skills, nil
+}
+
+func (m *eventSkillsLoader) LoadForUser(ctx context.Context, userID int64) ([]*Skill, error) {
+	return m.skills, nil
+}
+
+// eventSkillsExecutor records which skills ran with what input
+type eventSkillsExecutor struct {
+	mu     sync.Mutex
+	ran    []string
+	inputs []SkillInput
+}
+
+func (m *eventSkillsExecutor) Execute(ctx context.Context, skill *Skill, input SkillInput) (*SkillOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.ran = append(m.ran, skill.Name)
+	m.inputs = append(m.inputs, input)
+	return &SkillOutput{Result: "done"}, nil
+}
+
+func eventTrigger(names ...string) SkillTrigger {
+	var list []interface{}
+	for _, name := range names {
+		list = append(list, name)
+	}
+	return SkillTrigger{Type: "event", Parameters: map[string]interface{}{"events": list}}
+}
+
+// TestEventTriggeredSkills tests that events published on the bus run the
+// skills of their user whose event triggers list the topic
+func TestEventTriggeredSkills(t *testing.T) {
+	executor := &eventSkillsExecutor{}
+	server := &Server{
+		store: &mockStoreForAsk{},
+		skillsLoader: &eventSkillsLoader{skills: []*Skill{
+			{UserID: 1, Name: "on-ingest", Triggers: []SkillTrigger{eventTrigger("ingestion.completed")}},
+			{UserID: 1, Name: "legacy", Triggers: []SkillTrigger{eventTrigger("ingest_complete")}},
+			{UserID: 1, Name: "on-delete", Triggers: []SkillTrigger{eventTrigger("document.deleted")}},
+			{UserID: 1, Name: "manual", Triggers: []SkillTrigger{{Type: "manual"}}},
+			{UserID: 2, Name: "not-mine", Triggers: []SkillTrigger{eventTrigger("ingestion.completed")}},
+		}},
+		skillsExecutor: executor,
+		logger:         &mockLoggerForAsk{},
+	}
+	bus := events.New(nil)
+	server.SetEventBus(bus)
+
+	bus.Publish(context.Background(), events.Event{
+		Topic:  events.IngestionCompleted,
+		UserID: 1,
+		Data:   map[string]interface{}{"source": "notes.md", "chunks": 3},
+	})
+	// System events have no user whose skills could run
+	bus.Publish(context.Background(), events.Event{Topic: events.IngestionCompleted})
+	bus.Close()
+
+	if len(executor.ran) != 2 || executor.ran[0] != "on-ingest" || executor.ran[1] != "legacy" {
+		t.Fatalf("Expected on-ingest and legacy to run, got %v", executor.ran)
+	}
+	input := executor.inputs[0]
+	if input.Context["event"] != "ingestion.completed" {
+		t.Errorf("Expected the topic in the skill context, got %v", input.Context["event"])
+	}
+	if data, _ := input.Context["data"].(map[string]interface{}); data["source"] != "notes.md" {
+		t.Errorf("Expected the event data in the skill context, got %v", input.Context["data"])
+	}
+}
+
+// TestTriggeredBy tests matching event triggers against topics
+func TestTriggeredBy(t *testing.T) {
+	skill := &Skill{Triggers: []SkillTrigger{
+		{Type: "keyword", Parameters: map[string]interface{}{"events": []interface{}{"user.created"}}},
+		eventTrigger("document.deleted"),
+	}}
+	if !triggeredBy(skill, events.DocumentDeleted) {
+		t.Error("Expected the event trigger to match document.deleted")
+	}
+	if triggeredBy(skill, events.UserCreated) {
+		t.Error("Expected triggers other than event triggers to be ignored")
+	}
+	if triggeredBy(&Skill{Triggers: []SkillTrigger{{Type: "event"}}}, events.UserCreated) {
+		t.Error("Expected an event trigger without events to match nothing")
+	}
+}
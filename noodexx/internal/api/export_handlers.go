@@ -0,0 +1,123 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"noodexx/internal/auth"
+	"noodexx/internal/config"
+	"noodexx/internal/export"
+	"time"
+)
+
+// handleExportSession handles GET /api/session/:id/export?format=markdown|pdf
+// Renders the session transcript with provider badges and citation footnotes
+func (s *Server) handleExportSession(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	requestID := newRequestID(w)
+
+	// Create logger with request context
+	logger := s.logger.WithContext("request_id", requestID).
+		WithContext("method", r.Method).
+		WithContext("path", r.URL.Path)
+
+	logger.Debug("processing session export request")
+
+	ctx := r.Context()
+
+	// Extract user_id from context
+	userID, err := auth.GetUserID(ctx)
+	if err != nil {
+		logger.Error("request failed", "operation", "get_user_id", "error", err.Error())
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
+		return
+	}
+
+	sessionID := r.PathValue("id")
+	if sessionID == "" {
+		writeError(w, http.StatusBadRequest, CodeBadRequest, "Session ID required")
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "markdown"
+	}
+	if format != "markdown" && format != "pdf" {
+		writeError(w, http.StatusBadRequest, CodeBadRequest, "Invalid format: must be 'markdown' or 'pdf'")
+		return
+	}
+
+	// Verify ownership before exporting anything
+	owner, err := s.store.GetSessionOwner(ctx, sessionID)
+	if err != nil {
+		logger.Error("request failed", "operation", "get_session_owner", "error", err.Error())
+		writeError(w, http.StatusNotFound, CodeNotFound, "Session not found")
+		return
+	}
+	if owner != userID {
+		logger.Error("request failed", "operation", "verify_session_owner", "error", "unauthorized access to session")
+		writeError(w, http.StatusForbidden, CodeForbidden, "Forbidden: session belongs to another user")
+		return
+	}
+
+	messages, err := s.store.GetSessionMessages(ctx, userID, sessionID)
+	if err != nil {
+		logger.Error("request failed", "operation", "get_session_messages", "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to get session history")
+		return
+	}
+
+	transcript := export.Transcript{
+		SessionID:  sessionID,
+		ExportedAt: time.Now(),
+		Messages:   make([]export.Message, len(messages)),
+	}
+	for i, msg := range messages {
+		transcript.Messages[i] = export.Message{
+			Role:         msg.Role,
+			Content:      msg.Content,
+			ProviderMode: msg.ProviderMode,
+			Citations:    msg.Citations,
+			CreatedAt:    msg.CreatedAt,
+		}
+	}
+
+	filename := "session-" + sessionID
+
+	if format == "pdf" {
+		cfg, err := config.Load(s.configPath)
+		if err != nil {
+			logger.Error("request failed", "operation", "load_config", "error", err.Error())
+			writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to load config")
+			return
+		}
+
+		renderer, err := export.NewCommandPDFRenderer(cfg.Export.PDFCommand)
+		if err != nil {
+			logger.Warn("PDF export unavailable", "error", err.Error())
+			writeError(w, http.StatusNotImplemented, CodeNotImplemented, "PDF export is not available: "+err.Error())
+			return
+		}
+
+		pdf, err := renderer.RenderPDF(ctx, export.RenderHTML(transcript))
+		if err != nil {
+			logger.Error("request failed", "operation", "render_pdf", "error", err.Error())
+			writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to render PDF")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename+".pdf"))
+		w.Write(pdf)
+	} else {
+		w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename+".md"))
+		fmt.Fprint(w, export.RenderMarkdown(transcript))
+	}
+
+	// Audit log
+	s.store.AddAuditEntry(ctx, "export", fmt.Sprintf("Exported session %s as %s", sessionID, format), sessionID)
+
+	latency := time.Since(start).Milliseconds()
+	logger.Debug("request completed", "status", http.StatusOK, "latency_ms", latency, "session_id", sessionID, "format", format)
+}
@@ -0,0 +1,549 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"noodexx/internal/auth"
+	"noodexx/internal/extract"
+	"noodexx/internal/flags"
+	"noodexx/internal/skills"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Structured extraction limits
+const (
+	maxExtractionSources = 50       // Documents extracted in one run
+	maxRecordsLimit      = 500      // Records returned in one page
+	defaultRecordsLimit  = 100      // Records returned when no limit is given
+	maxRecordsExported   = 10000    // Records written to one CSV export
+	recordFieldPrefix    = "field." // Query parameter prefix of field filters
+)
+
+// extractionSchemaRequest is the body of POST /api/extraction/schemas
+type extractionSchemaRequest struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Schema      json.RawMessage `json:"schema"`
+}
+
+// handleGetExtractionSchemas handles GET /api/extraction/schemas - the user's record schemas
+func (s *Server) handleGetExtractionSchemas(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	requestID := newRequestID(w)
+
+	logger := s.logger.WithContext("request_id", requestID).
+		WithContext("method", r.Method).
+		WithContext("path", r.URL.Path)
+
+	logger.Debug("processing get extraction schemas request")
+
+	ctx := r.Context()
+
+	userID, err := auth.GetUserID(ctx)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
+		return
+	}
+
+	schemas, err := s.store.GetExtractionSchemas(ctx, userID)
+	if err != nil {
+		logger.Error("request failed", "operation", "get_extraction_schemas", "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to get extraction schemas")
+		return
+	}
+	if schemas == nil {
+		schemas = []ExtractionSchema{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"schemas": schemas,
+	})
+
+	latency := time.Since(start).Milliseconds()
+	logger.Debug("request completed", "status", http.StatusOK, "latency_ms", latency, "count", len(schemas))
+}
+
+// handleCreateExtractionSchema handles POST /api/extraction/schemas - define the
+// fields of a record type, such as an invoice's vendor, date and amount
+func (s *Server) handleCreateExtractionSchema(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	requestID := newRequestID(w)
+
+	logger := s.logger.WithContext("request_id", requestID).
+		WithContext("method", r.Method).
+		WithContext("path", r.URL.Path)
+
+	logger.Debug("processing create extraction schema request")
+
+	ctx := r.Context()
+
+	userID, err := auth.GetUserID(ctx)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req extractionSchemaRequest
+	if !decodeJSON(w, r, logger, &req) {
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		writeError(w, http.StatusBadRequest, CodeBadRequest, "name is required")
+		return
+	}
+	if len(req.Schema) == 0 {
+		writeError(w, http.StatusBadRequest, CodeBadRequest, "schema is required")
+		return
+	}
+	if _, err := extract.ParseSchema(req.Schema); err != nil {
+		writeError(w, http.StatusBadRequest, CodeValidationFailed, err.Error())
+		return
+	}
+
+	// Store the schema compacted so exports and prompts see one canonical form
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, req.Schema); err != nil {
+		writeError(w, http.StatusBadRequest, CodeValidationFailed, fmt.Sprintf("invalid schema: %v", err))
+		return
+	}
+
+	schemaID, err := s.store.CreateExtractionSchema(ctx, userID, req.Name, strings.TrimSpace(req.Description), compact.Bytes())
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			writeError(w, http.StatusConflict, CodeConflict, fmt.Sprintf("An extraction schema named %q already exists", req.Name))
+			return
+		}
+		logger.Error("request failed", "operation", "create_extraction_schema", "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to create extraction schema")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"id":      schemaID,
+	})
+
+	latency := time.Since(start).Milliseconds()
+	logger.Debug("request completed", "status", http.StatusCreated, "latency_ms", latency, "schema_id", schemaID)
+}
+
+// handleDeleteExtractionSchema handles DELETE /api/extraction/schemas/{id} -
+// delete a record schema and the records extracted with it
+func (s *Server) handleDeleteExtractionSchema(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	requestID := newRequestID(w)
+
+	logger := s.logger.WithContext("request_id", requestID).
+		WithContext("method", r.Method).
+		WithContext("path", r.URL.Path)
+
+	logger.Debug("processing delete extraction schema request")
+
+	ctx := r.Context()
+
+	userID, err := auth.GetUserID(ctx)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
+		return
+	}
+
+	schema, ok := s.userExtractionSchema(w, r, logger, userID, r.PathValue("id"))
+	if !ok {
+		return
+	}
+
+	if err := s.store.DeleteExtractionSchema(ctx, userID, schema.ID); err != nil {
+		logger.Error("request failed", "operation", "delete_extraction_schema", "schema_id", schema.ID, "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to delete extraction schema")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+	})
+
+	latency := time.Since(start).Milliseconds()
+	logger.Debug("request completed", "status", http.StatusOK, "latency_ms", latency, "schema_id", schema.ID)
+}
+
+// handleRunExtraction handles POST /api/extraction/schemas/{id}/run - extract a
+// record from each listed document with the active provider and store it,
+// replacing the document's earlier record for the schema
+func (s *Server) handleRunExtraction(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	requestID := newRequestID(w)
+
+	logger := s.logger.WithContext("request_id", requestID).
+		WithContext("method", r.Method).
+		WithContext("path", r.URL.Path)
+
+	logger.Debug("processing run extraction request")
+
+	ctx := r.Context()
+
+	userID, err := auth.GetUserID(ctx)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
+		return
+	}
+
+	if !s.featureEnabled(ctx, flags.StructuredExtraction) {
+		writeError(w, http.StatusForbidden, CodeForbidden, "Structured extraction is not enabled")
+		return
+	}
+
+	schema, ok := s.userExtractionSchema(w, r, logger, userID, r.PathValue("id"))
+	if !ok {
+		return
+	}
+	parsed, err := extract.ParseSchema(schema.Schema)
+	if err != nil {
+		logger.Error("request failed", "operation", "parse_extraction_schema", "schema_id", schema.ID, "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Stored extraction schema is invalid")
+		return
+	}
+
+	var req struct {
+		Sources []string `json:"sources"`
+	}
+	if !decodeJSON(w, r, logger, &req) {
+		return
+	}
+	sources := req.Sources[:0]
+	seen := make(map[string]bool)
+	for _, source := range req.Sources {
+		if source = strings.TrimSpace(source); source != "" && !seen[source] {
+			sources = append(sources, source)
+			seen[source] = true
+		}
+	}
+	if len(sources) == 0 || len(sources) > maxExtractionSources {
+		writeError(w, http.StatusBadRequest, CodeBadRequest, fmt.Sprintf("sources must list between 1 and %d documents", maxExtractionSources))
+		return
+	}
+
+	// Extraction sends document content to the provider, so the RAG policy applies
+	if !s.ragEnforcer.ShouldPerformRAG() {
+		writeError(w, http.StatusForbidden, CodeForbidden, "Extracting records from the library is not allowed by the RAG policy of the current provider")
+		return
+	}
+
+	provider, err := s.providerManager.GetActiveProvider()
+	if err != nil {
+		logger.Error("request failed", "operation", "get_active_provider", "error", err.Error())
+		writeError(w, http.StatusBadRequest, CodeProviderUnavailable, "Provider not configured. Please configure the AI provider in Settings.")
+		return
+	}
+
+	// Extraction copies values out of the text, so sample as little as possible
+	temperature := 0.0
+	opts, err := s.resolveGenerationOptions(ctx, logger, userID, GenerationOptions{Temperature: &temperature})
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeBadRequest, err.Error())
+		return
+	}
+
+	extractor := &extract.Extractor{
+		Schema:    parsed,
+		Generator: &extractionGenerator{provider: provider, opts: opts},
+	}
+	model := s.activeModel()
+
+	records := make([]StructuredRecord, 0, len(sources))
+	counts := make(map[string]int)
+	for _, source := range sources {
+		record := StructuredRecord{SchemaID: schema.ID, Source: source, Model: model}
+
+		chunks, err := s.store.GetSourceChunks(ctx, userID, source)
+		switch {
+		case err != nil:
+			logger.Warn("failed to get document chunks", "source", source, "error", err.Error())
+			record.Status, record.Error = extract.StatusFailed, "failed to read document"
+		case len(chunks) == 0:
+			record.Status, record.Error = extract.StatusFailed, "document not found"
+		default:
+			texts := make([]string, len(chunks))
+			for i, chunk := range chunks {
+				texts[i] = chunk.Text
+			}
+			s.recordSensitiveAccess(ctx, logger, userID, "", "Extraction: "+schema.Name, provider, chunks)
+			result := extractor.Extract(ctx, source, packByBudget(texts, s.docQABudgetTokens()))
+			record.Data, record.Status, record.Error = result.Record, result.Status, result.Error
+			for _, v := range result.Violations {
+				record.Violations = append(record.Violations, SkillViolation{Path: v.Path, Message: v.Message})
+			}
+		}
+		if ctx.Err() != nil {
+			// The client went away; keep what was extracted so far
+			logger.Warn("extraction cancelled", "schema_id", schema.ID, "extracted", len(records))
+			return
+		}
+		if record.Data == nil {
+			record.Data = map[string]interface{}{}
+		}
+
+		record.ID, err = s.store.SaveStructuredRecord(ctx, userID, &record)
+		if err != nil {
+			logger.Error("request failed", "operation", "save_structured_record", "schema_id", schema.ID, "source", source, "error", err.Error())
+			writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to save extracted record")
+			return
+		}
+		record.ExtractedAt = time.Now()
+		records = append(records, record)
+		counts[record.Status]++
+	}
+
+	s.store.AddAuditEntry(ctx, "extraction", fmt.Sprintf("Extracted %d %q records: %d ok, %d invalid, %d failed",
+		len(records), schema.Name, counts[extract.StatusOK], counts[extract.StatusInvalid], counts[extract.StatusFailed]), "")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"records": records,
+		"ok":      counts[extract.StatusOK],
+		"invalid": counts[extract.StatusInvalid],
+		"failed":  counts[extract.StatusFailed],
+	})
+
+	latency := time.Since(start).Milliseconds()
+	logger.Debug("request completed", "status", http.StatusOK, "latency_ms", latency, "schema_id", schema.ID, "records", len(records))
+}
+
+// handleGetRecords handles GET /api/records - a page of one schema's records,
+// filtered by status, source and field values
+func (s *Server) handleGetRecords(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	requestID := newRequestID(w)
+
+	logger := s.logger.WithContext("request_id", requestID).
+		WithContext("method", r.Method).
+		WithContext("path", r.URL.Path)
+
+	logger.Debug("processing get records request")
+
+	ctx := r.Context()
+
+	userID, err := auth.GetUserID(ctx)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
+		return
+	}
+
+	filter, _, ok := s.recordFilter(w, r, logger, userID)
+	if !ok {
+		return
+	}
+
+	query := r.URL.Query()
+	filter.Limit = defaultRecordsLimit
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > maxRecordsLimit {
+			writeError(w, http.StatusBadRequest, CodeBadRequest, fmt.Sprintf("limit must be between 1 and %d", maxRecordsLimit))
+			return
+		}
+		filter.Limit = n
+	}
+	if v := query.Get("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			writeError(w, http.StatusBadRequest, CodeBadRequest, "offset must be a non-negative integer")
+			return
+		}
+		filter.Offset = n
+	}
+
+	records, total, err := s.store.GetStructuredRecords(ctx, userID, filter)
+	if err != nil {
+		logger.Error("request failed", "operation", "get_structured_records", "schema_id", filter.SchemaID, "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to get records")
+		return
+	}
+	if records == nil {
+		records = []StructuredRecord{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"records": records,
+		"total":   total,
+	})
+
+	latency := time.Since(start).Milliseconds()
+	logger.Debug("request completed", "status", http.StatusOK, "latency_ms", latency, "schema_id", filter.SchemaID, "count", len(records))
+}
+
+// handleExportRecords handles GET /api/records/export - the filtered records of
+// one schema as CSV, one column per schema property
+func (s *Server) handleExportRecords(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	requestID := newRequestID(w)
+
+	logger := s.logger.WithContext("request_id", requestID).
+		WithContext("method", r.Method).
+		WithContext("path", r.URL.Path)
+
+	logger.Debug("processing export records request")
+
+	ctx := r.Context()
+
+	userID, err := auth.GetUserID(ctx)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
+		return
+	}
+
+	filter, parsed, ok := s.recordFilter(w, r, logger, userID)
+	if !ok {
+		return
+	}
+
+	filter.Limit = maxRecordsExported
+	records, total, err := s.store.GetStructuredRecords(ctx, userID, filter)
+	if err != nil {
+		logger.Error("request failed", "operation", "get_structured_records", "schema_id", filter.SchemaID, "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to export records")
+		return
+	}
+	if total > len(records) {
+		logger.Warn("record export truncated", "schema_id", filter.SchemaID, "total", total, "exported", len(records))
+	}
+
+	columns := extract.Columns(parsed)
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fmt.Sprintf("records-%d.csv", filter.SchemaID)))
+
+	cw := csv.NewWriter(w)
+	cw.Write(append([]string{"source", "status"}, columns...))
+	for _, record := range records {
+		row := make([]string, 0, len(columns)+2)
+		row = append(row, record.Source, record.Status)
+		for _, column := range columns {
+			row = append(row, csvValue(record.Data[column]))
+		}
+		cw.Write(row)
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		logger.Warn("failed to write record export", "schema_id", filter.SchemaID, "error", err.Error())
+	}
+
+	latency := time.Since(start).Milliseconds()
+	logger.Debug("request completed", "status", http.StatusOK, "latency_ms", latency, "schema_id", filter.SchemaID, "count", len(records))
+}
+
+// recordFilter builds the filter of a records request from its query, writing
+// an error response when the schema is not one of the user's or a field filter
+// names a field the schema does not have. It also returns the parsed schema
+func (s *Server) recordFilter(w http.ResponseWriter, r *http.Request, logger Logger, userID int64) (RecordFilter, *skills.Schema, bool) {
+	query := r.URL.Query()
+	if query.Get("schema_id") == "" {
+		writeError(w, http.StatusBadRequest, CodeBadRequest, "schema_id is required")
+		return RecordFilter{}, nil, false
+	}
+	schema, ok := s.userExtractionSchema(w, r, logger, userID, query.Get("schema_id"))
+	if !ok {
+		return RecordFilter{}, nil, false
+	}
+	parsed, err := extract.ParseSchema(schema.Schema)
+	if err != nil {
+		logger.Error("request failed", "operation", "parse_extraction_schema", "schema_id", schema.ID, "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Stored extraction schema is invalid")
+		return RecordFilter{}, nil, false
+	}
+
+	filter := RecordFilter{
+		SchemaID: schema.ID,
+		Status:   query.Get("status"),
+		Source:   strings.TrimSpace(query.Get("source")),
+	}
+	switch filter.Status {
+	case "", extract.StatusOK, extract.StatusInvalid, extract.StatusFailed:
+	default:
+		writeError(w, http.StatusBadRequest, CodeBadRequest, "status must be ok, invalid or failed")
+		return RecordFilter{}, nil, false
+	}
+
+	for key, values := range query {
+		field, ok := strings.CutPrefix(key, recordFieldPrefix)
+		if !ok {
+			continue
+		}
+		if _, known := parsed.Properties[field]; !known {
+			writeError(w, http.StatusBadRequest, CodeBadRequest, fmt.Sprintf("schema %q has no field %q", schema.Name, field))
+			return RecordFilter{}, nil, false
+		}
+		if filter.Fields == nil {
+			filter.Fields = make(map[string]string)
+		}
+		filter.Fields[field] = values[0]
+	}
+	return filter, parsed, true
+}
+
+// userExtractionSchema finds the record schema with the given ID, writing an
+// error response when it is not one of the user's
+func (s *Server) userExtractionSchema(w http.ResponseWriter, r *http.Request, logger Logger, userID int64, id string) (*ExtractionSchema, bool) {
+	schemaID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil || schemaID <= 0 {
+		writeError(w, http.StatusBadRequest, CodeBadRequest, "Invalid schema ID")
+		return nil, false
+	}
+
+	schema, err := s.store.GetExtractionSchema(r.Context(), userID, schemaID)
+	if err != nil {
+		logger.Error("request failed", "operation", "get_extraction_schema", "schema_id", schemaID, "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to get extraction schema")
+		return nil, false
+	}
+	if schema == nil {
+		writeError(w, http.StatusNotFound, CodeNotFound, "Extraction schema not found")
+		return nil, false
+	}
+	return schema, true
+}
+
+// csvValue formats a record field for a CSV cell: text as is, other values as
+// JSON, and missing fields empty
+func csvValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return string(data)
+	}
+}
+
+// extractionGenerator sends extraction prompts to the provider
+type extractionGenerator struct {
+	provider LLMProvider
+	opts     GenerationOptions
+}
+
+func (eg *extractionGenerator) Generate(ctx context.Context, prompt string) (string, error) {
+	var buf bytes.Buffer
+	messages := []Message{
+		{Role: "system", Content: "You extract structured data from documents. You reply with a single JSON object and nothing else."},
+		{Role: "user", Content: prompt},
+	}
+	return eg.provider.StreamWithOptions(ctx, messages, eg.opts, &buf)
+}
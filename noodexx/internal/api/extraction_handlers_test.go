@@ -0,0 +1,207 @@
+package api
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const testInvoiceSchema = `{"type":"object","properties":{"vendor":{"type":"string"},"amount":{"type":"number"},"items":{"type":"array","items":{"type":"string"}}},"required":["vendor","amount"]}`
+
+// mockStoreForExtraction holds schema 1 for user 1, the documents a.pdf and
+// b.pdf, and keeps saved records in memory
+type mockStoreForExtraction struct {
+	mockStore
+	records []StructuredRecord
+	filter  RecordFilter
+}
+
+func (m *mockStoreForExtraction) CreateExtractionSchema(ctx context.Context, userID int64, name, description string, schema json.RawMessage) (int64, error) {
+	if name == "Invoices" {
+		return 0, fmt.Errorf("failed to create extraction schema: UNIQUE constraint failed: extraction_schemas.user_id, extraction_schemas.name")
+	}
+	return 2, nil
+}
+
+func (m *mockStoreForExtraction) GetExtractionSchema(ctx context.Context, userID, schemaID int64) (*ExtractionSchema, error) {
+	if userID != 1 || schemaID != 1 {
+		return nil, nil
+	}
+	return &ExtractionSchema{ID: 1, Name: "Invoices", Schema: json.RawMessage(testInvoiceSchema)}, nil
+}
+
+func (m *mockStoreForExtraction) GetSourceChunks(ctx context.Context, userID int64, source string) ([]Chunk, error) {
+	switch source {
+	case "a.pdf":
+		return []Chunk{{Source: source, Text: "Invoice from Acme, total 120.50"}}, nil
+	case "b.pdf":
+		return []Chunk{{Source: source, Text: "Invoice from Globex"}}, nil
+	}
+	return nil, nil
+}
+
+func (m *mockStoreForExtraction) SaveStructuredRecord(ctx context.Context, userID int64, record *StructuredRecord) (int64, error) {
+	m.records = append(m.records, *record)
+	return int64(len(m.records)), nil
+}
+
+func (m *mockStoreForExtraction) GetStructuredRecords(ctx context.Context, userID int64, filter RecordFilter) ([]StructuredRecord, int, error) {
+	m.filter = filter
+	return m.records, len(m.records), nil
+}
+
+func newExtractionTestServer(store *mockStoreForExtraction, rag *mockRAGEnforcerForAsk) *Server {
+	provider := &mockProviderForAsk{
+		name:    "ollama",
+		isLocal: true,
+		streamFunc: func(ctx context.Context, messages []Message, w io.Writer) (string, error) {
+			prompt := messages[len(messages)-1].Content
+			switch {
+			case strings.Contains(prompt, "Acme"):
+				return `{"vendor": "Acme", "amount": 120.5, "items": ["bolts"]}`, nil
+			case strings.Contains(prompt, "Globex"):
+				return `{"vendor": "Globex", "amount": null}`, nil
+			}
+			return "no record", nil
+		},
+	}
+	return &Server{
+		store:           store,
+		logger:          &mockLogger{},
+		providerManager: &mockProviderManagerForAsk{provider: provider, providerName: "Ollama"},
+		ragEnforcer:     rag,
+	}
+}
+
+func TestHandleCreateExtractionSchema(t *testing.T) {
+	server := newExtractionTestServer(&mockStoreForExtraction{}, &mockRAGEnforcerForAsk{shouldPerformRAG: true})
+
+	create := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/extraction/schemas", strings.NewReader(body))
+		return serveRoute(server, withUser(req, 1))
+	}
+
+	for _, body := range []string{
+		`{"name":"","schema":` + testInvoiceSchema + `}`,
+		`{"name":"Receipts"}`,
+		`{"name":"Receipts","schema":{"type":"string"}}`,
+		`{"name":"Receipts","schema":{"type":"object","properties":{"total":{"type":"money"}}}}`,
+	} {
+		if w := create(body); w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for %s, got %d", body, w.Code)
+		}
+	}
+
+	if w := create(`{"name":"Invoices","schema":` + testInvoiceSchema + `}`); w.Code != http.StatusConflict {
+		t.Errorf("Expected status 409 for a duplicate name, got %d", w.Code)
+	}
+	if w := create(`{"name":"Receipts","schema":` + testInvoiceSchema + `}`); w.Code != http.StatusCreated {
+		t.Errorf("Expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestHandleRunExtraction(t *testing.T) {
+	store := &mockStoreForExtraction{}
+	rag := &mockRAGEnforcerForAsk{shouldPerformRAG: true}
+	server := newExtractionTestServer(store, rag)
+
+	run := func(path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		return serveRoute(server, withUser(req, 1))
+	}
+
+	w := run("/api/extraction/schemas/1/run", `{"sources":["a.pdf","b.pdf","missing.pdf","a.pdf"]}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Records []StructuredRecord `json:"records"`
+		OK      int                `json:"ok"`
+		Invalid int                `json:"invalid"`
+		Failed  int                `json:"failed"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(resp.Records) != 3 || resp.OK != 1 || resp.Invalid != 1 || resp.Failed != 1 {
+		t.Fatalf("Expected one record of each status for the three distinct sources, got %s", w.Body.String())
+	}
+	if len(store.records) != 3 || store.records[0].Data["vendor"] != "Acme" || store.records[0].SchemaID != 1 {
+		t.Errorf("Expected the records to be saved, got %+v", store.records)
+	}
+	if invalid := store.records[1]; invalid.Status != "invalid" || len(invalid.Violations) != 1 || invalid.Violations[0].Path != "$.amount" {
+		t.Errorf("Expected the missing amount to be reported, got %+v", invalid)
+	}
+	if failed := store.records[2]; failed.Status != "failed" || failed.Error != "document not found" {
+		t.Errorf("Expected the missing document to fail, got %+v", failed)
+	}
+
+	if w := run("/api/extraction/schemas/1/run", `{"sources":[" "]}`); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 without sources, got %d", w.Code)
+	}
+	if w := run("/api/extraction/schemas/9/run", `{"sources":["a.pdf"]}`); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for an unknown schema, got %d", w.Code)
+	}
+
+	// The RAG policy keeps library content away from providers it excludes
+	rag.shouldPerformRAG = false
+	if w := run("/api/extraction/schemas/1/run", `{"sources":["a.pdf"]}`); w.Code != http.StatusForbidden {
+		t.Errorf("Expected status 403 under a no-RAG policy, got %d", w.Code)
+	}
+}
+
+func TestHandleGetAndExportRecords(t *testing.T) {
+	store := &mockStoreForExtraction{records: []StructuredRecord{
+		{ID: 1, SchemaID: 1, Source: "a.pdf", Status: "ok", Data: map[string]interface{}{"vendor": "Acme, Inc.", "amount": 120.5, "items": []interface{}{"bolts", "nuts"}}},
+		{ID: 2, SchemaID: 1, Source: "b.pdf", Status: "invalid", Data: map[string]interface{}{"vendor": "Globex"}},
+	}}
+	server := newExtractionTestServer(store, &mockRAGEnforcerForAsk{shouldPerformRAG: true})
+
+	get := func(path string) *httptest.ResponseRecorder {
+		return serveRoute(server, withUser(httptest.NewRequest(http.MethodGet, path, nil), 1))
+	}
+
+	w := get("/api/records?schema_id=1&status=ok&source=a&field.vendor=Acme&limit=10&offset=5")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"total":2`) {
+		t.Fatalf("Expected the records, got %d: %s", w.Code, w.Body.String())
+	}
+	f := store.filter
+	if f.SchemaID != 1 || f.Status != "ok" || f.Source != "a" || f.Fields["vendor"] != "Acme" || f.Limit != 10 || f.Offset != 5 {
+		t.Errorf("Expected the query to become the filter, got %+v", f)
+	}
+
+	for path, code := range map[string]int{
+		"/api/records":                        http.StatusBadRequest,
+		"/api/records?schema_id=9":            http.StatusNotFound,
+		"/api/records?schema_id=1&status=new": http.StatusBadRequest,
+		"/api/records?schema_id=1&field.tax=": http.StatusBadRequest,
+		"/api/records?schema_id=1&limit=5000": http.StatusBadRequest,
+	} {
+		if w := get(path); w.Code != code {
+			t.Errorf("Expected status %d for %s, got %d", code, path, w.Code)
+		}
+	}
+
+	w = get("/api/records/export?schema_id=1")
+	if w.Code != http.StatusOK || !strings.Contains(w.Header().Get("Content-Disposition"), "records-1.csv") {
+		t.Fatalf("Expected a CSV attachment, got %d: %v", w.Code, w.Header())
+	}
+	rows, err := csv.NewReader(w.Body).ReadAll()
+	if err != nil {
+		t.Fatalf("Failed to read CSV: %v", err)
+	}
+	want := [][]string{
+		{"source", "status", "vendor", "amount", "items"},
+		{"a.pdf", "ok", "Acme, Inc.", "120.5", `["bolts","nuts"]`},
+		{"b.pdf", "invalid", "Globex", "", ""},
+	}
+	if fmt.Sprint(rows) != fmt.Sprint(want) {
+		t.Errorf("Expected %v, got %v", want, rows)
+	}
+}
@@ -0,0 +1,35 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// handleExtractors handles GET /api/admin/extractors - the external programs
+// that convert files into text, and the types each is registered for (admin only)
+func (s *Server) handleExtractors(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	requestID := newRequestID(w)
+
+	// Create logger with request context
+	logger := s.logger.WithContext("request_id", requestID).
+		WithContext("method", r.Method).
+		WithContext("path", r.URL.Path)
+
+	logger.Debug("processing extractors request")
+
+	extractors := []ExtractorInfo{}
+	if s.extractors != nil {
+		extractors = s.extractors.Extractors()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":    true,
+		"extractors": extractors,
+	})
+
+	latency := time.Since(start).Milliseconds()
+	logger.Debug("request completed", "latency_ms", latency, "extractors", len(extractors))
+}
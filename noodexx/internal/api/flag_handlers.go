@@ -0,0 +1,233 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"noodexx/internal/auth"
+	"noodexx/internal/flags"
+	"time"
+)
+
+// FeatureFlag is a feature flag's configured state and its per-user overrides
+type FeatureFlag struct {
+	Name           string         `json:"name"`
+	Description    string         `json:"description"`
+	Enabled        bool           `json:"enabled"`
+	RolloutPercent int            `json:"rollout_percent"` // 0 means every user
+	Overrides      []FlagOverride `json:"overrides"`
+}
+
+// featureEnabled reports whether a feature is on for the request's user
+// Every feature is on when no flags are configured
+func (s *Server) featureEnabled(ctx context.Context, name string) bool {
+	if s.flags == nil {
+		return true
+	}
+	userID, _ := auth.GetUserID(ctx)
+	return s.flags.Enabled(ctx, name, userID)
+}
+
+// handleGetFlags handles GET /api/flags - which features are on for the current user
+func (s *Server) handleGetFlags(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	requestID := newRequestID(w)
+
+	logger := s.logger.WithContext("request_id", requestID).
+		WithContext("method", r.Method).
+		WithContext("path", r.URL.Path)
+
+	ctx := r.Context()
+
+	userID, err := auth.GetUserID(ctx)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
+		return
+	}
+
+	enabled := make(map[string]bool, len(flags.Known))
+	if s.flags != nil {
+		enabled = s.flags.ForUser(ctx, userID)
+	} else {
+		for name := range flags.Known {
+			enabled[name] = true
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"flags":   enabled,
+	})
+
+	latency := time.Since(start).Milliseconds()
+	logger.Debug("request completed", "status", http.StatusOK, "latency_ms", latency)
+}
+
+// handleAdminFlags handles GET /api/admin/flags - every feature flag with its
+// config and overrides (admin only)
+func (s *Server) handleAdminFlags(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	requestID := newRequestID(w)
+
+	logger := s.logger.WithContext("request_id", requestID).
+		WithContext("method", r.Method).
+		WithContext("path", r.URL.Path)
+
+	logger.Debug("processing admin flags request")
+
+	ctx := r.Context()
+
+	if s.flags == nil {
+		writeError(w, http.StatusNotImplemented, CodeNotImplemented, "Feature flags are not enabled")
+		return
+	}
+
+	overrides, err := s.store.GetFlagOverrides(ctx)
+	if err != nil {
+		logger.Error("request failed", "operation", "get_flag_overrides", "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to get flag overrides")
+		return
+	}
+
+	byFlag := make(map[string][]FlagOverride)
+	for _, override := range overrides {
+		byFlag[override.Flag] = append(byFlag[override.Flag], override)
+	}
+
+	names := flags.Names()
+	result := make([]FeatureFlag, 0, len(names))
+	for _, name := range names {
+		config := s.flags.Config(name)
+		flag := FeatureFlag{
+			Name:           name,
+			Description:    flags.Known[name],
+			Enabled:        config.Enabled,
+			RolloutPercent: config.RolloutPercent,
+			Overrides:      byFlag[name],
+		}
+		if flag.Overrides == nil {
+			flag.Overrides = []FlagOverride{}
+		}
+		result = append(result, flag)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"flags":   result,
+	})
+
+	latency := time.Since(start).Milliseconds()
+	logger.Debug("request completed", "status", http.StatusOK, "latency_ms", latency, "overrides", len(overrides))
+}
+
+// handleSetFlagOverride handles PUT /api/admin/flags/{name}/users/{id} - turn a
+// feature on or off for one user regardless of the config (admin only)
+func (s *Server) handleSetFlagOverride(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	requestID := newRequestID(w)
+
+	logger := s.logger.WithContext("request_id", requestID).
+		WithContext("method", r.Method).
+		WithContext("path", r.URL.Path)
+
+	logger.Debug("processing set flag override request")
+
+	ctx := r.Context()
+
+	name, targetUser, ok := s.flagOverrideTarget(w, r, logger)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Enabled *bool `json:"enabled"`
+	}
+	if !decodeJSON(w, r, logger, &req) {
+		return
+	}
+	if req.Enabled == nil {
+		writeError(w, http.StatusBadRequest, CodeBadRequest, "enabled is required")
+		return
+	}
+
+	if err := s.store.SetFlagOverride(ctx, targetUser.ID, name, *req.Enabled); err != nil {
+		logger.Error("request failed", "operation", "set_flag_override", "flag", name, "target_user_id", targetUser.ID, "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to set flag override")
+		return
+	}
+	s.store.AddAuditEntry(ctx, "config", fmt.Sprintf("Feature flag %s set to %t for %s (ID %d)", name, *req.Enabled, targetUser.Username, targetUser.ID), "")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+	})
+
+	latency := time.Since(start).Milliseconds()
+	logger.Debug("request completed", "status", http.StatusOK, "latency_ms", latency, "flag", name, "target_user_id", targetUser.ID, "enabled", *req.Enabled)
+}
+
+// handleDeleteFlagOverride handles DELETE /api/admin/flags/{name}/users/{id} -
+// remove a user's override so the flag follows the config again (admin only)
+func (s *Server) handleDeleteFlagOverride(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	requestID := newRequestID(w)
+
+	logger := s.logger.WithContext("request_id", requestID).
+		WithContext("method", r.Method).
+		WithContext("path", r.URL.Path)
+
+	logger.Debug("processing delete flag override request")
+
+	ctx := r.Context()
+
+	name, targetUser, ok := s.flagOverrideTarget(w, r, logger)
+	if !ok {
+		return
+	}
+
+	if err := s.store.DeleteFlagOverride(ctx, targetUser.ID, name); err != nil {
+		logger.Error("request failed", "operation", "delete_flag_override", "flag", name, "target_user_id", targetUser.ID, "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to delete flag override")
+		return
+	}
+	s.store.AddAuditEntry(ctx, "config", fmt.Sprintf("Feature flag %s override removed for %s (ID %d)", name, targetUser.Username, targetUser.ID), "")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+	})
+
+	latency := time.Since(start).Milliseconds()
+	logger.Debug("request completed", "status", http.StatusOK, "latency_ms", latency, "flag", name, "target_user_id", targetUser.ID)
+}
+
+// flagOverrideTarget reads the flag {name} and user {id} of an override route,
+// writing an error response when either does not exist
+func (s *Server) flagOverrideTarget(w http.ResponseWriter, r *http.Request, logger Logger) (string, *User, bool) {
+	if s.flags == nil {
+		writeError(w, http.StatusNotImplemented, CodeNotImplemented, "Feature flags are not enabled")
+		return "", nil, false
+	}
+
+	name := r.PathValue("name")
+	if _, ok := flags.Known[name]; !ok {
+		writeError(w, http.StatusNotFound, CodeNotFound, "Feature flag not found")
+		return "", nil, false
+	}
+
+	targetUserID, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeBadRequest, "Invalid user ID")
+		return "", nil, false
+	}
+	targetUser, err := s.store.GetUserByID(r.Context(), targetUserID)
+	if err != nil {
+		logger.Warn("target user not found", "target_user_id", targetUserID)
+		writeError(w, http.StatusNotFound, CodeNotFound, "User not found")
+		return "", nil, false
+	}
+	return name, targetUser, true
+}
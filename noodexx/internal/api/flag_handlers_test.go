@@ -0,0 +1,133 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"noodexx/internal/flags"
+	"strings"
+	"testing"
+)
+
+// mockStoreForFlags keeps flag overrides in memory
+type mockStoreForFlags struct {
+	mockStoreForAdmin
+	overrides map[int64]map[string]bool
+}
+
+func (m *mockStoreForFlags) GetUserFlagOverrides(ctx context.Context, userID int64) (map[string]bool, error) {
+	return m.overrides[userID], nil
+}
+
+func (m *mockStoreForFlags) GetFlagOverrides(ctx context.Context) ([]FlagOverride, error) {
+	var overrides []FlagOverride
+	for userID, userOverrides := range m.overrides {
+		for flag, enabled := range userOverrides {
+			overrides = append(overrides, FlagOverride{UserID: userID, Flag: flag, Enabled: enabled})
+		}
+	}
+	return overrides, nil
+}
+
+func (m *mockStoreForFlags) SetFlagOverride(ctx context.Context, userID int64, flag string, enabled bool) error {
+	if m.overrides[userID] == nil {
+		m.overrides[userID] = map[string]bool{}
+	}
+	m.overrides[userID][flag] = enabled
+	return nil
+}
+
+func (m *mockStoreForFlags) DeleteFlagOverride(ctx context.Context, userID int64, flag string) error {
+	delete(m.overrides[userID], flag)
+	return nil
+}
+
+func TestFlagHandlers(t *testing.T) {
+	store := &mockStoreForFlags{overrides: map[int64]map[string]bool{}}
+	server := &Server{store: store, logger: &mockLogger{}}
+	server.SetFeatureFlags(flags.New(map[string]flags.Flag{flags.WebSearch: {Enabled: false}}, store))
+
+	request := func(method, path, body string, userID int64) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		return serveRoute(server, withUser(req, userID))
+	}
+	userFlags := func(userID int64) map[string]bool {
+		t.Helper()
+		w := request(http.MethodGet, "/api/flags", "", userID)
+		var resp struct {
+			Flags map[string]bool `json:"flags"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("Failed to decode flags: %v", err)
+		}
+		return resp.Flags
+	}
+
+	if f := userFlags(2); f[flags.WebSearch] || !f[flags.Rerank] {
+		t.Errorf("Expected the configured flags, got %v", f)
+	}
+
+	// An admin turns web search on for user 2 only
+	if w := request(http.MethodPut, "/api/admin/flags/web_search/users/2", `{"enabled":true}`, 1); w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if !userFlags(2)[flags.WebSearch] || userFlags(3)[flags.WebSearch] {
+		t.Error("Expected the override to apply to user 2 only")
+	}
+
+	w := request(http.MethodGet, "/api/admin/flags", "", 1)
+	var resp struct {
+		Flags []FeatureFlag `json:"flags"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode admin flags: %v", err)
+	}
+	if len(resp.Flags) != len(flags.Known) {
+		t.Fatalf("Expected every known flag, got %+v", resp.Flags)
+	}
+	for _, flag := range resp.Flags {
+		if flag.Name == flags.WebSearch && (flag.Enabled || len(flag.Overrides) != 1 || flag.Overrides[0].UserID != 2) {
+			t.Errorf("Expected web_search off with one override, got %+v", flag)
+		}
+	}
+
+	// Removing the override returns user 2 to the config
+	if w := request(http.MethodDelete, "/api/admin/flags/web_search/users/2", "", 1); w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if userFlags(2)[flags.WebSearch] {
+		t.Error("Expected web search off again after removing the override")
+	}
+
+	if w := request(http.MethodPut, "/api/admin/flags/teleport/users/2", `{"enabled":true}`, 1); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for an unknown flag, got %d", w.Code)
+	}
+	if w := request(http.MethodPut, "/api/admin/flags/web_search/users/2", `{}`, 1); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 without enabled, got %d", w.Code)
+	}
+	if w := request(http.MethodPut, "/api/admin/flags/web_search/users/2", `{"enabled":true}`, 2); w.Code != http.StatusForbidden {
+		t.Errorf("Expected status 403 for a non-admin, got %d", w.Code)
+	}
+}
+
+func TestFeatureFlagGates(t *testing.T) {
+	store := &mockStoreForFlags{overrides: map[int64]map[string]bool{2: {flags.Rerank: true}}}
+	server := &Server{store: store, logger: &mockLogger{}}
+	server.SetReranker(&mockReranker{})
+	ctx := withUser(httptest.NewRequest(http.MethodGet, "/", nil), 1).Context()
+
+	// Without flags every feature is on
+	if server.activeReranker(ctx) == nil {
+		t.Error("Expected reranking without feature flags")
+	}
+
+	server.SetFeatureFlags(flags.New(map[string]flags.Flag{flags.Rerank: {Enabled: false}}, store))
+	if server.activeReranker(ctx) != nil || server.libraryCandidates(ctx) != librarySearchTopK {
+		t.Error("Expected no reranking with the rerank flag off")
+	}
+	ctx = withUser(httptest.NewRequest(http.MethodGet, "/", nil), 2).Context()
+	if server.activeReranker(ctx) == nil {
+		t.Error("Expected reranking for a user with an override")
+	}
+}
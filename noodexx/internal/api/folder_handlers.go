@@ -0,0 +1,189 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"noodexx/internal/auth"
+	"time"
+)
+
+// defaultQuarantineAfter matches watcher.DefaultQuarantineAfter, for servers
+// running without a watcher
+const defaultQuarantineAfter = 3
+
+// FolderRetryResult is the outcome of retrying one watched file
+type FolderRetryResult struct {
+	Path     string `json:"path"`
+	Ingested bool   `json:"ingested"`
+	Error    string `json:"error,omitempty"`
+}
+
+// handleGetFolderErrors handles GET /api/folders/{id}/errors - list the files of
+// one of the user's watched folders that failed to ingest
+func (s *Server) handleGetFolderErrors(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	requestID := newRequestID(w)
+
+	logger := s.logger.WithContext("request_id", requestID).
+		WithContext("method", r.Method).
+		WithContext("path", r.URL.Path)
+
+	logger.Debug("processing get folder errors request")
+
+	ctx := r.Context()
+
+	userID, err := auth.GetUserID(ctx)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
+		return
+	}
+
+	folder, ok := s.userFolder(w, r, logger, userID)
+	if !ok {
+		return
+	}
+
+	failures, err := s.folderErrors(r, userID, folder)
+	if err != nil {
+		logger.Error("request failed", "operation", "get_ingest_failures", "folder_id", folder.ID, "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to get folder errors")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":          true,
+		"folder_id":        folder.ID,
+		"folder":           folder.Path,
+		"quarantine_after": s.quarantineLimit(),
+		"errors":           failures,
+	})
+
+	latency := time.Since(start).Milliseconds()
+	logger.Debug("request completed", "status", http.StatusOK, "latency_ms", latency, "folder_id", folder.ID, "count", len(failures))
+}
+
+// handleRetryFolderErrors handles POST /api/folders/{id}/errors/retry - ingest a
+// failed file again, or every failed file in the folder when no path is given
+func (s *Server) handleRetryFolderErrors(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	requestID := newRequestID(w)
+
+	logger := s.logger.WithContext("request_id", requestID).
+		WithContext("method", r.Method).
+		WithContext("path", r.URL.Path)
+
+	logger.Debug("processing retry folder errors request")
+
+	ctx := r.Context()
+
+	userID, err := auth.GetUserID(ctx)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
+		return
+	}
+
+	if s.folderRetrier == nil {
+		writeError(w, http.StatusNotImplemented, CodeNotImplemented, "Folder watching is not available")
+		return
+	}
+
+	var req struct {
+		Path string `json:"path"` // Empty retries every failed file
+	}
+	if !decodeJSON(w, r, logger, &req) {
+		return
+	}
+
+	folder, ok := s.userFolder(w, r, logger, userID)
+	if !ok {
+		return
+	}
+
+	failures, err := s.folderErrors(r, userID, folder)
+	if err != nil {
+		logger.Error("request failed", "operation", "get_ingest_failures", "folder_id", folder.ID, "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to get folder errors")
+		return
+	}
+
+	var paths []string
+	for _, f := range failures {
+		if req.Path == "" || f.Path == req.Path {
+			paths = append(paths, f.Path)
+		}
+	}
+	if req.Path != "" && len(paths) == 0 {
+		writeError(w, http.StatusNotFound, CodeNotFound, "File has no ingest errors in this folder")
+		return
+	}
+
+	results := make([]FolderRetryResult, 0, len(paths))
+	for _, path := range paths {
+		result := FolderRetryResult{Path: path, Ingested: true}
+		if err := s.folderRetrier.Retry(ctx, userID, path); err != nil {
+			result.Ingested = false
+			result.Error = err.Error()
+		}
+		results = append(results, result)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"results": results,
+	})
+
+	latency := time.Since(start).Milliseconds()
+	logger.Debug("request completed", "status", http.StatusOK, "latency_ms", latency, "folder_id", folder.ID, "retried", len(results))
+}
+
+// userFolder finds the watched folder named by the request's {id}, writing an
+// error response when it is not one of the user's
+func (s *Server) userFolder(w http.ResponseWriter, r *http.Request, logger Logger, userID int64) (*WatchedFolder, bool) {
+	folderID, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeBadRequest, "Invalid folder ID")
+		return nil, false
+	}
+
+	folders, err := s.store.GetWatchedFoldersByUser(r.Context(), userID)
+	if err != nil {
+		logger.Error("request failed", "operation", "get_watched_folders", "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to get watched folders")
+		return nil, false
+	}
+	for i := range folders {
+		if folders[i].ID == folderID {
+			return &folders[i], true
+		}
+	}
+
+	writeError(w, http.StatusNotFound, CodeNotFound, "Watched folder not found")
+	return nil, false
+}
+
+// folderErrors returns a folder's failed files, marking the quarantined ones
+func (s *Server) folderErrors(r *http.Request, userID int64, folder *WatchedFolder) ([]IngestFailure, error) {
+	failures, err := s.store.GetIngestFailures(r.Context(), userID, folder.Path)
+	if err != nil {
+		return nil, err
+	}
+	if failures == nil {
+		failures = []IngestFailure{}
+	}
+
+	limit := s.quarantineLimit()
+	for i := range failures {
+		failures[i].Quarantined = failures[i].Attempts >= limit
+	}
+	return failures, nil
+}
+
+// quarantineLimit returns how many failed attempts quarantine a watched file
+func (s *Server) quarantineLimit() int {
+	if s.folderRetrier == nil {
+		return defaultQuarantineAfter
+	}
+	return s.folderRetrier.QuarantineLimit()
+}
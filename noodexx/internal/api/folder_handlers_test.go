@@ -0,0 +1,128 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// mockStoreForFolders has one watched folder per user with failed files
+type mockStoreForFolders struct {
+	mockStore
+	failures map[string][]IngestFailure // folder path -> failures
+}
+
+func (m *mockStoreForFolders) GetWatchedFoldersByUser(ctx context.Context, userID int64) ([]WatchedFolder, error) {
+	if userID != 1 {
+		return []WatchedFolder{}, nil
+	}
+	return []WatchedFolder{{ID: 4, Path: "/docs", UserID: 1}}, nil
+}
+
+func (m *mockStoreForFolders) GetIngestFailures(ctx context.Context, userID int64, folder string) ([]IngestFailure, error) {
+	return m.failures[folder], nil
+}
+
+// mockFolderRetrier fails files whose path contains "broken"
+type mockFolderRetrier struct {
+	retried []string
+}
+
+func (m *mockFolderRetrier) Retry(ctx context.Context, userID int64, path string) error {
+	m.retried = append(m.retried, path)
+	if strings.Contains(path, "broken") {
+		return errors.New("extraction failed")
+	}
+	return nil
+}
+
+func (m *mockFolderRetrier) QuarantineLimit() int {
+	return 2
+}
+
+func TestHandleGetFolderErrors(t *testing.T) {
+	store := &mockStoreForFolders{failures: map[string][]IngestFailure{
+		"/docs": {
+			{Path: "/docs/broken.pdf", Error: "extraction failed", Attempts: 2},
+			{Path: "/docs/flaky.md", Error: "timeout", Attempts: 1},
+		},
+	}}
+	server := &Server{store: store, logger: &mockLogger{}}
+	server.SetFolderRetrier(&mockFolderRetrier{})
+
+	w := serveRoute(server, withUser(httptest.NewRequest(http.MethodGet, "/api/folders/4/errors", nil), 1))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		QuarantineAfter int             `json:"quarantine_after"`
+		Errors          []IngestFailure `json:"errors"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if resp.QuarantineAfter != 2 || len(resp.Errors) != 2 {
+		t.Fatalf("Expected 2 errors with a limit of 2, got %+v", resp)
+	}
+	if !resp.Errors[0].Quarantined || resp.Errors[1].Quarantined {
+		t.Errorf("Expected only the file at the limit to be quarantined, got %+v", resp.Errors)
+	}
+
+	// Another user's folder is not found
+	w = serveRoute(server, withUser(httptest.NewRequest(http.MethodGet, "/api/folders/4/errors", nil), 2))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for another user's folder, got %d", w.Code)
+	}
+}
+
+func TestHandleRetryFolderErrors(t *testing.T) {
+	store := &mockStoreForFolders{failures: map[string][]IngestFailure{
+		"/docs": {
+			{Path: "/docs/broken.pdf", Attempts: 2},
+			{Path: "/docs/fixed.md", Attempts: 2},
+		},
+	}}
+	retrier := &mockFolderRetrier{}
+	server := &Server{store: store, logger: &mockLogger{}}
+
+	retry := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/folders/4/errors/retry", strings.NewReader(body))
+		return serveRoute(server, withUser(req, 1))
+	}
+
+	// Without a watcher there is nothing to retry with
+	if w := retry(`{}`); w.Code != http.StatusNotImplemented {
+		t.Errorf("Expected status 501 without a watcher, got %d", w.Code)
+	}
+	server.SetFolderRetrier(retrier)
+
+	// An empty path retries every failed file
+	w := retry(`{}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Results []FolderRetryResult `json:"results"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if len(resp.Results) != 2 || resp.Results[0].Ingested || resp.Results[0].Error == "" || !resp.Results[1].Ingested {
+		t.Errorf("Expected the broken file to fail and the fixed one to ingest, got %+v", resp.Results)
+	}
+
+	// A single file is retried by path
+	retrier.retried = nil
+	if w := retry(`{"path":"/docs/fixed.md"}`); w.Code != http.StatusOK || len(retrier.retried) != 1 {
+		t.Errorf("Expected one file to be retried, got status %d and %v", w.Code, retrier.retried)
+	}
+
+	// Files without errors in the folder cannot be retried through it
+	if w := retry(`{"path":"/etc/passwd"}`); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for a file without errors, got %d", w.Code)
+	}
+}
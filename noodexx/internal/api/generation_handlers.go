@@ -0,0 +1,214 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"noodexx/internal/auth"
+	"strconv"
+	"time"
+)
+
+// Generation bounds used when the server has not been given guardrail limits
+const (
+	defaultMaxTemperature  = 2.0
+	defaultMaxOutputTokens = 4096
+)
+
+// limits returns the configured generation bounds, filling in defaults
+func (s *Server) limits() GenerationLimits {
+	limits := s.generationLimits
+	if limits.MaxTemperature <= 0 {
+		limits.MaxTemperature = defaultMaxTemperature
+	}
+	if limits.MaxTokens <= 0 {
+		limits.MaxTokens = defaultMaxOutputTokens
+	}
+	return limits
+}
+
+// validateGenerationOptions rejects options outside the server's bounds
+func validateGenerationOptions(opts GenerationOptions, limits GenerationLimits) error {
+	if opts.Temperature != nil && (*opts.Temperature < 0 || *opts.Temperature > limits.MaxTemperature) {
+		return fmt.Errorf("temperature must be between 0 and %g", limits.MaxTemperature)
+	}
+	if opts.TopP != nil && (*opts.TopP <= 0 || *opts.TopP > 1) {
+		return fmt.Errorf("top_p must be greater than 0 and at most 1")
+	}
+	if opts.MaxTokens < 0 || opts.MaxTokens > limits.MaxTokens {
+		return fmt.Errorf("max_tokens must be between 0 and %d", limits.MaxTokens)
+	}
+	return nil
+}
+
+// clampGenerationOptions brings saved options within the current bounds,
+// which may have been lowered since they were saved
+func clampGenerationOptions(opts GenerationOptions, limits GenerationLimits) GenerationOptions {
+	if opts.Temperature != nil && *opts.Temperature > limits.MaxTemperature {
+		temperature := limits.MaxTemperature
+		opts.Temperature = &temperature
+	}
+	if opts.MaxTokens > limits.MaxTokens {
+		opts.MaxTokens = limits.MaxTokens
+	}
+	return opts
+}
+
+// resolveGenerationOptions applies a request's overrides on top of the user's saved defaults
+// Overrides outside the bounds are an error; saved defaults are clamped to them
+func (s *Server) resolveGenerationOptions(ctx context.Context, logger Logger, userID int64, override GenerationOptions) (GenerationOptions, error) {
+	limits := s.limits()
+	if err := validateGenerationOptions(override, limits); err != nil {
+		return GenerationOptions{}, err
+	}
+
+	var opts GenerationOptions
+	defaults, err := s.store.GetGenerationDefaults(ctx, userID)
+	if err != nil {
+		logger.Warn("failed to load generation defaults, using provider defaults", "error", err.Error())
+	} else if defaults != nil {
+		opts = *defaults
+	}
+
+	if override.Temperature != nil {
+		opts.Temperature = override.Temperature
+	}
+	if override.TopP != nil {
+		opts.TopP = override.TopP
+	}
+	if override.MaxTokens > 0 {
+		opts.MaxTokens = override.MaxTokens
+	}
+	return clampGenerationOptions(opts, limits), nil
+}
+
+// parseGenerationForm reads generation overrides from multipart /api/ask form values
+func parseGenerationForm(r *http.Request) (GenerationOptions, error) {
+	var opts GenerationOptions
+	if v := r.FormValue("temperature"); v != "" {
+		temperature, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return opts, fmt.Errorf("invalid temperature")
+		}
+		opts.Temperature = &temperature
+	}
+	if v := r.FormValue("top_p"); v != "" {
+		topP, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return opts, fmt.Errorf("invalid top_p")
+		}
+		opts.TopP = &topP
+	}
+	if v := r.FormValue("max_tokens"); v != "" {
+		maxTokens, err := strconv.Atoi(v)
+		if err != nil {
+			return opts, fmt.Errorf("invalid max_tokens")
+		}
+		opts.MaxTokens = maxTokens
+	}
+	return opts, nil
+}
+
+// handleGenerationDefaults handles GET /api/generation-defaults
+// Returns the current user's defaults and the server's bounds
+func (s *Server) handleGenerationDefaults(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	requestID := newRequestID(w)
+
+	// Create logger with request context
+	logger := s.logger.WithContext("request_id", requestID).
+		WithContext("method", r.Method).
+		WithContext("path", r.URL.Path)
+
+	logger.Debug("processing generation defaults request")
+
+	ctx := r.Context()
+
+	// Extract user_id from context
+	userID, err := auth.GetUserID(ctx)
+	if err != nil {
+		logger.Error("request failed", "operation", "get_user_id", "error", err.Error())
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
+		return
+	}
+
+	limits := s.limits()
+
+	defaults, err := s.store.GetGenerationDefaults(ctx, userID)
+	if err != nil {
+		logger.Error("request failed", "operation", "get_generation_defaults", "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to get generation defaults")
+		return
+	}
+	if defaults == nil {
+		defaults = &GenerationOptions{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":  true,
+		"defaults": defaults,
+		"limits":   limits,
+	})
+
+	latency := time.Since(start).Milliseconds()
+	logger.Debug("request completed", "status", http.StatusOK, "latency_ms", latency)
+}
+
+// handleUpdateGenerationDefaults handles POST /api/generation-defaults
+// Replaces the current user's defaults, within the server's bounds
+func (s *Server) handleUpdateGenerationDefaults(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	requestID := newRequestID(w)
+
+	// Create logger with request context
+	logger := s.logger.WithContext("request_id", requestID).
+		WithContext("method", r.Method).
+		WithContext("path", r.URL.Path)
+
+	logger.Debug("processing update generation defaults request")
+
+	ctx := r.Context()
+
+	// Extract user_id from context
+	userID, err := auth.GetUserID(ctx)
+	if err != nil {
+		logger.Error("request failed", "operation", "get_user_id", "error", err.Error())
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
+		return
+	}
+
+	limits := s.limits()
+
+	var req GenerationOptions
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logger.Error("request failed", "operation", "parse_request", "error", err.Error())
+		writeError(w, http.StatusBadRequest, CodeBadRequest, "Invalid request")
+		return
+	}
+
+	if err := validateGenerationOptions(req, limits); err != nil {
+		logger.Error("request failed", "operation", "validate_generation_options", "error", err.Error())
+		writeError(w, http.StatusBadRequest, CodeBadRequest, err.Error())
+		return
+	}
+
+	if err := s.store.SaveGenerationDefaults(ctx, userID, req); err != nil {
+		logger.Error("request failed", "operation", "save_generation_defaults", "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to save generation defaults")
+		return
+	}
+
+	// Audit log
+	s.store.AddAuditEntry(ctx, "config", "Updated generation defaults", "")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Generation defaults updated successfully",
+	})
+
+	latency := time.Since(start).Milliseconds()
+	logger.Debug("request completed", "status", http.StatusOK, "latency_ms", latency, "user_id", userID)
+}
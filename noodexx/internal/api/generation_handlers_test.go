@@ -0,0 +1,174 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"noodexx/internal/auth"
+	"testing"
+)
+
+// generationStore keeps one user's generation defaults in memory
+type generationStore struct {
+	mockStoreForAsk
+	defaults *GenerationOptions
+	saved    *GenerationOptions
+}
+
+func (m *generationStore) GetGenerationDefaults(ctx context.Context, userID int64) (*GenerationOptions, error) {
+	if m.defaults == nil {
+		return &GenerationOptions{}, nil
+	}
+	return m.defaults, nil
+}
+
+func (m *generationStore) SaveGenerationDefaults(ctx context.Context, userID int64, opts GenerationOptions) error {
+	m.saved = &opts
+	return nil
+}
+
+// generationProvider records the options it was asked to generate with
+type generationProvider struct {
+	mockProviderForAsk
+	opts *GenerationOptions
+}
+
+func (m *generationProvider) StreamWithOptions(ctx context.Context, messages []Message, opts GenerationOptions, w io.Writer) (string, error) {
+	m.opts = &opts
+	return m.Stream(ctx, messages, w)
+}
+
+func float64Ptr(v float64) *float64 {
+	return &v
+}
+
+// TestValidateGenerationOptions tests the guardrail bounds on generation options
+func TestValidateGenerationOptions(t *testing.T) {
+	limits := GenerationLimits{MaxTemperature: 1.5, MaxTokens: 1000}
+
+	tests := []struct {
+		name    string
+		opts    GenerationOptions
+		wantErr bool
+	}{
+		{"empty", GenerationOptions{}, false},
+		{"within bounds", GenerationOptions{Temperature: float64Ptr(0), TopP: float64Ptr(1), MaxTokens: 1000}, false},
+		{"temperature too high", GenerationOptions{Temperature: float64Ptr(1.6)}, true},
+		{"negative temperature", GenerationOptions{Temperature: float64Ptr(-0.1)}, true},
+		{"zero top_p", GenerationOptions{TopP: float64Ptr(0)}, true},
+		{"top_p above one", GenerationOptions{TopP: float64Ptr(1.1)}, true},
+		{"too many tokens", GenerationOptions{MaxTokens: 1001}, true},
+		{"negative tokens", GenerationOptions{MaxTokens: -1}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateGenerationOptions(tt.opts, limits)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateGenerationOptions() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestResolveGenerationOptions tests that overrides replace saved defaults and defaults are clamped
+func TestResolveGenerationOptions(t *testing.T) {
+	store := &generationStore{defaults: &GenerationOptions{Temperature: float64Ptr(1.8), TopP: float64Ptr(0.9), MaxTokens: 5000}}
+	server := &Server{store: store, logger: &mockLogger{}, generationLimits: GenerationLimits{MaxTemperature: 1.5, MaxTokens: 2000}}
+
+	opts, err := server.resolveGenerationOptions(context.Background(), server.logger, 1, GenerationOptions{TopP: float64Ptr(0.5)})
+	if err != nil {
+		t.Fatalf("resolveGenerationOptions() error = %v", err)
+	}
+	if *opts.Temperature != 1.5 || opts.MaxTokens != 2000 {
+		t.Errorf("Expected saved defaults clamped to the limits, got temperature %v and max_tokens %d", *opts.Temperature, opts.MaxTokens)
+	}
+	if *opts.TopP != 0.5 {
+		t.Errorf("Expected top_p override 0.5, got %v", *opts.TopP)
+	}
+
+	if _, err := server.resolveGenerationOptions(context.Background(), server.logger, 1, GenerationOptions{MaxTokens: 3000}); err == nil {
+		t.Error("Expected an override above the limits to be rejected")
+	}
+}
+
+// TestHandleGenerationDefaults tests reading and saving a user's generation defaults
+func TestHandleGenerationDefaults(t *testing.T) {
+	store := &generationStore{defaults: &GenerationOptions{Temperature: float64Ptr(0.3)}}
+	server := &Server{store: store, logger: &mockLogger{}}
+
+	send := func(method, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/api/generation-defaults", bytes.NewBufferString(body))
+		return serveRoute(server, withUser(req, 1))
+	}
+
+	w := send(http.MethodGet, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Defaults GenerationOptions `json:"defaults"`
+		Limits   GenerationLimits  `json:"limits"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if resp.Defaults.Temperature == nil || *resp.Defaults.Temperature != 0.3 {
+		t.Errorf("Expected saved temperature 0.3, got %+v", resp.Defaults)
+	}
+	if resp.Limits.MaxTemperature != defaultMaxTemperature || resp.Limits.MaxTokens != defaultMaxOutputTokens {
+		t.Errorf("Expected default limits, got %+v", resp.Limits)
+	}
+
+	if w := send(http.MethodPost, `{"temperature":0.7,"max_tokens":512}`); w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if store.saved == nil || *store.saved.Temperature != 0.7 || store.saved.MaxTokens != 512 || store.saved.TopP != nil {
+		t.Errorf("Unexpected saved defaults: %+v", store.saved)
+	}
+
+	store.saved = nil
+	if w := send(http.MethodPost, `{"temperature":3}`); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a temperature above the limit, got %d", w.Code)
+	}
+	if store.saved != nil {
+		t.Error("Expected invalid defaults not to be saved")
+	}
+}
+
+// TestHandleAsk_GenerationOptions tests that /api/ask passes per-request options to the provider
+func TestHandleAsk_GenerationOptions(t *testing.T) {
+	provider := &generationProvider{mockProviderForAsk: mockProviderForAsk{name: "ollama", isLocal: true}}
+	server := &Server{
+		store:           &generationStore{defaults: &GenerationOptions{MaxTokens: 256}},
+		logger:          &mockLoggerForAsk{},
+		providerManager: &mockProviderManagerForAsk{provider: provider, providerName: "Ollama (llama3.2)"},
+		ragEnforcer:     &mockRAGEnforcerForAsk{shouldPerformRAG: false, ragStatus: "RAG Disabled"},
+	}
+
+	ask := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/ask", bytes.NewBufferString(body))
+		req = req.WithContext(context.WithValue(req.Context(), auth.UserIDKey, int64(1)))
+		w := httptest.NewRecorder()
+		server.handleAsk(w, req)
+		return w
+	}
+
+	if w := ask(`{"query":"test query","session_id":"s1","temperature":0.2}`); w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if provider.opts == nil || provider.opts.Temperature == nil || *provider.opts.Temperature != 0.2 || provider.opts.MaxTokens != 256 {
+		t.Errorf("Expected the temperature override on top of the saved defaults, got %+v", provider.opts)
+	}
+
+	provider.opts = nil
+	if w := ask(`{"query":"test query","session_id":"s1","top_p":2}`); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an out-of-range top_p, got %d", w.Code)
+	}
+	if provider.opts != nil {
+		t.Error("Expected the provider not to be called for invalid options")
+	}
+}
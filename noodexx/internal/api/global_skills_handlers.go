@@ -0,0 +1,326 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"noodexx/internal/auth"
+	"path/filepath"
+	"time"
+)
+
+// availableSkill is a skill found in the skills directory that can be
+// provisioned for every account
+type availableSkill struct {
+	Name        string `json:"name"`
+	Path        string `json:"path"`
+	Description string `json:"description"`
+}
+
+// handleSetSkillEnabled handles PUT /api/skills/{id}/enabled - turn one of your
+// skills on or off. For a global skill only your own setting changes
+func (s *Server) handleSetSkillEnabled(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	requestID := newRequestID(w)
+
+	logger := s.logger.WithContext("request_id", requestID).
+		WithContext("method", r.Method).
+		WithContext("path", r.URL.Path)
+
+	ctx := r.Context()
+
+	userID, err := auth.GetUserID(ctx)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
+		return
+	}
+
+	skillID, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeBadRequest, "Invalid skill ID")
+		return
+	}
+
+	var req struct {
+		Enabled *bool `json:"enabled"`
+	}
+	if !decodeJSON(w, r, logger, &req) {
+		return
+	}
+	if req.Enabled == nil {
+		writeError(w, http.StatusBadRequest, CodeBadRequest, "enabled is required")
+		return
+	}
+
+	// Only the user's own skills and the global skills they have are listed
+	skills, err := s.store.GetUserSkills(ctx, userID)
+	if err != nil {
+		logger.Error("request failed", "operation", "get_user_skills", "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to load skills")
+		return
+	}
+	var skill *Skill
+	for i := range skills {
+		if skills[i].ID == skillID {
+			skill = &skills[i]
+			break
+		}
+	}
+	if skill == nil {
+		writeError(w, http.StatusNotFound, CodeNotFound, "Skill not found")
+		return
+	}
+
+	if err := s.store.UpdateSkillEnabled(ctx, userID, skillID, *req.Enabled); err != nil {
+		logger.Error("request failed", "operation", "update_skill_enabled", "skill_id", skillID, "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to update skill")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"enabled": *req.Enabled,
+		"global":  skill.Global,
+	})
+
+	latency := time.Since(start).Milliseconds()
+	logger.Debug("request completed", "status", http.StatusOK, "latency_ms", latency, "skill_id", skillID, "enabled", *req.Enabled)
+}
+
+// handleGlobalSkills handles GET /api/admin/skills - the skills provisioned for
+// every account and the skills directory's skills (admin only)
+func (s *Server) handleGlobalSkills(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	requestID := newRequestID(w)
+
+	logger := s.logger.WithContext("request_id", requestID).
+		WithContext("method", r.Method).
+		WithContext("path", r.URL.Path)
+
+	logger.Debug("processing global skills request")
+
+	ctx := r.Context()
+
+	globals, err := s.store.GetGlobalSkills(ctx)
+	if err != nil {
+		logger.Error("request failed", "operation", "get_global_skills", "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to get global skills")
+		return
+	}
+	if globals == nil {
+		globals = []GlobalSkill{}
+	}
+
+	available := []availableSkill{}
+	if s.skillsLoader != nil {
+		loaded, err := s.skillsLoader.LoadAll()
+		if err != nil {
+			logger.Warn("failed to load skills directory", "error", err.Error())
+		}
+		for _, skill := range loaded {
+			available = append(available, availableSkill{
+				Name:        skill.Name,
+				Path:        filepath.Base(skill.Path),
+				Description: skill.Description,
+			})
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":   true,
+		"skills":    globals,
+		"available": available,
+	})
+
+	latency := time.Since(start).Milliseconds()
+	logger.Debug("request completed", "status", http.StatusOK, "latency_ms", latency, "skills", len(globals))
+}
+
+// handleCreateGlobalSkill handles POST /api/admin/skills - provision a skill
+// from the skills directory for every account (admin only)
+func (s *Server) handleCreateGlobalSkill(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	requestID := newRequestID(w)
+
+	logger := s.logger.WithContext("request_id", requestID).
+		WithContext("method", r.Method).
+		WithContext("path", r.URL.Path)
+
+	logger.Debug("processing create global skill request")
+
+	ctx := r.Context()
+
+	adminID, err := auth.GetUserID(ctx)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req struct {
+		Path    string `json:"path"`
+		Enabled *bool  `json:"enabled"`
+	}
+	if !decodeJSON(w, r, logger, &req) {
+		return
+	}
+	if req.Path == "" {
+		writeError(w, http.StatusBadRequest, CodeBadRequest, "path is required")
+		return
+	}
+	enabled := req.Enabled == nil || *req.Enabled
+
+	if s.skillsLoader == nil {
+		writeError(w, http.StatusNotImplemented, CodeNotImplemented, "Skills are not enabled")
+		return
+	}
+	loaded, err := s.skillsLoader.LoadAll()
+	if err != nil {
+		logger.Error("request failed", "operation", "load_skills", "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to load skills")
+		return
+	}
+	var skill *Skill
+	for _, candidate := range loaded {
+		if filepath.Base(candidate.Path) == req.Path {
+			skill = candidate
+			break
+		}
+	}
+	if skill == nil {
+		writeError(w, http.StatusBadRequest, CodeBadRequest, fmt.Sprintf("No skill %q in the skills directory", req.Path))
+		return
+	}
+
+	globals, err := s.store.GetGlobalSkills(ctx)
+	if err != nil {
+		logger.Error("request failed", "operation", "get_global_skills", "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to get global skills")
+		return
+	}
+	for _, global := range globals {
+		if global.Name == skill.Name || global.Path == req.Path {
+			writeError(w, http.StatusConflict, CodeConflict, fmt.Sprintf("Skill %q is already global", skill.Name))
+			return
+		}
+	}
+
+	skillID, err := s.store.CreateGlobalSkill(ctx, adminID, skill.Name, req.Path, enabled)
+	if err != nil {
+		logger.Error("request failed", "operation", "create_global_skill", "skill", skill.Name, "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to create global skill")
+		return
+	}
+	s.store.AddAuditEntry(ctx, "config", fmt.Sprintf("Global skill %s (ID %d) added, enabled %t", skill.Name, skillID, enabled), "")
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"skill": GlobalSkill{
+			ID:        skillID,
+			Name:      skill.Name,
+			Path:      req.Path,
+			Enabled:   enabled,
+			AddedBy:   adminID,
+			CreatedAt: time.Now().UTC(),
+		},
+	})
+
+	latency := time.Since(start).Milliseconds()
+	logger.Debug("request completed", "status", http.StatusCreated, "latency_ms", latency, "skill_id", skillID)
+}
+
+// handleUpdateGlobalSkill handles PUT /api/admin/skills/{id} - turn a global
+// skill on or off for users who haven't chosen themselves (admin only)
+func (s *Server) handleUpdateGlobalSkill(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	requestID := newRequestID(w)
+
+	logger := s.logger.WithContext("request_id", requestID).
+		WithContext("method", r.Method).
+		WithContext("path", r.URL.Path)
+
+	logger.Debug("processing update global skill request")
+
+	ctx := r.Context()
+
+	skillID, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeBadRequest, "Invalid skill ID")
+		return
+	}
+
+	var req struct {
+		Enabled *bool `json:"enabled"`
+	}
+	if !decodeJSON(w, r, logger, &req) {
+		return
+	}
+	if req.Enabled == nil {
+		writeError(w, http.StatusBadRequest, CodeBadRequest, "enabled is required")
+		return
+	}
+
+	found, err := s.store.UpdateGlobalSkill(ctx, skillID, *req.Enabled)
+	if err != nil {
+		logger.Error("request failed", "operation", "update_global_skill", "skill_id", skillID, "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to update global skill")
+		return
+	}
+	if !found {
+		writeError(w, http.StatusNotFound, CodeNotFound, "Global skill not found")
+		return
+	}
+	s.store.AddAuditEntry(ctx, "config", fmt.Sprintf("Global skill %d enabled set to %t", skillID, *req.Enabled), "")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+	})
+
+	latency := time.Since(start).Milliseconds()
+	logger.Debug("request completed", "status", http.StatusOK, "latency_ms", latency, "skill_id", skillID, "enabled", *req.Enabled)
+}
+
+// handleDeleteGlobalSkill handles DELETE /api/admin/skills/{id} - remove a
+// global skill from every account (admin only)
+func (s *Server) handleDeleteGlobalSkill(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	requestID := newRequestID(w)
+
+	logger := s.logger.WithContext("request_id", requestID).
+		WithContext("method", r.Method).
+		WithContext("path", r.URL.Path)
+
+	logger.Debug("processing delete global skill request")
+
+	ctx := r.Context()
+
+	skillID, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeBadRequest, "Invalid skill ID")
+		return
+	}
+
+	found, err := s.store.DeleteGlobalSkill(ctx, skillID)
+	if err != nil {
+		logger.Error("request failed", "operation", "delete_global_skill", "skill_id", skillID, "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to delete global skill")
+		return
+	}
+	if !found {
+		writeError(w, http.StatusNotFound, CodeNotFound, "Global skill not found")
+		return
+	}
+	s.store.AddAuditEntry(ctx, "config", fmt.Sprintf("Global skill %d removed", skillID), "")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+	})
+
+	latency := time.Since(start).Milliseconds()
+	logger.Debug("request completed", "status", http.StatusOK, "latency_ms", latency, "skill_id", skillID)
+}
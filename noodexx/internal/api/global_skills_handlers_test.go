@@ -0,0 +1,149 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// mockStoreForGlobalSkills keeps global skills and per-user preferences in memory
+type mockStoreForGlobalSkills struct {
+	mockStoreForAdmin
+	globals     []GlobalSkill
+	preferences map[int64]map[int64]bool // user ID -> skill ID -> enabled
+}
+
+func (m *mockStoreForGlobalSkills) GetUserSkills(ctx context.Context, userID int64) ([]Skill, error) {
+	var skills []Skill
+	for _, global := range m.globals {
+		enabled := global.Enabled
+		if pref, ok := m.preferences[userID][global.ID]; ok {
+			enabled = pref
+		}
+		skills = append(skills, Skill{ID: global.ID, UserID: userID, Name: global.Name, Path: global.Path, Enabled: enabled, Global: true})
+	}
+	return skills, nil
+}
+
+func (m *mockStoreForGlobalSkills) UpdateSkillEnabled(ctx context.Context, userID, skillID int64, enabled bool) error {
+	if m.preferences[userID] == nil {
+		m.preferences[userID] = map[int64]bool{}
+	}
+	m.preferences[userID][skillID] = enabled
+	return nil
+}
+
+func (m *mockStoreForGlobalSkills) CreateGlobalSkill(ctx context.Context, adminID int64, name, path string, enabled bool) (int64, error) {
+	id := int64(len(m.globals) + 1)
+	m.globals = append(m.globals, GlobalSkill{ID: id, Name: name, Path: path, Enabled: enabled, AddedBy: adminID})
+	return id, nil
+}
+
+func (m *mockStoreForGlobalSkills) GetGlobalSkills(ctx context.Context) ([]GlobalSkill, error) {
+	return m.globals, nil
+}
+
+func (m *mockStoreForGlobalSkills) UpdateGlobalSkill(ctx context.Context, skillID int64, enabled bool) (bool, error) {
+	for i := range m.globals {
+		if m.globals[i].ID == skillID {
+			m.globals[i].Enabled = enabled
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *mockStoreForGlobalSkills) DeleteGlobalSkill(ctx context.Context, skillID int64) (bool, error) {
+	for i := range m.globals {
+		if m.globals[i].ID == skillID {
+			m.globals = append(m.globals[:i], m.globals[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func TestGlobalSkillHandlers(t *testing.T) {
+	store := &mockStoreForGlobalSkills{preferences: map[int64]map[int64]bool{}}
+	loader := &mockSkillsLoader{skills: []*Skill{
+		{Name: "summarizer", Description: "Summarizes a document", Path: "skills/summarizer"},
+		{Name: "web-clipper", Description: "Saves a web page", Path: "skills/web-clipper"},
+	}}
+	server := &Server{store: store, skillsLoader: loader, logger: &mockLogger{}}
+
+	request := func(method, path, body string, userID int64) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		return serveRoute(server, withUser(req, userID))
+	}
+
+	// An admin provisions a skill from the skills directory
+	w := request(http.MethodPost, "/api/admin/skills", `{"path":"summarizer"}`, 1)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var created struct {
+		Skill GlobalSkill `json:"skill"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if created.Skill.Name != "summarizer" || !created.Skill.Enabled || created.Skill.AddedBy != 1 {
+		t.Errorf("Expected the enabled summarizer added by the admin, got %+v", created.Skill)
+	}
+
+	if w := request(http.MethodPost, "/api/admin/skills", `{"path":"summarizer"}`, 1); w.Code != http.StatusConflict {
+		t.Errorf("Expected status 409 for a skill already global, got %d", w.Code)
+	}
+	if w := request(http.MethodPost, "/api/admin/skills", `{"path":"missing"}`, 1); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for a skill not in the skills directory, got %d", w.Code)
+	}
+	if w := request(http.MethodPost, "/api/admin/skills", `{"path":"web-clipper"}`, 2); w.Code != http.StatusForbidden {
+		t.Errorf("Expected status 403 for a non-admin, got %d", w.Code)
+	}
+
+	w = request(http.MethodGet, "/api/admin/skills", "", 1)
+	var list struct {
+		Skills    []GlobalSkill    `json:"skills"`
+		Available []availableSkill `json:"available"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(list.Skills) != 1 || len(list.Available) != 2 || list.Available[1].Path != "web-clipper" {
+		t.Errorf("Expected one global skill and two available, got %+v", list)
+	}
+
+	// Users turn the global skill off for themselves only
+	if w := request(http.MethodPut, "/api/skills/1/enabled", `{"enabled":false}`, 2); w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if enabled, ok := store.preferences[2][1]; !ok || enabled {
+		t.Errorf("Expected user 2 to have turned the skill off, got %v", store.preferences)
+	}
+	if w := request(http.MethodPut, "/api/skills/9/enabled", `{"enabled":false}`, 2); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for a skill the user doesn't have, got %d", w.Code)
+	}
+	if w := request(http.MethodPut, "/api/skills/1/enabled", `{}`, 2); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 without enabled, got %d", w.Code)
+	}
+
+	// The admin changes the default, then removes the skill
+	if w := request(http.MethodPut, "/api/admin/skills/1", `{"enabled":false}`, 1); w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if store.globals[0].Enabled {
+		t.Error("Expected the global skill to be off by default")
+	}
+	if w := request(http.MethodDelete, "/api/admin/skills/1", "", 1); w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if len(store.globals) != 0 {
+		t.Errorf("Expected the global skill to be removed, got %+v", store.globals)
+	}
+	if w := request(http.MethodDelete, "/api/admin/skills/1", "", 1); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for a removed skill, got %d", w.Code)
+	}
+}
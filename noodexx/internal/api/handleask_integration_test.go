@@ -10,7 +10,6 @@ import (
 	"net/http/httptest"
 	"noodexx/internal/auth"
 	"testing"
-	"time"
 )
 
 // Mock implementations for handleAsk integration tests
@@ -39,6 +38,10 @@ func (m *mockProviderForAsk) Stream(ctx context.Context, messages []Message, w i
 	return response, nil
 }
 
+func (m *mockProviderForAsk) StreamWithOptions(ctx context.Context, messages []Message, opts GenerationOptions, w io.Writer) (string, error) {
+	return m.Stream(ctx, messages, w)
+}
+
 func (m *mockProviderForAsk) Name() string {
 	return m.name
 }
@@ -100,13 +103,19 @@ func (m *mockRAGEnforcerForAsk) Reload(cfg interface{}) {
 
 // mockStoreForAsk implements Store for testing handleAsk
 type mockStoreForAsk struct {
+	baseMockStore
 	searchByUserFunc    func(ctx context.Context, userID int64, queryVec []float32, topK int) ([]Chunk, error)
 	saveChatMessageFunc func(ctx context.Context, userID int64, sessionID, role, content, providerMode string) error
 	getSessionOwnerFunc func(ctx context.Context, sessionID string) (int64, error)
 	addAuditEntryFunc   func(ctx context.Context, opType, details, userCtx string) error
 }
 
-func (m *mockStoreForAsk) SearchByUser(ctx context.Context, userID int64, queryVec []float32, topK int) ([]Chunk, error) {
+// SearchLibrary searches the whole library with searchByUserFunc when it is set;
+// searches held to sources find nothing
+func (m *mockStoreForAsk) SearchLibrary(ctx context.Context, userID int64, queryVec []float32, opts SearchOptions, topK int) ([]Chunk, error) {
+	if opts.Sources != nil {
+		return nil, nil
+	}
 	if m.searchByUserFunc != nil {
 		return m.searchByUserFunc(ctx, userID, queryVec, topK)
 	}
@@ -137,63 +146,8 @@ func (m *mockStoreForAsk) AddAuditEntry(ctx context.Context, opType, details, us
 	return nil
 }
 
-// Stub methods for Store interface
-func (m *mockStoreForAsk) SaveChunk(ctx context.Context, source, text string, embedding []float32, tags []string, summary string) error {
-	return nil
-}
-func (m *mockStoreForAsk) Search(ctx context.Context, queryVec []float32, topK int) ([]Chunk, error) {
-	return nil, nil
-}
-func (m *mockStoreForAsk) Library(ctx context.Context) ([]LibraryEntry, error) {
-	return nil, nil
-}
-func (m *mockStoreForAsk) DeleteSource(ctx context.Context, source string) error {
-	return nil
-}
-func (m *mockStoreForAsk) SaveMessage(ctx context.Context, sessionID, role, content string) error {
-	return nil
-}
-func (m *mockStoreForAsk) GetSessionHistory(ctx context.Context, sessionID string) ([]ChatMessage, error) {
-	return nil, nil
-}
-func (m *mockStoreForAsk) ListSessions(ctx context.Context) ([]Session, error) {
-	return nil, nil
-}
-func (m *mockStoreForAsk) GetAuditLog(ctx context.Context, opType string, from, to time.Time) ([]AuditEntry, error) {
-	return nil, nil
-}
-func (m *mockStoreForAsk) GetUserByUsername(ctx context.Context, username string) (*User, error) {
-	return nil, nil
-}
-func (m *mockStoreForAsk) CreateUser(ctx context.Context, username, password, email string, isAdmin, mustChangePassword bool) (int64, error) {
-	return 0, nil
-}
-func (m *mockStoreForAsk) UpdatePassword(ctx context.Context, userID int64, newPassword string) error {
-	return nil
-}
-func (m *mockStoreForAsk) GetUserByID(ctx context.Context, userID int64) (*User, error) {
-	return nil, nil
-}
-func (m *mockStoreForAsk) ListUsers(ctx context.Context) ([]User, error) {
-	return nil, nil
-}
-func (m *mockStoreForAsk) DeleteUser(ctx context.Context, userID int64) error {
-	return nil
-}
-func (m *mockStoreForAsk) LibraryByUser(ctx context.Context, userID int64) ([]LibraryEntry, error) {
-	return nil, nil
-}
-func (m *mockStoreForAsk) GetUserSessions(ctx context.Context, userID int64) ([]Session, error) {
-	return nil, nil
-}
-func (m *mockStoreForAsk) GetSessionMessages(ctx context.Context, userID int64, sessionID string) ([]ChatMessage, error) {
-	return nil, nil
-}
-func (m *mockStoreForAsk) GetUserSkills(ctx context.Context, userID int64) ([]Skill, error) {
-	return nil, nil
-}
-func (m *mockStoreForAsk) GetWatchedFoldersByUser(ctx context.Context, userID int64) ([]WatchedFolder, error) {
-	return nil, nil
+func (m *mockStoreForAsk) WithTx(ctx context.Context, fn func(tx StoreTx) error) error {
+	return fn(m)
 }
 
 // mockLoggerForAsk implements Logger for testing
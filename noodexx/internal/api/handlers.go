@@ -6,14 +6,21 @@ import (
 	"encoding/base64"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"html"
+	"html/template"
 	"io"
 	"mime/multipart"
 	"net/http"
 	"noodexx/internal/auth"
 	"noodexx/internal/config"
-	"noodexx/internal/rag"
+	"noodexx/internal/events"
+	"noodexx/internal/ingest"
+	"noodexx/internal/markdown"
+	"noodexx/internal/validate"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -21,7 +28,7 @@ import (
 // handleDashboard renders the dashboard page with system stats
 func (s *Server) handleDashboard(w http.ResponseWriter, r *http.Request) {
 	start := time.Now()
-	requestID := generateRequestID()
+	requestID := newRequestID(w)
 
 	// Generate nonce for CSP
 	nonce := generateNonce()
@@ -43,12 +50,14 @@ func (s *Server) handleDashboard(w http.ResponseWriter, r *http.Request) {
 
 	// Get user ID from context
 	userID, err := auth.GetUserID(ctx)
-	var darkMode bool
+	theme := s.pageTheme(ctx)
+	var showOnboarding bool
 	if err == nil {
-		// Get user's dark mode preference
-		user, userErr := s.store.GetUserByID(ctx, userID)
-		if userErr == nil && user != nil {
-			darkMode = user.DarkMode
+		// Offer onboarding to new users with an empty library
+		showOnboarding, err = s.shouldShowOnboarding(ctx, userID)
+		if err != nil {
+			logger.Warn("failed to check onboarding state", "error", err.Error())
+			showOnboarding = false
 		}
 	}
 
@@ -56,7 +65,7 @@ func (s *Server) handleDashboard(w http.ResponseWriter, r *http.Request) {
 	library, err := s.store.Library(ctx)
 	if err != nil {
 		logger.Error("failed to get library", "error", err.Error())
-		http.Error(w, "Failed to load dashboard", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to load dashboard")
 		return
 	}
 	docCount := len(library)
@@ -85,16 +94,20 @@ func (s *Server) handleDashboard(w http.ResponseWriter, r *http.Request) {
 
 	// Prepare template data
 	data := map[string]interface{}{
-		"Title":         "Dashboard",
-		"Page":          "dashboard",
-		"DocumentCount": docCount,
-		"Provider":      providerName,
-		"PrivacyMode":   privacyMode,
-		"LastIngestion": lastIngestion,
-		"HasIngestions": !lastIngestion.IsZero(),
-		"UIStyle":       s.uiStyle,
-		"DarkMode":      darkMode,
-		"Nonce":         nonce,
+		"Title":          "Dashboard",
+		"Page":           "dashboard",
+		"DocumentCount":  docCount,
+		"Provider":       providerName,
+		"PrivacyMode":    privacyMode,
+		"LastIngestion":  lastIngestion,
+		"HasIngestions":  !lastIngestion.IsZero(),
+		"ShowOnboarding": showOnboarding,
+		"UIStyle":        s.uiStyle,
+		"Theme":          theme,
+		"Nonce":          nonce,
+	}
+	if s.modelWarmer != nil {
+		data["ModelWarmup"] = modelWarmupLabel(s.modelWarmer.Status())
 	}
 
 	logger.Debug("rendering dashboard template", "document_count", docCount)
@@ -105,7 +118,7 @@ func (s *Server) handleDashboard(w http.ResponseWriter, r *http.Request) {
 	// Render template
 	if err := s.templates.ExecuteTemplate(w, "base.html", data); err != nil {
 		logger.Error("failed to render dashboard template", "error", err.Error())
-		http.Error(w, "Failed to render dashboard", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to render dashboard")
 		return
 	}
 
@@ -116,7 +129,7 @@ func (s *Server) handleDashboard(w http.ResponseWriter, r *http.Request) {
 // handleChat renders the chat page
 func (s *Server) handleChat(w http.ResponseWriter, r *http.Request) {
 	start := time.Now()
-	requestID := generateRequestID()
+	requestID := newRequestID(w)
 
 	// Create logger with request context
 	logger := s.logger.WithContext("request_id", requestID).
@@ -132,16 +145,8 @@ func (s *Server) handleChat(w http.ResponseWriter, r *http.Request) {
 
 	ctx := r.Context()
 
-	// Get user ID from context
-	userID, err := auth.GetUserID(ctx)
-	var darkMode bool
-	if err == nil {
-		// Get user's dark mode preference
-		user, userErr := s.store.GetUserByID(ctx, userID)
-		if userErr == nil && user != nil {
-			darkMode = user.DarkMode
-		}
-	}
+	// Render in the user's theme from the start
+	theme := s.pageTheme(ctx)
 
 	// Check if cloud provider is available
 	cloudProviderAvailable := false
@@ -155,14 +160,15 @@ func (s *Server) handleChat(w http.ResponseWriter, r *http.Request) {
 		"Page":                   "chat",
 		"PrivacyMode":            s.config.PrivacyMode,
 		"CloudProviderAvailable": cloudProviderAvailable,
+		"TranscriptsArchived":    s.transcripts != nil, // Shown with the cloud warning
 		"UIStyle":                s.uiStyle,
-		"DarkMode":               darkMode,
+		"Theme":                  theme,
 	}
 
 	// Render chat template
 	if err := s.templates.ExecuteTemplate(w, "base.html", data); err != nil {
 		logger.Error("request failed", "operation", "render_template", "error", err.Error())
-		http.Error(w, "Failed to render chat", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to render chat")
 		return
 	}
 
@@ -170,10 +176,25 @@ func (s *Server) handleChat(w http.ResponseWriter, r *http.Request) {
 	logger.Debug("request completed", "status", http.StatusOK, "latency_ms", latency)
 }
 
+// askRequest is the body of POST /api/ask
+// Multipart requests may carry a file attached to this message
+type askRequest struct {
+	Query             string `json:"query"`
+	SessionID         string `json:"session_id"`
+	WebSearch         *bool  `json:"web_search"`      // Explicit web search opt-in/out; nil uses the default for the mode
+	Source            string `json:"source"`          // Answer over every chunk of this document instead of searching
+	Progress          bool   `json:"progress"`        // Send status events while retrieving, ahead of the answer
+	Events            bool   `json:"events"`          // Frame the answer as server-sent events: tokens, heartbeats and a final done or error event
+	SessionSources    bool   `json:"session_sources"` // Search only the library sources already cited in the session
+	Confirm           bool   `json:"confirm"`         // Send to a paid provider even when the estimated cost is over the user's limit
+	GenerationOptions        // Per-request temperature, top_p and max_tokens overrides
+	AnswerStyle              // Per-request language, tone and citation_style overrides
+}
+
 // handleAsk processes chat queries with RAG
 func (s *Server) handleAsk(w http.ResponseWriter, r *http.Request) {
 	start := time.Now()
-	requestID := generateRequestID()
+	requestID := newRequestID(w)
 
 	// Create logger with request context
 	logger := s.logger.WithContext("request_id", requestID).
@@ -182,139 +203,169 @@ func (s *Server) handleAsk(w http.ResponseWriter, r *http.Request) {
 
 	logger.Debug("processing request")
 
-	if r.Method != http.MethodPost {
-		logger.Error("request failed", "operation", "method_check", "error", "method not allowed")
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
 	ctx := r.Context()
 
 	// Extract user_id from context
 	userID, err := auth.GetUserID(ctx)
 	if err != nil {
 		logger.Error("request failed", "operation", "get_user_id", "error", err.Error())
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
 		return
 	}
 
-	// Parse request
-	var req struct {
-		Query     string `json:"query"`
-		SessionID string `json:"session_id"`
-	}
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		logger.Error("request failed", "operation", "parse_request", "error", err.Error())
-		http.Error(w, "Invalid request", http.StatusBadRequest)
+	req, upload, ok := parseAskRequest(w, r, logger)
+	if !ok {
 		return
 	}
 
-	// Generate session ID if not provided
-	if req.SessionID == "" {
-		req.SessionID = generateSessionID()
+	// Slash commands leading the question apply to this message only
+	commands, query, err := parseSlashCommands(req.Query)
+	if err != nil {
+		logger.Error("request failed", "operation", "parse_commands", "error", err.Error())
+		writeError(w, http.StatusBadRequest, CodeBadRequest, err.Error())
+		return
 	}
+	req.Query = query
 
-	// If session exists, verify ownership
-	if req.SessionID != "" {
-		owner, err := s.store.GetSessionOwner(ctx, req.SessionID)
-		if err == nil && owner != 0 {
-			// Session exists, verify it belongs to this user
-			if owner != userID {
-				logger.Error("request failed", "operation", "verify_session_owner", "error", "unauthorized access to session")
-				http.Error(w, "Forbidden: session belongs to another user", http.StatusForbidden)
-				return
-			}
-		}
+	// Apply the request's overrides to the user's generation defaults within the guardrails
+	genOpts, err := s.resolveGenerationOptions(ctx, logger, userID, req.GenerationOptions)
+	if err != nil {
+		logger.Error("request failed", "operation", "validate_generation_options", "error", err.Error())
+		writeError(w, http.StatusBadRequest, CodeBadRequest, err.Error())
+		return
 	}
 
-	// Save user message with user_id
-	// User messages don't have a provider mode, use empty string
-	if err := s.store.SaveChatMessage(ctx, userID, req.SessionID, "user", req.Query, ""); err != nil {
-		logger.Warn("failed to save user message", "error", err.Error())
+	// Apply the request's language, tone and citation style over the user's saved style
+	style, err := s.resolveAnswerStyle(ctx, logger, userID, req.AnswerStyle)
+	if err != nil {
+		logger.Error("request failed", "operation", "validate_answer_style", "error", err.Error())
+		writeError(w, http.StatusBadRequest, CodeBadRequest, err.Error())
+		return
 	}
 
-	// Audit log
-	s.store.AddAuditEntry(ctx, "query", req.Query, req.SessionID)
-
-	// Get active provider
-	provider, err := s.providerManager.GetActiveProvider()
-	if err != nil {
-		logger.Error("request failed", "operation", "get_active_provider", "error", err.Error())
-		http.Error(w, "Provider not configured. Please configure the AI provider in Settings.", http.StatusBadRequest)
+	sess, ok := s.openAskSession(ctx, w, logger, userID, req)
+	if !ok {
+		return
+	}
+	mode, ok := s.selectAskMode(ctx, w, logger, sess, commands)
+	if !ok {
+		return
+	}
+	provider := mode.provider
+	if !s.loadSessionContext(ctx, w, logger, userID, req, sess, mode) {
 		return
 	}
 
-	// Conditionally perform RAG based on policy
-	var chunks []Chunk
-	if s.ragEnforcer.ShouldPerformRAG() {
-		logger.Debug("performing RAG search")
+	// Chats on the local provider count as activity for model keep-alive; the
+	// models stay loaded for a while after the answer finishes
+	if s.modelWarmer != nil && provider.IsLocal() {
+		s.modelWarmer.Touch()
+		defer s.modelWarmer.Touch()
+	}
 
-		// Embed query
-		queryVec, err := provider.Embed(ctx, req.Query)
-		if err != nil {
-			logger.Error("request failed", "operation", "embed_query", "error", err.Error())
-			http.Error(w, "Embedding failed", http.StatusInternalServerError)
+	var attachmentID string
+	if upload != nil {
+		if attachmentID, ok = s.addAskAttachment(ctx, w, logger, provider, userID, req.SessionID, upload); !ok {
 			return
 		}
+	}
 
-		// Search for relevant chunks (user-scoped)
-		chunks, err = s.store.SearchByUser(ctx, userID, queryVec, 5)
-		if err != nil {
-			logger.Error("request failed", "operation", "search_chunks", "error", err.Error())
-			http.Error(w, "Search failed", http.StatusInternalServerError)
+	var docChunks []Chunk
+	if req.Source != "" {
+		if docChunks, ok = s.readAskDocument(ctx, w, logger, userID, req.Source, mode); !ok {
 			return
 		}
-	} else {
-		logger.Debug("skipping RAG search per policy")
 	}
 
-	// Build prompt using PromptBuilder (with or without chunks)
-	// Convert api.Chunk to rag.Chunk
-	ragChunks := make([]rag.Chunk, len(chunks))
-	for i, chunk := range chunks {
-		ragChunks[i] = rag.Chunk{
-			Source: chunk.Source,
-			Text:   chunk.Text,
-			Score:  chunk.Score,
-		}
+	w.Header().Set("X-Session-ID", req.SessionID)
+	w.Header().Set("X-Provider-Name", mode.name)
+	w.Header().Set("X-RAG-Status", mode.ragStatus)
+	if s.transcriptsArchived(mode.local) {
+		w.Header().Set("X-Transcripts-Archived", "true")
+	}
+	if attachmentID != "" {
+		w.Header().Set("X-Attachment-ID", attachmentID)
 	}
 
-	promptBuilder := rag.NewPromptBuilder()
-	prompt := promptBuilder.BuildPrompt(req.Query, ragChunks)
+	// With progress events the stream starts now, so the client hears about
+	// retrieval while it runs; counts known only afterwards go in the sources event.
+	// Slash commands are acknowledged with command events, so they turn it on
+	// Every event of the request goes through one writer, so heartbeats see them all
+	sse := NewSSEWriter(w)
+	progress := newAskProgress(w, sse, start, req.Progress || len(commands) > 0, req.Events)
+	progress.begin()
 
-	// Stream response
-	w.Header().Set("Content-Type", "text/event-stream")
-	w.Header().Set("Cache-Control", "no-cache")
-	w.Header().Set("Connection", "keep-alive")
-	w.Header().Set("X-Session-ID", req.SessionID)
-	w.Header().Set("X-Provider-Name", s.providerManager.GetProviderName())
-	w.Header().Set("X-RAG-Status", s.ragEnforcer.GetRAGStatus())
+	cmd := s.runSlashCommands(ctx, logger, userID, req.SessionID, req.Query, commands, mode, progress)
+	if req.Query == "" && len(commands) > 0 {
+		// Only commands, nothing to answer
+		logger.Debug("request completed", "status", http.StatusOK, "latency_ms", time.Since(start).Milliseconds(), "commands", len(commands))
+		return
+	}
 
-	messages := []Message{
-		{Role: "system", Content: "You are a helpful assistant."},
-		{Role: "user", Content: prompt},
+	chunks, webResults, ok := s.retrieveAskContext(ctx, logger, progress, userID, req, mode, cmd, sess.citedSources, len(docChunks))
+	if !ok {
+		return
 	}
 
-	response, err := provider.Stream(ctx, messages, w)
-	if err != nil {
-		logger.Error("request failed", "operation", "stream_response", "error", err.Error())
-		// Write error message to the stream so the client can display it
-		errorMsg := fmt.Sprintf("Error: Failed to get response from AI provider. %s", err.Error())
-		fmt.Fprint(w, errorMsg)
+	// A question about one document builds its own provider calls
+	var messages []Message
+	if len(docChunks) == 0 {
+		messages = askMessages(req.Query, chunks, sess, style)
+	}
+
+	if !s.confirmAskCost(ctx, logger, progress, userID, req, mode, messages, docChunks, genOpts) {
 		return
 	}
+	s.saveQuestion(ctx, logger, userID, req, sess)
 
-	// Save assistant message with user_id and provider mode
-	providerMode := "local"
-	if !s.providerManager.IsLocalMode() {
-		providerMode = "cloud"
+	// Stream response
+	setAnswerHeaders(w, req.Events)
+	if webResults > 0 {
+		w.Header().Set("X-Web-Results", strconv.Itoa(webResults))
 	}
-	if err := s.store.SaveChatMessage(ctx, userID, req.SessionID, "assistant", response, providerMode); err != nil {
-		logger.Warn("failed to save assistant message", "error", err.Error())
+	if len(docChunks) > 0 {
+		w.Header().Set("X-Document-Chunks", strconv.Itoa(len(docChunks)))
+		chunks = docChunks
+	}
+
+	stream, err := s.openAskStream(ctx, w, logger, progress, requestID, userID, req.SessionID, req.Events)
+	if err != nil {
+		return
 	}
+	defer stream.close()
+
+	// Prompts that include documents tagged sensitive are logged for their owners
+	s.recordSensitiveAccess(stream.ctx, logger, userID, req.SessionID, req.Query, provider, chunks)
+
+	messages, response, err := s.generateAnswer(stream, provider, genOpts, style, req.Query, messages, docChunks)
+	if err != nil {
+		logger.Error("request failed", "operation", "stream_response", "error", err.Error())
+		stream.fail(err)
+		return
+	}
+
+	s.writeAnswer(logger, progress, stream, &askAnswer{
+		requestID:      requestID,
+		userID:         userID,
+		sessionID:      req.SessionID,
+		source:         req.Source,
+		provider:       provider,
+		mode:           mode,
+		genOpts:        genOpts,
+		style:          style,
+		commands:       commands,
+		messages:       messages,
+		chunks:         chunks,
+		webResults:     webResults,
+		sessionSources: req.SessionSources,
+		citedSources:   sess.citedSources,
+		sessionLink:    sess.link,
+		history:        sess.history,
+		sessionExists:  sess.exists,
+	}, response)
 
 	latency := time.Since(start).Milliseconds()
-	logger.Debug("request completed", "status", http.StatusOK, "latency_ms", latency, "session_id", req.SessionID)
+	logger.Debug("request completed", "status", http.StatusOK, "latency_ms", latency, "queue_wait_ms", stream.queueWait.Milliseconds(), "session_id", req.SessionID)
 }
 
 // handleSessions returns a list of all chat sessions for the current user
@@ -324,14 +375,14 @@ func (s *Server) handleSessions(w http.ResponseWriter, r *http.Request) {
 	// Extract user_id from context
 	userID, err := auth.GetUserID(ctx)
 	if err != nil {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
 		return
 	}
 
 	// Get sessions for this user only
 	sessions, err := s.store.GetUserSessions(ctx, userID)
 	if err != nil {
-		http.Error(w, "Failed to list sessions", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to list sessions")
 		return
 	}
 
@@ -344,10 +395,18 @@ func (s *Server) handleSessions(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "text/html")
 		for _, session := range sessions {
 			relativeTime := formatRelativeTime(session.LastMessageAt)
+			continued := ""
+			if session.ContinuedFrom != "" {
+				continued = `<div class="session-continued">Continues an earlier chat</div>`
+			}
+			if session.ImportedFrom != "" {
+				continued += fmt.Sprintf(`<div class="session-imported">Imported from %s</div>`, html.EscapeString(chatFormatLabel(session.ImportedFrom)))
+			}
 			fmt.Fprintf(w, `<div class="session-item" data-session-id="%s" onclick="loadSession('%s')">
 				<div class="session-time">%s</div>
 				<div class="session-count">%d messages</div>
-			</div>`, session.ID, session.ID, relativeTime, session.MessageCount)
+				%s
+			</div>`, html.EscapeString(session.ID), html.EscapeString(template.JSEscapeString(session.ID)), relativeTime, session.MessageCount, continued)
 		}
 	}
 }
@@ -359,21 +418,20 @@ func (s *Server) handleSessionHistory(w http.ResponseWriter, r *http.Request) {
 	// Extract user_id from context
 	userID, err := auth.GetUserID(ctx)
 	if err != nil {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
 		return
 	}
 
-	// Extract session ID from URL path
-	sessionID := strings.TrimPrefix(r.URL.Path, "/api/session/")
+	sessionID := r.PathValue("id")
 	if sessionID == "" {
-		http.Error(w, "Session ID required", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, CodeBadRequest, "Session ID required")
 		return
 	}
 
 	// Get session messages with ownership verification
 	messages, err := s.store.GetSessionMessages(ctx, userID, sessionID)
 	if err != nil {
-		http.Error(w, "Failed to get session history", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to get session history")
 		return
 	}
 
@@ -399,6 +457,8 @@ func (s *Server) handleSessionHistory(w http.ResponseWriter, r *http.Request) {
 				// Add provider class for assistant messages
 				if msg.ProviderMode == "cloud" {
 					providerClass = " provider-cloud"
+				} else if msg.ProviderMode == "external" {
+					providerClass = " provider-external"
 				} else {
 					providerClass = " provider-local"
 				}
@@ -407,7 +467,7 @@ func (s *Server) handleSessionHistory(w http.ResponseWriter, r *http.Request) {
 			fmt.Fprintf(w, `<div class="message message-%s">
 				<div class="message-avatar%s">%s</div>
 				<div class="message-content">%s</div>
-			</div>`, msg.Role, providerClass, avatarSVG, msg.Content)
+			</div>`, html.EscapeString(msg.Role), providerClass, avatarSVG, markdown.Render(msg.Content))
 		}
 	}
 }
@@ -415,7 +475,7 @@ func (s *Server) handleSessionHistory(w http.ResponseWriter, r *http.Request) {
 // handleLibrary renders the library page with document cards
 func (s *Server) handleLibrary(w http.ResponseWriter, r *http.Request) {
 	start := time.Now()
-	requestID := generateRequestID()
+	requestID := newRequestID(w)
 
 	// Create logger with request context
 	logger := s.logger.WithContext("request_id", requestID).
@@ -435,16 +495,12 @@ func (s *Server) handleLibrary(w http.ResponseWriter, r *http.Request) {
 	userID, err := auth.GetUserID(ctx)
 	if err != nil {
 		logger.Error("request failed", "operation", "get_user_id", "error", err.Error())
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
 		return
 	}
 
-	// Get user's dark mode preference
-	var darkMode bool
-	user, userErr := s.store.GetUserByID(ctx, userID)
-	if userErr == nil && user != nil {
-		darkMode = user.DarkMode
-	}
+	// Render in the user's theme from the start
+	theme := s.pageTheme(ctx)
 
 	// Get tag filter from query parameter
 	tagFilter := r.URL.Query().Get("tag")
@@ -453,7 +509,7 @@ func (s *Server) handleLibrary(w http.ResponseWriter, r *http.Request) {
 	library, err := s.store.LibraryByUser(ctx, userID)
 	if err != nil {
 		logger.Error("request failed", "operation", "get_library", "error", err.Error())
-		http.Error(w, "Failed to load library", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to load library")
 		return
 	}
 
@@ -486,6 +542,34 @@ func (s *Server) handleLibrary(w http.ResponseWriter, r *http.Request) {
 	// Sort tags alphabetically
 	sort.Strings(allTags)
 
+	// Pinned documents are listed first, otherwise in their usual order
+	sort.SliceStable(filteredLibrary, func(i, j int) bool {
+		return filteredLibrary[i].Pinned && !filteredLibrary[j].Pinned
+	})
+
+	// JSON clients get pinned documents in a section of their own
+	if strings.Contains(r.Header.Get("Accept"), "application/json") {
+		pinned := []LibraryEntry{}
+		documents := []LibraryEntry{}
+		for _, entry := range filteredLibrary {
+			if entry.Pinned {
+				pinned = append(pinned, entry)
+			} else {
+				documents = append(documents, entry)
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"pinned":    pinned,
+			"documents": documents,
+			"tags":      allTags,
+		})
+
+		latency := time.Since(start).Milliseconds()
+		logger.Debug("request completed", "status", http.StatusOK, "latency_ms", latency, "pinned", len(pinned))
+		return
+	}
+
 	// Check if this is an HTMX request (return fragment)
 	if r.Header.Get("HX-Request") == "true" {
 		// Return HTML fragment with document cards
@@ -495,7 +579,7 @@ func (s *Server) handleLibrary(w http.ResponseWriter, r *http.Request) {
 		if len(filteredLibrary) == 0 {
 			if err := s.templates.ExecuteTemplate(w, "library-empty", nil); err != nil {
 				logger.Error("request failed", "operation", "render_empty_state", "error", err.Error())
-				http.Error(w, "Failed to render empty state", http.StatusInternalServerError)
+				writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to render empty state")
 				return
 			}
 		} else {
@@ -503,7 +587,7 @@ func (s *Server) handleLibrary(w http.ResponseWriter, r *http.Request) {
 			for _, entry := range filteredLibrary {
 				if err := s.templates.ExecuteTemplate(w, "document-card", entry); err != nil {
 					logger.Error("request failed", "operation", "render_document_card", "error", err.Error())
-					http.Error(w, "Failed to render document card", http.StatusInternalServerError)
+					writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to render document card")
 					return
 				}
 			}
@@ -523,12 +607,12 @@ func (s *Server) handleLibrary(w http.ResponseWriter, r *http.Request) {
 		"Tags":        allTags,
 		"SelectedTag": tagFilter,
 		"UIStyle":     s.uiStyle,
-		"DarkMode":    darkMode,
+		"Theme":       theme,
 	}
 
 	if err := s.templates.ExecuteTemplate(w, "base.html", data); err != nil {
 		logger.Error("request failed", "operation", "render_template", "error", err.Error())
-		http.Error(w, "Failed to render library", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to render library")
 		return
 	}
 
@@ -539,7 +623,7 @@ func (s *Server) handleLibrary(w http.ResponseWriter, r *http.Request) {
 // handleIngestText processes plain text ingestion
 func (s *Server) handleIngestText(w http.ResponseWriter, r *http.Request) {
 	start := time.Now()
-	requestID := generateRequestID()
+	requestID := newRequestID(w)
 
 	// Create logger with request context
 	logger := s.logger.WithContext("request_id", requestID).
@@ -548,46 +632,51 @@ func (s *Server) handleIngestText(w http.ResponseWriter, r *http.Request) {
 
 	logger.Debug("processing request")
 
-	if r.Method != http.MethodPost {
-		logger.Error("request failed", "operation", "method_check", "error", "method not allowed")
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
 	ctx := r.Context()
 
 	// Extract user_id from context
 	userID, err := auth.GetUserID(ctx)
 	if err != nil {
 		logger.Error("request failed", "operation", "get_user_id", "error", err.Error())
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
 		return
 	}
 
 	// Parse request
 	var req struct {
-		Source string   `json:"source"`
-		Text   string   `json:"text"`
-		Tags   []string `json:"tags"`
+		Source     string   `json:"source"`
+		Text       string   `json:"text"`
+		Tags       []string `json:"tags"`
+		Visibility string   `json:"visibility"` // Empty for the user's default
 	}
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		logger.Error("request failed", "operation", "parse_request", "error", err.Error())
-		http.Error(w, "Invalid request", http.StatusBadRequest)
+	if !decodeJSON(w, r, logger, &req) {
+		return
+	}
+
+	// Validate input
+	v := validate.New()
+	v.Required("source", "Source", req.Source)
+	v.Check("source", validate.Source(req.Source))
+	v.Required("text", "Text", req.Text)
+	v.Check("tags", validate.Tags(req.Tags))
+	v.Check("visibility", validate.Visibility(req.Visibility))
+	if err := v.Err(); err != nil {
+		writeValidationError(w, logger, err)
 		return
 	}
 
 	// Ingest text with user_id
-	if err := s.ingester.IngestText(ctx, userID, req.Source, req.Text, req.Tags); err != nil {
+	if err := s.ingester.IngestText(ingest.WithVisibility(ctx, req.Visibility), userID, req.Source, req.Text, req.Tags); err != nil {
 		logger.Error("request failed", "operation", "ingest_text", "source", req.Source, "error", err.Error())
-		http.Error(w, fmt.Sprintf("Ingestion failed: %v", err), http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, CodeInternal, fmt.Sprintf("Ingestion failed: %v", err))
 		return
 	}
 
 	// Audit log
 	s.store.AddAuditEntry(ctx, "ingest", fmt.Sprintf("Text: %s", req.Source), "")
 
-	// Broadcast WebSocket update
-	s.wsHub.Broadcast("ingestion", fmt.Sprintf("Document '%s' ingested successfully", req.Source))
+	// Notify the user
+	s.NotifyUser(ctx, userID, "ingestion", fmt.Sprintf("Document '%s' ingested successfully", req.Source), map[string]interface{}{"source": req.Source})
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{"status": "success"})
@@ -599,7 +688,7 @@ func (s *Server) handleIngestText(w http.ResponseWriter, r *http.Request) {
 // handleIngestURL processes URL ingestion
 func (s *Server) handleIngestURL(w http.ResponseWriter, r *http.Request) {
 	start := time.Now()
-	requestID := generateRequestID()
+	requestID := newRequestID(w)
 
 	// Create logger with request context
 	logger := s.logger.WithContext("request_id", requestID).
@@ -608,45 +697,49 @@ func (s *Server) handleIngestURL(w http.ResponseWriter, r *http.Request) {
 
 	logger.Debug("processing request")
 
-	if r.Method != http.MethodPost {
-		logger.Error("request failed", "operation", "method_check", "error", "method not allowed")
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
 	ctx := r.Context()
 
 	// Extract user_id from context
 	userID, err := auth.GetUserID(ctx)
 	if err != nil {
 		logger.Error("request failed", "operation", "get_user_id", "error", err.Error())
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
 		return
 	}
 
 	// Parse request
 	var req struct {
-		URL  string   `json:"url"`
-		Tags []string `json:"tags"`
+		URL        string   `json:"url"`
+		Tags       []string `json:"tags"`
+		Visibility string   `json:"visibility"` // Empty for the user's default
 	}
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		logger.Error("request failed", "operation", "parse_request", "error", err.Error())
-		http.Error(w, "Invalid request", http.StatusBadRequest)
+	if !decodeJSON(w, r, logger, &req) {
+		return
+	}
+
+	// Validate input
+	v := validate.New()
+	v.Required("url", "URL", req.URL)
+	v.Check("url", validate.URL(req.URL))
+	v.Check("tags", validate.Tags(req.Tags))
+	v.Check("visibility", validate.Visibility(req.Visibility))
+	if err := v.Err(); err != nil {
+		writeValidationError(w, logger, err)
 		return
 	}
 
 	// Ingest URL with user_id
-	if err := s.ingester.IngestURL(ctx, userID, req.URL, req.Tags); err != nil {
+	if err := s.ingester.IngestURL(ingest.WithVisibility(ctx, req.Visibility), userID, req.URL, req.Tags); err != nil {
 		logger.Error("request failed", "operation", "ingest_url", "url", req.URL, "error", err.Error())
-		http.Error(w, fmt.Sprintf("Ingestion failed: %v", err), http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, CodeInternal, fmt.Sprintf("Ingestion failed: %v", err))
 		return
 	}
 
 	// Audit log
 	s.store.AddAuditEntry(ctx, "ingest", fmt.Sprintf("URL: %s", req.URL), "")
 
-	// Broadcast WebSocket update
-	s.wsHub.Broadcast("ingestion", fmt.Sprintf("URL '%s' ingested successfully", req.URL))
+	// Notify the user
+	s.NotifyUser(ctx, userID, "ingestion", fmt.Sprintf("URL '%s' ingested successfully", req.URL), map[string]interface{}{"source": req.URL})
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{"status": "success"})
@@ -658,7 +751,7 @@ func (s *Server) handleIngestURL(w http.ResponseWriter, r *http.Request) {
 // handleIngestFile processes file upload ingestion
 func (s *Server) handleIngestFile(w http.ResponseWriter, r *http.Request) {
 	start := time.Now()
-	requestID := generateRequestID()
+	requestID := newRequestID(w)
 
 	// Create logger with request context
 	logger := s.logger.WithContext("request_id", requestID).
@@ -667,19 +760,19 @@ func (s *Server) handleIngestFile(w http.ResponseWriter, r *http.Request) {
 
 	logger.Debug("processing request")
 
-	if r.Method != http.MethodPost {
-		logger.Error("request failed", "operation", "method_check", "error", "method not allowed")
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
 	ctx := r.Context()
 
 	// Parse multipart form
-	if err := r.ParseMultipartForm(10 << 20); err != nil { // 10MB max
+	if err := r.ParseMultipartForm(10 << 20); err != nil { // Held in memory up to 10MB; the body limit caps the total
 		logger.Error("request failed", "operation", "parse_form", "error", err.Error())
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			w.Header().Set("HX-Trigger", `{"toast": {"variant": "error", "message": "File is too large"}}`)
+			writeBodyTooLarge(w, tooLarge.Limit)
+			return
+		}
 		w.Header().Set("HX-Trigger", `{"toast": {"variant": "error", "message": "Failed to parse form"}}`)
-		http.Error(w, "Failed to parse form", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, CodeBadRequest, "Failed to parse form")
 		return
 	}
 
@@ -687,7 +780,7 @@ func (s *Server) handleIngestFile(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		logger.Error("request failed", "operation", "get_file", "error", err.Error())
 		w.Header().Set("HX-Trigger", `{"toast": {"variant": "error", "message": "Failed to get file"}}`)
-		http.Error(w, "Failed to get file", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, CodeBadRequest, "Failed to get file")
 		return
 	}
 	defer file.Close()
@@ -702,19 +795,33 @@ func (s *Server) handleIngestFile(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// Validate input; an empty visibility keeps the user's default
+	visibility := r.FormValue("visibility")
+	v := validate.New()
+	v.Check("file", validate.Source(header.Filename))
+	v.Check("tags", validate.Tags(tags))
+	v.Check("visibility", validate.Visibility(visibility))
+	if err := v.Err(); err != nil {
+		w.Header().Set("HX-Trigger", `{"toast": {"variant": "error", "message": "Upload failed"}}`)
+		writeValidationError(w, logger, err)
+		return
+	}
+
 	// Ingest file
-	if err := s.ingestFile(ctx, file, header, tags); err != nil {
+	if err := s.ingestFile(ingest.WithVisibility(ctx, visibility), file, header, tags); err != nil {
 		logger.Error("request failed", "operation", "ingest_file", "filename", header.Filename, "error", err.Error())
 		w.Header().Set("HX-Trigger", `{"toast": {"variant": "error", "message": "Upload failed"}}`)
-		http.Error(w, fmt.Sprintf("Ingestion failed: %v", err), http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, CodeInternal, fmt.Sprintf("Ingestion failed: %v", err))
 		return
 	}
 
 	// Audit log
 	s.store.AddAuditEntry(ctx, "ingest", fmt.Sprintf("File: %s", header.Filename), "")
 
-	// Broadcast WebSocket update
-	s.wsHub.Broadcast("ingestion", fmt.Sprintf("File '%s' ingested successfully", header.Filename))
+	// Notify the user
+	if userID, err := auth.GetUserID(ctx); err == nil {
+		s.NotifyUser(ctx, userID, "ingestion", fmt.Sprintf("File '%s' ingested successfully", header.Filename), map[string]interface{}{"source": header.Filename})
+	}
 
 	w.Header().Set("HX-Trigger", `{"toast": {"variant": "success", "message": "Document uploaded successfully"}}`)
 	w.Header().Set("Content-Type", "application/json")
@@ -749,7 +856,7 @@ func (s *Server) ingestFile(ctx context.Context, file multipart.File, header *mu
 // handleDelete removes a document and all its chunks
 func (s *Server) handleDelete(w http.ResponseWriter, r *http.Request) {
 	start := time.Now()
-	requestID := generateRequestID()
+	requestID := newRequestID(w)
 
 	// Create logger with request context
 	logger := s.logger.WithContext("request_id", requestID).
@@ -758,12 +865,6 @@ func (s *Server) handleDelete(w http.ResponseWriter, r *http.Request) {
 
 	logger.Debug("processing request")
 
-	if r.Method != http.MethodPost && r.Method != http.MethodDelete {
-		logger.Error("request failed", "operation", "method_check", "error", "method not allowed")
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
 	ctx := r.Context()
 
 	// Parse request
@@ -773,23 +874,29 @@ func (s *Server) handleDelete(w http.ResponseWriter, r *http.Request) {
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		logger.Error("request failed", "operation", "parse_request", "error", err.Error())
 		w.Header().Set("HX-Trigger", `{"toast": {"variant": "error", "message": "Invalid request"}}`)
-		http.Error(w, "Invalid request", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, CodeBadRequest, "Invalid request")
 		return
 	}
 
-	// Delete document
-	if err := s.store.DeleteSource(ctx, req.Source); err != nil {
+	// Delete document and record the audit entry as a single unit of work
+	err := s.store.WithTx(ctx, func(tx StoreTx) error {
+		if err := tx.DeleteSource(ctx, req.Source); err != nil {
+			return err
+		}
+		return tx.AddAuditEntry(ctx, "delete", fmt.Sprintf("Source: %s", req.Source), "")
+	})
+	if err != nil {
 		logger.Error("request failed", "operation", "delete_source", "source", req.Source, "error", err.Error())
 		w.Header().Set("HX-Trigger", `{"toast": {"variant": "error", "message": "Delete failed"}}`)
-		http.Error(w, "Delete failed", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Delete failed")
 		return
 	}
 
-	// Audit log
-	s.store.AddAuditEntry(ctx, "delete", fmt.Sprintf("Source: %s", req.Source), "")
-
-	// Broadcast WebSocket update
-	s.wsHub.Broadcast("deletion", fmt.Sprintf("Document '%s' deleted", req.Source))
+	// Tell the user once the deletion is committed
+	if userID, err := auth.GetUserID(ctx); err == nil {
+		s.NotifyUser(ctx, userID, "deletion", fmt.Sprintf("Document '%s' deleted", req.Source), map[string]interface{}{"source": req.Source})
+		s.publish(ctx, events.DocumentDeleted, userID, map[string]interface{}{"source": req.Source})
+	}
 
 	w.Header().Set("HX-Trigger", `{"toast": {"variant": "success", "message": "Document deleted successfully"}}`)
 	w.Header().Set("Content-Type", "application/json")
@@ -808,6 +915,13 @@ func generateSessionID() string {
 	return hex.EncodeToString(bytes)
 }
 
+// generateAttachmentID creates a random ID for a chat attachment
+func generateAttachmentID() string {
+	bytes := make([]byte, 12)
+	rand.Read(bytes)
+	return hex.EncodeToString(bytes)
+}
+
 // generateRequestID creates a random request ID for logging
 func generateRequestID() string {
 	bytes := make([]byte, 8)
@@ -869,7 +983,7 @@ func formatRelativeTime(t time.Time) string {
 // handleSettings renders the settings page
 func (s *Server) handleSettings(w http.ResponseWriter, r *http.Request) {
 	start := time.Now()
-	requestID := generateRequestID()
+	requestID := newRequestID(w)
 
 	// Create logger with request context
 	logger := s.logger.WithContext("request_id", requestID).
@@ -885,25 +999,20 @@ func (s *Server) handleSettings(w http.ResponseWriter, r *http.Request) {
 
 	ctx := r.Context()
 
-	// Get user ID from context
-	userID, err := auth.GetUserID(ctx)
-	var darkMode bool
-	if err == nil {
-		// Get user's dark mode preference
-		user, userErr := s.store.GetUserByID(ctx, userID)
-		if userErr == nil && user != nil {
-			darkMode = user.DarkMode
-		}
-	}
+	// Render in the user's theme from the start
+	theme := s.pageTheme(ctx)
 
 	// Load current config from file to get latest values
 	cfg, err := config.Load(s.configPath)
 	if err != nil {
 		logger.Error("Failed to load config", "error", err.Error())
-		http.Error(w, "Failed to load configuration", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to load configuration")
 		return
 	}
 
+	// Where cloud embeddings come from when the cloud provider has no embeddings API
+	embeddingSource, _ := cfg.CloudProvider.EmbeddingSource(cfg.LocalProvider.Type != "")
+
 	// Create nested config structure that matches template expectations
 	configData := map[string]interface{}{
 		"Privacy": map[string]interface{}{
@@ -917,20 +1026,22 @@ func (s *Server) handleSettings(w http.ResponseWriter, r *http.Request) {
 			"OllamaChatModel":  cfg.LocalProvider.OllamaChatModel,
 		},
 		"CloudProvider": map[string]interface{}{
-			"Type":                cfg.CloudProvider.Type,
-			"OpenAIKey":           cfg.CloudProvider.OpenAIKey,
-			"OpenAIEmbedModel":    cfg.CloudProvider.OpenAIEmbedModel,
-			"OpenAIChatModel":     cfg.CloudProvider.OpenAIChatModel,
-			"AnthropicKey":        cfg.CloudProvider.AnthropicKey,
-			"AnthropicEmbedModel": cfg.CloudProvider.AnthropicEmbedModel,
-			"AnthropicChatModel":  cfg.CloudProvider.AnthropicChatModel,
+			"Type":               cfg.CloudProvider.Type,
+			"OpenAIKey":          cfg.CloudProvider.OpenAIKey,
+			"OpenAIEmbedModel":   cfg.CloudProvider.OpenAIEmbedModel,
+			"OpenAIChatModel":    cfg.CloudProvider.OpenAIChatModel,
+			"AnthropicKey":       cfg.CloudProvider.AnthropicKey,
+			"AnthropicChatModel": cfg.CloudProvider.AnthropicChatModel,
+			"EmbeddingSource":    embeddingSource,
 		},
 		"Folders": cfg.Folders,
 		"Guardrails": map[string]interface{}{
-			"PIIDetection":  cfg.Guardrails.PIIDetection,
-			"AutoSummarize": cfg.Guardrails.AutoSummarize,
-			"MaxFileSizeMB": cfg.Guardrails.MaxFileSizeMB,
-			"MaxConcurrent": cfg.Guardrails.MaxConcurrent,
+			"PIIDetection":    cfg.Guardrails.PIIDetection,
+			"AutoSummarize":   cfg.Guardrails.AutoSummarize,
+			"MaxFileSizeMB":   cfg.Guardrails.MaxFileSizeMB,
+			"MaxConcurrent":   cfg.Guardrails.MaxConcurrent,
+			"MaxTemperature":  cfg.Guardrails.MaxTemperature,
+			"MaxOutputTokens": cfg.Guardrails.MaxOutputTokens,
 		},
 	}
 
@@ -947,12 +1058,14 @@ func (s *Server) handleSettings(w http.ResponseWriter, r *http.Request) {
 		"Config":                 configData,
 		"CloudProviderAvailable": cloudProviderAvailable,
 		"UIStyle":                s.uiStyle,
-		"DarkMode":               darkMode,
+		"Theme":                  theme,
+		"PasswordMinLength":      s.passwordPolicy().MinLength(),
+		"PasswordHint":           s.passwordPolicy().Hint(),
 	}
 
 	if err := s.templates.ExecuteTemplate(w, "base.html", data); err != nil {
 		logger.Error("request failed", "operation", "render_template", "error", err.Error())
-		http.Error(w, "Failed to render template", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to render template")
 		return
 	}
 
@@ -960,19 +1073,33 @@ func (s *Server) handleSettings(w http.ResponseWriter, r *http.Request) {
 	logger.Debug("request completed", "status", http.StatusOK, "latency_ms", latency)
 }
 
-// handleConfig saves configuration changes
-func (s *Server) handleConfig(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
+// validateProviderForm checks the provider types, endpoints and RAG policy of the
+// dual provider form; keys and model names are checked by the provider config itself
+func validateProviderForm(r *http.Request) error {
+	v := validate.New()
+	if t := r.FormValue("local_provider_type"); t != "" {
+		v.Check("local_provider_type", validate.OneOf("Local provider type", t, "ollama", "builtin"))
+	}
+	if e := r.FormValue("local_ollama_endpoint"); e != "" {
+		v.Check("local_ollama_endpoint", validate.URL(e))
+	}
+	if t := r.FormValue("cloud_provider_type"); t != "" {
+		v.Check("cloud_provider_type", validate.OneOf("Cloud provider type", t, "openai", "anthropic"))
 	}
+	if p := r.FormValue("cloud_rag_policy"); p != "" {
+		v.Check("cloud_rag_policy", validate.OneOf("Cloud RAG policy", p, "no_rag", "allow_rag"))
+	}
+	return v.Err()
+}
 
+// handleConfig saves configuration changes
+func (s *Server) handleConfig(w http.ResponseWriter, r *http.Request) {
 	s.logger.Debug("Received dual provider config save request")
 
 	// Parse form data
 	if err := r.ParseForm(); err != nil {
 		s.logger.Error("Failed to parse form: %v", err)
-		http.Error(w, "Failed to parse form", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, CodeBadRequest, "Failed to parse form")
 		return
 	}
 
@@ -982,7 +1109,12 @@ func (s *Server) handleConfig(w http.ResponseWriter, r *http.Request) {
 	cfg, err := config.Load(s.configPath)
 	if err != nil {
 		s.logger.Error("Failed to load config: %v", err)
-		http.Error(w, "Failed to load config", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to load config")
+		return
+	}
+
+	if err := validateProviderForm(r); err != nil {
+		writeValidationError(w, s.logger, err)
 		return
 	}
 
@@ -1033,10 +1165,6 @@ func (s *Server) handleConfig(w http.ResponseWriter, r *http.Request) {
 		cfg.CloudProvider.AnthropicKey = v
 		s.logger.Debug("Cloud Anthropic key provided: %d chars", len(v))
 	}
-	if v := r.FormValue("cloud_anthropic_embed_model"); v != "" {
-		cfg.CloudProvider.AnthropicEmbedModel = v
-		s.logger.Debug("Cloud Anthropic embed model: %s", v)
-	}
 	if v := r.FormValue("cloud_anthropic_chat_model"); v != "" {
 		cfg.CloudProvider.AnthropicChatModel = v
 		s.logger.Debug("Cloud Anthropic chat model: %s", v)
@@ -1064,27 +1192,21 @@ func (s *Server) handleConfig(w http.ResponseWriter, r *http.Request) {
 	// Validate local provider configuration
 	if err := cfg.LocalProvider.ValidateLocal(); err != nil {
 		s.logger.Error("Local provider validation failed: %v", err)
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte(fmt.Sprintf(`{"success": false, "error": "Local provider validation failed: %s"}`, err.Error())))
+		writeError(w, http.StatusBadRequest, CodeBadRequest, "Local provider validation failed: "+err.Error())
 		return
 	}
 
 	// Validate cloud provider configuration
 	if err := cfg.CloudProvider.ValidateCloud(); err != nil {
 		s.logger.Error("Cloud provider validation failed: %v", err)
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte(fmt.Sprintf(`{"success": false, "error": "Cloud provider validation failed: %s"}`, err.Error())))
+		writeError(w, http.StatusBadRequest, CodeBadRequest, "Cloud provider validation failed: "+err.Error())
 		return
 	}
 
 	// Validate RAG policy
 	if err := cfg.Privacy.ValidateRAGPolicy(); err != nil {
 		s.logger.Error("RAG policy validation failed: %v", err)
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte(fmt.Sprintf(`{"success": false, "error": "RAG policy validation failed: %s"}`, err.Error())))
+		writeError(w, http.StatusBadRequest, CodeBadRequest, "RAG policy validation failed: "+err.Error())
 		return
 	}
 
@@ -1093,9 +1215,7 @@ func (s *Server) handleConfig(w http.ResponseWriter, r *http.Request) {
 	// Save configuration to disk
 	if err := cfg.Save(s.configPath); err != nil {
 		s.logger.Error("Failed to save config: %v", err)
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte(fmt.Sprintf(`{"success": false, "error": "Failed to save configuration: %s"}`, err.Error())))
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to save configuration: "+err.Error())
 		return
 	}
 
@@ -1104,9 +1224,7 @@ func (s *Server) handleConfig(w http.ResponseWriter, r *http.Request) {
 	// Reload providers with new configuration
 	if err := s.providerManager.Reload(cfg); err != nil {
 		s.logger.Error("Failed to reload providers: %v", err)
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte(fmt.Sprintf(`{"success": false, "error": "Failed to reload providers: %s"}`, err.Error())))
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to reload providers: "+err.Error())
 		return
 	}
 
@@ -1119,21 +1237,11 @@ func (s *Server) handleConfig(w http.ResponseWriter, r *http.Request) {
 
 // handleTestConnection tests provider connectivity
 func (s *Server) handleTestConnection(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
 	ctx := r.Context()
 
 	// Parse form to get test_provider_mode
 	if err := r.ParseForm(); err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]interface{}{
-			"success": false,
-			"error":   "Failed to parse form data",
-		})
+		writeError(w, http.StatusBadRequest, CodeBadRequest, "Failed to parse form data")
 		return
 	}
 
@@ -1152,24 +1260,14 @@ func (s *Server) handleTestConnection(w http.ResponseWriter, r *http.Request) {
 		case "local":
 			providerToTest = s.providerManager.GetLocalProvider()
 			if providerToTest == nil {
-				w.Header().Set("Content-Type", "application/json")
-				w.WriteHeader(http.StatusBadRequest)
-				json.NewEncoder(w).Encode(map[string]interface{}{
-					"success": false,
-					"error":   "Local provider not configured",
-				})
+				writeError(w, http.StatusBadRequest, CodeProviderUnavailable, "Local provider not configured")
 				return
 			}
 			providerName = "Local provider"
 		case "cloud":
 			providerToTest = s.providerManager.GetCloudProvider()
 			if providerToTest == nil {
-				w.Header().Set("Content-Type", "application/json")
-				w.WriteHeader(http.StatusBadRequest)
-				json.NewEncoder(w).Encode(map[string]interface{}{
-					"success": false,
-					"error":   "Cloud provider not configured",
-				})
+				writeError(w, http.StatusBadRequest, CodeProviderUnavailable, "Cloud provider not configured")
 				return
 			}
 			providerName = "Cloud provider"
@@ -1177,12 +1275,7 @@ func (s *Server) handleTestConnection(w http.ResponseWriter, r *http.Request) {
 			// Test active provider
 			activeProvider, err := s.providerManager.GetActiveProvider()
 			if err != nil {
-				w.Header().Set("Content-Type", "application/json")
-				w.WriteHeader(http.StatusBadRequest)
-				json.NewEncoder(w).Encode(map[string]interface{}{
-					"success": false,
-					"error":   err.Error(),
-				})
+				writeError(w, http.StatusBadRequest, CodeProviderUnavailable, err.Error())
 				return
 			}
 			providerToTest = activeProvider
@@ -1197,12 +1290,7 @@ func (s *Server) handleTestConnection(w http.ResponseWriter, r *http.Request) {
 	// Test embedding with a simple text
 	_, err := providerToTest.Embed(ctx, "test")
 	if err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]interface{}{
-			"success": false,
-			"error":   err.Error(),
-		})
+		writeError(w, http.StatusBadRequest, CodeBadRequest, err.Error())
 		return
 	}
 
@@ -1220,7 +1308,7 @@ func (s *Server) handleActivity(w http.ResponseWriter, r *http.Request) {
 	// Query audit log for recent 10 entries
 	entries, err := s.store.GetAuditLog(ctx, "", time.Time{}, time.Now())
 	if err != nil {
-		http.Error(w, "Failed to fetch activity", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to fetch activity")
 		return
 	}
 
@@ -1235,11 +1323,11 @@ func (s *Server) handleActivity(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Format as HTML fragment
-	var html strings.Builder
+	var fragment strings.Builder
 
 	// Handle empty state
 	if len(entries) == 0 {
-		html.WriteString(`<div class="flex flex-col items-center justify-center py-12 px-4 text-center">
+		fragment.WriteString(`<div class="flex flex-col items-center justify-center py-12 px-4 text-center">
 			<div class="mb-4 text-surface-400 dark:text-surface-500">
 				<svg class="w-16 h-16 mx-auto" fill="none" stroke="currentColor" viewBox="0 0 24 24">
 					<path stroke-linecap="round" stroke-linejoin="round" stroke-width="2" d="M9 5H7a2 2 0 00-2 2v12a2 2 0 002 2h10a2 2 0 002-2V7a2 2 0 00-2-2h-2M9 5a2 2 0 002 2h2a2 2 0 002-2M9 5a2 2 0 012-2h2a2 2 0 012 2"></path>
@@ -1250,43 +1338,38 @@ func (s *Server) handleActivity(w http.ResponseWriter, r *http.Request) {
 		</div>`)
 	} else {
 		// Render activity items with Tailwind classes
-		html.WriteString(`<div class="space-y-3">`)
+		fragment.WriteString(`<div class="space-y-3">`)
 		for _, entry := range entries {
-			html.WriteString(fmt.Sprintf(`<div class="flex items-start justify-between p-3 rounded-lg bg-surface-50 dark:bg-surface-900 border border-surface-200 dark:border-surface-700 hover:border-surface-300 dark:hover:border-surface-600 transition-colors">
+			fragment.WriteString(fmt.Sprintf(`<div class="flex items-start justify-between p-3 rounded-lg bg-surface-50 dark:bg-surface-900 border border-surface-200 dark:border-surface-700 hover:border-surface-300 dark:hover:border-surface-600 transition-colors">
 				<div class="flex-1 min-w-0">
 					<div class="text-sm font-medium text-surface-900 dark:text-surface-100">%s</div>
 					<div class="text-sm text-surface-600 dark:text-surface-400 mt-1 truncate">%s</div>
 				</div>
 				<div class="text-xs text-surface-500 dark:text-surface-500 ml-4 whitespace-nowrap">%s</div>
-			</div>`, entry.OperationType, entry.Details, formatRelativeTime(entry.Timestamp)))
+			</div>`, html.EscapeString(entry.OperationType), html.EscapeString(entry.Details), formatRelativeTime(entry.Timestamp)))
 		}
-		html.WriteString(`</div>`)
+		fragment.WriteString(`</div>`)
 	}
 
 	w.Header().Set("Content-Type", "text/html")
-	w.Write([]byte(html.String()))
+	w.Write([]byte(fragment.String()))
 }
 
 // handleSkills lists available skills for the current user
 func (s *Server) handleSkills(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
 	ctx := r.Context()
 
 	// Extract user_id from context
 	userID, err := auth.GetUserID(ctx)
 	if err != nil {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
 		return
 	}
 
 	// Get skills for this user from database
 	skills, err := s.store.GetUserSkills(ctx, userID)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to load skills: %v", err), http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, CodeInternal, fmt.Sprintf("Failed to load skills: %v", err))
 		return
 	}
 
@@ -1297,70 +1380,38 @@ func (s *Server) handleSkills(w http.ResponseWriter, r *http.Request) {
 }
 
 // handleRunSkill executes a manual-trigger skill
+// Each run is recorded in the skill's history; save_output also ingests the result as a document
 func (s *Server) handleRunSkill(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
 	ctx := r.Context()
 
 	// Extract user_id from context
 	userID, err := auth.GetUserID(ctx)
 	if err != nil {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
 		return
 	}
 
 	// Parse request body
 	var req struct {
-		SkillName string                 `json:"skill_name"`
-		Query     string                 `json:"query"`
-		Context   map[string]interface{} `json:"context"`
+		SkillName  string                 `json:"skill_name"`
+		Query      string                 `json:"query"`
+		Context    map[string]interface{} `json:"context"`
+		SaveOutput bool                   `json:"save_output"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, CodeBadRequest, "Invalid request body")
 		return
 	}
 
-	// Load skills for this user
-	skills, err := s.skillsLoader.LoadForUser(ctx, userID)
+	targetSkill, status, err := s.findRunnableSkill(ctx, userID, func(skill *Skill) bool {
+		return skill.Name == req.SkillName
+	})
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to load skills: %v", err), http.StatusInternalServerError)
-		return
-	}
-
-	var targetSkill *Skill
-	for _, skill := range skills {
-		if skill.Name == req.SkillName {
-			targetSkill = skill
-			break
+		if status == http.StatusNotFound {
+			err = fmt.Errorf("Skill not found: %s", req.SkillName)
 		}
-	}
-
-	if targetSkill == nil {
-		http.Error(w, fmt.Sprintf("Skill not found: %s", req.SkillName), http.StatusNotFound)
-		return
-	}
-
-	// Verify skill ownership - ensure the skill belongs to the current user
-	if targetSkill.UserID != userID {
-		http.Error(w, "Unauthorized: skill does not belong to current user", http.StatusForbidden)
-		return
-	}
-
-	// Check if skill has manual trigger
-	hasManualTrigger := false
-	for _, trigger := range targetSkill.Triggers {
-		if trigger.Type == "manual" {
-			hasManualTrigger = true
-			break
-		}
-	}
-
-	if !hasManualTrigger {
-		http.Error(w, "Skill does not support manual execution", http.StatusBadRequest)
+		writeError(w, status, codeForStatus(status), err.Error())
 		return
 	}
 
@@ -1371,46 +1422,25 @@ func (s *Server) handleRunSkill(w http.ResponseWriter, r *http.Request) {
 		Settings: make(map[string]interface{}),
 	}
 
-	output, err := s.skillsExecutor.Execute(ctx, targetSkill, input)
-	if err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(map[string]interface{}{
-			"success": false,
-			"error":   err.Error(),
-		})
-		return
-	}
-
-	// Return result
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"success":  true,
-		"result":   output.Result,
-		"metadata": output.Metadata,
-	})
+	result := s.runSkill(ctx, userID, targetSkill, input, 0, req.SaveOutput)
+	s.writeSkillRunResult(w, result)
 }
 
 // handleWatchedFolders returns the list of watched folders for the current user
 func (s *Server) handleWatchedFolders(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
 	ctx := r.Context()
 
 	// Extract user_id from context
 	userID, err := auth.GetUserID(ctx)
 	if err != nil {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
 		return
 	}
 
 	// Get watched folders for this user
 	folders, err := s.store.GetWatchedFoldersByUser(ctx, userID)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to get watched folders: %v", err), http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, CodeInternal, fmt.Sprintf("Failed to get watched folders: %v", err))
 		return
 	}
 
@@ -1425,7 +1455,7 @@ func (s *Server) handleWatchedFolders(w http.ResponseWriter, r *http.Request) {
 // handleLogin processes user login and returns a session token
 func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
 	start := time.Now()
-	requestID := generateRequestID()
+	requestID := newRequestID(w)
 
 	// Create logger with request context
 	logger := s.logger.WithContext("request_id", requestID).
@@ -1434,60 +1464,44 @@ func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
 
 	logger.Debug("processing login request")
 
-	if r.Method != http.MethodPost {
-		logger.Error("request failed", "operation", "method_check", "error", "method not allowed")
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
 	ctx := r.Context()
 
 	// Parse request
 	var req struct {
-		Username string `json:"username"`
-		Password string `json:"password"`
+		Username   string `json:"username"`
+		Password   string `json:"password"`
+		RememberMe bool   `json:"remember_me"`
 	}
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		logger.Error("request failed", "operation", "parse_request", "error", err.Error())
-		http.Error(w, "Invalid request", http.StatusBadRequest)
+	if !decodeJSON(w, r, logger, &req) {
 		return
 	}
 
 	// Validate input
-	if req.Username == "" || req.Password == "" {
-		logger.Warn("login failed", "reason", "missing credentials")
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]interface{}{
-			"success": false,
-			"error":   "Username and password are required",
-		})
+	v := validate.New()
+	v.Required("username", "Username", req.Username)
+	v.Required("password", "Password", req.Password)
+	if err := v.Err(); err != nil {
+		writeValidationError(w, logger, err)
 		return
 	}
 
 	// Call auth provider Login
-	token, err := s.authProvider.Login(ctx, req.Username, req.Password)
+	loginCtx := ctx
+	if req.RememberMe {
+		loginCtx = auth.WithRememberMe(ctx)
+	}
+	token, err := s.authProvider.Login(loginCtx, req.Username, req.Password)
 	if err != nil {
 		logger.Warn("login failed", "username", req.Username, "error", err.Error())
 
 		// Check if account is locked
 		if strings.Contains(err.Error(), "account locked") {
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusLocked) // 423
-			json.NewEncoder(w).Encode(map[string]interface{}{
-				"success": false,
-				"error":   err.Error(),
-			})
+			writeError(w, http.StatusLocked, CodeAccountLocked, err.Error())
 			return
 		}
 
 		// Invalid credentials
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusUnauthorized)
-		json.NewEncoder(w).Encode(map[string]interface{}{
-			"success": false,
-			"error":   "Invalid username or password",
-		})
+		writeError(w, http.StatusUnauthorized, CodeInvalidCredentials, "Invalid username or password")
 		return
 	}
 
@@ -1495,26 +1509,16 @@ func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
 	user, err := s.store.GetUserByUsername(ctx, req.Username)
 	if err != nil {
 		logger.Error("request failed", "operation", "get_user", "error", err.Error())
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Internal server error")
 		return
 	}
 
-	// Set session_token cookie
-	cookie := &http.Cookie{
-		Name:     "session_token",
-		Value:    token,
-		Path:     "/",
-		HttpOnly: true,
-		SameSite: http.SameSiteLaxMode,
-		MaxAge:   7 * 24 * 60 * 60, // 7 days
-	}
-
-	// Set Secure flag in production (when not localhost)
-	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
-		cookie.Secure = true
+	// Set session_token cookie; without "remember me" it lasts until the browser closes
+	maxAge := 0
+	if req.RememberMe {
+		maxAge = s.rememberMeMaxAge()
 	}
-
-	http.SetCookie(w, cookie)
+	auth.SetSessionCookie(w, r, token, maxAge)
 
 	// Determine redirect URL based on must_change_password
 	redirectURL := "/"
@@ -1541,7 +1545,7 @@ func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
 // handleLogout invalidates the user's session token
 func (s *Server) handleLogout(w http.ResponseWriter, r *http.Request) {
 	start := time.Now()
-	requestID := generateRequestID()
+	requestID := newRequestID(w)
 
 	// Create logger with request context
 	logger := s.logger.WithContext("request_id", requestID).
@@ -1550,12 +1554,6 @@ func (s *Server) handleLogout(w http.ResponseWriter, r *http.Request) {
 
 	logger.Debug("processing logout request")
 
-	if r.Method != http.MethodPost {
-		logger.Error("request failed", "operation", "method_check", "error", "method not allowed")
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
 	ctx := r.Context()
 
 	// Extract token from request (use extractToken from middleware)
@@ -1590,7 +1588,7 @@ func (s *Server) handleLogout(w http.ResponseWriter, r *http.Request) {
 // handleRegister creates a new user account
 func (s *Server) handleRegister(w http.ResponseWriter, r *http.Request) {
 	start := time.Now()
-	requestID := generateRequestID()
+	requestID := newRequestID(w)
 
 	// Create logger with request context
 	logger := s.logger.WithContext("request_id", requestID).
@@ -1599,12 +1597,6 @@ func (s *Server) handleRegister(w http.ResponseWriter, r *http.Request) {
 
 	logger.Debug("processing registration request")
 
-	if r.Method != http.MethodPost {
-		logger.Error("request failed", "operation", "method_check", "error", "method not allowed")
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
 	ctx := r.Context()
 
 	// Parse request
@@ -1614,88 +1606,41 @@ func (s *Server) handleRegister(w http.ResponseWriter, r *http.Request) {
 		Password        string `json:"password"`
 		ConfirmPassword string `json:"confirm_password"`
 	}
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		logger.Error("request failed", "operation", "parse_request", "error", err.Error())
-		http.Error(w, "Invalid request", http.StatusBadRequest)
+	if !decodeJSON(w, r, logger, &req) {
 		return
 	}
 
-	// Validate password confirmation
+	// Validate input
+	v := validate.New()
+	v.Check("username", validate.Username(req.Username))
+	v.Check("email", validate.Email(req.Email))
+	v.Check("password", s.passwordPolicy().Check(req.Password))
 	if req.Password != req.ConfirmPassword {
-		logger.Warn("registration failed", "reason", "password mismatch")
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]interface{}{
-			"success": false,
-			"error":   "Passwords do not match",
-		})
-		return
+		v.Check("confirm_password", errors.New("Passwords do not match"))
 	}
-
-	// Validate username format (3-32 chars, alphanumeric + underscore/dash)
-	if len(req.Username) < 3 || len(req.Username) > 32 {
-		logger.Warn("registration failed", "reason", "invalid username length")
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]interface{}{
-			"success": false,
-			"error":   "Username must be between 3 and 32 characters",
-		})
-		return
-	}
-
-	// Check username contains only valid characters
-	for _, c := range req.Username {
-		if !((c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') || c == '_' || c == '-') {
-			logger.Warn("registration failed", "reason", "invalid username characters")
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(map[string]interface{}{
-				"success": false,
-				"error":   "Username can only contain letters, numbers, underscores, and dashes",
-			})
-			return
-		}
-	}
-
-	// Validate email format (basic regex)
-	if !strings.Contains(req.Email, "@") || !strings.Contains(req.Email, ".") {
-		logger.Warn("registration failed", "reason", "invalid email format")
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]interface{}{
-			"success": false,
-			"error":   "Invalid email format",
-		})
+	if err := v.Err(); err != nil {
+		writeValidationError(w, logger, err)
 		return
 	}
 
 	// Create user (is_admin=false, must_change_password=false)
-	_, err := s.store.CreateUser(ctx, req.Username, req.Password, req.Email, false, false)
+	newUserID, err := s.store.CreateUser(ctx, req.Username, req.Password, req.Email, false, false)
 	if err != nil {
 		logger.Error("registration failed", "username", req.Username, "error", err.Error())
 
 		// Check for duplicate username/email
 		if strings.Contains(err.Error(), "UNIQUE constraint failed") || strings.Contains(err.Error(), "duplicate") {
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusConflict) // 409
-			json.NewEncoder(w).Encode(map[string]interface{}{
-				"success": false,
-				"error":   "Username or email already exists",
-			})
+			writeError(w, http.StatusConflict, CodeConflict, "Username or email already exists")
 			return
 		}
 
 		// Server error
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(map[string]interface{}{
-			"success": false,
-			"error":   "Failed to create account",
-		})
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to create account")
 		return
 	}
 
+	s.publish(ctx, events.UserCreated, newUserID, map[string]interface{}{"username": req.Username})
+
 	// Return success response
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
@@ -1710,7 +1655,7 @@ func (s *Server) handleRegister(w http.ResponseWriter, r *http.Request) {
 // handleChangePassword changes the user's password
 func (s *Server) handleChangePassword(w http.ResponseWriter, r *http.Request) {
 	start := time.Now()
-	requestID := generateRequestID()
+	requestID := newRequestID(w)
 
 	// Create logger with request context
 	logger := s.logger.WithContext("request_id", requestID).
@@ -1719,19 +1664,13 @@ func (s *Server) handleChangePassword(w http.ResponseWriter, r *http.Request) {
 
 	logger.Debug("processing change password request")
 
-	if r.Method != http.MethodPost {
-		logger.Error("request failed", "operation", "method_check", "error", "method not allowed")
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
 	ctx := r.Context()
 
 	// Extract user_id from context (set by auth middleware)
 	userID, err := auth.GetUserID(ctx)
 	if err != nil {
 		logger.Error("request failed", "operation", "get_user_id", "error", err.Error())
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
 		return
 	}
 
@@ -1740,48 +1679,62 @@ func (s *Server) handleChangePassword(w http.ResponseWriter, r *http.Request) {
 		NewPassword     string `json:"new_password"`
 		ConfirmPassword string `json:"confirm_password"`
 	}
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		logger.Error("request failed", "operation", "parse_request", "error", err.Error())
-		http.Error(w, "Invalid request", http.StatusBadRequest)
+	if !decodeJSON(w, r, logger, &req) {
 		return
 	}
 
-	// Validate password confirmation
+	// Validate input
+	v := validate.New()
 	if req.NewPassword != req.ConfirmPassword {
-		logger.Warn("password change failed", "reason", "password mismatch")
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]interface{}{
-			"success": false,
-			"error":   "Passwords do not match",
-		})
+		v.Check("confirm_password", errors.New("Passwords do not match"))
+	}
+	v.Check("new_password", s.passwordPolicy().Check(req.NewPassword))
+	if err := v.Err(); err != nil {
+		writeValidationError(w, logger, err)
 		return
 	}
 
-	// Validate password strength (min 8 chars)
-	if len(req.NewPassword) < 8 {
-		logger.Warn("password change failed", "reason", "password too short")
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]interface{}{
-			"success": false,
-			"error":   "Password must be at least 8 characters",
-		})
+	// Reject the user's recent passwords
+	reused, err := s.store.IsRecentPassword(ctx, userID, req.NewPassword, s.passwordPolicy().HistoryCount())
+	if err != nil {
+		logger.Error("request failed", "operation", "check_password_history", "user_id", userID, "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to change password")
+		return
+	}
+	if reused {
+		v.Check("new_password", errors.New("Password was used recently; choose a new one"))
+		writeValidationError(w, logger, v.Err())
 		return
 	}
 
 	// Update password
 	if err := s.store.UpdatePassword(ctx, userID, req.NewPassword); err != nil {
 		logger.Error("password change failed", "user_id", userID, "error", err.Error())
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(map[string]interface{}{
-			"success": false,
-			"error":   "Failed to change password",
-		})
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to change password")
 		return
 	}
 
+	// Rotate this session's token and sign out every other session
+	keepToken := extractTokenFromRequest(r)
+	if keepToken != "" && s.authProvider != nil {
+		if newToken, err := s.authProvider.RefreshToken(ctx, keepToken); err != nil {
+			logger.Warn("session rotation failed", "user_id", userID, "error", err.Error())
+		} else {
+			keepToken = newToken
+			// Browser clients get the new token as a cookie of the same kind
+			if !strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ") {
+				maxAge := 0
+				if auth.RememberMe(ctx) {
+					maxAge = s.rememberMeMaxAge()
+				}
+				auth.SetSessionCookie(w, r, newToken, maxAge)
+			}
+		}
+	}
+	if err := s.store.DeleteUserSessionTokens(ctx, userID, keepToken); err != nil {
+		logger.Error("request failed", "operation", "revoke_sessions", "user_id", userID, "error", err.Error())
+	}
+
 	// Return success response
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
@@ -1813,44 +1766,10 @@ func extractTokenFromRequest(r *http.Request) string {
 	return ""
 }
 
-// isAdmin checks if the current user is an admin
-// Returns (isAdmin bool, userID int64, error)
-func (s *Server) isAdmin(ctx context.Context) (bool, int64, error) {
-	userID, err := auth.GetUserID(ctx)
-	if err != nil {
-		return false, 0, err
-	}
-
-	user, err := s.store.GetUserByID(ctx, userID)
-	if err != nil {
-		return false, 0, err
-	}
-
-	return user.IsAdmin, userID, nil
-}
-
-// generateRandomPassword generates a secure random password
-func generateRandomPassword(length int) (string, error) {
-	const charset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
-	password := make([]byte, length)
-
-	for i := range password {
-		// Generate random byte
-		randomBytes := make([]byte, 1)
-		if _, err := rand.Read(randomBytes); err != nil {
-			return "", err
-		}
-		// Map to charset
-		password[i] = charset[int(randomBytes[0])%len(charset)]
-	}
-
-	return string(password), nil
-}
-
 // handleGetUsers handles GET /api/users - list all users (admin only)
 func (s *Server) handleGetUsers(w http.ResponseWriter, r *http.Request) {
 	start := time.Now()
-	requestID := generateRequestID()
+	requestID := newRequestID(w)
 
 	logger := s.logger.WithContext("request_id", requestID).
 		WithContext("method", r.Method).
@@ -1860,25 +1779,11 @@ func (s *Server) handleGetUsers(w http.ResponseWriter, r *http.Request) {
 
 	ctx := r.Context()
 
-	// Check if current user is admin
-	isAdmin, userID, err := s.isAdmin(ctx)
-	if err != nil {
-		logger.Error("failed to get user from context", "error", err.Error())
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
-		return
-	}
-
-	if !isAdmin {
-		logger.Warn("non-admin user attempted to list users", "user_id", userID)
-		http.Error(w, "Forbidden: admin access required", http.StatusForbidden)
-		return
-	}
-
 	// Get all users
 	users, err := s.store.ListUsers(ctx)
 	if err != nil {
 		logger.Error("failed to list users", "error", err.Error())
-		http.Error(w, "Failed to retrieve users", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to retrieve users")
 		return
 	}
 
@@ -1916,7 +1821,7 @@ func (s *Server) handleGetUsers(w http.ResponseWriter, r *http.Request) {
 // handleCreateUser handles POST /api/users - create new user (admin only)
 func (s *Server) handleCreateUser(w http.ResponseWriter, r *http.Request) {
 	start := time.Now()
-	requestID := generateRequestID()
+	requestID := newRequestID(w)
 
 	logger := s.logger.WithContext("request_id", requestID).
 		WithContext("method", r.Method).
@@ -1926,20 +1831,6 @@ func (s *Server) handleCreateUser(w http.ResponseWriter, r *http.Request) {
 
 	ctx := r.Context()
 
-	// Check if current user is admin
-	isAdmin, userID, err := s.isAdmin(ctx)
-	if err != nil {
-		logger.Error("failed to get user from context", "error", err.Error())
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
-		return
-	}
-
-	if !isAdmin {
-		logger.Warn("non-admin user attempted to create user", "user_id", userID)
-		http.Error(w, "Forbidden: admin access required", http.StatusForbidden)
-		return
-	}
-
 	// Parse request body
 	var req struct {
 		Username string `json:"username"`
@@ -1948,67 +1839,44 @@ func (s *Server) handleCreateUser(w http.ResponseWriter, r *http.Request) {
 		IsAdmin  bool   `json:"is_admin"`
 	}
 
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		logger.Error("failed to decode request body", "error", err.Error())
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
-		return
-	}
-
-	// Validate input
-	if req.Username == "" {
-		http.Error(w, "Username is required", http.StatusBadRequest)
+	if !decodeJSON(w, r, logger, &req) {
 		return
 	}
 
-	if req.Password == "" {
-		http.Error(w, "Password is required", http.StatusBadRequest)
-		return
+	// Validate input; email is optional for accounts created by an admin
+	v := validate.New()
+	v.Required("username", "Username", req.Username)
+	v.Check("username", validate.Username(req.Username))
+	v.Required("password", "Password", req.Password)
+	v.Check("password", s.passwordPolicy().Check(req.Password))
+	if req.Email != "" {
+		v.Check("email", validate.Email(req.Email))
 	}
-
-	if len(req.Password) < 8 {
-		http.Error(w, "Password must be at least 8 characters", http.StatusBadRequest)
-		return
-	}
-
-	// Validate username format (alphanumeric and underscore only)
-	for _, c := range req.Username {
-		if !((c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') || c == '_') {
-			http.Error(w, "Username must contain only alphanumeric characters and underscores", http.StatusBadRequest)
-			return
-		}
-	}
-
-	// Validate email format if provided
-	if req.Email != "" && !strings.Contains(req.Email, "@") {
-		http.Error(w, "Invalid email format", http.StatusBadRequest)
+	if err := v.Err(); err != nil {
+		writeValidationError(w, logger, err)
 		return
 	}
 
 	// Create user
 	newUserID, err := s.store.CreateUser(ctx, req.Username, req.Password, req.Email, req.IsAdmin, false)
 	if err != nil {
-		if strings.Contains(err.Error(), "UNIQUE constraint failed") || strings.Contains(err.Error(), "unique") {
-			if strings.Contains(err.Error(), "username") {
-				logger.Warn("duplicate username", "username", req.Username)
-				http.Error(w, "Username already exists", http.StatusConflict)
-			} else if strings.Contains(err.Error(), "email") {
-				logger.Warn("duplicate email", "email", req.Email)
-				http.Error(w, "Email already registered", http.StatusConflict)
-			} else {
-				http.Error(w, "User already exists", http.StatusConflict)
-			}
+		if msg := userConflictMessage(err); msg != "" {
+			logger.Warn("duplicate user", "username", req.Username, "email", req.Email)
+			writeError(w, http.StatusConflict, CodeConflict, msg)
 			return
 		}
 		logger.Error("failed to create user", "error", err.Error())
-		http.Error(w, "Failed to create user", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to create user")
 		return
 	}
 
+	s.publish(ctx, events.UserCreated, newUserID, map[string]interface{}{"username": req.Username})
+
 	// Get created user
 	newUser, err := s.store.GetUserByID(ctx, newUserID)
 	if err != nil {
 		logger.Error("failed to get created user", "error", err.Error())
-		http.Error(w, "User created but failed to retrieve details", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, CodeInternal, "User created but failed to retrieve details")
 		return
 	}
 
@@ -2038,7 +1906,7 @@ func (s *Server) handleCreateUser(w http.ResponseWriter, r *http.Request) {
 // handleDeleteUser handles DELETE /api/users/:id - delete user (admin only)
 func (s *Server) handleDeleteUser(w http.ResponseWriter, r *http.Request) {
 	start := time.Now()
-	requestID := generateRequestID()
+	requestID := newRequestID(w)
 
 	logger := s.logger.WithContext("request_id", requestID).
 		WithContext("method", r.Method).
@@ -2048,38 +1916,24 @@ func (s *Server) handleDeleteUser(w http.ResponseWriter, r *http.Request) {
 
 	ctx := r.Context()
 
-	// Check if current user is admin
-	isAdmin, userID, err := s.isAdmin(ctx)
+	// Admin access is checked by the route's middleware
+	userID, err := auth.GetUserID(ctx)
 	if err != nil {
 		logger.Error("failed to get user from context", "error", err.Error())
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
-		return
-	}
-
-	if !isAdmin {
-		logger.Warn("non-admin user attempted to delete user", "user_id", userID)
-		http.Error(w, "Forbidden: admin access required", http.StatusForbidden)
-		return
-	}
-
-	// Extract target user ID from URL path
-	// Expected format: /api/users/:id
-	pathParts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
-	if len(pathParts) < 3 {
-		http.Error(w, "Invalid URL format", http.StatusBadRequest)
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
 		return
 	}
 
-	var targetUserID int64
-	if _, err := fmt.Sscanf(pathParts[2], "%d", &targetUserID); err != nil {
-		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+	targetUserID, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeBadRequest, "Invalid user ID")
 		return
 	}
 
 	// Prevent admin from deleting themselves
 	if targetUserID == userID {
 		logger.Warn("admin attempted to delete themselves", "user_id", userID)
-		http.Error(w, "Cannot delete your own account", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, CodeBadRequest, "Cannot delete your own account")
 		return
 	}
 
@@ -2087,14 +1941,20 @@ func (s *Server) handleDeleteUser(w http.ResponseWriter, r *http.Request) {
 	targetUser, err := s.store.GetUserByID(ctx, targetUserID)
 	if err != nil {
 		logger.Warn("target user not found", "target_user_id", targetUserID)
-		http.Error(w, "User not found", http.StatusNotFound)
+		writeError(w, http.StatusNotFound, CodeNotFound, "User not found")
 		return
 	}
 
-	// Delete user
-	if err := s.store.DeleteUser(ctx, targetUserID); err != nil {
+	// Delete user and record the audit entry as a single unit of work
+	err = s.store.WithTx(ctx, func(tx StoreTx) error {
+		if err := tx.DeleteUser(ctx, targetUserID); err != nil {
+			return err
+		}
+		return tx.AddAuditEntry(ctx, "delete", fmt.Sprintf("User: %s (ID %d)", targetUser.Username, targetUserID), "")
+	})
+	if err != nil {
 		logger.Error("failed to delete user", "target_user_id", targetUserID, "error", err.Error())
-		http.Error(w, "Failed to delete user", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to delete user")
 		return
 	}
 
@@ -2111,7 +1971,7 @@ func (s *Server) handleDeleteUser(w http.ResponseWriter, r *http.Request) {
 // handleResetUserPassword handles POST /api/users/:id/reset-password - reset user password (admin only)
 func (s *Server) handleResetUserPassword(w http.ResponseWriter, r *http.Request) {
 	start := time.Now()
-	requestID := generateRequestID()
+	requestID := newRequestID(w)
 
 	logger := s.logger.WithContext("request_id", requestID).
 		WithContext("method", r.Method).
@@ -2121,31 +1981,9 @@ func (s *Server) handleResetUserPassword(w http.ResponseWriter, r *http.Request)
 
 	ctx := r.Context()
 
-	// Check if current user is admin
-	isAdmin, userID, err := s.isAdmin(ctx)
+	targetUserID, err := pathID(r, "id")
 	if err != nil {
-		logger.Error("failed to get user from context", "error", err.Error())
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
-		return
-	}
-
-	if !isAdmin {
-		logger.Warn("non-admin user attempted to reset password", "user_id", userID)
-		http.Error(w, "Forbidden: admin access required", http.StatusForbidden)
-		return
-	}
-
-	// Extract target user ID from URL path
-	// Expected format: /api/users/:id/reset-password
-	pathParts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
-	if len(pathParts) < 3 {
-		http.Error(w, "Invalid URL format", http.StatusBadRequest)
-		return
-	}
-
-	var targetUserID int64
-	if _, err := fmt.Sscanf(pathParts[2], "%d", &targetUserID); err != nil {
-		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, CodeBadRequest, "Invalid user ID")
 		return
 	}
 
@@ -2153,36 +1991,35 @@ func (s *Server) handleResetUserPassword(w http.ResponseWriter, r *http.Request)
 	targetUser, err := s.store.GetUserByID(ctx, targetUserID)
 	if err != nil {
 		logger.Warn("target user not found", "target_user_id", targetUserID)
-		http.Error(w, "User not found", http.StatusNotFound)
+		writeError(w, http.StatusNotFound, CodeNotFound, "User not found")
 		return
 	}
 
-	// Generate random password
-	randomPassword, err := generateRandomPassword(16)
+	// Generate a random password that meets the policy
+	randomPassword, err := s.passwordPolicy().Generate()
 	if err != nil {
 		logger.Error("failed to generate random password", "error", err.Error())
-		http.Error(w, "Failed to generate password", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to generate password")
 		return
 	}
 
-	// Update password (this sets must_change_password to false by default)
-	if err := s.store.UpdatePassword(ctx, targetUserID, randomPassword); err != nil {
+	// The user must choose their own password at next sign-in
+	if err := s.store.SetTemporaryPassword(ctx, targetUserID, randomPassword); err != nil {
 		logger.Error("failed to update password", "target_user_id", targetUserID, "error", err.Error())
-		http.Error(w, "Failed to reset password", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to reset password")
 		return
 	}
 
-	// Note: The design mentions we need to set must_change_password=true after reset
-	// However, UpdatePassword sets it to false. We need to update the user record separately.
-	// For now, we'll document this as a known limitation and the user will need to change
-	// their password voluntarily. A proper implementation would require a new store method
-	// or modifying UpdatePassword to accept a must_change_password parameter.
+	// Sign the user out everywhere; the old password may have been compromised
+	if err := s.store.DeleteUserSessionTokens(ctx, targetUserID, ""); err != nil {
+		logger.Error("request failed", "operation", "revoke_sessions", "target_user_id", targetUserID, "error", err.Error())
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"success":            true,
 		"temporary_password": randomPassword,
-		"message":            "Password reset successfully. User should change password on next login.",
+		"message":            "Password reset successfully. User must change password on next login.",
 	})
 
 	latency := time.Since(start).Milliseconds()
@@ -2205,7 +2042,7 @@ func (s *Server) handleLoginPage(w http.ResponseWriter, r *http.Request) {
 	// Render login template
 	if err := s.templates.ExecuteTemplate(w, "login-content", data); err != nil {
 		s.logger.Error("Failed to render login template: %v", err)
-		http.Error(w, "Failed to render login page", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to render login page")
 		return
 	}
 }
@@ -2219,14 +2056,16 @@ func (s *Server) handleRegisterPage(w http.ResponseWriter, r *http.Request) {
 
 	// Prepare template data
 	data := map[string]interface{}{
-		"Title":   "Register",
-		"UIStyle": s.uiStyle,
+		"Title":             "Register",
+		"UIStyle":           s.uiStyle,
+		"PasswordMinLength": s.passwordPolicy().MinLength(),
+		"PasswordHint":      s.passwordPolicy().Hint(),
 	}
 
 	// Render register template
 	if err := s.templates.ExecuteTemplate(w, "register-content", data); err != nil {
 		s.logger.Error("Failed to render register template: %v", err)
-		http.Error(w, "Failed to render register page", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to render register page")
 		return
 	}
 }
@@ -2240,14 +2079,16 @@ func (s *Server) handleChangePasswordPage(w http.ResponseWriter, r *http.Request
 
 	// Prepare template data
 	data := map[string]interface{}{
-		"Title":   "Change Password",
-		"UIStyle": s.uiStyle,
+		"Title":             "Change Password",
+		"UIStyle":           s.uiStyle,
+		"PasswordMinLength": s.passwordPolicy().MinLength(),
+		"PasswordHint":      s.passwordPolicy().Hint(),
 	}
 
 	// Render change-password template
 	if err := s.templates.ExecuteTemplate(w, "change-password-content", data); err != nil {
 		s.logger.Error("Failed to render change-password template: %v", err)
-		http.Error(w, "Failed to render change password page", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to render change password page")
 		return
 	}
 }
@@ -2256,19 +2097,13 @@ func (s *Server) handleChangePasswordPage(w http.ResponseWriter, r *http.Request
 // Allows users to quickly switch between local and cloud AI providers
 func (s *Server) handlePrivacyToggle(w http.ResponseWriter, r *http.Request) {
 	start := time.Now()
-	requestID := generateRequestID()
+	requestID := newRequestID(w)
 
 	// Create logger with request context
 	logger := s.logger.WithContext("request_id", requestID).
 		WithContext("method", r.Method).
 		WithContext("path", r.URL.Path)
 
-	if r.Method != http.MethodPost {
-		logger.Warn("method not allowed", "method", r.Method)
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
 	logger.Debug("processing privacy toggle request")
 
 	// Parse JSON body
@@ -2277,12 +2112,7 @@ func (s *Server) handlePrivacyToggle(w http.ResponseWriter, r *http.Request) {
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		logger.Error("failed to parse request body", "error", err.Error())
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]interface{}{
-			"success": false,
-			"error":   "Invalid request body",
-		})
+		writeError(w, http.StatusBadRequest, CodeBadRequest, "Invalid request body")
 		return
 	}
 
@@ -2291,12 +2121,7 @@ func (s *Server) handlePrivacyToggle(w http.ResponseWriter, r *http.Request) {
 	// Validate mode
 	if req.Mode != "local" && req.Mode != "cloud" {
 		logger.Error("invalid mode", "mode", req.Mode)
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]interface{}{
-			"success": false,
-			"error":   "Invalid mode: must be 'local' or 'cloud'",
-		})
+		writeError(w, http.StatusBadRequest, CodeBadRequest, "Invalid mode: must be 'local' or 'cloud'")
 		return
 	}
 
@@ -2304,12 +2129,7 @@ func (s *Server) handlePrivacyToggle(w http.ResponseWriter, r *http.Request) {
 	cfg, err := config.Load(s.configPath)
 	if err != nil {
 		logger.Error("failed to load config", "error", err.Error())
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(map[string]interface{}{
-			"success": false,
-			"error":   "Failed to load configuration",
-		})
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to load configuration")
 		return
 	}
 
@@ -2322,12 +2142,7 @@ func (s *Server) handlePrivacyToggle(w http.ResponseWriter, r *http.Request) {
 	// Save configuration to disk
 	if err := cfg.Save(s.configPath); err != nil {
 		logger.Error("failed to save config", "error", err.Error())
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(map[string]interface{}{
-			"success": false,
-			"error":   "Failed to save configuration",
-		})
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to save configuration")
 		return
 	}
 
@@ -2359,64 +2174,33 @@ func (s *Server) handlePrivacyToggle(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// handleUpdatePreferences handles POST /api/user/preferences endpoint
-// Updates user preferences such as dark mode
-func (s *Server) handleUpdatePreferences(w http.ResponseWriter, r *http.Request) {
+// handlePrivacyStatus handles GET /api/privacy-toggle - whether answers use
+// the local or the cloud AI, in the same shape as the toggle's response
+func (s *Server) handlePrivacyStatus(w http.ResponseWriter, r *http.Request) {
 	start := time.Now()
-	requestID := generateRequestID()
+	requestID := newRequestID(w)
 
 	// Create logger with request context
 	logger := s.logger.WithContext("request_id", requestID).
 		WithContext("method", r.Method).
 		WithContext("path", r.URL.Path)
 
-	logger.Debug("processing update preferences request")
+	logger.Debug("processing privacy status request")
 
-	if r.Method != http.MethodPost {
-		logger.Error("request failed", "operation", "method_check", "error", "method not allowed")
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
+	mode := "cloud"
+	if s.providerManager.IsLocalMode() {
+		mode = "local"
 	}
 
-	ctx := r.Context()
-
-	// Extract user_id from context (set by auth middleware)
-	userID, err := auth.GetUserID(ctx)
-	if err != nil {
-		logger.Error("request failed", "operation", "get_user_id", "error", err.Error())
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
-		return
-	}
-
-	// Parse request
-	var req struct {
-		DarkMode bool `json:"dark_mode"`
-	}
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		logger.Error("request failed", "operation", "parse_request", "error", err.Error())
-		http.Error(w, "Invalid request", http.StatusBadRequest)
-		return
-	}
-
-	// Update user dark mode preference
-	if err := s.store.UpdateUserDarkMode(ctx, userID, req.DarkMode); err != nil {
-		logger.Error("preferences update failed", "user_id", userID, "error", err.Error())
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(map[string]interface{}{
-			"success": false,
-			"error":   "Failed to update preferences",
-		})
-		return
-	}
-
-	// Return success response
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"success": true,
-		"message": "Preferences updated successfully",
+		"success":              true,
+		"mode":                 mode,
+		"provider":             s.providerManager.GetProviderName(),
+		"rag_status":           s.ragEnforcer.GetRAGStatus(),
+		"transcripts_archived": s.transcriptsArchived(s.providerManager.IsLocalMode()),
 	})
 
 	latency := time.Since(start).Milliseconds()
-	logger.Debug("preferences update successful", "user_id", userID, "dark_mode", req.DarkMode, "latency_ms", latency)
+	logger.Debug("request completed", "status", http.StatusOK, "latency_ms", latency)
 }
@@ -0,0 +1,182 @@
+package api
+
+import (
+	"context"
+	"noodexx/internal/rag"
+	"strings"
+	"unicode"
+)
+
+const (
+	// snippetRunes is the length of a search result snippet, before ellipses
+	snippetRunes = 240
+	// snippetLead is how much text is kept ahead of the first highlight
+	snippetLead = 40
+	// maxHighlightSentences caps the sentences of one chunk embedded to find
+	// the one nearest the query
+	maxHighlightSentences = 8
+)
+
+// Search result match kinds
+const (
+	MatchTerms    = "terms"    // Words of the query were found in the chunk
+	MatchSemantic = "semantic" // The sentence nearest the query is highlighted
+)
+
+// Highlight marks a matched part of a snippet by character offsets, end exclusive
+type Highlight struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// isWordRune reports whether r is part of a word
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// queryTerms returns the distinct lowercased words of a query, ignoring
+// single characters
+func queryTerms(query string) []string {
+	seen := make(map[string]bool)
+	var terms []string
+	for _, word := range strings.FieldsFunc(strings.ToLower(query), func(r rune) bool { return !isWordRune(r) }) {
+		if len([]rune(word)) < 2 || seen[word] {
+			continue
+		}
+		seen[word] = true
+		terms = append(terms, word)
+	}
+	return terms
+}
+
+// termHighlights finds the words of text that start with one of the terms, the
+// way full-text prefix search matches them. Each highlight covers the whole word
+func termHighlights(text []rune, terms []string) []Highlight {
+	var highlights []Highlight
+	for i := 0; i < len(text); i++ {
+		if !isWordRune(text[i]) || (i > 0 && isWordRune(text[i-1])) {
+			continue
+		}
+		end := i
+		for end < len(text) && isWordRune(text[end]) {
+			end++
+		}
+		word := strings.ToLower(string(text[i:end]))
+		for _, term := range terms {
+			if strings.HasPrefix(word, term) {
+				highlights = append(highlights, Highlight{Start: i, End: end})
+				break
+			}
+		}
+		i = end
+	}
+	return highlights
+}
+
+// sentenceSpans splits text into sentences at ., ! and ? followed by a space,
+// and at line breaks, returning their character ranges without surrounding space
+func sentenceSpans(text []rune) []Highlight {
+	var spans []Highlight
+	start := 0
+	add := func(end int) {
+		for start < end && unicode.IsSpace(text[start]) {
+			start++
+		}
+		e := end
+		for e > start && unicode.IsSpace(text[e-1]) {
+			e--
+		}
+		if e > start {
+			spans = append(spans, Highlight{Start: start, End: e})
+		}
+		start = end
+	}
+	for i, r := range text {
+		switch {
+		case r == '\n':
+			add(i + 1)
+		case (r == '.' || r == '!' || r == '?') && i+1 < len(text) && unicode.IsSpace(text[i+1]):
+			add(i + 1)
+		}
+	}
+	add(len(text))
+	return spans
+}
+
+// nearestSentence returns the sentence of text whose embedding is closest to
+// queryVec, embedding at most maxHighlightSentences of them
+func nearestSentence(ctx context.Context, provider LLMProvider, text []rune, queryVec []float32) (Highlight, bool) {
+	var best Highlight
+	bestScore := -2.0
+	for i, span := range sentenceSpans(text) {
+		if i == maxHighlightSentences {
+			break
+		}
+		vec, err := provider.Embed(ctx, string(text[span.Start:span.End]))
+		if err != nil {
+			return Highlight{}, false
+		}
+		if score := rag.CosineSimilarity(queryVec, vec); score > bestScore {
+			best, bestScore = span, score
+		}
+	}
+	return best, bestScore > -2.0
+}
+
+// snippet cuts a window of snippetRunes characters from text holding as many
+// highlights as possible, starting a little ahead of the first. Ellipses mark
+// cut ends, and the highlights are returned as offsets into the snippet,
+// clipped to it
+func snippet(text []rune, highlights []Highlight) (string, []Highlight) {
+	start := 0
+	if len(text) > snippetRunes && len(highlights) > 0 {
+		bestCount := -1
+		for _, h := range highlights {
+			from := max(h.Start-snippetLead, 0)
+			count := 0
+			for _, other := range highlights {
+				if other.Start >= from && other.End <= from+snippetRunes {
+					count++
+				}
+			}
+			if count > bestCount {
+				start, bestCount = from, count
+			}
+		}
+		start = min(start, len(text)-snippetRunes)
+		// Start at a word rather than inside one; highlights start at words, so
+		// none is cut
+		for start > 0 && isWordRune(text[start-1]) && isWordRune(text[start]) {
+			start++
+		}
+	}
+	end := min(start+snippetRunes, len(text))
+	cutStart, cutEnd := start > 0, end < len(text)
+	for start < end && unicode.IsSpace(text[start]) {
+		start++
+	}
+	for end > start && unicode.IsSpace(text[end-1]) {
+		end--
+	}
+
+	var b strings.Builder
+	shift := -start
+	if cutStart {
+		b.WriteString("…")
+		shift++
+	}
+	b.WriteString(string(text[start:end]))
+	if cutEnd {
+		b.WriteString("…")
+	}
+
+	var clipped []Highlight
+	for _, h := range highlights {
+		s, e := max(h.Start, start), min(h.End, end)
+		if s >= e {
+			continue
+		}
+		clipped = append(clipped, Highlight{Start: s + shift, End: e + shift})
+	}
+	return b.String(), clipped
+}
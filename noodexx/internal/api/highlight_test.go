@@ -0,0 +1,73 @@
+package api
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+// marked returns the highlighted parts of a snippet
+func marked(snippet string, highlights []Highlight) []string {
+	runes := []rune(snippet)
+	var parts []string
+	for _, h := range highlights {
+		parts = append(parts, string(runes[h.Start:h.End]))
+	}
+	return parts
+}
+
+// TestTermHighlights tests that query words and words starting with them are
+// highlighted in the snippet
+func TestTermHighlights(t *testing.T) {
+	result := highlightResult(context.Background(), &mockProviderForAsk{}, Chunk{Source: "handbook.md", Text: "Refunds are paid within 30 days. The refund policy applies to all orders."}, "refund Policy?", nil, false)
+	if result.Match != MatchTerms {
+		t.Errorf("Expected a terms match, got %q", result.Match)
+	}
+	got := strings.Join(marked(result.Snippet, result.Highlights), ",")
+	if got != "Refunds,refund,policy" {
+		t.Errorf("Expected the query words to be highlighted, got %q", got)
+	}
+}
+
+// TestSnippetWindow tests that a long chunk is cut around its highlights with
+// offsets into the cut snippet
+func TestSnippetWindow(t *testing.T) {
+	text := strings.Repeat("filler words here ", 30) + "the naïve answer is forty-two " + strings.Repeat("more filler ", 30)
+	result := highlightResult(context.Background(), &mockProviderForAsk{}, Chunk{Text: text}, "naïve answer", nil, false)
+	if !strings.HasPrefix(result.Snippet, "…") || !strings.HasSuffix(result.Snippet, "…") {
+		t.Errorf("Expected both ends of the snippet to be cut, got %q", result.Snippet)
+	}
+	if n := len([]rune(result.Snippet)); n > snippetRunes+2 {
+		t.Errorf("Expected at most %d characters, got %d", snippetRunes+2, n)
+	}
+	if got := strings.Join(marked(result.Snippet, result.Highlights), ","); got != "naïve,answer" {
+		t.Errorf("Expected the highlights to point into the snippet, got %q", got)
+	}
+}
+
+// TestSemanticHighlight tests that without a word match the sentence nearest
+// the query is highlighted, only when allowed
+func TestSemanticHighlight(t *testing.T) {
+	provider := &mockProviderForAsk{
+		embedFunc: func(ctx context.Context, text string) ([]float32, error) {
+			if strings.Contains(text, "reimburse") {
+				return []float32{1, 0}, nil
+			}
+			return []float32{0, 1}, nil
+		},
+	}
+	chunk := Chunk{Text: "Offices open at nine. We reimburse travel within a month.\nParking is free."}
+
+	result := highlightResult(context.Background(), provider, chunk, "refund", []float32{1, 0}, true)
+	if result.Match != MatchSemantic {
+		t.Fatalf("Expected a semantic match, got %q", result.Match)
+	}
+	if got := marked(result.Snippet, result.Highlights); len(got) != 1 || got[0] != "We reimburse travel within a month." {
+		t.Errorf("Expected the nearest sentence to be highlighted, got %q", got)
+	}
+
+	result = highlightResult(context.Background(), provider, chunk, "refund", []float32{1, 0}, false)
+	if result.Match != "" || len(result.Highlights) != 0 || result.Snippet != chunk.Text {
+		t.Errorf("Expected no highlight without semantic matching, got %+v", result)
+	}
+}
@@ -0,0 +1,432 @@
+package api
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"noodexx/internal/auth"
+	"noodexx/internal/importer"
+	"noodexx/internal/ingest"
+	"noodexx/internal/validate"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	// importArchiveLimit caps the size of an uploaded export archive
+	importArchiveLimit = 256 << 20
+	// maxImportedFileSize caps one file of an export; the ingestion guardrails
+	// still apply to the documents read from it
+	maxImportedFileSize = 50 << 20
+	// maxImportedDocuments caps the documents of one import
+	maxImportedDocuments = 5000
+	// maxImportedChats caps the conversations of one chat history import
+	maxImportedChats = 10000
+)
+
+// ImportResult is the outcome of one document or file of an import
+type ImportResult struct {
+	Source     string   `json:"source"`
+	Collection string   `json:"collection,omitempty"`
+	Tags       []string `json:"tags,omitempty"`
+	Links      int      `json:"links,omitempty"` // Wikilinks kept with the document
+	Status     string   `json:"status"`          // "imported", "failed" or "skipped"
+	Error      string   `json:"error,omitempty"`
+}
+
+// ChatImportResult is the outcome of one conversation of a chat history import
+type ChatImportResult struct {
+	Title     string `json:"title"`
+	SessionID string `json:"session_id,omitempty"`
+	Messages  int    `json:"messages"`
+	Source    string `json:"source,omitempty"` // Document the conversation was embedded as
+	Status    string `json:"status"`           // "imported", "failed" or "skipped" when it was imported before
+	Error     string `json:"error,omitempty"`
+}
+
+// handleImport handles POST /api/import?format=... - import a zip of an
+// Obsidian vault, a Logseq graph, an AnythingLLM documents folder or PrivateGPT's
+// local_data into the user's library, with ?visibility or else the user's
+// default visibility. Every document is ingested on its own, so one bad note
+// does not stop the rest; the response lists each one
+func (s *Server) handleImport(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	requestID := newRequestID(w)
+
+	logger := s.logger.WithContext("request_id", requestID).
+		WithContext("method", r.Method).
+		WithContext("path", r.URL.Path)
+
+	logger.Debug("processing import request")
+
+	ctx := r.Context()
+
+	userID, err := auth.GetUserID(ctx)
+	if err != nil {
+		logger.Error("request failed", "operation", "get_user_id", "error", err.Error())
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	visibility := r.URL.Query().Get("visibility")
+	v := validate.New()
+	v.Check("format", validate.OneOf("format", format, importer.Formats...))
+	v.Check("visibility", validate.Visibility(visibility))
+	if err := v.Err(); err != nil {
+		writeValidationError(w, logger, err)
+		return
+	}
+
+	// The archive is the request body, or a file field of a form upload
+	var body io.Reader = r.Body
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			logger.Error("request failed", "operation", "get_file", "error", err.Error())
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				writeBodyTooLarge(w, tooLarge.Limit)
+				return
+			}
+			writeError(w, http.StatusBadRequest, CodeBadRequest, "A zip file is required")
+			return
+		}
+		defer file.Close()
+		body = file
+	}
+
+	// Zip archives are read from their end, so the upload is spooled to disk
+	archive, err := os.CreateTemp("", "noodexx-import-*.zip")
+	if err != nil {
+		logger.Error("request failed", "operation", "create_temp_file", "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to store the upload")
+		return
+	}
+	defer os.Remove(archive.Name())
+	defer archive.Close()
+
+	size, err := io.Copy(archive, body)
+	if err != nil {
+		logger.Error("request failed", "operation", "read_upload", "error", err.Error())
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeBodyTooLarge(w, tooLarge.Limit)
+			return
+		}
+		writeError(w, http.StatusBadRequest, CodeBadRequest, "Failed to read the upload")
+		return
+	}
+	zr, err := zip.NewReader(archive, size)
+	if err != nil {
+		logger.Error("request failed", "operation", "open_archive", "error", err.Error())
+		writeError(w, http.StatusBadRequest, CodeBadRequest, "The upload must be a zip archive")
+		return
+	}
+
+	docs, skipped, err := importer.Read(zr, format, maxImportedFileSize)
+	if err != nil {
+		logger.Error("request failed", "operation", "read_export", "format", format, "error", err.Error())
+		writeError(w, http.StatusBadRequest, CodeBadRequest, fmt.Sprintf("Failed to read the export: %v", err))
+		return
+	}
+	if len(docs) > maxImportedDocuments {
+		writeError(w, http.StatusBadRequest, CodeBadRequest, fmt.Sprintf("An import may hold at most %d documents, this one has %d", maxImportedDocuments, len(docs)))
+		return
+	}
+
+	// Ingesting a large vault takes longer than the server's write timeout
+	http.NewResponseController(w).SetWriteDeadline(time.Time{})
+	ingestCtx := ingest.WithVisibility(ctx, visibility)
+
+	results := make([]ImportResult, 0, len(docs)+len(skipped))
+	imported, failed := 0, 0
+	for _, doc := range docs {
+		if err := ctx.Err(); err != nil {
+			// The client went away; leave the rest of the export
+			logger.Warn("import cancelled", "imported", imported, "remaining", len(docs)-imported-failed)
+			break
+		}
+		result := ImportResult{Source: doc.Source, Collection: doc.Collection, Tags: doc.Tags, Links: len(doc.Links)}
+		if err := s.importDocument(ingestCtx, userID, doc); err != nil {
+			logger.Warn("failed to import document", "source", doc.Source, "error", err.Error())
+			result.Status, result.Error = "failed", err.Error()
+			result.Links = 0
+			failed++
+		} else {
+			result.Status = "imported"
+			imported++
+		}
+		results = append(results, result)
+	}
+	for _, skip := range skipped {
+		results = append(results, ImportResult{Source: skip.Path, Status: "skipped", Error: skip.Reason})
+	}
+
+	// Audit log
+	s.store.AddAuditEntry(ctx, "ingest", fmt.Sprintf("Import from %s: %d documents, %d failed", format, imported, failed), "")
+
+	// Notify the user
+	s.NotifyUser(ctx, userID, "ingestion", fmt.Sprintf("Imported %d documents from %s", imported, format), map[string]interface{}{"format": format, "imported": imported, "failed": failed})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":  true,
+		"format":   format,
+		"imported": imported,
+		"failed":   failed,
+		"skipped":  len(skipped),
+		"results":  results,
+	})
+
+	latency := time.Since(start).Milliseconds()
+	logger.Debug("request completed", "status", http.StatusOK, "latency_ms", latency, "format", format, "imported", imported, "failed", failed)
+}
+
+// importDocument ingests one document of an import and keeps its wikilinks
+func (s *Server) importDocument(ctx context.Context, userID int64, doc importer.Document) error {
+	if strings.TrimSpace(doc.Text) == "" {
+		return fmt.Errorf("document is empty")
+	}
+	v := validate.New()
+	v.Check("source", validate.Source(doc.Source))
+	if err := v.Err(); err != nil {
+		return err
+	}
+	if err := s.ingester.IngestText(ctx, userID, doc.Source, doc.Text, doc.Tags); err != nil {
+		return err
+	}
+	if err := s.store.SetDocumentLinks(ctx, userID, doc.Source, doc.Links); err != nil {
+		return fmt.Errorf("document was imported but its links were not saved: %w", err)
+	}
+	return nil
+}
+
+// handleImportChats handles POST /api/import/chats?format=... - recreate the
+// conversations of a ChatGPT or Claude data export as the user's sessions. The
+// upload is the export's zip or its conversations.json. With ?embed=true each
+// new conversation is also ingested as a document, with ?visibility or else
+// the user's default visibility, so answers can draw on it
+func (s *Server) handleImportChats(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	requestID := newRequestID(w)
+
+	logger := s.logger.WithContext("request_id", requestID).
+		WithContext("method", r.Method).
+		WithContext("path", r.URL.Path)
+
+	logger.Debug("processing chat import request")
+
+	ctx := r.Context()
+
+	userID, err := auth.GetUserID(ctx)
+	if err != nil {
+		logger.Error("request failed", "operation", "get_user_id", "error", err.Error())
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	visibility := r.URL.Query().Get("visibility")
+	embed := r.URL.Query().Get("embed") == "true"
+	v := validate.New()
+	v.Check("format", validate.OneOf("format", format, importer.ChatFormats...))
+	v.Check("visibility", validate.Visibility(visibility))
+	if err := v.Err(); err != nil {
+		writeValidationError(w, logger, err)
+		return
+	}
+
+	var body io.Reader = r.Body
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			logger.Error("request failed", "operation", "get_file", "error", err.Error())
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				writeBodyTooLarge(w, tooLarge.Limit)
+				return
+			}
+			writeError(w, http.StatusBadRequest, CodeBadRequest, "An export file is required")
+			return
+		}
+		defer file.Close()
+		body = file
+	}
+
+	upload, err := os.CreateTemp("", "noodexx-chats-*")
+	if err != nil {
+		logger.Error("request failed", "operation", "create_temp_file", "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to store the upload")
+		return
+	}
+	defer os.Remove(upload.Name())
+	defer upload.Close()
+
+	size, err := io.Copy(upload, body)
+	if err != nil {
+		logger.Error("request failed", "operation", "read_upload", "error", err.Error())
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeBodyTooLarge(w, tooLarge.Limit)
+			return
+		}
+		writeError(w, http.StatusBadRequest, CodeBadRequest, "Failed to read the upload")
+		return
+	}
+
+	// The export's zip, or the conversations.json taken out of it. Long
+	// histories make a conversations.json larger than other exported files
+	var conversations []importer.Conversation
+	if zr, zipErr := zip.NewReader(upload, size); zipErr == nil {
+		conversations, err = importer.ReadChats(zr, format, importArchiveLimit)
+	} else {
+		var data []byte
+		if _, err = upload.Seek(0, io.SeekStart); err == nil {
+			if data, err = io.ReadAll(upload); err == nil {
+				conversations, err = importer.ParseChats(data, format)
+			}
+		}
+	}
+	if err != nil {
+		logger.Error("request failed", "operation", "read_chats", "format", format, "error", err.Error())
+		writeError(w, http.StatusBadRequest, CodeBadRequest, fmt.Sprintf("Failed to read the chat history: %v", err))
+		return
+	}
+	if len(conversations) > maxImportedChats {
+		writeError(w, http.StatusBadRequest, CodeBadRequest, fmt.Sprintf("An import may hold at most %d conversations, this one has %d", maxImportedChats, len(conversations)))
+		return
+	}
+
+	// Embedding a long history takes longer than the server's write timeout
+	http.NewResponseController(w).SetWriteDeadline(time.Time{})
+	ingestCtx := ingest.WithVisibility(ctx, visibility)
+
+	results := make([]ChatImportResult, 0, len(conversations))
+	imported, skipped, failed := 0, 0, 0
+	for _, c := range conversations {
+		if err := ctx.Err(); err != nil {
+			logger.Warn("chat import cancelled", "imported", imported, "remaining", len(conversations)-len(results))
+			break
+		}
+		result := ChatImportResult{Title: c.Title, Messages: len(c.Messages)}
+		session := &ImportedSession{
+			SessionID:  generateSessionID(),
+			Format:     format,
+			ExternalID: c.ID,
+			Title:      c.Title,
+			CreatedAt:  c.CreatedAt,
+			Messages:   make([]ImportedMessage, len(c.Messages)),
+		}
+		if session.ExternalID == "" {
+			// Without an ID, the same title and start count as the same conversation
+			session.ExternalID = c.Source
+		}
+		for i, msg := range c.Messages {
+			session.Messages[i] = ImportedMessage{Role: msg.Role, Content: msg.Content, CreatedAt: msg.CreatedAt}
+		}
+
+		created, err := s.store.ImportChatSession(ctx, userID, session)
+		switch {
+		case err != nil:
+			logger.Warn("failed to import conversation", "title", c.Title, "error", err.Error())
+			result.Status, result.Error = "failed", err.Error()
+			failed++
+		case !created:
+			result.Status = "skipped"
+			skipped++
+		default:
+			result.Status, result.SessionID = "imported", session.SessionID
+			imported++
+			if embed {
+				if err := s.ingester.IngestText(ingestCtx, userID, c.Source, c.Transcript(), []string{"chat-import", format}); err != nil {
+					// The session stays; only its retrieval is missing
+					logger.Warn("failed to embed conversation", "title", c.Title, "error", err.Error())
+					result.Error = fmt.Sprintf("imported, but not embedded: %v", err)
+				} else {
+					result.Source = c.Source
+				}
+			}
+		}
+		results = append(results, result)
+	}
+
+	// Audit log
+	s.store.AddAuditEntry(ctx, "chat_import", fmt.Sprintf("Chat import from %s: %d conversations, %d already imported, %d failed", format, imported, skipped, failed), "")
+
+	s.NotifyUser(ctx, userID, "ingestion", fmt.Sprintf("Imported %d conversations from %s", imported, format), map[string]interface{}{"format": format, "imported": imported, "skipped": skipped, "failed": failed})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":  true,
+		"format":   format,
+		"imported": imported,
+		"skipped":  skipped,
+		"failed":   failed,
+		"results":  results,
+	})
+
+	latency := time.Since(start).Milliseconds()
+	logger.Debug("request completed", "status", http.StatusOK, "latency_ms", latency, "format", format, "imported", imported, "skipped", skipped, "failed", failed)
+}
+
+// chatFormatLabel names the tool a chat history format was exported from
+func chatFormatLabel(format string) string {
+	switch format {
+	case importer.ChatFormatChatGPT:
+		return "ChatGPT"
+	case importer.ChatFormatClaude:
+		return "Claude"
+	}
+	return format
+}
+
+// handleDocumentLinks handles GET /api/library/links?source=... - the wikilinks
+// an imported note makes, in the order they appear
+func (s *Server) handleDocumentLinks(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	requestID := newRequestID(w)
+
+	logger := s.logger.WithContext("request_id", requestID).
+		WithContext("method", r.Method).
+		WithContext("path", r.URL.Path)
+
+	ctx := r.Context()
+
+	userID, err := auth.GetUserID(ctx)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
+		return
+	}
+
+	source := r.URL.Query().Get("source")
+	if source == "" {
+		writeError(w, http.StatusBadRequest, CodeBadRequest, "source is required")
+		return
+	}
+
+	links, err := s.store.GetDocumentLinks(ctx, userID, source)
+	if err != nil {
+		logger.Error("request failed", "operation", "get_document_links", "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to get document links")
+		return
+	}
+	if links == nil {
+		links = []string{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"source":  source,
+		"links":   links,
+	})
+
+	latency := time.Since(start).Milliseconds()
+	logger.Debug("request completed", "status", http.StatusOK, "latency_ms", latency)
+}
@@ -0,0 +1,198 @@
+package api
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"noodexx/internal/auth"
+	"reflect"
+	"testing"
+)
+
+// linkStore keeps the links saved per source
+type linkStore struct {
+	mockStoreForAsk
+	links map[string][]string
+}
+
+func (m *linkStore) SetDocumentLinks(ctx context.Context, userID int64, source string, targets []string) error {
+	m.links[source] = targets
+	return nil
+}
+
+func (m *linkStore) GetDocumentLinks(ctx context.Context, userID int64, source string) ([]string, error) {
+	return m.links[source], nil
+}
+
+// zipArchive builds a zip archive of files
+func zipArchive(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		f, err := zw.Create(name)
+		if err != nil {
+			t.Fatalf("Failed to create %s: %v", name, err)
+		}
+		f.Write([]byte(content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("Failed to close archive: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// TestHandleImport tests importing a vault, keeping its tags and links and
+// reporting the notes that could not be ingested
+func TestHandleImport(t *testing.T) {
+	store := &linkStore{links: make(map[string][]string)}
+	ingester := &notesIngester{}
+	server := &Server{store: store, ingester: ingester, logger: &mockLoggerForAsk{}}
+
+	archive := zipArchive(t, map[string]string{
+		"Vault/.obsidian/app.json": "{}",
+		"Vault/Work/Plan.md":       "---\ntags: [q3]\n---\nSee [[Roadmap]] and [[Budget|the budget]].",
+		"Vault/Private.md":         "Please reject this note",
+		"Vault/Empty.md":           "",
+	})
+
+	importVault := func(format string, body []byte) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/import?format="+format, bytes.NewReader(body))
+		req = req.WithContext(context.WithValue(req.Context(), auth.UserIDKey, int64(1)))
+		w := httptest.NewRecorder()
+		server.handleImport(w, req)
+		return w
+	}
+
+	w := importVault("obsidian", archive)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Imported int            `json:"imported"`
+		Failed   int            `json:"failed"`
+		Results  []ImportResult `json:"results"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if resp.Imported != 1 || resp.Failed != 2 || len(resp.Results) != 3 {
+		t.Errorf("Expected 1 imported and 2 failed, got %+v", resp)
+	}
+
+	if !reflect.DeepEqual(ingester.sources, []string{"Work/Plan.md"}) || ingester.texts[0] != "See Roadmap and the budget." {
+		t.Errorf("Unexpected ingested documents %v %q", ingester.sources, ingester.texts)
+	}
+	if got := store.links["Work/Plan.md"]; !reflect.DeepEqual(got, []string{"Roadmap", "Budget"}) {
+		t.Errorf("Expected the wikilinks saved, got %v", got)
+	}
+	for _, result := range resp.Results {
+		if result.Source == "Work/Plan.md" && (result.Status != "imported" || !reflect.DeepEqual(result.Tags, []string{"q3", "Work"}) || result.Links != 2) {
+			t.Errorf("Unexpected result %+v", result)
+		}
+	}
+
+	if w := importVault("evernote", archive); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an unknown format, got %d", w.Code)
+	}
+	if w := importVault("obsidian", []byte("not a zip")); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a body that is not a zip, got %d", w.Code)
+	}
+
+	// The links of an imported note can be read back
+	req := httptest.NewRequest(http.MethodGet, "/api/library/links?source=Work/Plan.md", nil)
+	req = req.WithContext(context.WithValue(req.Context(), auth.UserIDKey, int64(1)))
+	w = httptest.NewRecorder()
+	server.handleDocumentLinks(w, req)
+	var links struct {
+		Links []string `json:"links"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &links)
+	if !reflect.DeepEqual(links.Links, []string{"Roadmap", "Budget"}) {
+		t.Errorf("Expected the note's links, got %s", w.Body.String())
+	}
+}
+
+// chatImportStore keeps imported sessions by their conversation ID
+type chatImportStore struct {
+	mockStoreForAsk
+	sessions map[string]*ImportedSession
+}
+
+func (m *chatImportStore) ImportChatSession(ctx context.Context, userID int64, session *ImportedSession) (bool, error) {
+	if _, ok := m.sessions[session.ExternalID]; ok {
+		return false, nil
+	}
+	m.sessions[session.ExternalID] = session
+	return true, nil
+}
+
+// TestHandleImportChats tests recreating exported conversations as sessions,
+// embedding them on request and skipping conversations imported before
+func TestHandleImportChats(t *testing.T) {
+	store := &chatImportStore{sessions: make(map[string]*ImportedSession)}
+	ingester := &notesIngester{}
+	server := &Server{store: store, ingester: ingester, logger: &mockLoggerForAsk{}}
+
+	importChats := func(query string, body []byte) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/import/chats"+query, bytes.NewReader(body))
+		req = req.WithContext(context.WithValue(req.Context(), auth.UserIDKey, int64(1)))
+		w := httptest.NewRecorder()
+		server.handleImportChats(w, req)
+		return w
+	}
+
+	conversations := `[
+		{"uuid": "a", "name": "Soup", "created_at": "2024-05-01T10:00:00Z", "chat_messages": [
+			{"sender": "human", "text": "Soup ideas?"}, {"sender": "assistant", "text": "Minestrone"}]},
+		{"uuid": "b", "name": "Secrets", "chat_messages": [{"sender": "human", "text": "Please reject this"}]}
+	]`
+	archive := zipArchive(t, map[string]string{"conversations.json": conversations, "users.json": "[]"})
+
+	w := importChats("?format=claude&embed=true", archive)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Imported int                `json:"imported"`
+		Skipped  int                `json:"skipped"`
+		Results  []ChatImportResult `json:"results"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if resp.Imported != 2 || len(resp.Results) != 2 {
+		t.Fatalf("Expected 2 imported conversations, got %+v", resp)
+	}
+	soup := store.sessions["a"]
+	if soup == nil || soup.Format != "claude" || soup.Title != "Soup" || len(soup.Messages) != 2 || soup.Messages[0].Role != "user" {
+		t.Errorf("Unexpected imported session %+v", soup)
+	}
+	if resp.Results[0].Source != "Claude chat: Soup (2024-05-01)" || resp.Results[0].SessionID != soup.SessionID {
+		t.Errorf("Expected the conversation embedded and its session returned, got %+v", resp.Results[0])
+	}
+	// A conversation the guardrails reject keeps its session, unembedded
+	if resp.Results[1].Status != "imported" || resp.Results[1].Source != "" || resp.Results[1].Error == "" {
+		t.Errorf("Expected the rejected conversation imported but not embedded, got %+v", resp.Results[1])
+	}
+	if !reflect.DeepEqual(ingester.sources, []string{"Claude chat: Soup (2024-05-01)"}) {
+		t.Errorf("Unexpected embedded documents %v", ingester.sources)
+	}
+
+	// The bare conversations.json works too, and imports nothing twice
+	w = importChats("?format=claude", []byte(conversations))
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if w.Code != http.StatusOK || resp.Imported != 0 || resp.Skipped != 2 {
+		t.Errorf("Expected both conversations skipped, got %d %s", w.Code, w.Body.String())
+	}
+
+	if w := importChats("?format=gemini", archive); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an unknown format, got %d", w.Code)
+	}
+	if w := importChats("?format=chatgpt", []byte("not json")); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an upload that is not an export, got %d", w.Code)
+	}
+}
@@ -0,0 +1,147 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// handleAdminJobs handles GET /api/admin/jobs - every background job with its
+// schedule and last run (admin only)
+func (s *Server) handleAdminJobs(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	requestID := newRequestID(w)
+
+	logger := s.logger.WithContext("request_id", requestID).
+		WithContext("method", r.Method).
+		WithContext("path", r.URL.Path)
+
+	if s.scheduler == nil {
+		writeError(w, http.StatusNotImplemented, CodeNotImplemented, "The job scheduler is not running")
+		return
+	}
+
+	jobs := s.scheduler.Jobs(r.Context())
+	if jobs == nil {
+		jobs = []ScheduledJob{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"jobs":    jobs,
+	})
+
+	latency := time.Since(start).Milliseconds()
+	logger.Debug("request completed", "status", http.StatusOK, "latency_ms", latency, "jobs", len(jobs))
+}
+
+// handleRunJob handles POST /api/admin/jobs/{name}/run - start a job now, even
+// when it is paused (admin only)
+func (s *Server) handleRunJob(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	requestID := newRequestID(w)
+
+	logger := s.logger.WithContext("request_id", requestID).
+		WithContext("method", r.Method).
+		WithContext("path", r.URL.Path)
+
+	logger.Debug("processing run job request")
+
+	if s.scheduler == nil {
+		writeError(w, http.StatusNotImplemented, CodeNotImplemented, "The job scheduler is not running")
+		return
+	}
+
+	name := r.PathValue("name")
+	err := s.scheduler.RunJob(name)
+	switch {
+	case errors.Is(err, ErrJobNotFound):
+		writeError(w, http.StatusNotFound, CodeNotFound, "Job not found")
+		return
+	case errors.Is(err, ErrJobRunning):
+		writeError(w, http.StatusConflict, CodeConflict, "Job is already running")
+		return
+	case err != nil:
+		logger.Error("request failed", "operation", "run_job", "job", name, "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to run job")
+		return
+	}
+	s.store.AddAuditEntry(r.Context(), "job", fmt.Sprintf("Ran job %s", name), "")
+
+	// The job runs in the background; its outcome shows in GET /api/admin/jobs
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+	})
+
+	latency := time.Since(start).Milliseconds()
+	logger.Debug("request completed", "status", http.StatusAccepted, "latency_ms", latency, "job", name)
+}
+
+// handlePauseJob handles POST /api/admin/jobs/{name}/pause - stop a job's
+// scheduled runs until it is resumed (admin only)
+func (s *Server) handlePauseJob(w http.ResponseWriter, r *http.Request) {
+	s.setJobPaused(w, r, true)
+}
+
+// handleResumeJob handles POST /api/admin/jobs/{name}/resume - restart a paused
+// job's scheduled runs (admin only)
+func (s *Server) handleResumeJob(w http.ResponseWriter, r *http.Request) {
+	s.setJobPaused(w, r, false)
+}
+
+// setJobPaused pauses or resumes the job named by the request's {name}
+func (s *Server) setJobPaused(w http.ResponseWriter, r *http.Request, paused bool) {
+	start := time.Now()
+	requestID := newRequestID(w)
+
+	logger := s.logger.WithContext("request_id", requestID).
+		WithContext("method", r.Method).
+		WithContext("path", r.URL.Path)
+
+	logger.Debug("processing job pause request", "paused", paused)
+
+	if s.scheduler == nil {
+		writeError(w, http.StatusNotImplemented, CodeNotImplemented, "The job scheduler is not running")
+		return
+	}
+
+	ctx := r.Context()
+	name := r.PathValue("name")
+	if err := s.scheduler.SetJobPaused(ctx, name, paused); err != nil {
+		if errors.Is(err, ErrJobNotFound) {
+			writeError(w, http.StatusNotFound, CodeNotFound, "Job not found")
+			return
+		}
+		logger.Error("request failed", "operation", "set_job_paused", "job", name, "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to update job")
+		return
+	}
+
+	action := "Resumed"
+	if paused {
+		action = "Paused"
+	}
+	s.store.AddAuditEntry(ctx, "job", fmt.Sprintf("%s job %s", action, name), "")
+
+	var job *ScheduledJob
+	for _, j := range s.scheduler.Jobs(ctx) {
+		if j.Name == name {
+			job = &j
+			break
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"job":     job,
+	})
+
+	latency := time.Since(start).Milliseconds()
+	logger.Debug("request completed", "status", http.StatusOK, "latency_ms", latency, "job", name, "paused", paused)
+}
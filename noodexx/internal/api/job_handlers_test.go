@@ -0,0 +1,103 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// mockJobScheduler records job runs and pauses
+type mockJobScheduler struct {
+	jobs []ScheduledJob
+	ran  []string
+}
+
+func (m *mockJobScheduler) Jobs(ctx context.Context) []ScheduledJob {
+	return m.jobs
+}
+
+func (m *mockJobScheduler) RunJob(name string) error {
+	for _, job := range m.jobs {
+		if job.Name == name {
+			if job.Running {
+				return ErrJobRunning
+			}
+			m.ran = append(m.ran, name)
+			return nil
+		}
+	}
+	return ErrJobNotFound
+}
+
+func (m *mockJobScheduler) SetJobPaused(ctx context.Context, name string, paused bool) error {
+	for i := range m.jobs {
+		if m.jobs[i].Name == name {
+			m.jobs[i].Paused = paused
+			return nil
+		}
+	}
+	return ErrJobNotFound
+}
+
+func TestJobHandlers(t *testing.T) {
+	server := &Server{store: &mockStoreForAdmin{}, logger: &mockLogger{}}
+	request := func(method, path string, userID int64) *httptest.ResponseRecorder {
+		return serveRoute(server, withUser(httptest.NewRequest(method, path, nil), userID))
+	}
+
+	if w := request(http.MethodGet, "/api/admin/jobs", 1); w.Code != http.StatusNotImplemented {
+		t.Errorf("Expected status 501 without a scheduler, got %d", w.Code)
+	}
+
+	jobs := &mockJobScheduler{jobs: []ScheduledJob{
+		{Name: "token_cleanup", Schedule: "@hourly"},
+		{Name: "wal_checkpoint", Schedule: "@every 5m", Running: true},
+	}}
+	server.SetJobScheduler(jobs)
+
+	w := request(http.MethodGet, "/api/admin/jobs", 1)
+	var resp struct {
+		Jobs []ScheduledJob `json:"jobs"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode jobs: %v", err)
+	}
+	if len(resp.Jobs) != 2 || resp.Jobs[0].Schedule != "@hourly" {
+		t.Errorf("Expected both jobs, got %+v", resp.Jobs)
+	}
+	if w := request(http.MethodGet, "/api/admin/jobs", 2); w.Code != http.StatusForbidden {
+		t.Errorf("Expected status 403 for a non-admin, got %d", w.Code)
+	}
+
+	if w := request(http.MethodPost, "/api/admin/jobs/token_cleanup/run", 1); w.Code != http.StatusAccepted {
+		t.Errorf("Expected status 202, got %d: %s", w.Code, w.Body.String())
+	}
+	if len(jobs.ran) != 1 || jobs.ran[0] != "token_cleanup" {
+		t.Errorf("Expected token_cleanup to run, got %v", jobs.ran)
+	}
+	if w := request(http.MethodPost, "/api/admin/jobs/wal_checkpoint/run", 1); w.Code != http.StatusConflict {
+		t.Errorf("Expected status 409 for a running job, got %d", w.Code)
+	}
+	if w := request(http.MethodPost, "/api/admin/jobs/defrag/run", 1); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for an unknown job, got %d", w.Code)
+	}
+
+	w = request(http.MethodPost, "/api/admin/jobs/token_cleanup/pause", 1)
+	var paused struct {
+		Job ScheduledJob `json:"job"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &paused); err != nil {
+		t.Fatalf("Failed to decode job: %v", err)
+	}
+	if !paused.Job.Paused || !jobs.jobs[0].Paused {
+		t.Errorf("Expected the job to be paused, got %+v", paused.Job)
+	}
+	if w := request(http.MethodPost, "/api/admin/jobs/token_cleanup/resume", 1); w.Code != http.StatusOK || jobs.jobs[0].Paused {
+		t.Errorf("Expected the job to be resumed, got status %d", w.Code)
+	}
+	if w := request(http.MethodPost, "/api/admin/jobs/defrag/pause", 1); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for an unknown job, got %d", w.Code)
+	}
+}
@@ -0,0 +1,161 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Lockout policy used when none is configured (auth.lockout_threshold and
+// auth.lockout_duration_minutes defaults)
+const (
+	defaultLockoutThreshold = 5
+	defaultLockoutDuration  = 15 * time.Minute
+)
+
+// LockoutPolicy locks an account after Threshold failed sign-ins within Duration
+type LockoutPolicy struct {
+	Threshold int
+	Duration  time.Duration
+}
+
+// withDefaults fills unset fields with the default policy
+func (p LockoutPolicy) withDefaults() LockoutPolicy {
+	if p.Threshold <= 0 {
+		p.Threshold = defaultLockoutThreshold
+	}
+	if p.Duration <= 0 {
+		p.Duration = defaultLockoutDuration
+	}
+	return p
+}
+
+// UserLockout is a user's recent failed sign-ins and lockout state
+type UserLockout struct {
+	UserID          int64      `json:"user_id"`
+	Username        string     `json:"username"`
+	Locked          bool       `json:"locked"`
+	LockedUntil     *time.Time `json:"locked_until,omitempty"`
+	FailedAttempts  int        `json:"failed_attempts"` // Within the last duration_minutes
+	Threshold       int        `json:"threshold"`
+	DurationMinutes int        `json:"duration_minutes"`
+}
+
+// handleGetUserLockout handles GET /api/users/{id}/lockout - show a user's lockout state (admin only)
+func (s *Server) handleGetUserLockout(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	requestID := newRequestID(w)
+
+	logger := s.logger.WithContext("request_id", requestID).
+		WithContext("method", r.Method).
+		WithContext("path", r.URL.Path)
+
+	logger.Debug("processing get user lockout request")
+
+	ctx := r.Context()
+
+	targetUserID, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeBadRequest, "Invalid user ID")
+		return
+	}
+
+	targetUser, err := s.store.GetUserByID(ctx, targetUserID)
+	if err != nil {
+		logger.Warn("target user not found", "target_user_id", targetUserID)
+		writeError(w, http.StatusNotFound, CodeNotFound, "User not found")
+		return
+	}
+
+	lockout, err := s.userLockout(r, targetUser)
+	if err != nil {
+		logger.Error("request failed", "operation", "count_failed_logins", "target_user_id", targetUserID, "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to read lockout state")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"lockout": lockout,
+	})
+
+	latency := time.Since(start).Milliseconds()
+	logger.Debug("request completed", "status", http.StatusOK, "latency_ms", latency, "target_user_id", targetUserID, "locked", lockout.Locked)
+}
+
+// handleClearUserLockout handles DELETE /api/users/{id}/lockout - forget a user's
+// failed sign-ins, unlocking the account (admin only)
+func (s *Server) handleClearUserLockout(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	requestID := newRequestID(w)
+
+	logger := s.logger.WithContext("request_id", requestID).
+		WithContext("method", r.Method).
+		WithContext("path", r.URL.Path)
+
+	logger.Debug("processing clear user lockout request")
+
+	ctx := r.Context()
+
+	targetUserID, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeBadRequest, "Invalid user ID")
+		return
+	}
+
+	targetUser, err := s.store.GetUserByID(ctx, targetUserID)
+	if err != nil {
+		logger.Warn("target user not found", "target_user_id", targetUserID)
+		writeError(w, http.StatusNotFound, CodeNotFound, "User not found")
+		return
+	}
+
+	if err := s.store.ClearFailedLogins(ctx, targetUser.Username); err != nil {
+		logger.Error("request failed", "operation", "clear_failed_logins", "target_user_id", targetUserID, "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to clear lockout")
+		return
+	}
+	s.store.AddAuditEntry(ctx, "unlock", fmt.Sprintf("User: %s (ID %d)", targetUser.Username, targetUserID), "")
+
+	lockout, err := s.userLockout(r, targetUser)
+	if err != nil {
+		logger.Error("request failed", "operation", "count_failed_logins", "target_user_id", targetUserID, "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to read lockout state")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"lockout": lockout,
+	})
+
+	latency := time.Since(start).Milliseconds()
+	logger.Debug("lockout cleared", "target_user_id", targetUserID, "target_username", targetUser.Username, "latency_ms", latency)
+}
+
+// userLockout reads a user's lockout state under the server's policy
+func (s *Server) userLockout(r *http.Request, user *User) (*UserLockout, error) {
+	ctx := r.Context()
+	policy := s.lockoutPolicy.withDefaults()
+
+	failed, err := s.store.CountFailedLogins(ctx, user.Username, time.Now().Add(-policy.Duration))
+	if err != nil {
+		return nil, err
+	}
+
+	lockout := &UserLockout{
+		UserID:          user.ID,
+		Username:        user.Username,
+		FailedAttempts:  failed,
+		Threshold:       policy.Threshold,
+		DurationMinutes: int(policy.Duration / time.Minute),
+	}
+	if locked, until := s.store.IsAccountLocked(ctx, user.Username, policy.Threshold, policy.Duration); locked {
+		lockout.Locked = true
+		lockout.LockedUntil = &until
+	}
+	return lockout, nil
+}
@@ -0,0 +1,102 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// mockStoreForLockout records failed sign-ins by username
+type mockStoreForLockout struct {
+	mockStoreForAdmin
+	failedLogins map[string][]time.Time
+}
+
+func (m *mockStoreForLockout) CountFailedLogins(ctx context.Context, username string, since time.Time) (int, error) {
+	count := 0
+	for _, attempt := range m.failedLogins[username] {
+		if attempt.After(since) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (m *mockStoreForLockout) IsAccountLocked(ctx context.Context, username string, threshold int, window time.Duration) (bool, time.Time) {
+	attempts := m.failedLogins[username]
+	count, _ := m.CountFailedLogins(ctx, username, time.Now().Add(-window))
+	if count < threshold {
+		return false, time.Time{}
+	}
+	return true, attempts[len(attempts)-threshold].Add(window)
+}
+
+func (m *mockStoreForLockout) ClearFailedLogins(ctx context.Context, username string) error {
+	delete(m.failedLogins, username)
+	return nil
+}
+
+func TestHandleUserLockout(t *testing.T) {
+	now := time.Now()
+	store := &mockStoreForLockout{failedLogins: map[string][]time.Time{
+		"user2": {now.Add(-50 * time.Minute), now.Add(-2 * time.Minute), now.Add(-time.Minute), now},
+	}}
+	server := &Server{
+		store:  store,
+		logger: &mockLogger{},
+	}
+	server.SetLockoutPolicy(LockoutPolicy{Threshold: 3, Duration: 30 * time.Minute})
+
+	readLockout := func(w *httptest.ResponseRecorder) UserLockout {
+		t.Helper()
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var resp struct {
+			Success bool        `json:"success"`
+			Lockout UserLockout `json:"lockout"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("failed to unmarshal response: %v", err)
+		}
+		return resp.Lockout
+	}
+
+	// Three attempts in the configured 30 minute window lock the account
+	w := serveRoute(server, withUser(httptest.NewRequest(http.MethodGet, "/api/users/2/lockout", nil), 1))
+	lockout := readLockout(w)
+	if !lockout.Locked || lockout.FailedAttempts != 3 || lockout.Threshold != 3 || lockout.DurationMinutes != 30 {
+		t.Errorf("Expected a locked account with 3 recent failures, got %+v", lockout)
+	}
+	if lockout.LockedUntil == nil || lockout.LockedUntil.Sub(now) > 30*time.Minute {
+		t.Errorf("Expected the lockout to end within the window, got %v", lockout.LockedUntil)
+	}
+
+	// Clearing unlocks it
+	w = serveRoute(server, withUser(httptest.NewRequest(http.MethodDelete, "/api/users/2/lockout", nil), 1))
+	lockout = readLockout(w)
+	if lockout.Locked || lockout.FailedAttempts != 0 || lockout.LockedUntil != nil {
+		t.Errorf("Expected the account to be unlocked, got %+v", lockout)
+	}
+
+	// Other users cannot see or clear lockouts
+	w = serveRoute(server, withUser(httptest.NewRequest(http.MethodDelete, "/api/users/2/lockout", nil), 2))
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Expected status 403 for a non-admin, got %d", w.Code)
+	}
+}
+
+func TestLockoutPolicyDefaults(t *testing.T) {
+	policy := LockoutPolicy{}.withDefaults()
+	if policy.Threshold != 5 || policy.Duration != 15*time.Minute {
+		t.Errorf("Expected the 5 attempts / 15 minutes default, got %+v", policy)
+	}
+
+	policy = LockoutPolicy{Threshold: 10, Duration: time.Hour}.withDefaults()
+	if policy.Threshold != 10 || policy.Duration != time.Hour {
+		t.Errorf("Expected the configured policy to be kept, got %+v", policy)
+	}
+}
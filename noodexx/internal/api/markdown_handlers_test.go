@@ -0,0 +1,56 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"noodexx/internal/auth"
+	"strings"
+	"testing"
+	"time"
+)
+
+// historyStore serves one session whose messages hold markdown and raw HTML
+type historyStore struct {
+	mockStoreForAsk
+}
+
+func (m *historyStore) GetSessionMessages(ctx context.Context, userID int64, sessionID string) ([]ChatMessage, error) {
+	return []ChatMessage{
+		{Role: "user", Content: "Is <img src=x onerror=alert(1)> safe?"},
+		{Role: "assistant", Content: "**No.** Use:\n\n```html\n<img alt=\"x\">\n```", ProviderMode: "local"},
+	}, nil
+}
+
+func (m *historyStore) GetUserSessions(ctx context.Context, userID int64) ([]Session, error) {
+	return []Session{{ID: `s1');alert(1);('`, MessageCount: 2, LastMessageAt: time.Now()}}, nil
+}
+
+// TestSessionFragmentsRenderMarkdown tests that session history renders
+// markdown with raw HTML stripped, and that session IDs are escaped
+func TestSessionFragmentsRenderMarkdown(t *testing.T) {
+	server := &Server{store: &historyStore{}, logger: &mockLoggerForAsk{}}
+	get := func(handler http.HandlerFunc, path string) string {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.SetPathValue("id", "s1")
+		req = req.WithContext(context.WithValue(req.Context(), auth.UserIDKey, int64(1)))
+		w := httptest.NewRecorder()
+		handler(w, req)
+		return w.Body.String()
+	}
+
+	body := get(server.handleSessionHistory, "/api/session/s1")
+	if strings.Contains(body, "<img") {
+		t.Errorf("Expected raw HTML stripped, got %s", body)
+	}
+	for _, want := range []string{"<strong>No.</strong>", `<code class="language-html">&lt;img alt=&#34;x&#34;&gt;</code>`} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected %s in %s", want, body)
+		}
+	}
+
+	body = get(server.handleSessions, "/api/sessions")
+	if strings.Contains(body, `');alert(1)`) {
+		t.Errorf("Expected the session ID escaped, got %s", body)
+	}
+}
@@ -0,0 +1,136 @@
+package api
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"noodexx/internal/auth"
+	"noodexx/internal/metaquery"
+	"strconv"
+	"time"
+)
+
+// metadataQueryRequest is the body of POST /api/metadata/query: a preset, a
+// query, or a preset with extra filters
+type metadataQueryRequest struct {
+	Preset string `json:"preset,omitempty"`
+	metaquery.Query
+}
+
+// handleGetMetadataQueries handles GET /api/metadata/queries - the preset
+// queries and the fields each entity can be queried by
+func (s *Server) handleGetMetadataQueries(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":  true,
+		"presets":  metaquery.Presets,
+		"entities": metaquery.Fields,
+	})
+}
+
+// handleMetadataQuery handles POST /api/metadata/query - filter, group and
+// aggregate the user's documents, chunks or sessions, as JSON or, with
+// ?format=csv, as CSV
+func (s *Server) handleMetadataQuery(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	requestID := newRequestID(w)
+
+	logger := s.logger.WithContext("request_id", requestID).
+		WithContext("method", r.Method).
+		WithContext("path", r.URL.Path)
+
+	logger.Debug("processing metadata query request")
+
+	ctx := r.Context()
+
+	userID, err := auth.GetUserID(ctx)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "json" && format != "csv" {
+		writeError(w, http.StatusBadRequest, CodeBadRequest, "format must be json or csv")
+		return
+	}
+
+	var req metadataQueryRequest
+	if !decodeJSON(w, r, logger, &req) {
+		return
+	}
+	query := req.Query
+	if req.Preset != "" {
+		preset, ok := metaquery.FindPreset(req.Preset)
+		if !ok {
+			writeError(w, http.StatusBadRequest, CodeBadRequest, fmt.Sprintf("Unknown preset %q", req.Preset))
+			return
+		}
+		if req.Entity != "" || len(req.GroupBy) > 0 || len(req.Aggregates) > 0 || req.OrderBy != "" {
+			writeError(w, http.StatusBadRequest, CodeBadRequest, "A preset can only be combined with filters and a limit")
+			return
+		}
+		preset.Filters = append(preset.Filters, req.Filters...)
+		if req.Limit != 0 {
+			preset.Limit = req.Limit
+		}
+		query = preset
+	}
+	if err := query.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, CodeValidationFailed, err.Error())
+		return
+	}
+
+	rows, err := s.store.GetMetadataRows(ctx, userID, query.Entity)
+	if err != nil {
+		logger.Error("request failed", "operation", "get_metadata_rows", "entity", query.Entity, "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to query metadata")
+		return
+	}
+	result, err := metaquery.Run(query, rows)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeValidationFailed, err.Error())
+		return
+	}
+
+	if format == "csv" {
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", query.Entity+"-query.csv"))
+		cw := csv.NewWriter(w)
+		cw.Write(result.Columns)
+		for _, row := range result.Rows {
+			record := make([]string, len(row))
+			for i, value := range row {
+				record[i] = metadataCSVValue(value)
+			}
+			cw.Write(record)
+		}
+		cw.Flush()
+	} else {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"query":   query,
+			"columns": result.Columns,
+			"rows":    result.Rows,
+			"total":   result.Total,
+		})
+	}
+
+	latency := time.Since(start).Milliseconds()
+	logger.Debug("request completed", "status", http.StatusOK, "latency_ms", latency, "entity", query.Entity, "scanned", len(rows), "groups", result.Total)
+}
+
+// metadataCSVValue formats a result cell: numbers without trailing zeros and
+// empty groups as blank
+func metadataCSVValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
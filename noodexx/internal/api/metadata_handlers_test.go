@@ -0,0 +1,76 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"noodexx/internal/metaquery"
+	"strings"
+	"testing"
+	"time"
+)
+
+// mockStoreForMetadata returns three documents for user 1
+type mockStoreForMetadata struct {
+	mockStore
+	entity string
+}
+
+func (m *mockStoreForMetadata) GetMetadataRows(ctx context.Context, userID int64, entity string) ([]metaquery.Row, error) {
+	m.entity = entity
+	if userID != 1 || entity != metaquery.Documents {
+		return nil, nil
+	}
+	jan := time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC)
+	feb := time.Date(2024, 2, 3, 0, 0, 0, 0, time.UTC)
+	return []metaquery.Row{
+		{"source": "nda.pdf", "type": "pdf", "tags": []string{"contracts"}, "chunks": 4.0, "created_at": jan},
+		{"source": "lease.pdf", "type": "pdf", "tags": []string{"contracts"}, "chunks": 2.5, "created_at": feb},
+		{"source": "notes.md", "type": "md", "chunks": 1.0, "created_at": feb},
+	}, nil
+}
+
+func TestHandleMetadataQuery(t *testing.T) {
+	store := &mockStoreForMetadata{}
+	server := &Server{store: store, logger: &mockLogger{}}
+
+	query := func(path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		return serveRoute(server, withUser(req, 1))
+	}
+
+	w := query("/api/metadata/query", `{"preset":"documents_per_month","filters":[{"field":"tags","op":"eq","value":"contracts"}]}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if !strings.Contains(w.Body.String(), `"rows":[["2024-01",1],["2024-02",1]]`) {
+		t.Errorf("Expected contracts per month, got %s", w.Body.String())
+	}
+
+	w = query("/api/metadata/query?format=csv", `{"entity":"documents","group_by":["type"],"aggregates":[{"fn":"sum","field":"chunks"}]}`)
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "text/csv; charset=utf-8" {
+		t.Fatalf("Expected CSV, got %d: %v", w.Code, w.Header())
+	}
+	if got := w.Body.String(); got != "type,sum_chunks\nmd,1\npdf,6.5\n" {
+		t.Errorf("Unexpected CSV: %q", got)
+	}
+
+	for _, body := range []string{
+		`{"entity":"users"}`,
+		`{"preset":"nope"}`,
+		`{"preset":"documents_per_month","group_by":["type"]}`,
+		`{"entity":"documents","filters":[{"field":"text","op":"contains","value":"secret"}]}`,
+	} {
+		if w := query("/api/metadata/query", body); w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for %s, got %d", body, w.Code)
+		}
+	}
+	if w := query("/api/metadata/query?format=xml", `{"entity":"documents"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an unknown format, got %d", w.Code)
+	}
+
+	w = serveRoute(server, withUser(httptest.NewRequest(http.MethodGet, "/api/metadata/queries", nil), 1))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"documents_per_tag"`) {
+		t.Errorf("Expected the presets, got %d: %s", w.Code, w.Body.String())
+	}
+}
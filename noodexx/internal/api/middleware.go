@@ -0,0 +1,225 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"noodexx/internal/auth"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// middleware wraps a handler with behaviour shared by several routes
+type middleware func(http.Handler) http.Handler
+
+// chain wraps h in mws; the first middleware listed runs first
+func chain(h http.Handler, mws ...middleware) http.Handler {
+	for i := len(mws) - 1; i >= 0; i-- {
+		h = mws[i](h)
+	}
+	return h
+}
+
+// router registers method-qualified patterns ("GET /api/users/{id}") on a ServeMux
+// Other methods on a registered path are answered with a 405 in the API error
+// format rather than the mux's plain-text one
+type router struct {
+	mux     *http.ServeMux
+	methods map[string][]string                                  // Methods registered per path
+	paths   []string                                             // Paths in registration order
+	routes  []string                                             // Patterns in registration order
+	observe func(pattern string, next http.Handler) http.Handler // Wraps every route outside its middleware, nil for none
+}
+
+// newRouter creates a router on mux
+func newRouter(mux *http.ServeMux) *router {
+	return &router{mux: mux, methods: make(map[string][]string)}
+}
+
+// handle registers h for pattern, wrapped in mws
+func (rt *router) handle(pattern string, h http.HandlerFunc, mws ...middleware) {
+	method, path, ok := strings.Cut(pattern, " ")
+	if !ok {
+		panic(fmt.Sprintf("route %q has no method", pattern))
+	}
+	handler := chain(h, mws...)
+	if rt.observe != nil {
+		handler = rt.observe(pattern, handler)
+	}
+	rt.mux.Handle(pattern, handler)
+	rt.routes = append(rt.routes, pattern)
+
+	if _, seen := rt.methods[path]; !seen {
+		rt.paths = append(rt.paths, path)
+	}
+	rt.methods[path] = append(rt.methods[path], method)
+	if method == http.MethodGet {
+		rt.methods[path] = append(rt.methods[path], http.MethodHead) // The mux serves HEAD with GET handlers
+	}
+}
+
+// routeMethods are the methods methodNotAllowed answers for; a GET pattern
+// also matches HEAD
+var routeMethods = []string{
+	http.MethodGet, http.MethodPost, http.MethodPut,
+	http.MethodPatch, http.MethodDelete, http.MethodOptions,
+}
+
+// methodNotAllowed registers the 405 responses of every path handled so far
+// Each is registered per method: a pattern matching every method, such as
+// "/api/documents/visibility", would conflict with a wildcard route of
+// another method, such as "PATCH /api/documents/{id}", and make the mux panic
+func (rt *router) methodNotAllowed() {
+	for _, path := range rt.paths {
+		allow := strings.Join(rt.methods[path], ", ")
+		notAllowed := func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Allow", allow)
+			writeError(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "Method not allowed")
+		}
+		for _, method := range routeMethods {
+			if !slices.Contains(rt.methods[path], method) {
+				rt.mux.HandleFunc(method+" "+path, notAllowed)
+			}
+		}
+	}
+}
+
+// pathID parses the integer path wildcard name, such as {id} in /api/users/{id}
+func pathID(r *http.Request, name string) (int64, error) {
+	return strconv.ParseInt(r.PathValue(name), 10, 64)
+}
+
+// requireUser rejects requests without a signed-in user
+func (s *Server) requireUser(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := auth.GetUserID(r.Context()); err != nil {
+			writeError(w, http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requireAdmin rejects requests unless the signed-in user is an admin
+func (s *Server) requireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		userID, err := auth.GetUserID(ctx)
+		if err != nil {
+			writeError(w, http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
+			return
+		}
+
+		user, err := s.store.GetUserByID(ctx, userID)
+		if err != nil || user == nil {
+			writeError(w, http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
+			return
+		}
+		if !user.IsAdmin {
+			s.logger.Warn("non-admin user attempted admin request", "user_id", userID, "method", r.Method, "path", r.URL.Path)
+			writeError(w, http.StatusForbidden, CodeForbidden, "Forbidden: admin access required")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// sameOrigin rejects state-changing browser requests made from another site,
+// which would otherwise ride on the session cookie (CSRF)
+// Requests authenticated by a bearer token carry no ambient credentials and pass,
+// as do clients that send neither Origin nor Referer
+func sameOrigin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			next.ServeHTTP(w, r)
+			return
+		}
+		if strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ") || isSameOrigin(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		writeError(w, http.StatusForbidden, CodeForbidden, "Cross-site request rejected")
+	})
+}
+
+// isSameOrigin reports whether the Origin (or, without one, the Referer) of r
+// names the host r was sent to; requests with neither are treated as same-origin
+func isSameOrigin(r *http.Request) bool {
+	source := r.Header.Get("Origin")
+	if source == "" {
+		source = r.Header.Get("Referer")
+	}
+	if source == "" {
+		return true
+	}
+	u, err := url.Parse(source)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(u.Host, r.Host)
+}
+
+// rateLimiter allows a fixed number of requests per client within each window
+type rateLimiter struct {
+	limit  int
+	window time.Duration
+	now    func() time.Time
+
+	mu      sync.Mutex
+	clients map[string]*rateWindow
+}
+
+// rateWindow counts one client's requests in the current window
+type rateWindow struct {
+	start time.Time
+	count int
+}
+
+// newRateLimiter creates a limiter allowing limit requests per window
+func newRateLimiter(limit int, window time.Duration) *rateLimiter {
+	return &rateLimiter{limit: limit, window: window, now: time.Now, clients: make(map[string]*rateWindow)}
+}
+
+// allow records a request from key, returning false and the time until the
+// window resets if the limit is used up
+func (l *rateLimiter) allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	win, ok := l.clients[key]
+	if !ok || now.Sub(win.start) >= l.window {
+		// Drop expired windows as new ones open, so the map stays bounded by active clients
+		for k, w := range l.clients {
+			if now.Sub(w.start) >= l.window {
+				delete(l.clients, k)
+			}
+		}
+		win = &rateWindow{start: now}
+		l.clients[key] = win
+	}
+	if win.count >= l.limit {
+		return false, win.start.Add(l.window).Sub(now)
+	}
+	win.count++
+	return true, 0
+}
+
+// rateLimit rejects requests from a client address over the limiter's limit
+func rateLimit(l *rateLimiter) middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ok, retryAfter := l.allow(clientIP(r))
+			if !ok {
+				w.Header().Set("Retry-After", fmt.Sprintf("%d", int(retryAfter.Seconds())+1))
+				writeError(w, http.StatusTooManyRequests, CodeRateLimited, "Too many requests, please try again later")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,543 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"noodexx/internal/eval"
+	"noodexx/internal/metaquery"
+	"time"
+)
+
+// baseMockStore is a Store that keeps nothing and finds nothing: writes succeed,
+// lookups return empty results and the settings every request reads return their
+// defaults. Test stores embed it and override only the methods their tests
+// depend on, so a new Store method needs adding here alone
+type baseMockStore struct{}
+
+func (m baseMockStore) SaveChunk(ctx context.Context, source, text string, embedding []float32, tags []string, summary string) error {
+	return nil
+}
+
+func (m baseMockStore) Search(ctx context.Context, queryVec []float32, topK int) ([]Chunk, error) {
+	return nil, nil
+}
+
+func (m baseMockStore) SearchByUser(ctx context.Context, userID int64, queryVec []float32, queryModel string, topK int) ([]Chunk, error) {
+	return nil, nil
+}
+
+func (m baseMockStore) SearchUserSources(ctx context.Context, userID int64, queryVec []float32, queryModel string, sources []string, topK int) ([]Chunk, error) {
+	return nil, nil
+}
+
+func (m baseMockStore) SearchLibrary(ctx context.Context, userID int64, queryVec []float32, opts SearchOptions, topK int) ([]Chunk, error) {
+	return nil, nil
+}
+
+func (m baseMockStore) GetSourceChunks(ctx context.Context, userID int64, source string) ([]Chunk, error) {
+	return nil, nil
+}
+
+func (m baseMockStore) Library(ctx context.Context) ([]LibraryEntry, error) {
+	return nil, nil
+}
+
+func (m baseMockStore) LibraryByUser(ctx context.Context, userID int64) ([]LibraryEntry, error) {
+	return nil, nil
+}
+
+func (m baseMockStore) ForEachLibraryEntry(ctx context.Context, userID int64, fn func(LibraryEntry) bool) error {
+	return nil
+}
+
+func (m baseMockStore) DeleteSource(ctx context.Context, source string) error {
+	return nil
+}
+
+func (m baseMockStore) GetDocument(ctx context.Context, userID, documentID int64) (*Document, error) {
+	return nil, nil
+}
+
+func (m baseMockStore) RenameDocument(ctx context.Context, userID, documentID int64, title string) error {
+	return nil
+}
+
+func (m baseMockStore) PinDocument(ctx context.Context, userID, documentID int64) (bool, error) {
+	return true, nil
+}
+
+func (m baseMockStore) UnpinDocument(ctx context.Context, userID, documentID int64) error {
+	return nil
+}
+
+func (m baseMockStore) AddQuickNote(ctx context.Context, userID int64, source, text string, dedupSince time.Time) (int64, error) {
+	return 0, nil
+}
+
+func (m baseMockStore) GetQuickNotes(ctx context.Context, userID int64, source string) ([]QuickNote, error) {
+	return nil, nil
+}
+
+func (m baseMockStore) DeleteQuickNote(ctx context.Context, userID, noteID int64) error {
+	return nil
+}
+
+func (m baseMockStore) GetDefaultVisibility(ctx context.Context, userID int64) (string, error) {
+	return "private", nil
+}
+
+func (m baseMockStore) SetDefaultVisibility(ctx context.Context, userID int64, visibility string) error {
+	return nil
+}
+
+func (m baseMockStore) SetDocumentsVisibility(ctx context.Context, userID int64, documentIDs []int64, visibility string) (int, error) {
+	return 0, nil
+}
+
+func (m baseMockStore) CountChunkVisibility(ctx context.Context, chunkIDs []int64) (map[string]int, error) {
+	return nil, nil
+}
+
+func (m baseMockStore) GetCostThreshold(ctx context.Context, userID int64) (*float64, error) {
+	return nil, nil
+}
+
+func (m baseMockStore) SetCostThreshold(ctx context.Context, userID int64, threshold *float64) error {
+	return nil
+}
+
+func (m baseMockStore) SaveCostEstimate(ctx context.Context, e CostEstimate) error {
+	return nil
+}
+
+func (m baseMockStore) GetCostEstimates(ctx context.Context, userID int64, limit int) ([]CostEstimate, error) {
+	return nil, nil
+}
+
+func (m baseMockStore) SetDocumentLinks(ctx context.Context, userID int64, source string, targets []string) error {
+	return nil
+}
+
+func (m baseMockStore) GetDocumentLinks(ctx context.Context, userID int64, source string) ([]string, error) {
+	return nil, nil
+}
+
+func (m baseMockStore) GetDocumentEncoding(ctx context.Context, userID int64, source string) (*DocumentEncoding, error) {
+	return nil, nil
+}
+
+func (m baseMockStore) RecordChunkAccess(ctx context.Context, access ChunkAccess, chunkIDs []int64, tag string) (int, error) {
+	return 0, nil
+}
+
+func (m baseMockStore) GetChunkAccessLog(ctx context.Context, ownerID int64, source string, since time.Time, limit int) ([]ChunkAccess, error) {
+	return nil, nil
+}
+
+func (m baseMockStore) RecordRetrievals(ctx context.Context, hits []RetrievalHit) error {
+	return nil
+}
+
+func (m baseMockStore) GetRetrievalStats(ctx context.Context, userID int64, since time.Time) ([]SourceRetrieval, error) {
+	return nil, nil
+}
+
+func (m baseMockStore) SaveMessage(ctx context.Context, sessionID, role, content string) error {
+	return nil
+}
+
+func (m baseMockStore) SaveChatMessage(ctx context.Context, userID int64, sessionID, role, content, providerMode string) error {
+	return nil
+}
+
+func (m baseMockStore) SaveChatMessageWithCitations(ctx context.Context, userID int64, sessionID, role, content, providerMode string, citations []string) error {
+	return nil
+}
+
+func (m baseMockStore) SaveChatMessageWithProvenance(ctx context.Context, userID int64, sessionID, role, content, providerMode string, citations []string, provenance *MessageProvenance) (int64, error) {
+	return 0, nil
+}
+
+func (m baseMockStore) GetMessageProvenance(ctx context.Context, userID, messageID int64) (*MessageProvenance, error) {
+	return nil, nil
+}
+
+func (m baseMockStore) GetResponseStats(ctx context.Context, userID int64, since time.Time) ([]ResponseStats, error) {
+	return nil, nil
+}
+
+func (m baseMockStore) SaveMessageArtifacts(ctx context.Context, messageID int64, artifacts []MessageArtifact) error {
+	return nil
+}
+
+func (m baseMockStore) GetMessageArtifacts(ctx context.Context, userID, messageID int64) ([]MessageArtifact, error) {
+	return nil, nil
+}
+
+func (m baseMockStore) GetSessionHistory(ctx context.Context, sessionID string) ([]ChatMessage, error) {
+	return nil, nil
+}
+
+func (m baseMockStore) GetSessionMessages(ctx context.Context, userID int64, sessionID string) ([]ChatMessage, error) {
+	return nil, nil
+}
+
+func (m baseMockStore) ListSessions(ctx context.Context) ([]Session, error) {
+	return nil, nil
+}
+
+func (m baseMockStore) GetUserSessions(ctx context.Context, userID int64) ([]Session, error) {
+	return nil, nil
+}
+
+func (m baseMockStore) GetSessionOwner(ctx context.Context, sessionID string) (int64, error) {
+	return 0, nil
+}
+
+func (m baseMockStore) ImportChatSession(ctx context.Context, userID int64, session *ImportedSession) (bool, error) {
+	return true, nil
+}
+
+func (m baseMockStore) CreateSessionLink(ctx context.Context, userID int64, link *SessionLink) error {
+	return nil
+}
+
+func (m baseMockStore) GetSessionLink(ctx context.Context, userID int64, sessionID string) (*SessionLink, error) {
+	return nil, nil
+}
+
+func (m baseMockStore) GetSessionMode(ctx context.Context, userID int64, sessionID string) (string, error) {
+	return "", nil
+}
+
+func (m baseMockStore) SetSessionMode(ctx context.Context, userID int64, sessionID, mode string) error {
+	return nil
+}
+
+func (m baseMockStore) GetSessionSummary(ctx context.Context, userID int64, sessionID string) (*SessionSummary, error) {
+	return nil, nil
+}
+
+func (m baseMockStore) SaveSessionSummary(ctx context.Context, userID int64, summary *SessionSummary) error {
+	return nil
+}
+
+func (m baseMockStore) GetSessionsToCompact(ctx context.Context, idleBefore time.Time, keep int) ([]SessionToCompact, error) {
+	return nil, nil
+}
+
+func (m baseMockStore) CreateSessionShare(ctx context.Context, share *SessionShare) (int64, error) {
+	return 0, nil
+}
+
+func (m baseMockStore) GetSessionShareByToken(ctx context.Context, token string) (*SessionShare, error) {
+	return nil, nil
+}
+
+func (m baseMockStore) GetSessionShares(ctx context.Context, userID int64, sessionID string) ([]SessionShare, error) {
+	return nil, nil
+}
+
+func (m baseMockStore) RevokeSessionShare(ctx context.Context, userID, shareID int64) error {
+	return nil
+}
+
+func (m baseMockStore) RecordSessionShareView(ctx context.Context, shareID int64, ipAddress, userAgent string) error {
+	return nil
+}
+
+func (m baseMockStore) GetSessionShareViews(ctx context.Context, userID, shareID int64, limit int) ([]SessionShareView, error) {
+	return nil, nil
+}
+
+func (m baseMockStore) AddAuditEntry(ctx context.Context, opType, details, userCtx string) error {
+	return nil
+}
+
+func (m baseMockStore) GetAuditLog(ctx context.Context, opType string, from, to time.Time) ([]AuditEntry, error) {
+	return nil, nil
+}
+
+func (m baseMockStore) GetUserByUsername(ctx context.Context, username string) (*User, error) {
+	return nil, nil
+}
+
+func (m baseMockStore) GetUserByID(ctx context.Context, userID int64) (*User, error) {
+	return nil, nil
+}
+
+func (m baseMockStore) CreateUser(ctx context.Context, username, password, email string, isAdmin, mustChangePassword bool) (int64, error) {
+	return 0, nil
+}
+
+func (m baseMockStore) UpdatePassword(ctx context.Context, userID int64, newPassword string) error {
+	return nil
+}
+
+func (m baseMockStore) SetTemporaryPassword(ctx context.Context, userID int64, newPassword string) error {
+	return nil
+}
+
+func (m baseMockStore) IsRecentPassword(ctx context.Context, userID int64, password string, n int) (bool, error) {
+	return false, nil
+}
+
+func (m baseMockStore) UpdateUserDarkMode(ctx context.Context, userID int64, darkMode bool) error {
+	return nil
+}
+
+func (m baseMockStore) GetUserPreferences(ctx context.Context, userID int64) (map[string]string, error) {
+	return nil, nil
+}
+
+func (m baseMockStore) SetUserPreferences(ctx context.Context, userID int64, prefs map[string]string) error {
+	return nil
+}
+
+func (m baseMockStore) ListUsers(ctx context.Context) ([]User, error) {
+	return nil, nil
+}
+
+func (m baseMockStore) DeleteUser(ctx context.Context, userID int64) error {
+	return nil
+}
+
+func (m baseMockStore) CompleteOnboarding(ctx context.Context, userID int64) error {
+	return nil
+}
+
+func (m baseMockStore) DeleteUserSessionTokens(ctx context.Context, userID int64, keepToken string) error {
+	return nil
+}
+
+func (m baseMockStore) IsAccountLocked(ctx context.Context, username string, threshold int, window time.Duration) (bool, time.Time) {
+	return false, time.Time{}
+}
+
+func (m baseMockStore) CountFailedLogins(ctx context.Context, username string, since time.Time) (int, error) {
+	return 0, nil
+}
+
+func (m baseMockStore) ClearFailedLogins(ctx context.Context, username string) error {
+	return nil
+}
+
+func (m baseMockStore) GetUserSkills(ctx context.Context, userID int64) ([]Skill, error) {
+	return nil, nil
+}
+
+func (m baseMockStore) RecordSkillRun(ctx context.Context, run *SkillRun) (int64, error) {
+	return 1, nil
+}
+
+func (m baseMockStore) SetSkillRunArtifact(ctx context.Context, userID, runID int64, source string) error {
+	return nil
+}
+
+func (m baseMockStore) GetSkillRuns(ctx context.Context, userID, skillID int64, limit, offset int) ([]SkillRun, int, error) {
+	return nil, 0, nil
+}
+
+func (m baseMockStore) GetSkillRun(ctx context.Context, userID, runID int64) (*SkillRun, error) {
+	return nil, nil
+}
+
+func (m baseMockStore) UpdateSkillEnabled(ctx context.Context, userID, skillID int64, enabled bool) error {
+	return nil
+}
+
+func (m baseMockStore) CreateGlobalSkill(ctx context.Context, adminID int64, name, path string, enabled bool) (int64, error) {
+	return 0, nil
+}
+
+func (m baseMockStore) GetGlobalSkills(ctx context.Context) ([]GlobalSkill, error) {
+	return nil, nil
+}
+
+func (m baseMockStore) UpdateGlobalSkill(ctx context.Context, skillID int64, enabled bool) (bool, error) {
+	return false, nil
+}
+
+func (m baseMockStore) DeleteGlobalSkill(ctx context.Context, skillID int64) (bool, error) {
+	return false, nil
+}
+
+func (m baseMockStore) GetWatchedFoldersByUser(ctx context.Context, userID int64) ([]WatchedFolder, error) {
+	return nil, nil
+}
+
+func (m baseMockStore) GetIngestFailures(ctx context.Context, userID int64, folder string) ([]IngestFailure, error) {
+	return nil, nil
+}
+
+func (m baseMockStore) GetDocumentSummaries(ctx context.Context, userID int64, model string) ([]DocumentSummary, error) {
+	return nil, nil
+}
+
+func (m baseMockStore) GetChunkedDocuments(ctx context.Context, userID int64) ([]ChunkedDocument, error) {
+	return nil, nil
+}
+
+func (m baseMockStore) CreateEvalSet(ctx context.Context, userID int64, name string, cases []eval.Case) (int64, error) {
+	return 0, nil
+}
+
+func (m baseMockStore) UpdateEvalSet(ctx context.Context, userID, setID int64, name string, cases []eval.Case) error {
+	return nil
+}
+
+func (m baseMockStore) GetEvalSets(ctx context.Context, userID int64) ([]EvalSet, error) {
+	return nil, nil
+}
+
+func (m baseMockStore) GetEvalSet(ctx context.Context, userID, setID int64) (*EvalSet, error) {
+	return nil, nil
+}
+
+func (m baseMockStore) DeleteEvalSet(ctx context.Context, userID, setID int64) error {
+	return nil
+}
+
+func (m baseMockStore) CreateSavedSearch(ctx context.Context, userID int64, name, query, mode string) (int64, error) {
+	return 1, nil
+}
+
+func (m baseMockStore) GetSavedSearches(ctx context.Context, userID int64) ([]SavedSearch, error) {
+	return nil, nil
+}
+
+func (m baseMockStore) GetSavedSearch(ctx context.Context, userID, id int64) (*SavedSearch, error) {
+	return nil, nil
+}
+
+func (m baseMockStore) GetStaleSavedSearches(ctx context.Context, model string, limit int) ([]SavedSearch, error) {
+	return nil, nil
+}
+
+func (m baseMockStore) SetSavedSearchEmbedding(ctx context.Context, id int64, embedding []float32, model string) error {
+	return nil
+}
+
+func (m baseMockStore) DeleteSavedSearch(ctx context.Context, userID, id int64) (bool, error) {
+	return false, nil
+}
+
+func (m baseMockStore) RecordEvalRun(ctx context.Context, userID int64, run *EvalRun) (int64, error) {
+	return 0, nil
+}
+
+func (m baseMockStore) GetEvalRuns(ctx context.Context, userID, setID int64, limit int) ([]EvalRun, error) {
+	return nil, nil
+}
+
+func (m baseMockStore) GetEvalRun(ctx context.Context, userID, runID int64) (*EvalRun, error) {
+	return nil, nil
+}
+
+func (m baseMockStore) GetFlagOverrides(ctx context.Context) ([]FlagOverride, error) {
+	return nil, nil
+}
+
+func (m baseMockStore) SetFlagOverride(ctx context.Context, userID int64, flag string, enabled bool) error {
+	return nil
+}
+
+func (m baseMockStore) DeleteFlagOverride(ctx context.Context, userID int64, flag string) error {
+	return nil
+}
+
+func (m baseMockStore) GetRankingWeights(ctx context.Context, userID int64) (*RankingWeights, error) {
+	return &RankingWeights{TagWeights: map[string]float64{}, SourceWeights: map[string]float64{}}, nil
+}
+
+func (m baseMockStore) SaveRankingWeights(ctx context.Context, userID int64, weights RankingWeights) error {
+	return nil
+}
+
+func (m baseMockStore) GetGenerationDefaults(ctx context.Context, userID int64) (*GenerationOptions, error) {
+	return &GenerationOptions{}, nil
+}
+
+func (m baseMockStore) SaveGenerationDefaults(ctx context.Context, userID int64, opts GenerationOptions) error {
+	return nil
+}
+
+func (m baseMockStore) GetAnswerStyle(ctx context.Context, userID int64) (*AnswerStyle, error) {
+	return nil, nil
+}
+
+func (m baseMockStore) SaveAnswerStyle(ctx context.Context, userID int64, style AnswerStyle) error {
+	return nil
+}
+
+func (m baseMockStore) GetStorageStats(ctx context.Context, userID int64, allUsers bool, limit int) (*StorageStats, error) {
+	return &StorageStats{}, nil
+}
+
+func (m baseMockStore) GetEmbeddingGroups(ctx context.Context) ([]EmbeddingGroup, error) {
+	return nil, nil
+}
+
+func (m baseMockStore) GetUserActivity(ctx context.Context) ([]UserActivity, error) {
+	return nil, nil
+}
+
+func (m baseMockStore) QuickSearch(ctx context.Context, userID int64, query string, limits QuickSearchLimits) ([]QuickSearchResult, error) {
+	return nil, nil
+}
+
+func (m baseMockStore) GetSearchDictionary(ctx context.Context) (*SearchDictionary, error) {
+	return &SearchDictionary{}, nil
+}
+
+func (m baseMockStore) SetSearchDictionary(ctx context.Context, dict SearchDictionary) error {
+	return nil
+}
+
+func (m baseMockStore) CreateExtractionSchema(ctx context.Context, userID int64, name, description string, schema json.RawMessage) (int64, error) {
+	return 0, nil
+}
+
+func (m baseMockStore) GetExtractionSchemas(ctx context.Context, userID int64) ([]ExtractionSchema, error) {
+	return nil, nil
+}
+
+func (m baseMockStore) GetExtractionSchema(ctx context.Context, userID, schemaID int64) (*ExtractionSchema, error) {
+	return nil, nil
+}
+
+func (m baseMockStore) DeleteExtractionSchema(ctx context.Context, userID, schemaID int64) error {
+	return nil
+}
+
+func (m baseMockStore) SaveStructuredRecord(ctx context.Context, userID int64, record *StructuredRecord) (int64, error) {
+	return 0, nil
+}
+
+func (m baseMockStore) GetStructuredRecords(ctx context.Context, userID int64, filter RecordFilter) ([]StructuredRecord, int, error) {
+	return nil, 0, nil
+}
+
+func (m baseMockStore) GetMetadataRows(ctx context.Context, userID int64, entity string) ([]metaquery.Row, error) {
+	return nil, nil
+}
+
+func (m baseMockStore) CreateNotification(ctx context.Context, userID int64, notificationType, message, payload string) (*Notification, error) {
+	return &Notification{Type: notificationType, Message: message}, nil
+}
+
+func (m baseMockStore) GetNotifications(ctx context.Context, userID int64, unreadOnly bool, limit int) ([]Notification, error) {
+	return nil, nil
+}
+
+func (m baseMockStore) CountUnreadNotifications(ctx context.Context, userID int64) (int, error) {
+	return 0, nil
+}
+
+func (m baseMockStore) MarkNotificationsRead(ctx context.Context, userID int64, ids []int64) (int64, error) {
+	return 0, nil
+}
+
+func (m baseMockStore) WithTx(ctx context.Context, fn func(tx StoreTx) error) error {
+	return fn(m)
+}
@@ -0,0 +1,165 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"noodexx/internal/auth"
+	"strconv"
+	"time"
+)
+
+// Notification list paging limits
+const (
+	defaultNotificationsLimit = 50
+	maxNotificationsLimit     = 200
+)
+
+// NotifyUser saves a notification for a user and pushes it to their open tabs,
+// so events such as a finished ingestion are not lost when no tab is open.
+// The open tabs are told even when the notification cannot be saved
+// details is stored as the notification's JSON payload and may be nil
+func (s *Server) NotifyUser(ctx context.Context, userID int64, notificationType, message string, details map[string]interface{}) {
+	var payload string
+	if len(details) > 0 {
+		data, err := json.Marshal(details)
+		if err == nil {
+			payload = string(data)
+		}
+	}
+
+	notification, err := s.store.CreateNotification(ctx, userID, notificationType, message, payload)
+	if err != nil {
+		// Still tell the open tabs, as broadcasts did before notifications were kept
+		s.logger.Warn("failed to save notification", "user_id", userID, "type", notificationType, "error", err.Error())
+		notification = &Notification{Type: notificationType, Message: message, CreatedAt: time.Now()}
+		if payload != "" {
+			notification.Payload = json.RawMessage(payload)
+		}
+	}
+
+	unread, err := s.store.CountUnreadNotifications(ctx, userID)
+	if err != nil {
+		s.logger.Warn("failed to count unread notifications", "user_id", userID, "error", err.Error())
+	}
+	s.pushToUser(userID, topicNotifications, map[string]interface{}{
+		"type":         "notification",
+		"notification": notification,
+		"unread":       unread,
+	})
+}
+
+// handleGetNotifications handles GET /api/notifications - the current user's
+// notifications, newest first, with the unread count for the sidebar badge
+func (s *Server) handleGetNotifications(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	requestID := newRequestID(w)
+
+	logger := s.logger.WithContext("request_id", requestID).
+		WithContext("method", r.Method).
+		WithContext("path", r.URL.Path)
+
+	ctx := r.Context()
+
+	userID, err := auth.GetUserID(ctx)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
+		return
+	}
+
+	limit := defaultNotificationsLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > maxNotificationsLimit {
+			writeError(w, http.StatusBadRequest, CodeBadRequest, fmt.Sprintf("limit must be between 1 and %d", maxNotificationsLimit))
+			return
+		}
+		limit = n
+	}
+	unreadOnly := r.URL.Query().Get("unread") == "true"
+
+	notifications, err := s.store.GetNotifications(ctx, userID, unreadOnly, limit)
+	if err != nil {
+		logger.Error("request failed", "operation", "get_notifications", "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to get notifications")
+		return
+	}
+	if notifications == nil {
+		notifications = []Notification{}
+	}
+
+	unread, err := s.store.CountUnreadNotifications(ctx, userID)
+	if err != nil {
+		logger.Error("request failed", "operation", "count_unread_notifications", "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to get notifications")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":       true,
+		"notifications": notifications,
+		"unread":        unread,
+	})
+
+	latency := time.Since(start).Milliseconds()
+	logger.Debug("request completed", "status", http.StatusOK, "latency_ms", latency, "count", len(notifications))
+}
+
+// handleMarkNotificationsRead handles POST /api/notifications/read - marks the
+// listed notifications read, or all of them when no IDs are given
+func (s *Server) handleMarkNotificationsRead(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	requestID := newRequestID(w)
+
+	logger := s.logger.WithContext("request_id", requestID).
+		WithContext("method", r.Method).
+		WithContext("path", r.URL.Path)
+
+	ctx := r.Context()
+
+	userID, err := auth.GetUserID(ctx)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req struct {
+		IDs []int64 `json:"ids"` // Empty marks every notification read
+	}
+	if r.ContentLength != 0 {
+		if !decodeJSON(w, r, logger, &req) {
+			return
+		}
+	}
+
+	marked, err := s.store.MarkNotificationsRead(ctx, userID, req.IDs)
+	if err != nil {
+		logger.Error("request failed", "operation", "mark_notifications_read", "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to mark notifications read")
+		return
+	}
+	unread, err := s.store.CountUnreadNotifications(ctx, userID)
+	if err != nil {
+		logger.Error("request failed", "operation", "count_unread_notifications", "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to mark notifications read")
+		return
+	}
+
+	// Clear the badge in the user's other tabs
+	s.pushToUser(userID, topicNotifications, map[string]interface{}{
+		"type":   "notifications_read",
+		"unread": unread,
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"marked":  marked,
+		"unread":  unread,
+	})
+
+	latency := time.Since(start).Milliseconds()
+	logger.Debug("request completed", "status", http.StatusOK, "latency_ms", latency, "marked", marked)
+}
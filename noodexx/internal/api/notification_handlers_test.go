@@ -0,0 +1,192 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// notificationStore keeps notifications in memory
+type notificationStore struct {
+	mockStore
+	mu            sync.Mutex
+	notifications []Notification
+	owners        []int64 // User of each notification
+}
+
+func (m *notificationStore) CreateNotification(ctx context.Context, userID int64, notificationType, message, payload string) (*Notification, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	n := Notification{ID: int64(len(m.notifications) + 1), Type: notificationType, Message: message, CreatedAt: time.Now()}
+	if payload != "" {
+		n.Payload = json.RawMessage(payload)
+	}
+	m.notifications = append(m.notifications, n)
+	m.owners = append(m.owners, userID)
+	return &n, nil
+}
+
+func (m *notificationStore) GetNotifications(ctx context.Context, userID int64, unreadOnly bool, limit int) ([]Notification, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var notifications []Notification
+	for i := len(m.notifications) - 1; i >= 0 && len(notifications) < limit; i-- {
+		if m.owners[i] == userID && !(unreadOnly && m.notifications[i].Read) {
+			notifications = append(notifications, m.notifications[i])
+		}
+	}
+	return notifications, nil
+}
+
+func (m *notificationStore) CountUnreadNotifications(ctx context.Context, userID int64) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	count := 0
+	for i, n := range m.notifications {
+		if m.owners[i] == userID && !n.Read {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (m *notificationStore) MarkNotificationsRead(ctx context.Context, userID int64, ids []int64) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var marked int64
+	for i := range m.notifications {
+		n := &m.notifications[i]
+		if m.owners[i] != userID || n.Read {
+			continue
+		}
+		listed := len(ids) == 0
+		for _, id := range ids {
+			listed = listed || id == n.ID
+		}
+		if listed {
+			n.Read = true
+			marked++
+		}
+	}
+	return marked, nil
+}
+
+func TestNotificationHandlers(t *testing.T) {
+	store := &notificationStore{}
+	server := &Server{store: store, logger: &mockLogger{}}
+	ctx := context.Background()
+
+	server.NotifyUser(ctx, 1, "ingestion", "Document 'a.md' ingested successfully", map[string]interface{}{"source": "a.md"})
+	server.NotifyUser(ctx, 1, "skill_run", "Skill 'summarize' finished", nil)
+	server.NotifyUser(ctx, 2, "ingestion", "Document 'b.md' ingested successfully", nil)
+
+	list := func(path string, userID int64) (notifications []Notification, unread int) {
+		t.Helper()
+		w := serveRoute(server, withUser(httptest.NewRequest(http.MethodGet, path, nil), userID))
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var resp struct {
+			Notifications []Notification `json:"notifications"`
+			Unread        int            `json:"unread"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("Failed to decode notifications: %v", err)
+		}
+		return resp.Notifications, resp.Unread
+	}
+
+	notifications, unread := list("/api/notifications", 1)
+	if len(notifications) != 2 || unread != 2 || notifications[0].Type != "skill_run" {
+		t.Fatalf("Expected user 1's two notifications newest first, got %+v (%d unread)", notifications, unread)
+	}
+	if string(notifications[1].Payload) != `{"source":"a.md"}` {
+		t.Errorf("Expected the ingestion's payload, got %s", notifications[1].Payload)
+	}
+
+	markRead := func(body string, userID int64) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/notifications/read", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		return serveRoute(server, withUser(req, userID))
+	}
+
+	// Another user's notification is left alone
+	if w := markRead(`{"ids":[3]}`, 1); !strings.Contains(w.Body.String(), `"marked":0`) {
+		t.Errorf("Expected nothing marked, got %s", w.Body.String())
+	}
+	if w := markRead(`{"ids":[1]}`, 1); !strings.Contains(w.Body.String(), `"unread":1`) {
+		t.Errorf("Expected one unread left, got %s", w.Body.String())
+	}
+	if notifications, _ := list("/api/notifications?unread=true", 1); len(notifications) != 1 || notifications[0].ID != 2 {
+		t.Errorf("Expected only the unread notification, got %+v", notifications)
+	}
+	if w := markRead(`{}`, 1); !strings.Contains(w.Body.String(), `"unread":0`) {
+		t.Errorf("Expected everything marked read, got %s", w.Body.String())
+	}
+	if _, unread := list("/api/notifications", 2); unread != 1 {
+		t.Errorf("Expected user 2's notification still unread, got %d", unread)
+	}
+
+	if w := serveRoute(server, withUser(httptest.NewRequest(http.MethodGet, "/api/notifications?limit=0", nil), 1)); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for a bad limit, got %d", w.Code)
+	}
+	if w := serveRoute(server, httptest.NewRequest(http.MethodGet, "/api/notifications", nil)); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401 without a user, got %d", w.Code)
+	}
+}
+
+// TestNotifyUserPushesToUser tests that a notification reaches only its user's
+// WebSocket clients
+func TestNotifyUserPushesToUser(t *testing.T) {
+	hub := NewWebSocketHub()
+	go hub.Run()
+	server := &Server{store: &notificationStore{}, wsHub: hub, logger: &mockLogger{}}
+
+	dial := func(userID int64) *websocket.Conn {
+		t.Helper()
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			server.handleWebSocket(w, withUser(r, userID))
+		}))
+		t.Cleanup(ts.Close)
+		conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http"), nil)
+		if err != nil {
+			t.Fatalf("Failed to dial WebSocket: %v", err)
+		}
+		t.Cleanup(func() { conn.Close() })
+		return conn
+	}
+	owner, other := dial(1), dial(2)
+	time.Sleep(100 * time.Millisecond) // Give time for registration
+
+	server.NotifyUser(context.Background(), 1, "ingestion", "Document 'a.md' ingested successfully", nil)
+
+	owner.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, message, err := owner.ReadMessage()
+	if err != nil {
+		t.Fatalf("Failed to read message: %v", err)
+	}
+	var frame struct {
+		Type         string       `json:"type"`
+		Notification Notification `json:"notification"`
+		Unread       int          `json:"unread"`
+	}
+	if err := json.Unmarshal(message, &frame); err != nil {
+		t.Fatalf("Failed to decode message: %v", err)
+	}
+	if frame.Type != "notification" || frame.Notification.Type != "ingestion" || frame.Unread != 1 {
+		t.Errorf("Expected the notification with its unread count, got %s", message)
+	}
+
+	other.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+	if _, message, err := other.ReadMessage(); err == nil {
+		t.Errorf("Expected another user not to receive the notification, got %s", message)
+	}
+}
@@ -0,0 +1,281 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"noodexx/internal/auth"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// sampleSourcePrefix is prepended to bundled sample documents so they are easy to spot in the library
+const sampleSourcePrefix = "samples/"
+
+// sampleDocument is a bundled onboarding document
+type sampleDocument struct {
+	Name string
+	Text string
+}
+
+// sampleSession is the demonstration chat created alongside the sample documents
+// Citations are listed in the order they are referenced as [1], [2], ...
+var sampleSession = struct {
+	Question  string
+	Answer    string
+	Citations []string
+}{
+	Question: "What is Noodexx and does it keep my documents private?",
+	Answer: "Noodexx answers questions using your own documents: it splits them into chunks, finds the passages most relevant to your question and gives them to the AI model as context [1]. " +
+		"With Local AI everything runs on your machine through Ollama, so your questions and documents never leave your computer [2]. " +
+		"Numbered markers like the ones in this answer link each statement back to the document it came from, so you can always check the source [3].",
+	Citations: []string{
+		sampleSourcePrefix + "welcome-to-noodexx.md",
+		sampleSourcePrefix + "privacy-and-providers.md",
+		sampleSourcePrefix + "tips-for-better-answers.md",
+	},
+}
+
+// samplesDir returns the directory of bundled sample documents
+// A samples/ directory in the branding override directory takes precedence
+func (s *Server) samplesDir() string {
+	if s.overrideDir != "" {
+		dir := filepath.Join(s.overrideDir, "samples")
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			return dir
+		}
+	}
+	return "web/samples"
+}
+
+// loadSampleDocuments reads the bundled sample documents in name order
+func (s *Server) loadSampleDocuments() ([]sampleDocument, error) {
+	dir := s.samplesDir()
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sample directory: %w", err)
+	}
+
+	var docs []sampleDocument
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		ext := strings.ToLower(filepath.Ext(entry.Name()))
+		if ext != ".md" && ext != ".txt" {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read sample %s: %w", entry.Name(), err)
+		}
+		docs = append(docs, sampleDocument{Name: entry.Name(), Text: string(data)})
+	}
+
+	sort.Slice(docs, func(i, j int) bool { return docs[i].Name < docs[j].Name })
+	return docs, nil
+}
+
+// shouldShowOnboarding reports whether the onboarding flow should be offered to a user
+// Onboarding is shown once, to users who have not completed it and whose library is still empty
+func (s *Server) shouldShowOnboarding(ctx context.Context, userID int64) (bool, error) {
+	user, err := s.store.GetUserByID(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	if user.OnboardingCompleted {
+		return false, nil
+	}
+
+	// One entry is enough to know the library is not empty
+	empty := true
+	err = s.store.ForEachLibraryEntry(ctx, userID, func(LibraryEntry) bool {
+		empty = false
+		return false
+	})
+	if err != nil {
+		return false, err
+	}
+	return empty, nil
+}
+
+// handleOnboarding handles GET /api/onboarding - report onboarding state for the current user
+func (s *Server) handleOnboarding(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	requestID := newRequestID(w)
+
+	// Create logger with request context
+	logger := s.logger.WithContext("request_id", requestID).
+		WithContext("method", r.Method).
+		WithContext("path", r.URL.Path)
+
+	logger.Debug("processing onboarding status request")
+
+	ctx := r.Context()
+
+	// Extract user_id from context
+	userID, err := auth.GetUserID(ctx)
+	if err != nil {
+		logger.Error("request failed", "operation", "get_user_id", "error", err.Error())
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
+		return
+	}
+
+	user, err := s.store.GetUserByID(ctx, userID)
+	if err != nil {
+		logger.Error("request failed", "operation", "get_user", "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to load onboarding state")
+		return
+	}
+
+	show, err := s.shouldShowOnboarding(ctx, userID)
+	if err != nil {
+		logger.Error("request failed", "operation", "check_onboarding", "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to load onboarding state")
+		return
+	}
+
+	var sampleNames []string
+	if docs, err := s.loadSampleDocuments(); err == nil {
+		for _, doc := range docs {
+			sampleNames = append(sampleNames, doc.Name)
+		}
+	} else {
+		logger.Warn("sample documents unavailable", "error", err.Error())
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":   true,
+		"completed": user.OnboardingCompleted,
+		"show":      show,
+		"samples":   sampleNames,
+	})
+
+	latency := time.Since(start).Milliseconds()
+	logger.Debug("request completed", "status", http.StatusOK, "latency_ms", latency)
+}
+
+// handleOnboardingSamples handles POST /api/onboarding/samples
+// Ingests the bundled sample documents for the current user, creates a sample chat session
+// demonstrating citations and marks onboarding as completed
+func (s *Server) handleOnboardingSamples(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	requestID := newRequestID(w)
+
+	// Create logger with request context
+	logger := s.logger.WithContext("request_id", requestID).
+		WithContext("method", r.Method).
+		WithContext("path", r.URL.Path)
+
+	logger.Debug("processing onboarding samples request")
+
+	ctx := r.Context()
+
+	// Extract user_id from context
+	userID, err := auth.GetUserID(ctx)
+	if err != nil {
+		logger.Error("request failed", "operation", "get_user_id", "error", err.Error())
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
+		return
+	}
+
+	docs, err := s.loadSampleDocuments()
+	if err != nil || len(docs) == 0 {
+		if err == nil {
+			err = fmt.Errorf("no sample documents found")
+		}
+		logger.Error("request failed", "operation", "load_samples", "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Sample documents are not available")
+		return
+	}
+
+	// Ingest each sample document
+	for _, doc := range docs {
+		source := sampleSourcePrefix + doc.Name
+		if err := s.ingester.IngestText(ctx, userID, source, doc.Text, []string{"sample"}); err != nil {
+			logger.Error("request failed", "operation", "ingest_sample", "source", source, "error", err.Error())
+			writeError(w, http.StatusInternalServerError, CodeInternal, fmt.Sprintf("Ingestion failed: %v", err))
+			return
+		}
+	}
+
+	// Create the sample chat session demonstrating citations
+	sessionID := generateSessionID()
+	if err := s.store.SaveChatMessage(ctx, userID, sessionID, "user", sampleSession.Question, ""); err != nil {
+		logger.Error("request failed", "operation", "save_sample_question", "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to create sample session")
+		return
+	}
+	if err := s.store.SaveChatMessageWithCitations(ctx, userID, sessionID, "assistant", sampleSession.Answer, "local", sampleSession.Citations); err != nil {
+		logger.Error("request failed", "operation", "save_sample_answer", "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to create sample session")
+		return
+	}
+
+	if err := s.store.CompleteOnboarding(ctx, userID); err != nil {
+		logger.Error("request failed", "operation", "complete_onboarding", "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to complete onboarding")
+		return
+	}
+
+	// Audit log
+	s.store.AddAuditEntry(ctx, "ingest", fmt.Sprintf("Onboarding samples: %d documents", len(docs)), sessionID)
+
+	// Notify the user
+	s.NotifyUser(ctx, userID, "ingestion", fmt.Sprintf("%d sample documents ingested successfully", len(docs)), nil)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":    true,
+		"documents":  len(docs),
+		"session_id": sessionID,
+	})
+
+	latency := time.Since(start).Milliseconds()
+	logger.Debug("request completed", "status", http.StatusOK, "latency_ms", latency, "documents", len(docs), "session_id", sessionID)
+}
+
+// handleOnboardingComplete handles POST /api/onboarding/complete
+// Marks onboarding as completed when the user skips it or ingests their own document
+func (s *Server) handleOnboardingComplete(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	requestID := newRequestID(w)
+
+	// Create logger with request context
+	logger := s.logger.WithContext("request_id", requestID).
+		WithContext("method", r.Method).
+		WithContext("path", r.URL.Path)
+
+	logger.Debug("processing onboarding complete request")
+
+	ctx := r.Context()
+
+	// Extract user_id from context
+	userID, err := auth.GetUserID(ctx)
+	if err != nil {
+		logger.Error("request failed", "operation", "get_user_id", "error", err.Error())
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
+		return
+	}
+
+	if err := s.store.CompleteOnboarding(ctx, userID); err != nil {
+		logger.Error("request failed", "operation", "complete_onboarding", "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to complete onboarding")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+	})
+
+	latency := time.Since(start).Milliseconds()
+	logger.Debug("request completed", "status", http.StatusOK, "latency_ms", latency)
+}
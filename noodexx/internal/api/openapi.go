@@ -0,0 +1,486 @@
+package api
+
+import (
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// openAPIVersion is the version of the API the spec describes
+const openAPIVersion = "1.0.0"
+
+// Access levels of API operations
+const (
+	accessPublic = "public" // No sign-in needed
+	accessUser   = "user"   // Any signed-in user
+	accessAdmin  = "admin"  // Administrators only
+)
+
+// apiOperation describes one API route for the OpenAPI spec
+type apiOperation struct {
+	Method  string
+	Path    string
+	Tag     string
+	Summary string
+	Access  string
+	Body    string // "json", "multipart" or empty for none
+}
+
+// apiOperations lists every /api/ route. Adding a route without adding it
+// here is logged at startup and fails TestOpenAPISpecMatchesRoutes
+var apiOperations = []apiOperation{
+	{"GET", "/api/openapi.json", "Documentation", "OpenAPI 3 description of this API", accessUser, ""},
+	{"GET", "/api/docs", "Documentation", "Interactive API documentation page", accessUser, ""},
+
+	{"POST", "/api/ask", "Chat", "Ask a question, streaming the answer", accessUser, "json"},
+	{"GET", "/api/ask/{id}/stream", "Chat", "Resume a dropped answer stream", accessUser, ""},
+	{"GET", "/api/sessions", "Chat", "List chat sessions", accessUser, ""},
+	{"GET", "/api/session/{id}", "Chat", "Messages of a session", accessUser, ""},
+	{"GET", "/api/session/{id}/export", "Chat", "Export a session", accessUser, ""},
+	{"POST", "/api/session/{id}/continue", "Chat", "Start a new session with a summary of this one", accessUser, ""},
+	{"GET", "/api/session/{id}/link", "Chat", "Link to a session", accessUser, ""},
+	{"GET", "/api/session/{id}/mode", "Chat", "Provider mode a session is answered in", accessUser, ""},
+	{"PUT", "/api/session/{id}/mode", "Chat", "Change the provider mode of a session", accessUser, "json"},
+	{"GET", "/api/session/{id}/sources", "Chat", "Library sources cited in a session", accessUser, ""},
+	{"GET", "/api/session/{id}/search", "Chat", "Search only the sources cited in a session", accessUser, ""},
+	{"GET", "/api/message/{id}/provenance", "Chat", "What an answer was generated from", accessUser, ""},
+	{"GET", "/api/message/{id}/artifacts", "Chat", "Code blocks and tables in an answer", accessUser, ""},
+	{"GET", "/api/message/{id}/artifacts/{n}", "Chat", "Raw artifact of an answer", accessUser, ""},
+	{"GET", "/api/attachments", "Chat", "List a session's chat attachments", accessUser, ""},
+	{"POST", "/api/attachments/save", "Chat", "Save a chat attachment to the library", accessUser, "json"},
+
+	{"GET", "/api/session/{id}/shares", "Sharing", "List a session's share links", accessUser, ""},
+	{"POST", "/api/session/{id}/shares", "Sharing", "Create a share link for a session", accessUser, "json"},
+	{"DELETE", "/api/shares/{id}", "Sharing", "Revoke a share link", accessUser, ""},
+	{"GET", "/api/shares/{id}/views", "Sharing", "Views of a share link", accessUser, ""},
+
+	{"POST", "/api/ingest/text", "Ingestion", "Ingest text", accessUser, "json"},
+	{"POST", "/api/ingest/url", "Ingestion", "Ingest a web page", accessUser, "json"},
+	{"POST", "/api/ingest/file", "Ingestion", "Ingest an uploaded file", accessUser, "multipart"},
+	{"POST", "/api/notes", "Ingestion", "Append to today's notes document", accessUser, "json"},
+	{"POST", "/api/import", "Ingestion", "Import a vault or another tool's export", accessUser, "multipart"},
+	{"POST", "/api/import/chats", "Chat", "Recreate ChatGPT or Claude conversations as sessions", accessUser, "multipart"},
+
+	{"GET", "/api/library", "Library", "List documents", accessUser, ""},
+	{"POST", "/api/delete", "Library", "Delete a document", accessUser, "json"},
+	{"DELETE", "/api/delete", "Library", "Delete a document", accessUser, "json"},
+	{"PATCH", "/api/documents/{id}", "Library", "Set a document's display title", accessUser, "json"},
+	{"PUT", "/api/documents/{id}/pin", "Library", "Pin a document so it is listed first and boosted in search", accessUser, ""},
+	{"DELETE", "/api/documents/{id}/pin", "Library", "Unpin a document", accessUser, ""},
+	{"POST", "/api/documents/visibility", "Library", "Set the visibility of several documents", accessUser, "json"},
+	{"GET", "/api/library/summaries", "Library", "Document summaries and whether they are stale", accessUser, ""},
+	{"POST", "/api/library/summaries/regenerate", "Library", "Regenerate document summaries", accessUser, "json"},
+	{"GET", "/api/library/links", "Library", "Wikilinks of an imported note", accessUser, ""},
+	{"GET", "/api/library/encoding", "Library", "Encoding a document was decoded from and what normalizing its text changed", accessUser, ""},
+	{"GET", "/api/library/dead-content", "Library", "Sources to delete or re-chunk, from retrieval statistics", accessUser, ""},
+	{"GET", "/api/library/rechunk", "Library", "Documents chunked with other settings, and the running job", accessUser, ""},
+	{"POST", "/api/library/rechunk", "Library", "Re-chunk one document or the library in the background", accessUser, "json"},
+	{"GET", "/api/access-log", "Library", "Prompts that included the user's sensitive documents", accessUser, ""},
+	{"GET", "/api/stats/storage", "Library", "Storage used by source and user", accessUser, ""},
+	{"GET", "/api/stats/responses", "Chat", "Answer speed by provider and model", accessUser, ""},
+	{"GET", "/api/default-visibility", "Library", "Visibility of documents ingested without choosing one", accessUser, ""},
+	{"POST", "/api/default-visibility", "Library", "Set the default visibility of new documents", accessUser, "json"},
+
+	{"GET", "/api/search", "Search", "Library chunks with highlighted snippets", accessUser, ""},
+	{"GET", "/api/quicksearch", "Search", "Command palette lookup", accessUser, ""},
+	{"GET", "/api/ranking-weights", "Search", "Search ranking weights", accessUser, ""},
+	{"POST", "/api/ranking-weights", "Search", "Set search ranking weights", accessUser, "json"},
+	{"GET", "/api/metadata/queries", "Search", "Saved metadata queries", accessUser, ""},
+	{"POST", "/api/metadata/query", "Search", "Query documents by metadata", accessUser, "json"},
+
+	{"GET", "/api/skills", "Skills", "List skills", accessUser, ""},
+	{"POST", "/api/skills/run", "Skills", "Run a skill", accessUser, "json"},
+	{"GET", "/api/skills/{id}/runs", "Skills", "Past runs of a skill", accessUser, ""},
+	{"POST", "/api/skills/{id}/runs/{run_id}/rerun", "Skills", "Run a skill again with a past run's input", accessUser, ""},
+	{"PUT", "/api/skills/{id}/enabled", "Skills", "Turn a skill on or off for yourself", accessUser, "json"},
+
+	{"GET", "/api/watched-folders", "Folders", "List watched folders", accessUser, ""},
+	{"GET", "/api/folders/{id}/errors", "Folders", "Files of a folder that failed to ingest", accessUser, ""},
+	{"POST", "/api/folders/{id}/errors/retry", "Folders", "Retry a folder's failed files", accessUser, "json"},
+	{"GET", "/api/watcher", "Folders", "Folder watcher ingestion status", accessUser, ""},
+	{"POST", "/api/watcher/pause", "Folders", "Pause the folder watcher", accessAdmin, ""},
+	{"POST", "/api/watcher/resume", "Folders", "Resume the folder watcher", accessAdmin, ""},
+
+	{"GET", "/api/saved-searches", "Search", "List saved searches", accessUser, ""},
+	{"POST", "/api/saved-searches", "Search", "Save a search", accessUser, "json"},
+	{"DELETE", "/api/saved-searches/{id}", "Search", "Delete a saved search", accessUser, ""},
+	{"GET", "/api/saved-searches/{id}/results", "Search", "Run a saved search", accessUser, ""},
+	{"GET", "/api/eval/sets", "Evaluation", "List golden sets", accessUser, ""},
+	{"POST", "/api/eval/sets", "Evaluation", "Create a golden set", accessUser, "json"},
+	{"PUT", "/api/eval/sets/{id}", "Evaluation", "Replace a golden set", accessUser, "json"},
+	{"DELETE", "/api/eval/sets/{id}", "Evaluation", "Delete a golden set", accessUser, ""},
+	{"POST", "/api/eval/sets/{id}/run", "Evaluation", "Run a golden set", accessUser, ""},
+	{"GET", "/api/eval/sets/{id}/runs", "Evaluation", "Runs of a golden set", accessUser, ""},
+	{"GET", "/api/eval/runs/{id}", "Evaluation", "Results of a run", accessUser, ""},
+
+	{"GET", "/api/extraction/schemas", "Extraction", "List extraction schemas", accessUser, ""},
+	{"POST", "/api/extraction/schemas", "Extraction", "Create an extraction schema", accessUser, "json"},
+	{"DELETE", "/api/extraction/schemas/{id}", "Extraction", "Delete an extraction schema", accessUser, ""},
+	{"POST", "/api/extraction/schemas/{id}/run", "Extraction", "Extract records from documents", accessUser, "json"},
+	{"GET", "/api/records", "Extraction", "List extracted records", accessUser, ""},
+	{"GET", "/api/records/export", "Extraction", "Export extracted records", accessUser, ""},
+
+	{"GET", "/api/notifications", "Notifications", "List notifications", accessUser, ""},
+	{"POST", "/api/notifications/read", "Notifications", "Mark notifications read", accessUser, "json"},
+	{"GET", "/api/activity", "Notifications", "Recent activity", accessUser, ""},
+
+	{"POST", "/api/config", "Settings", "Update configuration", accessUser, "json"},
+	{"POST", "/api/settings", "Settings", "Save settings", accessUser, "json"},
+	{"POST", "/api/test-connection", "Settings", "Test a provider connection", accessUser, "json"},
+	{"POST", "/api/privacy-mode", "Settings", "Toggle privacy mode", accessUser, "json"},
+	{"GET", "/api/privacy-toggle", "Settings", "Current local or cloud AI mode", accessUser, ""},
+	{"POST", "/api/privacy-toggle", "Settings", "Toggle between local and cloud AI", accessUser, "json"},
+	{"POST", "/api/user/preferences", "Settings", "Switch dark mode", accessUser, "json"},
+	{"GET", "/api/me/preferences", "Settings", "Interface preferences such as the theme", accessUser, ""},
+	{"PUT", "/api/me/preferences", "Settings", "Change the interface preferences sent and return all of them", accessUser, "json"},
+	{"GET", "/api/generation-defaults", "Settings", "Per-user temperature, top_p and max_tokens", accessUser, ""},
+	{"POST", "/api/generation-defaults", "Settings", "Set per-user generation defaults", accessUser, "json"},
+	{"GET", "/api/answer-style", "Settings", "Per-user answer language, tone and citation style", accessUser, ""},
+	{"POST", "/api/answer-style", "Settings", "Set the per-user answer style", accessUser, "json"},
+	{"GET", "/api/cost-settings", "Settings", "Per-user cost confirmation limit and recent estimates", accessUser, ""},
+	{"POST", "/api/cost-settings", "Settings", "Set the per-user cost confirmation limit; null returns to the server's", accessUser, "json"},
+	{"GET", "/api/flags", "Settings", "Feature flags for the current user", accessUser, ""},
+	{"GET", "/api/model-warmup", "Settings", "Local model warm-up status", accessUser, ""},
+	{"GET", "/api/onboarding", "Settings", "Onboarding state", accessUser, ""},
+	{"POST", "/api/onboarding/samples", "Settings", "Ingest bundled sample documents", accessUser, ""},
+	{"POST", "/api/onboarding/complete", "Settings", "Dismiss onboarding", accessUser, ""},
+
+	{"POST", "/api/login", "Authentication", "Sign in", accessPublic, "json"},
+	{"POST", "/api/logout", "Authentication", "Sign out", accessPublic, ""},
+	{"POST", "/api/register", "Authentication", "Create an account", accessPublic, "json"},
+	{"POST", "/api/change-password", "Authentication", "Change the current user's password", accessUser, "json"},
+
+	{"GET", "/api/users", "Users", "List users", accessAdmin, ""},
+	{"POST", "/api/users", "Users", "Create a user", accessAdmin, "json"},
+	{"DELETE", "/api/users/{id}", "Users", "Delete a user", accessAdmin, ""},
+	{"POST", "/api/users/{id}/reset-password", "Users", "Reset a user's password", accessAdmin, "json"},
+	{"GET", "/api/users/{id}/lockout", "Users", "Failed sign-ins and lockout state", accessAdmin, ""},
+	{"DELETE", "/api/users/{id}/lockout", "Users", "Unlock an account", accessAdmin, ""},
+	{"POST", "/api/admin/users/import", "Users", "Create accounts from a CSV", accessAdmin, "multipart"},
+	{"GET", "/api/admin/users/export", "Users", "Every account as CSV", accessAdmin, ""},
+	{"GET", "/api/admin/users/activity", "Users", "Last activity and resource usage per user", accessAdmin, ""},
+
+	{"GET", "/api/admin/wire-log", "Administration", "Provider request log", accessAdmin, ""},
+	{"GET", "/api/admin/transcripts", "Administration", "List archived cloud provider transcripts", accessAdmin, ""},
+	{"GET", "/api/admin/transcripts/export", "Administration", "Export decrypted transcripts as JSON Lines", accessAdmin, ""},
+	{"GET", "/api/admin/embedding-pool", "Administration", "Embedding worker health", accessAdmin, ""},
+	{"GET", "/api/admin/provider-queue", "Administration", "Answer queue load and wait times", accessAdmin, ""},
+	{"GET", "/api/admin/skill-executor", "Administration", "Skill execution load and limits hit", accessAdmin, ""},
+	{"GET", "/api/admin/skills", "Administration", "Skills provisioned for every account", accessAdmin, ""},
+	{"POST", "/api/admin/skills", "Administration", "Provision a skill for every account", accessAdmin, "json"},
+	{"PUT", "/api/admin/skills/{id}", "Administration", "Turn a global skill on or off by default", accessAdmin, "json"},
+	{"DELETE", "/api/admin/skills/{id}", "Administration", "Remove a global skill from every account", accessAdmin, ""},
+	{"GET", "/api/admin/extractors", "Administration", "External text extractors and their types", accessAdmin, ""},
+	{"GET", "/api/admin/perf", "Administration", "Latency percentiles per route", accessAdmin, ""},
+	{"GET", "/api/admin/jobs", "Administration", "Background jobs and their last runs", accessAdmin, ""},
+	{"POST", "/api/admin/jobs/{name}/run", "Administration", "Run a job now", accessAdmin, ""},
+	{"POST", "/api/admin/jobs/{name}/pause", "Administration", "Pause a job", accessAdmin, ""},
+	{"POST", "/api/admin/jobs/{name}/resume", "Administration", "Resume a job", accessAdmin, ""},
+	{"GET", "/api/admin/telemetry", "Administration", "Anonymous usage report preview and status", accessAdmin, ""},
+	{"GET", "/api/admin/embeddings", "Administration", "Embedding models and dimensions across the library", accessAdmin, ""},
+	{"GET", "/api/admin/flags", "Administration", "Feature flags and per-user overrides", accessAdmin, ""},
+	{"PUT", "/api/admin/flags/{name}/users/{id}", "Administration", "Override a feature flag for a user", accessAdmin, "json"},
+	{"DELETE", "/api/admin/flags/{name}/users/{id}", "Administration", "Remove a user's feature flag override", accessAdmin, ""},
+	{"GET", "/api/admin/search-dictionary", "Administration", "Stop words, protected terms and synonyms of keyword search", accessAdmin, ""},
+	{"PUT", "/api/admin/search-dictionary", "Administration", "Replace the keyword search dictionary", accessAdmin, "json"},
+	{"GET", "/api/admin/replay", "Administration", "List failed provider requests kept for replay", accessAdmin, ""},
+	{"POST", "/api/admin/replay/{id}", "Administration", "Replay a failed provider request as a dry run or live", accessAdmin, "json"},
+}
+
+// buildOpenAPISpec returns the OpenAPI 3 document for operations
+func buildOpenAPISpec(operations []apiOperation) map[string]interface{} {
+	paths := make(map[string]map[string]interface{})
+	for _, op := range operations {
+		item := paths[op.Path]
+		if item == nil {
+			item = make(map[string]interface{})
+			paths[op.Path] = item
+		}
+
+		operation := map[string]interface{}{
+			"operationId": operationID(op.Method, op.Path),
+			"summary":     op.Summary,
+			"tags":        []string{op.Tag},
+			"responses": map[string]interface{}{
+				"200":     map[string]interface{}{"$ref": "#/components/responses/Success"},
+				"default": map[string]interface{}{"$ref": "#/components/responses/Error"},
+			},
+		}
+		if op.Access == accessPublic {
+			operation["security"] = []interface{}{}
+		} else {
+			operation["x-access"] = op.Access
+		}
+
+		var params []interface{}
+		for _, name := range pathParams(op.Path) {
+			params = append(params, map[string]interface{}{
+				"name":     name,
+				"in":       "path",
+				"required": true,
+				"schema":   map[string]interface{}{"type": "string"},
+			})
+		}
+		if params != nil {
+			operation["parameters"] = params
+		}
+
+		switch op.Body {
+		case "json":
+			operation["requestBody"] = map[string]interface{}{
+				"content": map[string]interface{}{
+					"application/json": map[string]interface{}{"schema": map[string]interface{}{"type": "object"}},
+				},
+			}
+		case "multipart":
+			operation["requestBody"] = map[string]interface{}{
+				"required": true,
+				"content": map[string]interface{}{
+					"multipart/form-data": map[string]interface{}{"schema": map[string]interface{}{
+						"type": "object",
+						"properties": map[string]interface{}{
+							"file": map[string]interface{}{"type": "string", "format": "binary"},
+						},
+					}},
+				},
+			}
+		}
+		item[strings.ToLower(op.Method)] = operation
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":       "Noodexx API",
+			"version":     openAPIVersion,
+			"description": "Operations marked x-access admin need an administrator. Errors share the Error response; see the README for the meaning of each code.",
+		},
+		"paths":    paths,
+		"security": []interface{}{map[string]interface{}{"sessionCookie": []string{}}, map[string]interface{}{"bearerToken": []string{}}},
+		"components": map[string]interface{}{
+			"securitySchemes": map[string]interface{}{
+				"sessionCookie": map[string]interface{}{"type": "apiKey", "in": "cookie", "name": "session_token"},
+				"bearerToken":   map[string]interface{}{"type": "http", "scheme": "bearer"},
+			},
+			"schemas": map[string]interface{}{
+				"Error": map[string]interface{}{
+					"type":     "object",
+					"required": []string{"success", "code", "message"},
+					"properties": map[string]interface{}{
+						"success":    map[string]interface{}{"type": "boolean"},
+						"code":       map[string]interface{}{"type": "string"},
+						"message":    map[string]interface{}{"type": "string"},
+						"error":      map[string]interface{}{"type": "string"},
+						"details":    map[string]interface{}{},
+						"request_id": map[string]interface{}{"type": "string"},
+					},
+				},
+			},
+			"responses": map[string]interface{}{
+				"Success": map[string]interface{}{
+					"description": "The operation succeeded",
+					"content": map[string]interface{}{
+						"application/json": map[string]interface{}{"schema": map[string]interface{}{
+							"type":                 "object",
+							"properties":           map[string]interface{}{"success": map[string]interface{}{"type": "boolean"}},
+							"additionalProperties": true,
+						}},
+					},
+				},
+				"Error": map[string]interface{}{
+					"description": "The operation failed",
+					"content": map[string]interface{}{
+						"application/json": map[string]interface{}{"schema": map[string]interface{}{"$ref": "#/components/schemas/Error"}},
+					},
+				},
+			},
+		},
+	}
+}
+
+// operationID names an operation after its method and path, such as
+// getSessionIdShares for GET /api/session/{id}/shares
+func operationID(method, path string) string {
+	id := strings.ToLower(method)
+	for _, part := range strings.FieldsFunc(strings.TrimPrefix(path, "/api/"), func(r rune) bool {
+		return r == '/' || r == '-' || r == '_' || r == '.' || r == '{' || r == '}'
+	}) {
+		id += strings.ToUpper(part[:1]) + part[1:]
+	}
+	return id
+}
+
+// pathParams returns the wildcard names of a route path in order
+func pathParams(path string) []string {
+	var names []string
+	for _, segment := range strings.Split(path, "/") {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			names = append(names, strings.TrimSuffix(strings.Trim(segment, "{}"), "..."))
+		}
+	}
+	return names
+}
+
+// specMismatches compares registered route patterns with the spec, returning
+// /api/ routes missing from it and operations no route serves
+func specMismatches(patterns []string, operations []apiOperation) (undocumented, unserved []string) {
+	registered := make(map[string]bool)
+	for _, pattern := range patterns {
+		if _, path, _ := strings.Cut(pattern, " "); strings.HasPrefix(path, "/api/") {
+			registered[pattern] = true
+		}
+	}
+	documented := make(map[string]bool)
+	for _, op := range operations {
+		pattern := op.Method + " " + op.Path
+		documented[pattern] = true
+		if !registered[pattern] {
+			unserved = append(unserved, pattern)
+		}
+	}
+	for pattern := range registered {
+		if !documented[pattern] {
+			undocumented = append(undocumented, pattern)
+		}
+	}
+	sort.Strings(undocumented)
+	return undocumented, unserved
+}
+
+// handleOpenAPISpec handles GET /api/openapi.json - the OpenAPI 3 description of the API
+func (s *Server) handleOpenAPISpec(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	requestID := newRequestID(w)
+
+	logger := s.logger.WithContext("request_id", requestID).
+		WithContext("method", r.Method).
+		WithContext("path", r.URL.Path)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-cache")
+	json.NewEncoder(w).Encode(buildOpenAPISpec(apiOperations))
+
+	latency := time.Since(start).Milliseconds()
+	logger.Debug("request completed", "status", http.StatusOK, "latency_ms", latency, "operations", len(apiOperations))
+}
+
+// apiDocsPage lists the operations of /api/openapi.json by tag and lets a
+// signed-in user send requests with their session
+var apiDocsPage = template.Must(template.New("docs").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Noodexx API</title>
+<style>
+body { font-family: system-ui, sans-serif; max-width: 60rem; margin: 2rem auto; padding: 0 1rem; color: #1f2937; }
+@media (prefers-color-scheme: dark) { body { background: #111827; color: #e5e7eb; } textarea, input { background: #1f2937; color: #e5e7eb; } }
+details { border: 1px solid #9ca3af55; border-radius: 6px; margin: 0.4rem 0; padding: 0.4rem 0.6rem; }
+summary { cursor: pointer; }
+.method { display: inline-block; min-width: 4.5rem; font-weight: 600; font-family: monospace; }
+.path { font-family: monospace; }
+.access { font-size: 0.8rem; color: #6b7280; margin-left: 0.5rem; }
+textarea { width: 100%; min-height: 5rem; font-family: monospace; }
+pre { white-space: pre-wrap; word-break: break-word; background: #9ca3af22; padding: 0.5rem; border-radius: 4px; max-height: 24rem; overflow: auto; }
+</style>
+</head>
+<body>
+<h1>Noodexx API</h1>
+<p>Generated from <a href="/api/openapi.json">/api/openapi.json</a>. Requests are sent with your current session.</p>
+<div id="operations">Loading...</div>
+<script nonce="{{.Nonce}}">
+(async function () {
+  const root = document.getElementById('operations');
+  const spec = await (await fetch('/api/openapi.json')).json();
+  const byTag = {};
+  for (const [path, item] of Object.entries(spec.paths)) {
+    for (const [method, op] of Object.entries(item)) {
+      (byTag[op.tags[0]] = byTag[op.tags[0]] || []).push({ path, method, op });
+    }
+  }
+  root.textContent = '';
+  for (const tag of Object.keys(byTag).sort()) {
+    const heading = document.createElement('h2');
+    heading.textContent = tag;
+    root.appendChild(heading);
+    for (const { path, method, op } of byTag[tag].sort((a, b) => a.path.localeCompare(b.path))) {
+      root.appendChild(operation(path, method, op));
+    }
+  }
+
+  function operation(path, method, op) {
+    const details = document.createElement('details');
+    const summary = document.createElement('summary');
+    summary.innerHTML = '<span class="method"></span> <span class="path"></span> <span></span><span class="access"></span>';
+    summary.children[0].textContent = method.toUpperCase();
+    summary.children[1].textContent = path;
+    summary.children[2].textContent = '- ' + op.summary;
+    summary.children[3].textContent = op['x-access'] || 'public';
+    details.appendChild(summary);
+
+    const form = document.createElement('form');
+    const inputs = {};
+    for (const param of op.parameters || []) {
+      const label = document.createElement('label');
+      label.textContent = param.name + ' ';
+      inputs[param.name] = document.createElement('input');
+      label.appendChild(inputs[param.name]);
+      form.appendChild(label);
+    }
+    const query = document.createElement('input');
+    query.placeholder = 'query string, e.g. q=notes&limit=5';
+    query.style.width = '100%';
+    form.appendChild(query);
+    let body = null;
+    if (op.requestBody && op.requestBody.content['application/json']) {
+      body = document.createElement('textarea');
+      body.value = '{}';
+      form.appendChild(body);
+    }
+    const send = document.createElement('button');
+    send.textContent = 'Send';
+    form.appendChild(send);
+    const output = document.createElement('pre');
+    output.hidden = true;
+    details.append(form, output);
+
+    form.addEventListener('submit', async (e) => {
+      e.preventDefault();
+      let url = path.replace(/\{([^}]+)\}/g, (_, name) => encodeURIComponent(inputs[name].value));
+      if (query.value) url += '?' + query.value;
+      const init = { method: method.toUpperCase(), credentials: 'same-origin', headers: {} };
+      if (body) {
+        init.headers['Content-Type'] = 'application/json';
+        init.body = body.value;
+      }
+      output.hidden = false;
+      output.textContent = 'Sending...';
+      try {
+        const resp = await fetch(url, init);
+        let text = await resp.text();
+        try { text = JSON.stringify(JSON.parse(text), null, 2); } catch (_) {}
+        output.textContent = resp.status + ' ' + resp.statusText + '\n\n' + text;
+      } catch (err) {
+        output.textContent = 'Request failed: ' + err;
+      }
+    });
+    return details;
+  }
+})();
+</script>
+</body>
+</html>
+`))
+
+// handleAPIDocs handles GET /api/docs - a page documenting the API from its
+// OpenAPI spec, with a form to try each operation
+func (s *Server) handleAPIDocs(w http.ResponseWriter, r *http.Request) {
+	nonce := generateNonce()
+	setCSPHeader(w, nonce)
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := apiDocsPage.Execute(w, map[string]interface{}{"Nonce": nonce}); err != nil {
+		s.logger.Error("failed to render API docs", "error", err.Error())
+	}
+}
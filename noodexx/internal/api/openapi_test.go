@@ -0,0 +1,77 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+// TestOpenAPISpecMatchesRoutes tests that every /api/ route is in the spec and
+// every operation in the spec is served
+func TestOpenAPISpecMatchesRoutes(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	server := &Server{store: &mockStore{}, logger: &mockLogger{}}
+	server.RegisterRoutes(http.NewServeMux())
+
+	for _, line := range strings.Split(buf.String(), "\n") {
+		if strings.Contains(line, "OpenAPI") {
+			t.Error(line)
+		}
+	}
+}
+
+func TestSpecMismatches(t *testing.T) {
+	operations := []apiOperation{
+		{"GET", "/api/sessions", "Chat", "List chat sessions", accessUser, ""},
+		{"POST", "/api/gone", "Chat", "Removed", accessUser, ""},
+	}
+	undocumented, unserved := specMismatches([]string{"GET /api/sessions", "GET /api/new", "GET /static/{path...}"}, operations)
+	if len(undocumented) != 1 || undocumented[0] != "GET /api/new" {
+		t.Errorf("Expected GET /api/new undocumented, got %v", undocumented)
+	}
+	if len(unserved) != 1 || unserved[0] != "POST /api/gone" {
+		t.Errorf("Expected POST /api/gone unserved, got %v", unserved)
+	}
+}
+
+func TestHandleOpenAPISpec(t *testing.T) {
+	server := &Server{store: &mockStore{}, logger: &mockLogger{}}
+
+	w := serveRoute(server, withUser(httptest.NewRequest(http.MethodGet, "/api/openapi.json", nil), 1))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	var spec struct {
+		OpenAPI string                                       `json:"openapi"`
+		Paths   map[string]map[string]map[string]interface{} `json:"paths"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &spec); err != nil {
+		t.Fatalf("failed to unmarshal spec: %v", err)
+	}
+	if !strings.HasPrefix(spec.OpenAPI, "3.") {
+		t.Errorf("Expected an OpenAPI 3 document, got %q", spec.OpenAPI)
+	}
+	op := spec.Paths["/api/skills/{id}/runs/{run_id}/rerun"]["post"]
+	if op == nil || op["operationId"] != "postSkillsIdRunsRunIdRerun" {
+		t.Fatalf("Expected the rerun operation, got %v", op)
+	}
+	if params, _ := op["parameters"].([]interface{}); len(params) != 2 {
+		t.Errorf("Expected 2 path parameters, got %v", op["parameters"])
+	}
+	if spec.Paths["/api/users"]["get"]["x-access"] != accessAdmin {
+		t.Errorf("Expected GET /api/users marked admin, got %v", spec.Paths["/api/users"]["get"])
+	}
+
+	w = serveRoute(server, withUser(httptest.NewRequest(http.MethodGet, "/api/docs", nil), 1))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "/api/openapi.json") {
+		t.Errorf("Expected the docs page, got %d", w.Code)
+	}
+}
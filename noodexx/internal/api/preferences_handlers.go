@@ -0,0 +1,205 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"noodexx/internal/auth"
+	"noodexx/internal/validate"
+	"time"
+)
+
+// Themes a user can choose; "system" follows the operating system's setting
+var Themes = []string{"light", "dark", "system"}
+
+// defaultTheme is the theme of users who never chose one
+const defaultTheme = "light"
+
+// Preference names in storage
+const (
+	preferenceTheme = "theme"
+)
+
+// Preferences are a user's interface settings. New settings are added here
+// and stored by name, without changing the database
+type Preferences struct {
+	Theme string `json:"theme"` // "light", "dark" or "system"
+}
+
+// userPreferences returns a user's preferences, with defaults for those they
+// have not set or that are no longer valid
+func (s *Server) userPreferences(ctx context.Context, userID int64) (Preferences, error) {
+	prefs := Preferences{Theme: defaultTheme}
+	stored, err := s.store.GetUserPreferences(ctx, userID)
+	if err != nil {
+		return prefs, err
+	}
+	if theme := stored[preferenceTheme]; validate.OneOf("Theme", theme, Themes...) == nil {
+		prefs.Theme = theme
+	}
+	return prefs, nil
+}
+
+// pageTheme returns the theme to render a page in for the signed-in user, so
+// it is drawn in that theme from the first paint
+func (s *Server) pageTheme(ctx context.Context) string {
+	userID, err := auth.GetUserID(ctx)
+	if err != nil {
+		return defaultTheme
+	}
+	prefs, err := s.userPreferences(ctx, userID)
+	if err != nil {
+		s.logger.Warn("failed to get preferences", "user_id", userID, "error", err.Error())
+	}
+	return prefs.Theme
+}
+
+// handlePreferences handles GET /api/me/preferences - the current user's
+// interface preferences
+func (s *Server) handlePreferences(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	requestID := newRequestID(w)
+
+	// Create logger with request context
+	logger := s.logger.WithContext("request_id", requestID).
+		WithContext("method", r.Method).
+		WithContext("path", r.URL.Path)
+
+	logger.Debug("processing preferences request")
+
+	ctx := r.Context()
+
+	// Extract user_id from context
+	userID, err := auth.GetUserID(ctx)
+	if err != nil {
+		logger.Error("request failed", "operation", "get_user_id", "error", err.Error())
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
+		return
+	}
+
+	s.writePreferences(ctx, w, logger, start, userID)
+}
+
+// handleSetPreferences handles PUT /api/me/preferences - changes only the
+// fields it sends, {"theme": "system"}, and returns every preference
+func (s *Server) handleSetPreferences(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	requestID := newRequestID(w)
+
+	// Create logger with request context
+	logger := s.logger.WithContext("request_id", requestID).
+		WithContext("method", r.Method).
+		WithContext("path", r.URL.Path)
+
+	logger.Debug("processing set preferences request")
+
+	ctx := r.Context()
+
+	// Extract user_id from context
+	userID, err := auth.GetUserID(ctx)
+	if err != nil {
+		logger.Error("request failed", "operation", "get_user_id", "error", err.Error())
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req struct {
+		Theme *string `json:"theme"`
+	}
+	if !decodeJSON(w, r, logger, &req) {
+		return
+	}
+
+	v := validate.New()
+	changes := map[string]string{}
+	if req.Theme != nil {
+		v.Check("theme", validate.OneOf("Theme", *req.Theme, Themes...))
+		changes[preferenceTheme] = *req.Theme
+	}
+	if err := v.Err(); err != nil {
+		writeValidationError(w, logger, err)
+		return
+	}
+
+	if len(changes) > 0 {
+		if err := s.store.SetUserPreferences(ctx, userID, changes); err != nil {
+			logger.Error("request failed", "operation", "set_preferences", "error", err.Error())
+			writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to save preferences")
+			return
+		}
+	}
+
+	s.writePreferences(ctx, w, logger, start, userID)
+}
+
+// writePreferences responds with a user's preferences and the themes they can choose
+func (s *Server) writePreferences(ctx context.Context, w http.ResponseWriter, logger Logger, start time.Time, userID int64) {
+	prefs, err := s.userPreferences(ctx, userID)
+	if err != nil {
+		logger.Error("request failed", "operation", "get_preferences", "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to get preferences")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":     true,
+		"preferences": prefs,
+		"themes":      Themes,
+	})
+
+	latency := time.Since(start).Milliseconds()
+	logger.Debug("request completed", "status", http.StatusOK, "latency_ms", latency, "theme", prefs.Theme)
+}
+
+// handleUpdatePreferences handles POST /api/user/preferences endpoint
+// Switches dark mode on or off, setting the theme to dark or light. Kept for
+// older clients; /api/me/preferences covers every preference
+func (s *Server) handleUpdatePreferences(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	requestID := newRequestID(w)
+
+	// Create logger with request context
+	logger := s.logger.WithContext("request_id", requestID).
+		WithContext("method", r.Method).
+		WithContext("path", r.URL.Path)
+
+	logger.Debug("processing update preferences request")
+
+	ctx := r.Context()
+
+	// Extract user_id from context (set by auth middleware)
+	userID, err := auth.GetUserID(ctx)
+	if err != nil {
+		logger.Error("request failed", "operation", "get_user_id", "error", err.Error())
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
+		return
+	}
+
+	// Parse request
+	var req struct {
+		DarkMode bool `json:"dark_mode"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logger.Error("request failed", "operation", "parse_request", "error", err.Error())
+		writeError(w, http.StatusBadRequest, CodeBadRequest, "Invalid request")
+		return
+	}
+
+	// Update user dark mode preference
+	if err := s.store.UpdateUserDarkMode(ctx, userID, req.DarkMode); err != nil {
+		logger.Error("preferences update failed", "user_id", userID, "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to update preferences")
+		return
+	}
+
+	// Return success response
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Preferences updated successfully",
+	})
+
+	latency := time.Since(start).Milliseconds()
+	logger.Debug("preferences update successful", "user_id", userID, "dark_mode", req.DarkMode, "latency_ms", latency)
+}
@@ -8,7 +8,6 @@ import (
 	"net/http/httptest"
 	"noodexx/internal/auth"
 	"testing"
-	"time"
 )
 
 // mockLogger for testing
@@ -23,7 +22,9 @@ func (m *mockLoggerForPreferences) WithFields(fields map[string]interface{}) Log
 
 // mockStoreForPreferences implements the Store interface for preferences testing
 type mockStoreForPreferences struct {
+	baseMockStore
 	updateUserDarkModeFunc func(ctx context.Context, userID int64, darkMode bool) error
+	prefs                  map[string]string
 }
 
 func (m *mockStoreForPreferences) UpdateUserDarkMode(ctx context.Context, userID int64, darkMode bool) error {
@@ -33,76 +34,19 @@ func (m *mockStoreForPreferences) UpdateUserDarkMode(ctx context.Context, userID
 	return nil
 }
 
-// Stub methods for Store interface (not used in preferences tests)
-func (m *mockStoreForPreferences) SaveChunk(ctx context.Context, source, text string, embedding []float32, tags []string, summary string) error {
-	return nil
-}
-func (m *mockStoreForPreferences) Search(ctx context.Context, queryVec []float32, topK int) ([]Chunk, error) {
-	return nil, nil
-}
-func (m *mockStoreForPreferences) SearchByUser(ctx context.Context, userID int64, queryVec []float32, topK int) ([]Chunk, error) {
-	return nil, nil
-}
-func (m *mockStoreForPreferences) Library(ctx context.Context) ([]LibraryEntry, error) {
-	return nil, nil
-}
-func (m *mockStoreForPreferences) LibraryByUser(ctx context.Context, userID int64) ([]LibraryEntry, error) {
-	return nil, nil
-}
-func (m *mockStoreForPreferences) DeleteSource(ctx context.Context, source string) error {
-	return nil
-}
-func (m *mockStoreForPreferences) SaveMessage(ctx context.Context, sessionID, role, content string) error {
-	return nil
-}
-func (m *mockStoreForPreferences) SaveChatMessage(ctx context.Context, userID int64, sessionID, role, content, providerMode string) error {
-	return nil
-}
-func (m *mockStoreForPreferences) GetSessionHistory(ctx context.Context, sessionID string) ([]ChatMessage, error) {
-	return nil, nil
-}
-func (m *mockStoreForPreferences) GetSessionMessages(ctx context.Context, userID int64, sessionID string) ([]ChatMessage, error) {
-	return nil, nil
-}
-func (m *mockStoreForPreferences) ListSessions(ctx context.Context) ([]Session, error) {
-	return nil, nil
-}
-func (m *mockStoreForPreferences) GetUserSessions(ctx context.Context, userID int64) ([]Session, error) {
-	return nil, nil
-}
-func (m *mockStoreForPreferences) GetSessionOwner(ctx context.Context, sessionID string) (int64, error) {
-	return 0, nil
-}
-func (m *mockStoreForPreferences) AddAuditEntry(ctx context.Context, opType, details, userCtx string) error {
-	return nil
-}
-func (m *mockStoreForPreferences) GetAuditLog(ctx context.Context, opType string, from, to time.Time) ([]AuditEntry, error) {
-	return nil, nil
-}
-func (m *mockStoreForPreferences) GetUserByUsername(ctx context.Context, username string) (*User, error) {
-	return nil, nil
-}
-func (m *mockStoreForPreferences) GetUserByID(ctx context.Context, userID int64) (*User, error) {
-	return nil, nil
-}
-func (m *mockStoreForPreferences) CreateUser(ctx context.Context, username, password, email string, isAdmin, mustChangePassword bool) (int64, error) {
-	return 0, nil
-}
-func (m *mockStoreForPreferences) UpdatePassword(ctx context.Context, userID int64, newPassword string) error {
-	return nil
-}
-func (m *mockStoreForPreferences) ListUsers(ctx context.Context) ([]User, error) {
-	return nil, nil
+func (m *mockStoreForPreferences) GetUserPreferences(ctx context.Context, userID int64) (map[string]string, error) {
+	return m.prefs, nil
 }
-func (m *mockStoreForPreferences) DeleteUser(ctx context.Context, userID int64) error {
+
+func (m *mockStoreForPreferences) SetUserPreferences(ctx context.Context, userID int64, prefs map[string]string) error {
+	if m.prefs == nil {
+		m.prefs = map[string]string{}
+	}
+	for name, value := range prefs {
+		m.prefs[name] = value
+	}
 	return nil
 }
-func (m *mockStoreForPreferences) GetUserSkills(ctx context.Context, userID int64) ([]Skill, error) {
-	return nil, nil
-}
-func (m *mockStoreForPreferences) GetWatchedFoldersByUser(ctx context.Context, userID int64) ([]WatchedFolder, error) {
-	return nil, nil
-}
 
 func TestHandleUpdatePreferences(t *testing.T) {
 	tests := []struct {
@@ -195,11 +139,8 @@ func TestHandleUpdatePreferences(t *testing.T) {
 			ctx := context.WithValue(req.Context(), auth.UserIDKey, tt.userID)
 			req = req.WithContext(ctx)
 
-			// Create response recorder
-			rr := httptest.NewRecorder()
-
-			// Call handler
-			server.handleUpdatePreferences(rr, req)
+			// Serve through the routes, which answer other methods with 405
+			rr := serveRoute(server, req)
 
 			// Check status code
 			if rr.Code != tt.expectedStatus {
@@ -224,3 +165,51 @@ func TestHandleUpdatePreferences(t *testing.T) {
 		})
 	}
 }
+
+func TestHandlePreferences(t *testing.T) {
+	store := &mockStoreForPreferences{}
+	server := &Server{store: store, logger: &mockLoggerForPreferences{}}
+	do := func(method, body string) (int, Preferences) {
+		req := httptest.NewRequest(method, "/api/me/preferences", bytes.NewReader([]byte(body)))
+		rr := serveRoute(server, withUser(req, 1))
+		var resp struct {
+			Preferences Preferences `json:"preferences"`
+		}
+		json.NewDecoder(rr.Body).Decode(&resp)
+		return rr.Code, resp.Preferences
+	}
+
+	// Users who never chose a theme get the light one
+	if code, prefs := do(http.MethodGet, ""); code != http.StatusOK || prefs.Theme != "light" {
+		t.Errorf("expected the light theme by default, got %d %+v", code, prefs)
+	}
+
+	if code, prefs := do(http.MethodPut, `{"theme": "system"}`); code != http.StatusOK || prefs.Theme != "system" {
+		t.Errorf("expected the system theme saved, got %d %+v", code, prefs)
+	}
+	if store.prefs["theme"] != "system" {
+		t.Errorf("expected the theme stored by name, got %v", store.prefs)
+	}
+
+	// Fields not sent are left alone, and unknown themes are refused
+	if code, prefs := do(http.MethodPut, `{}`); code != http.StatusOK || prefs.Theme != "system" {
+		t.Errorf("expected the theme unchanged, got %d %+v", code, prefs)
+	}
+	if code, _ := do(http.MethodPut, `{"theme": "sepia"}`); code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unknown theme, got %d", code)
+	}
+
+	// Pages are rendered in the saved theme, and fall back to light when a
+	// stored value is no longer valid
+	ctx := context.WithValue(context.Background(), auth.UserIDKey, int64(1))
+	if theme := server.pageTheme(ctx); theme != "system" {
+		t.Errorf("expected pages in the system theme, got %q", theme)
+	}
+	store.prefs["theme"] = "sepia"
+	if theme := server.pageTheme(ctx); theme != "light" {
+		t.Errorf("expected the default for an invalid theme, got %q", theme)
+	}
+	if theme := server.pageTheme(context.Background()); theme != "light" {
+		t.Errorf("expected the default without a user, got %q", theme)
+	}
+}
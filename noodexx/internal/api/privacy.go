@@ -0,0 +1,63 @@
+package api
+
+import "context"
+
+// Content kept out of a prompt, as listed in PrivacySummary.Redactions
+const (
+	redactLibrary = "library_content" // Library and attachment search skipped by the RAG policy
+	redactHistory = "session_history" // Earlier messages and summaries left out by the RAG policy
+)
+
+// PrivacySummary describes where the data behind an answer went, so the UI can
+// show a trust badge with it. It is sent as a trailing privacy event after
+// answers with progress events, and saved with the answer's provenance
+type PrivacySummary struct {
+	Provider           string         `json:"provider"`
+	ProviderMode       string         `json:"provider_mode"`        // "local" or "cloud"
+	RAGStatus          string         `json:"rag_status"`           // RAG policy the answer was generated under
+	ContextLeftMachine bool           `json:"context_left_machine"` // Whether retrieved context or history was sent to a cloud provider
+	Chunks             int            `json:"chunks"`               // Library chunks in the prompt
+	Visibility         map[string]int `json:"visibility"`           // Library chunks in the prompt by visibility level
+	OtherContext       int            `json:"other_context"`        // Attachment, web and skill results in the prompt
+	WebResults         int            `json:"web_results"`
+	Redactions         []string       `json:"redactions"` // Content kept out of the prompt
+}
+
+// privacySummary summarizes where an answer's prompt went. Chunk visibility is
+// looked up in the library; failing that, the levels are left out
+func (s *Server) privacySummary(ctx context.Context, logger Logger, mode *askMode, chunks []Chunk, webResults int, withHistory bool, redactions []string) *PrivacySummary {
+	p := &PrivacySummary{
+		Provider:     mode.provider.Name(),
+		ProviderMode: "local",
+		RAGStatus:    mode.ragStatus,
+		Visibility:   map[string]int{},
+		WebResults:   webResults,
+		Redactions:   redactions,
+	}
+	if !mode.local {
+		p.ProviderMode = "cloud"
+	}
+	if p.Redactions == nil {
+		p.Redactions = []string{}
+	}
+
+	var ids []int64
+	for _, chunk := range chunks {
+		if chunk.ID != 0 {
+			ids = append(ids, chunk.ID)
+		} else {
+			p.OtherContext++
+		}
+	}
+	p.Chunks = len(ids)
+	if len(ids) > 0 {
+		counts, err := s.store.CountChunkVisibility(ctx, ids)
+		if err != nil {
+			logger.Warn("failed to count chunk visibility", "error", err.Error())
+		} else if counts != nil {
+			p.Visibility = counts
+		}
+	}
+	p.ContextLeftMachine = !mode.local && (len(chunks) > 0 || withHistory)
+	return p
+}
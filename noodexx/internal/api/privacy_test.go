@@ -0,0 +1,83 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"noodexx/internal/auth"
+	"strings"
+	"testing"
+)
+
+// privacyStore serves library chunks of known visibility and records the provenance saved
+type privacyStore struct {
+	mockStoreForAsk
+	provenance *MessageProvenance
+}
+
+func (m *privacyStore) SearchLibrary(ctx context.Context, userID int64, queryVec []float32, opts SearchOptions, topK int) ([]Chunk, error) {
+	return []Chunk{{ID: 1, Source: "a.md", Text: "a"}, {ID: 2, Source: "b.md", Text: "b"}}, nil
+}
+
+func (m *privacyStore) GetSessionOwner(ctx context.Context, sessionID string) (int64, error) {
+	return 1, nil
+}
+
+func (m *privacyStore) CountChunkVisibility(ctx context.Context, chunkIDs []int64) (map[string]int, error) {
+	return map[string]int{"private": 1, "shared": len(chunkIDs) - 1}, nil
+}
+
+func (m *privacyStore) SaveChatMessageWithProvenance(ctx context.Context, userID int64, sessionID, role, content, providerMode string, citations []string, provenance *MessageProvenance) (int64, error) {
+	m.provenance = provenance
+	return 1, nil
+}
+
+func TestAskPrivacySummary(t *testing.T) {
+	store := &privacyStore{}
+	rag := &mockRAGEnforcerForAsk{shouldPerformRAG: true, ragStatus: "RAG Enabled (Local)"}
+	manager := &mockProviderManagerForAsk{provider: &mockProviderForAsk{name: "ollama", isLocal: true}, providerName: "Local AI (ollama)"}
+	server := &Server{
+		store:           store,
+		logger:          &mockLoggerForAsk{},
+		providerManager: manager,
+		ragEnforcer:     rag,
+	}
+	ask := func() (*PrivacySummary, string) {
+		body, _ := json.Marshal(map[string]interface{}{"query": "Hi", "session_id": "s1", "progress": true})
+		req := httptest.NewRequest(http.MethodPost, "/api/ask", bytes.NewReader(body))
+		req = req.WithContext(context.WithValue(req.Context(), auth.UserIDKey, int64(1)))
+		w := httptest.NewRecorder()
+		server.handleAsk(w, req)
+		if store.provenance == nil {
+			t.Fatalf("Expected the answer saved, got %d %s", w.Code, w.Body.String())
+		}
+		return store.provenance.Privacy, w.Body.String()
+	}
+
+	// A local answer keeps its context on the machine
+	p, body := ask()
+	if p == nil || p.ProviderMode != "local" || p.Provider != "ollama" || p.ContextLeftMachine || p.Chunks != 2 || p.Visibility["shared"] != 1 || p.Visibility["private"] != 1 || len(p.Redactions) != 0 {
+		t.Errorf("Unexpected local summary: %+v", p)
+	}
+	_, event, found := strings.Cut(body, "\n\nevent: privacy\ndata: ")
+	var sent PrivacySummary
+	if line, _, _ := strings.Cut(event, "\n"); !found || json.Unmarshal([]byte(line), &sent) != nil || sent.Chunks != 2 {
+		t.Errorf("Expected a privacy event after the answer, got %q", body)
+	}
+
+	// Library content sent to the cloud left the machine
+	manager.provider = &mockProviderForAsk{name: "openai", isLocal: false}
+	rag.ragStatus = "RAG Enabled (Cloud)"
+	if p, _ := ask(); p.ProviderMode != "cloud" || !p.ContextLeftMachine || p.Chunks != 2 {
+		t.Errorf("Unexpected cloud summary: %+v", p)
+	}
+
+	// What the cloud RAG policy withholds is listed
+	rag.shouldPerformRAG, rag.ragStatus = false, "RAG Disabled (Cloud Policy)"
+	p, _ = ask()
+	if p.ContextLeftMachine || p.Chunks != 0 || len(p.Redactions) != 2 || p.Redactions[0] != redactLibrary || p.Redactions[1] != redactHistory || p.RAGStatus != "RAG Disabled (Cloud Policy)" {
+		t.Errorf("Unexpected summary under the cloud policy: %+v", p)
+	}
+}
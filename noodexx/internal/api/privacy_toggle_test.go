@@ -76,9 +76,6 @@ func TestHandlePrivacyToggle_TogglingToLocal(t *testing.T) {
 
 	// Write initial config
 	cfg := &config.Config{
-		Provider: config.ProviderConfig{
-			Type: "ollama",
-		},
 		LocalProvider: config.ProviderConfig{
 			Type:             "ollama",
 			OllamaEndpoint:   "http://localhost:11434",
@@ -181,9 +178,6 @@ func TestHandlePrivacyToggle_TogglingToCloud(t *testing.T) {
 
 	// Write initial config
 	cfg := &config.Config{
-		Provider: config.ProviderConfig{
-			Type: "ollama",
-		},
 		LocalProvider: config.ProviderConfig{
 			Type:             "ollama",
 			OllamaEndpoint:   "http://localhost:11434",
@@ -282,7 +276,7 @@ func TestHandlePrivacyToggle_InvalidMode(t *testing.T) {
 
 	// Write initial config
 	cfg := &config.Config{
-		Provider: config.ProviderConfig{
+		LocalProvider: config.ProviderConfig{
 			Type: "ollama",
 		},
 		Privacy: config.PrivacyConfig{
@@ -346,16 +340,14 @@ func TestHandlePrivacyToggle_InvalidMode(t *testing.T) {
 	}
 }
 
-// TestHandlePrivacyToggle_MethodNotAllowed tests non-POST method
+// TestHandlePrivacyToggle_MethodNotAllowed tests a method the route does not handle
 func TestHandlePrivacyToggle_MethodNotAllowed(t *testing.T) {
 	server := &Server{
 		logger: &MockLogger{},
 	}
 
-	req := httptest.NewRequest(http.MethodGet, "/api/privacy-toggle", nil)
-	w := httptest.NewRecorder()
-
-	server.handlePrivacyToggle(w, req)
+	req := httptest.NewRequest(http.MethodDelete, "/api/privacy-toggle", nil)
+	w := serveRoute(server, req)
 
 	if w.Code != http.StatusMethodNotAllowed {
 		t.Errorf("Expected status %d, got %d", http.StatusMethodNotAllowed, w.Code)
@@ -389,8 +381,11 @@ func TestHandlePrivacyToggle_ResponseTime(t *testing.T) {
 
 	// Write initial config
 	cfg := &config.Config{
-		Provider: config.ProviderConfig{
-			Type: "ollama",
+		LocalProvider: config.ProviderConfig{
+			Type:             "ollama",
+			OllamaEndpoint:   "http://localhost:11434",
+			OllamaEmbedModel: "nomic-embed-text",
+			OllamaChatModel:  "llama3.2",
 		},
 		Privacy: config.PrivacyConfig{
 			DefaultToLocal:     false,
@@ -0,0 +1,109 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"noodexx/internal/auth"
+	"time"
+)
+
+// activeModel returns the chat model of the active provider, if the manager reports it
+func (s *Server) activeModel() string {
+	if reporter, ok := s.providerManager.(ActiveModelReporter); ok {
+		return reporter.GetActiveModel()
+	}
+	return ""
+}
+
+// hashMessages returns the SHA-256 of the messages sent to the model, identifying the exact prompt
+func hashMessages(messages []Message) string {
+	h := sha256.New()
+	for _, msg := range messages {
+		h.Write([]byte(msg.Role))
+		h.Write([]byte{0})
+		h.Write([]byte(msg.Content))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// estimateTokens approximates the token count of text at about four characters per token
+func estimateTokens(text string) int {
+	return (len(text) + 3) / 4
+}
+
+// newMessageProvenance describes how response was generated from messages and chunks
+func newMessageProvenance(provider LLMProvider, model string, params map[string]interface{}, messages []Message, chunks []Chunk, response string) *MessageProvenance {
+	p := &MessageProvenance{
+		Provider:         provider.Name(),
+		Model:            model,
+		PromptHash:       hashMessages(messages),
+		ChunkIDs:         []int64{},
+		CompletionTokens: estimateTokens(response),
+	}
+	if data, err := json.Marshal(params); err == nil {
+		p.Params = data
+	}
+	for _, msg := range messages {
+		p.PromptTokens += estimateTokens(msg.Content)
+	}
+	// Only library chunks have IDs; attachments and web results are identified by their citations
+	for _, chunk := range chunks {
+		if chunk.ID != 0 {
+			p.ChunkIDs = append(p.ChunkIDs, chunk.ID)
+		}
+	}
+	return p
+}
+
+// handleMessageProvenance returns how one of the user's assistant messages was generated
+// Path: GET /api/message/:id/provenance
+func (s *Server) handleMessageProvenance(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	requestID := newRequestID(w)
+
+	// Create logger with request context
+	logger := s.logger.WithContext("request_id", requestID).
+		WithContext("method", r.Method).
+		WithContext("path", r.URL.Path)
+
+	logger.Debug("processing message provenance request")
+
+	ctx := r.Context()
+
+	// Extract user_id from context
+	userID, err := auth.GetUserID(ctx)
+	if err != nil {
+		logger.Error("request failed", "operation", "get_user_id", "error", err.Error())
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
+		return
+	}
+
+	messageID, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeBadRequest, "Invalid message ID")
+		return
+	}
+
+	provenance, err := s.store.GetMessageProvenance(ctx, userID, messageID)
+	if err != nil {
+		logger.Error("request failed", "operation", "get_message_provenance", "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to get message provenance")
+		return
+	}
+	if provenance == nil {
+		writeError(w, http.StatusNotFound, CodeNotFound, "Provenance not found")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":    true,
+		"provenance": provenance,
+	})
+
+	latency := time.Since(start).Milliseconds()
+	logger.Debug("request completed", "message_id", messageID, "latency_ms", latency)
+}
@@ -0,0 +1,82 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// provenanceStore serves the provenance of message 5, owned by user 1
+type provenanceStore struct {
+	mockStore
+}
+
+func (m *provenanceStore) GetMessageProvenance(ctx context.Context, userID, messageID int64) (*MessageProvenance, error) {
+	if userID != 1 || messageID != 5 {
+		return nil, nil
+	}
+	return &MessageProvenance{MessageID: 5, Provider: "ollama", Model: "llama3.2", Params: json.RawMessage(`{"rag_status":"on"}`), ChunkIDs: []int64{9}}, nil
+}
+
+// TestNewMessageProvenance tests that only library chunks are recorded and the prompt is hashed
+func TestNewMessageProvenance(t *testing.T) {
+	messages := []Message{{Role: "system", Content: "sys"}, {Role: "user", Content: "question text"}}
+	chunks := []Chunk{{ID: 4, Source: "a.txt"}, {Source: "attachment:b.md"}, {ID: 2, Source: "c.txt"}}
+
+	p := newMessageProvenance(&mockProvider{}, "llama3.2", map[string]interface{}{"web_results": 0}, messages, chunks, "answer")
+	if p.Model != "llama3.2" || p.Provider != (&mockProvider{}).Name() {
+		t.Errorf("Unexpected provider or model: %+v", p)
+	}
+	if len(p.ChunkIDs) != 2 || p.ChunkIDs[0] != 4 || p.ChunkIDs[1] != 2 {
+		t.Errorf("Expected library chunk IDs [4 2], got %v", p.ChunkIDs)
+	}
+	if string(p.Params) != `{"web_results":0}` {
+		t.Errorf("Unexpected params: %s", p.Params)
+	}
+	if p.PromptTokens != estimateTokens("sys")+estimateTokens("question text") || p.CompletionTokens != estimateTokens("answer") {
+		t.Errorf("Unexpected token counts: %+v", p)
+	}
+	if len(p.PromptHash) != 64 || p.PromptHash != hashMessages(messages) {
+		t.Errorf("Expected a stable SHA-256 prompt hash, got %q", p.PromptHash)
+	}
+	changed := []Message{{Role: "system", Content: "sys"}, {Role: "user", Content: "question text!"}}
+	if hashMessages(changed) == p.PromptHash {
+		t.Error("Expected a different prompt to hash differently")
+	}
+}
+
+// TestHandleMessageProvenance tests the provenance endpoint
+func TestHandleMessageProvenance(t *testing.T) {
+	server := &Server{store: &provenanceStore{}, logger: &mockLogger{}}
+
+	get := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		return serveRoute(server, withUser(req, 1))
+	}
+
+	w := get("/api/message/5/provenance")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Provenance MessageProvenance `json:"provenance"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if resp.Provenance.Model != "llama3.2" || string(resp.Provenance.Params) != `{"rag_status":"on"}` || resp.Provenance.ChunkIDs[0] != 9 {
+		t.Errorf("Unexpected provenance: %+v", resp.Provenance)
+	}
+
+	if w := get("/api/message/6/provenance"); w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for a message without provenance, got %d", w.Code)
+	}
+	if w := get("/api/message/abc/provenance"); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an invalid ID, got %d", w.Code)
+	}
+	if w := get("/api/message/5"); w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 without /provenance, got %d", w.Code)
+	}
+}
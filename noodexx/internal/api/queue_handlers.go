@@ -0,0 +1,78 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// defaultQueueKeepAlive is the interval of queue position events when none is configured
+const defaultQueueKeepAlive = 5 * time.Second
+
+// writeQueueEvent sends the caller's queue position as an SSE event ahead of the answer
+// Position 0 means generation is starting
+func writeQueueEvent(sse *SSEWriter, position int) {
+	sse.Event("queue", map[string]int{"position": position})
+}
+
+// waitForProvider blocks until ticket is admitted, sending queue events to sse while it waits
+// It returns how long the request waited, or an error if the client went away first
+// Nothing is written when the ticket is admitted immediately
+func (s *Server) waitForProvider(ctx context.Context, sse *SSEWriter, ticket QueueTicket) (time.Duration, error) {
+	select {
+	case <-ticket.Ready():
+		return 0, nil
+	default:
+	}
+
+	start := time.Now()
+	keepAlive := s.queueKeepAlive
+	if keepAlive <= 0 {
+		keepAlive = defaultQueueKeepAlive
+	}
+	ticker := time.NewTicker(keepAlive)
+	defer ticker.Stop()
+
+	writeQueueEvent(sse, ticket.Position())
+	for {
+		select {
+		case <-ticket.Ready():
+			writeQueueEvent(sse, 0)
+			return time.Since(start), nil
+		case <-ticker.C:
+			writeQueueEvent(sse, ticket.Position())
+		case <-ctx.Done():
+			return time.Since(start), ctx.Err()
+		}
+	}
+}
+
+// handleProviderQueue handles GET /api/admin/provider-queue - load and wait times of
+// the answer generation queue (admin only)
+func (s *Server) handleProviderQueue(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	requestID := newRequestID(w)
+
+	// Create logger with request context
+	logger := s.logger.WithContext("request_id", requestID).
+		WithContext("method", r.Method).
+		WithContext("path", r.URL.Path)
+
+	logger.Debug("processing provider queue request")
+
+	var stats ProviderQueueStats
+	if s.providerQueue != nil {
+		stats = s.providerQueue.Stats()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"enabled": s.providerQueue != nil,
+		"stats":   stats,
+	})
+
+	latency := time.Since(start).Milliseconds()
+	logger.Debug("request completed", "latency_ms", latency)
+}
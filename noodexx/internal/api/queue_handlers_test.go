@@ -0,0 +1,143 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"noodexx/internal/auth"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeTicket is admitted when its ready channel is closed
+type fakeTicket struct {
+	ready    chan struct{}
+	position int
+	released bool
+}
+
+func (t *fakeTicket) Ready() <-chan struct{} { return t.ready }
+func (t *fakeTicket) Position() int          { return t.position }
+func (t *fakeTicket) Release()               { t.released = true }
+
+// fakeQueue hands out a single ticket
+type fakeQueue struct {
+	ticket *fakeTicket
+	userID int64
+}
+
+func (q *fakeQueue) Join(userID int64) QueueTicket {
+	q.userID = userID
+	return q.ticket
+}
+
+func (q *fakeQueue) Stats() ProviderQueueStats {
+	return ProviderQueueStats{Waiting: 1}
+}
+
+// TestWaitForProvider_Immediate tests that an admitted request writes no queue events
+func TestWaitForProvider_Immediate(t *testing.T) {
+	server := &Server{}
+	ticket := &fakeTicket{ready: make(chan struct{})}
+	close(ticket.ready)
+
+	w := httptest.NewRecorder()
+	wait, err := server.waitForProvider(context.Background(), NewSSEWriter(w), ticket)
+	if err != nil || wait != 0 {
+		t.Fatalf("Expected immediate admission, got wait %v and error %v", wait, err)
+	}
+	if w.Body.Len() != 0 {
+		t.Errorf("Expected no output, got %q", w.Body.String())
+	}
+}
+
+// TestWaitForProvider_Queued tests position events while waiting and the start event
+func TestWaitForProvider_Queued(t *testing.T) {
+	server := &Server{queueKeepAlive: 10 * time.Millisecond}
+	ticket := &fakeTicket{ready: make(chan struct{}), position: 2}
+	time.AfterFunc(35*time.Millisecond, func() { close(ticket.ready) })
+
+	w := httptest.NewRecorder()
+	if _, err := server.waitForProvider(context.Background(), NewSSEWriter(w), ticket); err != nil {
+		t.Fatalf("waitForProvider() error = %v", err)
+	}
+
+	body := w.Body.String()
+	if !strings.HasPrefix(body, "event: queue\ndata: {\"position\":2}\n\n") {
+		t.Errorf("Expected an initial position event, got %q", body)
+	}
+	if strings.Count(body, "{\"position\":2}") < 2 {
+		t.Errorf("Expected keep-alive position events while waiting, got %q", body)
+	}
+	if !strings.HasSuffix(body, "event: queue\ndata: {\"position\":0}\n\n") {
+		t.Errorf("Expected a final start event, got %q", body)
+	}
+}
+
+// TestWaitForProvider_ClientGone tests that waiting stops when the client disconnects
+func TestWaitForProvider_ClientGone(t *testing.T) {
+	server := &Server{queueKeepAlive: time.Hour}
+	ticket := &fakeTicket{ready: make(chan struct{}), position: 1}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := server.waitForProvider(ctx, NewSSEWriter(httptest.NewRecorder()), ticket); err == nil {
+		t.Error("Expected an error after the client went away")
+	}
+}
+
+// TestHandleAsk_ProviderQueue tests that /api/ask streams queue events before the answer
+func TestHandleAsk_ProviderQueue(t *testing.T) {
+	ticket := &fakeTicket{ready: make(chan struct{}), position: 3}
+	time.AfterFunc(20*time.Millisecond, func() { close(ticket.ready) })
+	queue := &fakeQueue{ticket: ticket}
+
+	server := &Server{
+		store:           &mockStoreForAsk{},
+		logger:          &mockLoggerForAsk{},
+		providerManager: &mockProviderManagerForAsk{provider: &mockProviderForAsk{name: "ollama", isLocal: true}, providerName: "Ollama (llama3.2)"},
+		ragEnforcer:     &mockRAGEnforcerForAsk{shouldPerformRAG: false, ragStatus: "RAG Disabled"},
+		providerQueue:   queue,
+		queueKeepAlive:  time.Hour,
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/ask", bytes.NewBufferString(`{"query":"test query","session_id":"s1"}`))
+	req = req.WithContext(context.WithValue(req.Context(), auth.UserIDKey, int64(7)))
+	w := httptest.NewRecorder()
+	server.handleAsk(w, req)
+
+	want := "event: queue\ndata: {\"position\":3}\n\nevent: queue\ndata: {\"position\":0}\n\ntest response"
+	if w.Body.String() != want {
+		t.Errorf("Expected queue events followed by the answer, got %q", w.Body.String())
+	}
+	if queue.userID != 7 || !ticket.released {
+		t.Errorf("Expected user 7's ticket to be released, got user %d (released=%v)", queue.userID, ticket.released)
+	}
+}
+
+// TestHandleAsk_ProviderQueueEvents tests that a queued answer framed as events is one
+// event stream, its queue events sent by the same writer as its tokens
+func TestHandleAsk_ProviderQueueEvents(t *testing.T) {
+	ticket := &fakeTicket{ready: make(chan struct{}), position: 2}
+	time.AfterFunc(20*time.Millisecond, func() { close(ticket.ready) })
+
+	server := &Server{
+		store:           &mockStoreForAsk{},
+		logger:          &mockLoggerForAsk{},
+		providerManager: &mockProviderManagerForAsk{provider: &mockProviderForAsk{name: "ollama", isLocal: true}, providerName: "Ollama (llama3.2)"},
+		ragEnforcer:     &mockRAGEnforcerForAsk{shouldPerformRAG: false, ragStatus: "RAG Disabled"},
+		providerQueue:   &fakeQueue{ticket: ticket},
+		queueKeepAlive:  time.Hour,
+	}
+
+	events := askWithEvents(t, server)
+	var kinds []string
+	for _, e := range events {
+		kinds = append(kinds, e.event)
+	}
+	if strings.Join(kinds, ",") != "queue,queue,token,done" {
+		t.Errorf("Expected queue events, the answer and done, got %v", kinds)
+	}
+}
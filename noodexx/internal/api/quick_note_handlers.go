@@ -0,0 +1,144 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"noodexx/internal/auth"
+	"noodexx/internal/validate"
+	"strings"
+	"time"
+)
+
+const (
+	// maxQuickNoteLength caps the characters of one captured note
+	maxQuickNoteLength = 4000
+	// quickNoteDedupWindow is how long an identical note is treated as a double
+	// submission and ignored
+	quickNoteDedupWindow = 30 * time.Second
+	// quickNotesTag tags every notes document
+	quickNotesTag = "notes"
+)
+
+// QuickNote is a captured note of a user's day document
+type QuickNote struct {
+	ID        int64
+	Text      string
+	CreatedAt time.Time
+}
+
+// notesSource names the notes document of the day t falls on, in server time
+func notesSource(t time.Time) string {
+	return "Notes " + t.Local().Format("2006-01-02")
+}
+
+// notesText builds a day document from its notes, each prefixed with the time
+// it was captured
+func notesText(notes []QuickNote) string {
+	parts := make([]string, len(notes))
+	for i, note := range notes {
+		parts[i] = fmt.Sprintf("[%s] %s", note.CreatedAt.Local().Format("15:04"), note.Text)
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// rebuildNotes re-ingests a user's day document from its notes. Only that
+// document is chunked and embedded again
+func (s *Server) rebuildNotes(ctx context.Context, userID int64, source string) error {
+	notes, err := s.store.GetQuickNotes(ctx, userID, source)
+	if err != nil {
+		return err
+	}
+	return s.ingester.IngestText(ctx, userID, source, notesText(notes), []string{quickNotesTag})
+}
+
+// handleQuickNote handles POST /api/notes - append a short note to the user's
+// notes document for today rather than creating a document per note. The same
+// text submitted again within quickNoteDedupWindow is ignored
+func (s *Server) handleQuickNote(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	requestID := newRequestID(w)
+
+	// Create logger with request context
+	logger := s.logger.WithContext("request_id", requestID).
+		WithContext("method", r.Method).
+		WithContext("path", r.URL.Path)
+
+	logger.Debug("processing quick note request")
+
+	ctx := r.Context()
+
+	// Extract user_id from context
+	userID, err := auth.GetUserID(ctx)
+	if err != nil {
+		logger.Error("request failed", "operation", "get_user_id", "error", err.Error())
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req struct {
+		Text string `json:"text"`
+	}
+	if !decodeJSON(w, r, logger, &req) {
+		return
+	}
+	text := strings.TrimSpace(req.Text)
+
+	v := validate.New()
+	v.Required("text", "Text", text)
+	if len([]rune(text)) > maxQuickNoteLength {
+		v.Check("text", fmt.Errorf("Text must be at most %d characters", maxQuickNoteLength))
+	}
+	if err := v.Err(); err != nil {
+		writeValidationError(w, logger, err)
+		return
+	}
+
+	source := notesSource(start)
+
+	// Notes of one document are added and re-ingested one at a time, so a
+	// rebuild never overwrites the document with fewer notes than it has
+	s.notesMu.Lock()
+	defer s.notesMu.Unlock()
+
+	noteID, err := s.store.AddQuickNote(ctx, userID, source, text, start.Add(-quickNoteDedupWindow))
+	if err != nil {
+		logger.Error("request failed", "operation", "add_quick_note", "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to save note")
+		return
+	}
+	if noteID == 0 {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success":   true,
+			"source":    source,
+			"duplicate": true,
+		})
+		logger.Debug("request completed", "status", http.StatusOK, "latency_ms", time.Since(start).Milliseconds(), "duplicate", true)
+		return
+	}
+
+	if err := s.rebuildNotes(ctx, userID, source); err != nil {
+		// Drop the note so it does not fail every later rebuild of the day
+		if delErr := s.store.DeleteQuickNote(ctx, userID, noteID); delErr != nil {
+			logger.Warn("failed to remove rejected note", "error", delErr.Error())
+		}
+		logger.Error("request failed", "operation", "ingest_notes", "source", source, "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, fmt.Sprintf("Ingestion failed: %v", err))
+		return
+	}
+
+	// Audit log
+	s.store.AddAuditEntry(ctx, "ingest", fmt.Sprintf("Note: %s", source), "")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":   true,
+		"source":    source,
+		"duplicate": false,
+	})
+
+	latency := time.Since(start).Milliseconds()
+	logger.Debug("request completed", "status", http.StatusOK, "latency_ms", latency, "source", source)
+}
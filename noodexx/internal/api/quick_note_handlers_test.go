@@ -0,0 +1,114 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"noodexx/internal/auth"
+	"strings"
+	"testing"
+	"time"
+)
+
+// quickNoteStore keeps notes in memory, ignoring the same text within the window
+type quickNoteStore struct {
+	mockStoreForAsk
+	notes []QuickNote
+}
+
+func (m *quickNoteStore) AddQuickNote(ctx context.Context, userID int64, source, text string, dedupSince time.Time) (int64, error) {
+	for _, note := range m.notes {
+		if note.Text == text && !note.CreatedAt.Before(dedupSince) {
+			return 0, nil
+		}
+	}
+	id := int64(len(m.notes) + 1)
+	m.notes = append(m.notes, QuickNote{ID: id, Text: text, CreatedAt: time.Now()})
+	return id, nil
+}
+
+func (m *quickNoteStore) GetQuickNotes(ctx context.Context, userID int64, source string) ([]QuickNote, error) {
+	return m.notes, nil
+}
+
+func (m *quickNoteStore) DeleteQuickNote(ctx context.Context, userID, noteID int64) error {
+	for i, note := range m.notes {
+		if note.ID == noteID {
+			m.notes = append(m.notes[:i], m.notes[i+1:]...)
+		}
+	}
+	return nil
+}
+
+// notesIngester records the documents ingested, rejecting text containing "reject"
+type notesIngester struct {
+	sources []string
+	texts   []string
+}
+
+func (m *notesIngester) IngestText(ctx context.Context, userID int64, source, text string, tags []string) error {
+	if strings.Contains(text, "reject") {
+		return errors.New("PII detected")
+	}
+	m.sources = append(m.sources, source)
+	m.texts = append(m.texts, text)
+	return nil
+}
+
+func (m *notesIngester) IngestURL(ctx context.Context, userID int64, url string, tags []string) error {
+	return nil
+}
+
+// TestHandleQuickNote tests appending notes to the day document and ignoring
+// double submissions
+func TestHandleQuickNote(t *testing.T) {
+	store := &quickNoteStore{}
+	ingester := &notesIngester{}
+	server := &Server{store: store, ingester: ingester, logger: &mockLoggerForAsk{}}
+
+	capture := func(text string) (*httptest.ResponseRecorder, bool) {
+		req := httptest.NewRequest(http.MethodPost, "/api/notes", bytes.NewBufferString(text))
+		req = req.WithContext(context.WithValue(req.Context(), auth.UserIDKey, int64(1)))
+		w := httptest.NewRecorder()
+		server.handleQuickNote(w, req)
+		var resp struct {
+			Duplicate bool `json:"duplicate"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return w, resp.Duplicate
+	}
+
+	if w, _ := capture(`{"text":"Call the plumber"}`); w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if w, dup := capture(`{"text":" Call the plumber "}`); w.Code != http.StatusOK || !dup {
+		t.Errorf("Expected the double submission to be ignored, got %d, duplicate %v", w.Code, dup)
+	}
+	capture(`{"text":"Buy milk"}`)
+
+	if len(ingester.texts) != 2 {
+		t.Fatalf("Expected the day document to be ingested twice, got %d", len(ingester.texts))
+	}
+	want := notesSource(time.Now())
+	if ingester.sources[1] != want {
+		t.Errorf("Expected source %q, got %q", want, ingester.sources[1])
+	}
+	if !strings.Contains(ingester.texts[1], "Call the plumber") || !strings.Contains(ingester.texts[1], "Buy milk") {
+		t.Errorf("Expected the document to hold both notes, got %q", ingester.texts[1])
+	}
+
+	// A note that fails ingestion is not kept for later rebuilds
+	if w, _ := capture(`{"text":"reject me"}`); w.Code != http.StatusInternalServerError {
+		t.Errorf("Expected status 500, got %d", w.Code)
+	}
+	if len(store.notes) != 2 {
+		t.Errorf("Expected the rejected note to be removed, got %+v", store.notes)
+	}
+
+	if w, _ := capture(`{"text":"   "}`); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an empty note, got %d", w.Code)
+	}
+}
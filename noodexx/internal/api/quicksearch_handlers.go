@@ -0,0 +1,136 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"noodexx/internal/auth"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Quick search limits
+const (
+	defaultQuickSearchLimit = 5  // Results per type when no limit is given
+	maxQuickSearchLimit     = 20 // Upper bound for any per-type limit
+	maxQuickSearchQuery     = 200
+)
+
+// parseQuickSearchLimits reads per-type result limits from the query string
+// "limit" sets every type, per-type parameters ("documents", "tags", "sessions",
+// "messages") override it, and "types" restricts the search to the listed types
+func parseQuickSearchLimits(r *http.Request) (QuickSearchLimits, error) {
+	query := r.URL.Query()
+
+	base := defaultQuickSearchLimit
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 || n > maxQuickSearchLimit {
+			return QuickSearchLimits{}, fmt.Errorf("limit must be between 0 and %d", maxQuickSearchLimit)
+		}
+		base = n
+	}
+
+	limits := map[string]int{
+		"documents": base,
+		"tags":      base,
+		"sessions":  base,
+		"messages":  base,
+	}
+
+	for name := range limits {
+		if v := query.Get(name); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 || n > maxQuickSearchLimit {
+				return QuickSearchLimits{}, fmt.Errorf("%s must be between 0 and %d", name, maxQuickSearchLimit)
+			}
+			limits[name] = n
+		}
+	}
+
+	if v := query.Get("types"); v != "" {
+		wanted := make(map[string]bool)
+		for _, t := range strings.Split(v, ",") {
+			t = strings.TrimSpace(t)
+			// Accept singular result type names as well ("document" or "documents")
+			if !strings.HasSuffix(t, "s") {
+				t += "s"
+			}
+			if _, ok := limits[t]; !ok {
+				return QuickSearchLimits{}, fmt.Errorf("unknown type: %s", t)
+			}
+			wanted[t] = true
+		}
+		for name := range limits {
+			if !wanted[name] {
+				limits[name] = 0
+			}
+		}
+	}
+
+	return QuickSearchLimits{
+		Documents: limits["documents"],
+		Tags:      limits["tags"],
+		Sessions:  limits["sessions"],
+		Messages:  limits["messages"],
+	}, nil
+}
+
+// handleQuickSearch handles GET /api/quicksearch?q=...
+// Backs the command palette with a fast lookup across document titles, tags,
+// session titles and recent messages, scoped to the current user
+func (s *Server) handleQuickSearch(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	requestID := newRequestID(w)
+
+	// Create logger with request context
+	logger := s.logger.WithContext("request_id", requestID).
+		WithContext("method", r.Method).
+		WithContext("path", r.URL.Path)
+
+	logger.Debug("processing quick search request")
+
+	ctx := r.Context()
+
+	// Extract user_id from context
+	userID, err := auth.GetUserID(ctx)
+	if err != nil {
+		logger.Error("request failed", "operation", "get_user_id", "error", err.Error())
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
+		return
+	}
+
+	q := strings.TrimSpace(r.URL.Query().Get("q"))
+	if len(q) > maxQuickSearchQuery {
+		writeError(w, http.StatusBadRequest, CodeBadRequest, fmt.Sprintf("Query too long (max %d characters)", maxQuickSearchQuery))
+		return
+	}
+
+	limits, err := parseQuickSearchLimits(r)
+	if err != nil {
+		logger.Error("request failed", "operation", "parse_limits", "error", err.Error())
+		writeError(w, http.StatusBadRequest, CodeBadRequest, "Invalid request: "+err.Error())
+		return
+	}
+
+	results, err := s.store.QuickSearch(ctx, userID, q, limits)
+	if err != nil {
+		logger.Error("request failed", "operation", "quick_search", "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Search failed")
+		return
+	}
+	if results == nil {
+		results = []QuickSearchResult{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"query":   q,
+		"results": results,
+	})
+
+	latency := time.Since(start).Milliseconds()
+	logger.Debug("request completed", "status", http.StatusOK, "latency_ms", latency, "results", len(results))
+}
@@ -0,0 +1,43 @@
+package api
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+// TestParseQuickSearchLimits tests per-type limit parsing for the quick search endpoint
+func TestParseQuickSearchLimits(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		want    QuickSearchLimits
+		wantErr bool
+	}{
+		{"defaults", "q=x", QuickSearchLimits{5, 5, 5, 5}, false},
+		{"global limit", "q=x&limit=3", QuickSearchLimits{3, 3, 3, 3}, false},
+		{"per-type override", "q=x&limit=3&messages=10", QuickSearchLimits{3, 3, 3, 10}, false},
+		{"types filter", "q=x&types=document,session", QuickSearchLimits{5, 0, 5, 0}, false},
+		{"limit too large", "q=x&limit=500", QuickSearchLimits{}, true},
+		{"negative per-type", "q=x&tags=-1", QuickSearchLimits{}, true},
+		{"unknown type", "q=x&types=users", QuickSearchLimits{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/quicksearch?"+tt.query, nil)
+			got, err := parseQuickSearchLimits(req)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected error, got %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
@@ -25,9 +25,6 @@ func TestRAGPolicyEnforcementFlow_CompleteIntegration(t *testing.T) {
 
 	// Configure both local and cloud providers
 	cfg := &config.Config{
-		Provider: config.ProviderConfig{
-			Type: "ollama",
-		},
 		LocalProvider: config.ProviderConfig{
 			Type:             "ollama",
 			OllamaEndpoint:   "http://localhost:11434",
@@ -0,0 +1,123 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"noodexx/internal/auth"
+	"time"
+)
+
+// maxRankingWeight caps tag and source multipliers so a single preference
+// cannot completely drown out vector similarity
+const maxRankingWeight = 10.0
+
+// handleGetRankingWeights handles GET /api/ranking-weights
+// Returns the current user's retrieval ranking modifiers
+func (s *Server) handleGetRankingWeights(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	requestID := newRequestID(w)
+
+	// Create logger with request context
+	logger := s.logger.WithContext("request_id", requestID).
+		WithContext("method", r.Method).
+		WithContext("path", r.URL.Path)
+
+	logger.Debug("processing get ranking weights request")
+
+	ctx := r.Context()
+
+	// Extract user_id from context
+	userID, err := auth.GetUserID(ctx)
+	if err != nil {
+		logger.Error("request failed", "operation", "get_user_id", "error", err.Error())
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
+		return
+	}
+
+	weights, err := s.store.GetRankingWeights(ctx, userID)
+	if err != nil {
+		logger.Error("request failed", "operation", "get_ranking_weights", "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to get ranking weights")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(weights)
+
+	latency := time.Since(start).Milliseconds()
+	logger.Debug("request completed", "status", http.StatusOK, "latency_ms", latency)
+}
+
+// handleSetRankingWeights handles POST /api/ranking-weights
+// Replaces the current user's recency half-life and tag/source weights
+func (s *Server) handleSetRankingWeights(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	requestID := newRequestID(w)
+
+	// Create logger with request context
+	logger := s.logger.WithContext("request_id", requestID).
+		WithContext("method", r.Method).
+		WithContext("path", r.URL.Path)
+
+	logger.Debug("processing set ranking weights request")
+
+	ctx := r.Context()
+
+	// Extract user_id from context
+	userID, err := auth.GetUserID(ctx)
+	if err != nil {
+		logger.Error("request failed", "operation", "get_user_id", "error", err.Error())
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req RankingWeights
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logger.Error("request failed", "operation", "parse_request", "error", err.Error())
+		writeError(w, http.StatusBadRequest, CodeBadRequest, "Invalid request")
+		return
+	}
+
+	if err := validateRankingWeights(req); err != nil {
+		logger.Error("request failed", "operation", "validate_weights", "error", err.Error())
+		writeError(w, http.StatusBadRequest, CodeBadRequest, err.Error())
+		return
+	}
+
+	if err := s.store.SaveRankingWeights(ctx, userID, req); err != nil {
+		logger.Error("request failed", "operation", "save_ranking_weights", "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to save ranking weights")
+		return
+	}
+
+	// Audit log
+	s.store.AddAuditEntry(ctx, "config", fmt.Sprintf("Updated ranking weights (%d tag, %d source)", len(req.TagWeights), len(req.SourceWeights)), "")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Ranking weights updated successfully",
+	})
+
+	latency := time.Since(start).Milliseconds()
+	logger.Debug("request completed", "status", http.StatusOK, "latency_ms", latency, "user_id", userID)
+}
+
+// validateRankingWeights rejects negative half-lives and out-of-range multipliers
+func validateRankingWeights(weights RankingWeights) error {
+	if weights.RecencyHalfLifeDays < 0 {
+		return fmt.Errorf("recency_half_life_days must not be negative")
+	}
+	for tag, weight := range weights.TagWeights {
+		if weight < 0 || weight > maxRankingWeight {
+			return fmt.Errorf("tag weight for %q must be between 0 and %g", tag, maxRankingWeight)
+		}
+	}
+	for source, weight := range weights.SourceWeights {
+		if weight < 0 || weight > maxRankingWeight {
+			return fmt.Errorf("source weight for %q must be between 0 and %g", source, maxRankingWeight)
+		}
+	}
+	return nil
+}
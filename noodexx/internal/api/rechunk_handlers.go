@@ -0,0 +1,265 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"noodexx/internal/auth"
+	"time"
+)
+
+// RechunkProgress is the state of a user's re-chunking job
+type RechunkProgress struct {
+	Running    bool             `json:"running"`
+	Chunker    string           `json:"chunker"` // Settings the documents are re-chunked with
+	Total      int              `json:"total"`
+	Done       int              `json:"done"`
+	Rechunked  int              `json:"rechunked"`
+	Failed     int              `json:"failed"`
+	Skipped    int              `json:"skipped"` // Documents whose content was not kept
+	Current    string           `json:"current,omitempty"`
+	Failures   []RechunkFailure `json:"failures,omitempty"`
+	StartedAt  time.Time        `json:"started_at"`
+	FinishedAt *time.Time       `json:"finished_at,omitempty"`
+}
+
+// RechunkFailure is a document that could not be re-chunked; it keeps its old chunks
+type RechunkFailure struct {
+	Source string `json:"source"`
+	Error  string `json:"error"`
+}
+
+// handleGetRechunk handles GET /api/library/rechunk - the user's documents with
+// the chunker settings they were chunked with, the current settings, and the
+// progress of the user's latest re-chunking job
+func (s *Server) handleGetRechunk(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	requestID := newRequestID(w)
+
+	logger := s.logger.WithContext("request_id", requestID).
+		WithContext("method", r.Method).
+		WithContext("path", r.URL.Path)
+
+	logger.Debug("processing get rechunk request")
+
+	ctx := r.Context()
+
+	userID, err := auth.GetUserID(ctx)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
+		return
+	}
+
+	if s.rechunker == nil {
+		writeError(w, http.StatusNotImplemented, CodeNotImplemented, "Re-chunking is not available")
+		return
+	}
+
+	documents, err := s.store.GetChunkedDocuments(ctx, userID)
+	if err != nil {
+		logger.Error("request failed", "operation", "get_chunked_documents", "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to get documents")
+		return
+	}
+	if documents == nil {
+		documents = []ChunkedDocument{}
+	}
+
+	chunker := s.rechunker.ChunkerSettings()
+	outdated := 0
+	for _, doc := range documents {
+		if doc.Stored && doc.Chunker != chunker {
+			outdated++
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":   true,
+		"chunker":   chunker,
+		"outdated":  outdated,
+		"documents": documents,
+		"progress":  s.rechunkProgress(userID),
+	})
+
+	latency := time.Since(start).Milliseconds()
+	logger.Debug("request completed", "status", http.StatusOK, "latency_ms", latency, "count", len(documents), "outdated", outdated)
+}
+
+// handleRechunk handles POST /api/library/rechunk - re-chunk and re-embed one
+// document, or every document chunked with other settings when no source is
+// given (all of them with force). The job runs in the background, one per
+// user, and reports progress over the WebSocket as rechunk_progress messages
+func (s *Server) handleRechunk(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	requestID := newRequestID(w)
+
+	logger := s.logger.WithContext("request_id", requestID).
+		WithContext("method", r.Method).
+		WithContext("path", r.URL.Path)
+
+	logger.Debug("processing rechunk request")
+
+	ctx := r.Context()
+
+	userID, err := auth.GetUserID(ctx)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
+		return
+	}
+
+	if s.rechunker == nil {
+		writeError(w, http.StatusNotImplemented, CodeNotImplemented, "Re-chunking is not available")
+		return
+	}
+
+	var req struct {
+		Source string `json:"source"` // Empty re-chunks the library
+		Force  bool   `json:"force"`  // Re-chunk documents already chunked with the current settings
+	}
+	if !decodeJSON(w, r, logger, &req) {
+		return
+	}
+
+	documents, err := s.store.GetChunkedDocuments(ctx, userID)
+	if err != nil {
+		logger.Error("request failed", "operation", "get_chunked_documents", "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to get documents")
+		return
+	}
+
+	chunker := s.rechunker.ChunkerSettings()
+	var sources []string
+	skipped := 0
+	found := false
+	for _, doc := range documents {
+		if req.Source != "" {
+			if doc.Source != req.Source {
+				continue
+			}
+			found = true
+			if !doc.Stored {
+				writeError(w, http.StatusUnprocessableEntity, CodeUnprocessable, "The document's content was not kept; ingest it again to re-chunk it")
+				return
+			}
+			sources = append(sources, doc.Source)
+			continue
+		}
+		if !req.Force && doc.Chunker == chunker {
+			continue
+		}
+		if !doc.Stored {
+			skipped++
+			continue
+		}
+		sources = append(sources, doc.Source)
+	}
+	if req.Source != "" && !found {
+		writeError(w, http.StatusNotFound, CodeNotFound, "Document not found")
+		return
+	}
+
+	progress := &RechunkProgress{
+		Running:   true,
+		Chunker:   chunker,
+		Total:     len(sources),
+		Skipped:   skipped,
+		StartedAt: time.Now(),
+	}
+	s.rechunkMu.Lock()
+	if current := s.rechunks[userID]; current != nil && current.Running {
+		s.rechunkMu.Unlock()
+		writeError(w, http.StatusConflict, CodeConflict, "Re-chunking is already running")
+		return
+	}
+	if s.rechunks == nil {
+		s.rechunks = make(map[int64]*RechunkProgress)
+	}
+	s.rechunks[userID] = progress
+	s.rechunkMu.Unlock()
+
+	go s.runRechunk(context.WithoutCancel(ctx), userID, sources, progress)
+
+	// Progress shows in GET /api/library/rechunk and over the WebSocket
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":  true,
+		"progress": s.rechunkProgress(userID),
+	})
+
+	latency := time.Since(start).Milliseconds()
+	logger.Debug("request completed", "status", http.StatusAccepted, "latency_ms", latency, "documents", len(sources), "skipped", skipped)
+}
+
+// runRechunk re-chunks the sources one at a time; a document that fails keeps
+// its old chunks and the job moves on to the next
+func (s *Server) runRechunk(ctx context.Context, userID int64, sources []string, progress *RechunkProgress) {
+	for _, source := range sources {
+		s.updateRechunk(userID, progress, func(p *RechunkProgress) { p.Current = source })
+
+		_, err := s.rechunker.Rechunk(ctx, userID, source)
+		if err != nil {
+			s.logger.Warn("re-chunking failed", "user_id", userID, "source", source, "error", err.Error())
+		}
+		s.updateRechunk(userID, progress, func(p *RechunkProgress) {
+			p.Done++
+			if err != nil {
+				p.Failed++
+				p.Failures = append(p.Failures, RechunkFailure{Source: source, Error: err.Error()})
+			} else {
+				p.Rechunked++
+			}
+		})
+	}
+
+	final := s.updateRechunk(userID, progress, func(p *RechunkProgress) {
+		finished := time.Now()
+		p.Running = false
+		p.Current = ""
+		p.FinishedAt = &finished
+	})
+
+	message := fmt.Sprintf("Re-chunked %d of %d documents", final.Rechunked, final.Total)
+	if final.Failed > 0 {
+		message += fmt.Sprintf(", %d failed", final.Failed)
+	}
+	if final.Skipped > 0 {
+		message += fmt.Sprintf(", %d skipped without kept content", final.Skipped)
+	}
+	s.NotifyUser(ctx, userID, "rechunk", message, map[string]interface{}{
+		"rechunked": final.Rechunked,
+		"failed":    final.Failed,
+		"skipped":   final.Skipped,
+	})
+}
+
+// updateRechunk changes a job's progress, pushes it to the user's clients and
+// returns a copy of it
+func (s *Server) updateRechunk(userID int64, progress *RechunkProgress, update func(p *RechunkProgress)) RechunkProgress {
+	s.rechunkMu.Lock()
+	update(progress)
+	snapshot := *progress
+	snapshot.Failures = append([]RechunkFailure(nil), progress.Failures...)
+	s.rechunkMu.Unlock()
+
+	s.pushToUser(userID, topicIngestion, map[string]interface{}{
+		"type":     "rechunk_progress",
+		"progress": snapshot,
+	})
+	return snapshot
+}
+
+// rechunkProgress returns a copy of the user's latest re-chunking job, nil if there was none
+func (s *Server) rechunkProgress(userID int64) *RechunkProgress {
+	s.rechunkMu.Lock()
+	defer s.rechunkMu.Unlock()
+	progress := s.rechunks[userID]
+	if progress == nil {
+		return nil
+	}
+	snapshot := *progress
+	snapshot.Failures = append([]RechunkFailure(nil), progress.Failures...)
+	return &snapshot
+}
@@ -0,0 +1,111 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// mockStoreForRechunk has a current document, two chunked with old settings
+// and an old one whose content was not kept
+type mockStoreForRechunk struct {
+	mockStore
+}
+
+func (m *mockStoreForRechunk) GetChunkedDocuments(ctx context.Context, userID int64) ([]ChunkedDocument, error) {
+	return []ChunkedDocument{
+		{Source: "current.md", Stored: true, Chunker: "size 800, overlap 80"},
+		{Source: "old.md", Stored: true, Chunker: "size 500, overlap 50"},
+		{Source: "broken.md", Stored: true, Chunker: "size 500, overlap 50"},
+		{Source: "legacy.md"},
+	}, nil
+}
+
+// mockRechunker fails documents whose source contains "broken" and waits for
+// release before each document
+type mockRechunker struct {
+	release chan struct{}
+
+	mu        sync.Mutex
+	rechunked []string
+}
+
+func (m *mockRechunker) ChunkerSettings() string {
+	return "size 800, overlap 80"
+}
+
+func (m *mockRechunker) Rechunk(ctx context.Context, userID int64, source string) (int, error) {
+	<-m.release
+	m.mu.Lock()
+	m.rechunked = append(m.rechunked, source)
+	m.mu.Unlock()
+	if strings.Contains(source, "broken") {
+		return 0, errors.New("provider unavailable")
+	}
+	return 3, nil
+}
+
+func TestHandleRechunk(t *testing.T) {
+	server := &Server{store: &mockStoreForRechunk{}, logger: &mockLogger{}}
+	rechunk := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/library/rechunk", strings.NewReader(body))
+		return serveRoute(server, withUser(req, 1))
+	}
+
+	if w := rechunk(`{}`); w.Code != http.StatusNotImplemented {
+		t.Errorf("Expected status 501 without a rechunker, got %d", w.Code)
+	}
+	rechunker := &mockRechunker{release: make(chan struct{})}
+	server.SetRechunker(rechunker)
+
+	w := serveRoute(server, withUser(httptest.NewRequest(http.MethodGet, "/api/library/rechunk", nil), 1))
+	var list struct {
+		Chunker   string            `json:"chunker"`
+		Outdated  int               `json:"outdated"`
+		Documents []ChunkedDocument `json:"documents"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if w.Code != http.StatusOK || list.Chunker != "size 800, overlap 80" || list.Outdated != 2 || len(list.Documents) != 4 {
+		t.Errorf("Expected 4 documents with 2 outdated, got %d %+v", w.Code, list)
+	}
+
+	if w := rechunk(`{"source":"legacy.md"}`); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected status 422 for a document without kept content, got %d", w.Code)
+	}
+	if w := rechunk(`{"source":"missing.md"}`); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for an unknown document, got %d", w.Code)
+	}
+
+	// Without a source only outdated documents are re-chunked, one job at a time
+	if w := rechunk(`{}`); w.Code != http.StatusAccepted {
+		t.Fatalf("Expected status 202, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := rechunk(`{}`); w.Code != http.StatusConflict {
+		t.Errorf("Expected status 409 while a job runs, got %d", w.Code)
+	}
+	close(rechunker.release)
+
+	deadline := time.Now().Add(2 * time.Second)
+	progress := server.rechunkProgress(1)
+	for progress.Running && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+		progress = server.rechunkProgress(1)
+	}
+	if progress.Running || progress.Total != 2 || progress.Rechunked != 1 || progress.Failed != 1 || progress.Skipped != 1 {
+		t.Errorf("Expected 1 re-chunked, 1 failed and 1 skipped, got %+v", progress)
+	}
+	if len(progress.Failures) != 1 || progress.Failures[0].Source != "broken.md" {
+		t.Errorf("Expected broken.md reported as failed, got %+v", progress.Failures)
+	}
+	if strings.Join(rechunker.rechunked, ",") != "old.md,broken.md" {
+		t.Errorf("Expected old.md and broken.md re-chunked, got %v", rechunker.rechunked)
+	}
+}
@@ -0,0 +1,144 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"noodexx/internal/validate"
+	"strconv"
+	"time"
+)
+
+// Failed request query limits
+const (
+	defaultFailedRequestLimit = 100
+	maxFailedRequestLimit     = 1000
+)
+
+// Replay modes
+const (
+	replayDryRun = "dry_run" // Resolve the provider and model without sending anything
+	replayLive   = "live"    // Send the request again
+)
+
+// handleFailedRequests handles GET /api/admin/replay - list the provider
+// requests that failed, newest first, with their redacted contents (admin only)
+// Supports limit
+func (s *Server) handleFailedRequests(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	requestID := newRequestID(w)
+
+	logger := s.logger.WithContext("request_id", requestID).
+		WithContext("method", r.Method).
+		WithContext("path", r.URL.Path)
+
+	logger.Debug("processing failed requests request")
+
+	if s.replays == nil {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success":  true,
+			"enabled":  false,
+			"requests": []FailedRequest{},
+		})
+		return
+	}
+
+	limit := defaultFailedRequestLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > maxFailedRequestLimit {
+			writeError(w, http.StatusBadRequest, CodeBadRequest, fmt.Sprintf("limit must be between 1 and %d", maxFailedRequestLimit))
+			return
+		}
+		limit = n
+	}
+
+	requests, err := s.replays.FailedRequests(r.Context(), limit)
+	if err != nil {
+		logger.Error("request failed", "operation", "get_failed_requests", "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to read failed requests")
+		return
+	}
+	if requests == nil {
+		requests = []FailedRequest{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":        true,
+		"enabled":        true,
+		"retention_days": s.replays.RetentionDays(),
+		"requests":       requests,
+	})
+
+	latency := time.Since(start).Milliseconds()
+	logger.Debug("request completed", "status", http.StatusOK, "latency_ms", latency, "count", len(requests))
+}
+
+// handleReplay handles POST /api/admin/replay/{id} - run a failed provider
+// request again against the current provider configuration, and compare the
+// result with the original error (admin only)
+// Request body: {"mode": "dry_run"} resolves the provider and model without
+// sending anything; {"mode": "live"} sends the request
+func (s *Server) handleReplay(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	requestID := newRequestID(w)
+
+	logger := s.logger.WithContext("request_id", requestID).
+		WithContext("method", r.Method).
+		WithContext("path", r.URL.Path)
+
+	logger.Debug("processing replay request")
+
+	ctx := r.Context()
+
+	if s.replays == nil {
+		writeError(w, http.StatusNotImplemented, CodeNotImplemented, "Failed request replay is not enabled")
+		return
+	}
+
+	id, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeBadRequest, "Invalid request ID")
+		return
+	}
+
+	var req struct {
+		Mode string `json:"mode"`
+	}
+	if !decodeJSON(w, r, logger, &req) {
+		return
+	}
+	v := validate.New()
+	v.Check("mode", validate.OneOf("Mode", req.Mode, replayDryRun, replayLive))
+	if err := v.Err(); err != nil {
+		writeValidationError(w, logger, err)
+		return
+	}
+	live := req.Mode == replayLive
+
+	result, err := s.replays.Replay(ctx, id, live)
+	if err != nil {
+		logger.Error("request failed", "operation", "replay", "failed_request_id", id, "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to replay request")
+		return
+	}
+	if result == nil {
+		writeError(w, http.StatusNotFound, CodeNotFound, "Failed request not found")
+		return
+	}
+	if live {
+		s.store.AddAuditEntry(ctx, "replay", fmt.Sprintf("Replayed failed %s request %d to %s: %s",
+			result.Request.Operation, id, result.Provider, result.Outcome), "")
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"replay":  result,
+	})
+
+	latency := time.Since(start).Milliseconds()
+	logger.Debug("request completed", "status", http.StatusOK, "latency_ms", latency, "mode", req.Mode, "outcome", result.Outcome)
+}
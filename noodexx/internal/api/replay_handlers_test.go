@@ -0,0 +1,112 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// mockReplayLog keeps one failed request and replays it with a fixed outcome
+type mockReplayLog struct {
+	request FailedRequest
+	live    []bool
+}
+
+func (m *mockReplayLog) FailedRequests(ctx context.Context, limit int) ([]FailedRequest, error) {
+	return []FailedRequest{m.request}, nil
+}
+
+func (m *mockReplayLog) Replay(ctx context.Context, id int64, live bool) (*ReplayResult, error) {
+	if id != m.request.ID {
+		return nil, nil
+	}
+	m.live = append(m.live, live)
+	result := &ReplayResult{Request: m.request, Mode: "dry_run", Provider: "openai", Model: "gpt-4o", OriginalError: m.request.Error, Outcome: "dry_run"}
+	if live {
+		result.Mode, result.Outcome, result.Response = "live", "succeeded", "Hello"
+		result.Diff = []string{"- " + m.request.Error, "+ Hello"}
+	}
+	return result, nil
+}
+
+func (m *mockReplayLog) RetentionDays() int { return 14 }
+
+// mockStoreForReplay records audit entries
+type mockStoreForReplay struct {
+	mockStoreForAdmin
+	audit []string
+}
+
+func (m *mockStoreForReplay) AddAuditEntry(ctx context.Context, opType, details, userCtx string) error {
+	m.audit = append(m.audit, opType+": "+details)
+	return nil
+}
+
+func TestReplayHandlers(t *testing.T) {
+	replays := &mockReplayLog{request: FailedRequest{ID: 3, Provider: "openai", Operation: "chat", Error: "429 rate limited"}}
+	store := &mockStoreForReplay{}
+	server := &Server{store: store, logger: &mockLogger{}}
+
+	request := func(method, path, body string, userID int64) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		return serveRoute(server, withUser(req, userID))
+	}
+
+	// Disabled: the list is empty and replays are refused
+	if w := request(http.MethodGet, "/api/admin/replay", "", 1); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"enabled":false`) {
+		t.Errorf("Expected an empty list when disabled, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := request(http.MethodPost, "/api/admin/replay/3", `{"mode":"live"}`, 1); w.Code != http.StatusNotImplemented {
+		t.Errorf("Expected status 501 when disabled, got %d", w.Code)
+	}
+
+	server.SetReplays(replays)
+	w := request(http.MethodGet, "/api/admin/replay", "", 1)
+	var list struct {
+		Requests      []FailedRequest `json:"requests"`
+		RetentionDays int             `json:"retention_days"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(list.Requests) != 1 || list.Requests[0].Error != "429 rate limited" || list.RetentionDays != 14 {
+		t.Errorf("Expected the failed request listed, got %+v", list)
+	}
+
+	w = request(http.MethodPost, "/api/admin/replay/3", `{"mode":"live"}`, 1)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Replay ReplayResult `json:"replay"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if resp.Replay.Outcome != "succeeded" || resp.Replay.OriginalError != "429 rate limited" || len(resp.Replay.Diff) != 2 {
+		t.Errorf("Expected the live replay compared with the original error, got %+v", resp.Replay)
+	}
+
+	if w := request(http.MethodPost, "/api/admin/replay/3", `{"mode":"dry_run"}`, 1); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"outcome":"dry_run"`) {
+		t.Errorf("Expected a dry run, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := request(http.MethodPost, "/api/admin/replay/3", `{"mode":"again"}`, 1); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an unknown mode, got %d", w.Code)
+	}
+	if w := request(http.MethodPost, "/api/admin/replay/9", `{"mode":"live"}`, 1); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for an unknown request, got %d", w.Code)
+	}
+	if w := request(http.MethodPost, "/api/admin/replay/3", `{"mode":"live"}`, 2); w.Code != http.StatusForbidden {
+		t.Errorf("Expected status 403 for a non-admin, got %d", w.Code)
+	}
+	if len(replays.live) != 2 || !replays.live[0] || replays.live[1] {
+		t.Errorf("Expected a live replay and a dry run, got %v", replays.live)
+	}
+	// Only live replays send anything, and only they are audited
+	if len(store.audit) != 1 || !strings.HasPrefix(store.audit[0], "replay: ") {
+		t.Errorf("Expected the live replay audited, got %v", store.audit)
+	}
+}
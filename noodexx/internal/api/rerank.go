@@ -0,0 +1,88 @@
+package api
+
+import (
+	"context"
+	"noodexx/internal/flags"
+	"noodexx/internal/rag"
+	"sort"
+)
+
+// Library search sizes: the number of chunks added to a prompt, and the
+// number of candidates fetched for the reranker to choose from
+const (
+	librarySearchTopK = 5
+	rerankCandidates  = 20
+)
+
+// activeReranker returns the reranker for the request's user, nil when reranking
+// is unavailable or the rerank feature flag is off for them
+func (s *Server) activeReranker(ctx context.Context) Reranker {
+	if s.reranker == nil || !s.featureEnabled(ctx, flags.Rerank) {
+		return nil
+	}
+	return s.reranker
+}
+
+// libraryCandidates returns how many chunks to fetch from the library for a question
+func (s *Server) libraryCandidates(ctx context.Context) int {
+	if s.activeReranker(ctx) != nil || s.mmrLambda > 0 {
+		return rerankCandidates
+	}
+	return librarySearchTopK
+}
+
+// rerankChunks orders chunks by reranker score and keeps the best topK
+// If reranking is unavailable or fails, the search order is kept
+func (s *Server) rerankChunks(ctx context.Context, logger Logger, query string, chunks []Chunk, topK int) []Chunk {
+	if reranker := s.activeReranker(ctx); reranker != nil && len(chunks) > 1 {
+		documents := make([]string, len(chunks))
+		for i, chunk := range chunks {
+			documents[i] = chunk.Text
+		}
+
+		scores, err := reranker.Rerank(ctx, query, documents)
+		switch {
+		case err != nil:
+			logger.Warn("rerank failed, using search order", "error", err.Error())
+		case len(scores) == len(chunks):
+			reranked := make([]Chunk, len(chunks))
+			copy(reranked, chunks)
+			for i := range reranked {
+				reranked[i].Score = scores[i]
+			}
+			sort.SliceStable(reranked, func(i, j int) bool {
+				return reranked[i].Score > reranked[j].Score
+			})
+			chunks = reranked
+			logger.Debug("reranked library results", "candidates", len(documents))
+		}
+	}
+
+	if len(chunks) > topK {
+		chunks = chunks[:topK]
+	}
+	return chunks
+}
+
+// selectLibraryChunks picks the topK library results to add to a prompt: the
+// reranker's order when it is available, spread across passages by Maximal
+// Marginal Relevance when diversity is enabled
+func (s *Server) selectLibraryChunks(ctx context.Context, logger Logger, query string, chunks []Chunk, topK int) []Chunk {
+	if s.mmrLambda <= 0 {
+		return s.rerankChunks(ctx, logger, query, chunks, topK)
+	}
+
+	chunks = s.rerankChunks(ctx, logger, query, chunks, len(chunks))
+	items := make([]rag.MMRItem, len(chunks))
+	for i, c := range chunks {
+		items[i] = rag.MMRItem{Source: c.Source, Embedding: c.Embedding, Relevance: c.Score}
+	}
+
+	selected := rag.SelectMMR(items, s.mmrLambda, topK)
+	diverse := make([]Chunk, len(selected))
+	for i, idx := range selected {
+		diverse[i] = chunks[idx]
+	}
+	logger.Debug("diversified library results", "candidates", len(chunks), "selected", len(diverse))
+	return diverse
+}
@@ -0,0 +1,55 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// mockReranker scores documents from a fixed map
+type mockReranker struct {
+	scores map[string]float64
+	err    error
+}
+
+func (m *mockReranker) Rerank(ctx context.Context, query string, documents []string) ([]float64, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	scores := make([]float64, len(documents))
+	for i, doc := range documents {
+		scores[i] = m.scores[doc]
+	}
+	return scores, nil
+}
+
+// TestRerankChunks tests that library results are reordered and trimmed
+func TestRerankChunks(t *testing.T) {
+	chunks := []Chunk{{Text: "a", Score: 0.9}, {Text: "b", Score: 0.8}, {Text: "c", Score: 0.7}}
+
+	server := &Server{logger: &mockLogger{}}
+	if server.libraryCandidates(context.Background()) != librarySearchTopK {
+		t.Errorf("Expected %d candidates without a reranker", librarySearchTopK)
+	}
+	if got := server.rerankChunks(context.Background(), server.logger, "q", chunks, 2); len(got) != 2 || got[0].Text != "a" {
+		t.Errorf("Expected search order without a reranker, got %+v", got)
+	}
+
+	server.SetReranker(&mockReranker{scores: map[string]float64{"a": 0.1, "b": 0.3, "c": 0.9}})
+	if server.libraryCandidates(context.Background()) != rerankCandidates {
+		t.Errorf("Expected %d candidates with a reranker", rerankCandidates)
+	}
+	got := server.rerankChunks(context.Background(), server.logger, "q", chunks, 2)
+	if len(got) != 2 || got[0].Text != "c" || got[1].Text != "b" || got[0].Score != 0.9 {
+		t.Errorf("Expected reranked [c b], got %+v", got)
+	}
+	if chunks[0].Text != "a" {
+		t.Error("Expected input chunks to be left unchanged")
+	}
+
+	// A failing reranker falls back to search order
+	server.SetReranker(&mockReranker{err: errors.New("boom")})
+	if got := server.rerankChunks(context.Background(), server.logger, "q", chunks, 2); got[0].Text != "a" {
+		t.Errorf("Expected search order after rerank failure, got %+v", got)
+	}
+}
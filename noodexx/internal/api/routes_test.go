@@ -0,0 +1,130 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"noodexx/internal/auth"
+	"strings"
+	"testing"
+	"time"
+)
+
+// serveRoute serves req through the registered routes, so path values, method
+// patterns and route middleware apply as they do in production
+func serveRoute(s *Server, req *http.Request) *httptest.ResponseRecorder {
+	mux := http.NewServeMux()
+	s.RegisterRoutes(mux)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	return w
+}
+
+// withUser returns req carrying userID as the signed-in user
+func withUser(req *http.Request, userID int64) *http.Request {
+	return req.WithContext(context.WithValue(req.Context(), auth.UserIDKey, userID))
+}
+
+// TestRoutesMethodNotAllowed tests that a wrong method gets a JSON 405 listing the allowed ones
+func TestRoutesMethodNotAllowed(t *testing.T) {
+	server := &Server{store: &mockStore{}, logger: &mockLogger{}}
+
+	w := serveRoute(server, withUser(httptest.NewRequest(http.MethodPut, "/api/session/s1/shares", nil), 1))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("Expected status 405, got %d", w.Code)
+	}
+	if allow := w.Header().Get("Allow"); allow != "GET, HEAD, POST" {
+		t.Errorf("Expected Allow header \"GET, HEAD, POST\", got %q", allow)
+	}
+	if resp := decodeErrorResponse(t, w); resp.Code != CodeMethodNotAllowed {
+		t.Errorf("Expected code %q, got %q", CodeMethodNotAllowed, resp.Code)
+	}
+}
+
+// TestRoutesRequireUser tests that user routes reject requests without a signed-in user
+func TestRoutesRequireUser(t *testing.T) {
+	server := &Server{store: &mockStore{}, logger: &mockLogger{}}
+
+	w := serveRoute(server, httptest.NewRequest(http.MethodGet, "/api/sessions", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401, got %d", w.Code)
+	}
+}
+
+// TestSameOrigin tests the cross-site request check
+func TestSameOrigin(t *testing.T) {
+	handler := sameOrigin(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	tests := []struct {
+		name     string
+		method   string
+		headers  map[string]string
+		expected int
+	}{
+		{"no origin", http.MethodPost, nil, http.StatusNoContent},
+		{"same origin", http.MethodPost, map[string]string{"Origin": "http://example.com"}, http.StatusNoContent},
+		{"same-site referer", http.MethodDelete, map[string]string{"Referer": "http://example.com/chat"}, http.StatusNoContent},
+		{"cross-site origin", http.MethodPost, map[string]string{"Origin": "http://evil.test"}, http.StatusForbidden},
+		{"cross-site referer", http.MethodPost, map[string]string{"Referer": "http://evil.test/page"}, http.StatusForbidden},
+		{"null origin", http.MethodPost, map[string]string{"Origin": "null"}, http.StatusForbidden},
+		{"bearer token", http.MethodPost, map[string]string{"Origin": "http://evil.test", "Authorization": "Bearer abc"}, http.StatusNoContent},
+		{"safe method", http.MethodGet, map[string]string{"Origin": "http://evil.test"}, http.StatusNoContent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "http://example.com/api/ask", nil)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			if w.Code != tt.expected {
+				t.Errorf("Expected status %d, got %d", tt.expected, w.Code)
+			}
+		})
+	}
+}
+
+// TestRateLimiter tests the fixed-window limiter and its middleware
+func TestRateLimiter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	limiter := newRateLimiter(2, time.Minute)
+	limiter.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		if ok, _ := limiter.allow("a"); !ok {
+			t.Fatalf("Expected request %d to be allowed", i+1)
+		}
+	}
+	ok, retry := limiter.allow("a")
+	if ok || retry != time.Minute {
+		t.Errorf("Expected the third request to wait a minute, got ok=%v retry=%v", ok, retry)
+	}
+	if ok, _ := limiter.allow("b"); !ok {
+		t.Error("Expected another client to have its own limit")
+	}
+
+	now = now.Add(time.Minute)
+	if ok, _ := limiter.allow("a"); !ok {
+		t.Error("Expected the limit to reset after the window")
+	}
+
+	handler := rateLimit(limiter)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	var w *httptest.ResponseRecorder
+	for i := 0; i < 3; i++ {
+		w = httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/auth/login", strings.NewReader("")))
+	}
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected status 429, got %d", w.Code)
+	}
+	if w.Header().Get("Retry-After") == "" {
+		t.Error("Expected a Retry-After header")
+	}
+	if resp := decodeErrorResponse(t, w); resp.Code != CodeRateLimited {
+		t.Errorf("Expected code %q, got %q", CodeRateLimited, resp.Code)
+	}
+}
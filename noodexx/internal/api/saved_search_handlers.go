@@ -0,0 +1,336 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"noodexx/internal/auth"
+	"noodexx/internal/validate"
+	"strings"
+	"time"
+)
+
+// Saved search limits
+const (
+	maxSavedSearches        = 100 // Per user
+	maxSavedSearchName      = 100
+	savedSearchRefreshBatch = 50 // Stale queries embedded per RefreshSavedSearches run
+)
+
+// savedSearchRequest is the body of POST /api/saved-searches
+type savedSearchRequest struct {
+	Name  string `json:"name"`
+	Query string `json:"query"`
+	Mode  string `json:"mode"`
+}
+
+// handleGetSavedSearches handles GET /api/saved-searches - the user's saved
+// searches, each marked stale when its query still has to be embedded with
+// the active embedding model
+func (s *Server) handleGetSavedSearches(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	requestID := newRequestID(w)
+
+	logger := s.logger.WithContext("request_id", requestID).
+		WithContext("method", r.Method).
+		WithContext("path", r.URL.Path)
+
+	logger.Debug("processing get saved searches request")
+
+	ctx := r.Context()
+
+	userID, err := auth.GetUserID(ctx)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
+		return
+	}
+
+	searches, err := s.store.GetSavedSearches(ctx, userID)
+	if err != nil {
+		logger.Error("request failed", "operation", "get_saved_searches", "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to get saved searches")
+		return
+	}
+	model := s.activeEmbedModel()
+	for i := range searches {
+		searches[i].Stale = savedSearchStale(&searches[i], model)
+	}
+	if searches == nil {
+		searches = []SavedSearch{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":         true,
+		"embedding_model": model,
+		"searches":        searches,
+	})
+
+	latency := time.Since(start).Milliseconds()
+	logger.Debug("request completed", "status", http.StatusOK, "latency_ms", latency, "count", len(searches))
+}
+
+// handleCreateSavedSearch handles POST /api/saved-searches - save a library
+// search. Its query is embedded right away when the provider is available, and
+// later by the saved_search_embeddings job or the first run otherwise
+func (s *Server) handleCreateSavedSearch(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	requestID := newRequestID(w)
+
+	logger := s.logger.WithContext("request_id", requestID).
+		WithContext("method", r.Method).
+		WithContext("path", r.URL.Path)
+
+	logger.Debug("processing create saved search request")
+
+	ctx := r.Context()
+
+	userID, err := auth.GetUserID(ctx)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req savedSearchRequest
+	if !decodeJSON(w, r, logger, &req) {
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	req.Query = strings.TrimSpace(req.Query)
+
+	v := validate.New()
+	v.Required("name", "Name", req.Name)
+	if len(req.Name) > maxSavedSearchName {
+		v.Check("name", fmt.Errorf("Name must be at most %d characters", maxSavedSearchName))
+	}
+	v.Required("query", "Query", req.Query)
+	if len(req.Query) > maxSearchQuery {
+		v.Check("query", fmt.Errorf("Query must be at most %d characters", maxSearchQuery))
+	}
+	if req.Mode != "" {
+		v.Check("mode", validate.OneOf("Mode", req.Mode, searchModes...))
+	}
+	if err := v.Err(); err != nil {
+		writeValidationError(w, logger, err)
+		return
+	}
+
+	searches, err := s.store.GetSavedSearches(ctx, userID)
+	if err != nil {
+		logger.Error("request failed", "operation", "get_saved_searches", "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to get saved searches")
+		return
+	}
+	if len(searches) >= maxSavedSearches {
+		writeError(w, http.StatusConflict, CodeConflict, fmt.Sprintf("You can save at most %d searches", maxSavedSearches))
+		return
+	}
+
+	id, err := s.store.CreateSavedSearch(ctx, userID, req.Name, req.Query, req.Mode)
+	if err != nil {
+		logger.Error("request failed", "operation", "create_saved_search", "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to save search")
+		return
+	}
+
+	// Embedding now spares the first run the wait; a failure leaves it stale
+	search := &SavedSearch{ID: id, UserID: userID, Name: req.Name, Query: req.Query, Mode: req.Mode, CreatedAt: time.Now().UTC()}
+	if err := s.embedSavedSearch(ctx, search); err != nil {
+		logger.Warn("failed to embed saved search", "search_id", id, "error", err.Error())
+	}
+	search.Stale = savedSearchStale(search, s.activeEmbedModel())
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"search":  search,
+	})
+
+	latency := time.Since(start).Milliseconds()
+	logger.Debug("request completed", "status", http.StatusCreated, "latency_ms", latency, "search_id", id)
+}
+
+// handleDeleteSavedSearch handles DELETE /api/saved-searches/{id}
+func (s *Server) handleDeleteSavedSearch(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	requestID := newRequestID(w)
+
+	logger := s.logger.WithContext("request_id", requestID).
+		WithContext("method", r.Method).
+		WithContext("path", r.URL.Path)
+
+	logger.Debug("processing delete saved search request")
+
+	ctx := r.Context()
+
+	userID, err := auth.GetUserID(ctx)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
+		return
+	}
+
+	id, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeBadRequest, "Invalid search ID")
+		return
+	}
+
+	deleted, err := s.store.DeleteSavedSearch(ctx, userID, id)
+	if err != nil {
+		logger.Error("request failed", "operation", "delete_saved_search", "search_id", id, "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to delete saved search")
+		return
+	}
+	if !deleted {
+		writeError(w, http.StatusNotFound, CodeNotFound, "Saved search not found")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+	})
+
+	latency := time.Since(start).Milliseconds()
+	logger.Debug("request completed", "status", http.StatusOK, "latency_ms", latency, "search_id", id)
+}
+
+// handleRunSavedSearch handles GET /api/saved-searches/{id}/results?limit=... -
+// run a saved search like GET /api/search. The precomputed query embedding is
+// used while it matches the active embedding model; a stale one is replaced
+// first, and embedding says which happened
+func (s *Server) handleRunSavedSearch(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	requestID := newRequestID(w)
+
+	logger := s.logger.WithContext("request_id", requestID).
+		WithContext("method", r.Method).
+		WithContext("path", r.URL.Path)
+
+	logger.Debug("processing run saved search request")
+
+	ctx := r.Context()
+
+	userID, err := auth.GetUserID(ctx)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
+		return
+	}
+
+	id, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeBadRequest, "Invalid search ID")
+		return
+	}
+	limit := defaultSearchLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		if limit, err = validate.IntRange("limit", v, 1, maxSearchLimit); err != nil {
+			writeError(w, http.StatusBadRequest, CodeBadRequest, err.Error())
+			return
+		}
+	}
+
+	search, err := s.store.GetSavedSearch(ctx, userID, id)
+	if err != nil {
+		logger.Error("request failed", "operation", "get_saved_search", "search_id", id, "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to get saved search")
+		return
+	}
+	if search == nil {
+		writeError(w, http.StatusNotFound, CodeNotFound, "Saved search not found")
+		return
+	}
+
+	provider, err := s.providerManager.GetActiveProvider()
+	if err != nil {
+		logger.Error("request failed", "operation", "get_active_provider", "error", err.Error())
+		writeError(w, http.StatusBadRequest, CodeProviderUnavailable, "Provider not configured. Please configure the AI provider in Settings.")
+		return
+	}
+
+	model := s.activeEmbedModel()
+	embedding := "cached"
+	embedStart := time.Now()
+	if savedSearchStale(search, model) {
+		if err := s.embedSavedSearch(ctx, search); err != nil {
+			logger.Error("request failed", "operation", "embed_query", "search_id", id, "error", err.Error())
+			writeError(w, http.StatusInternalServerError, CodeInternal, "Embedding failed")
+			return
+		}
+		embedding = "refreshed"
+	}
+	embedTime := time.Since(embedStart)
+
+	chunks, err := s.store.SearchLibrary(ctx, userID, search.Embedding, SearchOptions{Query: search.Query, QueryModel: model, Mode: search.Mode}, limit)
+	if err != nil {
+		logger.Error("request failed", "operation", "search_chunks", "search_id", id, "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Search failed")
+		return
+	}
+
+	semantic := s.ragEnforcer.ShouldPerformRAG()
+	results := make([]SearchResult, len(chunks))
+	for i, chunk := range chunks {
+		results[i] = highlightResult(ctx, provider, chunk, search.Query, search.Embedding, semantic)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":   true,
+		"search":    search,
+		"embedding": embedding,
+		"embed_ms":  embedTime.Milliseconds(),
+		"results":   results,
+	})
+
+	latency := time.Since(start).Milliseconds()
+	logger.Debug("request completed", "status", http.StatusOK, "latency_ms", latency, "search_id", id, "embedding", embedding, "results", len(results))
+}
+
+// RefreshSavedSearches embeds the queries of saved searches that were never
+// embedded or were embedded with another model than the active one, a batch
+// at a time, so saved searches run without waiting for the provider. It
+// returns how many were embedded
+func (s *Server) RefreshSavedSearches(ctx context.Context) (int, error) {
+	stale, err := s.store.GetStaleSavedSearches(ctx, s.activeEmbedModel(), savedSearchRefreshBatch)
+	if err != nil {
+		return 0, err
+	}
+	refreshed := 0
+	for i := range stale {
+		if err := s.embedSavedSearch(ctx, &stale[i]); err != nil {
+			return refreshed, fmt.Errorf("failed to embed saved search %d: %w", stale[i].ID, err)
+		}
+		refreshed++
+	}
+	return refreshed, nil
+}
+
+// embedSavedSearch embeds a saved search's query with the active provider and
+// stores the embedding with the active embedding model
+func (s *Server) embedSavedSearch(ctx context.Context, search *SavedSearch) error {
+	provider, err := s.providerManager.GetActiveProvider()
+	if err != nil {
+		return fmt.Errorf("failed to get active provider: %w", err)
+	}
+	model := s.activeEmbedModel()
+	vec, err := provider.Embed(ctx, search.Query)
+	if err != nil {
+		return err
+	}
+	if err := s.store.SetSavedSearchEmbedding(ctx, search.ID, vec, model); err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	search.Embedding, search.EmbeddingModel, search.EmbeddedAt = vec, model, &now
+	search.Stale = false
+	return nil
+}
+
+// savedSearchStale reports whether a saved search's query has to be embedded
+// again before it runs with the given embedding model
+func savedSearchStale(search *SavedSearch, model string) bool {
+	return search.EmbeddedAt == nil || search.EmbeddingModel != model
+}
@@ -0,0 +1,140 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// savedSearchStore keeps saved searches in memory and records the query
+// vector of the last library search
+type savedSearchStore struct {
+	mockStoreForAsk
+	searches   []SavedSearch
+	searchedBy []float32
+}
+
+func (m *savedSearchStore) CreateSavedSearch(ctx context.Context, userID int64, name, query, mode string) (int64, error) {
+	id := int64(len(m.searches) + 1)
+	m.searches = append(m.searches, SavedSearch{ID: id, UserID: userID, Name: name, Query: query, Mode: mode})
+	return id, nil
+}
+
+func (m *savedSearchStore) GetSavedSearches(ctx context.Context, userID int64) ([]SavedSearch, error) {
+	var searches []SavedSearch
+	for _, search := range m.searches {
+		if search.UserID == userID {
+			searches = append(searches, search)
+		}
+	}
+	return searches, nil
+}
+
+func (m *savedSearchStore) GetSavedSearch(ctx context.Context, userID, id int64) (*SavedSearch, error) {
+	for _, search := range m.searches {
+		if search.ID == id && search.UserID == userID {
+			return &search, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *savedSearchStore) GetStaleSavedSearches(ctx context.Context, model string, limit int) ([]SavedSearch, error) {
+	var stale []SavedSearch
+	for _, search := range m.searches {
+		if search.EmbeddedAt == nil || search.EmbeddingModel != model {
+			stale = append(stale, search)
+		}
+	}
+	return stale, nil
+}
+
+func (m *savedSearchStore) SetSavedSearchEmbedding(ctx context.Context, id int64, embedding []float32, model string) error {
+	now := time.Now()
+	search := &m.searches[id-1]
+	search.Embedding, search.EmbeddingModel, search.EmbeddedAt = embedding, model, &now
+	return nil
+}
+
+func (m *savedSearchStore) SearchLibrary(ctx context.Context, userID int64, queryVec []float32, opts SearchOptions, topK int) ([]Chunk, error) {
+	m.searchedBy = queryVec
+	return []Chunk{{ID: 2, Source: "errors.md", Text: "Error E4012 means the fuser is overheating", Score: 0.8}}, nil
+}
+
+func TestSavedSearches(t *testing.T) {
+	store := &savedSearchStore{}
+	embeds := 0
+	provider := &mockProviderForAsk{name: "ollama", isLocal: true, embedFunc: func(ctx context.Context, text string) ([]float32, error) {
+		embeds++
+		return []float32{float32(embeds), 0}, nil
+	}}
+	manager := &embedModelProviderManager{mockProviderManagerForAsk: mockProviderManagerForAsk{provider: provider}, embedModel: "nomic-embed-text"}
+	server := &Server{
+		store:           store,
+		logger:          &mockLoggerForAsk{},
+		providerManager: manager,
+		ragEnforcer:     &mockRAGEnforcerForAsk{shouldPerformRAG: true, ragStatus: "RAG Enabled"},
+	}
+	request := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		return serveRoute(server, withUser(req, 1))
+	}
+	run := func() (embedding string) {
+		t.Helper()
+		w := request(http.MethodGet, "/api/saved-searches/1/results", "")
+		var resp struct {
+			Embedding string         `json:"embedding"`
+			Results   []SearchResult `json:"results"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || w.Code != http.StatusOK || len(resp.Results) != 1 {
+			t.Fatalf("Expected the search results, got %d: %s", w.Code, w.Body.String())
+		}
+		return resp.Embedding
+	}
+
+	w := request(http.MethodPost, "/api/saved-searches", `{"name":"Fuser errors","query":"E4012 overheating","mode":"hybrid"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+	if embeds != 1 || store.searches[0].EmbeddingModel != "nomic-embed-text" {
+		t.Fatalf("Expected the query embedded when saved, got %d embeddings: %+v", embeds, store.searches[0])
+	}
+
+	// Running uses the stored embedding
+	if embedding := run(); embedding != "cached" || embeds != 1 || store.searchedBy[0] != 1 {
+		t.Errorf("Expected the cached embedding, got %s after %d embeddings", embedding, embeds)
+	}
+
+	// Another embedding model makes the search stale until it is refreshed
+	manager.embedModel = "mxbai-embed-large"
+	w = request(http.MethodGet, "/api/saved-searches", "")
+	var list struct {
+		Searches []SavedSearch `json:"searches"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil || len(list.Searches) != 1 || !list.Searches[0].Stale {
+		t.Errorf("Expected the search marked stale, got %s", w.Body.String())
+	}
+	if refreshed, err := server.RefreshSavedSearches(context.Background()); err != nil || refreshed != 1 {
+		t.Errorf("Expected 1 search refreshed, got %d, %v", refreshed, err)
+	}
+	if embedding := run(); embedding != "cached" || store.searchedBy[0] != 2 {
+		t.Errorf("Expected the refreshed embedding used, got %s with %v", embedding, store.searchedBy)
+	}
+
+	// A stale search is embedded again when it runs
+	manager.embedModel = "nomic-embed-text"
+	if embedding := run(); embedding != "refreshed" || embeds != 3 || store.searchedBy[0] != 3 {
+		t.Errorf("Expected the query embedded again, got %s after %d embeddings", embedding, embeds)
+	}
+
+	if w := request(http.MethodPost, "/api/saved-searches", `{"name":"Bad","query":"x","mode":"fuzzy"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an unknown mode, got %d", w.Code)
+	}
+	if w := request(http.MethodGet, "/api/saved-searches/9/results", ""); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for an unknown search, got %d", w.Code)
+	}
+}
@@ -0,0 +1,123 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"noodexx/internal/validate"
+	"time"
+)
+
+// Search dictionary limits
+const (
+	maxDictionaryEntries    = 5000 // Stop words, protected terms and synonyms together
+	maxDictionaryTermLength = 100
+)
+
+// SearchDictionary shapes keyword queries: stop words are left out, protected
+// terms are matched as exact phrases and never left out, and a term with
+// synonyms also matches any of them
+type SearchDictionary struct {
+	StopWords []string            `json:"stop_words"`
+	Protected []string            `json:"protected_terms"`
+	Synonyms  map[string][]string `json:"synonyms"`
+}
+
+// handleGetSearchDictionary handles GET /api/admin/search-dictionary - the
+// stop words, protected terms and synonyms of keyword search (admin only)
+func (s *Server) handleGetSearchDictionary(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	requestID := newRequestID(w)
+
+	logger := s.logger.WithContext("request_id", requestID).
+		WithContext("method", r.Method).
+		WithContext("path", r.URL.Path)
+
+	logger.Debug("processing get search dictionary request")
+
+	dict, err := s.store.GetSearchDictionary(r.Context())
+	if err != nil {
+		logger.Error("request failed", "operation", "get_search_dictionary", "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to get search dictionary")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":    true,
+		"dictionary": dict,
+	})
+
+	latency := time.Since(start).Milliseconds()
+	logger.Debug("request completed", "status", http.StatusOK, "latency_ms", latency)
+}
+
+// handleSetSearchDictionary handles PUT /api/admin/search-dictionary - replace
+// the keyword search dictionary (admin only)
+func (s *Server) handleSetSearchDictionary(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	requestID := newRequestID(w)
+
+	logger := s.logger.WithContext("request_id", requestID).
+		WithContext("method", r.Method).
+		WithContext("path", r.URL.Path)
+
+	logger.Debug("processing set search dictionary request")
+
+	ctx := r.Context()
+
+	var dict SearchDictionary
+	if !decodeJSON(w, r, logger, &dict) {
+		return
+	}
+
+	v := validate.New()
+	entries := len(dict.StopWords) + len(dict.Protected)
+	for _, synonyms := range dict.Synonyms {
+		entries += len(synonyms)
+	}
+	if entries > maxDictionaryEntries {
+		v.Check("dictionary", fmt.Errorf("Dictionary has %d entries, at most %d are allowed", entries, maxDictionaryEntries))
+	}
+	checkTerms := func(field string, terms []string) {
+		for _, term := range terms {
+			if len(term) > maxDictionaryTermLength {
+				v.Check(field, fmt.Errorf("Terms must be at most %d characters", maxDictionaryTermLength))
+			}
+		}
+	}
+	checkTerms("stop_words", dict.StopWords)
+	checkTerms("protected_terms", dict.Protected)
+	for term, synonyms := range dict.Synonyms {
+		checkTerms("synonyms", append([]string{term}, synonyms...))
+	}
+	if err := v.Err(); err != nil {
+		writeValidationError(w, logger, err)
+		return
+	}
+
+	if err := s.store.SetSearchDictionary(ctx, dict); err != nil {
+		logger.Error("request failed", "operation", "set_search_dictionary", "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to save search dictionary")
+		return
+	}
+	s.store.AddAuditEntry(ctx, "config", fmt.Sprintf("Search dictionary set: %d stop words, %d protected terms, %d synonym terms",
+		len(dict.StopWords), len(dict.Protected), len(dict.Synonyms)), "")
+
+	// Return the dictionary as stored, with terms normalized and duplicates dropped
+	saved, err := s.store.GetSearchDictionary(ctx)
+	if err != nil {
+		logger.Error("request failed", "operation", "get_search_dictionary", "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to get search dictionary")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":    true,
+		"dictionary": saved,
+	})
+
+	latency := time.Since(start).Milliseconds()
+	logger.Debug("request completed", "status", http.StatusOK, "latency_ms", latency, "entries", entries)
+}
@@ -0,0 +1,73 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// mockStoreForDictionary keeps the search dictionary in memory, lower-casing
+// terms as the store does
+type mockStoreForDictionary struct {
+	mockStoreForAdmin
+	dict SearchDictionary
+}
+
+func (m *mockStoreForDictionary) GetSearchDictionary(ctx context.Context) (*SearchDictionary, error) {
+	dict := m.dict
+	return &dict, nil
+}
+
+func (m *mockStoreForDictionary) SetSearchDictionary(ctx context.Context, dict SearchDictionary) error {
+	for i, word := range dict.StopWords {
+		dict.StopWords[i] = strings.ToLower(word)
+	}
+	m.dict = dict
+	return nil
+}
+
+func TestSearchDictionaryHandlers(t *testing.T) {
+	store := &mockStoreForDictionary{}
+	server := &Server{store: store, logger: &mockLogger{}}
+
+	request := func(method, body string, userID int64) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/api/admin/search-dictionary", strings.NewReader(body))
+		return serveRoute(server, withUser(req, userID))
+	}
+
+	w := request(http.MethodPut, `{"stop_words":["The","of"],"protected_terms":["Section 230"],"synonyms":{"mi":["heart attack"]}}`, 1)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Dictionary SearchDictionary `json:"dictionary"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(resp.Dictionary.StopWords) != 2 || resp.Dictionary.StopWords[0] != "the" {
+		t.Errorf("Expected the dictionary as stored, got %+v", resp.Dictionary)
+	}
+
+	w = request(http.MethodGet, "", 1)
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(resp.Dictionary.Protected) != 1 || resp.Dictionary.Synonyms["mi"][0] != "heart attack" {
+		t.Errorf("Expected the saved dictionary, got %+v", resp.Dictionary)
+	}
+
+	long := strings.Repeat("x", maxDictionaryTermLength+1)
+	if w := request(http.MethodPut, `{"stop_words":["`+long+`"]}`, 1); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for a term too long, got %d", w.Code)
+	}
+	if w := request(http.MethodPut, `{"stop_words":["a"]}`, 2); w.Code != http.StatusForbidden {
+		t.Errorf("Expected status 403 for a non-admin, got %d", w.Code)
+	}
+	if store.dict.StopWords[0] != "the" {
+		t.Errorf("Expected rejected requests to leave the dictionary, got %+v", store.dict)
+	}
+}
@@ -0,0 +1,166 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"noodexx/internal/auth"
+	"noodexx/internal/validate"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Library search limits
+const (
+	defaultSearchLimit = 10
+	maxSearchLimit     = 50
+	maxSearchQuery     = 500
+)
+
+// searchModes are the ways library search can rank chunks, see SearchOptions
+var searchModes = []string{"vector", "keyword", "hybrid"}
+
+// SearchResult is a library chunk matching a search, with a snippet of its
+// text and the parts of the snippet that explain the match
+type SearchResult struct {
+	ChunkID    int64       `json:"chunk_id"`
+	Source     string      `json:"source"`
+	Score      float64     `json:"score"`
+	Snippet    string      `json:"snippet"`
+	Highlights []Highlight `json:"highlights"`
+	Match      string      `json:"match"` // MatchTerms, MatchSemantic, or empty when nothing is highlighted
+}
+
+// handleSearch handles GET /api/search?q=...&limit=...&mode=... - search the
+// user's library the way /api/ask retrieves chunks, returning each chunk with a
+// highlighted snippet. mode overrides the configured search mode. Words of the query found in a chunk are highlighted;
+// otherwise the sentence nearest the query is, when the RAG policy allows
+// library text to be embedded by the provider
+func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	requestID := newRequestID(w)
+
+	logger := s.logger.WithContext("request_id", requestID).
+		WithContext("method", r.Method).
+		WithContext("path", r.URL.Path)
+
+	logger.Debug("processing search request")
+
+	ctx := r.Context()
+
+	userID, err := auth.GetUserID(ctx)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
+		return
+	}
+
+	q, limit, mode, err := searchParams(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeBadRequest, err.Error())
+		return
+	}
+
+	// Keyword search ranks by the query's words alone, so it needs no provider
+	var provider LLMProvider
+	var queryVec []float32
+	if s.embedsQuery(mode) {
+		provider, err = s.providerManager.GetActiveProvider()
+		if err != nil {
+			logger.Error("request failed", "operation", "get_active_provider", "error", err.Error())
+			writeError(w, http.StatusBadRequest, CodeProviderUnavailable, "Provider not configured. Please configure the AI provider in Settings.")
+			return
+		}
+
+		queryVec, err = provider.Embed(ctx, q)
+		if err != nil {
+			logger.Error("request failed", "operation", "embed_query", "error", err.Error())
+			writeError(w, http.StatusInternalServerError, CodeInternal, "Embedding failed")
+			return
+		}
+	}
+	chunks, err := s.store.SearchLibrary(ctx, userID, queryVec, SearchOptions{Query: q, QueryModel: s.activeEmbedModel(), Mode: mode}, limit)
+	if err != nil {
+		logger.Error("request failed", "operation", "search_chunks", "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Search failed")
+		return
+	}
+
+	semantic := provider != nil && s.ragEnforcer.ShouldPerformRAG()
+	results := make([]SearchResult, len(chunks))
+	for i, chunk := range chunks {
+		results[i] = highlightResult(ctx, provider, chunk, q, queryVec, semantic)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"query":   q,
+		"results": results,
+	})
+
+	latency := time.Since(start).Milliseconds()
+	logger.Debug("request completed", "status", http.StatusOK, "latency_ms", latency, "results", len(results))
+}
+
+// searchParams reads the q, limit and mode parameters of a search request
+func searchParams(r *http.Request) (string, int, string, error) {
+	q := strings.TrimSpace(r.URL.Query().Get("q"))
+	if q == "" {
+		return "", 0, "", errors.New("Query required")
+	}
+	if len(q) > maxSearchQuery {
+		return "", 0, "", fmt.Errorf("Query too long (max %d characters)", maxSearchQuery)
+	}
+	limit := defaultSearchLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxSearchLimit {
+			return "", 0, "", fmt.Errorf("limit must be between 1 and %d", maxSearchLimit)
+		}
+		limit = n
+	}
+	mode := r.URL.Query().Get("mode")
+	if mode != "" {
+		if err := validate.OneOf("mode", mode, searchModes...); err != nil {
+			return "", 0, "", err
+		}
+	}
+	return q, limit, mode, nil
+}
+
+// embedsQuery reports whether a library search in mode, or in the configured
+// mode when mode is empty, compares embeddings and so needs the query embedded
+func (s *Server) embedsQuery(mode string) bool {
+	if mode == "" {
+		mode = s.searchMode
+	}
+	return mode != "keyword"
+}
+
+// highlightResult builds the search result for a chunk. semantic allows the
+// chunk's sentences to be embedded when no query word is found in it
+func highlightResult(ctx context.Context, provider LLMProvider, chunk Chunk, query string, queryVec []float32, semantic bool) SearchResult {
+	result := SearchResult{
+		ChunkID: chunk.ID,
+		Source:  chunk.Source,
+		Score:   chunk.Score,
+	}
+	text := []rune(chunk.Text)
+	highlights := termHighlights(text, queryTerms(query))
+	if len(highlights) > 0 {
+		result.Match = MatchTerms
+	} else if semantic {
+		if sentence, ok := nearestSentence(ctx, provider, text, queryVec); ok {
+			highlights = []Highlight{sentence}
+			result.Match = MatchSemantic
+		}
+	}
+	result.Snippet, result.Highlights = snippet(text, highlights)
+	if result.Highlights == nil {
+		result.Highlights = []Highlight{}
+	}
+	return result
+}
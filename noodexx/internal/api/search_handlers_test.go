@@ -0,0 +1,100 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"noodexx/internal/auth"
+	"testing"
+)
+
+// searchOptionsStore records the options of the last library search
+type searchOptionsStore struct {
+	mockStoreForAsk
+	opts *SearchOptions
+}
+
+func (m *searchOptionsStore) SearchLibrary(ctx context.Context, userID int64, queryVec []float32, opts SearchOptions, topK int) ([]Chunk, error) {
+	m.opts = &opts
+	return []Chunk{{ID: 2, Source: "errors.md", Text: "Error E4012 means the fuser is overheating", Score: 4.2}}, nil
+}
+
+func TestHandleSearchMode(t *testing.T) {
+	store := &searchOptionsStore{}
+	server := &Server{
+		store:           store,
+		logger:          &mockLoggerForAsk{},
+		providerManager: &mockProviderManagerForAsk{provider: &mockProviderForAsk{name: "ollama", isLocal: true}, providerName: "Ollama (llama3.2)"},
+		ragEnforcer:     &mockRAGEnforcerForAsk{shouldPerformRAG: true, ragStatus: "RAG Enabled"},
+	}
+	search := func(query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/search?"+query, nil)
+		req = req.WithContext(context.WithValue(req.Context(), auth.UserIDKey, int64(1)))
+		w := httptest.NewRecorder()
+		server.handleSearch(w, req)
+		return w
+	}
+
+	if w := search("q=E4012"); w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if store.opts == nil || store.opts.Query != "E4012" || store.opts.Mode != "" {
+		t.Errorf("Expected the query text with the configured mode, got %+v", store.opts)
+	}
+
+	if w := search("q=E4012&mode=keyword"); w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if store.opts.Mode != "keyword" {
+		t.Errorf("Expected keyword mode, got %+v", store.opts)
+	}
+
+	store.opts = nil
+	if w := search("q=E4012&mode=fuzzy"); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an unknown mode, got %d", w.Code)
+	}
+	if store.opts != nil {
+		t.Errorf("Expected no search for an unknown mode, got %+v", store.opts)
+	}
+}
+
+func TestHandleSearchKeywordSkipsEmbedding(t *testing.T) {
+	embeds := 0
+	provider := &mockProviderForAsk{name: "ollama", isLocal: true, embedFunc: func(ctx context.Context, text string) ([]float32, error) {
+		embeds++
+		return []float32{0.1, 0.2, 0.3}, nil
+	}}
+	store := &searchOptionsStore{}
+	server := &Server{
+		store:           store,
+		logger:          &mockLoggerForAsk{},
+		providerManager: &mockProviderManagerForAsk{provider: provider, providerName: "Ollama (llama3.2)"},
+		ragEnforcer:     &mockRAGEnforcerForAsk{shouldPerformRAG: true, ragStatus: "RAG Enabled"},
+	}
+	search := func(query string) {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/api/search?"+query, nil)
+		req = req.WithContext(context.WithValue(req.Context(), auth.UserIDKey, int64(1)))
+		w := httptest.NewRecorder()
+		server.handleSearch(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+	}
+
+	search("q=E4012&mode=keyword")
+	if embeds != 0 {
+		t.Errorf("Expected no embedding for a keyword search, got %d", embeds)
+	}
+
+	// The configured mode applies when the request leaves it unset
+	server.SetSearchMode("keyword")
+	search("q=E4012")
+	if embeds != 0 {
+		t.Errorf("Expected no embedding when keyword search is configured, got %d", embeds)
+	}
+	search("q=E4012&mode=hybrid")
+	if embeds != 1 {
+		t.Errorf("Expected the query embedded for a hybrid search, got %d embeddings", embeds)
+	}
+}
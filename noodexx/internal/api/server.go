@@ -3,34 +3,80 @@ package api
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"html/template"
 	"io"
 	"log"
 	"net/http"
 	"noodexx/internal/auth"
+	"noodexx/internal/eval"
+	"noodexx/internal/events"
+	"noodexx/internal/flags"
+	"noodexx/internal/markdown"
+	"noodexx/internal/metaquery"
+	"noodexx/internal/pwpolicy"
 	"path/filepath"
-	"strings"
+	"sync"
 	"time"
 )
 
 // Server holds dependencies and provides HTTP handlers
 type Server struct {
-	store           Store
-	provider        LLMProvider
-	ingester        Ingester
-	searcher        Searcher
-	wsHub           *WebSocketHub
-	templates       *template.Template
-	config          *ServerConfig
-	skillsLoader    SkillsLoader
-	skillsExecutor  SkillsExecutor
-	logger          Logger
-	authProvider    AuthProvider
-	configPath      string // Path to config file for saving
-	providerManager ProviderManager
-	ragEnforcer     RAGEnforcer
-	uiStyle         interface{} // UIStyle configuration for theming
+	store            Store
+	provider         LLMProvider
+	ingester         Ingester
+	searcher         Searcher
+	wsHub            *WebSocketHub
+	templates        *template.Template
+	config           *ServerConfig
+	skillsLoader     SkillsLoader
+	skillsExecutor   SkillsExecutor
+	logger           Logger
+	authProvider     AuthProvider
+	configPath       string // Path to config file for saving
+	providerManager  ProviderManager
+	ragEnforcer      RAGEnforcer
+	uiStyle          interface{}        // UIStyle configuration for theming
+	templatePath     string             // Glob for stock page templates
+	branding         Branding           // Organization branding injected into templates
+	overrideDir      string             // Directory of admin-supplied templates/static assets
+	attachments      *attachmentStore   // Transient per-session chat attachments
+	streams          *streamBufferStore // Recent /api/ask output for resuming dropped streams
+	wireLog          WireLog            // Provider request log, nil when disabled
+	transcripts      TranscriptArchive  // Encrypted archive of cloud requests, nil when disabled
+	replays          ReplayLog          // Failed provider requests kept for replay, nil when disabled
+	webSearch        WebSearcher        // Live web search, nil when disabled
+	webSearchOpts    WebSearchOptions
+	reranker         Reranker                   // Reorders library search results, nil when unavailable
+	mmrLambda        float64                    // Relevance weight of diversity selection, 0 when disabled
+	searchMode       string                     // Library search mode of searches that leave it unset
+	embeddingPool    EmbeddingPool              // Ingestion embedding workers, nil when disabled
+	extractors       ExtractorRegistry          // External text extractors, nil when not set up
+	generationLimits GenerationLimits           // Bounds on generation options, defaults when zero
+	providerQueue    ProviderQueue              // Fair admission for answer generation, nil when unlimited
+	queueKeepAlive   time.Duration              // Interval of queue position events
+	streamKeepAlive  time.Duration              // Interval of keep-alive comments before an answer's first token, 0 for none
+	modelWarmer      ModelWarmer                // Keeps local models loaded, nil when disabled
+	telemetry        TelemetryReporter          // Anonymous usage reports, nil when not set up
+	rememberMeDays   int                        // Lifetime of "remember me" cookies, default when zero
+	lockoutPolicy    LockoutPolicy              // Failed sign-ins before an account locks, default when zero
+	passwords        *pwpolicy.Policy           // Rules for new passwords, pwpolicy.Default() when nil
+	folderRetrier    FolderRetrier              // Retries quarantined watched files, nil without a watcher
+	watcherControl   WatcherControl             // Pauses the folder watcher, nil when it runs on another instance
+	docQABudget      int                        // Document tokens per call of document questions, default when zero
+	costPolicy       CostPolicy                 // Cloud model prices and when to confirm expensive questions
+	historyMessages  int                        // Recent session messages included in prompts, default when zero
+	summaries        SummaryRegenerator         // Regenerates document summaries, nil when unavailable
+	rechunker        Rechunker                  // Re-chunks documents with the current chunker, nil when unavailable
+	rechunkMu        sync.Mutex                 // Guards rechunks
+	rechunks         map[int64]*RechunkProgress // Re-chunking job of each user, kept after it finishes
+	flags            *flags.Flags               // Feature flags, every feature on when nil
+	scheduler        JobScheduler               // Background job scheduler, nil when not running
+	events           EventPublisher             // Shares WebSocket events with other instances, nil when not clustered
+	bus              *events.Bus                // Internal event bus, nil when subsystems are not connected
+	notesMu          sync.Mutex                 // Serializes rebuilding notes documents from their notes
+	perf             *routeStats                // Request latencies per route, nil when not collected
 }
 
 // Logger interface for structured logging
@@ -47,17 +93,79 @@ type Logger interface {
 type Store interface {
 	SaveChunk(ctx context.Context, source, text string, embedding []float32, tags []string, summary string) error
 	Search(ctx context.Context, queryVec []float32, topK int) ([]Chunk, error)
-	SearchByUser(ctx context.Context, userID int64, queryVec []float32, topK int) ([]Chunk, error)
+	SearchByUser(ctx context.Context, userID int64, queryVec []float32, queryModel string, topK int) ([]Chunk, error)
+	SearchUserSources(ctx context.Context, userID int64, queryVec []float32, queryModel string, sources []string, topK int) ([]Chunk, error)
+	SearchLibrary(ctx context.Context, userID int64, queryVec []float32, opts SearchOptions, topK int) ([]Chunk, error)
+	GetSourceChunks(ctx context.Context, userID int64, source string) ([]Chunk, error)
 	Library(ctx context.Context) ([]LibraryEntry, error)
 	LibraryByUser(ctx context.Context, userID int64) ([]LibraryEntry, error)
+	ForEachLibraryEntry(ctx context.Context, userID int64, fn func(LibraryEntry) bool) error
 	DeleteSource(ctx context.Context, source string) error
+	GetDocument(ctx context.Context, userID, documentID int64) (*Document, error)
+	RenameDocument(ctx context.Context, userID, documentID int64, title string) error
+	PinDocument(ctx context.Context, userID, documentID int64) (bool, error)
+	UnpinDocument(ctx context.Context, userID, documentID int64) error
+	// AddQuickNote appends a note to a user's day document, returning its ID, or 0
+	// when the user captured the same text since dedupSince
+	AddQuickNote(ctx context.Context, userID int64, source, text string, dedupSince time.Time) (int64, error)
+	GetQuickNotes(ctx context.Context, userID int64, source string) ([]QuickNote, error)
+	DeleteQuickNote(ctx context.Context, userID, noteID int64) error
+	// GetDefaultVisibility returns the visibility of documents the user ingests without choosing one
+	GetDefaultVisibility(ctx context.Context, userID int64) (string, error)
+	SetDefaultVisibility(ctx context.Context, userID int64, visibility string) error
+	// SetDocumentsVisibility sets the visibility of the user's documents among
+	// documentIDs and their chunks, returning how many were updated
+	SetDocumentsVisibility(ctx context.Context, userID int64, documentIDs []int64, visibility string) (int, error)
+	CountChunkVisibility(ctx context.Context, chunkIDs []int64) (map[string]int, error)
+	// GetCostThreshold returns the user's cost confirmation threshold, nil for the server's
+	GetCostThreshold(ctx context.Context, userID int64) (*float64, error)
+	SetCostThreshold(ctx context.Context, userID int64, threshold *float64) error
+	SaveCostEstimate(ctx context.Context, e CostEstimate) error
+	GetCostEstimates(ctx context.Context, userID int64, limit int) ([]CostEstimate, error)
+	// SetDocumentLinks replaces the wikilinks kept with a user's imported document
+	SetDocumentLinks(ctx context.Context, userID int64, source string, targets []string) error
+	GetDocumentLinks(ctx context.Context, userID int64, source string) ([]string, error)
+	GetDocumentEncoding(ctx context.Context, userID int64, source string) (*DocumentEncoding, error)
+	// RecordChunkAccess logs the chunks among chunkIDs that carry tag, returning how many
+	RecordChunkAccess(ctx context.Context, access ChunkAccess, chunkIDs []int64, tag string) (int, error)
+	// GetChunkAccessLog returns the logged accesses to an owner's chunks since a time, newest first
+	GetChunkAccessLog(ctx context.Context, ownerID int64, source string, since time.Time, limit int) ([]ChunkAccess, error)
+	// RecordRetrievals counts the sources of one question's search results for their owners
+	RecordRetrievals(ctx context.Context, hits []RetrievalHit) error
+	// GetRetrievalStats returns how often each of a user's sources was retrieved since a time
+	GetRetrievalStats(ctx context.Context, userID int64, since time.Time) ([]SourceRetrieval, error)
 	SaveMessage(ctx context.Context, sessionID, role, content string) error
 	SaveChatMessage(ctx context.Context, userID int64, sessionID, role, content, providerMode string) error
+	SaveChatMessageWithCitations(ctx context.Context, userID int64, sessionID, role, content, providerMode string, citations []string) error
+	SaveChatMessageWithProvenance(ctx context.Context, userID int64, sessionID, role, content, providerMode string, citations []string, provenance *MessageProvenance) (int64, error)
+	GetMessageProvenance(ctx context.Context, userID, messageID int64) (*MessageProvenance, error)
+	GetResponseStats(ctx context.Context, userID int64, since time.Time) ([]ResponseStats, error)
+	// SaveMessageArtifacts stores the code blocks and tables extracted from a message
+	SaveMessageArtifacts(ctx context.Context, messageID int64, artifacts []MessageArtifact) error
+	// GetMessageArtifacts returns the artifacts of one of a user's messages, nil if it has none
+	GetMessageArtifacts(ctx context.Context, userID, messageID int64) ([]MessageArtifact, error)
 	GetSessionHistory(ctx context.Context, sessionID string) ([]ChatMessage, error)
 	GetSessionMessages(ctx context.Context, userID int64, sessionID string) ([]ChatMessage, error)
 	ListSessions(ctx context.Context) ([]Session, error)
 	GetUserSessions(ctx context.Context, userID int64) ([]Session, error)
 	GetSessionOwner(ctx context.Context, sessionID string) (int64, error)
+	// ImportChatSession recreates a conversation of another tool's chat history
+	// as a session; false means the user already imported it
+	ImportChatSession(ctx context.Context, userID int64, session *ImportedSession) (bool, error)
+	CreateSessionLink(ctx context.Context, userID int64, link *SessionLink) error
+	GetSessionLink(ctx context.Context, userID int64, sessionID string) (*SessionLink, error)
+	GetSessionMode(ctx context.Context, userID int64, sessionID string) (string, error)
+	SetSessionMode(ctx context.Context, userID int64, sessionID, mode string) error
+	GetSessionSummary(ctx context.Context, userID int64, sessionID string) (*SessionSummary, error)
+	SaveSessionSummary(ctx context.Context, userID int64, summary *SessionSummary) error
+	GetSessionsToCompact(ctx context.Context, idleBefore time.Time, keep int) ([]SessionToCompact, error)
+	// Session sharing methods
+	CreateSessionShare(ctx context.Context, share *SessionShare) (int64, error)
+	GetSessionShareByToken(ctx context.Context, token string) (*SessionShare, error)
+	GetSessionShares(ctx context.Context, userID int64, sessionID string) ([]SessionShare, error)
+	RevokeSessionShare(ctx context.Context, userID, shareID int64) error
+	RecordSessionShareView(ctx context.Context, shareID int64, ipAddress, userAgent string) error
+	GetSessionShareViews(ctx context.Context, userID, shareID int64, limit int) ([]SessionShareView, error)
 	AddAuditEntry(ctx context.Context, opType, details, userCtx string) error
 	GetAuditLog(ctx context.Context, opType string, from, to time.Time) ([]AuditEntry, error)
 	// User management methods
@@ -65,13 +173,97 @@ type Store interface {
 	GetUserByID(ctx context.Context, userID int64) (*User, error)
 	CreateUser(ctx context.Context, username, password, email string, isAdmin, mustChangePassword bool) (int64, error)
 	UpdatePassword(ctx context.Context, userID int64, newPassword string) error
+	SetTemporaryPassword(ctx context.Context, userID int64, newPassword string) error
+	IsRecentPassword(ctx context.Context, userID int64, password string, n int) (bool, error)
 	UpdateUserDarkMode(ctx context.Context, userID int64, darkMode bool) error
+	// GetUserPreferences returns the interface preferences a user has set, by name
+	GetUserPreferences(ctx context.Context, userID int64) (map[string]string, error)
+	// SetUserPreferences saves preferences by name; an empty value returns one to its default
+	SetUserPreferences(ctx context.Context, userID int64, prefs map[string]string) error
 	ListUsers(ctx context.Context) ([]User, error)
 	DeleteUser(ctx context.Context, userID int64) error
+	CompleteOnboarding(ctx context.Context, userID int64) error
+	DeleteUserSessionTokens(ctx context.Context, userID int64, keepToken string) error
+	// Account lockout methods
+	IsAccountLocked(ctx context.Context, username string, threshold int, window time.Duration) (bool, time.Time)
+	CountFailedLogins(ctx context.Context, username string, since time.Time) (int, error)
+	ClearFailedLogins(ctx context.Context, username string) error
 	// Skills management methods
 	GetUserSkills(ctx context.Context, userID int64) ([]Skill, error)
+	RecordSkillRun(ctx context.Context, run *SkillRun) (int64, error)
+	SetSkillRunArtifact(ctx context.Context, userID, runID int64, source string) error
+	GetSkillRuns(ctx context.Context, userID, skillID int64, limit, offset int) ([]SkillRun, int, error)
+	GetSkillRun(ctx context.Context, userID, runID int64) (*SkillRun, error)
+	UpdateSkillEnabled(ctx context.Context, userID, skillID int64, enabled bool) error
+	CreateGlobalSkill(ctx context.Context, adminID int64, name, path string, enabled bool) (int64, error)
+	GetGlobalSkills(ctx context.Context) ([]GlobalSkill, error)
+	UpdateGlobalSkill(ctx context.Context, skillID int64, enabled bool) (bool, error)
+	DeleteGlobalSkill(ctx context.Context, skillID int64) (bool, error)
 	// Watched folders management methods
 	GetWatchedFoldersByUser(ctx context.Context, userID int64) ([]WatchedFolder, error)
+	GetIngestFailures(ctx context.Context, userID int64, folder string) ([]IngestFailure, error)
+	// Document summary methods
+	GetDocumentSummaries(ctx context.Context, userID int64, model string) ([]DocumentSummary, error)
+	GetChunkedDocuments(ctx context.Context, userID int64) ([]ChunkedDocument, error)
+	// Retrieval evaluation methods
+	CreateEvalSet(ctx context.Context, userID int64, name string, cases []eval.Case) (int64, error)
+	UpdateEvalSet(ctx context.Context, userID, setID int64, name string, cases []eval.Case) error
+	GetEvalSets(ctx context.Context, userID int64) ([]EvalSet, error)
+	GetEvalSet(ctx context.Context, userID, setID int64) (*EvalSet, error)
+	DeleteEvalSet(ctx context.Context, userID, setID int64) error
+	CreateSavedSearch(ctx context.Context, userID int64, name, query, mode string) (int64, error)
+	GetSavedSearches(ctx context.Context, userID int64) ([]SavedSearch, error)
+	GetSavedSearch(ctx context.Context, userID, id int64) (*SavedSearch, error)
+	GetStaleSavedSearches(ctx context.Context, model string, limit int) ([]SavedSearch, error)
+	SetSavedSearchEmbedding(ctx context.Context, id int64, embedding []float32, model string) error
+	DeleteSavedSearch(ctx context.Context, userID, id int64) (bool, error)
+	RecordEvalRun(ctx context.Context, userID int64, run *EvalRun) (int64, error)
+	GetEvalRuns(ctx context.Context, userID, setID int64, limit int) ([]EvalRun, error)
+	GetEvalRun(ctx context.Context, userID, runID int64) (*EvalRun, error)
+	// Feature flag override methods
+	GetFlagOverrides(ctx context.Context) ([]FlagOverride, error)
+	SetFlagOverride(ctx context.Context, userID int64, flag string, enabled bool) error
+	DeleteFlagOverride(ctx context.Context, userID int64, flag string) error
+	// Retrieval ranking methods
+	GetRankingWeights(ctx context.Context, userID int64) (*RankingWeights, error)
+	SaveRankingWeights(ctx context.Context, userID int64, weights RankingWeights) error
+	// Generation defaults methods
+	GetGenerationDefaults(ctx context.Context, userID int64) (*GenerationOptions, error)
+	SaveGenerationDefaults(ctx context.Context, userID int64, opts GenerationOptions) error
+	GetAnswerStyle(ctx context.Context, userID int64) (*AnswerStyle, error)
+	SaveAnswerStyle(ctx context.Context, userID int64, style AnswerStyle) error
+	// Storage analytics methods
+	GetStorageStats(ctx context.Context, userID int64, allUsers bool, limit int) (*StorageStats, error)
+	GetEmbeddingGroups(ctx context.Context) ([]EmbeddingGroup, error)
+	GetUserActivity(ctx context.Context) ([]UserActivity, error)
+	// Quick search methods
+	QuickSearch(ctx context.Context, userID int64, query string, limits QuickSearchLimits) ([]QuickSearchResult, error)
+	GetSearchDictionary(ctx context.Context) (*SearchDictionary, error)
+	SetSearchDictionary(ctx context.Context, dict SearchDictionary) error
+	// Structured extraction methods
+	CreateExtractionSchema(ctx context.Context, userID int64, name, description string, schema json.RawMessage) (int64, error)
+	GetExtractionSchemas(ctx context.Context, userID int64) ([]ExtractionSchema, error)
+	GetExtractionSchema(ctx context.Context, userID, schemaID int64) (*ExtractionSchema, error)
+	DeleteExtractionSchema(ctx context.Context, userID, schemaID int64) error
+	SaveStructuredRecord(ctx context.Context, userID int64, record *StructuredRecord) (int64, error)
+	GetStructuredRecords(ctx context.Context, userID int64, filter RecordFilter) ([]StructuredRecord, int, error)
+	// Metadata query methods
+	GetMetadataRows(ctx context.Context, userID int64, entity string) ([]metaquery.Row, error)
+	// Notification methods
+	CreateNotification(ctx context.Context, userID int64, notificationType, message, payload string) (*Notification, error)
+	GetNotifications(ctx context.Context, userID int64, unreadOnly bool, limit int) ([]Notification, error)
+	CountUnreadNotifications(ctx context.Context, userID int64) (int, error)
+	MarkNotificationsRead(ctx context.Context, userID int64, ids []int64) (int64, error)
+	// Unit of work for multi-step operations
+	WithTx(ctx context.Context, fn func(tx StoreTx) error) error
+}
+
+// StoreTx is the subset of Store operations that can run inside a unit of work
+// All calls made through a StoreTx commit or roll back together
+type StoreTx interface {
+	DeleteSource(ctx context.Context, source string) error
+	AddAuditEntry(ctx context.Context, opType, details, userCtx string) error
+	DeleteUser(ctx context.Context, userID int64) error
 }
 
 // AuthProvider interface for authentication operations
@@ -84,21 +276,23 @@ type AuthProvider interface {
 
 // User represents a user account
 type User struct {
-	ID                 int64
-	Username           string
-	PasswordHash       string
-	Email              string
-	IsAdmin            bool
-	MustChangePassword bool
-	CreatedAt          time.Time
-	LastLogin          time.Time
-	DarkMode           bool
+	ID                  int64
+	Username            string
+	PasswordHash        string
+	Email               string
+	IsAdmin             bool
+	MustChangePassword  bool
+	CreatedAt           time.Time
+	LastLogin           time.Time
+	DarkMode            bool
+	OnboardingCompleted bool
 }
 
 // LLMProvider interface for chat and embeddings
 type LLMProvider interface {
 	Embed(ctx context.Context, text string) ([]float32, error)
 	Stream(ctx context.Context, messages []Message, w io.Writer) (string, error)
+	StreamWithOptions(ctx context.Context, messages []Message, opts GenerationOptions, w io.Writer) (string, error)
 	Name() string
 	IsLocal() bool
 }
@@ -113,6 +307,24 @@ type ProviderManager interface {
 	Reload(cfg interface{}) error
 }
 
+// ActiveModelReporter is implemented by provider managers that can report the
+// chat model of the active provider
+type ActiveModelReporter interface {
+	GetActiveModel() string
+}
+
+// ActiveEmbedModelReporter is implemented by provider managers that can report the
+// embedding model of the active provider
+type ActiveEmbedModelReporter interface {
+	GetActiveEmbedModel() string
+}
+
+// ModeModelReporter is implemented by provider managers that can report the
+// chat and embedding models of the local or cloud provider, whichever is active
+type ModeModelReporter interface {
+	GetModels(local bool) (chatModel, embedModel string)
+}
+
 // RAGEnforcer interface for RAG policy enforcement
 type RAGEnforcer interface {
 	ShouldPerformRAG() bool
@@ -120,15 +332,22 @@ type RAGEnforcer interface {
 	Reload(cfg interface{})
 }
 
+// ModeRAGEnforcer is implemented by RAG enforcers that can decide for a
+// request answered in local or cloud mode regardless of the privacy toggle
+type ModeRAGEnforcer interface {
+	ShouldPerformRAGFor(local bool) bool
+	GetRAGStatusFor(local bool) string
+}
+
 // Ingester interface for document ingestion
 type Ingester interface {
 	IngestText(ctx context.Context, userID int64, source, text string, tags []string) error
 	IngestURL(ctx context.Context, userID int64, url string, tags []string) error
 }
 
-// Searcher interface for RAG search
+// Searcher interface for RAG search over the chunks visible to a user
 type Searcher interface {
-	Search(ctx context.Context, queryVec []float32, topK int) ([]Chunk, error)
+	Search(ctx context.Context, userID int64, queryVec []float32, opts SearchOptions, topK int) ([]Chunk, error)
 }
 
 // SkillsLoader interface for loading skills
@@ -144,6 +363,7 @@ type SkillsExecutor interface {
 
 // Skill represents a loaded skill
 type Skill struct {
+	ID          int64 // Database ID
 	UserID      int64 // Owner of the skill
 	Name        string
 	Version     string
@@ -153,6 +373,12 @@ type Skill struct {
 	Timeout     time.Duration
 	RequiresNet bool
 	Path        string
+	Enabled     bool // Whether the user has the skill turned on
+	Global      bool // Provisioned by an administrator for every account
+
+	// Optional JSON Schemas declared in skill.json
+	InputSchema  json.RawMessage
+	OutputSchema json.RawMessage
 }
 
 // SkillTrigger defines when a skill executes
@@ -169,10 +395,139 @@ type SkillInput struct {
 }
 
 // SkillOutput is the output from a skill
+// Executors may return a SkillOutput alongside an error so that failed runs can still be recorded
 type SkillOutput struct {
 	Result   string                 `json:"result"`
 	Error    string                 `json:"error"`
 	Metadata map[string]interface{} `json:"metadata"`
+
+	// Execution details, not part of the skill's own output
+	Stderr     string `json:"-"`
+	ExitCode   int    `json:"-"`
+	DurationMS int64  `json:"-"`
+}
+
+// MessageProvenance records how an assistant message was generated
+type MessageProvenance struct {
+	MessageID        int64           `json:"message_id"`
+	Provider         string          `json:"provider"`
+	Model            string          `json:"model"`
+	Params           json.RawMessage `json:"params"`
+	PromptHash       string          `json:"prompt_hash"`
+	ChunkIDs         []int64         `json:"chunk_ids"`
+	PromptTokens     int             `json:"prompt_tokens"`     // Estimated from character count
+	CompletionTokens int             `json:"completion_tokens"` // Estimated from character count
+	FirstTokenMS     int64           `json:"first_token_ms"`    // From sending the prompt to the first token; 0 if not measured
+	GenerationMS     int64           `json:"generation_ms"`     // From sending the prompt to the end of the answer; 0 if not measured
+	Privacy          *PrivacySummary `json:"privacy,omitempty"` // Nil for answers recorded before it was
+	CreatedAt        time.Time       `json:"created_at"`
+}
+
+// ResponseStats summarizes how fast a provider and model answered a user
+type ResponseStats struct {
+	Provider         string  `json:"provider"`
+	Model            string  `json:"model"`
+	Responses        int     `json:"responses"`
+	AvgFirstTokenMS  float64 `json:"avg_first_token_ms"`
+	TokensPerSecond  float64 `json:"tokens_per_second"`
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+}
+
+// MessageArtifact is a code block or table extracted from an assistant message
+type MessageArtifact struct {
+	MessageID int64     `json:"message_id"`
+	N         int       `json:"n"`        // Position among the message's artifacts, from 1
+	Kind      string    `json:"kind"`     // "code" or "table"
+	Language  string    `json:"language"` // Language of a code block's fence, if given
+	Content   string    `json:"content"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// SessionShare is a read-only link to a chat session transcript
+type SessionShare struct {
+	ID           int64     `json:"id"`
+	Token        string    `json:"token"`
+	SessionID    string    `json:"session_id"`
+	UserID       int64     `json:"user_id"`
+	PasswordHash string    `json:"-"`
+	ExpiresAt    time.Time `json:"expires_at"` // Zero if the link never expires
+	RevokedAt    time.Time `json:"revoked_at"` // Zero unless revoked
+	ViewCount    int       `json:"view_count"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// SessionShareView is one recorded view of a shared session
+type SessionShareView struct {
+	ID        int64     `json:"id"`
+	ShareID   int64     `json:"share_id"`
+	IPAddress string    `json:"ip_address"`
+	UserAgent string    `json:"user_agent"`
+	ViewedAt  time.Time `json:"viewed_at"`
+}
+
+// SkillRun is a recorded skill execution
+type SkillRun struct {
+	ID             int64     `json:"id"`
+	SkillID        int64     `json:"skill_id"`
+	UserID         int64     `json:"user_id"`
+	Status         string    `json:"status"` // "success" or "error"
+	Input          string    `json:"input"`
+	Output         string    `json:"output"`
+	Error          string    `json:"error,omitempty"`
+	Stderr         string    `json:"stderr,omitempty"`
+	ExitCode       int       `json:"exit_code"`
+	DurationMS     int64     `json:"duration_ms"`
+	ArtifactSource string    `json:"artifact_source,omitempty"`
+	RerunOf        int64     `json:"rerun_of,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// SkillViolation describes one value that does not match a skill schema
+type SkillViolation struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+// SkillValidationError is returned by SkillsExecutor when input or output does not match the skill's schema
+type SkillValidationError struct {
+	Skill      string
+	Stage      string // "input" or "output"
+	Violations []SkillViolation
+}
+
+func (e *SkillValidationError) Error() string {
+	return fmt.Sprintf("skill %s %s does not match schema (%d violations)", e.Skill, e.Stage, len(e.Violations))
+}
+
+// SkillLimitError is returned by SkillsExecutor when an execution is stopped by
+// one of the server's limits rather than by the skill itself
+type SkillLimitError struct {
+	Skill  string
+	Limit  string // "queue_timeout", "max_runtime" or "max_output"
+	Detail string
+}
+
+func (e *SkillLimitError) Error() string {
+	return fmt.Sprintf("skill %s stopped by %s: %s", e.Skill, e.Limit, e.Detail)
+}
+
+// SkillStatsReporter is implemented by skill executors that can report their
+// load and the limits executions hit
+type SkillStatsReporter interface {
+	SkillStats() SkillExecutorStats
+}
+
+// SkillExecutorStats counts skill executions and the limits they hit since startup
+type SkillExecutorStats struct {
+	Running        int   `json:"running"`
+	Waiting        int   `json:"waiting"`
+	Runs           int64 `json:"runs"`
+	Queued         int64 `json:"queued"`
+	QueueTimeouts  int64 `json:"queue_timeouts"`
+	RuntimeCapped  int64 `json:"runtime_capped"`
+	Timeouts       int64 `json:"timeouts"`
+	OutputTooLarge int64 `json:"output_too_large"`
 }
 
 // Message represents a chat message
@@ -183,18 +538,52 @@ type Message struct {
 
 // Chunk represents a search result
 type Chunk struct {
-	Source string
-	Text   string
-	Score  float64
+	ID        int64 // Library chunk ID; zero for attachment and web results
+	Source    string
+	Text      string
+	Score     float64
+	Embedding []float32 // Set on library search results for diversity selection
+}
+
+// SearchOptions chooses how SearchLibrary ranks library chunks: by embedding,
+// by the query's words, or both fused into one ranking
+type SearchOptions struct {
+	Query      string   // Text of the query, for keyword scoring
+	QueryModel string   // Embedding model of the query vector; chunks from another model are skipped
+	Sources    []string // Only chunks of these sources, unless nil; empty finds nothing
+	Mode       string   // One of searchModes; empty uses the configured mode
 }
 
 // LibraryEntry represents a document in the library
 type LibraryEntry struct {
+	DocumentID int64
 	Source     string
+	Title      string // Display title; the source unless the document was renamed
 	ChunkCount int
 	Summary    string
 	Tags       []string
 	CreatedAt  time.Time
+	Warning    string // Why the latest ingestion kept only part of the document, if it did
+	Pinned     bool   // The user pinned the document
+}
+
+// Document is the stable record of an ingested source
+type Document struct {
+	ID         int64     `json:"id"`
+	Source     string    `json:"source"`     // Filename, path or URL it was ingested from
+	Title      string    `json:"title"`      // Display title; empty shows the source
+	Visibility string    `json:"visibility"` // Inherited by its chunks
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// DocumentEncoding is the encoding a document was decoded from at its latest
+// ingestion, and what normalizing its text changed
+type DocumentEncoding struct {
+	Source    string    `json:"source"`
+	Encoding  string    `json:"encoding"` // Such as "utf-8", "utf-16le" or "windows-1252"
+	Changes   string    `json:"changes"`  // Such as "12 line endings, 2 entities"; empty when nothing changed
+	UpdatedAt time.Time `json:"updated_at"`
 }
 
 // ChatMessage represents a chat message
@@ -204,6 +593,8 @@ type ChatMessage struct {
 	Role         string
 	Content      string
 	ProviderMode string
+	Citations    []string
+	Privacy      *PrivacySummary `json:"privacy,omitempty"` // Set for answers whose privacy summary was recorded
 	CreatedAt    time.Time
 }
 
@@ -212,6 +603,26 @@ type Session struct {
 	ID            string
 	LastMessageAt time.Time
 	MessageCount  int
+	ContinuedFrom string // Earlier session this one continues, if any
+	ImportedFrom  string // Chat history format the session was imported from, such as "chatgpt"; empty for sessions held here
+}
+
+// ImportedSession is a conversation from another tool's chat history, to be
+// recreated as a session
+type ImportedSession struct {
+	SessionID  string
+	Format     string // Chat history format, such as "chatgpt" or "claude"
+	ExternalID string // ID of the conversation in the export
+	Title      string
+	CreatedAt  time.Time
+	Messages   []ImportedMessage
+}
+
+// ImportedMessage is a message of an imported session
+type ImportedMessage struct {
+	Role      string // "user" or "assistant"
+	Content   string
+	CreatedAt time.Time // Zero takes the session's creation time
 }
 
 // WatchedFolder represents a monitored directory
@@ -221,6 +632,540 @@ type WatchedFolder struct {
 	UserID int64
 }
 
+// IngestFailure is a watched file that failed to ingest
+type IngestFailure struct {
+	Path          string    `json:"path"`
+	Error         string    `json:"error"`
+	Attempts      int       `json:"attempts"`
+	Quarantined   bool      `json:"quarantined"` // No longer retried until retried by hand
+	FirstFailedAt time.Time `json:"first_failed_at"`
+	LastAttemptAt time.Time `json:"last_attempt_at"`
+}
+
+// ExtractionSchema is a user's JSON schema for records extracted from documents
+type ExtractionSchema struct {
+	ID          int64           `json:"id"`
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Schema      json.RawMessage `json:"schema"`
+	CreatedAt   time.Time       `json:"created_at"`
+}
+
+// StructuredRecord is the record extracted from one document with a schema
+type StructuredRecord struct {
+	ID          int64                  `json:"id"`
+	SchemaID    int64                  `json:"schema_id"`
+	Source      string                 `json:"source"`
+	Data        map[string]interface{} `json:"data"`
+	Status      string                 `json:"status"` // "ok", "invalid" or "failed"
+	Violations  []SkillViolation       `json:"violations,omitempty"`
+	Error       string                 `json:"error,omitempty"`
+	Model       string                 `json:"model,omitempty"`
+	ExtractedAt time.Time              `json:"extracted_at"`
+}
+
+// RecordFilter selects structured records of one schema
+type RecordFilter struct {
+	SchemaID int64
+	Status   string            // Only records with this status when set
+	Source   string            // Only records whose source contains this when set
+	Fields   map[string]string // Only records whose field equals the value, compared as text
+	Limit    int
+	Offset   int
+}
+
+// Notification is an event kept for a user until they read it, such as a
+// finished ingestion or skill run
+type Notification struct {
+	ID        int64           `json:"id"`
+	Type      string          `json:"type"` // "ingestion", "deletion", "skill_run", "quarantine" or "rechunk"
+	Message   string          `json:"message"`
+	Payload   json.RawMessage `json:"payload,omitempty"`
+	Read      bool            `json:"read"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// DocumentSummary is a library document's summary and what it was generated from
+type DocumentSummary struct {
+	Source      string     `json:"source"`
+	Summary     string     `json:"summary"`
+	SummaryHTML string     `json:"summary_html"`           // The summary rendered from markdown, safe to insert into a page
+	Model       string     `json:"model,omitempty"`        // Empty for summaries from before models were recorded
+	GeneratedAt *time.Time `json:"generated_at,omitempty"` // Nil for summaries from before dates were recorded
+	Stale       string     `json:"stale,omitempty"`        // Why it needs regenerating: "untracked", "content_changed" or "model_changed"
+}
+
+// ChunkedDocument is a library document and the chunker settings its chunks were made with
+type ChunkedDocument struct {
+	Source  string `json:"source"`
+	Stored  bool   `json:"stored"`            // Whether its content was kept, so it can be re-chunked
+	Chunker string `json:"chunker,omitempty"` // Empty for documents chunked before settings were recorded
+}
+
+// FlagOverride turns a feature flag on or off for one user
+type FlagOverride struct {
+	UserID    int64     `json:"user_id"`
+	Username  string    `json:"username"`
+	Flag      string    `json:"flag"`
+	Enabled   bool      `json:"enabled"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// GlobalSkill is a skill an administrator provisioned for every account
+type GlobalSkill struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	Path      string    `json:"path"`    // Directory of the skill under the skills directory
+	Enabled   bool      `json:"enabled"` // For users who haven't turned it on or off themselves
+	AddedBy   int64     `json:"added_by"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// RankingWeights holds a user's retrieval ranking modifiers
+type RankingWeights struct {
+	RecencyHalfLifeDays float64            `json:"recency_half_life_days"`
+	TagWeights          map[string]float64 `json:"tag_weights"`
+	SourceWeights       map[string]float64 `json:"source_weights"`
+}
+
+// GenerationOptions controls sampling for an answer
+// Nil and zero fields leave the provider's default in place
+type GenerationOptions struct {
+	Temperature *float64 `json:"temperature,omitempty"`
+	TopP        *float64 `json:"top_p,omitempty"`
+	MaxTokens   int      `json:"max_tokens,omitempty"`
+}
+
+// AnswerStyle sets the language, tone and citation style of answers
+// Empty fields leave the model's own behaviour; see rag.AnswerStyle
+type AnswerStyle struct {
+	Language      string `json:"language,omitempty"`
+	Tone          string `json:"tone,omitempty"`           // "concise", "detailed" or "technical"
+	CitationStyle string `json:"citation_style,omitempty"` // "numbered", "sources" or "none"
+}
+
+// GenerationLimits bounds the generation options users may request
+type GenerationLimits struct {
+	MaxTemperature float64 `json:"max_temperature"`
+	MaxTokens      int     `json:"max_tokens"`
+}
+
+// StorageUsage is the space taken by a group of chunks, in bytes
+type StorageUsage struct {
+	Chunks         int   `json:"chunks"`
+	TextBytes      int64 `json:"text_bytes"`
+	EmbeddingBytes int64 `json:"embedding_bytes"`
+	MetadataBytes  int64 `json:"metadata_bytes"` // Summaries and tags
+	TotalBytes     int64 `json:"total_bytes"`
+}
+
+// SourceStorage is the space taken by one user's copy of a source
+type SourceStorage struct {
+	Source   string `json:"source"`
+	UserID   int64  `json:"user_id"`
+	Username string `json:"username,omitempty"`
+	StorageUsage
+}
+
+// UserStorage is the space taken by all of a user's sources
+type UserStorage struct {
+	UserID   int64  `json:"user_id"`
+	Username string `json:"username,omitempty"`
+	Sources  int    `json:"sources"`
+	StorageUsage
+}
+
+// StorageStats breaks down library storage by source and user
+type StorageStats struct {
+	Total   StorageUsage    `json:"total"`
+	Sources int             `json:"sources"`
+	Largest []SourceStorage `json:"largest"` // Largest sources first
+	Users   []UserStorage   `json:"users"`   // Largest users first
+}
+
+// EmbeddingGroup counts the chunks embedded by one model at one dimension
+type EmbeddingGroup struct {
+	Model     string `json:"model"` // Empty for chunks stored before embedding models were recorded
+	Dimension int    `json:"dimension"`
+	Chunks    int    `json:"chunks"`
+	Sources   int    `json:"sources"`
+}
+
+// UserActivity is an account with its last activity and resource usage
+type UserActivity struct {
+	UserID                int64
+	Username              string
+	Email                 string
+	IsAdmin               bool
+	CreatedAt             time.Time
+	LastLogin             time.Time // Zero if the user never signed in
+	LastChat              time.Time // Zero if the user never sent a chat message
+	Messages              int       // Chat messages the user sent
+	Documents             int       // Distinct sources in the user's library
+	StorageBytes          int64     // Chunk text, embeddings and metadata of those sources
+	CloudPromptTokens     int       // Estimated tokens sent to cloud providers
+	CloudCompletionTokens int       // Estimated tokens generated by cloud providers
+	EstimatedCostUSD      float64   // Estimated cost of the requests sent to paid providers with known prices
+	ConfirmationsAsked    int       // Requests held back until the user confirmed their cost
+}
+
+// QuickSearchLimits caps the number of quick search results per type (0 skips the type)
+type QuickSearchLimits struct {
+	Documents int
+	Tags      int
+	Sessions  int
+	Messages  int
+}
+
+// QuickSearchResult is a typed quick search hit with a navigation target
+type QuickSearchResult struct {
+	Type      string    `json:"type"` // "document", "tag", "session" or "message"
+	Title     string    `json:"title"`
+	Snippet   string    `json:"snippet,omitempty"`
+	Target    string    `json:"target"`
+	SessionID string    `json:"session_id,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// WireLog interface for reading the provider request log
+type WireLog interface {
+	Query(filter WireLogFilter) ([]WireLogEntry, error)
+}
+
+// WireLogFilter selects provider request log entries (zero values match everything)
+type WireLogFilter struct {
+	Provider   string
+	Model      string
+	Operation  string
+	UserID     int64
+	ErrorsOnly bool
+	Since      time.Time
+	Limit      int
+}
+
+// WireLogEntry is a single provider request from the wire log
+type WireLogEntry struct {
+	Time           time.Time `json:"time"`
+	Provider       string    `json:"provider"`
+	Model          string    `json:"model"`
+	Operation      string    `json:"operation"`
+	UserID         int64     `json:"user_id,omitempty"`
+	Messages       int       `json:"messages,omitempty"`
+	PromptHash     string    `json:"prompt_hash"`
+	PromptChars    int       `json:"prompt_chars"`
+	PromptTokens   int       `json:"prompt_tokens"`
+	ResponseChars  int       `json:"response_chars"`
+	ResponseTokens int       `json:"response_tokens"`
+	Dimensions     int       `json:"dimensions,omitempty"`
+	LatencyMS      int64     `json:"latency_ms"`
+	Error          string    `json:"error,omitempty"`
+	Prompt         string    `json:"prompt,omitempty"`
+	Response       string    `json:"response,omitempty"`
+}
+
+// TranscriptArchive reads the encrypted archive of requests to cloud providers
+type TranscriptArchive interface {
+	// Transcripts returns matching archived requests, oldest first, with their
+	// payloads decrypted when decrypt is set
+	Transcripts(ctx context.Context, filter TranscriptFilter, decrypt bool) ([]Transcript, error)
+	RetentionDays() int // 0 keeps everything
+	IncludesEmbeddings() bool
+}
+
+// TranscriptFilter selects archived transcripts (zero values match everything)
+type TranscriptFilter struct {
+	UserID   int64
+	Provider string
+	Since    time.Time
+	Until    time.Time // Exclusive
+	Limit    int
+}
+
+// Transcript is an archived request to a cloud provider
+type Transcript struct {
+	ID           int64              `json:"id"`
+	Time         time.Time          `json:"time"`
+	UserID       int64              `json:"user_id,omitempty"`
+	Provider     string             `json:"provider"`
+	Model        string             `json:"model"`
+	Operation    string             `json:"operation"`
+	Payload      *TranscriptPayload `json:"payload,omitempty"`
+	DecryptError string             `json:"decrypt_error,omitempty"` // Set instead of the payload when it cannot be opened
+}
+
+// TranscriptPayload is what was sent to a cloud provider and what came back
+type TranscriptPayload struct {
+	Messages    []Message `json:"messages,omitempty"`
+	Input       string    `json:"input,omitempty"`
+	Temperature *float64  `json:"temperature,omitempty"`
+	TopP        *float64  `json:"top_p,omitempty"`
+	MaxTokens   int       `json:"max_tokens,omitempty"`
+	Response    string    `json:"response,omitempty"`
+	Dimensions  int       `json:"dimensions,omitempty"`
+	Error       string    `json:"error,omitempty"`
+}
+
+// ReplayLog keeps provider requests that failed and runs them again
+type ReplayLog interface {
+	// FailedRequests returns the most recent failed requests, newest first
+	FailedRequests(ctx context.Context, limit int) ([]FailedRequest, error)
+	// Replay runs a failed request again against the current provider
+	// configuration, only resolving the provider and model unless live is set.
+	// It returns nil if there is no request with the ID
+	Replay(ctx context.Context, id int64, live bool) (*ReplayResult, error)
+	RetentionDays() int
+}
+
+// FailedRequest is a provider request that failed, with its contents redacted
+type FailedRequest struct {
+	ID          int64     `json:"id"`
+	Time        time.Time `json:"time"`
+	UserID      int64     `json:"user_id,omitempty"`
+	Provider    string    `json:"provider"`
+	Model       string    `json:"model"`
+	Operation   string    `json:"operation"`
+	Local       bool      `json:"local"`
+	Messages    []Message `json:"messages,omitempty"`
+	Input       string    `json:"input,omitempty"`
+	Temperature *float64  `json:"temperature,omitempty"`
+	TopP        *float64  `json:"top_p,omitempty"`
+	MaxTokens   int       `json:"max_tokens,omitempty"`
+	Error       string    `json:"error"`
+}
+
+// ReplayResult is the outcome of replaying a failed request
+type ReplayResult struct {
+	Request       FailedRequest `json:"request"`
+	Mode          string        `json:"mode"`               // "dry_run" or "live"
+	Provider      string        `json:"provider,omitempty"` // Provider the request was or would be sent to
+	Model         string        `json:"model"`
+	Response      string        `json:"response,omitempty"`
+	Dimensions    int           `json:"dimensions,omitempty"`
+	OriginalError string        `json:"original_error"`
+	Error         string        `json:"error,omitempty"`
+	Outcome       string        `json:"outcome"` // dry_run, succeeded, same_error, different_error or provider_unavailable
+	Diff          []string      `json:"diff,omitempty"`
+	LatencyMS     int64         `json:"latency_ms"`
+}
+
+// WebSearcher interface for searching the web and fetching the top results
+type WebSearcher interface {
+	Search(ctx context.Context, query string) ([]WebResult, error)
+}
+
+// WebResult is a fetched web search result
+type WebResult struct {
+	Title string `json:"title"`
+	URL   string `json:"url"`
+	Text  string `json:"-"`
+}
+
+// WebSearchOptions controls when web search runs and what happens to its results
+type WebSearchOptions struct {
+	AutoInCloudMode bool // Search on every question while in cloud mode
+	IngestResults   bool // Save fetched pages to the user's library
+}
+
+// Reranker interface for scoring documents by relevance to a query
+// A nil score slice with no error means no reranker is currently loaded
+type Reranker interface {
+	Rerank(ctx context.Context, query string, documents []string) ([]float64, error)
+}
+
+// ProviderQueue admits answer generation fairly across users
+type ProviderQueue interface {
+	Join(userID int64) QueueTicket
+	Stats() ProviderQueueStats
+}
+
+// QueueTicket is a place in the provider queue
+// It must always be released, whether or not it was admitted
+type QueueTicket interface {
+	Ready() <-chan struct{}
+	Position() int
+	Release()
+}
+
+// ProviderQueueStats is a snapshot of provider queue load and wait times
+type ProviderQueueStats struct {
+	Active     int   `json:"active"`
+	Waiting    int   `json:"waiting"`
+	Users      int   `json:"users"`
+	Admitted   int64 `json:"admitted"`
+	Queued     int64 `json:"queued"`
+	Abandoned  int64 `json:"abandoned"`
+	AvgWaitMS  int64 `json:"avg_wait_ms"`
+	MaxWaitMS  int64 `json:"max_wait_ms"`
+	LastWaitMS int64 `json:"last_wait_ms"`
+}
+
+// JobScheduler runs background jobs on cron schedules
+// RunJob and SetJobPaused return ErrJobNotFound for unknown jobs, and RunJob
+// returns ErrJobRunning when the job is already running
+type JobScheduler interface {
+	Jobs(ctx context.Context) []ScheduledJob
+	RunJob(name string) error
+	SetJobPaused(ctx context.Context, name string, paused bool) error
+}
+
+// Errors returned by JobScheduler
+var (
+	ErrJobNotFound = errors.New("job not found")
+	ErrJobRunning  = errors.New("job is already running")
+)
+
+// ScheduledJob is a background job's schedule and recent runs
+type ScheduledJob struct {
+	Name           string     `json:"name"`
+	Description    string     `json:"description"`
+	Schedule       string     `json:"schedule"` // Cron expression or "@every <duration>"
+	Paused         bool       `json:"paused"`
+	Running        bool       `json:"running"`
+	LastRun        *time.Time `json:"last_run,omitempty"`
+	LastDurationMS int64      `json:"last_duration_ms"`
+	LastError      string     `json:"last_error,omitempty"` // Error of the last run, empty if it succeeded
+	NextRun        *time.Time `json:"next_run,omitempty"`   // Nil when the schedule never matches again
+	Runs           int64      `json:"runs"`
+	Failures       int64      `json:"failures"`
+}
+
+// ModelWarmer keeps the local provider's models loaded between chats
+type ModelWarmer interface {
+	Touch()
+	Status() ModelWarmupStatus
+}
+
+// ModelWarmupStatus reports whether the local models are loaded
+type ModelWarmupStatus struct {
+	State         string     `json:"state"` // "cold", "warming", "warm", "failed" or "unsupported"
+	KeepAlive     bool       `json:"keep_alive"`
+	Paused        bool       `json:"paused"` // Keep-alive paused after the idle window
+	LastLoaded    *time.Time `json:"last_loaded,omitempty"`
+	LastActivity  *time.Time `json:"last_activity,omitempty"`
+	LastLatencyMS int64      `json:"last_latency_ms"`
+	LastError     string     `json:"last_error,omitempty"`
+	Pings         int64      `json:"pings"`
+}
+
+// TelemetryReporter previews the anonymous usage report and reports whether it is sent
+type TelemetryReporter interface {
+	Preview(ctx context.Context) ([]byte, error) // The exact request body that would be sent
+	Status() TelemetryStatus
+}
+
+// TelemetryStatus describes anonymous usage reporting
+type TelemetryStatus struct {
+	Enabled     bool       `json:"enabled"`
+	DisabledBy  string     `json:"disabled_by,omitempty"` // "config", "NOODEXX_TELEMETRY" or "DO_NOT_TRACK"
+	Endpoint    string     `json:"endpoint,omitempty"`
+	LastSent    *time.Time `json:"last_sent,omitempty"`
+	LastError   string     `json:"last_error,omitempty"`
+	ReportsSent int64      `json:"reports_sent"`
+}
+
+// FolderRetrier retries files in watched folders that failed to ingest
+type FolderRetrier interface {
+	Retry(ctx context.Context, userID int64, path string) error
+	QuarantineLimit() int // Failed attempts after which a file is quarantined
+}
+
+// WatcherControl pauses and resumes the folder watcher
+type WatcherControl interface {
+	Pause()
+	Resume()
+	Status() WatcherStatus
+}
+
+// WatcherStatus reports the folder watcher's ingestion activity since startup
+type WatcherStatus struct {
+	Paused     bool       `json:"paused"`
+	Pending    int        `json:"pending"`   // Changed files held back while paused
+	Settling   int        `json:"settling"`  // Changed files waiting to stop changing before they are ingested
+	Ingesting  int        `json:"ingesting"` // Files being ingested now
+	Ingested   int64      `json:"ingested"`
+	Failed     int64      `json:"failed"`
+	LastIngest *time.Time `json:"last_ingest,omitempty"`
+}
+
+// SavedSearch is a library search a user saved to run again. Its query is
+// embedded ahead of time, and again once another embedding model is active
+type SavedSearch struct {
+	ID             int64      `json:"id"`
+	UserID         int64      `json:"-"`
+	Name           string     `json:"name"`
+	Query          string     `json:"query"`
+	Mode           string     `json:"mode"` // Empty for the configured search mode
+	Embedding      []float32  `json:"-"`
+	EmbeddingModel string     `json:"embedding_model"`
+	EmbeddedAt     *time.Time `json:"embedded_at"`
+	Stale          bool       `json:"stale"` // Not embedded, or embedded with a model other than the active one
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+// EvalSet is a golden set of retrieval evaluation cases
+type EvalSet struct {
+	ID        int64       `json:"id"`
+	Name      string      `json:"name"`
+	Cases     []eval.Case `json:"cases"`
+	CreatedAt time.Time   `json:"created_at"`
+	UpdatedAt time.Time   `json:"updated_at"`
+}
+
+// EvalRun is one recorded evaluation of an EvalSet
+type EvalRun struct {
+	ID         int64                  `json:"id"`
+	SetID      int64                  `json:"set_id"`
+	Config     map[string]interface{} `json:"config"` // Retrieval settings the run used
+	Metrics    eval.Metrics           `json:"metrics"`
+	Results    []eval.Result          `json:"results,omitempty"` // Omitted from run lists
+	DurationMS int64                  `json:"duration_ms"`
+	CreatedAt  time.Time              `json:"created_at"`
+}
+
+// SummaryRegenerator regenerates the summaries of library documents
+type SummaryRegenerator interface {
+	RegenerateSummary(ctx context.Context, userID int64, source string) error
+	SummaryModel() string // Model new summaries are generated with
+}
+
+// Rechunker splits documents again from their kept content with the current chunker
+type Rechunker interface {
+	ChunkerSettings() string // Settings of the current chunker
+	Rechunk(ctx context.Context, userID int64, source string) (int, error)
+}
+
+// EmbeddingPool interface for reporting the health of ingestion embedding workers
+type EmbeddingPool interface {
+	Stats() []EmbeddingWorkerStats
+}
+
+// ExtractorRegistry interface for listing the external programs that convert
+// files into text
+type ExtractorRegistry interface {
+	Extractors() []ExtractorInfo
+}
+
+// ExtractorInfo describes a loaded external extractor
+type ExtractorInfo struct {
+	Name           string   `json:"name"`
+	Version        string   `json:"version,omitempty"`
+	Description    string   `json:"description,omitempty"`
+	MIMETypes      []string `json:"mime_types"`
+	Extensions     []string `json:"extensions"`
+	TimeoutSeconds int      `json:"timeout_seconds"` // From the manifest, before the server's cap
+}
+
+// EmbeddingWorkerStats is a snapshot of one embedding endpoint
+type EmbeddingWorkerStats struct {
+	Endpoint            string `json:"endpoint"`
+	Healthy             bool   `json:"healthy"`
+	ConsecutiveFailures int    `json:"consecutive_failures"`
+	InFlight            int    `json:"in_flight"`
+	Completed           int64  `json:"completed"`
+	Failed              int64  `json:"failed"`
+	AvgLatencyMS        int64  `json:"avg_latency_ms"`
+}
+
 // AuditEntry represents an audit log entry
 type AuditEntry struct {
 	ID            int64
@@ -230,6 +1175,14 @@ type AuditEntry struct {
 	UserContext   string
 }
 
+// Branding holds organization-specific branding injected into templates
+type Branding struct {
+	AppName      string
+	LogoPath     string
+	PrimaryColor string // Hex color applied to the primary palette
+	FooterText   string
+}
+
 // ServerConfig holds server configuration
 type ServerConfig struct {
 	PrivacyMode        bool
@@ -252,6 +1205,348 @@ func NewServer(store Store, provider LLMProvider, ingester Ingester, searcher Se
 
 // NewServerWithTemplatePath creates a server with a custom template path (useful for testing)
 func NewServerWithTemplatePath(store Store, provider LLMProvider, ingester Ingester, searcher Searcher, config *ServerConfig, skillsLoader SkillsLoader, skillsExecutor SkillsExecutor, logger Logger, authProvider AuthProvider, configPath string, templatePath string, providerManager ProviderManager, ragEnforcer RAGEnforcer, uiStyle interface{}) (*Server, error) {
+	srv := &Server{
+		store:           store,
+		provider:        provider,
+		ingester:        ingester,
+		searcher:        searcher,
+		wsHub:           NewWebSocketHub(),
+		config:          config,
+		skillsLoader:    skillsLoader,
+		skillsExecutor:  skillsExecutor,
+		logger:          logger,
+		authProvider:    authProvider,
+		configPath:      configPath,
+		providerManager: providerManager,
+		ragEnforcer:     ragEnforcer,
+		uiStyle:         uiStyle,
+		templatePath:    templatePath,
+		branding:        DefaultBranding(),
+		attachments:     newAttachmentStore(attachmentTTL),
+		streams:         newStreamBufferStore(streamBufferTTL),
+		perf:            newRouteStats(routeSampleSize),
+	}
+
+	if err := srv.loadTemplates(); err != nil {
+		return nil, err
+	}
+
+	// Start WebSocket hub
+	go srv.wsHub.Run()
+
+	return srv, nil
+}
+
+// DefaultBranding returns the stock Noodexx branding
+func DefaultBranding() Branding {
+	return Branding{
+		AppName:  "Noodexx",
+		LogoPath: "/static/logo.png",
+	}
+}
+
+// SetBranding applies organization branding and an optional override directory
+// Templates in overrideDir (and overrideDir/components) replace the stock templates
+// with the same name, and files in overrideDir/static take precedence over web/static
+func (s *Server) SetBranding(branding Branding, overrideDir string) error {
+	defaults := DefaultBranding()
+	if branding.AppName == "" {
+		branding.AppName = defaults.AppName
+	}
+	if branding.LogoPath == "" {
+		branding.LogoPath = defaults.LogoPath
+	}
+
+	s.branding = branding
+	s.overrideDir = overrideDir
+
+	return s.loadTemplates()
+}
+
+// SetTranscripts enables the admin transcript export and tells users that
+// their cloud requests are archived
+func (s *Server) SetTranscripts(archive TranscriptArchive) {
+	s.transcripts = archive
+}
+
+// SetReplays enables the admin API for replaying failed provider requests
+func (s *Server) SetReplays(replays ReplayLog) {
+	s.replays = replays
+}
+
+// SetWireLog enables the admin provider request log API
+func (s *Server) SetWireLog(wireLog WireLog) {
+	s.wireLog = wireLog
+}
+
+// SetWebSearch enables web search as an additional context source for /api/ask
+func (s *Server) SetWebSearch(searcher WebSearcher, opts WebSearchOptions) {
+	s.webSearch = searcher
+	s.webSearchOpts = opts
+}
+
+// SetReranker enables reranking of library search results for /api/ask
+func (s *Server) SetReranker(reranker Reranker) {
+	s.reranker = reranker
+}
+
+// SetDiversity picks library search results by Maximal Marginal Relevance with
+// the given relevance weight between 0 and 1, so answers draw on more than one
+// passage of the same document; 0 disables it
+func (s *Server) SetDiversity(lambda float64) {
+	s.mmrLambda = lambda
+}
+
+// SetSearchMode tells the server which library search mode the store uses for
+// searches that leave it unset, so keyword searches skip embedding the query
+func (s *Server) SetSearchMode(mode string) {
+	s.searchMode = mode
+}
+
+// SetFeatureFlags gates features behind the configured flags and the admin
+// flags API; without them every feature is on
+func (s *Server) SetFeatureFlags(f *flags.Flags) {
+	s.flags = f
+}
+
+// EventPublisher sends events to the other instances sharing the database
+type EventPublisher interface {
+	Publish(ctx context.Context, kind, payload string) error
+}
+
+// WebSocketEventKind is the kind of the events Notify publishes to other instances
+const WebSocketEventKind = "websocket"
+
+// SetEventPublisher shares WebSocket events with the other instances of a
+// cluster; their events arrive through RelayEvent
+func (s *Server) SetEventPublisher(events EventPublisher) {
+	s.events = events
+}
+
+// SetJobScheduler enables the admin background jobs API
+func (s *Server) SetJobScheduler(scheduler JobScheduler) {
+	s.scheduler = scheduler
+}
+
+// SetEmbeddingPool enables the admin embedding pool status API
+func (s *Server) SetEmbeddingPool(pool EmbeddingPool) {
+	s.embeddingPool = pool
+}
+
+// SetExtractors enables the admin API listing external extractors
+func (s *Server) SetExtractors(registry ExtractorRegistry) {
+	s.extractors = registry
+}
+
+// SetProviderQueue limits concurrent answer generation per user and overall
+// Queued /api/ask requests receive a queue event every keepAlive while they wait
+func (s *Server) SetProviderQueue(queue ProviderQueue, keepAlive time.Duration) {
+	s.providerQueue = queue
+	s.queueKeepAlive = keepAlive
+}
+
+// SetModelWarmer enables model warm-up status on the dashboard; chats on the
+// local provider count as activity for keep-alive
+func (s *Server) SetModelWarmer(warmer ModelWarmer) {
+	s.modelWarmer = warmer
+}
+
+// SetTelemetry lets admins preview the anonymous usage report
+func (s *Server) SetTelemetry(reporter TelemetryReporter) {
+	s.telemetry = reporter
+}
+
+// SetGenerationLimits bounds the generation options accepted from users
+func (s *Server) SetGenerationLimits(limits GenerationLimits) {
+	s.generationLimits = limits
+}
+
+// SetDocQABudget sets how many tokens of document text each model call may use when
+// /api/ask answers over a whole document
+func (s *Server) SetDocQABudget(tokens int) {
+	s.docQABudget = tokens
+}
+
+// SetHistoryWindow sets how many of a session's most recent messages /api/ask
+// includes in prompts; older messages reach the prompt through the session's
+// rolling summary
+func (s *Server) SetHistoryWindow(messages int) {
+	s.historyMessages = messages
+}
+
+// defaultRememberMeDays matches the auth.remember_me_days default
+const defaultRememberMeDays = 30
+
+// SetRememberMeDays sets how long "remember me" login cookies last; it should
+// match the auth provider's remember-me session lifetime
+func (s *Server) SetRememberMeDays(days int) {
+	s.rememberMeDays = days
+}
+
+// rememberMeMaxAge returns the max age in seconds of a "remember me" cookie
+func (s *Server) rememberMeMaxAge() int {
+	days := s.rememberMeDays
+	if days <= 0 {
+		days = defaultRememberMeDays
+	}
+	return days * 24 * 60 * 60
+}
+
+// SetLockoutPolicy sets the account lockout policy reported and applied by the
+// admin lockout API; it should match the auth provider's
+func (s *Server) SetLockoutPolicy(policy LockoutPolicy) {
+	s.lockoutPolicy = policy
+}
+
+// SetPasswordPolicy sets the rules for passwords chosen at registration, on
+// password change and by admins creating users
+func (s *Server) SetPasswordPolicy(policy *pwpolicy.Policy) {
+	s.passwords = policy
+}
+
+// passwordPolicy returns the configured password policy or the default one
+func (s *Server) passwordPolicy() *pwpolicy.Policy {
+	if s.passwords == nil {
+		return pwpolicy.Default()
+	}
+	return s.passwords
+}
+
+// SetFolderRetrier enables retrying quarantined files from the folder errors API
+func (s *Server) SetFolderRetrier(retrier FolderRetrier) {
+	s.folderRetrier = retrier
+}
+
+// SetWatcherControl lets admins pause and resume the folder watcher
+func (s *Server) SetWatcherControl(control WatcherControl) {
+	s.watcherControl = control
+}
+
+// SetSummaryRegenerator enables regenerating document summaries through the API
+func (s *Server) SetSummaryRegenerator(regenerator SummaryRegenerator) {
+	s.summaries = regenerator
+}
+
+// SetRechunker enables re-chunking documents through the API
+func (s *Server) SetRechunker(rechunker Rechunker) {
+	s.rechunker = rechunker
+}
+
+// Notify sends an event to every connected WebSocket client, including those of
+// the other instances of a cluster. Events that concern one user should go
+// through NotifyUser instead, which also keeps them until the user reads them
+func (s *Server) Notify(eventType, message string) {
+	if s.wsHub != nil {
+		s.wsHub.Broadcast(eventType, message)
+	}
+	payload, _ := json.Marshal(map[string]string{
+		"type":    eventType,
+		"message": message,
+	})
+	s.publishEvent(payload)
+}
+
+// pushToUser sends a WebSocket message of a topic to one user's clients,
+// including those of the other instances of a cluster
+func (s *Server) pushToUser(userID int64, topic string, message interface{}) {
+	data, err := json.Marshal(message)
+	if err != nil {
+		s.logger.Warn("failed to encode WebSocket message", "user_id", userID, "error", err.Error())
+		return
+	}
+	if s.wsHub != nil {
+		s.wsHub.SendToUser(userID, topic, data)
+	}
+	payload, _ := json.Marshal(map[string]interface{}{
+		"user_id": userID,
+		"topic":   topic,
+		"frame":   json.RawMessage(data),
+	})
+	s.publishEvent(payload)
+}
+
+// pushToTopic sends a WebSocket message to the clients subscribed to its topic,
+// including those of the other instances of a cluster
+func (s *Server) pushToTopic(topic string, message interface{}) {
+	data, err := json.Marshal(message)
+	if err != nil {
+		s.logger.Warn("failed to encode WebSocket message", "topic", topic, "error", err.Error())
+		return
+	}
+	if s.wsHub != nil {
+		s.wsHub.SendToTopic(topic, data)
+	}
+	payload, _ := json.Marshal(map[string]interface{}{
+		"topic": topic,
+		"frame": json.RawMessage(data),
+	})
+	s.publishEvent(payload)
+}
+
+// NotifyJob tells the clients subscribed to the jobs topic that a background
+// job run started or finished
+func (s *Server) NotifyJob(job ScheduledJob) {
+	s.pushToTopic(topicJobs, map[string]interface{}{
+		"type": "job",
+		"job":  job,
+	})
+}
+
+// pushSessionMessage tells the clients subscribed to a chat session's topic that
+// a message was saved to it, so the session's other tabs follow the chat.
+// messageID is 0 when the store did not return one
+func (s *Server) pushSessionMessage(userID int64, sessionID, role, content string, messageID int64) {
+	message := map[string]interface{}{
+		"type":       "chat_message",
+		"session_id": sessionID,
+		"role":       role,
+		"content":    content,
+	}
+	if messageID != 0 {
+		message["message_id"] = messageID
+	}
+	s.pushToUser(userID, chatTopicPrefix+sessionID, message)
+}
+
+// publishEvent shares a WebSocket event with the other instances of a cluster
+func (s *Server) publishEvent(payload []byte) {
+	if s.events == nil {
+		return
+	}
+	if err := s.events.Publish(context.Background(), WebSocketEventKind, string(payload)); err != nil {
+		s.logger.Warn("failed to share event with other instances", "error", err.Error())
+	}
+}
+
+// RelayEvent sends an event published by another instance to this instance's
+// WebSocket clients
+func (s *Server) RelayEvent(payload string) {
+	var event struct {
+		Type    string          `json:"type"`
+		Message string          `json:"message"`
+		UserID  int64           `json:"user_id"` // Set for messages to one user's clients
+		Topic   string          `json:"topic"`   // Set for messages to the clients of a topic
+		Frame   json.RawMessage `json:"frame"`
+	}
+	if err := json.Unmarshal([]byte(payload), &event); err != nil {
+		s.logger.Warn("dropping malformed event from another instance", "error", err.Error())
+		return
+	}
+	if s.wsHub == nil {
+		return
+	}
+	switch {
+	case event.UserID != 0:
+		s.wsHub.SendToUser(event.UserID, event.Topic, event.Frame)
+	case event.Topic != "":
+		s.wsHub.SendToTopic(event.Topic, event.Frame)
+	default:
+		s.wsHub.Broadcast(event.Type, event.Message)
+	}
+}
+
+// loadTemplates parses the stock templates followed by any override templates
+func (s *Server) loadTemplates() error {
 	// Create template with custom functions
 	funcMap := template.FuncMap{
 		"toJSON": func(v interface{}) (template.JS, error) {
@@ -289,146 +1584,275 @@ func NewServerWithTemplatePath(store Store, provider LLMProvider, ingester Inges
 			// Convert string to template.HTML to prevent escaping
 			return template.HTML(s)
 		},
+		"markdown": func(s string) template.HTML {
+			// The renderer strips raw HTML and escapes everything else
+			return template.HTML(markdown.Render(s))
+		},
+		"preview": markdown.Preview,
+		"branding": func() Branding {
+			return s.branding
+		},
 	}
 
 	// Load templates from the specified path with custom functions
-	tmpl, err := template.New("").Funcs(funcMap).ParseGlob(templatePath)
+	tmpl, err := template.New("").Funcs(funcMap).ParseGlob(s.templatePath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to load templates: %w", err)
+		return fmt.Errorf("failed to load templates: %w", err)
 	}
 
-	// Also load component templates if they exist
-	componentPath := "web/templates/components/*.html"
+	// Also load component templates, kept beside the page templates, if they exist
+	componentPath := filepath.Join(filepath.Dir(s.templatePath), "components", "*.html")
 	matches, _ := filepath.Glob(componentPath)
 	if len(matches) > 0 {
 		tmpl, err = tmpl.ParseGlob(componentPath)
 		if err != nil {
-			return nil, fmt.Errorf("failed to load component templates: %w", err)
+			return fmt.Errorf("failed to load component templates: %w", err)
 		}
 	}
 
-	srv := &Server{
-		store:           store,
-		provider:        provider,
-		ingester:        ingester,
-		searcher:        searcher,
-		wsHub:           NewWebSocketHub(),
-		templates:       tmpl,
-		config:          config,
-		skillsLoader:    skillsLoader,
-		skillsExecutor:  skillsExecutor,
-		logger:          logger,
-		authProvider:    authProvider,
-		configPath:      configPath,
-		providerManager: providerManager,
-		ragEnforcer:     ragEnforcer,
-		uiStyle:         uiStyle,
+	// Override templates are parsed last so their definitions take precedence
+	if s.overrideDir != "" {
+		for _, overridePath := range []string{
+			filepath.Join(s.overrideDir, "*.html"),
+			filepath.Join(s.overrideDir, "components", "*.html"),
+		} {
+			matches, _ := filepath.Glob(overridePath)
+			if len(matches) == 0 {
+				continue
+			}
+			tmpl, err = tmpl.ParseGlob(overridePath)
+			if err != nil {
+				return fmt.Errorf("failed to load override templates: %w", err)
+			}
+		}
 	}
 
-	// Start WebSocket hub
-	go srv.wsHub.Run()
+	s.templates = tmpl
+	return nil
+}
 
-	return srv, nil
+// serveStatic serves static assets, preferring files from the override directory
+func (s *Server) serveStatic(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("path")
+
+	if s.overrideDir != "" {
+		overrideFS := http.Dir(filepath.Join(s.overrideDir, "static"))
+		if f, err := overrideFS.Open("/" + name); err == nil {
+			info, statErr := f.Stat()
+			f.Close()
+			if statErr == nil && !info.IsDir() {
+				http.StripPrefix("/static/", http.FileServer(overrideFS)).ServeHTTP(w, r)
+				return
+			}
+		}
+	}
+
+	http.StripPrefix("/static/", http.FileServer(http.Dir("web/static"))).ServeHTTP(w, r)
 }
 
+// Requests allowed per client address on the unauthenticated routes that take a password
+const (
+	passwordRateLimit  = 10
+	passwordRateWindow = time.Minute
+)
+
 // RegisterRoutes sets up all HTTP routes
+// Routes are method-qualified, so handlers see only the methods registered for them;
+// each route lists the middleware it needs (user, admin, rate limit, same-origin)
 func (s *Server) RegisterRoutes(mux *http.ServeMux) {
 	log.Printf("=== Registering HTTP routes ===")
+	rt := newRouter(mux)
+	rt.observe = s.accessLog
+
+	user := []middleware{s.requireUser, sameOrigin}
+	admin := []middleware{s.requireAdmin, sameOrigin}
+	passwordLimiter := newRateLimiter(passwordRateLimit, passwordRateWindow)
+	public := []middleware{rateLimit(passwordLimiter), sameOrigin}
 
-	// Static files - serve from web/static/ with cache control
-	staticHandler := http.StripPrefix("/static/", http.FileServer(http.Dir("web/static")))
-	mux.HandleFunc("/static/", func(w http.ResponseWriter, r *http.Request) {
+	// Static files - serve from the override directory or web/static/ with cache control
+	rt.handle("GET /static/{path...}", func(w http.ResponseWriter, r *http.Request) {
 		// Set cache control headers for static assets
 		w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
 		w.Header().Set("Pragma", "no-cache")
 		w.Header().Set("Expires", "0")
-		staticHandler.ServeHTTP(w, r)
+		s.serveStatic(w, r)
 	})
 	log.Printf("Registered: /static/")
 
-	// API routes (register before page routes to avoid conflicts)
-	mux.HandleFunc("/api/ask", s.handleAsk)
-	mux.HandleFunc("/api/ingest/text", s.handleIngestText)
-	mux.HandleFunc("/api/ingest/url", s.handleIngestURL)
-	mux.HandleFunc("/api/ingest/file", s.handleIngestFile)
-	mux.HandleFunc("/api/delete", s.handleDelete)
-	mux.HandleFunc("/api/sessions", s.handleSessions)
-	mux.HandleFunc("/api/session/", s.handleSessionHistory)
-	mux.HandleFunc("/api/config", s.handleConfig)
-	mux.HandleFunc("/api/test-connection", s.handleTestConnection)
-	mux.HandleFunc("/api/activity", s.handleActivity)
-	mux.HandleFunc("/api/library", s.handleLibrary) // API endpoint for HTMX library loading
-	mux.HandleFunc("/api/skills", s.handleSkills)
-	mux.HandleFunc("/api/skills/run", s.handleRunSkill)
-	mux.HandleFunc("/api/watched-folders", s.handleWatchedFolders)
-	mux.HandleFunc("/api/settings", s.handleSaveSettings)              // Save settings endpoint
-	mux.HandleFunc("/api/privacy-mode", s.handlePrivacyMode)           // Toggle privacy mode
-	mux.HandleFunc("/api/privacy-toggle", s.handlePrivacyToggle)       // Toggle between local and cloud AI
-	mux.HandleFunc("/api/user/preferences", s.handleUpdatePreferences) // Update user preferences (dark mode, etc.)
+	// API routes
+	rt.handle("POST /api/ask", s.handleAsk, user...)
+	rt.handle("GET /api/ask/{id}/stream", s.handleAskStream, user...) // Resume a dropped answer stream
+	rt.handle("POST /api/ingest/text", s.handleIngestText, user...)
+	rt.handle("POST /api/ingest/url", s.handleIngestURL, user...)
+	rt.handle("POST /api/ingest/file", s.handleIngestFile, user...)
+	rt.handle("POST /api/notes", s.handleQuickNote, user...)          // Append to today's notes document
+	rt.handle("POST /api/import", s.handleImport, user...)            // Import a vault or another tool's export
+	rt.handle("POST /api/import/chats", s.handleImportChats, user...) // Recreate ChatGPT or Claude conversations as sessions
+	rt.handle("POST /api/delete", s.handleDelete, user...)
+	rt.handle("DELETE /api/delete", s.handleDelete, user...)
+	rt.handle("GET /api/sessions", s.handleSessions, user...)
+	rt.handle("GET /api/session/{id}", s.handleSessionHistory, user...)
+	rt.handle("GET /api/session/{id}/export", s.handleExportSession, user...)
+	rt.handle("POST /api/session/{id}/continue", s.handleContinueSession, user...) // New session with a summary of this one
+	rt.handle("GET /api/session/{id}/link", s.handleGetSessionLink, user...)
+	rt.handle("GET /api/session/{id}/mode", s.handleSessionMode, user...)
+	rt.handle("PUT /api/session/{id}/mode", s.handleUpdateSessionMode, user...)
+	rt.handle("GET /api/session/{id}/sources", s.handleSessionSources, user...) // Library sources cited in the session
+	rt.handle("GET /api/session/{id}/search", s.handleSessionSearch, user...)   // Search only the session's cited sources
+	rt.handle("GET /api/session/{id}/shares", s.handleListSessionShares, user...)
+	rt.handle("POST /api/session/{id}/shares", s.handleCreateSessionShare, user...)
+	rt.handle("GET /api/message/{id}/provenance", s.handleMessageProvenance, user...)
+	rt.handle("GET /api/message/{id}/artifacts", s.handleMessageArtifacts, user...)    // Code blocks and tables in an answer
+	rt.handle("GET /api/message/{id}/artifacts/{n}", s.handleMessageArtifact, user...) // Raw artifact; ?format=csv, ?download=1
+	rt.handle("DELETE /api/shares/{id}", s.handleRevokeShare, user...)
+	rt.handle("GET /api/shares/{id}/views", s.handleShareViews, user...)
+	rt.handle("GET /share/{token}", s.handleSharePage)                                    // Public read-only transcript
+	rt.handle("POST /share/{token}", s.handleUnlockSharePage, rateLimit(passwordLimiter)) // Password for a protected transcript; the page sends no Referer, so no origin check
+	rt.handle("POST /api/config", s.handleConfig, user...)
+	rt.handle("POST /api/test-connection", s.handleTestConnection, user...)
+	rt.handle("GET /api/activity", s.handleActivity, user...)
+	rt.handle("GET /api/stats/storage", s.handleStorageStats, user...)      // Storage used by source and user
+	rt.handle("GET /api/stats/responses", s.handleResponseStats, user...)   // Answer speed by provider and model
+	rt.handle("GET /api/library", s.handleLibrary, user...)                 // API endpoint for HTMX library loading
+	rt.handle("PATCH /api/documents/{id}", s.handleRenameDocument, user...) // Set a document's display title
+	rt.handle("PUT /api/documents/{id}/pin", s.handlePinDocument, user...)
+	rt.handle("DELETE /api/documents/{id}/pin", s.handleUnpinDocument, user...)
+	rt.handle("GET /api/library/summaries", s.handleGetSummaries, user...)
+	rt.handle("GET /api/library/links", s.handleDocumentLinks, user...)       // Wikilinks of an imported note
+	rt.handle("GET /api/library/encoding", s.handleDocumentEncoding, user...) // Encoding a document was decoded from and what normalizing changed
+	rt.handle("GET /api/access-log", s.handleAccessLog, user...)              // Prompts that included the user's sensitive documents
+	rt.handle("GET /api/library/dead-content", s.handleDeadContent, user...)  // Sources to delete or re-chunk, from retrieval statistics
+	rt.handle("POST /api/library/summaries/regenerate", s.handleRegenerateSummaries, user...)
+	rt.handle("GET /api/library/rechunk", s.handleGetRechunk, user...) // Documents chunked with other settings, and the running job
+	rt.handle("POST /api/library/rechunk", s.handleRechunk, user...)   // Re-chunk one document or the library in the background
+	rt.handle("GET /api/skills", s.handleSkills, user...)
+	rt.handle("POST /api/skills/run", s.handleRunSkill, user...)
+	rt.handle("GET /api/skills/{id}/runs", s.handleListSkillRuns, user...)
+	rt.handle("POST /api/skills/{id}/runs/{run_id}/rerun", s.handleRerunSkill, user...)
+	rt.handle("PUT /api/skills/{id}/enabled", s.handleSetSkillEnabled, user...) // Turn a skill on or off for yourself
+	rt.handle("GET /api/watched-folders", s.handleWatchedFolders, user...)
+	rt.handle("GET /api/folders/{id}/errors", s.handleGetFolderErrors, user...)
+	rt.handle("POST /api/folders/{id}/errors/retry", s.handleRetryFolderErrors, user...)
+	rt.handle("GET /api/watcher", s.handleWatcherStatus, user...) // Folder watcher ingestion status
+	rt.handle("POST /api/watcher/pause", s.handlePauseWatcher, admin...)
+	rt.handle("POST /api/watcher/resume", s.handleResumeWatcher, admin...)
+	rt.handle("GET /api/flags", s.handleGetFlags, user...)
+	rt.handle("GET /api/notifications", s.handleGetNotifications, user...)
+	rt.handle("POST /api/notifications/read", s.handleMarkNotificationsRead, user...)
+	rt.handle("GET /api/saved-searches", s.handleGetSavedSearches, user...)
+	rt.handle("POST /api/saved-searches", s.handleCreateSavedSearch, user...)
+	rt.handle("DELETE /api/saved-searches/{id}", s.handleDeleteSavedSearch, user...)
+	rt.handle("GET /api/saved-searches/{id}/results", s.handleRunSavedSearch, user...) // Runs with the precomputed query embedding
+	rt.handle("GET /api/eval/sets", s.handleGetEvalSets, user...)
+	rt.handle("POST /api/eval/sets", s.handleCreateEvalSet, user...)
+	rt.handle("PUT /api/eval/sets/{id}", s.handleUpdateEvalSet, user...)
+	rt.handle("DELETE /api/eval/sets/{id}", s.handleDeleteEvalSet, user...)
+	rt.handle("POST /api/eval/sets/{id}/run", s.handleRunEvalSet, user...)
+	rt.handle("GET /api/eval/sets/{id}/runs", s.handleGetEvalRuns, user...)
+	rt.handle("GET /api/eval/runs/{id}", s.handleGetEvalRun, user...)
+	rt.handle("GET /api/extraction/schemas", s.handleGetExtractionSchemas, user...)
+	rt.handle("POST /api/extraction/schemas", s.handleCreateExtractionSchema, user...)
+	rt.handle("DELETE /api/extraction/schemas/{id}", s.handleDeleteExtractionSchema, user...)
+	rt.handle("POST /api/extraction/schemas/{id}/run", s.handleRunExtraction, user...)
+	rt.handle("GET /api/records", s.handleGetRecords, user...)
+	rt.handle("GET /api/records/export", s.handleExportRecords, user...)
+	rt.handle("GET /api/metadata/queries", s.handleGetMetadataQueries, user...)
+	rt.handle("POST /api/metadata/query", s.handleMetadataQuery, user...)
+	rt.handle("POST /api/settings", s.handleSaveSettings, user...)              // Save settings endpoint
+	rt.handle("POST /api/privacy-mode", s.handlePrivacyMode, user...)           // Toggle privacy mode
+	rt.handle("GET /api/privacy-toggle", s.handlePrivacyStatus, user...)        // Current local or cloud AI mode
+	rt.handle("POST /api/privacy-toggle", s.handlePrivacyToggle, user...)       // Toggle between local and cloud AI
+	rt.handle("POST /api/user/preferences", s.handleUpdatePreferences, user...) // Switch dark mode (older clients)
+	rt.handle("GET /api/me/preferences", s.handlePreferences, user...)          // Theme and other interface preferences
+	rt.handle("PUT /api/me/preferences", s.handleSetPreferences, user...)
+	rt.handle("GET /api/quicksearch", s.handleQuickSearch, user...)                 // Command palette lookup
+	rt.handle("GET /api/search", s.handleSearch, user...)                           // Library chunks with highlighted snippets
+	rt.handle("GET /api/onboarding", s.handleOnboarding, user...)                   // Onboarding state for current user
+	rt.handle("POST /api/onboarding/samples", s.handleOnboardingSamples, user...)   // Ingest bundled sample documents
+	rt.handle("POST /api/onboarding/complete", s.handleOnboardingComplete, user...) // Dismiss onboarding
+	rt.handle("GET /api/attachments", s.handleAttachments, user...)                 // List a session's chat attachments
+	rt.handle("POST /api/attachments/save", s.handleSaveAttachment, user...)        // Save a chat attachment to the library
+	rt.handle("GET /api/model-warmup", s.handleModelWarmup, user...)                // Local model warm-up status
+	rt.handle("GET /api/ranking-weights", s.handleGetRankingWeights, user...)
+	rt.handle("POST /api/ranking-weights", s.handleSetRankingWeights, user...)
+	rt.handle("GET /api/generation-defaults", s.handleGenerationDefaults, user...) // Per-user temperature, top_p and max_tokens
+	rt.handle("POST /api/generation-defaults", s.handleUpdateGenerationDefaults, user...)
+	rt.handle("GET /api/answer-style", s.handleAnswerStyle, user...) // Per-user answer language, tone and citation style
+	rt.handle("POST /api/answer-style", s.handleUpdateAnswerStyle, user...)
+	rt.handle("GET /api/cost-settings", s.handleCostSettings, user...) // Per-user cost confirmation limit and recent estimates
+	rt.handle("POST /api/cost-settings", s.handleUpdateCostSettings, user...)
+	rt.handle("GET /api/default-visibility", s.handleDefaultVisibility, user...) // Visibility of documents ingested without choosing one
+	rt.handle("POST /api/default-visibility", s.handleUpdateDefaultVisibility, user...)
+	rt.handle("POST /api/documents/visibility", s.handleDocumentsVisibility, user...)
+	rt.handle("GET /api/openapi.json", s.handleOpenAPISpec, user...) // OpenAPI 3 description of the API
+	rt.handle("GET /api/docs", s.handleAPIDocs, user...)             // Interactive API documentation
 	// Authentication routes
-	mux.HandleFunc("/api/login", s.handleLogin)
-	mux.HandleFunc("/api/logout", s.handleLogout)
-	mux.HandleFunc("/api/register", s.handleRegister)
-	mux.HandleFunc("/api/change-password", s.handleChangePassword)
-	// Admin user management routes
-	mux.HandleFunc("/api/users", func(w http.ResponseWriter, r *http.Request) {
-		if r.Method == http.MethodGet {
-			s.handleGetUsers(w, r)
-		} else if r.Method == http.MethodPost {
-			s.handleCreateUser(w, r)
-		} else {
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		}
-	})
-	mux.HandleFunc("/api/users/", func(w http.ResponseWriter, r *http.Request) {
-		// Handle /api/users/:id and /api/users/:id/reset-password
-		if strings.HasSuffix(r.URL.Path, "/reset-password") {
-			if r.Method == http.MethodPost {
-				s.handleResetUserPassword(w, r)
-			} else {
-				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-			}
-		} else {
-			if r.Method == http.MethodDelete {
-				s.handleDeleteUser(w, r)
-			} else {
-				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-			}
-		}
-	})
+	rt.handle("POST /api/login", s.handleLogin, public...)
+	rt.handle("POST /api/logout", s.handleLogout, sameOrigin)
+	rt.handle("POST /api/register", s.handleRegister, public...)
+	rt.handle("POST /api/change-password", s.handleChangePassword, user...)
+	// Admin routes
+	rt.handle("GET /api/admin/wire-log", s.handleWireLog, admin...)                     // Provider request log
+	rt.handle("GET /api/admin/transcripts", s.handleTranscripts, admin...)              // Archived cloud requests
+	rt.handle("GET /api/admin/transcripts/export", s.handleExportTranscripts, admin...) // Decrypted transcript export
+	rt.handle("GET /api/admin/embedding-pool", s.handleEmbeddingPool, admin...)         // Embedding worker health
+	rt.handle("GET /api/admin/provider-queue", s.handleProviderQueue, admin...)         // Answer queue load and wait times
+	rt.handle("GET /api/admin/skill-executor", s.handleSkillExecutor, admin...)         // Skill execution load and limits hit
+	rt.handle("GET /api/admin/extractors", s.handleExtractors, admin...)                // External text extractors and their types
+	rt.handle("GET /api/admin/perf", s.handleAdminPerf, admin...)                       // Latency percentiles per route
+	rt.handle("GET /api/admin/jobs", s.handleAdminJobs, admin...)                       // Background jobs and their last runs
+	rt.handle("POST /api/admin/jobs/{name}/run", s.handleRunJob, admin...)
+	rt.handle("POST /api/admin/jobs/{name}/pause", s.handlePauseJob, admin...)
+	rt.handle("POST /api/admin/jobs/{name}/resume", s.handleResumeJob, admin...)
+	rt.handle("GET /api/admin/telemetry", s.handleAdminTelemetry, admin...)   // Anonymous usage report preview and status
+	rt.handle("GET /api/admin/embeddings", s.handleAdminEmbeddings, admin...) // Embedding models and dimensions across the library
+	rt.handle("GET /api/admin/flags", s.handleAdminFlags, admin...)
+	rt.handle("PUT /api/admin/flags/{name}/users/{id}", s.handleSetFlagOverride, admin...)
+	rt.handle("DELETE /api/admin/flags/{name}/users/{id}", s.handleDeleteFlagOverride, admin...)
+	rt.handle("GET /api/admin/skills", s.handleGlobalSkills, admin...) // Skills provisioned for every account
+	rt.handle("POST /api/admin/skills", s.handleCreateGlobalSkill, admin...)
+	rt.handle("PUT /api/admin/skills/{id}", s.handleUpdateGlobalSkill, admin...)
+	rt.handle("DELETE /api/admin/skills/{id}", s.handleDeleteGlobalSkill, admin...)
+	rt.handle("GET /api/admin/search-dictionary", s.handleGetSearchDictionary, admin...) // Keyword search stop words, protected terms and synonyms
+	rt.handle("PUT /api/admin/search-dictionary", s.handleSetSearchDictionary, admin...)
+	rt.handle("GET /api/admin/replay", s.handleFailedRequests, admin...) // Failed provider requests kept for replay
+	rt.handle("POST /api/admin/replay/{id}", s.handleReplay, admin...)
+	rt.handle("GET /api/users", s.handleGetUsers, admin...)
+	rt.handle("POST /api/users", s.handleCreateUser, admin...)
+	rt.handle("POST /api/admin/users/import", s.handleImportUsers, admin...)   // Create accounts from a CSV
+	rt.handle("GET /api/admin/users/export", s.handleExportUsers, admin...)    // Every account as CSV
+	rt.handle("GET /api/admin/users/activity", s.handleUserActivity, admin...) // Last activity and resource usage per user
+	rt.handle("DELETE /api/users/{id}", s.handleDeleteUser, admin...)
+	rt.handle("POST /api/users/{id}/reset-password", s.handleResetUserPassword, admin...)
+	rt.handle("GET /api/users/{id}/lockout", s.handleGetUserLockout, admin...)      // Failed sign-ins and lockout state
+	rt.handle("DELETE /api/users/{id}/lockout", s.handleClearUserLockout, admin...) // Unlock an account
 	log.Printf("Registered: API routes")
 
 	// WebSocket
-	mux.HandleFunc("/ws", s.handleWebSocket)
+	rt.handle("GET /ws", s.handleWebSocket)
 	log.Printf("Registered: /ws")
 
 	// Authentication page routes (multi-user mode only, but registered always)
-	mux.HandleFunc("/login", s.handleLoginPage)
+	rt.handle("GET /login", s.handleLoginPage)
 	log.Printf("Registered: /login -> handleLoginPage")
 
-	mux.HandleFunc("/register", s.handleRegisterPage)
+	rt.handle("GET /register", s.handleRegisterPage)
 	log.Printf("Registered: /register -> handleRegisterPage")
 
-	mux.HandleFunc("/change-password", s.handleChangePasswordPage)
+	rt.handle("GET /change-password", s.handleChangePasswordPage)
 	log.Printf("Registered: /change-password -> handleChangePasswordPage")
 
-	// Page routes (register last, with exact path matching)
-	mux.HandleFunc("/settings", s.handleSettings)
+	// Page routes
+	rt.handle("GET /settings", s.handleSettings)
 	log.Printf("Registered: /settings -> handleSettings")
 
-	mux.HandleFunc("/library", s.handleLibrary)
+	rt.handle("GET /library", s.handleLibrary)
 	log.Printf("Registered: /library -> handleLibrary")
 
-	mux.HandleFunc("/chat", s.handleChat)
+	rt.handle("GET /chat", s.handleChat)
 	log.Printf("Registered: /chat -> handleChat")
 
-	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		// Only handle exact "/" path
-		if r.URL.Path != "/" {
-			http.NotFound(w, r)
-			return
-		}
-
+	rt.handle("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
 		// In multi-user mode, redirect to login if not authenticated
 		if s.config.UserMode == "multi" {
 			// Check if user is authenticated by looking for user_id in context
@@ -447,5 +1871,16 @@ func (s *Server) RegisterRoutes(mux *http.ServeMux) {
 		s.handleDashboard(w, r)
 	})
 	log.Printf("Registered: / -> handleDashboard (with user_mode routing)")
+
+	rt.methodNotAllowed()
+
+	// Routes and the OpenAPI spec are kept side by side; flag any drift
+	undocumented, unserved := specMismatches(rt.routes, apiOperations)
+	for _, pattern := range undocumented {
+		log.Printf("WARNING: route %s is missing from the OpenAPI spec", pattern)
+	}
+	for _, pattern := range unserved {
+		log.Printf("WARNING: OpenAPI operation %s has no route", pattern)
+	}
 	log.Printf("=== Route registration complete ===")
 }
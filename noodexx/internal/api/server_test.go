@@ -11,10 +11,8 @@ import (
 
 // Mock implementations for testing
 
-type mockStore struct{}
-
-func (m *mockStore) SaveChunk(ctx context.Context, source, text string, embedding []float32, tags []string, summary string) error {
-	return nil
+type mockStore struct {
+	baseMockStore
 }
 
 func (m *mockStore) Search(ctx context.Context, queryVec []float32, topK int) ([]Chunk, error) {
@@ -25,14 +23,6 @@ func (m *mockStore) Library(ctx context.Context) ([]LibraryEntry, error) {
 	return []LibraryEntry{}, nil
 }
 
-func (m *mockStore) DeleteSource(ctx context.Context, source string) error {
-	return nil
-}
-
-func (m *mockStore) SaveMessage(ctx context.Context, sessionID, role, content string) error {
-	return nil
-}
-
 func (m *mockStore) GetSessionHistory(ctx context.Context, sessionID string) ([]ChatMessage, error) {
 	return []ChatMessage{}, nil
 }
@@ -41,10 +31,6 @@ func (m *mockStore) ListSessions(ctx context.Context) ([]Session, error) {
 	return []Session{}, nil
 }
 
-func (m *mockStore) AddAuditEntry(ctx context.Context, opType, details, userCtx string) error {
-	return nil
-}
-
 func (m *mockStore) GetAuditLog(ctx context.Context, opType string, from, to time.Time) ([]AuditEntry, error) {
 	return []AuditEntry{}, nil
 }
@@ -57,10 +43,6 @@ func (m *mockStore) CreateUser(ctx context.Context, username, password, email st
 	return 1, nil
 }
 
-func (m *mockStore) UpdatePassword(ctx context.Context, userID int64, newPassword string) error {
-	return nil
-}
-
 func (m *mockStore) GetUserByID(ctx context.Context, userID int64) (*User, error) {
 	return &User{ID: userID, Username: "testuser"}, nil
 }
@@ -69,30 +51,14 @@ func (m *mockStore) ListUsers(ctx context.Context) ([]User, error) {
 	return []User{}, nil
 }
 
-func (m *mockStore) DeleteUser(ctx context.Context, userID int64) error {
-	return nil
-}
-
-func (m *mockStore) SearchByUser(ctx context.Context, userID int64, queryVec []float32, topK int) ([]Chunk, error) {
-	return []Chunk{}, nil
-}
-
 func (m *mockStore) LibraryByUser(ctx context.Context, userID int64) ([]LibraryEntry, error) {
 	return []LibraryEntry{}, nil
 }
 
-func (m *mockStore) SaveChatMessage(ctx context.Context, userID int64, sessionID, role, content, providerMode string) error {
-	return nil
-}
-
 func (m *mockStore) GetUserSessions(ctx context.Context, userID int64) ([]Session, error) {
 	return []Session{}, nil
 }
 
-func (m *mockStore) GetSessionOwner(ctx context.Context, sessionID string) (int64, error) {
-	return 0, nil
-}
-
 func (m *mockStore) GetSessionMessages(ctx context.Context, userID int64, sessionID string) ([]ChatMessage, error) {
 	return []ChatMessage{}, nil
 }
@@ -117,6 +83,10 @@ func (m *mockProvider) Stream(ctx context.Context, messages []Message, w io.Writ
 	return "test response", nil
 }
 
+func (m *mockProvider) StreamWithOptions(ctx context.Context, messages []Message, opts GenerationOptions, w io.Writer) (string, error) {
+	return m.Stream(ctx, messages, w)
+}
+
 func (m *mockProvider) Name() string {
 	return "mock"
 }
@@ -137,7 +107,7 @@ func (m *mockIngester) IngestURL(ctx context.Context, userID int64, url string,
 
 type mockSearcher struct{}
 
-func (m *mockSearcher) Search(ctx context.Context, queryVec []float32, topK int) ([]Chunk, error) {
+func (m *mockSearcher) Search(ctx context.Context, userID int64, queryVec []float32, opts SearchOptions, topK int) ([]Chunk, error) {
 	return []Chunk{}, nil
 }
 
@@ -203,7 +173,7 @@ func TestNewServer(t *testing.T) {
 	}
 
 	// Use the correct path from the test's perspective (running from noodexx directory)
-	srv, err := NewServerWithTemplatePath(store, provider, ingester, searcher, config, nil, nil, logger, &mockAuthProvider{}, "config.json", "../../web/templates/*.html", &mockProviderManager{}, &mockRAGEnforcer{})
+	srv, err := NewServerWithTemplatePath(store, provider, ingester, searcher, config, nil, nil, logger, &mockAuthProvider{}, "config.json", "../../web/templates/*.html", &mockProviderManager{}, &mockRAGEnforcer{}, nil)
 	if err != nil {
 		t.Fatalf("NewServer failed: %v", err)
 	}
@@ -248,7 +218,7 @@ func TestRegisterRoutes(t *testing.T) {
 		Provider:    "ollama",
 	}
 
-	srv, err := NewServerWithTemplatePath(store, provider, ingester, searcher, config, nil, nil, logger, &mockAuthProvider{}, "config.json", "../../web/templates/*.html", &mockProviderManager{}, &mockRAGEnforcer{})
+	srv, err := NewServerWithTemplatePath(store, provider, ingester, searcher, config, nil, nil, logger, &mockAuthProvider{}, "config.json", "../../web/templates/*.html", &mockProviderManager{}, &mockRAGEnforcer{}, nil)
 	if err != nil {
 		t.Fatalf("NewServer failed: %v", err)
 	}
@@ -272,8 +242,8 @@ func TestRegisterRoutes(t *testing.T) {
 		{"/api/ingest/text", "POST", http.StatusUnauthorized, "ingest text requires auth"},
 		{"/api/ingest/url", "POST", http.StatusUnauthorized, "ingest url requires auth"},
 		{"/api/sessions", "GET", http.StatusUnauthorized, "sessions endpoint requires auth"},
-		{"/api/config", "POST", http.StatusOK, "config endpoint should return 200"},
-		{"/api/activity", "GET", http.StatusOK, "activity endpoint should return 200"},
+		{"/api/config", "POST", http.StatusUnauthorized, "config endpoint requires auth"},
+		{"/api/activity", "GET", http.StatusUnauthorized, "activity endpoint requires auth"},
 		{"/api/login", "POST", http.StatusBadRequest, "login endpoint should return 400 for empty request"},
 		{"/api/register", "POST", http.StatusBadRequest, "register endpoint should return 400 for empty request"},
 	}
@@ -300,7 +270,7 @@ func TestStaticFileServing(t *testing.T) {
 		Provider:    "ollama",
 	}
 
-	srv, err := NewServerWithTemplatePath(store, provider, ingester, searcher, config, nil, nil, logger, &mockAuthProvider{}, "config.json", "../../web/templates/*.html", &mockProviderManager{}, &mockRAGEnforcer{})
+	srv, err := NewServerWithTemplatePath(store, provider, ingester, searcher, config, nil, nil, logger, &mockAuthProvider{}, "config.json", "../../web/templates/*.html", &mockProviderManager{}, &mockRAGEnforcer{}, nil)
 	if err != nil {
 		t.Fatalf("NewServer failed: %v", err)
 	}
@@ -0,0 +1,176 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// defaultHistoryMessages matches the guardrails.history_messages default
+const defaultHistoryMessages = 10
+
+// compactBatch bounds the sessions compacted per run so compaction does not
+// tie up the provider for long
+const compactBatch = 20
+
+// SessionSummary is a session's rolling summary of its older messages
+type SessionSummary struct {
+	SessionID        string
+	Summary          string
+	ThroughMessageID int64  // Last message the summary covers
+	Model            string // Model the summary was generated with
+	UpdatedAt        time.Time
+}
+
+// SessionToCompact is an idle session with messages its summary does not cover yet
+type SessionToCompact struct {
+	SessionID string
+	UserID    int64
+}
+
+// sessionHistory is what a prompt carries of a session's earlier messages
+type sessionHistory struct {
+	Summary  string    // Rolling summary of the messages before Messages
+	Messages []Message // Most recent messages, oldest first
+}
+
+// historyWindow returns how many recent messages of a session prompts include
+func (s *Server) historyWindow() int {
+	if s.historyMessages <= 0 {
+		return defaultHistoryMessages
+	}
+	return s.historyMessages
+}
+
+// uncoveredMessages returns the messages after those a rolling summary covers
+func uncoveredMessages(summary *SessionSummary, messages []ChatMessage) []ChatMessage {
+	if summary == nil {
+		return messages
+	}
+	for i, msg := range messages {
+		if msg.ID > summary.ThroughMessageID {
+			return messages[i:]
+		}
+	}
+	return nil
+}
+
+// sessionHistory returns the rolling summary and most recent messages of a
+// session for its next prompt. Messages between the two, left by a session
+// that has not been compacted yet, are not included
+func (s *Server) sessionHistory(ctx context.Context, userID int64, sessionID string) (*sessionHistory, error) {
+	summary, err := s.store.GetSessionSummary(ctx, userID, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	messages, err := s.store.GetSessionMessages(ctx, userID, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	recent := uncoveredMessages(summary, messages)
+	if window := s.historyWindow(); len(recent) > window {
+		recent = recent[len(recent)-window:]
+	}
+	history := &sessionHistory{}
+	if summary != nil {
+		history.Summary = summary.Summary
+	}
+	for _, msg := range recent {
+		history.Messages = append(history.Messages, Message{Role: msg.Role, Content: msg.Content})
+	}
+	return history, nil
+}
+
+// historyPrompt returns the system prompt extended with the session's rolling
+// summary, followed by its recent messages
+func historyPrompt(systemPrompt string, history *sessionHistory) []Message {
+	if history == nil {
+		return []Message{{Role: "system", Content: systemPrompt}}
+	}
+	if history.Summary != "" {
+		systemPrompt += "\n\nSummary of the earlier part of this chat:\n\n" + history.Summary
+	}
+	return append([]Message{{Role: "system", Content: systemPrompt}}, history.Messages...)
+}
+
+// CompactIdleSessions folds the older messages of sessions with no message for
+// the idle duration into their rolling summaries, keeping the history window
+// of recent messages out of the summary. The messages themselves are kept
+func (s *Server) CompactIdleSessions(ctx context.Context, idle time.Duration) error {
+	// Sessions may quote library content, so they go to the provider only when
+	// the RAG policy allows library content to
+	if !s.ragEnforcer.ShouldPerformRAG() {
+		return nil
+	}
+	provider, err := s.providerManager.GetActiveProvider()
+	if err != nil {
+		return fmt.Errorf("failed to get active provider: %w", err)
+	}
+
+	keep := s.historyWindow()
+	sessions, err := s.store.GetSessionsToCompact(ctx, time.Now().Add(-idle), keep)
+	if err != nil {
+		return err
+	}
+	compacted := 0
+	for _, session := range sessions {
+		if compacted == compactBatch {
+			break
+		}
+		if err := s.compactSession(ctx, provider, session.UserID, session.SessionID, keep); err != nil {
+			s.logger.Warn("failed to compact session", "session_id", session.SessionID, "error", err.Error())
+		}
+		compacted++
+	}
+	if compacted > 0 {
+		s.logger.Debug("compacted idle sessions", "sessions", compacted)
+	}
+	return nil
+}
+
+// compactSession extends a session's rolling summary with its oldest messages
+// outside the history window that fit in one model call; later runs fold in
+// the rest
+func (s *Server) compactSession(ctx context.Context, provider LLMProvider, userID int64, sessionID string, keep int) error {
+	summary, err := s.store.GetSessionSummary(ctx, userID, sessionID)
+	if err != nil {
+		return err
+	}
+	messages, err := s.store.GetSessionMessages(ctx, userID, sessionID)
+	if err != nil {
+		return err
+	}
+	pending := uncoveredMessages(summary, messages)
+	if len(pending) <= keep {
+		return nil
+	}
+	pending = pending[:len(pending)-keep]
+
+	previous := ""
+	if summary != nil {
+		previous = summary.Summary
+	}
+	budget := s.docQABudgetTokens()
+	tokens := estimateTokens(previous)
+	n := 0
+	for n < len(pending) {
+		t := estimateTokens(fmt.Sprintf("%s: %s", pending[n].Role, pending[n].Content))
+		if n > 0 && tokens+t > budget {
+			break
+		}
+		tokens += t
+		n++
+	}
+
+	text, err := s.summarizeSession(ctx, provider, previous, pending[:n])
+	if err != nil {
+		return err
+	}
+	return s.store.SaveSessionSummary(ctx, userID, &SessionSummary{
+		SessionID:        sessionID,
+		Summary:          text,
+		ThroughMessageID: pending[n-1].ID,
+		Model:            s.activeModel(),
+	})
+}
@@ -0,0 +1,100 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+// sessionHistoryStore holds one session's messages and rolling summary
+type sessionHistoryStore struct {
+	*mockStoreForAsk
+	messages []ChatMessage
+	summary  *SessionSummary
+}
+
+func (m *sessionHistoryStore) GetSessionMessages(ctx context.Context, userID int64, sessionID string) ([]ChatMessage, error) {
+	return m.messages, nil
+}
+
+func (m *sessionHistoryStore) GetSessionSummary(ctx context.Context, userID int64, sessionID string) (*SessionSummary, error) {
+	return m.summary, nil
+}
+
+func (m *sessionHistoryStore) SaveSessionSummary(ctx context.Context, userID int64, summary *SessionSummary) error {
+	m.summary = summary
+	return nil
+}
+
+func (m *sessionHistoryStore) GetSessionsToCompact(ctx context.Context, idleBefore time.Time, keep int) ([]SessionToCompact, error) {
+	if len(uncoveredMessages(m.summary, m.messages)) <= keep {
+		return nil, nil
+	}
+	return []SessionToCompact{{SessionID: "long-session", UserID: 1}}, nil
+}
+
+// TestCompactIdleSessions tests that older messages are folded into the rolling
+// summary and prompts carry it with the recent messages only
+func TestCompactIdleSessions(t *testing.T) {
+	store := &sessionHistoryStore{mockStoreForAsk: &mockStoreForAsk{}}
+	for i := 1; i <= 6; i++ {
+		store.messages = append(store.messages, ChatMessage{ID: int64(i), Role: "user", Content: fmt.Sprintf("message %d", i)})
+	}
+
+	var summarized []string
+	provider := &mockProviderForAsk{
+		streamFunc: func(ctx context.Context, messages []Message, w io.Writer) (string, error) {
+			prompt := messages[len(messages)-1].Content
+			summarized = append(summarized, prompt)
+			return fmt.Sprintf("summary %d", len(summarized)), nil
+		},
+	}
+	srv := &Server{
+		store:           store,
+		logger:          &mockLoggerForAsk{},
+		providerManager: &mockProviderManagerForAsk{provider: provider},
+		ragEnforcer:     &mockRAGEnforcerForAsk{shouldPerformRAG: true},
+		historyMessages: 2,
+	}
+
+	if err := srv.CompactIdleSessions(context.Background(), 30*time.Minute); err != nil {
+		t.Fatalf("CompactIdleSessions failed: %v", err)
+	}
+	if store.summary == nil || store.summary.ThroughMessageID != 4 || store.summary.Summary != "summary 1" {
+		t.Fatalf("Expected messages 1-4 to be summarized, got %+v", store.summary)
+	}
+	if strings.Contains(summarized[0], "message 5") {
+		t.Errorf("Expected the history window to be left out of the summary, got %q", summarized[0])
+	}
+
+	history, err := srv.sessionHistory(context.Background(), 1, "long-session")
+	if err != nil {
+		t.Fatalf("sessionHistory failed: %v", err)
+	}
+	messages := historyPrompt("You are a helpful assistant.", history)
+	if len(messages) != 3 || !strings.Contains(messages[0].Content, "summary 1") || messages[1].Content != "message 5" {
+		t.Errorf("Expected the summary and the 2 recent messages, got %+v", messages)
+	}
+
+	// A later compaction extends the summary
+	store.messages = append(store.messages, ChatMessage{ID: 7, Role: "assistant", Content: "message 7"})
+	if err := srv.CompactIdleSessions(context.Background(), 30*time.Minute); err != nil {
+		t.Fatalf("CompactIdleSessions failed: %v", err)
+	}
+	if store.summary.ThroughMessageID != 5 || !strings.Contains(summarized[1], "summary 1") {
+		t.Errorf("Expected the rolling summary to be extended, got %+v from %q", store.summary, summarized[1])
+	}
+
+	// Sessions are not sent to the provider when the RAG policy disallows it
+	store.messages = append(store.messages, ChatMessage{ID: 8, Role: "user", Content: "message 8"})
+	srv.ragEnforcer = &mockRAGEnforcerForAsk{shouldPerformRAG: false}
+	if err := srv.CompactIdleSessions(context.Background(), 30*time.Minute); err != nil {
+		t.Fatalf("CompactIdleSessions failed: %v", err)
+	}
+	if len(summarized) != 2 {
+		t.Errorf("Expected no compaction under a no-RAG policy, got %d summaries", len(summarized))
+	}
+}
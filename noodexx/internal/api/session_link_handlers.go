@@ -0,0 +1,198 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"noodexx/internal/auth"
+	"strings"
+	"time"
+)
+
+// SessionLink records that a session continues an earlier one
+type SessionLink struct {
+	SessionID     string    `json:"session_id"`
+	ContinuedFrom string    `json:"continued_from"`
+	Summary       string    `json:"summary"`
+	Model         string    `json:"model,omitempty"` // Model the summary was generated with
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// sessionSummaryPrompt asks for a compact summary of an earlier conversation
+const sessionSummaryPrompt = "Summarize the conversation below so it can be continued in a new chat. " +
+	"In a few short paragraphs or bullet points, keep the questions asked, the answers and conclusions reached, " +
+	"decisions, open questions, and any names, numbers and sources mentioned. Reply with only the summary.\n\n"
+
+// handleContinueSession handles POST /api/session/{id}/continue - start a new
+// session that continues session {id}. A compact summary of the earlier
+// session is generated, stored with the link and included in the new
+// session's prompts
+func (s *Server) handleContinueSession(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	requestID := newRequestID(w)
+
+	logger := s.logger.WithContext("request_id", requestID).
+		WithContext("method", r.Method).
+		WithContext("path", r.URL.Path)
+
+	logger.Debug("processing continue session request")
+
+	ctx := r.Context()
+
+	userID, err := auth.GetUserID(ctx)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
+		return
+	}
+
+	sessionID := r.PathValue("id")
+	if owner, err := s.store.GetSessionOwner(ctx, sessionID); err != nil || owner != userID {
+		writeError(w, http.StatusNotFound, CodeNotFound, "Session not found")
+		return
+	}
+
+	// The earlier conversation may quote library content, so it goes to the
+	// provider only when the RAG policy allows library content to
+	if !s.ragEnforcer.ShouldPerformRAG() {
+		writeError(w, http.StatusForbidden, CodeForbidden, "Continuing a session is not allowed by the RAG policy of the current provider")
+		return
+	}
+
+	messages, err := s.store.GetSessionMessages(ctx, userID, sessionID)
+	if err != nil {
+		logger.Error("request failed", "operation", "get_session_messages", "session_id", sessionID, "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to get session history")
+		return
+	}
+	if len(messages) == 0 {
+		writeError(w, http.StatusBadRequest, CodeBadRequest, "Session has no messages to continue from")
+		return
+	}
+
+	provider, err := s.providerManager.GetActiveProvider()
+	if err != nil {
+		logger.Error("request failed", "operation", "get_active_provider", "error", err.Error())
+		writeError(w, http.StatusBadRequest, CodeProviderUnavailable, "Provider not configured. Please configure the AI provider in Settings.")
+		return
+	}
+
+	// A compacted session is summarized from its rolling summary and the
+	// messages after it
+	rolling, err := s.store.GetSessionSummary(ctx, userID, sessionID)
+	if err != nil {
+		logger.Warn("failed to get session summary", "session_id", sessionID, "error", err.Error())
+	}
+	previous := ""
+	if rolling != nil {
+		previous = rolling.Summary
+	}
+
+	summary, err := s.summarizeSession(ctx, provider, previous, uncoveredMessages(rolling, messages))
+	if err != nil {
+		logger.Error("request failed", "operation", "summarize_session", "session_id", sessionID, "error", err.Error())
+		writeError(w, http.StatusBadGateway, CodeUpstreamFailed, "Failed to summarize session")
+		return
+	}
+
+	link := &SessionLink{
+		SessionID:     generateSessionID(),
+		ContinuedFrom: sessionID,
+		Summary:       summary,
+		Model:         s.activeModel(),
+		CreatedAt:     time.Now(),
+	}
+	if err := s.store.CreateSessionLink(ctx, userID, link); err != nil {
+		logger.Error("request failed", "operation", "create_session_link", "session_id", sessionID, "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to start session")
+		return
+	}
+	s.store.AddAuditEntry(ctx, "continue_session", fmt.Sprintf("Continued from session %s", sessionID), link.SessionID)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"link":    link,
+	})
+
+	latency := time.Since(start).Milliseconds()
+	logger.Debug("request completed", "status", http.StatusCreated, "latency_ms", latency, "session_id", link.SessionID, "continued_from", sessionID)
+}
+
+// handleGetSessionLink handles GET /api/session/{id}/link - the earlier session
+// a session continues and the summary its prompts include
+func (s *Server) handleGetSessionLink(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID, err := auth.GetUserID(ctx)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
+		return
+	}
+
+	link, err := s.store.GetSessionLink(ctx, userID, r.PathValue("id"))
+	if err != nil {
+		s.logger.Error("request failed", "operation", "get_session_link", "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to get session link")
+		return
+	}
+	if link == nil {
+		writeError(w, http.StatusNotFound, CodeNotFound, "Session does not continue another session")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"link":    link,
+	})
+}
+
+// summarizeSession generates a compact summary of a conversation, extending
+// previous, a summary of the messages before it, when there is one. Only the
+// most recent messages that fit in the document question budget are summarized
+func (s *Server) summarizeSession(ctx context.Context, provider LLMProvider, previous string, messages []ChatMessage) (string, error) {
+	budget := s.docQABudgetTokens()
+	var turns []string
+	tokens := estimateTokens(previous)
+	for i := len(messages) - 1; i >= 0; i-- {
+		turn := fmt.Sprintf("%s: %s", messages[i].Role, messages[i].Content)
+		n := estimateTokens(turn)
+		if len(turns) > 0 && tokens+n > budget {
+			break
+		}
+		turns = append([]string{turn}, turns...)
+		tokens += n
+	}
+
+	var buf bytes.Buffer
+	summary, err := provider.Stream(ctx, []Message{
+		{Role: "system", Content: "You are a careful research assistant."},
+		{Role: "user", Content: sessionSummaryPrompt + previousSummaryText(previous) + strings.Join(turns, "\n\n")},
+	}, &buf)
+	if err != nil {
+		return "", err
+	}
+	summary = strings.TrimSpace(summary)
+	if summary == "" {
+		return "", fmt.Errorf("provider returned an empty summary")
+	}
+	return summary, nil
+}
+
+// continuedSessionPrompt returns the system prompt for a session that continues
+// an earlier one, carrying the earlier session's summary
+func continuedSessionPrompt(link *SessionLink) string {
+	return "You are a helpful assistant. This chat continues an earlier conversation with the same user. " +
+		"Use this summary of it when the question refers back to it:\n\n" + link.Summary
+}
+
+// previousSummaryText introduces the summary of the messages before those being summarized
+func previousSummaryText(previous string) string {
+	if previous == "" {
+		return ""
+	}
+	return "Summary of the conversation before these messages:\n" + previous + "\n\nLater messages:\n\n"
+}
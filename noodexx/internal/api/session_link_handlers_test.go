@@ -0,0 +1,107 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"noodexx/internal/auth"
+	"strings"
+	"testing"
+)
+
+// sessionLinkStore holds one user's session and the links created from it
+type sessionLinkStore struct {
+	*mockStoreForAsk
+	links map[string]*SessionLink
+}
+
+func (m *sessionLinkStore) GetSessionOwner(ctx context.Context, sessionID string) (int64, error) {
+	return 1, nil
+}
+
+func (m *sessionLinkStore) GetSessionMessages(ctx context.Context, userID int64, sessionID string) ([]ChatMessage, error) {
+	if sessionID != "old-session" {
+		return nil, nil
+	}
+	return []ChatMessage{
+		{Role: "user", Content: "What did the audit find?"},
+		{Role: "assistant", Content: "Two late invoices."},
+	}, nil
+}
+
+func (m *sessionLinkStore) CreateSessionLink(ctx context.Context, userID int64, link *SessionLink) error {
+	m.links[link.SessionID] = link
+	return nil
+}
+
+func (m *sessionLinkStore) GetSessionLink(ctx context.Context, userID int64, sessionID string) (*SessionLink, error) {
+	return m.links[sessionID], nil
+}
+
+func continueSessionRequest(id string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/api/session/"+id+"/continue", nil)
+	req.SetPathValue("id", id)
+	return req.WithContext(context.WithValue(req.Context(), auth.UserIDKey, int64(1)))
+}
+
+// TestHandleContinueSession tests that continuing a session stores a summary
+// of it under a new session, and that the RAG policy gates it
+func TestHandleContinueSession(t *testing.T) {
+	var summarized string
+	provider := &mockProviderForAsk{
+		streamFunc: func(ctx context.Context, messages []Message, w io.Writer) (string, error) {
+			summarized = messages[len(messages)-1].Content
+			return "  The audit found two late invoices.  ", nil
+		},
+	}
+	store := &sessionLinkStore{mockStoreForAsk: &mockStoreForAsk{}, links: map[string]*SessionLink{}}
+	srv := &Server{
+		store:           store,
+		logger:          &mockLoggerForAsk{},
+		providerManager: &mockProviderManagerForAsk{provider: provider},
+		ragEnforcer:     &mockRAGEnforcerForAsk{shouldPerformRAG: true},
+	}
+
+	w := httptest.NewRecorder()
+	srv.handleContinueSession(w, continueSessionRequest("old-session"))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Link SessionLink `json:"link"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if resp.Link.SessionID == "" || resp.Link.SessionID == "old-session" || resp.Link.ContinuedFrom != "old-session" {
+		t.Errorf("Expected a new session continuing old-session, got %+v", resp.Link)
+	}
+	if resp.Link.Summary != "The audit found two late invoices." {
+		t.Errorf("Expected the trimmed summary, got %q", resp.Link.Summary)
+	}
+	if !strings.Contains(summarized, "Two late invoices.") {
+		t.Errorf("Expected the earlier messages to be summarized, got %q", summarized)
+	}
+	if store.links[resp.Link.SessionID] == nil {
+		t.Error("Expected the link to be stored")
+	}
+
+	if prompt := continuedSessionPrompt(&resp.Link); !strings.Contains(prompt, resp.Link.Summary) {
+		t.Errorf("Expected the prompt to include the summary, got %q", prompt)
+	}
+
+	w = httptest.NewRecorder()
+	srv.handleContinueSession(w, continueSessionRequest("empty-session"))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a session with no messages, got %d", w.Code)
+	}
+
+	srv.ragEnforcer = &mockRAGEnforcerForAsk{shouldPerformRAG: false}
+	w = httptest.NewRecorder()
+	srv.handleContinueSession(w, continueSessionRequest("old-session"))
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 when the RAG policy disallows it, got %d", w.Code)
+	}
+}
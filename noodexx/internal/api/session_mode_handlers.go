@@ -0,0 +1,165 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"noodexx/internal/auth"
+	"noodexx/internal/validate"
+	"time"
+)
+
+// globalMode returns the active provider mode, "local" or "cloud"
+func (s *Server) globalMode() string {
+	if s.providerManager.IsLocalMode() {
+		return "local"
+	}
+	return "cloud"
+}
+
+// sessionModeDiffers reports whether a session's provider mode is set and is
+// not the active one
+func (s *Server) sessionModeDiffers(sessionMode string) bool {
+	return sessionMode != "" && sessionMode != s.globalMode()
+}
+
+// sessionAskMode returns the mode a question in a session is answered in: the
+// one a /mode command switched the message to, else the session's own mode
+// even when the global mode has changed since, else the active one. On
+// failure it returns the HTTP status to respond with
+func (s *Server) sessionAskMode(ctx context.Context, commandMode, sessionMode string) (*askMode, int, error) {
+	if commandMode != "" || !s.sessionModeDiffers(sessionMode) {
+		return s.resolveAskMode(ctx, commandMode)
+	}
+	m, err := s.providerMode(sessionMode == "local")
+	if err != nil {
+		return nil, http.StatusBadRequest, fmt.Errorf("%w; change the session's mode to continue it", err)
+	}
+	return m, http.StatusOK, nil
+}
+
+// handleSessionMode handles GET /api/session/{id}/mode
+// Returns the provider mode the session is answered in. A session takes the
+// global mode with its first question and keeps it when the global mode changes
+func (s *Server) handleSessionMode(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	requestID := newRequestID(w)
+
+	// Create logger with request context
+	logger := s.logger.WithContext("request_id", requestID).
+		WithContext("method", r.Method).
+		WithContext("path", r.URL.Path)
+
+	logger.Debug("processing session mode request")
+
+	ctx := r.Context()
+
+	// Extract user_id from context
+	userID, err := auth.GetUserID(ctx)
+	if err != nil {
+		logger.Error("request failed", "operation", "get_user_id", "error", err.Error())
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
+		return
+	}
+
+	sessionID := r.PathValue("id")
+	if owner, err := s.store.GetSessionOwner(ctx, sessionID); err != nil || owner != userID {
+		writeError(w, http.StatusNotFound, CodeNotFound, "Session not found")
+		return
+	}
+
+	mode, err := s.store.GetSessionMode(ctx, userID, sessionID)
+	if err != nil {
+		logger.Error("request failed", "operation", "get_session_mode", "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to get session mode")
+		return
+	}
+	// Sessions from before modes were recorded take the global one next
+	inherited := mode == ""
+	if inherited {
+		mode = s.globalMode()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":     true,
+		"session_id":  sessionID,
+		"mode":        mode,
+		"inherited":   inherited,
+		"global_mode": s.globalMode(),
+	})
+
+	latency := time.Since(start).Milliseconds()
+	logger.Debug("request completed", "status", http.StatusOK, "latency_ms", latency)
+}
+
+// handleUpdateSessionMode handles PUT /api/session/{id}/mode
+// Changes the provider mode the session is answered in
+func (s *Server) handleUpdateSessionMode(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	requestID := newRequestID(w)
+
+	// Create logger with request context
+	logger := s.logger.WithContext("request_id", requestID).
+		WithContext("method", r.Method).
+		WithContext("path", r.URL.Path)
+
+	logger.Debug("processing update session mode request")
+
+	ctx := r.Context()
+
+	// Extract user_id from context
+	userID, err := auth.GetUserID(ctx)
+	if err != nil {
+		logger.Error("request failed", "operation", "get_user_id", "error", err.Error())
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
+		return
+	}
+
+	sessionID := r.PathValue("id")
+	if owner, err := s.store.GetSessionOwner(ctx, sessionID); err != nil || owner != userID {
+		writeError(w, http.StatusNotFound, CodeNotFound, "Session not found")
+		return
+	}
+
+	var req struct {
+		Mode string `json:"mode"`
+	}
+	if !decodeJSON(w, r, logger, &req) {
+		return
+	}
+
+	v := validate.New()
+	v.Required("mode", "Mode", req.Mode)
+	v.Check("mode", validate.OneOf("Mode", req.Mode, "local", "cloud"))
+	if err := v.Err(); err != nil {
+		writeValidationError(w, logger, err)
+		return
+	}
+
+	if _, err := s.providerMode(req.Mode == "local"); err != nil {
+		writeError(w, http.StatusBadRequest, CodeProviderUnavailable, err.Error())
+		return
+	}
+
+	if err := s.store.SetSessionMode(ctx, userID, sessionID, req.Mode); err != nil {
+		logger.Error("request failed", "operation", "set_session_mode", "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to save session mode")
+		return
+	}
+
+	// Audit log
+	s.store.AddAuditEntry(ctx, "config", fmt.Sprintf("Session %s mode set to %s", sessionID, req.Mode), "")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":     true,
+		"session_id":  sessionID,
+		"mode":        req.Mode,
+		"global_mode": s.globalMode(),
+	})
+
+	latency := time.Since(start).Milliseconds()
+	logger.Debug("request completed", "status", http.StatusOK, "latency_ms", latency, "mode", req.Mode)
+}
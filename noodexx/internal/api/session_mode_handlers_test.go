@@ -0,0 +1,156 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"noodexx/internal/auth"
+	"strings"
+	"testing"
+)
+
+// sessionModeStore keeps the sessions questions create and their modes
+type sessionModeStore struct {
+	mockStoreForAsk
+	owners map[string]int64
+	modes  map[string]string
+}
+
+func (m *sessionModeStore) GetSessionOwner(ctx context.Context, sessionID string) (int64, error) {
+	return m.owners[sessionID], nil
+}
+
+func (m *sessionModeStore) SaveChatMessage(ctx context.Context, userID int64, sessionID, role, content, providerMode string) error {
+	if _, ok := m.owners[sessionID]; !ok {
+		m.owners[sessionID] = userID
+	}
+	return nil
+}
+
+func (m *sessionModeStore) GetSessionMode(ctx context.Context, userID int64, sessionID string) (string, error) {
+	return m.modes[sessionID], nil
+}
+
+func (m *sessionModeStore) SetSessionMode(ctx context.Context, userID int64, sessionID, mode string) error {
+	m.modes[sessionID] = mode
+	return nil
+}
+
+// dualProviderManager has both providers and a global mode that can be toggled
+type dualProviderManager struct {
+	mockProviderManagerForAsk
+	local, cloud LLMProvider
+	localMode    bool
+}
+
+func (m *dualProviderManager) GetActiveProvider() (LLMProvider, error) {
+	if m.localMode {
+		return m.local, nil
+	}
+	return m.cloud, nil
+}
+
+func (m *dualProviderManager) GetLocalProvider() LLMProvider { return m.local }
+func (m *dualProviderManager) GetCloudProvider() LLMProvider { return m.cloud }
+func (m *dualProviderManager) IsLocalMode() bool             { return m.localMode }
+
+func (m *dualProviderManager) GetProviderName() string {
+	if m.localMode {
+		return "Local AI (ollama)"
+	}
+	return "Cloud AI (openai)"
+}
+
+func TestSessionModeStickiness(t *testing.T) {
+	store := &sessionModeStore{owners: map[string]int64{"other": 2}, modes: map[string]string{}}
+	manager := &dualProviderManager{
+		local:     &mockProviderForAsk{name: "ollama", isLocal: true},
+		cloud:     &mockProviderForAsk{name: "openai", isLocal: false},
+		localMode: true,
+	}
+	server := &Server{
+		store:           store,
+		logger:          &mockLoggerForAsk{},
+		providerManager: manager,
+		ragEnforcer:     &mockRAGEnforcerForAsk{shouldPerformRAG: true, ragStatus: "RAG Enabled (Local)"},
+	}
+	ask := func(query string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(map[string]interface{}{"query": query, "session_id": "s1"})
+		req := httptest.NewRequest(http.MethodPost, "/api/ask", bytes.NewReader(body))
+		req = req.WithContext(context.WithValue(req.Context(), auth.UserIDKey, int64(1)))
+		w := httptest.NewRecorder()
+		server.handleAsk(w, req)
+		return w
+	}
+	answeredBy := func(w *httptest.ResponseRecorder) string {
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d %s", w.Code, w.Body.String())
+		}
+		return w.Header().Get("X-Provider-Name")
+	}
+	mode := func(method, id, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/api/session/"+id+"/mode", strings.NewReader(body))
+		return serveRoute(server, withUser(req, 1))
+	}
+
+	// A new session takes the global mode and keeps it when that changes
+	if got := answeredBy(ask("Hi")); !strings.HasPrefix(got, "Local AI") {
+		t.Errorf("Expected a local answer, got %q", got)
+	}
+	if store.modes["s1"] != "local" {
+		t.Fatalf("Expected the session pinned to local, got %q", store.modes["s1"])
+	}
+	manager.localMode = false
+	if got := answeredBy(ask("And now?")); !strings.HasPrefix(got, "Local AI") {
+		t.Errorf("Expected the session to stay local, got %q", got)
+	}
+
+	// /mode switches one message without changing the session
+	if got := answeredBy(ask("/mode cloud Just this once")); !strings.HasPrefix(got, "Cloud AI") {
+		t.Errorf("Expected a cloud answer, got %q", got)
+	}
+	if store.modes["s1"] != "local" {
+		t.Errorf("Expected the session to stay local, got %q", store.modes["s1"])
+	}
+
+	var resp struct {
+		Mode       string `json:"mode"`
+		GlobalMode string `json:"global_mode"`
+	}
+	w := mode(http.MethodGet, "s1", "")
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if w.Code != http.StatusOK || resp.Mode != "local" || resp.GlobalMode != "cloud" {
+		t.Errorf("Unexpected session mode: %d %s", w.Code, w.Body.String())
+	}
+
+	// Changing the session's mode moves the conversation
+	if w := mode(http.MethodPut, "s1", `{"mode":"cloud"}`); w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d %s", w.Code, w.Body.String())
+	}
+	manager.localMode = true
+	if got := answeredBy(ask("Still there?")); !strings.HasPrefix(got, "Cloud AI") {
+		t.Errorf("Expected the session to move to the cloud, got %q", got)
+	}
+
+	// A session whose provider is gone is not silently moved
+	manager.cloud = nil
+	if w := ask("Hello?"); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 without the session's provider, got %d", w.Code)
+	}
+
+	for _, tt := range []struct {
+		id, body string
+		want     int
+	}{
+		{"s1", `{"mode":"hybrid"}`, http.StatusBadRequest},
+		{"s1", `{"mode":"cloud"}`, http.StatusBadRequest}, // Not configured
+		{"other", `{"mode":"local"}`, http.StatusNotFound},
+		{"missing", `{"mode":"local"}`, http.StatusNotFound},
+	} {
+		if w := mode(http.MethodPut, tt.id, tt.body); w.Code != tt.want {
+			t.Errorf("%s %s: expected %d, got %d", tt.id, tt.body, tt.want, w.Code)
+		}
+	}
+}
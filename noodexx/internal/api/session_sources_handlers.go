@@ -0,0 +1,206 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"noodexx/internal/auth"
+	"sort"
+	"strings"
+	"time"
+)
+
+// CitedSource is a library source the answers of a session cited
+type CitedSource struct {
+	Source      string    `json:"source"`
+	Citations   int       `json:"citations"` // Answers that cited it
+	LastCitedAt time.Time `json:"last_cited_at"`
+}
+
+// sessionCitedSources returns the library sources cited by a session's
+// answers, most cited first. Web results and attachments are left out since
+// they cannot be searched again
+func (s *Server) sessionCitedSources(ctx context.Context, userID int64, sessionID string) ([]CitedSource, error) {
+	messages, err := s.store.GetSessionMessages(ctx, userID, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	bySource := make(map[string]*CitedSource)
+	var cited []*CitedSource
+	for _, msg := range messages {
+		if msg.Role != "assistant" {
+			continue
+		}
+		seen := make(map[string]bool)
+		for _, source := range msg.Citations {
+			if source == "" || seen[source] || strings.HasPrefix(source, webSourcePrefix) || strings.HasPrefix(source, attachmentSourcePrefix) {
+				continue
+			}
+			seen[source] = true
+			c := bySource[source]
+			if c == nil {
+				c = &CitedSource{Source: source}
+				bySource[source] = c
+				cited = append(cited, c)
+			}
+			c.Citations++
+			if msg.CreatedAt.After(c.LastCitedAt) {
+				c.LastCitedAt = msg.CreatedAt
+			}
+		}
+	}
+
+	sources := make([]CitedSource, len(cited))
+	for i, c := range cited {
+		sources[i] = *c
+	}
+	sort.SliceStable(sources, func(i, j int) bool {
+		if sources[i].Citations != sources[j].Citations {
+			return sources[i].Citations > sources[j].Citations
+		}
+		return sources[i].LastCitedAt.After(sources[j].LastCitedAt)
+	})
+	return sources, nil
+}
+
+// ownSession writes an error and returns false unless the session exists and
+// belongs to the user
+func (s *Server) ownSession(ctx context.Context, w http.ResponseWriter, logger Logger, userID int64, sessionID string) bool {
+	owner, err := s.store.GetSessionOwner(ctx, sessionID)
+	if err != nil || owner == 0 {
+		writeError(w, http.StatusNotFound, CodeNotFound, "Session not found")
+		return false
+	}
+	if owner != userID {
+		logger.Error("request failed", "operation", "verify_session_owner", "error", "unauthorized access to session")
+		writeError(w, http.StatusForbidden, CodeForbidden, "Forbidden: session belongs to another user")
+		return false
+	}
+	return true
+}
+
+// handleSessionSources handles GET /api/session/{id}/sources - the library
+// sources the session's answers cited, most cited first
+func (s *Server) handleSessionSources(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	requestID := newRequestID(w)
+
+	logger := s.logger.WithContext("request_id", requestID).
+		WithContext("method", r.Method).
+		WithContext("path", r.URL.Path)
+
+	logger.Debug("processing session sources request")
+
+	ctx := r.Context()
+
+	userID, err := auth.GetUserID(ctx)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
+		return
+	}
+
+	sessionID := r.PathValue("id")
+	if !s.ownSession(ctx, w, logger, userID, sessionID) {
+		return
+	}
+
+	sources, err := s.sessionCitedSources(ctx, userID, sessionID)
+	if err != nil {
+		logger.Error("request failed", "operation", "get_cited_sources", "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to get the session's sources")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"sources": sources,
+	})
+
+	latency := time.Since(start).Milliseconds()
+	logger.Debug("request completed", "status", http.StatusOK, "latency_ms", latency, "sources", len(sources))
+}
+
+// handleSessionSearch handles GET /api/session/{id}/search?q=...&limit=... -
+// search like /api/search, but only the library sources the session cited
+func (s *Server) handleSessionSearch(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	requestID := newRequestID(w)
+
+	logger := s.logger.WithContext("request_id", requestID).
+		WithContext("method", r.Method).
+		WithContext("path", r.URL.Path)
+
+	logger.Debug("processing session search request")
+
+	ctx := r.Context()
+
+	userID, err := auth.GetUserID(ctx)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
+		return
+	}
+
+	q, limit, mode, err := searchParams(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeBadRequest, err.Error())
+		return
+	}
+	sessionID := r.PathValue("id")
+	if !s.ownSession(ctx, w, logger, userID, sessionID) {
+		return
+	}
+
+	cited, err := s.sessionCitedSources(ctx, userID, sessionID)
+	if err != nil {
+		logger.Error("request failed", "operation", "get_cited_sources", "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to get the session's sources")
+		return
+	}
+	sources := make([]string, len(cited))
+	for i, c := range cited {
+		sources[i] = c.Source
+	}
+
+	results := []SearchResult{}
+	if len(sources) > 0 {
+		var provider LLMProvider
+		var queryVec []float32
+		if s.embedsQuery(mode) {
+			provider, err = s.providerManager.GetActiveProvider()
+			if err != nil {
+				logger.Error("request failed", "operation", "get_active_provider", "error", err.Error())
+				writeError(w, http.StatusBadRequest, CodeProviderUnavailable, "Provider not configured. Please configure the AI provider in Settings.")
+				return
+			}
+			queryVec, err = provider.Embed(ctx, q)
+			if err != nil {
+				logger.Error("request failed", "operation", "embed_query", "error", err.Error())
+				writeError(w, http.StatusInternalServerError, CodeInternal, "Embedding failed")
+				return
+			}
+		}
+		chunks, err := s.store.SearchLibrary(ctx, userID, queryVec, SearchOptions{Query: q, QueryModel: s.activeEmbedModel(), Sources: sources, Mode: mode}, limit)
+		if err != nil {
+			logger.Error("request failed", "operation", "search_chunks", "error", err.Error())
+			writeError(w, http.StatusInternalServerError, CodeInternal, "Search failed")
+			return
+		}
+		semantic := provider != nil && s.ragEnforcer.ShouldPerformRAG()
+		for _, chunk := range chunks {
+			results = append(results, highlightResult(ctx, provider, chunk, q, queryVec, semantic))
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"query":   q,
+		"sources": sources,
+		"results": results,
+	})
+
+	latency := time.Since(start).Milliseconds()
+	logger.Debug("request completed", "status", http.StatusOK, "latency_ms", latency, "sources", len(sources), "results", len(results))
+}
@@ -0,0 +1,107 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"noodexx/internal/auth"
+	"testing"
+	"time"
+)
+
+// sessionSourcesStore has session s1 of user 1, whose answers cited library
+// documents, a web page and an attachment, and records the sources searched
+type sessionSourcesStore struct {
+	mockStoreForAsk
+	searched     []string
+	searchedUser bool
+}
+
+func (m *sessionSourcesStore) GetSessionOwner(ctx context.Context, sessionID string) (int64, error) {
+	switch sessionID {
+	case "s1":
+		return 1, nil
+	case "other":
+		return 2, nil
+	}
+	return 0, nil
+}
+
+func (m *sessionSourcesStore) GetSessionMessages(ctx context.Context, userID int64, sessionID string) ([]ChatMessage, error) {
+	base := time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)
+	return []ChatMessage{
+		{Role: "user", Content: "What is the leave policy?", Citations: []string{"ignored.md"}},
+		{Role: "assistant", Content: "See [1] and [2]", Citations: []string{"plan.md", "handbook.md", "web:https://example.com"}, CreatedAt: base},
+		{Role: "assistant", Content: "As [1] says", Citations: []string{"handbook.md", "attachment:notes.txt", "handbook.md"}, CreatedAt: base.Add(time.Minute)},
+	}, nil
+}
+
+func (m *sessionSourcesStore) SearchLibrary(ctx context.Context, userID int64, queryVec []float32, opts SearchOptions, topK int) ([]Chunk, error) {
+	if opts.Sources == nil {
+		m.searchedUser = true
+		return nil, nil
+	}
+	m.searched = opts.Sources
+	return []Chunk{{ID: 3, Source: "handbook.md", Text: "The leave policy allows 25 days", Score: 0.9}}, nil
+}
+
+func TestSessionSources(t *testing.T) {
+	store := &sessionSourcesStore{}
+	server := &Server{
+		store:           store,
+		logger:          &mockLoggerForAsk{},
+		providerManager: &mockProviderManagerForAsk{provider: &mockProviderForAsk{name: "ollama", isLocal: true}, providerName: "Ollama (llama3.2)"},
+		ragEnforcer:     &mockRAGEnforcerForAsk{shouldPerformRAG: true, ragStatus: "RAG Enabled"},
+	}
+	get := func(handler http.HandlerFunc, path, id string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.SetPathValue("id", id)
+		req = req.WithContext(context.WithValue(req.Context(), auth.UserIDKey, int64(1)))
+		w := httptest.NewRecorder()
+		handler(w, req)
+		return w
+	}
+
+	w := get(server.handleSessionSources, "/api/session/s1/sources", "s1")
+	var resp struct {
+		Sources []CitedSource `json:"sources"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if w.Code != http.StatusOK || len(resp.Sources) != 2 {
+		t.Fatalf("Expected the 2 library sources, got %d %s", w.Code, w.Body.String())
+	}
+	if resp.Sources[0].Source != "handbook.md" || resp.Sources[0].Citations != 2 || resp.Sources[1].Source != "plan.md" || resp.Sources[1].Citations != 1 {
+		t.Errorf("Expected handbook.md cited twice then plan.md, got %+v", resp.Sources)
+	}
+
+	w = get(server.handleSessionSearch, "/api/session/s1/search?q=leave", "s1")
+	var search struct {
+		Results []SearchResult `json:"results"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &search)
+	if w.Code != http.StatusOK || len(search.Results) != 1 || search.Results[0].Source != "handbook.md" {
+		t.Errorf("Expected the handbook chunk, got %d %s", w.Code, w.Body.String())
+	}
+	if len(store.searched) != 2 || store.searched[0] != "handbook.md" || store.searched[1] != "plan.md" {
+		t.Errorf("Expected only the cited sources searched, got %v", store.searched)
+	}
+
+	if w := get(server.handleSessionSources, "/api/session/other/sources", "other"); w.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 for another user's session, got %d", w.Code)
+	}
+	if w := get(server.handleSessionSearch, "/api/session/missing/search?q=leave", "missing"); w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown session, got %d", w.Code)
+	}
+
+	// Asking with session_sources searches the cited sources instead of the library
+	store.searched = nil
+	body, _ := json.Marshal(map[string]interface{}{"query": "How many days?", "session_id": "s1", "session_sources": true})
+	req := httptest.NewRequest(http.MethodPost, "/api/ask", bytes.NewReader(body))
+	req = req.WithContext(context.WithValue(req.Context(), auth.UserIDKey, int64(1)))
+	server.handleAsk(httptest.NewRecorder(), req)
+	if len(store.searched) != 2 || store.searchedUser {
+		t.Errorf("Expected the ask restricted to the cited sources, searched %v (library %v)", store.searched, store.searchedUser)
+	}
+}
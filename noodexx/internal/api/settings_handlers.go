@@ -4,22 +4,18 @@ import (
 	"encoding/json"
 	"net/http"
 	"noodexx/internal/config"
+	"noodexx/internal/validate"
 	"strconv"
 )
 
 // handleSaveSettings saves configuration changes to config.json
 func (s *Server) handleSaveSettings(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
 	s.logger.Debug("Received settings save request")
 
 	// Parse form data
 	if err := r.ParseForm(); err != nil {
 		s.logger.Error("Failed to parse form: %v", err)
-		http.Error(w, "Failed to parse form", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, CodeBadRequest, "Failed to parse form")
 		return
 	}
 
@@ -29,12 +25,17 @@ func (s *Server) handleSaveSettings(w http.ResponseWriter, r *http.Request) {
 	cfg, err := config.Load(s.configPath)
 	if err != nil {
 		s.logger.Error("Failed to load config: %v", err)
-		http.Error(w, "Failed to load config", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to load config")
 		return
 	}
 
 	s.logger.Debug("Current config loaded, folders=%v", cfg.Folders)
 
+	if err := validateSettingsForm(r); err != nil {
+		writeValidationError(w, s.logger, err)
+		return
+	}
+
 	// Update privacy mode (legacy - no longer used)
 	// Privacy mode is now controlled via DefaultToLocal in dual-provider system
 
@@ -78,10 +79,6 @@ func (s *Server) handleSaveSettings(w http.ResponseWriter, r *http.Request) {
 		s.logger.Debug("Anthropic key provided: %d chars", len(v))
 		cfg.CloudProvider.AnthropicKey = v
 	}
-	if v := r.FormValue("anthropic_embed_model"); v != "" {
-		s.logger.Debug("Anthropic embed model: %s", v)
-		cfg.CloudProvider.AnthropicEmbedModel = v
-	}
 	if v := r.FormValue("anthropic_chat_model"); v != "" {
 		s.logger.Debug("Anthropic chat model: %s", v)
 		cfg.CloudProvider.AnthropicChatModel = v
@@ -110,31 +107,20 @@ func (s *Server) handleSaveSettings(w http.ResponseWriter, r *http.Request) {
 	s.logger.Debug("Auto summarize set to: %v", cfg.Guardrails.AutoSummarize)
 
 	if v := r.FormValue("max_file_size_mb"); v != "" {
-		if size, err := strconv.Atoi(v); err == nil {
-			s.logger.Debug("Max file size: %d MB", size)
-			cfg.Guardrails.MaxFileSizeMB = size
-		} else {
-			s.logger.Warn("Invalid max_file_size_mb value: %s", v)
-		}
+		size, _ := strconv.Atoi(v)
+		s.logger.Debug("Max file size: %d MB", size)
+		cfg.Guardrails.MaxFileSizeMB = size
 	}
 	if v := r.FormValue("max_concurrent"); v != "" {
-		if concurrent, err := strconv.Atoi(v); err == nil {
-			s.logger.Debug("Max concurrent: %d", concurrent)
-			cfg.Guardrails.MaxConcurrent = concurrent
-		} else {
-			s.logger.Warn("Invalid max_concurrent value: %s", v)
-		}
+		concurrent, _ := strconv.Atoi(v)
+		s.logger.Debug("Max concurrent: %d", concurrent)
+		cfg.Guardrails.MaxConcurrent = concurrent
 	}
 
 	// Validate configuration
 	if err := cfg.Validate(); err != nil {
 		s.logger.Error("Config validation failed: %v", err)
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]interface{}{
-			"success": false,
-			"error":   "Invalid configuration: " + err.Error(),
-		})
+		writeError(w, http.StatusBadRequest, CodeBadRequest, "Invalid configuration: "+err.Error())
 		return
 	}
 
@@ -143,12 +129,7 @@ func (s *Server) handleSaveSettings(w http.ResponseWriter, r *http.Request) {
 	// Save configuration
 	if err := cfg.Save(s.configPath); err != nil {
 		s.logger.Error("Failed to save config: %v", err)
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(map[string]interface{}{
-			"success": false,
-			"error":   "Failed to save configuration: " + err.Error(),
-		})
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to save configuration: "+err.Error())
 		return
 	}
 
@@ -163,22 +144,45 @@ func (s *Server) handleSaveSettings(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// handlePrivacyMode toggles privacy mode on/off and switches LLM provider
-func (s *Server) handlePrivacyMode(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
+// Bounds of the numeric guardrail settings
+const (
+	maxSettingsFileSizeMB = 1024
+	maxSettingsConcurrent = 64
+)
+
+// validateSettingsForm checks the settings form before any of it is applied,
+// so a bad field is reported instead of being silently ignored
+func validateSettingsForm(r *http.Request) error {
+	v := validate.New()
+	if e := r.FormValue("ollama_endpoint"); e != "" {
+		v.Check("ollama_endpoint", validate.URL(e))
+	}
+	if t := r.FormValue("cloud_provider_type"); t != "" {
+		v.Check("cloud_provider_type", validate.OneOf("Cloud provider type", t, "openai", "anthropic"))
+	}
+	if p := r.FormValue("pii_detection"); p != "" {
+		v.Check("pii_detection", validate.OneOf("PII detection", p, "strict", "normal", "off"))
+	}
+	if n := r.FormValue("max_file_size_mb"); n != "" {
+		_, err := validate.IntRange("Max file size", n, 1, maxSettingsFileSizeMB)
+		v.Check("max_file_size_mb", err)
 	}
+	if n := r.FormValue("max_concurrent"); n != "" {
+		_, err := validate.IntRange("Max concurrent", n, 1, maxSettingsConcurrent)
+		v.Check("max_concurrent", err)
+	}
+	return v.Err()
+}
 
+// handlePrivacyMode toggles privacy mode on/off and switches LLM provider
+func (s *Server) handlePrivacyMode(w http.ResponseWriter, r *http.Request) {
 	s.logger.Debug("Received privacy mode toggle request")
 
 	// Parse JSON body
 	var req struct {
 		Enabled bool `json:"enabled"`
 	}
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		s.logger.Error("Failed to parse request body: %v", err)
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+	if !decodeJSON(w, r, s.logger, &req) {
 		return
 	}
 
@@ -188,7 +192,7 @@ func (s *Server) handlePrivacyMode(w http.ResponseWriter, r *http.Request) {
 	cfg, err := config.Load(s.configPath)
 	if err != nil {
 		s.logger.Error("Failed to load config: %v", err)
-		http.Error(w, "Failed to load config", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to load config")
 		return
 	}
 
@@ -212,24 +216,14 @@ func (s *Server) handlePrivacyMode(w http.ResponseWriter, r *http.Request) {
 	// Save configuration
 	if err := cfg.Save(s.configPath); err != nil {
 		s.logger.Error("Failed to save config: %v", err)
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(map[string]interface{}{
-			"success": false,
-			"error":   "Failed to save configuration: " + err.Error(),
-		})
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to save configuration: "+err.Error())
 		return
 	}
 
 	// Reload provider manager with new configuration
 	if err := s.providerManager.Reload(cfg); err != nil {
 		s.logger.Error("Failed to reload provider manager: %v", err)
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(map[string]interface{}{
-			"success": false,
-			"error":   "Failed to reload providers: " + err.Error(),
-		})
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to reload providers: "+err.Error())
 		return
 	}
 
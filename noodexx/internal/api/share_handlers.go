@@ -0,0 +1,459 @@
+package api
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"noodexx/internal/auth"
+	"strconv"
+	"time"
+)
+
+// Share link limits
+const (
+	shareTokenBytes     = 32
+	maxShareExpiryHours = 24 * 365
+	maxShareViewsLimit  = 200
+)
+
+// createShareRequest is the body of POST /api/session/:id/shares
+type createShareRequest struct {
+	ExpiresInHours int    `json:"expires_in_hours"` // 0 for a link that never expires
+	Password       string `json:"password"`         // Empty for a link without a password
+}
+
+// generateShareToken creates the random token used in a share URL
+func generateShareToken() (string, error) {
+	bytes := make([]byte, shareTokenBytes)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(bytes), nil
+}
+
+// shareURL returns the public path for a share token
+func shareURL(token string) string {
+	return "/share/" + token
+}
+
+// shareResponse is the owner's view of a share link
+func shareResponse(share SessionShare) map[string]interface{} {
+	resp := map[string]interface{}{
+		"id":           share.ID,
+		"session_id":   share.SessionID,
+		"url":          shareURL(share.Token),
+		"has_password": share.PasswordHash != "",
+		"view_count":   share.ViewCount,
+		"created_at":   share.CreatedAt,
+		"revoked":      !share.RevokedAt.IsZero(),
+	}
+	if !share.ExpiresAt.IsZero() {
+		resp["expires_at"] = share.ExpiresAt
+	}
+	if !share.RevokedAt.IsZero() {
+		resp["revoked_at"] = share.RevokedAt
+	}
+	return resp
+}
+
+// ownedSessionID returns the {id} session of the request path, writing an error
+// response and returning false unless it belongs to userID
+func (s *Server) ownedSessionID(w http.ResponseWriter, r *http.Request, logger Logger, userID int64) (string, bool) {
+	sessionID := r.PathValue("id")
+	if sessionID == "" {
+		writeError(w, http.StatusBadRequest, CodeBadRequest, "Session ID required")
+		return "", false
+	}
+
+	// Verify ownership before sharing anything
+	owner, err := s.store.GetSessionOwner(r.Context(), sessionID)
+	if err != nil {
+		logger.Error("request failed", "operation", "get_session_owner", "error", err.Error())
+		writeError(w, http.StatusNotFound, CodeNotFound, "Session not found")
+		return "", false
+	}
+	if owner != userID {
+		logger.Error("request failed", "operation", "verify_session_owner", "error", "unauthorized access to session")
+		writeError(w, http.StatusForbidden, CodeForbidden, "Forbidden: session belongs to another user")
+		return "", false
+	}
+	return sessionID, true
+}
+
+// handleListSessionShares handles GET /api/session/{id}/shares - the share links of a session
+func (s *Server) handleListSessionShares(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	requestID := newRequestID(w)
+
+	// Create logger with request context
+	logger := s.logger.WithContext("request_id", requestID).
+		WithContext("method", r.Method).
+		WithContext("path", r.URL.Path)
+
+	logger.Debug("processing session shares request")
+
+	ctx := r.Context()
+
+	// Extract user_id from context
+	userID, err := auth.GetUserID(ctx)
+	if err != nil {
+		logger.Error("request failed", "operation", "get_user_id", "error", err.Error())
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
+		return
+	}
+
+	sessionID, ok := s.ownedSessionID(w, r, logger, userID)
+	if !ok {
+		return
+	}
+
+	shares, err := s.store.GetSessionShares(ctx, userID, sessionID)
+	if err != nil {
+		logger.Error("request failed", "operation", "get_session_shares", "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to get share links")
+		return
+	}
+
+	items := make([]map[string]interface{}, len(shares))
+	for i, share := range shares {
+		items[i] = shareResponse(share)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"shares":  items,
+	})
+
+	latency := time.Since(start).Milliseconds()
+	logger.Debug("request completed", "latency_ms", latency)
+}
+
+// handleCreateSessionShare handles POST /api/session/{id}/shares - creates a share link
+func (s *Server) handleCreateSessionShare(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	requestID := newRequestID(w)
+
+	// Create logger with request context
+	logger := s.logger.WithContext("request_id", requestID).
+		WithContext("method", r.Method).
+		WithContext("path", r.URL.Path)
+
+	logger.Debug("processing create share request")
+
+	ctx := r.Context()
+
+	// Extract user_id from context
+	userID, err := auth.GetUserID(ctx)
+	if err != nil {
+		logger.Error("request failed", "operation", "get_user_id", "error", err.Error())
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
+		return
+	}
+
+	sessionID, ok := s.ownedSessionID(w, r, logger, userID)
+	if !ok {
+		return
+	}
+
+	var req createShareRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logger.Error("request failed", "operation", "decode_request", "error", err.Error())
+		writeError(w, http.StatusBadRequest, CodeBadRequest, "Invalid request body")
+		return
+	}
+	if req.ExpiresInHours < 0 || req.ExpiresInHours > maxShareExpiryHours {
+		writeError(w, http.StatusBadRequest, CodeBadRequest, fmt.Sprintf("expires_in_hours must be between 0 and %d", maxShareExpiryHours))
+		return
+	}
+
+	token, err := generateShareToken()
+	if err != nil {
+		logger.Error("request failed", "operation", "generate_token", "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to create share link")
+		return
+	}
+
+	share := SessionShare{
+		Token:     token,
+		SessionID: sessionID,
+		UserID:    userID,
+		CreatedAt: time.Now(),
+	}
+	if req.ExpiresInHours > 0 {
+		share.ExpiresAt = time.Now().Add(time.Duration(req.ExpiresInHours) * time.Hour)
+	}
+	if req.Password != "" {
+		share.PasswordHash, err = auth.HashPassword(req.Password)
+		if err != nil {
+			logger.Error("request failed", "operation", "hash_password", "error", err.Error())
+			writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to create share link")
+			return
+		}
+	}
+
+	share.ID, err = s.store.CreateSessionShare(ctx, &share)
+	if err != nil {
+		logger.Error("request failed", "operation", "create_session_share", "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to create share link")
+		return
+	}
+
+	s.store.AddAuditEntry(ctx, "share_create", fmt.Sprintf("Shared session %s (link %d)", sessionID, share.ID), sessionID)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"share":   shareResponse(share),
+	})
+
+	latency := time.Since(start).Milliseconds()
+	logger.Debug("request completed", "share_id", share.ID, "latency_ms", latency)
+}
+
+// parseShareID parses the {id} share of the request path, writing an error response on failure
+func parseShareID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeBadRequest, "Invalid share ID")
+		return 0, false
+	}
+	return id, true
+}
+
+// handleRevokeShare handles DELETE /api/shares/{id} - revokes a share link
+func (s *Server) handleRevokeShare(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	requestID := newRequestID(w)
+
+	// Create logger with request context
+	logger := s.logger.WithContext("request_id", requestID).
+		WithContext("method", r.Method).
+		WithContext("path", r.URL.Path)
+
+	logger.Debug("processing revoke share request")
+
+	ctx := r.Context()
+
+	// Extract user_id from context
+	userID, err := auth.GetUserID(ctx)
+	if err != nil {
+		logger.Error("request failed", "operation", "get_user_id", "error", err.Error())
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
+		return
+	}
+
+	shareID, ok := parseShareID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := s.store.RevokeSessionShare(ctx, userID, shareID); err != nil {
+		logger.Error("request failed", "operation", "revoke_session_share", "error", err.Error())
+		writeError(w, http.StatusNotFound, CodeNotFound, "Share link not found")
+		return
+	}
+
+	s.store.AddAuditEntry(ctx, "share_revoke", fmt.Sprintf("Revoked share link %d", shareID), "")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+	})
+
+	latency := time.Since(start).Milliseconds()
+	logger.Debug("request completed", "share_id", shareID, "latency_ms", latency)
+}
+
+// handleShareViews handles GET /api/shares/{id}/views - the recorded views of a share link
+func (s *Server) handleShareViews(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	requestID := newRequestID(w)
+
+	// Create logger with request context
+	logger := s.logger.WithContext("request_id", requestID).
+		WithContext("method", r.Method).
+		WithContext("path", r.URL.Path)
+
+	logger.Debug("processing share views request")
+
+	ctx := r.Context()
+
+	// Extract user_id from context
+	userID, err := auth.GetUserID(ctx)
+	if err != nil {
+		logger.Error("request failed", "operation", "get_user_id", "error", err.Error())
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
+		return
+	}
+
+	shareID, ok := parseShareID(w, r)
+	if !ok {
+		return
+	}
+
+	limit := maxShareViewsLimit
+	if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 && l < maxShareViewsLimit {
+		limit = l
+	}
+
+	views, err := s.store.GetSessionShareViews(ctx, userID, shareID, limit)
+	if err != nil {
+		logger.Error("request failed", "operation", "get_session_share_views", "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to get share views")
+		return
+	}
+	if views == nil {
+		views = []SessionShareView{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"views":   views,
+	})
+
+	latency := time.Since(start).Milliseconds()
+	logger.Debug("request completed", "share_id", shareID, "views", len(views), "latency_ms", latency)
+}
+
+// shareMessage is one transcript entry on the public share page
+type shareMessage struct {
+	Role      string
+	Content   string
+	CreatedAt time.Time
+}
+
+// handleSharePage handles GET /share/{token} - renders a shared transcript for
+// anyone holding the link. Only the stored messages are shown: no citations,
+// library content or other sessions. Password-protected links show a form that
+// posts the password back to the same URL
+func (s *Server) handleSharePage(w http.ResponseWriter, r *http.Request) {
+	s.serveSharePage(w, r, false)
+}
+
+// handleUnlockSharePage handles POST /share/{token} - the password form of a
+// protected link; the transcript is rendered when the password matches
+func (s *Server) handleUnlockSharePage(w http.ResponseWriter, r *http.Request) {
+	s.serveSharePage(w, r, true)
+}
+
+// serveSharePage renders the shared transcript of the link in the request, or
+// the password form of a protected link unless unlock is set and the request
+// carries the link's password
+func (s *Server) serveSharePage(w http.ResponseWriter, r *http.Request, unlock bool) {
+	start := time.Now()
+	requestID := newRequestID(w)
+
+	// Create logger with request context
+	logger := s.logger.WithContext("request_id", requestID).
+		WithContext("method", r.Method).
+		WithContext("path", "/share/")
+
+	// Shared pages must not be cached, indexed, framed or leak the token in a Referer
+	w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
+	w.Header().Set("Pragma", "no-cache")
+	w.Header().Set("Expires", "0")
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	w.Header().Set("X-Robots-Tag", "noindex, nofollow")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Security-Policy",
+		"default-src 'none'; style-src 'self' 'unsafe-inline'; img-src 'self' data:; "+
+			"form-action 'self'; frame-ancestors 'none'; base-uri 'none'")
+
+	ctx := r.Context()
+	token := r.PathValue("token")
+
+	data := map[string]interface{}{
+		"Title": "Shared conversation",
+	}
+
+	var share *SessionShare
+	if token != "" {
+		var err error
+		share, err = s.store.GetSessionShareByToken(ctx, token)
+		if err != nil {
+			logger.Error("request failed", "operation", "get_session_share", "error", err.Error())
+			writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to load shared conversation")
+			return
+		}
+	}
+
+	switch {
+	case share == nil || !share.RevokedAt.IsZero():
+		data["Unavailable"] = true
+		s.renderSharePage(w, http.StatusNotFound, data)
+		return
+	case !share.ExpiresAt.IsZero() && time.Now().After(share.ExpiresAt):
+		data["Unavailable"] = true
+		s.renderSharePage(w, http.StatusGone, data)
+		return
+	}
+
+	if share.PasswordHash != "" {
+		if !unlock {
+			data["NeedsPassword"] = true
+			s.renderSharePage(w, http.StatusOK, data)
+			return
+		}
+		if !auth.CheckPasswordHash(r.PostFormValue("password"), share.PasswordHash) {
+			logger.Warn("share password rejected", "share_id", share.ID)
+			data["NeedsPassword"] = true
+			data["PasswordError"] = true
+			s.renderSharePage(w, http.StatusUnauthorized, data)
+			return
+		}
+	}
+
+	messages, err := s.store.GetSessionMessages(ctx, share.UserID, share.SessionID)
+	if err != nil {
+		logger.Error("request failed", "operation", "get_session_messages", "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to load shared conversation")
+		return
+	}
+
+	transcript := make([]shareMessage, len(messages))
+	for i, msg := range messages {
+		transcript[i] = shareMessage{
+			Role:      msg.Role,
+			Content:   msg.Content,
+			CreatedAt: msg.CreatedAt,
+		}
+	}
+	data["Messages"] = transcript
+
+	if err := s.store.RecordSessionShareView(ctx, share.ID, clientIP(r), r.UserAgent()); err != nil {
+		logger.Warn("failed to record share view", "share_id", share.ID, "error", err.Error())
+	}
+
+	s.renderSharePage(w, http.StatusOK, data)
+
+	latency := time.Since(start).Milliseconds()
+	logger.Debug("request completed", "share_id", share.ID, "latency_ms", latency)
+}
+
+// renderSharePage writes the standalone share page template
+func (s *Server) renderSharePage(w http.ResponseWriter, status int, data map[string]interface{}) {
+	if s.templates == nil {
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to render shared conversation")
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	if err := s.templates.ExecuteTemplate(w, "share-page", data); err != nil {
+		s.logger.Error("Failed to render share template: %v", err)
+	}
+}
+
+// clientIP returns the remote address of a request without its port
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
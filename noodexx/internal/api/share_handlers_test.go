@@ -0,0 +1,161 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// shareStore keeps share links in memory for session "s1", owned by user 1
+type shareStore struct {
+	mockStore
+	shares map[int64]*SessionShare
+	views  []SessionShareView
+	audits []string
+}
+
+func (m *shareStore) GetSessionOwner(ctx context.Context, sessionID string) (int64, error) {
+	return 1, nil
+}
+
+func (m *shareStore) GetSessionMessages(ctx context.Context, userID int64, sessionID string) ([]ChatMessage, error) {
+	return []ChatMessage{
+		{Role: "user", Content: "What is <b>noodexx</b>?", Citations: []string{"secret.pdf"}},
+		{Role: "assistant", Content: "A knowledge base.", Citations: []string{"secret.pdf"}},
+	}, nil
+}
+
+func (m *shareStore) CreateSessionShare(ctx context.Context, share *SessionShare) (int64, error) {
+	id := int64(len(m.shares) + 1)
+	stored := *share
+	stored.ID = id
+	m.shares[id] = &stored
+	return id, nil
+}
+
+func (m *shareStore) GetSessionShareByToken(ctx context.Context, token string) (*SessionShare, error) {
+	for _, share := range m.shares {
+		if share.Token == token {
+			return share, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *shareStore) RevokeSessionShare(ctx context.Context, userID, shareID int64) error {
+	m.shares[shareID].RevokedAt = time.Now()
+	return nil
+}
+
+func (m *shareStore) RecordSessionShareView(ctx context.Context, shareID int64, ipAddress, userAgent string) error {
+	m.views = append(m.views, SessionShareView{ShareID: shareID, IPAddress: ipAddress, UserAgent: userAgent})
+	return nil
+}
+
+func (m *shareStore) AddAuditEntry(ctx context.Context, opType, details, userCtx string) error {
+	m.audits = append(m.audits, opType)
+	return nil
+}
+
+// TestSessionShareLinks tests creating, viewing and revoking a share link
+func TestSessionShareLinks(t *testing.T) {
+	tmpl, err := template.New("").Funcs(template.FuncMap{
+		"branding": func() Branding { return Branding{AppName: "Noodexx", LogoPath: "/static/logo.png"} },
+	}).ParseFiles("../../web/templates/share.html")
+	if err != nil {
+		t.Fatalf("Failed to parse share template: %v", err)
+	}
+
+	store := &shareStore{shares: map[int64]*SessionShare{}}
+	server := &Server{store: store, templates: tmpl, logger: &mockLogger{}}
+
+	create := func(body interface{}) map[string]interface{} {
+		data, _ := json.Marshal(body)
+		req := httptest.NewRequest(http.MethodPost, "/api/session/s1/shares", bytes.NewReader(data))
+		w := serveRoute(server, withUser(req, 1))
+		if w.Code != http.StatusCreated {
+			t.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body.String())
+		}
+		var resp map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return resp["share"].(map[string]interface{})
+	}
+	view := func(method, path string, form url.Values) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("User-Agent", "test-agent")
+		return serveRoute(server, req)
+	}
+
+	// An open link renders the transcript, escaped and without citations
+	share := create(map[string]interface{}{"expires_in_hours": 24})
+	link := share["url"].(string)
+	if !strings.HasPrefix(link, "/share/") || share["has_password"] != false {
+		t.Fatalf("Unexpected share: %v", share)
+	}
+	w := view(http.MethodGet, link, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	body := w.Body.String()
+	if !strings.Contains(body, "&lt;b&gt;noodexx&lt;/b&gt;") || !strings.Contains(body, "A knowledge base.") {
+		t.Errorf("Expected escaped transcript in page, got %s", body)
+	}
+	if strings.Contains(body, "secret.pdf") {
+		t.Error("Expected citations to be left out of the shared page")
+	}
+	if w.Header().Get("Referrer-Policy") != "no-referrer" || !strings.Contains(w.Header().Get("X-Robots-Tag"), "noindex") {
+		t.Errorf("Expected privacy headers, got %v", w.Header())
+	}
+	if len(store.views) != 1 || store.views[0].UserAgent != "test-agent" || store.views[0].IPAddress != "192.0.2.1" {
+		t.Errorf("Expected one recorded view, got %+v", store.views)
+	}
+
+	// A password-protected link asks for the password and only then shows the transcript
+	protected := create(map[string]interface{}{"password": "hunter22"})
+	if store.shares[2].PasswordHash == "" || store.shares[2].PasswordHash == "hunter22" {
+		t.Errorf("Expected a hashed password, got %q", store.shares[2].PasswordHash)
+	}
+	if w = view(http.MethodGet, protected["url"].(string), nil); w.Code != http.StatusOK || strings.Contains(w.Body.String(), "A knowledge base.") {
+		t.Errorf("Expected a password form, got %d: %s", w.Code, w.Body.String())
+	}
+	if w = view(http.MethodPost, protected["url"].(string), url.Values{"password": {"wrong"}}); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 for a wrong password, got %d", w.Code)
+	}
+	if w = view(http.MethodPost, protected["url"].(string), url.Values{"password": {"hunter22"}}); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "A knowledge base.") {
+		t.Errorf("Expected the transcript for the right password, got %d", w.Code)
+	}
+
+	// Expired and revoked links are unavailable and not counted as views
+	store.shares[2].ExpiresAt = time.Now().Add(-time.Hour)
+	if w = view(http.MethodGet, protected["url"].(string), nil); w.Code != http.StatusGone {
+		t.Errorf("Expected 410 for an expired link, got %d", w.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodDelete, "/api/shares/1", nil)
+	w = serveRoute(server, withUser(req, 1))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 revoking, got %d: %s", w.Code, w.Body.String())
+	}
+	viewsBefore := len(store.views)
+	if w = view(http.MethodGet, link, nil); w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for a revoked link, got %d", w.Code)
+	}
+	if w = view(http.MethodGet, "/share/unknown", nil); w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown link, got %d", w.Code)
+	}
+	if len(store.views) != viewsBefore {
+		t.Errorf("Expected unavailable links not to record views")
+	}
+
+	if strings.Join(store.audits, ",") != "share_create,share_create,share_revoke" {
+		t.Errorf("Unexpected audit entries: %v", store.audits)
+	}
+}
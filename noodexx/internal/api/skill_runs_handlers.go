@@ -0,0 +1,376 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"noodexx/internal/auth"
+	"strconv"
+	"time"
+)
+
+// Skill run history paging limits
+const (
+	defaultSkillRunsLimit = 20
+	maxSkillRunsLimit     = 100
+)
+
+// skillRetryAfterSeconds is the Retry-After sent when a skill execution gave up
+// waiting for a free slot
+const skillRetryAfterSeconds = 10
+
+// skillRunResult is the outcome of runSkill
+type skillRunResult struct {
+	Output         *SkillOutput
+	Err            error
+	RunID          int64 // 0 if the run could not be recorded
+	ArtifactSource string
+}
+
+// findRunnableSkill loads the user's skills and returns the first one matching,
+// checking ownership and that it supports manual execution
+// On failure it returns the HTTP status to respond with
+func (s *Server) findRunnableSkill(ctx context.Context, userID int64, match func(*Skill) bool) (*Skill, int, error) {
+	skills, err := s.skillsLoader.LoadForUser(ctx, userID)
+	if err != nil {
+		return nil, http.StatusInternalServerError, fmt.Errorf("Failed to load skills: %v", err)
+	}
+
+	var targetSkill *Skill
+	for _, skill := range skills {
+		if match(skill) {
+			targetSkill = skill
+			break
+		}
+	}
+
+	if targetSkill == nil {
+		return nil, http.StatusNotFound, fmt.Errorf("Skill not found")
+	}
+
+	// Verify skill ownership - ensure the skill belongs to the current user
+	if targetSkill.UserID != userID {
+		return nil, http.StatusForbidden, fmt.Errorf("Unauthorized: skill does not belong to current user")
+	}
+
+	// Check if skill has manual trigger
+	for _, trigger := range targetSkill.Triggers {
+		if trigger.Type == "manual" {
+			return targetSkill, http.StatusOK, nil
+		}
+	}
+	return nil, http.StatusBadRequest, fmt.Errorf("Skill does not support manual execution")
+}
+
+// runSkill executes a skill, records the run and optionally saves a successful result to the library
+func (s *Server) runSkill(ctx context.Context, userID int64, skill *Skill, input SkillInput, rerunOf int64, saveOutput bool) skillRunResult {
+	output, err := s.skillsExecutor.Execute(ctx, skill, input)
+	result := skillRunResult{Output: output, Err: err}
+
+	// Skills loaded without a database ID (no store configured) have no history
+	if skill.ID == 0 {
+		return result
+	}
+
+	run := &SkillRun{
+		SkillID: skill.ID,
+		UserID:  userID,
+		Status:  "success",
+		RerunOf: rerunOf,
+	}
+	if inputJSON, err := json.Marshal(input); err == nil {
+		run.Input = string(inputJSON)
+	}
+	if output != nil {
+		run.Stderr = output.Stderr
+		run.ExitCode = output.ExitCode
+		run.DurationMS = output.DurationMS
+		if output.Result != "" || output.Error != "" || len(output.Metadata) > 0 {
+			if outputJSON, err := json.Marshal(output); err == nil {
+				run.Output = string(outputJSON)
+			}
+		}
+	}
+	if err != nil {
+		run.Status = "error"
+		run.Error = err.Error()
+	}
+
+	runID, recordErr := s.store.RecordSkillRun(ctx, run)
+	if recordErr != nil {
+		s.logger.Error("failed to record skill run", "skill", skill.Name, "error", recordErr.Error())
+		return result
+	}
+	result.RunID = runID
+	defer func() { s.notifySkillRun(ctx, userID, skill.Name, result) }()
+
+	if saveOutput && err == nil && output != nil && output.Result != "" {
+		source := fmt.Sprintf("skill:%s/run-%d", skill.Name, runID)
+		if ingestErr := s.ingester.IngestText(ctx, userID, source, output.Result, []string{"skill", skill.Name}); ingestErr != nil {
+			s.logger.Error("failed to save skill output", "skill", skill.Name, "run_id", runID, "error", ingestErr.Error())
+			return result
+		}
+		if err := s.store.SetSkillRunArtifact(ctx, userID, runID, source); err != nil {
+			s.logger.Warn("failed to record skill artifact", "run_id", runID, "error", err.Error())
+		}
+		result.ArtifactSource = source
+
+		s.store.AddAuditEntry(ctx, "ingest", fmt.Sprintf("Skill output: %s", source), "")
+	}
+
+	return result
+}
+
+// notifySkillRun tells the user how a recorded skill run went, with the library
+// source of its saved output if there is one
+func (s *Server) notifySkillRun(ctx context.Context, userID int64, skillName string, result skillRunResult) {
+	details := map[string]interface{}{
+		"skill":  skillName,
+		"run_id": result.RunID,
+		"status": "success",
+	}
+	message := fmt.Sprintf("Skill '%s' finished", skillName)
+	if result.Err != nil {
+		details["status"] = "error"
+		message = fmt.Sprintf("Skill '%s' failed: %v", skillName, result.Err)
+	}
+	if result.ArtifactSource != "" {
+		details["source"] = result.ArtifactSource
+		message = fmt.Sprintf("Skill '%s' finished and its output was saved as %s", skillName, result.ArtifactSource)
+	}
+	s.NotifyUser(ctx, userID, "skill_run", message, details)
+}
+
+// writeSkillRunResult writes the JSON response for a skill execution
+func (s *Server) writeSkillRunResult(w http.ResponseWriter, result skillRunResult) {
+	var validationErr *SkillValidationError
+	if errors.As(result.Err, &validationErr) {
+		// Bad input is the caller's fault; bad output is the skill's
+		status, code := http.StatusUnprocessableEntity, CodeUnprocessable
+		if validationErr.Stage == "output" {
+			status, code = http.StatusBadGateway, CodeUpstreamFailed
+		}
+		writeErrorDetails(w, status, code, result.Err.Error(), map[string]interface{}{
+			"stage":      validationErr.Stage,
+			"violations": validationErr.Violations,
+			"run_id":     result.RunID,
+		})
+		return
+	}
+
+	var limitErr *SkillLimitError
+	if errors.As(result.Err, &limitErr) {
+		// A full queue clears on its own; a skill that ran too long or wrote too
+		// much will do so again
+		status, code := http.StatusBadGateway, CodeUpstreamFailed
+		if limitErr.Limit == "queue_timeout" {
+			status, code = http.StatusTooManyRequests, CodeRateLimited
+			w.Header().Set("Retry-After", strconv.Itoa(skillRetryAfterSeconds))
+		}
+		writeErrorDetails(w, status, code, result.Err.Error(), map[string]interface{}{
+			"limit":  limitErr.Limit,
+			"run_id": result.RunID,
+		})
+		return
+	}
+
+	if result.Err != nil {
+		writeErrorDetails(w, http.StatusInternalServerError, CodeInternal, result.Err.Error(), map[string]interface{}{
+			"run_id": result.RunID,
+		})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	response := map[string]interface{}{
+		"success":  true,
+		"result":   result.Output.Result,
+		"metadata": result.Output.Metadata,
+		"run_id":   result.RunID,
+	}
+	if result.ArtifactSource != "" {
+		response["artifact_source"] = result.ArtifactSource
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// handleListSkillRuns handles GET /api/skills/{id}/runs?limit=&offset= - a page
+// of a skill's run history, newest first
+func (s *Server) handleListSkillRuns(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	requestID := newRequestID(w)
+
+	// Create logger with request context
+	logger := s.logger.WithContext("request_id", requestID).
+		WithContext("method", r.Method).
+		WithContext("path", r.URL.Path)
+
+	logger.Debug("processing skill runs request")
+
+	ctx := r.Context()
+
+	// Extract user_id from context
+	userID, err := auth.GetUserID(ctx)
+	if err != nil {
+		logger.Error("request failed", "operation", "get_user_id", "error", err.Error())
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
+		return
+	}
+
+	skillID, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeBadRequest, "Invalid skill ID")
+		return
+	}
+
+	limit := defaultSkillRunsLimit
+	offset := 0
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > maxSkillRunsLimit {
+			writeError(w, http.StatusBadRequest, CodeBadRequest, fmt.Sprintf("limit must be between 1 and %d", maxSkillRunsLimit))
+			return
+		}
+		limit = n
+	}
+	if v := r.URL.Query().Get("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			writeError(w, http.StatusBadRequest, CodeBadRequest, "offset must be a non-negative number")
+			return
+		}
+		offset = n
+	}
+
+	runs, total, err := s.store.GetSkillRuns(ctx, userID, skillID, limit, offset)
+	if err != nil {
+		logger.Error("request failed", "operation", "get_skill_runs", "skill_id", skillID, "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to load skill runs")
+		return
+	}
+	if runs == nil {
+		runs = []SkillRun{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"runs":    runs,
+		"total":   total,
+		"limit":   limit,
+		"offset":  offset,
+	})
+
+	latency := time.Since(start).Milliseconds()
+	logger.Debug("request completed", "skill_id", skillID, "runs", len(runs), "latency_ms", latency)
+}
+
+// handleRerunSkill handles POST /api/skills/{id}/runs/{run_id}/rerun - executes
+// a skill again with the input of a recorded run
+func (s *Server) handleRerunSkill(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	requestID := newRequestID(w)
+
+	// Create logger with request context
+	logger := s.logger.WithContext("request_id", requestID).
+		WithContext("method", r.Method).
+		WithContext("path", r.URL.Path)
+
+	logger.Debug("processing skill rerun request")
+
+	ctx := r.Context()
+
+	// Extract user_id from context
+	userID, err := auth.GetUserID(ctx)
+	if err != nil {
+		logger.Error("request failed", "operation", "get_user_id", "error", err.Error())
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
+		return
+	}
+
+	skillID, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeBadRequest, "Invalid skill ID")
+		return
+	}
+	runID, err := pathID(r, "run_id")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeBadRequest, "Invalid run ID")
+		return
+	}
+
+	// Optional body: {"save_output": true}
+	var req struct {
+		SaveOutput bool `json:"save_output"`
+	}
+	if r.ContentLength > 0 {
+		if !decodeJSON(w, r, logger, &req) {
+			return
+		}
+	}
+
+	previous, err := s.store.GetSkillRun(ctx, userID, runID)
+	if err != nil {
+		logger.Error("request failed", "operation", "get_skill_run", "run_id", runID, "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to load skill run")
+		return
+	}
+	if previous == nil || previous.SkillID != skillID {
+		writeError(w, http.StatusNotFound, CodeNotFound, "Skill run not found")
+		return
+	}
+
+	var input SkillInput
+	if err := json.Unmarshal([]byte(previous.Input), &input); err != nil {
+		logger.Error("request failed", "operation", "decode_run_input", "run_id", runID, "error", err.Error())
+		writeError(w, http.StatusUnprocessableEntity, CodeUnprocessable, "Recorded run input is unreadable")
+		return
+	}
+
+	skill, status, err := s.findRunnableSkill(ctx, userID, func(skill *Skill) bool {
+		return skill.ID == skillID
+	})
+	if err != nil {
+		writeError(w, status, codeForStatus(status), err.Error())
+		return
+	}
+
+	result := s.runSkill(ctx, userID, skill, input, runID, req.SaveOutput)
+	logger.Info("skill rerun", "skill", skill.Name, "rerun_of", runID, "run_id", result.RunID)
+	s.writeSkillRunResult(w, result)
+
+	latency := time.Since(start).Milliseconds()
+	logger.Debug("request completed", "latency_ms", latency)
+}
+
+// handleSkillExecutor handles GET /api/admin/skill-executor - load of the skill
+// executor and how often executions hit its limits (admin only)
+func (s *Server) handleSkillExecutor(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	requestID := newRequestID(w)
+
+	// Create logger with request context
+	logger := s.logger.WithContext("request_id", requestID).
+		WithContext("method", r.Method).
+		WithContext("path", r.URL.Path)
+
+	logger.Debug("processing skill executor request")
+
+	var stats SkillExecutorStats
+	reporter, ok := s.skillsExecutor.(SkillStatsReporter)
+	if ok {
+		stats = reporter.SkillStats()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"enabled": ok,
+		"stats":   stats,
+	})
+
+	latency := time.Since(start).Milliseconds()
+	logger.Debug("request completed", "latency_ms", latency)
+}
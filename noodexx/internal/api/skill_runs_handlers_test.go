@@ -0,0 +1,200 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// skillRunsStore records skill runs in memory
+type skillRunsStore struct {
+	mockStore
+	recorded []SkillRun
+	runs     map[int64]*SkillRun
+	limit    int
+	offset   int
+}
+
+func (m *skillRunsStore) RecordSkillRun(ctx context.Context, run *SkillRun) (int64, error) {
+	m.recorded = append(m.recorded, *run)
+	return int64(100 + len(m.recorded)), nil
+}
+
+func (m *skillRunsStore) GetSkillRuns(ctx context.Context, userID, skillID int64, limit, offset int) ([]SkillRun, int, error) {
+	m.limit, m.offset = limit, offset
+	var runs []SkillRun
+	for _, run := range m.runs {
+		if run.UserID == userID && run.SkillID == skillID {
+			runs = append(runs, *run)
+		}
+	}
+	return runs, len(runs), nil
+}
+
+func (m *skillRunsStore) GetSkillRun(ctx context.Context, userID, runID int64) (*SkillRun, error) {
+	run, ok := m.runs[runID]
+	if !ok || run.UserID != userID {
+		return nil, nil
+	}
+	return run, nil
+}
+
+// inputRecordingExecutor records the input of each execution
+type inputRecordingExecutor struct {
+	inputs []SkillInput
+}
+
+func (m *inputRecordingExecutor) Execute(ctx context.Context, skill *Skill, input SkillInput) (*SkillOutput, error) {
+	m.inputs = append(m.inputs, input)
+	return &SkillOutput{Result: "done", ExitCode: 0, DurationMS: 5, Stderr: "warning"}, nil
+}
+
+// TestSkillRunHistory tests that runs are recorded, listed and can be rerun
+func TestSkillRunHistory(t *testing.T) {
+	store := &skillRunsStore{runs: map[int64]*SkillRun{
+		3: {ID: 3, SkillID: 7, UserID: 1, Status: "success", Input: `{"query":"again","context":{"n":1},"settings":{}}`},
+		4: {ID: 4, SkillID: 8, UserID: 1, Status: "success", Input: `{"query":"other"}`},
+	}}
+	executor := &inputRecordingExecutor{}
+	server := &Server{
+		store: store,
+		skillsLoader: &mockSkillsLoader{skills: []*Skill{
+			{ID: 7, UserID: 1, Name: "echo", Triggers: []SkillTrigger{{Type: "manual"}}},
+		}},
+		skillsExecutor: executor,
+		logger:         &mockLogger{},
+	}
+
+	do := func(method, path string, body interface{}) *httptest.ResponseRecorder {
+		var reader *bytes.Reader
+		if body != nil {
+			data, _ := json.Marshal(body)
+			reader = bytes.NewReader(data)
+		} else {
+			reader = bytes.NewReader(nil)
+		}
+		req := httptest.NewRequest(method, path, reader)
+		return serveRoute(server, withUser(req, 1))
+	}
+
+	// Run: the execution is recorded with its input and details
+	w := do(http.MethodPost, "/api/skills/run", map[string]interface{}{"skill_name": "echo", "query": "hello"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if resp["run_id"] != float64(101) {
+		t.Errorf("Expected run_id 101, got %v", resp["run_id"])
+	}
+	if len(store.recorded) != 1 {
+		t.Fatalf("Expected 1 recorded run, got %d", len(store.recorded))
+	}
+	run := store.recorded[0]
+	if run.SkillID != 7 || run.Status != "success" || run.Stderr != "warning" || run.DurationMS != 5 {
+		t.Errorf("Unexpected recorded run: %+v", run)
+	}
+	var recordedInput SkillInput
+	if err := json.Unmarshal([]byte(run.Input), &recordedInput); err != nil || recordedInput.Query != "hello" {
+		t.Errorf("Expected recorded input with query, got %q", run.Input)
+	}
+
+	// List with pagination
+	w = do(http.MethodGet, "/api/skills/7/runs?limit=5&offset=10", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if store.limit != 5 || store.offset != 10 {
+		t.Errorf("Expected limit 5 offset 10, got %d %d", store.limit, store.offset)
+	}
+	if w = do(http.MethodGet, "/api/skills/7/runs?limit=1000", nil); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected oversized limit to be rejected, got %d", w.Code)
+	}
+
+	// Rerun replays the recorded input and links the new run to the old one
+	w = do(http.MethodPost, "/api/skills/7/runs/3/rerun", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	last := executor.inputs[len(executor.inputs)-1]
+	if last.Query != "again" || last.Context["n"] != float64(1) {
+		t.Errorf("Expected recorded input to be replayed, got %+v", last)
+	}
+	if store.recorded[len(store.recorded)-1].RerunOf != 3 {
+		t.Errorf("Expected rerun to reference run 3, got %+v", store.recorded[len(store.recorded)-1])
+	}
+
+	// A run belonging to a different skill is not found under this skill
+	if w = do(http.MethodPost, "/api/skills/7/runs/4/rerun", nil); w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for a run of another skill, got %d", w.Code)
+	}
+	if w = do(http.MethodGet, "/api/skills/7/runs/3/rerun", nil); w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405 for GET rerun, got %d", w.Code)
+	}
+}
+
+// limitedExecutor fails every execution with a limit error and reports fixed stats
+type limitedExecutor struct {
+	limit string
+}
+
+func (m *limitedExecutor) Execute(ctx context.Context, skill *Skill, input SkillInput) (*SkillOutput, error) {
+	return &SkillOutput{ExitCode: -1}, &SkillLimitError{Skill: skill.Name, Limit: m.limit, Detail: "test"}
+}
+
+func (m *limitedExecutor) SkillStats() SkillExecutorStats {
+	return SkillExecutorStats{Running: 2, Waiting: 1, Runs: 9, QueueTimeouts: 1}
+}
+
+// TestSkillLimitErrors tests that a full queue asks the client to retry, that a
+// skill stopped for running too long or writing too much is the skill's fault,
+// and that admins can read the executor's stats
+func TestSkillLimitErrors(t *testing.T) {
+	executor := &limitedExecutor{}
+	server := &Server{
+		store: &skillRunsStore{},
+		skillsLoader: &mockSkillsLoader{skills: []*Skill{
+			{ID: 7, UserID: 1, Name: "echo", Triggers: []SkillTrigger{{Type: "manual"}}},
+		}},
+		skillsExecutor: executor,
+		logger:         &mockLogger{},
+	}
+
+	for _, tc := range []struct {
+		limit  string
+		status int
+		code   ErrorCode
+	}{
+		{"queue_timeout", http.StatusTooManyRequests, CodeRateLimited},
+		{"max_runtime", http.StatusBadGateway, CodeUpstreamFailed},
+		{"max_output", http.StatusBadGateway, CodeUpstreamFailed},
+	} {
+		executor.limit = tc.limit
+		req := httptest.NewRequest(http.MethodPost, "/api/skills/run", bytes.NewReader([]byte(`{"skill_name":"echo"}`)))
+		w := serveRoute(server, withUser(req, 1))
+		var resp ErrorResponse
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		if w.Code != tc.status || resp.Code != tc.code {
+			t.Errorf("%s: expected %d %s, got %d %s", tc.limit, tc.status, tc.code, w.Code, resp.Code)
+		}
+		if details, _ := resp.Details.(map[string]interface{}); details["limit"] != tc.limit {
+			t.Errorf("%s: expected the limit in details, got %v", tc.limit, resp.Details)
+		}
+		if retry := w.Header().Get("Retry-After"); (retry != "") != (tc.limit == "queue_timeout") {
+			t.Errorf("%s: unexpected Retry-After %q", tc.limit, retry)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	server.handleSkillExecutor(w, httptest.NewRequest(http.MethodGet, "/api/admin/skill-executor", nil))
+	var resp struct {
+		Enabled bool               `json:"enabled"`
+		Stats   SkillExecutorStats `json:"stats"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || !resp.Enabled || resp.Stats.Runs != 9 || resp.Stats.Running != 2 {
+		t.Errorf("Unexpected executor stats %s", w.Body.String())
+	}
+}
@@ -114,9 +114,8 @@ func TestHandleRunSkill_OwnershipVerification(t *testing.T) {
 
 			// Check error message if expected
 			if tt.expectedError != "" {
-				body := w.Body.String()
-				if body != tt.expectedError+"\n" {
-					t.Errorf("Expected error %q, got %q", tt.expectedError, body)
+				if resp := decodeErrorResponse(t, w); resp.Message != tt.expectedError {
+					t.Errorf("Expected error %q, got %q", tt.expectedError, resp.Message)
 				}
 			}
 
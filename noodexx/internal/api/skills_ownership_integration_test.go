@@ -123,9 +123,9 @@ func TestSkillOwnershipIntegration(t *testing.T) {
 			t.Error("Execute should not be called for unauthorized user")
 		}
 
-		expectedError := "Unauthorized: skill does not belong to current user\n"
-		if w.Body.String() != expectedError {
-			t.Errorf("Expected error %q, got %q", expectedError, w.Body.String())
+		expectedError := "Unauthorized: skill does not belong to current user"
+		if resp := decodeErrorResponse(t, w); resp.Message != expectedError || resp.Code != CodeForbidden {
+			t.Errorf("Expected %s error %q, got %s %q", CodeForbidden, expectedError, resp.Code, resp.Message)
 		}
 	})
 
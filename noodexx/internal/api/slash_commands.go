@@ -0,0 +1,330 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"noodexx/internal/flags"
+	"noodexx/internal/ingest"
+	"noodexx/internal/validate"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Slash commands a question may start with. Each takes one argument and
+// applies to that message only
+const (
+	commandMode   = "mode"   // /mode local|cloud answers with the other provider
+	commandScope  = "scope"  // /scope tag:<tag> or source:<source> limits library search
+	commandSkill  = "skill"  // /skill <name> runs a skill and adds its output as context
+	commandIngest = "ingest" // /ingest <url> adds a page to the library before answering
+)
+
+// maxSlashCommands bounds the commands one message may carry
+const maxSlashCommands = 8
+
+// slashCommandPattern matches a command word; other words starting with a
+// slash, such as paths, begin the question
+var slashCommandPattern = regexp.MustCompile(`^/([a-z]+)$`)
+
+// slashCommand is a command parsed from the start of a question
+type slashCommand struct {
+	Name string
+	Arg  string
+}
+
+// String returns the command as typed
+func (c slashCommand) String() string {
+	return "/" + c.Name + " " + c.Arg
+}
+
+// commandAck acknowledges a slash command, sent as a command event ahead of
+// the answer
+type commandAck struct {
+	Command  string `json:"command"`
+	Argument string `json:"argument"`
+	OK       bool   `json:"ok"`
+	Message  string `json:"message"`
+}
+
+// parseSlashCommands splits the commands leading a question from the question
+// itself, which may be empty. Unknown commands and invalid arguments are errors,
+// so a mistyped command is not sent to the provider as part of the question
+func parseSlashCommands(query string) ([]slashCommand, string, error) {
+	var commands []slashCommand
+	rest := strings.TrimSpace(query)
+	mode := ""
+	for {
+		word, after := nextWord(rest)
+		match := slashCommandPattern.FindStringSubmatch(word)
+		if match == nil {
+			break
+		}
+		name := match[1]
+		switch name {
+		case commandMode, commandScope, commandSkill, commandIngest:
+		default:
+			return nil, "", fmt.Errorf("Unknown command /%s (use /mode, /scope, /skill or /ingest)", name)
+		}
+
+		arg, after := nextWord(after)
+		if arg == "" {
+			return nil, "", fmt.Errorf("/%s needs an argument", name)
+		}
+		rest = after
+
+		switch name {
+		case commandMode:
+			if arg != "local" && arg != "cloud" {
+				return nil, "", fmt.Errorf("/mode must be local or cloud")
+			}
+			if mode != "" && mode != arg {
+				return nil, "", fmt.Errorf("Conflicting /mode commands")
+			}
+			mode = arg
+		case commandScope:
+			kind, value, _ := strings.Cut(arg, ":")
+			if (kind != "tag" && kind != "source") || value == "" {
+				return nil, "", fmt.Errorf("/scope must be tag:<tag> or source:<source>")
+			}
+		case commandIngest:
+			if err := validate.URL(arg); err != nil {
+				return nil, "", fmt.Errorf("/ingest: %v", err)
+			}
+		}
+
+		commands = append(commands, slashCommand{Name: name, Arg: arg})
+		if len(commands) > maxSlashCommands {
+			return nil, "", fmt.Errorf("A message can carry at most %d commands", maxSlashCommands)
+		}
+	}
+	return commands, rest, nil
+}
+
+// nextWord splits the first whitespace-separated word from s
+func nextWord(s string) (word, rest string) {
+	s = strings.TrimSpace(s)
+	if i := strings.IndexFunc(s, unicode.IsSpace); i >= 0 {
+		return s[:i], strings.TrimSpace(s[i:])
+	}
+	return s, ""
+}
+
+// commandModeOf returns the mode a /mode command asks for, or "" for the active one
+func commandModeOf(commands []slashCommand) string {
+	for _, c := range commands {
+		if c.Name == commandMode {
+			return c.Arg
+		}
+	}
+	return ""
+}
+
+// askMode is the provider mode a question is answered in: the active one, or
+// the one a /mode command switched it to
+type askMode struct {
+	provider   LLMProvider
+	local      bool
+	name       string // Shown to the user, like "Local AI (ollama)"
+	model      string // Chat model; empty if unknown
+	embedModel string // Embedding model the query is searched with
+	rag        bool   // Whether library content may be sent to the provider
+	ragStatus  string
+}
+
+// resolveAskMode returns the active provider mode, or the local or cloud mode
+// when mode names one. Switching modes is gated by the mode_switch flag; the
+// RAG policy of the mode switched to applies. On failure it returns the HTTP
+// status to respond with
+func (s *Server) resolveAskMode(ctx context.Context, mode string) (*askMode, int, error) {
+	if mode == "" {
+		provider, err := s.providerManager.GetActiveProvider()
+		if err != nil {
+			return nil, http.StatusBadRequest, err
+		}
+		return &askMode{
+			provider:   provider,
+			local:      s.providerManager.IsLocalMode(),
+			name:       s.providerManager.GetProviderName(),
+			model:      s.activeModel(),
+			embedModel: s.activeEmbedModel(),
+			rag:        s.ragEnforcer.ShouldPerformRAG(),
+			ragStatus:  s.ragEnforcer.GetRAGStatus(),
+		}, http.StatusOK, nil
+	}
+
+	if !s.featureEnabled(ctx, flags.ModeSwitch) {
+		return nil, http.StatusForbidden, fmt.Errorf("Switching modes with /mode is not enabled")
+	}
+	m, err := s.providerMode(mode == "local")
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+	return m, http.StatusOK, nil
+}
+
+// providerMode returns the local or cloud mode, whichever is active, with the
+// RAG policy of that mode
+func (s *Server) providerMode(local bool) (*askMode, error) {
+	m := &askMode{local: local, name: "Cloud AI", provider: s.providerManager.GetCloudProvider()}
+	if local {
+		m.name, m.provider = "Local AI", s.providerManager.GetLocalProvider()
+	}
+	if m.provider == nil {
+		return nil, fmt.Errorf("%s is not configured", m.name)
+	}
+	m.name = fmt.Sprintf("%s (%s)", m.name, m.provider.Name())
+
+	active := local == s.providerManager.IsLocalMode()
+	if reporter, ok := s.providerManager.(ModeModelReporter); ok {
+		m.model, m.embedModel = reporter.GetModels(local)
+	} else if active {
+		m.model, m.embedModel = s.activeModel(), s.activeEmbedModel()
+	}
+	switch enforcer, ok := s.ragEnforcer.(ModeRAGEnforcer); {
+	case ok:
+		m.rag, m.ragStatus = enforcer.ShouldPerformRAGFor(local), enforcer.GetRAGStatusFor(local)
+	case active:
+		m.rag, m.ragStatus = s.ragEnforcer.ShouldPerformRAG(), s.ragEnforcer.GetRAGStatus()
+	case local:
+		m.rag, m.ragStatus = true, "RAG Enabled (Local)"
+	default:
+		// Without the cloud policy, library content stays local
+		m.rag, m.ragStatus = false, "RAG Disabled (Cloud Policy)"
+	}
+	return m, nil
+}
+
+// commandResult is what a question's slash commands add to its answer
+type commandResult struct {
+	scoped  bool     // Whether /scope limited library search
+	sources []string // Sources library search is limited to when scoped
+	context []Chunk  // Skill output added as context
+}
+
+// runSlashCommands runs a question's commands in order, sending an
+// acknowledgement for each. A command that fails is acknowledged with ok false
+// and the question is still answered
+func (s *Server) runSlashCommands(ctx context.Context, logger Logger, userID int64, sessionID, query string, commands []slashCommand, mode *askMode, progress *askProgress) commandResult {
+	var result commandResult
+
+	for _, c := range commands {
+		ack := commandAck{Command: c.Name, Argument: c.Arg, OK: true}
+		switch c.Name {
+		case commandMode:
+			ack.Message = "Answering this message with " + mode.name
+			if !mode.rag {
+				ack.Message += "; library content is not sent in this mode"
+			}
+
+		case commandIngest:
+			if s.ingester == nil {
+				ack.OK, ack.Message = false, "Ingestion is not available"
+				break
+			}
+			if err := s.ingester.IngestURL(ingest.WithVisibility(ctx, ""), userID, c.Arg, nil); err != nil {
+				logger.Warn("slash command failed", "command", c.Name, "url", c.Arg, "error", err.Error())
+				ack.OK, ack.Message = false, fmt.Sprintf("Ingestion failed: %v", err)
+				break
+			}
+			s.store.AddAuditEntry(ctx, "ingest", fmt.Sprintf("URL: %s", c.Arg), sessionID)
+			ack.Message = fmt.Sprintf("Added %s to your library", c.Arg)
+
+		case commandScope:
+			if !mode.rag {
+				ack.OK, ack.Message = false, "The RAG policy keeps library content from this provider"
+				break
+			}
+			var sources []string
+			err := s.store.ForEachLibraryEntry(ctx, userID, func(entry LibraryEntry) bool {
+				if scopeMatches(entry, c.Arg) {
+					sources = append(sources, entry.Source)
+				}
+				return true
+			})
+			if err != nil {
+				logger.Warn("slash command failed", "command", c.Name, "error", err.Error())
+				result.scoped = true
+				ack.OK, ack.Message = false, "Failed to read your library; the library is not searched"
+				break
+			}
+			result.scoped = true
+			result.sources = append(result.sources, sources...)
+			if len(sources) == 0 {
+				ack.OK, ack.Message = false, fmt.Sprintf("No documents match %s; the library is not searched", c.Arg)
+				break
+			}
+			ack.Message = fmt.Sprintf("Searching %d documents", len(sources))
+			if len(sources) == 1 {
+				ack.Message = "Searching 1 document"
+			}
+
+		case commandSkill:
+			if s.skillsLoader == nil || s.skillsExecutor == nil {
+				ack.OK, ack.Message = false, "Skills are not available"
+				break
+			}
+			skill, _, err := s.findRunnableSkill(ctx, userID, func(skill *Skill) bool {
+				return skill.Name == c.Arg
+			})
+			if err != nil {
+				ack.OK, ack.Message = false, err.Error()
+				break
+			}
+			run := s.runSkill(ctx, userID, skill, SkillInput{Query: query, Context: map[string]interface{}{"session_id": sessionID}}, 0, false)
+			if run.Err != nil || run.Output == nil || run.Output.Error != "" {
+				msg := "Skill failed"
+				if run.Err != nil {
+					msg = fmt.Sprintf("Skill failed: %v", run.Err)
+				} else if run.Output != nil && run.Output.Error != "" {
+					msg = "Skill failed: " + run.Output.Error
+				}
+				logger.Warn("slash command failed", "command", c.Name, "skill", c.Arg, "error", msg)
+				ack.OK, ack.Message = false, msg
+				break
+			}
+			if strings.TrimSpace(run.Output.Result) == "" {
+				ack.Message = "The skill returned nothing"
+				break
+			}
+			result.context = append(result.context, Chunk{Source: "skill:" + skill.Name, Text: run.Output.Result})
+			ack.Message = "Added the skill's output as context"
+		}
+
+		progress.command(ack)
+	}
+	return result
+}
+
+// scopeMatches reports whether a /scope argument selects a library entry:
+// every document with a tag, or one source
+func scopeMatches(entry LibraryEntry, scope string) bool {
+	kind, value, _ := strings.Cut(scope, ":")
+	switch kind {
+	case "tag":
+		for _, tag := range entry.Tags {
+			if strings.EqualFold(tag, value) {
+				return true
+			}
+		}
+	case "source":
+		return entry.Source == value
+	}
+	return false
+}
+
+// intersectSources returns the sources in both a and b
+func intersectSources(a, b []string) []string {
+	in := make(map[string]bool, len(b))
+	for _, source := range b {
+		in[source] = true
+	}
+	both := []string{}
+	for _, source := range a {
+		if in[source] {
+			both = append(both, source)
+		}
+	}
+	return both
+}
@@ -0,0 +1,253 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"noodexx/internal/auth"
+	"noodexx/internal/flags"
+	"strings"
+	"testing"
+)
+
+func TestParseSlashCommands(t *testing.T) {
+	tests := []struct {
+		query    string
+		commands []string
+		rest     string
+		wantErr  bool
+	}{
+		{query: "What is in /etc/hosts?", rest: "What is in /etc/hosts?"},
+		{query: "/etc/hosts explained", rest: "/etc/hosts explained"},
+		{query: " /mode local\t/scope tag:contracts  When does the lease end? ", commands: []string{"/mode local", "/scope tag:contracts"}, rest: "When does the lease end?"},
+		{query: "/ingest https://example.com/a", commands: []string{"/ingest https://example.com/a"}},
+		{query: "/skill weather\nForecast?", commands: []string{"/skill weather"}, rest: "Forecast?"},
+		{query: "/foo bar", wantErr: true},
+		{query: "/scope", wantErr: true},
+		{query: "/scope contracts", wantErr: true},
+		{query: "/mode hybrid", wantErr: true},
+		{query: "/mode local /mode cloud Hi", wantErr: true},
+		{query: "/ingest not-a-url", wantErr: true},
+	}
+	for _, tt := range tests {
+		commands, rest, err := parseSlashCommands(tt.query)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%q: expected error %v, got %v", tt.query, tt.wantErr, err)
+			continue
+		}
+		var typed []string
+		for _, c := range commands {
+			typed = append(typed, c.String())
+		}
+		if strings.Join(typed, ",") != strings.Join(tt.commands, ",") || rest != tt.rest {
+			t.Errorf("%q: got %v %q, want %v %q", tt.query, typed, rest, tt.commands, tt.rest)
+		}
+	}
+}
+
+// commandStore has a small library and records what slash commands did
+type commandStore struct {
+	mockStoreForAsk
+	searched []string
+	saved    []string
+	audits   []string
+}
+
+func (m *commandStore) LibraryByUser(ctx context.Context, userID int64) ([]LibraryEntry, error) {
+	return []LibraryEntry{
+		{Source: "lease.pdf", Tags: []string{"Contracts"}},
+		{Source: "notes.md", Tags: []string{"personal"}},
+	}, nil
+}
+
+func (m *commandStore) ForEachLibraryEntry(ctx context.Context, userID int64, fn func(LibraryEntry) bool) error {
+	library, _ := m.LibraryByUser(ctx, userID)
+	for _, entry := range library {
+		if !fn(entry) {
+			break
+		}
+	}
+	return nil
+}
+
+func (m *commandStore) SearchLibrary(ctx context.Context, userID int64, queryVec []float32, opts SearchOptions, topK int) ([]Chunk, error) {
+	if opts.Sources == nil {
+		return m.mockStoreForAsk.SearchLibrary(ctx, userID, queryVec, opts, topK)
+	}
+	m.searched = append(m.searched, opts.Sources...)
+	return []Chunk{{ID: 1, Source: "lease.pdf", Text: "The lease ends in May."}}, nil
+}
+
+func (m *commandStore) SaveChatMessage(ctx context.Context, userID int64, sessionID, role, content, providerMode string) error {
+	m.saved = append(m.saved, role+": "+content)
+	return nil
+}
+
+func (m *commandStore) AddAuditEntry(ctx context.Context, opType, details, userCtx string) error {
+	m.audits = append(m.audits, opType+": "+details)
+	return nil
+}
+
+// commandIngester records the URLs ingested
+type commandIngester struct {
+	urls []string
+}
+
+func (m *commandIngester) IngestText(ctx context.Context, userID int64, source, text string, tags []string) error {
+	return nil
+}
+
+func (m *commandIngester) IngestURL(ctx context.Context, userID int64, url string, tags []string) error {
+	m.urls = append(m.urls, url)
+	return nil
+}
+
+// commandSkills serves one manual skill returning a fixed result
+type commandSkills struct{}
+
+func (commandSkills) LoadAll() ([]*Skill, error) {
+	return nil, nil
+}
+
+func (commandSkills) LoadForUser(ctx context.Context, userID int64) ([]*Skill, error) {
+	return []*Skill{{Name: "weather", UserID: userID, Triggers: []SkillTrigger{{Type: "manual"}}}}, nil
+}
+
+func (commandSkills) Execute(ctx context.Context, skill *Skill, input SkillInput) (*SkillOutput, error) {
+	return &SkillOutput{Result: "Sunny in " + input.Query}, nil
+}
+
+// modeProviderManager has a separate local provider
+type modeProviderManager struct {
+	mockProviderManagerForAsk
+	local LLMProvider
+}
+
+func (m *modeProviderManager) GetLocalProvider() LLMProvider {
+	return m.local
+}
+
+func TestAskSlashCommands(t *testing.T) {
+	store := &commandStore{}
+	ingester := &commandIngester{}
+	var prompt string
+	provider := &mockProviderForAsk{name: "ollama", isLocal: true, streamFunc: func(ctx context.Context, messages []Message, w io.Writer) (string, error) {
+		prompt = messages[len(messages)-1].Content
+		w.Write([]byte("May"))
+		return "May", nil
+	}}
+	server := &Server{
+		store:           store,
+		logger:          &mockLoggerForAsk{},
+		providerManager: &mockProviderManagerForAsk{provider: provider, providerName: "Local AI (ollama)"},
+		ragEnforcer:     &mockRAGEnforcerForAsk{shouldPerformRAG: true, ragStatus: "RAG Enabled (Local)"},
+		ingester:        ingester,
+		skillsLoader:    commandSkills{},
+		skillsExecutor:  commandSkills{},
+	}
+	ask := func(query string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(map[string]interface{}{"query": query, "session_id": "s1"})
+		req := httptest.NewRequest(http.MethodPost, "/api/ask", bytes.NewReader(body))
+		req = req.WithContext(context.WithValue(req.Context(), auth.UserIDKey, int64(1)))
+		w := httptest.NewRecorder()
+		server.handleAsk(w, req)
+		return w
+	}
+	acks := func(w *httptest.ResponseRecorder) []commandAck {
+		var out []commandAck
+		for _, event := range strings.Split(w.Body.String(), "\n\n") {
+			if data, ok := strings.CutPrefix(event, "event: command\ndata: "); ok {
+				var ack commandAck
+				json.Unmarshal([]byte(data), &ack)
+				out = append(out, ack)
+			}
+		}
+		return out
+	}
+
+	w := ask("/ingest https://example.com/lease /scope tag:contracts /skill weather When does the lease end?")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d %s", w.Code, w.Body.String())
+	}
+	got := acks(w)
+	if len(got) != 3 || got[0].Command != "ingest" || got[1].Command != "scope" || got[2].Command != "skill" {
+		t.Fatalf("Expected three acknowledgements in order, got %+v", got)
+	}
+	for _, ack := range got {
+		if !ack.OK {
+			t.Errorf("Expected %s to succeed, got %q", ack.Command, ack.Message)
+		}
+	}
+	if !strings.Contains(w.Body.String(), "\n\nMay") {
+		t.Errorf("Expected the answer after the acknowledgements, got %q", w.Body.String())
+	}
+	if len(ingester.urls) != 1 || ingester.urls[0] != "https://example.com/lease" {
+		t.Errorf("Expected the URL ingested, got %v", ingester.urls)
+	}
+	if len(store.searched) != 1 || store.searched[0] != "lease.pdf" {
+		t.Errorf("Expected the search limited to the tagged document, got %v", store.searched)
+	}
+	if !strings.Contains(prompt, "Sunny in When does the lease end?") || !strings.Contains(prompt, "The lease ends in May.") {
+		t.Errorf("Expected the skill output and library content in the prompt, got %q", prompt)
+	}
+	if len(store.saved) < 1 || store.saved[0] != "user: When does the lease end?" {
+		t.Errorf("Expected the question saved without its commands, got %v", store.saved)
+	}
+
+	// A scope matching nothing searches nothing
+	store.searched = nil
+	got = acks(ask("/scope tag:missing Anything?"))
+	if len(got) != 1 || got[0].OK || len(store.searched) != 0 {
+		t.Errorf("Expected a failed scope and no search, got %+v %v", got, store.searched)
+	}
+
+	// Commands alone are acknowledged without an answer
+	prompt, store.saved = "", nil
+	w = ask("/ingest https://example.com/other")
+	if len(acks(w)) != 1 || prompt != "" || len(store.saved) != 0 {
+		t.Errorf("Expected only an acknowledgement, got %q and %v", w.Body.String(), store.saved)
+	}
+
+	if w := ask("/summon the answer"); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an unknown command, got %d", w.Code)
+	}
+}
+
+func TestAskSlashCommandMode(t *testing.T) {
+	cloud := &mockProviderForAsk{name: "openai", isLocal: false}
+	local := &mockProviderForAsk{name: "ollama", isLocal: true}
+	manager := &modeProviderManager{mockProviderManagerForAsk{provider: cloud, providerName: "Cloud AI (gpt-4o)"}, local}
+	server := &Server{
+		store:           &commandStore{},
+		logger:          &mockLoggerForAsk{},
+		providerManager: manager,
+		ragEnforcer:     &mockRAGEnforcerForAsk{shouldPerformRAG: false, ragStatus: "RAG Disabled (Cloud Policy)"},
+	}
+	ask := func(query string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(map[string]interface{}{"query": query, "session_id": "s1"})
+		req := httptest.NewRequest(http.MethodPost, "/api/ask", bytes.NewReader(body))
+		req = req.WithContext(context.WithValue(req.Context(), auth.UserIDKey, int64(1)))
+		w := httptest.NewRecorder()
+		server.handleAsk(w, req)
+		return w
+	}
+
+	// Switching to local uses the local provider and its RAG policy
+	w := ask("/mode local What is in my notes?")
+	if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("X-Provider-Name"), "Local AI") || w.Header().Get("X-RAG-Status") != "RAG Enabled (Local)" {
+		t.Fatalf("Expected a local answer, got %d %v", w.Code, w.Header())
+	}
+
+	// The cloud is refused when it is not configured or the flag is off
+	manager.provider, manager.local = local, local
+	if w := ask("/mode cloud Hi"); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 without a cloud provider, got %d", w.Code)
+	}
+	server.SetFeatureFlags(flags.New(map[string]flags.Flag{flags.ModeSwitch: {Enabled: false}}, nil))
+	if w := ask("/mode local Hi"); w.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 with mode switching off, got %d", w.Code)
+	}
+}
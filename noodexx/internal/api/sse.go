@@ -0,0 +1,196 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// Events of answers framed as server-sent events, sent to clients that ask
+// with "events": true
+const (
+	sseEventToken     = "token"     // Answer text; the ID is the answer's length in bytes so far
+	sseEventHeartbeat = "heartbeat" // Sent while nothing else is, so idle connections stay open
+	sseEventError     = "error"     // The answer failed; no token follows
+	sseEventDone      = "done"      // The answer is complete, with its session metadata
+	sseEventDropped   = "dropped"   // The client fell too far behind; resume from the last event ID
+)
+
+// defaultSSEHeartbeat is the interval of heartbeat events when no stream
+// keep-alive is configured
+const defaultSSEHeartbeat = 15 * time.Second
+
+// SSEWriter writes server-sent events to a response: named events with JSON
+// data, optionally with an ID a reconnecting client sends back as
+// Last-Event-ID, and comments clients ignore. Each event is written and
+// flushed in one piece, so it is safe to use from several goroutines
+type SSEWriter struct {
+	mu   sync.Mutex
+	w    io.Writer
+	last time.Time // When anything was last written
+}
+
+// NewSSEWriter creates a writer of events to w, which is flushed after each
+// event when it is an http.Flusher
+func NewSSEWriter(w io.Writer) *SSEWriter {
+	return &SSEWriter{w: w, last: time.Now()}
+}
+
+// Event sends a named event with data encoded as JSON
+func (s *SSEWriter) Event(event string, data interface{}) error {
+	return s.EventWithID("", event, data)
+}
+
+// EventWithID sends a named event with an ID, or none when id is empty
+func (s *SSEWriter) EventWithID(id, event string, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	return s.write(formatSSEEvent(id, event, payload))
+}
+
+// Redirect sends later events to w, such as a client stream that takes over
+// the response once the answer starts. When anything was last written carries
+// over, so heartbeats continue where they were
+func (s *SSEWriter) Redirect(w io.Writer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.w = w
+}
+
+// Comment sends a comment line
+func (s *SSEWriter) Comment(text string) error {
+	return s.write(": " + text + "\n\n")
+}
+
+// StartHeartbeat sends a heartbeat event whenever nothing was written for an
+// interval, so at most two intervals pass without output. The returned
+// function stops the heartbeats and waits until none is being written
+func (s *SSEWriter) StartHeartbeat(interval time.Duration) (stop func()) {
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				s.mu.Lock()
+				idle := time.Since(s.last) >= interval
+				s.mu.Unlock()
+				if idle {
+					s.Event(sseEventHeartbeat, map[string]time.Time{"time": time.Now().UTC()})
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(done) })
+		<-stopped
+	}
+}
+
+// write sends a framed event or comment and flushes it
+func (s *SSEWriter) write(frame string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.last = time.Now()
+	if _, err := io.WriteString(s.w, frame); err != nil {
+		return err
+	}
+	if f, ok := s.w.(http.Flusher); ok {
+		f.Flush()
+	}
+	return nil
+}
+
+// formatSSEEvent frames an event. payload is JSON, which has no line breaks,
+// so it fits on one data line
+func formatSSEEvent(id, event string, payload []byte) string {
+	var b strings.Builder
+	if id != "" {
+		b.WriteString("id: " + id + "\n")
+	}
+	b.WriteString("event: " + event + "\n")
+	b.WriteString("data: ")
+	b.Write(payload)
+	b.WriteString("\n\n")
+	return b.String()
+}
+
+// sseTokenWriter frames answer text as token events. Each event's ID is the
+// answer's length in bytes up to the end of its text, the offset a resumed
+// stream continues from. A character split between writes is held back until
+// the rest of it arrives
+type sseTokenWriter struct {
+	sse     *SSEWriter
+	offset  int
+	partial []byte
+}
+
+// newSSETokenWriter creates a token writer for an answer whose first offset
+// bytes were already sent
+func newSSETokenWriter(sse *SSEWriter, offset int) *sseTokenWriter {
+	return &sseTokenWriter{sse: sse, offset: offset}
+}
+
+// Write implements io.Writer
+func (t *sseTokenWriter) Write(p []byte) (int, error) {
+	text := append(t.partial, p...)
+	n := completeUTF8(text)
+	t.partial = append([]byte(nil), text[n:]...)
+	if n == 0 {
+		return len(p), nil
+	}
+
+	t.offset += n
+	if err := t.sse.EventWithID(strconv.Itoa(t.offset), sseEventToken, map[string]string{"text": string(text[:n])}); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// completeUTF8 returns the length of b without a character cut off at its end
+func completeUTF8(b []byte) int {
+	for i := len(b) - 1; i >= 0 && i >= len(b)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(b[i]) {
+			if utf8.FullRune(b[i:]) {
+				return len(b)
+			}
+			return i
+		}
+	}
+	return len(b)
+}
+
+// sseHeartbeat returns the interval of heartbeat events: the stream keep-alive
+// when one is configured
+func (s *Server) sseHeartbeat() time.Duration {
+	if s.streamKeepAlive > 0 {
+		return s.streamKeepAlive
+	}
+	return defaultSSEHeartbeat
+}
+
+// askDone ends an answer framed as events with what the client needs to
+// continue the session
+type askDone struct {
+	RequestID string `json:"request_id"`
+	SessionID string `json:"session_id"`
+	MessageID int64  `json:"message_id,omitempty"` // Saved assistant message, 0 if it could not be saved
+	Provider  string `json:"provider"`
+	Model     string `json:"model,omitempty"`
+	Mode      string `json:"mode"`  // "local" or "cloud"
+	Bytes     int    `json:"bytes"` // Length of the answer, the ID of its last token event
+}
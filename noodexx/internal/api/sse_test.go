@@ -0,0 +1,258 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"noodexx/internal/auth"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// sseEvent is a parsed server-sent event
+type sseEvent struct {
+	id    string
+	event string
+	data  map[string]interface{}
+}
+
+// parseSSEEvents parses a stream of events, skipping comments
+func parseSSEEvents(t *testing.T, body string) []sseEvent {
+	t.Helper()
+	var events []sseEvent
+	for _, frame := range strings.Split(strings.TrimSpace(body), "\n\n") {
+		var e sseEvent
+		for _, line := range strings.Split(frame, "\n") {
+			field, value, _ := strings.Cut(line, ": ")
+			switch field {
+			case "id":
+				e.id = value
+			case "event":
+				e.event = value
+			case "data":
+				if err := json.Unmarshal([]byte(value), &e.data); err != nil {
+					t.Fatalf("Malformed event data %q: %v", value, err)
+				}
+			}
+		}
+		if e.event != "" {
+			events = append(events, e)
+		}
+	}
+	return events
+}
+
+// lockedBuffer is a bytes.Buffer safe for the heartbeat goroutine
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestSSEWriter(t *testing.T) {
+	var buf bytes.Buffer
+	sse := NewSSEWriter(&buf)
+	sse.EventWithID("7", "token", map[string]string{"text": "a\nb"})
+	sse.Event("done", map[string]int{"bytes": 3})
+	sse.Comment("ping")
+
+	want := "id: 7\nevent: token\ndata: {\"text\":\"a\\nb\"}\n\n" +
+		"event: done\ndata: {\"bytes\":3}\n\n" +
+		": ping\n\n"
+	if got := buf.String(); got != want {
+		t.Errorf("Unexpected framing:\n%q\nwant\n%q", got, want)
+	}
+}
+
+// TestSSETokenWriter tests that token IDs are byte offsets and that characters
+// split between writes are sent whole
+func TestSSETokenWriter(t *testing.T) {
+	var buf bytes.Buffer
+	tokens := newSSETokenWriter(NewSSEWriter(&buf), 4)
+	answer := []byte("héllo")
+	tokens.Write(answer[:2]) // "h" and the first byte of "é"
+	tokens.Write(answer[2:])
+
+	events := parseSSEEvents(t, buf.String())
+	if len(events) != 2 {
+		t.Fatalf("Expected 2 token events, got %+v", events)
+	}
+	if events[0].data["text"] != "h" || events[0].id != "5" {
+		t.Errorf("Expected the complete character held back, got %+v", events[0])
+	}
+	if events[1].data["text"] != "éllo" || events[1].id != "10" {
+		t.Errorf("Expected the rest with the answer's length as ID, got %+v", events[1])
+	}
+}
+
+func TestSSEHeartbeat(t *testing.T) {
+	buf := &lockedBuffer{}
+	sse := NewSSEWriter(buf)
+	stop := sse.StartHeartbeat(10 * time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	stop()
+	stop()
+
+	events := parseSSEEvents(t, buf.String())
+	if len(events) == 0 || events[0].event != sseEventHeartbeat {
+		t.Errorf("Expected heartbeat events while idle, got %+v", events)
+	}
+	after := buf.String()
+	time.Sleep(30 * time.Millisecond)
+	if buf.String() != after {
+		t.Error("Expected no heartbeats once stopped")
+	}
+}
+
+func TestSSEWriterRedirect(t *testing.T) {
+	var before, after bytes.Buffer
+	sse := NewSSEWriter(&before)
+	sse.Event("queue", map[string]int{"position": 0})
+	sse.Redirect(&after)
+	stop := sse.StartHeartbeat(time.Hour)
+	sse.Event("status", map[string]string{"stage": "generating"})
+	stop()
+
+	if events := parseSSEEvents(t, before.String()); len(events) != 1 || events[0].event != "queue" {
+		t.Errorf("Expected the queue event before redirecting, got %+v", events)
+	}
+	if events := parseSSEEvents(t, after.String()); len(events) != 1 || events[0].event != "status" {
+		t.Errorf("Expected the status event after redirecting, got %+v", events)
+	}
+}
+
+// askWithEvents posts a question asking for the answer framed as events
+func askWithEvents(t *testing.T, server *Server) []sseEvent {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/api/ask", bytes.NewBufferString(`{"query":"test query","session_id":"s1","events":true}`))
+	req = req.WithContext(context.WithValue(req.Context(), auth.UserIDKey, int64(1)))
+	w := httptest.NewRecorder()
+	server.handleAsk(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("Expected an event stream, got %q", ct)
+	}
+	return parseSSEEvents(t, w.Body.String())
+}
+
+func TestHandleAsk_Events(t *testing.T) {
+	provider := &mockProviderForAsk{
+		name:    "ollama",
+		isLocal: true,
+		streamFunc: func(ctx context.Context, messages []Message, w io.Writer) (string, error) {
+			w.Write([]byte("test "))
+			w.Write([]byte("response"))
+			return "test response", nil
+		},
+	}
+	server := &Server{
+		store:           &mockStoreForAsk{},
+		logger:          &mockLoggerForAsk{},
+		providerManager: &mockProviderManagerForAsk{provider: provider, providerName: "Ollama (llama3.2)"},
+		ragEnforcer:     &mockRAGEnforcerForAsk{shouldPerformRAG: true, ragStatus: "RAG Enabled (Local)"},
+		streams:         newStreamBufferStore(streamBufferTTL),
+	}
+
+	events := askWithEvents(t, server)
+	var answer strings.Builder
+	var lastID string
+	for _, e := range events[:len(events)-1] {
+		if e.event != sseEventToken {
+			t.Fatalf("Expected only token events before the end, got %+v", e)
+		}
+		answer.WriteString(e.data["text"].(string))
+		lastID = e.id
+	}
+	if answer.String() != "test response" || lastID != "13" {
+		t.Errorf("Expected the answer in token events ending at ID 13, got %q (%s)", answer.String(), lastID)
+	}
+
+	done := events[len(events)-1]
+	if done.event != sseEventDone || done.data["session_id"] != "s1" || done.data["mode"] != "local" || done.data["bytes"] != float64(13) {
+		t.Errorf("Expected a done event with the session, got %+v", done)
+	}
+	if done.data["request_id"] == "" {
+		t.Errorf("Expected the request ID in the done event, got %+v", done)
+	}
+}
+
+func TestHandleAsk_EventsError(t *testing.T) {
+	provider := &mockProviderForAsk{
+		name:    "ollama",
+		isLocal: true,
+		streamFunc: func(ctx context.Context, messages []Message, w io.Writer) (string, error) {
+			return "", errors.New("model not loaded")
+		},
+	}
+	server := &Server{
+		store:           &mockStoreForAsk{},
+		logger:          &mockLoggerForAsk{},
+		providerManager: &mockProviderManagerForAsk{provider: provider, providerName: "Ollama (llama3.2)"},
+		ragEnforcer:     &mockRAGEnforcerForAsk{shouldPerformRAG: true, ragStatus: "RAG Enabled (Local)"},
+	}
+
+	events := askWithEvents(t, server)
+	if len(events) != 1 || events[0].event != sseEventError || events[0].data["code"] != string(CodeUpstreamFailed) {
+		t.Fatalf("Expected a single error event, got %+v", events)
+	}
+	if msg := events[0].data["message"].(string); !strings.Contains(msg, "model not loaded") {
+		t.Errorf("Expected the provider's error in the event, got %q", msg)
+	}
+}
+
+// TestHandleAskStreamResumeEvents tests resuming an answer framed as events
+// from the Last-Event-ID a reconnecting client sends
+func TestHandleAskStreamResumeEvents(t *testing.T) {
+	server := &Server{
+		logger:  &mockLoggerForAsk{},
+		streams: newStreamBufferStore(streamBufferTTL),
+	}
+
+	buf := server.streams.Start("req-1", 1, "session-1")
+	buf.SetEvents()
+	buf.Write([]byte("The answer "))
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		buf.Write([]byte("is 42."))
+		buf.FinishWith(sseEventDone, askDone{RequestID: "req-1", SessionID: "session-1", MessageID: 9, Bytes: 17})
+	}()
+
+	req := httptest.NewRequest(http.MethodGet, "/api/ask/req-1/stream", nil)
+	req.Header.Set("Last-Event-ID", "4")
+	w := serveRoute(server, withUser(req, 1))
+
+	events := parseSSEEvents(t, w.Body.String())
+	var answer strings.Builder
+	for _, e := range events {
+		if e.event == sseEventToken {
+			answer.WriteString(e.data["text"].(string))
+		}
+	}
+	if answer.String() != "answer is 42." {
+		t.Errorf("Expected the answer from offset 4, got %q", answer.String())
+	}
+	last := events[len(events)-1]
+	if last.event != sseEventDone || last.data["message_id"] != float64(9) {
+		t.Errorf("Expected the answer's done event at the end, got %+v", last)
+	}
+}
@@ -0,0 +1,93 @@
+package api
+
+import (
+	"html/template"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// startupRetryAfterSeconds is the Retry-After sent while dependencies are awaited,
+// and how often the starting page reloads
+const startupRetryAfterSeconds = 5
+
+// StartupGate reports whether the dependencies checked at startup are ready
+type StartupGate interface {
+	Ready() bool
+	Status() []DependencyStatus
+}
+
+// DependencyStatus is the state of one dependency checked at startup
+type DependencyStatus struct {
+	Name     string `json:"name"`
+	Required bool   `json:"required"`
+	OK       bool   `json:"ok"`
+	Attempts int    `json:"attempts"`
+	Error    string `json:"error,omitempty"`
+}
+
+// startingPage is shown to browsers until the gate opens; it reloads itself
+var startingPage = template.Must(template.New("starting").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta http-equiv="refresh" content="{{.Refresh}}">
+<title>Noodexx is starting</title>
+<style>
+body { font-family: system-ui, sans-serif; max-width: 32rem; margin: 4rem auto; padding: 0 1rem; color: #1f2937; }
+@media (prefers-color-scheme: dark) { body { background: #111827; color: #e5e7eb; } }
+li { margin: 0.25rem 0; }
+.waiting { color: #b45309; }
+.ok { color: #15803d; }
+</style>
+</head>
+<body>
+<h1>Noodexx is starting</h1>
+<p>Waiting for the services Noodexx depends on. This page reloads every {{.Refresh}} seconds.</p>
+<ul>
+{{range .Dependencies}}<li>{{.Name}}: {{if .OK}}<span class="ok">ready</span>{{else}}<span class="waiting">waiting</span>{{if .Error}} ({{.Error}}){{end}}{{end}}</li>
+{{end}}</ul>
+</body>
+</html>
+`))
+
+// StartupMiddleware answers every request with 503 until the gate opens: API
+// clients get a starting error listing the dependencies still awaited, and
+// browsers a page that reloads until Noodexx is ready
+func StartupMiddleware(gate StartupGate) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if gate.Ready() {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			status := gate.Status()
+			w.Header().Set("Retry-After", strconv.Itoa(startupRetryAfterSeconds))
+			if strings.HasPrefix(r.URL.Path, "/api/") || !strings.Contains(r.Header.Get("Accept"), "text/html") {
+				var waiting []string
+				for _, d := range status {
+					if !d.OK {
+						waiting = append(waiting, d.Name)
+					}
+				}
+				message := "Noodexx is starting"
+				if len(waiting) > 0 {
+					message += ", waiting for " + strings.Join(waiting, ", ")
+				}
+				writeErrorDetails(w, http.StatusServiceUnavailable, CodeStarting, message, map[string]interface{}{
+					"dependencies": status,
+				})
+				return
+			}
+
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Header().Set("Cache-Control", "no-store")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			startingPage.Execute(w, map[string]interface{}{
+				"Refresh":      startupRetryAfterSeconds,
+				"Dependencies": status,
+			})
+		})
+	}
+}
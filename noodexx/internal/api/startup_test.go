@@ -0,0 +1,61 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// fixedStartupGate reports a fixed readiness
+type fixedStartupGate struct {
+	ready  bool
+	status []DependencyStatus
+}
+
+func (g *fixedStartupGate) Ready() bool                { return g.ready }
+func (g *fixedStartupGate) Status() []DependencyStatus { return g.status }
+
+// TestStartupMiddleware tests that requests get a 503 naming the dependencies
+// still awaited until the gate opens, with a reloading page for browsers
+func TestStartupMiddleware(t *testing.T) {
+	gate := &fixedStartupGate{status: []DependencyStatus{
+		{Name: "database", Required: true, OK: true, Attempts: 1},
+		{Name: "ollama", Attempts: 3, Error: "connection refused"},
+	}}
+	handler := StartupMiddleware(gate)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("served"))
+	}))
+	serve := func(path, accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Accept", accept)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	w := serve("/api/sessions", "text/html")
+	var resp ErrorResponse
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if w.Code != http.StatusServiceUnavailable || resp.Code != CodeStarting || !strings.Contains(resp.Message, "ollama") || strings.Contains(resp.Message, "database") {
+		t.Errorf("Expected a starting error waiting for ollama, got %d %s", w.Code, w.Body.String())
+	}
+	if w.Header().Get("Retry-After") == "" {
+		t.Error("Expected a Retry-After header")
+	}
+
+	w = serve("/chat", "text/html,application/xhtml+xml")
+	body := w.Body.String()
+	if w.Code != http.StatusServiceUnavailable || !strings.Contains(w.Header().Get("Content-Type"), "text/html") {
+		t.Errorf("Expected the starting page, got %d %s", w.Code, w.Header().Get("Content-Type"))
+	}
+	if !strings.Contains(body, "http-equiv=\"refresh\"") || !strings.Contains(body, "ollama") || !strings.Contains(body, "connection refused") {
+		t.Errorf("Expected a reloading page listing the dependencies, got %s", body)
+	}
+
+	gate.ready = true
+	if w := serve("/chat", "text/html"); w.Code != http.StatusOK || w.Body.String() != "served" {
+		t.Errorf("Expected requests served once ready, got %d %s", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,75 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"noodexx/internal/auth"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultStorageSourcesLimit = 10
+	maxStorageSourcesLimit     = 100
+)
+
+// handleStorageStats handles GET /api/stats/storage - bytes used by chunk text,
+// embeddings and metadata, with the largest sources listed first
+// Admins see every user's storage; other users see their own
+func (s *Server) handleStorageStats(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	requestID := newRequestID(w)
+
+	// Create logger with request context
+	logger := s.logger.WithContext("request_id", requestID).
+		WithContext("method", r.Method).
+		WithContext("path", r.URL.Path)
+
+	logger.Debug("processing storage stats request")
+
+	ctx := r.Context()
+	userID, err := auth.GetUserID(ctx)
+	if err != nil {
+		logger.Error("failed to get user from context", "error", err.Error())
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
+		return
+	}
+
+	limit := defaultStorageSourcesLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > maxStorageSourcesLimit {
+			writeError(w, http.StatusBadRequest, CodeBadRequest, fmt.Sprintf("limit must be between 1 and %d", maxStorageSourcesLimit))
+			return
+		}
+		limit = n
+	}
+
+	allUsers := false
+	if user, err := s.store.GetUserByID(ctx, userID); err == nil && user != nil {
+		allUsers = user.IsAdmin
+	}
+
+	stats, err := s.store.GetStorageStats(ctx, userID, allUsers, limit)
+	if err != nil {
+		logger.Error("request failed", "operation", "get_storage_stats", "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to load storage stats")
+		return
+	}
+
+	scope := "user"
+	if allUsers {
+		scope = "all"
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"scope":   scope,
+		"stats":   stats,
+	})
+
+	latency := time.Since(start).Milliseconds()
+	logger.Debug("request completed", "scope", scope, "sources", stats.Sources, "latency_ms", latency)
+}
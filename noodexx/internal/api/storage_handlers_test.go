@@ -0,0 +1,81 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"noodexx/internal/auth"
+	"testing"
+)
+
+// storageStatsStore records the scope of storage queries
+type storageStatsStore struct {
+	*mockStoreForAsk
+	admin    bool
+	allUsers bool
+	limit    int
+}
+
+func (m *storageStatsStore) GetUserByID(ctx context.Context, userID int64) (*User, error) {
+	return &User{ID: userID, Username: "user", IsAdmin: m.admin}, nil
+}
+
+func (m *storageStatsStore) GetStorageStats(ctx context.Context, userID int64, allUsers bool, limit int) (*StorageStats, error) {
+	m.allUsers = allUsers
+	m.limit = limit
+	return &StorageStats{
+		Total:   StorageUsage{Chunks: 3, TextBytes: 100, EmbeddingBytes: 200, TotalBytes: 300},
+		Sources: 1,
+		Largest: []SourceStorage{{Source: "big.pdf", UserID: userID, StorageUsage: StorageUsage{Chunks: 3, TotalBytes: 300}}},
+	}, nil
+}
+
+// TestHandleStorageStats tests scoping and limits of the storage stats endpoint
+func TestHandleStorageStats(t *testing.T) {
+	tests := []struct {
+		name      string
+		admin     bool
+		query     string
+		wantCode  int
+		wantScope string
+		wantLimit int
+	}{
+		{"user sees own storage", false, "", http.StatusOK, "user", defaultStorageSourcesLimit},
+		{"admin sees all users", true, "?limit=3", http.StatusOK, "all", 3},
+		{"limit out of range", false, "?limit=0", http.StatusBadRequest, "", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &storageStatsStore{mockStoreForAsk: &mockStoreForAsk{}, admin: tt.admin}
+			server := &Server{store: store, logger: &mockLoggerForAsk{}}
+
+			req := httptest.NewRequest(http.MethodGet, "/api/stats/storage"+tt.query, nil)
+			req = req.WithContext(context.WithValue(req.Context(), auth.UserIDKey, int64(5)))
+			w := httptest.NewRecorder()
+			server.handleStorageStats(w, req)
+
+			if w.Code != tt.wantCode {
+				t.Fatalf("Expected status %d, got %d", tt.wantCode, w.Code)
+			}
+			if tt.wantCode != http.StatusOK {
+				return
+			}
+
+			var response struct {
+				Scope string       `json:"scope"`
+				Stats StorageStats `json:"stats"`
+			}
+			if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if response.Scope != tt.wantScope || store.allUsers != tt.admin || store.limit != tt.wantLimit {
+				t.Errorf("Expected scope %q with limit %d, got %q (allUsers=%v, limit=%d)", tt.wantScope, tt.wantLimit, response.Scope, store.allUsers, store.limit)
+			}
+			if response.Stats.Total.TotalBytes != 300 || len(response.Stats.Largest) != 1 || response.Stats.Largest[0].TotalBytes != 300 {
+				t.Errorf("Unexpected stats %+v", response.Stats)
+			}
+		})
+	}
+}
@@ -0,0 +1,190 @@
+package api
+
+import (
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// streamBufferTTL is how long a streamed answer can be resumed after its last write
+const streamBufferTTL = 5 * time.Minute
+
+// streamBuffer records the progressive output of a single /api/ask request so a
+// client whose connection dropped can reconnect and catch up
+type streamBuffer struct {
+	mu        sync.Mutex
+	userID    int64
+	sessionID string
+	data      []byte
+	done      bool
+	events    bool        // The answer is framed as server-sent events
+	endEvent  string      // Event that ended an answer framed as events, sent again on resume
+	endData   interface{} // Data of endEvent
+	updated   time.Time
+	changed   chan struct{} // Closed and replaced on every write and on completion
+}
+
+// Write appends streamed output and wakes any waiting readers
+func (b *streamBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.data = append(b.data, p...)
+	b.updated = time.Now()
+	close(b.changed)
+	b.changed = make(chan struct{})
+	return len(p), nil
+}
+
+// Finish marks the stream as complete
+func (b *streamBuffer) Finish() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.done {
+		return
+	}
+	b.done = true
+	b.updated = time.Now()
+	close(b.changed)
+	b.changed = make(chan struct{})
+}
+
+// SetEvents records that the answer is framed as server-sent events, so it is
+// resumed as events too
+func (b *streamBuffer) SetEvents() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.events = true
+}
+
+// Events reports whether the answer is framed as server-sent events
+func (b *streamBuffer) Events() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.events
+}
+
+// FinishWith marks the stream as complete with the event that ended it, which
+// resumed streams end with too
+func (b *streamBuffer) FinishWith(event string, data interface{}) {
+	b.mu.Lock()
+	b.endEvent, b.endData = event, data
+	b.mu.Unlock()
+	b.Finish()
+}
+
+// EndEvent returns the event the stream was finished with, empty if none
+func (b *streamBuffer) EndEvent() (string, interface{}) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.endEvent, b.endData
+}
+
+// ReadFrom returns the output after offset, whether the stream is complete and a
+// channel that is closed when more output is available
+func (b *streamBuffer) ReadFrom(offset int) ([]byte, bool, <-chan struct{}) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if offset < 0 {
+		offset = 0
+	}
+	if offset > len(b.data) {
+		offset = len(b.data)
+	}
+	chunk := make([]byte, len(b.data)-offset)
+	copy(chunk, b.data[offset:])
+	return chunk, b.done, b.changed
+}
+
+// streamBufferStore holds recent stream buffers keyed by request ID
+type streamBufferStore struct {
+	mu    sync.Mutex
+	items map[string]*streamBuffer
+	ttl   time.Duration
+	now   func() time.Time
+}
+
+// newStreamBufferStore creates an empty stream buffer store with the given TTL
+func newStreamBufferStore(ttl time.Duration) *streamBufferStore {
+	return &streamBufferStore{
+		items: make(map[string]*streamBuffer),
+		ttl:   ttl,
+		now:   time.Now,
+	}
+}
+
+// Start creates the buffer for a new request, dropping buffers idle for longer than the TTL
+func (s *streamBufferStore) Start(requestID string, userID int64, sessionID string) *streamBuffer {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	for id, buf := range s.items {
+		buf.mu.Lock()
+		expired := now.Sub(buf.updated) > s.ttl
+		buf.mu.Unlock()
+		if expired {
+			delete(s.items, id)
+		}
+	}
+
+	buf := &streamBuffer{
+		userID:    userID,
+		sessionID: sessionID,
+		updated:   now,
+		changed:   make(chan struct{}),
+	}
+	s.items[requestID] = buf
+	return buf
+}
+
+// Get returns the buffer for a request owned by userID
+func (s *streamBufferStore) Get(userID int64, requestID string) (*streamBuffer, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	buf, ok := s.items[requestID]
+	if !ok || buf.userID != userID {
+		return nil, false
+	}
+
+	buf.mu.Lock()
+	expired := s.now().Sub(buf.updated) > s.ttl
+	buf.mu.Unlock()
+	if expired {
+		delete(s.items, requestID)
+		return nil, false
+	}
+	return buf, true
+}
+
+// resumableWriter writes streamed output to both the client and a stream buffer
+// Once the client goes away, writes continue to the buffer only so generation can
+// finish and be resumed later
+type resumableWriter struct {
+	client     io.Writer
+	buffer     *streamBuffer
+	clientGone bool
+}
+
+// newResumableWriter creates a writer that tees client output into buffer
+func newResumableWriter(client io.Writer, buffer *streamBuffer) *resumableWriter {
+	return &resumableWriter{client: client, buffer: buffer}
+}
+
+// Write implements io.Writer and never fails because of a dropped client
+func (rw *resumableWriter) Write(p []byte) (int, error) {
+	rw.buffer.Write(p)
+
+	if !rw.clientGone {
+		if _, err := rw.client.Write(p); err != nil {
+			rw.clientGone = true
+		} else if f, ok := rw.client.(http.Flusher); ok {
+			f.Flush()
+		}
+	}
+	return len(p), nil
+}
@@ -0,0 +1,90 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// failingWriter simulates a client whose connection has dropped
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("connection reset")
+}
+
+// TestResumableWriterOutlivesClient tests that output is still buffered after the client goes away
+func TestResumableWriterOutlivesClient(t *testing.T) {
+	streams := newStreamBufferStore(time.Minute)
+	buf := streams.Start("req-1", 1, "session-1")
+
+	rw := newResumableWriter(failingWriter{}, buf)
+	for _, part := range []string{"Hello, ", "world"} {
+		if _, err := rw.Write([]byte(part)); err != nil {
+			t.Fatalf("Expected write to succeed after client disconnect, got %v", err)
+		}
+	}
+	buf.Finish()
+
+	data, done, _ := buf.ReadFrom(7)
+	if string(data) != "world" || !done {
+		t.Errorf("Expected remaining output and completion, got %q (done=%v)", data, done)
+	}
+
+	if _, ok := streams.Get(2, "req-1"); ok {
+		t.Error("Expected stream to be hidden from other users")
+	}
+}
+
+// TestStreamBufferExpiry tests that idle buffers expire after the TTL
+func TestStreamBufferExpiry(t *testing.T) {
+	now := time.Now()
+	streams := newStreamBufferStore(time.Minute)
+	streams.now = func() time.Time { return now }
+
+	streams.Start("req-1", 1, "session-1").Finish()
+
+	now = now.Add(2 * time.Minute)
+	if _, ok := streams.Get(1, "req-1"); ok {
+		t.Error("Expected stream buffer to expire")
+	}
+}
+
+// TestHandleAskStreamResume tests replaying a buffered answer from an offset while it completes
+func TestHandleAskStreamResume(t *testing.T) {
+	server := &Server{
+		logger:  &mockLoggerForAsk{},
+		streams: newStreamBufferStore(streamBufferTTL),
+	}
+
+	buf := server.streams.Start("req-1", 1, "session-1")
+	buf.Write([]byte("The answer "))
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		buf.Write([]byte("is 42."))
+		buf.Finish()
+	}()
+
+	req := httptest.NewRequest(http.MethodGet, "/api/ask/req-1/stream?from=4", nil)
+	w := serveRoute(server, withUser(req, 1))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	if got := w.Body.String(); got != "answer is 42." {
+		t.Errorf("Expected output from offset 4, got %q", got)
+	}
+	if w.Header().Get("X-Session-ID") != "session-1" {
+		t.Errorf("Expected session header, got %q", w.Header().Get("X-Session-ID"))
+	}
+
+	// Unknown and foreign streams are not found
+	req = httptest.NewRequest(http.MethodGet, "/api/ask/req-1/stream", nil)
+	w = serveRoute(server, withUser(req, 2))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for another user's stream, got %d", w.Code)
+	}
+}
@@ -0,0 +1,113 @@
+package api
+
+import (
+	"io"
+	"net/http"
+	"noodexx/internal/auth"
+	"strconv"
+	"time"
+)
+
+// handleAskStream handles GET /api/ask/{request_id}/stream?from=offset
+// Replays the buffered answer of an earlier /api/ask request from a byte offset and
+// keeps streaming until the answer is complete or the client disconnects again.
+// Answers framed as events are resumed as events, from the Last-Event-ID header
+// when the client sends one, and end with the event the answer ended with
+func (s *Server) handleAskStream(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	requestID := newRequestID(w)
+
+	// Create logger with request context
+	logger := s.logger.WithContext("request_id", requestID).
+		WithContext("method", r.Method).
+		WithContext("path", r.URL.Path)
+
+	logger.Debug("processing stream resume request")
+
+	ctx := r.Context()
+
+	// Extract user_id from context
+	userID, err := auth.GetUserID(ctx)
+	if err != nil {
+		logger.Error("request failed", "operation", "get_user_id", "error", err.Error())
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
+		return
+	}
+
+	// The original request ID from /api/ask/{id}/stream
+	askID := r.PathValue("id")
+
+	offset := 0
+	from := r.URL.Query().Get("from")
+	if lastEventID := r.Header.Get("Last-Event-ID"); lastEventID != "" {
+		from = lastEventID
+	}
+	if from != "" {
+		offset, err = strconv.Atoi(from)
+		if err != nil || offset < 0 {
+			logger.Error("request failed", "operation", "parse_offset", "error", "invalid offset")
+			writeError(w, http.StatusBadRequest, CodeBadRequest, "Invalid offset")
+			return
+		}
+	}
+
+	var buf *streamBuffer
+	var found bool
+	if s.streams != nil {
+		buf, found = s.streams.Get(userID, askID)
+	}
+	if !found {
+		logger.Error("request failed", "operation", "get_stream", "error", "stream not found or expired", "ask_request_id", askID)
+		writeError(w, http.StatusNotFound, CodeNotFound, "Stream not found or expired")
+		return
+	}
+
+	setAnswerHeaders(w, buf.Events())
+	w.Header().Set("X-Request-ID", askID)
+	w.Header().Set("X-Session-ID", buf.sessionID)
+
+	// Answers framed as events are resumed as token events with heartbeats
+	var out io.Writer = w
+	var sse *SSEWriter
+	if buf.Events() {
+		sse = NewSSEWriter(w)
+		out = newSSETokenWriter(sse, offset)
+		stopHeartbeat := sse.StartHeartbeat(s.sseHeartbeat())
+		defer stopHeartbeat()
+	}
+
+	for {
+		chunk, done, changed := buf.ReadFrom(offset)
+		if len(chunk) > 0 {
+			if _, err := out.Write(chunk); err != nil {
+				logger.Debug("client disconnected during resume", "offset", offset)
+				return
+			}
+			if f, ok := out.(http.Flusher); ok {
+				f.Flush()
+			}
+			offset += len(chunk)
+		}
+		if done {
+			break
+		}
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			logger.Debug("client disconnected during resume", "offset", offset)
+			return
+		}
+	}
+
+	if sse != nil {
+		event, data := buf.EndEvent()
+		if event == "" {
+			event, data = sseEventDone, askDone{RequestID: askID, SessionID: buf.sessionID, Bytes: offset}
+		}
+		sse.Event(event, data)
+	}
+
+	latency := time.Since(start).Milliseconds()
+	logger.Debug("request completed", "status", http.StatusOK, "latency_ms", latency, "ask_request_id", askID, "offset", offset)
+}
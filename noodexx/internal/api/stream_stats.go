@@ -0,0 +1,135 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"noodexx/internal/auth"
+	"strconv"
+	"time"
+)
+
+// Response stats periods, in days
+const (
+	defaultResponseStatsDays = 30
+	maxResponseStatsDays     = 365
+)
+
+// StreamStats describes how an answer was streamed. It is sent as a trailing
+// stats event after answers with progress events, and recorded in provenance.
+// Token counts are estimated from character count
+type StreamStats struct {
+	Provider         string  `json:"provider"`
+	Model            string  `json:"model"`
+	FirstTokenMS     int64   `json:"first_token_ms"` // From sending the prompt to the first token
+	GenerationMS     int64   `json:"generation_ms"`  // From sending the prompt to the end of the answer
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	TotalTokens      int     `json:"total_tokens"`
+	TokensPerSecond  float64 `json:"tokens_per_second"` // Over the time after the first token
+}
+
+// streamTimer passes an answer through, noting when its first bytes arrived
+type streamTimer struct {
+	w     io.Writer
+	start time.Time
+	first time.Duration
+}
+
+func newStreamTimer(w io.Writer) *streamTimer {
+	return &streamTimer{w: w, start: time.Now()}
+}
+
+// Write implements io.Writer
+func (t *streamTimer) Write(p []byte) (int, error) {
+	if t.first == 0 && len(p) > 0 {
+		t.first = max(time.Since(t.start), time.Millisecond)
+	}
+	return t.w.Write(p)
+}
+
+// record adds the answer's timings to its provenance and returns its stats
+func (t *streamTimer) record(p *MessageProvenance) StreamStats {
+	p.GenerationMS = max(time.Since(t.start).Milliseconds(), 1)
+	p.FirstTokenMS = min(t.first.Milliseconds(), p.GenerationMS)
+	if t.first == 0 {
+		// Nothing was streamed; the whole answer is its first token
+		p.FirstTokenMS = p.GenerationMS
+	}
+	return newStreamStats(p)
+}
+
+// newStreamStats computes an answer's stats from its provenance. Answers
+// that arrived at once are timed over the whole generation
+func newStreamStats(p *MessageProvenance) StreamStats {
+	stats := StreamStats{
+		Provider:         p.Provider,
+		Model:            p.Model,
+		FirstTokenMS:     p.FirstTokenMS,
+		GenerationMS:     p.GenerationMS,
+		PromptTokens:     p.PromptTokens,
+		CompletionTokens: p.CompletionTokens,
+		TotalTokens:      p.PromptTokens + p.CompletionTokens,
+	}
+	streaming := p.GenerationMS - p.FirstTokenMS
+	if streaming <= 0 {
+		streaming = p.GenerationMS
+	}
+	if streaming > 0 {
+		stats.TokensPerSecond = float64(p.CompletionTokens) / (float64(streaming) / 1000)
+	}
+	return stats
+}
+
+// handleResponseStats handles GET /api/stats/responses - how fast each provider
+// and model answered the user over the last days (default 30)
+func (s *Server) handleResponseStats(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	requestID := newRequestID(w)
+
+	// Create logger with request context
+	logger := s.logger.WithContext("request_id", requestID).
+		WithContext("method", r.Method).
+		WithContext("path", r.URL.Path)
+
+	logger.Debug("processing response stats request")
+
+	ctx := r.Context()
+	userID, err := auth.GetUserID(ctx)
+	if err != nil {
+		logger.Error("request failed", "operation", "get_user_id", "error", err.Error())
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
+		return
+	}
+
+	days := defaultResponseStatsDays
+	if v := r.URL.Query().Get("days"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > maxResponseStatsDays {
+			writeError(w, http.StatusBadRequest, CodeBadRequest, fmt.Sprintf("days must be between 1 and %d", maxResponseStatsDays))
+			return
+		}
+		days = n
+	}
+
+	stats, err := s.store.GetResponseStats(ctx, userID, time.Now().AddDate(0, 0, -days))
+	if err != nil {
+		logger.Error("request failed", "operation", "get_response_stats", "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to load response stats")
+		return
+	}
+	if stats == nil {
+		stats = []ResponseStats{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"days":    days,
+		"stats":   stats,
+	})
+
+	latency := time.Since(start).Milliseconds()
+	logger.Debug("request completed", "status", http.StatusOK, "latency_ms", latency, "models", len(stats))
+}
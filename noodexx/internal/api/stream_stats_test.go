@@ -0,0 +1,132 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"noodexx/internal/auth"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewStreamStats(t *testing.T) {
+	stats := newStreamStats(&MessageProvenance{Provider: "ollama", Model: "llama3.2", PromptTokens: 30, CompletionTokens: 100, FirstTokenMS: 500, GenerationMS: 2500})
+	if stats.TotalTokens != 130 || stats.TokensPerSecond != 50 {
+		t.Errorf("Expected 130 tokens at 50 per second, got %+v", stats)
+	}
+
+	// An answer that arrived at once is timed over the whole generation
+	stats = newStreamStats(&MessageProvenance{CompletionTokens: 100, FirstTokenMS: 2000, GenerationMS: 2000})
+	if stats.TokensPerSecond != 50 {
+		t.Errorf("Expected 50 tokens per second, got %+v", stats)
+	}
+}
+
+// statsStore records the provenance saved and serves fixed response stats
+type statsStore struct {
+	mockStoreForAsk
+	provenance *MessageProvenance
+	since      time.Time
+}
+
+func (m *statsStore) SaveChatMessageWithProvenance(ctx context.Context, userID int64, sessionID, role, content, providerMode string, citations []string, provenance *MessageProvenance) (int64, error) {
+	m.provenance = provenance
+	return 1, nil
+}
+
+func (m *statsStore) GetResponseStats(ctx context.Context, userID int64, since time.Time) ([]ResponseStats, error) {
+	m.since = since
+	return []ResponseStats{{Provider: "ollama", Model: "llama3.2", Responses: 2, AvgFirstTokenMS: 300, TokensPerSecond: 40}}, nil
+}
+
+func TestAskStreamStats(t *testing.T) {
+	store := &statsStore{}
+	provider := &mockProviderForAsk{name: "ollama", isLocal: true, streamFunc: func(ctx context.Context, messages []Message, w io.Writer) (string, error) {
+		time.Sleep(5 * time.Millisecond)
+		w.Write([]byte("Hello "))
+		time.Sleep(5 * time.Millisecond)
+		w.Write([]byte("there"))
+		return "Hello there", nil
+	}}
+	server := &Server{
+		store:           store,
+		logger:          &mockLoggerForAsk{},
+		providerManager: &modelProviderManager{mockProviderManagerForAsk{provider: provider}, "llama3.2"},
+		ragEnforcer:     &mockRAGEnforcerForAsk{},
+	}
+	ask := func(progress bool) string {
+		body, _ := json.Marshal(map[string]interface{}{"query": "Hi", "session_id": "s1", "progress": progress})
+		req := httptest.NewRequest(http.MethodPost, "/api/ask", bytes.NewReader(body))
+		req = req.WithContext(context.WithValue(req.Context(), auth.UserIDKey, int64(1)))
+		w := httptest.NewRecorder()
+		server.handleAsk(w, req)
+		return w.Body.String()
+	}
+
+	body := ask(true)
+	_, rendered, found := strings.Cut(body, "Hello there\n\nevent: rendered\ndata: ")
+	if !found {
+		t.Fatalf("Expected the rendered answer after the answer, got %q", body)
+	}
+	var answer struct {
+		HTML string `json:"html"`
+	}
+	if line, _, _ := strings.Cut(rendered, "\n"); json.Unmarshal([]byte(line), &answer) != nil || answer.HTML != "<p>Hello there</p>\n" {
+		t.Errorf("Unexpected rendered answer %q", line)
+	}
+	_, trailer, found := strings.Cut(body, "\n\nevent: stats\ndata: ")
+	if !found {
+		t.Fatalf("Expected a stats event after the answer, got %q", body)
+	}
+	var stats StreamStats
+	if line, _, _ := strings.Cut(trailer, "\n"); json.Unmarshal([]byte(line), &stats) != nil {
+		t.Fatalf("Invalid stats event %q", trailer)
+	}
+	if stats.Provider != "ollama" || stats.Model != "llama3.2" || stats.FirstTokenMS < 5 || stats.GenerationMS < stats.FirstTokenMS+5 || stats.TokensPerSecond <= 0 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+	p := store.provenance
+	if p == nil || p.FirstTokenMS != stats.FirstTokenMS || p.GenerationMS != stats.GenerationMS {
+		t.Errorf("Expected the timings saved with the answer, got %+v", p)
+	}
+
+	// Clients without progress events get the answer alone
+	if body := ask(false); body != "Hello there" {
+		t.Errorf("Expected only the answer, got %q", body)
+	}
+}
+
+func TestResponseStats(t *testing.T) {
+	store := &statsStore{}
+	server := &Server{store: store, logger: &mockLoggerForAsk{}}
+	get := func(query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/stats/responses"+query, nil)
+		req = req.WithContext(context.WithValue(req.Context(), auth.UserIDKey, int64(1)))
+		w := httptest.NewRecorder()
+		server.handleResponseStats(w, req)
+		return w
+	}
+
+	w := get("?days=7")
+	var resp struct {
+		Days  int             `json:"days"`
+		Stats []ResponseStats `json:"stats"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if w.Code != http.StatusOK || resp.Days != 7 || len(resp.Stats) != 1 || resp.Stats[0].TokensPerSecond != 40 {
+		t.Fatalf("Unexpected response: %d %s", w.Code, w.Body.String())
+	}
+	if age := time.Since(store.since); age < 7*24*time.Hour-time.Minute || age > 7*24*time.Hour+time.Minute {
+		t.Errorf("Expected stats since a week ago, got %v", store.since)
+	}
+
+	for _, query := range []string{"?days=0", "?days=366", "?days=week"} {
+		if w := get(query); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", query, w.Code)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"noodexx/internal/auth"
+	"noodexx/internal/markdown"
+	"time"
+)
+
+// SummaryRegenerateResult is the outcome of regenerating one document summary
+type SummaryRegenerateResult struct {
+	Source      string `json:"source"`
+	Regenerated bool   `json:"regenerated"`
+	Error       string `json:"error,omitempty"`
+}
+
+// handleGetSummaries handles GET /api/library/summaries - the summaries of the
+// user's documents with the model and date they were generated, and whether
+// they are stale
+func (s *Server) handleGetSummaries(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	requestID := newRequestID(w)
+
+	logger := s.logger.WithContext("request_id", requestID).
+		WithContext("method", r.Method).
+		WithContext("path", r.URL.Path)
+
+	logger.Debug("processing get summaries request")
+
+	ctx := r.Context()
+
+	userID, err := auth.GetUserID(ctx)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
+		return
+	}
+
+	model := s.summaryModel()
+	summaries, err := s.store.GetDocumentSummaries(ctx, userID, model)
+	if err != nil {
+		logger.Error("request failed", "operation", "get_document_summaries", "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to get summaries")
+		return
+	}
+	if summaries == nil {
+		summaries = []DocumentSummary{}
+	}
+
+	stale := 0
+	for i, summary := range summaries {
+		if summary.Stale != "" {
+			stale++
+		}
+		summaries[i].SummaryHTML = markdown.Render(summary.Summary)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":   true,
+		"model":     model,
+		"stale":     stale,
+		"summaries": summaries,
+	})
+
+	latency := time.Since(start).Milliseconds()
+	logger.Debug("request completed", "status", http.StatusOK, "latency_ms", latency, "count", len(summaries), "stale", stale)
+}
+
+// handleRegenerateSummaries handles POST /api/library/summaries/regenerate -
+// summarize a document again, or every document with a stale summary when no
+// source is given
+func (s *Server) handleRegenerateSummaries(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	requestID := newRequestID(w)
+
+	logger := s.logger.WithContext("request_id", requestID).
+		WithContext("method", r.Method).
+		WithContext("path", r.URL.Path)
+
+	logger.Debug("processing regenerate summaries request")
+
+	ctx := r.Context()
+
+	userID, err := auth.GetUserID(ctx)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
+		return
+	}
+
+	if s.summaries == nil {
+		writeError(w, http.StatusNotImplemented, CodeNotImplemented, "Summary regeneration is not available")
+		return
+	}
+
+	var req struct {
+		Source string `json:"source"` // Empty regenerates every stale summary
+	}
+	if !decodeJSON(w, r, logger, &req) {
+		return
+	}
+
+	summaries, err := s.store.GetDocumentSummaries(ctx, userID, s.summaryModel())
+	if err != nil {
+		logger.Error("request failed", "operation", "get_document_summaries", "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to get summaries")
+		return
+	}
+
+	var sources []string
+	for _, summary := range summaries {
+		if (req.Source == "" && summary.Stale != "") || summary.Source == req.Source {
+			sources = append(sources, summary.Source)
+		}
+	}
+	if req.Source != "" && len(sources) == 0 {
+		writeError(w, http.StatusNotFound, CodeNotFound, "Document not found")
+		return
+	}
+
+	results := make([]SummaryRegenerateResult, 0, len(sources))
+	for _, source := range sources {
+		result := SummaryRegenerateResult{Source: source, Regenerated: true}
+		if err := s.summaries.RegenerateSummary(ctx, userID, source); err != nil {
+			logger.Warn("summary regeneration failed", "source", source, "error", err.Error())
+			result.Regenerated = false
+			result.Error = err.Error()
+		}
+		results = append(results, result)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"results": results,
+	})
+
+	latency := time.Since(start).Milliseconds()
+	logger.Debug("request completed", "status", http.StatusOK, "latency_ms", latency, "regenerated", len(results))
+}
+
+// summaryModel returns the model summaries are generated with, empty when unknown
+func (s *Server) summaryModel() string {
+	if s.summaries == nil {
+		return ""
+	}
+	return s.summaries.SummaryModel()
+}
@@ -0,0 +1,102 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// mockStoreForSummaries has one current, one stale and one failing document
+type mockStoreForSummaries struct {
+	mockStore
+}
+
+func (m *mockStoreForSummaries) GetDocumentSummaries(ctx context.Context, userID int64, model string) ([]DocumentSummary, error) {
+	return []DocumentSummary{
+		{Source: "current.md", Summary: "Up to date", Model: model},
+		{Source: "edited.md", Summary: "Outdated", Model: model, Stale: "content_changed"},
+		{Source: "broken.md", Stale: "untracked"},
+	}, nil
+}
+
+// mockSummaryRegenerator fails documents whose source contains "broken"
+type mockSummaryRegenerator struct {
+	regenerated []string
+}
+
+func (m *mockSummaryRegenerator) RegenerateSummary(ctx context.Context, userID int64, source string) error {
+	m.regenerated = append(m.regenerated, source)
+	if strings.Contains(source, "broken") {
+		return errors.New("provider unavailable")
+	}
+	return nil
+}
+
+func (m *mockSummaryRegenerator) SummaryModel() string {
+	return "llama3.2"
+}
+
+func TestHandleGetSummaries(t *testing.T) {
+	server := &Server{store: &mockStoreForSummaries{}, logger: &mockLogger{}}
+	server.SetSummaryRegenerator(&mockSummaryRegenerator{})
+
+	w := serveRoute(server, withUser(httptest.NewRequest(http.MethodGet, "/api/library/summaries", nil), 1))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Model     string            `json:"model"`
+		Stale     int               `json:"stale"`
+		Summaries []DocumentSummary `json:"summaries"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if resp.Model != "llama3.2" || resp.Stale != 2 || len(resp.Summaries) != 3 {
+		t.Errorf("Expected 3 summaries with 2 stale for llama3.2, got %+v", resp)
+	}
+}
+
+func TestHandleRegenerateSummaries(t *testing.T) {
+	regenerator := &mockSummaryRegenerator{}
+	server := &Server{store: &mockStoreForSummaries{}, logger: &mockLogger{}}
+
+	regenerate := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/library/summaries/regenerate", strings.NewReader(body))
+		return serveRoute(server, withUser(req, 1))
+	}
+
+	if w := regenerate(`{}`); w.Code != http.StatusNotImplemented {
+		t.Errorf("Expected status 501 without a regenerator, got %d", w.Code)
+	}
+	server.SetSummaryRegenerator(regenerator)
+
+	// Without a source only stale summaries are regenerated
+	w := regenerate(`{}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Results []SummaryRegenerateResult `json:"results"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if len(resp.Results) != 2 || !resp.Results[0].Regenerated || resp.Results[1].Regenerated || resp.Results[1].Error == "" {
+		t.Errorf("Expected edited.md to regenerate and broken.md to fail, got %+v", resp.Results)
+	}
+
+	// A named document is regenerated even when current
+	regenerator.regenerated = nil
+	if w := regenerate(`{"source":"current.md"}`); w.Code != http.StatusOK || len(regenerator.regenerated) != 1 {
+		t.Errorf("Expected current.md to be regenerated, got status %d and %v", w.Code, regenerator.regenerated)
+	}
+
+	if w := regenerate(`{"source":"other.md"}`); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for an unknown document, got %d", w.Code)
+	}
+}
@@ -0,0 +1,47 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// handleAdminTelemetry handles GET /api/admin/telemetry - whether anonymous
+// usage reports are sent, and the exact report that would be sent now
+func (s *Server) handleAdminTelemetry(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	requestID := newRequestID(w)
+
+	// Create logger with request context
+	logger := s.logger.WithContext("request_id", requestID).
+		WithContext("method", r.Method).
+		WithContext("path", r.URL.Path)
+
+	logger.Debug("processing telemetry preview request")
+
+	if s.telemetry == nil {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"status":  TelemetryStatus{DisabledBy: "config"},
+		})
+		return
+	}
+
+	preview, err := s.telemetry.Preview(r.Context())
+	if err != nil {
+		logger.Error("request failed", "operation", "telemetry_preview", "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to build telemetry report")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"status":  s.telemetry.Status(),
+		"report":  json.RawMessage(preview),
+	})
+
+	latency := time.Since(start).Milliseconds()
+	logger.Debug("request completed", "latency_ms", latency)
+}
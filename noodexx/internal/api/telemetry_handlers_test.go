@@ -0,0 +1,46 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type mockTelemetry struct {
+	status TelemetryStatus
+}
+
+func (m *mockTelemetry) Preview(ctx context.Context) ([]byte, error) {
+	return []byte(`{"schema_version": 1, "library_size": "11-100"}`), nil
+}
+
+func (m *mockTelemetry) Status() TelemetryStatus {
+	return m.status
+}
+
+func TestHandleAdminTelemetry(t *testing.T) {
+	server := &Server{store: &mockStore{}, logger: &mockLogger{}}
+	get := func() string {
+		w := httptest.NewRecorder()
+		server.handleAdminTelemetry(w, httptest.NewRequest(http.MethodGet, "/api/admin/telemetry", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+		return w.Body.String()
+	}
+
+	if body := get(); !strings.Contains(body, `"enabled":false`) || strings.Contains(body, `"report"`) {
+		t.Errorf("Expected telemetry off without a reporter, got %s", body)
+	}
+
+	server.SetTelemetry(&mockTelemetry{status: TelemetryStatus{DisabledBy: "DO_NOT_TRACK"}})
+	body := get()
+	if !strings.Contains(body, `"disabled_by":"DO_NOT_TRACK"`) {
+		t.Errorf("Expected the off switch in the status, got %s", body)
+	}
+	if !strings.Contains(body, `"report":{"schema_version":1,"library_size":"11-100"}`) {
+		t.Errorf("Expected the preview in the response, got %s", body)
+	}
+}
@@ -0,0 +1,141 @@
+package api
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// keepAliveComment is an SSE comment. Clients ignore it; it keeps proxies and
+// browsers from giving up on an answer whose model is still loading
+const keepAliveComment = ": keep-alive\n\n"
+
+// WriteTimeouts bounds how long writing the response may take per route. The
+// deadline runs from the arrival of the request, so streamed answers need far
+// longer than other routes
+type WriteTimeouts struct {
+	Default time.Duration            // Routes without their own timeout; 0 leaves them unlimited
+	Routes  map[string]time.Duration // Exact paths, or path prefixes when the key ends in "/"; 0 is unlimited
+}
+
+// DefaultWriteTimeouts returns the timeouts for every route given the general
+// write timeout and the limit on streamed answers
+func DefaultWriteTimeouts(write, stream time.Duration) WriteTimeouts {
+	return WriteTimeouts{
+		Default: write,
+		Routes: map[string]time.Duration{
+			"/api/ask":  stream,
+			"/api/ask/": stream, // Resuming a dropped answer stream
+		},
+	}
+}
+
+// timeoutFor returns the timeout of path: an exact match, else the longest matching prefix, else the default
+func (t WriteTimeouts) timeoutFor(path string) time.Duration {
+	return routeSetting(t.Routes, t.Default, path)
+}
+
+// WriteTimeoutMiddleware sets the write deadline of each response from its
+// route. It must be the outermost handler, as the deadline is set on the
+// connection's own response writer
+func WriteTimeoutMiddleware(timeouts WriteTimeouts) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			deadline := time.Time{}
+			if timeout := timeouts.timeoutFor(r.URL.Path); timeout > 0 {
+				deadline = time.Now().Add(timeout)
+			}
+			// Writers without deadlines, such as test recorders, are left as they are
+			http.NewResponseController(w).SetWriteDeadline(deadline)
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), writeDeadlineKey{}, deadline)))
+		})
+	}
+}
+
+// writeDeadlineKey carries the write deadline WriteTimeoutMiddleware set
+type writeDeadlineKey struct{}
+
+// writeDeadline returns the write deadline of the response to the request of
+// ctx, zero when it has none. Handlers that change the deadline for a while
+// restore it
+func writeDeadline(ctx context.Context) time.Time {
+	deadline, _ := ctx.Value(writeDeadlineKey{}).(time.Time)
+	return deadline
+}
+
+// SetStreamKeepAlive sets how often a keep-alive comment is sent while an
+// answer waits for its first token, such as while the model loads; 0 sends none
+func (s *Server) SetStreamKeepAlive(interval time.Duration) {
+	s.streamKeepAlive = interval
+}
+
+// warmupKeepAlive sends keep-alive comments to a client until the answer's
+// first byte. Writes pass through to the answer; the comments go to the client
+// directly, so they are not part of the answer or its resumable buffer
+type warmupKeepAlive struct {
+	answer io.Writer
+	client io.Writer
+
+	mu       sync.Mutex
+	started  bool
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+// startKeepAlive starts sending keep-alive comments to client every interval
+// until the first write to the returned writer or Stop. With interval 0 no
+// comments are sent
+func startKeepAlive(answer, client io.Writer, interval time.Duration) *warmupKeepAlive {
+	k := &warmupKeepAlive{answer: answer, client: client, stop: make(chan struct{}), done: make(chan struct{})}
+	if interval <= 0 {
+		k.started = true
+		k.stopOnce.Do(func() { close(k.stop) })
+		close(k.done)
+		return k
+	}
+	go k.run(interval)
+	return k
+}
+
+func (k *warmupKeepAlive) run(interval time.Duration) {
+	defer close(k.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			// Holding the lock orders the comment before any answer output
+			k.mu.Lock()
+			if k.started {
+				k.mu.Unlock()
+				return
+			}
+			io.WriteString(k.client, keepAliveComment)
+			k.mu.Unlock()
+		case <-k.stop:
+			return
+		}
+	}
+}
+
+// Write ends the keep-alives and writes p to the answer
+func (k *warmupKeepAlive) Write(p []byte) (int, error) {
+	k.mu.Lock()
+	k.started = true
+	k.mu.Unlock()
+	k.stopOnce.Do(func() { close(k.stop) })
+	return k.answer.Write(p)
+}
+
+// Stop ends the keep-alives and waits until none is being written. It is
+// called once the answer is done, including when it failed before its first byte
+func (k *warmupKeepAlive) Stop() {
+	k.mu.Lock()
+	k.started = true
+	k.mu.Unlock()
+	k.stopOnce.Do(func() { close(k.stop) })
+	<-k.done
+}
@@ -0,0 +1,129 @@
+package api
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestWriteTimeoutsTimeoutFor tests that answer streams get the stream timeout
+// and other routes the default
+func TestWriteTimeoutsTimeoutFor(t *testing.T) {
+	timeouts := DefaultWriteTimeouts(15*time.Second, 0)
+	timeouts.Routes["/api/export/"] = time.Minute
+
+	tests := []struct {
+		path string
+		want time.Duration
+	}{
+		{"/api/ask", 0},
+		{"/api/ask/abc123/stream", 0},
+		{"/api/export/pdf", time.Minute},
+		{"/api/library", 15 * time.Second},
+	}
+	for _, tt := range tests {
+		if got := timeouts.timeoutFor(tt.path); got != tt.want {
+			t.Errorf("timeoutFor(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+// TestWriteTimeoutMiddleware tests that a slow response fails on a route with a
+// timeout and completes on one without
+func TestWriteTimeoutMiddleware(t *testing.T) {
+	slow := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+		io.WriteString(w, "done")
+	})
+	timeouts := WriteTimeouts{
+		Default: 50 * time.Millisecond,
+		Routes:  map[string]time.Duration{"/api/ask": 0},
+	}
+	server := httptest.NewServer(WriteTimeoutMiddleware(timeouts)(slow))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/api/ask")
+	if err != nil {
+		t.Fatalf("Expected the stream route to complete, got %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "done" {
+		t.Errorf("Expected the full response, got %q", body)
+	}
+
+	if resp, err := http.Get(server.URL + "/api/library"); err == nil {
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err == nil && string(body) == "done" {
+			t.Error("Expected the response to be cut off by the write timeout")
+		}
+	}
+	// Handlers learn the deadline, to restore it after changing it
+	var deadline time.Time
+	capture := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		deadline = writeDeadline(r.Context())
+	})
+	WriteTimeoutMiddleware(timeouts)(capture).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/library", nil))
+	if until := time.Until(deadline); until <= 0 || until > 50*time.Millisecond {
+		t.Errorf("Expected a deadline within 50ms, got %v", until)
+	}
+	WriteTimeoutMiddleware(timeouts)(capture).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/ask", nil))
+	if !deadline.IsZero() {
+		t.Errorf("Expected no deadline for the stream route, got %v", deadline)
+	}
+}
+
+// syncBuffer is a bytes.Buffer safe for the keep-alive goroutine
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// TestWarmupKeepAlive tests that keep-alive comments go to the client until the
+// answer's first byte, and not into the answer
+func TestWarmupKeepAlive(t *testing.T) {
+	client := &syncBuffer{}
+	answer := &syncBuffer{}
+
+	keepAlive := startKeepAlive(io.MultiWriter(answer, client), client, 10*time.Millisecond)
+	time.Sleep(45 * time.Millisecond)
+	io.WriteString(keepAlive, "Hello")
+	time.Sleep(30 * time.Millisecond)
+	keepAlive.Stop()
+
+	got := client.String()
+	comments := strings.Count(got, keepAliveComment)
+	if comments < 2 || got != strings.Repeat(keepAliveComment, comments)+"Hello" {
+		t.Errorf("Expected keep-alives only before the answer, got %q", got)
+	}
+	if answer.String() != "Hello" {
+		t.Errorf("Expected the answer without keep-alives, got %q", answer.String())
+	}
+
+	// With no interval nothing is sent, and Stop returns at once
+	client = &syncBuffer{}
+	keepAlive = startKeepAlive(client, client, 0)
+	time.Sleep(20 * time.Millisecond)
+	keepAlive.Stop()
+	if client.String() != "" {
+		t.Errorf("Expected no keep-alives, got %q", client.String())
+	}
+}
@@ -0,0 +1,171 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Transcript query limits. An export reads at most maxTranscriptExport
+// transcripts; narrow the time range to export more
+const (
+	defaultTranscriptLimit = 100
+	maxTranscriptLimit     = 1000
+	maxTranscriptExport    = 100000
+)
+
+// transcriptsArchived reports whether what the user asks in a mode is
+// archived: the archive is enabled and answers come from a cloud provider
+func (s *Server) transcriptsArchived(local bool) bool {
+	return s.transcripts != nil && !local
+}
+
+// handleTranscripts handles GET /api/admin/transcripts - list archived cloud
+// provider requests without their contents (admin only)
+// Supported filters: user_id, provider, since and until (RFC 3339) and limit
+func (s *Server) handleTranscripts(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	requestID := newRequestID(w)
+
+	// Create logger with request context
+	logger := s.logger.WithContext("request_id", requestID).
+		WithContext("method", r.Method).
+		WithContext("path", r.URL.Path)
+
+	logger.Debug("processing transcripts request")
+
+	if s.transcripts == nil {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success":     true,
+			"enabled":     false,
+			"transcripts": []Transcript{},
+		})
+		return
+	}
+
+	filter, err := parseTranscriptFilter(r, defaultTranscriptLimit, maxTranscriptLimit)
+	if err != nil {
+		logger.Error("request failed", "operation", "parse_filter", "error", err.Error())
+		writeError(w, http.StatusBadRequest, CodeBadRequest, err.Error())
+		return
+	}
+
+	transcripts, err := s.transcripts.Transcripts(r.Context(), filter, false)
+	if err != nil {
+		logger.Error("request failed", "operation", "get_transcripts", "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to read transcripts")
+		return
+	}
+	if transcripts == nil {
+		transcripts = []Transcript{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":            true,
+		"enabled":            true,
+		"retention_days":     s.transcripts.RetentionDays(),
+		"include_embeddings": s.transcripts.IncludesEmbeddings(),
+		"transcripts":        transcripts,
+	})
+
+	latency := time.Since(start).Milliseconds()
+	logger.Debug("request completed", "status", http.StatusOK, "latency_ms", latency, "count", len(transcripts))
+}
+
+// handleExportTranscripts handles GET /api/admin/transcripts/export - download
+// archived cloud provider requests with their decrypted contents as JSON
+// Lines, one transcript per line (admin only). Takes the same filters as
+// /api/admin/transcripts, with a default limit of everything up to
+// maxTranscriptExport. Every export is audited
+func (s *Server) handleExportTranscripts(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	requestID := newRequestID(w)
+
+	// Create logger with request context
+	logger := s.logger.WithContext("request_id", requestID).
+		WithContext("method", r.Method).
+		WithContext("path", r.URL.Path)
+
+	logger.Debug("processing export transcripts request")
+
+	if s.transcripts == nil {
+		logger.Warn("request failed", "operation", "export_transcripts", "error", "transcript archive disabled")
+		writeError(w, http.StatusNotFound, CodeNotFound, "Transcript archive is not enabled")
+		return
+	}
+
+	filter, err := parseTranscriptFilter(r, maxTranscriptExport, maxTranscriptExport)
+	if err != nil {
+		logger.Error("request failed", "operation", "parse_filter", "error", err.Error())
+		writeError(w, http.StatusBadRequest, CodeBadRequest, err.Error())
+		return
+	}
+
+	ctx := r.Context()
+	transcripts, err := s.transcripts.Transcripts(ctx, filter, true)
+	if err != nil {
+		logger.Error("request failed", "operation", "get_transcripts", "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to read transcripts")
+		return
+	}
+
+	// Audit log
+	s.store.AddAuditEntry(ctx, "export", fmt.Sprintf("Exported %d provider transcripts", len(transcripts)), r.URL.RawQuery)
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "transcripts-"+time.Now().UTC().Format("20060102-150405")+".jsonl"))
+	enc := json.NewEncoder(w)
+	for _, t := range transcripts {
+		if err := enc.Encode(t); err != nil {
+			logger.Error("request failed", "operation", "write_export", "error", err.Error())
+			return
+		}
+	}
+
+	latency := time.Since(start).Milliseconds()
+	logger.Info("exported provider transcripts", "count", len(transcripts), "latency_ms", latency)
+}
+
+// parseTranscriptFilter reads transcript filters from the query string
+func parseTranscriptFilter(r *http.Request, defaultLimit, maxLimit int) (TranscriptFilter, error) {
+	q := r.URL.Query()
+	filter := TranscriptFilter{
+		Provider: q.Get("provider"),
+		Limit:    defaultLimit,
+	}
+
+	if v := q.Get("user_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return filter, fmt.Errorf("user_id must be a number")
+		}
+		filter.UserID = id
+	}
+	if v := q.Get("since"); v != "" {
+		since, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return filter, fmt.Errorf("since must be an RFC 3339 timestamp")
+		}
+		filter.Since = since
+	}
+	if v := q.Get("until"); v != "" {
+		until, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return filter, fmt.Errorf("until must be an RFC 3339 timestamp")
+		}
+		filter.Until = until
+	}
+	if v := q.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 || limit > maxLimit {
+			return filter, fmt.Errorf("limit must be between 1 and %d", maxLimit)
+		}
+		filter.Limit = limit
+	}
+
+	return filter, nil
+}
@@ -0,0 +1,151 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeTranscriptArchive serves fixed transcripts and records the filters used
+type fakeTranscriptArchive struct {
+	transcripts []Transcript
+	filter      TranscriptFilter
+	decrypted   bool
+}
+
+func (f *fakeTranscriptArchive) Transcripts(ctx context.Context, filter TranscriptFilter, decrypt bool) ([]Transcript, error) {
+	f.filter, f.decrypted = filter, decrypt
+	out := make([]Transcript, len(f.transcripts))
+	for i, t := range f.transcripts {
+		if !decrypt {
+			t.Payload = nil
+		}
+		out[i] = t
+	}
+	return out, nil
+}
+
+func (f *fakeTranscriptArchive) RetentionDays() int       { return 90 }
+func (f *fakeTranscriptArchive) IncludesEmbeddings() bool { return false }
+
+// auditStore records audit entries
+type auditStore struct {
+	mockStoreForAsk
+	audits []string
+}
+
+func (m *auditStore) AddAuditEntry(ctx context.Context, opType, details, userCtx string) error {
+	m.audits = append(m.audits, opType+": "+details)
+	return nil
+}
+
+func TestTranscriptsList(t *testing.T) {
+	server := &Server{store: &auditStore{}, logger: &mockLoggerForAsk{}}
+
+	// Disabled archives list nothing
+	w := httptest.NewRecorder()
+	server.handleTranscripts(w, httptest.NewRequest(http.MethodGet, "/api/admin/transcripts", nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"enabled":false`) {
+		t.Fatalf("Expected a disabled archive, got %d %s", w.Code, w.Body.String())
+	}
+
+	archive := &fakeTranscriptArchive{transcripts: []Transcript{{
+		ID: 1, Time: time.Now(), UserID: 2, Provider: "openai", Model: "gpt-4o", Operation: "chat",
+		Payload: &TranscriptPayload{Messages: []Message{{Role: "user", Content: "secret question"}}, Response: "secret answer"},
+	}}}
+	server.SetTranscripts(archive)
+
+	w = httptest.NewRecorder()
+	server.handleTranscripts(w, httptest.NewRequest(http.MethodGet, "/api/admin/transcripts?user_id=2&provider=openai&since=2026-01-01T00:00:00Z&until=2026-02-01T00:00:00Z", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d %s", w.Code, w.Body.String())
+	}
+	if strings.Contains(w.Body.String(), "secret") || archive.decrypted {
+		t.Errorf("Expected the list without contents, got %s", w.Body.String())
+	}
+	f := archive.filter
+	if f.UserID != 2 || f.Provider != "openai" || f.Since.Month() != time.January || f.Until.Month() != time.February || f.Limit != defaultTranscriptLimit {
+		t.Errorf("Unexpected filter: %+v", f)
+	}
+
+	for _, query := range []string{"user_id=x", "since=yesterday", "until=soon", "limit=0", "limit=1001"} {
+		w := httptest.NewRecorder()
+		server.handleTranscripts(w, httptest.NewRequest(http.MethodGet, "/api/admin/transcripts?"+query, nil))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", query, w.Code)
+		}
+	}
+}
+
+func TestTranscriptsExport(t *testing.T) {
+	store := &auditStore{}
+	server := &Server{store: store, logger: &mockLoggerForAsk{}}
+
+	w := httptest.NewRecorder()
+	server.handleExportTranscripts(w, httptest.NewRequest(http.MethodGet, "/api/admin/transcripts/export", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 with the archive disabled, got %d", w.Code)
+	}
+
+	archive := &fakeTranscriptArchive{transcripts: []Transcript{
+		{ID: 1, Provider: "openai", Operation: "chat", Payload: &TranscriptPayload{Response: "first"}},
+		{ID: 2, Provider: "openai", Operation: "chat", DecryptError: "sealed with another key"},
+	}}
+	server.SetTranscripts(archive)
+
+	w = httptest.NewRecorder()
+	server.handleExportTranscripts(w, httptest.NewRequest(http.MethodGet, "/api/admin/transcripts/export?provider=openai", nil))
+	if w.Code != http.StatusOK || !archive.decrypted || archive.filter.Limit != maxTranscriptExport {
+		t.Fatalf("Expected a decrypted export, got %d %+v", w.Code, archive.filter)
+	}
+	if !strings.Contains(w.Header().Get("Content-Disposition"), ".jsonl") {
+		t.Errorf("Expected a JSON Lines attachment, got %q", w.Header().Get("Content-Disposition"))
+	}
+
+	var lines []Transcript
+	scanner := bufio.NewScanner(w.Body)
+	for scanner.Scan() {
+		var tr Transcript
+		if err := json.Unmarshal(scanner.Bytes(), &tr); err != nil {
+			t.Fatalf("Invalid line %q: %v", scanner.Text(), err)
+		}
+		lines = append(lines, tr)
+	}
+	if len(lines) != 2 || lines[0].Payload == nil || lines[0].Payload.Response != "first" || lines[1].DecryptError == "" {
+		t.Errorf("Unexpected export: %+v", lines)
+	}
+	if len(store.audits) != 1 || store.audits[0] != "export: Exported 2 provider transcripts" {
+		t.Errorf("Expected the export audited, got %v", store.audits)
+	}
+}
+
+func TestTranscriptsArchivedNotice(t *testing.T) {
+	provider := &mockProviderForAsk{name: "openai", isLocal: false}
+	server := &Server{logger: &mockLoggerForAsk{}, providerManager: &mockProviderManagerForAsk{provider: provider}, ragEnforcer: &mockRAGEnforcerForAsk{}}
+	status := func() bool {
+		w := httptest.NewRecorder()
+		server.handlePrivacyStatus(w, httptest.NewRequest(http.MethodGet, "/api/privacy-toggle", nil))
+		var resp struct {
+			TranscriptsArchived bool `json:"transcripts_archived"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return resp.TranscriptsArchived
+	}
+
+	if status() {
+		t.Error("Expected no notice without an archive")
+	}
+	server.SetTranscripts(&fakeTranscriptArchive{})
+	if !status() {
+		t.Error("Expected a notice for cloud requests with an archive")
+	}
+	provider.isLocal = true
+	if status() {
+		t.Error("Expected no notice in local mode")
+	}
+}
@@ -0,0 +1,331 @@
+package api
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"noodexx/internal/events"
+	"noodexx/internal/validate"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxUsersImported caps the rows of one user import
+const maxUsersImported = 1000
+
+// UserImportResult is the outcome of one row of a user import
+type UserImportResult struct {
+	Row               int    `json:"row"` // Line in the CSV, the header being line 1
+	Username          string `json:"username"`
+	Email             string `json:"email,omitempty"`
+	IsAdmin           bool   `json:"is_admin"`
+	Status            string `json:"status"`                       // "created" or "failed"
+	TemporaryPassword string `json:"temporary_password,omitempty"` // Only in this response; the user must change it at first sign-in
+	Error             string `json:"error,omitempty"`
+}
+
+// userImportRow is a parsed row of an import before the account is created
+type userImportRow struct {
+	line     int
+	username string
+	email    string
+	isAdmin  string
+}
+
+// handleImportUsers handles POST /api/admin/users/import - create accounts from a
+// CSV with a username, email and is_admin header (admin only). Each account gets
+// a generated temporary password; the response lists them per row, as JSON or,
+// with ?format=csv, as a CSV to hand out with the counts in X-Users-Created and
+// X-Users-Failed. The passwords are not shown again
+func (s *Server) handleImportUsers(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	requestID := newRequestID(w)
+
+	logger := s.logger.WithContext("request_id", requestID).
+		WithContext("method", r.Method).
+		WithContext("path", r.URL.Path)
+
+	logger.Debug("processing import users request")
+
+	ctx := r.Context()
+
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "json" && format != "csv" {
+		writeError(w, http.StatusBadRequest, CodeBadRequest, "format must be json or csv")
+		return
+	}
+
+	// The CSV is the request body, or a file field of a form upload
+	var body io.Reader = r.Body
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			logger.Error("request failed", "operation", "get_file", "error", err.Error())
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				writeBodyTooLarge(w, tooLarge.Limit)
+				return
+			}
+			writeError(w, http.StatusBadRequest, CodeBadRequest, "A CSV file is required")
+			return
+		}
+		defer file.Close()
+		body = file
+	}
+
+	rows, err := parseUserImport(body)
+	if err != nil {
+		logger.Warn("request failed", "operation", "parse_csv", "error", err.Error())
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeBodyTooLarge(w, tooLarge.Limit)
+			return
+		}
+		writeError(w, http.StatusBadRequest, CodeBadRequest, err.Error())
+		return
+	}
+
+	// Rows are created one by one so a bad row does not stop the rest
+	results := make([]UserImportResult, len(rows))
+	created := 0
+	for i, row := range rows {
+		results[i] = s.importUser(r, logger, row)
+		if results[i].Status == "created" {
+			created++
+		}
+	}
+	failed := len(rows) - created
+	s.store.AddAuditEntry(ctx, "user_import", fmt.Sprintf("Imported %d users, %d failed", created, failed), "")
+
+	// The response carries passwords; keep it out of caches
+	w.Header().Set("Cache-Control", "no-store")
+	if format == "csv" {
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="user-import.csv"`)
+		w.Header().Set("X-Users-Created", strconv.Itoa(created))
+		w.Header().Set("X-Users-Failed", strconv.Itoa(failed))
+		cw := csv.NewWriter(w)
+		cw.Write([]string{"row", "username", "email", "is_admin", "status", "temporary_password", "error"})
+		for _, result := range results {
+			cw.Write([]string{
+				strconv.Itoa(result.Row),
+				result.Username,
+				result.Email,
+				strconv.FormatBool(result.IsAdmin),
+				result.Status,
+				result.TemporaryPassword,
+				result.Error,
+			})
+		}
+		cw.Flush()
+	} else {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"created": created,
+			"failed":  failed,
+			"results": results,
+		})
+	}
+
+	latency := time.Since(start).Milliseconds()
+	logger.Debug("request completed", "status", http.StatusOK, "latency_ms", latency, "created", created, "failed", failed)
+}
+
+// importUser validates one row and creates its account with a temporary password
+func (s *Server) importUser(r *http.Request, logger Logger, row userImportRow) UserImportResult {
+	result := UserImportResult{Row: row.line, Username: row.username, Email: row.email, Status: "failed"}
+
+	v := validate.New()
+	v.Required("username", "Username", row.username)
+	v.Check("username", validate.Username(row.username))
+	if row.email != "" {
+		v.Check("email", validate.Email(row.email))
+	}
+	isAdmin, err := parseImportBool(row.isAdmin)
+	v.Check("is_admin", err)
+	if err := v.Err(); err != nil {
+		var fields validate.Errors
+		if errors.As(err, &fields) {
+			result.Error = fields[0].Message
+		} else {
+			result.Error = err.Error()
+		}
+		return result
+	}
+	result.IsAdmin = isAdmin
+
+	password, err := s.passwordPolicy().Generate()
+	if err != nil {
+		logger.Error("failed to generate random password", "row", row.line, "error", err.Error())
+		result.Error = "Failed to generate password"
+		return result
+	}
+	userID, err := s.store.CreateUser(r.Context(), row.username, password, row.email, isAdmin, true)
+	if err != nil {
+		if msg := userConflictMessage(err); msg != "" {
+			result.Error = msg
+		} else {
+			logger.Error("failed to create user", "row", row.line, "username", row.username, "error", err.Error())
+			result.Error = "Failed to create user"
+		}
+		return result
+	}
+	s.publish(r.Context(), events.UserCreated, userID, map[string]interface{}{"username": row.username})
+
+	result.Status = "created"
+	result.TemporaryPassword = password
+	return result
+}
+
+// parseUserImport reads the rows of a user import. The header names the
+// columns in any order; username is required, email and is_admin are optional
+// and other columns are ignored, so an export can be imported elsewhere
+func parseUserImport(body io.Reader) ([]userImportRow, error) {
+	cr := csv.NewReader(body)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, errors.New("the CSV is empty")
+	}
+	if err != nil {
+		return nil, csvError(err)
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		// Spreadsheets often save UTF-8 with a byte order mark
+		name = strings.TrimPrefix(name, "\ufeff")
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := columns["username"]; !ok {
+		return nil, errors.New("the CSV header must have a username column")
+	}
+	field := func(record []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	var rows []userImportRow
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, csvError(err)
+		}
+		// Skip blank lines between rows
+		if len(record) == 1 && strings.TrimSpace(record[0]) == "" {
+			continue
+		}
+		if len(rows) == maxUsersImported {
+			return nil, fmt.Errorf("an import may have at most %d users", maxUsersImported)
+		}
+		line, _ := cr.FieldPos(0)
+		rows = append(rows, userImportRow{
+			line:     line,
+			username: field(record, "username"),
+			email:    field(record, "email"),
+			isAdmin:  field(record, "is_admin"),
+		})
+	}
+	if len(rows) == 0 {
+		return nil, errors.New("the CSV has no users")
+	}
+	return rows, nil
+}
+
+// csvError describes a malformed CSV, passing body size errors through
+func csvError(err error) error {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return err
+	}
+	return fmt.Errorf("invalid CSV: %v", err)
+}
+
+// parseImportBool reads an is_admin cell; empty means false
+func parseImportBool(s string) (bool, error) {
+	switch strings.ToLower(s) {
+	case "", "false", "no", "0":
+		return false, nil
+	case "true", "yes", "1":
+		return true, nil
+	}
+	return false, errors.New("is_admin must be true or false")
+}
+
+// userConflictMessage describes a user creation that failed on a duplicate
+// username or email, or returns "" for other errors
+func userConflictMessage(err error) string {
+	if !strings.Contains(err.Error(), "UNIQUE constraint failed") && !strings.Contains(err.Error(), "unique") {
+		return ""
+	}
+	switch {
+	case strings.Contains(err.Error(), "username"):
+		return "Username already exists"
+	case strings.Contains(err.Error(), "email"):
+		return "Email already registered"
+	default:
+		return "User already exists"
+	}
+}
+
+// handleExportUsers handles GET /api/admin/users/export - every account as CSV
+// (admin only). Passwords are never exported
+func (s *Server) handleExportUsers(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	requestID := newRequestID(w)
+
+	logger := s.logger.WithContext("request_id", requestID).
+		WithContext("method", r.Method).
+		WithContext("path", r.URL.Path)
+
+	logger.Debug("processing export users request")
+
+	users, err := s.store.ListUsers(r.Context())
+	if err != nil {
+		logger.Error("request failed", "operation", "list_users", "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to retrieve users")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="users.csv"`)
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"id", "username", "email", "is_admin", "must_change_password", "created_at", "last_login"})
+	for _, user := range users {
+		cw.Write([]string{
+			strconv.FormatInt(user.ID, 10),
+			user.Username,
+			user.Email,
+			strconv.FormatBool(user.IsAdmin),
+			strconv.FormatBool(user.MustChangePassword),
+			csvTime(user.CreatedAt),
+			csvTime(user.LastLogin),
+		})
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		logger.Warn("failed to write user export", "error", err.Error())
+	}
+
+	latency := time.Since(start).Milliseconds()
+	logger.Debug("request completed", "status", http.StatusOK, "latency_ms", latency, "count", len(users))
+}
+
+// csvTime formats a time for a CSV cell, leaving unset times empty
+func csvTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
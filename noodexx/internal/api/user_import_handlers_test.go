@@ -0,0 +1,180 @@
+package api
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestHandleImportUsers tests that each row is created or reported on its own
+func TestHandleImportUsers(t *testing.T) {
+	type createdUser struct {
+		username, email, password string
+		isAdmin, mustChange       bool
+	}
+	var created []createdUser
+	store := &mockStoreForAdmin{}
+	store.createUserFunc = func(ctx context.Context, username, password, email string, isAdmin, mustChangePassword bool) (int64, error) {
+		if username == "taken" {
+			return 0, errors.New("UNIQUE constraint failed: users.username")
+		}
+		created = append(created, createdUser{username, email, password, isAdmin, mustChangePassword})
+		return int64(len(created)), nil
+	}
+	server := &Server{store: store, logger: &mockLogger{}}
+
+	body := "\ufeffUsername,Email,is_admin,notes\n" +
+		"alice,alice@example.com,true,teacher\n" +
+		"bob,,no,\n" +
+		"\n" +
+		"taken,taken@example.com,,\n" +
+		"carol,not-an-email,,\n" +
+		"dave,,maybe,\n"
+	req := httptest.NewRequest(http.MethodPost, "/api/admin/users/import", strings.NewReader(body))
+	req.Header.Set("Content-Type", "text/csv")
+	w := httptest.NewRecorder()
+	server.handleImportUsers(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if w.Header().Get("Cache-Control") != "no-store" {
+		t.Error("Expected the response with passwords not to be cached")
+	}
+	var resp struct {
+		Created int                `json:"created"`
+		Failed  int                `json:"failed"`
+		Results []UserImportResult `json:"results"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if resp.Created != 2 || resp.Failed != 3 || len(resp.Results) != 5 {
+		t.Fatalf("Expected 2 created and 3 failed rows, got %+v", resp)
+	}
+
+	alice := resp.Results[0]
+	if alice.Row != 2 || alice.Status != "created" || !alice.IsAdmin || alice.TemporaryPassword == "" {
+		t.Errorf("Expected alice created as an admin with a password, got %+v", alice)
+	}
+	if created[0].password != alice.TemporaryPassword || !created[0].mustChange {
+		t.Errorf("Expected alice's password to be temporary, got %+v", created[0])
+	}
+	if bob := resp.Results[1]; bob.Status != "created" || bob.IsAdmin || bob.Row != 3 {
+		t.Errorf("Expected bob created as a user, got %+v", bob)
+	}
+
+	failures := map[string]string{
+		"taken": "Username already exists",
+		"carol": "email",
+		"dave":  "is_admin must be true or false",
+	}
+	for _, result := range resp.Results[2:] {
+		want := failures[result.Username]
+		if result.Status != "failed" || result.TemporaryPassword != "" || !strings.Contains(strings.ToLower(result.Error), strings.ToLower(want)) {
+			t.Errorf("Expected %s to fail with %q, got %+v", result.Username, want, result)
+		}
+	}
+	if resp.Results[2].Row != 5 {
+		t.Errorf("Expected row numbers to count blank lines, got %d", resp.Results[2].Row)
+	}
+}
+
+// TestHandleImportUsers_CSV tests the downloadable result
+func TestHandleImportUsers_CSV(t *testing.T) {
+	server := &Server{store: &mockStoreForAdmin{}, logger: &mockLogger{}}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/admin/users/import?format=csv", strings.NewReader("username\nalice\n"))
+	w := httptest.NewRecorder()
+	server.handleImportUsers(w, req)
+
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/csv") {
+		t.Fatalf("Expected CSV, got %q: %s", ct, w.Body.String())
+	}
+	records, err := csv.NewReader(w.Body).ReadAll()
+	if err != nil {
+		t.Fatalf("Failed to read CSV: %v", err)
+	}
+	if len(records) != 2 || records[0][5] != "temporary_password" {
+		t.Fatalf("Expected a header and one row, got %v", records)
+	}
+	if records[1][1] != "alice" || records[1][4] != "created" || records[1][5] == "" {
+		t.Errorf("Expected alice created with a password, got %v", records[1])
+	}
+}
+
+// TestHandleImportUsers_Invalid tests files that are refused as a whole
+func TestHandleImportUsers_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"empty", ""},
+		{"no username column", "name,email\nalice,alice@example.com\n"},
+		{"header only", "username,email\n"},
+		{"malformed", "username\n\"alice\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := &Server{store: &mockStoreForAdmin{}, logger: &mockLogger{}}
+			req := httptest.NewRequest(http.MethodPost, "/api/admin/users/import", strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			server.handleImportUsers(w, req)
+
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("Expected status 400, got %d", w.Code)
+			}
+		})
+	}
+}
+
+// TestHandleExportUsers tests the user list as CSV
+func TestHandleExportUsers(t *testing.T) {
+	created := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+	store := &mockStoreForAdmin{}
+	store.listUsersFunc = func(ctx context.Context) ([]User, error) {
+		return []User{
+			{ID: 1, Username: "admin", Email: "admin@example.com", IsAdmin: true, CreatedAt: created, LastLogin: created},
+			{ID: 2, Username: "bob", MustChangePassword: true, CreatedAt: created, PasswordHash: "secret-hash"},
+		}, nil
+	}
+	server := &Server{store: store, logger: &mockLogger{}}
+
+	w := httptest.NewRecorder()
+	server.handleExportUsers(w, httptest.NewRequest(http.MethodGet, "/api/admin/users/export", nil))
+
+	exported := w.Body.String()
+	if strings.Contains(exported, "secret-hash") {
+		t.Fatal("Expected password hashes not to be exported")
+	}
+	records, err := csv.NewReader(strings.NewReader(exported)).ReadAll()
+	if err != nil {
+		t.Fatalf("Failed to read CSV: %v", err)
+	}
+	want := [][]string{
+		{"id", "username", "email", "is_admin", "must_change_password", "created_at", "last_login"},
+		{"1", "admin", "admin@example.com", "true", "false", "2024-01-15T10:30:00Z", "2024-01-15T10:30:00Z"},
+		{"2", "bob", "", "false", "true", "2024-01-15T10:30:00Z", ""},
+	}
+	if len(records) != len(want) {
+		t.Fatalf("Expected %d records, got %v", len(want), records)
+	}
+	for i := range want {
+		if strings.Join(records[i], ",") != strings.Join(want[i], ",") {
+			t.Errorf("Row %d: expected %v, got %v", i, want[i], records[i])
+		}
+	}
+
+	// The export can be imported again
+	rows, err := parseUserImport(strings.NewReader(exported))
+	if err != nil || len(rows) != 2 || rows[1].username != "bob" || rows[0].isAdmin != "true" {
+		t.Errorf("Expected the export to parse as an import, got %+v (%v)", rows, err)
+	}
+}
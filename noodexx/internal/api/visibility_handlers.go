@@ -0,0 +1,183 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"noodexx/internal/auth"
+	"noodexx/internal/validate"
+	"time"
+)
+
+// maxVisibilityDocuments bounds the documents one bulk visibility change may update
+const maxVisibilityDocuments = 500
+
+// handleDefaultVisibility handles GET /api/default-visibility
+// Returns the visibility the current user's documents are ingested with when a
+// request does not choose one
+func (s *Server) handleDefaultVisibility(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	requestID := newRequestID(w)
+
+	// Create logger with request context
+	logger := s.logger.WithContext("request_id", requestID).
+		WithContext("method", r.Method).
+		WithContext("path", r.URL.Path)
+
+	logger.Debug("processing default visibility request")
+
+	ctx := r.Context()
+
+	// Extract user_id from context
+	userID, err := auth.GetUserID(ctx)
+	if err != nil {
+		logger.Error("request failed", "operation", "get_user_id", "error", err.Error())
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
+		return
+	}
+
+	visibility, err := s.store.GetDefaultVisibility(ctx, userID)
+	if err != nil {
+		logger.Error("request failed", "operation", "get_default_visibility", "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to get default visibility")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":      true,
+		"visibility":   visibility,
+		"visibilities": validate.Visibilities,
+	})
+
+	latency := time.Since(start).Milliseconds()
+	logger.Debug("request completed", "status", http.StatusOK, "latency_ms", latency)
+}
+
+// handleUpdateDefaultVisibility handles POST /api/default-visibility
+// Sets the visibility the current user's documents are ingested with when a
+// request does not choose one
+func (s *Server) handleUpdateDefaultVisibility(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	requestID := newRequestID(w)
+
+	// Create logger with request context
+	logger := s.logger.WithContext("request_id", requestID).
+		WithContext("method", r.Method).
+		WithContext("path", r.URL.Path)
+
+	logger.Debug("processing update default visibility request")
+
+	ctx := r.Context()
+
+	// Extract user_id from context
+	userID, err := auth.GetUserID(ctx)
+	if err != nil {
+		logger.Error("request failed", "operation", "get_user_id", "error", err.Error())
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req struct {
+		Visibility string `json:"visibility"`
+	}
+	if !decodeJSON(w, r, logger, &req) {
+		return
+	}
+
+	v := validate.New()
+	v.Required("visibility", "Visibility", req.Visibility)
+	v.Check("visibility", validate.Visibility(req.Visibility))
+	if err := v.Err(); err != nil {
+		writeValidationError(w, logger, err)
+		return
+	}
+
+	if err := s.store.SetDefaultVisibility(ctx, userID, req.Visibility); err != nil {
+		logger.Error("request failed", "operation", "set_default_visibility", "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to save default visibility")
+		return
+	}
+
+	// Audit log
+	s.store.AddAuditEntry(ctx, "config", "Default visibility set to "+req.Visibility, "")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":    true,
+		"message":    "Default visibility updated successfully",
+		"visibility": req.Visibility,
+	})
+
+	latency := time.Since(start).Milliseconds()
+	logger.Debug("request completed", "status", http.StatusOK, "latency_ms", latency, "visibility", req.Visibility)
+}
+
+// handleDocumentsVisibility handles POST /api/documents/visibility - set the
+// visibility of several of the current user's documents at once. Each
+// document's chunks are updated with it in one transaction; IDs of documents
+// the user does not own are skipped
+func (s *Server) handleDocumentsVisibility(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	requestID := newRequestID(w)
+
+	// Create logger with request context
+	logger := s.logger.WithContext("request_id", requestID).
+		WithContext("method", r.Method).
+		WithContext("path", r.URL.Path)
+
+	logger.Debug("processing documents visibility request")
+
+	ctx := r.Context()
+
+	// Extract user_id from context
+	userID, err := auth.GetUserID(ctx)
+	if err != nil {
+		logger.Error("request failed", "operation", "get_user_id", "error", err.Error())
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req struct {
+		DocumentIDs []int64 `json:"document_ids"`
+		Visibility  string  `json:"visibility"`
+	}
+	if !decodeJSON(w, r, logger, &req) {
+		return
+	}
+
+	v := validate.New()
+	v.Required("visibility", "Visibility", req.Visibility)
+	v.Check("visibility", validate.Visibility(req.Visibility))
+	switch {
+	case len(req.DocumentIDs) == 0:
+		v.Check("document_ids", fmt.Errorf("At least one document is required"))
+	case len(req.DocumentIDs) > maxVisibilityDocuments:
+		v.Check("document_ids", fmt.Errorf("At most %d documents can be updated at once", maxVisibilityDocuments))
+	}
+	if err := v.Err(); err != nil {
+		writeValidationError(w, logger, err)
+		return
+	}
+
+	updated, err := s.store.SetDocumentsVisibility(ctx, userID, req.DocumentIDs, req.Visibility)
+	if err != nil {
+		logger.Error("request failed", "operation", "set_documents_visibility", "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to update document visibility")
+		return
+	}
+
+	// Audit log
+	s.store.AddAuditEntry(ctx, "config", fmt.Sprintf("Visibility of %d documents set to %s", updated, req.Visibility), "")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":    true,
+		"updated":    updated,
+		"skipped":    len(req.DocumentIDs) - updated,
+		"visibility": req.Visibility,
+	})
+
+	latency := time.Since(start).Milliseconds()
+	logger.Debug("request completed", "status", http.StatusOK, "latency_ms", latency, "updated", updated)
+}
@@ -0,0 +1,228 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"noodexx/internal/auth"
+	"noodexx/internal/ingest"
+	"noodexx/internal/logging"
+	"strings"
+	"testing"
+)
+
+// visibilityStore keeps the user's default visibility
+type visibilityStore struct {
+	mockStoreForAsk
+	defaultVisibility string
+	documents         map[int64]string
+}
+
+func (m *visibilityStore) GetDefaultVisibility(ctx context.Context, userID int64) (string, error) {
+	return m.defaultVisibility, nil
+}
+
+func (m *visibilityStore) SetDefaultVisibility(ctx context.Context, userID int64, visibility string) error {
+	m.defaultVisibility = visibility
+	return nil
+}
+
+// SetDocumentsVisibility updates the user's own documents, IDs below 100
+func (m *visibilityStore) SetDocumentsVisibility(ctx context.Context, userID int64, documentIDs []int64, visibility string) (int, error) {
+	if m.documents == nil {
+		m.documents = make(map[int64]string)
+	}
+	for _, id := range documentIDs {
+		if id < 100 {
+			m.documents[id] = visibility
+		}
+	}
+	return len(m.documents), nil
+}
+
+// visibilityIngestStore is the ingester's store, keeping the visibility each
+// ingested document was given
+type visibilityIngestStore struct {
+	*visibilityStore
+	sources map[string]string
+}
+
+func (m *visibilityIngestStore) SaveChunk(ctx context.Context, userID int64, source, text string, embedding []float32, tags []string, summary string) error {
+	if _, ok := m.sources[source]; !ok {
+		m.sources[source] = ingest.VisibilityPrivate
+	}
+	return nil
+}
+
+func (m *visibilityIngestStore) SetSourceVisibility(ctx context.Context, userID int64, source, visibility string) error {
+	m.sources[source] = visibility
+	return nil
+}
+
+func (m *visibilityIngestStore) SaveChunkTitles(ctx context.Context, userID int64, source string, titles []string, embeddings [][]float32) error {
+	return nil
+}
+
+func (m *visibilityIngestStore) DeleteChunksBySource(ctx context.Context, userID int64, source string) error {
+	return nil
+}
+
+func (m *visibilityIngestStore) CreateDocument(ctx context.Context, userID int64, source, visibility string) error {
+	if _, ok := m.sources[source]; !ok {
+		m.sources[source] = visibility
+	}
+	return nil
+}
+
+func (m *visibilityIngestStore) SetIngestWarning(ctx context.Context, userID int64, source, warning string) error {
+	return nil
+}
+
+func (m *visibilityIngestStore) SetDocumentEncoding(ctx context.Context, userID int64, source, encoding, changes string) error {
+	return nil
+}
+
+func (m *visibilityIngestStore) WithTx(ctx context.Context, fn func(tx ingest.StoreTx) error) error {
+	return fn(m)
+}
+
+func (m *visibilityIngestStore) SaveSummary(ctx context.Context, userID int64, source, summary, model string) error {
+	return nil
+}
+
+func (m *visibilityIngestStore) GetSourceTexts(ctx context.Context, userID int64, source string) ([]string, error) {
+	return nil, nil
+}
+
+func (m *visibilityIngestStore) SaveDocumentContent(ctx context.Context, userID int64, source, content, chunker string) error {
+	return nil
+}
+
+func (m *visibilityIngestStore) GetDocumentContent(ctx context.Context, userID int64, source string) (*ingest.DocumentContent, error) {
+	return nil, nil
+}
+
+func (m *visibilityIngestStore) ReplaceChunks(ctx context.Context, userID int64, source string, chunks []string, embeddings [][]float32) error {
+	return nil
+}
+
+// fixedEmbedder embeds every text as the same vector
+type fixedEmbedder struct{}
+
+func (fixedEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	return []float32{0.1, 0.2}, nil
+}
+
+func (fixedEmbedder) Stream(ctx context.Context, messages []ingest.Message, w io.Writer) (string, error) {
+	return "", nil
+}
+
+// wholeTextChunker keeps a text as one chunk
+type wholeTextChunker struct{}
+
+func (wholeTextChunker) ChunkText(text string) []string {
+	return []string{text}
+}
+
+// TestDefaultVisibility tests that documents ingested without a visibility get
+// the user's default, that a request can choose another, and that unknown
+// visibilities are rejected
+func TestDefaultVisibility(t *testing.T) {
+	store := &visibilityStore{defaultVisibility: ingest.VisibilityPrivate}
+	documents := &visibilityIngestStore{visibilityStore: store, sources: make(map[string]string)}
+	ingester := ingest.NewIngester(fixedEmbedder{}, documents, wholeTextChunker{}, false, false, logging.NewLogger("test", logging.DEBUG, io.Discard))
+	server := &Server{store: store, ingester: ingester, logger: &mockLoggerForAsk{}}
+
+	do := func(handler http.HandlerFunc, method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req = req.WithContext(context.WithValue(req.Context(), auth.UserIDKey, int64(1)))
+		w := httptest.NewRecorder()
+		handler(w, req)
+		return w
+	}
+	ingestText := func(source, visibility string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(map[string]string{"source": source, "text": "Team notes", "visibility": visibility})
+		return do(server.handleIngestText, http.MethodPost, "/api/ingest/text", string(body))
+	}
+
+	if w := do(server.handleUpdateDefaultVisibility, http.MethodPost, "/api/default-visibility", `{"visibility":"public"}`); w.Code != http.StatusOK {
+		t.Fatalf("Expected 200 saving the default, got %d: %s", w.Code, w.Body.String())
+	}
+	w := do(server.handleDefaultVisibility, http.MethodGet, "/api/default-visibility", "")
+	var resp struct {
+		Visibility string `json:"visibility"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || resp.Visibility != "public" {
+		t.Errorf("Expected the public default, got %s", w.Body.String())
+	}
+
+	if w := ingestText("faq.md", ""); w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := ingestText("diary.md", "private"); w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if documents.sources["faq.md"] != "public" || documents.sources["diary.md"] != "private" {
+		t.Errorf("Unexpected visibilities %v", documents.sources)
+	}
+
+	if w := ingestText("bad.md", "everyone"); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an unknown visibility, got %d", w.Code)
+	}
+	if w := do(server.handleUpdateDefaultVisibility, http.MethodPost, "/api/default-visibility", `{"visibility":""}`); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an empty default, got %d", w.Code)
+	}
+
+	var form bytes.Buffer
+	form.WriteString("--b\r\nContent-Disposition: form-data; name=\"visibility\"\r\n\r\nshared\r\n")
+	form.WriteString("--b\r\nContent-Disposition: form-data; name=\"file\"; filename=\"team.txt\"\r\n\r\nTeam handbook\r\n--b--\r\n")
+	req := httptest.NewRequest(http.MethodPost, "/api/ingest/file", &form)
+	req.Header.Set("Content-Type", "multipart/form-data; boundary=b")
+	req = req.WithContext(context.WithValue(req.Context(), auth.UserIDKey, int64(1)))
+	w = httptest.NewRecorder()
+	server.handleIngestFile(w, req)
+	if w.Code != http.StatusOK || documents.sources["team.txt"] != "shared" {
+		t.Errorf("Expected the upload shared, got %d %v", w.Code, documents.sources)
+	}
+}
+
+// TestDocumentsVisibility tests changing the visibility of several documents
+// and rejecting invalid requests
+func TestDocumentsVisibility(t *testing.T) {
+	store := &visibilityStore{}
+	server := &Server{store: store, logger: &mockLoggerForAsk{}}
+	post := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/documents/visibility", strings.NewReader(body))
+		req = req.WithContext(context.WithValue(req.Context(), auth.UserIDKey, int64(1)))
+		w := httptest.NewRecorder()
+		server.handleDocumentsVisibility(w, req)
+		return w
+	}
+
+	w := post(`{"document_ids":[1,2,300],"visibility":"shared"}`)
+	var resp struct {
+		Updated int `json:"updated"`
+		Skipped int `json:"skipped"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if resp.Updated != 2 || resp.Skipped != 1 || store.documents[1] != "shared" || store.documents[2] != "shared" {
+		t.Errorf("Expected two documents shared, got %+v %v", resp, store.documents)
+	}
+
+	ids, _ := json.Marshal(make([]int64, maxVisibilityDocuments+1))
+	for _, body := range []string{
+		`{"document_ids":[1],"visibility":"everyone"}`,
+		`{"document_ids":[1]}`,
+		`{"document_ids":[],"visibility":"public"}`,
+		`{"document_ids":` + string(ids) + `,"visibility":"public"}`,
+	} {
+		if w := post(body); w.Code != http.StatusBadRequest {
+			t.Errorf("%.60s: expected 400, got %d", body, w.Code)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"noodexx/internal/auth"
+	"time"
+)
+
+// handleModelWarmup handles GET /api/model-warmup - whether the local models are loaded
+func (s *Server) handleModelWarmup(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	requestID := newRequestID(w)
+
+	// Create logger with request context
+	logger := s.logger.WithContext("request_id", requestID).
+		WithContext("method", r.Method).
+		WithContext("path", r.URL.Path)
+
+	logger.Debug("processing model warm-up status request")
+
+	if _, err := auth.GetUserID(r.Context()); err != nil {
+		logger.Error("failed to get user from context", "error", err.Error())
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
+		return
+	}
+
+	response := map[string]interface{}{
+		"success": true,
+		"enabled": s.modelWarmer != nil,
+	}
+	if s.modelWarmer != nil {
+		status := s.modelWarmer.Status()
+		response["status"] = status
+		response["label"] = modelWarmupLabel(status)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+
+	latency := time.Since(start).Milliseconds()
+	logger.Debug("request completed", "latency_ms", latency)
+}
+
+// modelWarmupLabel describes a warm-up state for the dashboard
+func modelWarmupLabel(status ModelWarmupStatus) string {
+	switch status.State {
+	case "warm":
+		return "Ready"
+	case "warming":
+		return "Loading"
+	case "failed":
+		return "Unavailable"
+	case "unsupported":
+		return "Always ready"
+	default:
+		if status.Paused {
+			return "Sleeping"
+		}
+		return "Not loaded"
+	}
+}
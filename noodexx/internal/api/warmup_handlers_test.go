@@ -0,0 +1,80 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"noodexx/internal/auth"
+	"testing"
+)
+
+// fakeModelWarmer reports a fixed status and counts touches
+type fakeModelWarmer struct {
+	status  ModelWarmupStatus
+	touches int
+}
+
+func (f *fakeModelWarmer) Touch()                    { f.touches++ }
+func (f *fakeModelWarmer) Status() ModelWarmupStatus { return f.status }
+
+// TestHandleModelWarmup tests the warm-up status endpoint
+func TestHandleModelWarmup(t *testing.T) {
+	server := &Server{
+		logger:      &mockLoggerForAsk{},
+		modelWarmer: &fakeModelWarmer{status: ModelWarmupStatus{State: "warm", KeepAlive: true, Pings: 3}},
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/model-warmup", nil)
+	req = req.WithContext(context.WithValue(req.Context(), auth.UserIDKey, int64(1)))
+	w := httptest.NewRecorder()
+	server.handleModelWarmup(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	var response struct {
+		Enabled bool              `json:"enabled"`
+		Label   string            `json:"label"`
+		Status  ModelWarmupStatus `json:"status"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if !response.Enabled || response.Label != "Ready" || response.Status.State != "warm" || response.Status.Pings != 3 {
+		t.Errorf("Unexpected response %+v", response)
+	}
+
+	// Requires a signed-in user
+	w = httptest.NewRecorder()
+	server.handleModelWarmup(w, httptest.NewRequest(http.MethodGet, "/api/model-warmup", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401 without a user, got %d", w.Code)
+	}
+}
+
+// TestHandleAsk_TouchesModelWarmer tests that local chats count as keep-alive activity
+func TestHandleAsk_TouchesModelWarmer(t *testing.T) {
+	for _, local := range []bool{true, false} {
+		warmer := &fakeModelWarmer{}
+		server := &Server{
+			store:           &mockStoreForAsk{},
+			logger:          &mockLoggerForAsk{},
+			providerManager: &mockProviderManagerForAsk{provider: &mockProviderForAsk{name: "ollama", isLocal: local}, providerName: "Ollama (llama3.2)"},
+			ragEnforcer:     &mockRAGEnforcerForAsk{shouldPerformRAG: false, ragStatus: "RAG Disabled"},
+			modelWarmer:     warmer,
+		}
+
+		req := httptest.NewRequest(http.MethodPost, "/api/ask", bytes.NewBufferString(`{"query":"test query","session_id":"s1"}`))
+		req = req.WithContext(context.WithValue(req.Context(), auth.UserIDKey, int64(1)))
+		server.handleAsk(httptest.NewRecorder(), req)
+
+		if local && warmer.touches == 0 {
+			t.Error("Expected a local chat to touch the model warmer")
+		}
+		if !local && warmer.touches != 0 {
+			t.Errorf("Expected a cloud chat not to touch the model warmer, got %d touches", warmer.touches)
+		}
+	}
+}
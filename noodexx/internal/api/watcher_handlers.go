@@ -0,0 +1,80 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// handleWatcherStatus handles GET /api/watcher - whether the folder watcher is
+// paused and what it has ingested since startup
+func (s *Server) handleWatcherStatus(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	requestID := newRequestID(w)
+
+	// Create logger with request context
+	logger := s.logger.WithContext("request_id", requestID).
+		WithContext("method", r.Method).
+		WithContext("path", r.URL.Path)
+
+	logger.Debug("processing watcher status request")
+
+	response := map[string]interface{}{
+		"success":   true,
+		"available": s.watcherControl != nil,
+	}
+	if s.watcherControl != nil {
+		response["status"] = s.watcherControl.Status()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+
+	latency := time.Since(start).Milliseconds()
+	logger.Debug("request completed", "latency_ms", latency)
+}
+
+// handlePauseWatcher handles POST /api/watcher/pause - stop ingesting folder
+// changes until the watcher is resumed
+func (s *Server) handlePauseWatcher(w http.ResponseWriter, r *http.Request) {
+	s.setWatcherPaused(w, r, true)
+}
+
+// handleResumeWatcher handles POST /api/watcher/resume - ingest the changes held
+// while paused and continue watching
+func (s *Server) handleResumeWatcher(w http.ResponseWriter, r *http.Request) {
+	s.setWatcherPaused(w, r, false)
+}
+
+func (s *Server) setWatcherPaused(w http.ResponseWriter, r *http.Request, paused bool) {
+	start := time.Now()
+	requestID := newRequestID(w)
+
+	// Create logger with request context
+	logger := s.logger.WithContext("request_id", requestID).
+		WithContext("method", r.Method).
+		WithContext("path", r.URL.Path)
+
+	logger.Debug("processing watcher pause request", "paused", paused)
+
+	if s.watcherControl == nil {
+		writeError(w, http.StatusNotImplemented, CodeNotImplemented, "The folder watcher cannot be paused on this instance")
+		return
+	}
+
+	if paused {
+		s.watcherControl.Pause()
+	} else {
+		s.watcherControl.Resume()
+	}
+	logger.Info("folder watcher updated", "paused", paused)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"status":  s.watcherControl.Status(),
+	})
+
+	latency := time.Since(start).Milliseconds()
+	logger.Debug("request completed", "latency_ms", latency)
+}
@@ -0,0 +1,51 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type mockWatcherControl struct {
+	paused bool
+}
+
+func (m *mockWatcherControl) Pause()  { m.paused = true }
+func (m *mockWatcherControl) Resume() { m.paused = false }
+
+func (m *mockWatcherControl) Status() WatcherStatus {
+	return WatcherStatus{Paused: m.paused, Ingested: 2}
+}
+
+func TestWatcherHandlers(t *testing.T) {
+	server := &Server{store: &mockStore{}, logger: &mockLogger{}}
+	call := func(handler http.HandlerFunc, method string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler(w, httptest.NewRequest(method, "/api/watcher", nil))
+		return w
+	}
+
+	// Without a local watcher the status says so and pausing is refused
+	if w := call(server.handleWatcherStatus, http.MethodGet); !strings.Contains(w.Body.String(), `"available":false`) {
+		t.Errorf("Expected the watcher unavailable, got %s", w.Body.String())
+	}
+	if w := call(server.handlePauseWatcher, http.MethodPost); w.Code != http.StatusNotImplemented {
+		t.Errorf("Expected status 501 without a watcher, got %d", w.Code)
+	}
+
+	control := &mockWatcherControl{}
+	server.SetWatcherControl(control)
+
+	w := call(server.handlePauseWatcher, http.MethodPost)
+	if w.Code != http.StatusOK || !control.paused || !strings.Contains(w.Body.String(), `"paused":true`) {
+		t.Errorf("Expected the watcher paused, got %d: %s", w.Code, w.Body.String())
+	}
+	w = call(server.handleResumeWatcher, http.MethodPost)
+	if w.Code != http.StatusOK || control.paused {
+		t.Errorf("Expected the watcher resumed, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := call(server.handleWatcherStatus, http.MethodGet); !strings.Contains(w.Body.String(), `"ingested":2`) {
+		t.Errorf("Expected the watcher status, got %s", w.Body.String())
+	}
+}
@@ -0,0 +1,63 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"noodexx/internal/flags"
+)
+
+// webSourcePrefix marks web search results in prompt context and citations
+const webSourcePrefix = "web:"
+
+// shouldWebSearch decides whether a question gets web context
+// An explicit request always wins; otherwise web search only runs automatically for questions
+// answered in cloud mode when configured to, so local mode never reaches the internet without the user opting in
+func (s *Server) shouldWebSearch(ctx context.Context, requested *bool, local bool) bool {
+	if s.webSearch == nil || !s.featureEnabled(ctx, flags.WebSearch) {
+		return false
+	}
+	if requested != nil {
+		return *requested
+	}
+	return s.webSearchOpts.AutoInCloudMode && !local
+}
+
+// searchWeb runs a web search for a question and returns the results as context chunks
+// Failures are logged and yield no chunks so the question is still answered
+func (s *Server) searchWeb(ctx context.Context, logger Logger, userID int64, sessionID, query string) []Chunk {
+	results, err := s.webSearch.Search(ctx, query)
+	if err != nil {
+		logger.Warn("web search failed, answering without web context", "error", err.Error())
+		return nil
+	}
+
+	s.store.AddAuditEntry(ctx, "web_search", fmt.Sprintf("%s (%d results)", query, len(results)), sessionID)
+
+	chunks := make([]Chunk, 0, len(results))
+	for _, result := range results {
+		if result.Text == "" {
+			continue
+		}
+		chunks = append(chunks, Chunk{
+			Source: webSourcePrefix + result.URL,
+			Text:   result.Title + "\n" + result.Text,
+		})
+	}
+
+	if s.webSearchOpts.IngestResults && s.ingester != nil && len(results) > 0 {
+		// Ingest in the background so the answer isn't delayed by embedding
+		ingestCtx := context.WithoutCancel(ctx)
+		go func() {
+			for _, result := range results {
+				if result.Text == "" {
+					continue
+				}
+				if err := s.ingester.IngestText(ingestCtx, userID, result.URL, result.Text, []string{"web"}); err != nil {
+					logger.Warn("failed to ingest web result", "url", result.URL, "error", err.Error())
+				}
+			}
+		}()
+	}
+
+	return chunks
+}
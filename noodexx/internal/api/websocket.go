@@ -1,27 +1,58 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"noodexx/internal/auth"
+	"sort"
+	"strings"
 	"sync"
 
 	"github.com/gorilla/websocket"
 )
 
+// WebSocket subscription topics. A connection that never subscribes receives
+// its user's events and broadcasts, except for subscription-only topics; while
+// it has subscriptions, it receives only the events of the topics it subscribed
+// to. Broadcasts outside any topic reach every connection
+const (
+	topicIngestion     = "ingestion"     // Documents ingested, deleted or re-chunked
+	topicNotifications = "notifications" // The user's notifications
+	topicJobs          = "jobs"          // Background job runs starting and finishing; admins only, subscription only
+	chatTopicPrefix    = "chat:"         // chat:{session_id} - messages saved to one of the user's sessions; subscription only
+)
+
+// maxWebSocketTopics is the most topics one connection can subscribe to
+const maxWebSocketTopics = 50
+
 // WebSocketHub manages WebSocket connections
 type WebSocketHub struct {
 	clients    map[*websocket.Conn]bool
-	broadcast  chan []byte
+	users      map[*websocket.Conn]int64           // Signed-in user of each connection that has one
+	topics     map[*websocket.Conn]map[string]bool // Subscriptions of each connection that has any
+	broadcast  chan hubMessage
 	register   chan *websocket.Conn
 	unregister chan *websocket.Conn
 	mu         sync.RWMutex
 }
 
+// hubMessage is a message for the connections that receive its topic
+type hubMessage struct {
+	topic  string // Empty for broadcasts outside any topic
+	userID int64  // Only this user's connections receive it, unless 0
+	data   []byte
+}
+
 // NewWebSocketHub creates a hub
 func NewWebSocketHub() *WebSocketHub {
 	return &WebSocketHub{
 		clients:    make(map[*websocket.Conn]bool),
-		broadcast:  make(chan []byte, 256),
+		users:      make(map[*websocket.Conn]int64),
+		topics:     make(map[*websocket.Conn]map[string]bool),
+		broadcast:  make(chan hubMessage, 256),
 		register:   make(chan *websocket.Conn),
 		unregister: make(chan *websocket.Conn),
 	}
@@ -39,17 +70,15 @@ func (h *WebSocketHub) Run() {
 		case conn := <-h.unregister:
 			h.mu.Lock()
 			if _, ok := h.clients[conn]; ok {
-				delete(h.clients, conn)
-				conn.Close()
+				h.drop(conn)
 			}
 			h.mu.Unlock()
 
 		case message := <-h.broadcast:
 			h.mu.Lock()
 			for conn := range h.clients {
-				if err := conn.WriteMessage(websocket.TextMessage, message); err != nil {
-					conn.Close()
-					delete(h.clients, conn)
+				if h.receives(conn, message) {
+					h.write(conn, message.data)
 				}
 			}
 			h.mu.Unlock()
@@ -57,7 +86,99 @@ func (h *WebSocketHub) Run() {
 	}
 }
 
-// Broadcast sends a message to all connected clients
+// receives reports whether a connection gets a message
+// The caller must hold h.mu
+func (h *WebSocketHub) receives(conn *websocket.Conn, message hubMessage) bool {
+	if message.userID != 0 && h.users[conn] != message.userID {
+		return false
+	}
+	if message.topic == "" {
+		return true
+	}
+	topics, subscribed := h.topics[conn]
+	if !subscribed {
+		return !subscriptionOnly(message.topic)
+	}
+	return topics[message.topic]
+}
+
+// subscriptionOnly reports whether a topic's events reach only the connections
+// that subscribed to it
+func subscriptionOnly(topic string) bool {
+	return topic == topicJobs || strings.HasPrefix(topic, chatTopicPrefix)
+}
+
+// write sends a message to a connection, dropping the connection if that fails
+// The caller must hold h.mu
+func (h *WebSocketHub) write(conn *websocket.Conn, data []byte) {
+	if err := conn.WriteMessage(websocket.TextMessage, data); err != nil {
+		h.drop(conn)
+	}
+}
+
+// drop closes a connection and forgets it
+// The caller must hold h.mu
+func (h *WebSocketHub) drop(conn *websocket.Conn) {
+	conn.Close()
+	delete(h.clients, conn)
+	delete(h.users, conn)
+	delete(h.topics, conn)
+}
+
+// setUser records the signed-in user of a connection before it is registered
+func (h *WebSocketHub) setUser(conn *websocket.Conn, userID int64) {
+	h.mu.Lock()
+	h.users[conn] = userID
+	h.mu.Unlock()
+}
+
+// subscribe adds topics to a connection's subscriptions, or removes them when
+// add is false, and returns the topics it is now subscribed to. Topics past
+// maxWebSocketTopics are not added and are returned as rejected. A connection
+// left without subscriptions receives events as one that never subscribed
+func (h *WebSocketHub) subscribe(conn *websocket.Conn, topics []string, add bool) (subscribed, rejected []string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	current := h.topics[conn]
+	if current == nil {
+		current = make(map[string]bool)
+	}
+	for _, topic := range topics {
+		switch {
+		case !add:
+			delete(current, topic)
+		case current[topic] || len(current) < maxWebSocketTopics:
+			current[topic] = true
+		default:
+			rejected = append(rejected, topic)
+		}
+	}
+	if len(current) == 0 {
+		delete(h.topics, conn)
+	} else {
+		h.topics[conn] = current
+	}
+
+	subscribed = make([]string, 0, len(current))
+	for topic := range current {
+		subscribed = append(subscribed, topic)
+	}
+	sort.Strings(subscribed)
+	return subscribed, rejected
+}
+
+// reply sends a message to one connection, such as the answer to its
+// subscription request
+func (h *WebSocketHub) reply(conn *websocket.Conn, data []byte) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.clients[conn] {
+		h.write(conn, data)
+	}
+}
+
+// Broadcast sends a message to all connected clients; eventType is also its topic
 func (h *WebSocketHub) Broadcast(eventType, message string) {
 	data := map[string]string{
 		"type":    eventType,
@@ -65,15 +186,23 @@ func (h *WebSocketHub) Broadcast(eventType, message string) {
 	}
 
 	jsonData, _ := json.Marshal(data)
-	h.broadcast <- jsonData
+	h.broadcast <- hubMessage{topic: eventType, data: jsonData}
+}
+
+// SendToUser sends a JSON message of a topic to the connections of one signed-in user
+func (h *WebSocketHub) SendToUser(userID int64, topic string, data []byte) {
+	h.broadcast <- hubMessage{topic: topic, userID: userID, data: data}
+}
+
+// SendToTopic sends a JSON message to the connections that receive its topic
+func (h *WebSocketHub) SendToTopic(topic string, data []byte) {
+	h.broadcast <- hubMessage{topic: topic, data: data}
 }
 
 // handleWebSocket upgrades HTTP to WebSocket
 func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 	upgrader := websocket.Upgrader{
-		CheckOrigin: func(r *http.Request) bool {
-			return true // In production, validate origin
-		},
+		CheckOrigin: isSameOrigin, // The session cookie rides along, so refuse other sites' pages
 	}
 
 	conn, err := upgrader.Upgrade(w, r, nil)
@@ -81,19 +210,94 @@ func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Notifications go only to their user's connections
+	userID, err := auth.GetUserID(r.Context())
+	if err == nil {
+		s.wsHub.setUser(conn, userID)
+	}
 	s.wsHub.register <- conn
 
-	// Read loop (handle client messages if needed)
+	// Read loop: clients manage their subscriptions over the socket
 	go func() {
 		defer func() {
 			s.wsHub.unregister <- conn
 		}()
 
 		for {
-			_, _, err := conn.ReadMessage()
+			_, data, err := conn.ReadMessage()
 			if err != nil {
 				break
 			}
+			s.wsHub.reply(conn, s.handleWebSocketRequest(context.Background(), userID, conn, data))
 		}
 	}()
 }
+
+// webSocketRequest is a message from a WebSocket client
+type webSocketRequest struct {
+	Action string   `json:"action"` // "subscribe" or "unsubscribe"
+	Topics []string `json:"topics"`
+}
+
+// handleWebSocketRequest applies a client's subscribe or unsubscribe request and
+// returns the reply: the topics the connection is subscribed to, and why any it
+// asked for were refused
+func (s *Server) handleWebSocketRequest(ctx context.Context, userID int64, conn *websocket.Conn, data []byte) []byte {
+	var req webSocketRequest
+	if err := json.Unmarshal(data, &req); err != nil || (req.Action != "subscribe" && req.Action != "unsubscribe") {
+		reply, _ := json.Marshal(map[string]string{
+			"type":    "error",
+			"message": `Send {"action": "subscribe" or "unsubscribe", "topics": [...]}`,
+		})
+		return reply
+	}
+
+	denied := make(map[string]string)
+	var topics []string
+	for _, topic := range req.Topics {
+		if req.Action == "subscribe" {
+			if err := s.authorizeTopic(ctx, userID, topic); err != nil {
+				denied[topic] = err.Error()
+				continue
+			}
+		}
+		topics = append(topics, topic)
+	}
+
+	subscribed, rejected := s.wsHub.subscribe(conn, topics, req.Action == "subscribe")
+	for _, topic := range rejected {
+		denied[topic] = fmt.Sprintf("At most %d topics per connection", maxWebSocketTopics)
+	}
+
+	reply, _ := json.Marshal(map[string]interface{}{
+		"type":   "subscriptions",
+		"topics": subscribed,
+		"denied": denied,
+	})
+	return reply
+}
+
+// authorizeTopic returns why the user may not subscribe to a topic, or nil
+func (s *Server) authorizeTopic(ctx context.Context, userID int64, topic string) error {
+	if userID == 0 {
+		return errors.New("Sign in to subscribe")
+	}
+	switch {
+	case topic == topicIngestion || topic == topicNotifications:
+		return nil
+	case topic == topicJobs:
+		user, err := s.store.GetUserByID(ctx, userID)
+		if err != nil || user == nil || !user.IsAdmin {
+			return errors.New("Admin access required")
+		}
+		return nil
+	case strings.HasPrefix(topic, chatTopicPrefix):
+		// Sessions exist once their first message is saved
+		owner, err := s.store.GetSessionOwner(ctx, strings.TrimPrefix(topic, chatTopicPrefix))
+		if err != nil || owner != userID {
+			return errors.New("Session not found")
+		}
+		return nil
+	}
+	return errors.New("Unknown topic")
+}
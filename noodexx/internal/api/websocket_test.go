@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -159,3 +160,52 @@ func TestServer_handleWebSocket(t *testing.T) {
 		t.Errorf("Expected 0 clients after disconnect, got %d", clientCount)
 	}
 }
+
+// relayPublisher hands published events straight to another server
+type relayPublisher struct {
+	to    *Server
+	kinds []string
+}
+
+func (p *relayPublisher) Publish(ctx context.Context, kind, payload string) error {
+	p.kinds = append(p.kinds, kind)
+	p.to.RelayEvent(payload)
+	return nil
+}
+
+func TestServer_NotifySharesEvents(t *testing.T) {
+	hub := NewWebSocketHub()
+	go hub.Run()
+	other := &Server{wsHub: hub, logger: &mockLogger{}}
+
+	publisher := &relayPublisher{to: other}
+	server := &Server{logger: &mockLogger{}}
+	server.SetEventPublisher(publisher)
+
+	// A client of the other instance hears about this instance's ingestion
+	ts := httptest.NewServer(http.HandlerFunc(other.handleWebSocket))
+	defer ts.Close()
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("Failed to dial WebSocket: %v", err)
+	}
+	defer conn.Close()
+	time.Sleep(100 * time.Millisecond) // Give time for registration
+
+	server.Notify("ingestion", "Document 'notes.md' ingested successfully")
+
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, message, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatalf("Failed to read message: %v", err)
+	}
+	if !strings.Contains(string(message), `"type":"ingestion"`) || !strings.Contains(string(message), "notes.md") {
+		t.Errorf("Expected the relayed event, got %s", message)
+	}
+	if len(publisher.kinds) != 1 || publisher.kinds[0] != WebSocketEventKind {
+		t.Errorf("Expected one websocket event published, got %v", publisher.kinds)
+	}
+
+	// Malformed events from other instances are dropped
+	other.RelayEvent("not json")
+}
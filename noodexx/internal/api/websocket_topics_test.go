@@ -0,0 +1,147 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestServer_WebSocketSubscriptions(t *testing.T) {
+	hub := NewWebSocketHub()
+	go hub.Run()
+
+	store := &mockStoreForAsk{getSessionOwnerFunc: func(ctx context.Context, sessionID string) (int64, error) {
+		if sessionID == "s1" {
+			return 2, nil
+		}
+		return 3, nil
+	}}
+	server := &Server{wsHub: hub, store: store, logger: &mockLoggerForAsk{}}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		server.handleWebSocket(w, withUser(r, 2))
+	}))
+	defer ts.Close()
+	dial := func() *websocket.Conn {
+		t.Helper()
+		conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http"), nil)
+		if err != nil {
+			t.Fatalf("Failed to dial WebSocket: %v", err)
+		}
+		return conn
+	}
+	read := func(conn *websocket.Conn) map[string]interface{} {
+		t.Helper()
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("Failed to read message: %v", err)
+		}
+		var message map[string]interface{}
+		if err := json.Unmarshal(data, &message); err != nil {
+			t.Fatalf("Failed to decode message %s: %v", data, err)
+		}
+		return message
+	}
+
+	subscriber := dial()
+	defer subscriber.Close()
+	legacy := dial()
+	defer legacy.Close()
+	time.Sleep(100 * time.Millisecond) // Give time for registration
+
+	// Topics the user may not see are refused with a reason
+	subscriber.WriteJSON(webSocketRequest{Action: "subscribe", Topics: []string{"chat:s1", "notifications", topicJobs, "chat:s9", "weather"}})
+	reply := read(subscriber)
+	topics, _ := json.Marshal(reply["topics"])
+	if reply["type"] != "subscriptions" || string(topics) != `["chat:s1","notifications"]` {
+		t.Fatalf("Expected chat:s1 and notifications subscribed, got %v", reply)
+	}
+	denied, _ := reply["denied"].(map[string]interface{})
+	if denied[topicJobs] != "Admin access required" || denied["chat:s9"] != "Session not found" || denied["weather"] != "Unknown topic" {
+		t.Errorf("Expected jobs, chat:s9 and weather refused, got %v", denied)
+	}
+
+	// A subscribed connection gets only its topics; one that never subscribed
+	// gets everything but the subscription-only topics
+	server.pushToUser(2, topicIngestion, map[string]string{"type": "ingestion", "message": "notes.md ingested"})
+	server.NotifyJob(ScheduledJob{Name: "backup", Running: true})
+	server.pushSessionMessage(2, "s1", "user", "Hello", 0)
+
+	if message := read(subscriber); message["type"] != "chat_message" || message["session_id"] != "s1" || message["content"] != "Hello" {
+		t.Errorf("Expected the chat message first, got %v", message)
+	}
+	if message := read(legacy); message["type"] != "ingestion" {
+		t.Errorf("Expected the ingestion event, got %v", message)
+	}
+
+	// Malformed requests get an error and leave the subscriptions alone
+	subscriber.WriteMessage(websocket.TextMessage, []byte(`{"action":"watch"}`))
+	if message := read(subscriber); message["type"] != "error" {
+		t.Errorf("Expected an error reply, got %v", message)
+	}
+
+	subscriber.WriteJSON(webSocketRequest{Action: "unsubscribe", Topics: []string{"chat:s1"}})
+	reply = read(subscriber)
+	if topics, _ := json.Marshal(reply["topics"]); string(topics) != `["notifications"]` {
+		t.Errorf("Expected only notifications left, got %v", reply)
+	}
+	server.pushSessionMessage(2, "s1", "assistant", "Hi", 7)
+	server.pushToUser(2, topicNotifications, map[string]string{"type": "notification"})
+	if message := read(subscriber); message["type"] != "notification" {
+		t.Errorf("Expected the chat message skipped after unsubscribing, got %v", message)
+	}
+	if message := read(legacy); message["type"] != "notification" {
+		t.Errorf("Expected the notification, got %v", message)
+	}
+}
+
+func TestWebSocketHubReceives(t *testing.T) {
+	hub := NewWebSocketHub()
+	conn := &websocket.Conn{}
+	hub.users[conn] = 2
+
+	ingested := hubMessage{topic: topicIngestion, userID: 2}
+	announcement := hubMessage{}
+	chat := hubMessage{topic: chatTopicPrefix + "s1", userID: 2}
+
+	// Broadcasts outside any topic reach connections whatever they subscribed to
+	if !hub.receives(conn, announcement) {
+		t.Error("Expected a connection that never subscribed to get the broadcast")
+	}
+	hub.subscribe(conn, []string{topicNotifications}, true)
+	if !hub.receives(conn, announcement) {
+		t.Error("Expected a subscribed connection to get the broadcast")
+	}
+	if hub.receives(conn, ingested) {
+		t.Error("Expected a topic it did not subscribe to skipped")
+	}
+	if hub.receives(conn, hubMessage{userID: 3}) {
+		t.Error("Expected another user's message skipped")
+	}
+
+	// Unsubscribing from every topic forgets the subscriptions, so the
+	// connection gets events as one that never subscribed
+	subscribed, _ := hub.subscribe(conn, []string{topicNotifications}, false)
+	if len(subscribed) != 0 {
+		t.Errorf("Expected no topics left, got %v", subscribed)
+	}
+	if _, ok := hub.topics[conn]; ok {
+		t.Error("Expected the empty subscriptions removed")
+	}
+	if !hub.receives(conn, ingested) || hub.receives(conn, chat) {
+		t.Error("Expected the ingestion event but not the subscription-only chat event")
+	}
+
+	// Unsubscribing a connection without subscriptions leaves none behind
+	hub.subscribe(conn, []string{topicJobs}, false)
+	if _, ok := hub.topics[conn]; ok {
+		t.Error("Expected no subscriptions recorded by an unsubscribe")
+	}
+}
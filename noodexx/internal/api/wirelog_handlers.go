@@ -0,0 +1,102 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Wire log query limits
+const (
+	defaultWireLogLimit = 100
+	maxWireLogLimit     = 1000
+)
+
+// handleWireLog handles GET /api/admin/wire-log - query the provider request log (admin only)
+// Supported filters: provider, model, operation, user_id, errors=true, since (RFC 3339) and limit
+func (s *Server) handleWireLog(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	requestID := newRequestID(w)
+
+	// Create logger with request context
+	logger := s.logger.WithContext("request_id", requestID).
+		WithContext("method", r.Method).
+		WithContext("path", r.URL.Path)
+
+	logger.Debug("processing wire log request")
+
+	if s.wireLog == nil {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"enabled": false,
+			"entries": []WireLogEntry{},
+		})
+		return
+	}
+
+	filter, err := parseWireLogFilter(r)
+	if err != nil {
+		logger.Error("request failed", "operation", "parse_filter", "error", err.Error())
+		writeError(w, http.StatusBadRequest, CodeBadRequest, err.Error())
+		return
+	}
+
+	entries, err := s.wireLog.Query(filter)
+	if err != nil {
+		logger.Error("request failed", "operation", "query_wire_log", "error", err.Error())
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to read wire log")
+		return
+	}
+	if entries == nil {
+		entries = []WireLogEntry{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"enabled": true,
+		"entries": entries,
+	})
+
+	latency := time.Since(start).Milliseconds()
+	logger.Debug("request completed", "status", http.StatusOK, "latency_ms", latency, "count", len(entries))
+}
+
+// parseWireLogFilter reads wire log filters from the query string
+func parseWireLogFilter(r *http.Request) (WireLogFilter, error) {
+	q := r.URL.Query()
+	filter := WireLogFilter{
+		Provider:   q.Get("provider"),
+		Model:      q.Get("model"),
+		Operation:  q.Get("operation"),
+		ErrorsOnly: q.Get("errors") == "true",
+		Limit:      defaultWireLogLimit,
+	}
+
+	if v := q.Get("user_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return filter, fmt.Errorf("user_id must be a number")
+		}
+		filter.UserID = id
+	}
+	if v := q.Get("since"); v != "" {
+		since, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return filter, fmt.Errorf("since must be an RFC 3339 timestamp")
+		}
+		filter.Since = since
+	}
+	if v := q.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 || limit > maxWireLogLimit {
+			return filter, fmt.Errorf("limit must be between 1 and %d", maxWireLogLimit)
+		}
+		filter.Limit = limit
+	}
+
+	return filter, nil
+}
@@ -2,6 +2,7 @@ package auth
 
 import (
 	"context"
+	"strings"
 	"testing"
 	"time"
 )
@@ -31,15 +32,44 @@ func (m *MockStore) GetUserByUsername(ctx context.Context, username string) (*Us
 	return user, nil
 }
 
+func (m *MockStore) GetUserByID(ctx context.Context, userID int64) (*User, error) {
+	for _, user := range m.users {
+		if user.ID == userID {
+			return user, nil
+		}
+	}
+	return nil, ErrUserNotFound
+}
+
 func (m *MockStore) UpdateLastLogin(ctx context.Context, userID int64) error {
 	return nil
 }
 
-func (m *MockStore) CreateSessionToken(ctx context.Context, token string, userID int64, expiresAt interface{}) error {
+func (m *MockStore) CreateSessionToken(ctx context.Context, token string, userID int64, expiresAt interface{}, rememberMe bool) error {
 	m.tokens[token] = &SessionToken{
-		Token:     token,
-		UserID:    userID,
-		ExpiresAt: expiresAt,
+		Token:      token,
+		UserID:     userID,
+		CreatedAt:  time.Now(),
+		ExpiresAt:  expiresAt,
+		RememberMe: rememberMe,
+	}
+	return nil
+}
+
+func (m *MockStore) ExtendSessionToken(ctx context.Context, token string, expiresAt interface{}) error {
+	sessionToken, ok := m.tokens[token]
+	if !ok {
+		return ErrTokenNotFound
+	}
+	sessionToken.ExpiresAt = expiresAt
+	return nil
+}
+
+func (m *MockStore) DeleteUserSessionTokens(ctx context.Context, userID int64, keepToken string) error {
+	for token, sessionToken := range m.tokens {
+		if sessionToken.UserID == userID && token != keepToken {
+			delete(m.tokens, token)
+		}
 	}
 	return nil
 }
@@ -65,24 +95,32 @@ func (m *MockStore) DeleteSessionToken(ctx context.Context, token string) error
 	return nil
 }
 
-func (m *MockStore) IsAccountLocked(ctx context.Context, username string) (bool, interface{}) {
-	until, ok := m.lockedUntil[username]
-	if !ok {
+func (m *MockStore) IsAccountLocked(ctx context.Context, username string, threshold int, window time.Duration) (bool, interface{}) {
+	if until, ok := m.lockedUntil[username]; ok {
+		if time.Now().Before(until) {
+			return true, until
+		}
+		delete(m.lockedUntil, username)
+	}
+	if threshold <= 0 {
 		return false, time.Time{}
 	}
-	if time.Now().After(until) {
-		delete(m.lockedUntil, username)
+
+	// Lock after threshold failed attempts within the window
+	var recent []time.Time
+	for _, attempt := range m.failedLogins[username] {
+		if time.Since(attempt) < window {
+			recent = append(recent, attempt)
+		}
+	}
+	if len(recent) < threshold {
 		return false, time.Time{}
 	}
-	return true, until
+	return true, recent[len(recent)-threshold].Add(window)
 }
 
 func (m *MockStore) RecordFailedLogin(ctx context.Context, username string) error {
 	m.failedLogins[username] = append(m.failedLogins[username], time.Now())
-	// Lock account after 5 failed attempts
-	if len(m.failedLogins[username]) >= 5 {
-		m.lockedUntil[username] = time.Now().Add(15 * time.Minute)
-	}
 	return nil
 }
 
@@ -267,6 +305,154 @@ func TestUserpassAuth_Logout(t *testing.T) {
 	}
 }
 
+func TestUserpassAuth_LockoutPolicy(t *testing.T) {
+	password := "testPassword123"
+	hash, _ := hashPassword(password)
+
+	for _, tc := range []struct {
+		threshold       int
+		durationMinutes int
+		failures        int
+		locked          bool
+	}{
+		{5, 15, 4, false},
+		{5, 15, 5, true},
+		{3, 60, 3, true},
+		{10, 15, 9, false},
+	} {
+		store := NewMockStore()
+		store.users["testuser"] = &User{ID: 1, Username: "testuser", PasswordHash: hash}
+		auth := NewUserpassAuth(store, 7, tc.threshold, tc.durationMinutes)
+
+		for i := 0; i < tc.failures; i++ {
+			auth.Login(context.Background(), "testuser", "wrongPassword")
+		}
+
+		_, err := auth.Login(context.Background(), "testuser", password)
+		locked := err != nil && strings.Contains(err.Error(), "account locked")
+		if locked != tc.locked {
+			t.Errorf("threshold %d, %d failures: expected locked=%v, got error %v", tc.threshold, tc.failures, tc.locked, err)
+		}
+		if locked {
+			until, _ := time.Parse(time.RFC3339, strings.TrimPrefix(err.Error(), "account locked until "))
+			duration := time.Duration(tc.durationMinutes) * time.Minute
+			if remaining := time.Until(until); remaining > duration || remaining < duration-time.Minute {
+				t.Errorf("threshold %d: lockout should last %d minutes, %v remaining", tc.threshold, tc.durationMinutes, remaining)
+			}
+		}
+	}
+}
+
+func TestUserpassAuth_RememberMe(t *testing.T) {
+	store := NewMockStore()
+	auth := NewUserpassAuth(store, 7, 5, 15)
+	auth.SetSessionLimits(30, 90)
+
+	password := "testPassword123"
+	hash, _ := hashPassword(password)
+	store.users["testuser"] = &User{ID: 1, Username: "testuser", PasswordHash: hash}
+
+	token, err := auth.Login(context.Background(), "testuser", password)
+	if err != nil {
+		t.Fatalf("Login should succeed: %v", err)
+	}
+	if store.tokens[token].RememberMe {
+		t.Error("Plain login should not issue a remember-me session")
+	}
+	if until := time.Until(store.tokens[token].ExpiresAt.(time.Time)); until > 8*24*time.Hour {
+		t.Errorf("Plain session should expire in 7 days, got %v", until)
+	}
+
+	token, err = auth.Login(WithRememberMe(context.Background()), "testuser", password)
+	if err != nil {
+		t.Fatalf("Login should succeed: %v", err)
+	}
+	if !store.tokens[token].RememberMe {
+		t.Error("Remember-me login should issue a remember-me session")
+	}
+	if until := time.Until(store.tokens[token].ExpiresAt.(time.Time)); until < 29*24*time.Hour {
+		t.Errorf("Remember-me session should expire in 30 days, got %v", until)
+	}
+}
+
+func TestUserpassAuth_TouchSession(t *testing.T) {
+	store := NewMockStore()
+	auth := NewUserpassAuth(store, 7, 5, 15)
+	ctx := context.Background()
+
+	// A session used a day after login slides back to a full 7 days
+	store.CreateSessionToken(ctx, "t1", 1, time.Now().Add(6*24*time.Hour), false)
+	store.tokens["t1"].CreatedAt = time.Now().Add(-24 * time.Hour)
+
+	// Without a maximum lifetime the expiry is fixed
+	if _, extended, err := auth.TouchSession(ctx, store.tokens["t1"]); err != nil || extended {
+		t.Fatalf("Session should not slide without a max lifetime: extended=%v err=%v", extended, err)
+	}
+
+	auth.SetSessionLimits(30, 90)
+	expiresAt, extended, err := auth.TouchSession(ctx, store.tokens["t1"])
+	if err != nil || !extended {
+		t.Fatalf("Session should slide: extended=%v err=%v", extended, err)
+	}
+	if until := time.Until(expiresAt); until < 7*24*time.Hour-time.Minute {
+		t.Errorf("Expected expiry 7 days out, got %v", until)
+	}
+	if !store.tokens["t1"].ExpiresAt.(time.Time).Equal(expiresAt) {
+		t.Error("Extended expiry should be stored")
+	}
+
+	// Used again straight away, the expiry barely moves and is not rewritten
+	if _, extended, _ := auth.TouchSession(ctx, store.tokens["t1"]); extended {
+		t.Error("Session touched twice in a row should not be rewritten")
+	}
+
+	// A session near its maximum lifetime is capped
+	store.CreateSessionToken(ctx, "t2", 1, time.Now().Add(time.Hour), false)
+	store.tokens["t2"].CreatedAt = time.Now().Add(-89 * 24 * time.Hour)
+	expiresAt, extended, err = auth.TouchSession(ctx, store.tokens["t2"])
+	if err != nil || !extended {
+		t.Fatalf("Session should slide up to its limit: extended=%v err=%v", extended, err)
+	}
+	if until := time.Until(expiresAt); until > 24*time.Hour {
+		t.Errorf("Expiry should be capped at 90 days after creation, got %v from now", until)
+	}
+
+	// Remember-me sessions slide by the remember-me window
+	store.CreateSessionToken(ctx, "t3", 1, time.Now().Add(time.Hour), true)
+	expiresAt, _, _ = auth.TouchSession(ctx, store.tokens["t3"])
+	if until := time.Until(expiresAt); until < 29*24*time.Hour {
+		t.Errorf("Remember-me session should slide by 30 days, got %v", until)
+	}
+}
+
+func TestUserpassAuth_RefreshToken(t *testing.T) {
+	store := NewMockStore()
+	auth := NewUserpassAuth(store, 7, 5, 15)
+	ctx := context.Background()
+
+	expiresAt := time.Now().Add(48 * time.Hour)
+	store.CreateSessionToken(ctx, "old", 1, expiresAt, true)
+
+	newToken, err := auth.RefreshToken(ctx, "old")
+	if err != nil {
+		t.Fatalf("RefreshToken should succeed: %v", err)
+	}
+	if newToken == "" || newToken == "old" {
+		t.Fatalf("RefreshToken should return a new token, got %q", newToken)
+	}
+	if _, ok := store.tokens["old"]; ok {
+		t.Error("Old token should be revoked")
+	}
+	rotated := store.tokens[newToken]
+	if rotated == nil || rotated.UserID != 1 || !rotated.RememberMe || !rotated.ExpiresAt.(time.Time).Equal(expiresAt) {
+		t.Errorf("New token should keep the user, expiry and remember-me flag, got %+v", rotated)
+	}
+
+	if _, err := auth.RefreshToken(ctx, "old"); err == nil {
+		t.Error("Refreshing a revoked token should fail")
+	}
+}
+
 func TestGetProvider(t *testing.T) {
 	store := NewMockStore()
 
@@ -351,3 +537,37 @@ func TestSSOAuth_Stubs(t *testing.T) {
 		t.Error("SSO RefreshToken should return not implemented error")
 	}
 }
+
+func TestRegister(t *testing.T) {
+	Register("test-provider", func(opts ProviderOptions) (Provider, error) {
+		if opts.Settings["fail"] == "true" {
+			return nil, &mockError{"bad settings"}
+		}
+		return &SSOAuth{}, nil
+	})
+
+	if !IsRegistered("test-provider") {
+		t.Fatal("Expected test-provider to be registered")
+	}
+	found := false
+	for _, name := range Providers() {
+		found = found || name == "test-provider"
+	}
+	if !found {
+		t.Errorf("Expected test-provider in %v", Providers())
+	}
+
+	if _, err := NewProvider("test-provider", ProviderOptions{}); err != nil {
+		t.Errorf("NewProvider should succeed for a registered provider: %v", err)
+	}
+	if _, err := NewProvider("test-provider", ProviderOptions{Settings: map[string]string{"fail": "true"}}); err == nil {
+		t.Error("NewProvider should return the factory's error")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Register should panic for a duplicate name")
+		}
+	}()
+	Register("userpass", func(opts ProviderOptions) (Provider, error) { return nil, nil })
+}
@@ -0,0 +1,167 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// Default headers of the reverse-proxy header provider (Authelia's names;
+// oauth2-proxy sends X-Forwarded-User, X-Forwarded-Email and X-Forwarded-Groups)
+const (
+	defaultUserHeader   = "Remote-User"
+	defaultEmailHeader  = "Remote-Email"
+	defaultGroupsHeader = "Remote-Groups"
+)
+
+// HeaderAuth trusts the user named in a header set by an authenticating reverse
+// proxy such as Authelia or oauth2-proxy
+// The header is only believed on requests from a trusted proxy address, since
+// anyone reaching the server directly could set it
+type HeaderAuth struct {
+	store          Store
+	userHeader     string
+	emailHeader    string
+	groupsHeader   string
+	adminGroup     string
+	autoCreate     bool
+	trustedProxies []*net.IPNet
+}
+
+// NewHeaderAuth creates a header provider from its auth.settings:
+//
+//	trusted_proxies  comma-separated proxy IPs or CIDRs (required)
+//	user_header      header naming the user (default Remote-User)
+//	email_header     header with the user's email (default Remote-Email)
+//	groups_header    header with comma-separated groups (default Remote-Groups)
+//	admin_group      group whose members are created as admins (optional)
+//	auto_create      "false" to only admit users that already have an account
+func NewHeaderAuth(store Store, settings map[string]string) (*HeaderAuth, error) {
+	h := &HeaderAuth{
+		store:        store,
+		userHeader:   settingOr(settings, "user_header", defaultUserHeader),
+		emailHeader:  settingOr(settings, "email_header", defaultEmailHeader),
+		groupsHeader: settingOr(settings, "groups_header", defaultGroupsHeader),
+		adminGroup:   strings.TrimSpace(settings["admin_group"]),
+		autoCreate:   settings["auto_create"] != "false",
+	}
+
+	for _, entry := range strings.Split(settings["trusted_proxies"], ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if !strings.Contains(entry, "/") {
+			if ip := net.ParseIP(entry); ip != nil && ip.To4() != nil {
+				entry += "/32"
+			} else {
+				entry += "/128"
+			}
+		}
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q", entry)
+		}
+		h.trustedProxies = append(h.trustedProxies, network)
+	}
+	if len(h.trustedProxies) == 0 {
+		return nil, fmt.Errorf("trusted_proxies is required so the user header cannot be spoofed")
+	}
+
+	if h.autoCreate {
+		if _, ok := store.(UserCreator); !ok {
+			return nil, fmt.Errorf("auto_create needs a store that can create users")
+		}
+	}
+
+	return h, nil
+}
+
+// settingOr returns settings[key], or def if it is unset
+func settingOr(settings map[string]string, key, def string) string {
+	if v := strings.TrimSpace(settings[key]); v != "" {
+		return v
+	}
+	return def
+}
+
+// AuthenticateRequest returns the user named by the proxy's header, creating the
+// account on first sight if auto_create is on
+func (h *HeaderAuth) AuthenticateRequest(r *http.Request) (int64, error) {
+	if !h.fromTrustedProxy(r) {
+		return 0, ErrNoIdentity
+	}
+	username := strings.TrimSpace(r.Header.Get(h.userHeader))
+	if username == "" {
+		return 0, ErrNoIdentity
+	}
+
+	ctx := r.Context()
+	if user, err := h.store.GetUserByUsername(ctx, username); err == nil && user != nil {
+		return user.ID, nil
+	}
+	if !h.autoCreate {
+		return 0, fmt.Errorf("%w: %s", ErrUnknownUser, username)
+	}
+
+	userID, err := h.createUser(ctx, username, r.Header.Get(h.emailHeader), r.Header.Get(h.groupsHeader))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create user %s: %w", username, err)
+	}
+	return userID, nil
+}
+
+// createUser creates the account of a proxy-authenticated user
+func (h *HeaderAuth) createUser(ctx context.Context, username, email, groups string) (int64, error) {
+	isAdmin := false
+	if h.adminGroup != "" {
+		for _, group := range strings.Split(groups, ",") {
+			if strings.TrimSpace(group) == h.adminGroup {
+				isAdmin = true
+				break
+			}
+		}
+	}
+	return createExternalUser(ctx, h.store, username, email, isAdmin)
+}
+
+// fromTrustedProxy reports whether r was sent by one of the trusted proxies
+func (h *HeaderAuth) fromTrustedProxy(r *http.Request) bool {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, network := range h.trustedProxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// Login is not available: the reverse proxy signs users in
+func (h *HeaderAuth) Login(ctx context.Context, username, password string) (string, error) {
+	return "", fmt.Errorf("sign-in is handled by the reverse proxy")
+}
+
+// Logout invalidates a session token left from before header auth was enabled
+// The proxy's own session is not affected
+func (h *HeaderAuth) Logout(ctx context.Context, token string) error {
+	return h.store.DeleteSessionToken(ctx, token)
+}
+
+// ValidateToken returns an error: header auth does not issue tokens
+func (h *HeaderAuth) ValidateToken(ctx context.Context, token string) (int64, error) {
+	return 0, fmt.Errorf("header authentication does not use session tokens")
+}
+
+// RefreshToken returns an error: header auth does not issue tokens
+func (h *HeaderAuth) RefreshToken(ctx context.Context, token string) (string, error) {
+	return "", fmt.Errorf("header authentication does not use session tokens")
+}
@@ -0,0 +1,146 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// creatingStore is a MockStore that can also create users
+type creatingStore struct {
+	*MockStore
+	nextID int64
+}
+
+func (m *creatingStore) CreateUser(ctx context.Context, username, password, email string, isAdmin, mustChangePassword bool) (int64, error) {
+	m.nextID++
+	m.users[username] = &User{ID: m.nextID, Username: username, Email: email, IsAdmin: isAdmin}
+	return m.nextID, nil
+}
+
+func TestNewHeaderAuth_Settings(t *testing.T) {
+	store := &creatingStore{MockStore: NewMockStore()}
+
+	if _, err := NewHeaderAuth(store, nil); err == nil {
+		t.Error("Expected an error without trusted_proxies")
+	}
+	if _, err := NewHeaderAuth(store, map[string]string{"trusted_proxies": "not-an-ip"}); err == nil {
+		t.Error("Expected an error for an invalid trusted proxy")
+	}
+	if _, err := NewHeaderAuth(NewMockStore(), map[string]string{"trusted_proxies": "10.0.0.1"}); err == nil {
+		t.Error("Expected an error for auto_create with a store that cannot create users")
+	}
+	if _, err := NewHeaderAuth(NewMockStore(), map[string]string{"trusted_proxies": "10.0.0.1", "auto_create": "false"}); err != nil {
+		t.Errorf("Expected no error without auto_create, got %v", err)
+	}
+
+	provider, err := NewProvider("header", ProviderOptions{Store: store, Settings: map[string]string{"trusted_proxies": "10.0.0.0/8, ::1"}})
+	if err != nil {
+		t.Fatalf("NewProvider should succeed for header: %v", err)
+	}
+	if _, ok := provider.(RequestAuthenticator); !ok {
+		t.Error("Header provider should authenticate requests")
+	}
+}
+
+func TestHeaderAuth_AuthenticateRequest(t *testing.T) {
+	store := &creatingStore{MockStore: NewMockStore(), nextID: 10}
+	store.users["alice"] = &User{ID: 3, Username: "alice"}
+
+	h, err := NewHeaderAuth(store, map[string]string{
+		"trusted_proxies": "10.0.0.0/8",
+		"admin_group":     "admins",
+	})
+	if err != nil {
+		t.Fatalf("NewHeaderAuth failed: %v", err)
+	}
+
+	request := func(remoteAddr string, headers map[string]string) *http.Request {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = remoteAddr
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		return req
+	}
+
+	// Existing users are found by name
+	userID, err := h.AuthenticateRequest(request("10.1.2.3:5000", map[string]string{"Remote-User": "alice"}))
+	if err != nil || userID != 3 {
+		t.Errorf("Expected user 3, got %d (%v)", userID, err)
+	}
+
+	// The header is ignored from untrusted addresses and when missing
+	if _, err := h.AuthenticateRequest(request("192.0.2.1:5000", map[string]string{"Remote-User": "alice"})); err != ErrNoIdentity {
+		t.Errorf("Expected ErrNoIdentity from an untrusted address, got %v", err)
+	}
+	if _, err := h.AuthenticateRequest(request("10.1.2.3:5000", nil)); err != ErrNoIdentity {
+		t.Errorf("Expected ErrNoIdentity without the header, got %v", err)
+	}
+
+	// New users are created, as admins when in the admin group
+	userID, err = h.AuthenticateRequest(request("10.1.2.3:5000", map[string]string{
+		"Remote-User":   "bob",
+		"Remote-Email":  "bob@example.com",
+		"Remote-Groups": "users, admins",
+	}))
+	if err != nil || userID != 11 {
+		t.Fatalf("Expected new user 11, got %d (%v)", userID, err)
+	}
+	if bob := store.users["bob"]; bob.Email != "bob@example.com" || !bob.IsAdmin {
+		t.Errorf("Unexpected created user: %+v", bob)
+	}
+}
+
+func TestAuthMiddlewareWithProvider_Header(t *testing.T) {
+	store := &creatingStore{MockStore: NewMockStore()}
+	store.users["alice"] = &User{ID: 3, Username: "alice"}
+	store.tokens["valid"] = &SessionToken{Token: "valid", UserID: 7}
+
+	h, err := NewHeaderAuth(store, map[string]string{"trusted_proxies": "10.0.0.1", "auto_create": "false"})
+	if err != nil {
+		t.Fatalf("NewHeaderAuth failed: %v", err)
+	}
+
+	var gotUserID int64
+	handler := AuthMiddlewareWithProvider(store, "multi", h)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotUserID, _ = GetUserID(r.Context())
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		user       string
+		token      string
+		wantStatus int
+		wantUserID int64
+	}{
+		{"proxy user", "10.0.0.1:1234", "alice", "", http.StatusOK, 3},
+		{"unknown proxy user", "10.0.0.1:1234", "mallory", "", http.StatusForbidden, 0},
+		{"spoofed header falls back to token", "192.0.2.1:1234", "alice", "valid", http.StatusOK, 7},
+		{"spoofed header without token", "192.0.2.1:1234", "alice", "", http.StatusUnauthorized, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotUserID = 0
+			req := httptest.NewRequest(http.MethodGet, "/api/sessions", nil)
+			req.RemoteAddr = tt.remoteAddr
+			req.Header.Set("Remote-User", tt.user)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d", tt.wantStatus, w.Code)
+			}
+			if gotUserID != tt.wantUserID {
+				t.Errorf("Expected user %d, got %d", tt.wantUserID, gotUserID)
+			}
+		})
+	}
+}
@@ -0,0 +1,437 @@
+package auth
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// LDAP provider defaults
+const (
+	defaultLDAPPoolSize       = 4
+	defaultLDAPTimeoutSeconds = 10
+	defaultLDAPUserFilter     = "(uid={username})"
+	defaultLDAPEmailAttribute = "mail"
+	defaultLDAPGroupAttribute = "memberOf"
+)
+
+// errBadCredentials is returned by a directory for an unknown user or a wrong password
+var errBadCredentials = errors.New("invalid credentials")
+
+// directoryUser is a user whose password the directory has verified
+type directoryUser struct {
+	DN     string
+	Email  string
+	Groups []string // Group DNs or names, as the directory lists them
+}
+
+// directory verifies a user's password and returns their entry
+type directory interface {
+	Authenticate(username, password string) (*directoryUser, error)
+}
+
+// LDAPAuth signs users in against an LDAP server or Active Directory
+// A local account is created on a user's first sign-in; after that, sessions
+// work exactly as with userpass
+type LDAPAuth struct {
+	sessions     *UserpassAuth
+	store        Store
+	directory    directory
+	adminGroup   string
+	allowedGroup string
+	autoCreate   bool
+	localUsers   map[string]bool
+}
+
+// NewLDAPAuth creates an LDAP provider from its auth.settings:
+//
+//	url                   ldap://host:389 or ldaps://host:636 (required)
+//	start_tls             "true" to upgrade an ldap:// connection with StartTLS
+//	ca_file               PEM file of CAs to trust for the server certificate
+//	insecure_skip_verify  "true" to skip certificate checks (testing only)
+//	bind_dn, bind_password  service account used to look users up
+//	base_dn               where to search for users (required with bind_dn)
+//	user_filter           search filter, default (uid={username}); AD uses (sAMAccountName={username})
+//	user_dn_template      bind directly as e.g. uid={username},ou=people,dc=example,dc=com instead of searching
+//	email_attribute       default mail
+//	group_attribute       default memberOf
+//	admin_group           group DN or CN whose members are admins
+//	allowed_group         group DN or CN a user must be in to sign in (optional)
+//	auto_create           "false" to only admit users that already have an account
+//	local_users           comma-separated accounts, e.g. admin, that sign in with their Noodexx password
+//	pool_size             idle connections kept open (default 4)
+//	timeout_seconds       connect and request timeout (default 10)
+func NewLDAPAuth(opts ProviderOptions) (*LDAPAuth, error) {
+	settings := opts.Settings
+	dir, err := newLDAPDirectory(settings)
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := NewUserpassAuth(opts.Store, opts.SessionExpiryDays, opts.LockoutThreshold, opts.LockoutDurationMinutes)
+	sessions.SetSessionLimits(opts.RememberMeDays, opts.MaxSessionDays)
+
+	l := &LDAPAuth{
+		sessions:     sessions,
+		store:        opts.Store,
+		directory:    dir,
+		adminGroup:   strings.TrimSpace(settings["admin_group"]),
+		allowedGroup: strings.TrimSpace(settings["allowed_group"]),
+		autoCreate:   settings["auto_create"] != "false",
+		localUsers:   make(map[string]bool),
+	}
+	for _, name := range strings.Split(settings["local_users"], ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			l.localUsers[name] = true
+		}
+	}
+
+	if l.autoCreate {
+		if _, ok := opts.Store.(UserCreator); !ok {
+			return nil, fmt.Errorf("auto_create needs a store that can create users")
+		}
+	}
+
+	return l, nil
+}
+
+// Login verifies the password with the directory and returns a session token
+func (l *LDAPAuth) Login(ctx context.Context, username, password string) (string, error) {
+	if l.localUsers[username] {
+		return l.sessions.Login(ctx, username, password)
+	}
+
+	if err := l.sessions.checkLockout(ctx, username); err != nil {
+		return "", err
+	}
+
+	// An empty password would be an unauthenticated bind, which servers accept
+	if password == "" {
+		l.store.RecordFailedLogin(ctx, username)
+		return "", fmt.Errorf("invalid credentials")
+	}
+
+	entry, err := l.directory.Authenticate(username, password)
+	if errors.Is(err, errBadCredentials) {
+		l.store.RecordFailedLogin(ctx, username)
+		return "", fmt.Errorf("invalid credentials")
+	}
+	if err != nil {
+		return "", fmt.Errorf("directory unavailable: %w", err)
+	}
+
+	if l.allowedGroup != "" && !inGroup(entry.Groups, l.allowedGroup) {
+		return "", fmt.Errorf("user is not in the allowed group")
+	}
+	isAdmin := l.adminGroup != "" && inGroup(entry.Groups, l.adminGroup)
+
+	userID, err := l.localUser(ctx, username, entry.Email, isAdmin)
+	if err != nil {
+		return "", err
+	}
+
+	return l.sessions.startSession(ctx, userID, username)
+}
+
+// localUser returns the local account of a directory user, creating it on first
+// sign-in and keeping its admin rights in step with admin_group
+func (l *LDAPAuth) localUser(ctx context.Context, username, email string, isAdmin bool) (int64, error) {
+	user, err := l.store.GetUserByUsername(ctx, username)
+	if err == nil && user != nil {
+		if l.adminGroup != "" && user.IsAdmin != isAdmin {
+			if updater, ok := l.store.(AdminUpdater); ok {
+				if err := updater.SetUserAdmin(ctx, user.ID, isAdmin); err != nil {
+					return 0, fmt.Errorf("failed to update admin rights: %w", err)
+				}
+				// Sessions issued under the old rights must not outlive them
+				if err := l.store.DeleteUserSessionTokens(ctx, user.ID, ""); err != nil {
+					return 0, fmt.Errorf("failed to revoke sessions: %w", err)
+				}
+			}
+		}
+		return user.ID, nil
+	}
+
+	if !l.autoCreate {
+		return 0, fmt.Errorf("%w: %s", ErrUnknownUser, username)
+	}
+	userID, err := createExternalUser(ctx, l.store, username, email, isAdmin)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create user %s: %w", username, err)
+	}
+	return userID, nil
+}
+
+// Logout invalidates a session token
+func (l *LDAPAuth) Logout(ctx context.Context, token string) error {
+	return l.sessions.Logout(ctx, token)
+}
+
+// ValidateToken verifies a token and returns the user_id
+func (l *LDAPAuth) ValidateToken(ctx context.Context, token string) (int64, error) {
+	return l.sessions.ValidateToken(ctx, token)
+}
+
+// RefreshToken replaces a session token with a new one
+func (l *LDAPAuth) RefreshToken(ctx context.Context, token string) (string, error) {
+	return l.sessions.RefreshToken(ctx, token)
+}
+
+// TouchSession slides a session's expiry forward after it is used
+func (l *LDAPAuth) TouchSession(ctx context.Context, token *SessionToken) (time.Time, bool, error) {
+	return l.sessions.TouchSession(ctx, token)
+}
+
+// inGroup reports whether groups contains want, given as a full DN or as the
+// group's CN
+func inGroup(groups []string, want string) bool {
+	for _, group := range groups {
+		if strings.EqualFold(group, want) {
+			return true
+		}
+		dn, err := ldap.ParseDN(group)
+		if err != nil || len(dn.RDNs) == 0 {
+			continue
+		}
+		for _, attr := range dn.RDNs[0].Attributes {
+			if strings.EqualFold(attr.Type, "cn") && strings.EqualFold(attr.Value, want) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ldapDirectory is a directory backed by an LDAP server, with a small pool of
+// open connections
+type ldapDirectory struct {
+	url            string
+	startTLS       bool
+	tlsConfig      *tls.Config
+	timeout        time.Duration
+	bindDN         string
+	bindPassword   string
+	baseDN         string
+	userFilter     string
+	userDNTemplate string
+	emailAttribute string
+	groupAttribute string
+	pool           chan *ldap.Conn
+}
+
+// newLDAPDirectory creates a directory from the provider settings
+func newLDAPDirectory(settings map[string]string) (*ldapDirectory, error) {
+	d := &ldapDirectory{
+		url:            strings.TrimSpace(settings["url"]),
+		startTLS:       settings["start_tls"] == "true",
+		bindDN:         strings.TrimSpace(settings["bind_dn"]),
+		bindPassword:   settings["bind_password"],
+		baseDN:         strings.TrimSpace(settings["base_dn"]),
+		userFilter:     settingOr(settings, "user_filter", defaultLDAPUserFilter),
+		userDNTemplate: strings.TrimSpace(settings["user_dn_template"]),
+		emailAttribute: settingOr(settings, "email_attribute", defaultLDAPEmailAttribute),
+		groupAttribute: settingOr(settings, "group_attribute", defaultLDAPGroupAttribute),
+	}
+
+	u, err := url.Parse(d.url)
+	if err != nil || (u.Scheme != "ldap" && u.Scheme != "ldaps") || u.Host == "" {
+		return nil, fmt.Errorf("url must be an ldap:// or ldaps:// URL")
+	}
+	if d.startTLS && u.Scheme == "ldaps" {
+		return nil, fmt.Errorf("start_tls only applies to ldap:// URLs")
+	}
+	if d.bindDN == "" && d.userDNTemplate == "" {
+		return nil, fmt.Errorf("either bind_dn or user_dn_template is required")
+	}
+	if d.bindDN != "" && d.baseDN == "" {
+		return nil, fmt.Errorf("base_dn is required with bind_dn")
+	}
+	if d.bindDN != "" && !strings.Contains(d.userFilter, "{username}") {
+		return nil, fmt.Errorf("user_filter must contain {username}")
+	}
+	if d.userDNTemplate != "" && !strings.Contains(d.userDNTemplate, "{username}") {
+		return nil, fmt.Errorf("user_dn_template must contain {username}")
+	}
+
+	poolSize, err := intSetting(settings, "pool_size", defaultLDAPPoolSize)
+	if err != nil {
+		return nil, err
+	}
+	timeoutSeconds, err := intSetting(settings, "timeout_seconds", defaultLDAPTimeoutSeconds)
+	if err != nil {
+		return nil, err
+	}
+	d.pool = make(chan *ldap.Conn, poolSize)
+	d.timeout = time.Duration(timeoutSeconds) * time.Second
+
+	d.tlsConfig = &tls.Config{
+		ServerName:         u.Hostname(),
+		InsecureSkipVerify: settings["insecure_skip_verify"] == "true",
+		MinVersion:         tls.VersionTLS12,
+	}
+	if caFile := strings.TrimSpace(settings["ca_file"]); caFile != "" {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read ca_file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("ca_file contains no certificates")
+		}
+		d.tlsConfig.RootCAs = pool
+	}
+
+	return d, nil
+}
+
+// intSetting parses a positive integer setting, returning def if it is unset
+func intSetting(settings map[string]string, key string, def int) (int, error) {
+	v := strings.TrimSpace(settings[key])
+	if v == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("%s must be a positive number", key)
+	}
+	return n, nil
+}
+
+// Authenticate binds as the user to check the password and reads their entry
+func (d *ldapDirectory) Authenticate(username, password string) (*directoryUser, error) {
+	conn, err := d.get()
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := d.authenticate(conn, username, password)
+	// Keep the connection unless it failed below the LDAP protocol level
+	var ldapErr *ldap.Error
+	if err == nil || errors.Is(err, errBadCredentials) || (errors.As(err, &ldapErr) && ldapErr.ResultCode < ldap.ErrorNetwork) {
+		d.put(conn)
+	} else {
+		conn.Close()
+	}
+	return user, err
+}
+
+// authenticate finds the user's DN, by search or from user_dn_template, and
+// binds as it
+func (d *ldapDirectory) authenticate(conn *ldap.Conn, username, password string) (*directoryUser, error) {
+	attributes := []string{d.emailAttribute, d.groupAttribute}
+
+	if d.userDNTemplate != "" && d.bindDN == "" {
+		dn := strings.ReplaceAll(d.userDNTemplate, "{username}", ldap.EscapeDN(username))
+		if err := d.bind(conn, dn, password); err != nil {
+			return nil, err
+		}
+		// Read the user's own entry while bound as them
+		entry, err := d.searchOne(conn, dn, ldap.ScopeBaseObject, "(objectClass=*)", attributes)
+		if err != nil {
+			return nil, err
+		}
+		return d.toUser(dn, entry), nil
+	}
+
+	if err := conn.Bind(d.bindDN, d.bindPassword); err != nil {
+		return nil, fmt.Errorf("service account bind failed: %w", err)
+	}
+	filter := strings.ReplaceAll(d.userFilter, "{username}", ldap.EscapeFilter(username))
+	entry, err := d.searchOne(conn, d.baseDN, ldap.ScopeWholeSubtree, filter, attributes)
+	if err != nil {
+		return nil, err
+	}
+	if err := d.bind(conn, entry.DN, password); err != nil {
+		return nil, err
+	}
+	return d.toUser(entry.DN, entry), nil
+}
+
+// bind binds as dn, reporting a rejected password as errBadCredentials
+func (d *ldapDirectory) bind(conn *ldap.Conn, dn, password string) error {
+	err := conn.Bind(dn, password)
+	if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+		return errBadCredentials
+	}
+	return err
+}
+
+// searchOne returns the single entry matching filter; no match, or more than
+// one, is errBadCredentials
+func (d *ldapDirectory) searchOne(conn *ldap.Conn, baseDN string, scope int, filter string, attributes []string) (*ldap.Entry, error) {
+	req := ldap.NewSearchRequest(baseDN, scope, ldap.NeverDerefAliases, 2, int(d.timeout.Seconds()), false, filter, attributes, nil)
+	result, err := conn.Search(req)
+	if ldap.IsErrorWithCode(err, ldap.LDAPResultSizeLimitExceeded) || ldap.IsErrorWithCode(err, ldap.LDAPResultNoSuchObject) {
+		return nil, errBadCredentials
+	}
+	if err != nil {
+		return nil, fmt.Errorf("user search failed: %w", err)
+	}
+	if len(result.Entries) != 1 {
+		return nil, errBadCredentials
+	}
+	return result.Entries[0], nil
+}
+
+// toUser converts a directory entry
+func (d *ldapDirectory) toUser(dn string, entry *ldap.Entry) *directoryUser {
+	return &directoryUser{
+		DN:     dn,
+		Email:  entry.GetAttributeValue(d.emailAttribute),
+		Groups: entry.GetAttributeValues(d.groupAttribute),
+	}
+}
+
+// get takes an idle connection from the pool or opens a new one
+func (d *ldapDirectory) get() (*ldap.Conn, error) {
+	for {
+		select {
+		case conn := <-d.pool:
+			if !conn.IsClosing() {
+				return conn, nil
+			}
+			conn.Close()
+		default:
+			return d.dial()
+		}
+	}
+}
+
+// put returns a connection to the pool, closing it if the pool is full
+func (d *ldapDirectory) put(conn *ldap.Conn) {
+	select {
+	case d.pool <- conn:
+	default:
+		conn.Close()
+	}
+}
+
+// dial opens a connection, upgrading it with StartTLS if configured
+func (d *ldapDirectory) dial() (*ldap.Conn, error) {
+	conn, err := ldap.DialURL(d.url,
+		ldap.DialWithDialer(&net.Dialer{Timeout: d.timeout}),
+		ldap.DialWithTLSConfig(d.tlsConfig),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to LDAP server: %w", err)
+	}
+	conn.SetTimeout(d.timeout)
+
+	if d.startTLS {
+		if err := conn.StartTLS(d.tlsConfig); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("StartTLS failed: %w", err)
+		}
+	}
+	return conn, nil
+}
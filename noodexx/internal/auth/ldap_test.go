@@ -0,0 +1,194 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// fakeDirectory is a directory with fixed users and passwords
+type fakeDirectory struct {
+	users     map[string]*directoryUser
+	passwords map[string]string
+	err       error
+}
+
+func (d *fakeDirectory) Authenticate(username, password string) (*directoryUser, error) {
+	if d.err != nil {
+		return nil, d.err
+	}
+	user, ok := d.users[username]
+	if !ok || d.passwords[username] != password {
+		return nil, errBadCredentials
+	}
+	return user, nil
+}
+
+// adminStore is a creatingStore that can also change admin rights
+type adminStore struct {
+	*creatingStore
+}
+
+func (m *adminStore) SetUserAdmin(ctx context.Context, userID int64, isAdmin bool) error {
+	for _, user := range m.users {
+		if user.ID == userID {
+			user.IsAdmin = isAdmin
+		}
+	}
+	return nil
+}
+
+func TestNewLDAPAuth_Settings(t *testing.T) {
+	store := &creatingStore{MockStore: NewMockStore()}
+
+	tests := []struct {
+		name     string
+		settings map[string]string
+		wantErr  bool
+	}{
+		{"search with service account", map[string]string{"url": "ldap://dc.example.com", "bind_dn": "cn=svc", "base_dn": "dc=example,dc=com"}, false},
+		{"direct bind", map[string]string{"url": "ldaps://dc.example.com", "user_dn_template": "uid={username},dc=example,dc=com"}, false},
+		{"missing url", map[string]string{"bind_dn": "cn=svc", "base_dn": "dc=example,dc=com"}, true},
+		{"wrong scheme", map[string]string{"url": "http://dc.example.com", "bind_dn": "cn=svc", "base_dn": "dc=x"}, true},
+		{"no way to find users", map[string]string{"url": "ldap://dc.example.com"}, true},
+		{"bind_dn without base_dn", map[string]string{"url": "ldap://dc.example.com", "bind_dn": "cn=svc"}, true},
+		{"template without placeholder", map[string]string{"url": "ldap://dc.example.com", "user_dn_template": "uid=x,dc=example"}, true},
+		{"start_tls on ldaps", map[string]string{"url": "ldaps://dc.example.com", "start_tls": "true", "user_dn_template": "uid={username}"}, true},
+		{"bad pool size", map[string]string{"url": "ldap://dc.example.com", "user_dn_template": "uid={username}", "pool_size": "0"}, true},
+		{"missing ca_file", map[string]string{"url": "ldap://dc.example.com", "user_dn_template": "uid={username}", "ca_file": "/nonexistent.pem"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewLDAPAuth(ProviderOptions{Store: store, Settings: tt.settings})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	provider, err := NewProvider("ldap", ProviderOptions{Store: store, Settings: tests[0].settings})
+	if err != nil {
+		t.Fatalf("NewProvider should succeed for ldap: %v", err)
+	}
+	if _, ok := provider.(*LDAPAuth); !ok {
+		t.Error("Provider should be LDAPAuth")
+	}
+}
+
+func TestLDAPAuth_Login(t *testing.T) {
+	store := &adminStore{&creatingStore{MockStore: NewMockStore(), nextID: 10}}
+	hash, _ := hashPassword("local-secret")
+	store.users["admin"] = &User{ID: 1, Username: "admin", PasswordHash: hash, IsAdmin: true}
+	store.users["carol"] = &User{ID: 5, Username: "carol", IsAdmin: true}
+
+	dir := &fakeDirectory{
+		users: map[string]*directoryUser{
+			"alice": {DN: "uid=alice,dc=example,dc=com", Email: "alice@example.com", Groups: []string{"cn=Staff,ou=groups,dc=example,dc=com", "cn=Noodexx Admins,ou=groups,dc=example,dc=com"}},
+			"bob":   {DN: "uid=bob,dc=example,dc=com", Groups: []string{"cn=staff,ou=groups,dc=example,dc=com"}},
+			"carol": {DN: "uid=carol,dc=example,dc=com", Groups: []string{"cn=staff,ou=groups,dc=example,dc=com"}},
+			"eve":   {DN: "uid=eve,dc=example,dc=com"},
+		},
+		passwords: map[string]string{"alice": "pw", "bob": "pw", "carol": "pw", "eve": "pw"},
+	}
+	l := &LDAPAuth{
+		sessions:     NewUserpassAuth(store, 7, 5, 15),
+		store:        store,
+		directory:    dir,
+		adminGroup:   "noodexx admins",
+		allowedGroup: "staff",
+		autoCreate:   true,
+		localUsers:   map[string]bool{"admin": true},
+	}
+	ctx := context.Background()
+
+	// First sign-in creates the account, as admin via the admin group
+	token, err := l.Login(ctx, "alice", "pw")
+	if err != nil {
+		t.Fatalf("Login failed: %v", err)
+	}
+	alice := store.users["alice"]
+	if alice == nil || !alice.IsAdmin || alice.Email != "alice@example.com" {
+		t.Fatalf("Expected alice to be created as admin, got %+v", alice)
+	}
+	if userID, err := l.ValidateToken(ctx, token); err != nil || userID != alice.ID {
+		t.Errorf("Expected a session for alice, got %d (%v)", userID, err)
+	}
+
+	if _, err := l.Login(ctx, "bob", "pw"); err != nil || store.users["bob"].IsAdmin {
+		t.Errorf("Expected bob to sign in without admin rights (%v)", err)
+	}
+
+	// Admin rights follow the directory on later sign-ins, and sessions issued
+	// under the old rights are revoked
+	store.CreateSessionToken(ctx, "carol-old", 5, time.Now().Add(time.Hour), false)
+	carolToken, err := l.Login(ctx, "carol", "pw")
+	if err != nil {
+		t.Fatalf("Login failed: %v", err)
+	}
+	if store.users["carol"].IsAdmin {
+		t.Error("Expected carol's admin rights to be revoked")
+	}
+	if _, ok := store.tokens["carol-old"]; ok {
+		t.Error("Expected carol's old session to be revoked")
+	}
+	if _, ok := store.tokens[carolToken]; !ok {
+		t.Error("Expected carol's new session to survive")
+	}
+
+	// Wrong and empty passwords are rejected and counted
+	if _, err := l.Login(ctx, "alice", "wrong"); err == nil {
+		t.Error("Expected a wrong password to fail")
+	}
+	if _, err := l.Login(ctx, "alice", ""); err == nil {
+		t.Error("Expected an empty password to fail")
+	}
+	if n := len(store.failedLogins["alice"]); n != 2 {
+		t.Errorf("Expected 2 failed logins, got %d", n)
+	}
+
+	// Users outside the allowed group cannot sign in
+	if _, err := l.Login(ctx, "eve", "pw"); err == nil {
+		t.Error("Expected a user outside the allowed group to fail")
+	}
+	if store.users["eve"] != nil {
+		t.Error("Expected no account for a rejected user")
+	}
+
+	// Local users skip the directory
+	if _, err := l.Login(ctx, "admin", "local-secret"); err != nil {
+		t.Errorf("Expected the local admin to sign in: %v", err)
+	}
+
+	// Directory outages are not counted as failed logins
+	dir.err = errors.New("connection refused")
+	if _, err := l.Login(ctx, "bob", "pw"); err == nil {
+		t.Error("Expected login to fail while the directory is down")
+	}
+	if n := len(store.failedLogins["bob"]); n != 0 {
+		t.Errorf("Expected no failed logins for bob, got %d", n)
+	}
+}
+
+func TestInGroup(t *testing.T) {
+	groups := []string{"CN=Domain Admins,CN=Users,DC=corp,DC=local", "developers"}
+
+	tests := []struct {
+		want     string
+		expected bool
+	}{
+		{"Domain Admins", true},
+		{"domain admins", true},
+		{"cn=domain admins,cn=users,dc=corp,dc=local", true},
+		{"developers", true},
+		{"Users", false},
+		{"admins", false},
+	}
+
+	for _, tt := range tests {
+		if got := inGroup(groups, tt.want); got != tt.expected {
+			t.Errorf("inGroup(%q) = %v, expected %v", tt.want, got, tt.expected)
+		}
+	}
+}
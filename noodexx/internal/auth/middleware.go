@@ -2,8 +2,13 @@ package auth
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"net/http"
 	"strings"
+	"time"
 )
 
 // contextKey is a custom type for context keys to avoid collisions
@@ -16,6 +21,17 @@ const UserIDKey contextKey = "user_id"
 // In single-user mode: automatically injects local-default user_id
 // In multi-user mode: validates session token and injects user_id
 func AuthMiddleware(store Store, userMode string) func(http.Handler) http.Handler {
+	return AuthMiddlewareWithProvider(store, userMode, nil)
+}
+
+// AuthMiddlewareWithProvider is AuthMiddleware for a configured provider
+// In multi-user mode a provider that is a RequestAuthenticator is asked first;
+// requests it does not identify fall back to session tokens
+// A provider that is a SessionRefresher slides the expiry of each token it sees
+func AuthMiddlewareWithProvider(store Store, userMode string, provider Provider) func(http.Handler) http.Handler {
+	authenticator, _ := provider.(RequestAuthenticator)
+	refresher, _ := provider.(SessionRefresher)
+
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			// Skip authentication for public endpoints
@@ -28,7 +44,7 @@ func AuthMiddleware(store Store, userMode string) func(http.Handler) http.Handle
 			if userMode == "single" {
 				user, err := store.GetUserByUsername(r.Context(), "local-default")
 				if err != nil {
-					http.Error(w, "System error: local-default user not found", http.StatusInternalServerError)
+					writeAuthError(w, http.StatusInternalServerError, "internal_error", "System error: local-default user not found")
 					return
 				}
 				ctx := context.WithValue(r.Context(), UserIDKey, user.ID)
@@ -36,6 +52,23 @@ func AuthMiddleware(store Store, userMode string) func(http.Handler) http.Handle
 				return
 			}
 
+			// Multi-user mode: let the provider identify the request, e.g. by proxy header
+			if authenticator != nil {
+				userID, err := authenticator.AuthenticateRequest(r)
+				switch {
+				case err == nil:
+					ctx := context.WithValue(r.Context(), UserIDKey, userID)
+					next.ServeHTTP(w, r.WithContext(ctx))
+					return
+				case errors.Is(err, ErrUnknownUser):
+					writeAuthError(w, http.StatusForbidden, "forbidden", "Forbidden: no account for this user")
+					return
+				case !errors.Is(err, ErrNoIdentity):
+					writeAuthError(w, http.StatusInternalServerError, "internal_error", "System error: authentication failed")
+					return
+				}
+			}
+
 			// Multi-user mode: validate token and inject user_id
 			token := extractToken(r)
 			if token == "" {
@@ -44,7 +77,7 @@ func AuthMiddleware(store Store, userMode string) func(http.Handler) http.Handle
 					http.Redirect(w, r, "/login", http.StatusSeeOther)
 					return
 				}
-				http.Error(w, "Unauthorized: authentication required", http.StatusUnauthorized)
+				writeAuthError(w, http.StatusUnauthorized, "unauthorized", "Unauthorized: authentication required")
 				return
 			}
 
@@ -56,7 +89,7 @@ func AuthMiddleware(store Store, userMode string) func(http.Handler) http.Handle
 					http.Redirect(w, r, "/login", http.StatusSeeOther)
 					return
 				}
-				http.Error(w, "Unauthorized: invalid session", http.StatusUnauthorized)
+				writeAuthError(w, http.StatusUnauthorized, "unauthorized", "Unauthorized: invalid session")
 				return
 			}
 			if sessionToken == nil {
@@ -65,17 +98,60 @@ func AuthMiddleware(store Store, userMode string) func(http.Handler) http.Handle
 					http.Redirect(w, r, "/login", http.StatusSeeOther)
 					return
 				}
-				http.Error(w, "Unauthorized: invalid or expired session", http.StatusUnauthorized)
+				writeAuthError(w, http.StatusUnauthorized, "unauthorized", "Unauthorized: invalid or expired session")
 				return
 			}
 
-			// Inject user_id into request context
+			// Slide the session forward; a remember-me cookie is re-sent so the
+			// browser keeps it as long as the server does
+			if refresher != nil {
+				expiresAt, extended, err := refresher.TouchSession(r.Context(), sessionToken)
+				if err == nil && extended && sessionToken.RememberMe && tokenFromCookie(r) {
+					SetSessionCookie(w, r, token, int(time.Until(expiresAt).Seconds()))
+				}
+			}
+
+			// Users given a temporary password may only change it or sign out
+			if !passwordChangeAllowed(r.URL.Path) {
+				if user, err := store.GetUserByID(r.Context(), sessionToken.UserID); err == nil && user != nil && user.MustChangePassword {
+					if !strings.HasPrefix(r.URL.Path, "/api/") {
+						http.Redirect(w, r, "/change-password", http.StatusSeeOther)
+						return
+					}
+					writeAuthError(w, http.StatusForbidden, "password_change_required", "Forbidden: password change required")
+					return
+				}
+			}
+
+			// Inject user_id into request context, marking remember-me sessions
 			ctx := context.WithValue(r.Context(), UserIDKey, sessionToken.UserID)
+			if sessionToken.RememberMe {
+				ctx = WithRememberMe(ctx)
+			}
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
 
+// writeAuthError writes an error in the API's error format (see api.ErrorResponse);
+// the api package builds on auth, so the envelope is repeated here
+func writeAuthError(w http.ResponseWriter, status int, code, message string) {
+	bytes := make([]byte, 8)
+	rand.Read(bytes)
+	requestID := hex.EncodeToString(bytes)
+
+	w.Header().Set("X-Request-ID", requestID)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":    false,
+		"code":       code,
+		"message":    message,
+		"error":      message,
+		"request_id": requestID,
+	})
+}
+
 // extractToken extracts the session token from the request
 // First checks Authorization header with "Bearer " prefix
 // Falls back to session_token cookie if header not present
@@ -96,13 +172,40 @@ func extractToken(r *http.Request) string {
 	return ""
 }
 
+// tokenFromCookie reports whether the request's token came from the
+// session_token cookie rather than an Authorization header
+func tokenFromCookie(r *http.Request) bool {
+	return !strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ")
+}
+
+// SetSessionCookie sets the session_token cookie
+// A maxAge of 0 makes a browser-session cookie that is dropped when the browser closes
+func SetSessionCookie(w http.ResponseWriter, r *http.Request, token string, maxAge int) {
+	cookie := &http.Cookie{
+		Name:     "session_token",
+		Value:    token,
+		Path:     "/",
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+		MaxAge:   maxAge,
+	}
+
+	// Set Secure flag in production (when not localhost)
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		cookie.Secure = true
+	}
+
+	http.SetCookie(w, cookie)
+}
+
 // isPublicEndpoint checks if a path should bypass authentication
-// Public endpoints: /login, /register, /static/, /api/login, /api/register
+// Public endpoints: /login, /register, /static/, /share/, /api/login, /api/register
 func isPublicEndpoint(path string) bool {
 	publicPaths := []string{
 		"/login",
 		"/register",
 		"/static/",
+		"/share/",
 		"/api/login",
 		"/api/register",
 	}
@@ -115,6 +218,15 @@ func isPublicEndpoint(path string) bool {
 	return false
 }
 
+// passwordChangeAllowed checks if a path stays open to users who must change their password
+func passwordChangeAllowed(path string) bool {
+	switch path {
+	case "/change-password", "/api/change-password", "/api/logout":
+		return true
+	}
+	return false
+}
+
 // GetUserID extracts the user_id from request context
 // Returns (userID int64, error)
 // Returns error if user_id not found in context
@@ -2,6 +2,7 @@ package auth
 
 import (
 	"context"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -95,6 +96,51 @@ func TestAuthMiddleware_MultiUserMode_ValidToken(t *testing.T) {
 	}
 }
 
+// TestAuthMiddleware_SlidingSession tests that the middleware extends sessions
+// and re-sends remember-me cookies
+func TestAuthMiddleware_SlidingSession(t *testing.T) {
+	store := NewMockStore()
+	provider := NewUserpassAuth(store, 7, 5, 15)
+	provider.SetSessionLimits(30, 90)
+
+	store.tokens["remember"] = &SessionToken{Token: "remember", UserID: 2, CreatedAt: time.Now(), ExpiresAt: time.Now().Add(time.Hour), RememberMe: true}
+	store.tokens["plain"] = &SessionToken{Token: "plain", UserID: 2, CreatedAt: time.Now(), ExpiresAt: time.Now().Add(time.Hour)}
+
+	handler := AuthMiddlewareWithProvider(store, "multi", provider)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	// Remember-me cookie is extended and re-sent
+	req := httptest.NewRequest("GET", "/api/library", nil)
+	req.AddCookie(&http.Cookie{Name: "session_token", Value: "remember"})
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	if until := time.Until(store.tokens["remember"].ExpiresAt.(time.Time)); until < 29*24*time.Hour {
+		t.Errorf("Remember-me session should be extended, expires in %v", until)
+	}
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Value != "remember" || cookies[0].MaxAge < 29*24*60*60 {
+		t.Errorf("Expected a re-sent remember-me cookie, got %v", cookies)
+	}
+
+	// A browser-session cookie is extended on the server but left as it is
+	req = httptest.NewRequest("GET", "/api/library", nil)
+	req.AddCookie(&http.Cookie{Name: "session_token", Value: "plain"})
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	if until := time.Until(store.tokens["plain"].ExpiresAt.(time.Time)); until < 6*24*time.Hour {
+		t.Errorf("Session should be extended, expires in %v", until)
+	}
+	if len(w.Result().Cookies()) != 0 {
+		t.Error("Browser-session cookie should not be re-sent")
+	}
+}
+
 // TestAuthMiddleware_MultiUserMode_InvalidToken tests middleware with invalid token
 func TestAuthMiddleware_MultiUserMode_InvalidToken(t *testing.T) {
 	store := NewMockStore()
@@ -117,6 +163,14 @@ func TestAuthMiddleware_MultiUserMode_InvalidToken(t *testing.T) {
 	if w.Code != http.StatusUnauthorized {
 		t.Errorf("Expected status 401, got %d", w.Code)
 	}
+
+	var body map[string]interface{}
+	if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+		t.Fatalf("Expected a JSON error body: %v", err)
+	}
+	if body["code"] != "unauthorized" || body["request_id"] != w.Header().Get("X-Request-ID") {
+		t.Errorf("Unexpected error body %v", body)
+	}
 }
 
 // TestAuthMiddleware_MultiUserMode_NoToken tests middleware without token
@@ -238,6 +292,7 @@ func TestIsPublicEndpoint(t *testing.T) {
 		{"/register", true},
 		{"/static/css/style.css", true},
 		{"/static/js/app.js", true},
+		{"/share/abc123", true},
 		{"/api/library", false},
 		{"/api/search", false},
 		{"/dashboard", false},
@@ -367,3 +422,49 @@ func TestAuthMiddleware_ExpiredToken(t *testing.T) {
 		t.Errorf("Expected status 401 for expired token, got %d", w.Code)
 	}
 }
+
+// TestAuthMiddleware_MustChangePassword tests that users with a temporary password
+// can only reach the password change page and API
+func TestAuthMiddleware_MustChangePassword(t *testing.T) {
+	store := NewMockStore()
+	store.users["reset-user"] = &User{ID: 5, Username: "reset-user", MustChangePassword: true}
+	store.tokens["reset-token"] = &SessionToken{Token: "reset-token", UserID: 5, ExpiresAt: time.Now().Add(time.Hour)}
+
+	handler := AuthMiddleware(store, "multi")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		path       string
+		wantStatus int
+	}{
+		{"/", http.StatusSeeOther},
+		{"/api/library", http.StatusForbidden},
+		{"/change-password", http.StatusOK},
+		{"/api/change-password", http.StatusOK},
+		{"/api/logout", http.StatusOK},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", tt.path, nil)
+		req.AddCookie(&http.Cookie{Name: "session_token", Value: "reset-token"})
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+
+		if w.Code != tt.wantStatus {
+			t.Errorf("%s: expected status %d, got %d", tt.path, tt.wantStatus, w.Code)
+		}
+		if tt.wantStatus == http.StatusSeeOther && w.Header().Get("Location") != "/change-password" {
+			t.Errorf("%s: expected redirect to /change-password, got %q", tt.path, w.Header().Get("Location"))
+		}
+	}
+
+	// Once the password is changed the user is let through
+	store.users["reset-user"].MustChangePassword = false
+	req := httptest.NewRequest("GET", "/api/library", nil)
+	req.AddCookie(&http.Cookie{Name: "session_token", Value: "reset-token"})
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200 after the password change, got %d", w.Code)
+	}
+}
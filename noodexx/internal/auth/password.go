@@ -18,3 +18,13 @@ func checkPasswordHash(password, hash string) bool {
 	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
 	return err == nil
 }
+
+// HashPassword hashes a password using bcrypt, for callers outside the auth package
+func HashPassword(password string) (string, error) {
+	return hashPassword(password)
+}
+
+// CheckPasswordHash verifies a password against a bcrypt hash
+func CheckPasswordHash(password, hash string) bool {
+	return checkPasswordHash(password, hash)
+}
@@ -4,11 +4,18 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 )
 
 // Common errors
 var (
 	ErrUserIDNotFound = errors.New("user_id not found in context")
+	ErrNoIdentity     = errors.New("request carries no identity")
+	ErrUnknownUser    = errors.New("no account for authenticated user")
 )
 
 // Provider defines the authentication interface
@@ -26,23 +33,60 @@ type Provider interface {
 	RefreshToken(ctx context.Context, token string) (newToken string, err error)
 }
 
+// RequestAuthenticator is implemented by providers that identify the user from
+// the request itself, such as the reverse-proxy header provider
+// AuthenticateRequest returns ErrNoIdentity when the request does not identify
+// anyone, so the middleware can fall back to session tokens
+type RequestAuthenticator interface {
+	AuthenticateRequest(r *http.Request) (userID int64, err error)
+}
+
 // Store defines the interface for database operations needed by auth providers
 type Store interface {
 	// User operations
 	GetUserByUsername(ctx context.Context, username string) (*User, error)
+	GetUserByID(ctx context.Context, userID int64) (*User, error)
 	UpdateLastLogin(ctx context.Context, userID int64) error
 
 	// Session token operations
-	CreateSessionToken(ctx context.Context, token string, userID int64, expiresAt interface{}) error
+	CreateSessionToken(ctx context.Context, token string, userID int64, expiresAt interface{}, rememberMe bool) error
 	GetSessionToken(ctx context.Context, token string) (*SessionToken, error)
+	ExtendSessionToken(ctx context.Context, token string, expiresAt interface{}) error
 	DeleteSessionToken(ctx context.Context, token string) error
+	DeleteUserSessionTokens(ctx context.Context, userID int64, keepToken string) error
 
 	// Account lockout operations
-	IsAccountLocked(ctx context.Context, username string) (bool, interface{})
+	IsAccountLocked(ctx context.Context, username string, threshold int, window time.Duration) (bool, interface{})
 	RecordFailedLogin(ctx context.Context, username string) error
 	ClearFailedLogins(ctx context.Context, username string) error
 }
 
+// UserCreator is implemented by stores that can create accounts for users
+// authenticated by an external provider on their first visit
+type UserCreator interface {
+	CreateUser(ctx context.Context, username, password, email string, isAdmin, mustChangePassword bool) (int64, error)
+}
+
+// AdminUpdater is implemented by stores that can change a user's admin rights,
+// so directory providers can keep them in step with group membership
+type AdminUpdater interface {
+	SetUserAdmin(ctx context.Context, userID int64, isAdmin bool) error
+}
+
+// createExternalUser creates the account of a user authenticated elsewhere
+// The password is random and never shown: these users sign in through their provider
+func createExternalUser(ctx context.Context, store Store, username, email string, isAdmin bool) (int64, error) {
+	creator, ok := store.(UserCreator)
+	if !ok {
+		return 0, fmt.Errorf("store cannot create users")
+	}
+	password, err := generateSecureToken(32)
+	if err != nil {
+		return 0, err
+	}
+	return creator.CreateUser(ctx, username, password, strings.TrimSpace(email), isAdmin, false)
+}
+
 // User represents a user account
 type User struct {
 	ID                 int64
@@ -55,21 +99,130 @@ type User struct {
 
 // SessionToken represents a session token
 type SessionToken struct {
-	Token     string
-	UserID    int64
-	ExpiresAt interface{}
+	Token      string
+	UserID     int64
+	CreatedAt  time.Time
+	ExpiresAt  interface{}
+	RememberMe bool
 }
 
-// GetProvider returns the configured auth provider
-func GetProvider(providerType string, store Store, sessionExpiryDays, lockoutThreshold, lockoutDurationMinutes int) (Provider, error) {
-	switch providerType {
-	case "userpass", "":
-		return NewUserpassAuth(store, sessionExpiryDays, lockoutThreshold, lockoutDurationMinutes), nil
-	case "mfa":
-		return &MFAAuth{}, nil
-	case "sso":
-		return &SSOAuth{}, nil
-	default:
+// SessionRefresher is implemented by providers whose sessions slide: each use of
+// a token may push its expiry forward, up to the session's maximum lifetime
+type SessionRefresher interface {
+	TouchSession(ctx context.Context, token *SessionToken) (expiresAt time.Time, extended bool, err error)
+}
+
+// rememberMeKey marks a login context that asked for a long-lived session
+const rememberMeKey contextKey = "remember_me"
+
+// WithRememberMe returns a context whose Login issues a "remember me" session
+// The auth middleware also sets it on requests made with such a session
+func WithRememberMe(ctx context.Context) context.Context {
+	return context.WithValue(ctx, rememberMeKey, true)
+}
+
+// RememberMe reports whether ctx asks for a "remember me" session
+func RememberMe(ctx context.Context) bool {
+	remember, _ := ctx.Value(rememberMeKey).(bool)
+	return remember
+}
+
+// ProviderOptions are passed to a provider factory
+type ProviderOptions struct {
+	Store                  Store
+	SessionExpiryDays      int
+	LockoutThreshold       int
+	LockoutDurationMinutes int
+	RememberMeDays         int               // Idle timeout of "remember me" sessions
+	MaxSessionDays         int               // Longest a session can slide; 0 for fixed expiry
+	Settings               map[string]string // Provider-specific settings from auth.settings
+}
+
+// ProviderFactory builds a provider from its options
+type ProviderFactory func(opts ProviderOptions) (Provider, error)
+
+var (
+	providersMu sync.RWMutex
+	providers   = map[string]ProviderFactory{
+		"userpass": func(opts ProviderOptions) (Provider, error) {
+			u := NewUserpassAuth(opts.Store, opts.SessionExpiryDays, opts.LockoutThreshold, opts.LockoutDurationMinutes)
+			u.SetSessionLimits(opts.RememberMeDays, opts.MaxSessionDays)
+			return u, nil
+		},
+		"header": func(opts ProviderOptions) (Provider, error) {
+			return NewHeaderAuth(opts.Store, opts.Settings)
+		},
+		"ldap": func(opts ProviderOptions) (Provider, error) {
+			return NewLDAPAuth(opts)
+		},
+		"mfa": func(opts ProviderOptions) (Provider, error) { return &MFAAuth{}, nil },
+		"sso": func(opts ProviderOptions) (Provider, error) { return &SSOAuth{}, nil },
+	}
+)
+
+// Register makes a provider available under name for the auth.provider setting
+// It is meant to be called from an init function and panics if name is taken
+func Register(name string, factory ProviderFactory) {
+	providersMu.Lock()
+	defer providersMu.Unlock()
+
+	if factory == nil {
+		panic("auth: Register factory is nil")
+	}
+	if _, dup := providers[name]; dup {
+		panic(fmt.Sprintf("auth: Register called twice for provider %q", name))
+	}
+	providers[name] = factory
+}
+
+// Providers returns the names of the registered providers, sorted
+func Providers() []string {
+	providersMu.RLock()
+	defer providersMu.RUnlock()
+
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// IsRegistered reports whether a provider is registered under name
+func IsRegistered(name string) bool {
+	providersMu.RLock()
+	defer providersMu.RUnlock()
+
+	_, ok := providers[name]
+	return ok
+}
+
+// NewProvider builds the provider registered under providerType ("" means userpass)
+func NewProvider(providerType string, opts ProviderOptions) (Provider, error) {
+	if providerType == "" {
+		providerType = "userpass"
+	}
+
+	providersMu.RLock()
+	factory, ok := providers[providerType]
+	providersMu.RUnlock()
+	if !ok {
 		return nil, fmt.Errorf("unsupported auth provider: %s", providerType)
 	}
+
+	provider, err := factory(opts)
+	if err != nil {
+		return nil, fmt.Errorf("auth provider %s: %w", providerType, err)
+	}
+	return provider, nil
+}
+
+// GetProvider returns the configured auth provider without provider-specific settings
+func GetProvider(providerType string, store Store, sessionExpiryDays, lockoutThreshold, lockoutDurationMinutes int) (Provider, error) {
+	return NewProvider(providerType, ProviderOptions{
+		Store:                  store,
+		SessionExpiryDays:      sessionExpiryDays,
+		LockoutThreshold:       lockoutThreshold,
+		LockoutDurationMinutes: lockoutDurationMinutes,
+	})
 }
@@ -6,10 +6,16 @@ import (
 	"time"
 )
 
+// sessionTouchInterval is how far a sliding session's expiry must move before
+// it is written back, so a busy session does not update its row on every request
+const sessionTouchInterval = time.Hour
+
 // UserpassAuth implements username/password authentication
 type UserpassAuth struct {
 	store            Store
 	sessionExpiry    time.Duration
+	rememberMeExpiry time.Duration
+	maxLifetime      time.Duration
 	lockoutThreshold int
 	lockoutDuration  time.Duration
 }
@@ -24,20 +30,28 @@ func NewUserpassAuth(store Store, sessionExpiryDays, lockoutThreshold, lockoutDu
 	}
 }
 
+// SetSessionLimits enables "remember me" sessions, which stay valid for
+// rememberMeDays of inactivity, and sliding expiration: sessions in use are
+// extended until they are maxSessionDays old
+// A maxSessionDays of 0 keeps the fixed expiry set at login
+func (u *UserpassAuth) SetSessionLimits(rememberMeDays, maxSessionDays int) {
+	u.rememberMeExpiry = time.Duration(rememberMeDays) * 24 * time.Hour
+	u.maxLifetime = time.Duration(maxSessionDays) * 24 * time.Hour
+}
+
+// sessionWindow returns how long a session may sit idle before it expires
+func (u *UserpassAuth) sessionWindow(rememberMe bool) time.Duration {
+	if rememberMe && u.rememberMeExpiry > 0 {
+		return u.rememberMeExpiry
+	}
+	return u.sessionExpiry
+}
+
 // Login authenticates credentials and returns a session token
 func (u *UserpassAuth) Login(ctx context.Context, username, password string) (string, error) {
 	// Check if account is locked
-	locked, until := u.store.IsAccountLocked(ctx, username)
-	if locked {
-		// Convert until to time.Time if it's not already
-		var untilTime time.Time
-		switch v := until.(type) {
-		case time.Time:
-			untilTime = v
-		default:
-			untilTime = time.Now().Add(u.lockoutDuration)
-		}
-		return "", fmt.Errorf("account locked until %s", untilTime.Format(time.RFC3339))
+	if err := u.checkLockout(ctx, username); err != nil {
+		return "", err
 	}
 
 	// Get user by username
@@ -55,20 +69,39 @@ func (u *UserpassAuth) Login(ctx context.Context, username, password string) (st
 		return "", fmt.Errorf("invalid credentials")
 	}
 
-	// Generate secure session token (32 bytes = 256 bits of entropy)
-	token, err := generateSecureToken(32)
-	if err != nil {
-		return "", fmt.Errorf("failed to generate token: %w", err)
+	return u.startSession(ctx, user.ID, username)
+}
+
+// checkLockout returns an "account locked" error while username is locked out,
+// which happens after lockoutThreshold failed attempts within lockoutDuration
+func (u *UserpassAuth) checkLockout(ctx context.Context, username string) error {
+	locked, until := u.store.IsAccountLocked(ctx, username, u.lockoutThreshold, u.lockoutDuration)
+	if !locked {
+		return nil
 	}
 
-	// Store session token
-	expiresAt := time.Now().Add(u.sessionExpiry)
-	if err := u.store.CreateSessionToken(ctx, token, user.ID, expiresAt); err != nil {
-		return "", fmt.Errorf("failed to create session: %w", err)
+	// Convert until to time.Time if it's not already
+	var untilTime time.Time
+	switch v := until.(type) {
+	case time.Time:
+		untilTime = v
+	default:
+		untilTime = time.Now().Add(u.lockoutDuration)
+	}
+	return fmt.Errorf("account locked until %s", untilTime.Format(time.RFC3339))
+}
+
+// startSession issues a session token to a user whose credentials were verified
+// A context from WithRememberMe gets a longer-lived "remember me" session
+func (u *UserpassAuth) startSession(ctx context.Context, userID int64, username string) (string, error) {
+	rememberMe := RememberMe(ctx)
+	token, err := u.issueToken(ctx, userID, time.Now().Add(u.sessionWindow(rememberMe)), rememberMe)
+	if err != nil {
+		return "", err
 	}
 
 	// Update last login timestamp
-	u.store.UpdateLastLogin(ctx, user.ID)
+	u.store.UpdateLastLogin(ctx, userID)
 
 	// Clear failed login attempts
 	u.store.ClearFailedLogins(ctx, username)
@@ -76,6 +109,21 @@ func (u *UserpassAuth) Login(ctx context.Context, username, password string) (st
 	return token, nil
 }
 
+// issueToken generates and stores a new session token
+func (u *UserpassAuth) issueToken(ctx context.Context, userID int64, expiresAt time.Time, rememberMe bool) (string, error) {
+	// Generate secure session token (32 bytes = 256 bits of entropy)
+	token, err := generateSecureToken(32)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+
+	// Store session token
+	if err := u.store.CreateSessionToken(ctx, token, userID, expiresAt, rememberMe); err != nil {
+		return "", fmt.Errorf("failed to create session: %w", err)
+	}
+	return token, nil
+}
+
 // Logout invalidates a session token
 func (u *UserpassAuth) Logout(ctx context.Context, token string) error {
 	return u.store.DeleteSessionToken(ctx, token)
@@ -87,6 +135,9 @@ func (u *UserpassAuth) ValidateToken(ctx context.Context, token string) (int64,
 	if err != nil {
 		return 0, fmt.Errorf("invalid token: %w", err)
 	}
+	if sessionToken == nil {
+		return 0, fmt.Errorf("invalid or expired token")
+	}
 
 	// Check if token is expired
 	var expiresAt time.Time
@@ -106,7 +157,59 @@ func (u *UserpassAuth) ValidateToken(ctx context.Context, token string) (int64,
 	return sessionToken.UserID, nil
 }
 
-// RefreshToken extends a session token's expiration (stub for Phase 5)
+// TouchSession slides a session's expiry forward after it is used, never past
+// the maximum lifetime counted from when the session was created
+// extended is false when the expiry was left alone
+func (u *UserpassAuth) TouchSession(ctx context.Context, token *SessionToken) (time.Time, bool, error) {
+	expiresAt, ok := token.ExpiresAt.(time.Time)
+	if !ok {
+		return time.Time{}, false, fmt.Errorf("invalid expiration time format")
+	}
+	if u.maxLifetime <= 0 {
+		return expiresAt, false, nil
+	}
+
+	newExpiry := time.Now().Add(u.sessionWindow(token.RememberMe))
+	if !token.CreatedAt.IsZero() {
+		if limit := token.CreatedAt.Add(u.maxLifetime); newExpiry.After(limit) {
+			newExpiry = limit
+		}
+	}
+	if newExpiry.Sub(expiresAt) < sessionTouchInterval {
+		return expiresAt, false, nil
+	}
+
+	if err := u.store.ExtendSessionToken(ctx, token.Token, newExpiry); err != nil {
+		return expiresAt, false, fmt.Errorf("failed to extend session: %w", err)
+	}
+	token.ExpiresAt = newExpiry
+	return newExpiry, true, nil
+}
+
+// RefreshToken replaces a session token with a new one that keeps its expiry,
+// so a token seen before a privilege change stops working
 func (u *UserpassAuth) RefreshToken(ctx context.Context, token string) (string, error) {
-	return "", fmt.Errorf("token refresh not implemented in Phase 4")
+	sessionToken, err := u.store.GetSessionToken(ctx, token)
+	if err != nil {
+		return "", fmt.Errorf("invalid token: %w", err)
+	}
+	if sessionToken == nil {
+		return "", fmt.Errorf("invalid or expired token")
+	}
+	expiresAt, ok := sessionToken.ExpiresAt.(time.Time)
+	if !ok {
+		return "", fmt.Errorf("invalid expiration time format")
+	}
+	if time.Now().After(expiresAt) {
+		return "", fmt.Errorf("token expired")
+	}
+
+	newToken, err := u.issueToken(ctx, sessionToken.UserID, expiresAt, sessionToken.RememberMe)
+	if err != nil {
+		return "", err
+	}
+	if err := u.store.DeleteSessionToken(ctx, token); err != nil {
+		return "", fmt.Errorf("failed to revoke old session: %w", err)
+	}
+	return newToken, nil
 }
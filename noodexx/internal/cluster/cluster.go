@@ -0,0 +1,375 @@
+// Package cluster coordinates Noodexx instances that share one database, so
+// several can run behind a load balancer. Everything goes through the database:
+// locks with an expiry keep background work on one instance at a time, events
+// published by one instance reach the others, and a task queue hands work to
+// whichever instance claims it. An instance that stops without cleaning up
+// loses its locks and task claims once they expire.
+package cluster
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"noodexx/internal/logging"
+	"os"
+	"sync"
+	"time"
+)
+
+// How long published events and finished tasks are kept before Prune deletes them
+const (
+	eventRetention = time.Hour
+	taskRetention  = 24 * time.Hour
+)
+
+// eventBatch is how many events are read per poll
+const eventBatch = 100
+
+// Event is something one instance published for the others
+type Event struct {
+	ID      int64
+	Origin  string // ID of the publishing node
+	Kind    string
+	Payload string
+}
+
+// Task is a queued unit of work
+type Task struct {
+	ID        int64
+	Kind      string
+	Payload   string
+	Status    string // "pending", "running", "done" or "failed"
+	ClaimedBy string
+	Attempts  int
+	Result    string
+	Error     string
+}
+
+// Store persists locks, events and tasks in the shared database
+type Store interface {
+	AcquireLock(ctx context.Context, name, owner string, ttl time.Duration) (bool, error)
+	ReleaseLock(ctx context.Context, name, owner string) error
+	PublishEvent(ctx context.Context, origin, kind, payload string) (int64, error)
+	GetEventsAfter(ctx context.Context, afterID int64, limit int) ([]Event, error)
+	LatestEventID(ctx context.Context) (int64, error)
+	EnqueueTask(ctx context.Context, kind, payload string) (int64, error)
+	ClaimTask(ctx context.Context, owner string, kinds []string, lease time.Duration) (*Task, error)
+	RenewTask(ctx context.Context, id int64, owner string, lease time.Duration) error
+	FinishTask(ctx context.Context, id int64, owner, result, errMsg string) error
+	GetTask(ctx context.Context, id int64) (*Task, error)
+	Prune(ctx context.Context, eventsBefore, tasksBefore time.Time) (int64, error)
+}
+
+// Options configures a Node
+type Options struct {
+	NodeID       string        // Unique name of this instance; hostname and process ID when empty
+	LockTTL      time.Duration // How long locks and task claims outlive a node that stops renewing them
+	PollInterval time.Duration // How often to check for events, tasks and free locks
+	MaxAttempts  int           // Claims of a task before it is given up as failed
+}
+
+// Node is this instance's member of the cluster
+type Node struct {
+	store  Store
+	opts   Options
+	logger *logging.Logger
+
+	mu          sync.Mutex
+	subscribers map[string][]func(payload string)
+}
+
+// New creates a node; zero options take their defaults
+func New(store Store, opts Options, logger *logging.Logger) *Node {
+	if opts.NodeID == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			hostname = "noodexx"
+		}
+		opts.NodeID = fmt.Sprintf("%s-%d", hostname, os.Getpid())
+	}
+	if opts.LockTTL <= 0 {
+		opts.LockTTL = 30 * time.Second
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = time.Second
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = 3
+	}
+	return &Node{
+		store:       store,
+		opts:        opts,
+		logger:      logger,
+		subscribers: make(map[string][]func(payload string)),
+	}
+}
+
+// ID returns the node's name
+func (n *Node) ID() string {
+	return n.opts.NodeID
+}
+
+// TryLock takes the named lock if no other node holds it. The lock is renewed
+// until unlock is called; the returned context is cancelled if the lock is lost,
+// such as when the database cannot be reached for longer than the lock TTL.
+// Failures to reach the database are logged and reported as not acquired
+func (n *Node) TryLock(ctx context.Context, name string) (context.Context, func(), bool) {
+	ok, err := n.store.AcquireLock(ctx, name, n.opts.NodeID, n.opts.LockTTL)
+	if err != nil {
+		n.logger.Warn("Failed to acquire lock %s: %v", name, err)
+		return nil, nil, false
+	}
+	if !ok {
+		return nil, nil, false
+	}
+
+	lockCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		n.renewLock(lockCtx, name, cancel, done)
+	}()
+
+	var once sync.Once
+	unlock := func() {
+		once.Do(func() {
+			close(done)
+			wg.Wait()
+			cancel()
+			if err := n.store.ReleaseLock(context.Background(), name, n.opts.NodeID); err != nil {
+				n.logger.Warn("Failed to release lock %s: %v", name, err)
+			}
+		})
+	}
+	return lockCtx, unlock, true
+}
+
+// renewLock extends a held lock every third of its TTL until done is closed,
+// cancelling the lock's context once it can no longer be sure it holds it
+func (n *Node) renewLock(ctx context.Context, name string, cancel context.CancelFunc, done <-chan struct{}) {
+	ticker := time.NewTicker(n.opts.LockTTL / 3)
+	defer ticker.Stop()
+	held := time.Now()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		ok, err := n.store.AcquireLock(context.Background(), name, n.opts.NodeID, n.opts.LockTTL)
+		switch {
+		case err != nil && time.Since(held) < n.opts.LockTTL:
+			n.logger.Warn("Failed to renew lock %s, retrying: %v", name, err)
+		case err != nil || !ok:
+			n.logger.Warn("Lost lock %s", name)
+			cancel()
+			return
+		default:
+			held = time.Now()
+		}
+	}
+}
+
+// Lead runs fn whenever this node holds the named lock, until ctx is cancelled.
+// fn should run until its context is cancelled, which happens when the lock is
+// lost; the node then tries to take the lock again
+func (n *Node) Lead(ctx context.Context, name string, fn func(ctx context.Context)) {
+	for {
+		if lockCtx, unlock, ok := n.TryLock(ctx, name); ok {
+			n.logger.Info("Node %s is now running %s", n.opts.NodeID, name)
+			fn(lockCtx)
+			unlock()
+			n.logger.Info("Node %s stopped running %s", n.opts.NodeID, name)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(n.opts.PollInterval):
+		}
+	}
+}
+
+// Publish sends an event to the other nodes
+func (n *Node) Publish(ctx context.Context, kind, payload string) error {
+	_, err := n.store.PublishEvent(ctx, n.opts.NodeID, kind, payload)
+	return err
+}
+
+// Subscribe calls fn with the payload of each event of kind published by
+// another node; it must be called before Run
+func (n *Node) Subscribe(kind string, fn func(payload string)) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.subscribers[kind] = append(n.subscribers[kind], fn)
+}
+
+// Run delivers events published by other nodes after it starts, until ctx is
+// cancelled
+func (n *Node) Run(ctx context.Context) {
+	lastID, err := n.store.LatestEventID(ctx)
+	if err != nil {
+		n.logger.Warn("Failed to read the latest cluster event, delivering all kept events: %v", err)
+	}
+	n.logger.Info("Node %s joined the cluster", n.opts.NodeID)
+
+	ticker := time.NewTicker(n.opts.PollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		// Read until caught up so a burst of events is not spread over many polls
+		for {
+			events, err := n.store.GetEventsAfter(ctx, lastID, eventBatch)
+			if err != nil {
+				if ctx.Err() == nil {
+					n.logger.Warn("Failed to read cluster events: %v", err)
+				}
+				break
+			}
+			for _, event := range events {
+				lastID = event.ID
+				if event.Origin != n.opts.NodeID {
+					n.deliver(event)
+				}
+			}
+			if len(events) < eventBatch {
+				break
+			}
+		}
+	}
+}
+
+// deliver passes an event to its subscribers
+func (n *Node) deliver(event Event) {
+	n.mu.Lock()
+	subscribers := n.subscribers[event.Kind]
+	n.mu.Unlock()
+	for _, fn := range subscribers {
+		fn(event.Payload)
+	}
+}
+
+// Submit queues a task for whichever node works on its kind and returns its ID
+func (n *Node) Submit(ctx context.Context, kind, payload string) (int64, error) {
+	return n.store.EnqueueTask(ctx, kind, payload)
+}
+
+// Wait blocks until a submitted task finishes, returning its result, or the
+// error it failed with
+func (n *Node) Wait(ctx context.Context, id int64) (string, error) {
+	ticker := time.NewTicker(n.opts.PollInterval / 4)
+	defer ticker.Stop()
+	for {
+		task, err := n.store.GetTask(ctx, id)
+		if err != nil {
+			return "", err
+		}
+		if task == nil {
+			return "", fmt.Errorf("task %d not found", id)
+		}
+		switch task.Status {
+		case "done":
+			return task.Result, nil
+		case "failed":
+			return "", errors.New(task.Error)
+		}
+
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Work claims and runs tasks of kind one at a time until ctx is cancelled.
+// A task that returns an error is failed, not retried; tasks are only claimed
+// again when the node working on them stops renewing the claim
+func (n *Node) Work(ctx context.Context, kind string, fn func(ctx context.Context, payload string) (string, error)) {
+	kinds := []string{kind}
+	for {
+		task, err := n.store.ClaimTask(ctx, n.opts.NodeID, kinds, n.opts.LockTTL)
+		if err != nil && ctx.Err() == nil {
+			n.logger.Warn("Failed to claim %s task: %v", kind, err)
+		}
+		if task != nil {
+			n.runTask(ctx, task, fn)
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(n.opts.PollInterval):
+		}
+	}
+}
+
+// runTask runs a claimed task, renewing the claim while it runs, and records
+// the outcome
+func (n *Node) runTask(ctx context.Context, task *Task, fn func(ctx context.Context, payload string) (string, error)) {
+	var result, errMsg string
+	if task.Attempts > n.opts.MaxAttempts {
+		errMsg = fmt.Sprintf("abandoned after %d attempts", n.opts.MaxAttempts)
+	} else {
+		done := make(chan struct{})
+		go func() {
+			ticker := time.NewTicker(n.opts.LockTTL / 3)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-done:
+					return
+				case <-ticker.C:
+					if err := n.store.RenewTask(context.Background(), task.ID, n.opts.NodeID, n.opts.LockTTL); err != nil {
+						n.logger.Warn("Failed to renew claim on task %d: %v", task.ID, err)
+					}
+				}
+			}
+		}()
+
+		var err error
+		result, err = safeRun(ctx, task.Payload, fn)
+		close(done)
+		if err != nil {
+			errMsg = err.Error()
+		}
+	}
+
+	if err := n.store.FinishTask(context.Background(), task.ID, n.opts.NodeID, result, errMsg); err != nil {
+		n.logger.Warn("Failed to record the outcome of task %d: %v", task.ID, err)
+	}
+}
+
+// safeRun turns a panicking task into a failed one
+func safeRun(ctx context.Context, payload string, fn func(ctx context.Context, payload string) (string, error)) (result string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return fn(ctx, payload)
+}
+
+// Prune deletes events older than an hour and tasks finished more than a day ago
+func (n *Node) Prune(ctx context.Context) error {
+	now := time.Now()
+	removed, err := n.store.Prune(ctx, now.Add(-eventRetention), now.Add(-taskRetention))
+	if err != nil {
+		return err
+	}
+	if removed > 0 {
+		n.logger.Debug("Pruned %d cluster events and tasks", removed)
+	}
+	return nil
+}
@@ -0,0 +1,318 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+	"io"
+	"noodexx/internal/logging"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memStore is a shared in-memory database for several nodes
+type memStore struct {
+	mu     sync.Mutex
+	locks  map[string]memLock
+	events []Event
+	tasks  []*Task
+	leases map[int64]time.Time
+}
+
+type memLock struct {
+	owner   string
+	expires time.Time
+}
+
+func newMemStore() *memStore {
+	return &memStore{locks: map[string]memLock{}, leases: map[int64]time.Time{}}
+}
+
+func (m *memStore) AcquireLock(ctx context.Context, name, owner string, ttl time.Duration) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if lock, ok := m.locks[name]; ok && lock.owner != owner && time.Now().Before(lock.expires) {
+		return false, nil
+	}
+	m.locks[name] = memLock{owner: owner, expires: time.Now().Add(ttl)}
+	return true, nil
+}
+
+func (m *memStore) ReleaseLock(ctx context.Context, name, owner string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.locks[name].owner == owner {
+		delete(m.locks, name)
+	}
+	return nil
+}
+
+// steal hands a lock to another owner, as if this node had stalled past its TTL
+func (m *memStore) steal(name, owner string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.locks[name] = memLock{owner: owner, expires: time.Now().Add(time.Hour)}
+}
+
+func (m *memStore) PublishEvent(ctx context.Context, origin, kind, payload string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	id := int64(len(m.events) + 1)
+	m.events = append(m.events, Event{ID: id, Origin: origin, Kind: kind, Payload: payload})
+	return id, nil
+}
+
+func (m *memStore) GetEventsAfter(ctx context.Context, afterID int64, limit int) ([]Event, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var events []Event
+	for _, event := range m.events {
+		if event.ID > afterID && len(events) < limit {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+func (m *memStore) LatestEventID(ctx context.Context) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return int64(len(m.events)), nil
+}
+
+func (m *memStore) EnqueueTask(ctx context.Context, kind, payload string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	id := int64(len(m.tasks) + 1)
+	m.tasks = append(m.tasks, &Task{ID: id, Kind: kind, Payload: payload, Status: "pending"})
+	return id, nil
+}
+
+func (m *memStore) ClaimTask(ctx context.Context, owner string, kinds []string, lease time.Duration) (*Task, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, task := range m.tasks {
+		expired := task.Status == "running" && time.Now().After(m.leases[task.ID])
+		if task.Kind == kinds[0] && (task.Status == "pending" || expired) {
+			task.Status = "running"
+			task.ClaimedBy = owner
+			task.Attempts++
+			m.leases[task.ID] = time.Now().Add(lease)
+			claimed := *task
+			return &claimed, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *memStore) RenewTask(ctx context.Context, id int64, owner string, lease time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.leases[id] = time.Now().Add(lease)
+	return nil
+}
+
+func (m *memStore) FinishTask(ctx context.Context, id int64, owner, result, errMsg string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	task := m.tasks[id-1]
+	task.Status, task.Result, task.Error = "done", result, errMsg
+	if errMsg != "" {
+		task.Status = "failed"
+	}
+	return nil
+}
+
+func (m *memStore) GetTask(ctx context.Context, id int64) (*Task, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if id < 1 || int(id) > len(m.tasks) {
+		return nil, nil
+	}
+	task := *m.tasks[id-1]
+	return &task, nil
+}
+
+func (m *memStore) Prune(ctx context.Context, eventsBefore, tasksBefore time.Time) (int64, error) {
+	return 0, nil
+}
+
+func newTestNode(store Store, id string) *Node {
+	return New(store, Options{
+		NodeID:       id,
+		LockTTL:      300 * time.Millisecond,
+		PollInterval: 10 * time.Millisecond,
+	}, logging.NewLogger("test", logging.ERROR, io.Discard))
+}
+
+// TestLocks tests that a lock is held by one node at a time and that a node
+// learns when it loses one
+func TestLocks(t *testing.T) {
+	store := newMemStore()
+	a, b := newTestNode(store, "a"), newTestNode(store, "b")
+	ctx := context.Background()
+
+	lockCtx, unlock, ok := a.TryLock(ctx, "job:token_cleanup")
+	if !ok {
+		t.Fatal("Expected node a to take a free lock")
+	}
+	if _, _, ok := b.TryLock(ctx, "job:token_cleanup"); ok {
+		t.Fatal("Expected node b to be refused a held lock")
+	}
+
+	// Renewal keeps the lock past its TTL
+	time.Sleep(500 * time.Millisecond)
+	if _, _, ok := b.TryLock(ctx, "job:token_cleanup"); ok {
+		t.Fatal("Expected node a's renewed lock to stay held")
+	}
+	if lockCtx.Err() != nil {
+		t.Fatal("Expected the lock context to stay live while the lock is held")
+	}
+	unlock()
+	unlock()
+
+	_, unlockB, ok := b.TryLock(ctx, "job:token_cleanup")
+	if !ok {
+		t.Fatal("Expected node b to take the released lock")
+	}
+	defer unlockB()
+
+	// A lock taken over by another node cancels the holder's context
+	lockCtx, unlock, ok = a.TryLock(ctx, "watcher")
+	if !ok {
+		t.Fatal("Expected node a to take a free lock")
+	}
+	defer unlock()
+	store.steal("watcher", "b")
+	select {
+	case <-lockCtx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("Expected the lost lock to cancel its context")
+	}
+}
+
+// TestLead tests that leadership moves to another node when the leader stops
+func TestLead(t *testing.T) {
+	store := newMemStore()
+	leaders := make(chan string, 10)
+	lead := func(ctx context.Context, node *Node) {
+		node.Lead(ctx, "watcher", func(ctx context.Context) {
+			leaders <- node.ID()
+			<-ctx.Done()
+		})
+	}
+
+	ctxA, stopA := context.WithCancel(context.Background())
+	ctxB, stopB := context.WithCancel(context.Background())
+	defer stopB()
+	go lead(ctxA, newTestNode(store, "a"))
+	if leader := <-leaders; leader != "a" {
+		t.Fatalf("Expected node a to lead, got %s", leader)
+	}
+	go lead(ctxB, newTestNode(store, "b"))
+
+	time.Sleep(50 * time.Millisecond)
+	select {
+	case leader := <-leaders:
+		t.Fatalf("Expected a single leader, %s also led", leader)
+	default:
+	}
+
+	stopA()
+	select {
+	case leader := <-leaders:
+		if leader != "b" {
+			t.Errorf("Expected node b to take over, got %s", leader)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected node b to take over after node a stopped")
+	}
+}
+
+// TestEvents tests that events reach the other nodes but not their publisher
+func TestEvents(t *testing.T) {
+	store := newMemStore()
+	a, b := newTestNode(store, "a"), newTestNode(store, "b")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Events from before a node started are not delivered to it
+	a.Publish(ctx, "websocket", "old")
+
+	received := make(chan string, 10)
+	a.Subscribe("websocket", func(payload string) { received <- "a:" + payload })
+	b.Subscribe("websocket", func(payload string) { received <- "b:" + payload })
+	go a.Run(ctx)
+	go b.Run(ctx)
+	time.Sleep(30 * time.Millisecond)
+
+	if err := a.Publish(ctx, "websocket", "ingested"); err != nil {
+		t.Fatalf("Publish failed: %v", err)
+	}
+	a.Publish(ctx, "other", "ignored")
+
+	select {
+	case got := <-received:
+		if got != "b:ingested" {
+			t.Errorf("Expected node b to receive the event, got %s", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected node b to receive the event")
+	}
+	time.Sleep(50 * time.Millisecond)
+	select {
+	case got := <-received:
+		t.Errorf("Expected no other deliveries, got %s", got)
+	default:
+	}
+}
+
+// TestTasks tests that a submitted task runs once on the working node and its
+// outcome reaches the submitter
+func TestTasks(t *testing.T) {
+	store := newMemStore()
+	a, b := newTestNode(store, "a"), newTestNode(store, "b")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go b.Work(ctx, "folder_retry", func(ctx context.Context, payload string) (string, error) {
+		if payload == "missing.txt" {
+			return "", errors.New("file not found")
+		}
+		return "ingested " + payload, nil
+	})
+
+	id, err := a.Submit(ctx, "folder_retry", "notes.txt")
+	if err != nil {
+		t.Fatalf("Submit failed: %v", err)
+	}
+	result, err := a.Wait(ctx, id)
+	if err != nil || result != "ingested notes.txt" {
+		t.Errorf("Expected the task's result, got %q, %v", result, err)
+	}
+
+	id, _ = a.Submit(ctx, "folder_retry", "missing.txt")
+	if _, err := a.Wait(ctx, id); err == nil || err.Error() != "file not found" {
+		t.Errorf("Expected the task's error, got %v", err)
+	}
+
+	// A task claimed too often, as by nodes that kept stopping, is given up
+	id, _ = a.Submit(ctx, "stuck", "")
+	store.mu.Lock()
+	store.tasks[id-1].Attempts = 3
+	store.mu.Unlock()
+	go b.Work(ctx, "stuck", func(ctx context.Context, payload string) (string, error) {
+		t.Error("Expected the abandoned task not to run")
+		return "", nil
+	})
+	if _, err := a.Wait(ctx, id); err == nil {
+		t.Error("Expected the abandoned task to fail")
+	}
+
+	waitCtx, stop := context.WithTimeout(ctx, 30*time.Millisecond)
+	defer stop()
+	id, _ = a.Submit(ctx, "nobody_works_on_this", "")
+	if _, err := a.Wait(waitCtx, id); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected Wait to give up with its context, got %v", err)
+	}
+}
@@ -0,0 +1,54 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// CheckFile checks a config file the way Noodexx loads it and returns every
+// problem found: syntax errors with their position, unknown settings, values of
+// the wrong type, and the validation rules Load applies, including those that
+// span fields such as privacy mode and the local provider. Environment overrides
+// are applied as on startup. Unlike Load, a missing file is a problem rather
+// than replaced by a default one
+func CheckFile(path string) []error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return []error{fmt.Errorf("cannot read config file: %w", err)}
+	}
+
+	var doc interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		var syntaxErr *json.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			// The offset is just past the byte that could not be parsed
+			line, column := position(data, syntaxErr.Offset-1)
+			return []error{fmt.Errorf("line %d, column %d: %v", line, column, err)}
+		}
+		return []error{err}
+	}
+
+	problems := CheckSchema(doc)
+
+	// Values of the wrong type keep the file from loading at all; they are
+	// already reported above
+	if err := json.Unmarshal(data, &Config{}); err != nil {
+		return problems
+	}
+	if _, err := Load(path); err != nil {
+		problems = append(problems, err)
+	}
+	return problems
+}
+
+// position converts a byte offset into data to a 1-based line and column
+func position(data []byte, offset int64) (int, int) {
+	offset = max(0, min(offset, int64(len(data))))
+	before := data[:offset]
+	line := bytes.Count(before, []byte("\n")) + 1
+	column := len(before) - bytes.LastIndexByte(before, '\n')
+	return line, column
+}
@@ -3,35 +3,74 @@ package config
 import (
 	"encoding/json"
 	"fmt"
+	"net/url"
+	"noodexx/internal/auth"
+	"noodexx/internal/events"
+	"noodexx/internal/flags"
+	"noodexx/internal/pwpolicy"
+	"noodexx/internal/scheduler"
 	"os"
+	"regexp"
 	"strings"
 )
 
+// hexColorPattern matches #rgb and #rrggbb colors
+var hexColorPattern = regexp.MustCompile(`^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+// serviceNamePattern matches names valid for both systemd units and Windows services
+var serviceNamePattern = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
 // Config holds all application configuration
 type Config struct {
-	LocalProvider ProviderConfig   `json:"local_provider"` // Local AI provider configuration
-	CloudProvider ProviderConfig   `json:"cloud_provider"` // Cloud AI provider configuration
-	Privacy       PrivacyConfig    `json:"privacy"`
-	Folders       []string         `json:"folders"`
-	Logging       LoggingConfig    `json:"logging"`
-	Guardrails    GuardrailsConfig `json:"guardrails"`
-	Server        ServerConfig     `json:"server"`
-	UserMode      string           `json:"user_mode"` // "single" or "multi"
-	Auth          AuthConfig       `json:"auth"`
+	LocalProvider ProviderConfig      `json:"local_provider"` // Local AI provider configuration
+	CloudProvider ProviderConfig      `json:"cloud_provider"` // Cloud AI provider configuration
+	Privacy       PrivacyConfig       `json:"privacy"`
+	Folders       []string            `json:"folders"`
+	Logging       LoggingConfig       `json:"logging"`
+	Guardrails    GuardrailsConfig    `json:"guardrails"`
+	Server        ServerConfig        `json:"server"`
+	UserMode      string              `json:"user_mode"` // "single" or "multi"
+	Auth          AuthConfig          `json:"auth"`
+	Export        ExportConfig        `json:"export"`
+	Branding      BrandingConfig      `json:"branding"`
+	Database      DatabaseConfig      `json:"database"`
+	WireLog       WireLogConfig       `json:"wire_log"`
+	WebSearch     WebSearchConfig     `json:"web_search"`
+	EmbeddingPool EmbeddingPoolConfig `json:"embedding_pool"`
+	ProviderQueue ProviderQueueConfig `json:"provider_queue"`
+	Skills        SkillsConfig        `json:"skills"`
+	ModelWarmup   ModelWarmupConfig   `json:"model_warmup"`
+	Features      FeaturesConfig      `json:"features"`
+	Scheduler     SchedulerConfig     `json:"scheduler"`
+	Cluster       ClusterConfig       `json:"cluster"`
+	Telemetry     TelemetryConfig     `json:"telemetry"`
+	Service       ServiceConfig       `json:"service"`
+	Startup       StartupConfig       `json:"startup"`
+	Search        SearchConfig        `json:"search"`
+	Watcher       WatcherConfig       `json:"watcher"`
+	Extractors    ExtractorsConfig    `json:"extractors"`
+	Costs         CostsConfig         `json:"costs"`
+	Transcripts   TranscriptsConfig   `json:"transcripts"`
+	Replay        ReplayConfig        `json:"replay"`
+	Webhooks      []WebhookConfig     `json:"webhooks"` // Receivers of internal events
 }
 
 // ProviderConfig configures the LLM provider
 type ProviderConfig struct {
-	Type                string `json:"type"` // "ollama", "openai", "anthropic"
-	OllamaEndpoint      string `json:"ollama_endpoint"`
-	OllamaEmbedModel    string `json:"ollama_embed_model"`
-	OllamaChatModel     string `json:"ollama_chat_model"`
-	OpenAIKey           string `json:"openai_key"`
-	OpenAIEmbedModel    string `json:"openai_embed_model"`
-	OpenAIChatModel     string `json:"openai_chat_model"`
-	AnthropicKey        string `json:"anthropic_key"`
-	AnthropicEmbedModel string `json:"anthropic_embed_model"`
-	AnthropicChatModel  string `json:"anthropic_chat_model"`
+	Type                string   `json:"type"` // "ollama", "openai", "anthropic", "builtin"
+	OllamaEndpoint      string   `json:"ollama_endpoint"`
+	OllamaEmbedModel    string   `json:"ollama_embed_model"`
+	OllamaChatModel     string   `json:"ollama_chat_model"`
+	OpenAIKey           string   `json:"openai_key"`
+	OpenAIEmbedModel    string   `json:"openai_embed_model"`
+	OpenAIChatModel     string   `json:"openai_chat_model"`
+	AnthropicKey        string   `json:"anthropic_key"`
+	AnthropicChatModel  string   `json:"anthropic_chat_model"`
+	EmbeddingFallback   []string `json:"embedding_fallback,omitempty"`    // Embedders to try in order when the provider has no embeddings API
+	BuiltinModelPath    string   `json:"builtin_model_path,omitempty"`    // ONNX embedding model
+	BuiltinVocabPath    string   `json:"builtin_vocab_path,omitempty"`    // WordPiece vocab.txt
+	BuiltinRerankerPath string   `json:"builtin_reranker_path,omitempty"` // Optional ONNX cross-encoder
+	BuiltinRuntimePath  string   `json:"builtin_runtime_path,omitempty"`  // onnxruntime shared library
 }
 
 // PrivacyConfig controls privacy mode
@@ -74,27 +113,287 @@ type LoggingConfig struct {
 	MaxBackups   int    `json:"max_backups"`   // Number of backup files to keep
 }
 
-// GuardrailsConfig controls ingestion safety
+// GuardrailsConfig controls ingestion safety and bounds answer generation parameters
 type GuardrailsConfig struct {
-	MaxFileSizeMB     int      `json:"max_file_size_mb"`
-	AllowedExtensions []string `json:"allowed_extensions"`
-	MaxConcurrent     int      `json:"max_concurrent"`
-	PIIDetection      string   `json:"pii_detection"` // "strict", "normal", "off"
-	AutoSummarize     bool     `json:"auto_summarize"`
+	MaxFileSizeMB         int      `json:"max_file_size_mb"`
+	AllowedExtensions     []string `json:"allowed_extensions"`
+	MaxConcurrent         int      `json:"max_concurrent"`
+	PIIDetection          string   `json:"pii_detection"` // "strict", "normal", "off"
+	AutoSummarize         bool     `json:"auto_summarize"`
+	MaxTemperature        float64  `json:"max_temperature"`         // Highest temperature a user may request
+	MaxOutputTokens       int      `json:"max_output_tokens"`       // Highest max_tokens a user may request
+	QuarantineAfter       int      `json:"quarantine_after"`        // Failed ingests before a watched file is no longer retried
+	DocQATokenBudget      int      `json:"doc_qa_token_budget"`     // Document tokens per model call when answering over a whole document
+	SummaryRefreshMinutes int      `json:"summary_refresh_minutes"` // How often stale summaries are regenerated when auto_summarize is on
+	MaxSourceChunks       int      `json:"max_source_chunks"`       // Chunks kept per document; longer documents are truncated with a warning
+	MaxSourceChars        int      `json:"max_source_chars"`        // Characters of extracted text kept per document
+	HistoryMessages       int      `json:"history_messages"`        // Recent session messages included in prompts; older ones reach prompts through the session's rolling summary
+	CompactIdleMinutes    int      `json:"compact_idle_minutes"`    // Idle time after which a session's older messages are folded into its rolling summary
+	ChunkSize             int      `json:"chunk_size"`              // Characters per chunk; documents ingested with other settings can be re-chunked from the library
+	ChunkOverlap          int      `json:"chunk_overlap"`           // Characters shared by consecutive chunks
 }
 
 // ServerConfig controls HTTP server
 type ServerConfig struct {
-	Port        int    `json:"port"`
-	BindAddress string `json:"bind_address"`
+	Port                   int            `json:"port"`
+	BindAddress            string         `json:"bind_address"`
+	MaxBodyKB              int            `json:"max_body_kb"`              // Request body limit of routes without their own; uploads follow guardrails.max_file_size_mb
+	ReadTimeoutSeconds     int            `json:"read_timeout_seconds"`     // Default: 15; longest to read a request, headers and body
+	WriteTimeoutSeconds    int            `json:"write_timeout_seconds"`    // Default: 15; longest from a request's arrival to the end of its response, for routes that don't stream
+	IdleTimeoutSeconds     int            `json:"idle_timeout_seconds"`     // Default: 60; how long an idle keep-alive connection stays open
+	StreamTimeoutSeconds   int            `json:"stream_timeout_seconds"`   // Longest a streamed answer may take, including waiting for the model; 0 for no limit
+	KeepAliveSeconds       int            `json:"keep_alive_seconds"`       // Default: 10; interval of keep-alive comments while an answer waits for its first token; 0 sends none
+	ShutdownTimeoutSeconds int            `json:"shutdown_timeout_seconds"` // Default: 10; how long running requests may finish on shutdown
+	RouteTimeouts          map[string]int `json:"route_timeouts,omitempty"` // Write timeouts in seconds of particular paths, or path prefixes ending in "/"; 0 for no limit
 }
 
 // AuthConfig controls authentication behavior
 type AuthConfig struct {
-	Provider               string `json:"provider"`                 // "userpass", "mfa", "sso"
-	SessionExpiryDays      int    `json:"session_expiry_days"`      // Default: 7
-	LockoutThreshold       int    `json:"lockout_threshold"`        // Default: 5
-	LockoutDurationMinutes int    `json:"lockout_duration_minutes"` // Default: 15
+	Provider               string               `json:"provider"`                 // Registered provider: "userpass", "ldap", "header", "mfa", "sso"
+	SessionExpiryDays      int                  `json:"session_expiry_days"`      // Default: 7; idle days before a session expires
+	RememberMeDays         int                  `json:"remember_me_days"`         // Default: 30; idle days for "remember me" sessions
+	MaxSessionDays         int                  `json:"max_session_days"`         // Default: 90; sessions in use slide up to this age
+	LockoutThreshold       int                  `json:"lockout_threshold"`        // Default: 5
+	LockoutDurationMinutes int                  `json:"lockout_duration_minutes"` // Default: 15
+	PasswordPolicy         PasswordPolicyConfig `json:"password_policy"`          // Rules for new passwords
+	Settings               map[string]string    `json:"settings,omitempty"`       // Provider-specific settings, e.g. trusted_proxies for "header"
+}
+
+// PasswordPolicyConfig controls which new passwords are accepted
+type PasswordPolicyConfig struct {
+	MinLength     int    `json:"min_length"`      // Default: 8
+	MinClasses    int    `json:"min_classes"`     // Character classes (lowercase, uppercase, digits, symbols) to mix, 0-4
+	AllowCommon   bool   `json:"allow_common"`    // Accept passwords on the built-in common password list
+	BreachListDir string `json:"breach_list_dir"` // Local Pwned Passwords range files; empty to skip the breach check
+	HistoryCount  int    `json:"history_count"`   // Recent passwords, including the current one, that can't be reused (0-24)
+}
+
+// Options returns the password policy options for pwpolicy.New
+func (p PasswordPolicyConfig) Options() pwpolicy.Options {
+	return pwpolicy.Options{
+		MinLength:     p.MinLength,
+		MinClasses:    p.MinClasses,
+		AllowCommon:   p.AllowCommon,
+		BreachListDir: p.BreachListDir,
+		HistoryCount:  p.HistoryCount,
+	}
+}
+
+// ExportConfig controls chat transcript export
+type ExportConfig struct {
+	PDFCommand string `json:"pdf_command"` // HTML-to-PDF command reading stdin and writing stdout
+}
+
+// BrandingConfig controls organization branding and template/static overrides
+type BrandingConfig struct {
+	AppName      string `json:"app_name"`      // Shown in page titles and the sidebar
+	LogoPath     string `json:"logo_path"`     // URL of the logo image
+	PrimaryColor string `json:"primary_color"` // Hex color, e.g. "#0f766e" (empty keeps the theme color)
+	FooterText   string `json:"footer_text"`   // Optional footer shown on every page
+	OverrideDir  string `json:"override_dir"`  // Directory of replacement templates and static/ assets
+}
+
+// DatabaseConfig controls SQLite tuning and WAL maintenance
+type DatabaseConfig struct {
+	Synchronous               string `json:"synchronous"`                 // "OFF", "NORMAL", "FULL", "EXTRA"
+	CacheSizeKB               int    `json:"cache_size_kb"`               // Per-connection page cache in KiB (0 = SQLite default)
+	CheckpointIntervalMinutes int    `json:"checkpoint_interval_minutes"` // How often to truncate the WAL
+	SearchPageSize            int    `json:"search_page_size"`            // Rows read per page during vector search
+	VectorIndexPath           string `json:"vector_index_path"`           // Snapshot file for the in-memory vector index and its ANN lists
+	VectorSnapshotMinutes     int    `json:"vector_snapshot_minutes"`     // How often to repair and snapshot the vector index
+	ANNMinChunks              int    `json:"ann_min_chunks"`              // Chunks of one embedding dimension before searches go through the ANN index
+	ANNProbes                 int    `json:"ann_probes"`                  // ANN lists read per search; more finds more of the exact results
+	ANNRebuildHours           int    `json:"ann_rebuild_hours"`           // How often to rebuild the ANN index
+	WritePoolSize             int    `json:"write_pool_size"`             // Connections on the primary handle, which every write goes through
+	ReadPoolSize              int    `json:"read_pool_size"`              // Read-only connections for search and library queries; 0 runs them on the primary handle
+}
+
+// WireLogConfig controls the opt-in provider request log
+// Entries hold request metadata and prompt hashes; raw prompts and responses are only
+// recorded for the users listed in LogContentUserIDs
+type WireLogConfig struct {
+	Enabled           bool    `json:"enabled"`              // Record provider requests
+	File              string  `json:"file"`                 // Wire log file path
+	MaxSizeMB         int     `json:"max_size_mb"`          // Max file size before rotation
+	MaxBackups        int     `json:"max_backups"`          // Number of backup files to keep
+	LogContentUserIDs []int64 `json:"log_content_user_ids"` // Users whose raw prompts and responses are logged
+}
+
+// WebhookConfig posts internal events, such as a finished ingestion, to a URL
+// as JSON
+type WebhookConfig struct {
+	URL    string   `json:"url"`    // http or https URL events are POSTed to
+	Topics []string `json:"topics"` // Topics to send, such as "ingestion.completed"; every topic when empty
+	Secret string   `json:"secret"` // Signs each body with HMAC-SHA256 in the X-Noodexx-Signature header when set
+}
+
+// TranscriptsConfig controls the encrypted archive of requests to cloud
+// providers, kept for compliance review
+type TranscriptsConfig struct {
+	Enabled           bool   `json:"enabled"`            // Archive every request to a cloud provider and its response
+	KeyFile           string `json:"key_file"`           // Encryption key, created on first use; without it the archive cannot be read
+	RetentionDays     int    `json:"retention_days"`     // Delete archived requests older than this; 0 keeps them
+	IncludeEmbeddings bool   `json:"include_embeddings"` // Also archive the text sent for embedding, such as every ingested chunk
+}
+
+// ReplayConfig controls keeping provider requests that fail, so administrators
+// can replay them. Bearer tokens and API keys are always redacted first
+type ReplayConfig struct {
+	Enabled        bool     `json:"enabled"`         // Keep failed provider requests for replay
+	RetentionDays  int      `json:"retention_days"`  // Delete failed requests older than this
+	RedactPatterns []string `json:"redact_patterns"` // Regular expressions replaced with [REDACTED] before a request is kept
+}
+
+// WebSearchConfig configures the built-in web search used to add live context to answers
+// Searches only run in cloud mode with AutoInCloudMode set, or when a request explicitly opts in
+type WebSearchConfig struct {
+	Enabled           bool   `json:"enabled"`              // Allow web search at all
+	Engine            string `json:"engine"`               // "searxng" or "brave"
+	Endpoint          string `json:"endpoint"`             // Engine base URL (required for searxng)
+	APIKey            string `json:"api_key"`              // Engine API key (required for brave)
+	MaxResults        int    `json:"max_results"`          // Results fetched and added to the prompt
+	MaxCharsPerResult int    `json:"max_chars_per_result"` // Extracted text kept per page
+	TimeoutSeconds    int    `json:"timeout_seconds"`      // Timeout for the search and page fetches
+	AutoInCloudMode   bool   `json:"auto_in_cloud_mode"`   // Search on every question while in cloud mode
+	IngestResults     bool   `json:"ingest_results"`       // Save fetched pages to the user's library
+}
+
+// EmbeddingPoolConfig spreads ingestion embedding across several Ollama instances
+// Every endpoint must serve the same embedding model as the local provider
+type EmbeddingPoolConfig struct {
+	Enabled          bool     `json:"enabled"`           // Embed ingested documents through the pool
+	Endpoints        []string `json:"endpoints"`         // Ollama base URLs
+	Model            string   `json:"model"`             // Embedding model, defaults to the local provider's
+	Concurrency      int      `json:"concurrency"`       // Parallel requests per endpoint
+	MaxAttempts      int      `json:"max_attempts"`      // Tries per chunk before ingestion fails
+	FailureThreshold int      `json:"failure_threshold"` // Consecutive failures before an endpoint is skipped
+	CooldownSeconds  int      `json:"cooldown_seconds"`  // How long a failing endpoint is skipped
+}
+
+// ProviderQueueConfig limits concurrent answer generation so one user cannot starve others
+// Waiting requests are admitted round-robin across users
+type ProviderQueueConfig struct {
+	MaxConcurrent    int `json:"max_concurrent"`     // Answers generated at once across all users
+	MaxPerUser       int `json:"max_per_user"`       // Answers generated at once for a single user
+	KeepAliveSeconds int `json:"keep_alive_seconds"` // Interval of queue position events while waiting
+}
+
+// SkillsConfig caps skill executions whatever their manifests ask for
+// Executions beyond the concurrency limits wait, admitted round-robin across users
+type SkillsConfig struct {
+	MaxRuntimeSeconds   int `json:"max_runtime_seconds"`   // Longest a skill may run; lower manifest timeouts are kept
+	MaxConcurrent       int `json:"max_concurrent"`        // Skills running at once across all users
+	MaxPerUser          int `json:"max_per_user"`          // Skills running at once for a single user
+	MaxOutputKB         int `json:"max_output_kb"`         // Largest output accepted from a skill
+	QueueTimeoutSeconds int `json:"queue_timeout_seconds"` // Longest a skill waits for a free slot
+}
+
+// ModelWarmupConfig keeps the local Ollama models loaded so the first answer after idle is fast
+// Pinging stops once nobody has chatted for the idle window, letting Ollama unload the models
+type ModelWarmupConfig struct {
+	WarmOnStartup       bool `json:"warm_on_startup"`       // Load the local models when Noodexx starts
+	KeepAlive           bool `json:"keep_alive"`            // Ping the local models so Ollama keeps them loaded
+	PingIntervalSeconds int  `json:"ping_interval_seconds"` // Time between keep-alive pings
+	IdleWindowMinutes   int  `json:"idle_window_minutes"`   // Stop pinging after this long without a chat
+}
+
+// FeaturesConfig switches feature flags on or off, optionally for a share of users
+// Flags left out are on for everyone; admins can override them per user
+type FeaturesConfig map[string]flags.Flag
+
+// SchedulerConfig controls when background jobs run
+// Jobs keep their built-in schedules unless listed in Jobs
+type SchedulerConfig struct {
+	JitterSeconds int               `json:"jitter_seconds"` // Longest random delay before a scheduled run
+	Jobs          map[string]string `json:"jobs"`           // Job name to cron expression or "@every <duration>"
+}
+
+// ClusterConfig lets several Noodexx instances share one database
+// Background jobs and the folder watcher then run on one instance at a time,
+// and WebSocket events reach the clients of every instance
+type ClusterConfig struct {
+	Enabled        bool   `json:"enabled"`
+	NodeID         string `json:"node_id"`          // Unique name of this instance, hostname and process ID when empty
+	LockTTLSeconds int    `json:"lock_ttl_seconds"` // How long a lock outlives an instance that stopped renewing it
+	PollIntervalMS int    `json:"poll_interval_ms"` // How often to check for events, tasks and free locks
+}
+
+// TelemetryConfig opts in to anonymous usage reports: the version, OS,
+// provider types and a library size bucket, sent daily
+// NOODEXX_TELEMETRY=off or DO_NOT_TRACK=1 keeps telemetry off whatever this says
+type TelemetryConfig struct {
+	Enabled  bool   `json:"enabled"`  // Send reports; off by default
+	Endpoint string `json:"endpoint"` // URL reports are POSTed to
+}
+
+// SearchConfig controls how library search results are chosen for a prompt
+type SearchConfig struct {
+	Diversify     bool    `json:"diversify"`      // Pick results by Maximal Marginal Relevance so they are not near-copies
+	MMRLambda     float64 `json:"mmr_lambda"`     // Weight of relevance against diversity, from 0 (most diverse) to 1
+	TitleWeight   float64 `json:"title_weight"`   // Weight of a chunk's heading similarity in its score, from 0 (body only) to 1
+	PinBoost      float64 `json:"pin_boost"`      // Multiplier for the scores of documents the user pinned; 1 ranks them like any other
+	Mode          string  `json:"mode"`           // "vector" (embeddings only), "keyword" (BM25 over chunk text only) or "hybrid" (both)
+	Fusion        string  `json:"fusion"`         // How hybrid search merges its rankings: "rrf" (reciprocal rank fusion) or "weighted"
+	KeywordWeight float64 `json:"keyword_weight"` // Weight of keyword scores in weighted fusion, from 0 (vector only) to 1
+}
+
+// WatcherConfig controls when the folder watcher ingests a changed file, so
+// files written over seconds by sync clients such as Dropbox or OneDrive, or
+// copied to a network share, are not read half written
+type WatcherConfig struct {
+	StableSeconds  int                   `json:"stable_seconds"`   // A file's size and modification time must hold this long before it is ingested; 0 ingests on the first change
+	MaxWaitSeconds int                   `json:"max_wait_seconds"` // A file still changing or locked after this long is ingested anyway
+	ReadRetries    int                   `json:"read_retries"`     // Retries of a failed read, one second apart and doubling
+	Folders        []WatchedFolderTuning `json:"folders"`          // Settings for particular folders, such as slow network mounts
+}
+
+// WatchedFolderTuning overrides the watcher settings for one folder; fields
+// left at 0 keep the watcher's
+type WatchedFolderTuning struct {
+	Path           string `json:"path"`
+	StableSeconds  int    `json:"stable_seconds"`
+	MaxWaitSeconds int    `json:"max_wait_seconds"`
+	ReadRetries    int    `json:"read_retries"`
+	PollSeconds    int    `json:"poll_seconds"` // Scan the folder this often instead of waiting for change events, which network mounts often don't deliver
+}
+
+// ExtractorsConfig controls the external programs that convert files into
+// text, for formats Noodexx cannot read itself
+type ExtractorsConfig struct {
+	Dir               string `json:"dir"`                 // Directory of extractors, each in a subdirectory with an extractor.json
+	MaxRuntimeSeconds int    `json:"max_runtime_seconds"` // Longest an extractor may run; lower manifest timeouts are kept
+	MaxOutputMB       int    `json:"max_output_mb"`       // Largest output accepted from an extractor
+}
+
+// CostsConfig controls the estimate of what a question to a paid provider
+// will cost, and when the user must confirm it before it is sent
+type CostsConfig struct {
+	ConfirmAboveUSD  float64               `json:"confirm_above_usd"` // Ask before sending requests estimated to cost more; users may set their own, 0 never asks
+	CompletionTokens int                   `json:"completion_tokens"` // Answer length assumed when the request sets no max_tokens
+	Prices           map[string]ModelPrice `json:"prices"`            // Prices by model name; requests to models without one are estimated in tokens only
+}
+
+// ModelPrice is what a cloud model costs in US dollars per million tokens
+type ModelPrice struct {
+	InputPerMillion  float64 `json:"input_per_million"`
+	OutputPerMillion float64 `json:"output_per_million"`
+}
+
+// ServiceConfig describes the Windows service or systemd unit installed by
+// noodexx --service install
+type ServiceConfig struct {
+	Name        string `json:"name"`         // Service or unit name
+	DisplayName string `json:"display_name"` // Name in the Windows services console
+	Description string `json:"description"`
+	User        string `json:"user"`     // Account the systemd unit runs as; root when empty
+	LogFile     string `json:"log_file"` // Console output when run as a service; stdout (the journal) when empty, noodexx-service.log on Windows
+}
+
+// StartupConfig controls how long Noodexx waits for the database and the local
+// provider before serving; requests get a 503 "starting" response meanwhile
+type StartupConfig struct {
+	MaxWaitSeconds    int `json:"max_wait_seconds"`    // Longest to wait; the local provider may still be down afterwards
+	MaxBackoffSeconds int `json:"max_backoff_seconds"` // Longest delay between checks of a dependency
 }
 
 // Load reads configuration from file and environment
@@ -123,22 +422,146 @@ func Load(path string) (*Config, error) {
 			MaxBackups:   3,
 		},
 		Guardrails: GuardrailsConfig{
-			MaxFileSizeMB:     10,
-			AllowedExtensions: []string{".txt", ".md", ".pdf", ".html"},
-			MaxConcurrent:     3,
-			PIIDetection:      "normal",
-			AutoSummarize:     true,
+			MaxFileSizeMB:         10,
+			AllowedExtensions:     []string{".txt", ".md", ".pdf", ".html"},
+			MaxConcurrent:         3,
+			PIIDetection:          "normal",
+			AutoSummarize:         true,
+			MaxTemperature:        2.0,
+			MaxOutputTokens:       4096,
+			QuarantineAfter:       3,
+			DocQATokenBudget:      3000,
+			SummaryRefreshMinutes: 60,
+			MaxSourceChunks:       5000,
+			MaxSourceChars:        2000000,
+			HistoryMessages:       10,
+			CompactIdleMinutes:    30,
+			ChunkSize:             500,
+			ChunkOverlap:          50,
 		},
 		Server: ServerConfig{
-			Port:        8080,
-			BindAddress: "127.0.0.1",
+			Port:                   8080,
+			BindAddress:            "127.0.0.1",
+			MaxBodyKB:              1024,
+			ReadTimeoutSeconds:     15,
+			WriteTimeoutSeconds:    15,
+			IdleTimeoutSeconds:     60,
+			KeepAliveSeconds:       10,
+			ShutdownTimeoutSeconds: 10,
 		},
 		UserMode: "single",
 		Auth: AuthConfig{
 			Provider:               "userpass",
 			SessionExpiryDays:      7,
+			RememberMeDays:         30,
+			MaxSessionDays:         90,
 			LockoutThreshold:       5,
 			LockoutDurationMinutes: 15,
+			PasswordPolicy: PasswordPolicyConfig{
+				MinLength: 8,
+			},
+		},
+		Export: ExportConfig{
+			PDFCommand: "wkhtmltopdf --quiet - -",
+		},
+		Branding: BrandingConfig{
+			AppName:  "Noodexx",
+			LogoPath: "/static/logo.png",
+		},
+		Database: DatabaseConfig{
+			Synchronous:               "NORMAL",
+			CheckpointIntervalMinutes: 5,
+			SearchPageSize:            500,
+			VectorIndexPath:           "noodexx.vecidx",
+			VectorSnapshotMinutes:     30,
+			ANNMinChunks:              20000,
+			ANNProbes:                 16,
+			ANNRebuildHours:           24,
+			WritePoolSize:             25,
+		},
+		WireLog: WireLogConfig{
+			Enabled:    false,
+			File:       "provider-wire.log",
+			MaxSizeMB:  10,
+			MaxBackups: 3,
+		},
+		Transcripts: TranscriptsConfig{
+			KeyFile:       "transcripts.key",
+			RetentionDays: 365,
+		},
+		Replay: ReplayConfig{
+			RetentionDays: 14,
+		},
+		WebSearch: WebSearchConfig{
+			Enabled:           false,
+			Engine:            "searxng",
+			MaxResults:        3,
+			MaxCharsPerResult: 2000,
+			TimeoutSeconds:    10,
+		},
+		EmbeddingPool: EmbeddingPoolConfig{
+			Enabled:          false,
+			Concurrency:      2,
+			MaxAttempts:      3,
+			FailureThreshold: 3,
+			CooldownSeconds:  30,
+		},
+		ProviderQueue: ProviderQueueConfig{
+			MaxConcurrent:    4,
+			MaxPerUser:       1,
+			KeepAliveSeconds: 5,
+		},
+		Skills: SkillsConfig{
+			MaxRuntimeSeconds:   300,
+			MaxConcurrent:       4,
+			MaxPerUser:          2,
+			MaxOutputKB:         1024,
+			QueueTimeoutSeconds: 60,
+		},
+		ModelWarmup: ModelWarmupConfig{
+			WarmOnStartup:       false,
+			KeepAlive:           false,
+			PingIntervalSeconds: 120,
+			IdleWindowMinutes:   30,
+		},
+		Scheduler: SchedulerConfig{
+			JitterSeconds: 30,
+		},
+		Cluster: ClusterConfig{
+			LockTTLSeconds: 30,
+			PollIntervalMS: 1000,
+		},
+		Service: ServiceConfig{
+			Name:        "noodexx",
+			DisplayName: "Noodexx",
+			Description: "Noodexx local knowledge base",
+		},
+		Startup: StartupConfig{
+			MaxWaitSeconds:    120,
+			MaxBackoffSeconds: 10,
+		},
+		Search: SearchConfig{
+			Diversify:     false,
+			MMRLambda:     0.7,
+			TitleWeight:   0.3,
+			PinBoost:      1.5,
+			Mode:          "vector",
+			Fusion:        "rrf",
+			KeywordWeight: 0.3,
+		},
+		Watcher: WatcherConfig{
+			StableSeconds:  2,
+			MaxWaitSeconds: 300,
+			ReadRetries:    3,
+		},
+		Extractors: ExtractorsConfig{
+			Dir:               "extractors",
+			MaxRuntimeSeconds: 120,
+			MaxOutputMB:       50,
+		},
+		Costs: CostsConfig{
+			ConfirmAboveUSD:  0.10,
+			CompletionTokens: 1024,
 		},
 	}
 
@@ -190,6 +613,23 @@ func Load(path string) (*Config, error) {
 		if cfg.Server.BindAddress == "" {
 			cfg.Server.BindAddress = "127.0.0.1"
 		}
+		if cfg.Server.MaxBodyKB == 0 {
+			cfg.Server.MaxBodyKB = 1024
+		}
+		if cfg.Server.WriteTimeoutSeconds == 0 {
+			// Without server timeouts in the file, keep-alives are on too
+			cfg.Server.WriteTimeoutSeconds = 15
+			cfg.Server.KeepAliveSeconds = 10
+		}
+		if cfg.Server.ReadTimeoutSeconds == 0 {
+			cfg.Server.ReadTimeoutSeconds = 15
+		}
+		if cfg.Server.IdleTimeoutSeconds == 0 {
+			cfg.Server.IdleTimeoutSeconds = 60
+		}
+		if cfg.Server.ShutdownTimeoutSeconds == 0 {
+			cfg.Server.ShutdownTimeoutSeconds = 10
+		}
 		if cfg.UserMode == "" {
 			cfg.UserMode = "single"
 		}
@@ -199,12 +639,21 @@ func Load(path string) (*Config, error) {
 		if cfg.Auth.SessionExpiryDays == 0 {
 			cfg.Auth.SessionExpiryDays = 7
 		}
+		if cfg.Auth.RememberMeDays == 0 {
+			cfg.Auth.RememberMeDays = 30
+		}
+		if cfg.Auth.MaxSessionDays == 0 {
+			cfg.Auth.MaxSessionDays = 90
+		}
 		if cfg.Auth.LockoutThreshold == 0 {
 			cfg.Auth.LockoutThreshold = 5
 		}
 		if cfg.Auth.LockoutDurationMinutes == 0 {
 			cfg.Auth.LockoutDurationMinutes = 15
 		}
+		if cfg.Auth.PasswordPolicy.MinLength == 0 {
+			cfg.Auth.PasswordPolicy.MinLength = 8
+		}
 		if cfg.Guardrails.MaxFileSizeMB == 0 {
 			cfg.Guardrails.MaxFileSizeMB = 10
 		}
@@ -217,9 +666,197 @@ func Load(path string) (*Config, error) {
 		if len(cfg.Guardrails.AllowedExtensions) == 0 {
 			cfg.Guardrails.AllowedExtensions = []string{".txt", ".md", ".pdf", ".html"}
 		}
+		if cfg.Guardrails.MaxTemperature == 0 {
+			cfg.Guardrails.MaxTemperature = 2.0
+		}
+		if cfg.Guardrails.MaxOutputTokens == 0 {
+			cfg.Guardrails.MaxOutputTokens = 4096
+		}
+		if cfg.Guardrails.QuarantineAfter == 0 {
+			cfg.Guardrails.QuarantineAfter = 3
+		}
+		if cfg.Guardrails.DocQATokenBudget == 0 {
+			cfg.Guardrails.DocQATokenBudget = 3000
+		}
+		if cfg.Guardrails.SummaryRefreshMinutes == 0 {
+			cfg.Guardrails.SummaryRefreshMinutes = 60
+		}
+		if cfg.Guardrails.MaxSourceChunks == 0 {
+			cfg.Guardrails.MaxSourceChunks = 5000
+		}
+		if cfg.Guardrails.MaxSourceChars == 0 {
+			cfg.Guardrails.MaxSourceChars = 2000000
+		}
+		if cfg.Guardrails.HistoryMessages == 0 {
+			cfg.Guardrails.HistoryMessages = 10
+		}
+		if cfg.Guardrails.CompactIdleMinutes == 0 {
+			cfg.Guardrails.CompactIdleMinutes = 30
+		}
+		if cfg.Guardrails.ChunkSize == 0 {
+			cfg.Guardrails.ChunkSize = 500
+			cfg.Guardrails.ChunkOverlap = 50
+		}
 		if cfg.Privacy.CloudRAGPolicy == "" {
 			cfg.Privacy.CloudRAGPolicy = "no_rag"
 		}
+		if cfg.Export.PDFCommand == "" {
+			cfg.Export.PDFCommand = "wkhtmltopdf --quiet - -"
+		}
+		if cfg.Branding.AppName == "" {
+			cfg.Branding.AppName = "Noodexx"
+		}
+		if cfg.Branding.LogoPath == "" {
+			cfg.Branding.LogoPath = "/static/logo.png"
+		}
+		if cfg.Database.Synchronous == "" {
+			cfg.Database.Synchronous = "NORMAL"
+		}
+		if cfg.Database.CheckpointIntervalMinutes == 0 {
+			cfg.Database.CheckpointIntervalMinutes = 5
+		}
+		if cfg.Database.SearchPageSize == 0 {
+			cfg.Database.SearchPageSize = 500
+		}
+		if cfg.Database.VectorIndexPath == "" {
+			cfg.Database.VectorIndexPath = "noodexx.vecidx"
+		}
+		if cfg.Database.VectorSnapshotMinutes == 0 {
+			cfg.Database.VectorSnapshotMinutes = 30
+		}
+		if cfg.Database.ANNMinChunks == 0 {
+			cfg.Database.ANNMinChunks = 20000
+		}
+		if cfg.Database.ANNProbes == 0 {
+			cfg.Database.ANNProbes = 16
+		}
+		if cfg.Database.ANNRebuildHours == 0 {
+			cfg.Database.ANNRebuildHours = 24
+		}
+		if cfg.WireLog.File == "" {
+			cfg.WireLog.File = "provider-wire.log"
+		}
+		if cfg.WireLog.MaxSizeMB == 0 {
+			cfg.WireLog.MaxSizeMB = 10
+		}
+		if cfg.WireLog.MaxBackups == 0 {
+			cfg.WireLog.MaxBackups = 3
+		}
+		if cfg.Transcripts.KeyFile == "" {
+			cfg.Transcripts.KeyFile = "transcripts.key"
+			cfg.Transcripts.RetentionDays = 365
+		}
+		if cfg.Replay.RetentionDays == 0 {
+			cfg.Replay.RetentionDays = 14
+		}
+		if cfg.WebSearch.Engine == "" {
+			cfg.WebSearch.Engine = "searxng"
+		}
+		if cfg.WebSearch.MaxResults == 0 {
+			cfg.WebSearch.MaxResults = 3
+		}
+		if cfg.WebSearch.MaxCharsPerResult == 0 {
+			cfg.WebSearch.MaxCharsPerResult = 2000
+		}
+		if cfg.WebSearch.TimeoutSeconds == 0 {
+			cfg.WebSearch.TimeoutSeconds = 10
+		}
+		if cfg.EmbeddingPool.Concurrency == 0 {
+			cfg.EmbeddingPool.Concurrency = 2
+		}
+		if cfg.EmbeddingPool.MaxAttempts == 0 {
+			cfg.EmbeddingPool.MaxAttempts = 3
+		}
+		if cfg.EmbeddingPool.FailureThreshold == 0 {
+			cfg.EmbeddingPool.FailureThreshold = 3
+		}
+		if cfg.EmbeddingPool.CooldownSeconds == 0 {
+			cfg.EmbeddingPool.CooldownSeconds = 30
+		}
+		if cfg.ProviderQueue.MaxConcurrent == 0 {
+			cfg.ProviderQueue.MaxConcurrent = 4
+		}
+		if cfg.ProviderQueue.MaxPerUser == 0 {
+			cfg.ProviderQueue.MaxPerUser = 1
+		}
+		if cfg.ProviderQueue.KeepAliveSeconds == 0 {
+			cfg.ProviderQueue.KeepAliveSeconds = 5
+		}
+		if cfg.Skills.MaxRuntimeSeconds == 0 {
+			cfg.Skills.MaxRuntimeSeconds = 300
+		}
+		if cfg.Skills.MaxConcurrent == 0 {
+			cfg.Skills.MaxConcurrent = 4
+		}
+		if cfg.Skills.MaxPerUser == 0 {
+			cfg.Skills.MaxPerUser = 2
+		}
+		if cfg.Skills.MaxOutputKB == 0 {
+			cfg.Skills.MaxOutputKB = 1024
+		}
+		if cfg.Skills.QueueTimeoutSeconds == 0 {
+			cfg.Skills.QueueTimeoutSeconds = 60
+		}
+		if cfg.ModelWarmup.PingIntervalSeconds == 0 {
+			cfg.ModelWarmup.PingIntervalSeconds = 120
+		}
+		if cfg.ModelWarmup.IdleWindowMinutes == 0 {
+			cfg.ModelWarmup.IdleWindowMinutes = 30
+		}
+		if cfg.Scheduler.JitterSeconds == 0 {
+			cfg.Scheduler.JitterSeconds = 30
+		}
+		if cfg.Cluster.LockTTLSeconds == 0 {
+			cfg.Cluster.LockTTLSeconds = 30
+		}
+		if cfg.Cluster.PollIntervalMS == 0 {
+			cfg.Cluster.PollIntervalMS = 1000
+		}
+		if cfg.Service.Name == "" {
+			cfg.Service.Name = "noodexx"
+		}
+		if cfg.Service.DisplayName == "" {
+			cfg.Service.DisplayName = "Noodexx"
+		}
+		if cfg.Service.Description == "" {
+			cfg.Service.Description = "Noodexx local knowledge base"
+		}
+		if cfg.Startup.MaxWaitSeconds == 0 {
+			cfg.Startup.MaxWaitSeconds = 120
+		}
+		if cfg.Startup.MaxBackoffSeconds == 0 {
+			cfg.Startup.MaxBackoffSeconds = 10
+		}
+		if cfg.Search.MMRLambda == 0 {
+			cfg.Search.MMRLambda = 0.7
+		}
+		if cfg.Search.PinBoost == 0 {
+			cfg.Search.PinBoost = 1.5
+		}
+		if cfg.Search.Mode == "" {
+			cfg.Search.Mode = "vector"
+		}
+		if cfg.Search.Fusion == "" {
+			cfg.Search.Fusion = "rrf"
+		}
+		if cfg.Watcher.MaxWaitSeconds == 0 {
+			cfg.Watcher.StableSeconds = 2
+			cfg.Watcher.MaxWaitSeconds = 300
+			cfg.Watcher.ReadRetries = 3
+		}
+		if cfg.Extractors.Dir == "" {
+			cfg.Extractors.Dir = "extractors"
+		}
+		if cfg.Extractors.MaxRuntimeSeconds == 0 {
+			cfg.Extractors.MaxRuntimeSeconds = 120
+		}
+		if cfg.Extractors.MaxOutputMB == 0 {
+			cfg.Extractors.MaxOutputMB = 50
+		}
+		if cfg.Costs.CompletionTokens == 0 {
+			cfg.Costs.ConfirmAboveUSD = 0.10
+			cfg.Costs.CompletionTokens = 1024
+		}
 	} else {
 		// Create default config file
 		if err := cfg.Save(path); err != nil {
@@ -272,6 +909,18 @@ func (c *Config) applyEnvOverrides() {
 	if v := os.Getenv("NOODEXX_OLLAMA_CHAT_MODEL"); v != "" {
 		c.LocalProvider.OllamaChatModel = v
 	}
+	if v := os.Getenv("NOODEXX_BUILTIN_MODEL_PATH"); v != "" {
+		c.LocalProvider.BuiltinModelPath = v
+	}
+	if v := os.Getenv("NOODEXX_BUILTIN_VOCAB_PATH"); v != "" {
+		c.LocalProvider.BuiltinVocabPath = v
+	}
+	if v := os.Getenv("NOODEXX_BUILTIN_RERANKER_PATH"); v != "" {
+		c.LocalProvider.BuiltinRerankerPath = v
+	}
+	if v := os.Getenv("NOODEXX_ONNXRUNTIME_PATH"); v != "" {
+		c.LocalProvider.BuiltinRuntimePath = v
+	}
 	
 	// Cloud provider overrides
 	if v := os.Getenv("NOODEXX_CLOUD_PROVIDER_TYPE"); v != "" {
@@ -289,9 +938,6 @@ func (c *Config) applyEnvOverrides() {
 	if v := os.Getenv("NOODEXX_ANTHROPIC_KEY"); v != "" {
 		c.CloudProvider.AnthropicKey = v
 	}
-	if v := os.Getenv("NOODEXX_ANTHROPIC_EMBED_MODEL"); v != "" {
-		c.CloudProvider.AnthropicEmbedModel = v
-	}
 	if v := os.Getenv("NOODEXX_ANTHROPIC_CHAT_MODEL"); v != "" {
 		c.CloudProvider.AnthropicChatModel = v
 	}
@@ -329,6 +975,72 @@ func (c *Config) applyEnvOverrides() {
 	if v := os.Getenv("NOODEXX_AUTH_PROVIDER"); v != "" {
 		c.Auth.Provider = v
 	}
+	if v := os.Getenv("NOODEXX_EXPORT_PDF_COMMAND"); v != "" {
+		c.Export.PDFCommand = v
+	}
+	if v := os.Getenv("NOODEXX_BRANDING_OVERRIDE_DIR"); v != "" {
+		c.Branding.OverrideDir = v
+	}
+	if v := os.Getenv("NOODEXX_DATABASE_SYNCHRONOUS"); v != "" {
+		c.Database.Synchronous = v
+	}
+	if v := os.Getenv("NOODEXX_DATABASE_CACHE_SIZE_KB"); v != "" {
+		fmt.Sscanf(v, "%d", &c.Database.CacheSizeKB)
+	}
+	if v := os.Getenv("NOODEXX_DATABASE_READ_POOL_SIZE"); v != "" {
+		fmt.Sscanf(v, "%d", &c.Database.ReadPoolSize)
+	}
+	if v := os.Getenv("NOODEXX_DATABASE_VECTOR_INDEX_PATH"); v != "" {
+		c.Database.VectorIndexPath = v
+	}
+	if v := os.Getenv("NOODEXX_WIRE_LOG_ENABLED"); v != "" {
+		if v == "true" {
+			c.WireLog.Enabled = true
+		} else if v == "false" {
+			c.WireLog.Enabled = false
+		}
+	}
+	if v := os.Getenv("NOODEXX_WIRE_LOG_FILE"); v != "" {
+		c.WireLog.File = v
+	}
+	if v := os.Getenv("NOODEXX_WEB_SEARCH_ENABLED"); v != "" {
+		if v == "true" {
+			c.WebSearch.Enabled = true
+		} else if v == "false" {
+			c.WebSearch.Enabled = false
+		}
+	}
+	if v := os.Getenv("NOODEXX_WEB_SEARCH_ENGINE"); v != "" {
+		c.WebSearch.Engine = v
+	}
+	if v := os.Getenv("NOODEXX_WEB_SEARCH_ENDPOINT"); v != "" {
+		c.WebSearch.Endpoint = v
+	}
+	if v := os.Getenv("NOODEXX_WEB_SEARCH_API_KEY"); v != "" {
+		c.WebSearch.APIKey = v
+	}
+	if v := os.Getenv("NOODEXX_EMBEDDING_POOL_ENABLED"); v != "" {
+		if v == "true" {
+			c.EmbeddingPool.Enabled = true
+		} else if v == "false" {
+			c.EmbeddingPool.Enabled = false
+		}
+	}
+	if v := os.Getenv("NOODEXX_EMBEDDING_POOL_ENDPOINTS"); v != "" {
+		c.EmbeddingPool.Endpoints = nil
+		for _, endpoint := range strings.Split(v, ",") {
+			if endpoint = strings.TrimSpace(endpoint); endpoint != "" {
+				c.EmbeddingPool.Endpoints = append(c.EmbeddingPool.Endpoints, endpoint)
+			}
+		}
+	}
+	if v := os.Getenv("NOODEXX_MODEL_KEEP_ALIVE"); v != "" {
+		if v == "true" {
+			c.ModelWarmup.KeepAlive = true
+		} else if v == "false" {
+			c.ModelWarmup.KeepAlive = false
+		}
+	}
 }
 
 // Validate checks configuration validity
@@ -343,12 +1055,29 @@ func (c *Config) Validate() error {
 		if err := c.CloudProvider.ValidateCloud(); err != nil {
 			return fmt.Errorf("cloud provider validation failed: %w", err)
 		}
+		if _, err := c.CloudProvider.EmbeddingSource(c.LocalProvider.Type != ""); err != nil {
+			return fmt.Errorf("cloud provider validation failed: %w", err)
+		}
 	}
 
 	// Server validation
 	if c.Server.Port < 1024 && os.Geteuid() != 0 {
 		return fmt.Errorf("privileged port %d requires root", c.Server.Port)
 	}
+	if c.Server.MaxBodyKB < 1 {
+		return fmt.Errorf("server max_body_kb must be at least 1")
+	}
+	if c.Server.ReadTimeoutSeconds < 1 || c.Server.WriteTimeoutSeconds < 1 || c.Server.IdleTimeoutSeconds < 1 || c.Server.ShutdownTimeoutSeconds < 1 {
+		return fmt.Errorf("server read_timeout_seconds, write_timeout_seconds, idle_timeout_seconds and shutdown_timeout_seconds must be at least 1")
+	}
+	if c.Server.StreamTimeoutSeconds < 0 || c.Server.KeepAliveSeconds < 0 {
+		return fmt.Errorf("server stream_timeout_seconds and keep_alive_seconds must not be negative")
+	}
+	for route, seconds := range c.Server.RouteTimeouts {
+		if !strings.HasPrefix(route, "/") || seconds < 0 {
+			return fmt.Errorf("invalid server route timeout %q: %d (routes start with /, timeouts must not be negative)", route, seconds)
+		}
+	}
 
 	// Logging level validation
 	validLevels := map[string]bool{"debug": true, "info": true, "warn": true, "error": true}
@@ -362,22 +1091,66 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("invalid PII detection level: %s (must be strict, normal, or off)", c.Guardrails.PIIDetection)
 	}
 
+	// Generation bounds validation
+	if c.Guardrails.MaxTemperature < 0 || c.Guardrails.MaxTemperature > 2 {
+		return fmt.Errorf("invalid max_temperature: %g (must be between 0 and 2)", c.Guardrails.MaxTemperature)
+	}
+	if c.Guardrails.MaxOutputTokens < 1 {
+		return fmt.Errorf("invalid max_output_tokens: %d (must be at least 1)", c.Guardrails.MaxOutputTokens)
+	}
+	if c.Guardrails.QuarantineAfter < 1 {
+		return fmt.Errorf("invalid quarantine_after: %d (must be at least 1)", c.Guardrails.QuarantineAfter)
+	}
+	if c.Guardrails.DocQATokenBudget < 500 {
+		return fmt.Errorf("invalid doc_qa_token_budget: %d (must be at least 500)", c.Guardrails.DocQATokenBudget)
+	}
+	if c.Guardrails.SummaryRefreshMinutes < 0 {
+		return fmt.Errorf("invalid summary_refresh_minutes: %d (must not be negative)", c.Guardrails.SummaryRefreshMinutes)
+	}
+	if c.Guardrails.MaxSourceChunks < 0 || c.Guardrails.MaxSourceChars < 0 {
+		return fmt.Errorf("invalid source limits (max_source_chunks and max_source_chars must not be negative)")
+	}
+	if c.Guardrails.HistoryMessages < 1 {
+		return fmt.Errorf("invalid history_messages: %d (must be at least 1)", c.Guardrails.HistoryMessages)
+	}
+	if c.Guardrails.CompactIdleMinutes < 1 {
+		return fmt.Errorf("invalid compact_idle_minutes: %d (must be at least 1)", c.Guardrails.CompactIdleMinutes)
+	}
+	if c.Guardrails.ChunkSize < 50 {
+		return fmt.Errorf("invalid chunk_size: %d (must be at least 50)", c.Guardrails.ChunkSize)
+	}
+	if c.Guardrails.ChunkOverlap < 0 || c.Guardrails.ChunkOverlap >= c.Guardrails.ChunkSize {
+		return fmt.Errorf("invalid chunk_overlap: %d (must be between 0 and chunk_size)", c.Guardrails.ChunkOverlap)
+	}
+
 	// User mode validation
 	if c.UserMode != "single" && c.UserMode != "multi" {
 		return fmt.Errorf("invalid user_mode: %s (must be single or multi)", c.UserMode)
 	}
 
-	// Auth provider validation
-	validAuthProviders := map[string]bool{"userpass": true, "mfa": true, "sso": true}
-	if !validAuthProviders[c.Auth.Provider] {
-		return fmt.Errorf("invalid auth provider: %s (must be userpass, mfa, or sso)", c.Auth.Provider)
+	// Auth provider validation (providers can be added with auth.Register)
+	if !auth.IsRegistered(c.Auth.Provider) {
+		return fmt.Errorf("invalid auth provider: %s (must be one of %s)", c.Auth.Provider, strings.Join(auth.Providers(), ", "))
+	}
+
+	// Password policy validation
+	if _, err := pwpolicy.New(c.Auth.PasswordPolicy.Options()); err != nil {
+		return fmt.Errorf("invalid password policy: %w", err)
+	}
+
+	// Session lifetime validation (0 falls back to the defaults on load)
+	if c.Auth.RememberMeDays < 0 || c.Auth.MaxSessionDays < 0 {
+		return fmt.Errorf("invalid session lifetime: remember_me_days and max_session_days must not be negative")
+	}
+	if c.Auth.MaxSessionDays > 0 && (c.Auth.MaxSessionDays < c.Auth.SessionExpiryDays || c.Auth.MaxSessionDays < c.Auth.RememberMeDays) {
+		return fmt.Errorf("invalid max_session_days: %d (must be at least session_expiry_days and remember_me_days)", c.Auth.MaxSessionDays)
 	}
 
 	// Privacy mode validation
 	if c.Privacy.DefaultToLocal {
 		// When privacy mode is enabled (default to local), validate local provider
-		if c.LocalProvider.Type != "ollama" {
-			return fmt.Errorf("privacy mode requires local provider (Ollama), got %s", c.LocalProvider.Type)
+		if c.LocalProvider.Type != "ollama" && c.LocalProvider.Type != "builtin" {
+			return fmt.Errorf("privacy mode requires local provider (Ollama or builtin), got %s", c.LocalProvider.Type)
 		}
 
 		// Check that endpoint is localhost
@@ -394,16 +1167,242 @@ func (c *Config) Validate() error {
 		return err
 	}
 
+	// Branding color validation
+	if c.Branding.PrimaryColor != "" && !hexColorPattern.MatchString(c.Branding.PrimaryColor) {
+		return fmt.Errorf("invalid branding primary_color: %s (must be a hex color like #0f766e)", c.Branding.PrimaryColor)
+	}
+
+	// Database tuning validation
+	validSynchronous := map[string]bool{"OFF": true, "NORMAL": true, "FULL": true, "EXTRA": true}
+	if c.Database.Synchronous != "" && !validSynchronous[strings.ToUpper(c.Database.Synchronous)] {
+		return fmt.Errorf("invalid database synchronous mode: %s (must be OFF, NORMAL, FULL, or EXTRA)", c.Database.Synchronous)
+	}
+	if c.Database.CacheSizeKB < 0 {
+		return fmt.Errorf("invalid database cache_size_kb: %d (must not be negative)", c.Database.CacheSizeKB)
+	}
+	if c.Database.CheckpointIntervalMinutes < 0 {
+		return fmt.Errorf("invalid database checkpoint_interval_minutes: %d (must not be negative)", c.Database.CheckpointIntervalMinutes)
+	}
+	if c.Database.VectorSnapshotMinutes < 0 {
+		return fmt.Errorf("invalid database vector_snapshot_minutes: %d (must not be negative)", c.Database.VectorSnapshotMinutes)
+	}
+	if c.Database.ANNMinChunks < 0 || c.Database.ANNProbes < 0 || c.Database.ANNRebuildHours < 0 {
+		return fmt.Errorf("invalid database ANN settings: ann_min_chunks %d, ann_probes %d, ann_rebuild_hours %d (must not be negative)", c.Database.ANNMinChunks, c.Database.ANNProbes, c.Database.ANNRebuildHours)
+	}
+	if c.Database.WritePoolSize < 0 || c.Database.ReadPoolSize < 0 {
+		return fmt.Errorf("invalid database pool sizes: write_pool_size %d, read_pool_size %d (must not be negative)", c.Database.WritePoolSize, c.Database.ReadPoolSize)
+	}
+
+	// Wire log validation
+	if c.WireLog.Enabled && c.WireLog.File == "" {
+		return fmt.Errorf("wire_log file is required when the wire log is enabled")
+	}
+	if c.WireLog.MaxSizeMB < 0 || c.WireLog.MaxBackups < 0 {
+		return fmt.Errorf("invalid wire_log rotation settings (max_size_mb and max_backups must not be negative)")
+	}
+
+	// Transcript archive validation
+	if c.Transcripts.Enabled && c.Transcripts.KeyFile == "" {
+		return fmt.Errorf("transcripts key_file is required when the transcript archive is enabled")
+	}
+	if c.Transcripts.RetentionDays < 0 {
+		return fmt.Errorf("invalid transcripts retention_days (must not be negative)")
+	}
+
+	// Failed request replay validation
+	if c.Replay.RetentionDays < 0 {
+		return fmt.Errorf("invalid replay retention_days (must not be negative)")
+	}
+	for _, pattern := range c.Replay.RedactPatterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("invalid replay redact_patterns entry %q: %w", pattern, err)
+		}
+	}
+
+	// Web search validation
+	if c.WebSearch.Enabled {
+		switch c.WebSearch.Engine {
+		case "searxng":
+			if c.WebSearch.Endpoint == "" {
+				return fmt.Errorf("web_search endpoint is required for the searxng engine")
+			}
+		case "brave":
+			if c.WebSearch.APIKey == "" {
+				return fmt.Errorf("web_search api_key is required for the brave engine")
+			}
+		default:
+			return fmt.Errorf("invalid web_search engine: %s (must be 'searxng' or 'brave')", c.WebSearch.Engine)
+		}
+	}
+	if c.WebSearch.MaxResults < 0 || c.WebSearch.MaxCharsPerResult < 0 || c.WebSearch.TimeoutSeconds < 0 {
+		return fmt.Errorf("invalid web_search limits (max_results, max_chars_per_result and timeout_seconds must not be negative)")
+	}
+
+	// Embedding pool validation
+	if c.EmbeddingPool.Enabled {
+		if c.LocalProvider.Type != "ollama" {
+			return fmt.Errorf("embedding_pool requires an Ollama local provider, got %s", c.LocalProvider.Type)
+		}
+		if len(c.EmbeddingPool.Endpoints) == 0 {
+			return fmt.Errorf("embedding_pool endpoints are required when the pool is enabled")
+		}
+		for _, endpoint := range c.EmbeddingPool.Endpoints {
+			if !strings.HasPrefix(endpoint, "http://") && !strings.HasPrefix(endpoint, "https://") {
+				return fmt.Errorf("invalid embedding_pool endpoint: %s (must be an http or https URL)", endpoint)
+			}
+		}
+	}
+	if c.EmbeddingPool.Concurrency < 0 || c.EmbeddingPool.MaxAttempts < 0 || c.EmbeddingPool.FailureThreshold < 0 || c.EmbeddingPool.CooldownSeconds < 0 {
+		return fmt.Errorf("invalid embedding_pool limits (concurrency, max_attempts, failure_threshold and cooldown_seconds must not be negative)")
+	}
+
+	// Provider queue validation
+	if c.ProviderQueue.MaxConcurrent < 1 || c.ProviderQueue.MaxPerUser < 1 || c.ProviderQueue.KeepAliveSeconds < 1 {
+		return fmt.Errorf("invalid provider_queue limits (max_concurrent, max_per_user and keep_alive_seconds must be at least 1)")
+	}
+	if c.ProviderQueue.MaxPerUser > c.ProviderQueue.MaxConcurrent {
+		return fmt.Errorf("provider_queue max_per_user (%d) cannot exceed max_concurrent (%d)", c.ProviderQueue.MaxPerUser, c.ProviderQueue.MaxConcurrent)
+	}
+
+	// Skill limit validation
+	if c.Skills.MaxRuntimeSeconds < 1 || c.Skills.MaxConcurrent < 1 || c.Skills.MaxPerUser < 1 ||
+		c.Skills.MaxOutputKB < 1 || c.Skills.QueueTimeoutSeconds < 1 {
+		return fmt.Errorf("invalid skills limits (max_runtime_seconds, max_concurrent, max_per_user, max_output_kb and queue_timeout_seconds must be at least 1)")
+	}
+	if c.Skills.MaxPerUser > c.Skills.MaxConcurrent {
+		return fmt.Errorf("skills max_per_user (%d) cannot exceed max_concurrent (%d)", c.Skills.MaxPerUser, c.Skills.MaxConcurrent)
+	}
+
+	// Feature flag validation
+	for name, flag := range c.Features {
+		if err := flag.Validate(name); err != nil {
+			return err
+		}
+	}
+
+	// Scheduler validation
+	if c.Scheduler.JitterSeconds < 0 {
+		return fmt.Errorf("invalid scheduler jitter_seconds: %d (must not be negative)", c.Scheduler.JitterSeconds)
+	}
+	for name, spec := range c.Scheduler.Jobs {
+		if _, err := scheduler.Parse(spec); err != nil {
+			return fmt.Errorf("invalid scheduler schedule for job %s: %w", name, err)
+		}
+	}
+
+	// Cluster validation
+	if c.Cluster.Enabled {
+		if c.Cluster.LockTTLSeconds < 3 {
+			return fmt.Errorf("invalid cluster lock_ttl_seconds: %d (must be at least 3)", c.Cluster.LockTTLSeconds)
+		}
+		if c.Cluster.PollIntervalMS < 100 || c.Cluster.PollIntervalMS > c.Cluster.LockTTLSeconds*1000 {
+			return fmt.Errorf("invalid cluster poll_interval_ms: %d (must be between 100 and lock_ttl_seconds)", c.Cluster.PollIntervalMS)
+		}
+	}
+
+	// Telemetry validation
+	if c.Telemetry.Enabled {
+		u, err := url.Parse(c.Telemetry.Endpoint)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid telemetry endpoint: %q (must be an http or https URL)", c.Telemetry.Endpoint)
+		}
+	}
+
+	// Webhook validation
+	for _, hook := range c.Webhooks {
+		u, err := url.Parse(hook.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid webhook url: %q (must be an http or https URL)", hook.URL)
+		}
+		for _, topic := range hook.Topics {
+			if !events.Topic(topic).Valid() {
+				return fmt.Errorf("invalid webhook topic: %q (must be one of %v)", topic, events.Topics)
+			}
+		}
+	}
+
+	// Service validation
+	if !serviceNamePattern.MatchString(c.Service.Name) {
+		return fmt.Errorf("invalid service name: %q (letters, digits, '-', '_' and '.' only)", c.Service.Name)
+	}
+
+	// Startup validation
+	if c.Startup.MaxWaitSeconds < 1 || c.Startup.MaxBackoffSeconds < 1 {
+		return fmt.Errorf("invalid startup timing (max_wait_seconds and max_backoff_seconds must be at least 1)")
+	}
+
+	// Search validation
+	if c.Search.Diversify && (c.Search.MMRLambda <= 0 || c.Search.MMRLambda > 1) {
+		return fmt.Errorf("invalid search mmr_lambda: %v (must be greater than 0 and at most 1)", c.Search.MMRLambda)
+	}
+	if c.Search.TitleWeight < 0 || c.Search.TitleWeight > 1 {
+		return fmt.Errorf("invalid search title_weight: %v (must be between 0 and 1)", c.Search.TitleWeight)
+	}
+	if c.Search.PinBoost < 1 {
+		return fmt.Errorf("invalid search pin_boost: %v (must be at least 1)", c.Search.PinBoost)
+	}
+	if c.Search.Mode != "vector" && c.Search.Mode != "keyword" && c.Search.Mode != "hybrid" {
+		return fmt.Errorf("invalid search mode: %s (must be vector, keyword or hybrid)", c.Search.Mode)
+	}
+	if c.Search.Fusion != "rrf" && c.Search.Fusion != "weighted" {
+		return fmt.Errorf("invalid search fusion: %s (must be rrf or weighted)", c.Search.Fusion)
+	}
+	if c.Search.KeywordWeight < 0 || c.Search.KeywordWeight > 1 {
+		return fmt.Errorf("invalid search keyword_weight: %v (must be between 0 and 1)", c.Search.KeywordWeight)
+	}
+
+	// Watcher validation
+	if c.Watcher.StableSeconds < 0 || c.Watcher.ReadRetries < 0 {
+		return fmt.Errorf("invalid watcher settings (stable_seconds and read_retries cannot be negative)")
+	}
+	if c.Watcher.MaxWaitSeconds < c.Watcher.StableSeconds {
+		return fmt.Errorf("invalid watcher max_wait_seconds: %d (must be at least stable_seconds)", c.Watcher.MaxWaitSeconds)
+	}
+	for _, folder := range c.Watcher.Folders {
+		if folder.Path == "" {
+			return fmt.Errorf("invalid watcher folder: path is required")
+		}
+		if folder.StableSeconds < 0 || folder.MaxWaitSeconds < 0 || folder.ReadRetries < 0 || folder.PollSeconds < 0 {
+			return fmt.Errorf("invalid watcher settings for %s (values cannot be negative)", folder.Path)
+		}
+	}
+
+	// Extractors validation
+	if c.Extractors.MaxRuntimeSeconds < 1 || c.Extractors.MaxOutputMB < 1 {
+		return fmt.Errorf("invalid extractors limits (max_runtime_seconds and max_output_mb must be at least 1)")
+	}
+
+	// Costs validation
+	if c.Costs.ConfirmAboveUSD < 0 || c.Costs.CompletionTokens < 1 {
+		return fmt.Errorf("invalid costs settings (confirm_above_usd cannot be negative and completion_tokens must be at least 1)")
+	}
+	for model, price := range c.Costs.Prices {
+		if price.InputPerMillion < 0 || price.OutputPerMillion < 0 {
+			return fmt.Errorf("invalid price for model %s (prices cannot be negative)", model)
+		}
+	}
+
+	// Model warm-up validation
+	if c.ModelWarmup.PingIntervalSeconds < 1 || c.ModelWarmup.IdleWindowMinutes < 1 {
+		return fmt.Errorf("invalid model_warmup timing (ping_interval_seconds and idle_window_minutes must be at least 1)")
+	}
+
 	return nil
 }
 
-// ValidateLocal validates local provider (Ollama) configuration
+// ValidateLocal validates local provider (Ollama or builtin) configuration
 func (p *ProviderConfig) ValidateLocal() error {
 	if p.Type == "" {
 		return nil // Not configured is valid
 	}
+	if p.Type == "builtin" {
+		if p.BuiltinModelPath == "" || p.BuiltinVocabPath == "" {
+			return fmt.Errorf("builtin model and vocabulary paths are required")
+		}
+		return nil
+	}
 	if p.Type != "ollama" {
-		return fmt.Errorf("local provider must be Ollama")
+		return fmt.Errorf("local provider must be Ollama or builtin")
 	}
 	if p.OllamaEndpoint == "" {
 		return fmt.Errorf("Ollama endpoint is required")
@@ -444,6 +1443,57 @@ func (p *ProviderConfig) ValidateCloud() error {
 	return nil
 }
 
+// Embedding fallback sources for providers without an embeddings API
+const (
+	EmbedWithLocal  = "local"  // The local provider's embeddings
+	EmbedWithOllama = "ollama" // This provider's ollama_endpoint and ollama_embed_model
+	EmbedWithOpenAI = "openai" // This provider's openai_key and openai_embed_model
+)
+
+// DefaultEmbeddingFallback is used when a provider without embeddings sets no fallback
+var DefaultEmbeddingFallback = []string{EmbedWithLocal}
+
+// HasEmbeddings reports whether the provider type has its own embeddings API
+func (p *ProviderConfig) HasEmbeddings() bool {
+	return p.Type != "anthropic"
+}
+
+// EmbeddingSource returns where the provider's embeddings come from
+// It is empty when the provider embeds itself, otherwise the first entry of
+// EmbeddingFallback that is configured; an error means no embedder can be resolved
+func (p *ProviderConfig) EmbeddingSource(localConfigured bool) (string, error) {
+	for _, source := range p.EmbeddingFallback {
+		if source != EmbedWithLocal && source != EmbedWithOllama && source != EmbedWithOpenAI {
+			return "", fmt.Errorf("invalid embedding_fallback entry: %s (must be local, ollama or openai)", source)
+		}
+	}
+	if p.Type == "" || p.HasEmbeddings() {
+		return "", nil
+	}
+
+	chain := p.EmbeddingFallback
+	if len(chain) == 0 {
+		chain = DefaultEmbeddingFallback
+	}
+	for _, source := range chain {
+		switch source {
+		case EmbedWithLocal:
+			if localConfigured {
+				return source, nil
+			}
+		case EmbedWithOllama:
+			if p.OllamaEndpoint != "" && p.OllamaEmbedModel != "" {
+				return source, nil
+			}
+		case EmbedWithOpenAI:
+			if p.OpenAIKey != "" && p.OpenAIEmbedModel != "" {
+				return source, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("%s has no embeddings API and no embedding_fallback entry is configured (tried %s)", p.Type, strings.Join(chain, ", "))
+}
+
 // ValidateRAGPolicy validates RAG policy configuration
 func (p *PrivacyConfig) ValidateRAGPolicy() error {
 	// Empty is valid (will be defaulted)
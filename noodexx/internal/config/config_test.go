@@ -6,6 +6,17 @@ import (
 	"testing"
 )
 
+// defaultConfig returns the configuration Load writes when there is none, which
+// passes Validate, for a test to change
+func defaultConfig(t *testing.T) *Config {
+	t.Helper()
+	cfg, err := Load(filepath.Join(t.TempDir(), "config.json"))
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	return cfg
+}
+
 func TestLoad_DefaultConfig(t *testing.T) {
 	// Create a temporary directory for testing
 	tmpDir := t.TempDir()
@@ -18,8 +29,8 @@ func TestLoad_DefaultConfig(t *testing.T) {
 	}
 
 	// Verify defaults
-	if cfg.Provider.Type != "ollama" {
-		t.Errorf("Expected provider type 'ollama', got '%s'", cfg.Provider.Type)
+	if cfg.LocalProvider.Type != "ollama" {
+		t.Errorf("Expected local provider type 'ollama', got '%s'", cfg.LocalProvider.Type)
 	}
 	if cfg.Privacy.DefaultToLocal != true {
 		t.Errorf("Expected default_to_local enabled by default")
@@ -61,7 +72,7 @@ func TestLoad_ExistingConfig(t *testing.T) {
 
 	// Create a custom config
 	customCfg := &Config{
-		Provider: ProviderConfig{
+		LocalProvider: ProviderConfig{
 			Type:             "ollama",
 			OllamaEndpoint:   "http://localhost:11434",
 			OllamaEmbedModel: "custom-model",
@@ -104,8 +115,8 @@ func TestLoad_ExistingConfig(t *testing.T) {
 	}
 
 	// Verify custom values
-	if cfg.Provider.OllamaEmbedModel != "custom-model" {
-		t.Errorf("Expected embed model 'custom-model', got '%s'", cfg.Provider.OllamaEmbedModel)
+	if cfg.LocalProvider.OllamaEmbedModel != "custom-model" {
+		t.Errorf("Expected embed model 'custom-model', got '%s'", cfg.LocalProvider.OllamaEmbedModel)
 	}
 	if cfg.Logging.Level != "debug" {
 		t.Errorf("Expected log level 'debug', got '%s'", cfg.Logging.Level)
@@ -124,16 +135,20 @@ func TestEnvOverrides(t *testing.T) {
 	configPath := filepath.Join(tmpDir, "config.json")
 
 	// Set environment variables
-	os.Setenv("NOODEXX_PROVIDER", "openai")
+	os.Setenv("NOODEXX_CLOUD_PROVIDER_TYPE", "openai")
 	os.Setenv("NOODEXX_OPENAI_KEY", "test-key")
+	os.Setenv("NOODEXX_OPENAI_EMBED_MODEL", "text-embedding-3-small")
+	os.Setenv("NOODEXX_OPENAI_CHAT_MODEL", "gpt-4")
 	os.Setenv("NOODEXX_PRIVACY_MODE", "false")
 	os.Setenv("NOODEXX_LOG_LEVEL", "debug")
 	os.Setenv("NOODEXX_DEBUG_ENABLED", "false")
 	os.Setenv("NOODEXX_LOG_FILE", "custom.log")
 	os.Setenv("NOODEXX_SERVER_PORT", "9000")
 	defer func() {
-		os.Unsetenv("NOODEXX_PROVIDER")
+		os.Unsetenv("NOODEXX_CLOUD_PROVIDER_TYPE")
 		os.Unsetenv("NOODEXX_OPENAI_KEY")
+		os.Unsetenv("NOODEXX_OPENAI_EMBED_MODEL")
+		os.Unsetenv("NOODEXX_OPENAI_CHAT_MODEL")
 		os.Unsetenv("NOODEXX_PRIVACY_MODE")
 		os.Unsetenv("NOODEXX_LOG_LEVEL")
 		os.Unsetenv("NOODEXX_DEBUG_ENABLED")
@@ -148,11 +163,11 @@ func TestEnvOverrides(t *testing.T) {
 	}
 
 	// Verify environment overrides
-	if cfg.Provider.Type != "openai" {
-		t.Errorf("Expected provider type 'openai', got '%s'", cfg.Provider.Type)
+	if cfg.CloudProvider.Type != "openai" {
+		t.Errorf("Expected cloud provider type 'openai', got '%s'", cfg.CloudProvider.Type)
 	}
-	if cfg.Provider.OpenAIKey != "test-key" {
-		t.Errorf("Expected OpenAI key 'test-key', got '%s'", cfg.Provider.OpenAIKey)
+	if cfg.CloudProvider.OpenAIKey != "test-key" {
+		t.Errorf("Expected OpenAI key 'test-key', got '%s'", cfg.CloudProvider.OpenAIKey)
 	}
 	if cfg.Logging.Level != "debug" {
 		t.Errorf("Expected log level 'debug', got '%s'", cfg.Logging.Level)
@@ -171,69 +186,34 @@ func TestEnvOverrides(t *testing.T) {
 func TestValidate_PrivacyMode(t *testing.T) {
 	tests := []struct {
 		name        string
-		cfg         *Config
+		local       ProviderConfig
 		expectError bool
 	}{
 		{
 			name: "Valid privacy mode with Ollama",
-			cfg: &Config{
-				Provider: ProviderConfig{
-					Type:           "ollama",
-					OllamaEndpoint: "http://localhost:11434",
-				},
-				Privacy:    PrivacyConfig{DefaultToLocal: true},
-				Logging:    LoggingConfig{Level: "info"},
-				Guardrails: GuardrailsConfig{PIIDetection: "normal"},
-				Server:     ServerConfig{Port: 8080},
-				UserMode:   "single",
-				Auth: AuthConfig{
-					Provider:               "userpass",
-					SessionExpiryDays:      7,
-					LockoutThreshold:       5,
-					LockoutDurationMinutes: 15,
-				},
+			local: ProviderConfig{
+				Type:             "ollama",
+				OllamaEndpoint:   "http://localhost:11434",
+				OllamaEmbedModel: "nomic-embed-text",
+				OllamaChatModel:  "llama3.2",
 			},
 			expectError: false,
 		},
 		{
 			name: "Invalid privacy mode with OpenAI",
-			cfg: &Config{
-				Provider: ProviderConfig{
-					Type:      "openai",
-					OpenAIKey: "test-key",
-				},
-				Privacy:    PrivacyConfig{DefaultToLocal: true},
-				Logging:    LoggingConfig{Level: "info"},
-				Guardrails: GuardrailsConfig{PIIDetection: "normal"},
-				Server:     ServerConfig{Port: 8080},
-				UserMode:   "single",
-				Auth: AuthConfig{
-					Provider:               "userpass",
-					SessionExpiryDays:      7,
-					LockoutThreshold:       5,
-					LockoutDurationMinutes: 15,
-				},
+			local: ProviderConfig{
+				Type:      "openai",
+				OpenAIKey: "test-key",
 			},
 			expectError: true,
 		},
 		{
 			name: "Invalid privacy mode with non-localhost Ollama",
-			cfg: &Config{
-				Provider: ProviderConfig{
-					Type:           "ollama",
-					OllamaEndpoint: "http://192.168.1.100:11434",
-				},
-				Privacy:    PrivacyConfig{DefaultToLocal: true},
-				Logging:    LoggingConfig{Level: "info"},
-				Guardrails: GuardrailsConfig{PIIDetection: "normal"},
-				Server:     ServerConfig{Port: 8080},
-				UserMode:   "single",
-				Auth: AuthConfig{
-					Provider:               "userpass",
-					SessionExpiryDays:      7,
-					LockoutThreshold:       5,
-					LockoutDurationMinutes: 15,
-				},
+			local: ProviderConfig{
+				Type:             "ollama",
+				OllamaEndpoint:   "http://192.168.1.100:11434",
+				OllamaEmbedModel: "nomic-embed-text",
+				OllamaChatModel:  "llama3.2",
 			},
 			expectError: true,
 		},
@@ -241,7 +221,9 @@ func TestValidate_PrivacyMode(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			err := tt.cfg.Validate()
+			cfg := defaultConfig(t)
+			cfg.LocalProvider = tt.local
+			err := cfg.Validate()
 			if tt.expectError && err == nil {
 				t.Error("Expected validation error, got nil")
 			}
@@ -255,66 +237,27 @@ func TestValidate_PrivacyMode(t *testing.T) {
 func TestValidate_ProviderRequirements(t *testing.T) {
 	tests := []struct {
 		name        string
-		cfg         *Config
+		cloud       ProviderConfig
 		expectError bool
 	}{
 		{
 			name: "OpenAI without API key",
-			cfg: &Config{
-				Provider: ProviderConfig{
-					Type: "openai",
-				},
-				Privacy:    PrivacyConfig{DefaultToLocal: false},
-				Logging:    LoggingConfig{Level: "info"},
-				Guardrails: GuardrailsConfig{PIIDetection: "normal"},
-				Server:     ServerConfig{Port: 8080},
-				UserMode:   "single",
-				Auth: AuthConfig{
-					Provider:               "userpass",
-					SessionExpiryDays:      7,
-					LockoutThreshold:       5,
-					LockoutDurationMinutes: 15,
-				},
+			cloud: ProviderConfig{
+				Type: "openai",
 			},
 			expectError: true,
 		},
 		{
 			name: "Anthropic without API key",
-			cfg: &Config{
-				Provider: ProviderConfig{
-					Type: "anthropic",
-				},
-				Privacy:    PrivacyConfig{DefaultToLocal: false},
-				Logging:    LoggingConfig{Level: "info"},
-				Guardrails: GuardrailsConfig{PIIDetection: "normal"},
-				Server:     ServerConfig{Port: 8080},
-				UserMode:   "single",
-				Auth: AuthConfig{
-					Provider:               "userpass",
-					SessionExpiryDays:      7,
-					LockoutThreshold:       5,
-					LockoutDurationMinutes: 15,
-				},
+			cloud: ProviderConfig{
+				Type: "anthropic",
 			},
 			expectError: true,
 		},
 		{
 			name: "Unknown provider type",
-			cfg: &Config{
-				Provider: ProviderConfig{
-					Type: "unknown",
-				},
-				Privacy:    PrivacyConfig{DefaultToLocal: false},
-				Logging:    LoggingConfig{Level: "info"},
-				Guardrails: GuardrailsConfig{PIIDetection: "normal"},
-				Server:     ServerConfig{Port: 8080},
-				UserMode:   "single",
-				Auth: AuthConfig{
-					Provider:               "userpass",
-					SessionExpiryDays:      7,
-					LockoutThreshold:       5,
-					LockoutDurationMinutes: 15,
-				},
+			cloud: ProviderConfig{
+				Type: "unknown",
 			},
 			expectError: true,
 		},
@@ -322,7 +265,9 @@ func TestValidate_ProviderRequirements(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			err := tt.cfg.Validate()
+			cfg := defaultConfig(t)
+			cfg.CloudProvider = tt.cloud
+			err := cfg.Validate()
 			if tt.expectError && err == nil {
 				t.Error("Expected validation error, got nil")
 			}
@@ -338,10 +283,11 @@ func TestSave(t *testing.T) {
 	configPath := filepath.Join(tmpDir, "config.json")
 
 	cfg := &Config{
-		Provider: ProviderConfig{
+		LocalProvider: ProviderConfig{
 			Type:             "ollama",
 			OllamaEndpoint:   "http://localhost:11434",
 			OllamaEmbedModel: "test-model",
+			OllamaChatModel:  "llama3.2",
 		},
 		Privacy: PrivacyConfig{DefaultToLocal: true},
 		Folders: []string{"/test/path"},
@@ -382,8 +328,8 @@ func TestSave(t *testing.T) {
 		t.Fatalf("Load() failed: %v", err)
 	}
 
-	if loadedCfg.Provider.OllamaEmbedModel != "test-model" {
-		t.Errorf("Expected embed model 'test-model', got '%s'", loadedCfg.Provider.OllamaEmbedModel)
+	if loadedCfg.LocalProvider.OllamaEmbedModel != "test-model" {
+		t.Errorf("Expected embed model 'test-model', got '%s'", loadedCfg.LocalProvider.OllamaEmbedModel)
 	}
 	if len(loadedCfg.Folders) != 1 || loadedCfg.Folders[0] != "/test/path" {
 		t.Errorf("Expected folders ['/test/path'], got %v", loadedCfg.Folders)
@@ -397,7 +343,7 @@ func TestBackwardCompatibility_MissingDebugEnabled(t *testing.T) {
 
 	// Create a config file without debug_enabled field (simulating old config)
 	oldConfigJSON := `{
-		"provider": {
+		"local_provider": {
 			"type": "ollama",
 			"ollama_endpoint": "http://localhost:11434",
 			"ollama_embed_model": "nomic-embed-text",
@@ -467,28 +413,8 @@ func TestValidate_LogLevel(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			cfg := &Config{
-				Provider: ProviderConfig{
-					Type:           "ollama",
-					OllamaEndpoint: "http://localhost:11434",
-				},
-				Privacy: PrivacyConfig{DefaultToLocal: true},
-				Logging: LoggingConfig{
-					Level:      tt.level,
-					File:       "debug.log",
-					MaxSizeMB:  10,
-					MaxBackups: 3,
-				},
-				Guardrails: GuardrailsConfig{PIIDetection: "normal"},
-				Server:     ServerConfig{Port: 8080},
-				UserMode:   "single",
-				Auth: AuthConfig{
-					Provider:               "userpass",
-					SessionExpiryDays:      7,
-					LockoutThreshold:       5,
-					LockoutDurationMinutes: 15,
-				},
-			}
+			cfg := defaultConfig(t)
+			cfg.Logging.Level = tt.level
 
 			err := cfg.Validate()
 			if tt.expectError && err == nil {
@@ -574,23 +500,8 @@ func TestValidate_UserMode(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			cfg := &Config{
-				Provider: ProviderConfig{
-					Type:           "ollama",
-					OllamaEndpoint: "http://localhost:11434",
-				},
-				Privacy:    PrivacyConfig{DefaultToLocal: true},
-				Logging:    LoggingConfig{Level: "info"},
-				Guardrails: GuardrailsConfig{PIIDetection: "normal"},
-				Server:     ServerConfig{Port: 8080},
-				UserMode:   tt.userMode,
-				Auth: AuthConfig{
-					Provider:               "userpass",
-					SessionExpiryDays:      7,
-					LockoutThreshold:       5,
-					LockoutDurationMinutes: 15,
-				},
-			}
+			cfg := defaultConfig(t)
+			cfg.UserMode = tt.userMode
 
 			err := cfg.Validate()
 			if tt.expectError && err == nil {
@@ -618,23 +529,8 @@ func TestValidate_AuthProvider(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			cfg := &Config{
-				Provider: ProviderConfig{
-					Type:           "ollama",
-					OllamaEndpoint: "http://localhost:11434",
-				},
-				Privacy:    PrivacyConfig{DefaultToLocal: true},
-				Logging:    LoggingConfig{Level: "info"},
-				Guardrails: GuardrailsConfig{PIIDetection: "normal"},
-				Server:     ServerConfig{Port: 8080},
-				UserMode:   "single",
-				Auth: AuthConfig{
-					Provider:               tt.provider,
-					SessionExpiryDays:      7,
-					LockoutThreshold:       5,
-					LockoutDurationMinutes: 15,
-				},
-			}
+			cfg := defaultConfig(t)
+			cfg.Auth.Provider = tt.provider
 
 			err := cfg.Validate()
 			if tt.expectError && err == nil {
@@ -690,7 +586,7 @@ func TestProviderConfig_ValidateLocal(t *testing.T) {
 				OpenAIChatModel:  "gpt-4",
 			},
 			expectError: true,
-			errorMsg:    "local provider must be Ollama",
+			errorMsg:    "local provider must be Ollama or builtin",
 		},
 		{
 			name: "Missing Ollama endpoint",
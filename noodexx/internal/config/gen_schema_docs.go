@@ -0,0 +1,96 @@
+//go:build ignore
+
+// gen_schema_docs collects the comments of the config structs and their fields
+// into schema_docs.go, where Schema uses them as descriptions. Run it with
+// go generate after changing a config struct
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"log"
+	"os"
+	"sort"
+	"strings"
+)
+
+func main() {
+	out := flag.String("o", "schema_docs.go", "Output file")
+	flag.Parse()
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "config.go", nil, parser.ParseComments)
+	if err != nil {
+		log.Fatalf("Failed to parse config.go: %v", err)
+	}
+
+	docs := make(map[string]string)
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			typeSpec := spec.(*ast.TypeSpec)
+			name := typeSpec.Name.Name
+			if doc := commentText(gen.Doc); doc != "" {
+				// "FooConfig controls ..." becomes "Controls ..."
+				doc = strings.TrimSpace(strings.TrimPrefix(doc, name))
+				docs[name] = strings.ToUpper(doc[:1]) + doc[1:]
+			}
+			structType, ok := typeSpec.Type.(*ast.StructType)
+			if !ok {
+				continue
+			}
+			for _, field := range structType.Fields.List {
+				doc := commentText(field.Comment)
+				if doc == "" {
+					doc = commentText(field.Doc)
+				}
+				if doc == "" {
+					continue
+				}
+				for _, fieldName := range field.Names {
+					docs[name+"."+fieldName.Name] = doc
+				}
+			}
+		}
+	}
+
+	keys := make([]string, 0, len(docs))
+	for key := range docs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var buf bytes.Buffer
+	buf.WriteString("// Code generated by gen_schema_docs.go from the comments in config.go; DO NOT EDIT.\n\n")
+	buf.WriteString("package config\n\n")
+	buf.WriteString("// fieldDocs describes config types (by name) and fields (by Type.Field)\n")
+	buf.WriteString("var fieldDocs = map[string]string{\n")
+	for _, key := range keys {
+		fmt.Fprintf(&buf, "\t%q: %q,\n", key, docs[key])
+	}
+	buf.WriteString("}\n")
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		log.Fatalf("Failed to format output: %v", err)
+	}
+	if err := os.WriteFile(*out, src, 0644); err != nil {
+		log.Fatalf("Failed to write %s: %v", *out, err)
+	}
+}
+
+// commentText joins the lines of a comment group into one sentence-like string
+func commentText(group *ast.CommentGroup) string {
+	if group == nil {
+		return ""
+	}
+	return strings.Join(strings.Fields(group.Text()), " ")
+}
@@ -7,14 +7,14 @@ import (
 	"testing"
 )
 
-// TestMigration_OllamaToLocalProvider tests migration from old Ollama config to new dual-provider format
+// TestMigration_OllamaToLocalProvider tests that an Ollama config with the old privacy field migrates to the new privacy settings
 func TestMigration_OllamaToLocalProvider(t *testing.T) {
 	tmpDir := t.TempDir()
 	configPath := filepath.Join(tmpDir, "config.json")
 
-	// Create an old-style config with Ollama provider
+	// Create a config with the old privacy field and an Ollama provider
 	oldConfig := `{
-		"provider": {
+		"local_provider": {
 			"type": "ollama",
 			"ollama_endpoint": "http://localhost:11434",
 			"ollama_embed_model": "custom-embed",
@@ -74,14 +74,20 @@ func TestMigration_OllamaToLocalProvider(t *testing.T) {
 	}
 }
 
-// TestMigration_OpenAIToCloudProvider tests migration from old OpenAI config to new dual-provider format
+// TestMigration_OpenAIToCloudProvider tests that an OpenAI config with the old privacy field migrates to the new privacy settings
 func TestMigration_OpenAIToCloudProvider(t *testing.T) {
 	tmpDir := t.TempDir()
 	configPath := filepath.Join(tmpDir, "config.json")
 
-	// Create an old-style config with OpenAI provider
+	// Create a config with the old privacy field and an OpenAI provider
 	oldConfig := `{
-		"provider": {
+		"local_provider": {
+			"type": "ollama",
+			"ollama_endpoint": "http://localhost:11434",
+			"ollama_embed_model": "nomic-embed-text",
+			"ollama_chat_model": "llama3.2"
+		},
+		"cloud_provider": {
 			"type": "openai",
 			"openai_key": "sk-test-key",
 			"openai_embed_model": "text-embedding-3-small",
@@ -127,9 +133,9 @@ func TestMigration_OpenAIToCloudProvider(t *testing.T) {
 		t.Errorf("Expected CloudProvider.OpenAIChatModel 'gpt-4', got '%s'", cfg.CloudProvider.OpenAIChatModel)
 	}
 
-	// Verify LocalProvider has defaults
+	// Verify LocalProvider is kept
 	if cfg.LocalProvider.Type != "ollama" {
-		t.Errorf("Expected LocalProvider.Type 'ollama' (default), got '%s'", cfg.LocalProvider.Type)
+		t.Errorf("Expected LocalProvider.Type 'ollama', got '%s'", cfg.LocalProvider.Type)
 	}
 
 	// Verify privacy settings migrated
@@ -141,14 +147,20 @@ func TestMigration_OpenAIToCloudProvider(t *testing.T) {
 	}
 }
 
-// TestMigration_AnthropicToCloudProvider tests migration from old Anthropic config to new dual-provider format
+// TestMigration_AnthropicToCloudProvider tests that an Anthropic config with the old privacy field migrates to the new privacy settings
 func TestMigration_AnthropicToCloudProvider(t *testing.T) {
 	tmpDir := t.TempDir()
 	configPath := filepath.Join(tmpDir, "config.json")
 
-	// Create an old-style config with Anthropic provider
+	// Create a config with the old privacy field and an Anthropic provider
 	oldConfig := `{
-		"provider": {
+		"local_provider": {
+			"type": "ollama",
+			"ollama_endpoint": "http://localhost:11434",
+			"ollama_embed_model": "nomic-embed-text",
+			"ollama_chat_model": "llama3.2"
+		},
+		"cloud_provider": {
 			"type": "anthropic",
 			"anthropic_key": "sk-ant-test-key",
 			"anthropic_chat_model": "claude-3-opus-20240229"
@@ -190,9 +202,9 @@ func TestMigration_AnthropicToCloudProvider(t *testing.T) {
 		t.Errorf("Expected CloudProvider.AnthropicChatModel 'claude-3-opus-20240229', got '%s'", cfg.CloudProvider.AnthropicChatModel)
 	}
 
-	// Verify LocalProvider has defaults
+	// Verify LocalProvider is kept
 	if cfg.LocalProvider.Type != "ollama" {
-		t.Errorf("Expected LocalProvider.Type 'ollama' (default), got '%s'", cfg.LocalProvider.Type)
+		t.Errorf("Expected LocalProvider.Type 'ollama', got '%s'", cfg.LocalProvider.Type)
 	}
 
 	// Verify privacy settings migrated
@@ -211,12 +223,6 @@ func TestMigration_NewConfigNotMigrated(t *testing.T) {
 
 	// Create a new-style config with both providers
 	newConfig := `{
-		"provider": {
-			"type": "ollama",
-			"ollama_endpoint": "http://localhost:11434",
-			"ollama_embed_model": "old-embed",
-			"ollama_chat_model": "old-chat"
-		},
 		"local_provider": {
 			"type": "ollama",
 			"ollama_endpoint": "http://localhost:11434",
@@ -276,9 +282,9 @@ func TestMigration_RoundTrip(t *testing.T) {
 	tmpDir := t.TempDir()
 	configPath := filepath.Join(tmpDir, "config.json")
 
-	// Create an old-style config
+	// Create a config with the old privacy field
 	oldConfig := `{
-		"provider": {
+		"local_provider": {
 			"type": "ollama",
 			"ollama_endpoint": "http://localhost:11434",
 			"ollama_embed_model": "test-embed",
@@ -15,12 +15,6 @@ func TestSettingsPersistence_CompleteFlow(t *testing.T) {
 	// Step 1: Configure all settings
 	t.Log("Step 1: Configuring all settings")
 	cfg := &Config{
-		Provider: ProviderConfig{
-			Type:             "ollama",
-			OllamaEndpoint:   "http://localhost:11434",
-			OllamaEmbedModel: "nomic-embed-text",
-			OllamaChatModel:  "llama3.2",
-		},
 		LocalProvider: ProviderConfig{
 			Type:             "ollama",
 			OllamaEndpoint:   "http://localhost:11434",
@@ -213,12 +207,6 @@ func TestSettingsPersistence_AnthropicProvider(t *testing.T) {
 
 	// Configure with Anthropic as cloud provider
 	cfg := &Config{
-		Provider: ProviderConfig{
-			Type:             "ollama",
-			OllamaEndpoint:   "http://127.0.0.1:11434",
-			OllamaEmbedModel: "mxbai-embed-large",
-			OllamaChatModel:  "llama3.1",
-		},
 		LocalProvider: ProviderConfig{
 			Type:             "ollama",
 			OllamaEndpoint:   "http://127.0.0.1:11434",
@@ -290,12 +278,6 @@ func TestSettingsPersistence_MultipleReloads(t *testing.T) {
 
 	// Initial configuration
 	cfg1 := &Config{
-		Provider: ProviderConfig{
-			Type:             "ollama",
-			OllamaEndpoint:   "http://localhost:11434",
-			OllamaEmbedModel: "model-v1",
-			OllamaChatModel:  "chat-v1",
-		},
 		LocalProvider: ProviderConfig{
 			Type:             "ollama",
 			OllamaEndpoint:   "http://localhost:11434",
@@ -383,12 +365,6 @@ func TestSettingsPersistence_EmptyProviders(t *testing.T) {
 
 	// Configuration with only local provider configured (cloud provider empty)
 	cfg := &Config{
-		Provider: ProviderConfig{
-			Type:             "ollama",
-			OllamaEndpoint:   "http://localhost:11434",
-			OllamaEmbedModel: "nomic-embed-text",
-			OllamaChatModel:  "llama3.2",
-		},
 		LocalProvider: ProviderConfig{
 			Type:             "ollama",
 			OllamaEndpoint:   "http://localhost:11434",
@@ -460,12 +436,6 @@ func TestSettingsPersistence_LoadTime(t *testing.T) {
 
 	// Create a configuration with all fields populated
 	cfg := &Config{
-		Provider: ProviderConfig{
-			Type:             "ollama",
-			OllamaEndpoint:   "http://localhost:11434",
-			OllamaEmbedModel: "nomic-embed-text",
-			OllamaChatModel:  "llama3.2",
-		},
 		LocalProvider: ProviderConfig{
 			Type:             "ollama",
 			OllamaEndpoint:   "http://localhost:11434",
@@ -0,0 +1,254 @@
+package config
+
+//go:generate go run gen_schema_docs.go -o schema_docs.go
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// SchemaID is the $schema of the generated JSON Schema
+const SchemaID = "https://json-schema.org/draft/2020-12/schema"
+
+// Schema returns a JSON Schema for config.json, built from the Config structs
+// and their field comments (see schema_docs.go), so editors can offer
+// completion and flag unknown settings
+func Schema() map[string]interface{} {
+	schema := typeSchema(reflect.TypeOf(Config{}))
+	schema["$schema"] = SchemaID
+	schema["title"] = "Noodexx configuration"
+
+	// privacy.enabled is still read from older config files
+	privacy := schema["properties"].(map[string]interface{})["privacy"].(map[string]interface{})
+	privacy["properties"].(map[string]interface{})["enabled"] = map[string]interface{}{
+		"type":        "boolean",
+		"description": "Replaced by default_to_local",
+		"deprecated":  true,
+	}
+	return schema
+}
+
+// typeSchema describes a Go type the way encoding/json reads it
+func typeSchema(t reflect.Type) map[string]interface{} {
+	switch t.Kind() {
+	case reflect.Pointer:
+		return typeSchema(t.Elem())
+	case reflect.Struct:
+		properties := make(map[string]interface{})
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			name, ok := jsonName(field)
+			if !ok {
+				continue
+			}
+			property := typeSchema(field.Type)
+			if doc := fieldDocs[t.Name()+"."+field.Name]; doc != "" {
+				property["description"] = doc
+			}
+			properties[name] = property
+		}
+		schema := map[string]interface{}{
+			"type":                 "object",
+			"properties":           properties,
+			"additionalProperties": false,
+		}
+		if doc := fieldDocs[t.Name()]; doc != "" {
+			schema["description"] = doc
+		}
+		return schema
+	case reflect.Map:
+		schema := map[string]interface{}{
+			"type":                 "object",
+			"additionalProperties": typeSchema(t.Elem()),
+		}
+		if doc := fieldDocs[t.Name()]; doc != "" {
+			schema["description"] = doc
+		}
+		return schema
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": typeSchema(t.Elem())}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	default:
+		return map[string]interface{}{}
+	}
+}
+
+// jsonName returns the key encoding/json uses for a struct field, and false for
+// fields it skips
+func jsonName(field reflect.StructField) (string, bool) {
+	if !field.IsExported() {
+		return "", false
+	}
+	name := strings.Split(field.Tag.Get("json"), ",")[0]
+	if name == "-" {
+		return "", false
+	}
+	if name == "" {
+		name = field.Name
+	}
+	return name, true
+}
+
+// CheckSchema reports every setting of a parsed config file that the schema
+// does not allow: unknown keys, with the closest known key when one is near,
+// and values of the wrong type
+func CheckSchema(doc interface{}) []error {
+	var problems []error
+	checkValue(Schema(), doc, "", &problems)
+	return problems
+}
+
+// checkValue checks value at path against schema, appending any problems
+func checkValue(schema map[string]interface{}, value interface{}, path string, problems *[]error) {
+	if want, ok := schema["type"].(string); ok && !matchesType(want, value) {
+		*problems = append(*problems, fmt.Errorf("%s: expected %s, got %s", displayPath(path), describeType(want), describeValue(value)))
+		return
+	}
+	switch v := value.(type) {
+	case map[string]interface{}:
+		properties, _ := schema["properties"].(map[string]interface{})
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			child := joinPath(path, key)
+			if property, ok := properties[key].(map[string]interface{}); ok {
+				checkValue(property, v[key], child, problems)
+				continue
+			}
+			switch extra := schema["additionalProperties"].(type) {
+			case map[string]interface{}:
+				checkValue(extra, v[key], child, problems)
+			case bool:
+				if !extra {
+					*problems = append(*problems, unknownKey(child, key, properties))
+				}
+			}
+		}
+	case []interface{}:
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range v {
+				checkValue(items, item, fmt.Sprintf("%s[%d]", path, i), problems)
+			}
+		}
+	}
+}
+
+// unknownKey reports a key the schema does not know, suggesting the closest
+// known key of the same object
+func unknownKey(path, key string, properties map[string]interface{}) error {
+	best, bestDistance := "", 3
+	for known := range properties {
+		if d := editDistance(key, known); d < bestDistance || (d == bestDistance && known < best) {
+			best, bestDistance = known, d
+		}
+	}
+	if best != "" {
+		return fmt.Errorf("%s: unknown setting (did you mean %q?)", path, best)
+	}
+	return fmt.Errorf("%s: unknown setting", path)
+}
+
+// matchesType reports whether a decoded JSON value has the JSON Schema type want
+// null is accepted everywhere, as encoding/json leaves the default in place
+func matchesType(want string, value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case string:
+		return want == "string"
+	case bool:
+		return want == "boolean"
+	case float64:
+		return want == "number" || (want == "integer" && v == math.Trunc(v))
+	case json.Number:
+		_, err := v.Int64()
+		return want == "number" || (want == "integer" && err == nil)
+	case map[string]interface{}:
+		return want == "object"
+	case []interface{}:
+		return want == "array"
+	}
+	return false
+}
+
+// describeType names a JSON Schema type for an error message
+func describeType(t string) string {
+	switch t {
+	case "object", "array", "integer":
+		return "an " + t
+	}
+	return "a " + t
+}
+
+// describeValue names the type of a decoded JSON value, including the value
+// itself when it is short
+func describeValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		if len(v) <= 40 {
+			return fmt.Sprintf("string %q", v)
+		}
+		return "a string"
+	case bool:
+		return fmt.Sprintf("boolean %v", v)
+	case float64, json.Number:
+		return fmt.Sprintf("number %v", v)
+	case map[string]interface{}:
+		return "an object"
+	case []interface{}:
+		return "an array"
+	}
+	return "null"
+}
+
+// joinPath appends key to a dotted settings path
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// displayPath names the settings path of a problem; the root is the whole file
+func displayPath(path string) string {
+	if path == "" {
+		return "config"
+	}
+	return path
+}
+
+// editDistance is the Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,114 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestSchema tests that the schema follows the Config structs and their comments
+func TestSchema(t *testing.T) {
+	schema := Schema()
+	properties := schema["properties"].(map[string]interface{})
+
+	guardrails := properties["guardrails"].(map[string]interface{})["properties"].(map[string]interface{})
+	history, ok := guardrails["history_messages"].(map[string]interface{})
+	if !ok || history["type"] != "integer" || !strings.Contains(history["description"].(string), "Recent session messages") {
+		t.Errorf("Expected guardrails.history_messages to be a documented integer, got %v", history)
+	}
+
+	folders := properties["folders"].(map[string]interface{})
+	if folders["type"] != "array" || folders["items"].(map[string]interface{})["type"] != "string" {
+		t.Errorf("Expected folders to be an array of strings, got %v", folders)
+	}
+
+	features := properties["features"].(map[string]interface{})
+	flag := features["additionalProperties"].(map[string]interface{})
+	if _, ok := flag["properties"].(map[string]interface{})["rollout_percent"]; !ok {
+		t.Errorf("Expected feature flags to describe rollout_percent, got %v", flag)
+	}
+}
+
+// TestCheckSchema tests reporting unknown settings and values of the wrong type
+func TestCheckSchema(t *testing.T) {
+	doc := map[string]interface{}{
+		"server":  map[string]interface{}{"prot": 8080.0},
+		"logging": map[string]interface{}{"max_size_mb": "10"},
+		"folders": []interface{}{"docs", 3.0},
+		"privacy": map[string]interface{}{"enabled": true},
+	}
+	problems := CheckSchema(doc)
+	var messages []string
+	for _, p := range problems {
+		messages = append(messages, p.Error())
+	}
+	joined := strings.Join(messages, "\n")
+
+	for _, want := range []string{
+		`server.prot: unknown setting (did you mean "port"?)`,
+		`logging.max_size_mb: expected an integer, got string "10"`,
+		`folders[1]: expected a string, got number 3`,
+	} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("Expected problem %q, got:\n%s", want, joined)
+		}
+	}
+	if len(problems) != 3 {
+		t.Errorf("Expected 3 problems (the legacy privacy.enabled is allowed), got:\n%s", joined)
+	}
+}
+
+// TestCheckFile tests checking a config file, including rules spanning fields
+func TestCheckFile(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	if problems := CheckFile(filepath.Join(dir, "missing.json")); len(problems) != 1 {
+		t.Errorf("Expected a missing file to be reported, got %v", problems)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "missing.json")); err == nil {
+		t.Error("Expected no default config to be created")
+	}
+
+	problems := CheckFile(write("syntax.json", "{\n  \"server\": {\n    \"port\": 8080,\n  }\n}"))
+	if len(problems) != 1 || !strings.HasPrefix(problems[0].Error(), "line 4, column 3") {
+		t.Errorf("Expected the syntax error position, got %v", problems)
+	}
+
+	problems = CheckFile(write("privacy.json", `{"privacy": {"default_to_local": true}, "local_provider": {"type": "ollama", "ollama_endpoint": "http://gpu-box:11434", "ollama_embed_model": "nomic-embed-text", "ollama_chat_model": "llama3.2"}}`))
+	if len(problems) != 1 || !strings.Contains(problems[0].Error(), "localhost endpoint") {
+		t.Errorf("Expected the local endpoint rule to be reported, got %v", problems)
+	}
+
+	problems = CheckFile(write("valid.json", `{"server": {"port": 8080}}`))
+	if len(problems) != 0 {
+		t.Errorf("Expected no problems, got %v", problems)
+	}
+}
+
+// TestSchemaDocsUpToDate tests that schema_docs.go matches the comments in
+// config.go; run go generate ./internal/config after changing a config struct
+func TestSchemaDocsUpToDate(t *testing.T) {
+	if testing.Short() {
+		t.Skip("runs the generator")
+	}
+	out := filepath.Join(t.TempDir(), "schema_docs.go")
+	cmd := exec.Command("go", "run", "gen_schema_docs.go", "-o", out)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("Generator failed: %v\n%s", err, output)
+	}
+	want, _ := os.ReadFile(out)
+	got, _ := os.ReadFile("schema_docs.go")
+	if !bytes.Equal(got, want) {
+		t.Error("schema_docs.go is out of date; run go generate ./internal/config")
+	}
+}
@@ -0,0 +1,232 @@
+// Package embedpool distributes embedding requests across several embedding
+// endpoints. Each batch is split into per-worker queues; idle workers steal
+// from the busiest queue, and endpoints that keep failing are skipped for a
+// cooldown period while their work is picked up by the others.
+package embedpool
+
+import (
+	"context"
+	"fmt"
+	"noodexx/internal/events"
+	"noodexx/internal/llm"
+	"noodexx/internal/logging"
+	"sync"
+	"time"
+)
+
+// Embedder generates embeddings for one endpoint
+type Embedder interface {
+	Embed(ctx context.Context, text string) ([]float32, error)
+}
+
+// Worker is a named embedding endpoint
+type Worker struct {
+	Name     string
+	Embedder Embedder
+}
+
+// Options configures a Pool
+type Options struct {
+	Concurrency      int           // Parallel requests per worker
+	MaxAttempts      int           // Tries per text before the batch fails
+	FailureThreshold int           // Consecutive failures before a worker is skipped
+	Cooldown         time.Duration // How long a failing worker is skipped
+	Events           *events.Bus   // Told when a worker starts being skipped; may be nil
+}
+
+// WorkerStats is a snapshot of a worker's health and throughput
+type WorkerStats struct {
+	Name                string
+	Healthy             bool
+	ConsecutiveFailures int
+	InFlight            int
+	Completed           int64
+	Failed              int64
+	AvgLatencyMS        int64
+}
+
+// Pool embeds texts across a set of workers
+type Pool struct {
+	workers []*worker
+	opts    Options
+	logger  *logging.Logger
+}
+
+// worker tracks one endpoint's health; slots bounds its concurrency across all batches
+type worker struct {
+	name     string
+	embedder Embedder
+	slots    chan struct{}
+
+	mu                  sync.Mutex
+	consecutiveFailures int
+	unhealthyUntil      time.Time
+	inFlight            int
+	completed           int64
+	failed              int64
+	totalLatency        time.Duration
+}
+
+// NewOllama creates a pool of Ollama endpoints serving the same embedding model
+func NewOllama(endpoints []string, model string, opts Options, logger *logging.Logger) (*Pool, error) {
+	workers := make([]Worker, len(endpoints))
+	for i, endpoint := range endpoints {
+		workers[i] = Worker{
+			Name:     endpoint,
+			Embedder: llm.NewOllamaProvider(endpoint, model, "", logger),
+		}
+	}
+	return New(workers, opts, logger)
+}
+
+// New creates a pool from workers
+func New(workers []Worker, opts Options, logger *logging.Logger) (*Pool, error) {
+	if len(workers) == 0 {
+		return nil, fmt.Errorf("embedding pool needs at least one worker")
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = 2
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = 3
+	}
+	if opts.FailureThreshold <= 0 {
+		opts.FailureThreshold = 3
+	}
+	if opts.Cooldown <= 0 {
+		opts.Cooldown = 30 * time.Second
+	}
+
+	pool := &Pool{opts: opts, logger: logger}
+	for _, w := range workers {
+		pool.workers = append(pool.workers, &worker{
+			name:     w.Name,
+			embedder: w.Embedder,
+			slots:    make(chan struct{}, opts.Concurrency),
+		})
+	}
+	return pool, nil
+}
+
+// Embed generates an embedding for a single text
+func (p *Pool) Embed(ctx context.Context, text string) ([]float32, error) {
+	embeddings, err := p.EmbedBatch(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	return embeddings[0], nil
+}
+
+// EmbedBatch embeds texts in parallel across the pool and returns the embeddings in order
+// A text that fails is retried on other workers; the batch fails once a text has used up
+// its attempts or ctx is cancelled
+func (p *Pool) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	if len(texts) == 0 {
+		return nil, nil
+	}
+
+	start := time.Now()
+	b := newBatch(p, texts)
+
+	var wg sync.WaitGroup
+	for i, w := range p.workers {
+		for n := 0; n < p.opts.Concurrency; n++ {
+			wg.Add(1)
+			go func(i int, w *worker) {
+				defer wg.Done()
+				b.run(ctx, i, w)
+			}(i, w)
+		}
+	}
+
+	// Wake waiting workers if the caller gives up
+	stop := context.AfterFunc(ctx, func() {
+		b.fail(ctx.Err())
+	})
+	wg.Wait()
+	stop()
+
+	if b.err != nil {
+		return nil, b.err
+	}
+
+	p.logger.WithFields(map[string]interface{}{
+		"texts":      len(texts),
+		"latency_ms": time.Since(start).Milliseconds(),
+	}).Debug("embedding batch completed")
+	return b.results, nil
+}
+
+// Stats returns a snapshot of every worker
+func (p *Pool) Stats() []WorkerStats {
+	now := time.Now()
+	stats := make([]WorkerStats, len(p.workers))
+	for i, w := range p.workers {
+		w.mu.Lock()
+		stats[i] = WorkerStats{
+			Name:                w.name,
+			Healthy:             !now.Before(w.unhealthyUntil),
+			ConsecutiveFailures: w.consecutiveFailures,
+			InFlight:            w.inFlight,
+			Completed:           w.completed,
+			Failed:              w.failed,
+		}
+		if w.completed > 0 {
+			stats[i].AvgLatencyMS = (w.totalLatency / time.Duration(w.completed)).Milliseconds()
+		}
+		w.mu.Unlock()
+	}
+	return stats
+}
+
+// healthy reports whether a worker is outside its failure cooldown
+func (w *worker) healthy(now time.Time) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return !now.Before(w.unhealthyUntil)
+}
+
+// embed runs one request, holding one of the worker's slots, and updates its health
+func (w *worker) embed(ctx context.Context, text string, opts Options) ([]float32, error) {
+	select {
+	case w.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	defer func() { <-w.slots }()
+
+	w.mu.Lock()
+	w.inFlight++
+	w.mu.Unlock()
+
+	start := time.Now()
+	embedding, err := w.embedder.Embed(ctx, text)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.inFlight--
+	if err != nil && ctx.Err() != nil {
+		// Cancelled by the caller, not the endpoint's fault
+		return nil, err
+	}
+	if err != nil {
+		w.failed++
+		w.consecutiveFailures++
+		if w.consecutiveFailures >= opts.FailureThreshold {
+			w.unhealthyUntil = time.Now().Add(opts.Cooldown)
+		}
+		if w.consecutiveFailures == opts.FailureThreshold {
+			// Published once per outage, without holding the worker
+			go opts.Events.Publish(context.Background(), events.Event{
+				Topic: events.ProviderFailover,
+				Data:  map[string]interface{}{"endpoint": w.name, "error": err.Error()},
+			})
+		}
+		return nil, err
+	}
+	w.completed++
+	w.totalLatency += time.Since(start)
+	w.consecutiveFailures = 0
+	w.unhealthyUntil = time.Time{}
+	return embedding, nil
+}
@@ -0,0 +1,118 @@
+package embedpool
+
+import (
+	"context"
+	"errors"
+	"io"
+	"noodexx/internal/logging"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeEmbedder returns the text length as a one-dimensional embedding
+type fakeEmbedder struct {
+	mu    sync.Mutex
+	calls int
+	err   error
+	delay time.Duration
+}
+
+func (f *fakeEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	f.mu.Lock()
+	f.calls++
+	f.mu.Unlock()
+	if f.delay > 0 {
+		select {
+		case <-time.After(f.delay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	if f.err != nil {
+		return nil, f.err
+	}
+	return []float32{float32(len(text))}, nil
+}
+
+func testLogger() *logging.Logger {
+	return logging.NewLogger("embedpool", logging.ERROR, io.Discard)
+}
+
+func texts(n int) []string {
+	out := make([]string, n)
+	for i := range out {
+		out[i] = string(make([]byte, i+1))
+	}
+	return out
+}
+
+// TestEmbedBatchDistributes tests that results keep their order and work is shared
+func TestEmbedBatchDistributes(t *testing.T) {
+	fast := &fakeEmbedder{}
+	slow := &fakeEmbedder{delay: 20 * time.Millisecond}
+	pool, err := New([]Worker{{Name: "fast", Embedder: fast}, {Name: "slow", Embedder: slow}}, Options{Concurrency: 1}, testLogger())
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	embeddings, err := pool.EmbedBatch(context.Background(), texts(20))
+	if err != nil {
+		t.Fatalf("EmbedBatch failed: %v", err)
+	}
+	for i, embedding := range embeddings {
+		if embedding[0] != float32(i+1) {
+			t.Fatalf("Expected embedding %d to be %d, got %v", i, i+1, embedding)
+		}
+	}
+
+	// The fast worker steals most of the slow worker's queue
+	if fast.calls <= slow.calls || fast.calls+slow.calls != 20 {
+		t.Errorf("Expected the fast worker to do most of the work, got fast=%d slow=%d", fast.calls, slow.calls)
+	}
+}
+
+// TestEmbedBatchFailover tests that a failing worker is skipped and its work retried elsewhere
+func TestEmbedBatchFailover(t *testing.T) {
+	broken := &fakeEmbedder{err: errors.New("connection refused")}
+	healthy := &fakeEmbedder{delay: 5 * time.Millisecond}
+	pool, _ := New([]Worker{{Name: "broken", Embedder: broken}, {Name: "healthy", Embedder: healthy}},
+		Options{Concurrency: 1, MaxAttempts: 3, FailureThreshold: 1, Cooldown: time.Minute}, testLogger())
+
+	if _, err := pool.EmbedBatch(context.Background(), texts(10)); err != nil {
+		t.Fatalf("EmbedBatch failed: %v", err)
+	}
+	if broken.calls != 1 {
+		t.Errorf("Expected the broken worker to be skipped after one failure, got %d calls", broken.calls)
+	}
+
+	stats := pool.Stats()
+	if stats[0].Healthy || stats[0].Failed != 1 || !stats[1].Healthy || stats[1].Completed != 10 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+
+	// A later batch goes straight to the healthy worker
+	if _, err := pool.EmbedBatch(context.Background(), texts(4)); err != nil {
+		t.Fatalf("EmbedBatch failed: %v", err)
+	}
+	if broken.calls != 1 {
+		t.Errorf("Expected no calls to the worker in cooldown, got %d", broken.calls)
+	}
+}
+
+// TestEmbedBatchGivesUp tests that a batch fails once a text has used its attempts
+func TestEmbedBatchGivesUp(t *testing.T) {
+	a := &fakeEmbedder{err: errors.New("down")}
+	b := &fakeEmbedder{err: errors.New("down")}
+	pool, _ := New([]Worker{{Name: "a", Embedder: a}, {Name: "b", Embedder: b}}, Options{MaxAttempts: 2}, testLogger())
+
+	if _, err := pool.EmbedBatch(context.Background(), texts(3)); err == nil {
+		t.Fatal("Expected error when every worker fails")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := pool.Embed(ctx, "text"); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
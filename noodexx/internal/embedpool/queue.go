@@ -0,0 +1,179 @@
+package embedpool
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// batch is the work queue for one EmbedBatch call
+// Each worker has its own queue of text indexes; a worker with an empty queue
+// steals from the back of the longest one
+type batch struct {
+	pool     *Pool
+	texts    []string
+	results  [][]float32
+	attempts []int
+	queues   [][]int
+	pending  int
+	err      error
+
+	mu   sync.Mutex
+	cond *sync.Cond
+}
+
+// newBatch spreads the texts round-robin over the healthy workers, or over all
+// workers if none are healthy
+func newBatch(p *Pool, texts []string) *batch {
+	b := &batch{
+		pool:     p,
+		texts:    texts,
+		results:  make([][]float32, len(texts)),
+		attempts: make([]int, len(texts)),
+		queues:   make([][]int, len(p.workers)),
+		pending:  len(texts),
+	}
+	b.cond = sync.NewCond(&b.mu)
+
+	now := time.Now()
+	var targets []int
+	for i, w := range p.workers {
+		if w.healthy(now) {
+			targets = append(targets, i)
+		}
+	}
+	if len(targets) == 0 {
+		for i := range p.workers {
+			targets = append(targets, i)
+		}
+	}
+	for idx := range texts {
+		q := targets[idx%len(targets)]
+		b.queues[q] = append(b.queues[q], idx)
+	}
+	return b
+}
+
+// run processes texts for worker i until the batch is done or has failed
+func (b *batch) run(ctx context.Context, i int, w *worker) {
+	for {
+		idx, ok := b.next(i, w)
+		if !ok {
+			return
+		}
+		embedding, err := w.embed(ctx, b.texts[idx], b.pool.opts)
+		b.finish(ctx, i, idx, embedding, err)
+	}
+}
+
+// next waits for a text for worker i to embed
+// Unhealthy workers only take work when no healthy worker is left
+func (b *batch) next(i int, w *worker) (int, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for {
+		if b.err != nil || b.pending == 0 {
+			return 0, false
+		}
+		if w.healthy(time.Now()) || !b.otherHealthy(i) {
+			if idx, ok := b.take(i); ok {
+				return idx, true
+			}
+		}
+		b.cond.Wait()
+	}
+}
+
+// take pops from the front of worker i's queue, or steals from the back of the longest queue
+// Caller must hold b.mu
+func (b *batch) take(i int) (int, bool) {
+	if q := b.queues[i]; len(q) > 0 {
+		b.queues[i] = q[1:]
+		return q[0], true
+	}
+
+	victim := -1
+	for j, q := range b.queues {
+		if len(q) > 0 && (victim < 0 || len(q) > len(b.queues[victim])) {
+			victim = j
+		}
+	}
+	if victim < 0 {
+		return 0, false
+	}
+	q := b.queues[victim]
+	b.queues[victim] = q[:len(q)-1]
+	return q[len(q)-1], true
+}
+
+// otherHealthy reports whether any worker other than i is healthy
+// Caller must hold b.mu
+func (b *batch) otherHealthy(i int) bool {
+	now := time.Now()
+	for j, w := range b.pool.workers {
+		if j != i && w.healthy(now) {
+			return true
+		}
+	}
+	return false
+}
+
+// finish records a result, or requeues a failed text on another worker
+func (b *batch) finish(ctx context.Context, i, idx int, embedding []float32, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	defer b.cond.Broadcast()
+
+	if err == nil {
+		b.results[idx] = embedding
+		b.pending--
+		return
+	}
+	if b.err != nil {
+		return
+	}
+	if ctx.Err() != nil {
+		b.err = ctx.Err()
+		return
+	}
+
+	b.attempts[idx]++
+	name := b.pool.workers[i].name
+	if b.attempts[idx] >= b.pool.opts.MaxAttempts {
+		b.err = fmt.Errorf("embedding failed after %d attempts (last worker %s): %w", b.attempts[idx], name, err)
+		return
+	}
+
+	b.pool.logger.WithFields(map[string]interface{}{
+		"worker":  name,
+		"attempt": b.attempts[idx],
+		"error":   err.Error(),
+	}).Warn("embedding failed, retrying on another worker")
+	q := b.retryTarget(i)
+	b.queues[q] = append(b.queues[q], idx)
+}
+
+// retryTarget picks the next healthy worker after i, falling back to the next worker
+// Caller must hold b.mu
+func (b *batch) retryTarget(i int) int {
+	n := len(b.pool.workers)
+	now := time.Now()
+	for step := 1; step < n; step++ {
+		j := (i + step) % n
+		if b.pool.workers[j].healthy(now) {
+			return j
+		}
+	}
+	return (i + 1) % n
+}
+
+// fail stops the batch with err
+func (b *batch) fail(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.err == nil {
+		b.err = err
+	}
+	b.cond.Broadcast()
+}
@@ -0,0 +1,157 @@
+// Package eval measures retrieval quality against golden question sets. Each
+// case pairs a question with the sources a good retrieval returns for it; a run
+// retrieves for every question, optionally generates an answer, and scores the
+// ranking with hit rate, mean reciprocal rank and source recall.
+package eval
+
+import (
+	"context"
+	"time"
+)
+
+// Case is a golden question and the sources that answer it
+type Case struct {
+	Question        string   `json:"question"`
+	ExpectedSources []string `json:"expected_sources"`
+}
+
+// Passage is one retrieved chunk
+type Passage struct {
+	Source string
+	Text   string
+}
+
+// Retriever returns the top k passages for a question, best first
+type Retriever interface {
+	Retrieve(ctx context.Context, question string, k int) ([]Passage, error)
+}
+
+// Generator answers a question from retrieved passages
+type Generator interface {
+	Generate(ctx context.Context, question string, passages []Passage) (string, error)
+}
+
+// Result is the outcome of one case
+type Result struct {
+	Question  string   `json:"question"`
+	Expected  []string `json:"expected_sources"`
+	Retrieved []string `json:"retrieved_sources"` // Source of each retrieved passage, in rank order
+	Rank      int      `json:"rank"`              // 1-based rank of the first expected source, 0 if none was retrieved
+	Recall    float64  `json:"recall"`            // Share of expected sources retrieved
+	Answer    string   `json:"answer,omitempty"`
+	Error     string   `json:"error,omitempty"`
+	LatencyMS int64    `json:"latency_ms"`
+}
+
+// Metrics summarizes a run
+type Metrics struct {
+	Cases   int     `json:"cases"`
+	Hits    int     `json:"hits"`     // Cases with an expected source in the top k
+	Errors  int     `json:"errors"`   // Cases that failed to retrieve or generate
+	HitRate float64 `json:"hit_rate"` // Hits / Cases
+	MRR     float64 `json:"mrr"`      // Mean of 1/Rank, counting misses as 0
+	Recall  float64 `json:"recall"`   // Mean share of expected sources retrieved
+}
+
+// Run retrieves the top k passages for every case and scores them
+// Answers are generated when generator is non-nil. A failed case is recorded and
+// scored as a miss; Run stops early only when ctx is done
+func Run(ctx context.Context, cases []Case, k int, retriever Retriever, generator Generator) ([]Result, Metrics) {
+	results := make([]Result, 0, len(cases))
+	for _, c := range cases {
+		if ctx.Err() != nil {
+			break
+		}
+		results = append(results, runCase(ctx, c, k, retriever, generator))
+	}
+	return results, Score(results)
+}
+
+// runCase runs and scores a single case
+func runCase(ctx context.Context, c Case, k int, retriever Retriever, generator Generator) Result {
+	start := time.Now()
+	result := Result{Question: c.Question, Expected: c.ExpectedSources, Retrieved: []string{}}
+
+	passages, err := retriever.Retrieve(ctx, c.Question, k)
+	if err != nil {
+		result.Error = err.Error()
+		result.LatencyMS = time.Since(start).Milliseconds()
+		return result
+	}
+	for _, p := range passages {
+		result.Retrieved = append(result.Retrieved, p.Source)
+	}
+	result.Rank = FirstRelevantRank(c.ExpectedSources, result.Retrieved)
+	result.Recall = SourceRecall(c.ExpectedSources, result.Retrieved)
+
+	if generator != nil {
+		answer, err := generator.Generate(ctx, c.Question, passages)
+		if err != nil {
+			result.Error = err.Error()
+		}
+		result.Answer = answer
+	}
+
+	result.LatencyMS = time.Since(start).Milliseconds()
+	return result
+}
+
+// FirstRelevantRank returns the 1-based rank of the first retrieved source that
+// is expected, or 0 if none is
+func FirstRelevantRank(expected, retrieved []string) int {
+	want := toSet(expected)
+	for i, source := range retrieved {
+		if want[source] {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// SourceRecall returns the share of expected sources that were retrieved
+// A case with no expected sources has a recall of 1
+func SourceRecall(expected, retrieved []string) float64 {
+	want := toSet(expected)
+	if len(want) == 0 {
+		return 1
+	}
+	found := 0
+	for source := range toSet(retrieved) {
+		if want[source] {
+			found++
+		}
+	}
+	return float64(found) / float64(len(want))
+}
+
+// Score computes a run's metrics from its results
+func Score(results []Result) Metrics {
+	m := Metrics{Cases: len(results)}
+	if m.Cases == 0 {
+		return m
+	}
+	var reciprocal, recall float64
+	for _, r := range results {
+		if r.Error != "" {
+			m.Errors++
+		}
+		if r.Rank > 0 {
+			m.Hits++
+			reciprocal += 1 / float64(r.Rank)
+		}
+		recall += r.Recall
+	}
+	m.HitRate = float64(m.Hits) / float64(m.Cases)
+	m.MRR = reciprocal / float64(m.Cases)
+	m.Recall = recall / float64(m.Cases)
+	return m
+}
+
+// toSet returns the distinct values of a list
+func toSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, v := range values {
+		set[v] = true
+	}
+	return set
+}
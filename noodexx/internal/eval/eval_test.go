@@ -0,0 +1,109 @@
+package eval
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+)
+
+// fakeRetriever returns fixed rankings per question
+type fakeRetriever map[string][]string
+
+func (f fakeRetriever) Retrieve(ctx context.Context, question string, k int) ([]Passage, error) {
+	sources, ok := f[question]
+	if !ok {
+		return nil, errors.New("embedding failed")
+	}
+	var passages []Passage
+	for i, source := range sources {
+		if i == k {
+			break
+		}
+		passages = append(passages, Passage{Source: source, Text: "text of " + source})
+	}
+	return passages, nil
+}
+
+// echoGenerator answers with the sources it was given
+type echoGenerator struct{}
+
+func (echoGenerator) Generate(ctx context.Context, question string, passages []Passage) (string, error) {
+	var sources []string
+	for _, p := range passages {
+		sources = append(sources, p.Source)
+	}
+	return strings.Join(sources, ","), nil
+}
+
+func TestFirstRelevantRank(t *testing.T) {
+	tests := []struct {
+		expected, retrieved []string
+		want                int
+	}{
+		{[]string{"b.md"}, []string{"a.md", "b.md", "c.md"}, 2},
+		{[]string{"c.md", "a.md"}, []string{"a.md", "b.md", "c.md"}, 1},
+		{[]string{"z.md"}, []string{"a.md", "b.md"}, 0},
+		{[]string{"a.md"}, nil, 0},
+	}
+	for _, tt := range tests {
+		if got := FirstRelevantRank(tt.expected, tt.retrieved); got != tt.want {
+			t.Errorf("FirstRelevantRank(%v, %v) = %d, want %d", tt.expected, tt.retrieved, got, tt.want)
+		}
+	}
+}
+
+func TestSourceRecall(t *testing.T) {
+	if got := SourceRecall([]string{"a.md", "b.md"}, []string{"a.md", "a.md", "c.md"}); got != 0.5 {
+		t.Errorf("Expected recall 0.5, got %v", got)
+	}
+	if got := SourceRecall(nil, []string{"a.md"}); got != 1 {
+		t.Errorf("Expected recall 1 with no expected sources, got %v", got)
+	}
+}
+
+func TestRun(t *testing.T) {
+	retriever := fakeRetriever{
+		"q1": {"a.md", "b.md", "c.md"},
+		"q2": {"x.md", "y.md", "b.md"},
+		"q3": {"x.md", "y.md", "z.md", "b.md"},
+	}
+	cases := []Case{
+		{Question: "q1", ExpectedSources: []string{"a.md"}},
+		{Question: "q2", ExpectedSources: []string{"b.md"}},
+		{Question: "q3", ExpectedSources: []string{"b.md"}}, // Ranked 4th, outside k
+		{Question: "q4", ExpectedSources: []string{"a.md"}}, // Retrieval fails
+	}
+
+	results, metrics := Run(context.Background(), cases, 3, retriever, echoGenerator{})
+	if len(results) != 4 {
+		t.Fatalf("Expected 4 results, got %d", len(results))
+	}
+	if results[0].Rank != 1 || results[1].Rank != 3 || results[2].Rank != 0 {
+		t.Errorf("Unexpected ranks %d, %d, %d", results[0].Rank, results[1].Rank, results[2].Rank)
+	}
+	if results[0].Answer != "a.md,b.md,c.md" {
+		t.Errorf("Expected an answer from the retrieved passages, got %q", results[0].Answer)
+	}
+	if results[3].Error == "" || results[3].Answer != "" {
+		t.Errorf("Expected the failed case to record its error, got %+v", results[3])
+	}
+
+	if metrics.Cases != 4 || metrics.Hits != 2 || metrics.Errors != 1 {
+		t.Errorf("Unexpected counts %+v", metrics)
+	}
+	if metrics.HitRate != 0.5 {
+		t.Errorf("Expected hit rate 0.5, got %v", metrics.HitRate)
+	}
+	if want := (1 + 1.0/3) / 4; math.Abs(metrics.MRR-want) > 1e-9 {
+		t.Errorf("Expected MRR %v, got %v", want, metrics.MRR)
+	}
+
+	// A cancelled run stops before the next case
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if results, _ := Run(ctx, cases, 3, retriever, nil); len(results) != 0 {
+		t.Errorf("Expected a cancelled run to stop, got %d results", len(results))
+	}
+}
@@ -0,0 +1,214 @@
+// Package events is Noodexx's internal event bus. Subsystems publish what
+// happened to a topic, such as a finished ingestion, and other subsystems
+// subscribe to the topics they care about instead of being called directly:
+// the WebSocket hub, notifications, webhooks and event-triggered skills.
+//
+// Synchronous subscribers run in Publish, in the order they subscribed, so they
+// must be quick. Asynchronous subscribers each have their own queue and
+// goroutine and see their events in the order they were published; when a
+// queue is full, further events for that subscriber are dropped and logged
+// rather than blocking the publisher. A subscriber that panics is logged and
+// does not affect the others.
+package events
+
+import (
+	"context"
+	"noodexx/internal/logging"
+	"sync"
+	"time"
+)
+
+// Topic names a kind of event
+type Topic string
+
+// Topics published by Noodexx
+const (
+	IngestionCompleted Topic = "ingestion.completed" // A document was ingested; Data has "source" and "chunks"
+	DocumentDeleted    Topic = "document.deleted"    // A document was deleted; Data has "source"
+	ProviderFailover   Topic = "provider.failover"   // An endpoint failed and its work moves to the others; Data has "endpoint" and "error"
+	UserCreated        Topic = "user.created"        // An account was created; Data has "username"
+)
+
+// Topics lists every topic, for validating subscriptions in configuration
+var Topics = []Topic{IngestionCompleted, DocumentDeleted, ProviderFailover, UserCreated}
+
+// Valid reports whether t is one of Topics
+func (t Topic) Valid() bool {
+	for _, topic := range Topics {
+		if t == topic {
+			return true
+		}
+	}
+	return false
+}
+
+// Event is something that happened in one subsystem
+type Event struct {
+	Topic  Topic                  `json:"topic"`
+	UserID int64                  `json:"user_id,omitempty"` // User the event concerns, 0 for system events
+	Time   time.Time              `json:"time"`
+	Data   map[string]interface{} `json:"data,omitempty"`
+}
+
+// Handler receives events. The context of asynchronous handlers is not the
+// publisher's, which may be cancelled before they run
+type Handler func(ctx context.Context, e Event)
+
+// asyncQueueSize is how many events an asynchronous subscriber can fall behind
+// before further events for it are dropped
+const asyncQueueSize = 256
+
+// subscription is one handler of a topic
+type subscription struct {
+	id      int64
+	name    string
+	handler Handler
+	queue   chan Event // Nil for synchronous subscribers
+}
+
+// Bus delivers published events to the subscribers of their topic. A nil *Bus
+// drops everything published to it, so publishers need no check
+type Bus struct {
+	logger *logging.Logger
+
+	mu     sync.RWMutex
+	subs   map[Topic][]*subscription
+	nextID int64
+	closed bool
+	wg     sync.WaitGroup
+}
+
+// New creates an empty bus
+func New(logger *logging.Logger) *Bus {
+	return &Bus{logger: logger, subs: make(map[Topic][]*subscription)}
+}
+
+// Subscribe calls handler in Publish for every event of topic until the
+// returned function is called. name identifies the subscriber in logs
+func (b *Bus) Subscribe(topic Topic, name string, handler Handler) func() {
+	return b.subscribe(topic, &subscription{name: name, handler: handler})
+}
+
+// SubscribeAsync calls handler on its own goroutine for every event of topic,
+// in publishing order, until the returned function is called
+func (b *Bus) SubscribeAsync(topic Topic, name string, handler Handler) func() {
+	sub := &subscription{name: name, handler: handler, queue: make(chan Event, asyncQueueSize)}
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		for e := range sub.queue {
+			b.deliver(context.Background(), sub, e)
+		}
+	}()
+	return b.subscribe(topic, sub)
+}
+
+// subscribe adds sub to topic and returns the function that removes it
+func (b *Bus) subscribe(topic Topic, sub *subscription) func() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		if sub.queue != nil {
+			close(sub.queue)
+		}
+		return func() {}
+	}
+	b.nextID++
+	sub.id = b.nextID
+	b.subs[topic] = append(b.subs[topic], sub)
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { b.unsubscribe(topic, sub.id) })
+	}
+}
+
+// unsubscribe removes a subscription, letting an asynchronous one finish its queue
+func (b *Bus) unsubscribe(topic Topic, id int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	subs := b.subs[topic]
+	for i, sub := range subs {
+		if sub.id == id {
+			b.subs[topic] = append(subs[:i:i], subs[i+1:]...)
+			if sub.queue != nil && !b.closed {
+				close(sub.queue)
+			}
+			return
+		}
+	}
+}
+
+// Publish sends an event to the subscribers of its topic. Time is set to now
+// when it is zero. Events published after Close are dropped
+func (b *Bus) Publish(ctx context.Context, e Event) {
+	if b == nil {
+		return
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+
+	b.mu.RLock()
+	if b.closed {
+		b.mu.RUnlock()
+		return
+	}
+	subs := append([]*subscription(nil), b.subs[e.Topic]...)
+	// Queue for asynchronous subscribers under the lock, so none is closed meanwhile
+	for _, sub := range subs {
+		if sub.queue == nil {
+			continue
+		}
+		select {
+		case sub.queue <- e:
+		default:
+			b.logf("Event subscriber %s is falling behind, dropping a %s event", sub.name, e.Topic)
+		}
+	}
+	b.mu.RUnlock()
+
+	for _, sub := range subs {
+		if sub.queue == nil {
+			b.deliver(ctx, sub, e)
+		}
+	}
+}
+
+// Close stops delivering events and waits for asynchronous subscribers to
+// finish the events already queued for them
+func (b *Bus) Close() {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return
+	}
+	b.closed = true
+	for _, subs := range b.subs {
+		for _, sub := range subs {
+			if sub.queue != nil {
+				close(sub.queue)
+			}
+		}
+	}
+	b.subs = make(map[Topic][]*subscription)
+	b.mu.Unlock()
+	b.wg.Wait()
+}
+
+// deliver calls a subscriber's handler, recovering from a panic in it
+func (b *Bus) deliver(ctx context.Context, sub *subscription, e Event) {
+	defer func() {
+		if r := recover(); r != nil {
+			b.logf("Event subscriber %s panicked handling a %s event: %v", sub.name, e.Topic, r)
+		}
+	}()
+	sub.handler(ctx, e)
+}
+
+// logf logs a warning, if the bus has a logger
+func (b *Bus) logf(format string, args ...interface{}) {
+	if b.logger != nil {
+		b.logger.Warn(format, args...)
+	}
+}
@@ -0,0 +1,178 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"noodexx/internal/logging"
+	"sync"
+	"testing"
+	"time"
+)
+
+func testBus() *Bus {
+	return New(logging.NewLogger("test", logging.ERROR, io.Discard))
+}
+
+// TestSyncSubscribers tests that synchronous subscribers run in Publish, in
+// subscription order, and only for their topic
+func TestSyncSubscribers(t *testing.T) {
+	bus := testBus()
+	defer bus.Close()
+
+	var got []string
+	bus.Subscribe(IngestionCompleted, "first", func(ctx context.Context, e Event) {
+		got = append(got, "first:"+e.Data["source"].(string))
+	})
+	bus.Subscribe(IngestionCompleted, "second", func(ctx context.Context, e Event) {
+		got = append(got, "second")
+	})
+	bus.Subscribe(DocumentDeleted, "other", func(ctx context.Context, e Event) {
+		got = append(got, "other")
+	})
+
+	bus.Publish(context.Background(), Event{Topic: IngestionCompleted, UserID: 1, Data: map[string]interface{}{"source": "a.txt"}})
+	if len(got) != 2 || got[0] != "first:a.txt" || got[1] != "second" {
+		t.Errorf("Expected both ingestion subscribers in order, got %v", got)
+	}
+}
+
+// TestAsyncSubscribers tests that asynchronous subscribers see events in order
+// and that Close waits for them
+func TestAsyncSubscribers(t *testing.T) {
+	bus := testBus()
+
+	var mu sync.Mutex
+	var got []int64
+	bus.SubscribeAsync(UserCreated, "async", func(ctx context.Context, e Event) {
+		time.Sleep(time.Millisecond)
+		mu.Lock()
+		got = append(got, e.UserID)
+		mu.Unlock()
+	})
+
+	for i := int64(1); i <= 5; i++ {
+		bus.Publish(context.Background(), Event{Topic: UserCreated, UserID: i})
+	}
+	bus.Close()
+
+	if len(got) != 5 {
+		t.Fatalf("Expected 5 events delivered before Close returned, got %v", got)
+	}
+	for i, id := range got {
+		if id != int64(i+1) {
+			t.Errorf("Expected events in publishing order, got %v", got)
+			break
+		}
+	}
+
+	// Publishing after Close is dropped
+	bus.Publish(context.Background(), Event{Topic: UserCreated, UserID: 6})
+	if len(got) != 5 {
+		t.Errorf("Expected no delivery after Close, got %v", got)
+	}
+}
+
+// TestUnsubscribe tests that an unsubscribed handler gets no further events
+func TestUnsubscribe(t *testing.T) {
+	bus := testBus()
+	defer bus.Close()
+
+	calls := 0
+	unsubscribe := bus.Subscribe(DocumentDeleted, "counter", func(ctx context.Context, e Event) { calls++ })
+	bus.Publish(context.Background(), Event{Topic: DocumentDeleted})
+	unsubscribe()
+	unsubscribe()
+	bus.Publish(context.Background(), Event{Topic: DocumentDeleted})
+
+	if calls != 1 {
+		t.Errorf("Expected 1 call before unsubscribing, got %d", calls)
+	}
+
+	done := make(chan struct{})
+	stop := bus.SubscribeAsync(DocumentDeleted, "async", func(ctx context.Context, e Event) { close(done) })
+	bus.Publish(context.Background(), Event{Topic: DocumentDeleted})
+	<-done
+	stop()
+	bus.Publish(context.Background(), Event{Topic: DocumentDeleted})
+}
+
+// TestPanickingSubscriber tests that a panic in one subscriber is recovered
+// and the others still run
+func TestPanickingSubscriber(t *testing.T) {
+	bus := testBus()
+	defer bus.Close()
+
+	ran := false
+	bus.Subscribe(ProviderFailover, "broken", func(ctx context.Context, e Event) { panic("boom") })
+	bus.Subscribe(ProviderFailover, "working", func(ctx context.Context, e Event) { ran = true })
+
+	bus.Publish(context.Background(), Event{Topic: ProviderFailover})
+	if !ran {
+		t.Error("Expected the second subscriber to run after the first panicked")
+	}
+}
+
+// TestNilBus tests that publishing to a nil bus does nothing
+func TestNilBus(t *testing.T) {
+	var bus *Bus
+	bus.Publish(context.Background(), Event{Topic: UserCreated})
+}
+
+// TestTopicValid tests topic validation
+func TestTopicValid(t *testing.T) {
+	if !IngestionCompleted.Valid() || Topic("ingestion.started").Valid() {
+		t.Error("Expected only published topics to be valid")
+	}
+}
+
+// TestWebhook tests that webhooks post the event as signed JSON
+func TestWebhook(t *testing.T) {
+	var body []byte
+	var headers http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		headers = r.Header
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	hook := NewWebhook(server.URL, "s3cret", nil)
+	e := Event{Topic: DocumentDeleted, UserID: 7, Time: time.Unix(0, 0).UTC(), Data: map[string]interface{}{"source": "a.txt"}}
+	if err := hook.post(context.Background(), e); err != nil {
+		t.Fatalf("post failed: %v", err)
+	}
+
+	var got Event
+	if err := json.Unmarshal(body, &got); err != nil {
+		t.Fatalf("Expected a JSON body, got %q", body)
+	}
+	if got.Topic != DocumentDeleted || got.UserID != 7 || got.Data["source"] != "a.txt" {
+		t.Errorf("Unexpected event %+v", got)
+	}
+	if headers.Get("X-Noodexx-Topic") != "document.deleted" {
+		t.Errorf("Expected the topic header, got %q", headers.Get("X-Noodexx-Topic"))
+	}
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write(body)
+	if want := "sha256=" + hex.EncodeToString(mac.Sum(nil)); headers.Get("X-Noodexx-Signature") != want {
+		t.Errorf("Expected signature %s, got %s", want, headers.Get("X-Noodexx-Signature"))
+	}
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+	if err := NewWebhook(failing.URL, "", nil).post(context.Background(), e); err == nil {
+		t.Error("Expected an error when the receiver fails")
+	}
+	if !bytes.Contains(body, []byte(`"topic":"document.deleted"`)) {
+		t.Errorf("Expected the topic in the body, got %s", body)
+	}
+}
@@ -0,0 +1,75 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"noodexx/internal/logging"
+	"time"
+)
+
+// webhookTimeout bounds one delivery, so a slow receiver only delays its own queue
+const webhookTimeout = 10 * time.Second
+
+// Webhook posts events as JSON to a URL. With a secret, each body is signed
+// with HMAC-SHA256 and the hex digest sent as "X-Noodexx-Signature: sha256=<digest>"
+type Webhook struct {
+	url    string
+	secret string
+	client *http.Client
+	logger *logging.Logger
+}
+
+// NewWebhook creates a webhook posting to url
+func NewWebhook(url, secret string, logger *logging.Logger) *Webhook {
+	return &Webhook{
+		url:    url,
+		secret: secret,
+		client: &http.Client{Timeout: webhookTimeout},
+		logger: logger,
+	}
+}
+
+// Handle posts one event; subscribe it with SubscribeAsync. Failures are
+// logged and not retried
+func (w *Webhook) Handle(ctx context.Context, e Event) {
+	if err := w.post(ctx, e); err != nil && w.logger != nil {
+		w.logger.Warn("Failed to send %s event to webhook %s: %v", e.Topic, w.url, err)
+	}
+}
+
+// post sends the event and checks the receiver accepted it
+func (w *Webhook) post(ctx context.Context, e Event) error {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Noodexx-Topic", string(e.Topic))
+	if w.secret != "" {
+		mac := hmac.New(sha256.New, []byte(w.secret))
+		mac.Write(body)
+		req.Header.Set("X-Noodexx-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("receiver returned %s", resp.Status)
+	}
+	return nil
+}
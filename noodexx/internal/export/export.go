@@ -0,0 +1,230 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"html"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Message is a chat message to be rendered in an export
+type Message struct {
+	Role         string   // "user" or "assistant"
+	Content      string   // Message text, may contain [n] citation markers
+	ProviderMode string   // "local" or "cloud" (assistant messages only)
+	Citations    []string // citations[i] is the source referenced by marker [i+1]
+	CreatedAt    time.Time
+}
+
+// Transcript is a chat session prepared for export
+type Transcript struct {
+	SessionID  string
+	ExportedAt time.Time
+	Messages   []Message
+}
+
+// footnote is a resolved citation with its document-wide number
+type footnote struct {
+	number int
+	source string
+}
+
+// citationMarker matches numbered citation markers such as [1] or [12]
+var citationMarker = regexp.MustCompile(`\[(\d+)\]`)
+
+// providerBadge returns the human-readable badge for an assistant message's provider mode
+func providerBadge(mode string) string {
+	if mode == "cloud" {
+		return "Cloud AI"
+	}
+	return "Local AI"
+}
+
+// resolveCitations rewrites a message's [n] markers to document-wide footnote numbers
+// Cited sources that are never referenced inline are appended after the message so
+// every source that informed the answer is still listed. The format function renders
+// a single footnote reference.
+func resolveCitations(msg Message, next *int, format func(int) string) (string, []footnote) {
+	if len(msg.Citations) == 0 {
+		return msg.Content, nil
+	}
+
+	numbers := make(map[int]int) // local marker -> document-wide footnote number
+	var notes []footnote
+
+	assign := func(local int) int {
+		if n, ok := numbers[local]; ok {
+			return n
+		}
+		*next++
+		numbers[local] = *next
+		notes = append(notes, footnote{number: *next, source: msg.Citations[local-1]})
+		return *next
+	}
+
+	content := citationMarker.ReplaceAllStringFunc(msg.Content, func(marker string) string {
+		local, err := strconv.Atoi(marker[1 : len(marker)-1])
+		if err != nil || local < 1 || local > len(msg.Citations) {
+			return marker
+		}
+		return format(assign(local))
+	})
+
+	// List sources the model didn't reference inline
+	var trailing []string
+	for local := 1; local <= len(msg.Citations); local++ {
+		if _, ok := numbers[local]; !ok {
+			trailing = append(trailing, format(assign(local)))
+		}
+	}
+	if len(trailing) > 0 {
+		content += " " + strings.Join(trailing, "")
+	}
+
+	return content, notes
+}
+
+// RenderMarkdown renders a transcript as Markdown with provider badges and citation footnotes
+func RenderMarkdown(t Transcript) string {
+	var sb strings.Builder
+
+	sb.WriteString(fmt.Sprintf("# Chat Session %s\n\n", t.SessionID))
+	sb.WriteString(fmt.Sprintf("_Exported %s_\n\n", t.ExportedAt.Format("2006-01-02 15:04:05")))
+
+	next := 0
+	var notes []footnote
+
+	for _, msg := range t.Messages {
+		if msg.Role == "user" {
+			sb.WriteString("## You\n\n")
+		} else {
+			sb.WriteString(fmt.Sprintf("## Assistant `%s`\n\n", providerBadge(msg.ProviderMode)))
+		}
+
+		content, msgNotes := resolveCitations(msg, &next, func(n int) string {
+			return fmt.Sprintf("[^%d]", n)
+		})
+		notes = append(notes, msgNotes...)
+
+		sb.WriteString(content)
+		sb.WriteString("\n\n")
+	}
+
+	if len(notes) > 0 {
+		sb.WriteString("---\n\n")
+		for _, note := range notes {
+			sb.WriteString(fmt.Sprintf("[^%d]: %s\n", note.number, note.source))
+		}
+	}
+
+	return sb.String()
+}
+
+// RenderHTML renders a transcript as a standalone HTML document suitable for PDF conversion
+func RenderHTML(t Transcript) string {
+	var sb strings.Builder
+
+	title := html.EscapeString("Chat Session " + t.SessionID)
+	sb.WriteString("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n")
+	sb.WriteString("<title>" + title + "</title>\n")
+	sb.WriteString(`<style>
+body { font-family: sans-serif; max-width: 48rem; margin: 2rem auto; line-height: 1.5; }
+.message { margin-bottom: 1.5rem; }
+.role { font-weight: bold; }
+.badge { font-size: 0.75rem; padding: 0.1rem 0.4rem; border-radius: 0.25rem; margin-left: 0.5rem; }
+.badge-local { background: #dcfce7; color: #166534; }
+.badge-cloud { background: #dbeafe; color: #1e40af; }
+.content { white-space: pre-wrap; }
+.footnotes { border-top: 1px solid #ccc; font-size: 0.875rem; }
+</style>
+</head>
+<body>
+`)
+	sb.WriteString("<h1>" + title + "</h1>\n")
+	sb.WriteString("<p><em>Exported " + t.ExportedAt.Format("2006-01-02 15:04:05") + "</em></p>\n")
+
+	next := 0
+	var notes []footnote
+
+	for _, msg := range t.Messages {
+		sb.WriteString("<div class=\"message\">\n")
+		if msg.Role == "user" {
+			sb.WriteString("<div class=\"role\">You</div>\n")
+		} else {
+			badgeClass := "badge-local"
+			if msg.ProviderMode == "cloud" {
+				badgeClass = "badge-cloud"
+			}
+			sb.WriteString(fmt.Sprintf("<div class=\"role\">Assistant<span class=\"badge %s\">%s</span></div>\n", badgeClass, providerBadge(msg.ProviderMode)))
+		}
+
+		// Escape before resolving so footnote links aren't escaped
+		escaped := msg
+		escaped.Content = html.EscapeString(msg.Content)
+		content, msgNotes := resolveCitations(escaped, &next, func(n int) string {
+			return fmt.Sprintf("<sup><a href=\"#fn%d\">%d</a></sup>", n, n)
+		})
+		notes = append(notes, msgNotes...)
+
+		sb.WriteString("<div class=\"content\">" + content + "</div>\n</div>\n")
+	}
+
+	if len(notes) > 0 {
+		sb.WriteString("<ol class=\"footnotes\">\n")
+		for _, note := range notes {
+			sb.WriteString(fmt.Sprintf("<li id=\"fn%d\" value=\"%d\">%s</li>\n", note.number, note.number, html.EscapeString(note.source)))
+		}
+		sb.WriteString("</ol>\n")
+	}
+
+	sb.WriteString("</body>\n</html>\n")
+	return sb.String()
+}
+
+// PDFRenderer converts an HTML document to PDF
+type PDFRenderer interface {
+	RenderPDF(ctx context.Context, htmlDoc string) ([]byte, error)
+}
+
+// CommandPDFRenderer renders PDFs by piping HTML through an external HTML-to-PDF
+// command (e.g. "wkhtmltopdf - -") that reads HTML on stdin and writes PDF to stdout
+type CommandPDFRenderer struct {
+	command string
+	args    []string
+}
+
+// NewCommandPDFRenderer creates a renderer from a command line such as "wkhtmltopdf - -"
+func NewCommandPDFRenderer(commandLine string) (*CommandPDFRenderer, error) {
+	fields := strings.Fields(commandLine)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("PDF renderer command is not configured")
+	}
+	if _, err := exec.LookPath(fields[0]); err != nil {
+		return nil, fmt.Errorf("PDF renderer %q not found: %w", fields[0], err)
+	}
+	return &CommandPDFRenderer{command: fields[0], args: fields[1:]}, nil
+}
+
+// RenderPDF runs the configured command and returns its stdout
+func (r *CommandPDFRenderer) RenderPDF(ctx context.Context, htmlDoc string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, r.command, r.args...)
+	cmd.Stdin = strings.NewReader(htmlDoc)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("PDF rendering failed: %w (stderr: %s)", err, stderr.String())
+	}
+	if stdout.Len() == 0 {
+		return nil, fmt.Errorf("PDF renderer produced no output")
+	}
+
+	return stdout.Bytes(), nil
+}
@@ -0,0 +1,107 @@
+package export
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testTranscript() Transcript {
+	return Transcript{
+		SessionID:  "abc123",
+		ExportedAt: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC),
+		Messages: []Message{
+			{Role: "user", Content: "What is the leave policy?"},
+			{
+				Role:         "assistant",
+				Content:      "Employees get 20 days [2]. See also [2] and [9].",
+				ProviderMode: "local",
+				Citations:    []string{"handbook.md", "policies/leave.md"},
+			},
+			{Role: "user", Content: "Thanks <3"},
+			{
+				Role:         "assistant",
+				Content:      "You're welcome.",
+				ProviderMode: "cloud",
+				Citations:    []string{"faq.md"},
+			},
+		},
+	}
+}
+
+// TestRenderMarkdownFootnotes tests that citation markers resolve to document-wide footnotes
+func TestRenderMarkdownFootnotes(t *testing.T) {
+	md := RenderMarkdown(testTranscript())
+
+	expected := []string{
+		"# Chat Session abc123",
+		"## You",
+		"## Assistant `Local AI`",
+		"## Assistant `Cloud AI`",
+		// [2] is referenced first so it becomes footnote 1; [9] is out of range and left alone
+		"Employees get 20 days [^1]. See also [^1] and [9]. [^2]",
+		"You're welcome. [^3]",
+		"[^1]: policies/leave.md",
+		"[^2]: handbook.md",
+		"[^3]: faq.md",
+	}
+	for _, want := range expected {
+		if !strings.Contains(md, want) {
+			t.Errorf("Expected markdown to contain %q, got:\n%s", want, md)
+		}
+	}
+}
+
+// TestRenderMarkdownWithoutCitations tests that messages without citations have no footnotes
+func TestRenderMarkdownWithoutCitations(t *testing.T) {
+	md := RenderMarkdown(Transcript{
+		SessionID: "s1",
+		Messages:  []Message{{Role: "assistant", Content: "Plain answer [1]", ProviderMode: "local"}},
+	})
+
+	if strings.Contains(md, "[^") {
+		t.Errorf("Expected no footnotes, got:\n%s", md)
+	}
+	if !strings.Contains(md, "Plain answer [1]") {
+		t.Errorf("Expected content to be unchanged, got:\n%s", md)
+	}
+}
+
+// TestRenderHTMLEscapesContent tests that message content is escaped in HTML exports
+func TestRenderHTMLEscapesContent(t *testing.T) {
+	doc := RenderHTML(testTranscript())
+
+	if !strings.Contains(doc, "Thanks &lt;3") {
+		t.Errorf("Expected user content to be escaped, got:\n%s", doc)
+	}
+	if !strings.Contains(doc, `<sup><a href="#fn1">1</a></sup>`) {
+		t.Errorf("Expected footnote links, got:\n%s", doc)
+	}
+	if !strings.Contains(doc, `badge-cloud`) || !strings.Contains(doc, `badge-local`) {
+		t.Errorf("Expected provider badges, got:\n%s", doc)
+	}
+}
+
+// TestNewCommandPDFRenderer tests renderer construction
+func TestNewCommandPDFRenderer(t *testing.T) {
+	if _, err := NewCommandPDFRenderer(""); err == nil {
+		t.Error("Expected error for empty command")
+	}
+	if _, err := NewCommandPDFRenderer("definitely-not-a-real-pdf-tool - -"); err == nil {
+		t.Error("Expected error for missing command")
+	}
+
+	// cat echoes the HTML back, which is enough to exercise the pipe
+	renderer, err := NewCommandPDFRenderer("cat")
+	if err != nil {
+		t.Skipf("cat not available: %v", err)
+	}
+	out, err := renderer.RenderPDF(context.Background(), "<html></html>")
+	if err != nil {
+		t.Fatalf("RenderPDF failed: %v", err)
+	}
+	if string(out) != "<html></html>" {
+		t.Errorf("Expected command output to be returned, got %q", out)
+	}
+}
@@ -0,0 +1,237 @@
+// Package extract turns documents into typed records, such as an invoice's
+// vendor, date and amount. The record's JSON schema is given to the model with
+// each part of the document, the parts' replies are merged into one record, and
+// a record that does not match the schema is sent back once with its violations
+// to be corrected.
+package extract
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"noodexx/internal/skills"
+	"sort"
+	"strings"
+)
+
+// Record statuses
+const (
+	StatusOK      = "ok"      // The record matches the schema
+	StatusInvalid = "invalid" // A record was extracted but still has violations
+	StatusFailed  = "failed"  // No record could be extracted
+)
+
+// Generator sends one prompt to the model and returns its reply
+type Generator interface {
+	Generate(ctx context.Context, prompt string) (string, error)
+}
+
+// Result is the outcome of extracting one document
+type Result struct {
+	Record     map[string]interface{} `json:"record,omitempty"`
+	Status     string                 `json:"status"`
+	Violations []skills.Violation     `json:"violations,omitempty"`
+	Error      string                 `json:"error,omitempty"`
+}
+
+// ParseSchema decodes and checks a record schema. Records are JSON objects, so
+// the schema must describe an object with at least one property
+func ParseSchema(data []byte) (*skills.Schema, error) {
+	var schema skills.Schema
+	if err := json.Unmarshal(data, &schema); err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+	if schema.Type != "object" || len(schema.Properties) == 0 {
+		return nil, fmt.Errorf("schema must be an object with properties")
+	}
+	if err := schema.Check(); err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+	return &schema, nil
+}
+
+// Columns returns a schema's properties in table order: the required ones as
+// listed, then the rest by name
+func Columns(schema *skills.Schema) []string {
+	columns := make([]string, 0, len(schema.Properties))
+	seen := make(map[string]bool)
+	for _, name := range schema.Required {
+		if _, ok := schema.Properties[name]; ok && !seen[name] {
+			columns = append(columns, name)
+			seen[name] = true
+		}
+	}
+	var rest []string
+	for name := range schema.Properties {
+		if !seen[name] {
+			rest = append(rest, name)
+		}
+	}
+	sort.Strings(rest)
+	return append(columns, rest...)
+}
+
+// Extractor extracts records matching one schema
+type Extractor struct {
+	Schema    *skills.Schema
+	Generator Generator
+}
+
+// Extract builds one record from the parts of a document, each small enough for
+// one prompt. A field found in several parts keeps its first value, except lists,
+// which are joined
+func (e *Extractor) Extract(ctx context.Context, source string, parts []string) Result {
+	schemaJSON, err := json.MarshalIndent(e.Schema, "", "  ")
+	if err != nil {
+		return Result{Status: StatusFailed, Error: fmt.Sprintf("invalid schema: %v", err)}
+	}
+
+	record := make(map[string]interface{})
+	var parseErr error
+	parsed := 0
+	for i, part := range parts {
+		location := fmt.Sprintf("the document %q", source)
+		if len(parts) > 1 {
+			location = fmt.Sprintf("part %d of %d of the document %q", i+1, len(parts), source)
+		}
+		prompt := fmt.Sprintf("Extract a record from %s.\n\n"+
+			"The record must be a JSON object matching this JSON Schema:\n%s\n\n"+
+			"Document text:\n%s\n\n"+
+			"Reply with only the JSON object. Copy names, dates and numbers exactly as written, "+
+			"and use null for any field the text does not contain.",
+			location, schemaJSON, part)
+
+		fields, err := e.generateObject(ctx, prompt)
+		if err != nil {
+			if ctx.Err() != nil || !isParseError(err) {
+				return Result{Status: StatusFailed, Error: err.Error()}
+			}
+			parseErr = err
+			continue
+		}
+		merge(record, fields)
+		parsed++
+	}
+	if parsed == 0 {
+		msg := "document has no text"
+		if parseErr != nil {
+			msg = parseErr.Error()
+		}
+		return Result{Status: StatusFailed, Error: msg}
+	}
+
+	violations := e.Schema.Validate(record)
+	if len(violations) > 0 {
+		if repaired, ok := e.repair(ctx, schemaJSON, record, violations); ok {
+			record = repaired
+			violations = e.Schema.Validate(record)
+		}
+	}
+	if len(violations) > 0 {
+		return Result{Record: record, Status: StatusInvalid, Violations: violations}
+	}
+	return Result{Record: record, Status: StatusOK}
+}
+
+// repair asks the model to correct a record's violations, reporting whether it
+// replied with a record that has fewer of them
+func (e *Extractor) repair(ctx context.Context, schemaJSON []byte, record map[string]interface{}, violations []skills.Violation) (map[string]interface{}, bool) {
+	recordJSON, err := json.Marshal(record)
+	if err != nil {
+		return nil, false
+	}
+	problems := make([]string, len(violations))
+	for i, v := range violations {
+		problems[i] = fmt.Sprintf("- %s: %s", v.Path, v.Message)
+	}
+	prompt := fmt.Sprintf("This record was extracted from a document:\n%s\n\n"+
+		"It does not match its JSON Schema:\n%s\n\n"+
+		"Problems:\n%s\n\n"+
+		"Reply with only the corrected JSON object. Convert values to the required types and formats, "+
+		"but do not invent values; leave out fields you cannot fill.",
+		recordJSON, schemaJSON, strings.Join(problems, "\n"))
+
+	fields, err := e.generateObject(ctx, prompt)
+	if err != nil {
+		return nil, false
+	}
+	repaired := make(map[string]interface{})
+	merge(repaired, fields)
+	if len(e.Schema.Validate(repaired)) >= len(violations) {
+		return nil, false
+	}
+	return repaired, true
+}
+
+// parseError is a reply that is not a JSON object
+type parseError struct {
+	err error
+}
+
+func (e *parseError) Error() string {
+	return fmt.Sprintf("model did not reply with a JSON object: %v", e.err)
+}
+
+func isParseError(err error) bool {
+	var pe *parseError
+	return errors.As(err, &pe)
+}
+
+// generateObject sends a prompt and decodes the JSON object in the reply,
+// asking once more when the reply has none
+func (e *Extractor) generateObject(ctx context.Context, prompt string) (map[string]interface{}, error) {
+	reply, err := e.Generator.Generate(ctx, prompt)
+	if err != nil {
+		return nil, err
+	}
+	fields, err := parseObject(reply)
+	if err == nil {
+		return fields, nil
+	}
+
+	retry := prompt + "\n\nYour previous reply could not be read as a JSON object (" + err.Error() + "). " +
+		"Reply with the JSON object alone, without any other text."
+	reply, err = e.Generator.Generate(ctx, retry)
+	if err != nil {
+		return nil, err
+	}
+	if fields, err = parseObject(reply); err != nil {
+		return nil, &parseError{err: err}
+	}
+	return fields, nil
+}
+
+// parseObject decodes the JSON object in a model reply, ignoring text and code
+// fences around it
+func parseObject(reply string) (map[string]interface{}, error) {
+	start := strings.Index(reply, "{")
+	end := strings.LastIndex(reply, "}")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("no object found")
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal([]byte(reply[start:end+1]), &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// merge adds the non-null fields of one part's reply to the record
+func merge(record, fields map[string]interface{}) {
+	for name, value := range fields {
+		if value == nil {
+			continue
+		}
+		existing, ok := record[name]
+		if !ok {
+			record[name] = value
+			continue
+		}
+		if list, ok := existing.([]interface{}); ok {
+			if more, ok := value.([]interface{}); ok {
+				record[name] = append(list, more...)
+			}
+		}
+	}
+}
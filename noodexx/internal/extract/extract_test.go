@@ -0,0 +1,139 @@
+package extract
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// scriptedGenerator replies with canned answers in order and records the prompts
+type scriptedGenerator struct {
+	replies []string
+	prompts []string
+}
+
+func (g *scriptedGenerator) Generate(ctx context.Context, prompt string) (string, error) {
+	g.prompts = append(g.prompts, prompt)
+	if len(g.replies) == 0 {
+		return "", errors.New("provider unavailable")
+	}
+	reply := g.replies[0]
+	g.replies = g.replies[1:]
+	return reply, nil
+}
+
+const invoiceSchema = `{
+	"type": "object",
+	"properties": {
+		"vendor": {"type": "string"},
+		"amount": {"type": "number"},
+		"date": {"type": "string"},
+		"items": {"type": "array", "items": {"type": "string"}}
+	},
+	"required": ["vendor", "amount"]
+}`
+
+func newTestExtractor(t *testing.T, replies ...string) (*Extractor, *scriptedGenerator) {
+	t.Helper()
+	schema, err := ParseSchema([]byte(invoiceSchema))
+	if err != nil {
+		t.Fatalf("ParseSchema failed: %v", err)
+	}
+	gen := &scriptedGenerator{replies: replies}
+	return &Extractor{Schema: schema, Generator: gen}, gen
+}
+
+func TestParseSchema(t *testing.T) {
+	for _, bad := range []string{
+		`not json`,
+		`{"type": "string"}`,
+		`{"type": "object"}`,
+		`{"type": "object", "properties": {"amount": {"type": "money"}}}`,
+	} {
+		if _, err := ParseSchema([]byte(bad)); err == nil {
+			t.Errorf("Expected %s to be rejected", bad)
+		}
+	}
+
+	schema, err := ParseSchema([]byte(invoiceSchema))
+	if err != nil {
+		t.Fatalf("ParseSchema failed: %v", err)
+	}
+	if got := Columns(schema); !reflect.DeepEqual(got, []string{"vendor", "amount", "date", "items"}) {
+		t.Errorf("Expected required columns first, got %v", got)
+	}
+}
+
+func TestExtract(t *testing.T) {
+	ex, gen := newTestExtractor(t, "Here it is:\n```json\n{\"vendor\": \"Acme\", \"amount\": 120.5, \"date\": null}\n```")
+	result := ex.Extract(context.Background(), "invoice.pdf", []string{"Acme Corp invoice, total $120.50"})
+	if result.Status != StatusOK || result.Record["vendor"] != "Acme" || result.Record["amount"] != 120.5 {
+		t.Fatalf("Expected the record, got %+v", result)
+	}
+	if _, ok := result.Record["date"]; ok {
+		t.Error("Expected null fields to be left out")
+	}
+	if !strings.Contains(gen.prompts[0], `"vendor"`) || !strings.Contains(gen.prompts[0], "Acme Corp invoice") {
+		t.Errorf("Expected the schema and text in the prompt, got %s", gen.prompts[0])
+	}
+}
+
+func TestExtractMergesParts(t *testing.T) {
+	ex, _ := newTestExtractor(t,
+		`{"vendor": "Acme", "amount": null, "items": ["bolts"]}`,
+		`{"vendor": "Acme Corp", "amount": 99, "items": ["nuts"]}`,
+	)
+	result := ex.Extract(context.Background(), "invoice.pdf", []string{"page one", "page two"})
+	if result.Status != StatusOK {
+		t.Fatalf("Expected a valid record, got %+v", result)
+	}
+	if result.Record["vendor"] != "Acme" || result.Record["amount"] != float64(99) {
+		t.Errorf("Expected the first value of each field, got %v", result.Record)
+	}
+	if !reflect.DeepEqual(result.Record["items"], []interface{}{"bolts", "nuts"}) {
+		t.Errorf("Expected lists to be joined, got %v", result.Record["items"])
+	}
+}
+
+func TestExtractRepairsAndRetries(t *testing.T) {
+	// An unreadable reply is asked for again, and a record with violations is
+	// sent back to be corrected
+	ex, gen := newTestExtractor(t,
+		"I could not find a JSON object",
+		`{"vendor": "Acme", "amount": "$120.50"}`,
+		`{"vendor": "Acme", "amount": 120.5}`,
+	)
+	result := ex.Extract(context.Background(), "invoice.pdf", []string{"Acme Corp invoice"})
+	if result.Status != StatusOK || result.Record["amount"] != 120.5 {
+		t.Fatalf("Expected the repaired record, got %+v", result)
+	}
+	if len(gen.prompts) != 3 || !strings.Contains(gen.prompts[2], "$.amount: expected number") {
+		t.Errorf("Expected a repair prompt listing the violation, got %v", gen.prompts)
+	}
+
+	// A repair that does not help keeps the original with its violations
+	ex, _ = newTestExtractor(t, `{"amount": "lots"}`, `{"amount": "still lots"}`)
+	result = ex.Extract(context.Background(), "invoice.pdf", []string{"text"})
+	if result.Status != StatusInvalid || result.Record["amount"] != "lots" || len(result.Violations) != 2 {
+		t.Errorf("Expected the invalid record with its violations, got %+v", result)
+	}
+}
+
+func TestExtractFailures(t *testing.T) {
+	ex, _ := newTestExtractor(t)
+	if result := ex.Extract(context.Background(), "invoice.pdf", []string{"text"}); result.Status != StatusFailed || result.Error != "provider unavailable" {
+		t.Errorf("Expected the provider error, got %+v", result)
+	}
+
+	ex, _ = newTestExtractor(t, "no", "still no")
+	if result := ex.Extract(context.Background(), "invoice.pdf", []string{"text"}); result.Status != StatusFailed || !strings.Contains(result.Error, "JSON object") {
+		t.Errorf("Expected a parse failure, got %+v", result)
+	}
+
+	ex, _ = newTestExtractor(t)
+	if result := ex.Extract(context.Background(), "empty.txt", nil); result.Status != StatusFailed {
+		t.Errorf("Expected a document without text to fail, got %+v", result)
+	}
+}
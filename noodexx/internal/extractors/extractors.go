@@ -0,0 +1,409 @@
+// Package extractors converts files into text with external programs, so new
+// formats can be supported without building them into Noodexx. Like a skill,
+// each extractor is a directory holding a manifest, extractor.json, and an
+// executable. The manifest registers the extractor for MIME types and file
+// extensions; the executable receives the file on stdin and writes a JSON
+// object with its text and metadata to stdout.
+//
+// Extractors run in an empty scratch directory with a minimal environment, and
+// are stopped when they run too long or write too much.
+package extractors
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"noodexx/internal/logging"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ManifestFile is the name of an extractor's manifest in its directory
+const ManifestFile = "extractor.json"
+
+// defaultTimeout applies to extractors whose manifest sets none
+const defaultTimeout = 30 * time.Second
+
+// maxStderrExcerpt is the number of bytes of stderr kept in errors
+const maxStderrExcerpt = 2048
+
+// stopWaitDelay is how long a stopped extractor's output pipes are waited on
+// before they are closed, in case processes it started still hold them
+const stopWaitDelay = time.Second
+
+// Manifest is the extractor.json structure
+type Manifest struct {
+	Name        string   `json:"name"`
+	Version     string   `json:"version"`
+	Description string   `json:"description"`
+	Executable  string   `json:"executable"`
+	MIMETypes   []string `json:"mime_types"` // Exact types, or a whole family such as "image/*"
+	Extensions  []string `json:"extensions"` // Such as ".docx"; used when an upload's type is unknown
+	Timeout     int      `json:"timeout"`    // seconds
+}
+
+// Extractor is a loaded extractor
+type Extractor struct {
+	Name        string
+	Version     string
+	Description string
+	Executable  string
+	MIMETypes   []string
+	Extensions  []string
+	Timeout     time.Duration
+	Path        string
+}
+
+// Output is the JSON an extractor writes to stdout
+type Output struct {
+	Text     string                 `json:"text"`
+	Metadata map[string]interface{} `json:"metadata"`
+	Error    string                 `json:"error"`
+}
+
+// Result is the normalized output of an extractor
+type Result struct {
+	Extractor string
+	Text      string                 // Valid UTF-8 with \n line endings
+	Title     string                 // From metadata "title", if set
+	Tags      []string               // From metadata "tags", if set
+	Metadata  map[string]interface{} // Everything the extractor reported
+}
+
+// Limits are server-side guardrails on extractor runs, enforced whatever an
+// extractor's manifest asks for. Zero values leave a limit off
+type Limits struct {
+	MaxRuntime     time.Duration // Caps each extractor's manifest timeout
+	MaxOutputBytes int           // Largest stdout accepted from an extractor
+}
+
+// Registry holds the loaded extractors by the types they handle
+type Registry struct {
+	extractors []*Extractor
+	byMIME     map[string]*Extractor // Exact MIME types and "type/*" families
+	byExt      map[string]*Extractor
+	limits     Limits
+	logger     *logging.Logger
+}
+
+// Load loads every extractor in dir, one per subdirectory with a manifest. A
+// missing directory loads none. When two extractors claim a type, the first by
+// directory name keeps it
+func Load(dir string, logger *logging.Logger) (*Registry, error) {
+	r := &Registry{
+		byMIME: make(map[string]*Extractor),
+		byExt:  make(map[string]*Extractor),
+		logger: logger,
+	}
+
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		logger.WithContext("extractors_dir", dir).Debug("extractors directory does not exist")
+		return r, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read extractors directory: %w", err)
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		if _, err := os.Stat(filepath.Join(path, ManifestFile)); os.IsNotExist(err) {
+			continue
+		}
+		ex, err := loadExtractor(path)
+		if err != nil {
+			logger.WithFields(map[string]interface{}{
+				"extractor": entry.Name(),
+				"error":     err.Error(),
+			}).Warn("failed to load extractor")
+			continue
+		}
+		r.add(ex)
+	}
+
+	logger.WithContext("count", len(r.extractors)).Debug("extractors loaded")
+	return r, nil
+}
+
+// loadExtractor reads and checks an extractor's manifest
+func loadExtractor(path string) (*Extractor, error) {
+	data, err := os.ReadFile(filepath.Join(path, ManifestFile))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", ManifestFile, err)
+	}
+	var meta Manifest
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", ManifestFile, err)
+	}
+
+	if meta.Name == "" {
+		return nil, fmt.Errorf("%s missing required field: name", ManifestFile)
+	}
+	if meta.Executable == "" {
+		return nil, fmt.Errorf("%s missing required field: executable", ManifestFile)
+	}
+	if len(meta.MIMETypes) == 0 && len(meta.Extensions) == 0 {
+		return nil, fmt.Errorf("%s must list mime_types or extensions", ManifestFile)
+	}
+
+	mimeTypes := make([]string, 0, len(meta.MIMETypes))
+	for _, t := range meta.MIMETypes {
+		mediaType, _, err := mime.ParseMediaType(t)
+		if err != nil {
+			return nil, fmt.Errorf("invalid MIME type %q", t)
+		}
+		mimeTypes = append(mimeTypes, mediaType)
+	}
+	extensions := make([]string, 0, len(meta.Extensions))
+	for _, ext := range meta.Extensions {
+		ext = strings.ToLower(ext)
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		extensions = append(extensions, ext)
+	}
+
+	// The executable must be inside the extractor's directory
+	execPath := filepath.Join(path, meta.Executable)
+	if rel, err := filepath.Rel(path, execPath); err != nil || strings.HasPrefix(rel, "..") {
+		return nil, fmt.Errorf("executable must be inside the extractor directory: %s", meta.Executable)
+	}
+	info, err := os.Stat(execPath)
+	if err != nil {
+		return nil, fmt.Errorf("executable not found: %s", execPath)
+	}
+	if info.Mode()&0111 == 0 {
+		return nil, fmt.Errorf("executable %s does not have execute permissions", execPath)
+	}
+
+	timeout := time.Duration(meta.Timeout) * time.Second
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+
+	return &Extractor{
+		Name:        meta.Name,
+		Version:     meta.Version,
+		Description: meta.Description,
+		Executable:  execPath,
+		MIMETypes:   mimeTypes,
+		Extensions:  extensions,
+		Timeout:     timeout,
+		Path:        path,
+	}, nil
+}
+
+// add registers an extractor for the types it handles that are not taken
+func (r *Registry) add(ex *Extractor) {
+	r.extractors = append(r.extractors, ex)
+	for _, t := range ex.MIMETypes {
+		if _, taken := r.byMIME[t]; !taken {
+			r.byMIME[t] = ex
+		}
+	}
+	for _, ext := range ex.Extensions {
+		if _, taken := r.byExt[ext]; !taken {
+			r.byExt[ext] = ex
+		}
+	}
+}
+
+// SetLimits sets the guardrails applied to every run from now on
+func (r *Registry) SetLimits(limits Limits) {
+	r.limits = limits
+}
+
+// Extractors returns the loaded extractors
+func (r *Registry) Extractors() []*Extractor {
+	return r.extractors
+}
+
+// Extensions returns the file extensions extractors are registered for
+func (r *Registry) Extensions() []string {
+	exts := make([]string, 0, len(r.byExt))
+	for ext := range r.byExt {
+		exts = append(exts, ext)
+	}
+	sort.Strings(exts)
+	return exts
+}
+
+// Lookup returns the extractor for a file, or nil if there is none. An exact
+// MIME type match wins, then the file's extension, then a MIME type family.
+// Without a useful MIME type, the one for the extension is used
+func (r *Registry) Lookup(filename, mimeType string) *Extractor {
+	ext := strings.ToLower(filepath.Ext(filename))
+	mediaType, _, err := mime.ParseMediaType(mimeType)
+	if err != nil || mediaType == "application/octet-stream" {
+		mediaType, _, _ = mime.ParseMediaType(mime.TypeByExtension(ext))
+	}
+
+	if ex, ok := r.byMIME[mediaType]; ok && mediaType != "" {
+		return ex
+	}
+	if ex, ok := r.byExt[ext]; ok && ext != "" {
+		return ex
+	}
+	if family, _, ok := strings.Cut(mediaType, "/"); ok {
+		if ex, ok := r.byMIME[family+"/*"]; ok {
+			return ex
+		}
+	}
+	return nil
+}
+
+// Extract converts a file with the extractor registered for it, returning nil
+// when there is none
+func (r *Registry) Extract(ctx context.Context, filename, mimeType string, data []byte) (*Result, error) {
+	ex := r.Lookup(filename, mimeType)
+	if ex == nil {
+		return nil, nil
+	}
+	return r.Run(ctx, ex, filename, mimeType, data)
+}
+
+// Run converts a file with an extractor
+func (r *Registry) Run(ctx context.Context, ex *Extractor, filename, mimeType string, data []byte) (*Result, error) {
+	logger := r.logger.WithFields(map[string]interface{}{
+		"extractor": ex.Name,
+		"file_name": filename,
+		"file_size": len(data),
+	})
+	logger.Debug("starting extractor")
+
+	timeout := ex.Timeout
+	if r.limits.MaxRuntime > 0 && timeout > r.limits.MaxRuntime {
+		timeout = r.limits.MaxRuntime
+	}
+	runCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	// The extractor gets an empty directory for scratch files, removed afterwards
+	scratch, err := os.MkdirTemp("", "noodexx-extract-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create scratch directory: %w", err)
+	}
+	defer os.RemoveAll(scratch)
+
+	cmd := exec.CommandContext(runCtx, ex.Executable)
+	cmd.Dir = scratch
+	cmd.WaitDelay = stopWaitDelay
+	cmd.Env = buildEnv(ex, scratch, filename, mimeType)
+	cmd.Stdin = bytes.NewReader(data)
+
+	var stderr bytes.Buffer
+	stdout := &limitedBuffer{max: r.limits.MaxOutputBytes, stop: cancel}
+	cmd.Stdout = stdout
+	cmd.Stderr = &stderr
+
+	start := time.Now()
+	runErr := cmd.Run()
+	duration := time.Since(start)
+
+	if stdout.exceeded {
+		logger.WithContext("max_output_bytes", r.limits.MaxOutputBytes).Warn("extractor output too large")
+		return nil, fmt.Errorf("extractor %s wrote more than %d bytes", ex.Name, r.limits.MaxOutputBytes)
+	}
+	if runCtx.Err() == context.DeadlineExceeded {
+		logger.WithContext("timeout", timeout.String()).Warn("extractor timed out")
+		return nil, fmt.Errorf("extractor %s timed out after %v", ex.Name, timeout)
+	}
+
+	var output Output
+	if err := json.Unmarshal(stdout.buf.Bytes(), &output); err != nil {
+		if runErr != nil {
+			return nil, fmt.Errorf("extractor %s failed: %v (stderr: %s)", ex.Name, runErr, stderrExcerpt(stderr.Bytes()))
+		}
+		return nil, fmt.Errorf("failed to parse extractor %s output: %w", ex.Name, err)
+	}
+	if output.Error != "" {
+		return nil, fmt.Errorf("extractor %s: %s", ex.Name, output.Error)
+	}
+	if runErr != nil {
+		return nil, fmt.Errorf("extractor %s failed: %v (stderr: %s)", ex.Name, runErr, stderrExcerpt(stderr.Bytes()))
+	}
+
+	result := normalize(ex.Name, output)
+	logger.WithFields(map[string]interface{}{
+		"duration_ms": duration.Milliseconds(),
+		"text_size":   len(result.Text),
+	}).Debug("extractor completed")
+	return result, nil
+}
+
+// buildEnv creates the extractor's environment: nothing of the server's but
+// PATH, with its scratch directory as HOME and TMPDIR
+func buildEnv(ex *Extractor, scratch, filename, mimeType string) []string {
+	return []string{
+		"PATH=" + os.Getenv("PATH"),
+		"HOME=" + scratch,
+		"TMPDIR=" + scratch,
+		"NOODEXX_EXTRACTOR_NAME=" + ex.Name,
+		"NOODEXX_EXTRACTOR_DIR=" + ex.Path,
+		"NOODEXX_FILE_NAME=" + filepath.Base(filename),
+		"NOODEXX_MIME_TYPE=" + mimeType,
+	}
+}
+
+// normalize makes an extractor's text valid UTF-8 with \n line endings, and
+// picks the title and tags out of its metadata
+func normalize(name string, output Output) *Result {
+	text := strings.ToValidUTF8(output.Text, "�")
+	text = strings.ReplaceAll(text, "\x00", "")
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\r", "\n")
+
+	result := &Result{
+		Extractor: name,
+		Text:      strings.TrimSpace(text),
+		Metadata:  output.Metadata,
+	}
+	if title, ok := output.Metadata["title"].(string); ok {
+		result.Title = strings.TrimSpace(title)
+	}
+	if tags, ok := output.Metadata["tags"].([]interface{}); ok {
+		for _, tag := range tags {
+			if s, ok := tag.(string); ok && strings.TrimSpace(s) != "" {
+				result.Tags = append(result.Tags, strings.TrimSpace(s))
+			}
+		}
+	}
+	return result
+}
+
+// stderrExcerpt keeps the end of stderr, where errors usually are
+func stderrExcerpt(stderr []byte) string {
+	if len(stderr) > maxStderrExcerpt {
+		stderr = stderr[len(stderr)-maxStderrExcerpt:]
+	}
+	return strings.TrimSpace(string(stderr))
+}
+
+// limitedBuffer collects output and stops the extractor once it exceeds max
+type limitedBuffer struct {
+	buf      bytes.Buffer
+	max      int // 0 for no limit
+	stop     func()
+	exceeded bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if b.exceeded {
+		return len(p), nil
+	}
+	if b.max > 0 && b.buf.Len()+len(p) > b.max {
+		b.exceeded = true
+		b.stop()
+		return len(p), nil
+	}
+	return b.buf.Write(p)
+}
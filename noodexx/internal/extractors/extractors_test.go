@@ -0,0 +1,136 @@
+package extractors
+
+import (
+	"context"
+	"io"
+	"noodexx/internal/logging"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeExtractor creates an extractor directory with a manifest and script
+func writeExtractor(t *testing.T, dir, name, manifest, script string) {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.MkdirAll(path, 0755); err != nil {
+		t.Fatalf("Failed to create extractor directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(path, ManifestFile), []byte(manifest), 0644); err != nil {
+		t.Fatalf("Failed to write manifest: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(path, "extract.sh"), []byte("#!/bin/sh\n"+script), 0755); err != nil {
+		t.Fatalf("Failed to write script: %v", err)
+	}
+}
+
+func testLogger() *logging.Logger {
+	return logging.NewLogger("test", logging.ERROR, io.Discard)
+}
+
+func TestLoadAndLookup(t *testing.T) {
+	dir := t.TempDir()
+	writeExtractor(t, dir, "a-docx", `{"name": "docx", "executable": "extract.sh", "mime_types": ["application/vnd.openxmlformats-officedocument.wordprocessingml.document"], "extensions": ["docx"]}`, "cat\n")
+	writeExtractor(t, dir, "b-images", `{"name": "ocr", "executable": "extract.sh", "mime_types": ["image/*"]}`, "cat\n")
+	writeExtractor(t, dir, "c-broken", `{"name": "broken", "executable": "extract.sh"}`, "cat\n")
+	writeExtractor(t, dir, "d-escape", `{"name": "escape", "executable": "../a-docx/extract.sh", "extensions": [".x"]}`, "cat\n")
+
+	r, err := Load(dir, testLogger())
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if len(r.Extractors()) != 2 {
+		t.Fatalf("Expected the 2 valid extractors, got %d", len(r.Extractors()))
+	}
+	if exts := r.Extensions(); len(exts) != 1 || exts[0] != ".docx" {
+		t.Errorf("Expected .docx registered, got %v", exts)
+	}
+
+	tests := []struct {
+		filename, mimeType, want string
+	}{
+		{"report.docx", "application/octet-stream", "docx"}, // Generic type: by extension
+		{"report.bin", "application/vnd.openxmlformats-officedocument.wordprocessingml.document", "docx"},
+		{"scan.png", "", "ocr"}, // MIME family from the extension
+		{"photo.jpg", "image/jpeg; charset=binary", "ocr"},
+		{"notes.txt", "text/plain", ""},
+	}
+	for _, tt := range tests {
+		got := ""
+		if ex := r.Lookup(tt.filename, tt.mimeType); ex != nil {
+			got = ex.Name
+		}
+		if got != tt.want {
+			t.Errorf("Lookup(%q, %q) = %q, want %q", tt.filename, tt.mimeType, got, tt.want)
+		}
+	}
+
+	// A missing directory loads no extractors
+	r, err = Load(filepath.Join(dir, "missing"), testLogger())
+	if err != nil || len(r.Extractors()) != 0 {
+		t.Errorf("Expected no extractors from a missing directory, got %v", err)
+	}
+}
+
+func TestRunNormalizesOutput(t *testing.T) {
+	dir := t.TempDir()
+	// Echoes the file name, and checks it runs in its scratch directory
+	// without the server's environment
+	writeExtractor(t, dir, "echo", `{"name": "echo", "executable": "extract.sh", "extensions": [".echo"]}`, `
+body=$(cat)
+[ "$(pwd)" = "$HOME" ] || { echo '{"error": "not in scratch directory"}'; exit 0; }
+[ -z "$SECRET" ] || { echo '{"error": "environment leaked"}'; exit 0; }
+printf '{"text": "%s\\r\\nfrom %s\\r\\n", "metadata": {"title": " Report ", "tags": ["ocr", "", 3], "pages": 2}}' "$body" "$NOODEXX_FILE_NAME"
+`)
+	t.Setenv("SECRET", "value")
+
+	r, err := Load(dir, testLogger())
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	result, err := r.Extract(context.Background(), "/data/doc.echo", "", []byte("hello"))
+	if err != nil {
+		t.Fatalf("Extract failed: %v", err)
+	}
+	if result.Text != "hello\nfrom doc.echo" {
+		t.Errorf("Expected normalized text, got %q", result.Text)
+	}
+	if result.Title != "Report" || len(result.Tags) != 1 || result.Tags[0] != "ocr" || result.Metadata["pages"] != float64(2) {
+		t.Errorf("Unexpected metadata: %+v", result)
+	}
+
+	if result, err := r.Extract(context.Background(), "notes.txt", "text/plain", []byte("x")); result != nil || err != nil {
+		t.Errorf("Expected nothing for an unregistered type, got %v, %v", result, err)
+	}
+}
+
+func TestRunFailures(t *testing.T) {
+	dir := t.TempDir()
+	writeExtractor(t, dir, "fails", `{"name": "fails", "executable": "extract.sh", "extensions": [".fail"]}`, `echo '{"error": "encrypted document"}'`+"\n")
+	writeExtractor(t, dir, "crashes", `{"name": "crashes", "executable": "extract.sh", "extensions": [".crash"]}`, "echo 'segfault' >&2\nexit 3\n")
+	writeExtractor(t, dir, "slow", `{"name": "slow", "executable": "extract.sh", "extensions": [".slow"], "timeout": 60}`, "sleep 5\n")
+	writeExtractor(t, dir, "chatty", `{"name": "chatty", "executable": "extract.sh", "extensions": [".chatty"]}`, "head -c 100000 /dev/zero\n")
+
+	r, err := Load(dir, testLogger())
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	r.SetLimits(Limits{MaxRuntime: 200 * time.Millisecond, MaxOutputBytes: 1000})
+
+	tests := []struct {
+		filename, want string
+	}{
+		{"a.fail", "encrypted document"},
+		{"a.crash", "segfault"},
+		{"a.slow", "timed out after 200ms"},
+		{"a.chatty", "more than 1000 bytes"},
+	}
+	for _, tt := range tests {
+		_, err := r.Extract(context.Background(), tt.filename, "", []byte("data"))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: expected an error containing %q, got %v", tt.filename, tt.want, err)
+		}
+	}
+}
@@ -0,0 +1,277 @@
+// Package fairqueue admits provider calls under a global and a per-user
+// concurrency limit. Waiting calls are admitted round-robin across users, so a
+// user with many queued requests cannot starve users with only one.
+package fairqueue
+
+import (
+	"sync"
+	"time"
+)
+
+// Options configures a Queue
+type Options struct {
+	MaxActive  int // Calls running at once across all users
+	MaxPerUser int // Calls running at once for a single user
+}
+
+// Ticket is a place in the queue
+// Every ticket must be released, whether or not it was admitted
+type Ticket struct {
+	q        *Queue
+	userID   int64
+	ready    chan struct{}
+	joined   time.Time
+	queued   bool // Not admitted when it joined
+	admitted bool
+	released bool
+}
+
+// Stats is a snapshot of queue load and wait times
+type Stats struct {
+	Active     int   // Calls currently running
+	Waiting    int   // Calls currently queued
+	Users      int   // Users with running or queued calls
+	Admitted   int64 // Calls admitted since startup
+	Queued     int64 // Admitted calls that had to wait
+	Abandoned  int64 // Calls that left the queue before being admitted
+	AvgWaitMS  int64 // Mean wait of admitted calls that had to wait
+	MaxWaitMS  int64 // Longest wait of an admitted call
+	LastWaitMS int64 // Wait of the most recently admitted call that had to wait
+}
+
+// Queue is a fair admission queue for provider calls
+type Queue struct {
+	mu      sync.Mutex
+	opts    Options
+	active  int
+	running map[int64]int       // Running calls per user
+	waiting map[int64][]*Ticket // Queued tickets per user, oldest first
+	ring    []int64             // Users with queued tickets in round-robin order
+	next    int                 // Index into ring of the next user to serve
+	now     func() time.Time
+
+	admitted  int64
+	queued    int64
+	abandoned int64
+	totalWait time.Duration
+	maxWait   time.Duration
+	lastWait  time.Duration
+}
+
+// New creates a queue; limits below one are treated as one
+func New(opts Options) *Queue {
+	if opts.MaxActive < 1 {
+		opts.MaxActive = 1
+	}
+	if opts.MaxPerUser < 1 {
+		opts.MaxPerUser = 1
+	}
+	return &Queue{
+		opts:    opts,
+		running: make(map[int64]int),
+		waiting: make(map[int64][]*Ticket),
+		now:     time.Now,
+	}
+}
+
+// Join queues a call for userID
+// The ticket's Ready channel is closed once the call may run
+func (q *Queue) Join(userID int64) *Ticket {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	t := &Ticket{q: q, userID: userID, ready: make(chan struct{}), joined: q.now()}
+	if len(q.waiting[userID]) == 0 {
+		q.ring = append(q.ring, userID)
+	}
+	q.waiting[userID] = append(q.waiting[userID], t)
+	q.dispatch()
+	t.queued = !t.admitted
+	return t
+}
+
+// Ready returns a channel that is closed when the call is admitted
+func (t *Ticket) Ready() <-chan struct{} {
+	return t.ready
+}
+
+// Position returns how many queued calls, including this one, will be admitted
+// before or with this one, or 0 once it is admitted
+// It assumes round-robin order and ignores per-user limits, so it is an estimate
+func (t *Ticket) Position() int {
+	q := t.q
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if t.admitted || t.released {
+		return 0
+	}
+	index := -1
+	for i, queued := range q.waiting[t.userID] {
+		if queued == t {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return 0
+	}
+
+	// Each round admits one call per user in ring order, starting at next
+	position := index + 1
+	for offset := 0; offset < len(q.ring); offset++ {
+		userID := q.ring[(q.next+offset)%len(q.ring)]
+		if userID == t.userID {
+			// Users after this one in the ring get one fewer turn before it
+			for rest := offset + 1; rest < len(q.ring); rest++ {
+				other := q.ring[(q.next+rest)%len(q.ring)]
+				position += min(len(q.waiting[other]), index)
+			}
+			break
+		}
+		position += min(len(q.waiting[userID]), index+1)
+	}
+	return position
+}
+
+// Release frees the ticket's slot, or leaves the queue if it was not admitted yet
+// It is safe to call more than once
+func (t *Ticket) Release() {
+	q := t.q
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if t.released {
+		return
+	}
+	t.released = true
+
+	if t.admitted {
+		q.active--
+		q.running[t.userID]--
+		if q.running[t.userID] == 0 {
+			delete(q.running, t.userID)
+		}
+	} else {
+		q.remove(t)
+		q.abandoned++
+	}
+	q.dispatch()
+}
+
+// Stats returns a snapshot of queue load and wait times
+func (q *Queue) Stats() Stats {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	stats := Stats{
+		Active:     q.active,
+		Admitted:   q.admitted,
+		Queued:     q.queued,
+		Abandoned:  q.abandoned,
+		MaxWaitMS:  q.maxWait.Milliseconds(),
+		LastWaitMS: q.lastWait.Milliseconds(),
+	}
+	users := make(map[int64]bool)
+	for userID, tickets := range q.waiting {
+		stats.Waiting += len(tickets)
+		users[userID] = true
+	}
+	for userID := range q.running {
+		users[userID] = true
+	}
+	stats.Users = len(users)
+	if q.queued > 0 {
+		stats.AvgWaitMS = (q.totalWait / time.Duration(q.queued)).Milliseconds()
+	}
+	return stats
+}
+
+// dispatch admits queued tickets while there is capacity
+// It serves users round-robin, skipping users at their own limit
+// The caller must hold q.mu
+func (q *Queue) dispatch() {
+	for q.active < q.opts.MaxActive && len(q.ring) > 0 {
+		admitted := false
+		for offset := 0; offset < len(q.ring); offset++ {
+			i := (q.next + offset) % len(q.ring)
+			userID := q.ring[i]
+			if q.running[userID] >= q.opts.MaxPerUser {
+				continue
+			}
+
+			t := q.waiting[userID][0]
+			q.admit(t)
+			// Serve the user after this one next; remove may shrink the ring
+			if q.remove(t) {
+				q.next = i
+			} else {
+				q.next = i + 1
+			}
+			if len(q.ring) > 0 {
+				q.next %= len(q.ring)
+			} else {
+				q.next = 0
+			}
+			admitted = true
+			break
+		}
+		if !admitted {
+			return
+		}
+	}
+}
+
+// admit marks t as running and records its wait
+// The caller must hold q.mu
+func (q *Queue) admit(t *Ticket) {
+	t.admitted = true
+	q.active++
+	q.running[t.userID]++
+	q.admitted++
+
+	if t.queued {
+		wait := q.now().Sub(t.joined)
+		q.queued++
+		q.totalWait += wait
+		q.lastWait = wait
+		if wait > q.maxWait {
+			q.maxWait = wait
+		}
+	}
+	close(t.ready)
+}
+
+// remove takes t out of its user's queue, dropping the user from the ring when
+// it has no more queued tickets; it reports whether the user was dropped
+// The caller must hold q.mu
+func (q *Queue) remove(t *Ticket) bool {
+	tickets := q.waiting[t.userID]
+	for i, queued := range tickets {
+		if queued == t {
+			tickets = append(tickets[:i], tickets[i+1:]...)
+			break
+		}
+	}
+	if len(tickets) > 0 {
+		q.waiting[t.userID] = tickets
+		return false
+	}
+
+	delete(q.waiting, t.userID)
+	for i, userID := range q.ring {
+		if userID == t.userID {
+			q.ring = append(q.ring[:i], q.ring[i+1:]...)
+			if i < q.next {
+				q.next--
+			}
+			break
+		}
+	}
+	if len(q.ring) > 0 {
+		q.next %= len(q.ring)
+	} else {
+		q.next = 0
+	}
+	return true
+}
@@ -0,0 +1,110 @@
+package fairqueue
+
+import (
+	"testing"
+	"time"
+)
+
+// isReady reports whether t has been admitted
+func isReady(t *Ticket) bool {
+	select {
+	case <-t.Ready():
+		return true
+	default:
+		return false
+	}
+}
+
+// TestPerUserLimit tests that one user cannot take more than MaxPerUser slots
+func TestPerUserLimit(t *testing.T) {
+	q := New(Options{MaxActive: 4, MaxPerUser: 2})
+
+	a1, a2, a3 := q.Join(1), q.Join(1), q.Join(1)
+	if !isReady(a1) || !isReady(a2) {
+		t.Fatal("Expected the first two calls to be admitted")
+	}
+	if isReady(a3) {
+		t.Fatal("Expected the third call to wait for the per-user limit")
+	}
+
+	b1 := q.Join(2)
+	if !isReady(b1) {
+		t.Error("Expected another user to be admitted while the first is at its limit")
+	}
+
+	a1.Release()
+	if !isReady(a3) {
+		t.Error("Expected the queued call to be admitted after a release")
+	}
+}
+
+// TestRoundRobin tests that waiting users are served in turn rather than in arrival order
+func TestRoundRobin(t *testing.T) {
+	q := New(Options{MaxActive: 1, MaxPerUser: 1})
+
+	running := q.Join(9)
+	a1, a2, a3 := q.Join(1), q.Join(1), q.Join(1)
+	b1 := q.Join(2)
+
+	if got := b1.Position(); got != 2 {
+		t.Errorf("Expected user 2 to be second in line, got position %d", got)
+	}
+	if got := a3.Position(); got != 4 {
+		t.Errorf("Expected user 1's third call to be fourth in line, got position %d", got)
+	}
+
+	order := []*Ticket{a1, b1, a2, a3}
+	current := running
+	for i, next := range order {
+		current.Release()
+		if !isReady(next) {
+			t.Fatalf("Expected call %d in round-robin order to be admitted", i)
+		}
+		if next.Position() != 0 {
+			t.Errorf("Expected an admitted call to have position 0")
+		}
+		current = next
+	}
+	current.Release()
+
+	if stats := q.Stats(); stats.Active != 0 || stats.Waiting != 0 || stats.Admitted != 5 || stats.Queued != 4 {
+		t.Errorf("Unexpected stats after draining the queue: %+v", stats)
+	}
+}
+
+// TestAbandon tests that a call leaving the queue does not block the calls behind it
+func TestAbandon(t *testing.T) {
+	q := New(Options{MaxActive: 1, MaxPerUser: 1})
+
+	running := q.Join(1)
+	waiting := q.Join(2)
+	behind := q.Join(3)
+
+	waiting.Release()
+	waiting.Release()
+	running.Release()
+	if !isReady(behind) {
+		t.Fatal("Expected the call behind an abandoned one to be admitted")
+	}
+	if stats := q.Stats(); stats.Abandoned != 1 || stats.Active != 1 || stats.Users != 1 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+}
+
+// TestWaitStats tests that only calls that had to queue count toward wait times
+func TestWaitStats(t *testing.T) {
+	q := New(Options{MaxActive: 1, MaxPerUser: 1})
+	clock := time.Unix(0, 0)
+	q.now = func() time.Time { return clock }
+
+	first := q.Join(1)
+	second := q.Join(2)
+	clock = clock.Add(300 * time.Millisecond)
+	first.Release()
+	second.Release()
+
+	stats := q.Stats()
+	if stats.Queued != 1 || stats.AvgWaitMS != 300 || stats.MaxWaitMS != 300 || stats.LastWaitMS != 300 {
+		t.Errorf("Expected one 300ms wait, got %+v", stats)
+	}
+}
@@ -0,0 +1,135 @@
+// Package flags gates features so admins can roll them out gradually. A flag is
+// switched on or off in the config, optionally for only a share of users, and
+// can be overridden for individual users. Flags missing from the config are on.
+package flags
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"sort"
+)
+
+// Feature flags
+const (
+	Rerank               = "rerank"                // Rerank library search results with the cross-encoder
+	WebSearch            = "web_search"            // Add live web results to answers
+	DocumentQA           = "document_qa"           // Answer questions over a whole document
+	RetrievalEval        = "retrieval_eval"        // Run retrieval evaluation sets
+	StructuredExtraction = "structured_extraction" // Extract typed records from documents
+	ModeSwitch           = "mode_switch"           // Answer one message in the other provider mode with /mode
+)
+
+// Known describes every feature flag
+var Known = map[string]string{
+	Rerank:               "Rerank library search results with the cross-encoder",
+	WebSearch:            "Add live web results to answers",
+	DocumentQA:           "Answer questions over a whole document",
+	RetrievalEval:        "Run retrieval evaluation sets",
+	StructuredExtraction: "Extract typed records from documents",
+	ModeSwitch:           "Answer one message in the other provider mode with /mode",
+}
+
+// Names returns the known flag names in order
+func Names() []string {
+	names := make([]string, 0, len(Known))
+	for name := range Known {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Flag is the configured state of a feature flag
+type Flag struct {
+	Enabled        bool `json:"enabled"`
+	RolloutPercent int  `json:"rollout_percent"` // Share of users the flag is on for, 1-100; 0 means everyone
+}
+
+// Validate checks a configured flag
+func (f Flag) Validate(name string) error {
+	if _, ok := Known[name]; !ok {
+		return fmt.Errorf("unknown feature flag: %s", name)
+	}
+	if f.RolloutPercent < 0 || f.RolloutPercent > 100 {
+		return fmt.Errorf("invalid rollout_percent for feature flag %s: %d (must be between 0 and 100)", name, f.RolloutPercent)
+	}
+	return nil
+}
+
+// OverrideStore reads a user's flag overrides
+type OverrideStore interface {
+	GetUserFlagOverrides(ctx context.Context, userID int64) (map[string]bool, error)
+}
+
+// Flags decides which features are on for a user
+type Flags struct {
+	configured map[string]Flag
+	overrides  OverrideStore
+}
+
+// New returns Flags for the configured flags; overrides may be nil
+func New(configured map[string]Flag, overrides OverrideStore) *Flags {
+	return &Flags{configured: configured, overrides: overrides}
+}
+
+// Config returns the configured state of a flag
+func (f *Flags) Config(name string) Flag {
+	if flag, ok := f.configured[name]; ok {
+		return flag
+	}
+	return Flag{Enabled: true}
+}
+
+// Enabled reports whether a flag is on for a user. A user override wins over
+// the config; if overrides cannot be read, the config decides
+func (f *Flags) Enabled(ctx context.Context, name string, userID int64) bool {
+	if f.overrides != nil && userID != 0 {
+		overrides, err := f.overrides.GetUserFlagOverrides(ctx, userID)
+		if err == nil {
+			if enabled, ok := overrides[name]; ok {
+				return enabled
+			}
+		}
+	}
+	return f.configEnabled(name, userID)
+}
+
+// ForUser returns whether each known flag is on for a user
+func (f *Flags) ForUser(ctx context.Context, userID int64) map[string]bool {
+	var overrides map[string]bool
+	if f.overrides != nil && userID != 0 {
+		overrides, _ = f.overrides.GetUserFlagOverrides(ctx, userID)
+	}
+
+	enabled := make(map[string]bool, len(Known))
+	for name := range Known {
+		if on, ok := overrides[name]; ok {
+			enabled[name] = on
+		} else {
+			enabled[name] = f.configEnabled(name, userID)
+		}
+	}
+	return enabled
+}
+
+// configEnabled applies the configured state and rollout of a flag
+func (f *Flags) configEnabled(name string, userID int64) bool {
+	if _, ok := Known[name]; !ok {
+		return false
+	}
+	flag := f.Config(name)
+	if !flag.Enabled {
+		return false
+	}
+	return flag.RolloutPercent == 0 || InRollout(name, userID, flag.RolloutPercent)
+}
+
+// InRollout reports whether a user falls within the first percent of users for
+// a flag. Users are spread by a hash of the flag name and user ID, so raising the
+// percentage only adds users and each flag reaches a different share of them
+func InRollout(name string, userID int64, percent int) bool {
+	h := fnv.New32a()
+	fmt.Fprintf(h, "%s:%d", name, userID)
+	return int(h.Sum32()%100) < percent
+}
@@ -0,0 +1,89 @@
+package flags
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+type mockOverrides struct {
+	overrides map[int64]map[string]bool
+	err       error
+}
+
+func (m *mockOverrides) GetUserFlagOverrides(ctx context.Context, userID int64) (map[string]bool, error) {
+	return m.overrides[userID], m.err
+}
+
+func TestEnabled(t *testing.T) {
+	overrides := &mockOverrides{overrides: map[int64]map[string]bool{
+		1: {WebSearch: true},
+		2: {Rerank: false},
+	}}
+	f := New(map[string]Flag{WebSearch: {Enabled: false}}, overrides)
+	ctx := context.Background()
+
+	// Flags missing from the config are on
+	if !f.Enabled(ctx, Rerank, 3) {
+		t.Error("Expected an unconfigured flag to be on")
+	}
+	if f.Enabled(ctx, WebSearch, 3) {
+		t.Error("Expected a disabled flag to be off")
+	}
+	if f.Enabled(ctx, "no_such_flag", 3) {
+		t.Error("Expected an unknown flag to be off")
+	}
+
+	// Overrides win over the config
+	if !f.Enabled(ctx, WebSearch, 1) {
+		t.Error("Expected the override to turn the flag on for user 1")
+	}
+	if f.Enabled(ctx, Rerank, 2) {
+		t.Error("Expected the override to turn the flag off for user 2")
+	}
+	all := f.ForUser(ctx, 2)
+	if len(all) != len(Known) || all[Rerank] || all[WebSearch] || !all[DocumentQA] {
+		t.Errorf("Unexpected flags for user 2: %v", all)
+	}
+
+	// Unreadable overrides fall back to the config
+	overrides.err = errors.New("database locked")
+	if f.Enabled(ctx, WebSearch, 1) {
+		t.Error("Expected the config to decide when overrides fail")
+	}
+}
+
+func TestRollout(t *testing.T) {
+	f := New(map[string]Flag{DocumentQA: {Enabled: true, RolloutPercent: 25}}, nil)
+	ctx := context.Background()
+
+	on := 0
+	for userID := int64(1); userID <= 1000; userID++ {
+		enabled := f.Enabled(ctx, DocumentQA, userID)
+		if enabled != InRollout(DocumentQA, userID, 25) {
+			t.Fatalf("Expected the rollout to decide for user %d", userID)
+		}
+		// Raising the percentage keeps everyone already included
+		if enabled && !InRollout(DocumentQA, userID, 50) {
+			t.Fatalf("Expected user %d to stay in a larger rollout", userID)
+		}
+		if enabled {
+			on++
+		}
+	}
+	if on < 200 || on > 300 {
+		t.Errorf("Expected about 25%% of users, got %d of 1000", on)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := (Flag{Enabled: true, RolloutPercent: 50}).Validate(Rerank); err != nil {
+		t.Errorf("Expected a valid flag, got %v", err)
+	}
+	if err := (Flag{Enabled: true}).Validate("rerenk"); err == nil {
+		t.Error("Expected an error for an unknown flag")
+	}
+	if err := (Flag{Enabled: true, RolloutPercent: 101}).Validate(Rerank); err == nil {
+		t.Error("Expected an error for a rollout over 100")
+	}
+}
@@ -0,0 +1,329 @@
+package importer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Supported chat history formats
+const (
+	ChatFormatChatGPT = "chatgpt" // ChatGPT data export
+	ChatFormatClaude  = "claude"  // Claude data export
+)
+
+// ChatFormats lists the supported chat history formats
+var ChatFormats = []string{ChatFormatChatGPT, ChatFormatClaude}
+
+// chatsFile is the file both exports keep their conversations in
+const chatsFile = "conversations.json"
+
+// Conversation is a conversation read from a chat history export
+type Conversation struct {
+	ID        string // ID of the conversation in the export
+	Title     string
+	Source    string // Document name the conversation is embedded under, unique within the export
+	CreatedAt time.Time
+	Messages  []ChatMessage
+}
+
+// ChatMessage is a message of an exported conversation
+type ChatMessage struct {
+	Role      string // "user" or "assistant"
+	Content   string
+	CreatedAt time.Time // Zero when the export has no time for it
+}
+
+// Transcript renders the conversation as text to embed, one turn per paragraph
+func (c Conversation) Transcript() string {
+	var b strings.Builder
+	b.WriteString(c.Title)
+	for _, msg := range c.Messages {
+		speaker := "User"
+		if msg.Role == "assistant" {
+			speaker = "Assistant"
+		}
+		fmt.Fprintf(&b, "\n\n%s: %s", speaker, msg.Content)
+	}
+	return b.String()
+}
+
+// ReadChats reads the conversations.json of a ChatGPT or Claude data export
+// from fsys, which holds the unzipped export
+func ReadChats(fsys fs.FS, format string, maxFileSize int64) ([]Conversation, error) {
+	var found string
+	err := fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && p != "." && (isHidden(d.Name()) || d.Name() == "__MACOSX") {
+			return fs.SkipDir
+		}
+		if !d.IsDir() && path.Base(p) == chatsFile && found == "" {
+			found = p
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read export: %w", err)
+	}
+	if found == "" {
+		return nil, fmt.Errorf("the export has no %s", chatsFile)
+	}
+
+	r := &reader{fsys: fsys, maxFileSize: maxFileSize}
+	data, err := r.readFile(found)
+	if errors.Is(err, errTooLarge) {
+		return nil, fmt.Errorf("%s is larger than %d KB", found, maxFileSize>>10)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", found, err)
+	}
+	return ParseChats(data, format)
+}
+
+// ParseChats parses the conversations.json of a ChatGPT or Claude data export.
+// Conversations without any user or assistant text are left out
+func ParseChats(data []byte, format string) ([]Conversation, error) {
+	var conversations []Conversation
+	var err error
+	switch format {
+	case ChatFormatChatGPT:
+		conversations, err = parseChatGPT(data)
+	case ChatFormatClaude:
+		conversations, err = parseClaude(data)
+	default:
+		return nil, fmt.Errorf("unknown chat format %q (expected one of %s)", format, strings.Join(ChatFormats, ", "))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	kept := conversations[:0]
+	for _, c := range conversations {
+		if len(c.Messages) == 0 {
+			continue
+		}
+		if c.Title == "" {
+			c.Title = "Untitled chat"
+		}
+		c.Source = fmt.Sprintf("%s chat: %s", chatLabel(format), c.Title)
+		if !c.CreatedAt.IsZero() {
+			c.Source += " (" + c.CreatedAt.Format("2006-01-02") + ")"
+		}
+		kept = append(kept, c)
+	}
+
+	seen := make(map[string]bool, len(kept))
+	for i := range kept {
+		source := kept[i].Source
+		for n := 2; seen[source]; n++ {
+			source = fmt.Sprintf("%s (%d)", kept[i].Source, n)
+		}
+		seen[source] = true
+		kept[i].Source = source
+	}
+	return kept, nil
+}
+
+// chatLabel names the tool a chat format was exported from
+func chatLabel(format string) string {
+	if format == ChatFormatClaude {
+		return "Claude"
+	}
+	return "ChatGPT"
+}
+
+// chatGPTConversation is a conversation of ChatGPT's conversations.json. Its
+// messages form a tree, as edited prompts and regenerated answers branch off;
+// current_node is the last message of the branch that was shown
+type chatGPTConversation struct {
+	ID             string                 `json:"id"`
+	ConversationID string                 `json:"conversation_id"`
+	Title          string                 `json:"title"`
+	CreateTime     float64                `json:"create_time"`
+	CurrentNode    string                 `json:"current_node"`
+	Mapping        map[string]chatGPTNode `json:"mapping"`
+}
+
+type chatGPTNode struct {
+	Parent   string   `json:"parent"`
+	Children []string `json:"children"`
+	Message  *struct {
+		Author struct {
+			Role string `json:"role"`
+		} `json:"author"`
+		CreateTime float64 `json:"create_time"`
+		Content    struct {
+			ContentType string            `json:"content_type"`
+			Parts       []json.RawMessage `json:"parts"`
+		} `json:"content"`
+		Metadata struct {
+			Hidden bool `json:"is_visually_hidden_from_conversation"`
+		} `json:"metadata"`
+	} `json:"message"`
+}
+
+// parseChatGPT reads the branch of each conversation that was shown last.
+// System, tool and hidden messages and non-text parts such as images are left out
+func parseChatGPT(data []byte) ([]Conversation, error) {
+	var raw []chatGPTConversation
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("not a ChatGPT %s: %w", chatsFile, err)
+	}
+
+	conversations := make([]Conversation, 0, len(raw))
+	for _, rc := range raw {
+		c := Conversation{ID: rc.ConversationID, Title: strings.TrimSpace(rc.Title), CreatedAt: unixTime(rc.CreateTime)}
+		if c.ID == "" {
+			c.ID = rc.ID
+		}
+
+		for _, id := range chatGPTBranch(rc) {
+			node := rc.Mapping[id]
+			msg := node.Message
+			if msg == nil || msg.Metadata.Hidden || (msg.Author.Role != "user" && msg.Author.Role != "assistant") {
+				continue
+			}
+			if msg.Content.ContentType != "text" && msg.Content.ContentType != "multimodal_text" {
+				continue
+			}
+			var parts []string
+			for _, part := range msg.Content.Parts {
+				var text string
+				if json.Unmarshal(part, &text) == nil && strings.TrimSpace(text) != "" {
+					parts = append(parts, strings.TrimSpace(text))
+				}
+			}
+			if len(parts) == 0 {
+				continue
+			}
+			c.Messages = append(c.Messages, ChatMessage{
+				Role:      msg.Author.Role,
+				Content:   strings.Join(parts, "\n\n"),
+				CreatedAt: unixTime(msg.CreateTime),
+			})
+		}
+		conversations = append(conversations, c)
+	}
+	return conversations, nil
+}
+
+// chatGPTBranch returns the node IDs from the root to the conversation's
+// current node. Without one, the latest child is followed down from the root
+func chatGPTBranch(c chatGPTConversation) []string {
+	if _, ok := c.Mapping[c.CurrentNode]; ok {
+		var branch []string
+		seen := map[string]bool{}
+		for id := c.CurrentNode; id != "" && !seen[id]; id = c.Mapping[id].Parent {
+			seen[id] = true
+			branch = append(branch, id)
+		}
+		for i, j := 0, len(branch)-1; i < j; i, j = i+1, j-1 {
+			branch[i], branch[j] = branch[j], branch[i]
+		}
+		return branch
+	}
+
+	var roots []string
+	for id, node := range c.Mapping {
+		if _, ok := c.Mapping[node.Parent]; !ok {
+			roots = append(roots, id)
+		}
+	}
+	if len(roots) == 0 {
+		return nil
+	}
+	sort.Strings(roots)
+	var branch []string
+	seen := map[string]bool{}
+	for id := roots[0]; id != "" && !seen[id]; {
+		seen[id] = true
+		branch = append(branch, id)
+		children := c.Mapping[id].Children
+		id = ""
+		if len(children) > 0 {
+			id = children[len(children)-1]
+		}
+	}
+	return branch
+}
+
+// claudeConversation is a conversation of Claude's conversations.json
+type claudeConversation struct {
+	UUID         string `json:"uuid"`
+	Name         string `json:"name"`
+	CreatedAt    string `json:"created_at"`
+	ChatMessages []struct {
+		Sender    string `json:"sender"`
+		Text      string `json:"text"`
+		CreatedAt string `json:"created_at"`
+		Content   []struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+		} `json:"content"`
+	} `json:"chat_messages"`
+}
+
+// parseClaude reads Claude conversations, taking the text blocks of messages
+// that have them and the plain text of older exports otherwise
+func parseClaude(data []byte) ([]Conversation, error) {
+	var raw []claudeConversation
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("not a Claude %s: %w", chatsFile, err)
+	}
+
+	conversations := make([]Conversation, 0, len(raw))
+	for _, rc := range raw {
+		c := Conversation{ID: rc.UUID, Title: strings.TrimSpace(rc.Name), CreatedAt: isoTime(rc.CreatedAt)}
+		for _, msg := range rc.ChatMessages {
+			role := msg.Sender
+			if role == "human" {
+				role = "user"
+			}
+			if role != "user" && role != "assistant" {
+				continue
+			}
+			var parts []string
+			for _, block := range msg.Content {
+				if block.Type == "text" && strings.TrimSpace(block.Text) != "" {
+					parts = append(parts, strings.TrimSpace(block.Text))
+				}
+			}
+			text := strings.Join(parts, "\n\n")
+			if len(parts) == 0 {
+				text = strings.TrimSpace(msg.Text)
+			}
+			if text == "" {
+				continue
+			}
+			c.Messages = append(c.Messages, ChatMessage{Role: role, Content: text, CreatedAt: isoTime(msg.CreatedAt)})
+		}
+		conversations = append(conversations, c)
+	}
+	return conversations, nil
+}
+
+// unixTime converts the fractional Unix seconds of ChatGPT exports
+func unixTime(seconds float64) time.Time {
+	if seconds <= 0 {
+		return time.Time{}
+	}
+	whole, frac := math.Modf(seconds)
+	return time.Unix(int64(whole), int64(frac*1e9)).UTC()
+}
+
+// isoTime parses the RFC 3339 times of Claude exports, zero when it can't
+func isoTime(value string) time.Time {
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return time.Time{}
+	}
+	return t.UTC()
+}
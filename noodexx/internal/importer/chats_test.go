@@ -0,0 +1,103 @@
+package importer
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestParseChatGPT(t *testing.T) {
+	// The edited prompt n2b replaced n2, so only the branch ending at n3b counts
+	export := `[{
+		"id": "c1", "conversation_id": "c1", "title": "Trip planning", "create_time": 1709640000.5,
+		"current_node": "n3b",
+		"mapping": {
+			"root": {"parent": null, "children": ["sys"], "message": null},
+			"sys": {"parent": "root", "children": ["n2", "n2b"], "message": {"author": {"role": "system"}, "content": {"content_type": "text", "parts": ["You are helpful"]}}},
+			"n2": {"parent": "sys", "children": ["n3"], "message": {"author": {"role": "user"}, "create_time": 1709640001, "content": {"content_type": "text", "parts": ["Where to go?"]}}},
+			"n3": {"parent": "n2", "children": [], "message": {"author": {"role": "assistant"}, "content": {"content_type": "text", "parts": ["Paris"]}}},
+			"n2b": {"parent": "sys", "children": ["tool"], "message": {"author": {"role": "user"}, "create_time": 1709640002, "content": {"content_type": "multimodal_text", "parts": [{"content_type": "image_asset_pointer"}, "Where to go in spring?"]}}},
+			"tool": {"parent": "n2b", "children": ["n3b"], "message": {"author": {"role": "tool"}, "content": {"content_type": "text", "parts": ["search results"]}}},
+			"n3b": {"parent": "tool", "children": [], "message": {"author": {"role": "assistant"}, "create_time": 1709640003, "content": {"content_type": "text", "parts": ["Kyoto"]}}}
+		}
+	}, {
+		"id": "c2", "title": "", "mapping": {"root": {"parent": null, "children": [], "message": null}}
+	}]`
+
+	conversations, err := ParseChats([]byte(export), ChatFormatChatGPT)
+	if err != nil {
+		t.Fatalf("ParseChats failed: %v", err)
+	}
+	if len(conversations) != 1 {
+		t.Fatalf("Expected the empty conversation to be left out, got %+v", conversations)
+	}
+	c := conversations[0]
+	if c.ID != "c1" || c.Title != "Trip planning" || c.Source != "ChatGPT chat: Trip planning (2024-03-05)" {
+		t.Errorf("Unexpected conversation %+v", c)
+	}
+	if len(c.Messages) != 2 || c.Messages[0].Content != "Where to go in spring?" || c.Messages[1].Content != "Kyoto" {
+		t.Fatalf("Expected the current branch's user and assistant messages, got %+v", c.Messages)
+	}
+	if c.Messages[0].Role != "user" || !c.Messages[1].CreatedAt.Equal(time.Unix(1709640003, 0)) {
+		t.Errorf("Unexpected messages %+v", c.Messages)
+	}
+	if want := "Trip planning\n\nUser: Where to go in spring?\n\nAssistant: Kyoto"; c.Transcript() != want {
+		t.Errorf("Expected transcript %q, got %q", want, c.Transcript())
+	}
+}
+
+func TestParseClaude(t *testing.T) {
+	export := `[
+		{"uuid": "a", "name": "Recipe", "created_at": "2024-05-01T10:00:00.000000Z", "chat_messages": [
+			{"sender": "human", "text": "Soup ideas?", "created_at": "2024-05-01T10:00:01Z", "content": [{"type": "text", "text": "Soup ideas?"}]},
+			{"sender": "assistant", "text": "", "content": [{"type": "tool_use"}, {"type": "text", "text": "Minestrone"}]}
+		]},
+		{"uuid": "b", "name": "Recipe", "created_at": "2024-05-01T12:00:00Z", "chat_messages": [
+			{"sender": "human", "text": "Older export text"}
+		]}
+	]`
+
+	conversations, err := ParseChats([]byte(export), ChatFormatClaude)
+	if err != nil {
+		t.Fatalf("ParseChats failed: %v", err)
+	}
+	if len(conversations) != 2 {
+		t.Fatalf("Expected 2 conversations, got %+v", conversations)
+	}
+	if msgs := conversations[0].Messages; len(msgs) != 2 || msgs[0].Role != "user" || msgs[1].Content != "Minestrone" {
+		t.Errorf("Unexpected messages %+v", msgs)
+	}
+	if conversations[1].Messages[0].Content != "Older export text" {
+		t.Errorf("Expected the plain text without content blocks, got %+v", conversations[1].Messages)
+	}
+	if conversations[1].Source != "Claude chat: Recipe (2024-05-01) (2)" {
+		t.Errorf("Expected the second source numbered, got %q", conversations[1].Source)
+	}
+
+	if _, err := ParseChats([]byte(`{"not": "a list"}`), ChatFormatClaude); err == nil {
+		t.Error("Expected an error for JSON that is not a conversation list")
+	}
+	if _, err := ParseChats([]byte(`[]`), "gemini"); err == nil {
+		t.Error("Expected an error for an unknown format")
+	}
+}
+
+func TestReadChats(t *testing.T) {
+	export := fstest.MapFS{
+		"export/conversations.json": {Data: []byte(`[{"uuid": "a", "name": "Hi", "chat_messages": [{"sender": "human", "text": "Hello"}]}]`)},
+		"export/users.json":         {Data: []byte(`[]`)},
+	}
+	conversations, err := ReadChats(export, ChatFormatClaude, 0)
+	if err != nil || len(conversations) != 1 {
+		t.Fatalf("Expected one conversation, got %+v, %v", conversations, err)
+	}
+
+	if _, err := ReadChats(fstest.MapFS{"users.json": {Data: []byte(`[]`)}}, ChatFormatClaude, 0); err == nil {
+		t.Error("Expected an error for an export without conversations.json")
+	}
+	big := fstest.MapFS{"conversations.json": {Data: []byte(strings.Repeat(" ", 2048))}}
+	if _, err := ReadChats(big, ChatFormatClaude, 1024); err == nil {
+		t.Error("Expected an error for a conversations.json over the limit")
+	}
+}
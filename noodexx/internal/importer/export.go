@@ -0,0 +1,148 @@
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// anythingLLMDocument is a document JSON file of AnythingLLM's storage/documents
+// folder, one per uploaded file or page
+type anythingLLMDocument struct {
+	Title       string `json:"title"`
+	URL         string `json:"url"`
+	PageContent string `json:"pageContent"`
+}
+
+// readAnythingLLM reads the document JSON files of AnythingLLM's documents
+// folder. Each folder, such as custom-documents, becomes a collection
+func (r *reader) readAnythingLLM() error {
+	// The vector cache holds embeddings of the same documents
+	paths, err := sortedPaths(r.fsys, []string{".json"}, "vector-cache", "documents/vector-cache")
+	if err != nil {
+		return err
+	}
+
+	for _, p := range paths {
+		content, ok, err := r.readText(p)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		var doc anythingLLMDocument
+		if err := json.Unmarshal([]byte(content), &doc); err != nil || doc.PageContent == "" {
+			// Workspace settings and other JSON files are not documents
+			continue
+		}
+
+		source := strings.TrimSpace(doc.Title)
+		if source == "" {
+			source = strings.TrimSuffix(path.Base(p), path.Ext(p))
+		}
+		collection := ""
+		if dir := path.Base(path.Dir(p)); dir != "." && dir != "documents" {
+			collection = dir
+		}
+		r.docs = append(r.docs, Document{
+			Source:     source,
+			Text:       strings.TrimSpace(doc.PageContent),
+			Collection: collection,
+		})
+	}
+	return nil
+}
+
+// privateGPTDocstore is the llama-index document store PrivateGPT keeps its
+// ingested text in: the nodes of every page of every file
+type privateGPTDocstore struct {
+	Data map[string]struct {
+		Data struct {
+			Text string `json:"text"`
+		} `json:"__data__"`
+	} `json:"docstore/data"`
+	Docs map[string]struct {
+		NodeIDs  []string               `json:"node_ids"`
+		Metadata map[string]interface{} `json:"metadata"`
+	} `json:"docstore/ref_doc_info"`
+}
+
+// readPrivateGPT reads the docstore.json of PrivateGPT's local_data folder,
+// joining the pages of each file in order into one document
+func (r *reader) readPrivateGPT() error {
+	paths, err := sortedPaths(r.fsys, []string{".json"})
+	if err != nil {
+		return err
+	}
+	var docstore string
+	for _, p := range paths {
+		if path.Base(p) == "docstore.json" {
+			docstore = p
+			break
+		}
+	}
+	if docstore == "" {
+		return fmt.Errorf("no docstore.json found in the PrivateGPT export")
+	}
+
+	data, err := r.readFile(docstore)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", docstore, err)
+	}
+	var store privateGPTDocstore
+	if err := json.Unmarshal(data, &store); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", docstore, err)
+	}
+
+	type page struct {
+		id    string
+		label int
+		text  string
+	}
+	files := make(map[string][]page)
+	for id, doc := range store.Docs {
+		name, _ := doc.Metadata["file_name"].(string)
+		if name == "" {
+			name = id
+		}
+		var texts []string
+		for _, nodeID := range doc.NodeIDs {
+			if text := strings.TrimSpace(store.Data[nodeID].Data.Text); text != "" {
+				texts = append(texts, text)
+			}
+		}
+		label, _ := strconv.Atoi(fmt.Sprint(doc.Metadata["page_label"]))
+		files[name] = append(files[name], page{id: id, label: label, text: strings.Join(texts, "\n")})
+	}
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		pages := files[name]
+		sort.Slice(pages, func(a, b int) bool {
+			if pages[a].label != pages[b].label {
+				return pages[a].label < pages[b].label
+			}
+			return pages[a].id < pages[b].id
+		})
+		var texts []string
+		for _, p := range pages {
+			if p.text != "" {
+				texts = append(texts, p.text)
+			}
+		}
+		if len(texts) == 0 {
+			r.skip(name, "no text")
+			continue
+		}
+		r.docs = append(r.docs, Document{Source: name, Text: strings.Join(texts, "\n\n")})
+	}
+	return nil
+}
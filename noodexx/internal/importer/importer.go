@@ -0,0 +1,265 @@
+// Package importer reads the notes and documents of other knowledge tools, so a
+// library can be moved into noodexx in one step. Obsidian and Logseq vaults keep
+// their frontmatter tags, inline tags and wikilinks; AnythingLLM and PrivateGPT
+// exports keep their documents' text and folders. ChatGPT and Claude data
+// exports are read as conversations, to be recreated as chat sessions
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Supported import formats
+const (
+	FormatObsidian    = "obsidian"    // Obsidian vault of Markdown notes
+	FormatLogseq      = "logseq"      // Logseq graph of pages and journals
+	FormatAnythingLLM = "anythingllm" // AnythingLLM documents folder of JSON files
+	FormatPrivateGPT  = "privategpt"  // PrivateGPT local_data with its docstore.json
+)
+
+// Formats lists the supported import formats
+var Formats = []string{FormatObsidian, FormatLogseq, FormatAnythingLLM, FormatPrivateGPT}
+
+// Tag limits, matching the validation of ingested documents
+const (
+	maxTags      = 32
+	maxTagLength = 64
+)
+
+// Document is a document read from an export, ready to be ingested
+type Document struct {
+	Source     string   // Path of the note, or the name of the exported document
+	Text       string   // Text with wikilinks rendered as their titles
+	Tags       []string // Frontmatter and inline tags, and the collection
+	Links      []string // Targets of the note's wikilinks, in order of first appearance
+	Collection string   // Top-level folder, namespace or document folder the document belonged to
+}
+
+// Skipped is a file of an export that could not be read as a document
+type Skipped struct {
+	Path   string
+	Reason string
+}
+
+// errTooLarge is returned when a file of an export is larger than the limit
+var errTooLarge = errors.New("file is too large")
+
+// Read reads the documents of an export in format from fsys. Files larger than
+// maxFileSize bytes, when it is positive, and files that are not UTF-8 text are
+// skipped and listed; other errors stop the import
+func Read(fsys fs.FS, format string, maxFileSize int64) ([]Document, []Skipped, error) {
+	fsys, err := exportRoot(fsys)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	r := &reader{fsys: fsys, maxFileSize: maxFileSize}
+	switch format {
+	case FormatObsidian, FormatLogseq:
+		err = r.readVault(format == FormatLogseq)
+	case FormatAnythingLLM:
+		err = r.readAnythingLLM()
+	case FormatPrivateGPT:
+		err = r.readPrivateGPT()
+	default:
+		return nil, nil, fmt.Errorf("unknown import format %q (expected one of %s)", format, strings.Join(Formats, ", "))
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	uniqueSources(r.docs)
+	for i := range r.docs {
+		r.docs[i].Tags = cleanTags(append(r.docs[i].Tags, r.docs[i].Collection))
+	}
+	return r.docs, r.skipped, nil
+}
+
+// reader collects the documents and skipped files of one import
+type reader struct {
+	fsys        fs.FS
+	maxFileSize int64
+	docs        []Document
+	skipped     []Skipped
+}
+
+// skip records a file that could not be read
+func (r *reader) skip(name string, reason string) {
+	r.skipped = append(r.skipped, Skipped{Path: name, Reason: reason})
+}
+
+// readFile reads a file of the export, enforcing the size limit even when the
+// archive understates the size
+func (r *reader) readFile(name string) ([]byte, error) {
+	f, err := r.fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var src io.Reader = f
+	if r.maxFileSize > 0 {
+		src = io.LimitReader(f, r.maxFileSize+1)
+	}
+	data, err := io.ReadAll(src)
+	if err != nil {
+		return nil, err
+	}
+	if r.maxFileSize > 0 && int64(len(data)) > r.maxFileSize {
+		return nil, errTooLarge
+	}
+	return data, nil
+}
+
+// readText reads a text file, skipping it when it is too large or not UTF-8
+// It returns false for skipped files
+func (r *reader) readText(name string) (string, bool, error) {
+	data, err := r.readFile(name)
+	if errors.Is(err, errTooLarge) {
+		r.skip(name, fmt.Sprintf("larger than %d KB", r.maxFileSize>>10))
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	if !utf8.Valid(data) {
+		r.skip(name, "not UTF-8 text")
+		return "", false, nil
+	}
+	return strings.TrimPrefix(string(data), "\ufeff"), true, nil
+}
+
+// exportRoot returns the folder of fsys the export starts in: archives often
+// hold the exported folder itself, so a root with a single folder and no files
+// is skipped
+func exportRoot(fsys fs.FS) (fs.FS, error) {
+	for {
+		entries, err := fs.ReadDir(fsys, ".")
+		if err != nil {
+			return nil, fmt.Errorf("failed to read export: %w", err)
+		}
+		var dirs []fs.DirEntry
+		files := 0
+		for _, e := range entries {
+			if e.Name() == ".obsidian" {
+				// The vault's settings mark its root
+				return fsys, nil
+			}
+			if e.IsDir() {
+				if !isHidden(e.Name()) && e.Name() != "__MACOSX" {
+					dirs = append(dirs, e)
+				}
+			} else if !isHidden(e.Name()) {
+				files++
+			}
+		}
+		if len(dirs) != 1 || files > 0 || isMarker(dirs[0].Name()) {
+			return fsys, nil
+		}
+		if fsys, err = fs.Sub(fsys, dirs[0].Name()); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// isMarker reports whether a folder name belongs to the layout of an export
+// rather than being the exported folder itself
+func isMarker(name string) bool {
+	switch name {
+	case "pages", "journals", "logseq", "documents", "local_data", "private_gpt", "custom-documents":
+		return true
+	}
+	return false
+}
+
+// isHidden reports whether a file or folder is hidden, such as .obsidian or .DS_Store
+func isHidden(name string) bool {
+	return strings.HasPrefix(name, ".")
+}
+
+// uniqueSources numbers documents whose source another document already has,
+// as ingesting them under one source would keep only the last
+func uniqueSources(docs []Document) {
+	seen := make(map[string]bool, len(docs))
+	for i := range docs {
+		source := docs[i].Source
+		for n := 2; seen[source]; n++ {
+			source = fmt.Sprintf("%s (%d)", docs[i].Source, n)
+		}
+		seen[source] = true
+		docs[i].Source = source
+	}
+}
+
+// cleanTags trims tags and drops empty and repeated ones, keeping the first
+// maxTags. Characters tags cannot hold become dashes and long tags are cut
+func cleanTags(tags []string) []string {
+	seen := make(map[string]bool, len(tags))
+	var result []string
+	for _, tag := range tags {
+		tag = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(tag), "#"))
+		tag = strings.NewReplacer(",", "-", "\n", " ", "\r", "").Replace(tag)
+		for len(tag) > maxTagLength {
+			_, size := utf8.DecodeLastRuneInString(tag)
+			tag = tag[:len(tag)-size]
+		}
+		key := strings.ToLower(tag)
+		if tag == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		result = append(result, tag)
+		if len(result) == maxTags {
+			break
+		}
+	}
+	return result
+}
+
+// sortedPaths returns the paths of the files under fsys whose extension is one
+// of exts, skipping hidden files and folders and the folders in skipDirs
+func sortedPaths(fsys fs.FS, exts []string, skipDirs ...string) ([]string, error) {
+	var paths []string
+	err := fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p == "." {
+			return nil
+		}
+		if d.IsDir() {
+			for _, skip := range skipDirs {
+				if p == skip {
+					return fs.SkipDir
+				}
+			}
+			if isHidden(d.Name()) || d.Name() == "__MACOSX" {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if isHidden(d.Name()) {
+			return nil
+		}
+		ext := strings.ToLower(path.Ext(p))
+		for _, want := range exts {
+			if ext == want {
+				paths = append(paths, p)
+				break
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list export: %w", err)
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
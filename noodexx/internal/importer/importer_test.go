@@ -0,0 +1,194 @@
+package importer
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestReadObsidian(t *testing.T) {
+	vault := fstest.MapFS{
+		"MyVault/.obsidian/app.json": {Data: []byte(`{}`)},
+		"MyVault/Inbox.md":           {Data: []byte("---\ntags: [idea, \"draft\"]\naliases: [inbox]\n---\n# Inbox\n\nSee [[Projects/Plan|the plan]] and [[Ideas#Later]]. #todo\n")},
+		"MyVault/Projects/Plan.md":   {Data: []byte("---\ntags:\n  - work\n  - q3\n---\nShip it. #2024 is not a tag, #review/weekly is.\n\n```\n#notatag [[NotALink]]\n```\n![[diagram.png]]\n")},
+		"MyVault/Projects/Other.md":  {Data: []byte("tags in code `#nope` only")},
+		"MyVault/.trash/Old.md":      {Data: []byte("deleted")},
+		"MyVault/image.png":          {Data: []byte{0x89, 'P', 'N', 'G'}},
+	}
+
+	docs, skipped, err := Read(vault, FormatObsidian, 0)
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if len(skipped) != 0 {
+		t.Errorf("Expected no skipped files, got %v", skipped)
+	}
+	if len(docs) != 3 {
+		t.Fatalf("Expected 3 notes, got %d: %+v", len(docs), docs)
+	}
+
+	inbox := docs[0]
+	if inbox.Source != "Inbox.md" || inbox.Collection != "" {
+		t.Errorf("Unexpected inbox source %q and collection %q", inbox.Source, inbox.Collection)
+	}
+	if want := []string{"idea", "draft", "todo"}; !reflect.DeepEqual(inbox.Tags, want) {
+		t.Errorf("Expected tags %v, got %v", want, inbox.Tags)
+	}
+	if want := []string{"Projects/Plan", "Ideas"}; !reflect.DeepEqual(inbox.Links, want) {
+		t.Errorf("Expected links %v, got %v", want, inbox.Links)
+	}
+	if !strings.HasPrefix(inbox.Text, "# Inbox") || !strings.Contains(inbox.Text, "See the plan and Ideas.") {
+		t.Errorf("Expected the frontmatter removed and links rendered, got %q", inbox.Text)
+	}
+
+	plan := docs[2]
+	if plan.Source != "Projects/Plan.md" || plan.Collection != "Projects" {
+		t.Errorf("Unexpected plan source %q and collection %q", plan.Source, plan.Collection)
+	}
+	if want := []string{"work", "q3", "review/weekly", "Projects"}; !reflect.DeepEqual(plan.Tags, want) {
+		t.Errorf("Expected tags %v, got %v", want, plan.Tags)
+	}
+	if want := []string{"diagram.png"}; !reflect.DeepEqual(plan.Links, want) {
+		t.Errorf("Expected links outside code only, got %v", plan.Links)
+	}
+
+	if other := docs[1]; !reflect.DeepEqual(other.Tags, []string{"Projects"}) {
+		t.Errorf("Expected only the collection tag, got %v", other.Tags)
+	}
+}
+
+func TestReadLogseq(t *testing.T) {
+	graph := fstest.MapFS{
+		"pages/Projects___Plan.md":    {Data: []byte("tags:: work, [[deep work]], #q3\nalias:: plan\n\n- Draft with [[Alice]] #[[next step]]\n")},
+		"pages/Reading.md":            {Data: []byte("- [[Books]]\n")},
+		"journals/2024_01_02.md":      {Data: []byte("- Met [[Alice]]\n")},
+		"logseq/config.edn":           {Data: []byte("{}")},
+		"logseq/bak/pages/Reading.md": {Data: []byte("- backup\n")},
+	}
+
+	docs, _, err := Read(graph, FormatLogseq, 0)
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if len(docs) != 3 {
+		t.Fatalf("Expected 3 pages without backups, got %d: %+v", len(docs), docs)
+	}
+
+	journal, plan, reading := docs[0], docs[1], docs[2]
+	if journal.Collection != "journals" || !reflect.DeepEqual(journal.Links, []string{"Alice"}) {
+		t.Errorf("Unexpected journal %+v", journal)
+	}
+	if plan.Collection != "Projects" {
+		t.Errorf("Expected the namespace as collection, got %q", plan.Collection)
+	}
+	if want := []string{"work", "deep work", "q3", "next step", "Projects"}; !reflect.DeepEqual(plan.Tags, want) {
+		t.Errorf("Expected tags %v, got %v", want, plan.Tags)
+	}
+	if reading.Collection != "" || len(reading.Tags) != 0 {
+		t.Errorf("Expected a page without collection or tags, got %+v", reading)
+	}
+}
+
+func TestReadAnythingLLM(t *testing.T) {
+	export := fstest.MapFS{
+		"storage/documents/custom-documents/report.pdf-1a2b.json": {Data: []byte(`{"id":"1","title":"report.pdf","pageContent":"Quarterly results."}`)},
+		"storage/documents/research/notes.txt-3c4d.json":          {Data: []byte(`{"id":"2","title":"notes.txt","pageContent":"Findings."}`)},
+		"storage/documents/research/report.pdf-5e6f.json":         {Data: []byte(`{"id":"3","title":"report.pdf","pageContent":"Another report."}`)},
+		"storage/vector-cache/abc.json":                           {Data: []byte(`[{"vectors":[0.1]}]`)},
+		"storage/plugins/settings.json":                           {Data: []byte(`{"enabled":true}`)},
+	}
+
+	docs, _, err := Read(export, FormatAnythingLLM, 0)
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if len(docs) != 3 {
+		t.Fatalf("Expected 3 documents, got %d: %+v", len(docs), docs)
+	}
+	want := []Document{
+		{Source: "report.pdf", Text: "Quarterly results.", Tags: []string{"custom-documents"}, Collection: "custom-documents"},
+		{Source: "notes.txt", Text: "Findings.", Tags: []string{"research"}, Collection: "research"},
+		{Source: "report.pdf (2)", Text: "Another report.", Tags: []string{"research"}, Collection: "research"},
+	}
+	if !reflect.DeepEqual(docs, want) {
+		t.Errorf("Expected %+v, got %+v", want, docs)
+	}
+}
+
+func TestReadPrivateGPT(t *testing.T) {
+	docstore := `{
+		"docstore/data": {
+			"n1": {"__data__": {"text": "Page one."}},
+			"n2": {"__data__": {"text": "Page two, first."}},
+			"n3": {"__data__": {"text": "Page two, second."}},
+			"n4": {"__data__": {"text": "Notes."}}
+		},
+		"docstore/ref_doc_info": {
+			"d2": {"node_ids": ["n2", "n3"], "metadata": {"file_name": "guide.pdf", "page_label": "2"}},
+			"d1": {"node_ids": ["n1"], "metadata": {"file_name": "guide.pdf", "page_label": "1"}},
+			"d3": {"node_ids": ["n4"], "metadata": {"file_name": "notes.md"}}
+		}
+	}`
+	export := fstest.MapFS{
+		"local_data/private_gpt/docstore.json":    {Data: []byte(docstore)},
+		"local_data/private_gpt/index_store.json": {Data: []byte(`{}`)},
+	}
+
+	docs, _, err := Read(export, FormatPrivateGPT, 0)
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if len(docs) != 2 {
+		t.Fatalf("Expected 2 documents, got %d: %+v", len(docs), docs)
+	}
+	if docs[0].Source != "guide.pdf" || docs[0].Text != "Page one.\n\nPage two, first.\nPage two, second." {
+		t.Errorf("Expected the pages joined in order, got %+v", docs[0])
+	}
+	if docs[1].Source != "notes.md" || docs[1].Text != "Notes." {
+		t.Errorf("Unexpected document %+v", docs[1])
+	}
+
+	if _, _, err := Read(fstest.MapFS{"a.json": {Data: []byte(`{}`)}}, FormatPrivateGPT, 0); err == nil {
+		t.Error("Expected an error for an export without a docstore")
+	}
+}
+
+func TestReadSkipsUnreadableFiles(t *testing.T) {
+	vault := fstest.MapFS{
+		"big.md":    {Data: []byte(strings.Repeat("x", 2048))},
+		"binary.md": {Data: []byte{0xff, 0xfe, 0x00}},
+		"ok.md":     {Data: []byte("fine")},
+	}
+
+	docs, skipped, err := Read(vault, FormatObsidian, 1024)
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if len(docs) != 1 || docs[0].Source != "ok.md" {
+		t.Errorf("Expected only ok.md, got %+v", docs)
+	}
+	if len(skipped) != 2 || skipped[0].Path != "big.md" || skipped[1].Path != "binary.md" {
+		t.Errorf("Expected big.md and binary.md skipped, got %+v", skipped)
+	}
+
+	if _, _, err := Read(vault, "evernote", 0); err == nil {
+		t.Error("Expected an error for an unknown format")
+	}
+}
+
+func TestCleanTags(t *testing.T) {
+	long := strings.Repeat("é", 40)
+	got := cleanTags([]string{" #a ", "A", "", "x,y", long})
+	if len(got) != 3 || got[0] != "a" || got[1] != "x-y" || len(got[2]) > maxTagLength {
+		t.Errorf("Unexpected tags %q", got)
+	}
+
+	many := make([]string, 50)
+	for i := range many {
+		many[i] = strings.Repeat("t", i+1)
+	}
+	if got := cleanTags(many); len(got) != maxTags {
+		t.Errorf("Expected %d tags, got %d", maxTags, len(got))
+	}
+}
@@ -0,0 +1,249 @@
+package importer
+
+import (
+	"path"
+	"regexp"
+	"strings"
+)
+
+var (
+	// wikilink matches [[Target]], [[Target#Heading]], [[Target|Title]] and
+	// their ![[embed]] forms
+	wikilink = regexp.MustCompile(`!?\[\[([^\[\]|#^]*)([#^][^\[\]|]*)?(?:\|([^\[\]]*))?\]\]`)
+	// inlineTag matches #tag and #nested/tag; a tag needs a character that is not a digit
+	inlineTag = regexp.MustCompile(`(?:^|[\s(])#([\p{L}\p{N}_/-]*[\p{L}_/-][\p{L}\p{N}_/-]*)`)
+	// bracketTag matches Logseq's #[[multi word tag]]
+	bracketTag = regexp.MustCompile(`#\[\[([^\[\]]+)\]\]`)
+	// logseqProperty matches a Logseq page property line such as tags:: a, b
+	logseqProperty = regexp.MustCompile(`^\s*(?:-\s+)?([A-Za-z0-9_-]+)::\s*(.*)$`)
+	// logseqJournal matches the file names of Logseq journal pages
+	logseqJournal = regexp.MustCompile(`^\d{4}_\d{2}_\d{2}$`)
+)
+
+// readVault reads the Markdown notes of an Obsidian vault or a Logseq graph
+func (r *reader) readVault(logseq bool) error {
+	var skipDirs []string
+	if logseq {
+		// Logseq keeps its settings and page backups in logseq/
+		skipDirs = []string{"logseq"}
+	}
+	paths, err := sortedPaths(r.fsys, []string{".md", ".markdown"}, skipDirs...)
+	if err != nil {
+		return err
+	}
+
+	for _, p := range paths {
+		content, ok, err := r.readText(p)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		doc := parseNote(content, logseq)
+		doc.Source = p
+		if logseq {
+			doc.Collection = logseqCollection(p)
+		} else if dir := path.Dir(p); dir != "." {
+			doc.Collection = strings.Split(dir, "/")[0]
+		}
+		r.docs = append(r.docs, doc)
+	}
+	return nil
+}
+
+// logseqCollection returns the collection of a Logseq page: journals, or the
+// root of a namespaced page such as Projects/Plan, stored as Projects___Plan.md
+func logseqCollection(p string) string {
+	name := strings.TrimSuffix(path.Base(p), path.Ext(p))
+	if logseqJournal.MatchString(name) || strings.HasPrefix(p, "journals/") {
+		return "journals"
+	}
+	if i := strings.Index(name, "___"); i > 0 {
+		return name[:i]
+	}
+	// Older graphs separate namespaces with %2F or a dot
+	if i := strings.Index(name, "%2F"); i > 0 {
+		return name[:i]
+	}
+	return ""
+}
+
+// parseNote reads the tags and links of a note and renders its text
+func parseNote(content string, logseq bool) Document {
+	var doc Document
+	body := content
+	if fm, rest, ok := splitFrontmatter(content); ok {
+		doc.Tags = append(doc.Tags, frontmatterTags(fm)...)
+		body = rest
+	}
+	if logseq {
+		doc.Tags = append(doc.Tags, propertyTags(body)...)
+	}
+
+	// Tags and links in code are examples, not part of the note's metadata
+	prose := stripCode(body)
+	for _, m := range bracketTag.FindAllStringSubmatch(prose, -1) {
+		doc.Tags = append(doc.Tags, m[1])
+	}
+	for _, m := range inlineTag.FindAllStringSubmatch(bracketTag.ReplaceAllString(prose, ""), -1) {
+		doc.Tags = append(doc.Tags, m[1])
+	}
+	doc.Links = links(prose)
+
+	doc.Text = strings.TrimSpace(wikilink.ReplaceAllStringFunc(body, renderLink))
+	return doc
+}
+
+// splitFrontmatter splits a note's YAML frontmatter from its body
+func splitFrontmatter(content string) (string, string, bool) {
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+	if !strings.HasPrefix(content, "---\n") {
+		return "", content, false
+	}
+	rest := content[len("---\n"):]
+	end := strings.Index(rest, "\n---")
+	if end < 0 {
+		if strings.HasPrefix(rest, "---") {
+			end = 0
+		} else {
+			return "", content, false
+		}
+	}
+	fm := rest[:end]
+	body := rest[end:]
+	body = strings.TrimPrefix(strings.TrimPrefix(body, "\n"), "---")
+	return fm, body, true
+}
+
+// frontmatterTags reads the tags and tag keys of YAML frontmatter, written as a
+// flow list, a comma or space separated string, or a block list
+func frontmatterTags(fm string) []string {
+	var tags []string
+	lines := strings.Split(fm, "\n")
+	for i := 0; i < len(lines); i++ {
+		key, value, ok := strings.Cut(lines[i], ":")
+		if !ok || strings.HasPrefix(lines[i], " ") {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		if key != "tags" && key != "tag" {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		if value != "" {
+			tags = append(tags, splitTagList(value)...)
+			continue
+		}
+		for i+1 < len(lines) {
+			item := strings.TrimSpace(lines[i+1])
+			if !strings.HasPrefix(item, "- ") && item != "-" {
+				break
+			}
+			i++
+			tags = append(tags, unquote(strings.TrimSpace(strings.TrimPrefix(item, "-"))))
+		}
+	}
+	return tags
+}
+
+// splitTagList splits a one-line tag value such as [a, b], "a, b" or "a b"
+func splitTagList(value string) []string {
+	value = strings.TrimSuffix(strings.TrimPrefix(value, "["), "]")
+	sep := ","
+	if !strings.Contains(value, ",") {
+		sep = " "
+	}
+	var tags []string
+	for _, tag := range strings.Split(value, sep) {
+		if tag = unquote(strings.TrimSpace(tag)); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// unquote removes YAML quotes around a scalar
+func unquote(s string) string {
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
+
+// propertyTags reads the tags:: property of a Logseq page, whose values may be
+// plain, [[linked]] or #tagged
+func propertyTags(body string) []string {
+	var tags []string
+	for _, line := range strings.Split(body, "\n") {
+		m := logseqProperty.FindStringSubmatch(line)
+		if m == nil {
+			if strings.TrimSpace(line) == "" {
+				continue
+			}
+			// Page properties come before the first block
+			break
+		}
+		if strings.ToLower(m[1]) != "tags" {
+			continue
+		}
+		for _, tag := range strings.Split(m[2], ",") {
+			tag = strings.TrimSpace(tag)
+			tag = strings.TrimPrefix(tag, "#")
+			tag = strings.TrimSuffix(strings.TrimPrefix(tag, "[["), "]]")
+			if tag != "" {
+				tags = append(tags, tag)
+			}
+		}
+	}
+	return tags
+}
+
+// stripCode removes fenced code blocks and inline code
+func stripCode(body string) string {
+	var sb strings.Builder
+	inFence := false
+	for _, line := range strings.Split(body, "\n") {
+		if trimmed := strings.TrimSpace(line); strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			continue
+		}
+		parts := strings.Split(line, "`")
+		for i := 0; i < len(parts); i += 2 {
+			sb.WriteString(parts[i])
+			sb.WriteByte(' ')
+		}
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
+
+// links returns the targets of a note's wikilinks in order of first appearance
+func links(prose string) []string {
+	seen := make(map[string]bool)
+	var targets []string
+	for _, m := range wikilink.FindAllStringSubmatch(prose, -1) {
+		target := strings.TrimSpace(m[1])
+		if target == "" || seen[target] {
+			continue
+		}
+		seen[target] = true
+		targets = append(targets, target)
+	}
+	return targets
+}
+
+// renderLink renders a wikilink as the text a reader sees: its title, or its target
+func renderLink(link string) string {
+	m := wikilink.FindStringSubmatch(link)
+	if title := strings.TrimSpace(m[3]); title != "" {
+		return title
+	}
+	if target := strings.TrimSpace(m[1]); target != "" {
+		return target
+	}
+	return strings.TrimLeft(m[2], "#^")
+}
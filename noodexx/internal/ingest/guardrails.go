@@ -13,6 +13,8 @@ type Guardrails struct {
 	BlockedExtensions  []string
 	SensitiveFilenames []string
 	MaxConcurrent      int
+	MaxChunks          int // Chunks kept per document, 0 for no limit
+	MaxChars           int // Characters of extracted text kept per document, 0 for no limit
 }
 
 // NewGuardrails creates guardrails with safe defaults
@@ -56,6 +58,25 @@ func (g *Guardrails) Check(filename, content string) error {
 	return nil
 }
 
+// truncateChars cuts text to its first limit characters and reports its original
+// length in characters; ok is false when text fits or limit is 0
+func truncateChars(text string, limit int) (truncated string, total int, ok bool) {
+	if limit <= 0 || len(text) <= limit {
+		return text, 0, false
+	}
+	cut := -1
+	for i := range text {
+		if total == limit {
+			cut = i
+		}
+		total++
+	}
+	if cut < 0 {
+		return text, total, false
+	}
+	return text[:cut], total, true
+}
+
 // IsAllowedExtension checks if a file extension is allowed
 func (g *Guardrails) IsAllowedExtension(ext string) bool {
 	ext = strings.ToLower(ext)
@@ -1,12 +1,15 @@
 package ingest
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"io"
 	"mime/multipart"
 	"net/http"
 	"net/url"
+	"noodexx/internal/events"
+	"noodexx/internal/extractors"
 	"noodexx/internal/logging"
 	"path/filepath"
 	"strings"
@@ -20,6 +23,12 @@ type LLMProvider interface {
 	Stream(ctx context.Context, messages []Message, w io.Writer) (string, error)
 }
 
+// BatchEmbedder is implemented by providers that can embed many texts in parallel,
+// such as an embedding pool; IngestText uses it when available
+type BatchEmbedder interface {
+	EmbedBatch(ctx context.Context, texts []string) ([][]float32, error)
+}
+
 // Message represents a chat message for LLM
 type Message struct {
 	Role    string `json:"role"`
@@ -30,6 +39,36 @@ type Message struct {
 type Store interface {
 	SaveChunk(ctx context.Context, userID int64, source, text string, embedding []float32, tags []string, summary string) error
 	DeleteChunksBySource(ctx context.Context, userID int64, source string) error
+	WithTx(ctx context.Context, fn func(tx StoreTx) error) error
+	// SaveSummary replaces a document's summary and records the model and content it came from
+	SaveSummary(ctx context.Context, userID int64, source, summary, model string) error
+	// GetSourceTexts returns the text of a user's document chunks in order
+	GetSourceTexts(ctx context.Context, userID int64, source string) ([]string, error)
+	// GetDefaultVisibility returns the visibility of documents the user ingests without choosing one
+	GetDefaultVisibility(ctx context.Context, userID int64) (string, error)
+	// GetDocumentContent returns the text a document was chunked from, or nil if it was not kept
+	GetDocumentContent(ctx context.Context, userID int64, source string) (*DocumentContent, error)
+}
+
+// StoreTx is the subset of store operations used inside a unit of work
+type StoreTx interface {
+	SaveChunk(ctx context.Context, userID int64, source, text string, embedding []float32, tags []string, summary string) error
+	DeleteChunksBySource(ctx context.Context, userID int64, source string) error
+	// CreateDocument records a document with a visibility; an existing document keeps its own
+	CreateDocument(ctx context.Context, userID int64, source, visibility string) error
+	// SetIngestWarning records why a document was truncated; empty clears it
+	SetIngestWarning(ctx context.Context, userID int64, source, warning string) error
+	// SetDocumentEncoding records the encoding a document was decoded from and what normalization changed
+	SetDocumentEncoding(ctx context.Context, userID int64, source, encoding, changes string) error
+	// SetSourceVisibility sets the visibility of every chunk of a document
+	SetSourceVisibility(ctx context.Context, userID int64, source, visibility string) error
+	// SaveChunkTitles sets the heading context and its embedding of a document's chunks, in order
+	SaveChunkTitles(ctx context.Context, userID int64, source string, titles []string, embeddings [][]float32) error
+	// SaveDocumentContent keeps the text a document was chunked from and the chunker settings used
+	SaveDocumentContent(ctx context.Context, userID int64, source, content, chunker string) error
+	// ReplaceChunks swaps a document's chunks for new ones, keeping its tags, summary and sharing
+	ReplaceChunks(ctx context.Context, userID int64, source string, texts []string, embeddings [][]float32) error
+	AddAuditEntry(ctx context.Context, opType, details, userCtx string) error
 }
 
 // Chunker interface for text chunking
@@ -37,16 +76,33 @@ type Chunker interface {
 	ChunkText(text string) []string
 }
 
+// DescribedChunker is implemented by chunkers that can describe their settings
+// The description is kept with each document so documents chunked with other
+// settings can be found and chunked again
+type DescribedChunker interface {
+	Settings() string
+}
+
+// TextExtractor converts files into text with the external extractor
+// registered for their type, returning nil when there is none
+type TextExtractor interface {
+	Extract(ctx context.Context, filename, mimeType string, data []byte) (*extractors.Result, error)
+}
+
 // Ingester orchestrates document ingestion
 type Ingester struct {
-	provider    LLMProvider
-	store       Store
-	chunker     Chunker
-	piiDetector *PIIDetector
-	guardrails  *Guardrails
-	privacyMode bool
-	summarize   bool
-	logger      *logging.Logger
+	provider        LLMProvider
+	store           Store
+	chunker         Chunker
+	piiDetector     *PIIDetector
+	guardrails      *Guardrails
+	privacyMode     bool
+	summarize       bool
+	summaryModel    string // Model the provider summarizes with, recorded with each summary
+	titleEmbeddings bool   // Whether chunks get a second embedding of their heading context
+	extractor       TextExtractor
+	events          *events.Bus // Told about finished ingestions; nil when nothing listens
+	logger          *logging.Logger
 }
 
 // NewIngester creates a new Ingester with all dependencies
@@ -63,7 +119,61 @@ func NewIngester(provider LLMProvider, store Store, chunker Chunker, privacyMode
 	}
 }
 
+// SetEventBus publishes an ingestion.completed event for every document ingested
+func (ing *Ingester) SetEventBus(bus *events.Bus) {
+	ing.events = bus
+}
+
+// SetSummaryModel sets the name of the model the provider generates summaries
+// with; summaries from another model are regenerated as stale
+func (ing *Ingester) SetSummaryModel(model string) {
+	ing.summaryModel = model
+}
+
+// SetSourceLimits caps how much of a single document is kept: the characters
+// of extracted text and the number of chunks. Longer documents are truncated
+// with a warning rather than rejected; 0 leaves a limit off
+func (ing *Ingester) SetSourceLimits(maxChunks, maxChars int) {
+	ing.guardrails.MaxChunks = maxChunks
+	ing.guardrails.MaxChars = maxChars
+}
+
+// SetExtractor converts files with external extractors; a file type with an
+// extractor registered is extracted by it instead of the built-in parsers
+func (ing *Ingester) SetExtractor(extractor TextExtractor) {
+	ing.extractor = extractor
+}
+
+// ExtractFile converts a file with the external extractor registered for its
+// type, returning its text and any tags it reported. ok is false when no
+// extractor handles the file
+func (ing *Ingester) ExtractFile(ctx context.Context, filename, mimeType string, data []byte) (text string, tags []string, ok bool, err error) {
+	if ing.extractor == nil {
+		return "", nil, false, nil
+	}
+	result, err := ing.extractor.Extract(ctx, filename, mimeType, data)
+	if err != nil {
+		return "", nil, true, err
+	}
+	if result == nil {
+		return "", nil, false, nil
+	}
+	ing.logger.WithFields(map[string]interface{}{
+		"file_path": filename,
+		"extractor": result.Extractor,
+		"text_size": len(result.Text),
+	}).Debug("file extracted by external extractor")
+	return result.Text, result.Tags, true, nil
+}
+
+// SummaryModel returns the model summaries are generated with
+func (ing *Ingester) SummaryModel() string {
+	return ing.summaryModel
+}
+
 // IngestText processes plain text with chunking, embedding, and storage
+// The document gets the visibility set on ctx with WithVisibility. Otherwise a
+// new document gets the user's default and an existing one keeps its visibility
 func (ing *Ingester) IngestText(ctx context.Context, userID int64, source, text string, tags []string) error {
 	logger := ing.logger.WithFields(map[string]interface{}{
 		"source":     source,
@@ -72,10 +182,15 @@ func (ing *Ingester) IngestText(ctx context.Context, userID int64, source, text
 	})
 	logger.Debug("starting text ingestion")
 
-	// Delete existing chunks for this source (replace behavior)
-	if err := ing.store.DeleteChunksBySource(ctx, userID, source); err != nil {
-		logger.WithContext("error", err.Error()).Warn("failed to delete existing chunks")
-		// Don't fail ingestion if delete fails - continue with ingestion
+	// Clean up line endings, entities, control characters and whitespace
+	// before anything else reads the text
+	text, normalization := NormalizeText(text)
+	normalization.Encoding = textEncoding(ctx)
+	if changes := normalization.Changes(); changes != "" || normalization.Encoding != EncodingUTF8 {
+		logger.WithFields(map[string]interface{}{
+			"encoding": normalization.Encoding,
+			"changes":  changes,
+		}).Debug("text normalized")
 	}
 
 	// Check guardrails
@@ -84,6 +199,19 @@ func (ing *Ingester) IngestText(ctx context.Context, userID int64, source, text
 		return fmt.Errorf("guardrails check failed: %w", err)
 	}
 
+	visibility, chosen, err := ing.visibility(ctx, userID)
+	if err != nil {
+		logger.WithContext("error", err.Error()).Error("visibility check failed")
+		return err
+	}
+
+	// Keep runaway documents, such as huge logs, from producing endless chunks
+	var warnings []string
+	if truncated, total, ok := truncateChars(text, ing.guardrails.MaxChars); ok {
+		text = truncated
+		warnings = append(warnings, fmt.Sprintf("truncated to the first %d of %d characters", ing.guardrails.MaxChars, total))
+	}
+
 	// Detect PII
 	if piiTypes := ing.piiDetector.Detect(text); len(piiTypes) > 0 {
 		logger.WithContext("pii_types", piiTypes).Error("PII detected")
@@ -105,8 +233,107 @@ func (ing *Ingester) IngestText(ctx context.Context, userID int64, source, text
 	// Chunk text
 	chunks := ing.chunker.ChunkText(text)
 	logger.WithContext("total_chunks", len(chunks)).Debug("text chunked")
+	if limit := ing.guardrails.MaxChunks; limit > 0 && len(chunks) > limit {
+		warnings = append(warnings, fmt.Sprintf("kept the first %d of %d chunks", limit, len(chunks)))
+		chunks = chunks[:limit]
+	}
+	warning := strings.Join(warnings, "; ")
+	if warning != "" {
+		logger.WithContext("warning", warning).Warn("document exceeds source limits")
+	}
+
+	// Embed every chunk before touching the database so no transaction is held
+	// open across provider calls
+	embeddings, err := ing.embedChunks(ctx, logger, chunks)
+	if err != nil {
+		return err
+	}
+	titles, titleEmbeddings, err := ing.titles(ctx, logger, source, text, chunks)
+	if err != nil {
+		return err
+	}
 
-	// Embed and save each chunk
+	// Replace existing chunks for this source in a single unit of work so a failed
+	// save never leaves the source half-deleted or half-written
+	err = ing.store.WithTx(ctx, func(tx StoreTx) error {
+		if err := tx.DeleteChunksBySource(ctx, userID, source); err != nil {
+			return fmt.Errorf("delete existing chunks failed: %w", err)
+		}
+		// New chunks inherit their document's visibility
+		if err := tx.CreateDocument(ctx, userID, source, visibility); err != nil {
+			return err
+		}
+		if chosen {
+			if err := tx.SetSourceVisibility(ctx, userID, source, visibility); err != nil {
+				return err
+			}
+		}
+		for i, chunk := range chunks {
+			if err := tx.SaveChunk(ctx, userID, source, chunk, embeddings[i], tags, summary); err != nil {
+				logger.WithFields(map[string]interface{}{
+					"chunk_index": i,
+					"error":       err.Error(),
+				}).Error("save chunk failed")
+				return fmt.Errorf("save chunk failed: %w", err)
+			}
+		}
+		if titles != nil {
+			if err := tx.SaveChunkTitles(ctx, userID, source, titles, titleEmbeddings); err != nil {
+				return err
+			}
+		}
+		if err := tx.SaveDocumentContent(ctx, userID, source, text, ing.ChunkerSettings()); err != nil {
+			return err
+		}
+		if err := tx.SetIngestWarning(ctx, userID, source, warning); err != nil {
+			return err
+		}
+		if err := tx.SetDocumentEncoding(ctx, userID, source, normalization.Encoding, normalization.Changes()); err != nil {
+			return err
+		}
+		if warning != "" {
+			return tx.AddAuditEntry(ctx, "ingest_warning", fmt.Sprintf("%s: %s", source, warning), "")
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Record what the summary was generated from so it can be refreshed when
+	// the content or summary model changes
+	if summary != "" {
+		if err := ing.store.SaveSummary(ctx, userID, source, summary, ing.summaryModel); err != nil {
+			logger.WithContext("error", err.Error()).Warn("failed to record summary provenance")
+		}
+	}
+
+	ing.events.Publish(ctx, events.Event{
+		Topic:  events.IngestionCompleted,
+		UserID: userID,
+		Data:   map[string]interface{}{"source": source, "chunks": len(chunks)},
+	})
+
+	logger.WithContext("total_chunks", len(chunks)).Debug("text ingestion completed")
+	return nil
+}
+
+// embedChunks embeds a document's chunks, in batch when the provider supports it
+func (ing *Ingester) embedChunks(ctx context.Context, logger *logging.Logger, chunks []string) ([][]float32, error) {
+	if batcher, ok := ing.provider.(BatchEmbedder); ok {
+		embeddings, err := batcher.EmbedBatch(ctx, chunks)
+		if err != nil {
+			logger.WithContext("error", err.Error()).Error("embedding failed")
+			return nil, fmt.Errorf("embedding failed: %w", err)
+		}
+		if len(embeddings) != len(chunks) {
+			return nil, fmt.Errorf("embedding failed: got %d embeddings for %d chunks", len(embeddings), len(chunks))
+		}
+		logger.WithContext("total_chunks", len(chunks)).Debug("chunks embedded in batch")
+		return embeddings, nil
+	}
+
+	embeddings := make([][]float32, len(chunks))
 	for i, chunk := range chunks {
 		embedding, err := ing.provider.Embed(ctx, chunk)
 		if err != nil {
@@ -114,24 +341,30 @@ func (ing *Ingester) IngestText(ctx context.Context, userID int64, source, text
 				"chunk_index": i,
 				"error":       err.Error(),
 			}).Error("embedding failed")
-			return fmt.Errorf("embedding failed: %w", err)
-		}
-
-		if err := ing.store.SaveChunk(ctx, userID, source, chunk, embedding, tags, summary); err != nil {
-			logger.WithFields(map[string]interface{}{
-				"chunk_index": i,
-				"error":       err.Error(),
-			}).Error("save chunk failed")
-			return fmt.Errorf("save chunk failed: %w", err)
+			return nil, fmt.Errorf("embedding failed: %w", err)
 		}
+		embeddings[i] = embedding
 		logger.WithFields(map[string]interface{}{
 			"chunk_index":  i,
 			"total_chunks": len(chunks),
-		}).Debug("chunk processed")
+		}).Debug("chunk embedded")
 	}
+	return embeddings, nil
+}
 
-	logger.WithContext("total_chunks", len(chunks)).Debug("text ingestion completed")
-	return nil
+// titles returns the heading context of each chunk and its embedding, or nil
+// when title embeddings are off
+func (ing *Ingester) titles(ctx context.Context, logger *logging.Logger, source, text string, chunks []string) ([]string, [][]float32, error) {
+	if !ing.titleEmbeddings {
+		return nil, nil, nil
+	}
+	titles := chunkTitles(source, text, chunks)
+	embeddings, err := ing.embedTitles(ctx, titles)
+	if err != nil {
+		logger.WithContext("error", err.Error()).Error("title embedding failed")
+		return nil, nil, fmt.Errorf("title embedding failed: %w", err)
+	}
+	return titles, embeddings, nil
 }
 
 // IngestURL fetches and processes a web page
@@ -184,6 +417,25 @@ func (ing *Ingester) IngestFile(ctx context.Context, userID int64, file multipar
 		return fmt.Errorf("file size %d exceeds limit %d", header.Size, ing.guardrails.MaxFileSize)
 	}
 
+	// A registered external extractor handles its types, whatever the allowed extensions
+	var r io.Reader = file
+	if ing.extractor != nil {
+		data, err := io.ReadAll(file)
+		if err != nil {
+			logger.WithContext("error", err.Error()).Error("failed to read file")
+			return fmt.Errorf("failed to read file: %w", err)
+		}
+		text, extractedTags, ok, err := ing.ExtractFile(ctx, header.Filename, header.Header.Get("Content-Type"), data)
+		if ok {
+			if err != nil {
+				logger.WithContext("error", err.Error()).Error("failed to extract file")
+				return fmt.Errorf("failed to extract file: %w", err)
+			}
+			return ing.IngestText(ctx, userID, header.Filename, text, append(append([]string{}, tags...), extractedTags...))
+		}
+		r = bytes.NewReader(data)
+	}
+
 	// Check extension
 	ext := strings.ToLower(filepath.Ext(header.Filename))
 	if !ing.guardrails.IsAllowedExtension(ext) {
@@ -197,11 +449,13 @@ func (ing *Ingester) IngestFile(ctx context.Context, userID int64, file multipar
 
 	switch ext {
 	case ".txt", ".md":
-		text, err = ing.parseText(file)
+		var encoding string
+		text, encoding, err = ing.parseText(r)
+		ctx = WithEncoding(ctx, encoding)
 	case ".pdf":
-		text, err = ing.parsePDF(file)
+		text, err = ing.parsePDF(r)
 	case ".html":
-		text, err = ing.parseHTML(file)
+		text, err = ing.parseHTML(r)
 	default:
 		logger.WithContext("extension", ext).Error("unsupported file type")
 		return fmt.Errorf("unsupported file type: %s", ext)
@@ -216,13 +470,15 @@ func (ing *Ingester) IngestFile(ctx context.Context, userID int64, file multipar
 	return ing.IngestText(ctx, userID, header.Filename, text, tags)
 }
 
-// parseText reads plain text from a reader
-func (ing *Ingester) parseText(r io.Reader) (string, error) {
-	bytes, err := io.ReadAll(r)
+// parseText reads plain text from a reader, decoding it from the encoding it
+// detects, which it returns
+func (ing *Ingester) parseText(r io.Reader) (string, string, error) {
+	data, err := io.ReadAll(r)
 	if err != nil {
-		return "", err
+		return "", "", err
 	}
-	return string(bytes), nil
+	text, encoding := DecodeText(data)
+	return text, encoding, nil
 }
 
 // parsePDF parses a PDF file (placeholder implementation)
@@ -240,6 +496,32 @@ func (ing *Ingester) parseHTML(r io.Reader) (string, error) {
 	return article.TextContent, nil
 }
 
+// RegenerateSummary summarizes a user's document again from its stored chunks
+// It works whether or not summaries are generated at ingest
+func (ing *Ingester) RegenerateSummary(ctx context.Context, userID int64, source string) error {
+	logger := ing.logger.WithContext("source", source)
+
+	texts, err := ing.store.GetSourceTexts(ctx, userID, source)
+	if err != nil {
+		return fmt.Errorf("failed to read document: %w", err)
+	}
+	if len(texts) == 0 {
+		return fmt.Errorf("source not found: %s", source)
+	}
+
+	summary, err := ing.generateSummary(ctx, strings.Join(texts, "\n"))
+	if err != nil {
+		logger.WithContext("error", err.Error()).Warn("summary generation failed")
+		return fmt.Errorf("summary generation failed: %w", err)
+	}
+	if err := ing.store.SaveSummary(ctx, userID, source, strings.TrimSpace(summary), ing.summaryModel); err != nil {
+		return err
+	}
+
+	logger.WithContext("model", ing.summaryModel).Debug("summary regenerated")
+	return nil
+}
+
 // generateSummary creates a 2-3 sentence summary using the LLM
 func (ing *Ingester) generateSummary(ctx context.Context, text string) (string, error) {
 	// Take first 1000 characters as input
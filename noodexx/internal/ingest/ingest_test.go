@@ -2,8 +2,11 @@ package ingest
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"io"
 	"mime/multipart"
+	"noodexx/internal/extractors"
 	"noodexx/internal/logging"
 	"strings"
 	"testing"
@@ -45,6 +48,14 @@ type mockStore struct {
 		tags      []string
 		summary   string
 	}
+	summaryModels []string                   // Models passed to SaveSummary
+	warnings      map[string]string          // Ingest warnings by source
+	audit         []string                   // Audit entry details
+	defaultVis    string                     // Default visibility returned for every user
+	visibilities  map[string]string          // Visibility set on each source
+	titles        map[string][]string        // Chunk titles saved for each source
+	contents      map[string]DocumentContent // Content kept for each source
+	encodings     map[string]string          // Encoding and changes recorded for each source
 }
 
 func (m *mockStore) SaveChunk(ctx context.Context, userID int64, source, text string, embedding []float32, tags []string, summary string) error {
@@ -78,6 +89,117 @@ func (m *mockStore) DeleteChunksBySource(ctx context.Context, userID int64, sour
 	return nil
 }
 
+func (m *mockStore) SetIngestWarning(ctx context.Context, userID int64, source, warning string) error {
+	if m.warnings == nil {
+		m.warnings = make(map[string]string)
+	}
+	if warning == "" {
+		delete(m.warnings, source)
+	} else {
+		m.warnings[source] = warning
+	}
+	return nil
+}
+
+func (m *mockStore) CreateDocument(ctx context.Context, userID int64, source, visibility string) error {
+	if _, ok := m.visibilities[source]; !ok {
+		m.SetSourceVisibility(ctx, userID, source, visibility)
+	}
+	return nil
+}
+
+func (m *mockStore) SetSourceVisibility(ctx context.Context, userID int64, source, visibility string) error {
+	if m.visibilities == nil {
+		m.visibilities = make(map[string]string)
+	}
+	m.visibilities[source] = visibility
+	return nil
+}
+
+func (m *mockStore) SaveChunkTitles(ctx context.Context, userID int64, source string, titles []string, embeddings [][]float32) error {
+	if m.titles == nil {
+		m.titles = make(map[string][]string)
+	}
+	m.titles[source] = titles
+	return nil
+}
+
+func (m *mockStore) SetDocumentEncoding(ctx context.Context, userID int64, source, encoding, changes string) error {
+	if m.encodings == nil {
+		m.encodings = make(map[string]string)
+	}
+	m.encodings[source] = encoding + "; " + changes
+	return nil
+}
+
+func (m *mockStore) SaveDocumentContent(ctx context.Context, userID int64, source, content, chunker string) error {
+	if m.contents == nil {
+		m.contents = make(map[string]DocumentContent)
+	}
+	m.contents[source] = DocumentContent{Content: content, Chunker: chunker}
+	return nil
+}
+
+func (m *mockStore) GetDocumentContent(ctx context.Context, userID int64, source string) (*DocumentContent, error) {
+	content, ok := m.contents[source]
+	if !ok {
+		return nil, nil
+	}
+	return &content, nil
+}
+
+func (m *mockStore) ReplaceChunks(ctx context.Context, userID int64, source string, texts []string, embeddings [][]float32) error {
+	var tags []string
+	var summary string
+	for _, chunk := range m.chunks {
+		if chunk.userID == userID && chunk.source == source {
+			tags, summary = chunk.tags, chunk.summary
+			break
+		}
+	}
+	m.DeleteChunksBySource(ctx, userID, source)
+	for i, text := range texts {
+		m.SaveChunk(ctx, userID, source, text, embeddings[i], tags, summary)
+	}
+	return nil
+}
+
+func (m *mockStore) GetDefaultVisibility(ctx context.Context, userID int64) (string, error) {
+	if m.defaultVis == "" {
+		return VisibilityPrivate, nil
+	}
+	return m.defaultVis, nil
+}
+
+func (m *mockStore) AddAuditEntry(ctx context.Context, opType, details, userCtx string) error {
+	m.audit = append(m.audit, details)
+	return nil
+}
+
+func (m *mockStore) WithTx(ctx context.Context, fn func(tx StoreTx) error) error {
+	return fn(m)
+}
+
+func (m *mockStore) SaveSummary(ctx context.Context, userID int64, source, summary, model string) error {
+	for i := range m.chunks {
+		if m.chunks[i].userID == userID && m.chunks[i].source == source {
+			m.chunks[i].summary = summary
+		}
+	}
+	m.summaryModels = append(m.summaryModels, model)
+	return nil
+}
+
+func (m *mockStore) GetSourceTexts(ctx context.Context, userID int64, source string) ([]string, error) {
+	var texts []string
+	for _, chunk := range m.chunks {
+		if chunk.userID == userID && chunk.source == source {
+			texts = append(texts, chunk.text)
+		}
+	}
+	return texts, nil
+}
+
 type mockChunker struct {
 	chunkSize int
 }
@@ -98,6 +220,10 @@ func (m *mockChunker) ChunkText(text string) []string {
 	return chunks
 }
 
+func (m *mockChunker) Settings() string {
+	return fmt.Sprintf("size %d", m.chunkSize)
+}
+
 // Helper function to create a test logger
 func newTestLogger() *logging.Logger {
 	return logging.NewLogger("test", logging.DEBUG, io.Discard)
@@ -126,6 +252,130 @@ func TestIngestText_Basic(t *testing.T) {
 	}
 }
 
+func TestIngestText_Visibility(t *testing.T) {
+	store := &mockStore{}
+	ingester := NewIngester(&mockProvider{}, store, &mockChunker{chunkSize: 100}, false, false, newTestLogger())
+	ctx := context.Background()
+
+	// New documents get the user's default unless the request chose one
+	if err := ingester.IngestText(ctx, 1, "mine.txt", "Notes", nil); err != nil {
+		t.Fatalf("IngestText failed: %v", err)
+	}
+	store.defaultVis = VisibilityShared
+	if err := ingester.IngestText(ctx, 1, "team.txt", "Notes", nil); err != nil {
+		t.Fatalf("IngestText failed: %v", err)
+	}
+	if err := ingester.IngestText(WithVisibility(ctx, VisibilityPublic), 1, "faq.txt", "Notes", nil); err != nil {
+		t.Fatalf("IngestText failed: %v", err)
+	}
+	if store.visibilities["mine.txt"] != VisibilityPrivate || store.visibilities["team.txt"] != VisibilityShared || store.visibilities["faq.txt"] != VisibilityPublic {
+		t.Errorf("Unexpected visibilities %v", store.visibilities)
+	}
+
+	// Ingesting a document again keeps its visibility unless the request chooses one
+	if err := ingester.IngestText(ctx, 1, "mine.txt", "Updated notes", nil); err != nil {
+		t.Fatalf("IngestText failed: %v", err)
+	}
+	if err := ingester.IngestText(WithVisibility(ctx, VisibilityPrivate), 1, "faq.txt", "Updated notes", nil); err != nil {
+		t.Fatalf("IngestText failed: %v", err)
+	}
+	if store.visibilities["mine.txt"] != VisibilityPrivate || store.visibilities["faq.txt"] != VisibilityPrivate {
+		t.Errorf("Unexpected visibilities after ingesting again %v", store.visibilities)
+	}
+
+	if err := ingester.IngestText(WithVisibility(ctx, "everyone"), 1, "bad.txt", "Notes", nil); err == nil {
+		t.Error("Expected an error for an unknown visibility")
+	}
+	for _, c := range store.chunks {
+		if c.source == "bad.txt" {
+			t.Error("Expected nothing saved for an unknown visibility")
+		}
+	}
+}
+
+func TestIngestText_TitleEmbeddings(t *testing.T) {
+	store := &mockStore{}
+	var embedded []string
+	provider := &mockProvider{embedFunc: func(ctx context.Context, text string) ([]float32, error) {
+		embedded = append(embedded, text)
+		return []float32{0.1, 0.2, 0.3}, nil
+	}}
+	ingester := NewIngester(provider, store, &mockChunker{chunkSize: 30}, false, false, newTestLogger())
+	ctx := context.Background()
+
+	text := "# Setup\nInstall the package.\n## Linux\nUse apt to install.\n```\n# not a heading\n```\n# Usage\nRun it."
+	if err := ingester.IngestText(ctx, 1, "docs/install_guide.md", text, nil); err != nil {
+		t.Fatalf("IngestText failed: %v", err)
+	}
+	if store.titles != nil {
+		t.Errorf("Expected no titles while title embeddings are off, got %v", store.titles)
+	}
+
+	ingester.SetTitleEmbeddings(true)
+	embedded = nil
+	if err := ingester.IngestText(ctx, 1, "docs/install_guide.md", text, nil); err != nil {
+		t.Fatalf("IngestText failed: %v", err)
+	}
+	titles := store.titles["docs/install_guide.md"]
+	want := chunkTitles("docs/install_guide.md", text, (&mockChunker{chunkSize: 30}).ChunkText(text))
+	if len(titles) != len(store.chunks) || strings.Join(titles, "|") != strings.Join(want, "|") {
+		t.Fatalf("Expected a title per chunk, got %q for %d chunks", titles, len(store.chunks))
+	}
+	// Each distinct title is embedded once, after the chunks
+	if got := len(embedded) - len(store.chunks); got < 1 || got >= len(titles) {
+		t.Errorf("Expected distinct titles embedded once, got %d title embeddings for %q", got, titles)
+	}
+}
+
+func TestChunkTitles(t *testing.T) {
+	text := "Intro text\n# Setup\nInstall.\n## Linux\nUse apt.\n```\n# comment\n```\n# Usage\nRun it."
+	chunks := []string{"Intro text", "Install.", "Use apt.\n```\n# comment", "Run it."}
+	want := []string{
+		"install guide",
+		"install guide > Setup",
+		"install guide > Setup > Linux",
+		"install guide > Usage",
+	}
+	got := chunkTitles("docs/install-guide.md", text, chunks)
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Chunk %d: expected %q, got %q", i, want[i], got[i])
+		}
+	}
+}
+
+func TestIngestText_SourceLimits(t *testing.T) {
+	store := &mockStore{}
+	chunker := &mockChunker{chunkSize: 10}
+	ingester := NewIngester(&mockProvider{}, store, chunker, false, false, newTestLogger())
+	ingester.SetSourceLimits(3, 45)
+
+	ctx := context.Background()
+	text := strings.Repeat("é", 60) // Multi-byte characters are counted once
+	if err := ingester.IngestText(ctx, 1, "huge.log", text, nil); err != nil {
+		t.Fatalf("IngestText failed: %v", err)
+	}
+
+	if len(store.chunks) != 3 {
+		t.Errorf("Expected 3 chunks, got %d", len(store.chunks))
+	}
+	want := "truncated to the first 45 of 60 characters; kept the first 3 of 9 chunks"
+	if store.warnings["huge.log"] != want {
+		t.Errorf("Expected warning %q, got %q", want, store.warnings["huge.log"])
+	}
+	if len(store.audit) != 1 || !strings.HasPrefix(store.audit[0], "huge.log: ") {
+		t.Errorf("Expected one audit entry for the warning, got %v", store.audit)
+	}
+
+	// Re-ingesting within the limits clears the warning
+	if err := ingester.IngestText(ctx, 1, "huge.log", "short", nil); err != nil {
+		t.Fatalf("IngestText failed: %v", err)
+	}
+	if _, ok := store.warnings["huge.log"]; ok {
+		t.Errorf("Expected the warning to be cleared, got %q", store.warnings["huge.log"])
+	}
+}
+
 func TestIngestText_WithSummary(t *testing.T) {
 	store := &mockStore{}
 	provider := &mockProvider{}
@@ -149,6 +399,47 @@ func TestIngestText_WithSummary(t *testing.T) {
 	}
 }
 
+func TestRegenerateSummary(t *testing.T) {
+	store := &mockStore{}
+	calls := 0
+	provider := &mockProvider{
+		streamFunc: func(ctx context.Context, messages []Message, w io.Writer) (string, error) {
+			calls++
+			if !strings.Contains(messages[0].Content, "first part") {
+				t.Errorf("Expected the summary prompt to include the document, got %q", messages[0].Content)
+			}
+			return " Summary from the new model. ", nil
+		},
+	}
+	ingester := NewIngester(provider, store, &mockChunker{chunkSize: 10}, false, true, newTestLogger())
+	ingester.SetSummaryModel("old-model")
+
+	ctx := context.Background()
+	if err := ingester.IngestText(ctx, 1, "doc.md", "first part and second part", nil); err != nil {
+		t.Fatalf("IngestText failed: %v", err)
+	}
+	if len(store.summaryModels) != 1 || store.summaryModels[0] != "old-model" {
+		t.Fatalf("Expected the summary to be recorded with its model, got %v", store.summaryModels)
+	}
+
+	ingester.SetSummaryModel("new-model")
+	if err := ingester.RegenerateSummary(ctx, 1, "doc.md"); err != nil {
+		t.Fatalf("RegenerateSummary failed: %v", err)
+	}
+	if calls != 2 || store.summaryModels[1] != "new-model" {
+		t.Errorf("Expected a second summary from the new model, got %d calls and %v", calls, store.summaryModels)
+	}
+	for _, chunk := range store.chunks {
+		if chunk.summary != "Summary from the new model." {
+			t.Errorf("Expected every chunk to carry the new summary, got %q", chunk.summary)
+		}
+	}
+
+	if err := ingester.RegenerateSummary(ctx, 1, "missing.md"); err == nil {
+		t.Error("Expected an error for an unknown document")
+	}
+}
+
 func TestIngestText_PIIDetection(t *testing.T) {
 	store := &mockStore{}
 	provider := &mockProvider{}
@@ -396,6 +687,56 @@ func TestIngestFile_PDFFile(t *testing.T) {
 	}
 }
 
+// mockExtractor handles .pdf files, upper-casing their content
+type mockExtractor struct {
+	err error
+}
+
+func (m *mockExtractor) Extract(ctx context.Context, filename, mimeType string, data []byte) (*extractors.Result, error) {
+	if !strings.HasSuffix(filename, ".pdf") {
+		return nil, nil
+	}
+	if m.err != nil {
+		return nil, m.err
+	}
+	return &extractors.Result{Extractor: "pdf", Text: strings.ToUpper(string(data)), Tags: []string{"scanned"}}, nil
+}
+
+func TestIngestFile_ExternalExtractor(t *testing.T) {
+	store := &mockStore{}
+	ingester := NewIngester(&mockProvider{}, store, &mockChunker{chunkSize: 100}, false, false, newTestLogger())
+	ingester.SetExtractor(&mockExtractor{})
+	ctx := context.Background()
+
+	// The extractor takes over a type the built-in parsers can't read
+	header := &multipart.FileHeader{Filename: "scan.pdf", Size: 11}
+	if err := ingester.IngestFile(ctx, 1, &mockFile{content: "pdf content"}, header, []string{"test"}); err != nil {
+		t.Fatalf("IngestFile failed: %v", err)
+	}
+	if len(store.chunks) != 1 || store.chunks[0].text != "PDF CONTENT" {
+		t.Fatalf("Expected the extracted text saved, got %+v", store.chunks)
+	}
+	if tags := store.chunks[0].tags; len(tags) != 2 || tags[0] != "test" || tags[1] != "scanned" {
+		t.Errorf("Expected the extractor's tags added, got %v", tags)
+	}
+
+	// Other types still use the built-in parsers
+	header = &multipart.FileHeader{Filename: "notes.txt", Size: 5}
+	if err := ingester.IngestFile(ctx, 1, &mockFile{content: "notes"}, header, nil); err != nil {
+		t.Fatalf("IngestFile failed: %v", err)
+	}
+	if len(store.chunks) != 2 || store.chunks[1].text != "notes" {
+		t.Errorf("Expected the text file parsed as before, got %+v", store.chunks)
+	}
+
+	// An extractor failure fails the upload
+	ingester.SetExtractor(&mockExtractor{err: errors.New("encrypted document")})
+	header = &multipart.FileHeader{Filename: "locked.pdf", Size: 4}
+	if err := ingester.IngestFile(ctx, 1, &mockFile{content: "data"}, header, nil); err == nil || !strings.Contains(err.Error(), "encrypted document") {
+		t.Errorf("Expected the extractor's error, got %v", err)
+	}
+}
+
 // mockFile implements multipart.File for testing
 type mockFile struct {
 	content string
@@ -437,3 +778,46 @@ func (m *mockFile) Seek(offset int64, whence int) (int64, error) {
 func (m *mockFile) Close() error {
 	return nil
 }
+
+// TestRechunk tests that a document is split again from its kept content with
+// the current chunker, keeping its tags, and that documents without kept
+// content are reported
+func TestRechunk(t *testing.T) {
+	store := &mockStore{}
+	chunker := &mockChunker{chunkSize: 10}
+	ingester := NewIngester(&mockProvider{}, store, chunker, false, false, newTestLogger())
+
+	ctx := context.Background()
+	text := "The quick brown fox jumps over the lazy dog."
+	if err := ingester.IngestText(ctx, 1, "fox.txt", text, []string{"animals"}); err != nil {
+		t.Fatalf("IngestText failed: %v", err)
+	}
+	if content := store.contents["fox.txt"]; content.Content != text || content.Chunker != "size 10" {
+		t.Fatalf("Expected the content kept with the chunker settings, got %+v", content)
+	}
+	if len(store.chunks) != 5 {
+		t.Fatalf("Expected 5 chunks, got %d", len(store.chunks))
+	}
+
+	chunker.chunkSize = 20
+	n, err := ingester.Rechunk(ctx, 1, "fox.txt")
+	if err != nil {
+		t.Fatalf("Rechunk failed: %v", err)
+	}
+	if n != 3 || len(store.chunks) != 3 || store.chunks[0].text != text[:20] {
+		t.Errorf("Expected 3 chunks of 20 characters, got %d: %+v", n, store.chunks)
+	}
+	if len(store.chunks[0].tags) != 1 || store.chunks[0].tags[0] != "animals" {
+		t.Errorf("Expected the tags kept, got %v", store.chunks[0].tags)
+	}
+	if store.contents["fox.txt"].Chunker != "size 20" {
+		t.Errorf("Expected the new chunker settings recorded, got %q", store.contents["fox.txt"].Chunker)
+	}
+	if len(store.audit) == 0 || !strings.Contains(store.audit[len(store.audit)-1], "size 10 -> size 20") {
+		t.Errorf("Expected a rechunk audit entry, got %v", store.audit)
+	}
+
+	if _, err := ingester.Rechunk(ctx, 1, "old.txt"); !errors.Is(err, ErrNoContent) {
+		t.Errorf("Expected ErrNoContent for a document without kept content, got %v", err)
+	}
+}
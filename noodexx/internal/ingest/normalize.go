@@ -0,0 +1,248 @@
+package ingest
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Encodings DecodeText recognizes
+const (
+	EncodingUTF8        = "utf-8"
+	EncodingUTF16LE     = "utf-16le"
+	EncodingUTF16BE     = "utf-16be"
+	EncodingWindows1252 = "windows-1252"
+)
+
+// windows1252 maps the bytes 0x80-0x9F, where Windows-1252 differs from
+// Latin-1, to their characters. Its five unassigned bytes stay C1 controls,
+// which NormalizeText removes
+var windows1252 = [32]rune{
+	'€', '\u0081', '‚', 'ƒ', '„', '…', '†', '‡',
+	'ˆ', '‰', 'Š', '‹', 'Œ', '\u008D', 'Ž', '\u008F',
+	'\u0090', '‘', '’', '“', '”', '•', '–', '—',
+	'˜', '™', 'š', '›', 'œ', '\u009D', 'ž', 'Ÿ',
+}
+
+// DecodeText converts file contents to UTF-8 text and returns the encoding it
+// detected: a byte order mark, UTF-16 without one from the pattern of its zero
+// bytes, valid UTF-8, or otherwise Windows-1252, which also reads Latin-1
+func DecodeText(data []byte) (string, string) {
+	switch {
+	case len(data) >= 3 && data[0] == 0xEF && data[1] == 0xBB && data[2] == 0xBF:
+		return string(data[3:]), EncodingUTF8
+	case len(data) >= 2 && data[0] == 0xFF && data[1] == 0xFE:
+		return decodeUTF16(data[2:], false), EncodingUTF16LE
+	case len(data) >= 2 && data[0] == 0xFE && data[1] == 0xFF:
+		return decodeUTF16(data[2:], true), EncodingUTF16BE
+	}
+
+	if bigEndian, ok := looksLikeUTF16(data); ok {
+		if bigEndian {
+			return decodeUTF16(data, true), EncodingUTF16BE
+		}
+		return decodeUTF16(data, false), EncodingUTF16LE
+	}
+
+	if utf8.Valid(data) {
+		return string(data), EncodingUTF8
+	}
+
+	var b strings.Builder
+	b.Grow(len(data) + len(data)/4)
+	for _, c := range data {
+		if c >= 0x80 && c <= 0x9F {
+			b.WriteRune(windows1252[c-0x80])
+		} else {
+			b.WriteRune(rune(c))
+		}
+	}
+	return b.String(), EncodingWindows1252
+}
+
+// looksLikeUTF16 reports whether text without a byte order mark is UTF-16, as
+// mostly-ASCII UTF-16 has a zero in every other byte, and which byte order
+func looksLikeUTF16(data []byte) (bigEndian, ok bool) {
+	n := len(data)
+	if n > 1024 {
+		n = 1024
+	}
+	n -= n % 2
+	if n < 4 {
+		return false, false
+	}
+	var evenZeros, oddZeros int
+	for i := 0; i < n; i += 2 {
+		if data[i] == 0 {
+			evenZeros++
+		}
+		if data[i+1] == 0 {
+			oddZeros++
+		}
+	}
+	pairs := n / 2
+	switch {
+	case oddZeros*10 >= pairs*4 && evenZeros*10 < pairs:
+		return false, true
+	case evenZeros*10 >= pairs*4 && oddZeros*10 < pairs:
+		return true, true
+	}
+	return false, false
+}
+
+// decodeUTF16 decodes UTF-16 in the given byte order; a trailing odd byte is dropped
+func decodeUTF16(data []byte, bigEndian bool) string {
+	units := make([]uint16, len(data)/2)
+	for i := range units {
+		if bigEndian {
+			units[i] = uint16(data[2*i])<<8 | uint16(data[2*i+1])
+		} else {
+			units[i] = uint16(data[2*i+1])<<8 | uint16(data[2*i])
+		}
+	}
+	return string(utf16.Decode(units))
+}
+
+// Normalization reports what NormalizeText changed in a document, and the
+// encoding it was decoded from
+type Normalization struct {
+	Encoding     string
+	LineEndings  int // CRLF and CR line endings converted to LF
+	Entities     int // HTML character references decoded
+	ControlChars int // Control characters and byte order marks removed
+	Whitespace   int // Runs of spaces and of blank lines collapsed
+}
+
+// Changes describes what was changed, such as "3 line endings, 1 entity";
+// empty when the text was already normal
+func (n Normalization) Changes() string {
+	var changes []string
+	add := func(count int, one, many string) {
+		switch {
+		case count == 1:
+			changes = append(changes, "1 "+one)
+		case count > 1:
+			changes = append(changes, fmt.Sprintf("%d %s", count, many))
+		}
+	}
+	add(n.LineEndings, "line ending", "line endings")
+	add(n.Entities, "entity", "entities")
+	add(n.ControlChars, "control character", "control characters")
+	add(n.Whitespace, "whitespace run", "whitespace runs")
+	return strings.Join(changes, ", ")
+}
+
+// entityPattern matches HTML character references such as &amp;, &#8217; and &#x2019;
+var entityPattern = regexp.MustCompile(`&(?:#[0-9]{1,7}|#[xX][0-9a-fA-F]{1,6}|[a-zA-Z][a-zA-Z0-9]{1,31});`)
+
+// NormalizeText cleans up text before it is chunked: line endings become LF,
+// HTML character references are decoded, control characters other than tabs
+// and newlines are removed, and runs of spaces within a line, trailing spaces
+// and more than one blank line in a row are collapsed. Leading indentation is
+// kept, so code and nested lists still read as they did
+func NormalizeText(text string) (string, Normalization) {
+	var n Normalization
+
+	n.LineEndings = strings.Count(text, "\r\n")
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	if crs := strings.Count(text, "\r"); crs > 0 {
+		n.LineEndings += crs
+		text = strings.ReplaceAll(text, "\r", "\n")
+	}
+
+	text = entityPattern.ReplaceAllStringFunc(text, func(ref string) string {
+		// Only whole references are decoded; UnescapeString would also decode
+		// the "&not" of "&notice;", leaving the rest, including the semicolon
+		decoded := html.UnescapeString(ref)
+		if decoded == ref || (strings.HasSuffix(decoded, ";") && decoded != ";") {
+			return ref
+		}
+		n.Entities++
+		return decoded
+	})
+
+	text = strings.Map(func(r rune) rune {
+		if (unicode.IsControl(r) && r != '\n' && r != '\t') || r == '\uFEFF' {
+			n.ControlChars++
+			return -1
+		}
+		return r
+	}, text)
+
+	lines := strings.Split(text, "\n")
+	out := make([]string, 0, len(lines))
+	blank := 0
+	for _, line := range lines {
+		line, collapsed := collapseSpaces(line)
+		n.Whitespace += collapsed
+		if line == "" {
+			blank++
+			if blank == 2 {
+				n.Whitespace++
+			}
+			if blank > 1 {
+				continue
+			}
+		} else {
+			blank = 0
+		}
+		out = append(out, line)
+	}
+	return strings.Join(out, "\n"), n
+}
+
+// collapseSpaces trims trailing whitespace from a line and replaces runs of
+// spaces, tabs and no-break spaces after its indentation with one space,
+// returning how many runs it changed
+func collapseSpaces(line string) (string, int) {
+	trimmed := strings.TrimRightFunc(line, unicode.IsSpace)
+	changed := 0
+	if len(trimmed) != len(line) {
+		changed++
+	}
+	body := strings.TrimLeft(trimmed, " \t")
+	indent := trimmed[:len(trimmed)-len(body)]
+
+	var b strings.Builder
+	b.Grow(len(trimmed))
+	b.WriteString(indent)
+	run, plain := 0, true
+	for _, r := range body {
+		if unicode.IsSpace(r) {
+			run++
+			plain = plain && r == ' '
+			continue
+		}
+		if run > 0 {
+			b.WriteByte(' ')
+			if run > 1 || !plain {
+				changed++
+			}
+			run, plain = 0, true
+		}
+		b.WriteRune(r)
+	}
+	return b.String(), changed
+}
+
+// encodingKey carries the encoding a file was decoded from
+type encodingKey struct{}
+
+// WithEncoding returns a context whose ingestions record that the text was
+// decoded from encoding; without one, text is recorded as UTF-8
+func WithEncoding(ctx context.Context, encoding string) context.Context {
+	return context.WithValue(ctx, encodingKey{}, encoding)
+}
+
+// textEncoding returns the encoding set on ctx with WithEncoding
+func textEncoding(ctx context.Context) string {
+	if e, ok := ctx.Value(encodingKey{}).(string); ok && e != "" {
+		return e
+	}
+	return EncodingUTF8
+}
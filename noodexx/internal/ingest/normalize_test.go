@@ -0,0 +1,84 @@
+package ingest
+
+import (
+	"context"
+	"testing"
+)
+
+// TestDecodeText tests encoding detection and decoding
+func TestDecodeText(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     []byte
+		text     string
+		encoding string
+	}{
+		{"utf-8", []byte("café"), "café", EncodingUTF8},
+		{"utf-8 with bom", []byte("\xEF\xBB\xBFcafé"), "café", EncodingUTF8},
+		{"utf-16le with bom", []byte{0xFF, 0xFE, 'h', 0, 'i', 0, 0xE9, 0}, "hié", EncodingUTF16LE},
+		{"utf-16be with bom", []byte{0xFE, 0xFF, 0, 'h', 0, 'i'}, "hi", EncodingUTF16BE},
+		{"utf-16le without bom", []byte{'n', 0, 'o', 0, 't', 0, 'e', 0, 's', 0}, "notes", EncodingUTF16LE},
+		{"windows-1252", []byte("\x93Smart\x94 quotes \x96 caf\xE9 \x80"), "“Smart” quotes – café €", EncodingWindows1252},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			text, encoding := DecodeText(tt.data)
+			if text != tt.text || encoding != tt.encoding {
+				t.Errorf("Expected %q as %s, got %q as %s", tt.text, tt.encoding, text, encoding)
+			}
+		})
+	}
+}
+
+// TestNormalizeText tests each normalization and what it reports
+func TestNormalizeText(t *testing.T) {
+	input := "Title\r\n\r\n\r\n\r\nFish &amp; chips&nbsp;&#8211; &#x2019;s   menu\x00  \rBody\ttext \n    indented  code\n\uFEFFend &notanentity;"
+	text, n := NormalizeText(input)
+
+	want := "Title\n\nFish & chips – ’s menu\nBody text\n    indented code\nend &notanentity;"
+	if text != want {
+		t.Errorf("Expected %q, got %q", want, text)
+	}
+	if n.LineEndings != 5 {
+		t.Errorf("Expected 5 line endings converted, got %d", n.LineEndings)
+	}
+	if n.Entities != 4 {
+		t.Errorf("Expected 4 entities decoded, got %d", n.Entities)
+	}
+	if n.ControlChars != 2 {
+		t.Errorf("Expected 2 control characters removed, got %d", n.ControlChars)
+	}
+	if n.Changes() == "" {
+		t.Error("Expected the changes to be described")
+	}
+
+	clean := "Already clean.\n\n    Indented line"
+	if text, n := NormalizeText(clean); text != clean || n.Changes() != "" {
+		t.Errorf("Expected clean text unchanged, got %q with %q", text, n.Changes())
+	}
+}
+
+// TestIngestTextRecordsEncoding tests that ingestion normalizes the text and
+// records the encoding set on the context with what normalizing changed
+func TestIngestTextRecordsEncoding(t *testing.T) {
+	store := &mockStore{}
+	ingester := NewIngester(&mockProvider{}, store, &mockChunker{chunkSize: 100}, false, false, newTestLogger())
+
+	ctx := WithEncoding(context.Background(), EncodingWindows1252)
+	if err := ingester.IngestText(ctx, 1, "legacy.txt", "Line one\r\nLine two", nil); err != nil {
+		t.Fatalf("IngestText failed: %v", err)
+	}
+	if store.contents["legacy.txt"].Content != "Line one\nLine two" {
+		t.Errorf("Expected normalized content, got %q", store.contents["legacy.txt"].Content)
+	}
+	if got := store.encodings["legacy.txt"]; got != "windows-1252; 1 line ending" {
+		t.Errorf("Expected the encoding and changes recorded, got %q", got)
+	}
+
+	if err := ingester.IngestText(context.Background(), 1, "plain.txt", "Plain", nil); err != nil {
+		t.Fatalf("IngestText failed: %v", err)
+	}
+	if got := store.encodings["plain.txt"]; got != "utf-8; " {
+		t.Errorf("Expected UTF-8 without changes by default, got %q", got)
+	}
+}
@@ -0,0 +1,83 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrNoContent is returned by Rechunk for documents whose content was not
+// kept, such as those ingested before contents were; they must be ingested again
+var ErrNoContent = errors.New("document content was not kept")
+
+// DocumentContent is the text a document was chunked from
+type DocumentContent struct {
+	Content string
+	Chunker string // Settings of the chunker that produced the current chunks
+}
+
+// ChunkerSettings describes the current chunker's settings, or "" if it cannot
+func (ing *Ingester) ChunkerSettings() string {
+	if described, ok := ing.chunker.(DescribedChunker); ok {
+		return described.Settings()
+	}
+	return ""
+}
+
+// Rechunk splits a user's document again from its kept content with the
+// current chunker and embeds the new chunks, returning how many there are.
+// The document keeps its tags, summary and sharing, and keeps its old chunks
+// if anything fails
+func (ing *Ingester) Rechunk(ctx context.Context, userID int64, source string) (int, error) {
+	logger := ing.logger.WithContext("source", source)
+	logger.Debug("starting re-chunking")
+
+	content, err := ing.store.GetDocumentContent(ctx, userID, source)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read document content: %w", err)
+	}
+	if content == nil {
+		return 0, ErrNoContent
+	}
+
+	chunks := ing.chunker.ChunkText(content.Content)
+	if limit := ing.guardrails.MaxChunks; limit > 0 && len(chunks) > limit {
+		chunks = chunks[:limit]
+	}
+	if len(chunks) == 0 {
+		return 0, fmt.Errorf("document %s has no text to chunk", source)
+	}
+
+	embeddings, err := ing.embedChunks(ctx, logger, chunks)
+	if err != nil {
+		return 0, err
+	}
+	titles, titleEmbeddings, err := ing.titles(ctx, logger, source, content.Content, chunks)
+	if err != nil {
+		return 0, err
+	}
+
+	settings := ing.ChunkerSettings()
+	err = ing.store.WithTx(ctx, func(tx StoreTx) error {
+		if err := tx.ReplaceChunks(ctx, userID, source, chunks, embeddings); err != nil {
+			return err
+		}
+		if titles != nil {
+			if err := tx.SaveChunkTitles(ctx, userID, source, titles, titleEmbeddings); err != nil {
+				return err
+			}
+		}
+		if err := tx.SaveDocumentContent(ctx, userID, source, content.Content, settings); err != nil {
+			return err
+		}
+		details := fmt.Sprintf("%s (user %d): %d chunks, %s -> %s", source, userID, len(chunks), content.Chunker, settings)
+		return tx.AddAuditEntry(ctx, "rechunk", details, "")
+	})
+	if err != nil {
+		logger.WithContext("error", err.Error()).Error("re-chunking failed")
+		return 0, err
+	}
+
+	logger.WithContext("total_chunks", len(chunks)).Debug("re-chunking completed")
+	return len(chunks), nil
+}
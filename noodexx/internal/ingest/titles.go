@@ -0,0 +1,132 @@
+package ingest
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// headingLine matches a markdown ATX heading, capturing its level and text
+var headingLine = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*\s*$`)
+
+// heading is a markdown heading and where it starts in a document
+type heading struct {
+	offset int
+	level  int
+	text   string
+}
+
+// SetTitleEmbeddings enables storing a second embedding per chunk, of its
+// heading context, which search combines with the body's similarity
+func (ing *Ingester) SetTitleEmbeddings(enabled bool) {
+	ing.titleEmbeddings = enabled
+}
+
+// documentTitle names a document after its source, without directories,
+// extension or word separators
+func documentTitle(source string) string {
+	name := filepath.Base(source)
+	if ext := filepath.Ext(name); ext != "" && ext != name {
+		name = strings.TrimSuffix(name, ext)
+	}
+	return strings.TrimSpace(strings.NewReplacer("_", " ", "-", " ").Replace(name))
+}
+
+// documentHeadings returns the markdown headings of text outside code blocks
+func documentHeadings(text string) []heading {
+	var headings []heading
+	inCode := false
+	offset := 0
+	for _, line := range strings.SplitAfter(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~"):
+			inCode = !inCode
+		case !inCode:
+			if m := headingLine.FindStringSubmatch(trimmed); m != nil && m[2] != "" {
+				headings = append(headings, heading{offset: offset, level: len(m[1]), text: m[2]})
+			}
+		}
+		offset += len(line)
+	}
+	return headings
+}
+
+// chunkTitles returns the heading context of each chunk: the document's title
+// followed by the headings of the section the chunk starts in, outermost
+// first, e.g. "install guide > Linux > Packages"
+func chunkTitles(source, text string, chunks []string) []string {
+	headings := documentHeadings(text)
+	title := documentTitle(source)
+
+	titles := make([]string, len(chunks))
+	var path []heading
+	next, pos := 0, 0
+	for i, chunk := range chunks {
+		// Chunks overlap, so each one is searched for after the previous one's start
+		if at := strings.Index(text[pos:], chunk); at >= 0 {
+			pos += at
+		}
+		for next < len(headings) && headings[next].offset <= pos {
+			h := headings[next]
+			for len(path) > 0 && path[len(path)-1].level >= h.level {
+				path = path[:len(path)-1]
+			}
+			path = append(path, h)
+			next++
+		}
+
+		parts := make([]string, 0, len(path)+1)
+		if title != "" {
+			parts = append(parts, title)
+		}
+		for _, h := range path {
+			parts = append(parts, h.text)
+		}
+		titles[i] = strings.Join(parts, " > ")
+		if pos < len(text) {
+			pos++
+		}
+	}
+	return titles
+}
+
+// embedTitles embeds each distinct title once
+func (ing *Ingester) embedTitles(ctx context.Context, titles []string) ([][]float32, error) {
+	var distinct []string
+	index := make(map[string]int)
+	for _, title := range titles {
+		if _, ok := index[title]; !ok {
+			index[title] = len(distinct)
+			distinct = append(distinct, title)
+		}
+	}
+
+	var vectors [][]float32
+	if batcher, ok := ing.provider.(BatchEmbedder); ok {
+		var err error
+		if vectors, err = batcher.EmbedBatch(ctx, distinct); err != nil {
+			return nil, err
+		}
+		if len(vectors) != len(distinct) {
+			return nil, fmt.Errorf("got %d embeddings for %d titles", len(vectors), len(distinct))
+		}
+	} else {
+		vectors = make([][]float32, len(distinct))
+		for i, title := range distinct {
+			vec, err := ing.provider.Embed(ctx, title)
+			if err != nil {
+				return nil, err
+			}
+			vectors[i] = vec
+		}
+	}
+
+	embeddings := make([][]float32, len(titles))
+	for i, title := range titles {
+		embeddings[i] = vectors[index[title]]
+	}
+	return embeddings, nil
+}
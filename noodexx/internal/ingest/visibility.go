@@ -0,0 +1,48 @@
+package ingest
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Document visibilities: private documents are searched by their owner only,
+// shared ones also by the users they are shared with, public ones by everyone
+const (
+	VisibilityPrivate = "private"
+	VisibilityShared  = "shared"
+	VisibilityPublic  = "public"
+)
+
+// Visibilities lists the document visibilities
+var Visibilities = []string{VisibilityPrivate, VisibilityShared, VisibilityPublic}
+
+// visibilityKey carries a visibility chosen for one ingestion
+type visibilityKey struct{}
+
+// WithVisibility returns a context whose ingestions store documents with
+// visibility instead of the user's default; empty keeps the default
+func WithVisibility(ctx context.Context, visibility string) context.Context {
+	if visibility == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, visibilityKey{}, visibility)
+}
+
+// visibility returns the visibility of a document the user is ingesting: the
+// one chosen with WithVisibility, or else the user's default. chosen reports
+// whether it was chosen for this ingestion
+func (ing *Ingester) visibility(ctx context.Context, userID int64) (visibility string, chosen bool, err error) {
+	visibility, chosen = ctx.Value(visibilityKey{}).(string)
+	if !chosen {
+		if visibility, err = ing.store.GetDefaultVisibility(ctx, userID); err != nil {
+			return "", false, fmt.Errorf("failed to get default visibility: %w", err)
+		}
+	}
+	for _, v := range Visibilities {
+		if visibility == v {
+			return visibility, chosen, nil
+		}
+	}
+	return "", false, fmt.Errorf("unknown visibility %q (expected one of %s)", visibility, strings.Join(Visibilities, ", "))
+}
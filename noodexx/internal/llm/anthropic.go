@@ -7,48 +7,47 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"math"
 	"net/http"
 	"noodexx/internal/logging"
 	"strings"
 	"time"
 )
 
+// anthropicDefaultMaxTokens is sent when a request does not set max tokens, which Anthropic requires
+const anthropicDefaultMaxTokens = 4096
+
 // AnthropicProvider implements the Provider interface for Anthropic Claude
 type AnthropicProvider struct {
-	apiKey     string
-	embedModel string // Uses Voyage AI for embeddings
-	chatModel  string
-	client     *http.Client
-	logger     *logging.Logger
+	apiKey    string
+	chatModel string
+	client    *http.Client
+	logger    *logging.Logger
 }
 
 // NewAnthropicProvider creates a new Anthropic provider
-func NewAnthropicProvider(apiKey, embedModel, chatModel string, logger *logging.Logger) *AnthropicProvider {
+// Anthropic has no embeddings API; pair it with an embedder using WithEmbedder
+func NewAnthropicProvider(apiKey, chatModel string, logger *logging.Logger) *AnthropicProvider {
 	return &AnthropicProvider{
-		apiKey:     apiKey,
-		embedModel: embedModel,
-		chatModel:  chatModel,
-		client:     &http.Client{Timeout: 60 * time.Second},
-		logger:     logger,
+		apiKey:    apiKey,
+		chatModel: chatModel,
+		client:    &http.Client{Timeout: 60 * time.Second},
+		logger:    logger,
 	}
 }
 
-// Embed generates an embedding vector for the given text
-// Note: Anthropic doesn't provide embeddings directly, use Voyage AI
+// Embed always fails with ErrEmbedUnsupported because Anthropic has no embeddings API
 func (p *AnthropicProvider) Embed(ctx context.Context, text string) ([]float32, error) {
-	logger := p.logger.WithFields(map[string]interface{}{
-		"provider":  "anthropic",
-		"model":     p.embedModel,
-		"operation": "embed",
-	})
-	logger.WithContext("error", "Anthropic doesn't provide embeddings - use Voyage AI").Error("embeddings not implemented")
-	// Anthropic doesn't provide embeddings directly
-	// This is a placeholder - actual implementation would use Voyage AI API
-	return nil, fmt.Errorf("anthropic: embeddings not yet implemented - use Voyage AI")
+	return nil, fmt.Errorf("anthropic: %w", ErrEmbedUnsupported)
 }
 
 // Stream generates a chat completion and streams it to the writer
 func (p *AnthropicProvider) Stream(ctx context.Context, messages []Message, w io.Writer) (string, error) {
+	return p.StreamWithOptions(ctx, messages, GenerationOptions{}, w)
+}
+
+// StreamWithOptions generates a chat completion with the given sampling parameters
+func (p *AnthropicProvider) StreamWithOptions(ctx context.Context, messages []Message, opts GenerationOptions, w io.Writer) (string, error) {
 	logger := p.logger.WithFields(map[string]interface{}{
 		"provider":      "anthropic",
 		"model":         p.chatModel,
@@ -58,32 +57,38 @@ func (p *AnthropicProvider) Stream(ctx context.Context, messages []Message, w io
 	logger.Debug("starting chat stream request")
 
 	start := time.Now()
-	// Convert messages to Anthropic format (system message separate)
-	var system string
-	var anthropicMessages []map[string]string
-
-	for _, msg := range messages {
-		if msg.Role == "system" {
-			system = msg.Content
-		} else {
-			anthropicMessages = append(anthropicMessages, map[string]string{
-				"role":    msg.Role,
-				"content": msg.Content,
-			})
-		}
+	// Anthropic takes system prompts in a separate field and needs alternating
+	// turns that open with the user
+	prompt := PromptFormatFor("anthropic", p.chatModel).Apply(messages)
+	anthropicMessages := make([]map[string]string, 0, len(prompt.Messages))
+	for _, msg := range prompt.Messages {
+		anthropicMessages = append(anthropicMessages, map[string]string{
+			"role":    msg.Role,
+			"content": msg.Content,
+		})
 	}
 
 	// Prepare request body
 	reqBody := map[string]interface{}{
 		"model":      p.chatModel,
 		"messages":   anthropicMessages,
-		"max_tokens": 4096,
+		"max_tokens": anthropicDefaultMaxTokens,
 		"stream":     true,
 	}
+	if opts.MaxTokens > 0 {
+		reqBody["max_tokens"] = opts.MaxTokens
+	}
+	if opts.Temperature != nil {
+		// Anthropic accepts temperatures from 0 to 1
+		reqBody["temperature"] = math.Min(*opts.Temperature, 1)
+	}
+	if opts.TopP != nil {
+		reqBody["top_p"] = *opts.TopP
+	}
 
 	// Add system message if present
-	if system != "" {
-		reqBody["system"] = system
+	if prompt.System != "" {
+		reqBody["system"] = prompt.System
 	}
 
 	body, err := json.Marshal(reqBody)
@@ -0,0 +1,216 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"noodexx/internal/logging"
+	"time"
+)
+
+// builtinMaxTokens is the sequence length used for embedding and reranking
+// Small sentence-transformers models are trained on 256 tokens
+const builtinMaxTokens = 256
+
+// ErrChatUnsupported is returned by providers that only embed
+var ErrChatUnsupported = errors.New("this provider does not support chat")
+
+// Reranker scores documents by their relevance to a query
+// Higher scores are more relevant
+type Reranker interface {
+	Rerank(ctx context.Context, query string, documents []string) ([]float64, error)
+}
+
+// inferenceSession runs a loaded model on one tokenized sequence
+// and returns the data and shape of its first output
+type inferenceSession interface {
+	Run(enc Encoding) ([]float32, []int64, error)
+	Close() error
+}
+
+// BuiltinProvider runs a small ONNX embedding model, and optionally a
+// cross-encoder reranker, in-process so no external service is needed
+type BuiltinProvider struct {
+	embedder  inferenceSession
+	reranker  inferenceSession // nil if no reranker is configured
+	tokenizer *WordPiece
+	logger    *logging.Logger
+}
+
+// NewBuiltinProvider loads the embedding model, its vocabulary and an optional reranker
+// runtimePath is the onnxruntime shared library; empty uses the platform default
+func NewBuiltinProvider(modelPath, vocabPath, rerankerPath, runtimePath string, logger *logging.Logger) (*BuiltinProvider, error) {
+	if modelPath == "" || vocabPath == "" {
+		return nil, fmt.Errorf("builtin: model and vocabulary paths are required")
+	}
+
+	tokenizer, err := LoadWordPiece(vocabPath, builtinMaxTokens)
+	if err != nil {
+		return nil, fmt.Errorf("builtin: %w", err)
+	}
+
+	embedder, err := newONNXSession(modelPath, runtimePath)
+	if err != nil {
+		return nil, fmt.Errorf("builtin: failed to load embedding model: %w", err)
+	}
+
+	var reranker inferenceSession
+	if rerankerPath != "" {
+		reranker, err = newONNXSession(rerankerPath, runtimePath)
+		if err != nil {
+			embedder.Close()
+			return nil, fmt.Errorf("builtin: failed to load reranker model: %w", err)
+		}
+	}
+
+	return newBuiltinProvider(embedder, reranker, tokenizer, logger), nil
+}
+
+// newBuiltinProvider creates a provider around already loaded sessions
+func newBuiltinProvider(embedder, reranker inferenceSession, tokenizer *WordPiece, logger *logging.Logger) *BuiltinProvider {
+	return &BuiltinProvider{
+		embedder:  embedder,
+		reranker:  reranker,
+		tokenizer: tokenizer,
+		logger:    logger,
+	}
+}
+
+// Embed generates a normalized embedding vector for the given text
+// Token embeddings are mean-pooled unless the model already outputs a sentence embedding
+func (p *BuiltinProvider) Embed(ctx context.Context, text string) ([]float32, error) {
+	logger := p.logger.WithFields(map[string]interface{}{
+		"provider":  "builtin",
+		"operation": "embed",
+	})
+	logger.Debug("starting embedding request")
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	enc := p.tokenizer.Encode(text)
+	data, shape, err := p.embedder.Run(enc)
+	if err != nil {
+		logger.WithContext("error", err.Error()).Error("embedding inference failed")
+		return nil, fmt.Errorf("builtin: embedding inference failed: %w", err)
+	}
+
+	embedding, err := poolEmbedding(data, shape, enc.AttentionMask)
+	if err != nil {
+		logger.WithContext("error", err.Error()).Error("unexpected embedding output")
+		return nil, fmt.Errorf("builtin: %w", err)
+	}
+
+	logger.WithFields(map[string]interface{}{
+		"tokens":     len(enc.InputIDs),
+		"dimensions": len(embedding),
+		"latency_ms": time.Since(start).Milliseconds(),
+	}).Debug("embedding completed")
+	return embedding, nil
+}
+
+// Rerank scores each document against the query with the cross-encoder
+// Scores are relevance probabilities between 0 and 1
+func (p *BuiltinProvider) Rerank(ctx context.Context, query string, documents []string) ([]float64, error) {
+	if p.reranker == nil {
+		return nil, fmt.Errorf("builtin: no reranker model configured")
+	}
+
+	start := time.Now()
+	scores := make([]float64, len(documents))
+	for i, doc := range documents {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		data, _, err := p.reranker.Run(p.tokenizer.EncodePair(query, doc))
+		if err != nil {
+			p.logger.WithContext("error", err.Error()).Error("rerank inference failed")
+			return nil, fmt.Errorf("builtin: rerank inference failed: %w", err)
+		}
+		if len(data) == 0 {
+			return nil, fmt.Errorf("builtin: reranker returned no score")
+		}
+		// Single-logit models score relevance directly; two-class models put it last
+		scores[i] = 1 / (1 + math.Exp(-float64(data[len(data)-1])))
+	}
+
+	p.logger.WithFields(map[string]interface{}{
+		"documents":  len(documents),
+		"latency_ms": time.Since(start).Milliseconds(),
+	}).Debug("rerank completed")
+	return scores, nil
+}
+
+// HasReranker reports whether a reranker model is loaded
+func (p *BuiltinProvider) HasReranker() bool {
+	return p.reranker != nil
+}
+
+// Stream is not supported: the builtin provider only embeds and reranks
+func (p *BuiltinProvider) Stream(ctx context.Context, messages []Message, w io.Writer) (string, error) {
+	return "", fmt.Errorf("builtin: %w; configure Ollama or a cloud provider for chat", ErrChatUnsupported)
+}
+
+// StreamWithOptions is not supported: the builtin provider only embeds and reranks
+func (p *BuiltinProvider) StreamWithOptions(ctx context.Context, messages []Message, opts GenerationOptions, w io.Writer) (string, error) {
+	return p.Stream(ctx, messages, w)
+}
+
+// Name returns the provider name
+func (p *BuiltinProvider) Name() string {
+	return "builtin"
+}
+
+// IsLocal returns true; inference runs in-process
+func (p *BuiltinProvider) IsLocal() bool {
+	return true
+}
+
+// poolEmbedding turns model output into a unit-length sentence embedding
+// A [1, dim] output is used as is; a [1, tokens, dim] output is mean-pooled over the attention mask
+func poolEmbedding(data []float32, shape []int64, mask []int64) ([]float32, error) {
+	var pooled []float32
+	switch len(shape) {
+	case 2:
+		pooled = append([]float32(nil), data...)
+	case 3:
+		tokens, dim := int(shape[1]), int(shape[2])
+		if tokens != len(mask) || len(data) != tokens*dim {
+			return nil, fmt.Errorf("embedding output shape %v does not match %d tokens", shape, len(mask))
+		}
+		pooled = make([]float32, dim)
+		var count float32
+		for t := 0; t < tokens; t++ {
+			if mask[t] == 0 {
+				continue
+			}
+			count++
+			for d := 0; d < dim; d++ {
+				pooled[d] += data[t*dim+d]
+			}
+		}
+		if count > 0 {
+			for d := range pooled {
+				pooled[d] /= count
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unsupported embedding output shape %v", shape)
+	}
+
+	var norm float64
+	for _, v := range pooled {
+		norm += float64(v) * float64(v)
+	}
+	if norm > 0 {
+		scale := float32(1 / math.Sqrt(norm))
+		for i := range pooled {
+			pooled[i] *= scale
+		}
+	}
+	return pooled, nil
+}
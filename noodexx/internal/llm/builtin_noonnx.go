@@ -0,0 +1,11 @@
+//go:build !onnx
+
+package llm
+
+import "fmt"
+
+// newONNXSession is unavailable unless noodexx is built with the onnx tag,
+// which links the onnxruntime bindings through cgo
+func newONNXSession(modelPath, runtimePath string) (inferenceSession, error) {
+	return nil, fmt.Errorf("noodexx was built without ONNX support (rebuild with -tags onnx)")
+}
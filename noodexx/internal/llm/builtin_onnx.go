@@ -0,0 +1,112 @@
+//go:build onnx
+
+package llm
+
+import (
+	"fmt"
+	"sync"
+
+	ort "github.com/yalue/onnxruntime_go"
+)
+
+// The onnxruntime environment is process-wide and initialized on first use
+var (
+	ortInitOnce sync.Once
+	ortInitErr  error
+)
+
+func initONNXRuntime(runtimePath string) error {
+	ortInitOnce.Do(func() {
+		if runtimePath != "" {
+			ort.SetSharedLibraryPath(runtimePath)
+		}
+		ortInitErr = ort.InitializeEnvironment()
+	})
+	return ortInitErr
+}
+
+// onnxSession runs a BERT-style model through onnxruntime
+type onnxSession struct {
+	session    *ort.DynamicAdvancedSession
+	inputNames []string
+}
+
+// newONNXSession loads a model whose inputs are some of input_ids, attention_mask and token_type_ids
+func newONNXSession(modelPath, runtimePath string) (inferenceSession, error) {
+	if err := initONNXRuntime(runtimePath); err != nil {
+		return nil, fmt.Errorf("failed to initialize onnxruntime: %w", err)
+	}
+
+	inputs, outputs, err := ort.GetInputOutputInfo(modelPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read model %s: %w", modelPath, err)
+	}
+	if len(outputs) == 0 {
+		return nil, fmt.Errorf("model %s has no outputs", modelPath)
+	}
+
+	inputNames := make([]string, len(inputs))
+	for i, input := range inputs {
+		switch input.Name {
+		case "input_ids", "attention_mask", "token_type_ids":
+			inputNames[i] = input.Name
+		default:
+			return nil, fmt.Errorf("model %s has unsupported input %q", modelPath, input.Name)
+		}
+	}
+
+	session, err := ort.NewDynamicAdvancedSession(modelPath, inputNames, []string{outputs[0].Name}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create session for %s: %w", modelPath, err)
+	}
+	return &onnxSession{session: session, inputNames: inputNames}, nil
+}
+
+// Run executes the model on a batch of one sequence
+func (s *onnxSession) Run(enc Encoding) ([]float32, []int64, error) {
+	shape := ort.NewShape(1, int64(len(enc.InputIDs)))
+
+	inputs := make([]ort.Value, len(s.inputNames))
+	defer func() {
+		for _, input := range inputs {
+			if input != nil {
+				input.Destroy()
+			}
+		}
+	}()
+	for i, name := range s.inputNames {
+		var data []int64
+		switch name {
+		case "input_ids":
+			data = enc.InputIDs
+		case "attention_mask":
+			data = enc.AttentionMask
+		case "token_type_ids":
+			data = enc.TypeIDs
+		}
+		tensor, err := ort.NewTensor(shape, data)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create %s tensor: %w", name, err)
+		}
+		inputs[i] = tensor
+	}
+
+	outputs := []ort.Value{nil}
+	if err := s.session.Run(inputs, outputs); err != nil {
+		return nil, nil, err
+	}
+	defer outputs[0].Destroy()
+
+	output, ok := outputs[0].(*ort.Tensor[float32])
+	if !ok {
+		return nil, nil, fmt.Errorf("model output is not a float32 tensor")
+	}
+	// Copy out of the tensor, which is freed on return
+	data := append([]float32(nil), output.GetData()...)
+	return data, append([]int64(nil), output.GetShape()...), nil
+}
+
+// Close releases the session
+func (s *onnxSession) Close() error {
+	return s.session.Destroy()
+}
@@ -0,0 +1,132 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+	"noodexx/internal/logging"
+	"reflect"
+	"testing"
+)
+
+// testVocab is a tiny uncased BERT vocabulary
+var testVocab = []string{"[PAD]", "[UNK]", "[CLS]", "[SEP]", "the", "cafe", "run", "##ning", "##s", ",", "!", "中"}
+
+// fakeSession returns a fixed output and records its inputs
+type fakeSession struct {
+	data   []float32
+	shape  []int64
+	inputs []Encoding
+}
+
+func (f *fakeSession) Run(enc Encoding) ([]float32, []int64, error) {
+	f.inputs = append(f.inputs, enc)
+	return f.data, f.shape, nil
+}
+
+func (f *fakeSession) Close() error { return nil }
+
+// TestWordPieceEncode tests basic tokenization, sub-word splitting and truncation
+func TestWordPieceEncode(t *testing.T) {
+	wp, err := NewWordPiece(testVocab, 8)
+	if err != nil {
+		t.Fatalf("NewWordPiece failed: %v", err)
+	}
+
+	// Accents are stripped, punctuation and CJK characters split, unknown words become [UNK]
+	enc := wp.Encode("The Café runnings, 中 xyz!")
+	want := []int64{2, 4, 5, 6, 7, 8, 9, 3}
+	if !reflect.DeepEqual(enc.InputIDs, want) {
+		t.Errorf("Expected IDs %v, got %v", want, enc.InputIDs)
+	}
+
+	enc = wp.Encode("the cafe")
+	if !reflect.DeepEqual(enc.InputIDs, []int64{2, 4, 5, 3}) || len(enc.AttentionMask) != 4 {
+		t.Errorf("Unexpected encoding: %+v", enc)
+	}
+
+	// Pairs mark the second segment and keep the query when truncating the document
+	pair := wp.EncodePair("the cafe", "run run run run run")
+	if !reflect.DeepEqual(pair.InputIDs, []int64{2, 4, 5, 3, 6, 6, 6, 3}) {
+		t.Errorf("Unexpected pair IDs %v", pair.InputIDs)
+	}
+	if !reflect.DeepEqual(pair.TypeIDs, []int64{0, 0, 0, 0, 1, 1, 1, 1}) {
+		t.Errorf("Unexpected pair type IDs %v", pair.TypeIDs)
+	}
+
+	if _, err := NewWordPiece([]string{"the"}, 8); err == nil {
+		t.Error("Expected error for vocabulary without special tokens")
+	}
+}
+
+// TestBuiltinProviderEmbed tests mean pooling over the attention mask and normalization
+func TestBuiltinProviderEmbed(t *testing.T) {
+	wp, _ := NewWordPiece(testVocab, 16)
+	logger := logging.NewLogger("test", logging.ERROR, io.Discard)
+
+	// Token embeddings for [CLS] the [SEP]: mean is (2, 0)
+	session := &fakeSession{data: []float32{1, 0, 3, 0, 2, 0}, shape: []int64{1, 3, 2}}
+	provider := newBuiltinProvider(session, nil, wp, logger)
+
+	embedding, err := provider.Embed(context.Background(), "the")
+	if err != nil {
+		t.Fatalf("Embed failed: %v", err)
+	}
+	if !reflect.DeepEqual(embedding, []float32{1, 0}) {
+		t.Errorf("Expected normalized mean embedding [1 0], got %v", embedding)
+	}
+
+	// Sentence embedding outputs are only normalized
+	session.data, session.shape = []float32{3, 4}, []int64{1, 2}
+	embedding, _ = provider.Embed(context.Background(), "the")
+	if math.Abs(float64(embedding[0])-0.6) > 1e-6 || math.Abs(float64(embedding[1])-0.8) > 1e-6 {
+		t.Errorf("Expected [0.6 0.8], got %v", embedding)
+	}
+
+	// Mismatched output shapes are rejected
+	session.data, session.shape = []float32{1, 2}, []int64{1, 5, 2}
+	if _, err := provider.Embed(context.Background(), "the"); err == nil {
+		t.Error("Expected error for mismatched output shape")
+	}
+
+	if _, err := provider.Stream(context.Background(), nil, io.Discard); !errors.Is(err, ErrChatUnsupported) {
+		t.Errorf("Expected ErrChatUnsupported, got %v", err)
+	}
+	if provider.HasReranker() {
+		t.Error("Expected no reranker")
+	}
+	if _, err := provider.Rerank(context.Background(), "q", []string{"a"}); err == nil {
+		t.Error("Expected error when reranking without a reranker")
+	}
+}
+
+// TestBuiltinProviderRerank tests that cross-encoder logits become relevance probabilities
+func TestBuiltinProviderRerank(t *testing.T) {
+	wp, _ := NewWordPiece(testVocab, 16)
+	logger := logging.NewLogger("test", logging.ERROR, io.Discard)
+	reranker := &fakeSession{data: []float32{0}, shape: []int64{1, 1}}
+	provider := newBuiltinProvider(&fakeSession{}, reranker, wp, logger)
+
+	scores, err := provider.Rerank(context.Background(), "the cafe", []string{"run", "the"})
+	if err != nil {
+		t.Fatalf("Rerank failed: %v", err)
+	}
+	if len(scores) != 2 || scores[0] != 0.5 {
+		t.Errorf("Expected sigmoid(0) = 0.5 for each document, got %v", scores)
+	}
+	if len(reranker.inputs) != 2 || reranker.inputs[1].TypeIDs[len(reranker.inputs[1].TypeIDs)-1] != 1 {
+		t.Errorf("Expected each document to be scored as a query pair, got %+v", reranker.inputs)
+	}
+}
+
+// TestNewBuiltinProviderRequiresPaths tests configuration errors
+func TestNewBuiltinProviderRequiresPaths(t *testing.T) {
+	logger := logging.NewLogger("test", logging.ERROR, io.Discard)
+	if _, err := NewBuiltinProvider("", "vocab.txt", "", "", logger); err == nil {
+		t.Error("Expected error without a model path")
+	}
+	if _, err := NewProvider(Config{Type: "builtin"}, true, logger); err == nil {
+		t.Error("Expected error for builtin provider without paths")
+	}
+}
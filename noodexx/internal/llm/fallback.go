@@ -0,0 +1,26 @@
+package llm
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrEmbedUnsupported is returned by providers that only chat
+var ErrEmbedUnsupported = errors.New("this provider does not support embeddings")
+
+// embeddingFallback chats with one provider and embeds with another
+type embeddingFallback struct {
+	Provider
+	embedder Provider
+}
+
+// WithEmbedder returns chat with its embeddings served by embedder
+// Name and IsLocal describe chat; embedder only sees text sent for embedding
+func WithEmbedder(chat, embedder Provider) Provider {
+	return &embeddingFallback{Provider: chat, embedder: embedder}
+}
+
+// Embed generates the embedding with the fallback embedder
+func (p *embeddingFallback) Embed(ctx context.Context, text string) ([]float32, error) {
+	return p.embedder.Embed(ctx, text)
+}
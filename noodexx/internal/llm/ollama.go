@@ -116,6 +116,11 @@ func (p *OllamaProvider) Embed(ctx context.Context, text string) ([]float32, err
 
 // Stream generates a chat completion and streams it to the writer
 func (p *OllamaProvider) Stream(ctx context.Context, messages []Message, w io.Writer) (string, error) {
+	return p.StreamWithOptions(ctx, messages, GenerationOptions{}, w)
+}
+
+// StreamWithOptions generates a chat completion with the given sampling parameters
+func (p *OllamaProvider) StreamWithOptions(ctx context.Context, messages []Message, opts GenerationOptions, w io.Writer) (string, error) {
 	logger := p.logger.WithFields(map[string]interface{}{
 		"provider":      "ollama",
 		"model":         p.chatModel,
@@ -125,12 +130,22 @@ func (p *OllamaProvider) Stream(ctx context.Context, messages []Message, w io.Wr
 	logger.Debug("starting chat stream request")
 
 	start := time.Now()
+	// Adapt the conversation to the chat template of the model
+	prompt := PromptFormatFor("ollama", p.chatModel).Apply(messages)
+
 	// Prepare request body
 	reqBody := map[string]interface{}{
 		"model":    p.chatModel,
-		"messages": messages,
+		"messages": prompt.Messages,
 		"stream":   true,
 	}
+	options := ollamaOptions(opts)
+	if len(prompt.Stop) > 0 {
+		options["stop"] = prompt.Stop
+	}
+	if len(options) > 0 {
+		reqBody["options"] = options
+	}
 
 	body, err := json.Marshal(reqBody)
 	if err != nil {
@@ -225,6 +240,77 @@ func (p *OllamaProvider) Stream(ctx context.Context, messages []Message, w io.Wr
 	return fullResponse.String(), nil
 }
 
+// WarmUp loads the chat and embedding models so the next request does not wait for them
+// The chat model is loaded with an empty prompt, which Ollama answers without generating
+func (p *OllamaProvider) WarmUp(ctx context.Context) error {
+	logger := p.logger.WithFields(map[string]interface{}{
+		"provider":  "ollama",
+		"model":     p.chatModel,
+		"operation": "warm_up",
+	})
+	logger.Debug("starting warm-up request")
+
+	start := time.Now()
+	if p.chatModel != "" {
+		body, err := json.Marshal(map[string]interface{}{
+			"model":  p.chatModel,
+			"prompt": "",
+			"stream": false,
+		})
+		if err != nil {
+			return fmt.Errorf("ollama: failed to marshal warm-up request: %w", err)
+		}
+
+		req, err := http.NewRequestWithContext(ctx, "POST", p.endpoint+"/api/generate", bytes.NewReader(body))
+		if err != nil {
+			return fmt.Errorf("ollama: failed to create warm-up request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := p.client.Do(req)
+		if err != nil {
+			logger.WithContext("error", err.Error()).Warn("warm-up request failed")
+			return fmt.Errorf("ollama: warm-up request failed: %w", err)
+		}
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			logger.WithFields(map[string]interface{}{
+				"status": resp.StatusCode,
+				"error":  string(bodyBytes),
+			}).Warn("warm-up returned non-OK status")
+			return fmt.Errorf("ollama: warm-up returned status %d: %s", resp.StatusCode, string(bodyBytes))
+		}
+	}
+
+	if p.embedModel != "" {
+		if _, err := p.Embed(ctx, "warm-up"); err != nil {
+			return err
+		}
+	}
+
+	logger.WithContext("latency_ms", time.Since(start).Milliseconds()).Debug("warm-up completed")
+	return nil
+}
+
+// Ping checks that the Ollama server answers by listing its models
+func (p *OllamaProvider) Ping(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", p.endpoint+"/api/tags", nil)
+	if err != nil {
+		return fmt.Errorf("ollama: failed to create ping request: %w", err)
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("ollama: not reachable at %s: %w", p.endpoint, err)
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("ollama: ping returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
 // Name returns the provider name
 func (p *OllamaProvider) Name() string {
 	return "ollama"
@@ -234,3 +320,18 @@ func (p *OllamaProvider) Name() string {
 func (p *OllamaProvider) IsLocal() bool {
 	return true
 }
+
+// ollamaOptions maps generation options to Ollama's model options
+func ollamaOptions(opts GenerationOptions) map[string]interface{} {
+	options := map[string]interface{}{}
+	if opts.Temperature != nil {
+		options["temperature"] = *opts.Temperature
+	}
+	if opts.TopP != nil {
+		options["top_p"] = *opts.TopP
+	}
+	if opts.MaxTokens > 0 {
+		options["num_predict"] = opts.MaxTokens
+	}
+	return options
+}
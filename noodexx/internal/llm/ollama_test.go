@@ -0,0 +1,106 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"noodexx/internal/logging"
+	"reflect"
+	"testing"
+)
+
+// TestOllamaStreamWithOptions tests that generation options are sent as Ollama model options
+func TestOllamaStreamWithOptions(t *testing.T) {
+	var requests []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		requests = append(requests, body)
+		w.Write([]byte(`{"message":{"content":"Hi"},"done":false}` + "\n" + `{"message":{"content":"!"},"done":true}` + "\n"))
+	}))
+	defer server.Close()
+
+	logger := logging.NewLogger("test", logging.ERROR, io.Discard)
+	p := NewOllamaProvider(server.URL, "embed", "chat", logger)
+	messages := []Message{{Role: "user", Content: "hello"}}
+
+	temperature, topP := 0.2, 0.9
+	var out bytes.Buffer
+	response, err := p.StreamWithOptions(context.Background(), messages, GenerationOptions{Temperature: &temperature, TopP: &topP, MaxTokens: 128}, &out)
+	if err != nil || response != "Hi!" || out.String() != "Hi!" {
+		t.Fatalf("Unexpected stream result %q (%v)", response, err)
+	}
+	want := map[string]interface{}{"temperature": 0.2, "top_p": 0.9, "num_predict": float64(128)}
+	if !reflect.DeepEqual(requests[0]["options"], want) {
+		t.Errorf("Expected options %v, got %v", want, requests[0]["options"])
+	}
+
+	// Without options the backend defaults are left alone, including a zero temperature being explicit
+	if _, err := p.Stream(context.Background(), messages, &out); err != nil {
+		t.Fatalf("Stream failed: %v", err)
+	}
+	if _, ok := requests[1]["options"]; ok {
+		t.Errorf("Expected no options by default, got %v", requests[1]["options"])
+	}
+	zero := 0.0
+	p.StreamWithOptions(context.Background(), messages, GenerationOptions{Temperature: &zero}, &out)
+	if opts, _ := requests[2]["options"].(map[string]interface{}); opts["temperature"] != 0.0 {
+		t.Errorf("Expected an explicit zero temperature, got %v", requests[2]["options"])
+	}
+}
+
+// TestOllamaWarmUp tests that warm-up loads the chat model without generating and embeds once
+func TestOllamaWarmUp(t *testing.T) {
+	var paths []string
+	var generate map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		if r.URL.Path == "/api/generate" {
+			json.NewDecoder(r.Body).Decode(&generate)
+			w.Write([]byte(`{"model":"chat","response":"","done":true}`))
+			return
+		}
+		w.Write([]byte(`{"embedding":[0.1]}`))
+	}))
+	defer server.Close()
+
+	logger := logging.NewLogger("test", logging.ERROR, io.Discard)
+	p := NewOllamaProvider(server.URL, "embed", "chat", logger)
+	if err := p.WarmUp(context.Background()); err != nil {
+		t.Fatalf("WarmUp() error = %v", err)
+	}
+	if !reflect.DeepEqual(paths, []string{"/api/generate", "/api/embeddings"}) {
+		t.Errorf("Expected a generate and an embed request, got %v", paths)
+	}
+	if generate["model"] != "chat" || generate["prompt"] != "" || generate["stream"] != false {
+		t.Errorf("Unexpected warm-up request %v", generate)
+	}
+}
+
+// TestOllamaPing tests that ping lists the models and reports servers that are down
+func TestOllamaPing(t *testing.T) {
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/tags" {
+			t.Errorf("Unexpected ping path %s", r.URL.Path)
+		}
+		w.WriteHeader(status)
+	}))
+
+	logger := logging.NewLogger("test", logging.ERROR, io.Discard)
+	p := NewOllamaProvider(server.URL, "embed", "chat", logger)
+	if err := p.Ping(context.Background()); err != nil {
+		t.Errorf("Ping() error = %v", err)
+	}
+	status = http.StatusServiceUnavailable
+	if err := p.Ping(context.Background()); err == nil {
+		t.Error("Expected an error for a non-OK status")
+	}
+	server.Close()
+	if err := p.Ping(context.Background()); err == nil {
+		t.Error("Expected an error once the server is down")
+	}
+}
@@ -115,6 +115,11 @@ func (p *OpenAIProvider) Embed(ctx context.Context, text string) ([]float32, err
 
 // Stream generates a chat completion and streams it to the writer
 func (p *OpenAIProvider) Stream(ctx context.Context, messages []Message, w io.Writer) (string, error) {
+	return p.StreamWithOptions(ctx, messages, GenerationOptions{}, w)
+}
+
+// StreamWithOptions generates a chat completion with the given sampling parameters
+func (p *OpenAIProvider) StreamWithOptions(ctx context.Context, messages []Message, opts GenerationOptions, w io.Writer) (string, error) {
 	logger := p.logger.WithFields(map[string]interface{}{
 		"provider":      "openai",
 		"model":         p.chatModel,
@@ -124,11 +129,21 @@ func (p *OpenAIProvider) Stream(ctx context.Context, messages []Message, w io.Wr
 	logger.Debug("starting chat stream request")
 
 	start := time.Now()
+	prompt := PromptFormatFor("openai", p.chatModel).Apply(messages)
 	reqBody := map[string]interface{}{
 		"model":    p.chatModel,
-		"messages": messages,
+		"messages": prompt.Messages,
 		"stream":   true,
 	}
+	if opts.Temperature != nil {
+		reqBody["temperature"] = *opts.Temperature
+	}
+	if opts.TopP != nil {
+		reqBody["top_p"] = *opts.TopP
+	}
+	if opts.MaxTokens > 0 {
+		reqBody["max_tokens"] = opts.MaxTokens
+	}
 
 	body, err := json.Marshal(reqBody)
 	if err != nil {
@@ -0,0 +1,150 @@
+package llm
+
+import "strings"
+
+// PromptFormat describes how a backend expects the message array of a chat request
+// Callers build one provider-neutral conversation; each provider shapes it with
+// the format for its API and model before sending
+type PromptFormat struct {
+	SystemField   bool     // System prompts go in a separate request field, not the messages (Anthropic)
+	SystemAsUser  bool     // The model has no system role; system prompts are prepended to the first user message
+	MergeRoles    bool     // Consecutive messages from the same role are joined, for APIs and templates that need alternation
+	UserFirst     bool     // The conversation must open with a user message
+	StopSequences []string // End-of-turn markers of the model's chat template, sent as stop sequences
+}
+
+// FormattedPrompt is a conversation shaped for one backend
+type FormattedPrompt struct {
+	System   string // Set only when the format uses SystemField
+	Messages []Message
+	Stop     []string
+}
+
+// promptSeparator joins system prompts and merged messages
+const promptSeparator = "\n\n"
+
+// localModelFormats adjusts the format for local model families whose chat
+// templates need it, matched by prefix of the model name
+// Ollama applies each model's template itself; these cover what templates
+// leave out, such as a missing system role or an end-of-turn marker the
+// model's Modelfile does not stop on
+var localModelFormats = []struct {
+	prefix string
+	format PromptFormat
+}{
+	{"gemma", PromptFormat{SystemAsUser: true, MergeRoles: true, UserFirst: true, StopSequences: []string{"<end_of_turn>"}}},
+	{"mistral", PromptFormat{MergeRoles: true, UserFirst: true, StopSequences: []string{"</s>"}}},
+	{"mixtral", PromptFormat{MergeRoles: true, UserFirst: true, StopSequences: []string{"</s>"}}},
+	{"llama3", PromptFormat{StopSequences: []string{"<|eot_id|>"}}},
+	{"llama2", PromptFormat{MergeRoles: true, UserFirst: true, StopSequences: []string{"</s>"}}},
+	{"phi3", PromptFormat{StopSequences: []string{"<|end|>"}}},
+	{"qwen", PromptFormat{StopSequences: []string{"<|im_end|>"}}},
+}
+
+// PromptFormatFor returns the message format for a provider and chat model
+// Anthropic takes the system prompt as a request field and needs alternating
+// turns starting with the user; OpenAI accepts the conversation as it is; Ollama
+// depends on the model's chat template
+func PromptFormatFor(providerName, model string) PromptFormat {
+	switch providerName {
+	case "anthropic":
+		return PromptFormat{SystemField: true, MergeRoles: true, UserFirst: true}
+	case "ollama":
+		name := strings.ToLower(model)
+		// Drop a registry namespace such as "library/" or "hf.co/user/"
+		if i := strings.LastIndex(name, "/"); i >= 0 {
+			name = name[i+1:]
+		}
+		for _, f := range localModelFormats {
+			if strings.HasPrefix(name, f.prefix) {
+				return f.format
+			}
+		}
+	}
+	return PromptFormat{}
+}
+
+// Apply shapes messages for the backend
+// Empty messages are dropped, since some APIs reject them; the messages passed
+// in are not modified
+func (f PromptFormat) Apply(messages []Message) FormattedPrompt {
+	var system []string
+	var turns []Message
+	for _, msg := range messages {
+		if strings.TrimSpace(msg.Content) == "" {
+			continue
+		}
+		if msg.Role == "system" && (f.SystemField || f.SystemAsUser) {
+			system = append(system, msg.Content)
+			continue
+		}
+		turns = append(turns, msg)
+	}
+
+	var prompt FormattedPrompt
+	if f.SystemField {
+		prompt.System = strings.Join(system, promptSeparator)
+	}
+
+	if f.UserFirst && (len(turns) == 0 || turns[0].Role != "user") {
+		// A conversation that opens with the assistant gets an empty-handed user
+		// turn ahead of it; with no turns at all the system prompt becomes one
+		if len(turns) > 0 || (f.SystemAsUser && len(system) > 0) {
+			turns = append([]Message{{Role: "user", Content: ""}}, turns...)
+		}
+	}
+
+	if f.SystemAsUser && len(system) > 0 {
+		joined := strings.Join(system, promptSeparator)
+		placed := false
+		for i := range turns {
+			if turns[i].Role == "user" {
+				turns[i].Content = joinNonEmpty(joined, turns[i].Content)
+				placed = true
+				break
+			}
+		}
+		if !placed {
+			turns = append([]Message{{Role: "user", Content: joined}}, turns...)
+		}
+	}
+
+	if f.MergeRoles {
+		turns = mergeRoles(turns)
+	}
+
+	// The only empty message left is a placeholder user turn; fill it so APIs accept it
+	for i := range turns {
+		if turns[i].Content == "" {
+			turns[i].Content = "(continue)"
+		}
+	}
+
+	prompt.Messages = turns
+	prompt.Stop = f.StopSequences
+	return prompt
+}
+
+// mergeRoles joins consecutive messages from the same role
+func mergeRoles(messages []Message) []Message {
+	merged := make([]Message, 0, len(messages))
+	for _, msg := range messages {
+		if n := len(merged); n > 0 && merged[n-1].Role == msg.Role {
+			merged[n-1].Content = joinNonEmpty(merged[n-1].Content, msg.Content)
+			continue
+		}
+		merged = append(merged, msg)
+	}
+	return merged
+}
+
+// joinNonEmpty joins two pieces of text with promptSeparator, skipping empty ones
+func joinNonEmpty(a, b string) string {
+	switch {
+	case a == "":
+		return b
+	case b == "":
+		return a
+	}
+	return a + promptSeparator + b
+}
@@ -0,0 +1,134 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"noodexx/internal/logging"
+	"reflect"
+	"testing"
+)
+
+func TestPromptFormatApply(t *testing.T) {
+	conversation := []Message{
+		{Role: "system", Content: "Be brief."},
+		{Role: "system", Content: "Cite sources."},
+		{Role: "assistant", Content: "Hello!"},
+		{Role: "user", Content: "What is RAG?"},
+		{Role: "user", Content: "   "},
+		{Role: "user", Content: "Keep it short."},
+	}
+
+	tests := []struct {
+		name       string
+		provider   string
+		model      string
+		wantSystem string
+		want       []Message
+		wantStop   []string
+	}{
+		{
+			name:     "openai keeps the conversation",
+			provider: "openai",
+			model:    "gpt-4o",
+			want: []Message{
+				{Role: "system", Content: "Be brief."},
+				{Role: "system", Content: "Cite sources."},
+				{Role: "assistant", Content: "Hello!"},
+				{Role: "user", Content: "What is RAG?"},
+				{Role: "user", Content: "Keep it short."},
+			},
+		},
+		{
+			name:       "anthropic moves system prompts and alternates turns",
+			provider:   "anthropic",
+			model:      "claude-3-5-sonnet",
+			wantSystem: "Be brief.\n\nCite sources.",
+			want: []Message{
+				{Role: "user", Content: "(continue)"},
+				{Role: "assistant", Content: "Hello!"},
+				{Role: "user", Content: "What is RAG?\n\nKeep it short."},
+			},
+		},
+		{
+			name:     "gemma folds system prompts into the first user turn",
+			provider: "ollama",
+			model:    "gemma2:9b",
+			want: []Message{
+				{Role: "user", Content: "Be brief.\n\nCite sources."},
+				{Role: "assistant", Content: "Hello!"},
+				{Role: "user", Content: "What is RAG?\n\nKeep it short."},
+			},
+			wantStop: []string{"<end_of_turn>"},
+		},
+		{
+			name:     "llama3 only adds its end-of-turn marker",
+			provider: "ollama",
+			model:    "library/Llama3.1:8b",
+			want: []Message{
+				{Role: "system", Content: "Be brief."},
+				{Role: "system", Content: "Cite sources."},
+				{Role: "assistant", Content: "Hello!"},
+				{Role: "user", Content: "What is RAG?"},
+				{Role: "user", Content: "Keep it short."},
+			},
+			wantStop: []string{"<|eot_id|>"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prompt := PromptFormatFor(tt.provider, tt.model).Apply(conversation)
+			if prompt.System != tt.wantSystem {
+				t.Errorf("Expected system %q, got %q", tt.wantSystem, prompt.System)
+			}
+			if !reflect.DeepEqual(prompt.Messages, tt.want) {
+				t.Errorf("Expected messages %v, got %v", tt.want, prompt.Messages)
+			}
+			if !reflect.DeepEqual(prompt.Stop, tt.wantStop) {
+				t.Errorf("Expected stop %v, got %v", tt.wantStop, prompt.Stop)
+			}
+		})
+	}
+
+	// The caller's messages are left untouched
+	if conversation[3].Content != "What is RAG?" {
+		t.Errorf("Apply modified its input: %v", conversation)
+	}
+
+	// A system prompt alone becomes the user turn for models without a system role
+	prompt := PromptFormatFor("ollama", "gemma").Apply([]Message{{Role: "system", Content: "Summarise."}})
+	if want := []Message{{Role: "user", Content: "Summarise."}}; !reflect.DeepEqual(prompt.Messages, want) {
+		t.Errorf("Expected %v, got %v", want, prompt.Messages)
+	}
+}
+
+// TestOllamaStreamPromptFormat tests that Ollama requests follow the model's chat template
+func TestOllamaStreamPromptFormat(t *testing.T) {
+	var request map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&request)
+		w.Write([]byte(`{"message":{"content":"Hi"},"done":true}` + "\n"))
+	}))
+	defer server.Close()
+
+	logger := logging.NewLogger("test", logging.ERROR, io.Discard)
+	p := NewOllamaProvider(server.URL, "embed", "gemma2", logger)
+	messages := []Message{{Role: "system", Content: "Be brief."}, {Role: "user", Content: "hello"}}
+
+	var out bytes.Buffer
+	if _, err := p.Stream(context.Background(), messages, &out); err != nil {
+		t.Fatalf("Stream failed: %v", err)
+	}
+	wantMessages := []interface{}{map[string]interface{}{"role": "user", "content": "Be brief.\n\nhello"}}
+	if !reflect.DeepEqual(request["messages"], wantMessages) {
+		t.Errorf("Expected messages %v, got %v", wantMessages, request["messages"])
+	}
+	wantOptions := map[string]interface{}{"stop": []interface{}{"<end_of_turn>"}}
+	if !reflect.DeepEqual(request["options"], wantOptions) {
+		t.Errorf("Expected options %v, got %v", wantOptions, request["options"])
+	}
+}
@@ -15,6 +15,9 @@ type Provider interface {
 	// Stream generates a chat completion and streams it to the writer
 	Stream(ctx context.Context, messages []Message, w io.Writer) (string, error)
 
+	// StreamWithOptions is Stream with sampling parameters for this request
+	StreamWithOptions(ctx context.Context, messages []Message, opts GenerationOptions, w io.Writer) (string, error)
+
 	// Name returns the provider name (e.g., "ollama", "openai", "anthropic")
 	Name() string
 
@@ -22,15 +25,35 @@ type Provider interface {
 	IsLocal() bool
 }
 
+// Warmer is implemented by providers whose models load on first use
+// WarmUp loads them ahead of time so the next request does not wait
+type Warmer interface {
+	WarmUp(ctx context.Context) error
+}
+
+// Pinger is implemented by providers served by a separate process that may
+// not be running yet; Ping checks that it answers without loading any model
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
 // Message represents a chat message
 type Message struct {
 	Role    string `json:"role"` // "system", "user", "assistant"
 	Content string `json:"content"`
 }
 
+// GenerationOptions controls sampling for a chat completion
+// Nil and zero fields leave the backend's default in place
+type GenerationOptions struct {
+	Temperature *float64
+	TopP        *float64
+	MaxTokens   int
+}
+
 // Config holds provider configuration
 type Config struct {
-	Type                string // "ollama", "openai", "anthropic"
+	Type                string // "ollama", "openai", "anthropic", "builtin"
 	OllamaEndpoint      string
 	OllamaEmbedModel    string
 	OllamaChatModel     string
@@ -38,15 +61,18 @@ type Config struct {
 	OpenAIEmbedModel    string
 	OpenAIChatModel     string
 	AnthropicKey        string
-	AnthropicEmbedModel string
 	AnthropicChatModel  string
+	BuiltinModelPath    string // ONNX embedding model
+	BuiltinVocabPath    string // WordPiece vocab.txt for the models
+	BuiltinRerankerPath string // Optional ONNX cross-encoder
+	BuiltinRuntimePath  string // onnxruntime shared library, empty for the platform default
 }
 
 // NewProvider creates a provider based on config with privacy mode enforcement
 func NewProvider(cfg Config, privacyMode bool, logger *logging.Logger) (Provider, error) {
-	// Privacy mode enforcement: only allow Ollama when privacy mode is enabled
-	if privacyMode && cfg.Type != "ollama" {
-		return nil, fmt.Errorf("privacy mode is enabled - only Ollama provider is allowed")
+	// Privacy mode enforcement: only allow local providers when privacy mode is enabled
+	if privacyMode && cfg.Type != "ollama" && cfg.Type != "builtin" {
+		return nil, fmt.Errorf("privacy mode is enabled - only Ollama or builtin provider is allowed")
 	}
 
 	switch cfg.Type {
@@ -61,7 +87,13 @@ func NewProvider(cfg Config, privacyMode bool, logger *logging.Logger) (Provider
 		if cfg.AnthropicKey == "" {
 			return nil, fmt.Errorf("anthropic API key is required")
 		}
-		return NewAnthropicProvider(cfg.AnthropicKey, cfg.AnthropicEmbedModel, cfg.AnthropicChatModel, logger), nil
+		return NewAnthropicProvider(cfg.AnthropicKey, cfg.AnthropicChatModel, logger), nil
+	case "builtin":
+		provider, err := NewBuiltinProvider(cfg.BuiltinModelPath, cfg.BuiltinVocabPath, cfg.BuiltinRerankerPath, cfg.BuiltinRuntimePath, logger)
+		if err != nil {
+			return nil, err
+		}
+		return provider, nil
 	default:
 		return nil, fmt.Errorf("unknown provider type: %s", cfg.Type)
 	}
@@ -0,0 +1,57 @@
+package llm
+
+import "regexp"
+
+// ToolDefinition describes a function the model may call
+// Parameters is a JSON Schema object describing the call arguments
+type ToolDefinition struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	Parameters  map[string]interface{} `json:"parameters"`
+}
+
+// invalidToolNameChars matches characters providers reject in function names
+var invalidToolNameChars = regexp.MustCompile(`[^a-zA-Z0-9_-]`)
+
+// maxToolNameLength is the longest function name accepted by all providers
+const maxToolNameLength = 64
+
+// ToolName converts an arbitrary name into one accepted by every provider
+func ToolName(name string) string {
+	name = invalidToolNameChars.ReplaceAllString(name, "_")
+	if len(name) > maxToolNameLength {
+		name = name[:maxToolNameLength]
+	}
+	return name
+}
+
+// FormatTools renders tool definitions in the request format of the named provider
+// OpenAI and Ollama use {"type": "function", "function": {...}}; Anthropic uses input_schema
+func FormatTools(providerName string, tools []ToolDefinition) []map[string]interface{} {
+	formatted := make([]map[string]interface{}, len(tools))
+	for i, tool := range tools {
+		params := tool.Parameters
+		if params == nil {
+			params = map[string]interface{}{"type": "object", "properties": map[string]interface{}{}}
+		}
+
+		switch providerName {
+		case "anthropic":
+			formatted[i] = map[string]interface{}{
+				"name":         ToolName(tool.Name),
+				"description":  tool.Description,
+				"input_schema": params,
+			}
+		default:
+			formatted[i] = map[string]interface{}{
+				"type": "function",
+				"function": map[string]interface{}{
+					"name":        ToolName(tool.Name),
+					"description": tool.Description,
+					"parameters":  params,
+				},
+			}
+		}
+	}
+	return formatted
+}
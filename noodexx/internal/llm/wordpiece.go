@@ -0,0 +1,202 @@
+package llm
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// maxWordChars is the longest word WordPiece will split; longer words become [UNK]
+const maxWordChars = 100
+
+// WordPiece is an uncased BERT tokenizer, as used by sentence-transformers
+// embedding models and cross-encoder rerankers
+type WordPiece struct {
+	vocab  map[string]int64
+	unkID  int64
+	clsID  int64
+	sepID  int64
+	maxLen int
+}
+
+// LoadWordPiece reads a vocab.txt file (one token per line, line number is the ID)
+func LoadWordPiece(path string, maxLen int) (*WordPiece, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open vocabulary: %w", err)
+	}
+	defer file.Close()
+
+	var tokens []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		tokens = append(tokens, strings.TrimRight(scanner.Text(), "\r"))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read vocabulary: %w", err)
+	}
+	return NewWordPiece(tokens, maxLen)
+}
+
+// NewWordPiece creates a tokenizer from a vocabulary in ID order
+// Sequences are truncated to maxLen tokens including [CLS] and [SEP]
+func NewWordPiece(tokens []string, maxLen int) (*WordPiece, error) {
+	vocab := make(map[string]int64, len(tokens))
+	for i, token := range tokens {
+		vocab[token] = int64(i)
+	}
+
+	wp := &WordPiece{vocab: vocab, maxLen: maxLen}
+	for token, id := range map[string]*int64{"[UNK]": &wp.unkID, "[CLS]": &wp.clsID, "[SEP]": &wp.sepID} {
+		v, ok := vocab[token]
+		if !ok {
+			return nil, fmt.Errorf("vocabulary is missing %s", token)
+		}
+		*id = v
+	}
+	if maxLen < 3 {
+		return nil, fmt.Errorf("max sequence length must be at least 3, got %d", maxLen)
+	}
+	return wp, nil
+}
+
+// Encoding is a tokenized model input
+type Encoding struct {
+	InputIDs      []int64
+	AttentionMask []int64
+	TypeIDs       []int64
+}
+
+// Encode tokenizes a single text as [CLS] text [SEP]
+func (wp *WordPiece) Encode(text string) Encoding {
+	ids := wp.tokenize(text)
+	if len(ids) > wp.maxLen-2 {
+		ids = ids[:wp.maxLen-2]
+	}
+
+	enc := Encoding{}
+	enc.append(wp.clsID, 0)
+	for _, id := range ids {
+		enc.append(id, 0)
+	}
+	enc.append(wp.sepID, 0)
+	return enc
+}
+
+// EncodePair tokenizes a query and document as [CLS] a [SEP] b [SEP] for a cross-encoder
+// The document is truncated first so the query is kept whole where possible
+func (wp *WordPiece) EncodePair(a, b string) Encoding {
+	idsA := wp.tokenize(a)
+	idsB := wp.tokenize(b)
+	budget := wp.maxLen - 3
+	if len(idsA) > budget {
+		idsA = idsA[:budget]
+	}
+	if len(idsA)+len(idsB) > budget {
+		idsB = idsB[:budget-len(idsA)]
+	}
+
+	enc := Encoding{}
+	enc.append(wp.clsID, 0)
+	for _, id := range idsA {
+		enc.append(id, 0)
+	}
+	enc.append(wp.sepID, 0)
+	for _, id := range idsB {
+		enc.append(id, 1)
+	}
+	enc.append(wp.sepID, 1)
+	return enc
+}
+
+func (e *Encoding) append(id, typeID int64) {
+	e.InputIDs = append(e.InputIDs, id)
+	e.AttentionMask = append(e.AttentionMask, 1)
+	e.TypeIDs = append(e.TypeIDs, typeID)
+}
+
+// tokenize splits text into words and each word into vocabulary pieces
+func (wp *WordPiece) tokenize(text string) []int64 {
+	var ids []int64
+	for _, word := range basicTokenize(text) {
+		ids = append(ids, wp.wordPieces(word)...)
+	}
+	return ids
+}
+
+// wordPieces greedily matches the longest vocabulary prefix, continuing with "##" pieces
+func (wp *WordPiece) wordPieces(word string) []int64 {
+	runes := []rune(word)
+	if len(runes) > maxWordChars {
+		return []int64{wp.unkID}
+	}
+
+	var ids []int64
+	for start := 0; start < len(runes); {
+		end := len(runes)
+		var id int64
+		found := false
+		for ; end > start; end-- {
+			piece := string(runes[start:end])
+			if start > 0 {
+				piece = "##" + piece
+			}
+			if v, ok := wp.vocab[piece]; ok {
+				id, found = v, true
+				break
+			}
+		}
+		if !found {
+			return []int64{wp.unkID}
+		}
+		ids = append(ids, id)
+		start = end
+	}
+	return ids
+}
+
+// basicTokenize lowercases, strips accents and splits on whitespace and punctuation
+// Each CJK character becomes its own word
+func basicTokenize(text string) []string {
+	var words []string
+	var current strings.Builder
+	flush := func() {
+		if current.Len() > 0 {
+			words = append(words, current.String())
+			current.Reset()
+		}
+	}
+
+	for _, r := range norm.NFD.String(strings.ToLower(text)) {
+		switch {
+		case unicode.Is(unicode.Mn, r):
+			// Combining accent left over from NFD decomposition
+		case r == 0 || r == unicode.ReplacementChar || (unicode.IsControl(r) && !unicode.IsSpace(r)):
+		case unicode.IsSpace(r):
+			flush()
+		case isPunctuation(r) || isCJK(r):
+			flush()
+			words = append(words, string(r))
+		default:
+			current.WriteRune(r)
+		}
+	}
+	flush()
+	return words
+}
+
+// isPunctuation matches BERT, which treats all non-alphanumeric ASCII as punctuation
+func isPunctuation(r rune) bool {
+	if (r >= 33 && r <= 47) || (r >= 58 && r <= 64) || (r >= 91 && r <= 96) || (r >= 123 && r <= 126) {
+		return true
+	}
+	return unicode.IsPunct(r)
+}
+
+func isCJK(r rune) bool {
+	return unicode.Is(unicode.Han, r)
+}
@@ -0,0 +1,482 @@
+// Package markdown renders chat messages, document summaries and previews.
+// Raw HTML in the source is stripped and all other text is escaped, so the
+// output can be inserted into a page whatever the source contains
+package markdown
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Block patterns, matched against one line
+var (
+	fencePattern    = regexp.MustCompile("^\\s*(`{3,}|~{3,})\\s*([^`\\s]*)")
+	headingPattern  = regexp.MustCompile(`^ {0,3}(#{1,6})(?:\s+(.*?))?(?:\s+#+)?\s*$`)
+	rulePattern     = regexp.MustCompile(`^ {0,3}(?:(?:-\s*){3,}|(?:\*\s*){3,}|(?:_\s*){3,})$`)
+	bulletPattern   = regexp.MustCompile(`^(\s*)([-*+])\s+`)
+	orderedPattern  = regexp.MustCompile(`^(\s*)(\d{1,9})[.)]\s+`)
+	tableSeparator  = regexp.MustCompile(`^\|?\s*:?-+:?\s*(\|\s*:?-+:?\s*)*\|?$`)
+	languagePattern = regexp.MustCompile(`^[a-z0-9_+#.-]{1,32}$`)
+)
+
+// Inline patterns, matched against escaped text
+var (
+	linkPattern     = regexp.MustCompile(`\[([^\[\]]+)\]\(([^()\s]+)\)`)
+	autolinkPattern = regexp.MustCompile(`&lt;(https?://[^\s]+?)&gt;`)
+	strongPattern   = regexp.MustCompile(`\*\*(\S(?:.*?\S)?)\*\*|__(\S(?:.*?\S)?)__`)
+	emPattern       = regexp.MustCompile(`\*(\S(?:[^*]*?\S)?)\*`)
+	delPattern      = regexp.MustCompile(`~~(\S(?:.*?\S)?)~~`)
+	placeholder     = regexp.MustCompile("\x00(\\d+)\x00")
+)
+
+// Raw HTML patterns. Script and style elements are dropped with their content,
+// other tags and comments on their own
+var (
+	rawElementPattern = regexp.MustCompile(`(?is)<(script|style)\b[^>]*>.*?</(script|style)\s*>`)
+	rawCommentPattern = regexp.MustCompile(`(?s)<!--.*?-->`)
+	rawTagPattern     = regexp.MustCompile(`</?[a-zA-Z][a-zA-Z0-9-]*(?:\s[^<>]*)?/?>`)
+)
+
+// Render returns src rendered as HTML. It supports headings, paragraphs,
+// fenced code blocks (annotated with a language-<name> class for syntax
+// highlighting), lists, block quotes, tables, rules, inline code, emphasis and
+// links. Links keep only http, https and mailto URLs and relative paths
+func Render(src string) string {
+	var b strings.Builder
+	renderBlocks(&b, splitLines(src), false)
+	return b.String()
+}
+
+// Preview returns src as plain text without markdown syntax or raw HTML,
+// on one line and cut at a word boundary to at most n characters plus "..."
+func Preview(src string, n int) string {
+	var words []string
+	inFence := false
+	for _, line := range splitLines(stripHTML(src)) {
+		if fencePattern.MatchString(line) {
+			inFence = !inFence
+			continue
+		}
+		if !inFence {
+			if rulePattern.MatchString(line) || tableSeparator.MatchString(strings.TrimSpace(line)) {
+				continue
+			}
+			line = plainLine(line)
+		}
+		words = append(words, strings.Fields(line)...)
+	}
+	text := strings.Join(words, " ")
+	if n <= 0 || utf8.RuneCountInString(text) <= n {
+		return text
+	}
+
+	runes := []rune(text)
+	cut := string(runes[:n])
+	if i := strings.LastIndexByte(cut, ' '); i > len(cut)/2 {
+		cut = cut[:i]
+	}
+	return strings.TrimRight(cut, " .,;:") + "..."
+}
+
+// plainLine removes the block markers and inline syntax of one line
+func plainLine(line string) string {
+	if m := headingPattern.FindStringSubmatch(line); m != nil {
+		line = m[2]
+	}
+	line = strings.TrimLeft(strings.TrimSpace(line), "> ")
+	if m := bulletPattern.FindString(line); m != "" {
+		line = line[len(m):]
+	} else if m := orderedPattern.FindString(line); m != "" {
+		line = line[len(m):]
+	}
+	if strings.Contains(line, "|") {
+		line = strings.ReplaceAll(strings.Trim(line, "| "), "|", " ")
+	}
+	line = linkPattern.ReplaceAllString(line, "$1")
+	return strings.NewReplacer("**", "", "__", "", "~~", "", "`", "", "*", "").Replace(line)
+}
+
+// splitLines splits src into lines, accepting any line ending
+func splitLines(src string) []string {
+	src = strings.ReplaceAll(src, "\r\n", "\n")
+	return strings.Split(strings.ReplaceAll(src, "\r", "\n"), "\n")
+}
+
+// renderBlocks renders lines as a sequence of blocks. In tight lists, item
+// paragraphs are written without <p> tags
+func renderBlocks(b *strings.Builder, lines []string, tight bool) {
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		switch {
+		case strings.TrimSpace(line) == "":
+			i++
+		case fencePattern.MatchString(line):
+			i = renderFence(b, lines, i)
+		case headingPattern.MatchString(line):
+			m := headingPattern.FindStringSubmatch(line)
+			fmt.Fprintf(b, "<h%d>%s</h%d>\n", len(m[1]), renderInline(m[2]), len(m[1]))
+			i++
+		case rulePattern.MatchString(line):
+			b.WriteString("<hr>\n")
+			i++
+		case strings.HasPrefix(strings.TrimSpace(line), ">"):
+			i = renderQuote(b, lines, i)
+		case isListItem(line):
+			i = renderList(b, lines, i)
+		case isTableStart(lines, i):
+			i = renderTable(b, lines, i)
+		default:
+			i = renderParagraph(b, lines, i, tight)
+		}
+	}
+}
+
+// startsBlock reports whether a line interrupts a paragraph
+func startsBlock(lines []string, i int) bool {
+	line := lines[i]
+	return fencePattern.MatchString(line) || headingPattern.MatchString(line) ||
+		rulePattern.MatchString(line) || strings.HasPrefix(strings.TrimSpace(line), ">") ||
+		isListItem(line) || isTableStart(lines, i)
+}
+
+// renderFence renders a fenced code block. A block that is never closed runs
+// to the end, as it does while an answer is still streaming
+func renderFence(b *strings.Builder, lines []string, i int) int {
+	m := fencePattern.FindStringSubmatch(lines[i])
+	fence, language := m[1], strings.ToLower(m[2])
+
+	var code []string
+	j := i + 1
+	for ; j < len(lines); j++ {
+		t := strings.TrimSpace(lines[j])
+		if len(t) >= len(fence) && strings.Trim(t, fence[:1]) == "" {
+			j++
+			break
+		}
+		code = append(code, lines[j])
+	}
+
+	b.WriteString("<pre><code")
+	if languagePattern.MatchString(language) {
+		fmt.Fprintf(b, ` class="language-%s"`, html.EscapeString(language))
+	}
+	b.WriteString(">")
+	b.WriteString(html.EscapeString(strings.Join(code, "\n")))
+	b.WriteString("</code></pre>\n")
+	return j
+}
+
+// renderQuote renders consecutive lines starting with > as a block quote
+func renderQuote(b *strings.Builder, lines []string, i int) int {
+	var inner []string
+	for ; i < len(lines); i++ {
+		t := strings.TrimSpace(lines[i])
+		if !strings.HasPrefix(t, ">") {
+			break
+		}
+		t = strings.TrimPrefix(t, ">")
+		inner = append(inner, strings.TrimPrefix(t, " "))
+	}
+	b.WriteString("<blockquote>\n")
+	renderBlocks(b, inner, false)
+	b.WriteString("</blockquote>\n")
+	return i
+}
+
+// listMarker is the marker starting a list item
+type listMarker struct {
+	indent  int  // Spaces before the marker
+	width   int  // Length of the marker and the spaces after it
+	ordered bool // Numbered rather than bulleted
+	start   int  // Number of an ordered item
+}
+
+// parseListMarker returns the marker starting line, if it starts a list item
+func parseListMarker(line string) (listMarker, bool) {
+	if m := bulletPattern.FindStringSubmatch(line); m != nil && !rulePattern.MatchString(line) {
+		return listMarker{indent: len(m[1]), width: len(m[0]) - len(m[1])}, true
+	}
+	if m := orderedPattern.FindStringSubmatch(line); m != nil {
+		start, _ := strconv.Atoi(m[2])
+		return listMarker{indent: len(m[1]), width: len(m[0]) - len(m[1]), ordered: true, start: start}, true
+	}
+	return listMarker{}, false
+}
+
+// isListItem reports whether line starts a list item
+func isListItem(line string) bool {
+	_, ok := parseListMarker(line)
+	return ok
+}
+
+// indentOf returns the number of leading spaces of line
+func indentOf(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " \t"))
+}
+
+// renderList renders a list and the lists nested in its items. An item holds
+// the lines indented under it, and plain lines that follow it directly
+func renderList(b *strings.Builder, lines []string, i int) int {
+	first, _ := parseListMarker(lines[i])
+	switch {
+	case !first.ordered:
+		b.WriteString("<ul>\n")
+	case first.start != 1:
+		fmt.Fprintf(b, "<ol start=\"%d\">\n", first.start)
+	default:
+		b.WriteString("<ol>\n")
+	}
+
+	for i < len(lines) {
+		marker, ok := parseListMarker(lines[i])
+		if !ok || marker.ordered != first.ordered || marker.indent > first.indent+1 {
+			break
+		}
+		contentIndent := marker.indent + marker.width
+		item := []string{lines[i][contentIndent:]}
+		tight := true
+		i++
+		for i < len(lines) {
+			line := lines[i]
+			if strings.TrimSpace(line) == "" {
+				// A blank line continues the item only if indented lines follow
+				next := i + 1
+				for next < len(lines) && strings.TrimSpace(lines[next]) == "" {
+					next++
+				}
+				if next == len(lines) || indentOf(lines[next]) < contentIndent {
+					break
+				}
+				item = append(item, "")
+				tight = false
+				i++
+				continue
+			}
+			indent := indentOf(line)
+			if indent >= contentIndent {
+				item = append(item, line[contentIndent:])
+			} else if indent > first.indent+1 && isListItem(line) {
+				item = append(item, strings.TrimLeft(line, " \t"))
+			} else if !startsBlock(lines, i) {
+				item = append(item, strings.TrimSpace(line))
+			} else {
+				break
+			}
+			i++
+		}
+
+		b.WriteString("<li>")
+		var content strings.Builder
+		renderBlocks(&content, item, tight)
+		b.WriteString(strings.TrimSuffix(content.String(), "\n"))
+		b.WriteString("</li>\n")
+
+		// Blank lines between items of the same list keep it going
+		next := i
+		for next < len(lines) && strings.TrimSpace(lines[next]) == "" {
+			next++
+		}
+		if next < len(lines) {
+			if m, ok := parseListMarker(lines[next]); ok && m.ordered == first.ordered && m.indent <= first.indent+1 {
+				i = next
+			}
+		}
+	}
+
+	if first.ordered {
+		b.WriteString("</ol>\n")
+	} else {
+		b.WriteString("</ul>\n")
+	}
+	return i
+}
+
+// isTableStart reports whether a table, a header row and a separator, starts at line i
+func isTableStart(lines []string, i int) bool {
+	return i+1 < len(lines) && strings.Contains(lines[i], "|") &&
+		strings.Contains(lines[i+1], "-") && tableSeparator.MatchString(strings.TrimSpace(lines[i+1]))
+}
+
+// tableCells splits a table row into cells
+func tableCells(row string) []string {
+	row = strings.TrimSpace(row)
+	row = strings.TrimSuffix(strings.TrimPrefix(row, "|"), "|")
+	cells := strings.Split(row, "|")
+	for i := range cells {
+		cells[i] = strings.TrimSpace(cells[i])
+	}
+	return cells
+}
+
+// renderTable renders a table, padding or cutting its rows to the header's cells
+func renderTable(b *strings.Builder, lines []string, i int) int {
+	header := tableCells(lines[i])
+	var aligns []string
+	for _, cell := range tableCells(lines[i+1]) {
+		switch {
+		case strings.HasPrefix(cell, ":") && strings.HasSuffix(cell, ":"):
+			aligns = append(aligns, "center")
+		case strings.HasSuffix(cell, ":"):
+			aligns = append(aligns, "right")
+		case strings.HasPrefix(cell, ":"):
+			aligns = append(aligns, "left")
+		default:
+			aligns = append(aligns, "")
+		}
+	}
+	row := func(tag string, cells []string) {
+		b.WriteString("<tr>")
+		for c := range header {
+			text := ""
+			if c < len(cells) {
+				text = cells[c]
+			}
+			if c < len(aligns) && aligns[c] != "" {
+				fmt.Fprintf(b, `<%s style="text-align: %s">%s</%s>`, tag, aligns[c], renderInline(text), tag)
+			} else {
+				fmt.Fprintf(b, "<%s>%s</%s>", tag, renderInline(text), tag)
+			}
+		}
+		b.WriteString("</tr>\n")
+	}
+
+	b.WriteString("<table>\n<thead>\n")
+	row("th", header)
+	b.WriteString("</thead>\n<tbody>\n")
+	for i += 2; i < len(lines) && strings.TrimSpace(lines[i]) != "" && strings.Contains(lines[i], "|"); i++ {
+		row("td", tableCells(lines[i]))
+	}
+	b.WriteString("</tbody>\n</table>\n")
+	return i
+}
+
+// renderParagraph renders lines up to the next blank line or block as one
+// paragraph, keeping its line breaks
+func renderParagraph(b *strings.Builder, lines []string, i int, tight bool) int {
+	var text []string
+	for j := i; j < len(lines); j++ {
+		if strings.TrimSpace(lines[j]) == "" || (j > i && startsBlock(lines, j)) {
+			break
+		}
+		text = append(text, strings.TrimSpace(lines[j]))
+	}
+	content := strings.ReplaceAll(renderInline(strings.Join(text, "\n")), "\n", "<br>\n")
+	if tight {
+		b.WriteString(content + "\n")
+	} else {
+		b.WriteString("<p>" + content + "</p>\n")
+	}
+	return i + len(text)
+}
+
+// renderInline renders code spans, links and emphasis. Text outside code
+// spans has its raw HTML stripped and is escaped before any tags are added
+func renderInline(s string) string {
+	var b strings.Builder
+	for s != "" {
+		open := strings.IndexByte(s, '`')
+		if open < 0 {
+			b.WriteString(formatText(s))
+			break
+		}
+		n := backtickRun(s[open:])
+		end := closingBackticks(s[open+n:], n)
+		if end < 0 {
+			b.WriteString(formatText(s[:open+n]))
+			s = s[open+n:]
+			continue
+		}
+		b.WriteString(formatText(s[:open]))
+		code := s[open+n : open+n+end]
+		b.WriteString("<code>" + html.EscapeString(strings.TrimSpace(code)) + "</code>")
+		s = s[open+n+end+n:]
+	}
+	return b.String()
+}
+
+// backtickRun returns the number of backticks s starts with
+func backtickRun(s string) int {
+	n := 0
+	for n < len(s) && s[n] == '`' {
+		n++
+	}
+	return n
+}
+
+// closingBackticks returns the offset in s of a run of exactly n backticks, or -1
+func closingBackticks(s string, n int) int {
+	for i := 0; i < len(s); {
+		if s[i] != '`' {
+			i++
+			continue
+		}
+		run := backtickRun(s[i:])
+		if run == n {
+			return i
+		}
+		i += run
+	}
+	return -1
+}
+
+// formatText renders text outside code spans
+func formatText(s string) string {
+	// NUL bytes would be taken for link placeholders
+	s = html.EscapeString(stripHTML(strings.ReplaceAll(s, "\x00", "")))
+
+	// Links become placeholders so emphasis is not applied inside their URLs
+	var links []string
+	hold := func(link string) string {
+		links = append(links, link)
+		return fmt.Sprintf("\x00%d\x00", len(links)-1)
+	}
+	s = autolinkPattern.ReplaceAllStringFunc(s, func(m string) string {
+		url := autolinkPattern.FindStringSubmatch(m)[1]
+		return hold(anchor(url, url))
+	})
+	s = linkPattern.ReplaceAllStringFunc(s, func(m string) string {
+		parts := linkPattern.FindStringSubmatch(m)
+		if !safeURL(html.UnescapeString(parts[2])) {
+			return parts[1]
+		}
+		return hold(anchor(parts[2], parts[1]))
+	})
+
+	s = strongPattern.ReplaceAllString(s, "<strong>$1$2</strong>")
+	s = emPattern.ReplaceAllString(s, "<em>$1</em>")
+	s = delPattern.ReplaceAllString(s, "<del>$1</del>")
+
+	return placeholder.ReplaceAllStringFunc(s, func(m string) string {
+		n, _ := strconv.Atoi(placeholder.FindStringSubmatch(m)[1])
+		return links[n]
+	})
+}
+
+// anchor returns a link opening in a new tab; href and text are escaped already
+func anchor(href, text string) string {
+	return fmt.Sprintf(`<a href="%s" target="_blank" rel="noopener noreferrer">%s</a>`, href, text)
+}
+
+// safeURL reports whether a link URL is http, https, mailto or relative
+func safeURL(url string) bool {
+	i := strings.IndexAny(url, ":/?#")
+	if i < 0 || url[i] != ':' {
+		return true
+	}
+	switch strings.ToLower(url[:i]) {
+	case "http", "https", "mailto":
+		return true
+	}
+	return false
+}
+
+// stripHTML removes raw HTML tags, comments, and script and style elements
+func stripHTML(s string) string {
+	s = rawElementPattern.ReplaceAllString(s, "")
+	s = rawCommentPattern.ReplaceAllString(s, "")
+	return rawTagPattern.ReplaceAllString(s, "")
+}
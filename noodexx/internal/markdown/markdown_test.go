@@ -0,0 +1,75 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"paragraph", "Hello **there** and *you*\nnext ~~line~~", "<p>Hello <strong>there</strong> and <em>you</em><br>\nnext <del>line</del></p>\n"},
+		{"heading", "## Setup ##", "<h2>Setup</h2>\n"},
+		{"code span", "Run `go test <pkg>` now", "<p>Run <code>go test &lt;pkg&gt;</code> now</p>\n"},
+		{"fence", "```Go\nif a < b {}\n```\nafter", "<pre><code class=\"language-go\">if a &lt; b {}</code></pre>\n<p>after</p>\n"},
+		{"unclosed fence", "```\npartial", "<pre><code>partial</code></pre>\n"},
+		{"odd language", "```\"onmouseover=x\nx\n```", "<pre><code>x</code></pre>\n"},
+		{"list", "- a\n- b\n  - c\n\n1. one\n2. two", "<ul>\n<li>a</li>\n<li>b\n<ul>\n<li>c</li>\n</ul></li>\n</ul>\n<ol>\n<li>one</li>\n<li>two</li>\n</ol>\n"},
+		{"ordered start", "3. three", "<ol start=\"3\">\n<li>three</li>\n</ol>\n"},
+		{"quote", "> quoted\n> text", "<blockquote>\n<p>quoted<br>\ntext</p>\n</blockquote>\n"},
+		{"rule", "a\n\n---\n\nb", "<p>a</p>\n<hr>\n<p>b</p>\n"},
+		{"table", "| A | B |\n|---|--:|\n| 1 |", "<table>\n<thead>\n<tr><th>A</th><th style=\"text-align: right\">B</th></tr>\n</thead>\n<tbody>\n<tr><td>1</td><td style=\"text-align: right\"></td></tr>\n</tbody>\n</table>\n"},
+		{"link", "[docs](https://example.com/a_*b*)", "<p><a href=\"https://example.com/a_*b*\" target=\"_blank\" rel=\"noopener noreferrer\">docs</a></p>\n"},
+		{"autolink", "<https://example.com>", "<p><a href=\"https://example.com\" target=\"_blank\" rel=\"noopener noreferrer\">https://example.com</a></p>\n"},
+	}
+	for _, tt := range tests {
+		if got := Render(tt.src); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRenderStripsHTML(t *testing.T) {
+	for _, src := range []string{
+		`<script>alert(1)</script>hi`,
+		`<img src=x onerror=alert(1)>hi`,
+		`<a href="javascript:alert(1)">hi</a>`,
+		`[hi](javascript:alert)`,
+		`[hi](JavaScript:alert)`,
+		`[hi](data:text/html;base64,PHNjcmlwdD4=)`,
+		`<!-- <script> -->hi`,
+		"- <iframe src=x></iframe>hi",
+		"| <b onclick=x>h</b> |\n|---|\n| hi |",
+		"\"><svg/onload=alert(1)>hi",
+	} {
+		got := Render(src)
+		for _, bad := range []string{"<script", "<img", "<iframe", "<svg", "<b ", "<a href=\"javascript", "<a href=\"data"} {
+			if strings.Contains(strings.ToLower(got), bad) {
+				t.Errorf("%q: rendered %q, which contains %q", src, got, bad)
+			}
+		}
+		if !strings.Contains(got, "hi") {
+			t.Errorf("%q: expected the text kept, got %q", src, got)
+		}
+	}
+
+	if got := Render("a \x000\x00 b"); got != "<p>a 0 b</p>\n" {
+		t.Errorf("Expected NUL bytes dropped, got %q", got)
+	}
+}
+
+func TestPreview(t *testing.T) {
+	src := "# Summary\n\nThe **lease** ends in [May](https://example.com) <b>2026</b>.\n\n- Rent is due monthly"
+	if got := Preview(src, 0); got != "Summary The lease ends in May 2026. Rent is due monthly" {
+		t.Errorf("Unexpected preview %q", got)
+	}
+	if got := Preview(src, 30); got != "Summary The lease ends in May..." {
+		t.Errorf("Expected a cut at a word boundary, got %q", got)
+	}
+	if got := Preview("ééééé", 3); got != "ééé..." {
+		t.Errorf("Expected a cut between characters, got %q", got)
+	}
+}
@@ -0,0 +1,589 @@
+// Package metaquery answers aggregate questions about library metadata, such as
+// how many documents tagged "contracts" were added each month. A query names an
+// entity, filters its rows, groups them by fields or time buckets and
+// aggregates each group. Queries only refer to the fields described here, so no
+// SQL is ever built from user input: the rows are loaded with fixed queries and
+// evaluated in memory.
+package metaquery
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Entities
+const (
+	Documents = "documents" // One row per document
+	Chunks    = "chunks"    // One row per chunk
+	Sessions  = "sessions"  // One row per chat session
+)
+
+// FieldType is the type of an entity field, which decides the filters,
+// groupings and aggregates it supports
+type FieldType string
+
+// Field types
+const (
+	String FieldType = "string"
+	Number FieldType = "number"
+	Time   FieldType = "time"
+	Tags   FieldType = "tags" // A list of tags; a row belongs to a group per tag
+)
+
+// Fields describes the fields of each entity
+var Fields = map[string]map[string]FieldType{
+	Documents: {
+		"source":     String,
+		"type":       String, // File extension, e.g. "pdf"
+		"tags":       Tags,
+		"visibility": String,
+		"chunks":     Number,
+		"size":       Number, // Characters of text
+		"created_at": Time,
+	},
+	Chunks: {
+		"source":     String,
+		"type":       String,
+		"tags":       Tags,
+		"visibility": String,
+		"length":     Number,
+		"created_at": Time,
+	},
+	Sessions: {
+		"title":           String,
+		"messages":        Number,
+		"created_at":      Time,
+		"last_message_at": Time,
+	},
+}
+
+// Row is one entity's field values: strings, float64 numbers, time.Time
+// timestamps and []string tags. A missing field is treated as empty
+type Row map[string]interface{}
+
+// Filter keeps the rows whose field compares to the value
+type Filter struct {
+	Field string      `json:"field"`
+	Op    string      `json:"op"` // eq, ne, contains, in, gt, gte, lt, lte
+	Value interface{} `json:"value"`
+}
+
+// Aggregate summarizes each group: count, or sum, avg, min or max of a number field
+type Aggregate struct {
+	Fn    string `json:"fn"`
+	Field string `json:"field,omitempty"`
+}
+
+// Column is the aggregate's name in results, e.g. "count" or "sum_chunks"
+func (a Aggregate) Column() string {
+	if a.Fn == "count" {
+		return "count"
+	}
+	return a.Fn + "_" + a.Field
+}
+
+// Query limits
+const (
+	DefaultLimit = 1000
+	MaxLimit     = 10000
+)
+
+// Query is a metadata question. Group keys are field names, or a time field
+// with a bucket, e.g. "created_at:month" (day, week, month or year). Without
+// aggregates each group is counted
+type Query struct {
+	Entity     string      `json:"entity"`
+	Filters    []Filter    `json:"filters,omitempty"`
+	GroupBy    []string    `json:"group_by,omitempty"`
+	Aggregates []Aggregate `json:"aggregates,omitempty"`
+	OrderBy    string      `json:"order_by,omitempty"` // A result column; defaults to the group keys
+	Desc       bool        `json:"desc,omitempty"`
+	Limit      int         `json:"limit,omitempty"`
+}
+
+// Result is a query's table. Total is the number of groups before the limit
+type Result struct {
+	Columns []string        `json:"columns"`
+	Rows    [][]interface{} `json:"rows"`
+	Total   int             `json:"total"`
+}
+
+// Preset is a ready-made query
+type Preset struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Query       Query  `json:"query"`
+}
+
+// Presets are common questions; a request can add filters to them
+var Presets = []Preset{
+	{
+		Name:        "documents_per_month",
+		Description: "Documents added each month",
+		Query:       Query{Entity: Documents, GroupBy: []string{"created_at:month"}},
+	},
+	{
+		Name:        "documents_per_tag",
+		Description: "Documents and chunks per tag, most used first",
+		Query: Query{Entity: Documents, GroupBy: []string{"tags"},
+			Aggregates: []Aggregate{{Fn: "count"}, {Fn: "sum", Field: "chunks"}}, OrderBy: "count", Desc: true},
+	},
+	{
+		Name:        "documents_per_type",
+		Description: "Documents, chunks and characters per file type",
+		Query: Query{Entity: Documents, GroupBy: []string{"type"},
+			Aggregates: []Aggregate{{Fn: "count"}, {Fn: "sum", Field: "chunks"}, {Fn: "sum", Field: "size"}}, OrderBy: "count", Desc: true},
+	},
+	{
+		Name:        "largest_documents",
+		Description: "The 20 documents with the most chunks",
+		Query: Query{Entity: Documents, GroupBy: []string{"source"},
+			Aggregates: []Aggregate{{Fn: "sum", Field: "chunks"}, {Fn: "sum", Field: "size"}}, OrderBy: "sum_chunks", Desc: true, Limit: 20},
+	},
+	{
+		Name:        "sessions_per_month",
+		Description: "Chat sessions started and messages sent each month",
+		Query: Query{Entity: Sessions, GroupBy: []string{"created_at:month"},
+			Aggregates: []Aggregate{{Fn: "count"}, {Fn: "sum", Field: "messages"}}},
+	},
+}
+
+// FindPreset returns a copy of the named preset's query
+func FindPreset(name string) (Query, bool) {
+	for _, p := range Presets {
+		if p.Name == name {
+			q := p.Query
+			q.Filters = append([]Filter(nil), q.Filters...)
+			return q, true
+		}
+	}
+	return Query{}, false
+}
+
+// timeBuckets formats a timestamp as its bucket
+var timeBuckets = map[string]func(time.Time) string{
+	"day":   func(t time.Time) string { return t.Format("2006-01-02") },
+	"month": func(t time.Time) string { return t.Format("2006-01") },
+	"year":  func(t time.Time) string { return t.Format("2006") },
+	"week": func(t time.Time) string {
+		year, week := t.ISOWeek()
+		return fmt.Sprintf("%d-W%02d", year, week)
+	},
+}
+
+// opsByType lists the filter operators of each field type
+var opsByType = map[FieldType][]string{
+	String: {"eq", "ne", "contains", "in"},
+	Number: {"eq", "ne", "gt", "gte", "lt", "lte"},
+	Time:   {"gt", "gte", "lt", "lte"},
+	Tags:   {"eq", "ne", "in"}, // Has the tag, lacks it, or has any of them
+}
+
+// compiled is a validated query ready to run
+type compiled struct {
+	query   Query
+	filters []func(Row) bool
+	keys    []func(Row) []interface{} // Each group key's values for a row
+	columns []string
+}
+
+// Validate checks a query and fills in its defaults
+func (q *Query) Validate() error {
+	_, err := q.compile()
+	return err
+}
+
+func (q *Query) compile() (*compiled, error) {
+	fields, ok := Fields[q.Entity]
+	if !ok {
+		return nil, fmt.Errorf("unknown entity %q (must be documents, chunks or sessions)", q.Entity)
+	}
+	if q.Limit == 0 {
+		q.Limit = DefaultLimit
+	}
+	if q.Limit < 0 || q.Limit > MaxLimit {
+		return nil, fmt.Errorf("limit must be between 1 and %d", MaxLimit)
+	}
+	if len(q.Aggregates) == 0 {
+		q.Aggregates = []Aggregate{{Fn: "count"}}
+	}
+
+	c := &compiled{query: *q}
+	for _, f := range q.Filters {
+		match, err := compileFilter(fields, f)
+		if err != nil {
+			return nil, err
+		}
+		c.filters = append(c.filters, match)
+	}
+
+	seen := make(map[string]bool)
+	for _, key := range q.GroupBy {
+		fn, err := compileKey(fields, key)
+		if err != nil {
+			return nil, err
+		}
+		if seen[key] {
+			return nil, fmt.Errorf("duplicate group key %q", key)
+		}
+		seen[key] = true
+		c.keys = append(c.keys, fn)
+		c.columns = append(c.columns, key)
+	}
+	for _, a := range q.Aggregates {
+		switch a.Fn {
+		case "count":
+			if a.Field != "" {
+				return nil, fmt.Errorf("count takes no field")
+			}
+		case "sum", "avg", "min", "max":
+			if fields[a.Field] != Number {
+				return nil, fmt.Errorf("%s needs a number field of %s, got %q", a.Fn, q.Entity, a.Field)
+			}
+		default:
+			return nil, fmt.Errorf("unknown aggregate %q (must be count, sum, avg, min or max)", a.Fn)
+		}
+		if seen[a.Column()] {
+			return nil, fmt.Errorf("duplicate column %q", a.Column())
+		}
+		seen[a.Column()] = true
+		c.columns = append(c.columns, a.Column())
+	}
+	if q.OrderBy != "" && !seen[q.OrderBy] {
+		return nil, fmt.Errorf("order_by must be one of the result columns: %s", strings.Join(c.columns, ", "))
+	}
+	return c, nil
+}
+
+// compileFilter checks a filter and returns its row predicate
+func compileFilter(fields map[string]FieldType, f Filter) (func(Row) bool, error) {
+	typ, ok := fields[f.Field]
+	if !ok {
+		return nil, fmt.Errorf("unknown filter field %q", f.Field)
+	}
+	valid := false
+	for _, op := range opsByType[typ] {
+		valid = valid || op == f.Op
+	}
+	if !valid {
+		return nil, fmt.Errorf("filter on %s field %q must use one of: %s", typ, f.Field, strings.Join(opsByType[typ], ", "))
+	}
+
+	if f.Op == "in" {
+		list, ok := f.Value.([]interface{})
+		if !ok || len(list) == 0 {
+			return nil, fmt.Errorf("filter %q in needs a list of strings", f.Field)
+		}
+		values := make(map[string]bool, len(list))
+		for _, v := range list {
+			s, ok := v.(string)
+			if !ok {
+				return nil, fmt.Errorf("filter %q in needs a list of strings", f.Field)
+			}
+			values[strings.ToLower(s)] = true
+		}
+		return func(row Row) bool {
+			for _, s := range stringsOf(row[f.Field]) {
+				if values[strings.ToLower(s)] {
+					return true
+				}
+			}
+			return false
+		}, nil
+	}
+
+	switch typ {
+	case String, Tags:
+		want, ok := f.Value.(string)
+		if !ok {
+			return nil, fmt.Errorf("filter %q needs a string value", f.Field)
+		}
+		want = strings.ToLower(want)
+		return func(row Row) bool {
+			values := stringsOf(row[f.Field])
+			switch f.Op {
+			case "contains":
+				for _, s := range values {
+					if strings.Contains(strings.ToLower(s), want) {
+						return true
+					}
+				}
+				return false
+			default:
+				has := false
+				for _, s := range values {
+					has = has || strings.ToLower(s) == want
+				}
+				return has == (f.Op == "eq")
+			}
+		}, nil
+
+	case Number:
+		want, ok := f.Value.(float64)
+		if !ok {
+			return nil, fmt.Errorf("filter %q needs a number value", f.Field)
+		}
+		return func(row Row) bool {
+			n, _ := row[f.Field].(float64)
+			return compare(f.Op, cmpFloat(n, want))
+		}, nil
+
+	default: // Time
+		s, ok := f.Value.(string)
+		if !ok {
+			return nil, fmt.Errorf("filter %q needs a date, e.g. 2024-01-31", f.Field)
+		}
+		want, err := parseTime(s)
+		if err != nil {
+			return nil, fmt.Errorf("filter %q needs a date, e.g. 2024-01-31: %w", f.Field, err)
+		}
+		return func(row Row) bool {
+			t, ok := row[f.Field].(time.Time)
+			return ok && !t.IsZero() && compare(f.Op, t.Compare(want))
+		}, nil
+	}
+}
+
+// compileKey checks a group key and returns the function giving a row's values for it
+func compileKey(fields map[string]FieldType, key string) (func(Row) []interface{}, error) {
+	field, bucket, bucketed := strings.Cut(key, ":")
+	typ, ok := fields[field]
+	if !ok {
+		return nil, fmt.Errorf("unknown group key %q", key)
+	}
+
+	switch typ {
+	case Time:
+		format, ok := timeBuckets[bucket]
+		if !ok {
+			return nil, fmt.Errorf("group key %q needs a bucket: %s:day, %s:week, %s:month or %s:year", key, field, field, field, field)
+		}
+		return func(row Row) []interface{} {
+			t, ok := row[field].(time.Time)
+			if !ok || t.IsZero() {
+				return []interface{}{nil}
+			}
+			return []interface{}{format(t)}
+		}, nil
+	case Number:
+		return nil, fmt.Errorf("cannot group by number field %q", field)
+	}
+	if bucketed {
+		return nil, fmt.Errorf("only time fields take a bucket, got %q", key)
+	}
+
+	return func(row Row) []interface{} {
+		values := stringsOf(row[field])
+		if len(values) == 0 {
+			return []interface{}{nil}
+		}
+		keys := make([]interface{}, len(values))
+		for i, v := range values {
+			keys[i] = v
+		}
+		return keys
+	}, nil
+}
+
+// Run evaluates a query over an entity's rows
+func Run(q Query, rows []Row) (*Result, error) {
+	c, err := q.compile()
+	if err != nil {
+		return nil, err
+	}
+
+	type group struct {
+		key    []interface{}
+		count  int
+		sums   map[string]float64
+		counts map[string]int
+		mins   map[string]float64
+		maxs   map[string]float64
+	}
+	groups := make(map[string]*group)
+	var order []*group
+
+	for _, row := range rows {
+		kept := true
+		for _, match := range c.filters {
+			kept = kept && match(row)
+		}
+		if !kept {
+			continue
+		}
+
+		for _, key := range combinations(c.keys, row) {
+			id := fmt.Sprintf("%#v", key)
+			g, ok := groups[id]
+			if !ok {
+				g = &group{key: key, sums: map[string]float64{}, counts: map[string]int{}, mins: map[string]float64{}, maxs: map[string]float64{}}
+				groups[id] = g
+				order = append(order, g)
+			}
+			g.count++
+			for _, a := range c.query.Aggregates {
+				n, ok := row[a.Field].(float64)
+				if a.Fn == "count" || !ok {
+					continue
+				}
+				g.sums[a.Field] += n
+				if g.counts[a.Field] == 0 || n < g.mins[a.Field] {
+					g.mins[a.Field] = n
+				}
+				if g.counts[a.Field] == 0 || n > g.maxs[a.Field] {
+					g.maxs[a.Field] = n
+				}
+				g.counts[a.Field]++
+			}
+		}
+	}
+
+	// Without group keys the whole selection is one row, even when empty
+	if len(c.keys) == 0 && len(order) == 0 {
+		order = append(order, &group{sums: map[string]float64{}, counts: map[string]int{}})
+	}
+
+	result := &Result{Columns: c.columns, Rows: make([][]interface{}, 0, len(order)), Total: len(order)}
+	for _, g := range order {
+		values := append([]interface{}{}, g.key...)
+		for _, a := range c.query.Aggregates {
+			switch {
+			case a.Fn == "count":
+				values = append(values, g.count)
+			case g.counts[a.Field] == 0:
+				values = append(values, nil)
+			case a.Fn == "sum":
+				values = append(values, g.sums[a.Field])
+			case a.Fn == "avg":
+				values = append(values, g.sums[a.Field]/float64(g.counts[a.Field]))
+			case a.Fn == "min":
+				values = append(values, g.mins[a.Field])
+			case a.Fn == "max":
+				values = append(values, g.maxs[a.Field])
+			}
+		}
+		result.Rows = append(result.Rows, values)
+	}
+
+	sortRows(result, c)
+	if len(result.Rows) > c.query.Limit {
+		result.Rows = result.Rows[:c.query.Limit]
+	}
+	return result, nil
+}
+
+// sortRows orders the result by the order_by column, then by the group keys
+func sortRows(result *Result, c *compiled) {
+	var order []int
+	if c.query.OrderBy != "" {
+		for i, column := range result.Columns {
+			if column == c.query.OrderBy {
+				order = append(order, i)
+			}
+		}
+	}
+	for i := range c.keys {
+		order = append(order, i)
+	}
+
+	sort.SliceStable(result.Rows, func(a, b int) bool {
+		for n, i := range order {
+			cmp := cmpValues(result.Rows[a][i], result.Rows[b][i])
+			if cmp == 0 {
+				continue
+			}
+			if n == 0 && c.query.OrderBy != "" && c.query.Desc {
+				return cmp > 0
+			}
+			return cmp < 0
+		}
+		return false
+	})
+}
+
+// combinations returns the group keys of a row: one per value of each key, so
+// a document with two tags belongs to two tag groups
+func combinations(keys []func(Row) []interface{}, row Row) [][]interface{} {
+	combos := [][]interface{}{{}}
+	for _, key := range keys {
+		var next [][]interface{}
+		for _, combo := range combos {
+			for _, value := range key(row) {
+				next = append(next, append(append([]interface{}{}, combo...), value))
+			}
+		}
+		combos = next
+	}
+	return combos
+}
+
+// stringsOf returns a string or tags field's values
+func stringsOf(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		if v == "" {
+			return nil
+		}
+		return []string{v}
+	case []string:
+		return v
+	}
+	return nil
+}
+
+// parseTime reads a filter date or timestamp
+func parseTime(s string) (time.Time, error) {
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+func compare(op string, cmp int) bool {
+	switch op {
+	case "eq":
+		return cmp == 0
+	case "ne":
+		return cmp != 0
+	case "gt":
+		return cmp > 0
+	case "gte":
+		return cmp >= 0
+	case "lt":
+		return cmp < 0
+	case "lte":
+		return cmp <= 0
+	}
+	return false
+}
+
+func cmpFloat(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// cmpValues orders result values: empty first, then numbers or text
+func cmpValues(a, b interface{}) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1
+	case b == nil:
+		return 1
+	}
+	switch x := a.(type) {
+	case int:
+		return cmpFloat(float64(x), float64(b.(int)))
+	case float64:
+		return cmpFloat(x, b.(float64))
+	}
+	return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
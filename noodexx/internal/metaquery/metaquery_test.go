@@ -0,0 +1,105 @@
+package metaquery
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func date(s string) time.Time {
+	t, _ := time.Parse("2006-01-02", s)
+	return t
+}
+
+var testDocuments = []Row{
+	{"source": "nda.pdf", "type": "pdf", "tags": []string{"contracts", "legal"}, "chunks": 4.0, "created_at": date("2024-01-05")},
+	{"source": "lease.pdf", "type": "pdf", "tags": []string{"Contracts"}, "chunks": 10.0, "created_at": date("2024-01-20")},
+	{"source": "msa.docx", "type": "docx", "tags": []string{"contracts"}, "chunks": 6.0, "created_at": date("2024-03-02")},
+	{"source": "notes.md", "type": "md", "chunks": 1.0, "created_at": date("2024-03-09")},
+}
+
+func TestRunGroupsByMonth(t *testing.T) {
+	result, err := Run(Query{
+		Entity:     Documents,
+		Filters:    []Filter{{Field: "tags", Op: "eq", Value: "contracts"}},
+		GroupBy:    []string{"created_at:month"},
+		Aggregates: []Aggregate{{Fn: "count"}, {Fn: "sum", Field: "chunks"}},
+	}, testDocuments)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if got := fmt.Sprint(result.Columns, result.Rows); got != "[created_at:month count sum_chunks] [[2024-01 2 14] [2024-03 1 6]]" {
+		t.Errorf("Unexpected result: %s", got)
+	}
+}
+
+func TestRunGroupsByTagAndOrders(t *testing.T) {
+	result, err := Run(Query{Entity: Documents, GroupBy: []string{"tags"}, OrderBy: "count", Desc: true, Limit: 2}, testDocuments)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	// Each tag is its own group, untagged documents are grouped under null
+	if got := fmt.Sprint(result.Rows); got != "[[contracts 2] [<nil> 1]]" || result.Total != 4 {
+		t.Errorf("Expected the two largest of four groups, got %s (total %d)", got, result.Total)
+	}
+}
+
+func TestRunWithoutGroups(t *testing.T) {
+	result, err := Run(Query{
+		Entity: Documents,
+		Filters: []Filter{
+			{Field: "type", Op: "in", Value: []interface{}{"PDF", "docx"}},
+			{Field: "created_at", Op: "gte", Value: "2024-01-10"},
+			{Field: "chunks", Op: "lt", Value: 10.0},
+		},
+		Aggregates: []Aggregate{{Fn: "count"}, {Fn: "avg", Field: "chunks"}, {Fn: "max", Field: "chunks"}},
+	}, testDocuments)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if got := fmt.Sprint(result.Rows); got != "[[1 6 6]]" {
+		t.Errorf("Expected msa.docx alone, got %s", got)
+	}
+
+	result, _ = Run(Query{Entity: Documents, Filters: []Filter{{Field: "source", Op: "contains", Value: "zzz"}},
+		Aggregates: []Aggregate{{Fn: "count"}, {Fn: "sum", Field: "chunks"}}}, testDocuments)
+	if got := fmt.Sprint(result.Rows); got != "[[0 <nil>]]" {
+		t.Errorf("Expected an empty total, got %s", got)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	for _, tc := range []struct {
+		query Query
+		want  string
+	}{
+		{Query{Entity: "users"}, "unknown entity"},
+		{Query{Entity: Documents, Filters: []Filter{{Field: "owner", Op: "eq", Value: "x"}}}, "unknown filter field"},
+		{Query{Entity: Documents, Filters: []Filter{{Field: "chunks", Op: "contains", Value: 1.0}}}, "must use one of"},
+		{Query{Entity: Documents, Filters: []Filter{{Field: "chunks", Op: "gt", Value: "1"}}}, "needs a number"},
+		{Query{Entity: Documents, Filters: []Filter{{Field: "created_at", Op: "gte", Value: "last week"}}}, "needs a date"},
+		{Query{Entity: Documents, GroupBy: []string{"created_at"}}, "needs a bucket"},
+		{Query{Entity: Documents, GroupBy: []string{"chunks"}}, "cannot group"},
+		{Query{Entity: Documents, GroupBy: []string{"source:month"}}, "only time fields"},
+		{Query{Entity: Documents, Aggregates: []Aggregate{{Fn: "sum", Field: "source"}}}, "needs a number field"},
+		{Query{Entity: Documents, Aggregates: []Aggregate{{Fn: "median", Field: "chunks"}}}, "unknown aggregate"},
+		{Query{Entity: Documents, OrderBy: "size"}, "order_by"},
+		{Query{Entity: Documents, Limit: MaxLimit + 1}, "limit"},
+	} {
+		err := tc.query.Validate()
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("Expected %q for %+v, got %v", tc.want, tc.query, err)
+		}
+	}
+
+	for _, p := range Presets {
+		q, ok := FindPreset(p.Name)
+		if !ok {
+			t.Fatalf("Expected to find preset %s", p.Name)
+		}
+		if err := q.Validate(); err != nil {
+			t.Errorf("Preset %s is invalid: %v", p.Name, err)
+		}
+	}
+}
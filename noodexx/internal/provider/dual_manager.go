@@ -5,6 +5,10 @@ import (
 	"noodexx/internal/config"
 	"noodexx/internal/llm"
 	"noodexx/internal/logging"
+	"noodexx/internal/replay"
+	"noodexx/internal/transcripts"
+	"noodexx/internal/wirelog"
+	"path/filepath"
 )
 
 // DualProviderManager manages two provider instances (local and cloud)
@@ -14,7 +18,12 @@ type DualProviderManager struct {
 	cloudProvider  llm.Provider
 	config         *config.Config
 	logger         *logging.Logger
-	defaultToLocal bool // Internal state for provider selection
+	defaultToLocal bool                 // Internal state for provider selection
+	wireLog        *wirelog.Log         // Optional provider request log, nil when disabled
+	transcripts    *transcripts.Archive // Optional archive of cloud requests, nil when disabled
+	replays        *replay.Log          // Optional log of failed requests, nil when disabled
+	reranker       llm.Reranker         // Reranker of the local provider, nil if it has none
+	localEmbedder  llm.Provider         // Unwrapped local provider, the "local" embedding fallback
 }
 
 // NewDualProviderManager creates a manager with both providers
@@ -38,8 +47,11 @@ func NewDualProviderManager(cfg *config.Config, logger *logging.Logger) (*DualPr
 			OpenAIEmbedModel:    cfg.LocalProvider.OpenAIEmbedModel,
 			OpenAIChatModel:     cfg.LocalProvider.OpenAIChatModel,
 			AnthropicKey:        cfg.LocalProvider.AnthropicKey,
-			AnthropicEmbedModel: cfg.LocalProvider.AnthropicEmbedModel,
 			AnthropicChatModel:  cfg.LocalProvider.AnthropicChatModel,
+			BuiltinModelPath:    cfg.LocalProvider.BuiltinModelPath,
+			BuiltinVocabPath:    cfg.LocalProvider.BuiltinVocabPath,
+			BuiltinRerankerPath: cfg.LocalProvider.BuiltinRerankerPath,
+			BuiltinRuntimePath:  cfg.LocalProvider.BuiltinRuntimePath,
 		}
 
 		provider, err := llm.NewProvider(localCfg, false, logger)
@@ -47,25 +59,29 @@ func NewDualProviderManager(cfg *config.Config, logger *logging.Logger) (*DualPr
 			return nil, fmt.Errorf("failed to initialize local provider: %w", err)
 		}
 		manager.localProvider = provider
+		manager.localEmbedder = provider
+		manager.reranker = rerankerOf(provider)
 		logger.Info("Local provider initialized: %s", cfg.LocalProvider.Type)
 	}
 
 	// Initialize cloud provider if configured
 	if cfg.CloudProvider.Type != "" {
 		cloudCfg := llm.Config{
-			Type:                cfg.CloudProvider.Type,
-			OllamaEndpoint:      cfg.CloudProvider.OllamaEndpoint,
-			OllamaEmbedModel:    cfg.CloudProvider.OllamaEmbedModel,
-			OllamaChatModel:     cfg.CloudProvider.OllamaChatModel,
-			OpenAIKey:           cfg.CloudProvider.OpenAIKey,
-			OpenAIEmbedModel:    cfg.CloudProvider.OpenAIEmbedModel,
-			OpenAIChatModel:     cfg.CloudProvider.OpenAIChatModel,
-			AnthropicKey:        cfg.CloudProvider.AnthropicKey,
-			AnthropicEmbedModel: cfg.CloudProvider.AnthropicEmbedModel,
-			AnthropicChatModel:  cfg.CloudProvider.AnthropicChatModel,
+			Type:               cfg.CloudProvider.Type,
+			OllamaEndpoint:     cfg.CloudProvider.OllamaEndpoint,
+			OllamaEmbedModel:   cfg.CloudProvider.OllamaEmbedModel,
+			OllamaChatModel:    cfg.CloudProvider.OllamaChatModel,
+			OpenAIKey:          cfg.CloudProvider.OpenAIKey,
+			OpenAIEmbedModel:   cfg.CloudProvider.OpenAIEmbedModel,
+			OpenAIChatModel:    cfg.CloudProvider.OpenAIChatModel,
+			AnthropicKey:       cfg.CloudProvider.AnthropicKey,
+			AnthropicChatModel: cfg.CloudProvider.AnthropicChatModel,
 		}
 
 		provider, err := llm.NewProvider(cloudCfg, false, logger)
+		if err == nil {
+			provider, err = manager.withEmbeddingFallback(provider, cfg.CloudProvider)
+		}
 		if err != nil {
 			// Log warning and continue with local provider only
 			logger.Warn("Cloud provider initialization failed: %v. Application will run with local provider only.", err)
@@ -84,6 +100,126 @@ func NewDualProviderManager(cfg *config.Config, logger *logging.Logger) (*DualPr
 	return manager, nil
 }
 
+// SetWireLog records all requests made through the managed providers to log
+// It wraps the current providers and any created by later reloads, so it should be
+// called once, before providers are handed out
+func (m *DualProviderManager) SetWireLog(log *wirelog.Log) {
+	m.wireLog = log
+	m.localProvider = m.withWireLog(m.localProvider, m.config.LocalProvider)
+	m.cloudProvider = m.withWireLog(m.cloudProvider, m.config.CloudProvider)
+}
+
+// withWireLog wraps p for the wire log when one is set
+func (m *DualProviderManager) withWireLog(p llm.Provider, pc config.ProviderConfig) llm.Provider {
+	if m.wireLog == nil || p == nil {
+		return p
+	}
+	chatModel, embedModel := providerModels(pc, m.config.LocalProvider)
+	return wirelog.Wrap(p, chatModel, embedModel, m.wireLog)
+}
+
+// SetTranscripts archives the requests made through the managed providers that
+// are not local. Like SetWireLog it also wraps providers created by later
+// reloads, and should be called once, before SetWireLog
+func (m *DualProviderManager) SetTranscripts(archive *transcripts.Archive) {
+	m.transcripts = archive
+	m.localProvider = m.withTranscripts(m.localProvider, m.config.LocalProvider)
+	m.cloudProvider = m.withTranscripts(m.cloudProvider, m.config.CloudProvider)
+}
+
+// withTranscripts wraps p for the transcript archive when one is set
+func (m *DualProviderManager) withTranscripts(p llm.Provider, pc config.ProviderConfig) llm.Provider {
+	if m.transcripts == nil || p == nil {
+		return p
+	}
+	chatModel, embedModel := providerModels(pc, m.config.LocalProvider)
+	return transcripts.Wrap(p, chatModel, embedModel, m.transcripts)
+}
+
+// SetReplay keeps the requests made through the managed providers that fail,
+// for replay. Like SetWireLog it also wraps providers created by later
+// reloads, and should be called once, before SetTranscripts
+func (m *DualProviderManager) SetReplay(log *replay.Log) {
+	m.replays = log
+	m.localProvider = m.withReplay(m.localProvider, m.config.LocalProvider)
+	m.cloudProvider = m.withReplay(m.cloudProvider, m.config.CloudProvider)
+}
+
+// withReplay wraps p for the failed request log when one is set
+func (m *DualProviderManager) withReplay(p llm.Provider, pc config.ProviderConfig) llm.Provider {
+	if m.replays == nil || p == nil {
+		return p
+	}
+	chatModel, embedModel := providerModels(pc, m.config.LocalProvider)
+	return replay.Wrap(p, chatModel, embedModel, m.replays)
+}
+
+// providerModels returns the chat and embedding models configured for a provider
+// local is the local provider's config, used when pc embeds with the local provider
+func providerModels(pc, local config.ProviderConfig) (chatModel, embedModel string) {
+	switch pc.Type {
+	case "ollama":
+		return pc.OllamaChatModel, pc.OllamaEmbedModel
+	case "openai":
+		return pc.OpenAIChatModel, pc.OpenAIEmbedModel
+	case "anthropic":
+		return pc.AnthropicChatModel, fallbackEmbedModel(pc, local)
+	case "builtin":
+		return "", filepath.Base(pc.BuiltinModelPath)
+	default:
+		return "", ""
+	}
+}
+
+// fallbackEmbedModel returns the embedding model used by a provider without an embeddings API
+func fallbackEmbedModel(pc, local config.ProviderConfig) string {
+	source, _ := pc.EmbeddingSource(local.Type != "")
+	switch source {
+	case config.EmbedWithLocal:
+		_, embedModel := providerModels(local, config.ProviderConfig{})
+		return embedModel
+	case config.EmbedWithOllama:
+		return pc.OllamaEmbedModel
+	case config.EmbedWithOpenAI:
+		return pc.OpenAIEmbedModel
+	default:
+		return ""
+	}
+}
+
+// withEmbeddingFallback pairs a provider that has no embeddings API with the first
+// embedder in its embedding_fallback chain that can be resolved
+// Providers with their own embeddings are returned unchanged
+func (m *DualProviderManager) withEmbeddingFallback(p llm.Provider, pc config.ProviderConfig) (llm.Provider, error) {
+	source, err := pc.EmbeddingSource(m.localEmbedder != nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var embedder llm.Provider
+	switch source {
+	case "":
+		return p, nil
+	case config.EmbedWithLocal:
+		embedder = m.localEmbedder
+	case config.EmbedWithOllama:
+		embedder = llm.NewOllamaProvider(pc.OllamaEndpoint, pc.OllamaEmbedModel, "", m.logger)
+	case config.EmbedWithOpenAI:
+		embedder = llm.NewOpenAIProvider(pc.OpenAIKey, pc.OpenAIEmbedModel, "", m.logger)
+	}
+	m.logger.Info("%s embeddings will use the %s embedding fallback", pc.Type, source)
+	return llm.WithEmbedder(p, embedder), nil
+}
+
+// rerankerOf returns p's reranker if it has a reranker model loaded
+// It must be called on the unwrapped provider
+func rerankerOf(p llm.Provider) llm.Reranker {
+	if builtin, ok := p.(*llm.BuiltinProvider); ok && builtin.HasReranker() {
+		return builtin
+	}
+	return nil
+}
+
 // GetActiveProvider returns the currently active provider based on privacy toggle state
 // Returns error if the active provider is not configured
 func (m *DualProviderManager) GetActiveProvider() (llm.Provider, error) {
@@ -116,11 +252,58 @@ func (m *DualProviderManager) GetCloudProvider() llm.Provider {
 	return m.cloudProvider
 }
 
+// GetReranker returns the local provider's reranker, or nil if it has none
+// Reranking runs in-process, so it is safe to use in either privacy mode
+func (m *DualProviderManager) GetReranker() llm.Reranker {
+	return m.reranker
+}
+
+// GetLocalWarmer returns the local provider's model warmer, or nil if its models do not load on demand
+func (m *DualProviderManager) GetLocalWarmer() llm.Warmer {
+	if warmer, ok := m.localEmbedder.(llm.Warmer); ok {
+		return warmer
+	}
+	return nil
+}
+
+// GetLocalPinger returns the local provider's reachability check, or nil if it
+// runs in-process or is not configured
+func (m *DualProviderManager) GetLocalPinger() llm.Pinger {
+	if pinger, ok := m.localEmbedder.(llm.Pinger); ok {
+		return pinger
+	}
+	return nil
+}
+
 // IsLocalMode returns true if privacy toggle is set to local AI
 func (m *DualProviderManager) IsLocalMode() bool {
 	return m.defaultToLocal
 }
 
+// GetActiveModel returns the chat model configured for the active provider
+// It is empty if the provider has no chat model (e.g. builtin)
+func (m *DualProviderManager) GetActiveModel() string {
+	chatModel, _ := m.GetModels(m.defaultToLocal)
+	return chatModel
+}
+
+// GetActiveEmbedModel returns the embedding model the active provider embeds with,
+// including a fallback embedder for providers without an embeddings API
+func (m *DualProviderManager) GetActiveEmbedModel() string {
+	_, embedModel := m.GetModels(m.defaultToLocal)
+	return embedModel
+}
+
+// GetModels returns the chat and embedding models of the local or cloud
+// provider, whichever mode is active
+func (m *DualProviderManager) GetModels(local bool) (chatModel, embedModel string) {
+	pc := m.config.CloudProvider
+	if local {
+		pc = m.config.LocalProvider
+	}
+	return providerModels(pc, m.config.LocalProvider)
+}
+
 // GetProviderName returns the name of the active provider for UI display
 // Returns a human-readable name like "Local AI (Ollama)" or "Cloud AI (GPT-4)"
 func (m *DualProviderManager) GetProviderName() string {
@@ -175,46 +358,57 @@ func (m *DualProviderManager) Reload(cfg *config.Config) error {
 			OpenAIEmbedModel:    cfg.LocalProvider.OpenAIEmbedModel,
 			OpenAIChatModel:     cfg.LocalProvider.OpenAIChatModel,
 			AnthropicKey:        cfg.LocalProvider.AnthropicKey,
-			AnthropicEmbedModel: cfg.LocalProvider.AnthropicEmbedModel,
 			AnthropicChatModel:  cfg.LocalProvider.AnthropicChatModel,
+			BuiltinModelPath:    cfg.LocalProvider.BuiltinModelPath,
+			BuiltinVocabPath:    cfg.LocalProvider.BuiltinVocabPath,
+			BuiltinRerankerPath: cfg.LocalProvider.BuiltinRerankerPath,
+			BuiltinRuntimePath:  cfg.LocalProvider.BuiltinRuntimePath,
 		}
 
 		provider, err := llm.NewProvider(localCfg, false, m.logger)
 		if err != nil {
 			m.logger.Error("Failed to reinitialize local provider: %v", err)
 			m.localProvider = nil
+			m.localEmbedder = nil
+			m.reranker = nil
 		} else {
-			m.localProvider = provider
+			m.localProvider = m.withWireLog(m.withTranscripts(m.withReplay(provider, cfg.LocalProvider), cfg.LocalProvider), cfg.LocalProvider)
+			m.localEmbedder = provider
+			m.reranker = rerankerOf(provider)
 			m.logger.Info("Local provider reinitialized: %s", cfg.LocalProvider.Type)
 		}
 	} else {
 		// Local provider was removed from config
 		m.localProvider = nil
+		m.localEmbedder = nil
+		m.reranker = nil
 		m.logger.Info("Local provider removed from configuration")
 	}
 
 	// Reinitialize cloud provider if configured
 	if cfg.CloudProvider.Type != "" {
 		cloudCfg := llm.Config{
-			Type:                cfg.CloudProvider.Type,
-			OllamaEndpoint:      cfg.CloudProvider.OllamaEndpoint,
-			OllamaEmbedModel:    cfg.CloudProvider.OllamaEmbedModel,
-			OllamaChatModel:     cfg.CloudProvider.OllamaChatModel,
-			OpenAIKey:           cfg.CloudProvider.OpenAIKey,
-			OpenAIEmbedModel:    cfg.CloudProvider.OpenAIEmbedModel,
-			OpenAIChatModel:     cfg.CloudProvider.OpenAIChatModel,
-			AnthropicKey:        cfg.CloudProvider.AnthropicKey,
-			AnthropicEmbedModel: cfg.CloudProvider.AnthropicEmbedModel,
-			AnthropicChatModel:  cfg.CloudProvider.AnthropicChatModel,
+			Type:               cfg.CloudProvider.Type,
+			OllamaEndpoint:     cfg.CloudProvider.OllamaEndpoint,
+			OllamaEmbedModel:   cfg.CloudProvider.OllamaEmbedModel,
+			OllamaChatModel:    cfg.CloudProvider.OllamaChatModel,
+			OpenAIKey:          cfg.CloudProvider.OpenAIKey,
+			OpenAIEmbedModel:   cfg.CloudProvider.OpenAIEmbedModel,
+			OpenAIChatModel:    cfg.CloudProvider.OpenAIChatModel,
+			AnthropicKey:       cfg.CloudProvider.AnthropicKey,
+			AnthropicChatModel: cfg.CloudProvider.AnthropicChatModel,
 		}
 
 		provider, err := llm.NewProvider(cloudCfg, false, m.logger)
+		if err == nil {
+			provider, err = m.withEmbeddingFallback(provider, cfg.CloudProvider)
+		}
 		if err != nil {
 			// Log warning and continue with local provider only
 			m.logger.Warn("Cloud provider initialization failed: %v. Application will run with local provider only.", err)
 			m.cloudProvider = nil
 		} else {
-			m.cloudProvider = provider
+			m.cloudProvider = m.withWireLog(m.withTranscripts(m.withReplay(provider, cfg.CloudProvider), cfg.CloudProvider), cfg.CloudProvider)
 			m.logger.Info("Cloud provider reinitialized: %s", cfg.CloudProvider.Type)
 		}
 	} else {
@@ -247,6 +247,26 @@ func TestGetProviderName_CloudOpenAI(t *testing.T) {
 	}
 }
 
+// TestGetActiveEmbedModel tests GetActiveEmbedModel follows the privacy toggle
+func TestGetActiveEmbedModel(t *testing.T) {
+	cfg := createDualProviderConfig()
+	logger := createTestLogger()
+
+	manager, err := NewDualProviderManager(cfg, logger)
+	if err != nil {
+		t.Fatalf("NewDualProviderManager() failed: %v", err)
+	}
+
+	if model := manager.GetActiveEmbedModel(); model != "nomic-embed-text" {
+		t.Errorf("Expected local embed model 'nomic-embed-text', got '%s'", model)
+	}
+
+	manager.defaultToLocal = false
+	if model := manager.GetActiveEmbedModel(); model != "text-embedding-3-small" {
+		t.Errorf("Expected cloud embed model 'text-embedding-3-small', got '%s'", model)
+	}
+}
+
 // TestGetProviderName_CloudAnthropic tests GetProviderName returns correct name for cloud Anthropic
 func TestGetProviderName_CloudAnthropic(t *testing.T) {
 	cfg := createDualProviderConfig()
@@ -0,0 +1,93 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"noodexx/internal/config"
+	"noodexx/internal/llm"
+	"testing"
+)
+
+// createAnthropicConfig returns a config with a local Ollama at endpoint and an Anthropic cloud provider
+func createAnthropicConfig(endpoint string, fallback []string) *config.Config {
+	cfg := createDualProviderConfig()
+	cfg.LocalProvider.OllamaEndpoint = endpoint
+	cfg.CloudProvider = config.ProviderConfig{
+		Type:               "anthropic",
+		AnthropicKey:       "test-key",
+		AnthropicChatModel: "claude-3-opus",
+		EmbeddingFallback:  fallback,
+	}
+	return cfg
+}
+
+// TestEmbeddingFallback_Local tests that Anthropic embeds with the local provider by default
+func TestEmbeddingFallback_Local(t *testing.T) {
+	embedded := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/embeddings" {
+			embedded = true
+			w.Write([]byte(`{"embedding":[0.5,0.25]}`))
+		}
+	}))
+	defer server.Close()
+
+	manager, err := NewDualProviderManager(createAnthropicConfig(server.URL, nil), createTestLogger())
+	if err != nil {
+		t.Fatalf("NewDualProviderManager() error = %v", err)
+	}
+	cloud := manager.GetCloudProvider()
+	if cloud == nil {
+		t.Fatal("Expected the Anthropic provider to be initialized")
+	}
+	if cloud.Name() != "anthropic" || cloud.IsLocal() {
+		t.Errorf("Expected the cloud provider to report as Anthropic, got %s (local=%v)", cloud.Name(), cloud.IsLocal())
+	}
+
+	vec, err := cloud.Embed(context.Background(), "hello")
+	if err != nil {
+		t.Fatalf("Embed() error = %v", err)
+	}
+	if !embedded || len(vec) != 2 || vec[0] != 0.5 {
+		t.Errorf("Expected the embedding from the local provider, got %v", vec)
+	}
+}
+
+// TestEmbeddingFallback_Chain tests that the first resolvable entry is used and unresolvable chains fail
+func TestEmbeddingFallback_Chain(t *testing.T) {
+	cfg := createAnthropicConfig("http://localhost:11434", []string{config.EmbedWithOpenAI, config.EmbedWithOllama})
+	if _, err := cfg.CloudProvider.EmbeddingSource(true); err == nil {
+		t.Error("Expected an error when no fallback entry is configured")
+	}
+	manager, err := NewDualProviderManager(cfg, createTestLogger())
+	if err != nil {
+		t.Fatalf("NewDualProviderManager() error = %v", err)
+	}
+	if manager.GetCloudProvider() != nil {
+		t.Error("Expected the cloud provider to be disabled when no embedder resolves")
+	}
+
+	cfg.CloudProvider.OllamaEndpoint = "http://gpu-box:11434"
+	cfg.CloudProvider.OllamaEmbedModel = "mxbai-embed-large"
+	if source, err := cfg.CloudProvider.EmbeddingSource(true); err != nil || source != config.EmbedWithOllama {
+		t.Errorf("Expected the ollama fallback, got %q (%v)", source, err)
+	}
+	if _, embedModel := providerModels(cfg.CloudProvider, cfg.LocalProvider); embedModel != "mxbai-embed-large" {
+		t.Errorf("Expected the fallback embedding model, got %q", embedModel)
+	}
+
+	cfg.CloudProvider.EmbeddingFallback = []string{"voyage"}
+	if _, err := cfg.CloudProvider.EmbeddingSource(true); err == nil {
+		t.Error("Expected an error for an unknown fallback entry")
+	}
+}
+
+// TestAnthropicEmbedUnsupported tests that Anthropic on its own reports missing embeddings
+func TestAnthropicEmbedUnsupported(t *testing.T) {
+	p := llm.NewAnthropicProvider("test-key", "claude-3-opus", createTestLogger())
+	if _, err := p.Embed(context.Background(), "hello"); !errors.Is(err, llm.ErrEmbedUnsupported) {
+		t.Errorf("Expected ErrEmbedUnsupported, got %v", err)
+	}
+}
@@ -89,7 +89,7 @@ func TestPreservation_DualProviderFunctionalityWithValidCredentials(t *testing.T
 				cfg.CloudProvider.OpenAIChatModel = "gpt-4"
 			} else if tc.cloudProviderType == "anthropic" {
 				cfg.CloudProvider.AnthropicKey = tc.cloudProviderKey
-				cfg.CloudProvider.AnthropicEmbedModel = "claude-3-sonnet"
+				cfg.CloudProvider.EmbeddingFallback = []string{config.EmbedWithLocal}
 				cfg.CloudProvider.AnthropicChatModel = "claude-3-opus"
 			}
 
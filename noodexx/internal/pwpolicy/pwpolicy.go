@@ -0,0 +1,250 @@
+// Package pwpolicy checks new passwords against the configured password policy:
+// a minimum length, required character classes, a built-in list of common
+// passwords and an optional local copy of the Pwned Passwords breach list.
+// Reuse of previous passwords is checked by the store, which holds the hashes;
+// the policy only says how many to check.
+package pwpolicy
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha1"
+	_ "embed"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// Limits on the policy options
+const (
+	DefaultMinLength = 8
+	MaxLength        = 256 // bcrypt ignores bytes past 72; this only bounds request size
+	MaxClasses       = 4   // Lowercase, uppercase, digits and symbols
+	MaxHistory       = 24  // Matches the history the store keeps
+)
+
+// generatedLength is the length of passwords made by Generate, unless the
+// policy asks for longer ones
+const generatedLength = 16
+
+//go:embed common_passwords.txt
+var commonPasswordList string
+
+// Options configures a Policy
+type Options struct {
+	MinLength     int    // Shortest accepted password; DefaultMinLength when 0
+	MinClasses    int    // Character classes a password must mix, 0 to MaxClasses
+	AllowCommon   bool   // Accept passwords on the built-in common password list
+	BreachListDir string // Directory of Pwned Passwords range files, empty to skip the check
+	HistoryCount  int    // Most recent passwords, including the current one, that may not be reused
+}
+
+// Policy checks new passwords
+type Policy struct {
+	opts   Options
+	common map[string]bool
+}
+
+// New creates a policy, checking the options and that the breach list directory exists
+func New(opts Options) (*Policy, error) {
+	if opts.MinLength == 0 {
+		opts.MinLength = DefaultMinLength
+	}
+	if opts.MinLength < 1 || opts.MinLength > MaxLength {
+		return nil, fmt.Errorf("min_length must be between 1 and %d", MaxLength)
+	}
+	if opts.MinClasses < 0 || opts.MinClasses > MaxClasses {
+		return nil, fmt.Errorf("min_classes must be between 0 and %d", MaxClasses)
+	}
+	if opts.HistoryCount < 0 || opts.HistoryCount > MaxHistory {
+		return nil, fmt.Errorf("history_count must be between 0 and %d", MaxHistory)
+	}
+	if opts.BreachListDir != "" {
+		info, err := os.Stat(opts.BreachListDir)
+		if err != nil || !info.IsDir() {
+			return nil, fmt.Errorf("breach_list_dir %s is not a directory", opts.BreachListDir)
+		}
+	}
+
+	p := &Policy{opts: opts}
+	if !opts.AllowCommon {
+		p.common = make(map[string]bool)
+		for _, line := range strings.Split(commonPasswordList, "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				p.common[line] = true
+			}
+		}
+	}
+	return p, nil
+}
+
+// defaultPolicy is shared by every Default caller; policies are read-only
+var defaultPolicy, _ = New(Options{})
+
+// Default returns the policy used when none is configured: at least
+// DefaultMinLength characters and no common passwords
+func Default() *Policy {
+	return defaultPolicy
+}
+
+// HistoryCount returns how many recent passwords may not be reused
+func (p *Policy) HistoryCount() int {
+	return p.opts.HistoryCount
+}
+
+// MinLength returns the shortest accepted password length
+func (p *Policy) MinLength() int {
+	return p.opts.MinLength
+}
+
+// Hint describes the length and character class rules, for form hints
+func (p *Policy) Hint() string {
+	hint := fmt.Sprintf("Minimum %d characters", p.opts.MinLength)
+	if p.opts.MinClasses > 1 {
+		hint += fmt.Sprintf(", mixing %d of lowercase, uppercase, digits and symbols", p.opts.MinClasses)
+	}
+	return hint
+}
+
+// Check returns an error describing the first rule password breaks, or nil
+// The breach list check fails open: an unreadable range file is skipped
+func (p *Policy) Check(password string) error {
+	length := len([]rune(password))
+	if length < p.opts.MinLength {
+		return fmt.Errorf("Password must be at least %d characters", p.opts.MinLength)
+	}
+	if len(password) > MaxLength {
+		return fmt.Errorf("Password must be at most %d characters", MaxLength)
+	}
+	if p.opts.MinClasses > 1 && classes(password) < p.opts.MinClasses {
+		return fmt.Errorf("Password must mix at least %d of lowercase letters, uppercase letters, digits and symbols", p.opts.MinClasses)
+	}
+	if p.common[strings.ToLower(password)] {
+		return fmt.Errorf("Password is too common; choose a less predictable one")
+	}
+	if p.opts.BreachListDir != "" {
+		if breached, _ := p.breached(password); breached {
+			return fmt.Errorf("Password has appeared in a data breach; choose a different one")
+		}
+	}
+	return nil
+}
+
+// classes counts the character classes used in password
+func classes(password string) int {
+	var lower, upper, digit, symbol int
+	for _, c := range password {
+		switch {
+		case unicode.IsLower(c):
+			lower = 1
+		case unicode.IsUpper(c):
+			upper = 1
+		case unicode.IsDigit(c):
+			digit = 1
+		default:
+			symbol = 1
+		}
+	}
+	return lower + upper + digit + symbol
+}
+
+// breached looks password up in the local breach list
+// As with the Pwned Passwords range API (k-anonymity), the list is split by the
+// first 5 hex digits of the SHA-1 hash: file ABCDE (or ABCDE.txt) holds lines of
+// the remaining 35 digits and a count, e.g. 0018A45C4D1DEF81644B54AB7F969B88D65:21
+func (p *Policy) breached(password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := hash[:5], hash[5:]
+
+	f, err := os.Open(filepath.Join(p.opts.BreachListDir, prefix))
+	if os.IsNotExist(err) {
+		f, err = os.Open(filepath.Join(p.opts.BreachListDir, prefix+".txt"))
+	}
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if i := strings.IndexByte(line, ':'); i >= 0 {
+			line = line[:i]
+		}
+		if strings.EqualFold(line, suffix) {
+			return true, nil
+		}
+	}
+	return false, scanner.Err()
+}
+
+// Character sets used by Generate
+const (
+	lowerChars  = "abcdefghijkmnopqrstuvwxyz"
+	upperChars  = "ABCDEFGHJKLMNPQRSTUVWXYZ"
+	digitChars  = "23456789"
+	symbolChars = "!#$%*+-=?@_"
+)
+
+// Generate returns a random password that satisfies the policy, for admin resets
+// It uses every character class, so it passes any min_classes setting
+func (p *Policy) Generate() (string, error) {
+	length := generatedLength
+	if p.opts.MinLength > length {
+		length = p.opts.MinLength
+	}
+
+	sets := []string{lowerChars, upperChars, digitChars, symbolChars}
+	all := strings.Join(sets, "")
+	for {
+		password := make([]byte, length)
+		for i := range password {
+			// One character from each set, the rest from any
+			set := all
+			if i < len(sets) {
+				set = sets[i]
+			}
+			c, err := randomChar(set)
+			if err != nil {
+				return "", err
+			}
+			password[i] = c
+		}
+		if err := shuffle(password); err != nil {
+			return "", err
+		}
+		if p.Check(string(password)) == nil {
+			return string(password), nil
+		}
+	}
+}
+
+// randomChar picks a random character of set
+func randomChar(set string) (byte, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(int64(len(set))))
+	if err != nil {
+		return 0, fmt.Errorf("failed to generate password: %w", err)
+	}
+	return set[n.Int64()], nil
+}
+
+// shuffle randomly reorders b
+func shuffle(b []byte) error {
+	for i := len(b) - 1; i > 0; i-- {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(i+1)))
+		if err != nil {
+			return fmt.Errorf("failed to generate password: %w", err)
+		}
+		j := n.Int64()
+		b[i], b[j] = b[j], b[i]
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package pwpolicy
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNew_Options(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    Options
+		wantErr bool
+	}{
+		{"defaults", Options{}, false},
+		{"negative min length", Options{MinLength: -1}, true},
+		{"min length too long", Options{MinLength: MaxLength + 1}, true},
+		{"too many classes", Options{MinClasses: 5}, true},
+		{"history too long", Options{HistoryCount: MaxHistory + 1}, true},
+		{"missing breach list", Options{BreachListDir: filepath.Join(t.TempDir(), "missing")}, true},
+		{"breach list", Options{BreachListDir: t.TempDir()}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("New() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPolicy_Check(t *testing.T) {
+	strict, err := New(Options{MinLength: 12, MinClasses: 3})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	lenient, err := New(Options{AllowCommon: true})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		policy   *Policy
+		password string
+		wantErr  string
+	}{
+		{"default accepts a passphrase", Default(), "correct horse", ""},
+		{"default rejects short", Default(), "short", "at least 8"},
+		{"default rejects too long", Default(), strings.Repeat("x", MaxLength+1), "at most"},
+		{"default rejects common", Default(), "password123", "too common"},
+		{"common check ignores case", Default(), "PassWord123", "too common"},
+		{"common allowed when configured", lenient, "password123", ""},
+		{"strict rejects short", strict, "Abc123!", "at least 12"},
+		{"strict rejects two classes", strict, "alllowercase123", "mix at least 3"},
+		{"strict accepts three classes", strict, "Mixed-case-words", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.policy.Check(tt.password)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Check(%q) = %v, want nil", tt.password, err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Check(%q) = %v, want error containing %q", tt.password, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPolicy_BreachList(t *testing.T) {
+	dir := t.TempDir()
+
+	// The range file is named by the first 5 digits of the SHA-1 and lists the rest
+	password := "hunter2-breached"
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	rangeFile := "0000000000000000000000000000000000A:3\n" + hash[5:] + ":42\n"
+	if err := os.WriteFile(filepath.Join(dir, hash[:5]+".txt"), []byte(rangeFile), 0644); err != nil {
+		t.Fatalf("Failed to write range file: %v", err)
+	}
+
+	policy, err := New(Options{BreachListDir: dir})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if err := policy.Check(password); err == nil || !strings.Contains(err.Error(), "data breach") {
+		t.Errorf("Expected a breached password to be rejected, got %v", err)
+	}
+	// Prefixes without a range file are not breached
+	if err := policy.Check("an unlisted passphrase"); err != nil {
+		t.Errorf("Expected an unlisted password to pass, got %v", err)
+	}
+}
+
+func TestPolicy_Generate(t *testing.T) {
+	policy, err := New(Options{MinLength: 20, MinClasses: 4})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	for i := 0; i < 20; i++ {
+		password, err := policy.Generate()
+		if err != nil {
+			t.Fatalf("Generate failed: %v", err)
+		}
+		if len(password) != 20 {
+			t.Errorf("Expected a 20 character password, got %q", password)
+		}
+		if err := policy.Check(password); err != nil {
+			t.Errorf("Generated password %q breaks the policy: %v", password, err)
+		}
+	}
+
+	password, _ := Default().Generate()
+	if len(password) != generatedLength {
+		t.Errorf("Expected a %d character password, got %q", generatedLength, password)
+	}
+}
@@ -1,6 +1,9 @@
 package rag
 
-import "strings"
+import (
+	"fmt"
+	"strings"
+)
 
 // Chunker splits text into overlapping segments
 type Chunker struct {
@@ -16,6 +19,12 @@ func NewChunker(chunkSize, overlap int) *Chunker {
 	}
 }
 
+// Settings describes the chunker's settings, recorded with each document so
+// documents chunked differently can be found and re-chunked
+func (c *Chunker) Settings() string {
+	return fmt.Sprintf("size %d, overlap %d", c.ChunkSize, c.Overlap)
+}
+
 // ChunkText splits text into chunks with overlap using rune-based slicing
 // for proper Unicode handling
 func (c *Chunker) ChunkText(text string) []string {
@@ -0,0 +1,77 @@
+package rag
+
+// MMRItem is a search result considered for Maximal Marginal Relevance selection
+type MMRItem struct {
+	Source    string
+	Embedding []float32 // May be nil; such items are compared by source alone
+	Relevance float64   // Any scale; higher is more relevant
+}
+
+// SelectMMR picks up to k items by Maximal Marginal Relevance and returns their
+// indexes in selection order. Each pick maximizes
+//
+//	lambda*relevance - (1-lambda)*max similarity to the items already picked
+//
+// so lambda 1 keeps the relevance order and lower values favor results unlike
+// those already chosen. Relevance is rescaled to 0..1 across items so it weighs
+// against cosine similarity whatever scale the scores came in. Items without
+// embeddings count as identical to picked items from the same source
+func SelectMMR(items []MMRItem, lambda float64, k int) []int {
+	if k > len(items) {
+		k = len(items)
+	}
+	if k <= 0 {
+		return nil
+	}
+
+	lo, hi := items[0].Relevance, items[0].Relevance
+	for _, item := range items[1:] {
+		lo = min(lo, item.Relevance)
+		hi = max(hi, item.Relevance)
+	}
+	relevance := make([]float64, len(items))
+	for i, item := range items {
+		if hi > lo {
+			relevance[i] = (item.Relevance - lo) / (hi - lo)
+		} else {
+			relevance[i] = 1
+		}
+	}
+
+	// maxSim[i] is item i's highest similarity to any picked item
+	maxSim := make([]float64, len(items))
+	picked := make([]bool, len(items))
+	selected := make([]int, 0, k)
+	for len(selected) < k {
+		best, bestScore := -1, 0.0
+		for i := range items {
+			if picked[i] {
+				continue
+			}
+			score := lambda*relevance[i] - (1-lambda)*maxSim[i]
+			if best < 0 || score > bestScore {
+				best, bestScore = i, score
+			}
+		}
+
+		picked[best] = true
+		selected = append(selected, best)
+		for i := range items {
+			if !picked[i] {
+				maxSim[i] = max(maxSim[i], itemSimilarity(items[i], items[best]))
+			}
+		}
+	}
+	return selected
+}
+
+// itemSimilarity compares two items by embedding, or by source when either has none
+func itemSimilarity(a, b MMRItem) float64 {
+	if len(a.Embedding) == 0 || len(b.Embedding) == 0 {
+		if a.Source == b.Source {
+			return 1
+		}
+		return 0
+	}
+	return CosineSimilarity(a.Embedding, b.Embedding)
+}
@@ -0,0 +1,90 @@
+package rag
+
+import (
+	"context"
+	"io"
+	"noodexx/internal/logging"
+	"testing"
+)
+
+func TestSelectMMR(t *testing.T) {
+	// Two near-duplicates from one document outrank a different document
+	items := []MMRItem{
+		{Source: "a.txt", Embedding: []float32{1, 0, 0}, Relevance: 0.95},
+		{Source: "a.txt", Embedding: []float32{0.99, 0.01, 0}, Relevance: 0.94},
+		{Source: "b.txt", Embedding: []float32{0, 1, 0}, Relevance: 0.80},
+	}
+
+	t.Run("lambda 1 keeps relevance order", func(t *testing.T) {
+		got := SelectMMR(items, 1, 2)
+		if len(got) != 2 || got[0] != 0 || got[1] != 1 {
+			t.Errorf("Expected [0 1], got %v", got)
+		}
+	})
+
+	t.Run("lower lambda skips the near-duplicate", func(t *testing.T) {
+		got := SelectMMR(items, 0.5, 2)
+		if len(got) != 2 || got[0] != 0 || got[1] != 2 {
+			t.Errorf("Expected [0 2], got %v", got)
+		}
+	})
+
+	t.Run("items without embeddings are compared by source", func(t *testing.T) {
+		bare := []MMRItem{
+			{Source: "a.txt", Relevance: 3},
+			{Source: "a.txt", Relevance: 2.9},
+			{Source: "b.txt", Relevance: 1},
+		}
+		got := SelectMMR(bare, 0.5, 2)
+		if len(got) != 2 || got[1] != 2 {
+			t.Errorf("Expected the other source second, got %v", got)
+		}
+	})
+
+	t.Run("k larger than the items", func(t *testing.T) {
+		if got := SelectMMR(items, 0.7, 10); len(got) != 3 {
+			t.Errorf("Expected all 3 items, got %v", got)
+		}
+		if got := SelectMMR(nil, 0.7, 5); got != nil {
+			t.Errorf("Expected nil for no items, got %v", got)
+		}
+	})
+}
+
+// candidateStore returns fixed chunks and records the requested search
+type candidateStore struct {
+	chunks []Chunk
+	userID int64
+	opts   SearchOptions
+	topK   int
+}
+
+func (s *candidateStore) SearchLibrary(ctx context.Context, userID int64, queryVec []float32, opts SearchOptions, topK int) ([]Chunk, error) {
+	s.userID, s.opts, s.topK = userID, opts, topK
+	return s.chunks[:min(topK, len(s.chunks))], nil
+}
+
+func TestSearcherMMR(t *testing.T) {
+	store := &candidateStore{chunks: []Chunk{
+		{Source: "a.txt", Text: "first", Score: 0.9, Embedding: []float32{1, 0}},
+		{Source: "a.txt", Text: "copy", Score: 0.89, Embedding: []float32{1, 0}},
+		{Source: "b.txt", Text: "other", Score: 0.7, Embedding: []float32{0, 1}},
+	}}
+	searcher := NewSearcher(store, logging.NewLogger("test", logging.ERROR, io.Discard))
+	searcher.SetMMR(0.5)
+
+	opts := SearchOptions{Query: "first", Mode: "hybrid"}
+	results, err := searcher.Search(context.Background(), 7, []float32{1, 0}, opts, 2)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if store.userID != 7 || store.opts.Query != "first" || store.opts.Mode != "hybrid" {
+		t.Errorf("Expected user 7's hybrid search, got user %d with %+v", store.userID, store.opts)
+	}
+	if store.topK != 2*mmrCandidateFactor {
+		t.Errorf("Expected %d candidates fetched, got %d", 2*mmrCandidateFactor, store.topK)
+	}
+	if len(results) != 2 || results[0].Text != "first" || results[1].Text != "other" {
+		t.Errorf("Expected first and other, got %+v", results)
+	}
+}
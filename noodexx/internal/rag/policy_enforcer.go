@@ -28,8 +28,14 @@ func NewRAGPolicyEnforcer(cfg *config.Config, logger *logging.Logger) *RAGPolicy
 //   - If CloudRAGPolicy == "allow_rag": perform RAG
 //   - If CloudRAGPolicy == "no_rag": do NOT perform RAG
 func (e *RAGPolicyEnforcer) ShouldPerformRAG() bool {
+	return e.ShouldPerformRAGFor(e.config.Privacy.DefaultToLocal)
+}
+
+// ShouldPerformRAGFor is ShouldPerformRAG for a request answered in local or
+// cloud mode regardless of the privacy toggle, such as one switched with /mode
+func (e *RAGPolicyEnforcer) ShouldPerformRAGFor(local bool) bool {
 	// Local AI mode: always perform RAG
-	if e.config.Privacy.DefaultToLocal {
+	if local {
 		e.logger.Debug("RAG enabled: using local AI provider")
 		return true
 	}
@@ -50,8 +56,13 @@ func (e *RAGPolicyEnforcer) ShouldPerformRAG() bool {
 // - "RAG Enabled" - when using cloud AI with allow_rag policy
 // - "RAG Disabled (Cloud Policy)" - when using cloud AI with no_rag policy
 func (e *RAGPolicyEnforcer) GetRAGStatus() string {
+	return e.GetRAGStatusFor(e.config.Privacy.DefaultToLocal)
+}
+
+// GetRAGStatusFor is GetRAGStatus for a request answered in local or cloud mode
+func (e *RAGPolicyEnforcer) GetRAGStatusFor(local bool) string {
 	// Local AI mode: always enabled
-	if e.config.Privacy.DefaultToLocal {
+	if local {
 		return "RAG Enabled (Local)"
 	}
 
@@ -3,10 +3,13 @@ package rag
 import (
 	"fmt"
 	"strings"
+	"unicode"
 )
 
 // PromptBuilder constructs prompts with retrieved context
-type PromptBuilder struct{}
+type PromptBuilder struct {
+	style AnswerStyle
+}
 
 // NewPromptBuilder creates a new PromptBuilder
 func NewPromptBuilder() *PromptBuilder {
@@ -37,3 +40,99 @@ func (pb *PromptBuilder) BuildPrompt(query string, chunks []Chunk) string {
 
 	return sb.String()
 }
+
+// Answer tones
+const (
+	ToneConcise   = "concise"
+	ToneDetailed  = "detailed"
+	ToneTechnical = "technical"
+)
+
+// Citation styles
+const (
+	CitationNumbered = "numbered" // [n] markers referring to the numbered context
+	CitationSources  = "sources"  // Source names in parentheses
+	CitationNone     = "none"     // No citations in the answer
+)
+
+// maxLanguageLength bounds the answer language name
+const maxLanguageLength = 40
+
+// AnswerStyle shapes answers: the language they are written in, their tone and
+// how they cite the context. Empty fields leave the model's own behaviour
+type AnswerStyle struct {
+	Language      string // Language name, e.g. "German" or "Brazilian Portuguese"
+	Tone          string // ToneConcise, ToneDetailed or ToneTechnical
+	CitationStyle string // CitationNumbered, CitationSources or CitationNone
+}
+
+// Validate checks the tone and citation style are known and the language is a
+// plain name, so it cannot carry instructions of its own into the prompt
+func (s AnswerStyle) Validate() error {
+	if len(s.Language) > maxLanguageLength {
+		return fmt.Errorf("language must be at most %d characters", maxLanguageLength)
+	}
+	for _, r := range s.Language {
+		if !unicode.IsLetter(r) && r != ' ' && r != '-' && r != '(' && r != ')' {
+			return fmt.Errorf("language must be a language name")
+		}
+	}
+	switch s.Tone {
+	case "", ToneConcise, ToneDetailed, ToneTechnical:
+	default:
+		return fmt.Errorf("tone must be %s, %s or %s", ToneConcise, ToneDetailed, ToneTechnical)
+	}
+	switch s.CitationStyle {
+	case "", CitationNumbered, CitationSources, CitationNone:
+	default:
+		return fmt.Errorf("citation_style must be %s, %s or %s", CitationNumbered, CitationSources, CitationNone)
+	}
+	return nil
+}
+
+// Merge returns the style with the non-empty fields of override replacing its own
+func (s AnswerStyle) Merge(override AnswerStyle) AnswerStyle {
+	if override.Language != "" {
+		s.Language = override.Language
+	}
+	if override.Tone != "" {
+		s.Tone = override.Tone
+	}
+	if override.CitationStyle != "" {
+		s.CitationStyle = override.CitationStyle
+	}
+	return s
+}
+
+// WithStyle returns a PromptBuilder whose system prompts ask for answers in style
+func (pb *PromptBuilder) WithStyle(style AnswerStyle) *PromptBuilder {
+	return &PromptBuilder{style: style}
+}
+
+// SystemPrompt appends the instructions for the builder's answer style to base
+func (pb *PromptBuilder) SystemPrompt(base string) string {
+	var instructions []string
+	switch pb.style.Tone {
+	case ToneConcise:
+		instructions = append(instructions, "Keep answers short and to the point.")
+	case ToneDetailed:
+		instructions = append(instructions, "Give thorough answers that explain the reasoning and include relevant details.")
+	case ToneTechnical:
+		instructions = append(instructions, "Answer for a technical audience, using precise terminology.")
+	}
+	switch pb.style.CitationStyle {
+	case CitationNumbered:
+		instructions = append(instructions, "Cite the context you use with its number in square brackets, like [1].")
+	case CitationSources:
+		instructions = append(instructions, "Cite the context you use by its source name in parentheses.")
+	case CitationNone:
+		instructions = append(instructions, "Do not include citations in the answer.")
+	}
+	if pb.style.Language != "" {
+		instructions = append(instructions, fmt.Sprintf("Write the answer in %s, whatever the language of the question or context.", pb.style.Language))
+	}
+	if len(instructions) == 0 {
+		return base
+	}
+	return base + "\n\n" + strings.Join(instructions, " ")
+}
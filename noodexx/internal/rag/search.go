@@ -8,20 +8,35 @@ import (
 
 // Store interface for RAG operations
 type Store interface {
-	Search(ctx context.Context, queryVec []float32, topK int) ([]Chunk, error)
+	SearchLibrary(ctx context.Context, userID int64, queryVec []float32, opts SearchOptions, topK int) ([]Chunk, error)
+}
+
+// SearchOptions chooses how the library is searched: by embedding, by the
+// query's words, or both fused into one ranking
+type SearchOptions struct {
+	Query      string   // Text of the query, for keyword scoring
+	QueryModel string   // Embedding model of the query vector
+	Sources    []string // Only chunks of these sources, unless nil; empty finds nothing
+	Mode       string   // "vector", "keyword" or "hybrid"; empty uses the configured mode
 }
 
 // Chunk represents a search result
 type Chunk struct {
-	Source string
-	Text   string
-	Score  float64
+	Source    string
+	Text      string
+	Score     float64
+	Embedding []float32 // Used for diversity selection; may be nil
 }
 
-// Searcher performs vector similarity search
+// mmrCandidateFactor is how many more candidates than requested are fetched for
+// diversity selection to choose from
+const mmrCandidateFactor = 4
+
+// Searcher searches the library for the chunks relevant to a query
 type Searcher struct {
-	store  Store // Interface to database
-	logger *logging.Logger
+	store     Store // Interface to database
+	logger    *logging.Logger
+	mmrLambda float64 // Diversity selection's relevance weight, 0 when disabled
 }
 
 // NewSearcher creates a new Searcher with the given store
@@ -32,19 +47,34 @@ func NewSearcher(store Store, logger *logging.Logger) *Searcher {
 	}
 }
 
-// Search finds relevant chunks using cosine similarity
-func (s *Searcher) Search(ctx context.Context, queryVec []float32, topK int) ([]Chunk, error) {
+// SetMMR enables Maximal Marginal Relevance selection with the given relevance
+// weight between 0 and 1, so results are not near-copies of each other; 0 disables it
+func (s *Searcher) SetMMR(lambda float64) {
+	s.mmrLambda = lambda
+}
+
+// Search finds the chunks visible to the user that are relevant to the query,
+// ranked as opts chooses. queryVec may be nil for keyword search
+func (s *Searcher) Search(ctx context.Context, userID int64, queryVec []float32, opts SearchOptions, topK int) ([]Chunk, error) {
 	logger := s.logger.WithFields(map[string]interface{}{
 		"vector_size": len(queryVec),
+		"mode":        opts.Mode,
 		"limit":       topK,
 	})
 	logger.Debug("starting RAG search")
 
-	results, err := s.store.Search(ctx, queryVec, topK)
+	candidates := topK
+	if s.mmrLambda > 0 {
+		candidates = topK * mmrCandidateFactor
+	}
+	results, err := s.store.SearchLibrary(ctx, userID, queryVec, opts, candidates)
 	if err != nil {
 		logger.WithContext("error", err.Error()).Error("search failed")
 		return nil, err
 	}
+	if s.mmrLambda > 0 {
+		results = Diversify(results, s.mmrLambda, topK)
+	}
 
 	minScore := 0.0
 	if len(results) > 0 {
@@ -58,6 +88,22 @@ func (s *Searcher) Search(ctx context.Context, queryVec []float32, topK int) ([]
 	return results, nil
 }
 
+// Diversify picks up to k chunks by Maximal Marginal Relevance (see SelectMMR),
+// using each chunk's score as its relevance
+func Diversify(chunks []Chunk, lambda float64, k int) []Chunk {
+	items := make([]MMRItem, len(chunks))
+	for i, c := range chunks {
+		items[i] = MMRItem{Source: c.Source, Embedding: c.Embedding, Relevance: c.Score}
+	}
+
+	selected := SelectMMR(items, lambda, k)
+	diverse := make([]Chunk, len(selected))
+	for i, idx := range selected {
+		diverse[i] = chunks[idx]
+	}
+	return diverse
+}
+
 // CosineSimilarity computes similarity between two vectors
 // Returns a value between -1.0 and 1.0, where 1.0 means identical vectors
 func CosineSimilarity(a, b []float32) float64 {
@@ -0,0 +1,173 @@
+// Package readiness holds back traffic until the services Noodexx depends on
+// answer. On boot Noodexx often starts before Ollama, and every request made
+// in the meantime would fail; a Gate probes each dependency with backoff until
+// it answers or a maximum wait runs out, and reports which ones never did.
+package readiness
+
+import (
+	"context"
+	"fmt"
+	"noodexx/internal/logging"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Check is a dependency probed before traffic is served
+type Check struct {
+	Name     string
+	Required bool // Startup fails if the dependency never answers
+	Probe    func(ctx context.Context) error
+}
+
+// Options configures a Gate
+type Options struct {
+	MaxWait        time.Duration // Longest to wait for all dependencies
+	InitialBackoff time.Duration // Delay before the first retry, doubled after each failure
+	MaxBackoff     time.Duration // Longest delay between retries
+	ProbeTimeout   time.Duration // Longest a single probe may take
+}
+
+// Result is the state of one dependency
+type Result struct {
+	Name     string `json:"name"`
+	Required bool   `json:"required"`
+	OK       bool   `json:"ok"`
+	Attempts int    `json:"attempts"`
+	WaitedMS int64  `json:"waited_ms"` // Time until it answered, or until the gate gave up on it
+	Error    string `json:"error,omitempty"`
+}
+
+// Gate waits for dependencies and reports whether traffic may be served
+type Gate struct {
+	checks []Check
+	opts   Options
+	logger *logging.Logger
+
+	mu      sync.Mutex
+	ready   bool
+	results []Result
+}
+
+// New creates a gate for the checks; it stays closed until Wait opens it
+func New(checks []Check, opts Options, logger *logging.Logger) *Gate {
+	if opts.MaxWait <= 0 {
+		opts.MaxWait = 2 * time.Minute
+	}
+	if opts.InitialBackoff <= 0 {
+		opts.InitialBackoff = 500 * time.Millisecond
+	}
+	if opts.MaxBackoff <= 0 {
+		opts.MaxBackoff = 10 * time.Second
+	}
+	if opts.MaxBackoff < opts.InitialBackoff {
+		opts.MaxBackoff = opts.InitialBackoff
+	}
+	if opts.ProbeTimeout <= 0 {
+		opts.ProbeTimeout = 5 * time.Second
+	}
+
+	results := make([]Result, len(checks))
+	for i, c := range checks {
+		results[i] = Result{Name: c.Name, Required: c.Required}
+	}
+	return &Gate{checks: checks, opts: opts, logger: logger, results: results}
+}
+
+// Wait probes every dependency at once, retrying failures with backoff until
+// they answer, MaxWait passes or ctx is cancelled, then logs a summary. The
+// gate opens unless a required dependency never answered, in which case Wait
+// returns an error naming them; optional ones that failed are only logged.
+// If ctx is cancelled first, Wait returns its error and the gate stays closed
+func (g *Gate) Wait(ctx context.Context) error {
+	waitCtx, cancel := context.WithTimeout(ctx, g.opts.MaxWait)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for i := range g.checks {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			g.probe(waitCtx, i)
+		}(i)
+	}
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	results := g.Status()
+	var missing []string
+	for _, r := range results {
+		switch {
+		case r.OK:
+			g.logger.Info("Dependency %s ready (%d attempts, %v)", r.Name, r.Attempts, time.Duration(r.WaitedMS)*time.Millisecond)
+		case r.Required:
+			g.logger.Error("Required dependency %s not ready after %d attempts: %s", r.Name, r.Attempts, r.Error)
+			missing = append(missing, r.Name)
+		default:
+			g.logger.Warn("Dependency %s not ready after %d attempts, starting without it: %s", r.Name, r.Attempts, r.Error)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("required dependencies not ready: %s", strings.Join(missing, ", "))
+	}
+
+	g.mu.Lock()
+	g.ready = true
+	g.mu.Unlock()
+	return nil
+}
+
+// probe retries one check until it passes or ctx ends
+func (g *Gate) probe(ctx context.Context, i int) {
+	check := g.checks[i]
+	start := time.Now()
+	backoff := g.opts.InitialBackoff
+	for {
+		probeCtx, cancel := context.WithTimeout(ctx, g.opts.ProbeTimeout)
+		err := check.Probe(probeCtx)
+		cancel()
+
+		g.mu.Lock()
+		r := &g.results[i]
+		r.Attempts++
+		r.WaitedMS = time.Since(start).Milliseconds()
+		r.OK = err == nil
+		r.Error = ""
+		if err != nil {
+			r.Error = err.Error()
+		}
+		g.mu.Unlock()
+		if err == nil {
+			return
+		}
+		g.logger.Debug("Dependency %s not ready (attempt %d), retrying in %v: %v", check.Name, r.Attempts, backoff, err)
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		}
+		backoff *= 2
+		if backoff > g.opts.MaxBackoff {
+			backoff = g.opts.MaxBackoff
+		}
+	}
+}
+
+// Ready reports whether Wait has opened the gate
+func (g *Gate) Ready() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.ready
+}
+
+// Status returns the state of every dependency so far
+func (g *Gate) Status() []Result {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([]Result(nil), g.results...)
+}
@@ -0,0 +1,120 @@
+package readiness
+
+import (
+	"context"
+	"errors"
+	"io"
+	"noodexx/internal/logging"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// upAfter returns a probe that fails until its n-th call
+func upAfter(n int32) (func(ctx context.Context) error, *atomic.Int32) {
+	calls := &atomic.Int32{}
+	return func(ctx context.Context) error {
+		if calls.Add(1) < n {
+			return errors.New("connection refused")
+		}
+		return nil
+	}, calls
+}
+
+func testOptions() Options {
+	return Options{
+		MaxWait:        200 * time.Millisecond,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     5 * time.Millisecond,
+		ProbeTimeout:   50 * time.Millisecond,
+	}
+}
+
+// TestWaitRetriesUntilReady tests that failing probes are retried until they
+// answer, and that the gate opens only then
+func TestWaitRetriesUntilReady(t *testing.T) {
+	database, _ := upAfter(1)
+	ollama, calls := upAfter(3)
+	gate := New([]Check{
+		{Name: "database", Required: true, Probe: database},
+		{Name: "ollama", Probe: ollama},
+	}, testOptions(), logging.NewLogger("test", logging.ERROR, io.Discard))
+
+	if gate.Ready() {
+		t.Fatal("Expected the gate closed before Wait")
+	}
+	if err := gate.Wait(context.Background()); err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+	if !gate.Ready() {
+		t.Error("Expected the gate open once every dependency answered")
+	}
+	if calls.Load() != 3 {
+		t.Errorf("Expected 3 probes of ollama, got %d", calls.Load())
+	}
+	status := gate.Status()
+	if !status[0].OK || status[0].Attempts != 1 || !status[1].OK || status[1].Attempts != 3 || status[1].Error != "" {
+		t.Errorf("Unexpected status %+v", status)
+	}
+}
+
+// TestWaitGivesUp tests that an optional dependency that never answers is
+// reported but does not hold the gate closed, and that a required one does
+func TestWaitGivesUp(t *testing.T) {
+	down := func(ctx context.Context) error { return errors.New("connection refused") }
+	logger := logging.NewLogger("test", logging.ERROR, io.Discard)
+
+	gate := New([]Check{{Name: "ollama", Probe: down}}, testOptions(), logger)
+	start := time.Now()
+	if err := gate.Wait(context.Background()); err != nil {
+		t.Fatalf("Expected an optional dependency not to fail startup, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected Wait to give up after MaxWait, took %v", elapsed)
+	}
+	if !gate.Ready() {
+		t.Error("Expected the gate open without the optional dependency")
+	}
+	if r := gate.Status()[0]; r.OK || r.Attempts < 2 || r.Error != "connection refused" {
+		t.Errorf("Unexpected status %+v", r)
+	}
+
+	gate = New([]Check{{Name: "database", Required: true, Probe: down}}, testOptions(), logger)
+	err := gate.Wait(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "database") {
+		t.Errorf("Expected an error naming the database, got %v", err)
+	}
+	if gate.Ready() {
+		t.Error("Expected the gate closed without a required dependency")
+	}
+}
+
+// TestProbeTimeout tests that a probe that hangs is cut off and retried
+func TestProbeTimeout(t *testing.T) {
+	var calls atomic.Int32
+	hangOnce := func(ctx context.Context) error {
+		if calls.Add(1) == 1 {
+			<-ctx.Done()
+			return ctx.Err()
+		}
+		return nil
+	}
+	gate := New([]Check{{Name: "ollama", Probe: hangOnce}}, testOptions(), logging.NewLogger("test", logging.ERROR, io.Discard))
+	if err := gate.Wait(context.Background()); err != nil || !gate.Status()[0].OK || calls.Load() != 2 {
+		t.Errorf("Expected the hung probe retried, got err %v, %d calls", err, calls.Load())
+	}
+}
+
+// TestWaitCancelled tests that stopping during the wait leaves the gate closed
+func TestWaitCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	down := func(context.Context) error {
+		cancel()
+		return errors.New("connection refused")
+	}
+	gate := New([]Check{{Name: "ollama", Probe: down}}, testOptions(), logging.NewLogger("test", logging.ERROR, io.Discard))
+	if err := gate.Wait(ctx); !errors.Is(err, context.Canceled) || gate.Ready() {
+		t.Errorf("Expected the cancellation and a closed gate, got %v", err)
+	}
+}
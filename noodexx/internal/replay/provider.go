@@ -0,0 +1,85 @@
+package replay
+
+import (
+	"context"
+	"io"
+	"noodexx/internal/auth"
+	"noodexx/internal/llm"
+	"time"
+)
+
+// provider wraps an llm.Provider and keeps the requests made through it that fail
+type provider struct {
+	next       llm.Provider
+	chatModel  string
+	embedModel string
+	log        *Log
+}
+
+// Wrap returns a provider that keeps the failed requests made through p.
+// The user is taken from the request context when present
+func Wrap(p llm.Provider, chatModel, embedModel string, log *Log) llm.Provider {
+	if p == nil || log == nil {
+		return p
+	}
+	return &provider{next: p, chatModel: chatModel, embedModel: embedModel, log: log}
+}
+
+// Embed implements llm.Provider
+func (p *provider) Embed(ctx context.Context, text string) ([]float32, error) {
+	start := time.Now()
+	vec, err := p.next.Embed(ctx, text)
+	if err != nil {
+		p.record(ctx, OperationEmbed, p.embedModel, start, Request{Input: text}, err)
+	}
+	return vec, err
+}
+
+// Stream implements llm.Provider
+func (p *provider) Stream(ctx context.Context, messages []llm.Message, w io.Writer) (string, error) {
+	return p.StreamWithOptions(ctx, messages, llm.GenerationOptions{}, w)
+}
+
+// StreamWithOptions implements llm.Provider
+func (p *provider) StreamWithOptions(ctx context.Context, messages []llm.Message, opts llm.GenerationOptions, w io.Writer) (string, error) {
+	start := time.Now()
+	response, err := p.next.StreamWithOptions(ctx, messages, opts, w)
+	if err != nil {
+		p.record(ctx, OperationChat, p.chatModel, start, Request{
+			Messages:    messages,
+			Temperature: opts.Temperature,
+			TopP:        opts.TopP,
+			MaxTokens:   opts.MaxTokens,
+		}, err)
+	}
+	return response, err
+}
+
+// Name implements llm.Provider
+func (p *provider) Name() string {
+	return p.next.Name()
+}
+
+// IsLocal implements llm.Provider
+func (p *provider) IsLocal() bool {
+	return p.next.IsLocal()
+}
+
+// record keeps a failed request. Requests abandoned by their caller, such as
+// a user closing the page, did not fail, and replays are not kept again
+func (p *provider) record(ctx context.Context, operation, model string, start time.Time, req Request, err error) {
+	if ctx.Err() != nil || ctx.Value(replayingKey{}) != nil {
+		return
+	}
+	userID, _ := auth.GetUserID(ctx)
+	p.log.Record(context.WithoutCancel(ctx), Envelope{
+		Time:      start,
+		UserID:    userID,
+		Provider:  p.next.Name(),
+		Model:     model,
+		Operation: operation,
+		Local:     p.next.IsLocal(),
+		Request:   req,
+		Error:     err.Error(),
+	})
+}
@@ -0,0 +1,257 @@
+// Package replay keeps provider requests that failed, so administrators can
+// run them again once the cause has been dealt with.
+//
+// A failed request is kept as an envelope: what was sent, to which provider
+// and model, and the error that came back. Secrets such as bearer tokens and
+// API keys, and anything matching the configured redaction patterns, are
+// replaced before the envelope is saved, so a replay sends the redacted text.
+// Replays run against the current provider configuration, either as a dry
+// run that only resolves the provider and model, or live.
+package replay
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"noodexx/internal/llm"
+	"noodexx/internal/logging"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Operation names recorded in envelopes
+const (
+	OperationEmbed = "embed"
+	OperationChat  = "chat"
+)
+
+// Outcomes of a replay
+const (
+	OutcomeDryRun         = "dry_run"              // Nothing was sent
+	OutcomeSucceeded      = "succeeded"            // The request no longer fails
+	OutcomeSameError      = "same_error"           // It fails as it did
+	OutcomeDifferentError = "different_error"      // It still fails, with another error
+	OutcomeUnavailable    = "provider_unavailable" // No provider is configured for it
+)
+
+// Redacted replaces redacted text
+const Redacted = "[REDACTED]"
+
+// secretPatterns are redacted whatever the configured patterns: credentials
+// that end up in prompts or in providers' error messages
+var secretPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)\bbearer\s+[A-Za-z0-9._~+/=-]+`),
+	regexp.MustCompile(`\bsk-[A-Za-z0-9_-]{8,}`),
+}
+
+// Request is what was sent to the provider
+type Request struct {
+	Messages    []llm.Message `json:"messages,omitempty"`
+	Input       string        `json:"input,omitempty"` // Text embedded, for embedding requests
+	Temperature *float64      `json:"temperature,omitempty"`
+	TopP        *float64      `json:"top_p,omitempty"`
+	MaxTokens   int           `json:"max_tokens,omitempty"`
+}
+
+// options returns the generation options the request was made with
+func (r Request) options() llm.GenerationOptions {
+	return llm.GenerationOptions{Temperature: r.Temperature, TopP: r.TopP, MaxTokens: r.MaxTokens}
+}
+
+// Envelope is a failed provider request
+type Envelope struct {
+	ID        int64
+	Time      time.Time
+	UserID    int64 // 0 for requests made outside a user's request, such as scheduled jobs
+	Provider  string
+	Model     string
+	Operation string
+	Local     bool // Made through the local provider, and replayed through it
+	Request   Request
+	Error     string
+}
+
+// Store persists envelopes
+type Store interface {
+	SaveFailedRequest(ctx context.Context, e Envelope) (int64, error)
+}
+
+// Providers resolves the providers replays run against
+type Providers interface {
+	GetLocalProvider() llm.Provider
+	GetCloudProvider() llm.Provider
+	GetModels(local bool) (chatModel, embedModel string)
+}
+
+// Result is the outcome of replaying an envelope
+type Result struct {
+	Live       bool
+	Provider   string // Provider the request was or would be sent to
+	Model      string
+	Response   string // Chat response, for live replays that succeeded
+	Dimensions int    // Size of the returned embedding, for live replays that succeeded
+	Error      string
+	LatencyMS  int64
+	Outcome    string
+	Diff       []string // Original error against the replay's error or response, a line each prefixed "  ", "- " or "+ "
+}
+
+// Log redacts failed requests and saves them
+type Log struct {
+	store    Store
+	patterns []*regexp.Regexp
+	logger   *logging.Logger
+}
+
+// New creates a log saving envelopes to store, with text matching any of
+// redactPatterns (regular expressions) replaced before saving
+func New(store Store, redactPatterns []string, logger *logging.Logger) (*Log, error) {
+	patterns := append([]*regexp.Regexp{}, secretPatterns...)
+	for _, pattern := range redactPatterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redaction pattern %q: %w", pattern, err)
+		}
+		patterns = append(patterns, re)
+	}
+	return &Log{store: store, patterns: patterns, logger: logger}, nil
+}
+
+// Redact replaces the text matching the redaction patterns
+func (l *Log) Redact(text string) string {
+	for _, re := range l.patterns {
+		text = re.ReplaceAllString(text, Redacted)
+	}
+	return text
+}
+
+// Record redacts e and saves it. Failures are logged, and do not change the
+// error of the request the envelope describes
+func (l *Log) Record(ctx context.Context, e Envelope) error {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	e.Time = e.Time.UTC().Truncate(time.Microsecond)
+
+	messages := make([]llm.Message, len(e.Request.Messages))
+	for i, msg := range e.Request.Messages {
+		messages[i] = llm.Message{Role: msg.Role, Content: l.Redact(msg.Content)}
+	}
+	e.Request.Messages = messages
+	e.Request.Input = l.Redact(e.Request.Input)
+	e.Error = l.Redact(e.Error)
+
+	if _, err := l.store.SaveFailedRequest(ctx, e); err != nil {
+		l.logger.WithFields(map[string]interface{}{
+			"provider":  e.Provider,
+			"operation": e.Operation,
+			"user_id":   e.UserID,
+			"error":     err.Error(),
+		}).Error("failed to keep failed provider request")
+		return err
+	}
+	return nil
+}
+
+// replayingKey marks the context of a replay, whose failures are not kept again
+type replayingKey struct{}
+
+// Replay runs e again against the provider currently configured for it. A dry
+// run only resolves the provider and model the request would be sent to
+func (l *Log) Replay(ctx context.Context, providers Providers, e Envelope, live bool) Result {
+	p := providers.GetCloudProvider()
+	if e.Local {
+		p = providers.GetLocalProvider()
+	}
+	chatModel, embedModel := providers.GetModels(e.Local)
+
+	result := Result{Live: live, Model: chatModel}
+	if e.Operation == OperationEmbed {
+		result.Model = embedModel
+	}
+	if p == nil {
+		result.Outcome = OutcomeUnavailable
+		return result
+	}
+	result.Provider = p.Name()
+	if !live {
+		result.Outcome = OutcomeDryRun
+		return result
+	}
+
+	ctx = context.WithValue(ctx, replayingKey{}, true)
+	start := time.Now()
+	var replayed string
+	var err error
+	if e.Operation == OperationEmbed {
+		var vec []float32
+		vec, err = p.Embed(ctx, e.Request.Input)
+		result.Dimensions = len(vec)
+		replayed = fmt.Sprintf("embedding with %d dimensions", len(vec))
+	} else {
+		result.Response, err = p.StreamWithOptions(ctx, e.Request.Messages, e.Request.options(), io.Discard)
+		replayed = result.Response
+	}
+	result.LatencyMS = time.Since(start).Milliseconds()
+
+	if err != nil {
+		result.Error = l.Redact(err.Error())
+		replayed = result.Error
+	}
+	switch {
+	case err == nil:
+		result.Outcome = OutcomeSucceeded
+	case result.Error == e.Error:
+		result.Outcome = OutcomeSameError
+	default:
+		result.Outcome = OutcomeDifferentError
+	}
+	result.Diff = Diff(e.Error, replayed)
+	return result
+}
+
+// Diff compares two texts line by line, returning every line prefixed with
+// "  " when both have it, "- " when only a has it and "+ " when only b has it
+func Diff(a, b string) []string {
+	x, y := strings.Split(a, "\n"), strings.Split(b, "\n")
+
+	// lcs[i][j] is the length of the longest common subsequence of x[i:] and y[j:]
+	lcs := make([][]int, len(x)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(y)+1)
+	}
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			if x[i] == y[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var diff []string
+	i, j := 0, 0
+	for i < len(x) && j < len(y) {
+		switch {
+		case x[i] == y[j]:
+			diff = append(diff, "  "+x[i])
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			diff = append(diff, "- "+x[i])
+			i++
+		default:
+			diff = append(diff, "+ "+y[j])
+			j++
+		}
+	}
+	for ; i < len(x); i++ {
+		diff = append(diff, "- "+x[i])
+	}
+	for ; j < len(y); j++ {
+		diff = append(diff, "+ "+y[j])
+	}
+	return diff
+}
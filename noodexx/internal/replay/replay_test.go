@@ -0,0 +1,189 @@
+package replay
+
+import (
+	"context"
+	"errors"
+	"io"
+	"noodexx/internal/auth"
+	"noodexx/internal/llm"
+	"noodexx/internal/logging"
+	"reflect"
+	"testing"
+)
+
+// fakeProvider fails with err when set, and streams a fixed answer otherwise
+type fakeProvider struct {
+	local bool
+	err   error
+	calls int
+}
+
+func (f *fakeProvider) Embed(ctx context.Context, text string) ([]float32, error) {
+	f.calls++
+	if f.err != nil {
+		return nil, f.err
+	}
+	return []float32{1, 2, 3}, nil
+}
+
+func (f *fakeProvider) Stream(ctx context.Context, messages []llm.Message, w io.Writer) (string, error) {
+	return f.StreamWithOptions(ctx, messages, llm.GenerationOptions{}, w)
+}
+
+func (f *fakeProvider) StreamWithOptions(ctx context.Context, messages []llm.Message, opts llm.GenerationOptions, w io.Writer) (string, error) {
+	f.calls++
+	if f.err != nil {
+		return "", f.err
+	}
+	return "The answer", nil
+}
+
+func (f *fakeProvider) Name() string  { return "fake" }
+func (f *fakeProvider) IsLocal() bool { return f.local }
+
+// memoryStore keeps saved envelopes
+type memoryStore struct {
+	envelopes []Envelope
+}
+
+func (m *memoryStore) SaveFailedRequest(ctx context.Context, e Envelope) (int64, error) {
+	e.ID = int64(len(m.envelopes) + 1)
+	m.envelopes = append(m.envelopes, e)
+	return e.ID, nil
+}
+
+// fakeProviders returns fixed providers
+type fakeProviders struct {
+	local, cloud llm.Provider
+}
+
+func (f *fakeProviders) GetLocalProvider() llm.Provider { return f.local }
+func (f *fakeProviders) GetCloudProvider() llm.Provider { return f.cloud }
+func (f *fakeProviders) GetModels(local bool) (string, string) {
+	if local {
+		return "llama3", "nomic-embed-text"
+	}
+	return "gpt-4o", "text-embedding-3-small"
+}
+
+func testLog(t *testing.T, patterns ...string) (*Log, *memoryStore) {
+	t.Helper()
+	store := &memoryStore{}
+	log, err := New(store, patterns, logging.NewLogger("test", logging.ERROR, io.Discard))
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	return log, store
+}
+
+func TestNewRejectsInvalidPattern(t *testing.T) {
+	if _, err := New(&memoryStore{}, []string{"("}, logging.NewLogger("test", logging.ERROR, io.Discard)); err == nil {
+		t.Error("Expected an error for an invalid redaction pattern")
+	}
+}
+
+func TestWrapKeepsFailedRequests(t *testing.T) {
+	log, store := testLog(t, `\b\d{3}-\d{2}-\d{4}\b`)
+	next := &fakeProvider{err: errors.New("401 invalid key sk-abcdefghijkl")}
+	p := Wrap(next, "gpt-4o", "text-embedding-3-small", log)
+
+	ctx := context.WithValue(context.Background(), auth.UserIDKey, int64(7))
+	temp := 0.2
+	messages := []llm.Message{{Role: "user", Content: "My SSN is 123-45-6789"}}
+	if _, err := p.StreamWithOptions(ctx, messages, llm.GenerationOptions{Temperature: &temp}, io.Discard); err == nil {
+		t.Fatal("Expected the provider's error")
+	}
+	if len(store.envelopes) != 1 {
+		t.Fatalf("Expected the failed request kept, got %d", len(store.envelopes))
+	}
+	e := store.envelopes[0]
+	if e.UserID != 7 || e.Provider != "fake" || e.Model != "gpt-4o" || e.Operation != OperationChat || e.Local {
+		t.Errorf("Unexpected envelope metadata: %+v", e)
+	}
+	if got := e.Request.Messages[0].Content; got != "My SSN is [REDACTED]" {
+		t.Errorf("Expected the configured pattern redacted, got %q", got)
+	}
+	if e.Error != "401 invalid key [REDACTED]" {
+		t.Errorf("Expected the key redacted from the error, got %q", e.Error)
+	}
+	if messages[0].Content != "My SSN is 123-45-6789" {
+		t.Error("Expected the caller's messages left unchanged")
+	}
+	if e.Request.Temperature == nil || *e.Request.Temperature != 0.2 {
+		t.Errorf("Expected the generation options kept, got %+v", e.Request)
+	}
+
+	// Successful requests and requests abandoned by their caller are not kept
+	next.err = nil
+	p.Embed(ctx, "text")
+	next.err = context.Canceled
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	p.Embed(cancelled, "text")
+	if len(store.envelopes) != 1 {
+		t.Errorf("Expected only the failed request kept, got %d", len(store.envelopes))
+	}
+}
+
+func TestReplay(t *testing.T) {
+	log, store := testLog(t)
+	cloud := &fakeProvider{err: errors.New("429 rate limited")}
+	providers := &fakeProviders{local: &fakeProvider{local: true}, cloud: Wrap(cloud, "gpt-4o", "", log)}
+	e := Envelope{
+		Operation: OperationChat,
+		Request:   Request{Messages: []llm.Message{{Role: "user", Content: "Hi"}}},
+		Error:     "429 rate limited",
+	}
+
+	result := log.Replay(context.Background(), providers, e, false)
+	if result.Outcome != OutcomeDryRun || result.Provider != "fake" || result.Model != "gpt-4o" || cloud.calls != 0 {
+		t.Errorf("Expected a dry run to resolve the provider without calling it, got %+v (%d calls)", result, cloud.calls)
+	}
+
+	result = log.Replay(context.Background(), providers, e, true)
+	if result.Outcome != OutcomeSameError || result.Error != "429 rate limited" || cloud.calls != 1 {
+		t.Errorf("Expected the same error replayed, got %+v", result)
+	}
+	if len(store.envelopes) != 0 {
+		t.Errorf("Expected a failed replay not to be kept again, got %d", len(store.envelopes))
+	}
+
+	cloud.err = errors.New("500 internal error")
+	if result = log.Replay(context.Background(), providers, e, true); result.Outcome != OutcomeDifferentError {
+		t.Errorf("Expected a different error, got %+v", result)
+	}
+	if want := []string{"- 429 rate limited", "+ 500 internal error"}; !reflect.DeepEqual(result.Diff, want) {
+		t.Errorf("Expected diff %v, got %v", want, result.Diff)
+	}
+
+	cloud.err = nil
+	if result = log.Replay(context.Background(), providers, e, true); result.Outcome != OutcomeSucceeded || result.Response != "The answer" {
+		t.Errorf("Expected the replay to succeed, got %+v", result)
+	}
+
+	e.Local, e.Operation, e.Request = true, OperationEmbed, Request{Input: "text"}
+	if result = log.Replay(context.Background(), providers, e, true); result.Outcome != OutcomeSucceeded || result.Dimensions != 3 || result.Model != "nomic-embed-text" {
+		t.Errorf("Expected the embedding replayed through the local provider, got %+v", result)
+	}
+
+	providers.local = nil
+	if result = log.Replay(context.Background(), providers, e, true); result.Outcome != OutcomeUnavailable {
+		t.Errorf("Expected no provider to replay with, got %+v", result)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want []string
+	}{
+		{"same", "same", []string{"  same"}},
+		{"a\nb\nc", "a\nc\nd", []string{"  a", "- b", "  c", "+ d"}},
+		{"", "new", []string{"- ", "+ new"}},
+	}
+	for _, tt := range tests {
+		if got := Diff(tt.a, tt.b); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Diff(%q, %q) = %q, want %q", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
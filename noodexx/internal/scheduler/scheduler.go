@@ -0,0 +1,501 @@
+// Package scheduler runs Noodexx's background jobs. Each job has a cron or
+// interval schedule and runs with a random delay (jitter) so jobs sharing a
+// schedule do not start together. A job never overlaps itself: a run that comes
+// due while the previous one is still going is skipped. Pauses and the last and
+// next run times are saved, so a job that came due while Noodexx was stopped
+// runs soon after it starts again.
+//
+// With a Locker, several instances sharing a database can run the same jobs:
+// each run takes the job's lock, the job's saved state is read first so a run
+// another instance already made, or a pause it saved, is respected.
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"noodexx/internal/logging"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrNotFound is returned for a job name that was never added
+	ErrNotFound = errors.New("job not found")
+	// ErrRunning is returned when a job is asked to run while it is running
+	ErrRunning = errors.New("job is already running")
+
+	// errLocked is returned by claim when another instance holds a job's lock
+	errLocked = errors.New("job is running on another instance")
+)
+
+// Job is a unit of background work
+type Job struct {
+	Name        string
+	Description string
+	Schedule    string        // Cron expression or "@every <duration>", see Parse
+	Jitter      time.Duration // Longest random delay before a scheduled run; Options.Jitter when zero
+	Local       bool          // Runs on every instance rather than on one at a time when there is a Locker
+	Run         func(ctx context.Context) error
+}
+
+// State is what is saved about a job between restarts
+type State struct {
+	Name         string
+	Schedule     string
+	Paused       bool
+	LastRun      time.Time // Zero if the job never ran
+	LastDuration time.Duration
+	LastError    string
+	NextRun      time.Time
+	Runs         int64
+	Failures     int64
+}
+
+// Status is a snapshot of a job for the admin API
+type Status struct {
+	State
+	Description string
+	Running     bool
+}
+
+// Store persists job state
+type Store interface {
+	GetJobStates(ctx context.Context) ([]State, error)
+	SaveJobState(ctx context.Context, state State) error
+}
+
+// Locker keeps a job from running on two instances at once
+type Locker interface {
+	// TryLock takes the named lock if it is free, returning a context that is
+	// cancelled if the lock is lost and a function that releases it
+	TryLock(ctx context.Context, name string) (context.Context, func(), bool)
+}
+
+// Options configures a Scheduler
+type Options struct {
+	Store  Store         // Optional; without it pauses and run times are not kept across restarts
+	Jitter time.Duration // Longest random delay before a scheduled run of jobs without their own
+	Locker Locker        // Optional; set when other instances run the same jobs against the same Store
+	OnRun  func(Status)  // Optional; called with a job's status when a run starts and when it finishes
+}
+
+// Scheduler runs jobs on their schedules
+type Scheduler struct {
+	opts   Options
+	logger *logging.Logger
+	now    func() time.Time
+	jitter func(max time.Duration) time.Duration
+
+	mu      sync.Mutex
+	jobs    map[string]*entry
+	ctx     context.Context
+	started bool
+	wg      sync.WaitGroup
+}
+
+// entry is a job and its state; fields after mu are guarded by Scheduler.mu
+type entry struct {
+	job      Job
+	schedule Schedule
+	wake     chan struct{} // Recompute the next run after a pause or resume
+
+	state   State
+	running bool
+}
+
+// New creates a scheduler; add jobs, then call Start
+func New(opts Options, logger *logging.Logger) *Scheduler {
+	return &Scheduler{
+		opts:   opts,
+		logger: logger,
+		now:    time.Now,
+		jitter: func(max time.Duration) time.Duration {
+			if max <= 0 {
+				return 0
+			}
+			return time.Duration(rand.Int63n(int64(max)))
+		},
+		jobs: make(map[string]*entry),
+	}
+}
+
+// Add registers a job; it must be called before Start
+func (s *Scheduler) Add(job Job) error {
+	if job.Name == "" || job.Run == nil {
+		return fmt.Errorf("job needs a name and a run function")
+	}
+	schedule, err := Parse(job.Schedule)
+	if err != nil {
+		return fmt.Errorf("job %s: %w", job.Name, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.started {
+		return fmt.Errorf("job %s: jobs must be added before the scheduler starts", job.Name)
+	}
+	if _, ok := s.jobs[job.Name]; ok {
+		return fmt.Errorf("job %s is already added", job.Name)
+	}
+	s.jobs[job.Name] = &entry{
+		job:      job,
+		schedule: schedule,
+		wake:     make(chan struct{}, 1),
+		state:    State{Name: job.Name, Schedule: job.Schedule},
+	}
+	return nil
+}
+
+// Start restores saved job state and runs every job on its schedule until ctx
+// is cancelled
+func (s *Scheduler) Start(ctx context.Context) {
+	saved := s.loadStates(ctx)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ctx = ctx
+	s.started = true
+
+	now := s.now()
+	for name, e := range s.jobs {
+		e.state.NextRun = e.schedule.Next(now)
+		if state, ok := saved[name]; ok {
+			e.applySaved(state)
+			// A run missed while stopped happens now; a changed schedule starts afresh
+			if state.Schedule == e.job.Schedule && !state.NextRun.IsZero() && state.NextRun.Before(e.state.NextRun) {
+				e.state.NextRun = state.NextRun
+			}
+		}
+		s.wg.Add(1)
+		go s.loop(ctx, e)
+	}
+	s.logger.Info("Scheduler started with %d jobs", len(s.jobs))
+}
+
+// Wait blocks until every job loop and run has finished after ctx is cancelled
+func (s *Scheduler) Wait() {
+	s.wg.Wait()
+}
+
+// loop waits for each scheduled run of a job
+func (s *Scheduler) loop(ctx context.Context, e *entry) {
+	defer s.wg.Done()
+	for {
+		s.mu.Lock()
+		next := e.state.NextRun
+		s.mu.Unlock()
+
+		// A schedule that never matches again leaves the job to run-now
+		var timer *time.Timer
+		var due <-chan time.Time
+		if !next.IsZero() {
+			delay := next.Sub(s.now()) + s.jitter(s.jitterFor(e.job))
+			timer = time.NewTimer(max(delay, 0))
+			due = timer.C
+		}
+
+		select {
+		case <-ctx.Done():
+			stopTimer(timer)
+			return
+		case <-e.wake:
+			stopTimer(timer)
+			continue
+		case <-due:
+		}
+
+		s.mu.Lock()
+		e.state.NextRun = e.schedule.Next(s.now())
+		s.mu.Unlock()
+
+		if err := s.run(ctx, e, next); errors.Is(err, ErrRunning) {
+			s.logger.Warn("Job %s is still running, skipping its scheduled run", e.job.Name)
+		}
+	}
+}
+
+// stopTimer stops a timer that may be nil
+func stopTimer(t *time.Timer) {
+	if t != nil {
+		t.Stop()
+	}
+}
+
+// claim marks a job running and, when it runs on one instance at a time, takes
+// its lock; the returned context is the one to run the job with
+func (s *Scheduler) claim(ctx context.Context, e *entry) (context.Context, func(), error) {
+	s.mu.Lock()
+	if e.running {
+		s.mu.Unlock()
+		return nil, nil, ErrRunning
+	}
+	e.running = true
+	s.mu.Unlock()
+
+	if s.opts.Locker == nil || e.job.Local {
+		return ctx, func() {}, nil
+	}
+	lockCtx, unlock, ok := s.opts.Locker.TryLock(ctx, "job:"+e.job.Name)
+	if !ok {
+		s.mu.Lock()
+		e.running = false
+		s.mu.Unlock()
+		return nil, nil, errLocked
+	}
+	return lockCtx, unlock, nil
+}
+
+// run runs a job once and records the outcome. A scheduled run passes the time
+// it was due, and is skipped when the job is paused or, with a Locker, when
+// another instance already made it; a run on request passes the zero time
+func (s *Scheduler) run(ctx context.Context, e *entry, due time.Time) error {
+	runCtx, unlock, err := s.claim(ctx, e)
+	if errors.Is(err, errLocked) {
+		// Another instance is making this run
+		s.logger.Debug("Job %s is running on another instance", e.job.Name)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return s.runClaimed(runCtx, e, due)
+}
+
+// runClaimed runs a job claimed by claim
+func (s *Scheduler) runClaimed(ctx context.Context, e *entry, due time.Time) error {
+	if s.opts.Locker != nil {
+		s.syncState(ctx, e)
+	}
+	if !due.IsZero() {
+		s.mu.Lock()
+		paused := e.state.Paused
+		done := s.opts.Locker != nil && !e.job.Local && !e.state.LastRun.Before(due)
+		if paused || done {
+			e.running = false
+		}
+		s.mu.Unlock()
+		if paused {
+			s.saveState(ctx, e)
+			return nil
+		}
+		if done {
+			s.logger.Debug("Job %s already ran on another instance", e.job.Name)
+			return nil
+		}
+	}
+
+	s.notify(e)
+	start := s.now()
+	err := safeRun(ctx, e.job.Run)
+	duration := s.now().Sub(start)
+
+	s.mu.Lock()
+	e.running = false
+	e.state.LastRun = start
+	e.state.LastDuration = duration
+	e.state.Runs++
+	e.state.LastError = ""
+	if err != nil {
+		e.state.LastError = err.Error()
+		e.state.Failures++
+	}
+	s.mu.Unlock()
+
+	if err != nil {
+		s.logger.Error("Job %s failed after %v: %v", e.job.Name, duration.Round(time.Millisecond), err)
+	} else {
+		s.logger.Debug("Job %s finished in %v", e.job.Name, duration.Round(time.Millisecond))
+	}
+	s.saveState(ctx, e)
+	s.notify(e)
+	return nil
+}
+
+// notify passes a job's status to Options.OnRun, if set
+func (s *Scheduler) notify(e *entry) {
+	if s.opts.OnRun == nil {
+		return
+	}
+	s.mu.Lock()
+	status := Status{State: e.state, Description: e.job.Description, Running: e.running}
+	s.mu.Unlock()
+	s.opts.OnRun(status)
+}
+
+// safeRun turns a panicking job into a failed run
+func safeRun(ctx context.Context, run func(ctx context.Context) error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return run(ctx)
+}
+
+// RunNow starts a job immediately, even when it is paused, without changing
+// its next scheduled run
+func (s *Scheduler) RunNow(name string) error {
+	s.mu.Lock()
+	e, ok := s.jobs[name]
+	if !ok {
+		s.mu.Unlock()
+		return ErrNotFound
+	}
+	ctx := s.ctx
+	s.mu.Unlock()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	// Claim before returning so a job running here or on another instance is reported
+	runCtx, unlock, err := s.claim(ctx, e)
+	if errors.Is(err, errLocked) {
+		return ErrRunning
+	}
+	if err != nil {
+		return err
+	}
+
+	s.logger.Info("Running job %s on request", name)
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer unlock()
+		s.runClaimed(runCtx, e, time.Time{})
+	}()
+	return nil
+}
+
+// SetPaused pauses or resumes a job's scheduled runs
+func (s *Scheduler) SetPaused(ctx context.Context, name string, paused bool) error {
+	s.mu.Lock()
+	e, ok := s.jobs[name]
+	s.mu.Unlock()
+	if !ok {
+		return ErrNotFound
+	}
+	// Keep the run history other instances saved
+	if s.opts.Locker != nil {
+		s.syncState(ctx, e)
+	}
+
+	s.mu.Lock()
+	e.state.Paused = paused
+	if !paused {
+		e.state.NextRun = e.schedule.Next(s.now())
+	}
+	s.mu.Unlock()
+
+	select {
+	case e.wake <- struct{}{}:
+	default:
+	}
+	s.saveState(ctx, e)
+	return nil
+}
+
+// Refresh reads job state saved by other instances; it does nothing without a
+// Locker, as only this instance saves then
+func (s *Scheduler) Refresh(ctx context.Context) {
+	if s.opts.Locker == nil || s.opts.Store == nil {
+		return
+	}
+	saved := s.loadStates(ctx)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for name, e := range s.jobs {
+		if state, ok := saved[name]; ok && !e.running {
+			e.applySaved(state)
+		}
+	}
+}
+
+// syncState reads a job's state saved by other instances before it runs
+func (s *Scheduler) syncState(ctx context.Context, e *entry) {
+	if s.opts.Store == nil {
+		return
+	}
+	state, ok := s.loadStates(ctx)[e.job.Name]
+	if !ok {
+		return
+	}
+	s.mu.Lock()
+	e.applySaved(state)
+	s.mu.Unlock()
+}
+
+// applySaved takes the pause and run history from saved state; the caller holds
+// Scheduler.mu. The next run is kept, as every instance computes its own
+func (e *entry) applySaved(state State) {
+	e.state.Paused = state.Paused
+	e.state.LastRun = state.LastRun
+	e.state.LastDuration = state.LastDuration
+	e.state.LastError = state.LastError
+	e.state.Runs = state.Runs
+	e.state.Failures = state.Failures
+}
+
+// Jobs returns the status of every job, ordered by name
+func (s *Scheduler) Jobs() []Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	statuses := make([]Status, 0, len(s.jobs))
+	for _, e := range s.jobs {
+		statuses = append(statuses, Status{
+			State:       e.state,
+			Description: e.job.Description,
+			Running:     e.running,
+		})
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Name < statuses[j].Name
+	})
+	return statuses
+}
+
+// jitterFor returns the longest random delay before a job's scheduled runs
+func (s *Scheduler) jitterFor(job Job) time.Duration {
+	if job.Jitter > 0 {
+		return job.Jitter
+	}
+	return s.opts.Jitter
+}
+
+// loadStates reads saved job state, keyed by job name
+func (s *Scheduler) loadStates(ctx context.Context) map[string]State {
+	saved := make(map[string]State)
+	if s.opts.Store == nil {
+		return saved
+	}
+	states, err := s.opts.Store.GetJobStates(ctx)
+	if err != nil {
+		s.logger.Warn("Failed to load job state, starting with fresh schedules: %v", err)
+		return saved
+	}
+	for _, state := range states {
+		saved[state.Name] = state
+	}
+	return saved
+}
+
+// saveState persists a job's state; failures are logged and the job carries on
+func (s *Scheduler) saveState(ctx context.Context, e *entry) {
+	if s.opts.Store == nil {
+		return
+	}
+	s.mu.Lock()
+	state := e.state
+	s.mu.Unlock()
+
+	if ctx.Err() != nil {
+		ctx = context.Background()
+	}
+	if err := s.opts.Store.SaveJobState(ctx, state); err != nil {
+		s.logger.Warn("Failed to save state of job %s: %v", e.job.Name, err)
+	}
+}
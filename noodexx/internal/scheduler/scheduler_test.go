@@ -0,0 +1,336 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"io"
+	"noodexx/internal/logging"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestParseNext tests the next run time of cron expressions and intervals
+func TestParseNext(t *testing.T) {
+	// Wednesday 2024-01-10 10:17
+	from := time.Date(2024, 1, 10, 10, 17, 30, 0, time.UTC)
+
+	tests := []struct {
+		spec string
+		want time.Time
+	}{
+		{"*/15 * * * *", time.Date(2024, 1, 10, 10, 30, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2024, 1, 10, 11, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2024, 1, 11, 0, 0, 0, 0, time.UTC)},
+		{"30 2 * * MON-FRI", time.Date(2024, 1, 11, 2, 30, 0, 0, time.UTC)},
+		{"0 9 * * 7", time.Date(2024, 1, 14, 9, 0, 0, 0, time.UTC)},
+		{"0 0 1 FEB *", time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{"5/20 10 * * *", time.Date(2024, 1, 10, 10, 25, 0, 0, time.UTC)},
+		// Both day fields restricted: either one matches
+		{"0 0 13 * FRI", time.Date(2024, 1, 12, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"@every 90m", time.Date(2024, 1, 10, 11, 47, 30, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		schedule, err := Parse(tt.spec)
+		if err != nil {
+			t.Errorf("Parse(%q) failed: %v", tt.spec, err)
+			continue
+		}
+		if got := schedule.Next(from); !got.Equal(tt.want) {
+			t.Errorf("Parse(%q).Next = %v, want %v", tt.spec, got, tt.want)
+		}
+	}
+
+	// An expression that never matches has no next run
+	schedule, err := Parse("0 0 30 2 *")
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if next := schedule.Next(from); !next.IsZero() {
+		t.Errorf("Expected no run on February 30th, got %v", next)
+	}
+
+	for _, spec := range []string{"", "* * * *", "60 * * * *", "* * * * MONDAY", "*/0 * * * *", "5-1 * * * *", "@every 10ms", "@every soon"} {
+		if _, err := Parse(spec); err == nil {
+			t.Errorf("Expected Parse(%q) to fail", spec)
+		}
+	}
+}
+
+// memStore keeps job state in memory
+type memStore struct {
+	mu     sync.Mutex
+	states map[string]State
+}
+
+func (m *memStore) GetJobStates(ctx context.Context) ([]State, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var states []State
+	for _, state := range m.states {
+		states = append(states, state)
+	}
+	return states, nil
+}
+
+func (m *memStore) SaveJobState(ctx context.Context, state State) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.states[state.Name] = state
+	return nil
+}
+
+func (m *memStore) get(name string) State {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.states[name]
+}
+
+func newTestScheduler(store Store) *Scheduler {
+	return New(Options{Store: store}, logging.NewLogger("test", logging.ERROR, io.Discard))
+}
+
+// waitFor polls until cond holds or a second passes
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// TestRunNowAndPause tests on-demand runs, overlap protection, failures and pausing
+func TestRunNowAndPause(t *testing.T) {
+	store := &memStore{states: map[string]State{}}
+	s := newTestScheduler(store)
+
+	release := make(chan struct{})
+	var mu sync.Mutex
+	runs := 0
+	var reported []Status
+	s.opts.OnRun = func(status Status) {
+		mu.Lock()
+		reported = append(reported, status)
+		mu.Unlock()
+	}
+	err := s.Add(Job{Name: "cleanup", Schedule: "@daily", Run: func(ctx context.Context) error {
+		mu.Lock()
+		runs++
+		n := runs
+		mu.Unlock()
+		if n == 1 {
+			<-release
+			return nil
+		}
+		return errors.New("disk full")
+	}})
+	if err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if err := s.Add(Job{Name: "cleanup", Schedule: "@daily", Run: func(ctx context.Context) error { return nil }}); err == nil {
+		t.Error("Expected adding a job twice to fail")
+	}
+	if err := s.Add(Job{Name: "broken", Schedule: "every day", Run: func(ctx context.Context) error { return nil }}); err == nil {
+		t.Error("Expected an invalid schedule to fail")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer func() {
+		cancel()
+		s.Wait()
+	}()
+	s.Start(ctx)
+
+	if err := s.RunNow("missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+	if err := s.RunNow("cleanup"); err != nil {
+		t.Fatalf("RunNow failed: %v", err)
+	}
+	waitFor(t, "the job to start", func() bool { return s.Jobs()[0].Running })
+
+	// A job never overlaps itself
+	if err := s.RunNow("cleanup"); !errors.Is(err, ErrRunning) {
+		t.Errorf("Expected ErrRunning while the job runs, got %v", err)
+	}
+	close(release)
+	waitFor(t, "the first run to be saved", func() bool { return store.get("cleanup").Runs == 1 })
+
+	// Failures are recorded and saved
+	if err := s.RunNow("cleanup"); err != nil {
+		t.Fatalf("RunNow failed: %v", err)
+	}
+	waitFor(t, "the second run to be saved", func() bool { return store.get("cleanup").Runs == 2 })
+	state := store.get("cleanup")
+	if state.LastError != "disk full" || state.Failures != 1 || state.LastRun.IsZero() || state.Schedule != "@daily" {
+		t.Errorf("Expected the failed run to be saved, got %+v", state)
+	}
+
+	// Each run is reported as it starts and finishes
+	waitFor(t, "both runs to be reported", func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(reported) == 4
+	})
+	if !reported[0].Running || reported[1].Running || reported[1].Runs != 1 || !reported[2].Running || reported[3].LastError != "disk full" {
+		t.Errorf("Expected each run reported starting and finishing, got %+v", reported)
+	}
+
+	if err := s.SetPaused(ctx, "cleanup", true); err != nil {
+		t.Fatalf("SetPaused failed: %v", err)
+	}
+	if !store.get("cleanup").Paused || !s.Jobs()[0].Paused {
+		t.Error("Expected the pause to be saved")
+	}
+}
+
+// TestRestoreState tests that pauses and missed runs survive a restart
+func TestRestoreState(t *testing.T) {
+	lastWeek := time.Now().Add(-7 * 24 * time.Hour)
+	store := &memStore{states: map[string]State{
+		"missed":  {Name: "missed", Schedule: "@daily", NextRun: lastWeek, Runs: 4},
+		"paused":  {Name: "paused", Schedule: "@every 1s", Paused: true},
+		"changed": {Name: "changed", Schedule: "@hourly", NextRun: lastWeek},
+	}}
+	s := newTestScheduler(store)
+
+	ran := make(chan string, 10)
+	for _, job := range []Job{
+		{Name: "missed", Schedule: "@daily"},
+		{Name: "paused", Schedule: "@every 1s"},
+		{Name: "changed", Schedule: "@daily"},
+	} {
+		name := job.Name
+		job.Run = func(ctx context.Context) error {
+			ran <- name
+			return nil
+		}
+		if err := s.Add(job); err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.Start(ctx)
+
+	// The run missed while stopped happens right away
+	select {
+	case name := <-ran:
+		if name != "missed" {
+			t.Errorf("Expected the missed job to run, got %s", name)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the missed run to happen at startup")
+	}
+	waitFor(t, "the missed run to be saved", func() bool { return store.get("missed").Runs == 5 })
+
+	// Paused jobs stay paused, and a changed schedule is not caught up
+	time.Sleep(1500 * time.Millisecond)
+	cancel()
+	s.Wait()
+	select {
+	case name := <-ran:
+		t.Errorf("Expected no other runs, got %s", name)
+	default:
+	}
+	for _, status := range s.Jobs() {
+		if status.Name == "paused" && !status.Paused {
+			t.Error("Expected the paused job to stay paused")
+		}
+		if status.Name == "missed" && !status.NextRun.After(time.Now()) {
+			t.Errorf("Expected the next run in the future, got %v", status.NextRun)
+		}
+	}
+}
+
+// memLocker is a set of locks shared by several schedulers
+type memLocker struct {
+	mu   sync.Mutex
+	held map[string]bool
+}
+
+func (l *memLocker) TryLock(ctx context.Context, name string) (context.Context, func(), bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.held[name] {
+		return nil, nil, false
+	}
+	l.held[name] = true
+	return ctx, func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		delete(l.held, name)
+	}, true
+}
+
+// TestClusteredRuns tests that instances sharing a store and locks make each run once
+func TestClusteredRuns(t *testing.T) {
+	lastWeek := time.Now().Add(-7 * 24 * time.Hour)
+	store := &memStore{states: map[string]State{
+		"cleanup": {Name: "cleanup", Schedule: "@daily", NextRun: lastWeek},
+	}}
+	locker := &memLocker{held: map[string]bool{}}
+
+	release := make(chan struct{})
+	ran := make(chan string, 10)
+	var nodes []*Scheduler
+	for _, name := range []string{"a", "b"} {
+		node := New(Options{Store: store, Locker: locker}, logging.NewLogger("test", logging.ERROR, io.Discard))
+		err := node.Add(Job{Name: "cleanup", Schedule: "@daily", Run: func(ctx context.Context) error {
+			ran <- name
+			<-release
+			return nil
+		}})
+		if err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+		nodes = append(nodes, node)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer func() {
+		cancel()
+		for _, node := range nodes {
+			node.Wait()
+		}
+	}()
+	for _, node := range nodes {
+		node.Start(ctx)
+	}
+
+	// Both instances missed the run; only one makes it
+	var first string
+	select {
+	case first = <-ran:
+	case <-time.After(time.Second):
+		t.Fatal("Expected the missed run to happen")
+	}
+	other := nodes[1]
+	if first == "b" {
+		other = nodes[0]
+	}
+	if err := other.RunNow("cleanup"); !errors.Is(err, ErrRunning) {
+		t.Errorf("Expected ErrRunning while another instance runs the job, got %v", err)
+	}
+	close(release)
+	waitFor(t, "the run to be saved", func() bool { return store.get("cleanup").Runs == 1 })
+	time.Sleep(50 * time.Millisecond)
+	select {
+	case name := <-ran:
+		t.Errorf("Expected a single run, %s also ran", name)
+	default:
+	}
+
+	// A pause saved by one instance is seen by the other
+	if err := nodes[0].SetPaused(ctx, "cleanup", true); err != nil {
+		t.Fatalf("SetPaused failed: %v", err)
+	}
+	nodes[1].Refresh(ctx)
+	if status := nodes[1].Jobs()[0]; !status.Paused || status.Runs != 1 {
+		t.Errorf("Expected the other instance to see the pause and run, got %+v", status)
+	}
+}
@@ -0,0 +1,109 @@
+// Package service runs Noodexx unattended under the operating system's service
+// manager: the Windows service control manager, or systemd on Linux. Install
+// registers the service to start at boot with the arguments it is given,
+// Uninstall stops and removes it, and Run runs the application until the
+// service manager, Ctrl-C or SIGTERM stops it, reporting when it is ready and
+// when it starts shutting down.
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+)
+
+// ErrUnsupported is returned when the platform has no supported service manager
+var ErrUnsupported = errors.New("service install is supported on Windows and on Linux with systemd")
+
+// Options describes the service to install
+type Options struct {
+	Name        string   // Service or unit name, e.g. "noodexx"
+	DisplayName string   // Name shown in the Windows services console
+	Description string   // One-line description
+	Executable  string   // Absolute path of the noodexx binary
+	Args        []string // Arguments the service manager starts the binary with
+	WorkDir     string   // Directory holding config.json and the database
+	User        string   // Account the systemd unit runs as; root when empty
+}
+
+// Runner is the application. It runs until ctx is cancelled, calls ready once
+// it serves requests, and returns after shutting down
+type Runner func(ctx context.Context, ready func()) error
+
+// Install registers the service to start at boot. It does not start it
+func Install(opts Options) error {
+	if opts.Name == "" || opts.Executable == "" {
+		return fmt.Errorf("service name and executable are required")
+	}
+	return install(opts)
+}
+
+// Uninstall stops the service if it is running and removes it
+func Uninstall(name string) error {
+	return uninstall(name)
+}
+
+// SystemdUnit returns the systemd unit file for the service. The unit uses
+// Type=notify, so systemd considers Noodexx started once it listens, and
+// stops it with SIGTERM, which Run turns into a graceful shutdown
+func SystemdUnit(opts Options) string {
+	var b strings.Builder
+	b.WriteString("[Unit]\n")
+	fmt.Fprintf(&b, "Description=%s\n", opts.Description)
+	b.WriteString("After=network-online.target\n")
+	b.WriteString("Wants=network-online.target\n")
+	b.WriteString("\n[Service]\n")
+	b.WriteString("Type=notify\n")
+	fmt.Fprintf(&b, "ExecStart=%s\n", execLine(append([]string{opts.Executable}, opts.Args...)))
+	if opts.WorkDir != "" {
+		// A path setting: taken whole, spaces included, but % specifiers expand
+		fmt.Fprintf(&b, "WorkingDirectory=%s\n", strings.ReplaceAll(opts.WorkDir, "%", "%%"))
+	}
+	if opts.User != "" {
+		fmt.Fprintf(&b, "User=%s\n", opts.User)
+	}
+	b.WriteString("Restart=on-failure\n")
+	b.WriteString("RestartSec=5\n")
+	b.WriteString("KillSignal=SIGTERM\n")
+	b.WriteString("TimeoutStopSec=30\n")
+	b.WriteString("\n[Install]\n")
+	b.WriteString("WantedBy=multi-user.target\n")
+	return b.String()
+}
+
+// execLine joins a command line for ExecStart, quoting arguments systemd
+// would otherwise split or expand
+func execLine(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = quoteArg(arg)
+	}
+	return strings.Join(quoted, " ")
+}
+
+func quoteArg(arg string) string {
+	// systemd expands % specifiers and $ variables in ExecStart
+	arg = strings.ReplaceAll(arg, "%", "%%")
+	arg = strings.ReplaceAll(arg, "$", "$$")
+	if arg != "" && !strings.ContainsAny(arg, " \t\"'\\") {
+		return arg
+	}
+	arg = strings.ReplaceAll(arg, `\`, `\\`)
+	arg = strings.ReplaceAll(arg, `"`, `\"`)
+	return `"` + arg + `"`
+}
+
+// runUntilSignal runs the application until Ctrl-C or SIGTERM. stopping is
+// called, when not nil, as soon as a signal arrives
+func runUntilSignal(run Runner, ready func(), stopping func()) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if stopping != nil {
+		context.AfterFunc(ctx, stopping)
+	}
+	return run(ctx, ready)
+}
@@ -0,0 +1,65 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestSystemdUnit(t *testing.T) {
+	unit := SystemdUnit(Options{
+		Name:        "noodexx",
+		Description: "Noodexx local knowledge base",
+		Executable:  "/opt/noodexx/noodexx",
+		Args:        []string{"--service", "run", "--workdir", "/srv/my docs"},
+		WorkDir:     "/srv/my docs",
+		User:        "noodexx",
+	})
+
+	for _, line := range []string{
+		"Description=Noodexx local knowledge base",
+		"Type=notify",
+		`ExecStart=/opt/noodexx/noodexx --service run --workdir "/srv/my docs"`,
+		"WorkingDirectory=/srv/my docs",
+		"User=noodexx",
+		"Restart=on-failure",
+		"WantedBy=multi-user.target",
+	} {
+		if !strings.Contains(unit, line+"\n") {
+			t.Errorf("Expected unit to contain %q, got:\n%s", line, unit)
+		}
+	}
+}
+
+func TestSystemdUnitEscapesSpecifiers(t *testing.T) {
+	unit := SystemdUnit(Options{
+		Executable: "/opt/noodexx/noodexx",
+		Args:       []string{"--workdir", "/data/100%$HOME"},
+		WorkDir:    "/data/100%",
+	})
+
+	if !strings.Contains(unit, "ExecStart=/opt/noodexx/noodexx --workdir /data/100%%$$HOME\n") {
+		t.Errorf("Expected %% and $ escaped in ExecStart, got:\n%s", unit)
+	}
+	if !strings.Contains(unit, "WorkingDirectory=/data/100%%\n") {
+		t.Errorf("Expected %% escaped in WorkingDirectory, got:\n%s", unit)
+	}
+	if strings.Contains(unit, "User=") {
+		t.Errorf("Expected no User= line without a user, got:\n%s", unit)
+	}
+}
+
+func TestRunUntilSignalStopsOnCancel(t *testing.T) {
+	readyCalled := false
+	err := runUntilSignal(func(ctx context.Context, ready func()) error {
+		ready()
+		return nil
+	}, func() { readyCalled = true }, nil)
+
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !readyCalled {
+		t.Error("Expected ready to be passed to the runner")
+	}
+}
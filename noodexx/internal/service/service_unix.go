@@ -0,0 +1,91 @@
+//go:build !windows
+
+package service
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// unitDir is where Install writes unit files
+var unitDir = "/etc/systemd/system"
+
+// Run runs the application until SIGTERM or Ctrl-C. Under systemd it reports
+// READY=1 once the application is serving and STOPPING=1 when it shuts down;
+// elsewhere the notifications are skipped. name is only used on Windows
+func Run(name string, run Runner) error {
+	return runUntilSignal(run, func() { notify("READY=1") }, func() { notify("STOPPING=1") })
+}
+
+// notify sends a state change to systemd when it started the process with
+// Type=notify; errors are ignored because the notification is advisory
+func notify(state string) {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return
+	}
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	conn.Write([]byte(state))
+}
+
+func install(opts Options) error {
+	if runtime.GOOS != "linux" {
+		return ErrUnsupported
+	}
+	if _, err := exec.LookPath("systemctl"); err != nil {
+		return ErrUnsupported
+	}
+
+	path := filepath.Join(unitDir, opts.Name+".service")
+	if _, err := os.Stat(path); err == nil {
+		return fmt.Errorf("service %s is already installed (%s)", opts.Name, path)
+	}
+	if err := os.WriteFile(path, []byte(SystemdUnit(opts)), 0644); err != nil {
+		return fmt.Errorf("failed to write unit file (run as root, or print it with --service unit): %w", err)
+	}
+	if err := systemctl("daemon-reload"); err != nil {
+		os.Remove(path)
+		return err
+	}
+	if err := systemctl("enable", opts.Name); err != nil {
+		os.Remove(path)
+		systemctl("daemon-reload")
+		return err
+	}
+	return nil
+}
+
+func uninstall(name string) error {
+	if runtime.GOOS != "linux" {
+		return ErrUnsupported
+	}
+	path := filepath.Join(unitDir, name+".service")
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("service %s is not installed (%s not found)", name, path)
+	}
+	// Stops a running service and removes its boot links
+	if err := systemctl("disable", "--now", name); err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("failed to remove unit file: %w", err)
+	}
+	return systemctl("daemon-reload")
+}
+
+func systemctl(args ...string) error {
+	out, err := exec.Command("systemctl", args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("systemctl %s failed: %v: %s", strings.Join(args, " "), err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
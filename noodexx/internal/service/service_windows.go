@@ -0,0 +1,144 @@
+//go:build windows
+
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+const (
+	startWaitHint = 60 * time.Second // How long startup may take before the SCM gives up
+	stopWaitHint  = 30 * time.Second // How long a graceful shutdown may take
+)
+
+// Run runs the application as the named Windows service when the service
+// control manager started the process, and until Ctrl-C otherwise
+func Run(name string, run Runner) error {
+	isService, err := svc.IsWindowsService()
+	if err != nil {
+		return fmt.Errorf("failed to detect the service control manager: %w", err)
+	}
+	if !isService {
+		return runUntilSignal(run, func() {}, nil)
+	}
+
+	h := &handler{run: run}
+	if err := svc.Run(name, h); err != nil {
+		return fmt.Errorf("failed to run service %s: %w", name, err)
+	}
+	return h.err
+}
+
+// handler reports the application's lifecycle to the service control manager
+type handler struct {
+	run Runner
+	err error
+}
+
+func (h *handler) Execute(args []string, requests <-chan svc.ChangeRequest, status chan<- svc.Status) (bool, uint32) {
+	status <- svc.Status{State: svc.StartPending, WaitHint: uint32(startWaitHint / time.Millisecond)}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ready := make(chan struct{})
+	var readyOnce sync.Once
+	done := make(chan error, 1)
+	go func() {
+		done <- h.run(ctx, func() { readyOnce.Do(func() { close(ready) }) })
+	}()
+
+	for {
+		select {
+		case <-ready:
+			status <- svc.Status{State: svc.Running, Accepts: svc.AcceptStop | svc.AcceptShutdown}
+			ready = nil
+		case err := <-done:
+			h.err = err
+			if err != nil {
+				return false, 1
+			}
+			return false, 0
+		case req := <-requests:
+			switch req.Cmd {
+			case svc.Interrogate:
+				status <- req.CurrentStatus
+			case svc.Stop, svc.Shutdown:
+				status <- svc.Status{State: svc.StopPending, WaitHint: uint32(stopWaitHint / time.Millisecond)}
+				cancel()
+			}
+		}
+	}
+}
+
+func install(opts Options) error {
+	m, err := mgr.Connect()
+	if err != nil {
+		return fmt.Errorf("failed to connect to the service control manager (run as administrator): %w", err)
+	}
+	defer m.Disconnect()
+
+	if s, err := m.OpenService(opts.Name); err == nil {
+		s.Close()
+		return fmt.Errorf("service %s is already installed", opts.Name)
+	}
+
+	s, err := m.CreateService(opts.Name, opts.Executable, mgr.Config{
+		DisplayName:      opts.DisplayName,
+		Description:      opts.Description,
+		StartType:        mgr.StartAutomatic,
+		DelayedAutoStart: true,
+	}, opts.Args...)
+	if err != nil {
+		return fmt.Errorf("failed to create service %s: %w", opts.Name, err)
+	}
+	defer s.Close()
+
+	// Restart after a crash, as systemd's Restart=on-failure does
+	actions := []mgr.RecoveryAction{{Type: mgr.ServiceRestart, Delay: 5 * time.Second}}
+	if err := s.SetRecoveryActions(actions, uint32((24 * time.Hour).Seconds())); err != nil {
+		s.Delete()
+		return fmt.Errorf("failed to set recovery actions: %w", err)
+	}
+	return nil
+}
+
+func uninstall(name string) error {
+	m, err := mgr.Connect()
+	if err != nil {
+		return fmt.Errorf("failed to connect to the service control manager (run as administrator): %w", err)
+	}
+	defer m.Disconnect()
+
+	s, err := m.OpenService(name)
+	if err != nil {
+		return fmt.Errorf("service %s is not installed: %w", name, err)
+	}
+	defer s.Close()
+
+	// Stop the service and wait for its graceful shutdown before removing it
+	if st, err := s.Control(svc.Stop); err == nil {
+		deadline := time.Now().Add(stopWaitHint)
+		for st.State != svc.Stopped && time.Now().Before(deadline) {
+			time.Sleep(300 * time.Millisecond)
+			if st, err = s.Query(); err != nil {
+				break
+			}
+		}
+	} else if !errors.Is(err, windows.ERROR_SERVICE_NOT_ACTIVE) {
+		return fmt.Errorf("failed to stop service %s: %w", name, err)
+	}
+
+	if err := s.Delete(); err != nil {
+		return fmt.Errorf("failed to delete service %s: %w", name, err)
+	}
+	return nil
+}
@@ -5,16 +5,22 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"noodexx/internal/fairqueue"
 	"noodexx/internal/logging"
 	"os"
 	"os/exec"
 	"strings"
+	"time"
 )
 
-// Executor runs skills as subprocesses
+// Executor runs skills as subprocesses, within the server's Limits
 type Executor struct {
 	privacyMode bool
 	logger      *logging.Logger
+	limits      Limits
+	queue       *fairqueue.Queue // Admits executions under the concurrency limits, nil when unlimited
+	auditor     Auditor
+	stats       executorStats
 }
 
 // NewExecutor creates a skill executor
@@ -39,21 +45,68 @@ type Output struct {
 	Metadata map[string]interface{} `json:"metadata"`
 }
 
+// maxStderrExcerpt is the number of bytes of stderr kept in a Run
+const maxStderrExcerpt = 4096
+
+// stopWaitDelay is how long a stopped skill's output pipes are waited on
+// before they are closed, in case processes it started still hold them
+const stopWaitDelay = time.Second
+
+// Run describes a single skill execution
+// Output is nil when the skill did not produce parseable output
+type Run struct {
+	Output   *Output
+	Stderr   string // Trailing excerpt of stderr
+	ExitCode int    // -1 if the process did not start or was killed
+	Duration time.Duration
+}
+
 // Execute runs a skill with the given input
 func (e *Executor) Execute(ctx context.Context, skill *Skill, input Input) (*Output, error) {
+	run, err := e.Run(ctx, skill, input)
+	return run.Output, err
+}
+
+// Run executes a skill and returns details about the execution alongside its output
+// The returned Run is never nil, even when err is set
+func (e *Executor) Run(ctx context.Context, skill *Skill, input Input) (*Run, error) {
 	logger := e.logger.WithFields(map[string]interface{}{
 		"skill_name": skill.Name,
 		"skill_path": skill.Path,
 	})
 	logger.Debug("starting skill execution")
 
+	run := &Run{ExitCode: -1}
+
+	// Validate input before starting the process
+	if violations := skill.InputSchema.Validate(input.Context); len(violations) > 0 {
+		logger.WithContext("violations", len(violations)).Warn("skill input does not match schema")
+		return run, &ValidationError{Skill: skill.Name, Stage: "input", Violations: violations}
+	}
+
+	// Wait for a slot under the concurrency limits
+	release, err := e.acquire(ctx, skill)
+	if err != nil {
+		return run, err
+	}
+	defer release()
+	e.stats.runs.Add(1)
+
 	// Create context with timeout
-	ctx, cancel := context.WithTimeout(ctx, skill.Timeout)
+	timeout, capped := e.timeout(skill)
+	if capped {
+		logger.WithFields(map[string]interface{}{
+			"manifest_timeout": skill.Timeout,
+			"max_runtime":      timeout,
+		}).Debug("skill timeout capped by the server")
+	}
+	runCtx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
 	// Prepare command
-	cmd := exec.CommandContext(ctx, skill.Executable)
+	cmd := exec.CommandContext(runCtx, skill.Executable)
 	cmd.Dir = skill.Path
+	cmd.WaitDelay = stopWaitDelay
 
 	// Set environment variables
 	cmd.Env = e.buildEnv(skill)
@@ -62,23 +115,39 @@ func (e *Executor) Execute(ctx context.Context, skill *Skill, input Input) (*Out
 	inputJSON, err := json.Marshal(input)
 	if err != nil {
 		logger.WithContext("error", err.Error()).Error("failed to marshal input")
-		return nil, fmt.Errorf("failed to marshal input: %w", err)
+		return run, fmt.Errorf("failed to marshal input: %w", err)
 	}
 
 	cmd.Stdin = bytes.NewReader(inputJSON)
 
-	// Capture output
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
+	// Capture output, stopping the skill if it writes more than allowed
+	var stderr bytes.Buffer
+	stdout := &limitedBuffer{max: e.limits.MaxOutputBytes, stop: cancel}
+	cmd.Stdout = stdout
 	cmd.Stderr = &stderr
 
 	// Run command
+	start := time.Now()
 	err = cmd.Run()
+	run.Duration = time.Since(start)
+	run.Stderr = stderrExcerpt(stderr.Bytes())
+	if cmd.ProcessState != nil {
+		run.ExitCode = cmd.ProcessState.ExitCode()
+	}
+
+	if stdout.exceeded {
+		e.stats.outputTooLarge.Add(1)
+		return run, e.limitHit(ctx, skill, LimitOutputSize, fmt.Sprintf("output over %d bytes", e.limits.MaxOutputBytes))
+	}
 
 	// Check for timeout
-	if ctx.Err() == context.DeadlineExceeded {
-		logger.WithContext("timeout", skill.Timeout).Error("skill execution timed out")
-		return nil, fmt.Errorf("skill execution timed out after %v", skill.Timeout)
+	if runCtx.Err() == context.DeadlineExceeded {
+		e.stats.timeouts.Add(1)
+		if capped {
+			return run, e.limitHit(ctx, skill, LimitRuntime, fmt.Sprintf("still running after %v", timeout))
+		}
+		logger.WithContext("timeout", timeout).Error("skill execution timed out")
+		return run, fmt.Errorf("skill execution timed out after %v", timeout)
 	}
 
 	// Parse output
@@ -88,23 +157,33 @@ func (e *Executor) Execute(ctx context.Context, skill *Skill, input Input) (*Out
 			"error":  err.Error(),
 			"stderr": stderr.String(),
 		}).Error("failed to parse skill output")
-		return nil, fmt.Errorf("failed to parse skill output: %w (stderr: %s)", err, stderr.String())
+		return run, fmt.Errorf("failed to parse skill output: %w (stderr: %s)", err, stderr.String())
 	}
+	run.Output = &output
 
 	if output.Error != "" {
 		logger.WithContext("skill_error", output.Error).Error("skill returned error")
-		return &output, fmt.Errorf("skill error: %s", output.Error)
+		return run, fmt.Errorf("skill error: %s", output.Error)
 	}
 
-	exitCode := 0
-	if err != nil {
-		if exitErr, ok := err.(*exec.ExitError); ok {
-			exitCode = exitErr.ExitCode()
-		}
+	if violations := skill.OutputSchema.Validate(output.Metadata); len(violations) > 0 {
+		logger.WithContext("violations", len(violations)).Error("skill output does not match schema")
+		return run, &ValidationError{Skill: skill.Name, Stage: "output", Violations: violations}
 	}
 
-	logger.WithContext("exit_code", exitCode).Debug("skill execution completed")
-	return &output, nil
+	logger.WithFields(map[string]interface{}{
+		"exit_code":   run.ExitCode,
+		"duration_ms": run.Duration.Milliseconds(),
+	}).Debug("skill execution completed")
+	return run, nil
+}
+
+// stderrExcerpt keeps the end of stderr, where errors usually are
+func stderrExcerpt(stderr []byte) string {
+	if len(stderr) > maxStderrExcerpt {
+		stderr = stderr[len(stderr)-maxStderrExcerpt:]
+	}
+	return string(stderr)
 }
 
 // buildEnv creates environment variables for the skill
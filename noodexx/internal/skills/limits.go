@@ -0,0 +1,186 @@
+package skills
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"noodexx/internal/fairqueue"
+	"sync/atomic"
+	"time"
+)
+
+// Limits a skill execution can hit
+const (
+	LimitQueueTimeout = "queue_timeout" // No execution slot freed up in time
+	LimitRuntime      = "max_runtime"   // The skill ran until the server's runtime cap
+	LimitOutputSize   = "max_output"    // The skill wrote more output than allowed
+)
+
+// Limits are server-side guardrails on skill executions, enforced whatever a
+// skill's manifest asks for. Zero values leave a limit off
+type Limits struct {
+	MaxRuntime     time.Duration // Caps each skill's manifest timeout
+	MaxConcurrent  int           // Executions running at once across all users
+	MaxPerUser     int           // Executions running at once for a single user
+	MaxOutputBytes int           // Largest stdout accepted from a skill
+	QueueTimeout   time.Duration // Longest an execution waits for a slot
+}
+
+// LimitError is returned when a skill execution is stopped by one of the limits
+type LimitError struct {
+	Skill  string
+	Limit  string // One of the Limit constants
+	Detail string
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("skill %s stopped by %s: %s", e.Skill, e.Limit, e.Detail)
+}
+
+// Auditor records executions stopped by a limit
+type Auditor interface {
+	AddAuditEntry(ctx context.Context, opType, details, userCtx string) error
+}
+
+// Stats counts executions and the limits they hit since startup
+type Stats struct {
+	Running        int   `json:"running"`          // Executions holding a slot
+	Waiting        int   `json:"waiting"`          // Executions queued for a slot
+	Runs           int64 `json:"runs"`             // Executions started
+	Queued         int64 `json:"queued"`           // Executions that had to wait for a slot
+	QueueTimeouts  int64 `json:"queue_timeouts"`   // Executions given up after QueueTimeout
+	RuntimeCapped  int64 `json:"runtime_capped"`   // Executions whose manifest timeout was lowered to MaxRuntime
+	Timeouts       int64 `json:"timeouts"`         // Executions killed at their timeout
+	OutputTooLarge int64 `json:"output_too_large"` // Executions killed for exceeding MaxOutputBytes
+}
+
+// executorStats holds the counters behind Stats
+type executorStats struct {
+	runs, queued, queueTimeouts, runtimeCapped, timeouts, outputTooLarge atomic.Int64
+}
+
+// SetLimits sets the guardrails applied to every execution from now on
+// A MaxConcurrent above zero queues executions beyond it, admitting waiting
+// users round-robin
+func (e *Executor) SetLimits(limits Limits) {
+	e.limits = limits
+	e.queue = nil
+	if limits.MaxConcurrent > 0 {
+		perUser := limits.MaxPerUser
+		if perUser <= 0 || perUser > limits.MaxConcurrent {
+			perUser = limits.MaxConcurrent
+		}
+		e.queue = fairqueue.New(fairqueue.Options{MaxActive: limits.MaxConcurrent, MaxPerUser: perUser})
+	}
+}
+
+// SetAuditor records an audit entry whenever an execution hits a limit
+func (e *Executor) SetAuditor(auditor Auditor) {
+	e.auditor = auditor
+}
+
+// Stats returns a snapshot of executions and the limits they hit
+func (e *Executor) Stats() Stats {
+	stats := Stats{
+		Runs:           e.stats.runs.Load(),
+		Queued:         e.stats.queued.Load(),
+		QueueTimeouts:  e.stats.queueTimeouts.Load(),
+		RuntimeCapped:  e.stats.runtimeCapped.Load(),
+		Timeouts:       e.stats.timeouts.Load(),
+		OutputTooLarge: e.stats.outputTooLarge.Load(),
+	}
+	if e.queue != nil {
+		q := e.queue.Stats()
+		stats.Running, stats.Waiting = q.Active, q.Waiting
+	}
+	return stats
+}
+
+// acquire waits for an execution slot for the skill's owner. The returned
+// release must be called once the execution ends
+func (e *Executor) acquire(ctx context.Context, skill *Skill) (func(), error) {
+	if e.queue == nil {
+		return func() {}, nil
+	}
+
+	ticket := e.queue.Join(skill.UserID)
+	select {
+	case <-ticket.Ready():
+		return ticket.Release, nil
+	default:
+	}
+	e.stats.queued.Add(1)
+
+	var timeout <-chan time.Time
+	if e.limits.QueueTimeout > 0 {
+		timer := time.NewTimer(e.limits.QueueTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	select {
+	case <-ticket.Ready():
+		return ticket.Release, nil
+	case <-timeout:
+		ticket.Release()
+		e.stats.queueTimeouts.Add(1)
+		return nil, e.limitHit(ctx, skill, LimitQueueTimeout, fmt.Sprintf("no slot free after %v", e.limits.QueueTimeout))
+	case <-ctx.Done():
+		ticket.Release()
+		return nil, ctx.Err()
+	}
+}
+
+// timeout returns how long a skill may run: its manifest timeout, capped by MaxRuntime
+func (e *Executor) timeout(skill *Skill) (timeout time.Duration, capped bool) {
+	if max := e.limits.MaxRuntime; max > 0 && (skill.Timeout <= 0 || skill.Timeout > max) {
+		e.stats.runtimeCapped.Add(1)
+		return max, true
+	}
+	return skill.Timeout, false
+}
+
+// limitHit logs and audits an execution stopped by a limit and returns its error
+func (e *Executor) limitHit(ctx context.Context, skill *Skill, limit, detail string) error {
+	err := &LimitError{Skill: skill.Name, Limit: limit, Detail: detail}
+	e.logger.WithFields(map[string]interface{}{
+		"skill_name": skill.Name,
+		"user_id":    skill.UserID,
+		"limit":      limit,
+	}).Warn("skill execution hit a limit")
+	if e.auditor != nil {
+		// The caller may have given up already; the entry is still worth keeping
+		details := fmt.Sprintf("%s (user %d): %s, %s", skill.Name, skill.UserID, limit, detail)
+		if auditErr := e.auditor.AddAuditEntry(context.WithoutCancel(ctx), "skill_limit", details, ""); auditErr != nil {
+			e.logger.WithContext("error", auditErr.Error()).Warn("failed to audit skill limit")
+		}
+	}
+	return err
+}
+
+// limitedBuffer keeps at most max bytes of a skill's output, calling stop the
+// first time more arrives. Writes never fail, so the skill is not blocked on a
+// full pipe before it is stopped. The buffer is not embedded, so copies cannot
+// bypass Write through bytes.Buffer's ReadFrom
+type limitedBuffer struct {
+	buf      bytes.Buffer
+	max      int // 0 for no limit
+	stop     func()
+	exceeded bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if b.exceeded {
+		return len(p), nil
+	}
+	if b.max > 0 && b.buf.Len()+len(p) > b.max {
+		b.exceeded = true
+		b.stop()
+		return len(p), nil
+	}
+	return b.buf.Write(p)
+}
+
+// Bytes returns the output kept
+func (b *limitedBuffer) Bytes() []byte {
+	return b.buf.Bytes()
+}
@@ -0,0 +1,121 @@
+package skills
+
+import (
+	"context"
+	"errors"
+	"io"
+	"noodexx/internal/logging"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// auditLog keeps the audit entries written by the executor
+type auditLog struct {
+	mu      sync.Mutex
+	entries []string
+}
+
+func (a *auditLog) AddAuditEntry(ctx context.Context, opType, details, userCtx string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.entries = append(a.entries, opType+": "+details)
+	return nil
+}
+
+// limitSkill writes a skill running script and returns it with the given manifest timeout
+func limitSkill(t *testing.T, name, script string, timeout time.Duration) *Skill {
+	t.Helper()
+	dir := t.TempDir()
+	path := filepath.Join(dir, name+".sh")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+script+"\n"), 0755); err != nil {
+		t.Fatalf("Failed to create test script: %v", err)
+	}
+	return &Skill{Name: name, UserID: 1, Executable: path, Path: dir, Timeout: timeout}
+}
+
+func TestExecutor_RuntimeCap(t *testing.T) {
+	executor := NewExecutor(false, logging.NewLogger("test", logging.DEBUG, io.Discard))
+	audit := &auditLog{}
+	executor.SetAuditor(audit)
+	executor.SetLimits(Limits{MaxRuntime: 200 * time.Millisecond})
+
+	skill := limitSkill(t, "slow", "sleep 5", time.Hour)
+	start := time.Now()
+	_, err := executor.Execute(context.Background(), skill, Input{})
+
+	var limitErr *LimitError
+	if !errors.As(err, &limitErr) || limitErr.Limit != LimitRuntime {
+		t.Fatalf("Expected a runtime limit error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("Expected the skill stopped at the cap, ran %v", elapsed)
+	}
+	if stats := executor.Stats(); stats.RuntimeCapped != 1 || stats.Timeouts != 1 || stats.Runs != 1 {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+	if len(audit.entries) != 1 || !strings.HasPrefix(audit.entries[0], "skill_limit: slow (user 1): max_runtime") {
+		t.Errorf("Expected the limit audited, got %v", audit.entries)
+	}
+}
+
+func TestExecutor_OutputLimit(t *testing.T) {
+	executor := NewExecutor(false, logging.NewLogger("test", logging.DEBUG, io.Discard))
+	executor.SetLimits(Limits{MaxOutputBytes: 1024})
+
+	skill := limitSkill(t, "chatty", "yes '{\"result\":\"spam\"}'", 5*time.Second)
+	_, err := executor.Execute(context.Background(), skill, Input{})
+	var limitErr *LimitError
+	if !errors.As(err, &limitErr) || limitErr.Limit != LimitOutputSize {
+		t.Fatalf("Expected an output limit error, got %v", err)
+	}
+
+	small := limitSkill(t, "small", `echo '{"result":"ok"}'`, 5*time.Second)
+	if output, err := executor.Execute(context.Background(), small, Input{}); err != nil || output.Result != "ok" {
+		t.Errorf("Expected output under the limit accepted, got %+v (%v)", output, err)
+	}
+	if stats := executor.Stats(); stats.OutputTooLarge != 1 {
+		t.Errorf("Expected one oversized output counted, got %+v", stats)
+	}
+}
+
+func TestExecutor_ConcurrencyLimits(t *testing.T) {
+	executor := NewExecutor(false, logging.NewLogger("test", logging.DEBUG, io.Discard))
+	audit := &auditLog{}
+	executor.SetAuditor(audit)
+	executor.SetLimits(Limits{MaxConcurrent: 2, MaxPerUser: 1, QueueTimeout: 300 * time.Millisecond})
+
+	slow := limitSkill(t, "slow", `sleep 1; echo '{"result":"done"}'`, 5*time.Second)
+	other := *slow
+	other.UserID = 2
+
+	// The first user's second execution queues behind its first, while another user's runs
+	var wg sync.WaitGroup
+	errs := make([]error, 3)
+	for i, skill := range []*Skill{slow, slow, &other} {
+		wg.Add(1)
+		go func(i int, skill *Skill) {
+			defer wg.Done()
+			_, errs[i] = executor.Execute(context.Background(), skill, Input{})
+		}(i, skill)
+		time.Sleep(50 * time.Millisecond)
+	}
+	wg.Wait()
+
+	var limitErr *LimitError
+	if errs[0] != nil || errs[2] != nil {
+		t.Errorf("Expected the admitted executions to succeed, got %v and %v", errs[0], errs[2])
+	}
+	if !errors.As(errs[1], &limitErr) || limitErr.Limit != LimitQueueTimeout {
+		t.Errorf("Expected the queued execution to time out, got %v", errs[1])
+	}
+	if stats := executor.Stats(); stats.Queued != 1 || stats.QueueTimeouts != 1 || stats.Runs != 2 || stats.Running != 0 {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+	if len(audit.entries) != 1 || !strings.Contains(audit.entries[0], "queue_timeout") {
+		t.Errorf("Expected the queue timeout audited, got %v", audit.entries)
+	}
+}
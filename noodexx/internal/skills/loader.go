@@ -12,6 +12,7 @@ import (
 
 // Skill represents a loaded skill with its metadata and configuration
 type Skill struct {
+	ID          int64 // Database ID (set when loaded via LoadForUser)
 	UserID      int64 // Owner of the skill (set when loaded via LoadForUser)
 	Name        string
 	Version     string
@@ -22,6 +23,10 @@ type Skill struct {
 	Timeout     time.Duration
 	RequiresNet bool
 	Path        string
+
+	// Optional JSON Schemas for the input context and output metadata
+	InputSchema  *Schema
+	OutputSchema *Schema
 }
 
 // Trigger defines when a skill executes
@@ -40,6 +45,8 @@ type Metadata struct {
 	SettingsSchema map[string]interface{} `json:"settings_schema"`
 	Timeout        int                    `json:"timeout"` // seconds
 	RequiresNet    bool                   `json:"requires_network"`
+	InputSchema    *Schema                `json:"input_schema"`  // Validates Input.Context
+	OutputSchema   *Schema                `json:"output_schema"` // Validates Output.Metadata
 }
 
 // Store interface for accessing user skills from database
@@ -194,7 +201,8 @@ func (l *Loader) LoadForUser(ctx context.Context, userID int64) ([]*Skill, error
 			continue
 		}
 
-		// Set the UserID from the metadata
+		// Set the ID and UserID from the metadata
+		skill.ID = skillMeta.ID
 		skill.UserID = skillMeta.UserID
 
 		// Skip network-requiring skills in privacy mode
@@ -239,6 +247,14 @@ func (l *Loader) loadSkill(path string) (*Skill, error) {
 		return nil, fmt.Errorf("skill.json missing required field: executable")
 	}
 
+	// Validate declared schemas so mistakes surface at load time rather than on every run
+	if err := meta.InputSchema.Check(); err != nil {
+		return nil, fmt.Errorf("invalid input_schema: %w", err)
+	}
+	if err := meta.OutputSchema.Check(); err != nil {
+		return nil, fmt.Errorf("invalid output_schema: %w", err)
+	}
+
 	// Check executable exists and is within the skill directory
 	execPath := filepath.Join(path, meta.Executable)
 	info, err := os.Stat(execPath)
@@ -258,13 +274,15 @@ func (l *Loader) loadSkill(path string) (*Skill, error) {
 	}
 
 	return &Skill{
-		Name:        meta.Name,
-		Version:     meta.Version,
-		Description: meta.Description,
-		Executable:  execPath,
-		Triggers:    meta.Triggers,
-		Timeout:     timeout,
-		RequiresNet: meta.RequiresNet,
-		Path:        path,
+		Name:         meta.Name,
+		Version:      meta.Version,
+		Description:  meta.Description,
+		Executable:   execPath,
+		Triggers:     meta.Triggers,
+		Timeout:      timeout,
+		RequiresNet:  meta.RequiresNet,
+		Path:         path,
+		InputSchema:  meta.InputSchema,
+		OutputSchema: meta.OutputSchema,
 	}, nil
 }
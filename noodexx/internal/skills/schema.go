@@ -0,0 +1,264 @@
+package skills
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Schema is the subset of JSON Schema that skill manifests can use to declare
+// their input and output. Unsupported keywords are ignored.
+type Schema struct {
+	Type                 string             `json:"type,omitempty"` // "object", "array", "string", "number", "integer", "boolean", "null"
+	Description          string             `json:"description,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *bool              `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Enum                 []interface{}      `json:"enum,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+}
+
+// schemaTypes lists the type names a schema may declare
+var schemaTypes = map[string]bool{
+	"object": true, "array": true, "string": true, "number": true,
+	"integer": true, "boolean": true, "null": true,
+}
+
+// Violation describes a single value that does not match a schema
+type Violation struct {
+	Path    string `json:"path"` // JSON path of the offending value, e.g. "$.location"
+	Message string `json:"message"`
+}
+
+// ValidationError is returned when skill input or output does not match the declared schema
+type ValidationError struct {
+	Skill      string      `json:"skill"`
+	Stage      string      `json:"stage"` // "input" or "output"
+	Violations []Violation `json:"violations"`
+}
+
+func (e *ValidationError) Error() string {
+	parts := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		parts[i] = v.Path + ": " + v.Message
+	}
+	return fmt.Sprintf("skill %s %s does not match schema: %s", e.Skill, e.Stage, strings.Join(parts, "; "))
+}
+
+// Check verifies that the schema itself is well formed
+func (s *Schema) Check() error {
+	return s.check("$")
+}
+
+func (s *Schema) check(path string) error {
+	if s == nil {
+		return nil
+	}
+	if s.Type != "" && !schemaTypes[s.Type] {
+		return fmt.Errorf("%s: unknown type %q", path, s.Type)
+	}
+	for _, name := range s.Required {
+		if _, ok := s.Properties[name]; !ok && s.AdditionalProperties != nil && !*s.AdditionalProperties {
+			return fmt.Errorf("%s: required property %q is not allowed by the schema", path, name)
+		}
+	}
+	for name, prop := range s.Properties {
+		if prop == nil {
+			return fmt.Errorf("%s.%s: schema is empty", path, name)
+		}
+		if err := prop.check(path + "." + name); err != nil {
+			return err
+		}
+	}
+	return s.Items.check(path + "[]")
+}
+
+// Validate checks a decoded JSON value against the schema and returns every violation found
+// A nil schema accepts any value
+func (s *Schema) Validate(value interface{}) []Violation {
+	var violations []Violation
+	s.validate("$", normalizeJSON(value), &violations)
+	return violations
+}
+
+func (s *Schema) validate(path string, value interface{}, out *[]Violation) {
+	if s == nil {
+		return
+	}
+
+	fail := func(format string, args ...interface{}) {
+		*out = append(*out, Violation{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if s.Type != "" && !matchesType(s.Type, value) {
+		fail("expected %s, got %s", s.Type, jsonTypeName(value))
+		return
+	}
+
+	if len(s.Enum) > 0 {
+		found := false
+		for _, allowed := range s.Enum {
+			if reflect.DeepEqual(normalizeJSON(allowed), value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			fail("value is not one of the allowed values")
+		}
+	}
+
+	switch v := value.(type) {
+	case string:
+		n := utf8.RuneCountInString(v)
+		if s.MinLength != nil && n < *s.MinLength {
+			fail("must be at least %d characters", *s.MinLength)
+		}
+		if s.MaxLength != nil && n > *s.MaxLength {
+			fail("must be at most %d characters", *s.MaxLength)
+		}
+	case float64:
+		if s.Minimum != nil && v < *s.Minimum {
+			fail("must be at least %v", *s.Minimum)
+		}
+		if s.Maximum != nil && v > *s.Maximum {
+			fail("must be at most %v", *s.Maximum)
+		}
+	case []interface{}:
+		if s.MinItems != nil && len(v) < *s.MinItems {
+			fail("must have at least %d items", *s.MinItems)
+		}
+		if s.MaxItems != nil && len(v) > *s.MaxItems {
+			fail("must have at most %d items", *s.MaxItems)
+		}
+		for i, item := range v {
+			s.Items.validate(fmt.Sprintf("%s[%d]", path, i), item, out)
+		}
+	case map[string]interface{}:
+		for _, name := range s.Required {
+			if _, ok := v[name]; !ok {
+				*out = append(*out, Violation{Path: path + "." + name, Message: "required property is missing"})
+			}
+		}
+
+		// Walk properties in a stable order so violations are deterministic
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			prop, ok := s.Properties[name]
+			if !ok {
+				if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+					*out = append(*out, Violation{Path: path + "." + name, Message: "property is not allowed"})
+				}
+				continue
+			}
+			prop.validate(path+"."+name, v[name], out)
+		}
+	}
+}
+
+// ToMap returns the schema as a generic JSON object, as used in provider function definitions
+func (s *Schema) ToMap() map[string]interface{} {
+	if s == nil {
+		return nil
+	}
+	data, err := json.Marshal(s)
+	if err != nil {
+		return nil
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil
+	}
+	return m
+}
+
+// matchesType reports whether a normalized JSON value has the given schema type
+func matchesType(typ string, value interface{}) bool {
+	switch typ {
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	case "array":
+		_, ok := value.([]interface{})
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	case "integer":
+		f, ok := value.(float64)
+		return ok && f == math.Trunc(f)
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "null":
+		return value == nil
+	}
+	return false
+}
+
+// jsonTypeName names the JSON type of a normalized value for error messages
+func jsonTypeName(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+// normalizeJSON converts a Go value to the types produced by encoding/json so that
+// values built in code (ints, typed slices and maps) validate the same way as decoded input
+func normalizeJSON(value interface{}) interface{} {
+	switch v := value.(type) {
+	case nil, string, float64, bool:
+		return value
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			out[k] = normalizeJSON(item)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			out[i] = normalizeJSON(item)
+		}
+		return out
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return value
+	}
+	var decoded interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return value
+	}
+	return decoded
+}
@@ -0,0 +1,174 @@
+package skills
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"noodexx/internal/llm"
+	"noodexx/internal/logging"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func parseSchema(t *testing.T, data string) *Schema {
+	t.Helper()
+	var schema Schema
+	if err := json.Unmarshal([]byte(data), &schema); err != nil {
+		t.Fatalf("Failed to parse schema: %v", err)
+	}
+	return &schema
+}
+
+func TestSchema_Validate(t *testing.T) {
+	schema := parseSchema(t, `{
+		"type": "object",
+		"properties": {
+			"location": {"type": "string", "minLength": 1},
+			"days": {"type": "integer", "minimum": 1, "maximum": 7},
+			"units": {"enum": ["metric", "imperial"]},
+			"tags": {"type": "array", "items": {"type": "string"}, "maxItems": 2}
+		},
+		"required": ["location"],
+		"additionalProperties": false
+	}`)
+
+	tests := []struct {
+		name  string
+		value interface{}
+		paths []string
+	}{
+		{"valid", map[string]interface{}{"location": "Paris", "days": 3, "units": "metric"}, nil},
+		{"nil context is an empty object", map[string]interface{}(nil), []string{"$.location"}},
+		{"wrong type", map[string]interface{}{"location": 42}, []string{"$.location"}},
+		{"not an integer", map[string]interface{}{"location": "Paris", "days": 1.5}, []string{"$.days"}},
+		{"out of range", map[string]interface{}{"location": "Paris", "days": 10}, []string{"$.days"}},
+		{"enum", map[string]interface{}{"location": "Paris", "units": "kelvin"}, []string{"$.units"}},
+		{"array items", map[string]interface{}{"location": "Paris", "tags": []interface{}{"a", 1}}, []string{"$.tags[1]"}},
+		{"typed slice", map[string]interface{}{"location": "Paris", "tags": []string{"a", "b", "c"}}, []string{"$.tags"}},
+		{"additional property", map[string]interface{}{"location": "Paris", "extra": true}, []string{"$.extra"}},
+		{"multiple", map[string]interface{}{"location": "", "days": 0}, []string{"$.days", "$.location"}},
+		{"not an object", "Paris", []string{"$"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			violations := schema.Validate(tt.value)
+			if len(violations) != len(tt.paths) {
+				t.Fatalf("Expected %d violations, got %+v", len(tt.paths), violations)
+			}
+			for i, v := range violations {
+				if v.Path != tt.paths[i] {
+					t.Errorf("Expected violation at %s, got %s (%s)", tt.paths[i], v.Path, v.Message)
+				}
+			}
+		})
+	}
+
+	var nilSchema *Schema
+	if violations := nilSchema.Validate(map[string]interface{}{"anything": 1}); len(violations) != 0 {
+		t.Errorf("Expected a nil schema to accept any value, got %+v", violations)
+	}
+}
+
+func TestSchema_Check(t *testing.T) {
+	if err := parseSchema(t, `{"type": "object", "properties": {"a": {"type": "strng"}}}`).Check(); err == nil {
+		t.Error("Expected unknown type to be rejected")
+	}
+	if err := parseSchema(t, `{"type": "object", "required": ["a"], "additionalProperties": false}`).Check(); err == nil {
+		t.Error("Expected unsatisfiable required property to be rejected")
+	}
+	if err := parseSchema(t, `{"type": "array", "items": {"type": "number"}}`).Check(); err != nil {
+		t.Errorf("Expected valid schema, got %v", err)
+	}
+}
+
+func TestExecutor_Execute_SchemaValidation(t *testing.T) {
+	skillDir := t.TempDir()
+	scriptPath := filepath.Join(skillDir, "test.sh")
+	scriptContent := `#!/bin/bash
+cat <<EOF
+{"result": "ok", "metadata": {"count": "three"}}
+EOF
+`
+	if err := os.WriteFile(scriptPath, []byte(scriptContent), 0755); err != nil {
+		t.Fatalf("Failed to create test script: %v", err)
+	}
+
+	skill := &Skill{
+		Name:         "schema-skill",
+		Executable:   scriptPath,
+		Path:         skillDir,
+		Timeout:      5 * time.Second,
+		InputSchema:  parseSchema(t, `{"type": "object", "properties": {"n": {"type": "integer"}}, "required": ["n"]}`),
+		OutputSchema: parseSchema(t, `{"type": "object", "properties": {"count": {"type": "integer"}}}`),
+	}
+
+	executor := NewExecutor(false, logging.NewLogger("test", logging.DEBUG, io.Discard))
+	ctx := context.Background()
+
+	_, err := executor.Execute(ctx, skill, Input{Context: map[string]interface{}{}})
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) || validationErr.Stage != "input" {
+		t.Fatalf("Expected input validation error, got %v", err)
+	}
+	if len(validationErr.Violations) != 1 || validationErr.Violations[0].Path != "$.n" {
+		t.Errorf("Unexpected violations: %+v", validationErr.Violations)
+	}
+
+	_, err = executor.Execute(ctx, skill, Input{Context: map[string]interface{}{"n": 3}})
+	if !errors.As(err, &validationErr) || validationErr.Stage != "output" {
+		t.Fatalf("Expected output validation error, got %v", err)
+	}
+	if validationErr.Violations[0].Path != "$.count" {
+		t.Errorf("Unexpected violations: %+v", validationErr.Violations)
+	}
+}
+
+func TestToolDefinitions(t *testing.T) {
+	manual := []Trigger{{Type: "manual"}}
+	skillList := []*Skill{
+		{Name: "weather lookup", Description: "Weather", Triggers: manual,
+			InputSchema: parseSchema(t, `{"type": "object", "properties": {"location": {"type": "string"}}}`)},
+		{Name: "digest", Description: "Digest", Triggers: manual},
+		{Name: "timer-only", Triggers: []Trigger{{Type: "timer"}}},
+	}
+
+	tools := ToolDefinitions(skillList)
+	if len(tools) != 2 {
+		t.Fatalf("Expected 2 tools for manual skills, got %d", len(tools))
+	}
+	if tools[0].Name != "weather_lookup" {
+		t.Errorf("Expected sanitized tool name, got %s", tools[0].Name)
+	}
+	if _, ok := tools[0].Parameters["properties"].(map[string]interface{})["location"]; !ok {
+		t.Errorf("Expected input schema as parameters, got %+v", tools[0].Parameters)
+	}
+	if _, ok := tools[1].Parameters["properties"].(map[string]interface{})["query"]; !ok {
+		t.Errorf("Expected query parameter for skill without schema, got %+v", tools[1].Parameters)
+	}
+
+	anthropic := llm.FormatTools("anthropic", tools)
+	if anthropic[0]["input_schema"] == nil {
+		t.Errorf("Expected anthropic tools to use input_schema, got %+v", anthropic[0])
+	}
+	openai := llm.FormatTools("openai", tools)
+	if openai[0]["type"] != "function" {
+		t.Errorf("Expected openai tools to be functions, got %+v", openai[0])
+	}
+
+	skill := FindTool(skillList, "weather_lookup")
+	if skill == nil || skill.Name != "weather lookup" {
+		t.Fatalf("Expected to find skill by tool name, got %+v", skill)
+	}
+	input := InputFromArguments(skill, map[string]interface{}{"location": "Oslo"})
+	if input.Context["location"] != "Oslo" {
+		t.Errorf("Expected arguments in context, got %+v", input.Context)
+	}
+	input = InputFromArguments(skillList[1], map[string]interface{}{"query": "today"})
+	if input.Query != "today" || len(input.Context) != 0 {
+		t.Errorf("Expected query argument only, got %+v", input)
+	}
+}
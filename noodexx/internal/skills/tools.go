@@ -0,0 +1,76 @@
+package skills
+
+import "noodexx/internal/llm"
+
+// queryInputSchema is the function signature of skills that do not declare an input schema:
+// a single free-text query
+var queryInputSchema = &Schema{
+	Type: "object",
+	Properties: map[string]*Schema{
+		"query": {Type: "string", Description: "Request to pass to the skill"},
+	},
+	Required: []string{"query"},
+}
+
+// ToolDefinition builds the function-call definition offered to providers for a skill
+// Skills with an input schema expose it as the call parameters; others take a single query string
+func (s *Skill) ToolDefinition() llm.ToolDefinition {
+	params := s.InputSchema
+	if params == nil {
+		params = queryInputSchema
+	}
+	return llm.ToolDefinition{
+		Name:        llm.ToolName(s.Name),
+		Description: s.Description,
+		Parameters:  params.ToMap(),
+	}
+}
+
+// ToolDefinitions returns definitions for every skill that can be invoked on demand
+func ToolDefinitions(skills []*Skill) []llm.ToolDefinition {
+	var tools []llm.ToolDefinition
+	for _, skill := range skills {
+		if skill.HasTrigger("manual") {
+			tools = append(tools, skill.ToolDefinition())
+		}
+	}
+	return tools
+}
+
+// FindTool returns the skill a provider function call refers to, or nil
+func FindTool(skills []*Skill, toolName string) *Skill {
+	for _, skill := range skills {
+		if llm.ToolName(skill.Name) == toolName {
+			return skill
+		}
+	}
+	return nil
+}
+
+// InputFromArguments converts function-call arguments into skill input
+// Arguments become the input context for skills with an input schema; otherwise only the query is used
+func InputFromArguments(skill *Skill, args map[string]interface{}) Input {
+	input := Input{
+		Context:  make(map[string]interface{}),
+		Settings: make(map[string]interface{}),
+	}
+	if query, ok := args["query"].(string); ok {
+		input.Query = query
+	}
+	if skill.InputSchema != nil {
+		for k, v := range args {
+			input.Context[k] = v
+		}
+	}
+	return input
+}
+
+// HasTrigger reports whether the skill declares a trigger of the given type
+func (s *Skill) HasTrigger(triggerType string) bool {
+	for _, trigger := range s.Triggers {
+		if trigger.Type == triggerType {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,107 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// GetUserActivity returns every account with its last chat, library size and
+// cloud token usage, oldest account first
+// Storage is counted as GetStorageStats counts it; tokens are the provenance
+// estimates of answers generated in cloud mode
+func (s *Store) GetUserActivity(ctx context.Context) ([]UserActivity, error) {
+	query := `
+		SELECT
+			u.id,
+			u.username,
+			COALESCE(u.email, ''),
+			u.is_admin,
+			u.created_at,
+			u.last_login,
+			COALESCE(m.last_chat, ''),
+			COALESCE(m.messages, 0),
+			COALESCE(c.documents, 0),
+			COALESCE(c.bytes, 0),
+			COALESCE(t.prompt_tokens, 0),
+			COALESCE(t.completion_tokens, 0),
+			COALESCE(e.cost, 0),
+			COALESCE(e.confirmations, 0)
+		FROM users u
+		LEFT JOIN (
+			SELECT user_id, MAX(created_at) AS last_chat, COUNT(*) AS messages
+			FROM chat_messages
+			WHERE role = 'user'
+			GROUP BY user_id
+		) m ON m.user_id = u.id
+		LEFT JOIN (
+			SELECT
+				user_id,
+				COUNT(DISTINCT source) AS documents,
+				SUM(LENGTH(CAST(text AS BLOB)) + COALESCE(LENGTH(embedding), 0) +
+					LENGTH(CAST(COALESCE(summary, '') AS BLOB)) + LENGTH(CAST(COALESCE(tags, '') AS BLOB))) AS bytes
+			FROM chunks
+			GROUP BY user_id
+		) c ON c.user_id = u.id
+		LEFT JOIN (
+			SELECT cm.user_id, SUM(p.prompt_tokens) AS prompt_tokens, SUM(p.completion_tokens) AS completion_tokens
+			FROM message_provenance p
+			JOIN chat_messages cm ON cm.id = p.message_id
+			WHERE cm.provider_mode = 'cloud'
+			GROUP BY cm.user_id
+		) t ON t.user_id = u.id
+		LEFT JOIN (
+			SELECT
+				user_id,
+				SUM(CASE WHEN outcome != 'confirmation_required' THEN COALESCE(cost_usd, 0) ELSE 0 END) AS cost,
+				SUM(CASE WHEN outcome = 'confirmation_required' THEN 1 ELSE 0 END) AS confirmations
+			FROM cost_estimates
+			GROUP BY user_id
+		) e ON e.user_id = u.id
+		ORDER BY u.id
+	`
+
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query user activity: %w", err)
+	}
+	defer rows.Close()
+
+	var activity []UserActivity
+	for rows.Next() {
+		var a UserActivity
+		var lastLogin sql.NullTime
+		var lastChat string
+		err := rows.Scan(
+			&a.UserID,
+			&a.Username,
+			&a.Email,
+			&a.IsAdmin,
+			&a.CreatedAt,
+			&lastLogin,
+			&lastChat,
+			&a.Messages,
+			&a.Documents,
+			&a.StorageBytes,
+			&a.CloudPromptTokens,
+			&a.CloudCompletionTokens,
+			&a.EstimatedCostUSD,
+			&a.ConfirmationsAsked,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan user activity: %w", err)
+		}
+		if lastLogin.Valid {
+			a.LastLogin = lastLogin.Time
+		}
+		if lastChat != "" {
+			a.LastChat, _ = time.Parse("2006-01-02 15:04:05", lastChat)
+		}
+		activity = append(activity, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating user activity: %w", err)
+	}
+	return activity, nil
+}
@@ -0,0 +1,79 @@
+package store
+
+import (
+	"context"
+	"testing"
+)
+
+// TestGetUserActivity tests the per-user chat, library and cloud token totals
+func TestGetUserActivity(t *testing.T) {
+	store, err := NewStore(t.TempDir()+"/test_activity.db", "multi")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+
+	aliceID, err := store.CreateUser(ctx, "alice", "password123", "alice@example.com", false, false)
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	bobID, err := store.CreateUser(ctx, "bob", "password123", "bob@example.com", false, false)
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	for _, source := range []string{"a.txt", "a.txt", "b.txt"} {
+		if err := store.SaveChunk(ctx, aliceID, source, "four", []float32{1, 0}, nil, ""); err != nil {
+			t.Fatalf("Failed to save chunk: %v", err)
+		}
+	}
+	if err := store.SaveChatMessage(ctx, aliceID, "s1", "user", "question", ""); err != nil {
+		t.Fatalf("Failed to save message: %v", err)
+	}
+	for _, mode := range []string{"cloud", "local"} {
+		provenance := &MessageProvenance{Provider: mode, PromptTokens: 100, CompletionTokens: 20}
+		if _, err := store.SaveChatMessageWithProvenance(ctx, aliceID, "s1", "assistant", "answer", mode, nil, provenance); err != nil {
+			t.Fatalf("Failed to save answer: %v", err)
+		}
+	}
+
+	activity, err := store.GetUserActivity(ctx)
+	if err != nil {
+		t.Fatalf("GetUserActivity failed: %v", err)
+	}
+	byID := map[int64]UserActivity{}
+	for _, a := range activity {
+		byID[a.UserID] = a
+	}
+
+	alice, ok := byID[aliceID]
+	if !ok {
+		t.Fatalf("Expected alice in %+v", activity)
+	}
+	if alice.Username != "alice" || alice.Email != "alice@example.com" {
+		t.Errorf("Expected alice's account details, got %+v", alice)
+	}
+	if alice.Messages != 1 || alice.LastChat.IsZero() {
+		t.Errorf("Expected one chat message with a time, got %d at %v", alice.Messages, alice.LastChat)
+	}
+	if alice.Documents != 2 {
+		t.Errorf("Expected 2 documents, got %d", alice.Documents)
+	}
+	// 3 chunks of 4 text bytes and 8 embedding bytes
+	if alice.StorageBytes != 36 {
+		t.Errorf("Expected 36 bytes of storage, got %d", alice.StorageBytes)
+	}
+	if alice.CloudPromptTokens != 100 || alice.CloudCompletionTokens != 20 {
+		t.Errorf("Expected only the cloud answer's tokens, got %d and %d", alice.CloudPromptTokens, alice.CloudCompletionTokens)
+	}
+
+	bob, ok := byID[bobID]
+	if !ok {
+		t.Fatalf("Expected bob in %+v", activity)
+	}
+	if bob.Messages != 0 || !bob.LastChat.IsZero() || bob.Documents != 0 || bob.StorageBytes != 0 || bob.CloudPromptTokens != 0 {
+		t.Errorf("Expected no activity for bob, got %+v", bob)
+	}
+}
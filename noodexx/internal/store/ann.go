@@ -0,0 +1,449 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+)
+
+// ANN index tuning
+const (
+	annMinLists        = 16   // Fewest lists a built index has, unless it holds fewer chunks
+	annMaxLists        = 4096 // Most lists a built index has
+	annTrainPerList    = 32   // Embeddings sampled per list to train the centroids
+	annTrainIterations = 10   // Most k-means rounds when training the centroids
+	annDefaultProbes   = 16   // Lists searched per query when ANNOptions.Probes is unset
+)
+
+// ANNOptions control when searches go through the ANN index and how much of it they read
+type ANNOptions struct {
+	MinChunks int // Chunks of one dimension needed before an index is built and searched
+	Probes    int // Lists read per query; more finds more of the true nearest chunks but reads more rows
+}
+
+// annLists is an inverted file (IVF) index over the chunk embeddings of one dimension,
+// held by the vector index and saved in its snapshot. Embeddings are clustered around
+// centroids by spherical k-means and each chunk ID is kept in the list of its nearest
+// centroid. A search reads only the chunks of the lists nearest the query and scores them
+// exactly, trading a little recall for not reading the table. IDs of deleted chunks stay
+// in their lists until the next repair or rebuild; the searches' queries no longer find
+// them, so they only cost a lookup. The vector index's mu guards it
+type annLists struct {
+	opts      ANNOptions
+	dim       int         // Embedding dimension indexed, 0 until the lists are built
+	centroids [][]float32 // Unit-length centroid of each list
+	lists     [][]int64   // Chunk IDs nearest each centroid, ascending
+	lastID    int64       // Highest chunk ID the lists have seen; newer chunks are not in them yet
+	size      int         // Chunk IDs across the lists
+}
+
+// add puts a new chunk into the list of its nearest centroid when it has the indexed
+// dimension. Chunks the lists have already seen are skipped, so a catch-up racing a
+// rebuild adds nothing twice
+func (a *annLists) add(id int64, vec []float32) {
+	if a.dim == 0 || id <= a.lastID {
+		return
+	}
+	a.lastID = id
+	if len(vec) != a.dim {
+		return
+	}
+	list := nearestCentroid(a.centroids, vec)
+	a.lists[list] = append(a.lists[list], id)
+	a.size++
+}
+
+// drop removes the IDs of chunks not in live from the lists
+func (a *annLists) drop(live map[int64]bool) {
+	for i, list := range a.lists {
+		kept := list[:0]
+		for _, id := range list {
+			if live[id] {
+				kept = append(kept, id)
+			}
+		}
+		a.size -= len(list) - len(kept)
+		a.lists[i] = kept
+	}
+}
+
+// ANNIndexReport describes the ANN lists after a rebuild
+type ANNIndexReport struct {
+	Dimension int // Embedding dimension indexed, 0 when no lists are built
+	Lists     int // Lists the chunks are divided into
+	Size      int // Chunks indexed
+	Chunks    int // Chunks of the most common dimension at a rebuild; none are indexed below MinChunks
+}
+
+// RebuildANNIndex trains new centroids on the embeddings of the library's most common
+// dimension and reassigns every chunk of it to the vector index's ANN lists, dropping
+// deleted chunks from them. A library with fewer than MinChunks such chunks is left
+// without lists. Searches keep using the old lists until the new ones replace them.
+// Call SnapshotVectorIndex afterwards to save them
+func (s *Store) RebuildANNIndex(ctx context.Context) (*ANNIndexReport, error) {
+	index := s.vindex
+	if index == nil {
+		return nil, fmt.Errorf("vector index is not enabled")
+	}
+
+	var dim, count int
+	var maxID int64
+	err := s.reader().QueryRowContext(ctx, `
+		SELECT embedding_dim, COUNT(*), MAX(id) FROM chunks
+		WHERE embedding_dim > 0
+		GROUP BY embedding_dim
+		ORDER BY COUNT(*) DESC, embedding_dim
+		LIMIT 1
+	`).Scan(&dim, &count, &maxID)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to count chunk embeddings: %w", err)
+	}
+
+	report := &ANNIndexReport{Chunks: count}
+	if count == 0 || count < index.ann.opts.MinChunks {
+		index.mu.Lock()
+		index.ann = annLists{opts: index.ann.opts}
+		index.mu.Unlock()
+		return report, nil
+	}
+
+	lists := int(math.Sqrt(float64(count)))
+	lists = max(annMinLists, min(lists, annMaxLists))
+	lists = min(lists, count)
+
+	// Train on a uniform sample so building does not hold the library in memory
+	r := rand.New(rand.NewSource(1))
+	sample := make([][]float32, 0, lists*annTrainPerList)
+	seen := 0
+	err = s.scanEmbeddings(ctx, dim, 0, maxID, func(ids []int64, vecs [][]float32) {
+		for _, vec := range vecs {
+			seen++
+			if len(sample) < cap(sample) {
+				sample = append(sample, normalized(vec))
+			} else if j := r.Intn(seen); j < len(sample) {
+				sample[j] = normalized(vec)
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(sample) < lists {
+		// Chunks were deleted since they were counted
+		lists = max(len(sample), 1)
+	}
+	if len(sample) == 0 {
+		return report, nil
+	}
+	centroids := trainCentroids(sample, lists, r)
+
+	assigned := make([][]int64, len(centroids))
+	size := 0
+	err = s.scanEmbeddings(ctx, dim, 0, maxID, func(ids []int64, vecs [][]float32) {
+		for i, id := range ids {
+			list := nearestCentroid(centroids, vecs[i])
+			assigned[list] = append(assigned[list], id)
+			size++
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// A catch-up running on the old lists finishes first, and the chunks saved while the
+	// new ones were built are added before any other catch-up can skip past them
+	index.catchUp.Lock()
+	index.mu.Lock()
+	index.ann = annLists{opts: index.ann.opts, dim: dim, centroids: centroids, lists: assigned, lastID: maxID, size: size}
+	index.mu.Unlock()
+	err = s.scanEmbeddings(ctx, 0, maxID, math.MaxInt64, index.add)
+	index.catchUp.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	index.mu.RLock()
+	report.Dimension, report.Lists, report.Size = index.ann.dim, len(index.ann.lists), index.ann.size
+	index.mu.RUnlock()
+	return report, nil
+}
+
+// annCandidates returns the IDs of the chunks in the ANN lists nearest queryVec, ascending,
+// and the highest chunk ID the lists have seen. ok is false when the lists do not cover
+// queryVec's dimension or hold fewer than MinChunks chunks, and the table must be scanned
+func (s *Store) annCandidates(queryVec []float32) (ids []int64, lastID int64, ok bool) {
+	index := s.vindex
+	if index == nil {
+		return nil, 0, false
+	}
+	index.mu.RLock()
+	defer index.mu.RUnlock()
+	a := &index.ann
+	if a.dim == 0 || a.dim != len(queryVec) || a.size < a.opts.MinChunks {
+		return nil, 0, false
+	}
+
+	order := make([]int, len(a.centroids))
+	scores := make([]float64, len(a.centroids))
+	for i, centroid := range a.centroids {
+		order[i] = i
+		scores[i] = dot(centroid, queryVec)
+	}
+	sort.Slice(order, func(x, y int) bool { return scores[order[x]] > scores[order[y]] })
+
+	for _, list := range order[:min(a.opts.Probes, len(order))] {
+		ids = append(ids, a.lists[list]...)
+	}
+	sort.Slice(ids, func(x, y int) bool { return ids[x] < ids[y] })
+	return ids, a.lastID, true
+}
+
+// scanCandidatePages is scanChunkPages over the chunks with the given IDs, then the chunks
+// newer than lastID, so the pages arrive in id order as a full scan's would
+func (s *Store) scanCandidatePages(ctx context.Context, filter string, args []interface{}, ids []int64, lastID int64, fn func(page []Chunk) bool) error {
+	for start := 0; start < len(ids); start += vectorRepairBatchSize {
+		batch := ids[start:min(start+vectorRepairBatchSize, len(ids))]
+		pageArgs := append([]interface{}{}, args...)
+		for _, id := range batch {
+			pageArgs = append(pageArgs, id)
+		}
+		placeholders := strings.TrimSuffix(strings.Repeat("?,", len(batch)), ",")
+		query := `
+			SELECT ` + s.chunkColumns() + `
+			FROM chunks
+			WHERE ` + filter + ` AND id IN (` + placeholders + `)
+		`
+		page, err := s.readChunkPage(ctx, query, pageArgs)
+		if err != nil {
+			return err
+		}
+		if len(page) == 0 {
+			continue
+		}
+		if s.vindex != nil {
+			if err := s.fillEmbeddings(ctx, page); err != nil {
+				return err
+			}
+		}
+		if !fn(page) {
+			return nil
+		}
+	}
+
+	return s.scanChunkPages(ctx, filter+" AND id > ?", append(append([]interface{}{}, args...), lastID), fn)
+}
+
+// scanEmbeddings reads the embeddings of dimension dim, or of every dimension when dim is 0,
+// of the chunks with IDs after afterID up to maxID in id order, searchPageSize rows at a
+// time, and hands each page to fn. Like scanChunkPages, each page's rows are closed before fn runs
+func (s *Store) scanEmbeddings(ctx context.Context, dim int, afterID, maxID int64, fn func(ids []int64, vecs [][]float32)) error {
+	pageSize := s.searchPageSize
+	if pageSize <= 0 {
+		pageSize = defaultSearchPageSize
+	}
+
+	for {
+		ids, vecs, err := s.readEmbeddingPage(ctx, dim, afterID, maxID, pageSize)
+		if err != nil {
+			return err
+		}
+		if len(ids) > 0 {
+			fn(ids, vecs)
+		}
+		if len(ids) < pageSize {
+			return nil
+		}
+		afterID = ids[len(ids)-1]
+	}
+}
+
+// readEmbeddingPage reads one page of scanEmbeddings
+func (s *Store) readEmbeddingPage(ctx context.Context, dim int, afterID, maxID int64, pageSize int) ([]int64, [][]float32, error) {
+	rows, err := s.reader().QueryContext(ctx, `
+		SELECT id, embedding FROM chunks
+		WHERE (? = 0 OR embedding_dim = ?) AND id > ? AND id <= ?
+		ORDER BY id
+		LIMIT ?
+	`, dim, dim, afterID, maxID, pageSize)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load embeddings: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []int64
+	var vecs [][]float32
+	for rows.Next() {
+		var id int64
+		var embeddingBytes []byte
+		if err := rows.Scan(&id, &embeddingBytes); err != nil {
+			return nil, nil, fmt.Errorf("failed to scan embedding: %w", err)
+		}
+		ids = append(ids, id)
+		vecs = append(vecs, deserializeEmbedding(embeddingBytes))
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("error iterating embeddings: %w", err)
+	}
+	return ids, vecs, nil
+}
+
+// trainCentroids clusters unit-length vectors into k lists by spherical k-means, starting
+// from k distinct sample vectors, and returns the unit-length centroids. A list left empty
+// in a round starts over from a random sample vector
+func trainCentroids(sample [][]float32, k int, r *rand.Rand) [][]float32 {
+	centroids := make([][]float32, k)
+	for i, j := range r.Perm(len(sample))[:k] {
+		centroids[i] = append([]float32(nil), sample[j]...)
+	}
+
+	assignment := make([]int, len(sample))
+	for i := range assignment {
+		assignment[i] = -1
+	}
+	dim := len(sample[0])
+	for round := 0; round < annTrainIterations; round++ {
+		changed := 0
+		for i, vec := range sample {
+			if list := nearestCentroid(centroids, vec); list != assignment[i] {
+				assignment[i] = list
+				changed++
+			}
+		}
+		if changed == 0 {
+			break
+		}
+
+		sums := make([][]float32, k)
+		for i := range sums {
+			sums[i] = make([]float32, dim)
+		}
+		counts := make([]int, k)
+		for i, vec := range sample {
+			list := assignment[i]
+			counts[list]++
+			for d, x := range vec {
+				sums[list][d] += x
+			}
+		}
+		for list := range centroids {
+			if counts[list] == 0 {
+				centroids[list] = append([]float32(nil), sample[r.Intn(len(sample))]...)
+				continue
+			}
+			centroids[list] = normalized(sums[list])
+		}
+	}
+	return centroids
+}
+
+// nearestCentroid returns the list whose centroid is most similar to vec
+func nearestCentroid(centroids [][]float32, vec []float32) int {
+	best, bestScore := 0, math.Inf(-1)
+	for i, centroid := range centroids {
+		if score := dot(centroid, vec); score > bestScore {
+			best, bestScore = i, score
+		}
+	}
+	return best
+}
+
+// dot returns the dot product of two vectors of the same length
+func dot(a, b []float32) float64 {
+	var sum float64
+	for i := range a {
+		sum += float64(a[i] * b[i])
+	}
+	return sum
+}
+
+// normalized returns vec scaled to unit length, or vec itself when it is all zeros
+func normalized(vec []float32) []float32 {
+	norm := math.Sqrt(dot(vec, vec))
+	if norm == 0 {
+		return vec
+	}
+	out := make([]float32, len(vec))
+	for i, x := range vec {
+		out[i] = float32(float64(x) / norm)
+	}
+	return out
+}
+
+// encodeANNLists writes the lists in the vector index snapshot format: dimension, last seen
+// chunk ID and list count, then each centroid's little-endian float32 values, then per list
+// its ID count and IDs
+func encodeANNLists(w io.Writer, a *annLists) error {
+	header := []interface{}{uint32(a.dim), a.lastID, uint32(len(a.lists))}
+	for _, v := range header {
+		if err := binary.Write(w, binary.LittleEndian, v); err != nil {
+			return err
+		}
+	}
+	for _, centroid := range a.centroids {
+		if _, err := w.Write(serializeEmbedding(centroid)); err != nil {
+			return err
+		}
+	}
+	for _, list := range a.lists {
+		if err := binary.Write(w, binary.LittleEndian, uint32(len(list))); err != nil {
+			return err
+		}
+		if err := binary.Write(w, binary.LittleEndian, list); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decodeANNLists reads lists written by encodeANNLists from the start of body into a and
+// returns how many bytes they took
+func decodeANNLists(body []byte, a *annLists) (int, error) {
+	const headerSize = 4 + 8 + 4
+	if len(body) < headerSize {
+		return 0, errBadSnapshot
+	}
+	dim := int(binary.LittleEndian.Uint32(body))
+	lastID := int64(binary.LittleEndian.Uint64(body[4:]))
+	lists := int(binary.LittleEndian.Uint32(body[12:]))
+	pos := headerSize
+
+	if lists > 0 && (dim <= 0 || (len(body)-pos)/(dim*4) < lists) {
+		return 0, errBadSnapshot
+	}
+	centroids := make([][]float32, lists)
+	for i := range centroids {
+		centroids[i] = deserializeEmbedding(body[pos : pos+dim*4])
+		pos += dim * 4
+	}
+
+	ids := make([][]int64, lists)
+	size := 0
+	for i := range ids {
+		if len(body)-pos < 4 {
+			return 0, errBadSnapshot
+		}
+		count := int(binary.LittleEndian.Uint32(body[pos:]))
+		pos += 4
+		if (len(body)-pos)/8 < count {
+			return 0, errBadSnapshot
+		}
+		ids[i] = make([]int64, count)
+		for j := range ids[i] {
+			ids[i][j] = int64(binary.LittleEndian.Uint64(body[pos:]))
+			pos += 8
+		}
+		size += count
+	}
+
+	if lists == 0 {
+		dim, lastID = 0, 0
+	}
+	a.dim, a.centroids, a.lists, a.lastID, a.size = dim, centroids, ids, lastID, size
+	return pos, nil
+}
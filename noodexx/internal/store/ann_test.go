@@ -0,0 +1,200 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"testing"
+)
+
+// TestANNIndex tests building, searching, growing, repairing and snapshotting the ANN lists
+func TestANNIndex(t *testing.T) {
+	tmpFile := "test_ann.db"
+	snapshot := "test_ann.vecidx"
+	defer os.Remove(tmpFile)
+	defer os.Remove(snapshot)
+
+	store, err := NewStore(tmpFile, "multi")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+
+	userID, err := store.CreateUser(ctx, "annuser", "password", "ann@test.com", false, false)
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	// 8 clusters of 40 chunks, so the nearest lists hold each query's neighbors
+	const dims, clusters, perCluster = 16, 8, 40
+	r := rand.New(rand.NewSource(7))
+	centers := make([][]float32, clusters)
+	for i := range centers {
+		centers[i] = make([]float32, dims)
+		for d := range centers[i] {
+			centers[i][d] = r.Float32()*2 - 1
+		}
+	}
+	near := func(center []float32) []float32 {
+		vec := make([]float32, dims)
+		for d := range vec {
+			vec[d] = center[d] + (r.Float32()-0.5)*0.2
+		}
+		return vec
+	}
+	for i := 0; i < clusters*perCluster; i++ {
+		source := fmt.Sprintf("cluster-%d.txt", i%clusters)
+		if err := store.SaveChunk(ctx, userID, source, fmt.Sprintf("chunk %d", i), near(centers[i%clusters]), nil, ""); err != nil {
+			t.Fatalf("Failed to save chunk: %v", err)
+		}
+	}
+
+	// Below MinChunks nothing is built and searches scan the table
+	opened, err := store.OpenVectorIndex(snapshot, ANNOptions{MinChunks: 1000, Probes: 3})
+	if err != nil {
+		t.Fatalf("OpenVectorIndex failed: %v", err)
+	}
+	if opened.Snapshot == nil || opened.Lists != 0 {
+		t.Errorf("Expected a start without a snapshot, got %+v", opened)
+	}
+	report, err := store.RebuildANNIndex(ctx)
+	if err != nil {
+		t.Fatalf("RebuildANNIndex failed: %v", err)
+	}
+	if report.Chunks != clusters*perCluster || report.Dimension != 0 || report.Size != 0 {
+		t.Errorf("Expected no index below MinChunks, got %+v", report)
+	}
+	if _, _, ok := store.annCandidates(centers[0]); ok {
+		t.Error("Expected searches to scan without an index")
+	}
+
+	if _, err := store.OpenVectorIndex(snapshot, ANNOptions{MinChunks: 100, Probes: 3}); err != nil {
+		t.Fatalf("OpenVectorIndex failed: %v", err)
+	}
+	report, err = store.RebuildANNIndex(ctx)
+	if err != nil {
+		t.Fatalf("RebuildANNIndex failed: %v", err)
+	}
+	if report.Dimension != dims || report.Size != clusters*perCluster || report.Lists != annMinLists+1 {
+		t.Fatalf("Expected %d chunks of %d dimensions indexed in %d lists, got %+v", clusters*perCluster, dims, annMinLists+1, report)
+	}
+
+	// Searches read a fraction of the library and find what a full scan finds
+	exact := func(query []float32) []Chunk {
+		store.vindex.ann.opts.MinChunks = math.MaxInt
+		defer func() { store.vindex.ann.opts.MinChunks = 100 }()
+		results, err := store.SearchByUser(ctx, userID, query, "", 5)
+		if err != nil {
+			t.Fatalf("SearchByUser failed: %v", err)
+		}
+		return results
+	}
+	found, total := 0, 0
+	for i := 0; i < 20; i++ {
+		query := near(centers[i%clusters])
+		ids, _, ok := store.annCandidates(query)
+		if !ok || len(ids) >= clusters*perCluster {
+			t.Fatalf("Expected the index to narrow the search, got %d candidates", len(ids))
+		}
+		results, err := store.SearchByUser(ctx, userID, query, "", 5)
+		if err != nil {
+			t.Fatalf("SearchByUser failed: %v", err)
+		}
+		want := exact(query)
+		if len(results) != len(want) || results[0].ID != want[0].ID {
+			t.Fatalf("Expected the best match %+v, got %+v", want[0], results)
+		}
+		for _, w := range want {
+			total++
+			for _, got := range results {
+				if got.ID == w.ID && got.Score == w.Score {
+					found++
+				}
+			}
+		}
+	}
+	if found < total*9/10 {
+		t.Errorf("Expected at least 90%% of the exact results, got %d of %d", found, total)
+	}
+
+	// New chunks are added to the index as they are saved
+	if err := store.SaveChunk(ctx, userID, "new.txt", "saved alone", centers[3], nil, ""); err != nil {
+		t.Fatalf("Failed to save chunk: %v", err)
+	}
+	err = store.WithTx(ctx, func(tx StoreTx) error {
+		return tx.SaveChunk(ctx, userID, "new.txt", "saved in a transaction", near(centers[5]), nil, "")
+	})
+	if err != nil {
+		t.Fatalf("WithTx failed: %v", err)
+	}
+	if store.vindex.ann.size != clusters*perCluster+2 {
+		t.Errorf("Expected 2 chunks added to the lists, got %d", store.vindex.ann.size)
+	}
+	if _, ok := store.vindex.get(store.vindex.ann.lastID); !ok {
+		t.Error("Expected the new chunks' embeddings cached as they were added")
+	}
+	results, err := store.Search(ctx, centers[3], 1)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(results) != 1 || results[0].Text != "saved alone" {
+		t.Errorf("Expected the new chunk as the best match, got %+v", results)
+	}
+
+	// Deleted chunks drop out of results before the next rebuild
+	if err := store.DeleteChunksBySource(ctx, userID, "new.txt"); err != nil {
+		t.Fatalf("Failed to delete source: %v", err)
+	}
+	results, err = store.Search(ctx, centers[3], 1)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(results) != 1 || results[0].Source != "cluster-3.txt" {
+		t.Errorf("Expected a cluster chunk once the new one was deleted, got %+v", results)
+	}
+
+	// Repair drops them from the lists
+	repair, err := store.RepairVectorIndex(ctx)
+	if err != nil {
+		t.Fatalf("RepairVectorIndex failed: %v", err)
+	}
+	if repair.Lists != annMinLists+1 || store.vindex.ann.size != clusters*perCluster {
+		t.Errorf("Expected the deleted chunks dropped from the lists, got %d (%+v)", store.vindex.ann.size, repair)
+	}
+
+	// The vector index snapshot restores the lists
+	if err := store.SnapshotVectorIndex(); err != nil {
+		t.Fatalf("SnapshotVectorIndex failed: %v", err)
+	}
+	lastID := store.vindex.ann.lastID
+	opened, err = store.OpenVectorIndex(snapshot, ANNOptions{MinChunks: 100, Probes: 3})
+	if err != nil {
+		t.Fatalf("OpenVectorIndex failed: %v", err)
+	}
+	if opened.Snapshot != nil || opened.Lists != annMinLists+1 || store.vindex.ann.dim != dims || store.vindex.ann.size != clusters*perCluster || store.vindex.ann.lastID != lastID {
+		t.Errorf("Expected the lists restored from the snapshot, got %+v", opened)
+	}
+	if _, _, ok := store.annCandidates(centers[0]); !ok {
+		t.Error("Expected searches to use the restored lists")
+	}
+
+	// A corrupt snapshot starts without an index
+	data, err := os.ReadFile(snapshot)
+	if err != nil {
+		t.Fatalf("Failed to read snapshot: %v", err)
+	}
+	if err := os.WriteFile(snapshot, data[:len(data)-10], 0644); err != nil {
+		t.Fatalf("Failed to truncate snapshot: %v", err)
+	}
+	opened, err = store.OpenVectorIndex(snapshot, ANNOptions{MinChunks: 100, Probes: 3})
+	if err != nil {
+		t.Fatalf("OpenVectorIndex failed: %v", err)
+	}
+	if opened.Snapshot != errBadSnapshot || opened.Lists != 0 || opened.Loaded != 0 {
+		t.Errorf("Expected the corrupt snapshot rejected, got %+v", opened)
+	}
+}
@@ -0,0 +1,62 @@
+package store
+
+import (
+	"context"
+	"fmt"
+)
+
+// SaveMessageArtifacts stores the artifacts extracted from message messageID,
+// numbering them from 1 in the order given
+func (s *Store) SaveMessageArtifacts(ctx context.Context, messageID int64, artifacts []MessageArtifact) error {
+	if len(artifacts) == 0 {
+		return nil
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO message_artifacts (message_id, n, kind, language, content) VALUES (?, ?, ?, ?, ?)`
+	for i, a := range artifacts {
+		if _, err := tx.ExecContext(ctx, query, messageID, i+1, a.Kind, a.Language, a.Content); err != nil {
+			return fmt.Errorf("failed to save message artifact: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit message artifacts: %w", err)
+	}
+	return nil
+}
+
+// GetMessageArtifacts returns the artifacts of one of a user's messages in order
+// It returns nil if the message does not exist, belongs to another user or has no artifacts
+func (s *Store) GetMessageArtifacts(ctx context.Context, userID, messageID int64) ([]MessageArtifact, error) {
+	query := `
+		SELECT a.message_id, a.n, a.kind, a.language, a.content, a.created_at
+		FROM message_artifacts a
+		JOIN chat_messages m ON m.id = a.message_id
+		WHERE a.message_id = ? AND m.user_id = ?
+		ORDER BY a.n
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, messageID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query message artifacts: %w", err)
+	}
+	defer rows.Close()
+
+	var artifacts []MessageArtifact
+	for rows.Next() {
+		var a MessageArtifact
+		if err := rows.Scan(&a.MessageID, &a.N, &a.Kind, &a.Language, &a.Content, &a.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan message artifact: %w", err)
+		}
+		artifacts = append(artifacts, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating message artifacts: %w", err)
+	}
+	return artifacts, nil
+}
@@ -0,0 +1,57 @@
+package store
+
+import (
+	"context"
+	"testing"
+)
+
+// TestMessageArtifacts tests saving the artifacts of a message and reading them
+// back in order, for its owner only
+func TestMessageArtifacts(t *testing.T) {
+	store, err := NewStore(t.TempDir()+"/test_artifacts.db", "multi")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+
+	ownerID, _ := store.CreateUser(ctx, "owner", "password123", "owner@example.com", false, false)
+	otherID, _ := store.CreateUser(ctx, "other", "password123", "other@example.com", false, false)
+
+	messageID, err := store.SaveChatMessageWithProvenance(ctx, ownerID, "session-1", "assistant", "Here you go", "local", nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to save message: %v", err)
+	}
+
+	if err := store.SaveMessageArtifacts(ctx, messageID, []MessageArtifact{
+		{Kind: "code", Language: "go", Content: "package main"},
+		{Kind: "table", Content: "| a | b |\n|---|---|\n| 1 | 2 |"},
+	}); err != nil {
+		t.Fatalf("SaveMessageArtifacts failed: %v", err)
+	}
+
+	artifacts, err := store.GetMessageArtifacts(ctx, ownerID, messageID)
+	if err != nil {
+		t.Fatalf("GetMessageArtifacts failed: %v", err)
+	}
+	if len(artifacts) != 2 {
+		t.Fatalf("Expected 2 artifacts, got %+v", artifacts)
+	}
+	if a := artifacts[0]; a.N != 1 || a.Kind != "code" || a.Language != "go" || a.Content != "package main" || a.CreatedAt.IsZero() {
+		t.Errorf("Unexpected first artifact %+v", a)
+	}
+	if a := artifacts[1]; a.N != 2 || a.Kind != "table" || a.MessageID != messageID {
+		t.Errorf("Unexpected second artifact %+v", a)
+	}
+
+	// Other users cannot read them
+	if artifacts, err := store.GetMessageArtifacts(ctx, otherID, messageID); err != nil || artifacts != nil {
+		t.Errorf("Expected no artifacts for another user, got %+v (%v)", artifacts, err)
+	}
+
+	// Unknown kinds are rejected
+	if err := store.SaveMessageArtifacts(ctx, messageID, []MessageArtifact{{Kind: "image", Content: "x"}}); err == nil {
+		t.Error("Expected an error for an unknown artifact kind")
+	}
+}
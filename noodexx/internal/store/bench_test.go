@@ -0,0 +1,91 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+// benchDims is the embedding size of synthetic chunks, that of all-MiniLM-L6-v2
+const benchDims = 384
+
+// randomEmbedding returns a random vector of the given size
+func randomEmbedding(r *rand.Rand, dims int) []float32 {
+	v := make([]float32, dims)
+	for i := range v {
+		v[i] = r.Float32()*2 - 1
+	}
+	return v
+}
+
+// benchStore returns a store holding a user's synthetic library of n chunks,
+// in documents of 20 chunks
+func benchStore(b *testing.B, n int) (*Store, int64) {
+	b.Helper()
+	store, err := NewStore(b.TempDir()+"/bench.db", "multi")
+	if err != nil {
+		b.Fatalf("Failed to create store: %v", err)
+	}
+	b.Cleanup(func() { store.Close() })
+
+	ctx := context.Background()
+	userID, err := store.CreateUser(ctx, "bench", "password123", "bench@example.com", false, false)
+	if err != nil {
+		b.Fatalf("Failed to create user: %v", err)
+	}
+	r := rand.New(rand.NewSource(1))
+	for doc := 0; doc*20 < n; doc++ {
+		err := store.WithTx(ctx, func(tx StoreTx) error {
+			for i := doc * 20; i < min((doc+1)*20, n); i++ {
+				text := fmt.Sprintf("Synthetic chunk %d of document %d", i, doc)
+				if err := tx.SaveChunk(ctx, userID, fmt.Sprintf("doc-%d.md", doc), text, randomEmbedding(r, benchDims), nil, ""); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			b.Fatalf("Failed to populate library: %v", err)
+		}
+	}
+	return store, userID
+}
+
+func BenchmarkSaveChunk(b *testing.B) {
+	store, userID := benchStore(b, 0)
+	ctx := context.Background()
+	r := rand.New(rand.NewSource(2))
+	embeddings := make([][]float32, 64)
+	for i := range embeddings {
+		embeddings[i] = randomEmbedding(r, benchDims)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := store.SaveChunk(ctx, userID, fmt.Sprintf("doc-%d.md", i/20), "Synthetic chunk", embeddings[i%len(embeddings)], nil, ""); err != nil {
+			b.Fatalf("SaveChunk failed: %v", err)
+		}
+	}
+}
+
+func BenchmarkSearchByUser(b *testing.B) {
+	for _, n := range []int{1000, 10000} {
+		b.Run(fmt.Sprintf("chunks=%d", n), func(b *testing.B) {
+			store, userID := benchStore(b, n)
+			ctx := context.Background()
+			r := rand.New(rand.NewSource(3))
+			queries := make([][]float32, 32)
+			for i := range queries {
+				queries[i] = randomEmbedding(r, benchDims)
+			}
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := store.SearchByUser(ctx, userID, queries[i%len(queries)], "", 10); err != nil {
+					b.Fatalf("SearchByUser failed: %v", err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// ImportChatSession recreates a conversation from another tool's chat history
+// as a session of the user, keeping the times of its messages. Its messages are
+// saved with provider mode "external", as no provider here answered them. A
+// conversation the user already imported is skipped; false means it was
+func (s *Store) ImportChatSession(ctx context.Context, userID int64, session *ImportedSession) (bool, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO imported_sessions (session_id, user_id, format, external_id)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(user_id, format, external_id) DO NOTHING
+	`, session.SessionID, userID, session.Format, session.ExternalID)
+	if err != nil {
+		return false, fmt.Errorf("failed to record imported session: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return false, nil
+	}
+
+	createdAt := session.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = time.Now()
+	}
+	last := createdAt
+	for _, msg := range session.Messages {
+		at := msg.CreatedAt
+		if at.IsZero() {
+			at = last
+		}
+		last = at
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO chat_messages (session_id, role, content, user_id, provider_mode, created_at)
+			VALUES (?, ?, ?, ?, 'external', ?)
+		`, session.SessionID, msg.Role, msg.Content, userID, at.UTC().Format("2006-01-02 15:04:05"))
+		if err != nil {
+			return false, fmt.Errorf("failed to save imported message: %w", err)
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO sessions (id, user_id, title, created_at, last_message_at)
+		VALUES (?, ?, ?, ?, ?)
+	`, session.SessionID, userID, session.Title, createdAt.UTC().Format("2006-01-02 15:04:05"), last.UTC().Format("2006-01-02 15:04:05"))
+	if err != nil {
+		return false, fmt.Errorf("failed to create imported session: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return true, nil
+}
@@ -0,0 +1,88 @@
+package store
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+)
+
+// TestImportChatSession tests recreating an exported conversation as a session
+// with its message times, and skipping it when imported again
+func TestImportChatSession(t *testing.T) {
+	tmpFile := "test_chat_imports.db"
+	defer os.Remove(tmpFile)
+
+	store, err := NewStore(tmpFile, "multi")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+
+	userID, err := store.CreateUser(ctx, "importer", "password", "importer@test.com", false, false)
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	started := time.Date(2024, 3, 5, 12, 0, 0, 0, time.UTC)
+	session := &ImportedSession{
+		SessionID:  "imported-1",
+		Format:     "chatgpt",
+		ExternalID: "conv-1",
+		Title:      "Trip planning",
+		CreatedAt:  started,
+		Messages: []ImportedMessage{
+			{Role: "user", Content: "Where to go?", CreatedAt: started.Add(time.Minute)},
+			{Role: "assistant", Content: "Kyoto"},
+		},
+	}
+	created, err := store.ImportChatSession(ctx, userID, session)
+	if err != nil || !created {
+		t.Fatalf("Expected the session to be imported, got %v, %v", created, err)
+	}
+
+	messages, err := store.GetSessionMessages(ctx, userID, "imported-1")
+	if err != nil {
+		t.Fatalf("GetSessionMessages failed: %v", err)
+	}
+	if len(messages) != 2 || messages[0].Content != "Where to go?" || messages[1].ProviderMode != "external" {
+		t.Fatalf("Unexpected messages %+v", messages)
+	}
+	// A message without a time takes the one before it
+	var times []time.Time
+	rows, err := store.db.QueryContext(ctx, `SELECT created_at FROM chat_messages WHERE session_id = ? ORDER BY id`, "imported-1")
+	if err != nil {
+		t.Fatalf("Failed to query message times: %v", err)
+	}
+	for rows.Next() {
+		var at time.Time
+		if err := rows.Scan(&at); err != nil {
+			t.Fatalf("Failed to scan message time: %v", err)
+		}
+		times = append(times, at)
+	}
+	rows.Close()
+	if len(times) != 2 || !times[0].Equal(started.Add(time.Minute)) || !times[1].Equal(started.Add(time.Minute)) {
+		t.Errorf("Expected both messages at %v, got %v", started.Add(time.Minute), times)
+	}
+
+	sessions, err := store.GetUserSessions(ctx, userID)
+	if err != nil {
+		t.Fatalf("GetUserSessions failed: %v", err)
+	}
+	if len(sessions) != 1 || sessions[0].ImportedFrom != "chatgpt" || sessions[0].MessageCount != 2 {
+		t.Errorf("Expected the session flagged as imported, got %+v", sessions)
+	}
+
+	// The same conversation is not imported twice, even under a new session ID
+	again := *session
+	again.SessionID = "imported-2"
+	if created, err := store.ImportChatSession(ctx, userID, &again); err != nil || created {
+		t.Errorf("Expected the conversation to be skipped, got %v, %v", created, err)
+	}
+	if owner, err := store.GetSessionOwner(ctx, "imported-2"); err == nil {
+		t.Errorf("Expected no second session, found one owned by %d", owner)
+	}
+}
@@ -258,3 +258,46 @@ func TestChatHistoryOperations(t *testing.T) {
 		}
 	})
 }
+
+// TestSaveChatMessageWithCitations tests that cited sources round-trip in order
+func TestSaveChatMessageWithCitations(t *testing.T) {
+	tmpFile := "test_chat_citations.db"
+	defer os.Remove(tmpFile)
+
+	store, err := NewStore(tmpFile, "multi")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+
+	userID, err := store.CreateUser(ctx, "citer", "password", "citer@test.com", false, false)
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	if err := store.SaveChatMessage(ctx, userID, "cite-session", "user", "Question", ""); err != nil {
+		t.Fatalf("Failed to save user message: %v", err)
+	}
+
+	citations := []string{"https://example.com/a,b", "docs/handbook.md"}
+	if err := store.SaveChatMessageWithCitations(ctx, userID, "cite-session", "assistant", "Answer [1][2]", "local", citations); err != nil {
+		t.Fatalf("Failed to save assistant message: %v", err)
+	}
+
+	messages, err := store.GetSessionMessages(ctx, userID, "cite-session")
+	if err != nil {
+		t.Fatalf("Failed to get session messages: %v", err)
+	}
+	if len(messages) != 2 {
+		t.Fatalf("Expected 2 messages, got %d", len(messages))
+	}
+
+	if len(messages[0].Citations) != 0 {
+		t.Errorf("Expected no citations on user message, got %v", messages[0].Citations)
+	}
+	if len(messages[1].Citations) != 2 || messages[1].Citations[0] != citations[0] || messages[1].Citations[1] != citations[1] {
+		t.Errorf("Expected citations %v, got %v", citations, messages[1].Citations)
+	}
+}
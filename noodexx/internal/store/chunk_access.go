@@ -0,0 +1,114 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// RecordChunkAccess logs access for each of chunkIDs that carries tag, with the
+// owner and source of the chunk. It returns how many accesses were logged
+func (s *Store) RecordChunkAccess(ctx context.Context, access ChunkAccess, chunkIDs []int64, tag string) (int, error) {
+	if len(chunkIDs) == 0 {
+		return 0, nil
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(chunkIDs)), ",")
+	args := make([]interface{}, len(chunkIDs))
+	for i, id := range chunkIDs {
+		args[i] = id
+	}
+	rows, err := s.db.QueryContext(ctx, `SELECT id, user_id, source, COALESCE(tags, '') FROM chunks WHERE id IN (`+placeholders+`)`, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query chunk tags: %w", err)
+	}
+	type taggedChunk struct {
+		id, owner int64
+		source    string
+	}
+	var tagged []taggedChunk
+	for rows.Next() {
+		var c taggedChunk
+		var tags string
+		if err := rows.Scan(&c.id, &c.owner, &c.source, &tags); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan chunk tags: %w", err)
+		}
+		for _, t := range splitTags(tags) {
+			if strings.EqualFold(t, tag) {
+				tagged = append(tagged, c)
+				break
+			}
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("error iterating chunk tags: %w", err)
+	}
+	if len(tagged) == 0 {
+		return 0, nil
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO chunk_access_log (owner_id, user_id, chunk_id, source, session_id, query, provider, provider_mode)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`
+	for _, c := range tagged {
+		if _, err := tx.ExecContext(ctx, query, c.owner, access.UserID, c.id, c.source, access.SessionID, access.Query, access.Provider, access.ProviderMode); err != nil {
+			return 0, fmt.Errorf("failed to record chunk access: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit chunk access: %w", err)
+	}
+	return len(tagged), nil
+}
+
+// GetChunkAccessLog returns the logged accesses to an owner's chunks since a
+// time, newest first, optionally for one source. A limit of 0 returns them all
+func (s *Store) GetChunkAccessLog(ctx context.Context, ownerID int64, source string, since time.Time, limit int) ([]ChunkAccess, error) {
+	query := `
+		SELECT a.id, a.owner_id, a.user_id, COALESCE(u.username, ''), a.chunk_id, a.source,
+			a.session_id, a.query, a.provider, a.provider_mode, a.accessed_at
+		FROM chunk_access_log a
+		LEFT JOIN users u ON u.id = a.user_id
+		WHERE a.owner_id = ? AND a.accessed_at >= ?
+	`
+	args := []interface{}{ownerID, since.UTC().Format("2006-01-02 15:04:05")}
+	if source != "" {
+		query += ` AND a.source = ?`
+		args = append(args, source)
+	}
+	query += ` ORDER BY a.accessed_at DESC, a.id DESC`
+	if limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, limit)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query chunk access log: %w", err)
+	}
+	defer rows.Close()
+
+	var log []ChunkAccess
+	for rows.Next() {
+		var a ChunkAccess
+		var accessedAt sql.NullTime
+		if err := rows.Scan(&a.ID, &a.OwnerID, &a.UserID, &a.Username, &a.ChunkID, &a.Source,
+			&a.SessionID, &a.Query, &a.Provider, &a.ProviderMode, &accessedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan chunk access: %w", err)
+		}
+		a.AccessedAt = accessedAt.Time
+		log = append(log, a)
+	}
+	return log, rows.Err()
+}
@@ -0,0 +1,65 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// TestChunkAccessLog tests that only chunks with the tag are logged, for their
+// owner, and that the log can be filtered by source
+func TestChunkAccessLog(t *testing.T) {
+	store, err := NewStore(t.TempDir()+"/test_chunk_access.db", "multi")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+
+	alice, _ := store.CreateUser(ctx, "alice", "password123", "alice@example.com", false, false)
+	bob, _ := store.CreateUser(ctx, "bob", "password123", "bob@example.com", false, false)
+
+	embedding := []float32{0.1, 0.2}
+	store.SaveChunk(ctx, alice, "salaries.csv", "Alice earns a lot", embedding, []string{"hr", " Sensitive"}, "")
+	store.SaveChunk(ctx, alice, "handbook.md", "Leave policy", embedding, []string{"hr"}, "")
+	salaries, _ := store.GetSourceChunks(ctx, alice, "salaries.csv")
+	handbook, _ := store.GetSourceChunks(ctx, alice, "handbook.md")
+
+	access := ChunkAccess{UserID: bob, SessionID: "s1", Query: "Who earns the most?", Provider: "openai", ProviderMode: "cloud"}
+	logged, err := store.RecordChunkAccess(ctx, access, []int64{salaries[0].ID, handbook[0].ID}, "sensitive")
+	if err != nil {
+		t.Fatalf("RecordChunkAccess failed: %v", err)
+	}
+	if logged != 1 {
+		t.Errorf("Expected only the sensitive chunk logged, got %d", logged)
+	}
+
+	entries, err := store.GetChunkAccessLog(ctx, alice, "", time.Now().Add(-time.Hour), 0)
+	if err != nil {
+		t.Fatalf("GetChunkAccessLog failed: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("Expected 1 entry, got %d", len(entries))
+	}
+	e := entries[0]
+	if e.OwnerID != alice || e.UserID != bob || e.Username != "bob" || e.Source != "salaries.csv" ||
+		e.ChunkID != salaries[0].ID || e.Query != access.Query || e.Provider != "openai" || e.ProviderMode != "cloud" {
+		t.Errorf("Unexpected entry %+v", e)
+	}
+
+	// Accesses are reported to the owner only, and outlive the document
+	if entries, _ := store.GetChunkAccessLog(ctx, bob, "", time.Time{}, 0); len(entries) != 0 {
+		t.Errorf("Expected no entries for bob, got %d", len(entries))
+	}
+	store.DeleteDocument(ctx, alice, "salaries.csv")
+	if entries, _ := store.GetChunkAccessLog(ctx, alice, "salaries.csv", time.Time{}, 0); len(entries) != 1 {
+		t.Errorf("Expected the entry kept after deleting the document, got %d", len(entries))
+	}
+	if entries, _ := store.GetChunkAccessLog(ctx, alice, "handbook.md", time.Time{}, 0); len(entries) != 0 {
+		t.Errorf("Expected no entries for another source, got %d", len(entries))
+	}
+	if entries, _ := store.GetChunkAccessLog(ctx, alice, "", time.Now().Add(time.Hour), 0); len(entries) != 0 {
+		t.Errorf("Expected no entries after since, got %d", len(entries))
+	}
+}
@@ -0,0 +1,216 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// AcquireLock takes the named lock for owner until ttl from now, returning false
+// when another owner holds it. The owner already holding the lock extends it,
+// and a lock whose holder let it expire can be taken over
+func (s *Store) AcquireLock(ctx context.Context, name, owner string, ttl time.Duration) (bool, error) {
+	now := time.Now()
+	query := `
+		INSERT INTO cluster_locks (name, owner, expires_at)
+		VALUES (?, ?, ?)
+		ON CONFLICT(name) DO UPDATE SET
+			owner = excluded.owner,
+			expires_at = excluded.expires_at
+		WHERE cluster_locks.owner = excluded.owner OR cluster_locks.expires_at < ?
+	`
+	result, err := s.db.ExecContext(ctx, query, name, owner, now.Add(ttl).UnixMilli(), now.UnixMilli())
+	if err != nil {
+		return false, fmt.Errorf("failed to acquire lock %s: %w", name, err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to acquire lock %s: %w", name, err)
+	}
+	return n == 1, nil
+}
+
+// ReleaseLock gives up the named lock if owner holds it
+func (s *Store) ReleaseLock(ctx context.Context, name, owner string) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM cluster_locks WHERE name = ? AND owner = ?`, name, owner)
+	if err != nil {
+		return fmt.Errorf("failed to release lock %s: %w", name, err)
+	}
+	return nil
+}
+
+// PublishClusterEvent records an event for the other instances and returns its ID
+func (s *Store) PublishClusterEvent(ctx context.Context, origin, kind, payload string) (int64, error) {
+	query := `INSERT INTO cluster_events (origin, kind, payload, created_at) VALUES (?, ?, ?, ?)`
+	result, err := s.db.ExecContext(ctx, query, origin, kind, payload, time.Now())
+	if err != nil {
+		return 0, fmt.Errorf("failed to publish cluster event: %w", err)
+	}
+	return result.LastInsertId()
+}
+
+// GetClusterEventsAfter returns up to limit events newer than afterID, oldest first
+func (s *Store) GetClusterEventsAfter(ctx context.Context, afterID int64, limit int) ([]ClusterEvent, error) {
+	query := `
+		SELECT id, origin, kind, payload, created_at
+		FROM cluster_events
+		WHERE id > ?
+		ORDER BY id
+		LIMIT ?
+	`
+	rows, err := s.db.QueryContext(ctx, query, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query cluster events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []ClusterEvent
+	for rows.Next() {
+		var event ClusterEvent
+		if err := rows.Scan(&event.ID, &event.Origin, &event.Kind, &event.Payload, &event.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan cluster event: %w", err)
+		}
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating cluster events: %w", err)
+	}
+	return events, nil
+}
+
+// LatestClusterEventID returns the ID of the newest event, 0 if there are none
+func (s *Store) LatestClusterEventID(ctx context.Context) (int64, error) {
+	var id int64
+	if err := s.db.QueryRowContext(ctx, `SELECT COALESCE(MAX(id), 0) FROM cluster_events`).Scan(&id); err != nil {
+		return 0, fmt.Errorf("failed to get latest cluster event: %w", err)
+	}
+	return id, nil
+}
+
+// EnqueueClusterTask queues a task for any instance to claim and returns its ID
+func (s *Store) EnqueueClusterTask(ctx context.Context, kind, payload string) (int64, error) {
+	query := `INSERT INTO cluster_tasks (kind, payload, created_at) VALUES (?, ?, ?)`
+	result, err := s.db.ExecContext(ctx, query, kind, payload, time.Now())
+	if err != nil {
+		return 0, fmt.Errorf("failed to enqueue cluster task: %w", err)
+	}
+	return result.LastInsertId()
+}
+
+// ClaimClusterTask claims the oldest task of one of kinds for owner until lease
+// from now, or returns nil when there is none. Tasks whose claim expired, because
+// the instance working on them stopped, are claimed again
+func (s *Store) ClaimClusterTask(ctx context.Context, owner string, kinds []string, lease time.Duration) (*ClusterTask, error) {
+	if len(kinds) == 0 {
+		return nil, nil
+	}
+	now := time.Now()
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(kinds)), ", ")
+	query := `
+		UPDATE cluster_tasks
+		SET status = 'running', claimed_by = ?, claimed_until = ?, attempts = attempts + 1
+		WHERE id = (
+			SELECT id FROM cluster_tasks
+			WHERE kind IN (` + placeholders + `)
+				AND (status = 'pending' OR (status = 'running' AND claimed_until < ?))
+			ORDER BY id
+			LIMIT 1
+		)
+		RETURNING id, kind, payload, status, claimed_by, attempts, result, error, created_at, finished_at
+	`
+	args := []interface{}{owner, now.Add(lease).UnixMilli()}
+	for _, kind := range kinds {
+		args = append(args, kind)
+	}
+	args = append(args, now.UnixMilli())
+
+	task, err := scanClusterTask(s.db.QueryRowContext(ctx, query, args...))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim cluster task: %w", err)
+	}
+	return task, nil
+}
+
+// RenewClusterTask extends owner's claim on a running task until lease from now
+func (s *Store) RenewClusterTask(ctx context.Context, id int64, owner string, lease time.Duration) error {
+	query := `UPDATE cluster_tasks SET claimed_until = ? WHERE id = ? AND claimed_by = ? AND status = 'running'`
+	result, err := s.db.ExecContext(ctx, query, time.Now().Add(lease).UnixMilli(), id, owner)
+	if err != nil {
+		return fmt.Errorf("failed to renew cluster task %d: %w", id, err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("cluster task %d is no longer claimed by %s", id, owner)
+	}
+	return nil
+}
+
+// FinishClusterTask records the outcome of owner's task; an empty errMsg marks
+// it done, anything else failed
+func (s *Store) FinishClusterTask(ctx context.Context, id int64, owner, result, errMsg string) error {
+	status := "done"
+	if errMsg != "" {
+		status = "failed"
+	}
+	query := `
+		UPDATE cluster_tasks
+		SET status = ?, result = ?, error = ?, finished_at = ?
+		WHERE id = ? AND claimed_by = ?
+	`
+	if _, err := s.db.ExecContext(ctx, query, status, result, errMsg, time.Now(), id, owner); err != nil {
+		return fmt.Errorf("failed to finish cluster task %d: %w", id, err)
+	}
+	return nil
+}
+
+// GetClusterTask returns a task, or nil if it does not exist
+func (s *Store) GetClusterTask(ctx context.Context, id int64) (*ClusterTask, error) {
+	query := `
+		SELECT id, kind, payload, status, claimed_by, attempts, result, error, created_at, finished_at
+		FROM cluster_tasks
+		WHERE id = ?
+	`
+	task, err := scanClusterTask(s.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cluster task %d: %w", id, err)
+	}
+	return task, nil
+}
+
+// PruneCluster deletes events older than eventsBefore and finished tasks older
+// than tasksBefore, returning how many rows were removed
+func (s *Store) PruneCluster(ctx context.Context, eventsBefore, tasksBefore time.Time) (int64, error) {
+	events, err := s.db.ExecContext(ctx, `DELETE FROM cluster_events WHERE created_at < ?`, eventsBefore)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune cluster events: %w", err)
+	}
+	tasks, err := s.db.ExecContext(ctx, `DELETE FROM cluster_tasks WHERE status IN ('done', 'failed') AND finished_at < ?`, tasksBefore)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune cluster tasks: %w", err)
+	}
+	nEvents, _ := events.RowsAffected()
+	nTasks, _ := tasks.RowsAffected()
+	return nEvents + nTasks, nil
+}
+
+// scanClusterTask reads one task in the column order used above
+func scanClusterTask(row rowScanner) (*ClusterTask, error) {
+	var task ClusterTask
+	var finishedAt sql.NullTime
+	err := row.Scan(&task.ID, &task.Kind, &task.Payload, &task.Status, &task.ClaimedBy, &task.Attempts,
+		&task.Result, &task.Error, &task.CreatedAt, &finishedAt)
+	if err != nil {
+		return nil, err
+	}
+	if finishedAt.Valid {
+		task.FinishedAt = &finishedAt.Time
+	}
+	return &task, nil
+}
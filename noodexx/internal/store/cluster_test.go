@@ -0,0 +1,120 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestClusterLocks(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	acquire := func(owner string, ttl time.Duration) bool {
+		t.Helper()
+		ok, err := store.AcquireLock(ctx, "watcher", owner, ttl)
+		if err != nil {
+			t.Fatalf("AcquireLock failed: %v", err)
+		}
+		return ok
+	}
+
+	if !acquire("node-a", time.Minute) {
+		t.Fatal("Expected node-a to take a free lock")
+	}
+	if acquire("node-b", time.Minute) {
+		t.Error("Expected node-b to be refused a held lock")
+	}
+	if !acquire("node-a", time.Minute) {
+		t.Error("Expected node-a to extend its own lock")
+	}
+
+	// Releasing as someone else leaves the lock alone
+	if err := store.ReleaseLock(ctx, "watcher", "node-b"); err != nil {
+		t.Fatalf("ReleaseLock failed: %v", err)
+	}
+	if acquire("node-b", time.Minute) {
+		t.Error("Expected the lock to stay with node-a")
+	}
+	if err := store.ReleaseLock(ctx, "watcher", "node-a"); err != nil {
+		t.Fatalf("ReleaseLock failed: %v", err)
+	}
+
+	// An expired lock is taken over
+	if !acquire("node-b", -time.Second) {
+		t.Fatal("Expected node-b to take the released lock")
+	}
+	if !acquire("node-a", time.Minute) {
+		t.Error("Expected node-a to take over an expired lock")
+	}
+}
+
+func TestClusterEventsAndTasks(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	for _, payload := range []string{"one", "two", "three"} {
+		if _, err := store.PublishClusterEvent(ctx, "node-a", "websocket", payload); err != nil {
+			t.Fatalf("PublishClusterEvent failed: %v", err)
+		}
+	}
+	latest, err := store.LatestClusterEventID(ctx)
+	if err != nil {
+		t.Fatalf("LatestClusterEventID failed: %v", err)
+	}
+	events, err := store.GetClusterEventsAfter(ctx, latest-2, 10)
+	if err != nil {
+		t.Fatalf("GetClusterEventsAfter failed: %v", err)
+	}
+	if len(events) != 2 || events[0].Payload != "two" || events[1].ID != latest || events[1].Origin != "node-a" {
+		t.Errorf("Expected the last two events in order, got %+v", events)
+	}
+
+	id, err := store.EnqueueClusterTask(ctx, "folder_retry", `{"path":"/a.txt"}`)
+	if err != nil {
+		t.Fatalf("EnqueueClusterTask failed: %v", err)
+	}
+	if task, err := store.ClaimClusterTask(ctx, "node-a", []string{"other"}, time.Minute); err != nil || task != nil {
+		t.Fatalf("Expected no task of another kind, got %+v, %v", task, err)
+	}
+
+	// A claim that expires lets another instance take the task
+	task, err := store.ClaimClusterTask(ctx, "node-a", []string{"folder_retry"}, -time.Second)
+	if err != nil || task == nil || task.ID != id || task.Attempts != 1 {
+		t.Fatalf("Expected node-a to claim the task, got %+v, %v", task, err)
+	}
+	task, err = store.ClaimClusterTask(ctx, "node-b", []string{"folder_retry"}, time.Minute)
+	if err != nil || task == nil || task.ClaimedBy != "node-b" || task.Attempts != 2 {
+		t.Fatalf("Expected node-b to reclaim the expired task, got %+v, %v", task, err)
+	}
+	if task, err := store.ClaimClusterTask(ctx, "node-a", []string{"folder_retry"}, time.Minute); err != nil || task != nil {
+		t.Fatalf("Expected a claimed task not to be claimed twice, got %+v, %v", task, err)
+	}
+	if err := store.RenewClusterTask(ctx, id, "node-a", time.Minute); err == nil {
+		t.Error("Expected node-a's renewal to fail after losing the task")
+	}
+	if err := store.RenewClusterTask(ctx, id, "node-b", time.Minute); err != nil {
+		t.Errorf("RenewClusterTask failed: %v", err)
+	}
+
+	if err := store.FinishClusterTask(ctx, id, "node-b", "", "file not found"); err != nil {
+		t.Fatalf("FinishClusterTask failed: %v", err)
+	}
+	task, err = store.GetClusterTask(ctx, id)
+	if err != nil || task == nil || task.Status != "failed" || task.Error != "file not found" || task.FinishedAt == nil {
+		t.Fatalf("Expected the failed task, got %+v, %v", task, err)
+	}
+
+	removed, err := store.PruneCluster(ctx, time.Now().Add(time.Second), time.Now().Add(time.Second))
+	if err != nil {
+		t.Fatalf("PruneCluster failed: %v", err)
+	}
+	if removed != 4 {
+		t.Errorf("Expected 3 events and 1 task pruned, got %d", removed)
+	}
+	if task, _ := store.GetClusterTask(ctx, id); task != nil {
+		t.Error("Expected the finished task to be pruned")
+	}
+}
@@ -0,0 +1,121 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// SaveDocumentContent keeps the text a user's document was chunked from and
+// the settings of the chunker used, replacing what was kept before
+func (s *Store) SaveDocumentContent(ctx context.Context, userID int64, source, content, chunker string) error {
+	return saveDocumentContent(ctx, s.db, userID, source, content, chunker)
+}
+
+// saveDocumentContent upserts a document's content using the given connection or transaction
+func saveDocumentContent(ctx context.Context, ex execer, userID int64, source, content, chunker string) error {
+	_, err := ex.ExecContext(ctx, `
+		INSERT INTO document_contents (user_id, source, content, chunker, updated_at)
+		VALUES (?, ?, ?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT (user_id, source) DO UPDATE SET
+			content = excluded.content,
+			chunker = excluded.chunker,
+			updated_at = excluded.updated_at
+	`, userID, source, content, chunker)
+	if err != nil {
+		return fmt.Errorf("failed to save document content: %w", err)
+	}
+	return nil
+}
+
+// GetDocumentContent returns the text one of the user's documents was chunked
+// from, or nil if it was not kept
+func (s *Store) GetDocumentContent(ctx context.Context, userID int64, source string) (*DocumentContent, error) {
+	var c DocumentContent
+	err := s.db.QueryRowContext(ctx, `
+		SELECT source, content, chunker, updated_at
+		FROM document_contents
+		WHERE user_id = ? AND source = ?
+	`, userID, source).Scan(&c.Source, &c.Content, &c.Chunker, &c.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get document content: %w", err)
+	}
+	return &c, nil
+}
+
+// GetChunkedDocuments lists the user's documents with the chunker settings
+// their chunks came from, by source
+func (s *Store) GetChunkedDocuments(ctx context.Context, userID int64) ([]ChunkedDocument, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT d.source, c.source IS NOT NULL, COALESCE(c.chunker, '')
+		FROM documents d
+		LEFT JOIN document_contents c ON c.user_id = d.user_id AND c.source = d.source
+		WHERE d.user_id = ? AND EXISTS (SELECT 1 FROM chunks WHERE document_id = d.id)
+		ORDER BY d.source
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list chunked documents: %w", err)
+	}
+	defer rows.Close()
+
+	var documents []ChunkedDocument
+	for rows.Next() {
+		var d ChunkedDocument
+		if err := rows.Scan(&d.Source, &d.Stored, &d.Chunker); err != nil {
+			return nil, fmt.Errorf("failed to scan chunked document: %w", err)
+		}
+		documents = append(documents, d)
+	}
+	return documents, rows.Err()
+}
+
+// ReplaceChunks swaps the chunks of a user's document for new ones, keeping
+// its tags, summary and sharing. See replaceChunks
+func (s *Store) ReplaceChunks(ctx context.Context, userID int64, source string, texts []string, embeddings [][]float32) error {
+	if err := replaceChunks(ctx, s.db, s.embeddingModel, userID, source, texts, embeddings); err != nil {
+		return err
+	}
+	s.indexNewChunks(ctx)
+	return nil
+}
+
+// replaceChunks inserts the new chunks with the per-document columns of the
+// document's first chunk, then deletes the chunks that were there before. Run
+// it in a transaction so a failure leaves the old chunks in place
+func replaceChunks(ctx context.Context, ex execer, embeddingModel string, userID int64, source string, texts []string, embeddings [][]float32) error {
+	if len(texts) == 0 || len(texts) != len(embeddings) {
+		return fmt.Errorf("got %d embeddings for %d chunks", len(embeddings), len(texts))
+	}
+
+	// The first chunk keeps the lowest ID while new chunks are added after it
+	query := `
+		INSERT INTO chunks (user_id, source, text, embedding, tags, summary, visibility, shared_with, embedding_model, embedding_dim, document_id)
+		SELECT user_id, source, ?, ?, tags, summary, visibility, shared_with, ?, ?, document_id
+		FROM chunks
+		WHERE id = (SELECT MIN(id) FROM chunks WHERE user_id = ? AND source = ?)
+	`
+	var firstNew int64
+	for i, text := range texts {
+		result, err := ex.ExecContext(ctx, query, text, serializeEmbedding(embeddings[i]), embeddingModel, len(embeddings[i]), userID, source)
+		if err != nil {
+			return fmt.Errorf("failed to save chunk: %w", err)
+		}
+		if i > 0 {
+			continue
+		}
+		if n, err := result.RowsAffected(); err != nil || n == 0 {
+			return fmt.Errorf("document %s has no chunks to replace", source)
+		}
+		if firstNew, err = result.LastInsertId(); err != nil {
+			return fmt.Errorf("failed to get chunk ID: %w", err)
+		}
+	}
+
+	if _, err := ex.ExecContext(ctx, `DELETE FROM chunks WHERE user_id = ? AND source = ? AND id < ?`, userID, source, firstNew); err != nil {
+		return fmt.Errorf("failed to delete replaced chunks: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// TestReplaceChunks tests that a document's chunks are swapped for new ones
+// keeping its tags, summary and visibility, and that a failed replacement
+// leaves the old chunks in place
+func TestReplaceChunks(t *testing.T) {
+	store, err := NewStore(t.TempDir()+"/test_contents.db", "multi")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+	userID, _ := store.CreateUser(ctx, "alice", "password123", "alice@example.com", false, false)
+
+	err = store.WithTx(ctx, func(tx StoreTx) error {
+		tx.SaveChunk(ctx, userID, "guide.md", "Old one", []float32{1, 0}, []string{"manual"}, "A guide")
+		tx.SaveChunk(ctx, userID, "guide.md", "Old two", []float32{0, 1}, []string{"manual"}, "A guide")
+		if err := tx.SetSourceVisibility(ctx, userID, "guide.md", "public"); err != nil {
+			return err
+		}
+		return tx.SaveDocumentContent(ctx, userID, "guide.md", "Old one Old two", "size 500, overlap 50")
+	})
+	if err != nil {
+		t.Fatalf("Failed to save document: %v", err)
+	}
+	store.SaveChunk(ctx, userID, "notes.md", "Notes", []float32{1, 1}, nil, "")
+
+	docs, err := store.GetChunkedDocuments(ctx, userID)
+	if err != nil || len(docs) != 2 {
+		t.Fatalf("Expected 2 documents, got %+v (%v)", docs, err)
+	}
+	if docs[0].Source != "guide.md" || !docs[0].Stored || docs[0].Chunker != "size 500, overlap 50" || docs[1].Stored {
+		t.Errorf("Unexpected documents %+v", docs)
+	}
+
+	err = store.WithTx(ctx, func(tx StoreTx) error {
+		if err := tx.ReplaceChunks(ctx, userID, "guide.md", []string{"New one", "New two", "New three"}, [][]float32{{1, 0}, {0, 1}, {1, 1}}); err != nil {
+			return err
+		}
+		return tx.SaveDocumentContent(ctx, userID, "guide.md", "Old one Old two", "size 200, overlap 20")
+	})
+	if err != nil {
+		t.Fatalf("ReplaceChunks failed: %v", err)
+	}
+	texts, _ := store.GetSourceTexts(ctx, userID, "guide.md")
+	if len(texts) != 3 || texts[0] != "New one" || texts[2] != "New three" {
+		t.Errorf("Expected the new chunks in order, got %v", texts)
+	}
+	var tags, summary, visibility string
+	var wrongDocument int
+	store.db.QueryRowContext(ctx, `SELECT tags, summary, visibility FROM chunks WHERE text = 'New three'`).Scan(&tags, &summary, &visibility)
+	store.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM chunks WHERE source = 'guide.md' AND document_id IS NOT (SELECT id FROM documents WHERE source = 'guide.md')`).Scan(&wrongDocument)
+	if tags != "manual" || summary != "A guide" || visibility != "public" || wrongDocument != 0 {
+		t.Errorf("Expected document columns kept, got tags %q summary %q visibility %q (%d outside the document)", tags, summary, visibility, wrongDocument)
+	}
+	if content, _ := store.GetDocumentContent(ctx, userID, "guide.md"); content == nil || content.Chunker != "size 200, overlap 20" {
+		t.Errorf("Expected the new chunker recorded, got %+v", content)
+	}
+
+	// A failure after the swap rolls it back
+	err = store.WithTx(ctx, func(tx StoreTx) error {
+		if err := tx.ReplaceChunks(ctx, userID, "guide.md", []string{"Lost"}, [][]float32{{1, 0}}); err != nil {
+			return err
+		}
+		return errors.New("embedding failed")
+	})
+	if texts, _ := store.GetSourceTexts(ctx, userID, "guide.md"); err == nil || len(texts) != 3 {
+		t.Errorf("Expected the chunks kept after a failed replacement, got %v", texts)
+	}
+	if err := store.ReplaceChunks(ctx, userID, "missing.md", []string{"x"}, [][]float32{{1}}); err == nil {
+		t.Error("Expected an error replacing the chunks of a document without any")
+	}
+
+	// Deleting the document deletes its content
+	if err := store.DeleteDocument(ctx, userID, "guide.md"); err != nil {
+		t.Fatalf("DeleteDocument failed: %v", err)
+	}
+	if content, err := store.GetDocumentContent(ctx, userID, "guide.md"); err != nil || content != nil {
+		t.Errorf("Expected no content after deletion, got %+v (%v)", content, err)
+	}
+}
@@ -0,0 +1,90 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// SaveCostEstimate records the estimated size and cost of a request to a
+// paid provider, and whether it was sent
+func (s *Store) SaveCostEstimate(ctx context.Context, e CostEstimate) error {
+	var cost interface{}
+	if e.CostUSD != nil {
+		cost = *e.CostUSD
+	}
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO cost_estimates (user_id, session_id, provider, model, prompt_tokens, completion_tokens, cost_usd, threshold_usd, outcome)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, e.UserID, e.SessionID, e.Provider, e.Model, e.PromptTokens, e.CompletionTokens, cost, e.ThresholdUSD, e.Outcome)
+	if err != nil {
+		return fmt.Errorf("failed to save cost estimate: %w", err)
+	}
+	return nil
+}
+
+// GetCostEstimates returns a user's most recent cost estimates, newest first
+func (s *Store) GetCostEstimates(ctx context.Context, userID int64, limit int) ([]CostEstimate, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, user_id, session_id, provider, model, prompt_tokens, completion_tokens, cost_usd, threshold_usd, outcome, created_at
+		FROM cost_estimates
+		WHERE user_id = ?
+		ORDER BY created_at DESC, id DESC
+		LIMIT ?
+	`, userID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query cost estimates: %w", err)
+	}
+	defer rows.Close()
+
+	var estimates []CostEstimate
+	for rows.Next() {
+		var e CostEstimate
+		var cost sql.NullFloat64
+		if err := rows.Scan(&e.ID, &e.UserID, &e.SessionID, &e.Provider, &e.Model, &e.PromptTokens, &e.CompletionTokens, &cost, &e.ThresholdUSD, &e.Outcome, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan cost estimate: %w", err)
+		}
+		if cost.Valid {
+			e.CostUSD = &cost.Float64
+		}
+		estimates = append(estimates, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating cost estimates: %w", err)
+	}
+	return estimates, nil
+}
+
+// GetCostThreshold returns the estimated cost in US dollars above which the
+// user must confirm a request, or nil if they keep the server's threshold
+func (s *Store) GetCostThreshold(ctx context.Context, userID int64) (*float64, error) {
+	var threshold sql.NullFloat64
+	err := s.db.QueryRowContext(ctx, `SELECT cost_confirm_above FROM users WHERE id = ?`, userID).Scan(&threshold)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cost threshold: %w", err)
+	}
+	if !threshold.Valid {
+		return nil, nil
+	}
+	return &threshold.Float64, nil
+}
+
+// SetCostThreshold sets the estimated cost above which the user must confirm
+// a request; nil returns them to the server's threshold
+func (s *Store) SetCostThreshold(ctx context.Context, userID int64, threshold *float64) error {
+	var value interface{}
+	if threshold != nil {
+		value = *threshold
+	}
+	result, err := s.db.ExecContext(ctx, `UPDATE users SET cost_confirm_above = ? WHERE id = ?`, value, userID)
+	if err != nil {
+		return fmt.Errorf("failed to set cost threshold: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("user not found: %d", userID)
+	}
+	return nil
+}
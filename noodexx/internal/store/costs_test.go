@@ -0,0 +1,74 @@
+package store
+
+import (
+	"context"
+	"testing"
+)
+
+// TestCostEstimates tests the per-user cost threshold and the estimates
+// counted in user activity
+func TestCostEstimates(t *testing.T) {
+	store, err := NewStore(t.TempDir()+"/test_costs.db", "multi")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+	alice, _ := store.CreateUser(ctx, "alice", "password123", "alice@example.com", false, false)
+
+	if threshold, err := store.GetCostThreshold(ctx, alice); err != nil || threshold != nil {
+		t.Errorf("Expected the server threshold by default, got %v (%v)", threshold, err)
+	}
+	limit := 0.5
+	if err := store.SetCostThreshold(ctx, alice, &limit); err != nil {
+		t.Fatalf("SetCostThreshold failed: %v", err)
+	}
+	if threshold, _ := store.GetCostThreshold(ctx, alice); threshold == nil || *threshold != 0.5 {
+		t.Errorf("Expected 0.5, got %v", threshold)
+	}
+	if err := store.SetCostThreshold(ctx, alice, nil); err != nil {
+		t.Fatalf("SetCostThreshold failed: %v", err)
+	}
+	if threshold, _ := store.GetCostThreshold(ctx, alice); threshold != nil {
+		t.Errorf("Expected the threshold cleared, got %v", *threshold)
+	}
+	if err := store.SetCostThreshold(ctx, 9999, &limit); err == nil {
+		t.Error("Expected an error for an unknown user")
+	}
+
+	cost := 0.75
+	for _, e := range []CostEstimate{
+		{UserID: alice, SessionID: "s1", Provider: "openai", Model: "gpt-4o", PromptTokens: 90000, CompletionTokens: 1024, CostUSD: &cost, ThresholdUSD: 0.5, Outcome: "confirmation_required"},
+		{UserID: alice, SessionID: "s1", Provider: "openai", Model: "gpt-4o", PromptTokens: 90000, CompletionTokens: 1024, CostUSD: &cost, ThresholdUSD: 0.5, Outcome: "confirmed"},
+		{UserID: alice, SessionID: "s2", Provider: "openai", Model: "unpriced", PromptTokens: 100, CompletionTokens: 1024, Outcome: "sent"},
+	} {
+		if err := store.SaveCostEstimate(ctx, e); err != nil {
+			t.Fatalf("SaveCostEstimate failed: %v", err)
+		}
+	}
+	if err := store.SaveCostEstimate(ctx, CostEstimate{UserID: alice, Outcome: "dropped"}); err == nil {
+		t.Error("Expected an error for an unknown outcome")
+	}
+
+	estimates, err := store.GetCostEstimates(ctx, alice, 10)
+	if err != nil || len(estimates) != 3 {
+		t.Fatalf("Expected 3 estimates, got %d (%v)", len(estimates), err)
+	}
+	if estimates[0].Model != "unpriced" || estimates[0].CostUSD != nil {
+		t.Errorf("Expected the unpriced estimate first with no cost, got %+v", estimates[0])
+	}
+	if estimates[1].CostUSD == nil || *estimates[1].CostUSD != 0.75 || estimates[1].Outcome != "confirmed" {
+		t.Errorf("Expected the confirmed estimate, got %+v", estimates[1])
+	}
+
+	activity, err := store.GetUserActivity(ctx)
+	if err != nil {
+		t.Fatalf("GetUserActivity failed: %v", err)
+	}
+	for _, a := range activity {
+		if a.UserID == alice && (a.EstimatedCostUSD != 0.75 || a.ConfirmationsAsked != 1) {
+			t.Errorf("Expected only the sent request costed and 1 confirmation, got %v and %d", a.EstimatedCostUSD, a.ConfirmationsAsked)
+		}
+	}
+}
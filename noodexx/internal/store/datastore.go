@@ -14,6 +14,12 @@ import (
 type DataStore interface {
 	// Lifecycle
 	Close() error
+	Checkpoint(ctx context.Context) (*CheckpointResult, error)
+	OpenVectorIndex(path string, ann ANNOptions) (*VectorIndexReport, error)
+	RepairVectorIndex(ctx context.Context) (*VectorIndexReport, error)
+	SnapshotVectorIndex() error
+	RebuildANNIndex(ctx context.Context) (*ANNIndexReport, error)
+	WithTx(ctx context.Context, fn func(tx StoreTx) error) error
 
 	// User Management
 	CreateUser(ctx context.Context, username, password, email string, isAdmin, mustChangePassword bool) (int64, error)
@@ -21,43 +27,215 @@ type DataStore interface {
 	GetUserByID(ctx context.Context, userID int64) (*User, error)
 	ValidateCredentials(ctx context.Context, username, password string) (*User, error)
 	UpdatePassword(ctx context.Context, userID int64, newPassword string) error
+	SetTemporaryPassword(ctx context.Context, userID int64, newPassword string) error
+	IsRecentPassword(ctx context.Context, userID int64, password string, n int) (bool, error)
 	UpdateLastLogin(ctx context.Context, userID int64) error
 	ListUsers(ctx context.Context) ([]User, error)
 	DeleteUser(ctx context.Context, userID int64) error
+	CompleteOnboarding(ctx context.Context, userID int64) error
 
 	// Session Token Management
-	CreateSessionToken(ctx context.Context, token string, userID int64, expiresAt time.Time) error
+	CreateSessionToken(ctx context.Context, token string, userID int64, expiresAt time.Time, rememberMe bool) error
 	GetSessionToken(ctx context.Context, token string) (*SessionToken, error)
+	ExtendSessionToken(ctx context.Context, token string, expiresAt time.Time) error
 	DeleteSessionToken(ctx context.Context, token string) error
+	DeleteUserSessionTokens(ctx context.Context, userID int64, keepToken string) error
 	CleanupExpiredTokens(ctx context.Context) error
 
 	// Account Lockout
 	RecordFailedLogin(ctx context.Context, username string) error
 	ClearFailedLogins(ctx context.Context, username string) error
-	IsAccountLocked(ctx context.Context, username string) (bool, time.Time)
+	IsAccountLocked(ctx context.Context, username string, threshold int, window time.Duration) (bool, time.Time)
+	CountFailedLogins(ctx context.Context, username string, since time.Time) (int, error)
 
 	// User-Scoped Data Access
 	SaveChunk(ctx context.Context, userID int64, source, text string, embedding []float32, tags []string, summary string) error
-	SearchByUser(ctx context.Context, userID int64, queryVec []float32, topK int) ([]Chunk, error)
+	SearchByUser(ctx context.Context, userID int64, queryVec []float32, queryModel string, topK int) ([]Chunk, error)
+	SearchUserSources(ctx context.Context, userID int64, queryVec []float32, queryModel string, sources []string, topK int) ([]Chunk, error)
+	SearchLibrary(ctx context.Context, userID int64, queryVec []float32, opts SearchOptions, topK int) ([]Chunk, error)
+	GetSourceChunks(ctx context.Context, userID int64, source string) ([]Chunk, error)
+	GetSourceTexts(ctx context.Context, userID int64, source string) ([]string, error)
+	SaveSummary(ctx context.Context, userID int64, source, summary, model string) error
+	GetSummaryStates(ctx context.Context, userID int64, allUsers bool) ([]SummaryState, error)
 	LibraryByUser(ctx context.Context, userID int64) ([]LibraryEntry, error)
+	ForEachLibraryEntry(ctx context.Context, userID int64, fn func(LibraryEntry) bool) error
+	ForEachChunk(ctx context.Context, userID int64, fn func(Chunk) bool) error
 	DeleteChunksBySource(ctx context.Context, userID int64, source string) error
+	DeleteDocument(ctx context.Context, userID int64, source string) error
+	GetDocument(ctx context.Context, userID, documentID int64) (*Document, error)
+	RenameDocument(ctx context.Context, userID, documentID int64, title string) error
+	PinDocument(ctx context.Context, userID, documentID int64) (bool, error)
+	UnpinDocument(ctx context.Context, userID, documentID int64) error
+	AddQuickNote(ctx context.Context, note *QuickNote, dedupSince time.Time) (bool, error)
+	GetQuickNotes(ctx context.Context, userID int64, source string) ([]QuickNote, error)
+	DeleteQuickNote(ctx context.Context, userID, noteID int64) error
+	GetDefaultVisibility(ctx context.Context, userID int64) (string, error)
+	SetDefaultVisibility(ctx context.Context, userID int64, visibility string) error
+	GetCostThreshold(ctx context.Context, userID int64) (*float64, error)
+	SetCostThreshold(ctx context.Context, userID int64, threshold *float64) error
+	GetUserPreferences(ctx context.Context, userID int64) (map[string]string, error)
+	SetUserPreferences(ctx context.Context, userID int64, prefs map[string]string) error
+	SaveCostEstimate(ctx context.Context, e CostEstimate) error
+	GetCostEstimates(ctx context.Context, userID int64, limit int) ([]CostEstimate, error)
+	SaveTranscript(ctx context.Context, t Transcript) error
+	GetTranscripts(ctx context.Context, filter TranscriptFilter) ([]Transcript, error)
+	DeleteTranscriptsBefore(ctx context.Context, before time.Time) (int64, error)
+	SaveFailedRequest(ctx context.Context, f FailedRequest) (int64, error)
+	GetFailedRequests(ctx context.Context, limit int) ([]FailedRequest, error)
+	GetFailedRequest(ctx context.Context, id int64) (*FailedRequest, error)
+	DeleteFailedRequestsBefore(ctx context.Context, before time.Time) (int64, error)
+	SetSourceVisibility(ctx context.Context, userID int64, source, visibility string) error
+	SetDocumentsVisibility(ctx context.Context, userID int64, documentIDs []int64, visibility string) (int, error)
+	CountChunkVisibility(ctx context.Context, chunkIDs []int64) (map[string]int, error)
+	RepairChunkVisibility(ctx context.Context) (int64, error)
+	SaveChunkTitles(ctx context.Context, userID int64, source string, titles []string, embeddings [][]float32) error
+	SaveDocumentContent(ctx context.Context, userID int64, source, content, chunker string) error
+	SetDocumentEncoding(ctx context.Context, userID int64, source, encoding, changes string) error
+	GetDocumentContent(ctx context.Context, userID int64, source string) (*DocumentContent, error)
+	GetDocumentEncoding(ctx context.Context, userID int64, source string) (*DocumentEncoding, error)
+	GetChunkedDocuments(ctx context.Context, userID int64) ([]ChunkedDocument, error)
+	ReplaceChunks(ctx context.Context, userID int64, source string, texts []string, embeddings [][]float32) error
+	SetDocumentLinks(ctx context.Context, userID int64, source string, targets []string) error
+	GetDocumentLinks(ctx context.Context, userID int64, source string) ([]string, error)
+
+	// Sensitive Chunk Access
+	RecordChunkAccess(ctx context.Context, access ChunkAccess, chunkIDs []int64, tag string) (int, error)
+	GetChunkAccessLog(ctx context.Context, ownerID int64, source string, since time.Time, limit int) ([]ChunkAccess, error)
+
+	// Retrieval Statistics
+	RecordRetrievals(ctx context.Context, hits []RetrievalHit) error
+	GetRetrievalStats(ctx context.Context, userID int64, since time.Time) ([]SourceRetrieval, error)
+
+	// Retrieval Ranking
+	GetRankingWeights(ctx context.Context, userID int64) (*RankingWeights, error)
+	SaveRankingWeights(ctx context.Context, userID int64, recencyHalfLifeDays float64, tagWeights, sourceWeights map[string]float64) error
+
+	// Generation Defaults
+	GetGenerationDefaults(ctx context.Context, userID int64) (*GenerationDefaults, error)
+	SaveGenerationDefaults(ctx context.Context, defaults GenerationDefaults) error
+	GetAnswerStyle(ctx context.Context, userID int64) (*AnswerStyle, error)
+	SaveAnswerStyle(ctx context.Context, style AnswerStyle) error
+
+	// Storage Analytics
+	GetStorageStats(ctx context.Context, userID int64, allUsers bool, limit int) (*StorageStats, error)
+	CountDocuments(ctx context.Context) (int, error)
+	GetEmbeddingGroups(ctx context.Context) ([]EmbeddingGroup, error)
+	GetUserActivity(ctx context.Context) ([]UserActivity, error)
+
+	// Quick Search
+	QuickSearch(ctx context.Context, userID int64, query string, limits QuickSearchLimits) ([]QuickSearchResult, error)
+	GetSearchDictionary(ctx context.Context) (*SearchDictionary, error)
+	SetSearchDictionary(ctx context.Context, dict SearchDictionary) error
 
 	// Session Management
 	SaveChatMessage(ctx context.Context, userID int64, sessionID, role, content, providerMode string) error
+	SaveChatMessageWithCitations(ctx context.Context, userID int64, sessionID, role, content, providerMode string, citations []string) error
+	SaveChatMessageWithProvenance(ctx context.Context, userID int64, sessionID, role, content, providerMode string, citations []string, provenance *MessageProvenance) (int64, error)
+	GetMessageProvenance(ctx context.Context, userID, messageID int64) (*MessageProvenance, error)
+	GetResponseStats(ctx context.Context, userID int64, since time.Time) ([]ResponseStats, error)
+	SaveMessageArtifacts(ctx context.Context, messageID int64, artifacts []MessageArtifact) error
+	GetMessageArtifacts(ctx context.Context, userID, messageID int64) ([]MessageArtifact, error)
 	GetUserSessions(ctx context.Context, userID int64) ([]Session, error)
 	GetSessionOwner(ctx context.Context, sessionID string) (int64, error)
 	GetSessionMessages(ctx context.Context, userID int64, sessionID string) ([]ChatMessage, error)
+	ImportChatSession(ctx context.Context, userID int64, session *ImportedSession) (bool, error)
+	CreateSessionLink(ctx context.Context, link *SessionLink) error
+	GetSessionLink(ctx context.Context, userID int64, sessionID string) (*SessionLink, error)
+	GetSessionMode(ctx context.Context, userID int64, sessionID string) (string, error)
+	SetSessionMode(ctx context.Context, userID int64, sessionID, mode string) error
+	GetSessionSummary(ctx context.Context, userID int64, sessionID string) (*SessionSummary, error)
+	SaveSessionSummary(ctx context.Context, summary *SessionSummary) error
+	GetSessionsToCompact(ctx context.Context, idleBefore time.Time, keep int) ([]SessionToCompact, error)
+
+	// Session Sharing
+	CreateSessionShare(ctx context.Context, share *SessionShare) (int64, error)
+	GetSessionShareByToken(ctx context.Context, token string) (*SessionShare, error)
+	GetSessionShares(ctx context.Context, userID int64, sessionID string) ([]SessionShare, error)
+	RevokeSessionShare(ctx context.Context, userID, shareID int64) error
+	RecordSessionShareView(ctx context.Context, shareID int64, ipAddress, userAgent string) error
+	GetSessionShareViews(ctx context.Context, userID, shareID int64, limit int) ([]SessionShareView, error)
 
 	// Skills Management
 	CreateSkill(ctx context.Context, userID int64, name, path string, enabled bool) (int64, error)
 	GetUserSkills(ctx context.Context, userID int64) ([]Skill, error)
 	UpdateSkillEnabled(ctx context.Context, userID int64, skillID int64, enabled bool) error
 	DeleteSkill(ctx context.Context, userID int64, skillID int64) error
+	RecordSkillRun(ctx context.Context, run *SkillRun) (int64, error)
+	SetSkillRunArtifact(ctx context.Context, userID, runID int64, source string) error
+	GetSkillRuns(ctx context.Context, userID, skillID int64, limit, offset int) ([]SkillRun, int, error)
+	GetSkillRun(ctx context.Context, userID, runID int64) (*SkillRun, error)
+	CreateGlobalSkill(ctx context.Context, adminID int64, name, path string, enabled bool) (int64, error)
+	GetGlobalSkills(ctx context.Context) ([]Skill, error)
+	UpdateGlobalSkill(ctx context.Context, skillID int64, enabled bool) (bool, error)
+	DeleteGlobalSkill(ctx context.Context, skillID int64) (bool, error)
 
 	// Watched Folders Management
 	AddWatchedFolder(ctx context.Context, userID int64, path string) error
 	GetWatchedFoldersByUser(ctx context.Context, userID int64) ([]WatchedFolder, error)
 	RemoveWatchedFolder(ctx context.Context, userID int64, folderID int64) error
+	RecordIngestFailure(ctx context.Context, userID int64, folder, path, errMsg string) (int, error)
+	GetIngestFailureAttempts(ctx context.Context, path string) (int, error)
+	ClearIngestFailure(ctx context.Context, path string) error
+	GetIngestFailures(ctx context.Context, userID int64, folder string) ([]IngestFailure, error)
+
+	// Retrieval Evaluation
+	CreateEvalSet(ctx context.Context, userID int64, name, cases string) (int64, error)
+	UpdateEvalSet(ctx context.Context, userID, setID int64, name, cases string) error
+	GetEvalSets(ctx context.Context, userID int64) ([]EvalSet, error)
+	GetEvalSet(ctx context.Context, userID, setID int64) (*EvalSet, error)
+	DeleteEvalSet(ctx context.Context, userID, setID int64) error
+	CreateSavedSearch(ctx context.Context, userID int64, name, query, mode string) (int64, error)
+	GetSavedSearches(ctx context.Context, userID int64) ([]SavedSearch, error)
+	GetSavedSearch(ctx context.Context, userID, id int64) (*SavedSearch, error)
+	GetStaleSavedSearches(ctx context.Context, model string, limit int) ([]SavedSearch, error)
+	SetSavedSearchEmbedding(ctx context.Context, id int64, embedding []float32, model string) error
+	DeleteSavedSearch(ctx context.Context, userID, id int64) (bool, error)
+	RecordEvalRun(ctx context.Context, run *EvalRun) (int64, error)
+	GetEvalRuns(ctx context.Context, userID, setID int64, limit int) ([]EvalRun, error)
+	GetEvalRun(ctx context.Context, userID, runID int64) (*EvalRun, error)
+
+	// Feature Flag Overrides
+	GetUserFlagOverrides(ctx context.Context, userID int64) (map[string]bool, error)
+	GetFlagOverrides(ctx context.Context) ([]FlagOverride, error)
+	SetFlagOverride(ctx context.Context, userID int64, flag string, enabled bool) error
+	DeleteFlagOverride(ctx context.Context, userID int64, flag string) error
+
+	// Scheduled Jobs
+	GetJobStates(ctx context.Context) ([]JobState, error)
+	SaveJobState(ctx context.Context, state JobState) error
+
+	// Cluster Coordination
+	AcquireLock(ctx context.Context, name, owner string, ttl time.Duration) (bool, error)
+	ReleaseLock(ctx context.Context, name, owner string) error
+	PublishClusterEvent(ctx context.Context, origin, kind, payload string) (int64, error)
+	GetClusterEventsAfter(ctx context.Context, afterID int64, limit int) ([]ClusterEvent, error)
+	LatestClusterEventID(ctx context.Context) (int64, error)
+	EnqueueClusterTask(ctx context.Context, kind, payload string) (int64, error)
+	ClaimClusterTask(ctx context.Context, owner string, kinds []string, lease time.Duration) (*ClusterTask, error)
+	RenewClusterTask(ctx context.Context, id int64, owner string, lease time.Duration) error
+	FinishClusterTask(ctx context.Context, id int64, owner, result, errMsg string) error
+	GetClusterTask(ctx context.Context, id int64) (*ClusterTask, error)
+	PruneCluster(ctx context.Context, eventsBefore, tasksBefore time.Time) (int64, error)
+
+	// Notifications
+	CreateNotification(ctx context.Context, userID int64, notificationType, message, payload string) (*Notification, error)
+	GetNotifications(ctx context.Context, userID int64, unreadOnly bool, limit int) ([]Notification, error)
+	CountUnreadNotifications(ctx context.Context, userID int64) (int, error)
+	MarkNotificationsRead(ctx context.Context, userID int64, ids []int64) (int64, error)
+	DeleteReadNotificationsBefore(ctx context.Context, before time.Time) (int64, error)
+
+	// Structured Extraction
+	CreateExtractionSchema(ctx context.Context, userID int64, name, description, schema string) (int64, error)
+	GetExtractionSchemas(ctx context.Context, userID int64) ([]ExtractionSchema, error)
+	GetExtractionSchema(ctx context.Context, userID, schemaID int64) (*ExtractionSchema, error)
+	DeleteExtractionSchema(ctx context.Context, userID, schemaID int64) error
+	SaveStructuredRecord(ctx context.Context, record *StructuredRecord) (int64, error)
+	GetStructuredRecords(ctx context.Context, userID int64, filter RecordFilter) ([]StructuredRecord, int, error)
+
+	// Metadata Queries
+	GetDocumentFacts(ctx context.Context, userID int64) ([]DocumentFact, error)
+	GetChunkFacts(ctx context.Context, userID int64) ([]ChunkFact, error)
+	GetSessionFacts(ctx context.Context, userID int64) ([]SessionFact, error)
 
 	// Audit Log
 	LogAudit(ctx context.Context, userID int64, username, operation, details string) error
@@ -82,8 +260,10 @@ func NewDataStore(dbType, connectionString string) (DataStore, error) {
 // NewSQLiteStore creates a new SQLite-backed DataStore
 // This will be fully implemented in task 4.1 when Store is updated to implement DataStore
 func NewSQLiteStore(path string) (DataStore, error) {
+	opts := DefaultOptions()
+
 	// Enable WAL mode for concurrent access and busy timeout for write contention
-	db, err := sql.Open("sqlite", path+"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
+	db, err := sql.Open("sqlite", path+opts.dsnParams())
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
@@ -100,8 +280,9 @@ func NewSQLiteStore(path string) (DataStore, error) {
 	}
 
 	store := &Store{
-		db:       db,
-		userMode: "multi", // Default to multi-user mode for DataStore interface
+		db:             db,
+		userMode:       "multi", // Default to multi-user mode for DataStore interface
+		searchPageSize: opts.SearchPageSize,
 	}
 
 	// Run migrations
@@ -0,0 +1,102 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// ensureDocument creates the document record for a user's source if it does not
+// exist yet, using the given connection or transaction. An existing record keeps
+// its ID and title, so re-ingesting a source does not undo a rename
+func ensureDocument(ctx context.Context, ex execer, userID int64, source string) error {
+	query := `
+		INSERT INTO documents (user_id, source) VALUES (?, ?)
+		ON CONFLICT(user_id, source) DO NOTHING
+	`
+	if _, err := ex.ExecContext(ctx, query, userID, source); err != nil {
+		return fmt.Errorf("failed to record document: %w", err)
+	}
+	return nil
+}
+
+// createDocument records a user's document with a visibility. A document that
+// already exists, such as one being ingested again, keeps the visibility it has
+func createDocument(ctx context.Context, ex execer, userID int64, source, visibility string) error {
+	query := `
+		INSERT INTO documents (user_id, source, visibility) VALUES (?, ?, ?)
+		ON CONFLICT(user_id, source) DO NOTHING
+	`
+	if _, err := ex.ExecContext(ctx, query, userID, source, visibility); err != nil {
+		return fmt.Errorf("failed to record document: %w", err)
+	}
+	return nil
+}
+
+// DeleteDocument removes a user's document: its chunks, its document record and
+// any quick notes it was built from
+func (s *Store) DeleteDocument(ctx context.Context, userID int64, source string) error {
+	return deleteDocument(ctx, s.db, userID, source)
+}
+
+// deleteDocument removes a user's document using the given connection or transaction
+func deleteDocument(ctx context.Context, ex execer, userID int64, source string) error {
+	if err := deleteChunksBySource(ctx, ex, userID, source); err != nil {
+		return err
+	}
+	// Pins are removed for everyone the document was shared with, not just its owner
+	_, err := ex.ExecContext(ctx, `
+		DELETE FROM document_pins
+		WHERE document_id IN (SELECT id FROM documents WHERE user_id = ? AND source = ?)
+	`, userID, source)
+	if err != nil {
+		return fmt.Errorf("failed to delete document pins: %w", err)
+	}
+	if _, err := ex.ExecContext(ctx, `DELETE FROM documents WHERE user_id = ? AND source = ?`, userID, source); err != nil {
+		return fmt.Errorf("failed to delete document: %w", err)
+	}
+	if _, err := ex.ExecContext(ctx, `DELETE FROM quick_notes WHERE user_id = ? AND source = ?`, userID, source); err != nil {
+		return fmt.Errorf("failed to delete quick notes: %w", err)
+	}
+	if _, err := ex.ExecContext(ctx, `DELETE FROM document_links WHERE user_id = ? AND source = ?`, userID, source); err != nil {
+		return fmt.Errorf("failed to delete document links: %w", err)
+	}
+	if _, err := ex.ExecContext(ctx, `DELETE FROM source_retrievals WHERE user_id = ? AND source = ?`, userID, source); err != nil {
+		return fmt.Errorf("failed to delete retrieval statistics: %w", err)
+	}
+	if _, err := ex.ExecContext(ctx, `DELETE FROM document_contents WHERE user_id = ? AND source = ?`, userID, source); err != nil {
+		return fmt.Errorf("failed to delete document content: %w", err)
+	}
+	if _, err := ex.ExecContext(ctx, `DELETE FROM document_encodings WHERE user_id = ? AND source = ?`, userID, source); err != nil {
+		return fmt.Errorf("failed to delete document encoding: %w", err)
+	}
+	return nil
+}
+
+// GetDocument returns one of the user's own documents, or nil if it does not exist
+func (s *Store) GetDocument(ctx context.Context, userID, documentID int64) (*Document, error) {
+	query := `SELECT id, user_id, source, title, visibility, created_at, updated_at FROM documents WHERE id = ? AND user_id = ?`
+	var d Document
+	err := s.db.QueryRowContext(ctx, query, documentID, userID).Scan(&d.ID, &d.UserID, &d.Source, &d.Title, &d.Visibility, &d.CreatedAt, &d.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get document: %w", err)
+	}
+	return &d, nil
+}
+
+// RenameDocument sets the display title of one of the user's own documents
+// The source it was ingested from is kept; an empty title shows the source again
+func (s *Store) RenameDocument(ctx context.Context, userID, documentID int64, title string) error {
+	query := `UPDATE documents SET title = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ? AND user_id = ?`
+	result, err := s.db.ExecContext(ctx, query, title, documentID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to rename document: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("document not found: %d", documentID)
+	}
+	return nil
+}
@@ -0,0 +1,164 @@
+package store
+
+import (
+	"context"
+	"testing"
+)
+
+// TestRenameDocument tests that a rename changes the library title but not the
+// source, and survives re-ingesting the source
+func TestRenameDocument(t *testing.T) {
+	store, err := NewStore(t.TempDir()+"/test_documents.db", "multi")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+
+	alice, err := store.CreateUser(ctx, "alice", "password123", "alice@example.com", false, false)
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	bob, err := store.CreateUser(ctx, "bob", "password123", "bob@example.com", false, false)
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	if err := store.SaveChunk(ctx, alice, "notes.txt", "alice's notes", []float32{1, 0}, nil, ""); err != nil {
+		t.Fatalf("Failed to save chunk: %v", err)
+	}
+	if err := store.SaveChunk(ctx, alice, "notes.txt", "more notes", []float32{0, 1}, nil, ""); err != nil {
+		t.Fatalf("Failed to save chunk: %v", err)
+	}
+
+	entries, err := store.LibraryByUser(ctx, alice)
+	if err != nil {
+		t.Fatalf("LibraryByUser failed: %v", err)
+	}
+	if len(entries) != 1 || entries[0].ChunkCount != 2 || entries[0].Title != "notes.txt" || entries[0].DocumentID == 0 {
+		t.Fatalf("Expected one untitled document with 2 chunks, got %+v", entries)
+	}
+	docID := entries[0].DocumentID
+
+	// Another user's document is not theirs to rename
+	if err := store.RenameDocument(ctx, bob, docID, "Stolen"); err == nil {
+		t.Error("Expected renaming another user's document to fail")
+	}
+	if doc, err := store.GetDocument(ctx, bob, docID); err != nil || doc != nil {
+		t.Errorf("Expected no document for another user, got %+v, %v", doc, err)
+	}
+
+	if err := store.RenameDocument(ctx, alice, docID, "Meeting notes"); err != nil {
+		t.Fatalf("RenameDocument failed: %v", err)
+	}
+
+	// Re-ingesting replaces the chunks but keeps the document and its title
+	err = store.WithTx(ctx, func(tx StoreTx) error {
+		if err := tx.DeleteChunksBySource(ctx, alice, "notes.txt"); err != nil {
+			return err
+		}
+		return tx.SaveChunk(ctx, alice, "notes.txt", "rewritten notes", []float32{1, 1}, nil, "")
+	})
+	if err != nil {
+		t.Fatalf("WithTx failed: %v", err)
+	}
+
+	entries, err = store.LibraryByUser(ctx, alice)
+	if err != nil {
+		t.Fatalf("LibraryByUser failed: %v", err)
+	}
+	if len(entries) != 1 || entries[0].DocumentID != docID || entries[0].Title != "Meeting notes" || entries[0].Source != "notes.txt" {
+		t.Fatalf("Expected the renamed document to survive re-ingestion, got %+v", entries)
+	}
+
+	// An empty title shows the source again
+	if err := store.RenameDocument(ctx, alice, docID, ""); err != nil {
+		t.Fatalf("RenameDocument failed: %v", err)
+	}
+	doc, err := store.GetDocument(ctx, alice, docID)
+	if err != nil || doc == nil {
+		t.Fatalf("GetDocument failed: %+v, %v", doc, err)
+	}
+	if doc.Title != "" || doc.Source != "notes.txt" {
+		t.Errorf("Expected the title to be cleared, got %+v", doc)
+	}
+
+	if err := store.DeleteDocument(ctx, alice, "notes.txt"); err != nil {
+		t.Fatalf("DeleteDocument failed: %v", err)
+	}
+	if doc, err := store.GetDocument(ctx, alice, docID); err != nil || doc != nil {
+		t.Errorf("Expected the document to be deleted, got %+v, %v", doc, err)
+	}
+}
+
+// TestSameSourceDifferentUsers tests that two users' documents with the same
+// source are separate library entries
+func TestSameSourceDifferentUsers(t *testing.T) {
+	store, err := NewStore(t.TempDir()+"/test_documents.db", "multi")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+
+	alice, _ := store.CreateUser(ctx, "alice", "password123", "alice@example.com", false, false)
+	bob, _ := store.CreateUser(ctx, "bob", "password123", "bob@example.com", false, false)
+
+	if err := store.SaveChunk(ctx, alice, "notes.txt", "alice's notes", []float32{1, 0}, nil, ""); err != nil {
+		t.Fatalf("Failed to save chunk: %v", err)
+	}
+	if err := store.SaveChunk(ctx, bob, "notes.txt", "bob's notes", []float32{0, 1}, nil, ""); err != nil {
+		t.Fatalf("Failed to save chunk: %v", err)
+	}
+
+	entries, err := store.Library(ctx)
+	if err != nil {
+		t.Fatalf("Library failed: %v", err)
+	}
+	if len(entries) != 2 || entries[0].DocumentID == entries[1].DocumentID {
+		t.Fatalf("Expected two documents named notes.txt, got %+v", entries)
+	}
+}
+
+// TestBackfillChunkDocuments tests that chunks stored before documents existed
+// are given a document when migrations run
+func TestBackfillChunkDocuments(t *testing.T) {
+	store, err := NewStore(t.TempDir()+"/test_documents.db", "multi")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+
+	userID, _ := store.CreateUser(ctx, "alice", "password123", "alice@example.com", false, false)
+	for _, text := range []string{"one", "two"} {
+		_, err := store.db.ExecContext(ctx, `INSERT INTO chunks (user_id, source, text, embedding) VALUES (?, 'old.txt', ?, ?)`,
+			userID, text, serializeEmbedding([]float32{1, 0}))
+		if err != nil {
+			t.Fatalf("Failed to insert legacy chunk: %v", err)
+		}
+	}
+
+	if err := store.runMigrations(ctx); err != nil {
+		t.Fatalf("runMigrations failed: %v", err)
+	}
+
+	var unlinked int
+	if err := store.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM chunks WHERE document_id IS NULL`).Scan(&unlinked); err != nil {
+		t.Fatalf("Failed to count chunks: %v", err)
+	}
+	if unlinked != 0 {
+		t.Errorf("Expected every chunk to have a document, %d do not", unlinked)
+	}
+
+	entries, err := store.LibraryByUser(ctx, userID)
+	if err != nil {
+		t.Fatalf("LibraryByUser failed: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Source != "old.txt" || entries[0].ChunkCount != 2 {
+		t.Errorf("Expected the legacy chunks as one document, got %+v", entries)
+	}
+}
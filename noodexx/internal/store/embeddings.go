@@ -0,0 +1,36 @@
+package store
+
+import (
+	"context"
+	"fmt"
+)
+
+// GetEmbeddingGroups counts the library's chunks by the embedding model and
+// dimension that produced them, largest group first
+func (s *Store) GetEmbeddingGroups(ctx context.Context) ([]EmbeddingGroup, error) {
+	query := `
+		SELECT embedding_model, embedding_dim, COUNT(*), COUNT(DISTINCT source)
+		FROM chunks
+		GROUP BY embedding_model, embedding_dim
+		ORDER BY COUNT(*) DESC, embedding_model, embedding_dim
+	`
+
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query embedding groups: %w", err)
+	}
+	defer rows.Close()
+
+	var groups []EmbeddingGroup
+	for rows.Next() {
+		var g EmbeddingGroup
+		if err := rows.Scan(&g.Model, &g.Dimension, &g.Chunks, &g.Sources); err != nil {
+			return nil, fmt.Errorf("failed to scan embedding group: %w", err)
+		}
+		groups = append(groups, g)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating embedding groups: %w", err)
+	}
+	return groups, nil
+}
@@ -0,0 +1,87 @@
+package store
+
+import (
+	"context"
+	"testing"
+)
+
+// TestEmbeddingModelTagging tests that chunks record their embedding model and
+// dimension, and that search skips chunks from other models or dimensions
+func TestEmbeddingModelTagging(t *testing.T) {
+	store, err := NewStore(t.TempDir()+"/test_embeddings.db", "multi")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+
+	userID, err := store.CreateUser(ctx, "alice", "password123", "alice@example.com", false, false)
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	// Untagged chunk, as stored before embedding models were recorded
+	if err := store.SaveChunk(ctx, userID, "old.txt", "untagged", []float32{1, 0, 0}, nil, ""); err != nil {
+		t.Fatalf("Failed to save chunk: %v", err)
+	}
+
+	store.SetEmbeddingModel("nomic-embed-text")
+	for _, source := range []string{"a.txt", "b.txt"} {
+		if err := store.SaveChunk(ctx, userID, source, "nomic", []float32{1, 0, 0}, nil, ""); err != nil {
+			t.Fatalf("Failed to save chunk: %v", err)
+		}
+	}
+
+	store.SetEmbeddingModel("text-embedding-3-small")
+	if err := store.SaveChunk(ctx, userID, "c.txt", "openai same dim", []float32{1, 0, 0}, nil, ""); err != nil {
+		t.Fatalf("Failed to save chunk: %v", err)
+	}
+	if err := store.WithTx(ctx, func(tx StoreTx) error {
+		return tx.SaveChunk(ctx, userID, "d.txt", "openai other dim", []float32{1, 0, 0, 0}, nil, "")
+	}); err != nil {
+		t.Fatalf("WithTx failed: %v", err)
+	}
+
+	groups, err := store.GetEmbeddingGroups(ctx)
+	if err != nil {
+		t.Fatalf("GetEmbeddingGroups failed: %v", err)
+	}
+	want := []EmbeddingGroup{
+		{Model: "nomic-embed-text", Dimension: 3, Chunks: 2, Sources: 2},
+		{Model: "", Dimension: 3, Chunks: 1, Sources: 1},
+		{Model: "text-embedding-3-small", Dimension: 3, Chunks: 1, Sources: 1},
+		{Model: "text-embedding-3-small", Dimension: 4, Chunks: 1, Sources: 1},
+	}
+	if len(groups) != len(want) {
+		t.Fatalf("Expected %d groups, got %+v", len(want), groups)
+	}
+	for i := range want {
+		if groups[i] != want[i] {
+			t.Errorf("Group %d: expected %+v, got %+v", i, want[i], groups[i])
+		}
+	}
+
+	// The query model's chunks and untagged chunks of the same dimension are searched
+	results, err := store.SearchByUser(ctx, userID, []float32{1, 0, 0}, "nomic-embed-text", 10)
+	if err != nil {
+		t.Fatalf("SearchByUser failed: %v", err)
+	}
+	if len(results) != 3 {
+		t.Errorf("Expected 3 results, got %d", len(results))
+	}
+	for _, r := range results {
+		if r.Source == "c.txt" || r.Source == "d.txt" {
+			t.Errorf("Expected chunks from another model to be skipped, got %s", r.Source)
+		}
+	}
+
+	// Without a query model only the dimension is checked
+	results, err = store.SearchByUser(ctx, userID, []float32{0, 0, 0, 1}, "", 10)
+	if err != nil {
+		t.Fatalf("SearchByUser failed: %v", err)
+	}
+	if len(results) != 1 || results[0].Source != "d.txt" {
+		t.Errorf("Expected only the 4-dimension chunk, got %+v", results)
+	}
+}
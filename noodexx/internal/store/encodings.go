@@ -0,0 +1,48 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// SetDocumentEncoding records the encoding a user's document was decoded from
+// and what normalizing its text changed, replacing the previous ingestion's
+func (s *Store) SetDocumentEncoding(ctx context.Context, userID int64, source, encoding, changes string) error {
+	return setDocumentEncoding(ctx, s.db, userID, source, encoding, changes)
+}
+
+// setDocumentEncoding upserts a document's encoding using the given connection or transaction
+func setDocumentEncoding(ctx context.Context, ex execer, userID int64, source, encoding, changes string) error {
+	_, err := ex.ExecContext(ctx, `
+		INSERT INTO document_encodings (user_id, source, encoding, changes, updated_at)
+		VALUES (?, ?, ?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT (user_id, source) DO UPDATE SET
+			encoding = excluded.encoding,
+			changes = excluded.changes,
+			updated_at = excluded.updated_at
+	`, userID, source, encoding, changes)
+	if err != nil {
+		return fmt.Errorf("failed to record document encoding: %w", err)
+	}
+	return nil
+}
+
+// GetDocumentEncoding returns the encoding one of the user's documents was
+// decoded from, or nil if none was recorded, as for documents ingested before
+// encodings were
+func (s *Store) GetDocumentEncoding(ctx context.Context, userID int64, source string) (*DocumentEncoding, error) {
+	var e DocumentEncoding
+	err := s.db.QueryRowContext(ctx, `
+		SELECT source, encoding, changes, updated_at
+		FROM document_encodings
+		WHERE user_id = ? AND source = ?
+	`, userID, source).Scan(&e.Source, &e.Encoding, &e.Changes, &e.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get document encoding: %w", err)
+	}
+	return &e, nil
+}
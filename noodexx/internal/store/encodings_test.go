@@ -0,0 +1,51 @@
+package store
+
+import (
+	"context"
+	"testing"
+)
+
+// TestDocumentEncoding tests recording a document's encoding in a unit of
+// work, replacing it on re-ingestion and removing it with the document
+func TestDocumentEncoding(t *testing.T) {
+	store, err := NewStore(t.TempDir()+"/test_encodings.db", "multi")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+	userID, _ := store.CreateUser(ctx, "alice", "password123", "alice@example.com", false, false)
+
+	if e, err := store.GetDocumentEncoding(ctx, userID, "legacy.txt"); err != nil || e != nil {
+		t.Fatalf("Expected no encoding before ingestion, got %+v (%v)", e, err)
+	}
+
+	err = store.WithTx(ctx, func(tx StoreTx) error {
+		if err := tx.SaveChunk(ctx, userID, "legacy.txt", "Text", []float32{1, 0}, nil, ""); err != nil {
+			return err
+		}
+		return tx.SetDocumentEncoding(ctx, userID, "legacy.txt", "windows-1252", "3 line endings")
+	})
+	if err != nil {
+		t.Fatalf("Failed to save document: %v", err)
+	}
+	e, err := store.GetDocumentEncoding(ctx, userID, "legacy.txt")
+	if err != nil || e == nil || e.Encoding != "windows-1252" || e.Changes != "3 line endings" {
+		t.Fatalf("Expected the recorded encoding, got %+v (%v)", e, err)
+	}
+
+	if err := store.SetDocumentEncoding(ctx, userID, "legacy.txt", "utf-8", ""); err != nil {
+		t.Fatalf("SetDocumentEncoding failed: %v", err)
+	}
+	if e, _ := store.GetDocumentEncoding(ctx, userID, "legacy.txt"); e == nil || e.Encoding != "utf-8" || e.Changes != "" {
+		t.Errorf("Expected the encoding to be replaced, got %+v", e)
+	}
+
+	if err := store.DeleteDocument(ctx, userID, "legacy.txt"); err != nil {
+		t.Fatalf("DeleteDocument failed: %v", err)
+	}
+	if e, _ := store.GetDocumentEncoding(ctx, userID, "legacy.txt"); e != nil {
+		t.Errorf("Expected the encoding to be deleted with the document, got %+v", e)
+	}
+}
@@ -0,0 +1,166 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// CreateEvalSet stores a new evaluation set and returns its ID
+func (s *Store) CreateEvalSet(ctx context.Context, userID int64, name, cases string) (int64, error) {
+	result, err := s.db.ExecContext(ctx, `INSERT INTO eval_sets (user_id, name, cases) VALUES (?, ?, ?)`, userID, name, cases)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create eval set: %w", err)
+	}
+	return result.LastInsertId()
+}
+
+// UpdateEvalSet replaces the name and cases of a user's evaluation set
+// Earlier runs are kept for comparison
+func (s *Store) UpdateEvalSet(ctx context.Context, userID, setID int64, name, cases string) error {
+	query := `UPDATE eval_sets SET name = ?, cases = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ? AND user_id = ?`
+	result, err := s.db.ExecContext(ctx, query, name, cases, setID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to update eval set: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("eval set not found: %d", setID)
+	}
+	return nil
+}
+
+// GetEvalSets returns a user's evaluation sets by name
+func (s *Store) GetEvalSets(ctx context.Context, userID int64) ([]EvalSet, error) {
+	query := `SELECT id, user_id, name, cases, created_at, updated_at FROM eval_sets WHERE user_id = ? ORDER BY name, id`
+	rows, err := s.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query eval sets: %w", err)
+	}
+	defer rows.Close()
+
+	var sets []EvalSet
+	for rows.Next() {
+		var set EvalSet
+		if err := rows.Scan(&set.ID, &set.UserID, &set.Name, &set.Cases, &set.CreatedAt, &set.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan eval set: %w", err)
+		}
+		sets = append(sets, set)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating eval sets: %w", err)
+	}
+	return sets, nil
+}
+
+// GetEvalSet returns a user's evaluation set, or nil if it does not exist
+func (s *Store) GetEvalSet(ctx context.Context, userID, setID int64) (*EvalSet, error) {
+	query := `SELECT id, user_id, name, cases, created_at, updated_at FROM eval_sets WHERE id = ? AND user_id = ?`
+	var set EvalSet
+	err := s.db.QueryRowContext(ctx, query, setID, userID).Scan(&set.ID, &set.UserID, &set.Name, &set.Cases, &set.CreatedAt, &set.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get eval set: %w", err)
+	}
+	return &set, nil
+}
+
+// DeleteEvalSet removes a user's evaluation set and its runs
+func (s *Store) DeleteEvalSet(ctx context.Context, userID, setID int64) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `DELETE FROM eval_sets WHERE id = ? AND user_id = ?`, setID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete eval set: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("eval set not found: %d", setID)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM eval_runs WHERE set_id = ?`, setID); err != nil {
+		return fmt.Errorf("failed to delete eval runs: %w", err)
+	}
+	return tx.Commit()
+}
+
+// RecordEvalRun stores an evaluation run and returns its ID
+// The set must belong to the run's user
+func (s *Store) RecordEvalRun(ctx context.Context, run *EvalRun) (int64, error) {
+	query := `
+		INSERT INTO eval_runs (set_id, user_id, config, metrics, results, duration_ms)
+		SELECT id, user_id, ?, ?, ?, ?
+		FROM eval_sets
+		WHERE id = ? AND user_id = ?
+	`
+	result, err := s.db.ExecContext(ctx, query, run.Config, run.Metrics, run.Results, run.DurationMS, run.SetID, run.UserID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to record eval run: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return 0, fmt.Errorf("eval set not found or access denied: %d", run.SetID)
+	}
+	return result.LastInsertId()
+}
+
+// evalRunColumns is the column list shared by eval run queries
+const evalRunColumns = `id, set_id, user_id, config, metrics, results, duration_ms, created_at`
+
+// GetEvalRuns returns the most recent runs of a user's evaluation set, newest
+// first, without their per-case results
+func (s *Store) GetEvalRuns(ctx context.Context, userID, setID int64, limit int) ([]EvalRun, error) {
+	query := `
+		SELECT id, set_id, user_id, config, metrics, '', duration_ms, created_at
+		FROM eval_runs
+		WHERE set_id = ? AND user_id = ?
+		ORDER BY created_at DESC, id DESC
+		LIMIT ?
+	`
+	rows, err := s.db.QueryContext(ctx, query, setID, userID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query eval runs: %w", err)
+	}
+	defer rows.Close()
+
+	var runs []EvalRun
+	for rows.Next() {
+		run, err := scanEvalRun(rows)
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, *run)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating eval runs: %w", err)
+	}
+	return runs, nil
+}
+
+// GetEvalRun returns a user's evaluation run with its results, or nil if it does not exist
+func (s *Store) GetEvalRun(ctx context.Context, userID, runID int64) (*EvalRun, error) {
+	query := `SELECT ` + evalRunColumns + ` FROM eval_runs WHERE id = ? AND user_id = ?`
+	run, err := scanEvalRun(s.db.QueryRowContext(ctx, query, runID, userID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return run, nil
+}
+
+// scanEvalRun reads one eval run in evalRunColumns order
+func scanEvalRun(row rowScanner) (*EvalRun, error) {
+	var run EvalRun
+	err := row.Scan(&run.ID, &run.SetID, &run.UserID, &run.Config, &run.Metrics, &run.Results, &run.DurationMS, &run.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan eval run: %w", err)
+	}
+	return &run, nil
+}
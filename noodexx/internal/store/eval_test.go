@@ -0,0 +1,67 @@
+package store
+
+import (
+	"context"
+	"testing"
+)
+
+func TestEvalSetsAndRuns(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	otherID, err := store.CreateUser(ctx, "other", "password", "other@test.com", false, false)
+	if err != nil {
+		t.Fatalf("CreateUser failed: %v", err)
+	}
+
+	setID, err := store.CreateEvalSet(ctx, 1, "handbook", `[{"question":"q","expected_sources":["a.md"]}]`)
+	if err != nil {
+		t.Fatalf("CreateEvalSet failed: %v", err)
+	}
+	if err := store.UpdateEvalSet(ctx, 1, setID, "handbook v2", `[]`); err != nil {
+		t.Fatalf("UpdateEvalSet failed: %v", err)
+	}
+	set, err := store.GetEvalSet(ctx, 1, setID)
+	if err != nil || set == nil || set.Name != "handbook v2" || set.Cases != "[]" {
+		t.Fatalf("Expected the updated set, got %+v (%v)", set, err)
+	}
+
+	// Other users cannot see, change or run the set
+	if set, _ := store.GetEvalSet(ctx, otherID, setID); set != nil {
+		t.Error("Expected another user's set to be hidden")
+	}
+	if err := store.UpdateEvalSet(ctx, otherID, setID, "x", "[]"); err == nil {
+		t.Error("Expected updating another user's set to fail")
+	}
+	if _, err := store.RecordEvalRun(ctx, &EvalRun{SetID: setID, UserID: otherID, Config: "{}", Metrics: "{}", Results: "[]"}); err == nil {
+		t.Error("Expected recording a run of another user's set to fail")
+	}
+
+	for _, metrics := range []string{`{"mrr":0.5}`, `{"mrr":0.7}`} {
+		if _, err := store.RecordEvalRun(ctx, &EvalRun{SetID: setID, UserID: 1, Config: `{"top_k":5}`, Metrics: metrics, Results: `[{"rank":1}]`, DurationMS: 12}); err != nil {
+			t.Fatalf("RecordEvalRun failed: %v", err)
+		}
+	}
+	runs, err := store.GetEvalRuns(ctx, 1, setID, 10)
+	if err != nil {
+		t.Fatalf("GetEvalRuns failed: %v", err)
+	}
+	if len(runs) != 2 || runs[0].Metrics != `{"mrr":0.7}` || runs[0].Results != "" {
+		t.Fatalf("Expected 2 runs newest first without results, got %+v", runs)
+	}
+	run, err := store.GetEvalRun(ctx, 1, runs[0].ID)
+	if err != nil || run == nil || run.Results != `[{"rank":1}]` || run.DurationMS != 12 {
+		t.Errorf("Expected the run with its results, got %+v (%v)", run, err)
+	}
+
+	if err := store.DeleteEvalSet(ctx, 1, setID); err != nil {
+		t.Fatalf("DeleteEvalSet failed: %v", err)
+	}
+	if run, _ := store.GetEvalRun(ctx, 1, runs[0].ID); run != nil {
+		t.Error("Expected the set's runs to be deleted with it")
+	}
+	if sets, _ := store.GetEvalSets(ctx, 1); len(sets) != 0 {
+		t.Errorf("Expected no sets, got %d", len(sets))
+	}
+}
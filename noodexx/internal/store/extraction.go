@@ -0,0 +1,152 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// CreateExtractionSchema stores a new record schema and returns its ID
+func (s *Store) CreateExtractionSchema(ctx context.Context, userID int64, name, description, schema string) (int64, error) {
+	query := `INSERT INTO extraction_schemas (user_id, name, description, schema) VALUES (?, ?, ?, ?)`
+	result, err := s.db.ExecContext(ctx, query, userID, name, description, schema)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create extraction schema: %w", err)
+	}
+	return result.LastInsertId()
+}
+
+// GetExtractionSchemas returns a user's record schemas by name
+func (s *Store) GetExtractionSchemas(ctx context.Context, userID int64) ([]ExtractionSchema, error) {
+	query := `SELECT id, user_id, name, description, schema, created_at FROM extraction_schemas WHERE user_id = ? ORDER BY name, id`
+	rows, err := s.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query extraction schemas: %w", err)
+	}
+	defer rows.Close()
+
+	var schemas []ExtractionSchema
+	for rows.Next() {
+		var es ExtractionSchema
+		if err := rows.Scan(&es.ID, &es.UserID, &es.Name, &es.Description, &es.Schema, &es.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan extraction schema: %w", err)
+		}
+		schemas = append(schemas, es)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating extraction schemas: %w", err)
+	}
+	return schemas, nil
+}
+
+// GetExtractionSchema returns a user's record schema, or nil if it does not exist
+func (s *Store) GetExtractionSchema(ctx context.Context, userID, schemaID int64) (*ExtractionSchema, error) {
+	query := `SELECT id, user_id, name, description, schema, created_at FROM extraction_schemas WHERE id = ? AND user_id = ?`
+	var es ExtractionSchema
+	err := s.db.QueryRowContext(ctx, query, schemaID, userID).Scan(&es.ID, &es.UserID, &es.Name, &es.Description, &es.Schema, &es.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get extraction schema: %w", err)
+	}
+	return &es, nil
+}
+
+// DeleteExtractionSchema removes a user's record schema and the records extracted with it
+func (s *Store) DeleteExtractionSchema(ctx context.Context, userID, schemaID int64) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `DELETE FROM extraction_schemas WHERE id = ? AND user_id = ?`, schemaID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete extraction schema: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("extraction schema not found: %d", schemaID)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM structured_records WHERE schema_id = ?`, schemaID); err != nil {
+		return fmt.Errorf("failed to delete structured records: %w", err)
+	}
+	return tx.Commit()
+}
+
+// SaveStructuredRecord stores the record extracted from a document, replacing
+// any earlier extraction of the same document with the same schema, and returns its ID
+func (s *Store) SaveStructuredRecord(ctx context.Context, record *StructuredRecord) (int64, error) {
+	query := `
+		INSERT INTO structured_records (schema_id, user_id, source, data, status, violations, error, model, extracted_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT(schema_id, source) DO UPDATE SET
+			data = excluded.data,
+			status = excluded.status,
+			violations = excluded.violations,
+			error = excluded.error,
+			model = excluded.model,
+			extracted_at = excluded.extracted_at
+		RETURNING id
+	`
+	var id int64
+	err := s.db.QueryRowContext(ctx, query, record.SchemaID, record.UserID, record.Source, record.Data,
+		record.Status, record.Violations, record.Error, record.Model).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("failed to save structured record: %w", err)
+	}
+	return id, nil
+}
+
+// GetStructuredRecords returns a page of a user's records matching the filter,
+// ordered by source, and how many match in total
+func (s *Store) GetStructuredRecords(ctx context.Context, userID int64, filter RecordFilter) ([]StructuredRecord, int, error) {
+	where := []string{"user_id = ?", "schema_id = ?"}
+	args := []interface{}{userID, filter.SchemaID}
+	if filter.Status != "" {
+		where = append(where, "status = ?")
+		args = append(args, filter.Status)
+	}
+	if filter.Source != "" {
+		where = append(where, "instr(lower(source), lower(?)) > 0")
+		args = append(args, filter.Source)
+	}
+	for field, value := range filter.Fields {
+		// The path is bound, so field names cannot change the query
+		where = append(where, "CAST(json_extract(data, ?) AS TEXT) = ?")
+		args = append(args, `$."`+field+`"`, value)
+	}
+	conditions := strings.Join(where, " AND ")
+
+	var total int
+	if err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM structured_records WHERE `+conditions, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count structured records: %w", err)
+	}
+
+	query := `
+		SELECT id, schema_id, user_id, source, data, status, violations, error, model, extracted_at
+		FROM structured_records
+		WHERE ` + conditions + `
+		ORDER BY source, id
+		LIMIT ? OFFSET ?
+	`
+	rows, err := s.db.QueryContext(ctx, query, append(args, filter.Limit, filter.Offset)...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query structured records: %w", err)
+	}
+	defer rows.Close()
+
+	var records []StructuredRecord
+	for rows.Next() {
+		var r StructuredRecord
+		if err := rows.Scan(&r.ID, &r.SchemaID, &r.UserID, &r.Source, &r.Data, &r.Status, &r.Violations, &r.Error, &r.Model, &r.ExtractedAt); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan structured record: %w", err)
+		}
+		records = append(records, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error iterating structured records: %w", err)
+	}
+	return records, total, nil
+}
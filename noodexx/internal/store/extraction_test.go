@@ -0,0 +1,88 @@
+package store
+
+import (
+	"context"
+	"testing"
+)
+
+func TestExtractionSchemasAndRecords(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	userID, err := store.CreateUser(ctx, "clerk", "password", "clerk@test.com", false, false)
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	otherID, err := store.CreateUser(ctx, "other", "password", "other@test.com", false, false)
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	schemaID, err := store.CreateExtractionSchema(ctx, userID, "invoices", "Supplier invoices", `{"type":"object"}`)
+	if err != nil {
+		t.Fatalf("CreateExtractionSchema failed: %v", err)
+	}
+	if _, err := store.CreateExtractionSchema(ctx, userID, "invoices", "", `{}`); err == nil {
+		t.Error("Expected a duplicate schema name to be rejected")
+	}
+	if es, err := store.GetExtractionSchema(ctx, otherID, schemaID); err != nil || es != nil {
+		t.Errorf("Expected another user not to see the schema, got %+v, %v", es, err)
+	}
+	schemas, err := store.GetExtractionSchemas(ctx, userID)
+	if err != nil || len(schemas) != 1 || schemas[0].Description != "Supplier invoices" {
+		t.Fatalf("Expected the schema, got %+v, %v", schemas, err)
+	}
+
+	save := func(source, data, status string) int64 {
+		t.Helper()
+		id, err := store.SaveStructuredRecord(ctx, &StructuredRecord{SchemaID: schemaID, UserID: userID, Source: source, Data: data, Status: status, Model: "llama3.2"})
+		if err != nil {
+			t.Fatalf("SaveStructuredRecord failed: %v", err)
+		}
+		return id
+	}
+	first := save("acme-01.pdf", `{"vendor":"Acme","amount":100}`, "ok")
+	save("globex-07.pdf", `{"vendor":"Globex","amount":250.5}`, "ok")
+	save("scan.png", `{}`, "failed")
+
+	// Extracting a document again replaces its record
+	if id := save("acme-01.pdf", `{"vendor":"Acme","amount":120}`, "ok"); id != first {
+		t.Errorf("Expected the record to be replaced in place, got id %d, want %d", id, first)
+	}
+
+	records, total, err := store.GetStructuredRecords(ctx, userID, RecordFilter{SchemaID: schemaID, Limit: 10})
+	if err != nil || total != 3 || len(records) != 3 || records[0].Data != `{"vendor":"Acme","amount":120}` {
+		t.Fatalf("Expected the 3 records by source, got %+v (%d), %v", records, total, err)
+	}
+
+	filters := []struct {
+		filter RecordFilter
+		want   string
+	}{
+		{RecordFilter{Status: "failed"}, "scan.png"},
+		{RecordFilter{Source: "GLOBEX"}, "globex-07.pdf"},
+		{RecordFilter{Fields: map[string]string{"vendor": "Acme"}}, "acme-01.pdf"},
+		{RecordFilter{Fields: map[string]string{"amount": "250.5"}}, "globex-07.pdf"},
+	}
+	for _, tt := range filters {
+		tt.filter.SchemaID, tt.filter.Limit = schemaID, 10
+		records, total, err := store.GetStructuredRecords(ctx, userID, tt.filter)
+		if err != nil || total != 1 || len(records) != 1 || records[0].Source != tt.want {
+			t.Errorf("Filter %+v: expected %s, got %+v, %v", tt.filter, tt.want, records, err)
+		}
+	}
+	if _, total, _ := store.GetStructuredRecords(ctx, otherID, RecordFilter{SchemaID: schemaID, Limit: 10}); total != 0 {
+		t.Errorf("Expected no records for another user, got %d", total)
+	}
+
+	if err := store.DeleteExtractionSchema(ctx, otherID, schemaID); err == nil {
+		t.Error("Expected another user's delete to fail")
+	}
+	if err := store.DeleteExtractionSchema(ctx, userID, schemaID); err != nil {
+		t.Fatalf("DeleteExtractionSchema failed: %v", err)
+	}
+	if _, total, _ := store.GetStructuredRecords(ctx, userID, RecordFilter{SchemaID: schemaID, Limit: 10}); total != 0 {
+		t.Errorf("Expected the schema's records deleted, got %d", total)
+	}
+}
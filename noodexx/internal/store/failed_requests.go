@@ -0,0 +1,74 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// SaveFailedRequest keeps a failed provider request for replay and returns its ID
+func (s *Store) SaveFailedRequest(ctx context.Context, f FailedRequest) (int64, error) {
+	result, err := s.db.ExecContext(ctx, `
+		INSERT INTO failed_requests (user_id, provider, model, operation, local, request, error, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, f.UserID, f.Provider, f.Model, f.Operation, f.Local, f.Request, f.Error, f.CreatedAt.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("failed to save failed request: %w", err)
+	}
+	return result.LastInsertId()
+}
+
+// GetFailedRequests returns the most recent failed provider requests, newest first
+func (s *Store) GetFailedRequests(ctx context.Context, limit int) ([]FailedRequest, error) {
+	rows, err := s.reader().QueryContext(ctx, `
+		SELECT id, user_id, provider, model, operation, local, request, error, created_at
+		FROM failed_requests
+		ORDER BY created_at DESC, id DESC
+		LIMIT ?
+	`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query failed requests: %w", err)
+	}
+	defer rows.Close()
+
+	var requests []FailedRequest
+	for rows.Next() {
+		var f FailedRequest
+		if err := rows.Scan(&f.ID, &f.UserID, &f.Provider, &f.Model, &f.Operation, &f.Local, &f.Request, &f.Error, &f.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan failed request: %w", err)
+		}
+		requests = append(requests, f)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating failed requests: %w", err)
+	}
+	return requests, nil
+}
+
+// GetFailedRequest returns a failed provider request, or nil if there is none with the ID
+func (s *Store) GetFailedRequest(ctx context.Context, id int64) (*FailedRequest, error) {
+	var f FailedRequest
+	err := s.reader().QueryRowContext(ctx, `
+		SELECT id, user_id, provider, model, operation, local, request, error, created_at
+		FROM failed_requests
+		WHERE id = ?
+	`, id).Scan(&f.ID, &f.UserID, &f.Provider, &f.Model, &f.Operation, &f.Local, &f.Request, &f.Error, &f.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get failed request: %w", err)
+	}
+	return &f, nil
+}
+
+// DeleteFailedRequestsBefore deletes failed provider requests made before a
+// time and returns how many were removed
+func (s *Store) DeleteFailedRequestsBefore(ctx context.Context, before time.Time) (int64, error) {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM failed_requests WHERE created_at < ?`, before.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete old failed requests: %w", err)
+	}
+	return result.RowsAffected()
+}
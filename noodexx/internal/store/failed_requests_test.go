@@ -0,0 +1,57 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// TestFailedRequests tests keeping, reading and expiring failed provider requests
+func TestFailedRequests(t *testing.T) {
+	store, err := NewStore(t.TempDir()+"/test_failed_requests.db", "multi")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+	base := time.Date(2025, 3, 1, 9, 30, 0, 0, time.UTC)
+	var ids []int64
+	for i, f := range []FailedRequest{
+		{UserID: 1, Provider: "openai", Model: "gpt-4o", Operation: "chat", Request: `{"messages":[]}`, Error: "429 rate limited", CreatedAt: base},
+		{UserID: 2, Provider: "ollama", Model: "nomic-embed-text", Operation: "embed", Local: true, Request: `{"input":"x"}`, Error: "connection refused", CreatedAt: base.Add(48 * time.Hour)},
+	} {
+		id, err := store.SaveFailedRequest(ctx, f)
+		if err != nil {
+			t.Fatalf("SaveFailedRequest %d failed: %v", i, err)
+		}
+		ids = append(ids, id)
+	}
+
+	all, err := store.GetFailedRequests(ctx, 10)
+	if err != nil || len(all) != 2 {
+		t.Fatalf("Expected 2 failed requests, got %d (%v)", len(all), err)
+	}
+	if all[0].ID != ids[1] || !all[0].Local || all[0].Operation != "embed" {
+		t.Errorf("Expected the newest request first, got %+v", all[0])
+	}
+
+	got, err := store.GetFailedRequest(ctx, ids[0])
+	if err != nil || got == nil {
+		t.Fatalf("GetFailedRequest failed: %v", err)
+	}
+	if got.Error != "429 rate limited" || got.Request != `{"messages":[]}` || got.Local || !got.CreatedAt.Equal(base) {
+		t.Errorf("Expected the first request unchanged, got %+v", got)
+	}
+	if missing, err := store.GetFailedRequest(ctx, 999); err != nil || missing != nil {
+		t.Errorf("Expected nil for an unknown request, got %+v (%v)", missing, err)
+	}
+
+	removed, err := store.DeleteFailedRequestsBefore(ctx, base.Add(24*time.Hour))
+	if err != nil || removed != 1 {
+		t.Errorf("Expected 1 failed request expired, got %d (%v)", removed, err)
+	}
+	if all, _ := store.GetFailedRequests(ctx, 10); len(all) != 1 || all[0].ID != ids[1] {
+		t.Errorf("Expected only the recent request kept, got %+v", all)
+	}
+}
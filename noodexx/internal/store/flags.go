@@ -0,0 +1,80 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// GetUserFlagOverrides returns the feature flags turned on or off for a user
+func (s *Store) GetUserFlagOverrides(ctx context.Context, userID int64) (map[string]bool, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT flag, enabled FROM feature_flag_overrides WHERE user_id = ?`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query flag overrides: %w", err)
+	}
+	defer rows.Close()
+
+	overrides := make(map[string]bool)
+	for rows.Next() {
+		var flag string
+		var enabled bool
+		if err := rows.Scan(&flag, &enabled); err != nil {
+			return nil, fmt.Errorf("failed to scan flag override: %w", err)
+		}
+		overrides[flag] = enabled
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating flag overrides: %w", err)
+	}
+	return overrides, nil
+}
+
+// GetFlagOverrides returns every user's flag overrides, ordered by flag and username
+func (s *Store) GetFlagOverrides(ctx context.Context) ([]FlagOverride, error) {
+	query := `
+		SELECT o.user_id, u.username, o.flag, o.enabled, o.updated_at
+		FROM feature_flag_overrides o
+		JOIN users u ON u.id = o.user_id
+		ORDER BY o.flag, u.username
+	`
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query flag overrides: %w", err)
+	}
+	defer rows.Close()
+
+	var overrides []FlagOverride
+	for rows.Next() {
+		var o FlagOverride
+		if err := rows.Scan(&o.UserID, &o.Username, &o.Flag, &o.Enabled, &o.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan flag override: %w", err)
+		}
+		overrides = append(overrides, o)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating flag overrides: %w", err)
+	}
+	return overrides, nil
+}
+
+// SetFlagOverride turns a feature flag on or off for a user, replacing any earlier override
+func (s *Store) SetFlagOverride(ctx context.Context, userID int64, flag string, enabled bool) error {
+	query := `
+		INSERT INTO feature_flag_overrides (user_id, flag, enabled, updated_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(user_id, flag) DO UPDATE SET enabled = excluded.enabled, updated_at = excluded.updated_at
+	`
+	if _, err := s.db.ExecContext(ctx, query, userID, flag, enabled, time.Now()); err != nil {
+		return fmt.Errorf("failed to set flag override: %w", err)
+	}
+	return nil
+}
+
+// DeleteFlagOverride removes a user's override so the flag follows the config again
+// Removing an override that does not exist is not an error
+func (s *Store) DeleteFlagOverride(ctx context.Context, userID int64, flag string) error {
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM feature_flag_overrides WHERE user_id = ? AND flag = ?`, userID, flag); err != nil {
+		return fmt.Errorf("failed to delete flag override: %w", err)
+	}
+	return nil
+}
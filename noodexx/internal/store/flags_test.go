@@ -0,0 +1,65 @@
+package store
+
+import (
+	"context"
+	"testing"
+)
+
+func TestFlagOverrides(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	otherID, err := store.CreateUser(ctx, "other", "password", "other@test.com", false, false)
+	if err != nil {
+		t.Fatalf("CreateUser failed: %v", err)
+	}
+
+	if err := store.SetFlagOverride(ctx, otherID, "web_search", false); err != nil {
+		t.Fatalf("SetFlagOverride failed: %v", err)
+	}
+	// Setting a flag again replaces the override
+	if err := store.SetFlagOverride(ctx, otherID, "web_search", true); err != nil {
+		t.Fatalf("SetFlagOverride failed: %v", err)
+	}
+	if err := store.SetFlagOverride(ctx, otherID, "rerank", false); err != nil {
+		t.Fatalf("SetFlagOverride failed: %v", err)
+	}
+
+	overrides, err := store.GetUserFlagOverrides(ctx, otherID)
+	if err != nil {
+		t.Fatalf("GetUserFlagOverrides failed: %v", err)
+	}
+	if len(overrides) != 2 || !overrides["web_search"] || overrides["rerank"] {
+		t.Errorf("Unexpected overrides: %v", overrides)
+	}
+	if overrides, _ := store.GetUserFlagOverrides(ctx, 1); len(overrides) != 0 {
+		t.Errorf("Expected no overrides for another user, got %v", overrides)
+	}
+
+	all, err := store.GetFlagOverrides(ctx)
+	if err != nil {
+		t.Fatalf("GetFlagOverrides failed: %v", err)
+	}
+	if len(all) != 2 || all[0].Flag != "rerank" || all[0].Username != "other" || all[1].Flag != "web_search" {
+		t.Errorf("Expected overrides ordered by flag with usernames, got %+v", all)
+	}
+
+	if err := store.DeleteFlagOverride(ctx, otherID, "rerank"); err != nil {
+		t.Fatalf("DeleteFlagOverride failed: %v", err)
+	}
+	if err := store.DeleteFlagOverride(ctx, otherID, "rerank"); err != nil {
+		t.Errorf("Expected deleting a missing override to succeed, got %v", err)
+	}
+	if overrides, _ := store.GetUserFlagOverrides(ctx, otherID); len(overrides) != 1 {
+		t.Errorf("Expected one override left, got %v", overrides)
+	}
+
+	// Deleting the user removes their overrides
+	if err := store.DeleteUser(ctx, otherID); err != nil {
+		t.Fatalf("DeleteUser failed: %v", err)
+	}
+	if all, _ := store.GetFlagOverrides(ctx); len(all) != 0 {
+		t.Errorf("Expected the deleted user's overrides to be removed, got %+v", all)
+	}
+}
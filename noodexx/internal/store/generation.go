@@ -0,0 +1,90 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// GetGenerationDefaults returns a user's default generation parameters
+// Users without saved defaults get empty defaults, which leave every provider default in place
+func (s *Store) GetGenerationDefaults(ctx context.Context, userID int64) (*GenerationDefaults, error) {
+	query := `SELECT temperature, top_p, max_tokens, updated_at FROM generation_defaults WHERE user_id = ?`
+
+	defaults := &GenerationDefaults{UserID: userID}
+	var temperature, topP sql.NullFloat64
+	err := s.db.QueryRowContext(ctx, query, userID).Scan(&temperature, &topP, &defaults.MaxTokens, &defaults.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return defaults, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get generation defaults: %w", err)
+	}
+
+	if temperature.Valid {
+		defaults.Temperature = &temperature.Float64
+	}
+	if topP.Valid {
+		defaults.TopP = &topP.Float64
+	}
+	return defaults, nil
+}
+
+// SaveGenerationDefaults stores a user's default generation parameters, replacing any previous values
+func (s *Store) SaveGenerationDefaults(ctx context.Context, defaults GenerationDefaults) error {
+	query := `
+		INSERT INTO generation_defaults (user_id, temperature, top_p, max_tokens, updated_at)
+		VALUES (?, ?, ?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT(user_id) DO UPDATE SET
+			temperature = excluded.temperature,
+			top_p = excluded.top_p,
+			max_tokens = excluded.max_tokens,
+			updated_at = CURRENT_TIMESTAMP
+	`
+
+	var temperature, topP interface{}
+	if defaults.Temperature != nil {
+		temperature = *defaults.Temperature
+	}
+	if defaults.TopP != nil {
+		topP = *defaults.TopP
+	}
+
+	if _, err := s.db.ExecContext(ctx, query, defaults.UserID, temperature, topP, defaults.MaxTokens); err != nil {
+		return fmt.Errorf("failed to save generation defaults: %w", err)
+	}
+	return nil
+}
+
+// GetAnswerStyle returns a user's default answer style
+// Users without a saved style get an empty one, which leaves the model's own behaviour in place
+func (s *Store) GetAnswerStyle(ctx context.Context, userID int64) (*AnswerStyle, error) {
+	query := `SELECT language, tone, citation_style, updated_at FROM answer_styles WHERE user_id = ?`
+
+	style := &AnswerStyle{UserID: userID}
+	err := s.db.QueryRowContext(ctx, query, userID).Scan(&style.Language, &style.Tone, &style.CitationStyle, &style.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return style, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get answer style: %w", err)
+	}
+	return style, nil
+}
+
+// SaveAnswerStyle stores a user's default answer style, replacing any previous one
+func (s *Store) SaveAnswerStyle(ctx context.Context, style AnswerStyle) error {
+	query := `
+		INSERT INTO answer_styles (user_id, language, tone, citation_style, updated_at)
+		VALUES (?, ?, ?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT(user_id) DO UPDATE SET
+			language = excluded.language,
+			tone = excluded.tone,
+			citation_style = excluded.citation_style,
+			updated_at = CURRENT_TIMESTAMP
+	`
+	if _, err := s.db.ExecContext(ctx, query, style.UserID, style.Language, style.Tone, style.CitationStyle); err != nil {
+		return fmt.Errorf("failed to save answer style: %w", err)
+	}
+	return nil
+}
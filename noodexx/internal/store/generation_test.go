@@ -0,0 +1,95 @@
+package store
+
+import (
+	"context"
+	"testing"
+)
+
+// TestGenerationDefaults tests saving, reading and clearing a user's generation defaults
+func TestGenerationDefaults(t *testing.T) {
+	store, err := NewStore(t.TempDir()+"/test_generation.db", "multi")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+
+	userID, err := store.CreateUser(ctx, "user", "password123", "user@example.com", false, false)
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	// No saved defaults: everything is left to the provider
+	defaults, err := store.GetGenerationDefaults(ctx, userID)
+	if err != nil {
+		t.Fatalf("Failed to get defaults: %v", err)
+	}
+	if defaults.Temperature != nil || defaults.TopP != nil || defaults.MaxTokens != 0 {
+		t.Errorf("Expected empty defaults, got %+v", defaults)
+	}
+
+	// A zero temperature is a real value, distinct from unset
+	zero := 0.0
+	if err := store.SaveGenerationDefaults(ctx, GenerationDefaults{UserID: userID, Temperature: &zero, MaxTokens: 512}); err != nil {
+		t.Fatalf("Failed to save defaults: %v", err)
+	}
+	defaults, err = store.GetGenerationDefaults(ctx, userID)
+	if err != nil {
+		t.Fatalf("Failed to get defaults: %v", err)
+	}
+	if defaults.Temperature == nil || *defaults.Temperature != 0 || defaults.TopP != nil || defaults.MaxTokens != 512 {
+		t.Errorf("Unexpected defaults: %+v", defaults)
+	}
+	if defaults.UpdatedAt.IsZero() {
+		t.Error("Expected updated_at to be set")
+	}
+
+	// Saving again replaces the previous values
+	topP := 0.8
+	if err := store.SaveGenerationDefaults(ctx, GenerationDefaults{UserID: userID, TopP: &topP}); err != nil {
+		t.Fatalf("Failed to save defaults: %v", err)
+	}
+	defaults, _ = store.GetGenerationDefaults(ctx, userID)
+	if defaults.Temperature != nil || defaults.TopP == nil || *defaults.TopP != 0.8 || defaults.MaxTokens != 0 {
+		t.Errorf("Expected replaced defaults, got %+v", defaults)
+	}
+}
+
+// TestAnswerStyle tests saving and replacing a user's answer style
+func TestAnswerStyle(t *testing.T) {
+	store, err := NewStore(t.TempDir()+"/test_answer_style.db", "multi")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+
+	userID, err := store.CreateUser(ctx, "user", "password123", "user@example.com", false, false)
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	style, err := store.GetAnswerStyle(ctx, userID)
+	if err != nil {
+		t.Fatalf("Failed to get answer style: %v", err)
+	}
+	if style.Language != "" || style.Tone != "" || style.CitationStyle != "" {
+		t.Errorf("Expected an empty style, got %+v", style)
+	}
+
+	if err := store.SaveAnswerStyle(ctx, AnswerStyle{UserID: userID, Language: "German", Tone: "concise"}); err != nil {
+		t.Fatalf("Failed to save answer style: %v", err)
+	}
+	if err := store.SaveAnswerStyle(ctx, AnswerStyle{UserID: userID, Language: "French", CitationStyle: "none"}); err != nil {
+		t.Fatalf("Failed to save answer style: %v", err)
+	}
+	style, err = store.GetAnswerStyle(ctx, userID)
+	if err != nil {
+		t.Fatalf("Failed to get answer style: %v", err)
+	}
+	if style.Language != "French" || style.Tone != "" || style.CitationStyle != "none" || style.UpdatedAt.IsZero() {
+		t.Errorf("Expected the saved style to be replaced, got %+v", style)
+	}
+}
@@ -0,0 +1,107 @@
+package store
+
+import (
+	"context"
+	"fmt"
+)
+
+// CreateGlobalSkill adds a skill every account gets, recorded as added by the
+// administrator. enabled is its setting for users who haven't changed it
+func (s *Store) CreateGlobalSkill(ctx context.Context, adminID int64, name, path string, enabled bool) (int64, error) {
+	query := `INSERT INTO skills (user_id, name, path, enabled, global) VALUES (?, ?, ?, ?, 1)`
+
+	result, err := s.db.ExecContext(ctx, query, adminID, name, path, enabled)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create global skill: %w", err)
+	}
+
+	skillID, err := result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get skill ID: %w", err)
+	}
+
+	return skillID, nil
+}
+
+// GetGlobalSkills returns the global skills, oldest first, with the
+// administrator who added each as its UserID
+func (s *Store) GetGlobalSkills(ctx context.Context) ([]Skill, error) {
+	query := `
+		SELECT id, user_id, name, path, enabled, created_at, global
+		FROM skills
+		WHERE global = 1
+		ORDER BY created_at, id
+	`
+
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query global skills: %w", err)
+	}
+	defer rows.Close()
+
+	var skills []Skill
+	for rows.Next() {
+		var skill Skill
+		if err := rows.Scan(&skill.ID, &skill.UserID, &skill.Name, &skill.Path, &skill.Enabled, &skill.CreatedAt, &skill.Global); err != nil {
+			return nil, fmt.Errorf("failed to scan global skill: %w", err)
+		}
+		skills = append(skills, skill)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating global skills: %w", err)
+	}
+	return skills, nil
+}
+
+// UpdateGlobalSkill sets whether a global skill is enabled for users who
+// haven't turned it on or off themselves. false means there is no such skill
+func (s *Store) UpdateGlobalSkill(ctx context.Context, skillID int64, enabled bool) (bool, error) {
+	result, err := s.db.ExecContext(ctx, `UPDATE skills SET enabled = ? WHERE id = ? AND global = 1`, enabled, skillID)
+	if err != nil {
+		return false, fmt.Errorf("failed to update global skill: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	return n > 0, nil
+}
+
+// DeleteGlobalSkill removes a global skill from every account, along with the
+// users' preferences for it. false means there is no such skill
+func (s *Store) DeleteGlobalSkill(ctx context.Context, skillID int64) (bool, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `DELETE FROM skills WHERE id = ? AND global = 1`, skillID)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete global skill: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return false, nil
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM skill_preferences WHERE skill_id = ?`, skillID); err != nil {
+		return false, fmt.Errorf("failed to delete skill preferences: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return true, nil
+}
+
+// setSkillPreference turns a global skill on or off for one user
+func (s *Store) setSkillPreference(ctx context.Context, userID, skillID int64, enabled bool) error {
+	query := `
+		INSERT INTO skill_preferences (user_id, skill_id, enabled, updated_at)
+		VALUES (?, ?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT(user_id, skill_id) DO UPDATE SET enabled = excluded.enabled, updated_at = excluded.updated_at
+	`
+	if _, err := s.db.ExecContext(ctx, query, userID, skillID, enabled); err != nil {
+		return fmt.Errorf("failed to update skill preference: %w", err)
+	}
+	return nil
+}
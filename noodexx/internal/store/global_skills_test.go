@@ -0,0 +1,131 @@
+package store
+
+import (
+	"context"
+	"testing"
+)
+
+// userSkill finds a skill by name in a user's skill list
+func userSkill(t *testing.T, store *Store, userID int64, name string) *Skill {
+	t.Helper()
+	skills, err := store.GetUserSkills(context.Background(), userID)
+	if err != nil {
+		t.Fatalf("GetUserSkills failed: %v", err)
+	}
+	for i := range skills {
+		if skills[i].Name == name {
+			return &skills[i]
+		}
+	}
+	return nil
+}
+
+// TestGlobalSkills tests that global skills are merged into every user's
+// skills, can be turned off per user, and give way to a user's own skill of
+// the same name
+func TestGlobalSkills(t *testing.T) {
+	store, cleanup := setupSkillsTestStore(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	adminID, err := store.CreateUser(ctx, "skills-admin", "password123", "skills-admin@example.com", true, false)
+	if err != nil {
+		t.Fatalf("Failed to create admin: %v", err)
+	}
+	userID, err := store.CreateUser(ctx, "user", "password123", "user@example.com", false, false)
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	otherID, err := store.CreateUser(ctx, "other", "password123", "other@example.com", false, false)
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	summarizerID, err := store.CreateGlobalSkill(ctx, adminID, "summarizer", "summarizer", true)
+	if err != nil {
+		t.Fatalf("CreateGlobalSkill failed: %v", err)
+	}
+	if _, err := store.CreateGlobalSkill(ctx, adminID, "web-clipper", "web-clipper", true); err != nil {
+		t.Fatalf("CreateGlobalSkill failed: %v", err)
+	}
+
+	globals, err := store.GetGlobalSkills(ctx)
+	if err != nil {
+		t.Fatalf("GetGlobalSkills failed: %v", err)
+	}
+	if len(globals) != 2 || globals[0].Name != "summarizer" || globals[0].UserID != adminID || !globals[0].Global {
+		t.Fatalf("Expected both global skills, added by the admin, got %+v", globals)
+	}
+
+	// Every user gets the global skills, as their own
+	skill := userSkill(t, store, userID, "summarizer")
+	if skill == nil || !skill.Global || !skill.Enabled || skill.UserID != userID {
+		t.Fatalf("Expected the user to have the enabled summarizer, got %+v", skill)
+	}
+
+	// A user's own skill of the same name replaces the global one
+	ownID, err := store.CreateSkill(ctx, userID, "web-clipper", "my-clipper", true)
+	if err != nil {
+		t.Fatalf("CreateSkill failed: %v", err)
+	}
+	skills, err := store.GetUserSkills(ctx, userID)
+	if err != nil {
+		t.Fatalf("GetUserSkills failed: %v", err)
+	}
+	if len(skills) != 2 || skills[0].ID != ownID || skills[0].Global || skills[1].ID != summarizerID {
+		t.Errorf("Expected the user's own web-clipper and the global summarizer, got %+v", skills)
+	}
+
+	// Turning a global skill off only affects that user
+	if err := store.UpdateSkillEnabled(ctx, userID, summarizerID, false); err != nil {
+		t.Fatalf("UpdateSkillEnabled failed: %v", err)
+	}
+	if skill := userSkill(t, store, userID, "summarizer"); skill == nil || skill.Enabled {
+		t.Errorf("Expected the summarizer to be off for the user, got %+v", skill)
+	}
+	if skill := userSkill(t, store, otherID, "summarizer"); skill == nil || !skill.Enabled {
+		t.Errorf("Expected the summarizer to stay on for others, got %+v", skill)
+	}
+
+	// The admin's default applies to users without a preference of their own
+	if ok, err := store.UpdateGlobalSkill(ctx, summarizerID, false); err != nil || !ok {
+		t.Fatalf("UpdateGlobalSkill failed: %v, %v", ok, err)
+	}
+	if err := store.UpdateSkillEnabled(ctx, userID, summarizerID, true); err != nil {
+		t.Fatalf("UpdateSkillEnabled failed: %v", err)
+	}
+	if skill := userSkill(t, store, userID, "summarizer"); skill == nil || !skill.Enabled {
+		t.Errorf("Expected the user's preference to win, got %+v", skill)
+	}
+	if skill := userSkill(t, store, otherID, "summarizer"); skill == nil || skill.Enabled {
+		t.Errorf("Expected the summarizer to be off by default, got %+v", skill)
+	}
+
+	// Users can't delete global skills, but can run them
+	if err := store.DeleteSkill(ctx, adminID, summarizerID); err == nil {
+		t.Error("Expected DeleteSkill to refuse a global skill")
+	}
+	if _, err := store.RecordSkillRun(ctx, &SkillRun{SkillID: summarizerID, UserID: otherID, Status: SkillRunSuccess}); err != nil {
+		t.Errorf("Expected a run of a global skill to be recorded: %v", err)
+	}
+	runs, _, err := store.GetSkillRuns(ctx, otherID, summarizerID, 10, 0)
+	if err != nil || len(runs) != 1 {
+		t.Errorf("Expected the run under the user who ran it, got %+v, %v", runs, err)
+	}
+
+	// Deleting a global skill removes it and its preferences
+	if ok, err := store.DeleteGlobalSkill(ctx, summarizerID); err != nil || !ok {
+		t.Fatalf("DeleteGlobalSkill failed: %v, %v", ok, err)
+	}
+	if skill := userSkill(t, store, userID, "summarizer"); skill != nil {
+		t.Errorf("Expected the deleted summarizer to be gone, got %+v", skill)
+	}
+	var preferences int
+	if err := store.db.QueryRow(`SELECT COUNT(*) FROM skill_preferences WHERE skill_id = ?`, summarizerID).Scan(&preferences); err != nil || preferences != 0 {
+		t.Errorf("Expected the preferences to be removed, got %d, %v", preferences, err)
+	}
+	if ok, err := store.DeleteGlobalSkill(ctx, ownID); err != nil || ok {
+		t.Errorf("Expected DeleteGlobalSkill to leave a user's own skill, got %v, %v", ok, err)
+	}
+}
@@ -0,0 +1,249 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Search modes
+const (
+	SearchVector  = "vector"  // Cosine similarity of embeddings
+	SearchKeyword = "keyword" // BM25 scores of the query's words
+	SearchHybrid  = "hybrid"  // Both rankings, fused into one
+)
+
+// Ways hybrid search fuses its two rankings
+const (
+	FusionRRF      = "rrf"      // Reciprocal rank fusion, by rank alone
+	FusionWeighted = "weighted" // Normalized scores, weighted by KeywordWeight
+)
+
+// rrfK damps how much the top ranks of each ranking outweigh the rest in
+// reciprocal rank fusion; 60 is the usual choice
+const rrfK = 60
+
+// hybridCandidateFactor is how many more candidates than requested each
+// ranking contributes to hybrid search
+const hybridCandidateFactor = 2
+
+// SearchOptions chooses how SearchLibrary ranks chunks. Mode, Fusion and
+// KeywordWeight left unset take the store's defaults (see SetSearchDefaults)
+type SearchOptions struct {
+	Query         string   // Text of the query, for keyword scoring; without it chunks are ranked by vector alone
+	QueryModel    string   // Embedding model of the query vector, see SearchByUser
+	Sources       []string // Only chunks of these sources, unless nil; empty finds nothing
+	Mode          string   // SearchVector, SearchKeyword or SearchHybrid
+	Fusion        string   // FusionRRF or FusionWeighted, for hybrid search
+	KeywordWeight float64  // Weight of keyword scores in weighted fusion, between 0 and 1
+}
+
+// SetSearchDefaults sets the mode, fusion and keyword weight of searches that
+// leave them unset. Without defaults, search compares vectors only and hybrid
+// search fuses by rank
+func (s *Store) SetSearchDefaults(opts SearchOptions) {
+	s.searchDefaults = SearchOptions{Mode: opts.Mode, Fusion: opts.Fusion, KeywordWeight: opts.KeywordWeight}
+}
+
+// SearchLibrary searches the chunks visible to the user, by vector as
+// SearchByUser does, by keyword or both. Keyword search scores chunks with
+// BM25 over their text, applying the search dictionary, so exact terms such
+// as error codes and product names rank first even when their embeddings are
+// not close to the query's. Both apply the user's ranking modifiers. Hybrid
+// search fuses the two rankings, so a chunk found by both ranks above one
+// found by either
+func (s *Store) SearchLibrary(ctx context.Context, userID int64, queryVec []float32, opts SearchOptions, topK int) ([]Chunk, error) {
+	if opts.Sources != nil && len(opts.Sources) == 0 {
+		return nil, nil
+	}
+	if opts.Mode == "" {
+		opts.Mode = s.searchDefaults.Mode
+	}
+	if opts.Fusion == "" {
+		opts.Fusion = s.searchDefaults.Fusion
+	}
+	if opts.KeywordWeight == 0 {
+		opts.KeywordWeight = s.searchDefaults.KeywordWeight
+	}
+	if strings.TrimSpace(opts.Query) == "" {
+		opts.Mode = SearchVector
+	}
+
+	switch opts.Mode {
+	case SearchKeyword:
+		return s.searchKeywords(ctx, userID, opts.Query, opts.Sources, topK)
+	case SearchHybrid:
+		candidates := topK * hybridCandidateFactor
+		vector, err := s.searchByUser(ctx, userID, queryVec, opts.QueryModel, opts.Sources, candidates)
+		if err != nil {
+			return nil, err
+		}
+		keyword, err := s.searchKeywords(ctx, userID, opts.Query, opts.Sources, candidates)
+		if err != nil {
+			return nil, err
+		}
+		if opts.Fusion == FusionWeighted {
+			return fuseWeighted(vector, keyword, opts.KeywordWeight, topK), nil
+		}
+		return fuseRRF(vector, keyword, topK), nil
+	default:
+		return s.searchByUser(ctx, userID, queryVec, opts.QueryModel, opts.Sources, topK)
+	}
+}
+
+// searchKeywords ranks the chunks visible to the user that have any word of
+// query by BM25, only those of sources unless it is nil. A chunk's score is
+// its BM25 score, higher for better matches, times the user's ranking modifier
+func (s *Store) searchKeywords(ctx context.Context, userID int64, query string, sources []string, topK int) ([]Chunk, error) {
+	dict, err := s.GetSearchDictionary(ctx)
+	if err != nil {
+		return nil, err
+	}
+	match := dict.ftsAnyQuery(query)
+	if match == "" || topK <= 0 {
+		return nil, nil
+	}
+	modifier, err := s.rankingModifier(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Rank the matching chunks first, then read the best of them
+	filter := `(c.user_id = ?
+			OR c.visibility = 'public'
+			OR (',' || COALESCE(c.shared_with, '') || ',') LIKE '%,' || CAST(? AS TEXT) || ',%')`
+	args := []interface{}{match, userID, userID}
+	if sources != nil {
+		filter += ` AND c.source IN (?` + strings.Repeat(`, ?`, len(sources)-1) + `)`
+		for _, source := range sources {
+			args = append(args, source)
+		}
+	}
+	rows, err := s.reader().QueryContext(ctx, `
+		SELECT c.id, bm25(chunks_fts)
+		FROM chunks_fts
+		JOIN chunks c ON c.id = chunks_fts.rowid
+		WHERE chunks_fts MATCH ? AND `+filter+`
+		ORDER BY bm25(chunks_fts)
+		LIMIT ?
+	`, append(args, topK)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search chunk keywords: %w", err)
+	}
+	scores := make(map[int64]float64)
+	var ids []interface{}
+	for rows.Next() {
+		var id int64
+		var rank float64
+		if err := rows.Scan(&id, &rank); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan keyword match: %w", err)
+		}
+		// bm25 is negative, lower for better matches
+		scores[id] = -rank
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return nil, fmt.Errorf("error iterating keyword matches: %w", err)
+	}
+	rows.Close()
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	embeddingColumn := "embedding"
+	if s.vindex != nil {
+		embeddingColumn = "NULL"
+	}
+	chunks, err := s.readChunkPage(ctx, `
+		SELECT id, source, text, `+embeddingColumn+`, NULL, tags, summary, created_at
+		FROM chunks
+		WHERE id IN (?`+strings.Repeat(`, ?`, len(ids)-1)+`)
+	`, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keyword matches: %w", err)
+	}
+	if s.vindex != nil {
+		if err := s.fillEmbeddings(ctx, chunks); err != nil {
+			return nil, err
+		}
+	}
+
+	top := newTopChunks(topK)
+	for _, c := range chunks {
+		top.add(c, scores[c.ID]*modifier(c))
+	}
+	return top.results(), nil
+}
+
+// fuseRRF merges two rankings by reciprocal rank fusion: each chunk scores
+// the sum of 1/(rrfK+rank) over the rankings that have it, so scores are
+// small, at most about 0.033, and only their order matters
+func fuseRRF(vector, keyword []Chunk, topK int) []Chunk {
+	fused := newFusion()
+	for _, ranking := range [][]Chunk{vector, keyword} {
+		for rank, c := range ranking {
+			fused.add(c, 1/float64(rrfK+rank+1))
+		}
+	}
+	return fused.results(topK)
+}
+
+// fuseWeighted merges two rankings by score: each ranking's scores are
+// divided by its best, and a chunk scores (1-weight)*vector + weight*keyword,
+// with 0 for a ranking that doesn't have it
+func fuseWeighted(vector, keyword []Chunk, keywordWeight float64, topK int) []Chunk {
+	fused := newFusion()
+	for _, ranking := range []struct {
+		chunks []Chunk
+		weight float64
+	}{{vector, 1 - keywordWeight}, {keyword, keywordWeight}} {
+		if len(ranking.chunks) == 0 || ranking.chunks[0].Score <= 0 {
+			continue
+		}
+		best := ranking.chunks[0].Score
+		for _, c := range ranking.chunks {
+			fused.add(c, ranking.weight*max(c.Score, 0)/best)
+		}
+	}
+	return fused.results(topK)
+}
+
+// fusion sums the scores chunks get in several rankings
+type fusion struct {
+	chunks map[int64]Chunk
+	scores map[int64]float64
+}
+
+func newFusion() *fusion {
+	return &fusion{chunks: make(map[int64]Chunk), scores: make(map[int64]float64)}
+}
+
+func (f *fusion) add(c Chunk, score float64) {
+	if _, ok := f.chunks[c.ID]; !ok {
+		f.chunks[c.ID] = c
+	}
+	f.scores[c.ID] += score
+}
+
+// results returns the topK chunks with the highest summed scores, ties in
+// chunk order
+func (f *fusion) results(topK int) []Chunk {
+	results := make([]Chunk, 0, len(f.chunks))
+	for id, c := range f.chunks {
+		c.Score = f.scores[id]
+		results = append(results, c)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].ID < results[j].ID
+	})
+	if len(results) > topK {
+		results = results[:topK]
+	}
+	return results
+}
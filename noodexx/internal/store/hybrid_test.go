@@ -0,0 +1,143 @@
+package store
+
+import (
+	"context"
+	"os"
+	"testing"
+)
+
+func TestFuseRRF(t *testing.T) {
+	vector := []Chunk{{ID: 1, Score: 0.9}, {ID: 2, Score: 0.8}, {ID: 3, Score: 0.7}}
+	keyword := []Chunk{{ID: 3, Score: 12}, {ID: 4, Score: 9}}
+
+	results := fuseRRF(vector, keyword, 3)
+	if len(results) != 3 {
+		t.Fatalf("Expected 3 results, got %+v", results)
+	}
+	// Chunk 3 is in both rankings, so it outranks the best of either
+	if results[0].ID != 3 || results[1].ID != 1 {
+		t.Errorf("Expected chunks 3 and 1 first, got %+v", results)
+	}
+	if want := 1.0/63 + 1.0/61; results[0].Score != want {
+		t.Errorf("Expected fused score %v, got %v", want, results[0].Score)
+	}
+}
+
+func TestFuseWeighted(t *testing.T) {
+	vector := []Chunk{{ID: 1, Score: 0.8}, {ID: 2, Score: 0.4}}
+	keyword := []Chunk{{ID: 2, Score: 10}, {ID: 3, Score: 5}}
+
+	results := fuseWeighted(vector, keyword, 0.5, 10)
+	want := map[int64]float64{1: 0.5, 2: 0.75, 3: 0.25}
+	if len(results) != 3 || results[0].ID != 2 {
+		t.Fatalf("Expected chunk 2 first of 3, got %+v", results)
+	}
+	for _, c := range results {
+		if c.Score != want[c.ID] {
+			t.Errorf("Expected chunk %d to score %v, got %v", c.ID, want[c.ID], c.Score)
+		}
+	}
+
+	// Without keyword weight the vector ranking is kept
+	results = fuseWeighted(vector, keyword, 0, 10)
+	if results[0].ID != 1 {
+		t.Errorf("Expected chunk 1 first by vector alone, got %+v", results)
+	}
+}
+
+// TestSearchLibrary tests that keyword and hybrid search find an exact term
+// whose embedding is far from the query's
+func TestSearchLibrary(t *testing.T) {
+	tmpFile := "test_search_library.db"
+	defer os.Remove(tmpFile)
+
+	store, err := NewStore(tmpFile, "multi")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+
+	userID, err := store.CreateUser(ctx, "searcher", "password", "searcher@test.com", false, false)
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	otherID, err := store.CreateUser(ctx, "other", "password", "other@test.com", false, false)
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	chunks := []struct {
+		userID    int64
+		source    string
+		text      string
+		embedding []float32
+	}{
+		{userID, "faq.md", "Printers jam when the paper tray is overfilled", []float32{1, 0, 0}},
+		{userID, "errors.md", "Error E4012 means the fuser is overheating", []float32{0, 1, 0}},
+		{userID, "manual.md", "Clear a paper jam by opening the rear door", []float32{0.9, 0.1, 0}},
+		{otherID, "private.md", "E4012 notes nobody else may read", []float32{0, 1, 0}},
+	}
+	for _, c := range chunks {
+		if err := store.SaveChunk(ctx, c.userID, c.source, c.text, c.embedding, nil, ""); err != nil {
+			t.Fatalf("Failed to save chunk: %v", err)
+		}
+	}
+
+	// The query's embedding is close to the paper jam chunks, not the error code
+	queryVec := []float32{1, 0, 0}
+	search := func(opts SearchOptions, topK int) []Chunk {
+		t.Helper()
+		results, err := store.SearchLibrary(ctx, userID, queryVec, opts, topK)
+		if err != nil {
+			t.Fatalf("SearchLibrary failed: %v", err)
+		}
+		return results
+	}
+
+	results := search(SearchOptions{Query: "printer error E4012"}, 1)
+	if len(results) != 1 || results[0].Source != "faq.md" {
+		t.Errorf("Expected vector search by default, got %+v", results)
+	}
+
+	results = search(SearchOptions{Query: "error E4012", Mode: SearchKeyword}, 5)
+	if len(results) != 1 || results[0].Source != "errors.md" {
+		t.Errorf("Expected only the visible chunk with the words, got %+v", results)
+	}
+
+	results = search(SearchOptions{Query: "rear door E4012", Mode: SearchHybrid}, 3)
+	if len(results) != 3 || results[0].Source != "manual.md" {
+		t.Errorf("Expected the chunk found both ways first, got %+v", results)
+	}
+
+	// Defaults apply to searches that leave the mode unset
+	store.SetSearchDefaults(SearchOptions{Mode: SearchHybrid, Fusion: FusionWeighted, KeywordWeight: 0.9})
+	results = search(SearchOptions{Query: "E4012"}, 1)
+	if len(results) != 1 || results[0].Source != "errors.md" {
+		t.Errorf("Expected the error code first with weighted keywords, got %+v", results)
+	}
+
+	// Without query text only vectors are compared
+	results = search(SearchOptions{Mode: SearchKeyword}, 1)
+	if len(results) != 1 || results[0].Source != "faq.md" {
+		t.Errorf("Expected vector search without query text, got %+v", results)
+	}
+
+	results = search(SearchOptions{Query: "E4012", Mode: SearchKeyword, Sources: []string{"faq.md"}}, 5)
+	if len(results) != 0 {
+		t.Errorf("Expected no match outside the sources, got %+v", results)
+	}
+	if results := search(SearchOptions{Query: "E4012", Sources: []string{}}, 5); results != nil {
+		t.Errorf("Expected no sources to find nothing, got %+v", results)
+	}
+
+	// Deleted chunks leave the keyword index
+	if err := store.DeleteChunksBySource(ctx, userID, "errors.md"); err != nil {
+		t.Fatalf("Failed to delete source: %v", err)
+	}
+	results = search(SearchOptions{Query: "E4012", Mode: SearchKeyword}, 5)
+	if len(results) != 0 {
+		t.Errorf("Expected the deleted chunk gone from keyword search, got %+v", results)
+	}
+}
@@ -0,0 +1,78 @@
+package store
+
+import (
+	"context"
+	"fmt"
+)
+
+// RecordIngestFailure records a failed attempt to ingest a watched file and
+// returns how many times in a row it has failed
+func (s *Store) RecordIngestFailure(ctx context.Context, userID int64, folder, path, errMsg string) (int, error) {
+	query := `
+		INSERT INTO ingest_failures (path, folder, user_id, error, attempts)
+		VALUES (?, ?, ?, ?, 1)
+		ON CONFLICT(path) DO UPDATE SET
+			folder = excluded.folder,
+			user_id = excluded.user_id,
+			error = excluded.error,
+			attempts = ingest_failures.attempts + 1,
+			last_attempt_at = CURRENT_TIMESTAMP
+	`
+	if _, err := s.db.ExecContext(ctx, query, path, folder, userID, errMsg); err != nil {
+		return 0, fmt.Errorf("failed to record ingest failure: %w", err)
+	}
+
+	var attempts int
+	if err := s.db.QueryRowContext(ctx, `SELECT attempts FROM ingest_failures WHERE path = ?`, path).Scan(&attempts); err != nil {
+		return 0, fmt.Errorf("failed to read ingest failure: %w", err)
+	}
+	return attempts, nil
+}
+
+// GetIngestFailureAttempts returns how many times in a row a watched file has
+// failed to ingest, 0 if it has not
+func (s *Store) GetIngestFailureAttempts(ctx context.Context, path string) (int, error) {
+	var attempts int
+	err := s.db.QueryRowContext(ctx, `SELECT COALESCE(MAX(attempts), 0) FROM ingest_failures WHERE path = ?`, path).Scan(&attempts)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read ingest failure: %w", err)
+	}
+	return attempts, nil
+}
+
+// ClearIngestFailure forgets a watched file's failures, after it ingests or is removed
+func (s *Store) ClearIngestFailure(ctx context.Context, path string) error {
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM ingest_failures WHERE path = ?`, path); err != nil {
+		return fmt.Errorf("failed to clear ingest failure: %w", err)
+	}
+	return nil
+}
+
+// GetIngestFailures returns the files of a user's watched folder that failed to
+// ingest, most recently attempted first
+func (s *Store) GetIngestFailures(ctx context.Context, userID int64, folder string) ([]IngestFailure, error) {
+	query := `
+		SELECT path, folder, user_id, error, attempts, first_failed_at, last_attempt_at
+		FROM ingest_failures
+		WHERE user_id = ? AND folder = ?
+		ORDER BY last_attempt_at DESC, path
+	`
+	rows, err := s.db.QueryContext(ctx, query, userID, folder)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query ingest failures: %w", err)
+	}
+	defer rows.Close()
+
+	var failures []IngestFailure
+	for rows.Next() {
+		var f IngestFailure
+		if err := rows.Scan(&f.Path, &f.Folder, &f.UserID, &f.Error, &f.Attempts, &f.FirstFailedAt, &f.LastAttemptAt); err != nil {
+			return nil, fmt.Errorf("failed to scan ingest failure: %w", err)
+		}
+		failures = append(failures, f)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating ingest failures: %w", err)
+	}
+	return failures, nil
+}
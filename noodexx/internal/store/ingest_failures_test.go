@@ -0,0 +1,68 @@
+package store
+
+import (
+	"context"
+	"os"
+	"testing"
+)
+
+// TestIngestFailures tests counting, listing and clearing watched file failures
+func TestIngestFailures(t *testing.T) {
+	tmpFile := "test_ingest_failures.db"
+	defer os.Remove(tmpFile)
+
+	store, err := NewStore(tmpFile, "multi")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+
+	userID, err := store.CreateUser(ctx, "watcher", "password", "", false, false)
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	// Each failure counts another attempt and keeps the latest error
+	for i, errMsg := range []string{"first error", "second error"} {
+		attempts, err := store.RecordIngestFailure(ctx, userID, "/docs", "/docs/bad.pdf", errMsg)
+		if err != nil {
+			t.Fatalf("RecordIngestFailure failed: %v", err)
+		}
+		if attempts != i+1 {
+			t.Errorf("Expected %d attempts, got %d", i+1, attempts)
+		}
+	}
+	if _, err := store.RecordIngestFailure(ctx, userID, "/other", "/other/bad.md", "elsewhere"); err != nil {
+		t.Fatalf("RecordIngestFailure failed: %v", err)
+	}
+
+	attempts, err := store.GetIngestFailureAttempts(ctx, "/docs/bad.pdf")
+	if err != nil || attempts != 2 {
+		t.Errorf("Expected 2 attempts, got %d (%v)", attempts, err)
+	}
+	if attempts, _ := store.GetIngestFailureAttempts(ctx, "/docs/good.pdf"); attempts != 0 {
+		t.Errorf("Expected no attempts for a file that never failed, got %d", attempts)
+	}
+
+	// Failures are listed per folder and per user
+	failures, err := store.GetIngestFailures(ctx, userID, "/docs")
+	if err != nil {
+		t.Fatalf("GetIngestFailures failed: %v", err)
+	}
+	if len(failures) != 1 || failures[0].Error != "second error" || failures[0].Attempts != 2 || failures[0].FirstFailedAt.IsZero() {
+		t.Errorf("Unexpected failures: %+v", failures)
+	}
+	if failures, _ := store.GetIngestFailures(ctx, userID+1, "/docs"); len(failures) != 0 {
+		t.Errorf("Expected no failures for another user, got %+v", failures)
+	}
+
+	// Clearing starts the count again
+	if err := store.ClearIngestFailure(ctx, "/docs/bad.pdf"); err != nil {
+		t.Fatalf("ClearIngestFailure failed: %v", err)
+	}
+	if attempts, _ := store.GetIngestFailureAttempts(ctx, "/docs/bad.pdf"); attempts != 0 {
+		t.Errorf("Expected the failures to be cleared, got %d attempts", attempts)
+	}
+}
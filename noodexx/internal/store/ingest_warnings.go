@@ -0,0 +1,34 @@
+package store
+
+import (
+	"context"
+	"fmt"
+)
+
+// SetIngestWarning records why a document's latest ingestion kept only part of
+// it; an empty warning clears the previous one
+func (s *Store) SetIngestWarning(ctx context.Context, userID int64, source, warning string) error {
+	return setIngestWarning(ctx, s.db, userID, source, warning)
+}
+
+// setIngestWarning replaces a document's ingest warning using the given connection or transaction
+func setIngestWarning(ctx context.Context, ex execer, userID int64, source, warning string) error {
+	if warning == "" {
+		if _, err := ex.ExecContext(ctx, `DELETE FROM ingest_warnings WHERE user_id = ? AND source = ?`, userID, source); err != nil {
+			return fmt.Errorf("failed to clear ingest warning: %w", err)
+		}
+		return nil
+	}
+
+	query := `
+		INSERT INTO ingest_warnings (user_id, source, warning)
+		VALUES (?, ?, ?)
+		ON CONFLICT(user_id, source) DO UPDATE SET
+			warning = excluded.warning,
+			created_at = CURRENT_TIMESTAMP
+	`
+	if _, err := ex.ExecContext(ctx, query, userID, source, warning); err != nil {
+		return fmt.Errorf("failed to record ingest warning: %w", err)
+	}
+	return nil
+}
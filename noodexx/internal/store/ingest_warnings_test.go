@@ -0,0 +1,60 @@
+package store
+
+import (
+	"context"
+	"testing"
+)
+
+// TestIngestWarningInLibrary tests that a document's ingest warning is listed
+// with it in the library until it is cleared
+func TestIngestWarningInLibrary(t *testing.T) {
+	store, err := NewStore(t.TempDir()+"/test_ingest_warnings.db", "multi")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+
+	userID, err := store.CreateUser(ctx, "alice", "password123", "alice@example.com", false, false)
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	err = store.WithTx(ctx, func(tx StoreTx) error {
+		if err := tx.SaveChunk(ctx, userID, "huge.log", "first lines", []float32{1, 0}, nil, ""); err != nil {
+			return err
+		}
+		return tx.SetIngestWarning(ctx, userID, "huge.log", "kept the first 1 of 900 chunks")
+	})
+	if err != nil {
+		t.Fatalf("WithTx failed: %v", err)
+	}
+	if err := store.SaveChunk(ctx, userID, "notes.txt", "notes", []float32{0, 1}, nil, ""); err != nil {
+		t.Fatalf("Failed to save chunk: %v", err)
+	}
+
+	warnings := func() map[string]string {
+		entries, err := store.LibraryByUser(ctx, userID)
+		if err != nil {
+			t.Fatalf("LibraryByUser failed: %v", err)
+		}
+		found := make(map[string]string)
+		for _, e := range entries {
+			found[e.Source] = e.Warning
+		}
+		return found
+	}
+
+	got := warnings()
+	if got["huge.log"] != "kept the first 1 of 900 chunks" || got["notes.txt"] != "" {
+		t.Errorf("Unexpected warnings %v", got)
+	}
+
+	if err := store.SetIngestWarning(ctx, userID, "huge.log", ""); err != nil {
+		t.Fatalf("SetIngestWarning failed: %v", err)
+	}
+	if got := warnings(); got["huge.log"] != "" {
+		t.Errorf("Expected the warning to be cleared, got %q", got["huge.log"])
+	}
+}
@@ -0,0 +1,114 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// ForEachChunk calls fn with each chunk the user owns, with its embedding, in id
+// order until fn returns false. Chunks are read a page at a time, so exports
+// and re-embedding jobs can walk a library of any size in bounded memory
+func (s *Store) ForEachChunk(ctx context.Context, userID int64, fn func(Chunk) bool) error {
+	err := s.scanChunkPages(ctx, "user_id = ?", []interface{}{userID}, func(page []Chunk) bool {
+		for _, c := range page {
+			if !fn(c) {
+				return false
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return fmt.Errorf("failed to iterate chunks: %w", err)
+	}
+	return nil
+}
+
+// ForEachLibraryEntry calls fn with each library entry visible to the user, as
+// LibraryByUser lists them but ordered by owner and source, until fn returns
+// false. Entries are read a page at a time and no query is open while fn runs
+func (s *Store) ForEachLibraryEntry(ctx context.Context, userID int64, fn func(LibraryEntry) bool) error {
+	pageSize := s.searchPageSize
+	if pageSize <= 0 {
+		pageSize = defaultSearchPageSize
+	}
+
+	// Pages continue after the last (owner, source) pair read
+	query := `
+		SELECT
+			c.user_id,
+			COALESCE(d.id, 0),
+			c.source,
+			COALESCE(NULLIF(d.title, ''), c.source) as title,
+			COUNT(*) as chunk_count,
+			MAX(c.summary) as summary,
+			MAX(c.tags) as tags,
+			MIN(c.created_at) as created_at,
+			MAX(w.warning) as warning,
+			MAX(p.user_id IS NOT NULL) as pinned
+		FROM chunks c
+		LEFT JOIN documents d ON d.user_id = c.user_id AND d.source = c.source
+		LEFT JOIN ingest_warnings w ON w.user_id = c.user_id AND w.source = c.source
+		LEFT JOIN document_pins p ON p.document_id = d.id AND p.user_id = ?
+		WHERE (c.user_id = ?
+			OR c.visibility = 'public'
+			OR (',' || COALESCE(c.shared_with, '') || ',') LIKE '%,' || CAST(? AS TEXT) || ',%')
+			AND (c.user_id > ? OR (c.user_id = ? AND c.source > ?))
+		GROUP BY c.user_id, c.source
+		ORDER BY c.user_id, c.source
+		LIMIT ?
+	`
+
+	var lastOwner int64
+	var lastSource string
+	for {
+		page, owner, err := s.readLibraryPage(ctx, query, userID, userID, userID, lastOwner, lastOwner, lastSource, pageSize)
+		if err != nil {
+			return err
+		}
+		for _, entry := range page {
+			if !fn(entry) {
+				return nil
+			}
+		}
+		if len(page) < pageSize {
+			return nil
+		}
+		lastOwner, lastSource = owner, page[len(page)-1].Source
+	}
+}
+
+// readLibraryPage runs one ForEachLibraryEntry page query, returning its
+// entries and the owner of the last one
+func (s *Store) readLibraryPage(ctx context.Context, query string, args ...interface{}) ([]LibraryEntry, int64, error) {
+	rows, err := s.reader().QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query library: %w", err)
+	}
+	defer rows.Close()
+
+	var page []LibraryEntry
+	var owner int64
+	for rows.Next() {
+		var entry LibraryEntry
+		var tagsStr, summary, warning sql.NullString
+		var createdAtStr string
+		if err := rows.Scan(&owner, &entry.DocumentID, &entry.Source, &entry.Title, &entry.ChunkCount, &summary, &tagsStr, &createdAtStr, &warning, &entry.Pinned); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan library entry: %w", err)
+		}
+		entry.Summary = summary.String
+		entry.Warning = warning.String
+		if tagsStr.String != "" {
+			entry.Tags = splitTags(tagsStr.String)
+		}
+		if createdAtStr != "" {
+			entry.CreatedAt, _ = time.Parse("2006-01-02 15:04:05", createdAtStr)
+		}
+		page = append(page, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error iterating library entries: %w", err)
+	}
+	return page, owner, nil
+}
@@ -0,0 +1,179 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+)
+
+// TestForEachChunk tests that chunk iteration walks every page of the user's own
+// chunks with their embeddings, and stops when asked
+func TestForEachChunk(t *testing.T) {
+	tmpFile := "test_for_each_chunk.db"
+	defer os.Remove(tmpFile)
+
+	opts := DefaultOptions()
+	opts.SearchPageSize = 2
+	store, err := NewStoreWithOptions(tmpFile, "multi", opts)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+	owner, err := store.CreateUser(ctx, "owner", "password", "owner@test.com", false, false)
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	other, err := store.CreateUser(ctx, "other", "password", "other@test.com", false, false)
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	for i := 0; i < 5; i++ {
+		if err := store.SaveChunk(ctx, owner, fmt.Sprintf("doc%d.txt", i), fmt.Sprintf("chunk %d", i), []float32{float32(i), 1}, nil, ""); err != nil {
+			t.Fatalf("Failed to save chunk: %v", err)
+		}
+	}
+	// Other users' chunks are left out, even public ones
+	if err := store.SaveChunk(ctx, other, "public.txt", "not mine", []float32{1, 1}, nil, ""); err != nil {
+		t.Fatalf("Failed to save chunk: %v", err)
+	}
+	if err := store.SetSourceVisibility(ctx, other, "public.txt", "public"); err != nil {
+		t.Fatalf("Failed to set visibility: %v", err)
+	}
+
+	var texts []string
+	err = store.ForEachChunk(ctx, owner, func(c Chunk) bool {
+		if len(c.Embedding) != 2 {
+			t.Errorf("Expected the embedding of %q, got %v", c.Text, c.Embedding)
+		}
+		texts = append(texts, c.Text)
+		return true
+	})
+	if err != nil {
+		t.Fatalf("ForEachChunk failed: %v", err)
+	}
+	if len(texts) != 5 || texts[0] != "chunk 0" || texts[4] != "chunk 4" {
+		t.Errorf("Expected the 5 owned chunks in order, got %v", texts)
+	}
+
+	seen := 0
+	err = store.ForEachChunk(ctx, owner, func(c Chunk) bool {
+		seen++
+		return seen < 3
+	})
+	if err != nil {
+		t.Fatalf("ForEachChunk failed: %v", err)
+	}
+	if seen != 3 {
+		t.Errorf("Expected iteration to stop after 3 chunks, got %d", seen)
+	}
+}
+
+// TestForEachLibraryEntry tests that library iteration pages through the entries
+// LibraryByUser lists
+func TestForEachLibraryEntry(t *testing.T) {
+	tmpFile := "test_for_each_library_entry.db"
+	defer os.Remove(tmpFile)
+
+	opts := DefaultOptions()
+	opts.SearchPageSize = 2
+	store, err := NewStoreWithOptions(tmpFile, "multi", opts)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+	owner, err := store.CreateUser(ctx, "owner", "password", "owner@test.com", false, false)
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	other, err := store.CreateUser(ctx, "other", "password", "other@test.com", false, false)
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	for i := 0; i < 4; i++ {
+		source := fmt.Sprintf("doc%d.txt", i)
+		for j := 0; j < 2; j++ {
+			if err := store.SaveChunk(ctx, owner, source, "text", []float32{1, 0}, []string{"notes"}, ""); err != nil {
+				t.Fatalf("Failed to save chunk: %v", err)
+			}
+		}
+	}
+	if err := store.SaveChunk(ctx, other, "public.txt", "text", []float32{1, 0}, nil, ""); err != nil {
+		t.Fatalf("Failed to save chunk: %v", err)
+	}
+	if err := store.SetSourceVisibility(ctx, other, "public.txt", "public"); err != nil {
+		t.Fatalf("Failed to set visibility: %v", err)
+	}
+	if err := store.SaveChunk(ctx, other, "private.txt", "text", []float32{1, 0}, nil, ""); err != nil {
+		t.Fatalf("Failed to save chunk: %v", err)
+	}
+
+	want, err := store.LibraryByUser(ctx, owner)
+	if err != nil {
+		t.Fatalf("LibraryByUser failed: %v", err)
+	}
+
+	var sources []string
+	err = store.ForEachLibraryEntry(ctx, owner, func(entry LibraryEntry) bool {
+		if entry.Source != "public.txt" && (entry.ChunkCount != 2 || len(entry.Tags) != 1) {
+			t.Errorf("Unexpected entry %+v", entry)
+		}
+		sources = append(sources, entry.Source)
+		return true
+	})
+	if err != nil {
+		t.Fatalf("ForEachLibraryEntry failed: %v", err)
+	}
+	expected := []string{"doc0.txt", "doc1.txt", "doc2.txt", "doc3.txt", "public.txt"}
+	if fmt.Sprint(sources) != fmt.Sprint(expected) {
+		t.Errorf("Expected %v, got %v", expected, sources)
+	}
+	if len(sources) != len(want) {
+		t.Errorf("Expected the %d entries LibraryByUser lists, got %d", len(want), len(sources))
+	}
+
+	seen := 0
+	err = store.ForEachLibraryEntry(ctx, owner, func(entry LibraryEntry) bool {
+		seen++
+		return false
+	})
+	if err != nil {
+		t.Fatalf("ForEachLibraryEntry failed: %v", err)
+	}
+	if seen != 1 {
+		t.Errorf("Expected iteration to stop after 1 entry, got %d", seen)
+	}
+}
+
+// TestTopChunks tests that the best chunks are kept in score order, ties in the
+// order they were added
+func TestTopChunks(t *testing.T) {
+	top := newTopChunks(3)
+	for i, score := range []float64{0.2, 0.9, 0.5, 0.9, 0.1, 0.5} {
+		top.add(Chunk{ID: int64(i)}, score)
+	}
+
+	results := top.results()
+	var ids []int64
+	for _, c := range results {
+		ids = append(ids, c.ID)
+	}
+	if fmt.Sprint(ids) != "[1 3 2]" {
+		t.Errorf("Expected chunks [1 3 2], got %v", ids)
+	}
+	if results[0].Score != 0.9 || results[2].Score != 0.5 {
+		t.Errorf("Expected scores to be set, got %v and %v", results[0].Score, results[2].Score)
+	}
+
+	none := newTopChunks(0)
+	none.add(Chunk{}, 1)
+	if len(none.results()) != 0 {
+		t.Errorf("Expected nothing kept with k=0")
+	}
+}
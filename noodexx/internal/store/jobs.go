@@ -0,0 +1,67 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// GetJobStates returns the saved state of every scheduled job
+func (s *Store) GetJobStates(ctx context.Context) ([]JobState, error) {
+	query := `
+		SELECT name, schedule, paused, last_run_at, last_duration_ms, last_error, next_run_at, runs, failures
+		FROM scheduled_jobs
+		ORDER BY name
+	`
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query scheduled jobs: %w", err)
+	}
+	defer rows.Close()
+
+	var states []JobState
+	for rows.Next() {
+		var state JobState
+		var lastRun, nextRun sql.NullTime
+		if err := rows.Scan(&state.Name, &state.Schedule, &state.Paused, &lastRun, &state.LastDurationMS,
+			&state.LastError, &nextRun, &state.Runs, &state.Failures); err != nil {
+			return nil, fmt.Errorf("failed to scan scheduled job: %w", err)
+		}
+		if lastRun.Valid {
+			state.LastRunAt = &lastRun.Time
+		}
+		if nextRun.Valid {
+			state.NextRunAt = &nextRun.Time
+		}
+		states = append(states, state)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating scheduled jobs: %w", err)
+	}
+	return states, nil
+}
+
+// SaveJobState inserts or replaces the saved state of a scheduled job
+func (s *Store) SaveJobState(ctx context.Context, state JobState) error {
+	query := `
+		INSERT INTO scheduled_jobs (name, schedule, paused, last_run_at, last_duration_ms, last_error, next_run_at, runs, failures, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(name) DO UPDATE SET
+			schedule = excluded.schedule,
+			paused = excluded.paused,
+			last_run_at = excluded.last_run_at,
+			last_duration_ms = excluded.last_duration_ms,
+			last_error = excluded.last_error,
+			next_run_at = excluded.next_run_at,
+			runs = excluded.runs,
+			failures = excluded.failures,
+			updated_at = excluded.updated_at
+	`
+	_, err := s.db.ExecContext(ctx, query, state.Name, state.Schedule, state.Paused, state.LastRunAt,
+		state.LastDurationMS, state.LastError, state.NextRunAt, state.Runs, state.Failures, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to save scheduled job %s: %w", state.Name, err)
+	}
+	return nil
+}
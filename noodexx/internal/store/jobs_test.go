@@ -0,0 +1,52 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestJobStates(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	next := time.Now().Add(time.Hour).Truncate(time.Second)
+	if err := store.SaveJobState(ctx, JobState{Name: "token_cleanup", Schedule: "@hourly", NextRunAt: &next}); err != nil {
+		t.Fatalf("SaveJobState failed: %v", err)
+	}
+
+	states, err := store.GetJobStates(ctx)
+	if err != nil {
+		t.Fatalf("GetJobStates failed: %v", err)
+	}
+	if len(states) != 1 || states[0].LastRunAt != nil || states[0].NextRunAt == nil || !states[0].NextRunAt.Equal(next) {
+		t.Fatalf("Expected a job that never ran, got %+v", states)
+	}
+
+	// Saving again replaces the state
+	lastRun := time.Now().Truncate(time.Second)
+	err = store.SaveJobState(ctx, JobState{
+		Name:           "token_cleanup",
+		Schedule:       "*/30 * * * *",
+		Paused:         true,
+		LastRunAt:      &lastRun,
+		LastDurationMS: 42,
+		LastError:      "database is locked",
+		NextRunAt:      &next,
+		Runs:           3,
+		Failures:       1,
+	})
+	if err != nil {
+		t.Fatalf("SaveJobState failed: %v", err)
+	}
+	states, err = store.GetJobStates(ctx)
+	if err != nil {
+		t.Fatalf("GetJobStates failed: %v", err)
+	}
+	state := states[0]
+	if len(states) != 1 || state.Schedule != "*/30 * * * *" || !state.Paused || state.LastRunAt == nil || !state.LastRunAt.Equal(lastRun) ||
+		state.LastDurationMS != 42 || state.LastError != "database is locked" || state.Runs != 3 || state.Failures != 1 {
+		t.Errorf("Expected the replaced state, got %+v", state)
+	}
+}
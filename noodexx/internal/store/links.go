@@ -0,0 +1,49 @@
+package store
+
+import (
+	"context"
+	"fmt"
+)
+
+// SetDocumentLinks replaces the wikilinks of a user's document with targets,
+// kept in order
+func (s *Store) SetDocumentLinks(ctx context.Context, userID int64, source string, targets []string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM document_links WHERE user_id = ? AND source = ?`, userID, source); err != nil {
+		return fmt.Errorf("failed to clear document links: %w", err)
+	}
+	for _, target := range targets {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO document_links (user_id, source, target) VALUES (?, ?, ?)`, userID, source, target); err != nil {
+			return fmt.Errorf("failed to save document link: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit document links: %w", err)
+	}
+	return nil
+}
+
+// GetDocumentLinks returns the wikilink targets of a user's document in order
+func (s *Store) GetDocumentLinks(ctx context.Context, userID int64, source string) ([]string, error) {
+	query := `SELECT target FROM document_links WHERE user_id = ? AND source = ? ORDER BY id ASC`
+	rows, err := s.db.QueryContext(ctx, query, userID, source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query document links: %w", err)
+	}
+	defer rows.Close()
+
+	var targets []string
+	for rows.Next() {
+		var target string
+		if err := rows.Scan(&target); err != nil {
+			return nil, fmt.Errorf("failed to scan document link: %w", err)
+		}
+		targets = append(targets, target)
+	}
+	return targets, rows.Err()
+}
@@ -0,0 +1,47 @@
+package store
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+// TestDocumentLinks tests replacing, reading and deleting a document's wikilinks
+func TestDocumentLinks(t *testing.T) {
+	store, err := NewStore(t.TempDir()+"/test_links.db", "multi")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+
+	alice, _ := store.CreateUser(ctx, "alice", "password123", "alice@example.com", false, false)
+	bob, _ := store.CreateUser(ctx, "bob", "password123", "bob@example.com", false, false)
+
+	if err := store.SetDocumentLinks(ctx, alice, "Projects/Plan.md", []string{"Old"}); err != nil {
+		t.Fatalf("SetDocumentLinks failed: %v", err)
+	}
+	if err := store.SetDocumentLinks(ctx, alice, "Projects/Plan.md", []string{"Roadmap", "Alice", "Budget"}); err != nil {
+		t.Fatalf("SetDocumentLinks failed: %v", err)
+	}
+	store.SetDocumentLinks(ctx, bob, "Projects/Plan.md", []string{"Bob's page"})
+
+	links, err := store.GetDocumentLinks(ctx, alice, "Projects/Plan.md")
+	if err != nil {
+		t.Fatalf("GetDocumentLinks failed: %v", err)
+	}
+	if want := []string{"Roadmap", "Alice", "Budget"}; !reflect.DeepEqual(links, want) {
+		t.Errorf("Expected %v, got %v", want, links)
+	}
+
+	if err := store.DeleteDocument(ctx, alice, "Projects/Plan.md"); err != nil {
+		t.Fatalf("DeleteDocument failed: %v", err)
+	}
+	if links, _ := store.GetDocumentLinks(ctx, alice, "Projects/Plan.md"); len(links) != 0 {
+		t.Errorf("Expected the links deleted with the document, got %v", links)
+	}
+	if links, _ := store.GetDocumentLinks(ctx, bob, "Projects/Plan.md"); len(links) != 1 {
+		t.Errorf("Expected bob's links kept, got %v", links)
+	}
+}
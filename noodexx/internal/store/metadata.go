@@ -0,0 +1,141 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// GetDocumentFacts returns the metadata of every document visible to a user,
+// for metadata queries. A document's tags are the tags of any of its chunks
+func (s *Store) GetDocumentFacts(ctx context.Context, userID int64) ([]DocumentFact, error) {
+	query := `
+		SELECT
+			source,
+			COUNT(*),
+			SUM(LENGTH(text)),
+			GROUP_CONCAT(tags),
+			MAX(visibility),
+			MIN(created_at)
+		FROM chunks
+		WHERE user_id = ?
+			OR visibility = 'public'
+			OR (',' || COALESCE(shared_with, '') || ',') LIKE '%,' || CAST(? AS TEXT) || ',%'
+		GROUP BY source
+	`
+	rows, err := s.db.QueryContext(ctx, query, userID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query document facts: %w", err)
+	}
+	defer rows.Close()
+
+	var facts []DocumentFact
+	for rows.Next() {
+		var f DocumentFact
+		var tags, visibility, createdAt sql.NullString
+		if err := rows.Scan(&f.Source, &f.Chunks, &f.Size, &tags, &visibility, &createdAt); err != nil {
+			return nil, fmt.Errorf("failed to scan document facts: %w", err)
+		}
+		f.Tags = uniqueTags(tags.String)
+		f.Visibility = visibility.String
+		f.CreatedAt = parseFactTime(createdAt.String)
+		facts = append(facts, f)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating document facts: %w", err)
+	}
+	return facts, nil
+}
+
+// GetChunkFacts returns the metadata of every chunk visible to a user, for metadata queries
+func (s *Store) GetChunkFacts(ctx context.Context, userID int64) ([]ChunkFact, error) {
+	query := `
+		SELECT source, LENGTH(text), tags, visibility, created_at
+		FROM chunks
+		WHERE user_id = ?
+			OR visibility = 'public'
+			OR (',' || COALESCE(shared_with, '') || ',') LIKE '%,' || CAST(? AS TEXT) || ',%'
+	`
+	rows, err := s.db.QueryContext(ctx, query, userID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query chunk facts: %w", err)
+	}
+	defer rows.Close()
+
+	var facts []ChunkFact
+	for rows.Next() {
+		var f ChunkFact
+		var tags, visibility sql.NullString
+		var createdAt sql.NullTime
+		if err := rows.Scan(&f.Source, &f.Length, &tags, &visibility, &createdAt); err != nil {
+			return nil, fmt.Errorf("failed to scan chunk facts: %w", err)
+		}
+		f.Tags = uniqueTags(tags.String)
+		f.Visibility = visibility.String
+		f.CreatedAt = createdAt.Time
+		facts = append(facts, f)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating chunk facts: %w", err)
+	}
+	return facts, nil
+}
+
+// GetSessionFacts returns the metadata of a user's chat sessions, for metadata queries
+func (s *Store) GetSessionFacts(ctx context.Context, userID int64) ([]SessionFact, error) {
+	query := `
+		SELECT s.title, COUNT(cm.id), s.created_at, s.last_message_at
+		FROM sessions s
+		LEFT JOIN chat_messages cm ON s.id = cm.session_id
+		WHERE s.user_id = ?
+		GROUP BY s.id
+	`
+	rows, err := s.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query session facts: %w", err)
+	}
+	defer rows.Close()
+
+	var facts []SessionFact
+	for rows.Next() {
+		var f SessionFact
+		var title sql.NullString
+		var createdAt, lastMessageAt sql.NullTime
+		if err := rows.Scan(&title, &f.Messages, &createdAt, &lastMessageAt); err != nil {
+			return nil, fmt.Errorf("failed to scan session facts: %w", err)
+		}
+		f.Title = title.String
+		f.CreatedAt = createdAt.Time
+		f.LastMessageAt = lastMessageAt.Time
+		facts = append(facts, f)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating session facts: %w", err)
+	}
+	return facts, nil
+}
+
+// uniqueTags splits comma-separated tags, dropping blanks and repeats
+func uniqueTags(tagsStr string) []string {
+	var tags []string
+	seen := make(map[string]bool)
+	for _, tag := range splitTags(tagsStr) {
+		if tag != "" && !seen[tag] {
+			tags = append(tags, tag)
+			seen[tag] = true
+		}
+	}
+	return tags
+}
+
+// parseFactTime reads an aggregated timestamp, which SQLite returns as text
+func parseFactTime(s string) time.Time {
+	for _, layout := range []string{"2006-01-02 15:04:05", time.RFC3339Nano} {
+		if t, err := time.Parse(layout, strings.TrimSpace(s)); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
@@ -0,0 +1,79 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMetadataFacts(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	userID, err := store.CreateUser(ctx, "analyst", "password", "analyst@test.com", false, false)
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	otherID, err := store.CreateUser(ctx, "other", "password", "other@test.com", false, false)
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	embedding := []float32{0.1, 0.2}
+	for _, c := range []struct {
+		source, text string
+		tags         []string
+	}{
+		{"nda.pdf", "first part", []string{"contracts", "legal"}},
+		{"nda.pdf", "second", []string{"contracts"}},
+		{"notes.md", "hello", nil},
+	} {
+		if err := store.SaveChunk(ctx, userID, c.source, c.text, embedding, c.tags, ""); err != nil {
+			t.Fatalf("SaveChunk failed: %v", err)
+		}
+	}
+	if err := store.SaveChunk(ctx, otherID, "private.md", "secret", embedding, nil, ""); err != nil {
+		t.Fatalf("SaveChunk failed: %v", err)
+	}
+
+	docs, err := store.GetDocumentFacts(ctx, userID)
+	if err != nil {
+		t.Fatalf("GetDocumentFacts failed: %v", err)
+	}
+	if len(docs) != 2 {
+		t.Fatalf("Expected the user's two documents, got %+v", docs)
+	}
+	for _, d := range docs {
+		if d.Source == "nda.pdf" {
+			if d.Chunks != 2 || d.Size != 16 || len(d.Tags) != 2 || d.Visibility != "private" {
+				t.Errorf("Expected nda.pdf's chunks, size and tags, got %+v", d)
+			}
+			if time.Since(d.CreatedAt) > time.Hour {
+				t.Errorf("Expected a creation time, got %v", d.CreatedAt)
+			}
+		}
+	}
+
+	chunks, err := store.GetChunkFacts(ctx, userID)
+	if err != nil || len(chunks) != 3 {
+		t.Fatalf("Expected the user's three chunks, got %+v, %v", chunks, err)
+	}
+	if chunks[0].Length != 10 || chunks[0].CreatedAt.IsZero() {
+		t.Errorf("Expected the chunk's length and time, got %+v", chunks[0])
+	}
+
+	if err := store.SaveChatMessage(ctx, userID, "s1", "user", "hi", "local"); err != nil {
+		t.Fatalf("SaveChatMessage failed: %v", err)
+	}
+	if err := store.SaveChatMessage(ctx, userID, "s1", "assistant", "hello", "local"); err != nil {
+		t.Fatalf("SaveChatMessage failed: %v", err)
+	}
+	sessions, err := store.GetSessionFacts(ctx, userID)
+	if err != nil || len(sessions) != 1 || sessions[0].Messages != 2 || sessions[0].CreatedAt.IsZero() {
+		t.Fatalf("Expected one session with two messages, got %+v, %v", sessions, err)
+	}
+	if other, _ := store.GetSessionFacts(ctx, otherID); len(other) != 0 {
+		t.Errorf("Expected no sessions for another user, got %+v", other)
+	}
+}
@@ -88,12 +88,214 @@ func (s *Store) runMigrations(ctx context.Context) error {
 		return fmt.Errorf("failed to add dark_mode to users: %w", err)
 	}
 
+	if err = addCitationsToChatMessages(ctx, tx); err != nil {
+		return fmt.Errorf("failed to add citations to chat_messages: %w", err)
+	}
+
+	if err = createRankingWeightsTable(ctx, tx); err != nil {
+		return fmt.Errorf("failed to create ranking_weights table: %w", err)
+	}
+
+	if err = addOnboardingToUsers(ctx, tx); err != nil {
+		return fmt.Errorf("failed to add onboarding_completed_at to users: %w", err)
+	}
+
+	if err = addRememberMeToSessionTokens(ctx, tx); err != nil {
+		return fmt.Errorf("failed to add remember_me to session_tokens: %w", err)
+	}
+
+	if err = createPasswordHistoryTable(ctx, tx); err != nil {
+		return fmt.Errorf("failed to create password_history table: %w", err)
+	}
+
+	if err = createChatMessagesFTS(ctx, tx); err != nil {
+		return fmt.Errorf("failed to create chat_messages_fts index: %w", err)
+	}
+
+	if err = createSkillRunsTable(ctx, tx); err != nil {
+		return fmt.Errorf("failed to create skill_runs table: %w", err)
+	}
+
+	if err = createSessionSharesTables(ctx, tx); err != nil {
+		return fmt.Errorf("failed to create session_shares tables: %w", err)
+	}
+
+	if err = createMessageProvenanceTable(ctx, tx); err != nil {
+		return fmt.Errorf("failed to create message_provenance table: %w", err)
+	}
+
+	if err = createGenerationDefaultsTable(ctx, tx); err != nil {
+		return fmt.Errorf("failed to create generation_defaults table: %w", err)
+	}
+
+	if err = createAnswerStylesTable(ctx, tx); err != nil {
+		return fmt.Errorf("failed to create answer_styles table: %w", err)
+	}
+
+	if err = createIngestFailuresTable(ctx, tx); err != nil {
+		return fmt.Errorf("failed to create ingest_failures table: %w", err)
+	}
+
+	if err = createSummaryProvenanceTable(ctx, tx); err != nil {
+		return fmt.Errorf("failed to create summary_provenance table: %w", err)
+	}
+
+	if err = createEvalTables(ctx, tx); err != nil {
+		return fmt.Errorf("failed to create eval tables: %w", err)
+	}
+
+	if err = createFeatureFlagOverridesTable(ctx, tx); err != nil {
+		return fmt.Errorf("failed to create feature_flag_overrides table: %w", err)
+	}
+
+	if err = createScheduledJobsTable(ctx, tx); err != nil {
+		return fmt.Errorf("failed to create scheduled_jobs table: %w", err)
+	}
+
+	if err = createClusterTables(ctx, tx); err != nil {
+		return fmt.Errorf("failed to create cluster tables: %w", err)
+	}
+
+	if err = createNotificationsTable(ctx, tx); err != nil {
+		return fmt.Errorf("failed to create notifications table: %w", err)
+	}
+
+	if err = createExtractionTables(ctx, tx); err != nil {
+		return fmt.Errorf("failed to create extraction tables: %w", err)
+	}
+
+	if err = addEmbeddingModelToChunks(ctx, tx); err != nil {
+		return fmt.Errorf("failed to add embedding model columns: %w", err)
+	}
+
+	if err = createIngestWarningsTable(ctx, tx); err != nil {
+		return fmt.Errorf("failed to create ingest_warnings table: %w", err)
+	}
+
+	if err = createDocumentsTable(ctx, tx); err != nil {
+		return fmt.Errorf("failed to create documents table: %w", err)
+	}
+
+	if err = createSessionLinksTable(ctx, tx); err != nil {
+		return fmt.Errorf("failed to create session_links table: %w", err)
+	}
+
+	if err = createSessionSummariesTable(ctx, tx); err != nil {
+		return fmt.Errorf("failed to create session_summaries table: %w", err)
+	}
+
+	if err = createQuickNotesTable(ctx, tx); err != nil {
+		return fmt.Errorf("failed to create quick_notes table: %w", err)
+	}
+
+	if err = createDocumentLinksTable(ctx, tx); err != nil {
+		return fmt.Errorf("failed to create document_links table: %w", err)
+	}
+
+	if err = createChunkAccessLogTable(ctx, tx); err != nil {
+		return fmt.Errorf("failed to create chunk_access_log table: %w", err)
+	}
+
+	if err = createSourceRetrievalsTable(ctx, tx); err != nil {
+		return fmt.Errorf("failed to create source_retrievals table: %w", err)
+	}
+
+	if err = addDefaultVisibilityToUsers(ctx, tx); err != nil {
+		return fmt.Errorf("failed to add default_visibility to users: %w", err)
+	}
+
+	if err = createMessageArtifactsTable(ctx, tx); err != nil {
+		return fmt.Errorf("failed to create message_artifacts table: %w", err)
+	}
+
+	if err = addTitleEmbeddingToChunks(ctx, tx); err != nil {
+		return fmt.Errorf("failed to add title columns to chunks: %w", err)
+	}
+
+	if err = createDocumentContentsTable(ctx, tx); err != nil {
+		return fmt.Errorf("failed to create document_contents table: %w", err)
+	}
+
+	if err = createCostEstimatesTable(ctx, tx); err != nil {
+		return fmt.Errorf("failed to create cost_estimates table: %w", err)
+	}
+
+	if err = addCostThresholdToUsers(ctx, tx); err != nil {
+		return fmt.Errorf("failed to add cost_confirm_above to users: %w", err)
+	}
+
+	if err = createProviderTranscriptsTable(ctx, tx); err != nil {
+		return fmt.Errorf("failed to create provider_transcripts table: %w", err)
+	}
+
+	if err = createUserPreferencesTable(ctx, tx); err != nil {
+		return fmt.Errorf("failed to create user_preferences table: %w", err)
+	}
+
+	if err = addStreamStatsToProvenance(ctx, tx); err != nil {
+		return fmt.Errorf("failed to add stream stats to message_provenance: %w", err)
+	}
+
+	if err = addProviderModeToSessions(ctx, tx); err != nil {
+		return fmt.Errorf("failed to add provider_mode to sessions: %w", err)
+	}
+
+	if err = addPrivacyToProvenance(ctx, tx); err != nil {
+		return fmt.Errorf("failed to add privacy to message_provenance: %w", err)
+	}
+
+	if err = createDocumentPinsTable(ctx, tx); err != nil {
+		return fmt.Errorf("failed to create document_pins table: %w", err)
+	}
+
+	if err = createDocumentEncodingsTable(ctx, tx); err != nil {
+		return fmt.Errorf("failed to create document_encodings table: %w", err)
+	}
+
+	if err = createImportedSessionsTable(ctx, tx); err != nil {
+		return fmt.Errorf("failed to create imported_sessions table: %w", err)
+	}
+
+	if err = addGlobalToSkills(ctx, tx); err != nil {
+		return fmt.Errorf("failed to add global to skills: %w", err)
+	}
+
+	if err = createSkillPreferencesTable(ctx, tx); err != nil {
+		return fmt.Errorf("failed to create skill_preferences table: %w", err)
+	}
+
+	if err = createSearchDictionaryTable(ctx, tx); err != nil {
+		return fmt.Errorf("failed to create search_dictionary table: %w", err)
+	}
+
+	if err = createFailedRequestsTable(ctx, tx); err != nil {
+		return fmt.Errorf("failed to create failed_requests table: %w", err)
+	}
+
+	if err = createChunksFTS(ctx, tx); err != nil {
+		return fmt.Errorf("failed to create chunks_fts index: %w", err)
+	}
+
+	if err = createSavedSearchesTable(ctx, tx); err != nil {
+		return fmt.Errorf("failed to create saved_searches table: %w", err)
+	}
+
 	// Run Phase 3 to Phase 4 data migration
 	// This must happen after tables and columns are created but before indexes
 	if err = migratePhase3ToPhase4(ctx, tx, s.userMode); err != nil {
 		return fmt.Errorf("failed to migrate Phase 3 to Phase 4: %w", err)
 	}
 
+	// Chunks only have their final owner once the Phase 4 migration has run
+	if err = backfillChunkDocuments(ctx, tx); err != nil {
+		return fmt.Errorf("failed to backfill chunk documents: %w", err)
+	}
+
+	// Documents must exist for every chunk before they take over its visibility
+	if err = addVisibilityToDocuments(ctx, tx); err != nil {
+		return fmt.Errorf("failed to add visibility to documents: %w", err)
+	}
+
 	if err = createIndexes(ctx, tx); err != nil {
 		return fmt.Errorf("failed to create indexes: %w", err)
 	}
@@ -215,6 +417,7 @@ func createIndexes(ctx context.Context, tx *sql.Tx) error {
 		`CREATE INDEX IF NOT EXISTS idx_chunks_created ON chunks(created_at)`,
 		`CREATE INDEX IF NOT EXISTS idx_chunks_user ON chunks(user_id)`,
 		`CREATE INDEX IF NOT EXISTS idx_chunks_visibility ON chunks(visibility)`,
+		`CREATE INDEX IF NOT EXISTS idx_chunks_document ON chunks(document_id)`,
 		`CREATE INDEX IF NOT EXISTS idx_messages_session ON chat_messages(session_id)`,
 		`CREATE INDEX IF NOT EXISTS idx_messages_created ON chat_messages(created_at)`,
 		`CREATE INDEX IF NOT EXISTS idx_messages_user ON chat_messages(user_id)`,
@@ -223,11 +426,18 @@ func createIndexes(ctx context.Context, tx *sql.Tx) error {
 		`CREATE INDEX IF NOT EXISTS idx_audit_user ON audit_log(user_id)`,
 		`CREATE INDEX IF NOT EXISTS idx_sessions_user ON sessions(user_id)`,
 		`CREATE INDEX IF NOT EXISTS idx_skills_user ON skills(user_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_skill_runs_skill ON skill_runs(skill_id, created_at)`,
+		`CREATE INDEX IF NOT EXISTS idx_session_shares_session ON session_shares(session_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_session_share_views_share ON session_share_views(share_id, viewed_at)`,
 		`CREATE INDEX IF NOT EXISTS idx_watched_folders_user ON watched_folders(user_id)`,
 		`CREATE INDEX IF NOT EXISTS idx_session_tokens_user ON session_tokens(user_id)`,
 		`CREATE INDEX IF NOT EXISTS idx_session_tokens_expires ON session_tokens(expires_at)`,
 		`CREATE INDEX IF NOT EXISTS idx_failed_logins_username ON failed_logins(username)`,
 		`CREATE INDEX IF NOT EXISTS idx_failed_logins_attempted ON failed_logins(attempted_at)`,
+		`CREATE INDEX IF NOT EXISTS idx_quick_notes_user ON quick_notes(user_id, source)`,
+		`CREATE INDEX IF NOT EXISTS idx_document_links_source ON document_links(user_id, source)`,
+		`CREATE INDEX IF NOT EXISTS idx_chunk_access_log_owner ON chunk_access_log(owner_id, accessed_at)`,
+		`CREATE INDEX IF NOT EXISTS idx_source_retrievals_day ON source_retrievals(user_id, day)`,
 	}
 
 	for _, indexQuery := range indexes {
@@ -739,3 +949,1222 @@ func addDarkModeToUsers(ctx context.Context, tx *sql.Tx) error {
 
 	return nil
 }
+
+// addDefaultVisibilityToUsers adds the default_visibility column to the users
+// table: the visibility of documents a user ingests without choosing one
+func addDefaultVisibilityToUsers(ctx context.Context, tx *sql.Tx) error {
+	var exists bool
+	err := tx.QueryRowContext(ctx, `
+		SELECT COUNT(*) > 0
+		FROM pragma_table_info('users')
+		WHERE name = 'default_visibility'
+	`).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to check default_visibility column: %w", err)
+	}
+
+	if !exists {
+		_, err = tx.ExecContext(ctx, `ALTER TABLE users ADD COLUMN default_visibility TEXT DEFAULT 'private' CHECK(default_visibility IN ('private', 'shared', 'public'))`)
+		if err != nil {
+			return fmt.Errorf("failed to add default_visibility column: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// createRankingWeightsTable creates the per-user ranking_weights table if it doesn't exist
+func createRankingWeightsTable(ctx context.Context, tx *sql.Tx) error {
+	query := `
+		CREATE TABLE IF NOT EXISTS ranking_weights (
+			user_id INTEGER PRIMARY KEY,
+			recency_half_life_days REAL DEFAULT 0,
+			tag_weights TEXT,
+			source_weights TEXT,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+		)
+	`
+	_, err := tx.ExecContext(ctx, query)
+	return err
+}
+
+// createSkillRunsTable creates the skill execution history table
+// Input and output are stored as JSON; stderr holds only a trailing excerpt
+func createSkillRunsTable(ctx context.Context, tx *sql.Tx) error {
+	query := `
+		CREATE TABLE IF NOT EXISTS skill_runs (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			skill_id INTEGER NOT NULL,
+			user_id INTEGER NOT NULL,
+			status TEXT NOT NULL,
+			input TEXT,
+			output TEXT,
+			error TEXT,
+			stderr TEXT,
+			exit_code INTEGER,
+			duration_ms INTEGER,
+			artifact_source TEXT,
+			rerun_of INTEGER,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (skill_id) REFERENCES skills(id) ON DELETE CASCADE,
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+		)
+	`
+	_, err := tx.ExecContext(ctx, query)
+	return err
+}
+
+// createSessionSharesTables creates the read-only session share links and their view log
+// Expiry and revocation times are NULL when not set
+func createSessionSharesTables(ctx context.Context, tx *sql.Tx) error {
+	queries := []string{`
+		CREATE TABLE IF NOT EXISTS session_shares (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			token TEXT NOT NULL UNIQUE,
+			session_id TEXT NOT NULL,
+			user_id INTEGER NOT NULL,
+			password_hash TEXT,
+			expires_at TIMESTAMP,
+			revoked_at TIMESTAMP,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (session_id) REFERENCES sessions(id) ON DELETE CASCADE,
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+		)
+	`, `
+		CREATE TABLE IF NOT EXISTS session_share_views (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			share_id INTEGER NOT NULL,
+			ip_address TEXT,
+			user_agent TEXT,
+			viewed_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (share_id) REFERENCES session_shares(id) ON DELETE CASCADE
+		)
+	`}
+	for _, query := range queries {
+		if _, err := tx.ExecContext(ctx, query); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// createMessageProvenanceTable creates the table recording how each assistant message was generated
+// params holds the generation parameters as a JSON object and chunk_ids the library chunks
+// supplied to the model as a JSON array
+func createMessageProvenanceTable(ctx context.Context, tx *sql.Tx) error {
+	query := `
+		CREATE TABLE IF NOT EXISTS message_provenance (
+			message_id INTEGER PRIMARY KEY,
+			provider TEXT NOT NULL DEFAULT '',
+			model TEXT NOT NULL DEFAULT '',
+			params TEXT NOT NULL DEFAULT '{}',
+			prompt_hash TEXT NOT NULL DEFAULT '',
+			chunk_ids TEXT NOT NULL DEFAULT '[]',
+			prompt_tokens INTEGER NOT NULL DEFAULT 0,
+			completion_tokens INTEGER NOT NULL DEFAULT 0,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (message_id) REFERENCES chat_messages(id) ON DELETE CASCADE
+		)
+	`
+	_, err := tx.ExecContext(ctx, query)
+	return err
+}
+
+// createGenerationDefaultsTable creates the per-user generation parameter defaults
+// NULL temperature or top_p and a zero max_tokens leave the provider's default in place
+func createGenerationDefaultsTable(ctx context.Context, tx *sql.Tx) error {
+	query := `
+		CREATE TABLE IF NOT EXISTS generation_defaults (
+			user_id INTEGER PRIMARY KEY,
+			temperature REAL,
+			top_p REAL,
+			max_tokens INTEGER NOT NULL DEFAULT 0,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+		)
+	`
+	_, err := tx.ExecContext(ctx, query)
+	return err
+}
+
+// createAnswerStylesTable creates the per-user answer language, tone and citation style
+// Empty values leave the model's own behaviour in place
+func createAnswerStylesTable(ctx context.Context, tx *sql.Tx) error {
+	query := `
+		CREATE TABLE IF NOT EXISTS answer_styles (
+			user_id INTEGER PRIMARY KEY,
+			language TEXT NOT NULL DEFAULT '',
+			tone TEXT NOT NULL DEFAULT '',
+			citation_style TEXT NOT NULL DEFAULT '',
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+		)
+	`
+	_, err := tx.ExecContext(ctx, query)
+	return err
+}
+
+// addCitationsToChatMessages adds the citations column to chat_messages
+// Citations hold the newline-separated sources that were supplied to the model as [1], [2], ...
+func addCitationsToChatMessages(ctx context.Context, tx *sql.Tx) error {
+	// Check if citations column exists
+	var citationsExists bool
+	err := tx.QueryRowContext(ctx, `
+		SELECT COUNT(*) > 0 
+		FROM pragma_table_info('chat_messages') 
+		WHERE name = 'citations'
+	`).Scan(&citationsExists)
+	if err != nil {
+		return fmt.Errorf("failed to check citations column: %w", err)
+	}
+
+	// Add citations column if it doesn't exist
+	if !citationsExists {
+		_, err = tx.ExecContext(ctx, `ALTER TABLE chat_messages ADD COLUMN citations TEXT`)
+		if err != nil {
+			return fmt.Errorf("failed to add citations column: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// addOnboardingToUsers adds the onboarding_completed_at column to users
+// NULL means the user has not yet finished or dismissed onboarding
+func addOnboardingToUsers(ctx context.Context, tx *sql.Tx) error {
+	// Check if onboarding_completed_at column exists
+	var onboardingExists bool
+	err := tx.QueryRowContext(ctx, `
+		SELECT COUNT(*) > 0 
+		FROM pragma_table_info('users') 
+		WHERE name = 'onboarding_completed_at'
+	`).Scan(&onboardingExists)
+	if err != nil {
+		return fmt.Errorf("failed to check onboarding_completed_at column: %w", err)
+	}
+
+	// Add onboarding_completed_at column if it doesn't exist
+	if !onboardingExists {
+		_, err = tx.ExecContext(ctx, `ALTER TABLE users ADD COLUMN onboarding_completed_at DATETIME`)
+		if err != nil {
+			return fmt.Errorf("failed to add onboarding_completed_at column: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// addRememberMeToSessionTokens adds the remember_me column to session_tokens if it doesn't exist
+func addRememberMeToSessionTokens(ctx context.Context, tx *sql.Tx) error {
+	var rememberMeExists bool
+	err := tx.QueryRowContext(ctx, `
+		SELECT COUNT(*) > 0 
+		FROM pragma_table_info('session_tokens') 
+		WHERE name = 'remember_me'
+	`).Scan(&rememberMeExists)
+	if err != nil {
+		return fmt.Errorf("failed to check remember_me column: %w", err)
+	}
+
+	if !rememberMeExists {
+		_, err = tx.ExecContext(ctx, `ALTER TABLE session_tokens ADD COLUMN remember_me INTEGER NOT NULL DEFAULT 0`)
+		if err != nil {
+			return fmt.Errorf("failed to add remember_me column: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// addEmbeddingModelToChunks adds the embedding_model and embedding_dim columns to chunks
+// Chunks stored before the columns existed keep an empty model; their dimension is
+// filled in from the stored embedding, four bytes per float32
+func addEmbeddingModelToChunks(ctx context.Context, tx *sql.Tx) error {
+	var modelExists bool
+	err := tx.QueryRowContext(ctx, `
+		SELECT COUNT(*) > 0 
+		FROM pragma_table_info('chunks') 
+		WHERE name = 'embedding_model'
+	`).Scan(&modelExists)
+	if err != nil {
+		return fmt.Errorf("failed to check embedding_model column: %w", err)
+	}
+
+	if !modelExists {
+		_, err = tx.ExecContext(ctx, `ALTER TABLE chunks ADD COLUMN embedding_model TEXT NOT NULL DEFAULT ''`)
+		if err != nil {
+			return fmt.Errorf("failed to add embedding_model column: %w", err)
+		}
+		_, err = tx.ExecContext(ctx, `ALTER TABLE chunks ADD COLUMN embedding_dim INTEGER NOT NULL DEFAULT 0`)
+		if err != nil {
+			return fmt.Errorf("failed to add embedding_dim column: %w", err)
+		}
+		_, err = tx.ExecContext(ctx, `UPDATE chunks SET embedding_dim = length(embedding) / 4 WHERE embedding IS NOT NULL`)
+		if err != nil {
+			return fmt.Errorf("failed to backfill embedding_dim: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// addTitleEmbeddingToChunks adds the title and title_embedding columns to chunks
+// They hold the heading context of a chunk and its embedding; chunks stored before
+// the columns existed have neither and are scored by their body alone
+func addTitleEmbeddingToChunks(ctx context.Context, tx *sql.Tx) error {
+	var titleExists bool
+	err := tx.QueryRowContext(ctx, `
+		SELECT COUNT(*) > 0
+		FROM pragma_table_info('chunks')
+		WHERE name = 'title_embedding'
+	`).Scan(&titleExists)
+	if err != nil {
+		return fmt.Errorf("failed to check title_embedding column: %w", err)
+	}
+
+	if !titleExists {
+		_, err = tx.ExecContext(ctx, `ALTER TABLE chunks ADD COLUMN title TEXT NOT NULL DEFAULT ''`)
+		if err != nil {
+			return fmt.Errorf("failed to add title column: %w", err)
+		}
+		_, err = tx.ExecContext(ctx, `ALTER TABLE chunks ADD COLUMN title_embedding BLOB`)
+		if err != nil {
+			return fmt.Errorf("failed to add title_embedding column: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// createPasswordHistoryTable creates the password_history table if it doesn't exist
+// It keeps the hashes of users' previous passwords so they cannot be reused
+func createPasswordHistoryTable(ctx context.Context, tx *sql.Tx) error {
+	query := `
+		CREATE TABLE IF NOT EXISTS password_history (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL,
+			password_hash TEXT NOT NULL,
+			replaced_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+		)
+	`
+	if _, err := tx.ExecContext(ctx, query); err != nil {
+		return err
+	}
+	_, err := tx.ExecContext(ctx, `CREATE INDEX IF NOT EXISTS idx_password_history_user ON password_history(user_id, id)`)
+	return err
+}
+
+// createIngestFailuresTable creates the ingest_failures table if it doesn't exist
+// It tracks watched files that fail to ingest, keyed by path
+func createIngestFailuresTable(ctx context.Context, tx *sql.Tx) error {
+	query := `
+		CREATE TABLE IF NOT EXISTS ingest_failures (
+			path TEXT PRIMARY KEY,
+			folder TEXT NOT NULL,
+			user_id INTEGER NOT NULL,
+			error TEXT NOT NULL,
+			attempts INTEGER NOT NULL DEFAULT 1,
+			first_failed_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			last_attempt_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+		)
+	`
+	if _, err := tx.ExecContext(ctx, query); err != nil {
+		return err
+	}
+	_, err := tx.ExecContext(ctx, `CREATE INDEX IF NOT EXISTS idx_ingest_failures_folder ON ingest_failures(user_id, folder)`)
+	return err
+}
+
+// createIngestWarningsTable creates the ingest_warnings table if it doesn't exist
+// It records why a document's latest ingestion kept only part of it
+func createIngestWarningsTable(ctx context.Context, tx *sql.Tx) error {
+	query := `
+		CREATE TABLE IF NOT EXISTS ingest_warnings (
+			user_id INTEGER NOT NULL,
+			source TEXT NOT NULL,
+			warning TEXT NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (user_id, source),
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+		)
+	`
+	_, err := tx.ExecContext(ctx, query)
+	return err
+}
+
+// createDocumentsTable creates the documents table if it doesn't exist and adds the
+// document_id column to chunks. A document is one user's source, with a stable ID
+// and a display title that can be changed without touching its chunks
+func createDocumentsTable(ctx context.Context, tx *sql.Tx) error {
+	query := `
+		CREATE TABLE IF NOT EXISTS documents (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL,
+			source TEXT NOT NULL,
+			title TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE (user_id, source),
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+		)
+	`
+	if _, err := tx.ExecContext(ctx, query); err != nil {
+		return err
+	}
+
+	var documentIDExists bool
+	err := tx.QueryRowContext(ctx, `
+		SELECT COUNT(*) > 0 
+		FROM pragma_table_info('chunks') 
+		WHERE name = 'document_id'
+	`).Scan(&documentIDExists)
+	if err != nil {
+		return fmt.Errorf("failed to check document_id column: %w", err)
+	}
+
+	if !documentIDExists {
+		_, err = tx.ExecContext(ctx, `ALTER TABLE chunks ADD COLUMN document_id INTEGER REFERENCES documents(id)`)
+		if err != nil {
+			return fmt.Errorf("failed to add document_id column: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// createSessionLinksTable creates the session_links table if it doesn't exist
+// It records which earlier session a session continues and the summary it was given
+func createSessionLinksTable(ctx context.Context, tx *sql.Tx) error {
+	query := `
+		CREATE TABLE IF NOT EXISTS session_links (
+			session_id TEXT PRIMARY KEY,
+			user_id INTEGER NOT NULL,
+			continued_from TEXT NOT NULL,
+			summary TEXT NOT NULL,
+			model TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+		)
+	`
+	_, err := tx.ExecContext(ctx, query)
+	return err
+}
+
+// createSessionSummariesTable creates the session_summaries table if it doesn't exist
+// It holds each session's rolling summary of its messages up to through_message_id
+func createSessionSummariesTable(ctx context.Context, tx *sql.Tx) error {
+	query := `
+		CREATE TABLE IF NOT EXISTS session_summaries (
+			session_id TEXT PRIMARY KEY,
+			user_id INTEGER NOT NULL,
+			summary TEXT NOT NULL,
+			through_message_id INTEGER NOT NULL,
+			model TEXT NOT NULL DEFAULT '',
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+		)
+	`
+	_, err := tx.ExecContext(ctx, query)
+	return err
+}
+
+// createQuickNotesTable creates the quick_notes table if it doesn't exist
+// Each note belongs to a day document, whose text is rebuilt from its notes
+func createQuickNotesTable(ctx context.Context, tx *sql.Tx) error {
+	query := `
+		CREATE TABLE IF NOT EXISTS quick_notes (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL,
+			source TEXT NOT NULL,
+			text TEXT NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+		)
+	`
+	_, err := tx.ExecContext(ctx, query)
+	return err
+}
+
+// createDocumentLinksTable creates the document_links table if it doesn't exist
+// It keeps the wikilinks of imported notes, in the order they appear
+func createDocumentLinksTable(ctx context.Context, tx *sql.Tx) error {
+	query := `
+		CREATE TABLE IF NOT EXISTS document_links (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL,
+			source TEXT NOT NULL,
+			target TEXT NOT NULL,
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+		)
+	`
+	_, err := tx.ExecContext(ctx, query)
+	return err
+}
+
+// createChunkAccessLogTable creates the chunk_access_log table if it doesn't exist
+// Rows outlive their chunks, so the record of where sensitive text went survives
+// deleting the document
+func createChunkAccessLogTable(ctx context.Context, tx *sql.Tx) error {
+	query := `
+		CREATE TABLE IF NOT EXISTS chunk_access_log (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			owner_id INTEGER NOT NULL,
+			user_id INTEGER NOT NULL,
+			chunk_id INTEGER NOT NULL,
+			source TEXT NOT NULL,
+			session_id TEXT NOT NULL DEFAULT '',
+			query TEXT NOT NULL DEFAULT '',
+			provider TEXT NOT NULL DEFAULT '',
+			provider_mode TEXT NOT NULL DEFAULT '',
+			accessed_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (owner_id) REFERENCES users(id) ON DELETE CASCADE
+		)
+	`
+	_, err := tx.ExecContext(ctx, query)
+	return err
+}
+
+// createSourceRetrievalsTable creates the source_retrievals table if it doesn't exist
+// It keeps one row of search statistics per source and day
+func createSourceRetrievalsTable(ctx context.Context, tx *sql.Tx) error {
+	query := `
+		CREATE TABLE IF NOT EXISTS source_retrievals (
+			user_id INTEGER NOT NULL,
+			source TEXT NOT NULL,
+			day TEXT NOT NULL,
+			candidates INTEGER NOT NULL DEFAULT 0,
+			selected INTEGER NOT NULL DEFAULT 0,
+			best_score REAL NOT NULL DEFAULT 0,
+			score_sum REAL NOT NULL DEFAULT 0,
+			PRIMARY KEY (user_id, source, day),
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+		)
+	`
+	_, err := tx.ExecContext(ctx, query)
+	return err
+}
+
+// createMessageArtifactsTable creates the table of code blocks and tables
+// extracted from assistant messages, numbered from 1 within each message
+func createMessageArtifactsTable(ctx context.Context, tx *sql.Tx) error {
+	query := `
+		CREATE TABLE IF NOT EXISTS message_artifacts (
+			message_id INTEGER NOT NULL,
+			n INTEGER NOT NULL,
+			kind TEXT NOT NULL CHECK(kind IN ('code', 'table')),
+			language TEXT NOT NULL DEFAULT '',
+			content TEXT NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (message_id, n),
+			FOREIGN KEY (message_id) REFERENCES chat_messages(id) ON DELETE CASCADE
+		)
+	`
+	_, err := tx.ExecContext(ctx, query)
+	return err
+}
+
+// createDocumentContentsTable creates the table of the text each document was
+// chunked from, with the chunker settings used, so it can be chunked again
+func createDocumentContentsTable(ctx context.Context, tx *sql.Tx) error {
+	query := `
+		CREATE TABLE IF NOT EXISTS document_contents (
+			user_id INTEGER NOT NULL,
+			source TEXT NOT NULL,
+			content TEXT NOT NULL,
+			chunker TEXT NOT NULL DEFAULT '',
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (user_id, source),
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+		)
+	`
+	_, err := tx.ExecContext(ctx, query)
+	return err
+}
+
+// backfillChunkDocuments creates a document for every user's source that has chunks
+// without one, and points those chunks at it. Chunks keep their source string
+func backfillChunkDocuments(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO documents (user_id, source, created_at)
+		SELECT user_id, source, MIN(created_at)
+		FROM chunks
+		WHERE document_id IS NULL AND user_id IS NOT NULL
+		GROUP BY user_id, source
+		ON CONFLICT(user_id, source) DO NOTHING
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create documents: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE chunks SET document_id = (
+			SELECT d.id FROM documents d WHERE d.user_id = chunks.user_id AND d.source = chunks.source
+		)
+		WHERE document_id IS NULL AND user_id IS NOT NULL
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to link chunks to documents: %w", err)
+	}
+
+	return nil
+}
+
+// createSummaryProvenanceTable creates the summary_provenance table if it doesn't exist
+// It records the model and content hash each document summary was generated from
+func createSummaryProvenanceTable(ctx context.Context, tx *sql.Tx) error {
+	query := `
+		CREATE TABLE IF NOT EXISTS summary_provenance (
+			user_id INTEGER NOT NULL,
+			source TEXT NOT NULL,
+			model TEXT NOT NULL,
+			content_hash TEXT NOT NULL,
+			generated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (user_id, source),
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+		)
+	`
+	_, err := tx.ExecContext(ctx, query)
+	return err
+}
+
+// createEvalTables creates the retrieval evaluation sets and their run history
+// Cases, configs, metrics and results are stored as JSON
+func createEvalTables(ctx context.Context, tx *sql.Tx) error {
+	queries := []string{`
+		CREATE TABLE IF NOT EXISTS eval_sets (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL,
+			name TEXT NOT NULL,
+			cases TEXT NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+		)`, `
+		CREATE TABLE IF NOT EXISTS eval_runs (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			set_id INTEGER NOT NULL,
+			user_id INTEGER NOT NULL,
+			config TEXT NOT NULL,
+			metrics TEXT NOT NULL,
+			results TEXT NOT NULL,
+			duration_ms INTEGER NOT NULL DEFAULT 0,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (set_id) REFERENCES eval_sets(id) ON DELETE CASCADE,
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_eval_sets_user ON eval_sets(user_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_eval_runs_set ON eval_runs(set_id, created_at)`,
+	}
+	for _, query := range queries {
+		if _, err := tx.ExecContext(ctx, query); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// createFeatureFlagOverridesTable creates the feature_flag_overrides table if it doesn't exist
+// It holds the flags admins turned on or off for individual users
+func createFeatureFlagOverridesTable(ctx context.Context, tx *sql.Tx) error {
+	query := `
+		CREATE TABLE IF NOT EXISTS feature_flag_overrides (
+			user_id INTEGER NOT NULL,
+			flag TEXT NOT NULL,
+			enabled BOOLEAN NOT NULL,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (user_id, flag),
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+		)
+	`
+	_, err := tx.ExecContext(ctx, query)
+	return err
+}
+
+// createScheduledJobsTable creates the scheduled_jobs table if it doesn't exist
+// It keeps background job pauses and run times across restarts
+func createScheduledJobsTable(ctx context.Context, tx *sql.Tx) error {
+	query := `
+		CREATE TABLE IF NOT EXISTS scheduled_jobs (
+			name TEXT PRIMARY KEY,
+			schedule TEXT NOT NULL,
+			paused BOOLEAN NOT NULL DEFAULT 0,
+			last_run_at TIMESTAMP,
+			last_duration_ms INTEGER NOT NULL DEFAULT 0,
+			last_error TEXT NOT NULL DEFAULT '',
+			next_run_at TIMESTAMP,
+			runs INTEGER NOT NULL DEFAULT 0,
+			failures INTEGER NOT NULL DEFAULT 0,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)
+	`
+	_, err := tx.ExecContext(ctx, query)
+	return err
+}
+
+// createClusterTables creates the cluster_locks, cluster_events and cluster_tasks
+// tables if they don't exist
+// They let instances sharing the database take turns running background work,
+// see each other's events and hand tasks to one another. Lock and claim expiry
+// times are Unix milliseconds so every instance compares them the same way
+func createClusterTables(ctx context.Context, tx *sql.Tx) error {
+	queries := []string{`
+		CREATE TABLE IF NOT EXISTS cluster_locks (
+			name TEXT PRIMARY KEY,
+			owner TEXT NOT NULL,
+			expires_at INTEGER NOT NULL
+		)`, `
+		CREATE TABLE IF NOT EXISTS cluster_events (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			origin TEXT NOT NULL,
+			kind TEXT NOT NULL,
+			payload TEXT NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`, `
+		CREATE TABLE IF NOT EXISTS cluster_tasks (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			kind TEXT NOT NULL,
+			payload TEXT NOT NULL,
+			status TEXT NOT NULL DEFAULT 'pending',
+			claimed_by TEXT NOT NULL DEFAULT '',
+			claimed_until INTEGER NOT NULL DEFAULT 0,
+			attempts INTEGER NOT NULL DEFAULT 0,
+			result TEXT NOT NULL DEFAULT '',
+			error TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			finished_at TIMESTAMP
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_cluster_events_created ON cluster_events(created_at)`,
+		`CREATE INDEX IF NOT EXISTS idx_cluster_tasks_status ON cluster_tasks(status, kind)`,
+	}
+	for _, query := range queries {
+		if _, err := tx.ExecContext(ctx, query); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// createNotificationsTable creates the notifications table if it doesn't exist
+// It keeps events for users, such as a finished ingestion, until they read them
+func createNotificationsTable(ctx context.Context, tx *sql.Tx) error {
+	queries := []string{`
+		CREATE TABLE IF NOT EXISTS notifications (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL,
+			type TEXT NOT NULL,
+			message TEXT NOT NULL,
+			payload TEXT NOT NULL DEFAULT '',
+			read BOOLEAN NOT NULL DEFAULT 0,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_notifications_user ON notifications(user_id, read, id)`,
+	}
+	for _, query := range queries {
+		if _, err := tx.ExecContext(ctx, query); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// createExtractionTables creates the record schemas users extract documents with
+// and the structured records extracted; schemas and record data are stored as JSON
+func createExtractionTables(ctx context.Context, tx *sql.Tx) error {
+	queries := []string{`
+		CREATE TABLE IF NOT EXISTS extraction_schemas (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL,
+			name TEXT NOT NULL,
+			description TEXT NOT NULL DEFAULT '',
+			schema TEXT NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE(user_id, name),
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+		)`, `
+		CREATE TABLE IF NOT EXISTS structured_records (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			schema_id INTEGER NOT NULL,
+			user_id INTEGER NOT NULL,
+			source TEXT NOT NULL,
+			data TEXT NOT NULL DEFAULT '{}',
+			status TEXT NOT NULL,
+			violations TEXT NOT NULL DEFAULT '',
+			error TEXT NOT NULL DEFAULT '',
+			model TEXT NOT NULL DEFAULT '',
+			extracted_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE(schema_id, source),
+			FOREIGN KEY (schema_id) REFERENCES extraction_schemas(id) ON DELETE CASCADE,
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_structured_records_user ON structured_records(user_id, schema_id)`,
+	}
+	for _, query := range queries {
+		if _, err := tx.ExecContext(ctx, query); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// createChatMessagesFTS creates an FTS5 index over chat message content for quick search
+// The index is external-content (backed by chat_messages) and kept in sync by triggers
+func createChatMessagesFTS(ctx context.Context, tx *sql.Tx) error {
+	// Check if the index already exists
+	var ftsExists bool
+	err := tx.QueryRowContext(ctx, `
+		SELECT COUNT(*) > 0 
+		FROM sqlite_master 
+		WHERE type = 'table' AND name = 'chat_messages_fts'
+	`).Scan(&ftsExists)
+	if err != nil {
+		return fmt.Errorf("failed to check chat_messages_fts table: %w", err)
+	}
+
+	queries := []string{
+		`CREATE VIRTUAL TABLE IF NOT EXISTS chat_messages_fts USING fts5(content, content='chat_messages', content_rowid='id')`,
+		`CREATE TRIGGER IF NOT EXISTS chat_messages_fts_insert AFTER INSERT ON chat_messages BEGIN
+			INSERT INTO chat_messages_fts(rowid, content) VALUES (new.id, new.content);
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS chat_messages_fts_delete AFTER DELETE ON chat_messages BEGIN
+			INSERT INTO chat_messages_fts(chat_messages_fts, rowid, content) VALUES ('delete', old.id, old.content);
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS chat_messages_fts_update AFTER UPDATE OF content ON chat_messages BEGIN
+			INSERT INTO chat_messages_fts(chat_messages_fts, rowid, content) VALUES ('delete', old.id, old.content);
+			INSERT INTO chat_messages_fts(rowid, content) VALUES (new.id, new.content);
+		END`,
+	}
+	for _, query := range queries {
+		if _, err := tx.ExecContext(ctx, query); err != nil {
+			return err
+		}
+	}
+
+	// Index messages that were saved before the index existed
+	if !ftsExists {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO chat_messages_fts(chat_messages_fts) VALUES ('rebuild')`); err != nil {
+			return fmt.Errorf("failed to build chat_messages_fts index: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// createCostEstimatesTable creates the table of estimated token counts and
+// costs of requests to paid providers, with whether each was sent
+func createCostEstimatesTable(ctx context.Context, tx *sql.Tx) error {
+	queries := []string{`
+		CREATE TABLE IF NOT EXISTS cost_estimates (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL,
+			session_id TEXT NOT NULL DEFAULT '',
+			provider TEXT NOT NULL DEFAULT '',
+			model TEXT NOT NULL DEFAULT '',
+			prompt_tokens INTEGER NOT NULL,
+			completion_tokens INTEGER NOT NULL,
+			cost_usd REAL,
+			threshold_usd REAL NOT NULL DEFAULT 0,
+			outcome TEXT NOT NULL CHECK(outcome IN ('sent', 'confirmed', 'confirmation_required')),
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_cost_estimates_user ON cost_estimates(user_id, created_at)`,
+	}
+	for _, query := range queries {
+		if _, err := tx.ExecContext(ctx, query); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addCostThresholdToUsers adds the cost_confirm_above column to the users
+// table; NULL keeps the server's threshold
+func addCostThresholdToUsers(ctx context.Context, tx *sql.Tx) error {
+	var exists bool
+	err := tx.QueryRowContext(ctx, `
+		SELECT COUNT(*) > 0
+		FROM pragma_table_info('users')
+		WHERE name = 'cost_confirm_above'
+	`).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to check cost_confirm_above column: %w", err)
+	}
+
+	if !exists {
+		if _, err = tx.ExecContext(ctx, `ALTER TABLE users ADD COLUMN cost_confirm_above REAL`); err != nil {
+			return fmt.Errorf("failed to add cost_confirm_above column: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// createProviderTranscriptsTable creates the archive of encrypted requests to
+// cloud providers. Rows outlive their user's account, since compliance
+// retention is decided by the archive's own schedule
+func createProviderTranscriptsTable(ctx context.Context, tx *sql.Tx) error {
+	queries := []string{`
+		CREATE TABLE IF NOT EXISTS provider_transcripts (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL DEFAULT 0,
+			provider TEXT NOT NULL,
+			model TEXT NOT NULL DEFAULT '',
+			operation TEXT NOT NULL,
+			key_id TEXT NOT NULL,
+			payload BLOB NOT NULL,
+			created_at TIMESTAMP NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_provider_transcripts_created ON provider_transcripts(created_at)`,
+		`CREATE INDEX IF NOT EXISTS idx_provider_transcripts_user ON provider_transcripts(user_id, created_at)`,
+	}
+	for _, query := range queries {
+		if _, err := tx.ExecContext(ctx, query); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// createUserPreferencesTable creates the user_preferences table, which holds
+// interface settings by name so new ones need no migration. Users who turned
+// dark mode on before themes existed keep the dark theme
+func createUserPreferencesTable(ctx context.Context, tx *sql.Tx) error {
+	queries := []string{`
+		CREATE TABLE IF NOT EXISTS user_preferences (
+			user_id INTEGER NOT NULL,
+			name TEXT NOT NULL,
+			value TEXT NOT NULL,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (user_id, name),
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+		)`,
+		`INSERT OR IGNORE INTO user_preferences (user_id, name, value)
+		SELECT id, 'theme', 'dark' FROM users WHERE dark_mode = 1`,
+	}
+	for _, query := range queries {
+		if _, err := tx.ExecContext(ctx, query); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addStreamStatsToProvenance adds the time to the first token and the total
+// generation time of each answer to message_provenance; 0 for answers recorded
+// before they were measured
+func addStreamStatsToProvenance(ctx context.Context, tx *sql.Tx) error {
+	for _, column := range []string{"first_token_ms", "generation_ms"} {
+		var exists bool
+		err := tx.QueryRowContext(ctx, `
+			SELECT COUNT(*) > 0
+			FROM pragma_table_info('message_provenance')
+			WHERE name = ?
+		`, column).Scan(&exists)
+		if err != nil {
+			return fmt.Errorf("failed to check %s column: %w", column, err)
+		}
+		if exists {
+			continue
+		}
+		if _, err = tx.ExecContext(ctx, fmt.Sprintf(`ALTER TABLE message_provenance ADD COLUMN %s INTEGER NOT NULL DEFAULT 0`, column)); err != nil {
+			return fmt.Errorf("failed to add %s column: %w", column, err)
+		}
+	}
+	return nil
+}
+
+// addPrivacyToProvenance adds the privacy summary of each answer to
+// message_provenance as a JSON object; empty for answers recorded before it
+func addPrivacyToProvenance(ctx context.Context, tx *sql.Tx) error {
+	var exists bool
+	err := tx.QueryRowContext(ctx, `
+		SELECT COUNT(*) > 0
+		FROM pragma_table_info('message_provenance')
+		WHERE name = 'privacy'
+	`).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to check privacy column: %w", err)
+	}
+	if exists {
+		return nil
+	}
+	if _, err := tx.ExecContext(ctx, `ALTER TABLE message_provenance ADD COLUMN privacy TEXT NOT NULL DEFAULT ''`); err != nil {
+		return fmt.Errorf("failed to add privacy column: %w", err)
+	}
+	return nil
+}
+
+// addProviderModeToSessions adds the provider mode a session is answered in.
+// Sessions recorded before it take the global mode on their next question
+func addProviderModeToSessions(ctx context.Context, tx *sql.Tx) error {
+	var exists bool
+	err := tx.QueryRowContext(ctx, `
+		SELECT COUNT(*) > 0
+		FROM pragma_table_info('sessions')
+		WHERE name = 'provider_mode'
+	`).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to check provider_mode column: %w", err)
+	}
+	if exists {
+		return nil
+	}
+	query := `ALTER TABLE sessions ADD COLUMN provider_mode TEXT NOT NULL DEFAULT '' CHECK(provider_mode IN ('', 'local', 'cloud'))`
+	if _, err := tx.ExecContext(ctx, query); err != nil {
+		return fmt.Errorf("failed to add provider_mode column: %w", err)
+	}
+	return nil
+}
+
+// addVisibilityToDocuments adds the visibility column to the documents table,
+// which its chunks inherit. Existing documents take the most restrictive
+// visibility of their chunks, and chunks that differ are brought in line
+func addVisibilityToDocuments(ctx context.Context, tx *sql.Tx) error {
+	var exists bool
+	err := tx.QueryRowContext(ctx, `
+		SELECT COUNT(*) > 0
+		FROM pragma_table_info('documents')
+		WHERE name = 'visibility'
+	`).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to check visibility column: %w", err)
+	}
+	if exists {
+		return nil
+	}
+
+	_, err = tx.ExecContext(ctx, `ALTER TABLE documents ADD COLUMN visibility TEXT NOT NULL DEFAULT 'private' CHECK(visibility IN ('private', 'shared', 'public'))`)
+	if err != nil {
+		return fmt.Errorf("failed to add visibility column: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE documents SET visibility = (
+			SELECT CASE
+				WHEN SUM(c.visibility = 'private' OR c.visibility IS NULL) > 0 THEN 'private'
+				WHEN SUM(c.visibility = 'shared') > 0 THEN 'shared'
+				ELSE 'public'
+			END
+			FROM chunks c WHERE c.document_id = documents.id
+		)
+		WHERE EXISTS (SELECT 1 FROM chunks c WHERE c.document_id = documents.id)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to backfill document visibility: %w", err)
+	}
+
+	_, err = repairChunkVisibility(ctx, tx)
+	return err
+}
+
+// createDocumentPinsTable creates the table of documents users pinned. Pins are
+// per user, so a shared or public document can be pinned by anyone who sees it
+func createDocumentPinsTable(ctx context.Context, tx *sql.Tx) error {
+	query := `
+		CREATE TABLE IF NOT EXISTS document_pins (
+			user_id INTEGER NOT NULL,
+			document_id INTEGER NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (user_id, document_id),
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+			FOREIGN KEY (document_id) REFERENCES documents(id) ON DELETE CASCADE
+		)
+	`
+	_, err := tx.ExecContext(ctx, query)
+	return err
+}
+
+// createDocumentEncodingsTable creates the table recording the encoding each
+// document was decoded from at its latest ingestion, and what normalizing its
+// text changed
+func createDocumentEncodingsTable(ctx context.Context, tx *sql.Tx) error {
+	query := `
+		CREATE TABLE IF NOT EXISTS document_encodings (
+			user_id INTEGER NOT NULL,
+			source TEXT NOT NULL,
+			encoding TEXT NOT NULL,
+			changes TEXT NOT NULL DEFAULT '',
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (user_id, source),
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+		)
+	`
+	_, err := tx.ExecContext(ctx, query)
+	return err
+}
+
+// createImportedSessionsTable creates the table of sessions imported from
+// another tool's chat history, keyed by the conversation's ID in that tool so
+// importing an export again skips the conversations already imported
+func createImportedSessionsTable(ctx context.Context, tx *sql.Tx) error {
+	query := `
+		CREATE TABLE IF NOT EXISTS imported_sessions (
+			session_id TEXT PRIMARY KEY,
+			user_id INTEGER NOT NULL,
+			format TEXT NOT NULL,
+			external_id TEXT NOT NULL,
+			imported_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE (user_id, format, external_id),
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+		)
+	`
+	_, err := tx.ExecContext(ctx, query)
+	return err
+}
+
+// addGlobalToSkills adds the global column to skills. Global skills are
+// provisioned by an administrator for every account; their user_id is the
+// administrator who added them
+func addGlobalToSkills(ctx context.Context, tx *sql.Tx) error {
+	var globalExists bool
+	err := tx.QueryRowContext(ctx, `
+		SELECT COUNT(*) > 0
+		FROM pragma_table_info('skills')
+		WHERE name = 'global'
+	`).Scan(&globalExists)
+	if err != nil {
+		return fmt.Errorf("failed to check global column: %w", err)
+	}
+
+	if !globalExists {
+		_, err = tx.ExecContext(ctx, `ALTER TABLE skills ADD COLUMN global INTEGER NOT NULL DEFAULT 0`)
+		if err != nil {
+			return fmt.Errorf("failed to add global column: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// createSkillPreferencesTable creates the table of users turning global skills
+// on or off for themselves. Users without a row get the skill's own setting
+func createSkillPreferencesTable(ctx context.Context, tx *sql.Tx) error {
+	query := `
+		CREATE TABLE IF NOT EXISTS skill_preferences (
+			user_id INTEGER NOT NULL,
+			skill_id INTEGER NOT NULL,
+			enabled INTEGER NOT NULL,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (user_id, skill_id),
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+			FOREIGN KEY (skill_id) REFERENCES skills(id) ON DELETE CASCADE
+		)
+	`
+	_, err := tx.ExecContext(ctx, query)
+	return err
+}
+
+// createSearchDictionaryTable creates the table of stop words, protected terms
+// and synonyms applied to keyword queries. A synonym entry has a row per term
+// it expands to; other entries leave synonym empty
+func createSearchDictionaryTable(ctx context.Context, tx *sql.Tx) error {
+	query := `
+		CREATE TABLE IF NOT EXISTS search_dictionary (
+			kind TEXT NOT NULL,
+			term TEXT NOT NULL,
+			synonym TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (kind, term, synonym)
+		)
+	`
+	_, err := tx.ExecContext(ctx, query)
+	return err
+}
+
+// createFailedRequestsTable creates the table of provider requests that failed,
+// kept with their redacted contents so administrators can replay them
+func createFailedRequestsTable(ctx context.Context, tx *sql.Tx) error {
+	queries := []string{`
+		CREATE TABLE IF NOT EXISTS failed_requests (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL DEFAULT 0,
+			provider TEXT NOT NULL,
+			model TEXT NOT NULL DEFAULT '',
+			operation TEXT NOT NULL,
+			local INTEGER NOT NULL DEFAULT 0,
+			request TEXT NOT NULL,
+			error TEXT NOT NULL,
+			created_at TIMESTAMP NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_failed_requests_created ON failed_requests(created_at)`,
+	}
+	for _, query := range queries {
+		if _, err := tx.ExecContext(ctx, query); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// createChunksFTS creates an FTS5 index over chunk text for keyword and hybrid
+// search. Like chat_messages_fts, it is external-content (backed by chunks)
+// and kept in sync by triggers
+func createChunksFTS(ctx context.Context, tx *sql.Tx) error {
+	// Check if the index already exists
+	var ftsExists bool
+	err := tx.QueryRowContext(ctx, `
+		SELECT COUNT(*) > 0
+		FROM sqlite_master
+		WHERE type = 'table' AND name = 'chunks_fts'
+	`).Scan(&ftsExists)
+	if err != nil {
+		return fmt.Errorf("failed to check chunks_fts table: %w", err)
+	}
+
+	queries := []string{
+		`CREATE VIRTUAL TABLE IF NOT EXISTS chunks_fts USING fts5(text, content='chunks', content_rowid='id')`,
+		`CREATE TRIGGER IF NOT EXISTS chunks_fts_insert AFTER INSERT ON chunks BEGIN
+			INSERT INTO chunks_fts(rowid, text) VALUES (new.id, new.text);
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS chunks_fts_delete AFTER DELETE ON chunks BEGIN
+			INSERT INTO chunks_fts(chunks_fts, rowid, text) VALUES ('delete', old.id, old.text);
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS chunks_fts_update AFTER UPDATE OF text ON chunks BEGIN
+			INSERT INTO chunks_fts(chunks_fts, rowid, text) VALUES ('delete', old.id, old.text);
+			INSERT INTO chunks_fts(rowid, text) VALUES (new.id, new.text);
+		END`,
+	}
+	for _, query := range queries {
+		if _, err := tx.ExecContext(ctx, query); err != nil {
+			return err
+		}
+	}
+
+	// Index chunks that were saved before the index existed
+	if !ftsExists {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO chunks_fts(chunks_fts) VALUES ('rebuild')`); err != nil {
+			return fmt.Errorf("failed to build chunks_fts index: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// createSavedSearchesTable creates the table of users' saved library searches
+// and the embeddings of their queries
+func createSavedSearchesTable(ctx context.Context, tx *sql.Tx) error {
+	queries := []string{`
+		CREATE TABLE IF NOT EXISTS saved_searches (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL,
+			name TEXT NOT NULL,
+			query TEXT NOT NULL,
+			mode TEXT NOT NULL DEFAULT '',
+			embedding BLOB,
+			embedding_model TEXT NOT NULL DEFAULT '',
+			embedded_at TIMESTAMP,
+			created_at TIMESTAMP NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_saved_searches_user ON saved_searches(user_id)`,
+	}
+	for _, query := range queries {
+		if _, err := tx.ExecContext(ctx, query); err != nil {
+			return err
+		}
+	}
+	return nil
+}
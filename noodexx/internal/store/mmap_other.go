@@ -0,0 +1,14 @@
+//go:build !unix
+
+package store
+
+import "os"
+
+// mapSnapshotFile reads a snapshot file into memory on platforms without mmap support
+func mapSnapshotFile(path string) ([]byte, func(), error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, func() {}, nil
+}
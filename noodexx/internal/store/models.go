@@ -7,22 +7,40 @@ import (
 
 // Chunk represents a text segment with its embedding
 type Chunk struct {
-	ID        int64
-	Source    string
-	Text      string
-	Embedding []float32
-	Tags      []string
-	Summary   string
-	CreatedAt time.Time
+	ID             int64
+	Source         string
+	Text           string
+	Embedding      []float32
+	TitleEmbedding []float32 // Embedding of the chunk's heading context; nil if it has none or it was not read
+	Tags           []string
+	Summary        string
+	CreatedAt      time.Time
+	Score          float64 // Ranking score assigned by search (zero outside search results)
 }
 
 // LibraryEntry represents a document in the library
 type LibraryEntry struct {
+	DocumentID int64
 	Source     string
+	Title      string // Display title; the source unless the document was renamed
 	ChunkCount int
 	Summary    string
 	Tags       []string
 	CreatedAt  time.Time
+	Warning    string // Why the latest ingestion kept only part of the document, if it did
+	Pinned     bool   // The user pinned the document; only set by per-user listings
+}
+
+// Document is the stable record of an ingested source. Chunks reference it by
+// ID, so it can be renamed without re-ingesting
+type Document struct {
+	ID         int64
+	UserID     int64
+	Source     string // Original filename, path or URL the document was ingested from
+	Title      string // Display title set by a rename; empty shows the source
+	Visibility string // "private", "shared" or "public"; its chunks inherit it
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
 }
 
 // ChatMessage represents a chat message
@@ -31,7 +49,9 @@ type ChatMessage struct {
 	SessionID    string
 	Role         string // "user" or "assistant"
 	Content      string
-	ProviderMode string // "local" or "cloud"
+	ProviderMode string   // "local" or "cloud"
+	Citations    []string // Sources supplied as numbered context, in order
+	Privacy      string   // Privacy summary of an answer as a JSON object; empty if not recorded
 	CreatedAt    time.Time
 }
 
@@ -40,6 +60,100 @@ type Session struct {
 	ID            string
 	LastMessageAt time.Time
 	MessageCount  int
+	ContinuedFrom string // Earlier session this one continues, if any
+	ImportedFrom  string // Chat history format the session was imported from, such as "chatgpt"; empty for sessions held here
+}
+
+// ImportedSession is a conversation from another tool's chat history, to be
+// recreated as a session
+type ImportedSession struct {
+	SessionID  string
+	Format     string // Chat history format, such as "chatgpt" or "claude"
+	ExternalID string // ID of the conversation in the export
+	Title      string
+	CreatedAt  time.Time
+	Messages   []ImportedMessage
+}
+
+// ImportedMessage is a message of an imported session
+type ImportedMessage struct {
+	Role      string // "user" or "assistant"
+	Content   string
+	CreatedAt time.Time // Zero takes the session's creation time
+}
+
+// SessionLink records that a session continues an earlier one; the summary of
+// the earlier session is included in the new session's prompts
+type SessionLink struct {
+	SessionID     string
+	UserID        int64
+	ContinuedFrom string
+	Summary       string
+	Model         string // Model the summary was generated with
+	CreatedAt     time.Time
+}
+
+// SessionSummary is a session's rolling summary of its older messages
+// Prompts use it in place of the messages up to ThroughMessageID; the messages
+// themselves are kept for display
+type SessionSummary struct {
+	SessionID        string
+	UserID           int64
+	Summary          string
+	ThroughMessageID int64  // Last message the summary covers
+	Model            string // Model the summary was generated with
+	UpdatedAt        time.Time
+}
+
+// QuickNote is a short captured snippet, appended to the user's notes
+// document for the day it was captured
+type QuickNote struct {
+	ID        int64
+	UserID    int64
+	Source    string // Day document the note belongs to
+	Text      string
+	CreatedAt time.Time
+}
+
+// ChunkAccess records a sensitive chunk being included in a prompt
+type ChunkAccess struct {
+	ID           int64
+	OwnerID      int64 // User the chunk belongs to
+	UserID       int64 // User whose request included the chunk
+	Username     string
+	ChunkID      int64
+	Source       string
+	SessionID    string
+	Query        string // Question or operation that retrieved the chunk
+	Provider     string // Provider the prompt was sent to
+	ProviderMode string // "local" or "cloud"
+	AccessedAt   time.Time
+}
+
+// RetrievalHit is a library chunk returned by a search for a question
+type RetrievalHit struct {
+	ChunkID  int64
+	Score    float64 // Search similarity, before any reranking
+	Selected bool    // Whether the chunk was included in the prompt
+}
+
+// SourceRetrieval summarizes how often one of a user's sources was retrieved
+// over a window. Candidates and Selected count questions, not chunks
+type SourceRetrieval struct {
+	Source        string
+	Chunks        int
+	CreatedAt     time.Time
+	Candidates    int       // Questions whose search returned one of its chunks
+	Selected      int       // Questions whose prompt included one of its chunks
+	BestScore     float64   // Best search score of its chunks
+	AvgScore      float64   // Average over questions of its best chunk's score
+	LastRetrieved time.Time // Day it was last returned by a search, zero if never
+}
+
+// SessionToCompact is an idle session with messages its summary does not cover yet
+type SessionToCompact struct {
+	SessionID string
+	UserID    int64
 }
 
 // AuditEntry represents an audit log entry
@@ -62,23 +176,25 @@ type WatchedFolder struct {
 
 // User represents a user account
 type User struct {
-	ID                 int64
-	Username           string
-	PasswordHash       string
-	Email              sql.NullString
-	IsAdmin            bool
-	MustChangePassword bool
-	CreatedAt          time.Time
-	LastLogin          time.Time
-	DarkMode           bool
+	ID                  int64
+	Username            string
+	PasswordHash        string
+	Email               sql.NullString
+	IsAdmin             bool
+	MustChangePassword  bool
+	CreatedAt           time.Time
+	LastLogin           time.Time
+	DarkMode            bool
+	OnboardingCompleted bool
 }
 
 // SessionToken represents an authentication session token
 type SessionToken struct {
-	Token     string
-	UserID    int64
-	CreatedAt time.Time
-	ExpiresAt time.Time
+	Token      string
+	UserID     int64
+	CreatedAt  time.Time
+	ExpiresAt  time.Time
+	RememberMe bool // Issued for "remember me", with the longer idle timeout
 }
 
 // Skill represents a user-owned skill/plugin
@@ -89,4 +205,453 @@ type Skill struct {
 	Path      string
 	Enabled   bool
 	CreatedAt time.Time
+	Global    bool // Provisioned by an administrator for every account
+}
+
+// Skill run statuses
+const (
+	SkillRunSuccess = "success"
+	SkillRunError   = "error"
+)
+
+// IngestFailure is a watched file that failed to ingest
+// Files that fail too often are quarantined: the watcher stops retrying them
+type IngestFailure struct {
+	Path          string
+	Folder        string // Watched folder the file is in
+	UserID        int64
+	Error         string // Most recent error
+	Attempts      int    // Failed attempts in a row
+	FirstFailedAt time.Time
+	LastAttemptAt time.Time
+}
+
+// SummaryState is a document's summary and what it was generated from
+// Model, ContentHash and GeneratedAt are empty for summaries from before
+// provenance was tracked, and for documents never summarized
+type SummaryState struct {
+	UserID      int64
+	Source      string
+	Summary     string
+	Model       string    // Model that generated the summary
+	ContentHash string    // Hash of the chunks the summary was generated from
+	CurrentHash string    // Hash of the document's chunks now
+	GeneratedAt time.Time // Zero when untracked
+}
+
+// Summary stale reasons returned by SummaryState.StaleReason
+const (
+	SummaryUntracked      = "untracked"
+	SummaryContentChanged = "content_changed"
+	SummaryModelChanged   = "model_changed"
+)
+
+// StaleReason reports why the summary should be regenerated with model, or ""
+// when it is current; an empty model matches any
+func (st SummaryState) StaleReason(model string) string {
+	switch {
+	case st.ContentHash == "":
+		return SummaryUntracked
+	case st.ContentHash != st.CurrentHash:
+		return SummaryContentChanged
+	case model != "" && st.Model != model:
+		return SummaryModelChanged
+	}
+	return ""
+}
+
+// EvalSet is a user's golden set of retrieval evaluation cases
+type EvalSet struct {
+	ID        int64
+	UserID    int64
+	Name      string
+	Cases     string // JSON array of questions and their expected sources
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// EvalRun is one recorded evaluation of an EvalSet
+type EvalRun struct {
+	ID         int64
+	SetID      int64
+	UserID     int64
+	Config     string // JSON of the retrieval settings the run used
+	Metrics    string // JSON of the run's aggregate scores
+	Results    string // JSON array of per-case results
+	DurationMS int64
+	CreatedAt  time.Time
+}
+
+// SkillRun is one recorded execution of a skill
+type SkillRun struct {
+	ID             int64
+	SkillID        int64
+	UserID         int64
+	Status         string // SkillRunSuccess or SkillRunError
+	Input          string // JSON sent to the skill
+	Output         string // JSON returned by the skill, empty if it produced none
+	Error          string
+	Stderr         string // Trailing excerpt of stderr
+	ExitCode       int
+	DurationMS     int64
+	ArtifactSource string // Library source the output was saved under, if any
+	RerunOf        int64  // ID of the run this one repeated, 0 if none
+	CreatedAt      time.Time
+}
+
+// GenerationDefaults holds a user's default sampling parameters for answers
+type GenerationDefaults struct {
+	UserID      int64
+	Temperature *float64 // Nil uses the provider default
+	TopP        *float64 // Nil uses the provider default
+	MaxTokens   int      // Zero uses the provider default
+	UpdatedAt   time.Time
+}
+
+// AnswerStyle holds a user's default answer language, tone and citation style
+type AnswerStyle struct {
+	UserID        int64
+	Language      string // Empty leaves the language to the model
+	Tone          string // "concise", "detailed", "technical" or empty
+	CitationStyle string // "numbered", "sources", "none" or empty
+	UpdatedAt     time.Time
+}
+
+// MessageProvenance records how an assistant message was generated
+type MessageProvenance struct {
+	MessageID        int64
+	Provider         string
+	Model            string
+	Params           string // Generation parameters as a JSON object
+	PromptHash       string
+	ChunkIDs         []int64 // Library chunks supplied to the model, in prompt order
+	PromptTokens     int
+	CompletionTokens int
+	FirstTokenMS     int64  // From sending the prompt to the first token of the answer
+	GenerationMS     int64  // From sending the prompt to the end of the answer
+	Privacy          string // Privacy summary as a JSON object; empty if not recorded
+	CreatedAt        time.Time
+}
+
+// ResponseStats summarizes how fast a provider and model answered
+type ResponseStats struct {
+	Provider         string
+	Model            string
+	Responses        int
+	AvgFirstTokenMS  float64
+	TokensPerSecond  float64 // Completion tokens over the time spent streaming them
+	PromptTokens     int
+	CompletionTokens int
+}
+
+// MessageArtifact is a code block or table extracted from an assistant message
+// so it can be copied or downloaded on its own
+type MessageArtifact struct {
+	MessageID int64
+	N         int    // Position among the message's artifacts, from 1
+	Kind      string // "code" or "table"
+	Language  string // Language of a code block's fence, if given
+	Content   string
+	CreatedAt time.Time
+}
+
+// DocumentContent is the text a document was chunked from
+type DocumentContent struct {
+	Source    string
+	Content   string
+	Chunker   string // Settings of the chunker that produced the current chunks
+	UpdatedAt time.Time
+}
+
+// DocumentEncoding is the encoding a document was decoded from at its latest
+// ingestion, and what normalizing its text changed
+type DocumentEncoding struct {
+	Source    string
+	Encoding  string // Such as "utf-8", "utf-16le" or "windows-1252"
+	Changes   string // Such as "12 line endings, 2 entities"; empty when nothing changed
+	UpdatedAt time.Time
+}
+
+// ChunkedDocument is one of a user's documents with the chunker settings its
+// chunks came from; Stored is false when its content was not kept, as for
+// documents ingested before contents were
+type ChunkedDocument struct {
+	Source  string
+	Stored  bool
+	Chunker string
+}
+
+// SessionShare is a read-only link to a chat session transcript
+type SessionShare struct {
+	ID           int64
+	Token        string
+	SessionID    string
+	UserID       int64
+	PasswordHash string    // Empty if the link has no password
+	ExpiresAt    time.Time // Zero if the link never expires
+	RevokedAt    time.Time // Zero unless revoked by the owner
+	ViewCount    int
+	CreatedAt    time.Time
+}
+
+// SessionShareView is one recorded view of a shared session
+type SessionShareView struct {
+	ID        int64
+	ShareID   int64
+	IPAddress string
+	UserAgent string
+	ViewedAt  time.Time
+}
+
+// RankingWeights holds a user's retrieval ranking modifiers
+type RankingWeights struct {
+	UserID              int64
+	RecencyHalfLifeDays float64            // 0 disables the recency boost
+	TagWeights          map[string]float64 // Multipliers keyed by lowercase tag
+	SourceWeights       map[string]float64 // Multipliers keyed by lowercase source substring
+	UpdatedAt           time.Time
+}
+
+// StorageUsage is the space taken by a group of chunks, in bytes
+type StorageUsage struct {
+	Chunks         int
+	TextBytes      int64 // Chunk text
+	EmbeddingBytes int64 // Embedding vectors
+	MetadataBytes  int64 // Summaries and tags
+}
+
+// TotalBytes returns the combined size of text, embeddings and metadata
+func (u StorageUsage) TotalBytes() int64 {
+	return u.TextBytes + u.EmbeddingBytes + u.MetadataBytes
+}
+
+// SourceStorage is the space taken by one user's copy of a source
+type SourceStorage struct {
+	Source   string
+	UserID   int64
+	Username string
+	StorageUsage
+}
+
+// UserStorage is the space taken by all of a user's sources
+type UserStorage struct {
+	UserID   int64
+	Username string
+	Sources  int
+	StorageUsage
+}
+
+// StorageStats breaks down library storage by source and user
+type StorageStats struct {
+	Total   StorageUsage
+	Sources int             // Number of sources counted in Total
+	Largest []SourceStorage // Largest sources first, up to the requested limit
+	Users   []UserStorage   // Largest users first
+}
+
+// EmbeddingGroup counts the chunks embedded by one model at one dimension
+type EmbeddingGroup struct {
+	Model     string // Empty for chunks stored before embedding models were recorded
+	Dimension int
+	Chunks    int
+	Sources   int // Distinct sources with chunks in the group
+}
+
+// UserActivity is an account with its last activity and resource usage
+type UserActivity struct {
+	UserID                int64
+	Username              string
+	Email                 string
+	IsAdmin               bool
+	CreatedAt             time.Time
+	LastLogin             time.Time // Zero if the user never signed in
+	LastChat              time.Time // Zero if the user never sent a chat message
+	Messages              int       // Chat messages the user sent
+	Documents             int       // Distinct sources in the user's library
+	StorageBytes          int64     // Chunk text, embeddings and metadata of those sources
+	CloudPromptTokens     int       // Estimated tokens sent to cloud providers
+	CloudCompletionTokens int       // Estimated tokens generated by cloud providers
+	EstimatedCostUSD      float64   // Estimated cost of the requests sent to paid providers with known prices
+	ConfirmationsAsked    int       // Requests held back until the user confirmed their cost
+}
+
+// Transcript is an archived request to a cloud provider; the payload is
+// encrypted by the transcripts package
+type Transcript struct {
+	ID        int64
+	UserID    int64 // 0 for requests made outside a user's request
+	Provider  string
+	Model     string
+	Operation string // "chat" or "embed"
+	KeyID     string // Identifies the key that sealed the payload
+	Payload   []byte
+	CreatedAt time.Time
+}
+
+// TranscriptFilter selects archived transcripts; zero values match everything
+type TranscriptFilter struct {
+	UserID   int64
+	Provider string
+	Since    time.Time
+	Until    time.Time // Exclusive
+	Limit    int
+}
+
+// FailedRequest is a provider request that failed, kept for replay
+type FailedRequest struct {
+	ID        int64
+	UserID    int64 // 0 for requests made outside a user's request
+	Provider  string
+	Model     string
+	Operation string // "chat" or "embed"
+	Local     bool   // Made through the local provider
+	Request   string // JSON of the redacted messages or input and the generation options
+	Error     string
+	CreatedAt time.Time
+}
+
+// SavedSearch is a library search a user saved to run again, with its query
+// embedded ahead of time so running it doesn't wait for the provider
+type SavedSearch struct {
+	ID             int64
+	UserID         int64
+	Name           string
+	Query          string
+	Mode           string     // Search mode; empty uses the configured one
+	Embedding      []float32  // Nil until the query is embedded, and in listings
+	EmbeddingModel string     // Model that embedded the query; the embedding is stale once another model is active
+	EmbeddedAt     *time.Time // Nil until the query is embedded
+	CreatedAt      time.Time
+}
+
+// CostEstimate is the estimated size and cost of a request to a paid provider
+type CostEstimate struct {
+	ID               int64
+	UserID           int64
+	SessionID        string
+	Provider         string
+	Model            string
+	PromptTokens     int
+	CompletionTokens int
+	CostUSD          *float64 // Nil when the model has no configured price
+	ThresholdUSD     float64  // Threshold in force; 0 never asks
+	Outcome          string   // "sent", "confirmed" or "confirmation_required"
+	CreatedAt        time.Time
+}
+
+// FlagOverride turns a feature flag on or off for one user
+type FlagOverride struct {
+	UserID    int64
+	Username  string
+	Flag      string
+	Enabled   bool
+	UpdatedAt time.Time
+}
+
+// JobState is the saved state of a scheduled background job
+type JobState struct {
+	Name           string
+	Schedule       string
+	Paused         bool
+	LastRunAt      *time.Time // Nil if the job never ran
+	LastDurationMS int64
+	LastError      string
+	NextRunAt      *time.Time
+	Runs           int64
+	Failures       int64
+}
+
+// ClusterEvent is an event one instance published for the others
+type ClusterEvent struct {
+	ID        int64
+	Origin    string // Node ID of the publishing instance
+	Kind      string
+	Payload   string
+	CreatedAt time.Time
+}
+
+// ClusterTask is a unit of work queued for whichever instance claims it
+type ClusterTask struct {
+	ID         int64
+	Kind       string
+	Payload    string
+	Status     string // "pending", "running", "done" or "failed"
+	ClaimedBy  string // Node ID of the instance working on it
+	Attempts   int
+	Result     string
+	Error      string
+	CreatedAt  time.Time
+	FinishedAt *time.Time
+}
+
+// Notification is an event kept for a user until they read it
+type Notification struct {
+	ID        int64
+	UserID    int64
+	Type      string // "ingestion", "deletion", "skill_run", "quarantine"
+	Message   string
+	Payload   string // JSON object with details such as the document source, empty if none
+	Read      bool
+	CreatedAt time.Time
+}
+
+// ExtractionSchema is a user's JSON schema for records extracted from documents
+type ExtractionSchema struct {
+	ID          int64
+	UserID      int64
+	Name        string
+	Description string
+	Schema      string // JSON Schema of one record
+	CreatedAt   time.Time
+}
+
+// StructuredRecord is the record extracted from one document with a schema
+type StructuredRecord struct {
+	ID          int64
+	SchemaID    int64
+	UserID      int64
+	Source      string
+	Data        string // JSON object, "{}" when extraction failed
+	Status      string // "ok", "invalid" or "failed"
+	Violations  string // JSON array of schema violations, empty if none
+	Error       string
+	Model       string
+	ExtractedAt time.Time
+}
+
+// RecordFilter selects structured records of one schema
+type RecordFilter struct {
+	SchemaID int64
+	Status   string            // Only records with this status when set
+	Source   string            // Only records whose source contains this when set
+	Fields   map[string]string // Only records whose field equals the value, compared as text
+	Limit    int
+	Offset   int
+}
+
+// DocumentFact is a document's metadata for metadata queries
+type DocumentFact struct {
+	Source     string
+	Tags       []string
+	Visibility string
+	Chunks     int
+	Size       int // Characters of text
+	CreatedAt  time.Time
+}
+
+// ChunkFact is a chunk's metadata for metadata queries
+type ChunkFact struct {
+	Source     string
+	Tags       []string
+	Visibility string
+	Length     int
+	CreatedAt  time.Time
+}
+
+// SessionFact is a chat session's metadata for metadata queries
+type SessionFact struct {
+	Title         string
+	Messages      int
+	CreatedAt     time.Time
+	LastMessageAt time.Time
 }
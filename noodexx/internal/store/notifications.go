@@ -0,0 +1,98 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// CreateNotification saves an unread notification for a user
+func (s *Store) CreateNotification(ctx context.Context, userID int64, notificationType, message, payload string) (*Notification, error) {
+	n := &Notification{
+		UserID:    userID,
+		Type:      notificationType,
+		Message:   message,
+		Payload:   payload,
+		CreatedAt: time.Now(),
+	}
+	query := `INSERT INTO notifications (user_id, type, message, payload, created_at) VALUES (?, ?, ?, ?, ?)`
+	result, err := s.db.ExecContext(ctx, query, userID, notificationType, message, payload, n.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create notification: %w", err)
+	}
+	if n.ID, err = result.LastInsertId(); err != nil {
+		return nil, fmt.Errorf("failed to create notification: %w", err)
+	}
+	return n, nil
+}
+
+// GetNotifications returns up to limit of a user's notifications, newest first
+func (s *Store) GetNotifications(ctx context.Context, userID int64, unreadOnly bool, limit int) ([]Notification, error) {
+	query := `
+		SELECT id, user_id, type, message, payload, read, created_at
+		FROM notifications
+		WHERE user_id = ?`
+	if unreadOnly {
+		query += ` AND read = 0`
+	}
+	query += ` ORDER BY id DESC LIMIT ?`
+
+	rows, err := s.db.QueryContext(ctx, query, userID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query notifications: %w", err)
+	}
+	defer rows.Close()
+
+	var notifications []Notification
+	for rows.Next() {
+		var n Notification
+		if err := rows.Scan(&n.ID, &n.UserID, &n.Type, &n.Message, &n.Payload, &n.Read, &n.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan notification: %w", err)
+		}
+		notifications = append(notifications, n)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating notifications: %w", err)
+	}
+	return notifications, nil
+}
+
+// CountUnreadNotifications returns how many notifications a user has not read
+func (s *Store) CountUnreadNotifications(ctx context.Context, userID int64) (int, error) {
+	var count int
+	err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM notifications WHERE user_id = ? AND read = 0`, userID).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count unread notifications: %w", err)
+	}
+	return count, nil
+}
+
+// MarkNotificationsRead marks a user's notifications with the given IDs read, or
+// all of them when ids is empty, and returns how many were unread
+func (s *Store) MarkNotificationsRead(ctx context.Context, userID int64, ids []int64) (int64, error) {
+	query := `UPDATE notifications SET read = 1 WHERE user_id = ? AND read = 0`
+	args := []interface{}{userID}
+	if len(ids) > 0 {
+		query += ` AND id IN (` + strings.TrimSuffix(strings.Repeat("?, ", len(ids)), ", ") + `)`
+		for _, id := range ids {
+			args = append(args, id)
+		}
+	}
+
+	result, err := s.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to mark notifications read: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// DeleteReadNotificationsBefore deletes read notifications created before a time
+// and returns how many were removed
+func (s *Store) DeleteReadNotificationsBefore(ctx context.Context, before time.Time) (int64, error) {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM notifications WHERE read = 1 AND created_at < ?`, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete old notifications: %w", err)
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,70 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestNotifications(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	userID, err := store.CreateUser(ctx, "reader", "password", "reader@test.com", false, false)
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	otherID, err := store.CreateUser(ctx, "other", "password", "other@test.com", false, false)
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	var ids []int64
+	for _, message := range []string{"Ingested a.txt", "Ingested b.txt", "Deleted c.txt"} {
+		n, err := store.CreateNotification(ctx, userID, "ingestion", message, `{"source":"a.txt"}`)
+		if err != nil {
+			t.Fatalf("CreateNotification failed: %v", err)
+		}
+		ids = append(ids, n.ID)
+	}
+	if _, err := store.CreateNotification(ctx, otherID, "ingestion", "Ingested d.txt", ""); err != nil {
+		t.Fatalf("CreateNotification failed: %v", err)
+	}
+
+	notifications, err := store.GetNotifications(ctx, userID, false, 2)
+	if err != nil {
+		t.Fatalf("GetNotifications failed: %v", err)
+	}
+	if len(notifications) != 2 || notifications[0].ID != ids[2] || notifications[0].Message != "Deleted c.txt" {
+		t.Errorf("Expected the two newest notifications first, got %+v", notifications)
+	}
+
+	// Marking another user's notification read has no effect
+	if marked, err := store.MarkNotificationsRead(ctx, otherID, ids[:1]); err != nil || marked != 0 {
+		t.Errorf("Expected nothing marked for another user, got %d, %v", marked, err)
+	}
+	if marked, err := store.MarkNotificationsRead(ctx, userID, ids[:1]); err != nil || marked != 1 {
+		t.Errorf("Expected one notification marked, got %d, %v", marked, err)
+	}
+	if unread, err := store.CountUnreadNotifications(ctx, userID); err != nil || unread != 2 {
+		t.Errorf("Expected 2 unread, got %d, %v", unread, err)
+	}
+	unread, err := store.GetNotifications(ctx, userID, true, 10)
+	if err != nil || len(unread) != 2 || unread[1].ID != ids[1] {
+		t.Errorf("Expected the two unread notifications, got %+v, %v", unread, err)
+	}
+
+	// No IDs marks everything read
+	if marked, err := store.MarkNotificationsRead(ctx, userID, nil); err != nil || marked != 2 {
+		t.Errorf("Expected the remaining two marked, got %d, %v", marked, err)
+	}
+
+	removed, err := store.DeleteReadNotificationsBefore(ctx, time.Now().Add(time.Second))
+	if err != nil || removed != 3 {
+		t.Errorf("Expected the 3 read notifications deleted, got %d, %v", removed, err)
+	}
+	if unread, _ := store.CountUnreadNotifications(ctx, otherID); unread != 1 {
+		t.Errorf("Expected the other user's unread notification kept, got %d", unread)
+	}
+}
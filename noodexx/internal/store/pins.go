@@ -0,0 +1,76 @@
+package store
+
+import (
+	"context"
+	"fmt"
+)
+
+// SetPinBoost sets the multiplier applied to the search scores of chunks from
+// documents the searching user pinned. 1 or less leaves pinned documents
+// ranked like any other
+func (s *Store) SetPinBoost(boost float64) {
+	s.pinBoost = boost
+}
+
+// PinDocument pins a document for the user, so it is listed first in their
+// library and boosted in their searches. The document must be visible to the
+// user; false means it is not. Pinning a pinned document does nothing
+func (s *Store) PinDocument(ctx context.Context, userID, documentID int64) (bool, error) {
+	query := `
+		INSERT INTO document_pins (user_id, document_id)
+		SELECT ?, d.id FROM documents d
+		WHERE d.id = ? AND (d.user_id = ? OR EXISTS (
+			SELECT 1 FROM chunks c
+			WHERE c.document_id = d.id AND (c.visibility = 'public'
+				OR (',' || COALESCE(c.shared_with, '') || ',') LIKE '%,' || CAST(? AS TEXT) || ',%')
+		))
+		ON CONFLICT(user_id, document_id) DO NOTHING
+	`
+	if _, err := s.db.ExecContext(ctx, query, userID, documentID, userID, userID); err != nil {
+		return false, fmt.Errorf("failed to pin document: %w", err)
+	}
+
+	// A conflict affects no rows either, so check the pin is there
+	var pinned bool
+	err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) > 0 FROM document_pins WHERE user_id = ? AND document_id = ?`, userID, documentID).Scan(&pinned)
+	if err != nil {
+		return false, fmt.Errorf("failed to check document pin: %w", err)
+	}
+	return pinned, nil
+}
+
+// UnpinDocument removes the user's pin from a document, if it has one
+func (s *Store) UnpinDocument(ctx context.Context, userID, documentID int64) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM document_pins WHERE user_id = ? AND document_id = ?`, userID, documentID)
+	if err != nil {
+		return fmt.Errorf("failed to unpin document: %w", err)
+	}
+	return nil
+}
+
+// pinnedSources returns the sources of the documents the user pinned
+func (s *Store) pinnedSources(ctx context.Context, userID int64) (map[string]bool, error) {
+	query := `
+		SELECT d.source FROM document_pins p
+		JOIN documents d ON d.id = p.document_id
+		WHERE p.user_id = ?
+	`
+	rows, err := s.reader().QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pinned documents: %w", err)
+	}
+	defer rows.Close()
+
+	sources := map[string]bool{}
+	for rows.Next() {
+		var source string
+		if err := rows.Scan(&source); err != nil {
+			return nil, fmt.Errorf("failed to scan pinned document: %w", err)
+		}
+		sources[source] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating pinned documents: %w", err)
+	}
+	return sources, nil
+}
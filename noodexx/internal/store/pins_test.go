@@ -0,0 +1,156 @@
+package store
+
+import (
+	"context"
+	"os"
+	"testing"
+)
+
+// libraryEntry finds a source in a user's library listing
+func libraryEntry(t *testing.T, store *Store, userID int64, source string) LibraryEntry {
+	t.Helper()
+	library, err := store.LibraryByUser(context.Background(), userID)
+	if err != nil {
+		t.Fatalf("LibraryByUser failed: %v", err)
+	}
+	for _, entry := range library {
+		if entry.Source == source {
+			return entry
+		}
+	}
+	t.Fatalf("Expected %s in the library of user %d", source, userID)
+	return LibraryEntry{}
+}
+
+// TestPinDocument tests pinning and unpinning documents, and that pins are
+// per user and limited to documents the user can see
+func TestPinDocument(t *testing.T) {
+	tmpFile := "test_pins.db"
+	defer os.Remove(tmpFile)
+
+	store, err := NewStore(tmpFile, "multi")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+
+	owner, err := store.CreateUser(ctx, "owner", "password", "owner@test.com", false, false)
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	other, err := store.CreateUser(ctx, "other", "password", "other@test.com", false, false)
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	if err := store.SaveChunk(ctx, owner, "handbook.md", "Policy text", []float32{1, 0, 0}, nil, ""); err != nil {
+		t.Fatalf("Failed to save chunk: %v", err)
+	}
+	documentID := libraryEntry(t, store, owner, "handbook.md").DocumentID
+
+	pinned, err := store.PinDocument(ctx, owner, documentID)
+	if err != nil || !pinned {
+		t.Fatalf("Expected the owner to pin the document, got %v, %v", pinned, err)
+	}
+	// Pinning twice keeps the pin
+	if pinned, err := store.PinDocument(ctx, owner, documentID); err != nil || !pinned {
+		t.Errorf("Expected pinning again to succeed, got %v, %v", pinned, err)
+	}
+	if !libraryEntry(t, store, owner, "handbook.md").Pinned {
+		t.Error("Expected the library entry to be pinned")
+	}
+
+	// Private documents of others cannot be pinned
+	if pinned, err := store.PinDocument(ctx, other, documentID); err != nil || pinned {
+		t.Errorf("Expected another user's private document not to be pinned, got %v, %v", pinned, err)
+	}
+	if pinned, err := store.PinDocument(ctx, owner, documentID+100); err != nil || pinned {
+		t.Errorf("Expected a missing document not to be pinned, got %v, %v", pinned, err)
+	}
+
+	// Once public, anyone may pin it, and pins are per user
+	if err := store.SetSourceVisibility(ctx, owner, "handbook.md", "public"); err != nil {
+		t.Fatalf("SetSourceVisibility failed: %v", err)
+	}
+	if libraryEntry(t, store, other, "handbook.md").Pinned {
+		t.Error("Expected the owner's pin not to show for another user")
+	}
+	if pinned, err := store.PinDocument(ctx, other, documentID); err != nil || !pinned {
+		t.Errorf("Expected a public document to be pinned, got %v, %v", pinned, err)
+	}
+
+	if err := store.UnpinDocument(ctx, owner, documentID); err != nil {
+		t.Fatalf("UnpinDocument failed: %v", err)
+	}
+	if libraryEntry(t, store, owner, "handbook.md").Pinned {
+		t.Error("Expected the library entry to be unpinned")
+	}
+	if !libraryEntry(t, store, other, "handbook.md").Pinned {
+		t.Error("Expected the other user's pin to remain")
+	}
+
+	// Deleting the document removes everyone's pins
+	if err := store.DeleteDocument(ctx, owner, "handbook.md"); err != nil {
+		t.Fatalf("DeleteDocument failed: %v", err)
+	}
+	pins, err := store.pinnedSources(ctx, other)
+	if err != nil {
+		t.Fatalf("pinnedSources failed: %v", err)
+	}
+	if len(pins) != 0 {
+		t.Errorf("Expected pins of a deleted document to be removed, got %v", pins)
+	}
+}
+
+// TestSearchByUserBoostsPinnedDocuments tests that pinned documents rank higher
+// by the pin boost
+func TestSearchByUserBoostsPinnedDocuments(t *testing.T) {
+	tmpFile := "test_pins_search.db"
+	defer os.Remove(tmpFile)
+
+	store, err := NewStore(tmpFile, "multi")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+
+	userID, err := store.CreateUser(ctx, "pinner", "password", "pinner@test.com", false, false)
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	// The meeting notes chunk is slightly more similar to the query than the handbook chunk
+	if err := store.SaveChunk(ctx, userID, "meeting-notes.md", "Meeting notes", []float32{1, 0.1, 0}, nil, ""); err != nil {
+		t.Fatalf("Failed to save chunk: %v", err)
+	}
+	if err := store.SaveChunk(ctx, userID, "handbook.md", "Policy text", []float32{1, 0.3, 0}, nil, ""); err != nil {
+		t.Fatalf("Failed to save chunk: %v", err)
+	}
+	if _, err := store.PinDocument(ctx, userID, libraryEntry(t, store, userID, "handbook.md").DocumentID); err != nil {
+		t.Fatalf("PinDocument failed: %v", err)
+	}
+
+	queryVec := []float32{1, 0, 0}
+
+	// Without a boost, pins do not change the ranking
+	results, err := store.SearchByUser(ctx, userID, queryVec, "", 2)
+	if err != nil {
+		t.Fatalf("SearchByUser failed: %v", err)
+	}
+	if len(results) != 2 || results[0].Source != "meeting-notes.md" {
+		t.Fatalf("Expected meeting notes first without a boost, got %+v", results)
+	}
+
+	store.SetPinBoost(1.5)
+	results, err = store.SearchByUser(ctx, userID, queryVec, "", 2)
+	if err != nil {
+		t.Fatalf("SearchByUser failed: %v", err)
+	}
+	if len(results) != 2 || results[0].Source != "handbook.md" {
+		t.Errorf("Expected the pinned handbook first, got %+v", results)
+	}
+}
@@ -0,0 +1,73 @@
+package store
+
+import (
+	"context"
+	"fmt"
+)
+
+// PreferenceTheme names the theme preference, which is mirrored in the
+// users.dark_mode column read by older code
+const PreferenceTheme = "theme"
+
+// GetUserPreferences returns the interface preferences a user has set, by
+// name. Preferences never set are absent
+func (s *Store) GetUserPreferences(ctx context.Context, userID int64) (map[string]string, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT name, value
+		FROM user_preferences
+		WHERE user_id = ?
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query preferences: %w", err)
+	}
+	defer rows.Close()
+
+	prefs := make(map[string]string)
+	for rows.Next() {
+		var name, value string
+		if err := rows.Scan(&name, &value); err != nil {
+			return nil, fmt.Errorf("failed to scan preference: %w", err)
+		}
+		prefs[name] = value
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating preferences: %w", err)
+	}
+	return prefs, nil
+}
+
+// SetUserPreferences saves the given preferences, leaving others unchanged.
+// An empty value removes the preference, returning it to its default
+func (s *Store) SetUserPreferences(ctx context.Context, userID int64, prefs map[string]string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for name, value := range prefs {
+		if value == "" {
+			_, err = tx.ExecContext(ctx, `DELETE FROM user_preferences WHERE user_id = ? AND name = ?`, userID, name)
+		} else {
+			_, err = tx.ExecContext(ctx, `
+				INSERT INTO user_preferences (user_id, name, value, updated_at)
+				VALUES (?, ?, ?, CURRENT_TIMESTAMP)
+				ON CONFLICT(user_id, name) DO UPDATE SET value = excluded.value, updated_at = excluded.updated_at
+			`, userID, name, value)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to save preference %s: %w", name, err)
+		}
+	}
+
+	if theme, ok := prefs[PreferenceTheme]; ok {
+		if _, err := tx.ExecContext(ctx, `UPDATE users SET dark_mode = ? WHERE id = ?`, theme == "dark", userID); err != nil {
+			return fmt.Errorf("failed to update dark mode: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit preferences: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package store
+
+import (
+	"context"
+	"testing"
+)
+
+// TestUserPreferences tests saving preferences by name and keeping the theme
+// in step with the dark mode flag
+func TestUserPreferences(t *testing.T) {
+	store, err := NewStore(t.TempDir()+"/test_preferences.db", "multi")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+	alice, _ := store.CreateUser(ctx, "alice", "password123", "alice@example.com", false, false)
+
+	if prefs, err := store.GetUserPreferences(ctx, alice); err != nil || len(prefs) != 0 {
+		t.Errorf("Expected no preferences by default, got %v (%v)", prefs, err)
+	}
+
+	if err := store.SetUserPreferences(ctx, alice, map[string]string{PreferenceTheme: "dark", "density": "compact"}); err != nil {
+		t.Fatalf("SetUserPreferences failed: %v", err)
+	}
+	prefs, _ := store.GetUserPreferences(ctx, alice)
+	if prefs[PreferenceTheme] != "dark" || prefs["density"] != "compact" {
+		t.Errorf("Expected both preferences saved, got %v", prefs)
+	}
+	if user, _ := store.GetUserByID(ctx, alice); !user.DarkMode {
+		t.Error("Expected the dark theme to set dark mode")
+	}
+
+	// Unnamed preferences are kept, and an empty value returns to the default
+	if err := store.SetUserPreferences(ctx, alice, map[string]string{PreferenceTheme: "system", "density": ""}); err != nil {
+		t.Fatalf("SetUserPreferences failed: %v", err)
+	}
+	prefs, _ = store.GetUserPreferences(ctx, alice)
+	if len(prefs) != 1 || prefs[PreferenceTheme] != "system" {
+		t.Errorf("Expected only the system theme, got %v", prefs)
+	}
+	if user, _ := store.GetUserByID(ctx, alice); user.DarkMode {
+		t.Error("Expected the system theme to clear dark mode")
+	}
+
+	// The dark mode toggle sets the theme
+	if err := store.UpdateUserDarkMode(ctx, alice, true); err != nil {
+		t.Fatalf("UpdateUserDarkMode failed: %v", err)
+	}
+	if prefs, _ := store.GetUserPreferences(ctx, alice); prefs[PreferenceTheme] != "dark" {
+		t.Errorf("Expected the dark theme, got %v", prefs)
+	}
+}
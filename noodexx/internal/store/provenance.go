@@ -0,0 +1,111 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// saveMessageProvenance records how message messageID was generated
+func saveMessageProvenance(ctx context.Context, db execer, messageID int64, p *MessageProvenance) error {
+	params := p.Params
+	if params == "" {
+		params = "{}"
+	}
+	chunkIDs := p.ChunkIDs
+	if chunkIDs == nil {
+		chunkIDs = []int64{}
+	}
+	chunkIDsJSON, err := json.Marshal(chunkIDs)
+	if err != nil {
+		return fmt.Errorf("failed to encode chunk IDs: %w", err)
+	}
+
+	query := `
+		INSERT INTO message_provenance (message_id, provider, model, params, prompt_hash, chunk_ids, prompt_tokens, completion_tokens, first_token_ms, generation_ms, privacy)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+	_, err = db.ExecContext(ctx, query, messageID, p.Provider, p.Model, params, p.PromptHash, string(chunkIDsJSON), p.PromptTokens, p.CompletionTokens, p.FirstTokenMS, p.GenerationMS, p.Privacy)
+	if err != nil {
+		return fmt.Errorf("failed to save message provenance: %w", err)
+	}
+	return nil
+}
+
+// GetMessageProvenance returns the provenance of one of a user's messages
+// It returns nil if the message does not exist, belongs to another user or has no provenance
+func (s *Store) GetMessageProvenance(ctx context.Context, userID, messageID int64) (*MessageProvenance, error) {
+	query := `
+		SELECT p.message_id, p.provider, p.model, p.params, p.prompt_hash, p.chunk_ids,
+			p.prompt_tokens, p.completion_tokens, p.first_token_ms, p.generation_ms, p.privacy, p.created_at
+		FROM message_provenance p
+		JOIN chat_messages m ON m.id = p.message_id
+		WHERE p.message_id = ? AND m.user_id = ?
+	`
+
+	var p MessageProvenance
+	var chunkIDs string
+	err := s.db.QueryRowContext(ctx, query, messageID, userID).Scan(
+		&p.MessageID,
+		&p.Provider,
+		&p.Model,
+		&p.Params,
+		&p.PromptHash,
+		&chunkIDs,
+		&p.PromptTokens,
+		&p.CompletionTokens,
+		&p.FirstTokenMS,
+		&p.GenerationMS,
+		&p.Privacy,
+		&p.CreatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get message provenance: %w", err)
+	}
+
+	if err := json.Unmarshal([]byte(chunkIDs), &p.ChunkIDs); err != nil {
+		return nil, fmt.Errorf("failed to decode chunk IDs: %w", err)
+	}
+	return &p, nil
+}
+
+// GetResponseStats summarizes the speed of a user's answers since a time by
+// provider and model, fastest first token first. Tokens per second are over the
+// time after the first token, or the whole generation for answers that arrived
+// at once. Answers recorded before their timing was measured are left out
+func (s *Store) GetResponseStats(ctx context.Context, userID int64, since time.Time) ([]ResponseStats, error) {
+	query := `
+		SELECT p.provider, p.model, COUNT(*), AVG(p.first_token_ms),
+			COALESCE(SUM(p.prompt_tokens), 0), COALESCE(SUM(p.completion_tokens), 0),
+			COALESCE(SUM(CASE WHEN p.generation_ms > p.first_token_ms THEN p.generation_ms - p.first_token_ms ELSE p.generation_ms END), 0)
+		FROM message_provenance p
+		JOIN chat_messages m ON m.id = p.message_id
+		WHERE m.user_id = ? AND p.generation_ms > 0 AND p.created_at >= ?
+		GROUP BY p.provider, p.model
+		ORDER BY AVG(p.first_token_ms), p.provider, p.model
+	`
+	rows, err := s.db.QueryContext(ctx, query, userID, since.UTC().Format("2006-01-02 15:04:05"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get response stats: %w", err)
+	}
+	defer rows.Close()
+
+	var stats []ResponseStats
+	for rows.Next() {
+		var st ResponseStats
+		var streamingMS int64
+		if err := rows.Scan(&st.Provider, &st.Model, &st.Responses, &st.AvgFirstTokenMS, &st.PromptTokens, &st.CompletionTokens, &streamingMS); err != nil {
+			return nil, fmt.Errorf("failed to scan response stats: %w", err)
+		}
+		if streamingMS > 0 {
+			st.TokensPerSecond = float64(st.CompletionTokens) / (float64(streamingMS) / 1000)
+		}
+		stats = append(stats, st)
+	}
+	return stats, rows.Err()
+}
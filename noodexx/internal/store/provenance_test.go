@@ -0,0 +1,149 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// TestMessageProvenance tests saving and reading the provenance of assistant messages
+func TestMessageProvenance(t *testing.T) {
+	store, err := NewStore(t.TempDir()+"/test_provenance.db", "multi")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+
+	ownerID, err := store.CreateUser(ctx, "owner", "password123", "owner@example.com", false, false)
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	otherID, err := store.CreateUser(ctx, "other", "password123", "other@example.com", false, false)
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	messageID, err := store.SaveChatMessageWithProvenance(ctx, ownerID, "session-1", "assistant", "Answer [1]", "local",
+		[]string{"doc.txt"}, &MessageProvenance{
+			Provider:         "ollama",
+			Model:            "llama3.2",
+			Params:           `{"temperature":0.2}`,
+			PromptHash:       "abc123",
+			ChunkIDs:         []int64{7, 3},
+			PromptTokens:     120,
+			CompletionTokens: 40,
+			FirstTokenMS:     150,
+			GenerationMS:     900,
+			Privacy:          `{"provider_mode":"local"}`,
+		})
+	if err != nil {
+		t.Fatalf("Failed to save message: %v", err)
+	}
+
+	messages, err := store.GetSessionMessages(ctx, ownerID, "session-1")
+	if err != nil || len(messages) != 1 || messages[0].ID != messageID || len(messages[0].Citations) != 1 {
+		t.Fatalf("Expected the saved message with its citations, got %+v (%v)", messages, err)
+	}
+	if messages[0].Privacy != `{"provider_mode":"local"}` {
+		t.Errorf("Expected the privacy summary with the message, got %q", messages[0].Privacy)
+	}
+
+	p, err := store.GetMessageProvenance(ctx, ownerID, messageID)
+	if err != nil || p == nil {
+		t.Fatalf("Failed to get provenance: %v", err)
+	}
+	if p.Provider != "ollama" || p.Model != "llama3.2" || p.Params != `{"temperature":0.2}` || p.PromptHash != "abc123" {
+		t.Errorf("Unexpected provenance: %+v", p)
+	}
+	if len(p.ChunkIDs) != 2 || p.ChunkIDs[0] != 7 || p.ChunkIDs[1] != 3 {
+		t.Errorf("Expected chunk IDs in prompt order, got %v", p.ChunkIDs)
+	}
+	if p.PromptTokens != 120 || p.CompletionTokens != 40 || p.FirstTokenMS != 150 || p.GenerationMS != 900 || p.Privacy == "" || p.CreatedAt.IsZero() {
+		t.Errorf("Unexpected token counts or time: %+v", p)
+	}
+
+	// Other users cannot read it
+	if p, err := store.GetMessageProvenance(ctx, otherID, messageID); err != nil || p != nil {
+		t.Errorf("Expected no provenance for another user, got %+v (%v)", p, err)
+	}
+
+	// Messages saved without provenance have none
+	if err := store.SaveChatMessage(ctx, ownerID, "session-1", "user", "Hello", ""); err != nil {
+		t.Fatalf("Failed to save message: %v", err)
+	}
+	messages, _ = store.GetSessionMessages(ctx, ownerID, "session-1")
+	for _, msg := range messages {
+		if msg.Role != "user" {
+			continue
+		}
+		if msg.Privacy != "" {
+			t.Errorf("Expected no privacy summary for a user message, got %q", msg.Privacy)
+		}
+		if p, err := store.GetMessageProvenance(ctx, ownerID, msg.ID); err != nil || p != nil {
+			t.Errorf("Expected no provenance for a user message, got %+v (%v)", p, err)
+		}
+	}
+
+	// Defaults are stored for empty params and chunk lists
+	messageID, err = store.SaveChatMessageWithProvenance(ctx, ownerID, "session-1", "assistant", "Plain", "cloud", nil, &MessageProvenance{Provider: "openai"})
+	if err != nil {
+		t.Fatalf("Failed to save message: %v", err)
+	}
+	if p, err := store.GetMessageProvenance(ctx, ownerID, messageID); err != nil || p.Params != "{}" || len(p.ChunkIDs) != 0 {
+		t.Errorf("Expected empty params and chunk IDs, got %+v (%v)", p, err)
+	}
+}
+
+// TestResponseStats tests summarizing answer timings by provider and model
+func TestResponseStats(t *testing.T) {
+	store, err := NewStore(t.TempDir()+"/test_response_stats.db", "multi")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+
+	ownerID, err := store.CreateUser(ctx, "owner", "password123", "owner@example.com", false, false)
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	otherID, err := store.CreateUser(ctx, "other", "password123", "other@example.com", false, false)
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	save := func(userID int64, p MessageProvenance) {
+		if _, err := store.SaveChatMessageWithProvenance(ctx, userID, "session-1", "assistant", "Answer", "local", nil, &p); err != nil {
+			t.Fatalf("Failed to save message: %v", err)
+		}
+	}
+	// Two answers of 100 tokens, each streamed over one second after the first token
+	save(ownerID, MessageProvenance{Provider: "ollama", Model: "llama3.2", PromptTokens: 50, CompletionTokens: 100, FirstTokenMS: 200, GenerationMS: 1200})
+	save(ownerID, MessageProvenance{Provider: "ollama", Model: "llama3.2", PromptTokens: 50, CompletionTokens: 100, FirstTokenMS: 400, GenerationMS: 1400})
+	save(ownerID, MessageProvenance{Provider: "openai", Model: "gpt-4o", CompletionTokens: 10, FirstTokenMS: 900, GenerationMS: 1000})
+	// Answers without timings and other users' answers are left out
+	save(ownerID, MessageProvenance{Provider: "ollama", Model: "mistral"})
+	save(otherID, MessageProvenance{Provider: "ollama", Model: "llama3.2", CompletionTokens: 5, FirstTokenMS: 1, GenerationMS: 2})
+
+	stats, err := store.GetResponseStats(ctx, ownerID, time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("Failed to get response stats: %v", err)
+	}
+	if len(stats) != 2 {
+		t.Fatalf("Expected two models, got %+v", stats)
+	}
+	local := stats[0]
+	if local.Model != "llama3.2" || local.Responses != 2 || local.AvgFirstTokenMS != 300 || local.TokensPerSecond != 100 || local.PromptTokens != 100 || local.CompletionTokens != 200 {
+		t.Errorf("Unexpected local stats: %+v", local)
+	}
+	if stats[1].Model != "gpt-4o" || stats[1].TokensPerSecond != 100 {
+		t.Errorf("Unexpected cloud stats: %+v", stats[1])
+	}
+
+	if stats, err := store.GetResponseStats(ctx, ownerID, time.Now().Add(time.Hour)); err != nil || len(stats) != 0 {
+		t.Errorf("Expected nothing after since, got %+v (%v)", stats, err)
+	}
+}
@@ -0,0 +1,74 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// AddQuickNote appends a note to a user's day document. It returns false
+// without adding the note when the user captured the same text since dedupSince,
+// so a double submission is ignored
+func (s *Store) AddQuickNote(ctx context.Context, note *QuickNote, dedupSince time.Time) (bool, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var duplicates int
+	query := `SELECT COUNT(*) FROM quick_notes WHERE user_id = ? AND text = ? AND created_at >= ?`
+	if err := tx.QueryRowContext(ctx, query, note.UserID, note.Text, dedupSince.UTC().Format("2006-01-02 15:04:05")).Scan(&duplicates); err != nil {
+		return false, fmt.Errorf("failed to check for duplicate note: %w", err)
+	}
+	if duplicates > 0 {
+		return false, nil
+	}
+
+	result, err := tx.ExecContext(ctx, `INSERT INTO quick_notes (user_id, source, text) VALUES (?, ?, ?)`, note.UserID, note.Source, note.Text)
+	if err != nil {
+		return false, fmt.Errorf("failed to add quick note: %w", err)
+	}
+	if note.ID, err = result.LastInsertId(); err != nil {
+		return false, fmt.Errorf("failed to get quick note ID: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit quick note: %w", err)
+	}
+	return true, nil
+}
+
+// GetQuickNotes returns the notes of a user's day document in the order they
+// were captured
+func (s *Store) GetQuickNotes(ctx context.Context, userID int64, source string) ([]QuickNote, error) {
+	query := `
+		SELECT id, user_id, source, text, created_at
+		FROM quick_notes
+		WHERE user_id = ? AND source = ?
+		ORDER BY id ASC
+	`
+	rows, err := s.db.QueryContext(ctx, query, userID, source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query quick notes: %w", err)
+	}
+	defer rows.Close()
+
+	var notes []QuickNote
+	for rows.Next() {
+		var note QuickNote
+		if err := rows.Scan(&note.ID, &note.UserID, &note.Source, &note.Text, &note.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan quick note: %w", err)
+		}
+		notes = append(notes, note)
+	}
+	return notes, rows.Err()
+}
+
+// DeleteQuickNote removes one of a user's notes, such as one whose day document
+// could not be rebuilt with it
+func (s *Store) DeleteQuickNote(ctx context.Context, userID, noteID int64) error {
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM quick_notes WHERE id = ? AND user_id = ?`, noteID, userID); err != nil {
+		return fmt.Errorf("failed to delete quick note: %w", err)
+	}
+	return nil
+}
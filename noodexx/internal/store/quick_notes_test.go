@@ -0,0 +1,69 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// TestQuickNotes tests capturing notes, ignoring double submissions, and
+// removing the notes with their day document
+func TestQuickNotes(t *testing.T) {
+	store, err := NewStore(t.TempDir()+"/test_quick_notes.db", "multi")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+
+	alice, _ := store.CreateUser(ctx, "alice", "password123", "alice@example.com", false, false)
+	bob, _ := store.CreateUser(ctx, "bob", "password123", "bob@example.com", false, false)
+
+	window := time.Now().Add(-time.Minute)
+	added, err := store.AddQuickNote(ctx, &QuickNote{UserID: alice, Source: "Notes 2024-01-15", Text: "Call the plumber"}, window)
+	if err != nil || !added {
+		t.Fatalf("AddQuickNote failed: %v, %v", added, err)
+	}
+
+	// The same text within the window is a double submission
+	added, err = store.AddQuickNote(ctx, &QuickNote{UserID: alice, Source: "Notes 2024-01-15", Text: "Call the plumber"}, window)
+	if err != nil || added {
+		t.Errorf("Expected the duplicate to be ignored, got %v, %v", added, err)
+	}
+
+	// Other users and later captures are not duplicates
+	if added, _ := store.AddQuickNote(ctx, &QuickNote{UserID: bob, Source: "Notes 2024-01-15", Text: "Call the plumber"}, window); !added {
+		t.Error("Expected another user's note to be added")
+	}
+	if added, _ := store.AddQuickNote(ctx, &QuickNote{UserID: alice, Source: "Notes 2024-01-15", Text: "Call the plumber"}, time.Now().Add(time.Minute)); !added {
+		t.Error("Expected a note outside the window to be added")
+	}
+	store.AddQuickNote(ctx, &QuickNote{UserID: alice, Source: "Notes 2024-01-15", Text: "Buy milk"}, window)
+	rejected := &QuickNote{UserID: alice, Source: "Notes 2024-01-15", Text: "My SSN is 123-45-6789"}
+	store.AddQuickNote(ctx, rejected, window)
+	if err := store.DeleteQuickNote(ctx, bob, rejected.ID); err != nil {
+		t.Fatalf("DeleteQuickNote failed: %v", err)
+	}
+	if err := store.DeleteQuickNote(ctx, alice, rejected.ID); err != nil {
+		t.Fatalf("DeleteQuickNote failed: %v", err)
+	}
+
+	notes, err := store.GetQuickNotes(ctx, alice, "Notes 2024-01-15")
+	if err != nil {
+		t.Fatalf("GetQuickNotes failed: %v", err)
+	}
+	if len(notes) != 3 || notes[2].Text != "Buy milk" {
+		t.Errorf("Expected alice's 3 notes in order, got %+v", notes)
+	}
+
+	if err := store.DeleteDocument(ctx, alice, "Notes 2024-01-15"); err != nil {
+		t.Fatalf("DeleteDocument failed: %v", err)
+	}
+	if notes, _ := store.GetQuickNotes(ctx, alice, "Notes 2024-01-15"); len(notes) != 0 {
+		t.Errorf("Expected the notes to be deleted with the document, got %d", len(notes))
+	}
+	if notes, _ := store.GetQuickNotes(ctx, bob, "Notes 2024-01-15"); len(notes) != 1 {
+		t.Errorf("Expected bob's note to be kept, got %d", len(notes))
+	}
+}
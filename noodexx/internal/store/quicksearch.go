@@ -0,0 +1,353 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Quick search result types
+const (
+	QuickSearchDocument = "document"
+	QuickSearchTag      = "tag"
+	QuickSearchSession  = "session"
+	QuickSearchMessage  = "message"
+)
+
+// QuickSearchLimits caps the number of results returned for each result type
+// A zero limit skips that type entirely
+type QuickSearchLimits struct {
+	Documents int
+	Tags      int
+	Sessions  int
+	Messages  int
+}
+
+// QuickSearchResult is a single typed hit from QuickSearch
+type QuickSearchResult struct {
+	Type      string    // QuickSearchDocument, QuickSearchTag, QuickSearchSession or QuickSearchMessage
+	Title     string    // Display text: source, tag, session title or message role
+	Snippet   string    // Extra context, e.g. the matching part of a message
+	Target    string    // Navigation URL for the result
+	SessionID string    // Set for session and message results
+	UpdatedAt time.Time // Most recent activity for the result, when known
+}
+
+// quickSearchTitleLength is the maximum length of a session title derived from its first message
+const quickSearchTitleLength = 80
+
+// QuickSearch performs a fast combined lookup across document sources, tags, session titles
+// and chat messages visible to a user. Sources, tags and session titles are matched by
+// case-insensitive prefix (of the whole value or any word in it); messages are matched
+// through the chat_messages_fts full-text index.
+func (s *Store) QuickSearch(ctx context.Context, userID int64, query string, limits QuickSearchLimits) ([]QuickSearchResult, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, nil
+	}
+
+	var results []QuickSearchResult
+
+	if limits.Documents > 0 {
+		docs, err := s.quickSearchDocuments(ctx, userID, query, limits.Documents)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, docs...)
+	}
+
+	if limits.Tags > 0 {
+		tags, err := s.quickSearchTags(ctx, userID, query, limits.Tags)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, tags...)
+	}
+
+	if limits.Sessions > 0 {
+		sessions, err := s.quickSearchSessions(ctx, userID, query, limits.Sessions)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, sessions...)
+	}
+
+	if limits.Messages > 0 {
+		messages, err := s.quickSearchMessages(ctx, userID, query, limits.Messages)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, messages...)
+	}
+
+	return results, nil
+}
+
+// quickSearchDocuments matches visible document sources by prefix
+func (s *Store) quickSearchDocuments(ctx context.Context, userID int64, query string, limit int) ([]QuickSearchResult, error) {
+	// Candidate rows contain the query anywhere; prefix matching is applied in Go so
+	// path and word boundaries ("docs/handbook.md", "leave-policy.md") are handled uniformly
+	sqlQuery := `
+		SELECT source, MAX(created_at) as updated_at
+		FROM chunks
+		WHERE (user_id = ?
+			OR visibility = 'public'
+			OR (',' || COALESCE(shared_with, '') || ',') LIKE '%,' || CAST(? AS TEXT) || ',%')
+			AND source LIKE ? ESCAPE '\'
+		GROUP BY source
+		ORDER BY updated_at DESC
+	`
+
+	rows, err := s.reader().QueryContext(ctx, sqlQuery, userID, userID, likeContains(query))
+	if err != nil {
+		return nil, fmt.Errorf("failed to quick search documents: %w", err)
+	}
+	defer rows.Close()
+
+	var results []QuickSearchResult
+	for rows.Next() && len(results) < limit {
+		var source string
+		var updatedAtStr string
+		if err := rows.Scan(&source, &updatedAtStr); err != nil {
+			return nil, fmt.Errorf("failed to scan document result: %w", err)
+		}
+		if !hasWordPrefix(source, query) {
+			continue
+		}
+
+		result := QuickSearchResult{
+			Type:   QuickSearchDocument,
+			Title:  source,
+			Target: "/library?source=" + url.QueryEscape(source),
+		}
+		result.UpdatedAt, _ = time.Parse("2006-01-02 15:04:05", updatedAtStr)
+		results = append(results, result)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating document results: %w", err)
+	}
+
+	return results, nil
+}
+
+// quickSearchTags matches tags on visible chunks by prefix, most used first
+func (s *Store) quickSearchTags(ctx context.Context, userID int64, query string, limit int) ([]QuickSearchResult, error) {
+	sqlQuery := `
+		SELECT tags, COUNT(DISTINCT source)
+		FROM chunks
+		WHERE (user_id = ?
+			OR visibility = 'public'
+			OR (',' || COALESCE(shared_with, '') || ',') LIKE '%,' || CAST(? AS TEXT) || ',%')
+			AND tags LIKE ? ESCAPE '\'
+		GROUP BY tags
+	`
+
+	rows, err := s.reader().QueryContext(ctx, sqlQuery, userID, userID, likeContains(query))
+	if err != nil {
+		return nil, fmt.Errorf("failed to quick search tags: %w", err)
+	}
+	defer rows.Close()
+
+	// Tags are stored comma-separated per chunk, so split and aggregate here
+	counts := make(map[string]int)
+	for rows.Next() {
+		var tagsStr sql.NullString
+		var sources int
+		if err := rows.Scan(&tagsStr, &sources); err != nil {
+			return nil, fmt.Errorf("failed to scan tag result: %w", err)
+		}
+		for _, tag := range splitTags(tagsStr.String) {
+			tag = strings.TrimSpace(tag)
+			if tag != "" && hasWordPrefix(tag, query) {
+				counts[tag] += sources
+			}
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating tag results: %w", err)
+	}
+
+	tags := make([]string, 0, len(counts))
+	for tag := range counts {
+		tags = append(tags, tag)
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		if counts[tags[i]] != counts[tags[j]] {
+			return counts[tags[i]] > counts[tags[j]]
+		}
+		return tags[i] < tags[j]
+	})
+
+	var results []QuickSearchResult
+	for i := 0; i < len(tags) && i < limit; i++ {
+		results = append(results, QuickSearchResult{
+			Type:    QuickSearchTag,
+			Title:   tags[i],
+			Snippet: fmt.Sprintf("%d documents", counts[tags[i]]),
+			Target:  "/library?tag=" + url.QueryEscape(tags[i]),
+		})
+	}
+
+	return results, nil
+}
+
+// quickSearchSessions matches the user's session titles by prefix
+// Sessions without a stored title are titled by their first user message
+func (s *Store) quickSearchSessions(ctx context.Context, userID int64, query string, limit int) ([]QuickSearchResult, error) {
+	sqlQuery := `
+		SELECT id, title, last_message_at
+		FROM (
+			SELECT
+				s.id,
+				COALESCE(NULLIF(s.title, ''), (
+					SELECT cm.content FROM chat_messages cm
+					WHERE cm.session_id = s.id AND cm.role = 'user'
+					ORDER BY cm.id LIMIT 1
+				), '') as title,
+				COALESCE(s.last_message_at, s.created_at) as last_message_at
+			FROM sessions s
+			WHERE s.user_id = ?
+		)
+		WHERE title LIKE ? ESCAPE '\'
+		ORDER BY last_message_at DESC
+	`
+
+	rows, err := s.reader().QueryContext(ctx, sqlQuery, userID, likeContains(query))
+	if err != nil {
+		return nil, fmt.Errorf("failed to quick search sessions: %w", err)
+	}
+	defer rows.Close()
+
+	var results []QuickSearchResult
+	for rows.Next() && len(results) < limit {
+		var id, title string
+		var lastMessageAtStr sql.NullString
+		if err := rows.Scan(&id, &title, &lastMessageAtStr); err != nil {
+			return nil, fmt.Errorf("failed to scan session result: %w", err)
+		}
+		if !hasWordPrefix(title, query) {
+			continue
+		}
+
+		result := QuickSearchResult{
+			Type:      QuickSearchSession,
+			Title:     truncateTitle(title, quickSearchTitleLength),
+			Target:    "/chat?session=" + url.QueryEscape(id),
+			SessionID: id,
+		}
+		if lastMessageAtStr.Valid {
+			result.UpdatedAt, _ = time.Parse("2006-01-02 15:04:05", lastMessageAtStr.String)
+		}
+		results = append(results, result)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating session results: %w", err)
+	}
+
+	return results, nil
+}
+
+// quickSearchMessages finds the user's most recent matching messages via full-text search
+func (s *Store) quickSearchMessages(ctx context.Context, userID int64, query string, limit int) ([]QuickSearchResult, error) {
+	dict, err := s.GetSearchDictionary(ctx)
+	if err != nil {
+		return nil, err
+	}
+	match := dict.ftsQuery(query)
+	if match == "" {
+		return nil, nil
+	}
+
+	sqlQuery := `
+		SELECT cm.session_id, cm.role, snippet(chat_messages_fts, 0, '', '', '…', 12), cm.created_at
+		FROM chat_messages_fts
+		JOIN chat_messages cm ON cm.id = chat_messages_fts.rowid
+		WHERE chat_messages_fts MATCH ? AND cm.user_id = ?
+		ORDER BY cm.created_at DESC, cm.id DESC
+		LIMIT ?
+	`
+
+	rows, err := s.reader().QueryContext(ctx, sqlQuery, match, userID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to quick search messages: %w", err)
+	}
+	defer rows.Close()
+
+	var results []QuickSearchResult
+	for rows.Next() {
+		var sessionID, role, snippet, createdAtStr string
+		if err := rows.Scan(&sessionID, &role, &snippet, &createdAtStr); err != nil {
+			return nil, fmt.Errorf("failed to scan message result: %w", err)
+		}
+
+		title := "You"
+		if role == "assistant" {
+			title = "Assistant"
+		}
+
+		result := QuickSearchResult{
+			Type:      QuickSearchMessage,
+			Title:     title,
+			Snippet:   snippet,
+			Target:    "/chat?session=" + url.QueryEscape(sessionID),
+			SessionID: sessionID,
+		}
+		result.UpdatedAt, _ = time.Parse("2006-01-02 15:04:05", createdAtStr)
+		results = append(results, result)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating message results: %w", err)
+	}
+
+	return results, nil
+}
+
+// likeContains builds a LIKE pattern matching values that contain s, escaping wildcards
+func likeContains(s string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return "%" + replacer.Replace(s) + "%"
+}
+
+// hasWordPrefix reports whether value, or any word within it, starts with prefix (case-insensitive)
+// Words are separated by anything that is not a letter or digit, so "docs/leave-policy.md"
+// matches "leave" and "policy" as well as "docs"
+func hasWordPrefix(value, prefix string) bool {
+	value = strings.ToLower(value)
+	prefix = strings.ToLower(prefix)
+
+	if strings.HasPrefix(value, prefix) {
+		return true
+	}
+	prev := rune(0)
+	for i, r := range value {
+		if i > 0 && isWordRune(r) && !isWordRune(prev) && strings.HasPrefix(value[i:], prefix) {
+			return true
+		}
+		prev = r
+	}
+	return false
+}
+
+// isWordRune reports whether r is part of a word
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// truncateTitle shortens s to at most max runes, adding an ellipsis when truncated
+func truncateTitle(s string, max int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+	return string(runes[:max-1]) + "…"
+}
@@ -0,0 +1,140 @@
+package store
+
+import (
+	"context"
+	"os"
+	"testing"
+)
+
+// TestQuickSearch tests the combined document, tag, session and message lookup
+func TestQuickSearch(t *testing.T) {
+	tmpFile := "test_quicksearch.db"
+	defer os.Remove(tmpFile)
+
+	store, err := NewStore(tmpFile, "multi")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+
+	alice, err := store.CreateUser(ctx, "alice", "password", "alice@test.com", false, false)
+	if err != nil {
+		t.Fatalf("Failed to create alice: %v", err)
+	}
+	bob, err := store.CreateUser(ctx, "bob", "password", "bob@test.com", false, false)
+	if err != nil {
+		t.Fatalf("Failed to create bob: %v", err)
+	}
+
+	embedding := []float32{1, 0, 0}
+	if err := store.SaveChunk(ctx, alice, "docs/leave-policy.md", "Leave policy", embedding, []string{"policies", "hr"}, ""); err != nil {
+		t.Fatalf("Failed to save chunk: %v", err)
+	}
+	if err := store.SaveChunk(ctx, alice, "docs/leave-policy.md", "More leave policy", embedding, []string{"policies", "hr"}, ""); err != nil {
+		t.Fatalf("Failed to save chunk: %v", err)
+	}
+	if err := store.SaveChunk(ctx, alice, "notes/apollo.md", "Unrelated", embedding, []string{"projects"}, ""); err != nil {
+		t.Fatalf("Failed to save chunk: %v", err)
+	}
+	if err := store.SaveChunk(ctx, bob, "bob/policy-draft.md", "Bob's private draft", embedding, []string{"policy-drafts"}, ""); err != nil {
+		t.Fatalf("Failed to save chunk: %v", err)
+	}
+
+	if err := store.SaveChatMessage(ctx, alice, "session-a", "user", "How many days of parental leave do we get?", "local"); err != nil {
+		t.Fatalf("Failed to save message: %v", err)
+	}
+	if err := store.SaveChatMessage(ctx, alice, "session-a", "assistant", "Employees receive sixteen weeks of paid parental leave [1].", "local"); err != nil {
+		t.Fatalf("Failed to save message: %v", err)
+	}
+	if err := store.SaveChatMessage(ctx, bob, "session-b", "user", "Parental leave question from bob", "local"); err != nil {
+		t.Fatalf("Failed to save message: %v", err)
+	}
+
+	limits := QuickSearchLimits{Documents: 5, Tags: 5, Sessions: 5, Messages: 5}
+
+	results, err := store.QuickSearch(ctx, alice, "pol", limits)
+	if err != nil {
+		t.Fatalf("QuickSearch failed: %v", err)
+	}
+
+	byType := make(map[string][]QuickSearchResult)
+	for _, r := range results {
+		byType[r.Type] = append(byType[r.Type], r)
+	}
+
+	// Word prefix within the path matches; bob's private document is not visible
+	if len(byType[QuickSearchDocument]) != 1 || byType[QuickSearchDocument][0].Title != "docs/leave-policy.md" {
+		t.Errorf("Expected only alice's leave policy document, got %+v", byType[QuickSearchDocument])
+	}
+	if byType[QuickSearchDocument][0].Target != "/library?source=docs%2Fleave-policy.md" {
+		t.Errorf("Unexpected document target: %s", byType[QuickSearchDocument][0].Target)
+	}
+	if len(byType[QuickSearchTag]) != 1 || byType[QuickSearchTag][0].Title != "policies" {
+		t.Errorf("Expected the policies tag, got %+v", byType[QuickSearchTag])
+	}
+
+	// Session titles fall back to the first user message; messages use full-text prefix search
+	results, err = store.QuickSearch(ctx, alice, "parental lea", limits)
+	if err != nil {
+		t.Fatalf("QuickSearch failed: %v", err)
+	}
+	byType = make(map[string][]QuickSearchResult)
+	for _, r := range results {
+		byType[r.Type] = append(byType[r.Type], r)
+	}
+	if len(byType[QuickSearchSession]) != 1 || byType[QuickSearchSession][0].SessionID != "session-a" {
+		t.Errorf("Expected alice's session, got %+v", byType[QuickSearchSession])
+	}
+	if len(byType[QuickSearchMessage]) != 2 {
+		t.Fatalf("Expected both of alice's messages, got %+v", byType[QuickSearchMessage])
+	}
+	for _, m := range byType[QuickSearchMessage] {
+		if m.SessionID != "session-a" || m.Target != "/chat?session=session-a" {
+			t.Errorf("Expected message results scoped to alice, got %+v", m)
+		}
+		if m.Snippet == "" {
+			t.Errorf("Expected message snippet, got %+v", m)
+		}
+	}
+
+	// Per-type limits are honored and zero skips a type
+	results, err = store.QuickSearch(ctx, alice, "parental", QuickSearchLimits{Messages: 1})
+	if err != nil {
+		t.Fatalf("QuickSearch failed: %v", err)
+	}
+	if len(results) != 1 || results[0].Type != QuickSearchMessage {
+		t.Errorf("Expected a single message result, got %+v", results)
+	}
+
+	// FTS operators in user input are treated literally
+	if _, err := store.QuickSearch(ctx, alice, `leave" OR NEAR(*`, limits); err != nil {
+		t.Errorf("Expected special characters to be handled, got %v", err)
+	}
+
+	results, err = store.QuickSearch(ctx, alice, "   ", limits)
+	if err != nil || len(results) != 0 {
+		t.Errorf("Expected no results for a blank query, got %+v (err %v)", results, err)
+	}
+}
+
+// TestHasWordPrefix tests prefix matching on whole values and word boundaries
+func TestHasWordPrefix(t *testing.T) {
+	tests := []struct {
+		value  string
+		prefix string
+		want   bool
+	}{
+		{"Handbook.md", "hand", true},
+		{"docs/leave-policy.md", "pol", true},
+		{"docs/leave-policy.md", "leave-p", true},
+		{"docs/leave-policy.md", "olicy", false},
+		{"meeting notes", "NOT", true},
+	}
+	for _, tt := range tests {
+		if got := hasWordPrefix(tt.value, tt.prefix); got != tt.want {
+			t.Errorf("hasWordPrefix(%q, %q) = %v, want %v", tt.value, tt.prefix, got, tt.want)
+		}
+	}
+}
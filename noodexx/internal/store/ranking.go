@@ -0,0 +1,138 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// maxRecencyBoost is the largest multiplier bonus a brand-new chunk can receive
+// from the recency modifier (a chunk one half-life old receives half of it)
+const maxRecencyBoost = 0.25
+
+// GetRankingWeights returns the ranking modifiers for a user
+// Users without saved weights get neutral weights (no boost, no tag/source preferences)
+func (s *Store) GetRankingWeights(ctx context.Context, userID int64) (*RankingWeights, error) {
+	query := `
+		SELECT recency_half_life_days, tag_weights, source_weights, updated_at
+		FROM ranking_weights
+		WHERE user_id = ?
+	`
+
+	weights := &RankingWeights{
+		UserID:        userID,
+		TagWeights:    map[string]float64{},
+		SourceWeights: map[string]float64{},
+	}
+
+	var halfLife sql.NullFloat64
+	var tagWeights, sourceWeights sql.NullString
+	var updatedAtStr string
+	err := s.reader().QueryRowContext(ctx, query, userID).Scan(&halfLife, &tagWeights, &sourceWeights, &updatedAtStr)
+	if err == sql.ErrNoRows {
+		return weights, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ranking weights: %w", err)
+	}
+
+	if halfLife.Valid {
+		weights.RecencyHalfLifeDays = halfLife.Float64
+	}
+	if tagWeights.Valid && tagWeights.String != "" {
+		if err := json.Unmarshal([]byte(tagWeights.String), &weights.TagWeights); err != nil {
+			return nil, fmt.Errorf("failed to decode tag weights: %w", err)
+		}
+	}
+	if sourceWeights.Valid && sourceWeights.String != "" {
+		if err := json.Unmarshal([]byte(sourceWeights.String), &weights.SourceWeights); err != nil {
+			return nil, fmt.Errorf("failed to decode source weights: %w", err)
+		}
+	}
+	if updatedAtStr != "" {
+		weights.UpdatedAt, _ = time.Parse("2006-01-02 15:04:05", updatedAtStr)
+	}
+
+	return weights, nil
+}
+
+// SaveRankingWeights stores the ranking modifiers for a user, replacing any previous values
+// Tag and source keys are normalized to lowercase so matching is case-insensitive
+func (s *Store) SaveRankingWeights(ctx context.Context, userID int64, recencyHalfLifeDays float64, tagWeights, sourceWeights map[string]float64) error {
+	tagJSON, err := json.Marshal(normalizeWeightKeys(tagWeights))
+	if err != nil {
+		return fmt.Errorf("failed to encode tag weights: %w", err)
+	}
+	sourceJSON, err := json.Marshal(normalizeWeightKeys(sourceWeights))
+	if err != nil {
+		return fmt.Errorf("failed to encode source weights: %w", err)
+	}
+
+	query := `
+		INSERT INTO ranking_weights (user_id, recency_half_life_days, tag_weights, source_weights, updated_at)
+		VALUES (?, ?, ?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT(user_id) DO UPDATE SET
+			recency_half_life_days = excluded.recency_half_life_days,
+			tag_weights = excluded.tag_weights,
+			source_weights = excluded.source_weights,
+			updated_at = CURRENT_TIMESTAMP
+	`
+
+	_, err = s.db.ExecContext(ctx, query, userID, recencyHalfLifeDays, string(tagJSON), string(sourceJSON))
+	if err != nil {
+		return fmt.Errorf("failed to save ranking weights: %w", err)
+	}
+
+	return nil
+}
+
+// normalizeWeightKeys lowercases and trims weight keys, dropping empty ones
+func normalizeWeightKeys(weights map[string]float64) map[string]float64 {
+	normalized := make(map[string]float64, len(weights))
+	for key, weight := range weights {
+		key = strings.ToLower(strings.TrimSpace(key))
+		if key == "" {
+			continue
+		}
+		normalized[key] = weight
+	}
+	return normalized
+}
+
+// multiplier returns the factor applied to a chunk's similarity score
+// Combines the recency boost with every matching tag and source weight
+func (w *RankingWeights) multiplier(c Chunk, now time.Time) float64 {
+	if w == nil {
+		return 1
+	}
+
+	m := 1.0
+
+	// Recency boost decays by half every RecencyHalfLifeDays
+	if w.RecencyHalfLifeDays > 0 && !c.CreatedAt.IsZero() {
+		ageDays := now.Sub(c.CreatedAt).Hours() / 24
+		if ageDays < 0 {
+			ageDays = 0
+		}
+		m *= 1 + maxRecencyBoost*math.Pow(0.5, ageDays/w.RecencyHalfLifeDays)
+	}
+
+	for _, tag := range c.Tags {
+		if weight, ok := w.TagWeights[strings.ToLower(tag)]; ok {
+			m *= weight
+		}
+	}
+
+	source := strings.ToLower(c.Source)
+	for pattern, weight := range w.SourceWeights {
+		if strings.Contains(source, pattern) {
+			m *= weight
+		}
+	}
+
+	return m
+}
@@ -0,0 +1,143 @@
+package store
+
+import (
+	"context"
+	"math"
+	"os"
+	"testing"
+	"time"
+)
+
+// TestRankingWeightsRoundTrip tests saving and loading per-user ranking weights
+func TestRankingWeightsRoundTrip(t *testing.T) {
+	tmpFile := "test_ranking_weights.db"
+	defer os.Remove(tmpFile)
+
+	store, err := NewStore(tmpFile, "multi")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+
+	userID, err := store.CreateUser(ctx, "ranker", "password", "ranker@test.com", false, false)
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	// Users without saved weights get neutral defaults
+	weights, err := store.GetRankingWeights(ctx, userID)
+	if err != nil {
+		t.Fatalf("GetRankingWeights failed: %v", err)
+	}
+	if weights.RecencyHalfLifeDays != 0 || len(weights.TagWeights) != 0 || len(weights.SourceWeights) != 0 {
+		t.Errorf("Expected neutral default weights, got %+v", weights)
+	}
+
+	err = store.SaveRankingWeights(ctx, userID, 30, map[string]float64{"Policies": 2}, map[string]float64{" Meeting-Notes ": 0.5})
+	if err != nil {
+		t.Fatalf("SaveRankingWeights failed: %v", err)
+	}
+
+	weights, err = store.GetRankingWeights(ctx, userID)
+	if err != nil {
+		t.Fatalf("GetRankingWeights failed: %v", err)
+	}
+	if weights.RecencyHalfLifeDays != 30 {
+		t.Errorf("Expected half-life 30, got %v", weights.RecencyHalfLifeDays)
+	}
+	if weights.TagWeights["policies"] != 2 {
+		t.Errorf("Expected normalized tag weight for 'policies', got %v", weights.TagWeights)
+	}
+	if weights.SourceWeights["meeting-notes"] != 0.5 {
+		t.Errorf("Expected normalized source weight for 'meeting-notes', got %v", weights.SourceWeights)
+	}
+
+	// Saving again replaces the previous values
+	if err := store.SaveRankingWeights(ctx, userID, 0, nil, nil); err != nil {
+		t.Fatalf("SaveRankingWeights failed: %v", err)
+	}
+	weights, err = store.GetRankingWeights(ctx, userID)
+	if err != nil {
+		t.Fatalf("GetRankingWeights failed: %v", err)
+	}
+	if weights.RecencyHalfLifeDays != 0 || len(weights.TagWeights) != 0 {
+		t.Errorf("Expected weights to be replaced, got %+v", weights)
+	}
+}
+
+// TestSearchByUserAppliesRankingWeights tests that tag and source weights reorder results
+func TestSearchByUserAppliesRankingWeights(t *testing.T) {
+	tmpFile := "test_ranking_search.db"
+	defer os.Remove(tmpFile)
+
+	store, err := NewStore(tmpFile, "multi")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+
+	userID, err := store.CreateUser(ctx, "ranker", "password", "ranker@test.com", false, false)
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	queryVec := []float32{1, 0, 0}
+
+	// The meeting notes chunk is slightly more similar to the query than the policy chunk
+	if err := store.SaveChunk(ctx, userID, "notes/meeting-notes.md", "Meeting notes", []float32{1, 0.1, 0}, []string{"meetings"}, ""); err != nil {
+		t.Fatalf("Failed to save chunk: %v", err)
+	}
+	if err := store.SaveChunk(ctx, userID, "docs/handbook.md", "Policy text", []float32{1, 0.3, 0}, []string{"policies"}, ""); err != nil {
+		t.Fatalf("Failed to save chunk: %v", err)
+	}
+
+	results, err := store.SearchByUser(ctx, userID, queryVec, "", 2)
+	if err != nil {
+		t.Fatalf("SearchByUser failed: %v", err)
+	}
+	if len(results) != 2 || results[0].Source != "notes/meeting-notes.md" {
+		t.Fatalf("Expected meeting notes first without weights, got %+v", results)
+	}
+	if results[0].Score <= 0 {
+		t.Errorf("Expected search results to carry a score, got %v", results[0].Score)
+	}
+
+	// Prefer policies over meeting notes
+	if err := store.SaveRankingWeights(ctx, userID, 0, map[string]float64{"policies": 1.5}, map[string]float64{"meeting-notes": 0.5}); err != nil {
+		t.Fatalf("SaveRankingWeights failed: %v", err)
+	}
+
+	results, err = store.SearchByUser(ctx, userID, queryVec, "", 2)
+	if err != nil {
+		t.Fatalf("SearchByUser failed: %v", err)
+	}
+	if len(results) != 2 || results[0].Source != "docs/handbook.md" {
+		t.Errorf("Expected weighted policy chunk first, got %+v", results)
+	}
+}
+
+// TestRankingMultiplierRecency tests the recency half-life decay
+func TestRankingMultiplierRecency(t *testing.T) {
+	now := time.Now()
+	weights := &RankingWeights{RecencyHalfLifeDays: 10}
+
+	fresh := weights.multiplier(Chunk{CreatedAt: now}, now)
+	if math.Abs(fresh-(1+maxRecencyBoost)) > 1e-9 {
+		t.Errorf("Expected full boost for a fresh chunk, got %v", fresh)
+	}
+
+	halfLife := weights.multiplier(Chunk{CreatedAt: now.Add(-10 * 24 * time.Hour)}, now)
+	if math.Abs(halfLife-(1+maxRecencyBoost/2)) > 1e-9 {
+		t.Errorf("Expected half boost after one half-life, got %v", halfLife)
+	}
+
+	// A zero half-life disables the boost entirely
+	disabled := (&RankingWeights{}).multiplier(Chunk{CreatedAt: now}, now)
+	if disabled != 1 {
+		t.Errorf("Expected neutral multiplier when recency is disabled, got %v", disabled)
+	}
+}
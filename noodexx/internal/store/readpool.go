@@ -0,0 +1,43 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// openReadPool opens a second handle on the database for search and library
+// queries, so a burst of searches cannot take every connection writes need.
+// Its connections are query_only, and with WAL they read alongside the writer
+// and see every committed write. In-memory databases are private to their
+// connection, so they keep reading through the primary handle
+func (s *Store) openReadPool(path string, opts Options) error {
+	if path == ":memory:" || strings.Contains(path, "mode=memory") {
+		return nil
+	}
+
+	read, err := sql.Open("sqlite", path+opts.dsnParams()+"&_pragma=query_only(1)")
+	if err != nil {
+		return fmt.Errorf("failed to open read pool: %w", err)
+	}
+	read.SetMaxOpenConns(opts.ReadPoolSize)
+	read.SetMaxIdleConns(opts.ReadPoolSize)
+	read.SetConnMaxLifetime(5 * time.Minute)
+
+	if err := read.Ping(); err != nil {
+		read.Close()
+		return fmt.Errorf("failed to ping read pool: %w", err)
+	}
+	s.read = read
+	return nil
+}
+
+// reader returns the handle for search and library queries: the read pool
+// when one is open, otherwise the primary handle
+func (s *Store) reader() *sql.DB {
+	if s.read != nil {
+		return s.read
+	}
+	return s.db
+}
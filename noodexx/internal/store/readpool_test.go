@@ -0,0 +1,84 @@
+package store
+
+import (
+	"context"
+	"os"
+	"testing"
+)
+
+// TestReadPool tests that search and library queries go through a separate
+// read-only pool that sees every committed write, and that writes stay on the
+// primary handle
+func TestReadPool(t *testing.T) {
+	tmpFile := "test_read_pool.db"
+	defer os.Remove(tmpFile)
+	defer os.Remove(tmpFile + "-wal")
+	defer os.Remove(tmpFile + "-shm")
+
+	opts := DefaultOptions()
+	opts.WritePoolSize = 4
+	opts.ReadPoolSize = 3
+	store, err := NewStoreWithOptions(tmpFile, "multi", opts)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	if store.read == nil || store.reader() != store.read {
+		t.Fatal("Expected reads to use the read pool")
+	}
+	if n := store.db.Stats().MaxOpenConnections; n != 4 {
+		t.Errorf("Expected 4 write connections, got %d", n)
+	}
+	if n := store.read.Stats().MaxOpenConnections; n != 3 {
+		t.Errorf("Expected 3 read connections, got %d", n)
+	}
+
+	ctx := context.Background()
+	userID, err := store.CreateUser(ctx, "reader", "password", "reader@test.com", false, false)
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	if err := store.SaveChunk(ctx, userID, "notes.md", "Notes", []float32{1, 0, 0}, nil, ""); err != nil {
+		t.Fatalf("Failed to save chunk: %v", err)
+	}
+
+	// Writes are visible to the read pool as soon as they commit
+	results, err := store.SearchByUser(ctx, userID, []float32{1, 0, 0}, "", 5)
+	if err != nil {
+		t.Fatalf("SearchByUser failed: %v", err)
+	}
+	if len(results) != 1 || results[0].Source != "notes.md" {
+		t.Errorf("Expected the new chunk in search results, got %+v", results)
+	}
+	library, err := store.LibraryByUser(ctx, userID)
+	if err != nil {
+		t.Fatalf("LibraryByUser failed: %v", err)
+	}
+	if len(library) != 1 {
+		t.Errorf("Expected the new document in the library, got %+v", library)
+	}
+
+	// The read pool cannot write
+	if _, err := store.read.ExecContext(ctx, `DELETE FROM chunks`); err == nil {
+		t.Error("Expected a write through the read pool to fail")
+	}
+}
+
+// TestReadPoolDisabled tests that without a read pool, reads use the primary handle
+func TestReadPoolDisabled(t *testing.T) {
+	tmpFile := "test_read_pool_disabled.db"
+	defer os.Remove(tmpFile)
+	defer os.Remove(tmpFile + "-wal")
+	defer os.Remove(tmpFile + "-shm")
+
+	store, err := NewStore(tmpFile, "single")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	if store.read != nil || store.reader() != store.db {
+		t.Error("Expected reads to use the primary handle")
+	}
+}
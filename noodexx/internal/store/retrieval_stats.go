@@ -0,0 +1,163 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// retrievalDayFormat is the layout of the day a retrieval is counted under, in UTC
+const retrievalDayFormat = "2006-01-02"
+
+// RecordRetrievals counts the sources of one question's search results under
+// today's statistics for their owners. A source counts once per question, with
+// the best score among its chunks
+func (s *Store) RecordRetrievals(ctx context.Context, hits []RetrievalHit) error {
+	if len(hits) == 0 {
+		return nil
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(hits)), ",")
+	args := make([]interface{}, len(hits))
+	for i, h := range hits {
+		args[i] = h.ChunkID
+	}
+	rows, err := s.db.QueryContext(ctx, `SELECT id, user_id, source FROM chunks WHERE id IN (`+placeholders+`) AND user_id IS NOT NULL`, args...)
+	if err != nil {
+		return fmt.Errorf("failed to query retrieved chunks: %w", err)
+	}
+	type sourceKey struct {
+		owner  int64
+		source string
+	}
+	owners := make(map[int64]sourceKey, len(hits))
+	for rows.Next() {
+		var id int64
+		var key sourceKey
+		if err := rows.Scan(&id, &key.owner, &key.source); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan retrieved chunk: %w", err)
+		}
+		owners[id] = key
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating retrieved chunks: %w", err)
+	}
+
+	type sourceHit struct {
+		selected bool
+		best     float64
+	}
+	var order []sourceKey
+	bySource := make(map[sourceKey]*sourceHit)
+	for _, h := range hits {
+		key, ok := owners[h.ChunkID]
+		if !ok {
+			continue
+		}
+		hit, ok := bySource[key]
+		if !ok {
+			hit = &sourceHit{best: h.Score}
+			bySource[key] = hit
+			order = append(order, key)
+		}
+		hit.selected = hit.selected || h.Selected
+		if h.Score > hit.best {
+			hit.best = h.Score
+		}
+	}
+	if len(order) == 0 {
+		return nil
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO source_retrievals (user_id, source, day, candidates, selected, best_score, score_sum)
+		VALUES (?, ?, ?, 1, ?, ?, ?)
+		ON CONFLICT(user_id, source, day) DO UPDATE SET
+			candidates = candidates + 1,
+			selected = selected + excluded.selected,
+			best_score = MAX(best_score, excluded.best_score),
+			score_sum = score_sum + excluded.score_sum
+	`
+	day := time.Now().UTC().Format(retrievalDayFormat)
+	for _, key := range order {
+		hit := bySource[key]
+		selected := 0
+		if hit.selected {
+			selected = 1
+		}
+		if _, err := tx.ExecContext(ctx, query, key.owner, key.source, day, selected, hit.best, hit.best); err != nil {
+			return fmt.Errorf("failed to record retrieval: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit retrievals: %w", err)
+	}
+	return nil
+}
+
+// GetRetrievalStats returns the retrieval statistics of each of a user's sources
+// over the days since a time, including sources that were never retrieved,
+// ordered by source
+func (s *Store) GetRetrievalStats(ctx context.Context, userID int64, since time.Time) ([]SourceRetrieval, error) {
+	query := `
+		SELECT
+			c.source,
+			COUNT(*),
+			MIN(c.created_at),
+			MAX(COALESCE(r.candidates, 0)),
+			MAX(COALESCE(r.selected, 0)),
+			MAX(COALESCE(r.best_score, 0)),
+			MAX(COALESCE(r.score_sum, 0)),
+			MAX(COALESCE(r.last_day, ''))
+		FROM chunks c
+		LEFT JOIN (
+			SELECT source, SUM(candidates) AS candidates, SUM(selected) AS selected,
+				MAX(best_score) AS best_score, SUM(score_sum) AS score_sum, MAX(day) AS last_day
+			FROM source_retrievals
+			WHERE user_id = ? AND day >= ?
+			GROUP BY source
+		) r ON r.source = c.source
+		WHERE c.user_id = ?
+		GROUP BY c.source
+		ORDER BY c.source
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, userID, since.UTC().Format(retrievalDayFormat), userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query retrieval stats: %w", err)
+	}
+	defer rows.Close()
+
+	var stats []SourceRetrieval
+	for rows.Next() {
+		var st SourceRetrieval
+		var createdAt, lastDay string
+		var scoreSum float64
+		if err := rows.Scan(&st.Source, &st.Chunks, &createdAt, &st.Candidates, &st.Selected, &st.BestScore, &scoreSum, &lastDay); err != nil {
+			return nil, fmt.Errorf("failed to scan retrieval stats: %w", err)
+		}
+		if createdAt != "" {
+			st.CreatedAt, _ = time.Parse("2006-01-02 15:04:05", createdAt)
+		}
+		if lastDay != "" {
+			st.LastRetrieved, _ = time.Parse(retrievalDayFormat, lastDay)
+		}
+		if st.Candidates > 0 {
+			st.AvgScore = scoreSum / float64(st.Candidates)
+		}
+		stats = append(stats, st)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating retrieval stats: %w", err)
+	}
+	return stats, nil
+}
@@ -0,0 +1,79 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// TestRetrievalStats tests that retrievals are counted once per question and
+// source, for the owner, and that sources never retrieved are still listed
+func TestRetrievalStats(t *testing.T) {
+	store, err := NewStore(t.TempDir()+"/test_retrieval_stats.db", "multi")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+
+	alice, _ := store.CreateUser(ctx, "alice", "password123", "alice@example.com", false, false)
+	bob, _ := store.CreateUser(ctx, "bob", "password123", "bob@example.com", false, false)
+
+	embedding := []float32{0.1, 0.2}
+	store.SaveChunk(ctx, alice, "guide.md", "Part one", embedding, nil, "")
+	store.SaveChunk(ctx, alice, "guide.md", "Part two", embedding, nil, "")
+	store.SaveChunk(ctx, alice, "old.md", "Stale notes", embedding, nil, "")
+	store.SaveChunk(ctx, bob, "bob.md", "Bob's notes", embedding, nil, "")
+	guide, _ := store.GetSourceChunks(ctx, alice, "guide.md")
+	bobs, _ := store.GetSourceChunks(ctx, bob, "bob.md")
+
+	// Two chunks of guide.md in one question count once, with the best score
+	if err := store.RecordRetrievals(ctx, []RetrievalHit{
+		{ChunkID: guide[0].ID, Score: 0.8, Selected: true},
+		{ChunkID: guide[1].ID, Score: 0.6},
+		{ChunkID: bobs[0].ID, Score: 0.2},
+		{ChunkID: 999999, Score: 0.9, Selected: true},
+	}); err != nil {
+		t.Fatalf("RecordRetrievals failed: %v", err)
+	}
+	if err := store.RecordRetrievals(ctx, []RetrievalHit{{ChunkID: guide[1].ID, Score: 0.4}}); err != nil {
+		t.Fatalf("RecordRetrievals failed: %v", err)
+	}
+
+	stats, err := store.GetRetrievalStats(ctx, alice, time.Now().AddDate(0, 0, -30))
+	if err != nil {
+		t.Fatalf("GetRetrievalStats failed: %v", err)
+	}
+	if len(stats) != 2 {
+		t.Fatalf("Expected alice's 2 sources, got %+v", stats)
+	}
+	g, old := stats[0], stats[1]
+	if g.Source != "guide.md" || g.Chunks != 2 || g.Candidates != 2 || g.Selected != 1 || g.BestScore != 0.8 {
+		t.Errorf("Unexpected guide stats %+v", g)
+	}
+	if g.AvgScore < 0.599 || g.AvgScore > 0.601 {
+		t.Errorf("Expected an average best score of 0.6, got %v", g.AvgScore)
+	}
+	if g.LastRetrieved.IsZero() || g.CreatedAt.IsZero() {
+		t.Errorf("Expected retrieval and creation times, got %+v", g)
+	}
+	if old.Source != "old.md" || old.Candidates != 0 || old.Selected != 0 || !old.LastRetrieved.IsZero() {
+		t.Errorf("Expected old.md never retrieved, got %+v", old)
+	}
+
+	// Days before the window are left out
+	store.db.ExecContext(ctx, `UPDATE source_retrievals SET day = '2000-01-01' WHERE user_id = ?`, alice)
+	stats, _ = store.GetRetrievalStats(ctx, alice, time.Now().AddDate(0, 0, -30))
+	if stats[0].Candidates != 0 {
+		t.Errorf("Expected retrievals outside the window ignored, got %+v", stats[0])
+	}
+
+	// Deleting a document drops its statistics
+	store.DeleteDocument(ctx, bob, "bob.md")
+	var rows int
+	store.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM source_retrievals WHERE user_id = ?`, bob).Scan(&rows)
+	if rows != 0 {
+		t.Errorf("Expected bob's statistics deleted with the document, got %d rows", rows)
+	}
+}
@@ -0,0 +1,124 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// CreateSavedSearch saves a library search for the user and returns its ID.
+// Its query is not embedded yet, see SetSavedSearchEmbedding
+func (s *Store) CreateSavedSearch(ctx context.Context, userID int64, name, query, mode string) (int64, error) {
+	result, err := s.db.ExecContext(ctx, `
+		INSERT INTO saved_searches (user_id, name, query, mode, created_at)
+		VALUES (?, ?, ?, ?, ?)
+	`, userID, name, query, mode, time.Now().UTC())
+	if err != nil {
+		return 0, fmt.Errorf("failed to create saved search: %w", err)
+	}
+	return result.LastInsertId()
+}
+
+// GetSavedSearches returns the user's saved searches, oldest first, without
+// their embeddings
+func (s *Store) GetSavedSearches(ctx context.Context, userID int64) ([]SavedSearch, error) {
+	rows, err := s.reader().QueryContext(ctx, `
+		SELECT id, user_id, name, query, mode, NULL, embedding_model, embedded_at, created_at
+		FROM saved_searches
+		WHERE user_id = ?
+		ORDER BY created_at, id
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query saved searches: %w", err)
+	}
+	return scanSavedSearches(rows)
+}
+
+// GetSavedSearch returns one of the user's saved searches with its embedding,
+// or nil if the user has none with the ID
+func (s *Store) GetSavedSearch(ctx context.Context, userID, id int64) (*SavedSearch, error) {
+	rows, err := s.reader().QueryContext(ctx, `
+		SELECT id, user_id, name, query, mode, embedding, embedding_model, embedded_at, created_at
+		FROM saved_searches
+		WHERE id = ? AND user_id = ?
+	`, id, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query saved search: %w", err)
+	}
+	searches, err := scanSavedSearches(rows)
+	if err != nil || len(searches) == 0 {
+		return nil, err
+	}
+	return &searches[0], nil
+}
+
+// GetStaleSavedSearches returns up to limit saved searches of any user whose
+// query is not embedded with model, never embedded first
+func (s *Store) GetStaleSavedSearches(ctx context.Context, model string, limit int) ([]SavedSearch, error) {
+	rows, err := s.reader().QueryContext(ctx, `
+		SELECT id, user_id, name, query, mode, NULL, embedding_model, embedded_at, created_at
+		FROM saved_searches
+		WHERE embedding IS NULL OR embedding_model != ?
+		ORDER BY embedded_at IS NOT NULL, embedded_at, id
+		LIMIT ?
+	`, model, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query stale saved searches: %w", err)
+	}
+	return scanSavedSearches(rows)
+}
+
+// SetSavedSearchEmbedding stores the embedding of a saved search's query and
+// the model that produced it
+func (s *Store) SetSavedSearchEmbedding(ctx context.Context, id int64, embedding []float32, model string) error {
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE saved_searches SET embedding = ?, embedding_model = ?, embedded_at = ? WHERE id = ?
+	`, serializeEmbedding(embedding), model, time.Now().UTC(), id)
+	if err != nil {
+		return fmt.Errorf("failed to save saved search embedding: %w", err)
+	}
+	return nil
+}
+
+// DeleteSavedSearch deletes one of the user's saved searches. false means the
+// user has none with the ID
+func (s *Store) DeleteSavedSearch(ctx context.Context, userID, id int64) (bool, error) {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM saved_searches WHERE id = ? AND user_id = ?`, id, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete saved search: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	return n > 0, nil
+}
+
+// scanSavedSearches reads saved search rows and closes them
+func scanSavedSearches(rows *sql.Rows) ([]SavedSearch, error) {
+	defer rows.Close()
+
+	var searches []SavedSearch
+	for rows.Next() {
+		var search SavedSearch
+		var embedding []byte
+		var embeddedAt sql.NullTime
+		err := rows.Scan(&search.ID, &search.UserID, &search.Name, &search.Query, &search.Mode,
+			&embedding, &search.EmbeddingModel, &embeddedAt, &search.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan saved search: %w", err)
+		}
+		if len(embedding) > 0 {
+			search.Embedding = deserializeEmbedding(embedding)
+		}
+		if embeddedAt.Valid {
+			search.EmbeddedAt = &embeddedAt.Time
+		}
+		searches = append(searches, search)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating saved searches: %w", err)
+	}
+	return searches, nil
+}
@@ -0,0 +1,87 @@
+package store
+
+import (
+	"context"
+	"os"
+	"testing"
+)
+
+func TestSavedSearches(t *testing.T) {
+	tmpFile := "test_saved_searches.db"
+	defer os.Remove(tmpFile)
+
+	store, err := NewStore(tmpFile, "multi")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+
+	fuserID, err := store.CreateSavedSearch(ctx, 1, "Fuser errors", "E4012 overheating", "keyword")
+	if err != nil {
+		t.Fatalf("CreateSavedSearch failed: %v", err)
+	}
+	leaveID, err := store.CreateSavedSearch(ctx, 1, "Leave", "leave policy", "")
+	if err != nil {
+		t.Fatalf("CreateSavedSearch failed: %v", err)
+	}
+	if _, err := store.CreateSavedSearch(ctx, 2, "Other", "other", ""); err != nil {
+		t.Fatalf("CreateSavedSearch failed: %v", err)
+	}
+
+	// Searches start without embeddings and are stale for any model
+	stale, err := store.GetStaleSavedSearches(ctx, "nomic-embed-text", 10)
+	if err != nil {
+		t.Fatalf("GetStaleSavedSearches failed: %v", err)
+	}
+	if len(stale) != 3 {
+		t.Fatalf("Expected 3 stale searches, got %+v", stale)
+	}
+
+	if err := store.SetSavedSearchEmbedding(ctx, fuserID, []float32{0.5, 0.25}, "nomic-embed-text"); err != nil {
+		t.Fatalf("SetSavedSearchEmbedding failed: %v", err)
+	}
+	search, err := store.GetSavedSearch(ctx, 1, fuserID)
+	if err != nil {
+		t.Fatalf("GetSavedSearch failed: %v", err)
+	}
+	if search == nil || search.Mode != "keyword" || len(search.Embedding) != 2 || search.Embedding[1] != 0.25 ||
+		search.EmbeddingModel != "nomic-embed-text" || search.EmbeddedAt == nil {
+		t.Errorf("Expected the embedded search, got %+v", search)
+	}
+	if search, err := store.GetSavedSearch(ctx, 2, fuserID); err != nil || search != nil {
+		t.Errorf("Expected no search for another user, got %+v, %v", search, err)
+	}
+
+	stale, err = store.GetStaleSavedSearches(ctx, "nomic-embed-text", 10)
+	if err != nil {
+		t.Fatalf("GetStaleSavedSearches failed: %v", err)
+	}
+	if len(stale) != 2 {
+		t.Errorf("Expected 2 stale searches, got %+v", stale)
+	}
+	// Another model makes every embedding stale, never embedded first
+	stale, err = store.GetStaleSavedSearches(ctx, "mxbai-embed-large", 10)
+	if err != nil {
+		t.Fatalf("GetStaleSavedSearches failed: %v", err)
+	}
+	if len(stale) != 3 || stale[2].ID != fuserID {
+		t.Errorf("Expected all 3 stale, the embedded one last, got %+v", stale)
+	}
+
+	searches, err := store.GetSavedSearches(ctx, 1)
+	if err != nil {
+		t.Fatalf("GetSavedSearches failed: %v", err)
+	}
+	if len(searches) != 2 || searches[0].ID != fuserID || searches[0].Embedding != nil || searches[1].EmbeddedAt != nil {
+		t.Errorf("Expected the user's 2 searches without embeddings, got %+v", searches)
+	}
+
+	if deleted, err := store.DeleteSavedSearch(ctx, 2, leaveID); err != nil || deleted {
+		t.Errorf("Expected another user's delete to do nothing, got %v, %v", deleted, err)
+	}
+	if deleted, err := store.DeleteSavedSearch(ctx, 1, leaveID); err != nil || !deleted {
+		t.Errorf("Expected the search deleted, got %v, %v", deleted, err)
+	}
+}
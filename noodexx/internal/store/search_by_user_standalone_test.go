@@ -67,7 +67,7 @@ func TestSearchByUserStandalone(t *testing.T) {
 
 	// Test: User1 should see their own chunk and public chunk (2 total)
 	queryVec := []float32{0.1, 0.2, 0.3, 0.4, 0.5}
-	results, err := store.SearchByUser(ctx, user1ID, queryVec, 10)
+	results, err := store.SearchByUser(ctx, user1ID, queryVec, "", 10)
 	if err != nil {
 		t.Fatalf("SearchByUser failed: %v", err)
 	}
@@ -87,7 +87,7 @@ func TestSearchByUserStandalone(t *testing.T) {
 	}
 
 	// Test: User2 should see their own chunk and public chunk (2 total)
-	results, err = store.SearchByUser(ctx, user2ID, queryVec, 10)
+	results, err = store.SearchByUser(ctx, user2ID, queryVec, "", 10)
 	if err != nil {
 		t.Fatalf("SearchByUser failed for user2: %v", err)
 	}
@@ -85,7 +85,7 @@ func TestSearchByUser(t *testing.T) {
 	// Test 1: User1 should see their own private chunk, public chunk, and shared chunk
 	t.Run("User1 visibility", func(t *testing.T) {
 		queryVec := []float32{0.1, 0.2, 0.3, 0.4, 0.5}
-		results, err := store.SearchByUser(ctx, user1ID, queryVec, 10)
+		results, err := store.SearchByUser(ctx, user1ID, queryVec, "", 10)
 		if err != nil {
 			t.Fatalf("SearchByUser failed for user1: %v", err)
 		}
@@ -129,7 +129,7 @@ func TestSearchByUser(t *testing.T) {
 	// Test 2: User2 should see their own private chunk, public chunk, but NOT the shared chunk (not shared with them)
 	t.Run("User2 visibility", func(t *testing.T) {
 		queryVec := []float32{0.9, 0.8, 0.7, 0.6, 0.5}
-		results, err := store.SearchByUser(ctx, user2ID, queryVec, 10)
+		results, err := store.SearchByUser(ctx, user2ID, queryVec, "", 10)
 		if err != nil {
 			t.Fatalf("SearchByUser failed for user2: %v", err)
 		}
@@ -173,7 +173,7 @@ func TestSearchByUser(t *testing.T) {
 	// Test 3: User3 should only see the public chunk
 	t.Run("User3 visibility", func(t *testing.T) {
 		queryVec := []float32{0.5, 0.5, 0.5, 0.5, 0.5}
-		results, err := store.SearchByUser(ctx, user3ID, queryVec, 10)
+		results, err := store.SearchByUser(ctx, user3ID, queryVec, "", 10)
 		if err != nil {
 			t.Fatalf("SearchByUser failed for user3: %v", err)
 		}
@@ -192,7 +192,7 @@ func TestSearchByUser(t *testing.T) {
 	t.Run("Results sorted by score", func(t *testing.T) {
 		// Query vector identical to embedding1
 		queryVec := []float32{0.1, 0.2, 0.3, 0.4, 0.5}
-		results, err := store.SearchByUser(ctx, user1ID, queryVec, 10)
+		results, err := store.SearchByUser(ctx, user1ID, queryVec, "", 10)
 		if err != nil {
 			t.Fatalf("SearchByUser failed: %v", err)
 		}
@@ -211,7 +211,7 @@ func TestSearchByUser(t *testing.T) {
 	// Test 5: Verify topK limit is respected
 	t.Run("TopK limit", func(t *testing.T) {
 		queryVec := []float32{0.1, 0.2, 0.3, 0.4, 0.5}
-		results, err := store.SearchByUser(ctx, user1ID, queryVec, 2)
+		results, err := store.SearchByUser(ctx, user1ID, queryVec, "", 2)
 		if err != nil {
 			t.Fatalf("SearchByUser failed: %v", err)
 		}
@@ -244,7 +244,7 @@ func TestSearchByUserEmptyResults(t *testing.T) {
 
 	// Search with no chunks in database
 	queryVec := []float32{0.1, 0.2, 0.3}
-	results, err := store.SearchByUser(ctx, userID, queryVec, 10)
+	results, err := store.SearchByUser(ctx, userID, queryVec, "", 10)
 	if err != nil {
 		t.Fatalf("SearchByUser failed: %v", err)
 	}
@@ -254,3 +254,87 @@ func TestSearchByUserEmptyResults(t *testing.T) {
 		t.Errorf("Expected 0 results, got %d", len(results))
 	}
 }
+
+// TestGetSourceChunks tests reading a whole document in order with visibility filtering
+func TestGetSourceChunks(t *testing.T) {
+	tmpFile := "test_get_source_chunks.db"
+	defer os.Remove(tmpFile)
+
+	store, err := NewStore(tmpFile, "multi")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+	user1ID, _ := store.CreateUser(ctx, "user1", "password1", "user1@test.com", false, false)
+	user2ID, _ := store.CreateUser(ctx, "user2", "password2", "user2@test.com", false, false)
+
+	embedding := []float32{0.1, 0.2, 0.3}
+	for i := 1; i <= 3; i++ {
+		if err := store.SaveChunk(ctx, user1ID, "book.md", fmt.Sprintf("part %d", i), embedding, nil, ""); err != nil {
+			t.Fatalf("Failed to save chunk: %v", err)
+		}
+	}
+	if err := store.SaveChunk(ctx, user1ID, "other.md", "other", embedding, nil, ""); err != nil {
+		t.Fatalf("Failed to save chunk: %v", err)
+	}
+
+	chunks, err := store.GetSourceChunks(ctx, user1ID, "book.md")
+	if err != nil {
+		t.Fatalf("GetSourceChunks failed: %v", err)
+	}
+	if len(chunks) != 3 {
+		t.Fatalf("Expected 3 chunks, got %d", len(chunks))
+	}
+	for i, c := range chunks {
+		if c.Text != fmt.Sprintf("part %d", i+1) {
+			t.Errorf("Expected chunks in document order, got %q at %d", c.Text, i)
+		}
+		if len(c.Embedding) != 0 {
+			t.Errorf("Expected no embeddings, got %v", c.Embedding)
+		}
+	}
+
+	// Another user sees the document only once it is public
+	if chunks, _ := store.GetSourceChunks(ctx, user2ID, "book.md"); len(chunks) != 0 {
+		t.Errorf("Expected user2 to see no private chunks, got %d", len(chunks))
+	}
+	if _, err := store.db.ExecContext(ctx, "UPDATE chunks SET visibility = 'public' WHERE source = 'book.md'"); err != nil {
+		t.Fatalf("Failed to publish document: %v", err)
+	}
+	if chunks, _ := store.GetSourceChunks(ctx, user2ID, "book.md"); len(chunks) != 3 {
+		t.Errorf("Expected user2 to see the public document, got %d chunks", len(chunks))
+	}
+}
+
+// TestSearchUserSources tests that a search restricted to sources finds only
+// their visible chunks, and nothing without sources
+func TestSearchUserSources(t *testing.T) {
+	store, err := NewStore(t.TempDir()+"/test_search_sources.db", "multi")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+	user1ID, _ := store.CreateUser(ctx, "user1", "password1", "user1@test.com", false, false)
+	user2ID, _ := store.CreateUser(ctx, "user2", "password2", "user2@test.com", false, false)
+
+	store.SaveChunk(ctx, user1ID, "plan.md", "Plan", []float32{1, 0}, nil, "")
+	store.SaveChunk(ctx, user1ID, "handbook.md", "Handbook", []float32{0.9, 0.1}, nil, "")
+	store.SaveChunk(ctx, user1ID, "other.md", "Other", []float32{1, 0}, nil, "")
+	store.SaveChunk(ctx, user2ID, "private.md", "Private", []float32{1, 0}, nil, "")
+
+	chunks, err := store.SearchUserSources(ctx, user1ID, []float32{1, 0}, "", []string{"plan.md", "handbook.md", "private.md"}, 10)
+	if err != nil {
+		t.Fatalf("SearchUserSources failed: %v", err)
+	}
+	if len(chunks) != 2 || chunks[0].Source != "plan.md" || chunks[1].Source != "handbook.md" {
+		t.Errorf("Expected plan.md then handbook.md, got %+v", chunks)
+	}
+
+	if chunks, err := store.SearchUserSources(ctx, user1ID, []float32{1, 0}, "", nil, 10); err != nil || len(chunks) != 0 {
+		t.Errorf("Expected nothing without sources, got %d (%v)", len(chunks), err)
+	}
+}
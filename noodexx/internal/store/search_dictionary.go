@@ -0,0 +1,204 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Kinds of search dictionary entries
+const (
+	dictionaryStopWord  = "stop_word"
+	dictionaryProtected = "protected"
+	dictionarySynonym   = "synonym"
+)
+
+// SearchDictionary shapes the keyword queries built from what users type.
+// Stop words are left out of queries, protected terms are matched as exact
+// phrases and never left out, and a term with synonyms also matches any of them
+type SearchDictionary struct {
+	StopWords []string
+	Protected []string
+	Synonyms  map[string][]string // Term to the terms it also matches
+}
+
+// GetSearchDictionary returns the search dictionary, empty when none was set
+func (s *Store) GetSearchDictionary(ctx context.Context) (*SearchDictionary, error) {
+	rows, err := s.reader().QueryContext(ctx, `SELECT kind, term, synonym FROM search_dictionary ORDER BY kind, term, synonym`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query search dictionary: %w", err)
+	}
+	defer rows.Close()
+
+	dict := &SearchDictionary{StopWords: []string{}, Protected: []string{}, Synonyms: map[string][]string{}}
+	for rows.Next() {
+		var kind, term, synonym string
+		if err := rows.Scan(&kind, &term, &synonym); err != nil {
+			return nil, fmt.Errorf("failed to scan search dictionary entry: %w", err)
+		}
+		switch kind {
+		case dictionaryStopWord:
+			dict.StopWords = append(dict.StopWords, term)
+		case dictionaryProtected:
+			dict.Protected = append(dict.Protected, term)
+		case dictionarySynonym:
+			dict.Synonyms[term] = append(dict.Synonyms[term], synonym)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating search dictionary: %w", err)
+	}
+	return dict, nil
+}
+
+// SetSearchDictionary replaces the search dictionary. Terms are stored in
+// lower case and with single spaces; duplicates and terms without any letter
+// or digit are dropped
+func (s *Store) SetSearchDictionary(ctx context.Context, dict SearchDictionary) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM search_dictionary`); err != nil {
+		return fmt.Errorf("failed to clear search dictionary: %w", err)
+	}
+
+	insert := func(kind, term, synonym string) error {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO search_dictionary (kind, term, synonym) VALUES (?, ?, ?)
+			ON CONFLICT(kind, term, synonym) DO NOTHING
+		`, kind, term, synonym)
+		if err != nil {
+			return fmt.Errorf("failed to save search dictionary entry: %w", err)
+		}
+		return nil
+	}
+	for _, word := range dict.StopWords {
+		if term := dictionaryTerm(word); term != "" {
+			if err := insert(dictionaryStopWord, term, ""); err != nil {
+				return err
+			}
+		}
+	}
+	for _, phrase := range dict.Protected {
+		if term := dictionaryTerm(phrase); term != "" {
+			if err := insert(dictionaryProtected, term, ""); err != nil {
+				return err
+			}
+		}
+	}
+	for key, synonyms := range dict.Synonyms {
+		term := dictionaryTerm(key)
+		if term == "" {
+			continue
+		}
+		for _, synonym := range synonyms {
+			if synonym = dictionaryTerm(synonym); synonym != "" && synonym != term {
+				if err := insert(dictionarySynonym, term, synonym); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// dictionaryTerm normalizes a dictionary term to its lower-case words joined
+// by single spaces, the way they are compared with what users type
+func dictionaryTerm(term string) string {
+	return strings.Join(queryWords(strings.ToLower(term)), " ")
+}
+
+// queryWords splits text into words, separated by anything that is not a
+// letter or digit
+func queryWords(text string) []string {
+	return strings.FieldsFunc(text, func(r rune) bool { return !isWordRune(r) })
+}
+
+// ftsQuery converts free text into an FTS5 query where every word must match
+// as a prefix, applying the dictionary: stop words are dropped unless the text
+// has nothing else, protected terms become exact phrases, and terms with
+// synonyms match any of them. Words are quoted so FTS5 operators and
+// punctuation in user input are treated literally
+func (d *SearchDictionary) ftsQuery(query string) string {
+	return strings.Join(d.ftsTerms(query), " ")
+}
+
+// ftsAnyQuery is ftsQuery for ranking: text matches when it has any of the
+// words, and BM25 scores it higher the more it has and the rarer they are
+func (d *SearchDictionary) ftsAnyQuery(query string) string {
+	return strings.Join(d.ftsTerms(query), " OR ")
+}
+
+// ftsTerms returns the terms of ftsQuery, each a quoted word or phrase or a
+// parenthesized group of synonyms
+func (d *SearchDictionary) ftsTerms(query string) []string {
+	words := queryWords(query)
+	if d == nil {
+		d = &SearchDictionary{}
+	}
+
+	stopWords := make(map[string]bool, len(d.StopWords))
+	for _, word := range d.StopWords {
+		stopWords[word] = true
+	}
+	protected := make(map[string]bool, len(d.Protected))
+	for _, term := range d.Protected {
+		protected[term] = true
+	}
+	// Terms of several words are matched longest first
+	maxWords := 1
+	for term := range protected {
+		maxWords = max(maxWords, strings.Count(term, " ")+1)
+	}
+	for term := range d.Synonyms {
+		maxWords = max(maxWords, strings.Count(term, " ")+1)
+	}
+
+	var terms, stopped []string
+	for i := 0; i < len(words); {
+		n, term := 1, strings.ToLower(words[i])
+		for size := min(maxWords, len(words)-i); size > 1; size-- {
+			candidate := strings.ToLower(strings.Join(words[i:i+size], " "))
+			if protected[candidate] || d.Synonyms[candidate] != nil {
+				n, term = size, candidate
+				break
+			}
+		}
+		i += n
+
+		match := ftsTerm(term, !protected[term])
+		if synonyms := d.Synonyms[term]; len(synonyms) > 0 {
+			alternatives := []string{match}
+			for _, synonym := range synonyms {
+				alternatives = append(alternatives, ftsTerm(synonym, !protected[synonym]))
+			}
+			match = "(" + strings.Join(alternatives, " OR ") + ")"
+		}
+
+		if stopWords[term] && !protected[term] {
+			stopped = append(stopped, match)
+			continue
+		}
+		terms = append(terms, match)
+	}
+	if len(terms) == 0 {
+		terms = stopped
+	}
+	return terms
+}
+
+// ftsTerm quotes a term for an FTS5 query, matching its last word as a prefix
+func ftsTerm(term string, prefix bool) string {
+	quoted := `"` + term + `"`
+	if prefix {
+		quoted += "*"
+	}
+	return quoted
+}
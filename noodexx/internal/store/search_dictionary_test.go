@@ -0,0 +1,114 @@
+package store
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestSearchDictionaryFTSQuery(t *testing.T) {
+	dict := &SearchDictionary{
+		StopWords: []string{"the", "of", "it"},
+		Protected: []string{"it", "section 230"},
+		Synonyms: map[string][]string{
+			"mi":           {"myocardial infarction", "heart attack"},
+			"heart attack": {"mi"},
+		},
+	}
+
+	tests := []struct {
+		name  string
+		dict  *SearchDictionary
+		query string
+		want  string
+	}{
+		{"no dictionary", nil, "Leave policy!", `"leave"* "policy"*`},
+		{"operators are literal", nil, `NEAR(a OR "b")`, `"near"* "a"* "or"* "b"*`},
+		{"stop words are dropped", dict, "the cost of leave", `"cost"* "leave"*`},
+		{"only stop words are kept", dict, "the of", `"the"* "of"*`},
+		{"protected words are exact and kept", dict, "IT budget", `"it" "budget"*`},
+		{"protected phrases are exact", dict, "Section 230 claims", `"section 230" "claims"*`},
+		{"synonyms expand", dict, "MI risk", `("mi"* OR "myocardial infarction"* OR "heart attack"*) "risk"*`},
+		{"phrase synonyms expand", dict, "heart-attack", `("heart attack"* OR "mi"*)`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.dict.ftsQuery(tt.query); got != tt.want {
+				t.Errorf("ftsQuery(%q) = %s, want %s", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestSearchDictionary tests saving the dictionary and that quick search applies it
+func TestSearchDictionary(t *testing.T) {
+	tmpFile := "test_search_dictionary.db"
+	defer os.Remove(tmpFile)
+
+	store, err := NewStore(tmpFile, "multi")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+
+	dict, err := store.GetSearchDictionary(ctx)
+	if err != nil {
+		t.Fatalf("GetSearchDictionary failed: %v", err)
+	}
+	if len(dict.StopWords) != 0 || len(dict.Protected) != 0 || len(dict.Synonyms) != 0 {
+		t.Fatalf("Expected an empty dictionary, got %+v", dict)
+	}
+
+	err = store.SetSearchDictionary(ctx, SearchDictionary{
+		StopWords: []string{"The", "the", "  "},
+		Protected: []string{"Section  230"},
+		Synonyms:  map[string][]string{"MI": {"Heart attack", "mi"}},
+	})
+	if err != nil {
+		t.Fatalf("SetSearchDictionary failed: %v", err)
+	}
+	dict, err = store.GetSearchDictionary(ctx)
+	if err != nil {
+		t.Fatalf("GetSearchDictionary failed: %v", err)
+	}
+	want := &SearchDictionary{
+		StopWords: []string{"the"},
+		Protected: []string{"section 230"},
+		Synonyms:  map[string][]string{"mi": {"heart attack"}},
+	}
+	if !reflect.DeepEqual(dict, want) {
+		t.Errorf("Expected normalized entries %+v, got %+v", want, dict)
+	}
+
+	userID, err := store.CreateUser(ctx, "carol", "password", "carol@test.com", false, false)
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	if err := store.SaveChatMessage(ctx, userID, "session-a", "user", "What are the signs of a heart attack?", "local"); err != nil {
+		t.Fatalf("Failed to save message: %v", err)
+	}
+
+	// "MI" finds the message through its synonym, and "the" is left out
+	results, err := store.QuickSearch(ctx, userID, "the MI", QuickSearchLimits{Messages: 5})
+	if err != nil {
+		t.Fatalf("QuickSearch failed: %v", err)
+	}
+	if len(results) != 1 || results[0].SessionID != "session-a" {
+		t.Errorf("Expected the message found through the synonym, got %+v", results)
+	}
+
+	// Replacing the dictionary removes the synonym
+	if err := store.SetSearchDictionary(ctx, SearchDictionary{}); err != nil {
+		t.Fatalf("SetSearchDictionary failed: %v", err)
+	}
+	results, err = store.QuickSearch(ctx, userID, "MI", QuickSearchLimits{Messages: 5})
+	if err != nil {
+		t.Fatalf("QuickSearch failed: %v", err)
+	}
+	if len(results) != 0 {
+		t.Errorf("Expected no match without the synonym, got %+v", results)
+	}
+}
@@ -0,0 +1,129 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// CreateSessionLink starts a new session that continues an earlier one of the
+// same user, recording the summary of the earlier session it is given as context
+func (s *Store) CreateSessionLink(ctx context.Context, link *SessionLink) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var owner int64
+	err = tx.QueryRowContext(ctx, `SELECT user_id FROM sessions WHERE id = ?`, link.ContinuedFrom).Scan(&owner)
+	if err == sql.ErrNoRows || (err == nil && owner != link.UserID) {
+		return fmt.Errorf("session not found or access denied: %s", link.ContinuedFrom)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get linked session: %w", err)
+	}
+
+	// The new session exists before its first message so ownership checks apply to it
+	_, err = tx.ExecContext(ctx, `INSERT INTO sessions (id, user_id, last_message_at) VALUES (?, ?, CURRENT_TIMESTAMP)`, link.SessionID, link.UserID)
+	if err != nil {
+		return fmt.Errorf("failed to create session: %w", err)
+	}
+
+	query := `
+		INSERT INTO session_links (session_id, user_id, continued_from, summary, model)
+		VALUES (?, ?, ?, ?, ?)
+	`
+	if _, err := tx.ExecContext(ctx, query, link.SessionID, link.UserID, link.ContinuedFrom, link.Summary, link.Model); err != nil {
+		return fmt.Errorf("failed to create session link: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// GetSessionLink returns the earlier session a user's session continues, or nil
+// if it was not started from another session
+func (s *Store) GetSessionLink(ctx context.Context, userID int64, sessionID string) (*SessionLink, error) {
+	query := `
+		SELECT session_id, user_id, continued_from, summary, model, created_at
+		FROM session_links
+		WHERE session_id = ? AND user_id = ?
+	`
+	var link SessionLink
+	err := s.db.QueryRowContext(ctx, query, sessionID, userID).Scan(&link.SessionID, &link.UserID, &link.ContinuedFrom, &link.Summary, &link.Model, &link.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session link: %w", err)
+	}
+	return &link, nil
+}
+
+// GetSessionSummary returns the rolling summary of a user's session, or nil if
+// the session has not been compacted
+func (s *Store) GetSessionSummary(ctx context.Context, userID int64, sessionID string) (*SessionSummary, error) {
+	query := `
+		SELECT session_id, user_id, summary, through_message_id, model, updated_at
+		FROM session_summaries
+		WHERE session_id = ? AND user_id = ?
+	`
+	var summary SessionSummary
+	err := s.db.QueryRowContext(ctx, query, sessionID, userID).Scan(&summary.SessionID, &summary.UserID, &summary.Summary, &summary.ThroughMessageID, &summary.Model, &summary.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session summary: %w", err)
+	}
+	return &summary, nil
+}
+
+// SaveSessionSummary creates or replaces the rolling summary of a session
+func (s *Store) SaveSessionSummary(ctx context.Context, summary *SessionSummary) error {
+	query := `
+		INSERT INTO session_summaries (session_id, user_id, summary, through_message_id, model, updated_at)
+		VALUES (?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT(session_id) DO UPDATE SET
+			summary = excluded.summary,
+			through_message_id = excluded.through_message_id,
+			model = excluded.model,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE session_summaries.user_id = excluded.user_id
+	`
+	_, err := s.db.ExecContext(ctx, query, summary.SessionID, summary.UserID, summary.Summary, summary.ThroughMessageID, summary.Model)
+	if err != nil {
+		return fmt.Errorf("failed to save session summary: %w", err)
+	}
+	return nil
+}
+
+// GetSessionsToCompact returns sessions with no message since idleBefore that
+// have more than keep messages their rolling summary does not cover
+func (s *Store) GetSessionsToCompact(ctx context.Context, idleBefore time.Time, keep int) ([]SessionToCompact, error) {
+	query := `
+		SELECT cm.session_id, cm.user_id
+		FROM chat_messages cm
+		LEFT JOIN session_summaries ss ON ss.session_id = cm.session_id
+		WHERE cm.user_id IS NOT NULL AND cm.id > COALESCE(ss.through_message_id, 0)
+		GROUP BY cm.session_id, cm.user_id
+		HAVING COUNT(*) > ? AND MAX(cm.created_at) < ?
+		ORDER BY MAX(cm.created_at) ASC
+	`
+	rows, err := s.db.QueryContext(ctx, query, keep, idleBefore.UTC().Format("2006-01-02 15:04:05"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query sessions to compact: %w", err)
+	}
+	defer rows.Close()
+
+	var sessions []SessionToCompact
+	for rows.Next() {
+		var session SessionToCompact
+		if err := rows.Scan(&session.SessionID, &session.UserID); err != nil {
+			return nil, fmt.Errorf("failed to scan session: %w", err)
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, rows.Err()
+}
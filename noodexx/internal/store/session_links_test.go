@@ -0,0 +1,143 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// TestSessionLink tests starting a session from an earlier one and listing the link
+func TestSessionLink(t *testing.T) {
+	store, err := NewStore(t.TempDir()+"/test_session_links.db", "multi")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+
+	alice, _ := store.CreateUser(ctx, "alice", "password123", "alice@example.com", false, false)
+	bob, _ := store.CreateUser(ctx, "bob", "password123", "bob@example.com", false, false)
+
+	if err := store.SaveChatMessage(ctx, alice, "old-session", "user", "What is our refund policy?", ""); err != nil {
+		t.Fatalf("Failed to save message: %v", err)
+	}
+
+	// Only the owner of the earlier session can continue it
+	err = store.CreateSessionLink(ctx, &SessionLink{SessionID: "bob-session", UserID: bob, ContinuedFrom: "old-session", Summary: "stolen"})
+	if err == nil {
+		t.Fatal("Expected continuing another user's session to fail")
+	}
+	if owner, _ := store.GetSessionOwner(ctx, "bob-session"); owner != 0 {
+		t.Errorf("Expected no session to be created, got owner %d", owner)
+	}
+
+	link := &SessionLink{SessionID: "new-session", UserID: alice, ContinuedFrom: "old-session", Summary: "Discussed the refund policy.", Model: "llama3.2"}
+	if err := store.CreateSessionLink(ctx, link); err != nil {
+		t.Fatalf("CreateSessionLink failed: %v", err)
+	}
+
+	owner, err := store.GetSessionOwner(ctx, "new-session")
+	if err != nil || owner != alice {
+		t.Errorf("Expected the new session to belong to alice, got %d, %v", owner, err)
+	}
+
+	got, err := store.GetSessionLink(ctx, alice, "new-session")
+	if err != nil || got == nil {
+		t.Fatalf("GetSessionLink failed: %+v, %v", got, err)
+	}
+	if got.ContinuedFrom != "old-session" || got.Summary != link.Summary || got.Model != "llama3.2" {
+		t.Errorf("Unexpected link %+v", got)
+	}
+	if got, _ := store.GetSessionLink(ctx, bob, "new-session"); got != nil {
+		t.Errorf("Expected no link for another user, got %+v", got)
+	}
+	if got, _ := store.GetSessionLink(ctx, alice, "old-session"); got != nil {
+		t.Errorf("Expected no link for a session started from scratch, got %+v", got)
+	}
+
+	sessions, err := store.GetUserSessions(ctx, alice)
+	if err != nil {
+		t.Fatalf("GetUserSessions failed: %v", err)
+	}
+	continued := make(map[string]string)
+	for _, s := range sessions {
+		continued[s.ID] = s.ContinuedFrom
+	}
+	if len(sessions) != 2 || continued["new-session"] != "old-session" || continued["old-session"] != "" {
+		t.Errorf("Unexpected sessions %+v", sessions)
+	}
+}
+
+// TestSessionSummary tests saving rolling summaries and finding idle sessions
+// with messages left to compact
+func TestSessionSummary(t *testing.T) {
+	store, err := NewStore(t.TempDir()+"/test_session_summaries.db", "multi")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+
+	alice, _ := store.CreateUser(ctx, "alice", "password123", "alice@example.com", false, false)
+	for i := 0; i < 5; i++ {
+		if err := store.SaveChatMessage(ctx, alice, "long-session", "user", "question", ""); err != nil {
+			t.Fatalf("Failed to save message: %v", err)
+		}
+	}
+	if err := store.SaveChatMessage(ctx, alice, "short-session", "user", "question", ""); err != nil {
+		t.Fatalf("Failed to save message: %v", err)
+	}
+
+	// Sessions still in use are left alone
+	sessions, err := store.GetSessionsToCompact(ctx, time.Now().Add(-time.Hour), 2)
+	if err != nil {
+		t.Fatalf("GetSessionsToCompact failed: %v", err)
+	}
+	if len(sessions) != 0 {
+		t.Errorf("Expected no idle sessions, got %+v", sessions)
+	}
+
+	sessions, err = store.GetSessionsToCompact(ctx, time.Now().Add(time.Minute), 2)
+	if err != nil {
+		t.Fatalf("GetSessionsToCompact failed: %v", err)
+	}
+	if len(sessions) != 1 || sessions[0].SessionID != "long-session" || sessions[0].UserID != alice {
+		t.Fatalf("Expected only long-session to need compacting, got %+v", sessions)
+	}
+
+	if got, err := store.GetSessionSummary(ctx, alice, "long-session"); err != nil || got != nil {
+		t.Errorf("Expected no summary before compaction, got %+v, %v", got, err)
+	}
+
+	messages, _ := store.GetSessionMessages(ctx, alice, "long-session")
+	summary := &SessionSummary{SessionID: "long-session", UserID: alice, Summary: "Three questions.", ThroughMessageID: messages[2].ID, Model: "llama3.2"}
+	if err := store.SaveSessionSummary(ctx, summary); err != nil {
+		t.Fatalf("SaveSessionSummary failed: %v", err)
+	}
+
+	// Two uncovered messages are within what is kept
+	sessions, _ = store.GetSessionsToCompact(ctx, time.Now().Add(time.Minute), 2)
+	if len(sessions) != 0 {
+		t.Errorf("Expected the summary to cover the session, got %+v", sessions)
+	}
+
+	summary.Summary = "Four questions."
+	summary.ThroughMessageID = messages[3].ID
+	if err := store.SaveSessionSummary(ctx, summary); err != nil {
+		t.Fatalf("SaveSessionSummary failed: %v", err)
+	}
+	got, err := store.GetSessionSummary(ctx, alice, "long-session")
+	if err != nil || got == nil {
+		t.Fatalf("GetSessionSummary failed: %+v, %v", got, err)
+	}
+	if got.Summary != "Four questions." || got.ThroughMessageID != messages[3].ID || got.Model != "llama3.2" {
+		t.Errorf("Unexpected summary %+v", got)
+	}
+
+	// Messages are kept for display
+	if messages, _ := store.GetSessionMessages(ctx, alice, "long-session"); len(messages) != 5 {
+		t.Errorf("Expected all 5 messages to be kept, got %d", len(messages))
+	}
+}
@@ -0,0 +1,37 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// GetSessionMode returns the provider mode a user's session is answered in,
+// "local" or "cloud", or "" if it has none yet or the session is not theirs
+func (s *Store) GetSessionMode(ctx context.Context, userID int64, sessionID string) (string, error) {
+	var mode string
+	err := s.db.QueryRowContext(ctx, `SELECT provider_mode FROM sessions WHERE id = ? AND user_id = ?`, sessionID, userID).Scan(&mode)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get session mode: %w", err)
+	}
+	return mode, nil
+}
+
+// SetSessionMode sets the provider mode of a user's existing session, "local"
+// or "cloud"
+func (s *Store) SetSessionMode(ctx context.Context, userID int64, sessionID, mode string) error {
+	if mode != "local" && mode != "cloud" {
+		return fmt.Errorf("invalid session mode: %s", mode)
+	}
+	result, err := s.db.ExecContext(ctx, `UPDATE sessions SET provider_mode = ? WHERE id = ? AND user_id = ?`, mode, sessionID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to set session mode: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("session not found or access denied: %s", sessionID)
+	}
+	return nil
+}
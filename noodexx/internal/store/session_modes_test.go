@@ -0,0 +1,59 @@
+package store
+
+import (
+	"context"
+	"testing"
+)
+
+// TestSessionMode tests recording the provider mode a session is answered in
+func TestSessionMode(t *testing.T) {
+	store, err := NewStore(t.TempDir()+"/test_session_modes.db", "multi")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+
+	alice, _ := store.CreateUser(ctx, "alice", "password123", "alice@example.com", false, false)
+	bob, _ := store.CreateUser(ctx, "bob", "password123", "bob@example.com", false, false)
+
+	if err := store.SaveChatMessage(ctx, alice, "s1", "user", "Hello", ""); err != nil {
+		t.Fatalf("Failed to save message: %v", err)
+	}
+	if mode, err := store.GetSessionMode(ctx, alice, "s1"); err != nil || mode != "" {
+		t.Errorf("Expected no mode for a new session, got %q, %v", mode, err)
+	}
+
+	if err := store.SetSessionMode(ctx, alice, "s1", "local"); err != nil {
+		t.Fatalf("SetSessionMode failed: %v", err)
+	}
+	if mode, _ := store.GetSessionMode(ctx, alice, "s1"); mode != "local" {
+		t.Errorf("Expected local, got %q", mode)
+	}
+
+	// Saving later messages keeps the mode
+	if err := store.SaveChatMessage(ctx, alice, "s1", "assistant", "Hi", "local"); err != nil {
+		t.Fatalf("Failed to save message: %v", err)
+	}
+	if mode, _ := store.GetSessionMode(ctx, alice, "s1"); mode != "local" {
+		t.Errorf("Expected the mode kept, got %q", mode)
+	}
+
+	// Other users can neither see nor change it, and sessions must exist
+	if mode, _ := store.GetSessionMode(ctx, bob, "s1"); mode != "" {
+		t.Errorf("Expected no mode for another user, got %q", mode)
+	}
+	if err := store.SetSessionMode(ctx, bob, "s1", "cloud"); err == nil {
+		t.Error("Expected changing another user's session to fail")
+	}
+	if err := store.SetSessionMode(ctx, alice, "missing", "cloud"); err == nil {
+		t.Error("Expected changing a missing session to fail")
+	}
+	if err := store.SetSessionMode(ctx, alice, "s1", "hybrid"); err == nil {
+		t.Error("Expected an invalid mode to fail")
+	}
+	if mode, _ := store.GetSessionMode(ctx, alice, "s1"); mode != "local" {
+		t.Errorf("Expected the mode unchanged, got %q", mode)
+	}
+}
@@ -0,0 +1,190 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// sessionShareColumns is the column list shared by session share queries
+const sessionShareColumns = `s.id, s.token, s.session_id, s.user_id, s.password_hash, s.expires_at, s.revoked_at, s.created_at,
+	(SELECT COUNT(*) FROM session_share_views v WHERE v.share_id = s.id)`
+
+// CreateSessionShare stores a share link and returns its ID
+// The session must belong to the share's user
+func (s *Store) CreateSessionShare(ctx context.Context, share *SessionShare) (int64, error) {
+	query := `
+		INSERT INTO session_shares (token, session_id, user_id, password_hash, expires_at)
+		SELECT ?, id, user_id, ?, ?
+		FROM sessions
+		WHERE id = ? AND user_id = ?
+	`
+
+	var passwordHash, expiresAt interface{}
+	if share.PasswordHash != "" {
+		passwordHash = share.PasswordHash
+	}
+	if !share.ExpiresAt.IsZero() {
+		expiresAt = share.ExpiresAt.UTC()
+	}
+
+	result, err := s.db.ExecContext(ctx, query, share.Token, passwordHash, expiresAt, share.SessionID, share.UserID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create session share: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return 0, fmt.Errorf("session not found or access denied: %s", share.SessionID)
+	}
+
+	return result.LastInsertId()
+}
+
+// GetSessionShareByToken returns the share link for a token, or nil if there is none
+// Revoked and expired links are returned; callers decide whether they may be viewed
+func (s *Store) GetSessionShareByToken(ctx context.Context, token string) (*SessionShare, error) {
+	query := `SELECT ` + sessionShareColumns + ` FROM session_shares s WHERE s.token = ?`
+
+	share, err := scanSessionShare(s.db.QueryRowContext(ctx, query, token))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return share, nil
+}
+
+// GetSessionShares returns a user's share links, newest first
+// An empty sessionID returns the links for all of the user's sessions
+func (s *Store) GetSessionShares(ctx context.Context, userID int64, sessionID string) ([]SessionShare, error) {
+	query := `SELECT ` + sessionShareColumns + ` FROM session_shares s WHERE s.user_id = ?`
+	args := []interface{}{userID}
+	if sessionID != "" {
+		query += ` AND s.session_id = ?`
+		args = append(args, sessionID)
+	}
+	query += ` ORDER BY s.created_at DESC, s.id DESC`
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query session shares: %w", err)
+	}
+	defer rows.Close()
+
+	var shares []SessionShare
+	for rows.Next() {
+		share, err := scanSessionShare(rows)
+		if err != nil {
+			return nil, err
+		}
+		shares = append(shares, *share)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating session shares: %w", err)
+	}
+
+	return shares, nil
+}
+
+// RevokeSessionShare disables a user's share link; revoking twice keeps the first time
+func (s *Store) RevokeSessionShare(ctx context.Context, userID, shareID int64) error {
+	query := `UPDATE session_shares SET revoked_at = COALESCE(revoked_at, ?) WHERE id = ? AND user_id = ?`
+
+	result, err := s.db.ExecContext(ctx, query, time.Now().UTC(), shareID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke session share: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("session share not found or access denied: %d", shareID)
+	}
+	return nil
+}
+
+// RecordSessionShareView logs a view of a shared session
+func (s *Store) RecordSessionShareView(ctx context.Context, shareID int64, ipAddress, userAgent string) error {
+	query := `INSERT INTO session_share_views (share_id, ip_address, user_agent) VALUES (?, ?, ?)`
+	if _, err := s.db.ExecContext(ctx, query, shareID, ipAddress, userAgent); err != nil {
+		return fmt.Errorf("failed to record session share view: %w", err)
+	}
+	return nil
+}
+
+// GetSessionShareViews returns the most recent views of one of a user's share links
+func (s *Store) GetSessionShareViews(ctx context.Context, userID, shareID int64, limit int) ([]SessionShareView, error) {
+	query := `
+		SELECT v.id, v.share_id, COALESCE(v.ip_address, ''), COALESCE(v.user_agent, ''), v.viewed_at
+		FROM session_share_views v
+		JOIN session_shares s ON s.id = v.share_id
+		WHERE v.share_id = ? AND s.user_id = ?
+		ORDER BY v.viewed_at DESC, v.id DESC
+		LIMIT ?
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, shareID, userID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query session share views: %w", err)
+	}
+	defer rows.Close()
+
+	var views []SessionShareView
+	for rows.Next() {
+		var view SessionShareView
+		if err := rows.Scan(&view.ID, &view.ShareID, &view.IPAddress, &view.UserAgent, &view.ViewedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan session share view: %w", err)
+		}
+		views = append(views, view)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating session share views: %w", err)
+	}
+
+	return views, nil
+}
+
+// scanSessionShare reads one share link in sessionShareColumns order
+func scanSessionShare(row rowScanner) (*SessionShare, error) {
+	var share SessionShare
+	var passwordHash sql.NullString
+	var expiresAt, revokedAt sql.NullTime
+
+	err := row.Scan(
+		&share.ID,
+		&share.Token,
+		&share.SessionID,
+		&share.UserID,
+		&passwordHash,
+		&expiresAt,
+		&revokedAt,
+		&share.CreatedAt,
+		&share.ViewCount,
+	)
+	if err == sql.ErrNoRows {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan session share: %w", err)
+	}
+
+	share.PasswordHash = passwordHash.String
+	if expiresAt.Valid {
+		share.ExpiresAt = expiresAt.Time
+	}
+	if revokedAt.Valid {
+		share.RevokedAt = revokedAt.Time
+	}
+
+	return &share, nil
+}
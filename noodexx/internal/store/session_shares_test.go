@@ -0,0 +1,86 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// TestSessionShares tests creating, listing, revoking and viewing share links
+func TestSessionShares(t *testing.T) {
+	store, err := NewStore(t.TempDir()+"/test_shares.db", "multi")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+
+	ownerID, err := store.CreateUser(ctx, "owner", "password123", "owner@example.com", false, false)
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	otherID, err := store.CreateUser(ctx, "other", "password123", "other@example.com", false, false)
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	if err := store.SaveChatMessage(ctx, ownerID, "session-1", "user", "Hello", ""); err != nil {
+		t.Fatalf("Failed to save message: %v", err)
+	}
+
+	// Only the session owner can share it
+	if _, err := store.CreateSessionShare(ctx, &SessionShare{Token: "t0", SessionID: "session-1", UserID: otherID}); err == nil {
+		t.Error("Expected error sharing another user's session")
+	}
+
+	expires := time.Now().Add(24 * time.Hour).UTC().Truncate(time.Second)
+	shareID, err := store.CreateSessionShare(ctx, &SessionShare{
+		Token:        "token-1",
+		SessionID:    "session-1",
+		UserID:       ownerID,
+		PasswordHash: "hash",
+		ExpiresAt:    expires,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create share: %v", err)
+	}
+
+	share, err := store.GetSessionShareByToken(ctx, "token-1")
+	if err != nil || share == nil {
+		t.Fatalf("Failed to get share: %v", err)
+	}
+	if share.ID != shareID || share.PasswordHash != "hash" || !share.ExpiresAt.Equal(expires) || !share.RevokedAt.IsZero() {
+		t.Errorf("Unexpected share: %+v", share)
+	}
+	if missing, err := store.GetSessionShareByToken(ctx, "nope"); err != nil || missing != nil {
+		t.Errorf("Expected nil for unknown token, got %+v, %v", missing, err)
+	}
+
+	// Views are counted and only visible to the owner
+	store.RecordSessionShareView(ctx, shareID, "10.0.0.1", "test-agent")
+	store.RecordSessionShareView(ctx, shareID, "10.0.0.2", "test-agent")
+	views, err := store.GetSessionShareViews(ctx, ownerID, shareID, 10)
+	if err != nil || len(views) != 2 {
+		t.Fatalf("Expected 2 views, got %d (%v)", len(views), err)
+	}
+	if views, _ := store.GetSessionShareViews(ctx, otherID, shareID, 10); len(views) != 0 {
+		t.Errorf("Expected no views for another user, got %d", len(views))
+	}
+
+	shares, err := store.GetSessionShares(ctx, ownerID, "session-1")
+	if err != nil || len(shares) != 1 || shares[0].ViewCount != 2 {
+		t.Fatalf("Expected 1 share with 2 views, got %+v (%v)", shares, err)
+	}
+
+	// Revocation is owner-only
+	if err := store.RevokeSessionShare(ctx, otherID, shareID); err == nil {
+		t.Error("Expected error revoking another user's share")
+	}
+	if err := store.RevokeSessionShare(ctx, ownerID, shareID); err != nil {
+		t.Fatalf("Failed to revoke share: %v", err)
+	}
+	share, _ = store.GetSessionShareByToken(ctx, "token-1")
+	if share.RevokedAt.IsZero() {
+		t.Error("Expected share to be revoked")
+	}
+}
@@ -31,7 +31,7 @@ func TestSessionTokenManagement(t *testing.T) {
 		token := "test-token-123"
 		expiresAt := time.Now().Add(24 * time.Hour)
 
-		err := store.CreateSessionToken(ctx, token, userID, expiresAt)
+		err := store.CreateSessionToken(ctx, token, userID, expiresAt, false)
 		if err != nil {
 			t.Errorf("CreateSessionToken failed: %v", err)
 		}
@@ -42,7 +42,7 @@ func TestSessionTokenManagement(t *testing.T) {
 		token := "test-token-456"
 		expiresAt := time.Now().Add(24 * time.Hour)
 
-		err := store.CreateSessionToken(ctx, token, userID, expiresAt)
+		err := store.CreateSessionToken(ctx, token, userID, expiresAt, false)
 		if err != nil {
 			t.Fatalf("Failed to create token: %v", err)
 		}
@@ -64,7 +64,7 @@ func TestSessionTokenManagement(t *testing.T) {
 		token := "test-token-expired"
 		expiresAt := time.Now().Add(-1 * time.Hour) // Expired 1 hour ago
 
-		err := store.CreateSessionToken(ctx, token, userID, expiresAt)
+		err := store.CreateSessionToken(ctx, token, userID, expiresAt, false)
 		if err != nil {
 			t.Fatalf("Failed to create token: %v", err)
 		}
@@ -94,7 +94,7 @@ func TestSessionTokenManagement(t *testing.T) {
 		token := "test-token-delete"
 		expiresAt := time.Now().Add(24 * time.Hour)
 
-		err := store.CreateSessionToken(ctx, token, userID, expiresAt)
+		err := store.CreateSessionToken(ctx, token, userID, expiresAt, false)
 		if err != nil {
 			t.Fatalf("Failed to create token: %v", err)
 		}
@@ -130,7 +130,7 @@ func TestSessionTokenManagement(t *testing.T) {
 		for i := 0; i < 3; i++ {
 			token := "expired-token-" + string(rune('a'+i))
 			expiresAt := time.Now().Add(-1 * time.Hour)
-			err := store.CreateSessionToken(ctx, token, userID, expiresAt)
+			err := store.CreateSessionToken(ctx, token, userID, expiresAt, false)
 			if err != nil {
 				t.Fatalf("Failed to create expired token: %v", err)
 			}
@@ -139,7 +139,7 @@ func TestSessionTokenManagement(t *testing.T) {
 		// Create a valid token
 		validToken := "valid-token"
 		expiresAt := time.Now().Add(24 * time.Hour)
-		err := store.CreateSessionToken(ctx, validToken, userID, expiresAt)
+		err := store.CreateSessionToken(ctx, validToken, userID, expiresAt, false)
 		if err != nil {
 			t.Fatalf("Failed to create valid token: %v", err)
 		}
@@ -171,4 +171,58 @@ func TestSessionTokenManagement(t *testing.T) {
 			}
 		}
 	})
+	// Test ExtendSessionToken and the remember_me flag
+	t.Run("ExtendSessionToken", func(t *testing.T) {
+		token := "remembered-token"
+		if err := store.CreateSessionToken(ctx, token, userID, time.Now().Add(time.Hour), true); err != nil {
+			t.Fatalf("Failed to create token: %v", err)
+		}
+
+		later := time.Now().Add(48 * time.Hour).Truncate(time.Second)
+		if err := store.ExtendSessionToken(ctx, token, later); err != nil {
+			t.Fatalf("ExtendSessionToken failed: %v", err)
+		}
+
+		st, err := store.GetSessionToken(ctx, token)
+		if err != nil || st == nil {
+			t.Fatalf("Expected token to be found, got %v (%v)", st, err)
+		}
+		if !st.RememberMe {
+			t.Error("Expected remember_me to be stored")
+		}
+		if !st.ExpiresAt.Equal(later) {
+			t.Errorf("Expected expiry %v, got %v", later, st.ExpiresAt)
+		}
+	})
+
+	// Test DeleteUserSessionTokens
+	t.Run("DeleteUserSessionTokens", func(t *testing.T) {
+		otherID, err := store.CreateUser(ctx, "otheruser", "password123", "other@example.com", false, false)
+		if err != nil {
+			t.Fatalf("Failed to create user: %v", err)
+		}
+		expiresAt := time.Now().Add(24 * time.Hour)
+		for _, token := range []string{"user-a", "user-b", "user-keep"} {
+			if err := store.CreateSessionToken(ctx, token, userID, expiresAt, false); err != nil {
+				t.Fatalf("Failed to create token: %v", err)
+			}
+		}
+		if err := store.CreateSessionToken(ctx, "other-user", otherID, expiresAt, false); err != nil {
+			t.Fatalf("Failed to create token: %v", err)
+		}
+
+		if err := store.DeleteUserSessionTokens(ctx, userID, "user-keep"); err != nil {
+			t.Fatalf("DeleteUserSessionTokens failed: %v", err)
+		}
+
+		for token, wantKept := range map[string]bool{"user-a": false, "user-b": false, "user-keep": true, "other-user": true} {
+			st, err := store.GetSessionToken(ctx, token)
+			if err != nil {
+				t.Fatalf("GetSessionToken failed: %v", err)
+			}
+			if (st != nil) != wantKept {
+				t.Errorf("Token %s: expected kept=%v", token, wantKept)
+			}
+		}
+	})
 }
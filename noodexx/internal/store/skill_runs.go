@@ -0,0 +1,151 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// skillRunColumns is the column list shared by skill run queries
+const skillRunColumns = `id, skill_id, user_id, status, input, output, error, stderr, exit_code, duration_ms, artifact_source, rerun_of, created_at`
+
+// RecordSkillRun stores a skill execution and returns its ID
+// The skill must belong to the run's user or be a global skill
+func (s *Store) RecordSkillRun(ctx context.Context, run *SkillRun) (int64, error) {
+	query := `
+		INSERT INTO skill_runs (skill_id, user_id, status, input, output, error, stderr, exit_code, duration_ms, artifact_source, rerun_of)
+		SELECT id, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?
+		FROM skills
+		WHERE id = ? AND (user_id = ? OR global = 1)
+	`
+
+	var rerunOf interface{}
+	if run.RerunOf != 0 {
+		rerunOf = run.RerunOf
+	}
+
+	result, err := s.db.ExecContext(ctx, query,
+		run.UserID, run.Status, run.Input, run.Output, run.Error, run.Stderr, run.ExitCode, run.DurationMS, run.ArtifactSource, rerunOf,
+		run.SkillID, run.UserID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to record skill run: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return 0, fmt.Errorf("skill not found or access denied: %d", run.SkillID)
+	}
+
+	return result.LastInsertId()
+}
+
+// SetSkillRunArtifact records the library source a run's output was saved under
+func (s *Store) SetSkillRunArtifact(ctx context.Context, userID, runID int64, source string) error {
+	query := `UPDATE skill_runs SET artifact_source = ? WHERE id = ? AND user_id = ?`
+	if _, err := s.db.ExecContext(ctx, query, source, runID, userID); err != nil {
+		return fmt.Errorf("failed to update skill run artifact: %w", err)
+	}
+	return nil
+}
+
+// GetSkillRuns returns a page of a skill's runs for a user, newest first, and the total number of runs
+func (s *Store) GetSkillRuns(ctx context.Context, userID, skillID int64, limit, offset int) ([]SkillRun, int, error) {
+	var total int
+	err := s.db.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM skill_runs WHERE skill_id = ? AND user_id = ?`,
+		skillID, userID).Scan(&total)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count skill runs: %w", err)
+	}
+
+	query := `
+		SELECT ` + skillRunColumns + `
+		FROM skill_runs
+		WHERE skill_id = ? AND user_id = ?
+		ORDER BY created_at DESC, id DESC
+		LIMIT ? OFFSET ?
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, skillID, userID, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query skill runs: %w", err)
+	}
+	defer rows.Close()
+
+	var runs []SkillRun
+	for rows.Next() {
+		run, err := scanSkillRun(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		runs = append(runs, *run)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error iterating skill runs: %w", err)
+	}
+
+	return runs, total, nil
+}
+
+// GetSkillRun returns a single run owned by a user, or nil if it does not exist
+func (s *Store) GetSkillRun(ctx context.Context, userID, runID int64) (*SkillRun, error) {
+	query := `SELECT ` + skillRunColumns + ` FROM skill_runs WHERE id = ? AND user_id = ?`
+
+	run, err := scanSkillRun(s.db.QueryRowContext(ctx, query, runID, userID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return run, nil
+}
+
+// rowScanner is implemented by *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanSkillRun reads one skill run in skillRunColumns order
+func scanSkillRun(row rowScanner) (*SkillRun, error) {
+	var run SkillRun
+	var input, output, runErr, stderr, artifact sql.NullString
+	var exitCode, durationMS, rerunOf sql.NullInt64
+
+	err := row.Scan(
+		&run.ID,
+		&run.SkillID,
+		&run.UserID,
+		&run.Status,
+		&input,
+		&output,
+		&runErr,
+		&stderr,
+		&exitCode,
+		&durationMS,
+		&artifact,
+		&rerunOf,
+		&run.CreatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan skill run: %w", err)
+	}
+
+	run.Input = input.String
+	run.Output = output.String
+	run.Error = runErr.String
+	run.Stderr = stderr.String
+	run.ExitCode = int(exitCode.Int64)
+	run.DurationMS = durationMS.Int64
+	run.ArtifactSource = artifact.String
+	run.RerunOf = rerunOf.Int64
+
+	return &run, nil
+}
@@ -277,3 +277,88 @@ func TestDeleteSkill_NonExistent(t *testing.T) {
 		t.Error("Expected error when deleting non-existent skill, got nil")
 	}
 }
+
+func TestSkillRuns(t *testing.T) {
+	store, cleanup := setupSkillsTestStore(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	user1ID, err := store.CreateUser(ctx, "runner1", "password123", "runner1@example.com", false, false)
+	if err != nil {
+		t.Fatalf("Failed to create user1: %v", err)
+	}
+	user2ID, err := store.CreateUser(ctx, "runner2", "password456", "runner2@example.com", false, false)
+	if err != nil {
+		t.Fatalf("Failed to create user2: %v", err)
+	}
+
+	skillID, err := store.CreateSkill(ctx, user1ID, "weather", "weather", true)
+	if err != nil {
+		t.Fatalf("Failed to create skill: %v", err)
+	}
+
+	// Another user's run against this skill is rejected
+	if _, err := store.RecordSkillRun(ctx, &SkillRun{SkillID: skillID, UserID: user2ID, Status: SkillRunSuccess}); err == nil {
+		t.Error("Expected recording a run for another user's skill to fail")
+	}
+
+	var firstID int64
+	for i := 0; i < 3; i++ {
+		runID, err := store.RecordSkillRun(ctx, &SkillRun{
+			SkillID:    skillID,
+			UserID:     user1ID,
+			Status:     SkillRunSuccess,
+			Input:      `{"query":"weather"}`,
+			Output:     `{"result":"sunny"}`,
+			ExitCode:   0,
+			DurationMS: 12,
+			RerunOf:    firstID,
+		})
+		if err != nil {
+			t.Fatalf("Failed to record run: %v", err)
+		}
+		if firstID == 0 {
+			firstID = runID
+		}
+	}
+
+	runs, total, err := store.GetSkillRuns(ctx, user1ID, skillID, 2, 0)
+	if err != nil {
+		t.Fatalf("Failed to get runs: %v", err)
+	}
+	if total != 3 || len(runs) != 2 {
+		t.Fatalf("Expected 2 of 3 runs, got %d of %d", len(runs), total)
+	}
+	if runs[0].ID < runs[1].ID {
+		t.Errorf("Expected newest run first, got %d then %d", runs[0].ID, runs[1].ID)
+	}
+	if runs[0].RerunOf != firstID || runs[0].Output != `{"result":"sunny"}` || runs[0].DurationMS != 12 {
+		t.Errorf("Unexpected run fields: %+v", runs[0])
+	}
+
+	runs, _, err = store.GetSkillRuns(ctx, user1ID, skillID, 2, 2)
+	if err != nil {
+		t.Fatalf("Failed to get second page: %v", err)
+	}
+	if len(runs) != 1 || runs[0].ID != firstID || runs[0].RerunOf != 0 {
+		t.Errorf("Expected the first run on the second page, got %+v", runs)
+	}
+
+	if err := store.SetSkillRunArtifact(ctx, user1ID, firstID, "skill:weather/1"); err != nil {
+		t.Fatalf("Failed to set artifact: %v", err)
+	}
+	run, err := store.GetSkillRun(ctx, user1ID, firstID)
+	if err != nil || run == nil {
+		t.Fatalf("Failed to get run: %v", err)
+	}
+	if run.ArtifactSource != "skill:weather/1" {
+		t.Errorf("Expected artifact source to be saved, got %q", run.ArtifactSource)
+	}
+
+	// Runs are private to their owner
+	run, err = store.GetSkillRun(ctx, user2ID, firstID)
+	if err != nil || run != nil {
+		t.Errorf("Expected no run for another user, got %+v (%v)", run, err)
+	}
+}
@@ -0,0 +1,98 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+// GetStorageStats measures the bytes used by chunk text, embeddings and metadata,
+// grouped by source and by user. Without allUsers only userID's own chunks are
+// counted; limit caps the number of largest sources returned (0 returns all)
+func (s *Store) GetStorageStats(ctx context.Context, userID int64, allUsers bool, limit int) (*StorageStats, error) {
+	query := `
+		SELECT
+			c.source,
+			COALESCE(c.user_id, 0),
+			COALESCE(u.username, ''),
+			COUNT(*),
+			COALESCE(SUM(LENGTH(CAST(c.text AS BLOB))), 0),
+			COALESCE(SUM(LENGTH(c.embedding)), 0),
+			COALESCE(SUM(LENGTH(CAST(COALESCE(c.summary, '') AS BLOB)) + LENGTH(CAST(COALESCE(c.tags, '') AS BLOB))), 0)
+		FROM chunks c
+		LEFT JOIN users u ON u.id = c.user_id
+		WHERE ? OR c.user_id = ?
+		GROUP BY c.source, c.user_id
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, allUsers, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query storage stats: %w", err)
+	}
+	defer rows.Close()
+
+	stats := &StorageStats{}
+	users := make(map[int64]*UserStorage)
+	for rows.Next() {
+		var src SourceStorage
+		if err := rows.Scan(&src.Source, &src.UserID, &src.Username, &src.Chunks, &src.TextBytes, &src.EmbeddingBytes, &src.MetadataBytes); err != nil {
+			return nil, fmt.Errorf("failed to scan storage stats: %w", err)
+		}
+		stats.Largest = append(stats.Largest, src)
+		stats.Sources++
+		stats.Total.add(src.StorageUsage)
+
+		user, ok := users[src.UserID]
+		if !ok {
+			user = &UserStorage{UserID: src.UserID, Username: src.Username}
+			users[src.UserID] = user
+		}
+		user.Sources++
+		user.add(src.StorageUsage)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read storage stats: %w", err)
+	}
+
+	sort.SliceStable(stats.Largest, func(i, j int) bool {
+		a, b := stats.Largest[i], stats.Largest[j]
+		if a.TotalBytes() != b.TotalBytes() {
+			return a.TotalBytes() > b.TotalBytes()
+		}
+		return a.Source < b.Source
+	})
+	if limit > 0 && len(stats.Largest) > limit {
+		stats.Largest = stats.Largest[:limit]
+	}
+
+	for _, user := range users {
+		stats.Users = append(stats.Users, *user)
+	}
+	sort.Slice(stats.Users, func(i, j int) bool {
+		a, b := stats.Users[i], stats.Users[j]
+		if a.TotalBytes() != b.TotalBytes() {
+			return a.TotalBytes() > b.TotalBytes()
+		}
+		return a.UserID < b.UserID
+	})
+	return stats, nil
+}
+
+// add accumulates other into u
+func (u *StorageUsage) add(other StorageUsage) {
+	u.Chunks += other.Chunks
+	u.TextBytes += other.TextBytes
+	u.EmbeddingBytes += other.EmbeddingBytes
+	u.MetadataBytes += other.MetadataBytes
+}
+
+// CountDocuments returns the number of documents in the library across all
+// users; the same source ingested by two users counts twice
+func (s *Store) CountDocuments(ctx context.Context) (int, error) {
+	query := `SELECT COUNT(*) FROM (SELECT 1 FROM chunks GROUP BY source, user_id)`
+	var count int
+	if err := s.db.QueryRowContext(ctx, query).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count documents: %w", err)
+	}
+	return count, nil
+}
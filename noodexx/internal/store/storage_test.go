@@ -0,0 +1,81 @@
+package store
+
+import (
+	"context"
+	"testing"
+)
+
+// TestGetStorageStats tests per-source and per-user byte counts and user scoping
+func TestGetStorageStats(t *testing.T) {
+	store, err := NewStore(t.TempDir()+"/test_storage.db", "multi")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+
+	alice, err := store.CreateUser(ctx, "alice", "password123", "alice@example.com", false, false)
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	bob, err := store.CreateUser(ctx, "bob", "password123", "bob@example.com", false, false)
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	vec := []float32{0.1, 0.2, 0.3} // 12 bytes
+	chunks := []struct {
+		userID  int64
+		source  string
+		text    string
+		tags    []string
+		summary string
+	}{
+		{alice, "big.pdf", "0123456789", []string{"a"}, "sum"},
+		{alice, "big.pdf", "0123456789", nil, ""},
+		{alice, "small.txt", "héllo", nil, ""}, // 6 bytes of UTF-8
+		{bob, "big.pdf", "x", nil, ""},
+	}
+	for _, c := range chunks {
+		if err := store.SaveChunk(ctx, c.userID, c.source, c.text, vec, c.tags, c.summary); err != nil {
+			t.Fatalf("Failed to save chunk: %v", err)
+		}
+	}
+
+	stats, err := store.GetStorageStats(ctx, 0, true, 2)
+	if err != nil {
+		t.Fatalf("GetStorageStats() error = %v", err)
+	}
+	if stats.Sources != 3 || stats.Total.Chunks != 4 || stats.Total.TextBytes != 27 || stats.Total.EmbeddingBytes != 48 || stats.Total.MetadataBytes != 4 {
+		t.Errorf("Unexpected totals: %d sources, %+v", stats.Sources, stats.Total)
+	}
+
+	if count, err := store.CountDocuments(ctx); err != nil || count != 3 {
+		t.Errorf("Expected 3 documents, got %d, %v", count, err)
+	}
+
+	if len(stats.Largest) != 2 {
+		t.Fatalf("Expected the limit to cap the largest sources at 2, got %d", len(stats.Largest))
+	}
+	first := stats.Largest[0]
+	if first.Source != "big.pdf" || first.UserID != alice || first.Username != "alice" || first.TotalBytes() != 20+24+4 {
+		t.Errorf("Expected alice's big.pdf to be largest, got %+v", first)
+	}
+	if second := stats.Largest[1]; second.Source != "small.txt" || second.TextBytes != 6 {
+		t.Errorf("Expected small.txt second, got %+v", second)
+	}
+
+	if len(stats.Users) != 2 || stats.Users[0].UserID != alice || stats.Users[0].Sources != 2 || stats.Users[1].Username != "bob" {
+		t.Errorf("Unexpected per-user breakdown: %+v", stats.Users)
+	}
+
+	// Without allUsers only the caller's own chunks are counted
+	own, err := store.GetStorageStats(ctx, bob, false, 0)
+	if err != nil {
+		t.Fatalf("GetStorageStats() error = %v", err)
+	}
+	if own.Sources != 1 || own.Total.Chunks != 1 || len(own.Users) != 1 || own.Users[0].UserID != bob {
+		t.Errorf("Expected only bob's chunks, got %+v", own)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"fmt"
 	"math"
+	"sort"
 	"strings"
 	"time"
 	"unsafe"
@@ -15,21 +16,42 @@ import (
 
 // Store provides database operations for Noodexx
 type Store struct {
-	db       *sql.DB
-	userMode string // "single" or "multi"
+	db             *sql.DB
+	read           *sql.DB       // Read-only pool for search and library queries, nil when they use db; see reader
+	userMode       string        // "single" or "multi"
+	searchPageSize int           // Rows read per page during vector search
+	vindex         *vectorIndex  // In-memory embedding cache and ANN lists, nil unless OpenVectorIndex was called
+	embeddingModel string        // Model recorded on saved chunks, see SetEmbeddingModel
+	titleWeight    float64       // Weight of title similarity in search scores, see SetTitleWeight
+	pinBoost       float64       // Multiplier for the scores of pinned documents, see SetPinBoost
+	searchDefaults SearchOptions // Mode, fusion and keyword weight of searches that leave them unset, see SetSearchDefaults
 }
 
 // NewStore creates a new Store instance and initializes the database
 func NewStore(path string, userMode string) (*Store, error) {
-	// Enable WAL mode for concurrent access and busy timeout for write contention
-	db, err := sql.Open("sqlite", path+"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
+	return NewStoreWithOptions(path, userMode, DefaultOptions())
+}
+
+// NewStoreWithOptions creates a new Store instance with the given SQLite tuning options
+func NewStoreWithOptions(path string, userMode string, opts Options) (*Store, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+
+	// Enable WAL mode for concurrent access and busy timeout for write contention.
+	// Pragmas in the DSN are applied to every pooled connection.
+	db, err := sql.Open("sqlite", path+opts.dsnParams())
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
 
 	// Configure connection pool for concurrent multi-user access
-	db.SetMaxOpenConns(25)                 // Support up to 25 concurrent connections
-	db.SetMaxIdleConns(5)                  // Keep 5 connections ready
+	maxOpen := 25 // Support up to 25 concurrent connections unless configured
+	if opts.WritePoolSize > 0 {
+		maxOpen = opts.WritePoolSize
+	}
+	db.SetMaxOpenConns(maxOpen)
+	db.SetMaxIdleConns(min(5, maxOpen))    // Keep up to 5 connections ready
 	db.SetConnMaxLifetime(5 * time.Minute) // Recycle connections periodically
 
 	// Test the connection
@@ -39,8 +61,9 @@ func NewStore(path string, userMode string) (*Store, error) {
 	}
 
 	store := &Store{
-		db:       db,
-		userMode: userMode,
+		db:             db,
+		userMode:       userMode,
+		searchPageSize: opts.SearchPageSize,
 	}
 
 	// Run migrations
@@ -49,19 +72,53 @@ func NewStore(path string, userMode string) (*Store, error) {
 		return nil, fmt.Errorf("failed to run migrations: %w", err)
 	}
 
+	// The read pool opens after migrations, so it never sees an old schema
+	if opts.ReadPoolSize > 0 {
+		if err := store.openReadPool(path, opts); err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+
 	return store, nil
 }
 
-// Close closes the database connection
+// Ping checks that the database answers a query
+func (s *Store) Ping(ctx context.Context) error {
+	var one int
+	return s.db.QueryRowContext(ctx, "SELECT 1").Scan(&one)
+}
+
+// Close closes the database connections
 func (s *Store) Close() error {
+	if s.read != nil {
+		s.read.Close()
+	}
 	if s.db != nil {
 		return s.db.Close()
 	}
 	return nil
 }
 
+// SetEmbeddingModel sets the embedding model recorded on chunks saved from now on,
+// alongside their embedding's dimension. It should be called once at startup, before
+// ingestion begins, with the model the ingester embeds with
+func (s *Store) SetEmbeddingModel(model string) {
+	s.embeddingModel = model
+}
+
 // SaveChunk saves a text chunk with its embedding to the database
 func (s *Store) SaveChunk(ctx context.Context, userID int64, source, text string, embedding []float32, tags []string, summary string) error {
+	if err := saveChunk(ctx, s.db, s.embeddingModel, userID, source, text, embedding, tags, summary); err != nil {
+		return err
+	}
+	// The chunk is saved either way; one the ANN index misses is still searched in full
+	s.indexNewChunks(ctx)
+	return nil
+}
+
+// saveChunk inserts a chunk using the given connection or transaction
+func saveChunk(ctx context.Context, ex execer, embeddingModel string, userID int64, source, text string, embedding []float32, tags []string, summary string) error {
 	// Serialize embedding to bytes
 	embeddingBytes := serializeEmbedding(embedding)
 
@@ -71,8 +128,17 @@ func (s *Store) SaveChunk(ctx context.Context, userID int64, source, text string
 		tagsStr = joinTags(tags)
 	}
 
-	query := `INSERT INTO chunks (user_id, source, text, embedding, tags, summary, visibility) VALUES (?, ?, ?, ?, ?, ?, ?)`
-	_, err := s.db.ExecContext(ctx, query, userID, source, text, embeddingBytes, tagsStr, summary, "private")
+	// Chunks reference their document by ID; the source string is kept alongside
+	if err := ensureDocument(ctx, ex, userID, source); err != nil {
+		return err
+	}
+
+	// The chunk inherits its document's visibility
+	query := `
+		INSERT INTO chunks (user_id, source, text, embedding, tags, summary, visibility, embedding_model, embedding_dim, document_id)
+		SELECT ?, ?, ?, ?, ?, ?, visibility, ?, ?, id FROM documents WHERE user_id = ? AND source = ?
+	`
+	_, err := ex.ExecContext(ctx, query, userID, source, text, embeddingBytes, tagsStr, summary, embeddingModel, len(embedding), userID, source)
 	if err != nil {
 		return fmt.Errorf("failed to save chunk: %w", err)
 	}
@@ -82,103 +148,226 @@ func (s *Store) SaveChunk(ctx context.Context, userID int64, source, text string
 
 // Search performs vector similarity search and returns top K chunks
 func (s *Store) Search(ctx context.Context, queryVec []float32, topK int) ([]Chunk, error) {
-	// Get all chunks from database
-	query := `SELECT id, source, text, embedding, tags, summary, created_at FROM chunks`
-	rows, err := s.db.QueryContext(ctx, query)
-	if err != nil {
-		return nil, fmt.Errorf("failed to query chunks: %w", err)
-	}
-	defer rows.Close()
+	// Embeddings of another dimension cannot be compared with the query
+	return s.scanSearch(ctx, queryVec, "embedding_dim = ?", []interface{}{len(queryVec)}, topK, true, func(c Chunk) float64 {
+		return s.similarity(queryVec, c)
+	})
+}
 
-	// Calculate similarity scores for each chunk
-	var scored []scoredChunk
+// SearchByUser performs vector similarity search with user-scoped visibility filtering
+// Returns chunks visible to the specified user: owned by user, public, or shared with user
+// Only chunks embedded at the query's dimension are searched, and when queryModel is set,
+// chunks tagged with a different embedding model are skipped; untagged chunks from before
+// models were recorded are kept if their dimension matches
+func (s *Store) SearchByUser(ctx context.Context, userID int64, queryVec []float32, queryModel string, topK int) ([]Chunk, error) {
+	return s.searchByUser(ctx, userID, queryVec, queryModel, nil, topK)
+}
 
-	for rows.Next() {
-		var c Chunk
-		var embeddingBytes []byte
-		var tagsStr sql.NullString
-		var summary sql.NullString
-		var createdAtStr string
+// SearchUserSources is SearchByUser restricted to chunks of the given sources,
+// such as those already cited in a chat session. No sources finds nothing
+func (s *Store) SearchUserSources(ctx context.Context, userID int64, queryVec []float32, queryModel string, sources []string, topK int) ([]Chunk, error) {
+	if len(sources) == 0 {
+		return nil, nil
+	}
+	return s.searchByUser(ctx, userID, queryVec, queryModel, sources, topK)
+}
 
-		err := rows.Scan(&c.ID, &c.Source, &c.Text, &embeddingBytes, &tagsStr, &summary, &createdAtStr)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan chunk: %w", err)
+// searchByUser searches the chunks visible to the user, only those of sources unless it is nil
+func (s *Store) searchByUser(ctx context.Context, userID int64, queryVec []float32, queryModel string, sources []string, topK int) ([]Chunk, error) {
+	modifier, err := s.rankingModifier(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Visibility filter: owned by user, public, or shared with user
+	filter := `(user_id = ?
+			OR visibility = 'public'
+			OR (',' || COALESCE(shared_with, '') || ',') LIKE '%,' || CAST(? AS TEXT) || ',%')
+		AND embedding_dim = ?`
+	args := []interface{}{userID, userID, len(queryVec)}
+	if queryModel != "" {
+		filter += ` AND embedding_model IN ('', ?)`
+		args = append(args, queryModel)
+	}
+	if sources != nil {
+		filter += ` AND source IN (?` + strings.Repeat(`, ?`, len(sources)-1) + `)`
+		for _, source := range sources {
+			args = append(args, source)
 		}
+	}
 
-		// Deserialize embedding
-		c.Embedding = deserializeEmbedding(embeddingBytes)
+	// Calculate cosine similarity and apply the user's ranking modifiers. A few
+	// sources are scanned directly, as the nearest lists would hold little of them
+	results, err := s.scanSearch(ctx, queryVec, filter, args, topK, sources == nil, func(c Chunk) float64 {
+		return s.similarity(queryVec, c) * modifier(c)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query chunks for user: %w", err)
+	}
 
-		// Parse tags
-		if tagsStr.Valid && tagsStr.String != "" {
-			c.Tags = splitTags(tagsStr.String)
-		}
+	return results, nil
+}
 
-		// Set summary
-		if summary.Valid {
-			c.Summary = summary.String
+// scanSearch scores the chunks matching filter, one page at a time, keeping only the best
+// k so memory does not grow with the library. With approximate set and an ANN index
+// covering queryVec's dimension, only the chunks in its lists nearest queryVec are read;
+// the table is scanned instead when they hold fewer than k matches, such as when filter
+// leaves the user few chunks
+func (s *Store) scanSearch(ctx context.Context, queryVec []float32, filter string, args []interface{}, k int, approximate bool, score func(Chunk) float64) ([]Chunk, error) {
+	collect := func(top *topChunks) func(page []Chunk) bool {
+		return func(page []Chunk) bool {
+			for _, c := range page {
+				top.add(c, score(c))
+			}
+			return true
 		}
+	}
 
-		// Parse timestamp
-		if createdAtStr != "" {
-			c.CreatedAt, _ = time.Parse("2006-01-02 15:04:05", createdAtStr)
+	if approximate {
+		if ids, lastID, ok := s.annCandidates(queryVec); ok {
+			top := newTopChunks(k)
+			if err := s.scanCandidatePages(ctx, filter, args, ids, lastID, collect(top)); err != nil {
+				return nil, err
+			}
+			if len(top.scored) >= k {
+				return top.results(), nil
+			}
 		}
-
-		// Calculate cosine similarity
-		score := cosineSimilarity(queryVec, c.Embedding)
-		scored = append(scored, scoredChunk{chunk: c, score: score})
 	}
 
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating chunks: %w", err)
+	top := newTopChunks(k)
+	if err := s.scanChunkPages(ctx, filter, args, collect(top)); err != nil {
+		return nil, err
 	}
+	return top.results(), nil
+}
 
-	// Sort by score descending
-	sortByScore(scored)
-
-	// Return top K
-	var results []Chunk
-	for i := 0; i < len(scored) && i < topK; i++ {
-		results = append(results, scored[i].chunk)
+// rankingModifier returns the multiplier of a chunk's search score for the
+// user: their recency boost, tag and source weights, and the boost of the
+// documents they pinned
+func (s *Store) rankingModifier(ctx context.Context, userID int64) (func(Chunk) float64, error) {
+	weights, err := s.GetRankingWeights(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	pinned, err := s.pinnedSources(ctx, userID)
+	if err != nil {
+		return nil, err
 	}
 
-	return results, nil
+	now := time.Now()
+	return func(c Chunk) float64 {
+		multiplier := weights.multiplier(c, now)
+		if pinned[c.Source] && s.pinBoost > 0 {
+			multiplier *= s.pinBoost
+		}
+		return multiplier
+	}, nil
 }
 
-// SearchByUser performs vector similarity search with user-scoped visibility filtering
-// Returns chunks visible to the specified user: owned by user, public, or shared with user
-func (s *Store) SearchByUser(ctx context.Context, userID int64, queryVec []float32, topK int) ([]Chunk, error) {
-	// Query chunks with visibility filtering
+// GetSourceChunks returns the chunks of a source visible to the user, in
+// document order, without their embeddings
+func (s *Store) GetSourceChunks(ctx context.Context, userID int64, source string) ([]Chunk, error) {
 	query := `
-		SELECT id, source, text, embedding, tags, summary, created_at 
+		SELECT id, source, text, NULL, NULL, tags, summary, created_at
 		FROM chunks
-		WHERE user_id = ? 
+		WHERE source = ? AND (user_id = ?
 			OR visibility = 'public'
-			OR (',' || COALESCE(shared_with, '') || ',') LIKE '%,' || CAST(? AS TEXT) || ',%'
+			OR (',' || COALESCE(shared_with, '') || ',') LIKE '%,' || CAST(? AS TEXT) || ',%')
+		ORDER BY id
+	`
+	chunks, err := s.readChunkPage(ctx, query, []interface{}{source, userID, userID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get source chunks: %w", err)
+	}
+	return chunks, nil
+}
+
+// scanChunkPages reads chunks matching filter in id order, searchPageSize rows at a time,
+// and hands each page to fn until it returns false. Each page's rows are fully read and closed
+// before fn runs, so no read transaction is held open while callers do scoring work; this keeps
+// long searches from pinning the WAL and blocking checkpoints during heavy ingestion.
+func (s *Store) scanChunkPages(ctx context.Context, filter string, args []interface{}, fn func(page []Chunk) bool) error {
+	pageSize := s.searchPageSize
+	if pageSize <= 0 {
+		pageSize = defaultSearchPageSize
+	}
+
+	query := `
+		SELECT ` + s.chunkColumns() + `
+		FROM chunks
+		WHERE ` + filter + ` AND id > ?
+		ORDER BY id
+		LIMIT ?
 	`
 
-	rows, err := s.db.QueryContext(ctx, query, userID, userID)
+	var lastID int64
+	for {
+		pageArgs := append(append([]interface{}{}, args...), lastID, pageSize)
+		page, err := s.readChunkPage(ctx, query, pageArgs)
+		if err != nil {
+			return err
+		}
+		if len(page) == 0 {
+			return nil
+		}
+		if s.vindex != nil {
+			if err := s.fillEmbeddings(ctx, page); err != nil {
+				return err
+			}
+		}
+
+		if !fn(page) {
+			return nil
+		}
+
+		if len(page) < pageSize {
+			return nil
+		}
+		lastID = page[len(page)-1].ID
+	}
+}
+
+// chunkColumns returns the columns search pages select, in the order readChunkPage scans them
+func (s *Store) chunkColumns() string {
+	// With the vector index enabled, embeddings come from memory instead of being
+	// read and decoded from every row
+	embeddingColumn := "embedding"
+	if s.vindex != nil {
+		embeddingColumn = "NULL"
+	}
+	// Title embeddings are only read when they count towards the score
+	titleColumn := "NULL"
+	if s.titleWeight > 0 {
+		titleColumn = "title_embedding"
+	}
+	return "id, source, text, " + embeddingColumn + ", " + titleColumn + ", tags, summary, created_at"
+}
+
+// readChunkPage runs a single page query and returns the scanned chunks
+func (s *Store) readChunkPage(ctx context.Context, query string, args []interface{}) ([]Chunk, error) {
+	rows, err := s.reader().QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query chunks for user: %w", err)
+		return nil, fmt.Errorf("failed to query chunks: %w", err)
 	}
 	defer rows.Close()
 
-	// Calculate similarity scores for each chunk
-	var scored []scoredChunk
-
+	var page []Chunk
 	for rows.Next() {
 		var c Chunk
-		var embeddingBytes []byte
+		var embeddingBytes, titleBytes []byte
 		var tagsStr sql.NullString
 		var summary sql.NullString
 		var createdAtStr string
 
-		err := rows.Scan(&c.ID, &c.Source, &c.Text, &embeddingBytes, &tagsStr, &summary, &createdAtStr)
+		err := rows.Scan(&c.ID, &c.Source, &c.Text, &embeddingBytes, &titleBytes, &tagsStr, &summary, &createdAtStr)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan chunk: %w", err)
 		}
 
-		// Deserialize embedding
+		// Deserialize embeddings
 		c.Embedding = deserializeEmbedding(embeddingBytes)
+		c.TitleEmbedding = deserializeEmbedding(titleBytes)
 
 		// Parse tags
 		if tagsStr.Valid && tagsStr.String != "" {
@@ -195,42 +384,36 @@ func (s *Store) SearchByUser(ctx context.Context, userID int64, queryVec []float
 			c.CreatedAt, _ = time.Parse("2006-01-02 15:04:05", createdAtStr)
 		}
 
-		// Calculate cosine similarity
-		score := cosineSimilarity(queryVec, c.Embedding)
-		scored = append(scored, scoredChunk{chunk: c, score: score})
+		page = append(page, c)
 	}
 
 	if err := rows.Err(); err != nil {
 		return nil, fmt.Errorf("error iterating chunks: %w", err)
 	}
 
-	// Sort by score descending
-	sortByScore(scored)
-
-	// Return top K
-	var results []Chunk
-	for i := 0; i < len(scored) && i < topK; i++ {
-		results = append(results, scored[i].chunk)
-	}
-
-	return results, nil
+	return page, nil
 }
 
 // Library returns all unique sources with metadata
 func (s *Store) Library(ctx context.Context) ([]LibraryEntry, error) {
 	query := `
 		SELECT 
-			source,
+			COALESCE(d.id, 0),
+			c.source,
+			COALESCE(NULLIF(d.title, ''), c.source) as title,
 			COUNT(*) as chunk_count,
-			MAX(summary) as summary,
-			MAX(tags) as tags,
-			MIN(created_at) as created_at
-		FROM chunks
-		GROUP BY source
+			MAX(c.summary) as summary,
+			MAX(c.tags) as tags,
+			MIN(c.created_at) as created_at,
+			MAX(w.warning) as warning
+		FROM chunks c
+		LEFT JOIN documents d ON d.user_id = c.user_id AND d.source = c.source
+		LEFT JOIN ingest_warnings w ON w.user_id = c.user_id AND w.source = c.source
+		GROUP BY c.user_id, c.source
 		ORDER BY created_at DESC
 	`
 
-	rows, err := s.db.QueryContext(ctx, query)
+	rows, err := s.reader().QueryContext(ctx, query)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query library: %w", err)
 	}
@@ -242,11 +425,13 @@ func (s *Store) Library(ctx context.Context) ([]LibraryEntry, error) {
 		var tagsStr sql.NullString
 		var summary sql.NullString
 		var createdAtStr string
+		var warning sql.NullString
 
-		err := rows.Scan(&entry.Source, &entry.ChunkCount, &summary, &tagsStr, &createdAtStr)
+		err := rows.Scan(&entry.DocumentID, &entry.Source, &entry.Title, &entry.ChunkCount, &summary, &tagsStr, &createdAtStr, &warning)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan library entry: %w", err)
 		}
+		entry.Warning = warning.String
 
 		// Parse tags
 		if tagsStr.Valid && tagsStr.String != "" {
@@ -278,20 +463,27 @@ func (s *Store) Library(ctx context.Context) ([]LibraryEntry, error) {
 func (s *Store) LibraryByUser(ctx context.Context, userID int64) ([]LibraryEntry, error) {
 	query := `
 		SELECT 
-			source,
+			COALESCE(d.id, 0),
+			c.source,
+			COALESCE(NULLIF(d.title, ''), c.source) as title,
 			COUNT(*) as chunk_count,
-			MAX(summary) as summary,
-			MAX(tags) as tags,
-			MIN(created_at) as created_at
-		FROM chunks
-		WHERE user_id = ? 
-			OR visibility = 'public'
-			OR (',' || COALESCE(shared_with, '') || ',') LIKE '%,' || CAST(? AS TEXT) || ',%'
-		GROUP BY source
+			MAX(c.summary) as summary,
+			MAX(c.tags) as tags,
+			MIN(c.created_at) as created_at,
+			MAX(w.warning) as warning,
+			MAX(p.user_id IS NOT NULL) as pinned
+		FROM chunks c
+		LEFT JOIN documents d ON d.user_id = c.user_id AND d.source = c.source
+		LEFT JOIN ingest_warnings w ON w.user_id = c.user_id AND w.source = c.source
+		LEFT JOIN document_pins p ON p.document_id = d.id AND p.user_id = ?
+		WHERE c.user_id = ? 
+			OR c.visibility = 'public'
+			OR (',' || COALESCE(c.shared_with, '') || ',') LIKE '%,' || CAST(? AS TEXT) || ',%'
+		GROUP BY c.user_id, c.source
 		ORDER BY created_at DESC
 	`
 
-	rows, err := s.db.QueryContext(ctx, query, userID, userID)
+	rows, err := s.reader().QueryContext(ctx, query, userID, userID, userID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query library by user: %w", err)
 	}
@@ -303,11 +495,13 @@ func (s *Store) LibraryByUser(ctx context.Context, userID int64) ([]LibraryEntry
 		var tagsStr sql.NullString
 		var summary sql.NullString
 		var createdAtStr string
+		var warning sql.NullString
 
-		err := rows.Scan(&entry.Source, &entry.ChunkCount, &summary, &tagsStr, &createdAtStr)
+		err := rows.Scan(&entry.DocumentID, &entry.Source, &entry.Title, &entry.ChunkCount, &summary, &tagsStr, &createdAtStr, &warning, &entry.Pinned)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan library entry: %w", err)
 		}
+		entry.Warning = warning.String
 
 		// Parse tags
 		if tagsStr.Valid && tagsStr.String != "" {
@@ -336,8 +530,14 @@ func (s *Store) LibraryByUser(ctx context.Context, userID int64) ([]LibraryEntry
 
 // DeleteChunksBySource removes all chunks for a given source owned by the specified user
 func (s *Store) DeleteChunksBySource(ctx context.Context, userID int64, source string) error {
+	return deleteChunksBySource(ctx, s.db, userID, source)
+}
+
+// deleteChunksBySource removes a user's chunks for a source using the given connection or transaction.
+// The document goes back to private, so re-ingesting it applies the visibility chosen then
+func deleteChunksBySource(ctx context.Context, ex execer, userID int64, source string) error {
 	query := `DELETE FROM chunks WHERE source = ? AND user_id = ?`
-	_, err := s.db.ExecContext(ctx, query, source, userID)
+	_, err := ex.ExecContext(ctx, query, source, userID)
 	if err != nil {
 		return fmt.Errorf("failed to delete chunks by source: %w", err)
 	}
@@ -347,18 +547,41 @@ func (s *Store) DeleteChunksBySource(ctx context.Context, userID int64, source s
 // SaveMessage persists a chat message to the database
 // SaveChatMessage saves a chat message with user ownership and provider mode
 func (s *Store) SaveChatMessage(ctx context.Context, userID int64, sessionID, role, content, providerMode string) error {
+	return s.SaveChatMessageWithCitations(ctx, userID, sessionID, role, content, providerMode, nil)
+}
+
+// SaveChatMessageWithCitations saves a chat message along with the sources it cites
+// citations[i] is the source that was presented to the model as [i+1]
+func (s *Store) SaveChatMessageWithCitations(ctx context.Context, userID int64, sessionID, role, content, providerMode string, citations []string) error {
+	_, err := s.SaveChatMessageWithProvenance(ctx, userID, sessionID, role, content, providerMode, citations, nil)
+	return err
+}
+
+// SaveChatMessageWithProvenance saves a chat message with its citations and, if provenance
+// is not nil, a record of how it was generated, and returns the message ID
+func (s *Store) SaveChatMessageWithProvenance(ctx context.Context, userID int64, sessionID, role, content, providerMode string, citations []string, provenance *MessageProvenance) (int64, error) {
 	// Start a transaction to update both chat_messages and sessions tables
 	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
 	}
 	defer tx.Rollback()
 
 	// Insert the chat message with provider_mode
-	query := `INSERT INTO chat_messages (session_id, role, content, user_id, provider_mode) VALUES (?, ?, ?, ?, ?)`
-	_, err = tx.ExecContext(ctx, query, sessionID, role, content, userID, providerMode)
+	query := `INSERT INTO chat_messages (session_id, role, content, user_id, provider_mode, citations) VALUES (?, ?, ?, ?, ?, ?)`
+	result, err := tx.ExecContext(ctx, query, sessionID, role, content, userID, providerMode, joinCitations(citations))
 	if err != nil {
-		return fmt.Errorf("failed to save message: %w", err)
+		return 0, fmt.Errorf("failed to save message: %w", err)
+	}
+	messageID, err := result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get message ID: %w", err)
+	}
+
+	if provenance != nil {
+		if err := saveMessageProvenance(ctx, tx, messageID, provenance); err != nil {
+			return 0, err
+		}
 	}
 
 	// Update or create session metadata
@@ -370,15 +593,15 @@ func (s *Store) SaveChatMessage(ctx context.Context, userID int64, sessionID, ro
 	`
 	_, err = tx.ExecContext(ctx, sessionQuery, sessionID, userID)
 	if err != nil {
-		return fmt.Errorf("failed to update session metadata: %w", err)
+		return 0, fmt.Errorf("failed to update session metadata: %w", err)
 	}
 
 	// Commit the transaction
 	if err = tx.Commit(); err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
-	return nil
+	return messageID, nil
 }
 
 // SaveMessage is deprecated, use SaveChatMessage instead
@@ -471,9 +694,13 @@ func (s *Store) GetUserSessions(ctx context.Context, userID int64) ([]Session, e
 			s.title,
 			s.created_at,
 			s.last_message_at,
-			COUNT(cm.id) as message_count
+			COUNT(cm.id) as message_count,
+			COALESCE(MAX(l.continued_from), '') as continued_from,
+			COALESCE(MAX(i.format), '') as imported_from
 		FROM sessions s
 		LEFT JOIN chat_messages cm ON s.id = cm.session_id
+		LEFT JOIN session_links l ON l.session_id = s.id
+		LEFT JOIN imported_sessions i ON i.session_id = s.id
 		WHERE s.user_id = ?
 		GROUP BY s.id, s.title, s.created_at, s.last_message_at
 		ORDER BY s.last_message_at DESC
@@ -491,7 +718,7 @@ func (s *Store) GetUserSessions(ctx context.Context, userID int64) ([]Session, e
 		var title sql.NullString
 		var createdAtStr string
 		var lastMessageAtStr sql.NullString
-		err := rows.Scan(&session.ID, &title, &createdAtStr, &lastMessageAtStr, &session.MessageCount)
+		err := rows.Scan(&session.ID, &title, &createdAtStr, &lastMessageAtStr, &session.MessageCount, &session.ContinuedFrom, &session.ImportedFrom)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan session: %w", err)
 		}
@@ -541,10 +768,12 @@ func (s *Store) GetSessionMessages(ctx context.Context, userID int64, sessionID
 
 	// Retrieve messages
 	query := `
-		SELECT id, session_id, role, content, COALESCE(provider_mode, 'local') as provider_mode, created_at 
-		FROM chat_messages 
-		WHERE session_id = ? AND user_id = ?
-		ORDER BY created_at ASC
+		SELECT m.id, m.session_id, m.role, m.content, COALESCE(m.provider_mode, 'local') as provider_mode, COALESCE(m.citations, ''),
+			COALESCE(p.privacy, ''), m.created_at
+		FROM chat_messages m
+		LEFT JOIN message_provenance p ON p.message_id = m.id
+		WHERE m.session_id = ? AND m.user_id = ?
+		ORDER BY m.created_at ASC
 	`
 	rows, err := s.db.QueryContext(ctx, query, sessionID, userID)
 	if err != nil {
@@ -555,11 +784,13 @@ func (s *Store) GetSessionMessages(ctx context.Context, userID int64, sessionID
 	var messages []ChatMessage
 	for rows.Next() {
 		var msg ChatMessage
+		var citationsStr string
 		var createdAtStr string
-		err := rows.Scan(&msg.ID, &msg.SessionID, &msg.Role, &msg.Content, &msg.ProviderMode, &createdAtStr)
+		err := rows.Scan(&msg.ID, &msg.SessionID, &msg.Role, &msg.Content, &msg.ProviderMode, &citationsStr, &msg.Privacy, &createdAtStr)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan message: %w", err)
 		}
+		msg.Citations = splitCitations(citationsStr)
 		// Parse timestamp
 		if createdAtStr != "" {
 			msg.CreatedAt, _ = time.Parse("2006-01-02 15:04:05", createdAtStr)
@@ -576,8 +807,13 @@ func (s *Store) GetSessionMessages(ctx context.Context, userID int64, sessionID
 
 // AddAuditEntry records an operation in the audit log
 func (s *Store) AddAuditEntry(ctx context.Context, opType, details, userCtx string) error {
+	return addAuditEntry(ctx, s.db, opType, details, userCtx)
+}
+
+// addAuditEntry records an audit entry using the given connection or transaction
+func addAuditEntry(ctx context.Context, ex execer, opType, details, userCtx string) error {
 	query := `INSERT INTO audit_log (operation_type, details, user_context) VALUES (?, ?, ?)`
-	_, err := s.db.ExecContext(ctx, query, opType, details, userCtx)
+	_, err := ex.ExecContext(ctx, query, opType, details, userCtx)
 	if err != nil {
 		return fmt.Errorf("failed to add audit entry: %w", err)
 	}
@@ -759,6 +995,20 @@ func splitTags(tagsStr string) []string {
 	return tags
 }
 
+// joinCitations converts a citation list to a newline-separated string
+// Newlines are used because sources (URLs, file paths) may contain commas
+func joinCitations(citations []string) string {
+	return strings.Join(citations, "\n")
+}
+
+// splitCitations converts a newline-separated string to a citation list
+func splitCitations(citationsStr string) []string {
+	if citationsStr == "" {
+		return nil
+	}
+	return strings.Split(citationsStr, "\n")
+}
+
 // cosineSimilarity computes the cosine similarity between two vectors
 func cosineSimilarity(a, b []float32) float64 {
 	if len(a) != len(b) || len(a) == 0 {
@@ -785,16 +1035,43 @@ type scoredChunk struct {
 	score float64
 }
 
-// sortByScore sorts scored chunks by score in descending order
-func sortByScore(scored []scoredChunk) {
-	// Simple bubble sort for small datasets
-	for i := 0; i < len(scored); i++ {
-		for j := i + 1; j < len(scored); j++ {
-			if scored[j].score > scored[i].score {
-				scored[i], scored[j] = scored[j], scored[i]
-			}
-		}
+// topChunks keeps the k best-scoring chunks seen so far, best first. Chunks
+// with equal scores keep the order they were added in
+type topChunks struct {
+	k      int
+	scored []scoredChunk
+}
+
+// newTopChunks returns an empty topChunks keeping at most k chunks
+func newTopChunks(k int) *topChunks {
+	return &topChunks{k: k}
+}
+
+// add offers a chunk, dropping it or the current worst if more than k are kept
+func (t *topChunks) add(c Chunk, score float64) {
+	if t.k <= 0 {
+		return
+	}
+	if len(t.scored) == t.k && score <= t.scored[len(t.scored)-1].score {
+		return
+	}
+	// Insert after every chunk scoring at least as well
+	i := sort.Search(len(t.scored), func(i int) bool { return t.scored[i].score < score })
+	if len(t.scored) < t.k {
+		t.scored = append(t.scored, scoredChunk{})
 	}
+	copy(t.scored[i+1:], t.scored[i:])
+	t.scored[i] = scoredChunk{chunk: c, score: score}
+}
+
+// results returns the kept chunks best first, with their scores set
+func (t *topChunks) results() []Chunk {
+	var results []Chunk
+	for _, sc := range t.scored {
+		sc.chunk.Score = sc.score
+		results = append(results, sc.chunk)
+	}
+	return results
 }
 
 // User Management Methods
@@ -807,11 +1084,17 @@ func (s *Store) CreateUser(ctx context.Context, username, password, email string
 		return 0, fmt.Errorf("failed to hash password: %w", err)
 	}
 
+	// Store a missing email as NULL so accounts without one don't collide on the unique index
+	var emailValue interface{}
+	if email != "" {
+		emailValue = email
+	}
+
 	query := `
 		INSERT INTO users (username, password_hash, email, is_admin, must_change_password)
 		VALUES (?, ?, ?, ?, ?)
 	`
-	result, err := s.db.ExecContext(ctx, query, username, passwordHash, email, isAdmin, mustChangePassword)
+	result, err := s.db.ExecContext(ctx, query, username, passwordHash, emailValue, isAdmin, mustChangePassword)
 	if err != nil {
 		return 0, fmt.Errorf("failed to create user: %w", err)
 	}
@@ -827,7 +1110,8 @@ func (s *Store) CreateUser(ctx context.Context, username, password, email string
 // GetUserByUsername retrieves a user by username
 func (s *Store) GetUserByUsername(ctx context.Context, username string) (*User, error) {
 	query := `
-		SELECT id, username, password_hash, email, is_admin, must_change_password, created_at, last_login, COALESCE(dark_mode, 0) as dark_mode
+		SELECT id, username, password_hash, email, is_admin, must_change_password, created_at, last_login, COALESCE(dark_mode, 0) as dark_mode,
+			onboarding_completed_at IS NOT NULL as onboarding_completed
 		FROM users
 		WHERE username = ?
 	`
@@ -845,6 +1129,7 @@ func (s *Store) GetUserByUsername(ctx context.Context, username string) (*User,
 		&user.CreatedAt,
 		&lastLogin,
 		&user.DarkMode,
+		&user.OnboardingCompleted,
 	)
 
 	if err == sql.ErrNoRows {
@@ -864,7 +1149,8 @@ func (s *Store) GetUserByUsername(ctx context.Context, username string) (*User,
 // GetUserByID retrieves a user by ID
 func (s *Store) GetUserByID(ctx context.Context, userID int64) (*User, error) {
 	query := `
-		SELECT id, username, password_hash, email, is_admin, must_change_password, created_at, last_login, COALESCE(dark_mode, 0) as dark_mode
+		SELECT id, username, password_hash, email, is_admin, must_change_password, created_at, last_login, COALESCE(dark_mode, 0) as dark_mode,
+			onboarding_completed_at IS NOT NULL as onboarding_completed
 		FROM users
 		WHERE id = ?
 	`
@@ -882,6 +1168,7 @@ func (s *Store) GetUserByID(ctx context.Context, userID int64) (*User, error) {
 		&user.CreatedAt,
 		&lastLogin,
 		&user.DarkMode,
+		&user.OnboardingCompleted,
 	)
 
 	if err == sql.ErrNoRows {
@@ -914,27 +1201,102 @@ func (s *Store) ValidateCredentials(ctx context.Context, username, password stri
 }
 
 // UpdatePassword updates a user's password and resets must_change_password flag
+// The replaced password's hash is kept in password_history (see IsRecentPassword)
 func (s *Store) UpdatePassword(ctx context.Context, userID int64, newPassword string) error {
+	return s.setPassword(ctx, userID, newPassword, false)
+}
+
+// SetTemporaryPassword updates a user's password and sets must_change_password,
+// so the user has to choose a new one before doing anything else
+func (s *Store) SetTemporaryPassword(ctx context.Context, userID int64, newPassword string) error {
+	return s.setPassword(ctx, userID, newPassword, true)
+}
+
+// setPassword replaces a user's password, keeping the old hash in password_history
+func (s *Store) setPassword(ctx context.Context, userID int64, newPassword string, mustChange bool) error {
 	// Hash the new password using bcrypt
 	passwordHash, err := hashPassword(newPassword)
 	if err != nil {
 		return fmt.Errorf("failed to hash password: %w", err)
 	}
 
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Keep the old hash, then trim the history to the most that can be checked
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO password_history (user_id, password_hash)
+		SELECT id, password_hash FROM users WHERE id = ?
+	`, userID)
+	if err != nil {
+		return fmt.Errorf("failed to record password history: %w", err)
+	}
+	_, err = tx.ExecContext(ctx, `
+		DELETE FROM password_history
+		WHERE user_id = ? AND id NOT IN (
+			SELECT id FROM password_history WHERE user_id = ? ORDER BY id DESC LIMIT ?
+		)
+	`, userID, userID, MaxPasswordHistory)
+	if err != nil {
+		return fmt.Errorf("failed to trim password history: %w", err)
+	}
+
 	query := `
 		UPDATE users
-		SET password_hash = ?, must_change_password = 0
+		SET password_hash = ?, must_change_password = ?
 		WHERE id = ?
 	`
 
-	_, err = s.db.ExecContext(ctx, query, passwordHash, userID)
+	_, err = tx.ExecContext(ctx, query, passwordHash, mustChange, userID)
 	if err != nil {
 		return fmt.Errorf("failed to update password: %w", err)
 	}
 
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
 	return nil
 }
 
+// MaxPasswordHistory is the most previous passwords kept per user
+const MaxPasswordHistory = 24
+
+// IsRecentPassword reports whether password is the user's current password or one
+// of their previous n-1 passwords; n of 0 or less checks nothing
+func (s *Store) IsRecentPassword(ctx context.Context, userID int64, password string, n int) (bool, error) {
+	if n <= 0 {
+		return false, nil
+	}
+
+	query := `
+		SELECT password_hash FROM users WHERE id = ?
+		UNION ALL
+		SELECT password_hash FROM (
+			SELECT password_hash FROM password_history WHERE user_id = ? ORDER BY id DESC LIMIT ?
+		)
+	`
+	rows, err := s.db.QueryContext(ctx, query, userID, userID, n-1)
+	if err != nil {
+		return false, fmt.Errorf("failed to read password history: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var hash string
+		if err := rows.Scan(&hash); err != nil {
+			return false, fmt.Errorf("failed to scan password history: %w", err)
+		}
+		if checkPasswordHash(password, hash) {
+			return true, nil
+		}
+	}
+	return false, rows.Err()
+}
+
 // UpdateLastLogin updates the last_login timestamp for a user
 func (s *Store) UpdateLastLogin(ctx context.Context, userID int64) error {
 	query := `
@@ -951,17 +1313,30 @@ func (s *Store) UpdateLastLogin(ctx context.Context, userID int64) error {
 	return nil
 }
 
-// UpdateUserDarkMode updates a user's dark mode preference
+// UpdateUserDarkMode updates a user's dark mode preference by setting their
+// theme to dark or light
 func (s *Store) UpdateUserDarkMode(ctx context.Context, userID int64, darkMode bool) error {
+	theme := "light"
+	if darkMode {
+		theme = "dark"
+	}
+	return s.SetUserPreferences(ctx, userID, map[string]string{PreferenceTheme: theme})
+}
+
+// SetUserAdmin grants or revokes a user's admin rights
+func (s *Store) SetUserAdmin(ctx context.Context, userID int64, isAdmin bool) error {
 	query := `
 		UPDATE users
-		SET dark_mode = ?
+		SET is_admin = ?
 		WHERE id = ?
 	`
 
-	_, err := s.db.ExecContext(ctx, query, darkMode, userID)
+	result, err := s.db.ExecContext(ctx, query, isAdmin, userID)
 	if err != nil {
-		return fmt.Errorf("failed to update dark mode: %w", err)
+		return fmt.Errorf("failed to update admin flag: %w", err)
+	}
+	if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+		return fmt.Errorf("user not found: %d", userID)
 	}
 
 	return nil
@@ -987,6 +1362,32 @@ func (s *Store) GetUserDarkMode(ctx context.Context, userID int64) (bool, error)
 	return darkMode, nil
 }
 
+// CompleteOnboarding marks onboarding as finished for a user so it is not shown again
+// The original completion time is kept if onboarding was already completed
+func (s *Store) CompleteOnboarding(ctx context.Context, userID int64) error {
+	query := `
+		UPDATE users
+		SET onboarding_completed_at = COALESCE(onboarding_completed_at, CURRENT_TIMESTAMP)
+		WHERE id = ?
+	`
+
+	result, err := s.db.ExecContext(ctx, query, userID)
+	if err != nil {
+		return fmt.Errorf("failed to complete onboarding: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("user not found: %d", userID)
+	}
+
+	return nil
+}
+
 // ListUsers returns all users in the system
 func (s *Store) ListUsers(ctx context.Context) ([]User, error) {
 	query := `
@@ -1037,9 +1438,14 @@ func (s *Store) ListUsers(ctx context.Context) ([]User, error) {
 // DeleteUser deletes a user from the system
 // Note: Foreign key constraints will cascade delete user's data
 func (s *Store) DeleteUser(ctx context.Context, userID int64) error {
+	return deleteUser(ctx, s.db, userID)
+}
+
+// deleteUser deletes a user using the given connection or transaction
+func deleteUser(ctx context.Context, ex execer, userID int64) error {
 	query := `DELETE FROM users WHERE id = ?`
 
-	result, err := s.db.ExecContext(ctx, query, userID)
+	result, err := ex.ExecContext(ctx, query, userID)
 	if err != nil {
 		return fmt.Errorf("failed to delete user: %w", err)
 	}
@@ -1077,10 +1483,10 @@ func checkPasswordHash(password, hash string) bool {
 
 // CreateSessionToken stores a new session token in the database
 // The token is associated with a user and has an expiration time
-func (s *Store) CreateSessionToken(ctx context.Context, token string, userID int64, expiresAt time.Time) error {
-	query := `INSERT INTO session_tokens (token, user_id, expires_at) VALUES (?, ?, ?)`
+func (s *Store) CreateSessionToken(ctx context.Context, token string, userID int64, expiresAt time.Time, rememberMe bool) error {
+	query := `INSERT INTO session_tokens (token, user_id, expires_at, remember_me) VALUES (?, ?, ?, ?)`
 
-	_, err := s.db.ExecContext(ctx, query, token, userID, expiresAt)
+	_, err := s.db.ExecContext(ctx, query, token, userID, expiresAt, rememberMe)
 	if err != nil {
 		return fmt.Errorf("failed to create session token: %w", err)
 	}
@@ -1092,7 +1498,7 @@ func (s *Store) CreateSessionToken(ctx context.Context, token string, userID int
 // Returns nil if the token doesn't exist or has expired
 func (s *Store) GetSessionToken(ctx context.Context, token string) (*SessionToken, error) {
 	query := `
-		SELECT token, user_id, created_at, expires_at 
+		SELECT token, user_id, created_at, expires_at, remember_me
 		FROM session_tokens 
 		WHERE token = ?
 	`
@@ -1103,6 +1509,7 @@ func (s *Store) GetSessionToken(ctx context.Context, token string) (*SessionToke
 		&st.UserID,
 		&st.CreatedAt,
 		&st.ExpiresAt,
+		&st.RememberMe,
 	)
 
 	if err == sql.ErrNoRows {
@@ -1133,6 +1540,31 @@ func (s *Store) DeleteSessionToken(ctx context.Context, token string) error {
 	return nil
 }
 
+// ExtendSessionToken moves a session token's expiry, for sliding sessions
+func (s *Store) ExtendSessionToken(ctx context.Context, token string, expiresAt time.Time) error {
+	query := `UPDATE session_tokens SET expires_at = ? WHERE token = ?`
+
+	_, err := s.db.ExecContext(ctx, query, expiresAt, token)
+	if err != nil {
+		return fmt.Errorf("failed to extend session token: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteUserSessionTokens signs a user out everywhere except the session of
+// keepToken (empty to keep none), e.g. after their password or rights change
+func (s *Store) DeleteUserSessionTokens(ctx context.Context, userID int64, keepToken string) error {
+	query := `DELETE FROM session_tokens WHERE user_id = ? AND token != ?`
+
+	_, err := s.db.ExecContext(ctx, query, userID, keepToken)
+	if err != nil {
+		return fmt.Errorf("failed to delete user session tokens: %w", err)
+	}
+
+	return nil
+}
+
 // CleanupExpiredTokens removes all expired session tokens from the database
 // This should be called periodically as a background job
 func (s *Store) CleanupExpiredTokens(ctx context.Context) error {
@@ -1184,45 +1616,53 @@ func (s *Store) ClearFailedLogins(ctx context.Context, username string) error {
 
 // IsAccountLocked checks if an account is locked due to too many failed login attempts
 // Returns true and the lockout expiration time if the account is locked
-// An account is locked if there are 5 or more failed attempts within the last 15 minutes
-func (s *Store) IsAccountLocked(ctx context.Context, username string) (bool, time.Time) {
-	// Calculate the time threshold (15 minutes ago)
-	threshold := time.Now().Add(-15 * time.Minute)
-
-	// Count failed login attempts within the last 15 minutes
-	query := `SELECT COUNT(*) FROM failed_logins WHERE username = ? AND attempted_at > ?`
+// An account is locked if there are threshold or more failed attempts within the
+// last window; a threshold of 0 or less disables lockout
+func (s *Store) IsAccountLocked(ctx context.Context, username string, threshold int, window time.Duration) (bool, time.Time) {
+	if threshold <= 0 {
+		return false, time.Time{}
+	}
 
-	var count int
-	err := s.db.QueryRowContext(ctx, query, username, threshold).Scan(&count)
+	// Count failed login attempts within the window
+	since := time.Now().Add(-window)
+	count, err := s.CountFailedLogins(ctx, username, since)
 	if err != nil {
 		// If there's an error, assume not locked (fail open for availability)
 		return false, time.Time{}
 	}
 
-	// If 5 or more attempts, account is locked
-	if count >= 5 {
-		// Find the timestamp of the 5th most recent attempt
-		// The lockout expires 15 minutes after that attempt
+	if count >= threshold {
+		// Find the attempt that reached the threshold
+		// The lockout expires one window after that attempt
 		query := `SELECT attempted_at FROM failed_logins 
 		          WHERE username = ? AND attempted_at > ?
 		          ORDER BY attempted_at DESC
-		          LIMIT 1 OFFSET 4`
+		          LIMIT 1 OFFSET ?`
 
-		var fifthAttempt time.Time
-		err := s.db.QueryRowContext(ctx, query, username, threshold).Scan(&fifthAttempt)
+		var lockingAttempt time.Time
+		err := s.db.QueryRowContext(ctx, query, username, since, threshold-1).Scan(&lockingAttempt)
 		if err != nil {
-			// If we can't find the 5th attempt, use the threshold as a fallback
-			return true, threshold.Add(15 * time.Minute)
+			// If we can't find the attempt, lock for a full window
+			return true, time.Now().Add(window)
 		}
 
-		// Lockout expires 15 minutes after the 5th attempt
-		lockoutExpires := fifthAttempt.Add(15 * time.Minute)
-		return true, lockoutExpires
+		return true, lockingAttempt.Add(window)
 	}
 
 	return false, time.Time{}
 }
 
+// CountFailedLogins returns the number of failed login attempts for username since the given time
+func (s *Store) CountFailedLogins(ctx context.Context, username string, since time.Time) (int, error) {
+	query := `SELECT COUNT(*) FROM failed_logins WHERE username = ? AND attempted_at > ?`
+
+	var count int
+	if err := s.db.QueryRowContext(ctx, query, username, since).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count failed logins: %w", err)
+	}
+	return count, nil
+}
+
 // Skills Management Methods
 
 // CreateSkill creates a new skill for a user
@@ -1243,16 +1683,24 @@ func (s *Store) CreateSkill(ctx context.Context, userID int64, name, path string
 	return skillID, nil
 }
 
-// GetUserSkills retrieves all skills owned by a specific user
+// GetUserSkills retrieves the skills owned by a specific user along with the
+// global skills provisioned for every account. A global skill is left out when
+// the user has a skill of the same name, is enabled as the user last set it or
+// otherwise as the administrator did, and carries the user's ID, so its runs are
+// recorded as theirs
 func (s *Store) GetUserSkills(ctx context.Context, userID int64) ([]Skill, error) {
 	query := `
-		SELECT id, user_id, name, path, enabled, created_at
-		FROM skills
-		WHERE user_id = ?
-		ORDER BY created_at DESC
+		SELECT s.id, ?, s.name, s.path, COALESCE(p.enabled, s.enabled), s.created_at, s.global
+		FROM skills s
+		LEFT JOIN skill_preferences p ON p.skill_id = s.id AND p.user_id = ?
+		WHERE (s.global = 0 AND s.user_id = ?)
+			OR (s.global = 1 AND NOT EXISTS (
+				SELECT 1 FROM skills o WHERE o.global = 0 AND o.user_id = ? AND o.name = s.name
+			))
+		ORDER BY s.global, s.created_at DESC
 	`
 
-	rows, err := s.db.QueryContext(ctx, query, userID)
+	rows, err := s.db.QueryContext(ctx, query, userID, userID, userID, userID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query user skills: %w", err)
 	}
@@ -1268,6 +1716,7 @@ func (s *Store) GetUserSkills(ctx context.Context, userID int64) ([]Skill, error
 			&skill.Path,
 			&skill.Enabled,
 			&skill.CreatedAt,
+			&skill.Global,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan skill: %w", err)
@@ -1283,11 +1732,13 @@ func (s *Store) GetUserSkills(ctx context.Context, userID int64) ([]Skill, error
 }
 
 // UpdateSkillEnabled updates the enabled status of a skill with ownership verification
+// For a global skill, only the user's own preference changes
 func (s *Store) UpdateSkillEnabled(ctx context.Context, userID int64, skillID int64, enabled bool) error {
 	// First verify the skill belongs to the user
 	var ownerID int64
-	checkQuery := `SELECT user_id FROM skills WHERE id = ?`
-	err := s.db.QueryRowContext(ctx, checkQuery, skillID).Scan(&ownerID)
+	var global bool
+	checkQuery := `SELECT user_id, global FROM skills WHERE id = ?`
+	err := s.db.QueryRowContext(ctx, checkQuery, skillID).Scan(&ownerID, &global)
 	if err == sql.ErrNoRows {
 		return fmt.Errorf("skill not found: %d", skillID)
 	}
@@ -1295,6 +1746,10 @@ func (s *Store) UpdateSkillEnabled(ctx context.Context, userID int64, skillID in
 		return fmt.Errorf("failed to verify skill ownership: %w", err)
 	}
 
+	if global {
+		return s.setSkillPreference(ctx, userID, skillID, enabled)
+	}
+
 	if ownerID != userID {
 		return fmt.Errorf("access denied: skill %d does not belong to user %d", skillID, userID)
 	}
@@ -1319,11 +1774,13 @@ func (s *Store) UpdateSkillEnabled(ctx context.Context, userID int64, skillID in
 }
 
 // DeleteSkill deletes a skill with ownership verification
+// Global skills are removed with DeleteGlobalSkill instead
 func (s *Store) DeleteSkill(ctx context.Context, userID int64, skillID int64) error {
 	// First verify the skill belongs to the user
 	var ownerID int64
-	checkQuery := `SELECT user_id FROM skills WHERE id = ?`
-	err := s.db.QueryRowContext(ctx, checkQuery, skillID).Scan(&ownerID)
+	var global bool
+	checkQuery := `SELECT user_id, global FROM skills WHERE id = ?`
+	err := s.db.QueryRowContext(ctx, checkQuery, skillID).Scan(&ownerID, &global)
 	if err == sql.ErrNoRows {
 		return fmt.Errorf("skill not found: %d", skillID)
 	}
@@ -1331,6 +1788,10 @@ func (s *Store) DeleteSkill(ctx context.Context, userID int64, skillID int64) er
 		return fmt.Errorf("failed to verify skill ownership: %w", err)
 	}
 
+	if global {
+		return fmt.Errorf("access denied: skill %d is a global skill", skillID)
+	}
+
 	if ownerID != userID {
 		return fmt.Errorf("access denied: skill %d does not belong to user %d", skillID, userID)
 	}
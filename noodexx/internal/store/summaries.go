@@ -0,0 +1,164 @@
+package store
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"hash"
+)
+
+// SaveSummary replaces the summary of a user's document and records what it was
+// generated from: the model and the hash of the document's current chunks
+func (s *Store) SaveSummary(ctx context.Context, userID int64, source, summary, model string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `SELECT text FROM chunks WHERE user_id = ? AND source = ? ORDER BY id`, userID, source)
+	if err != nil {
+		return fmt.Errorf("failed to query chunks: %w", err)
+	}
+	h := sha256.New()
+	count := 0
+	for rows.Next() {
+		var text string
+		if err := rows.Scan(&text); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan chunk: %w", err)
+		}
+		hashChunk(h, text)
+		count++
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating chunks: %w", err)
+	}
+	if count == 0 {
+		return fmt.Errorf("source not found: %s", source)
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE chunks SET summary = ? WHERE user_id = ? AND source = ?`, summary, userID, source); err != nil {
+		return fmt.Errorf("failed to update summary: %w", err)
+	}
+	query := `
+		INSERT INTO summary_provenance (user_id, source, model, content_hash, generated_at)
+		VALUES (?, ?, ?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT(user_id, source) DO UPDATE SET
+			model = excluded.model,
+			content_hash = excluded.content_hash,
+			generated_at = excluded.generated_at
+	`
+	if _, err := tx.ExecContext(ctx, query, userID, source, model, hex.EncodeToString(h.Sum(nil))); err != nil {
+		return fmt.Errorf("failed to record summary provenance: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit summary: %w", err)
+	}
+	return nil
+}
+
+// GetSummaryStates returns the summary and its provenance for every document
+// owned by the user, or by anyone when allUsers is set, ordered by user and source
+func (s *Store) GetSummaryStates(ctx context.Context, userID int64, allUsers bool) ([]SummaryState, error) {
+	provenance := map[[2]interface{}]SummaryState{}
+	provQuery := `SELECT user_id, source, model, content_hash, generated_at FROM summary_provenance`
+	var provArgs []interface{}
+	if !allUsers {
+		provQuery += ` WHERE user_id = ?`
+		provArgs = append(provArgs, userID)
+	}
+	rows, err := s.db.QueryContext(ctx, provQuery, provArgs...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query summary provenance: %w", err)
+	}
+	for rows.Next() {
+		var p SummaryState
+		if err := rows.Scan(&p.UserID, &p.Source, &p.Model, &p.ContentHash, &p.GeneratedAt); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan summary provenance: %w", err)
+		}
+		provenance[[2]interface{}{p.UserID, p.Source}] = p
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating summary provenance: %w", err)
+	}
+
+	// Hash each document's chunks in order to detect content that changed since
+	// its summary was generated
+	query := `SELECT user_id, source, text, summary FROM chunks`
+	var args []interface{}
+	if !allUsers {
+		query += ` WHERE user_id = ?`
+		args = append(args, userID)
+	}
+	query += ` ORDER BY user_id, source, id`
+	rows, err = s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query chunks: %w", err)
+	}
+	defer rows.Close()
+
+	var states []SummaryState
+	var h hash.Hash
+	finish := func() {
+		if len(states) > 0 {
+			states[len(states)-1].CurrentHash = hex.EncodeToString(h.Sum(nil))
+		}
+	}
+	for rows.Next() {
+		var owner int64
+		var source, text string
+		var summary sql.NullString
+		if err := rows.Scan(&owner, &source, &text, &summary); err != nil {
+			return nil, fmt.Errorf("failed to scan chunk: %w", err)
+		}
+		if n := len(states); n == 0 || states[n-1].UserID != owner || states[n-1].Source != source {
+			finish()
+			state := provenance[[2]interface{}{owner, source}]
+			state.UserID, state.Source, state.Summary = owner, source, summary.String
+			states = append(states, state)
+			h = sha256.New()
+		}
+		hashChunk(h, text)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating chunks: %w", err)
+	}
+	finish()
+	return states, nil
+}
+
+// GetSourceTexts returns the text of the chunks of a document the user owns, in
+// document order
+func (s *Store) GetSourceTexts(ctx context.Context, userID int64, source string) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT text FROM chunks WHERE user_id = ? AND source = ? ORDER BY id`, userID, source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query chunks: %w", err)
+	}
+	defer rows.Close()
+
+	var texts []string
+	for rows.Next() {
+		var text string
+		if err := rows.Scan(&text); err != nil {
+			return nil, fmt.Errorf("failed to scan chunk: %w", err)
+		}
+		texts = append(texts, text)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating chunks: %w", err)
+	}
+	return texts, nil
+}
+
+// hashChunk adds one chunk's text to a document content hash
+func hashChunk(h hash.Hash, text string) {
+	h.Write([]byte(text))
+	h.Write([]byte{0})
+}
@@ -0,0 +1,59 @@
+package store
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSummaryProvenance(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	embedding := []float32{0.1, 0.2}
+	for _, text := range []string{"first part", "second part"} {
+		if err := store.SaveChunk(ctx, 1, "doc.md", text, embedding, nil, "old summary"); err != nil {
+			t.Fatalf("SaveChunk failed: %v", err)
+		}
+	}
+
+	// Summaries from before provenance was tracked are untracked
+	states, err := store.GetSummaryStates(ctx, 1, false)
+	if err != nil {
+		t.Fatalf("GetSummaryStates failed: %v", err)
+	}
+	if len(states) != 1 || states[0].Summary != "old summary" || states[0].StaleReason("llama3") != SummaryUntracked {
+		t.Fatalf("Expected one untracked summary, got %+v", states)
+	}
+
+	if err := store.SaveSummary(ctx, 1, "doc.md", "new summary", "llama3"); err != nil {
+		t.Fatalf("SaveSummary failed: %v", err)
+	}
+	states, _ = store.GetSummaryStates(ctx, 1, false)
+	st := states[0]
+	if st.Summary != "new summary" || st.Model != "llama3" || st.GeneratedAt.IsZero() {
+		t.Errorf("Expected the summary and its provenance to be saved, got %+v", st)
+	}
+	if reason := st.StaleReason("llama3"); reason != "" {
+		t.Errorf("Expected a current summary, got %q", reason)
+	}
+	if reason := st.StaleReason("mistral"); reason != SummaryModelChanged {
+		t.Errorf("Expected a model change to make the summary stale, got %q", reason)
+	}
+	if reason := st.StaleReason(""); reason != "" {
+		t.Errorf("Expected an unknown model to match any, got %q", reason)
+	}
+
+	// Changing the document's content makes the summary stale
+	if err := store.SaveChunk(ctx, 1, "doc.md", "third part", embedding, nil, "new summary"); err != nil {
+		t.Fatalf("SaveChunk failed: %v", err)
+	}
+	states, _ = store.GetSummaryStates(ctx, 1, false)
+	if reason := states[0].StaleReason("llama3"); reason != SummaryContentChanged {
+		t.Errorf("Expected changed content to make the summary stale, got %q", reason)
+	}
+
+	if err := store.SaveSummary(ctx, 1, "missing.md", "summary", "llama3"); err == nil {
+		t.Error("Expected an error summarizing an unknown document")
+	}
+}
@@ -0,0 +1,49 @@
+package store
+
+import (
+	"context"
+	"fmt"
+)
+
+// SetTitleWeight sets how much a chunk's title similarity counts towards its
+// search score, between 0 and 1. A chunk with a title embedding of the query's
+// dimension scores (1-weight)*body + weight*title; other chunks score by body
+// alone. 0 ignores titles
+func (s *Store) SetTitleWeight(weight float64) {
+	s.titleWeight = weight
+}
+
+// similarity scores a chunk against a query vector, combining the similarities
+// of its body and title embeddings by the title weight
+func (s *Store) similarity(queryVec []float32, c Chunk) float64 {
+	body := cosineSimilarity(queryVec, c.Embedding)
+	if s.titleWeight <= 0 || len(c.TitleEmbedding) != len(queryVec) {
+		return body
+	}
+	return (1-s.titleWeight)*body + s.titleWeight*cosineSimilarity(queryVec, c.TitleEmbedding)
+}
+
+// SaveChunkTitles sets the heading context and its embedding of a user's
+// document chunks, in document order. See saveChunkTitles
+func (s *Store) SaveChunkTitles(ctx context.Context, userID int64, source string, titles []string, embeddings [][]float32) error {
+	return saveChunkTitles(ctx, s.db, userID, source, titles, embeddings)
+}
+
+// saveChunkTitles gives the nth chunk of a document, by id, the nth title and
+// title embedding. Chunks beyond the titles given keep theirs
+func saveChunkTitles(ctx context.Context, ex execer, userID int64, source string, titles []string, embeddings [][]float32) error {
+	if len(titles) != len(embeddings) {
+		return fmt.Errorf("got %d title embeddings for %d titles", len(embeddings), len(titles))
+	}
+
+	query := `
+		UPDATE chunks SET title = ?, title_embedding = ?
+		WHERE id = (SELECT id FROM chunks WHERE user_id = ? AND source = ? ORDER BY id LIMIT 1 OFFSET ?)
+	`
+	for i, title := range titles {
+		if _, err := ex.ExecContext(ctx, query, title, serializeEmbedding(embeddings[i]), userID, source, i); err != nil {
+			return fmt.Errorf("failed to save chunk title: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package store
+
+import (
+	"context"
+	"testing"
+)
+
+// TestTitleEmbeddings tests that a chunk whose title matches the query outranks
+// a closer body match once titles are weighted, and that chunks without a title
+// are scored by their body alone
+func TestTitleEmbeddings(t *testing.T) {
+	store, err := NewStore(t.TempDir()+"/test_titles.db", "multi")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+	userID, _ := store.CreateUser(ctx, "alice", "password123", "alice@example.com", false, false)
+
+	store.SaveChunk(ctx, userID, "guide.md", "Body close to the query", []float32{0.9, 0.1}, nil, "")
+	store.SaveChunk(ctx, userID, "guide.md", "Body far from the query", []float32{0.1, 0.9}, nil, "")
+	store.SaveChunk(ctx, userID, "notes.md", "Untitled body", []float32{0.5, 0.5}, nil, "")
+	err = store.WithTx(ctx, func(tx StoreTx) error {
+		return tx.SaveChunkTitles(ctx, userID, "guide.md",
+			[]string{"guide > Other", "guide > Query"},
+			[][]float32{{0, 1}, {1, 0}})
+	})
+	if err != nil {
+		t.Fatalf("SaveChunkTitles failed: %v", err)
+	}
+
+	query := []float32{1, 0}
+	search := func() []Chunk {
+		results, err := store.SearchByUser(ctx, userID, query, "", 3)
+		if err != nil {
+			t.Fatalf("SearchByUser failed: %v", err)
+		}
+		return results
+	}
+
+	// Without a title weight, the body decides
+	if results := search(); results[0].Text != "Body close to the query" {
+		t.Errorf("Expected the closest body first, got %q", results[0].Text)
+	}
+
+	store.SetTitleWeight(0.8)
+	results := search()
+	if results[0].Text != "Body far from the query" {
+		t.Errorf("Expected the matching title first, got %q", results[0].Text)
+	}
+	untitled := cosineSimilarity(query, []float32{0.5, 0.5})
+	for _, r := range results {
+		if r.Source == "notes.md" && (r.Score < untitled-1e-6 || r.Score > untitled+1e-6) {
+			t.Errorf("Expected the untitled chunk scored by its body, got %v", r.Score)
+		}
+	}
+
+	var title string
+	store.db.QueryRowContext(ctx, `SELECT title FROM chunks WHERE text = 'Body far from the query'`).Scan(&title)
+	if title != "guide > Query" {
+		t.Errorf("Expected the title stored in document order, got %q", title)
+	}
+
+	if err := store.SaveChunkTitles(ctx, userID, "guide.md", []string{"a"}, nil); err == nil {
+		t.Error("Expected an error when titles and embeddings differ in number")
+	}
+}
@@ -0,0 +1,79 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// SaveTranscript archives a sealed provider request
+func (s *Store) SaveTranscript(ctx context.Context, t Transcript) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO provider_transcripts (user_id, provider, model, operation, key_id, payload, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, t.UserID, t.Provider, t.Model, t.Operation, t.KeyID, t.Payload, t.CreatedAt.UTC())
+	if err != nil {
+		return fmt.Errorf("failed to save transcript: %w", err)
+	}
+	return nil
+}
+
+// GetTranscripts returns archived provider requests matching filter, oldest
+// first so an export reads in order
+func (s *Store) GetTranscripts(ctx context.Context, filter TranscriptFilter) ([]Transcript, error) {
+	query := `
+		SELECT id, user_id, provider, model, operation, key_id, payload, created_at
+		FROM provider_transcripts
+		WHERE 1 = 1`
+	var args []interface{}
+	if filter.UserID != 0 {
+		query += ` AND user_id = ?`
+		args = append(args, filter.UserID)
+	}
+	if filter.Provider != "" {
+		query += ` AND provider = ?`
+		args = append(args, filter.Provider)
+	}
+	if !filter.Since.IsZero() {
+		query += ` AND created_at >= ?`
+		args = append(args, filter.Since.UTC())
+	}
+	if !filter.Until.IsZero() {
+		query += ` AND created_at < ?`
+		args = append(args, filter.Until.UTC())
+	}
+	query += ` ORDER BY created_at, id`
+	if filter.Limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, filter.Limit)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query transcripts: %w", err)
+	}
+	defer rows.Close()
+
+	var transcripts []Transcript
+	for rows.Next() {
+		var t Transcript
+		if err := rows.Scan(&t.ID, &t.UserID, &t.Provider, &t.Model, &t.Operation, &t.KeyID, &t.Payload, &t.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan transcript: %w", err)
+		}
+		transcripts = append(transcripts, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating transcripts: %w", err)
+	}
+	return transcripts, nil
+}
+
+// DeleteTranscriptsBefore deletes archived provider requests made before a
+// time and returns how many were removed
+func (s *Store) DeleteTranscriptsBefore(ctx context.Context, before time.Time) (int64, error) {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM provider_transcripts WHERE created_at < ?`, before.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete old transcripts: %w", err)
+	}
+	return result.RowsAffected()
+}
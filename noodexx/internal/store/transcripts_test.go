@@ -0,0 +1,56 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// TestTranscripts tests archiving, filtering and expiring provider transcripts
+func TestTranscripts(t *testing.T) {
+	store, err := NewStore(t.TempDir()+"/test_transcripts.db", "multi")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+	base := time.Date(2025, 3, 1, 9, 30, 0, 123456000, time.UTC)
+	for i, tr := range []Transcript{
+		{UserID: 1, Provider: "openai", Model: "gpt-4o", Operation: "chat", KeyID: "k1", Payload: []byte{1, 2, 3}, CreatedAt: base},
+		{UserID: 2, Provider: "anthropic", Model: "claude", Operation: "chat", KeyID: "k1", Payload: []byte{4}, CreatedAt: base.Add(time.Hour)},
+		{UserID: 1, Provider: "openai", Model: "text-embedding-3-small", Operation: "embed", KeyID: "k1", Payload: []byte{5}, CreatedAt: base.Add(48 * time.Hour)},
+	} {
+		if err := store.SaveTranscript(ctx, tr); err != nil {
+			t.Fatalf("SaveTranscript %d failed: %v", i, err)
+		}
+	}
+
+	all, err := store.GetTranscripts(ctx, TranscriptFilter{})
+	if err != nil || len(all) != 3 {
+		t.Fatalf("Expected 3 transcripts, got %d (%v)", len(all), err)
+	}
+	// Payloads are opened against the time they were sealed with, so it must
+	// come back exactly
+	if !all[0].CreatedAt.Equal(base) || string(all[0].Payload) != "\x01\x02\x03" || all[0].Model != "gpt-4o" {
+		t.Errorf("Expected the first transcript unchanged, got %+v", all[0])
+	}
+
+	if got, _ := store.GetTranscripts(ctx, TranscriptFilter{UserID: 1}); len(got) != 2 {
+		t.Errorf("Expected 2 transcripts of user 1, got %d", len(got))
+	}
+	if got, _ := store.GetTranscripts(ctx, TranscriptFilter{Since: base.Add(time.Minute), Until: base.Add(24 * time.Hour)}); len(got) != 1 || got[0].Provider != "anthropic" {
+		t.Errorf("Expected the anthropic transcript in the range, got %+v", got)
+	}
+	if got, _ := store.GetTranscripts(ctx, TranscriptFilter{Provider: "openai", Limit: 1}); len(got) != 1 || got[0].Operation != "chat" {
+		t.Errorf("Expected the oldest openai transcript, got %+v", got)
+	}
+
+	removed, err := store.DeleteTranscriptsBefore(ctx, base.Add(24*time.Hour))
+	if err != nil || removed != 2 {
+		t.Errorf("Expected 2 transcripts expired, got %d (%v)", removed, err)
+	}
+	if got, _ := store.GetTranscripts(ctx, TranscriptFilter{}); len(got) != 1 || got[0].Operation != "embed" {
+		t.Errorf("Expected only the recent transcript kept, got %+v", got)
+	}
+}
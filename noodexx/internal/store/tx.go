@@ -0,0 +1,108 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// execer is satisfied by both *sql.DB and *sql.Tx so write helpers can run
+// either directly or as part of a unit of work
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// StoreTx exposes the store operations that can participate in a unit of work
+// All calls made through a StoreTx commit or roll back together
+type StoreTx interface {
+	SaveChunk(ctx context.Context, userID int64, source, text string, embedding []float32, tags []string, summary string) error
+	DeleteChunksBySource(ctx context.Context, userID int64, source string) error
+	DeleteDocument(ctx context.Context, userID int64, source string) error
+	CreateDocument(ctx context.Context, userID int64, source, visibility string) error
+	SetIngestWarning(ctx context.Context, userID int64, source, warning string) error
+	SetDocumentEncoding(ctx context.Context, userID int64, source, encoding, changes string) error
+	SetSourceVisibility(ctx context.Context, userID int64, source, visibility string) error
+	SaveChunkTitles(ctx context.Context, userID int64, source string, titles []string, embeddings [][]float32) error
+	SaveDocumentContent(ctx context.Context, userID int64, source, content, chunker string) error
+	ReplaceChunks(ctx context.Context, userID int64, source string, texts []string, embeddings [][]float32) error
+	AddAuditEntry(ctx context.Context, opType, details, userCtx string) error
+	DeleteUser(ctx context.Context, userID int64) error
+}
+
+// txStore implements StoreTx on top of a database transaction
+type txStore struct {
+	tx             *sql.Tx
+	embeddingModel string
+}
+
+func (t *txStore) SaveChunk(ctx context.Context, userID int64, source, text string, embedding []float32, tags []string, summary string) error {
+	return saveChunk(ctx, t.tx, t.embeddingModel, userID, source, text, embedding, tags, summary)
+}
+
+func (t *txStore) DeleteChunksBySource(ctx context.Context, userID int64, source string) error {
+	return deleteChunksBySource(ctx, t.tx, userID, source)
+}
+
+func (t *txStore) DeleteDocument(ctx context.Context, userID int64, source string) error {
+	return deleteDocument(ctx, t.tx, userID, source)
+}
+
+func (t *txStore) CreateDocument(ctx context.Context, userID int64, source, visibility string) error {
+	return createDocument(ctx, t.tx, userID, source, visibility)
+}
+
+func (t *txStore) SetIngestWarning(ctx context.Context, userID int64, source, warning string) error {
+	return setIngestWarning(ctx, t.tx, userID, source, warning)
+}
+
+func (t *txStore) SetDocumentEncoding(ctx context.Context, userID int64, source, encoding, changes string) error {
+	return setDocumentEncoding(ctx, t.tx, userID, source, encoding, changes)
+}
+
+func (t *txStore) SetSourceVisibility(ctx context.Context, userID int64, source, visibility string) error {
+	return setSourceVisibility(ctx, t.tx, userID, source, visibility)
+}
+
+func (t *txStore) SaveChunkTitles(ctx context.Context, userID int64, source string, titles []string, embeddings [][]float32) error {
+	return saveChunkTitles(ctx, t.tx, userID, source, titles, embeddings)
+}
+
+func (t *txStore) SaveDocumentContent(ctx context.Context, userID int64, source, content, chunker string) error {
+	return saveDocumentContent(ctx, t.tx, userID, source, content, chunker)
+}
+
+func (t *txStore) ReplaceChunks(ctx context.Context, userID int64, source string, texts []string, embeddings [][]float32) error {
+	return replaceChunks(ctx, t.tx, t.embeddingModel, userID, source, texts, embeddings)
+}
+
+func (t *txStore) AddAuditEntry(ctx context.Context, opType, details, userCtx string) error {
+	return addAuditEntry(ctx, t.tx, opType, details, userCtx)
+}
+
+func (t *txStore) DeleteUser(ctx context.Context, userID int64) error {
+	return deleteUser(ctx, t.tx, userID)
+}
+
+// WithTx runs fn as a single unit of work. The transaction is committed if fn
+// returns nil and rolled back if fn returns an error or panics.
+// Callers should finish slow work (embedding, network calls) before calling WithTx
+// so the write transaction is held only for the database operations themselves.
+func (s *Store) WithTx(ctx context.Context, fn func(tx StoreTx) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := fn(&txStore{tx: tx, embeddingModel: s.embeddingModel}); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	// Chunks the unit of work saved go into the ANN index; one it misses is still searched in full
+	s.indexNewChunks(ctx)
+	return nil
+}
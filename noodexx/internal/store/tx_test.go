@@ -0,0 +1,122 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+// TestWithTxCommit tests that all operations in a unit of work are committed together
+func TestWithTxCommit(t *testing.T) {
+	tmpFile := "test_withtx_commit.db"
+	defer os.Remove(tmpFile)
+
+	store, err := NewStore(tmpFile, "multi")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+
+	userID, err := store.CreateUser(ctx, "txuser", "password", "tx@test.com", false, false)
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	if err := store.SaveChunk(ctx, userID, "doc.txt", "old text", []float32{1, 0, 0}, nil, ""); err != nil {
+		t.Fatalf("Failed to save chunk: %v", err)
+	}
+
+	err = store.WithTx(ctx, func(tx StoreTx) error {
+		if err := tx.DeleteChunksBySource(ctx, userID, "doc.txt"); err != nil {
+			return err
+		}
+		if err := tx.SaveChunk(ctx, userID, "doc.txt", "new text", []float32{1, 0, 0}, nil, ""); err != nil {
+			return err
+		}
+		return tx.AddAuditEntry(ctx, "ingest", "Text: doc.txt", "")
+	})
+	if err != nil {
+		t.Fatalf("WithTx failed: %v", err)
+	}
+
+	results, err := store.SearchByUser(ctx, userID, []float32{1, 0, 0}, "", 10)
+	if err != nil {
+		t.Fatalf("SearchByUser failed: %v", err)
+	}
+	if len(results) != 1 || results[0].Text != "new text" {
+		t.Errorf("Expected only the replacement chunk, got %+v", results)
+	}
+
+	entries, err := store.GetAuditLog(ctx, "ingest", time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatalf("GetAuditLog failed: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("Expected 1 audit entry, got %d", len(entries))
+	}
+}
+
+// TestWithTxRollback tests that a failed unit of work leaves no partial changes
+func TestWithTxRollback(t *testing.T) {
+	tmpFile := "test_withtx_rollback.db"
+	defer os.Remove(tmpFile)
+
+	store, err := NewStore(tmpFile, "multi")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+
+	userID, err := store.CreateUser(ctx, "txuser", "password", "tx@test.com", false, false)
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	if err := store.SaveChunk(ctx, userID, "doc.txt", "old text", []float32{1, 0, 0}, nil, ""); err != nil {
+		t.Fatalf("Failed to save chunk: %v", err)
+	}
+
+	errBoom := errors.New("boom")
+	err = store.WithTx(ctx, func(tx StoreTx) error {
+		if err := tx.DeleteChunksBySource(ctx, userID, "doc.txt"); err != nil {
+			return err
+		}
+		if err := tx.AddAuditEntry(ctx, "delete", "Source: doc.txt", ""); err != nil {
+			return err
+		}
+		return errBoom
+	})
+	if !errors.Is(err, errBoom) {
+		t.Fatalf("Expected WithTx to return the callback error, got %v", err)
+	}
+
+	results, err := store.SearchByUser(ctx, userID, []float32{1, 0, 0}, "", 10)
+	if err != nil {
+		t.Fatalf("SearchByUser failed: %v", err)
+	}
+	if len(results) != 1 || results[0].Text != "old text" {
+		t.Errorf("Expected original chunk to survive rollback, got %+v", results)
+	}
+
+	entries, err := store.GetAuditLog(ctx, "delete", time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatalf("GetAuditLog failed: %v", err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("Expected no audit entries after rollback, got %d", len(entries))
+	}
+
+	// Deleting a missing user inside a unit of work surfaces the error
+	err = store.WithTx(ctx, func(tx StoreTx) error {
+		return tx.DeleteUser(ctx, 9999)
+	})
+	if err == nil {
+		t.Error("Expected error deleting a missing user")
+	}
+}
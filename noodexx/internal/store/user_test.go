@@ -2,8 +2,10 @@ package store
 
 import (
 	"context"
+	"fmt"
 	"os"
 	"testing"
+	"time"
 )
 
 func TestPasswordHashing(t *testing.T) {
@@ -67,7 +69,7 @@ func TestAccountLockout(t *testing.T) {
 	username := "testuser"
 
 	// Initially, account should not be locked
-	locked, _ := store.IsAccountLocked(ctx, username)
+	locked, _ := store.IsAccountLocked(ctx, username, 5, 15*time.Minute)
 	if locked {
 		t.Error("Account should not be locked initially")
 	}
@@ -80,7 +82,7 @@ func TestAccountLockout(t *testing.T) {
 		}
 	}
 
-	locked, _ = store.IsAccountLocked(ctx, username)
+	locked, _ = store.IsAccountLocked(ctx, username, 5, 15*time.Minute)
 	if locked {
 		t.Error("Account should not be locked after 4 attempts")
 	}
@@ -91,7 +93,7 @@ func TestAccountLockout(t *testing.T) {
 		t.Fatalf("Failed to record 5th failed login attempt: %v", err)
 	}
 
-	locked, lockoutExpires := store.IsAccountLocked(ctx, username)
+	locked, lockoutExpires := store.IsAccountLocked(ctx, username, 5, 15*time.Minute)
 	if !locked {
 		t.Error("Account should be locked after 5 attempts")
 	}
@@ -106,7 +108,7 @@ func TestAccountLockout(t *testing.T) {
 	}
 
 	// Account should no longer be locked
-	locked, _ = store.IsAccountLocked(ctx, username)
+	locked, _ = store.IsAccountLocked(ctx, username, 5, 15*time.Minute)
 	if locked {
 		t.Error("Account should not be locked after clearing failed logins")
 	}
@@ -144,13 +146,13 @@ func TestAccountLockoutMultipleUsers(t *testing.T) {
 	}
 
 	// user1 should be locked
-	locked1, _ := store.IsAccountLocked(ctx, user1)
+	locked1, _ := store.IsAccountLocked(ctx, user1, 5, 15*time.Minute)
 	if !locked1 {
 		t.Error("user1 should be locked after 5 attempts")
 	}
 
 	// user2 should not be locked
-	locked2, _ := store.IsAccountLocked(ctx, user2)
+	locked2, _ := store.IsAccountLocked(ctx, user2, 5, 15*time.Minute)
 	if locked2 {
 		t.Error("user2 should not be locked after 2 attempts")
 	}
@@ -162,14 +164,269 @@ func TestAccountLockoutMultipleUsers(t *testing.T) {
 	}
 
 	// user1 should no longer be locked
-	locked1, _ = store.IsAccountLocked(ctx, user1)
+	locked1, _ = store.IsAccountLocked(ctx, user1, 5, 15*time.Minute)
 	if locked1 {
 		t.Error("user1 should not be locked after clearing")
 	}
 
 	// user2 should still not be locked
-	locked2, _ = store.IsAccountLocked(ctx, user2)
+	locked2, _ = store.IsAccountLocked(ctx, user2, 5, 15*time.Minute)
 	if locked2 {
 		t.Error("user2 should still not be locked")
 	}
 }
+
+// TestAccountLockoutPolicy tests that the lockout threshold and window are honored
+func TestAccountLockoutPolicy(t *testing.T) {
+	dbPath := "test_account_lockout_policy.db"
+	defer os.Remove(dbPath)
+
+	store, err := NewStore(dbPath, "single")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+	username := "testuser"
+
+	for i := 0; i < 3; i++ {
+		if err := store.RecordFailedLogin(ctx, username); err != nil {
+			t.Fatalf("Failed to record failed login: %v", err)
+		}
+	}
+
+	if count, err := store.CountFailedLogins(ctx, username, time.Now().Add(-time.Hour)); err != nil || count != 3 {
+		t.Errorf("Expected 3 failed logins, got %d (%v)", count, err)
+	}
+
+	// Three attempts lock with a threshold of 3 but not with the default 5
+	if locked, _ := store.IsAccountLocked(ctx, username, 5, 15*time.Minute); locked {
+		t.Error("Account should not be locked with a threshold of 5")
+	}
+	locked, until := store.IsAccountLocked(ctx, username, 3, time.Hour)
+	if !locked {
+		t.Fatal("Account should be locked with a threshold of 3")
+	}
+	if remaining := time.Until(until); remaining < 59*time.Minute || remaining > time.Hour {
+		t.Errorf("Lockout should last the one hour window, %v remaining", remaining)
+	}
+
+	// Attempts older than the window do not count
+	if locked, _ := store.IsAccountLocked(ctx, username, 3, time.Nanosecond); locked {
+		t.Error("Attempts outside the window should not lock the account")
+	}
+
+	// A threshold of 0 disables lockout
+	if locked, _ := store.IsAccountLocked(ctx, username, 0, time.Hour); locked {
+		t.Error("A threshold of 0 should disable lockout")
+	}
+}
+
+// TestCompleteOnboarding tests that onboarding completion is tracked per user
+func TestCompleteOnboarding(t *testing.T) {
+	tmpFile := "test_onboarding.db"
+	defer os.Remove(tmpFile)
+
+	store, err := NewStore(tmpFile, "multi")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+
+	user1, err := store.CreateUser(ctx, "newbie1", "password", "newbie1@test.com", false, false)
+	if err != nil {
+		t.Fatalf("Failed to create user1: %v", err)
+	}
+	user2, err := store.CreateUser(ctx, "newbie2", "password", "newbie2@test.com", false, false)
+	if err != nil {
+		t.Fatalf("Failed to create user2: %v", err)
+	}
+
+	// New users have not completed onboarding
+	user, err := store.GetUserByID(ctx, user1)
+	if err != nil {
+		t.Fatalf("Failed to get user1: %v", err)
+	}
+	if user.OnboardingCompleted {
+		t.Error("New user should not have completed onboarding")
+	}
+
+	if err := store.CompleteOnboarding(ctx, user1); err != nil {
+		t.Fatalf("CompleteOnboarding failed: %v", err)
+	}
+	// Completing again is a no-op
+	if err := store.CompleteOnboarding(ctx, user1); err != nil {
+		t.Fatalf("CompleteOnboarding failed on second call: %v", err)
+	}
+
+	user, err = store.GetUserByUsername(ctx, "newbie1")
+	if err != nil {
+		t.Fatalf("Failed to get user1: %v", err)
+	}
+	if !user.OnboardingCompleted {
+		t.Error("user1 should have completed onboarding")
+	}
+
+	// Other users are unaffected
+	user, err = store.GetUserByID(ctx, user2)
+	if err != nil {
+		t.Fatalf("Failed to get user2: %v", err)
+	}
+	if user.OnboardingCompleted {
+		t.Error("user2 should not have completed onboarding")
+	}
+
+	if err := store.CompleteOnboarding(ctx, 9999); err == nil {
+		t.Error("Expected error completing onboarding for a missing user")
+	}
+}
+
+func TestSetUserAdmin(t *testing.T) {
+	tmpFile := "test_set_user_admin.db"
+	defer os.Remove(tmpFile)
+
+	store, err := NewStore(tmpFile, "multi")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+
+	userID, err := store.CreateUser(ctx, "directory-user", "password", "", false, false)
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	// Accounts without an email don't collide with each other
+	if _, err := store.CreateUser(ctx, "directory-user2", "password", "", false, false); err != nil {
+		t.Fatalf("Failed to create a second user without email: %v", err)
+	}
+
+	if err := store.SetUserAdmin(ctx, userID, true); err != nil {
+		t.Fatalf("SetUserAdmin failed: %v", err)
+	}
+	user, err := store.GetUserByID(ctx, userID)
+	if err != nil {
+		t.Fatalf("Failed to get user: %v", err)
+	}
+	if !user.IsAdmin {
+		t.Error("Expected user to be an admin")
+	}
+
+	if err := store.SetUserAdmin(ctx, userID, false); err != nil {
+		t.Fatalf("SetUserAdmin failed: %v", err)
+	}
+	if user, _ = store.GetUserByID(ctx, userID); user.IsAdmin {
+		t.Error("Expected admin rights to be revoked")
+	}
+
+	if err := store.SetUserAdmin(ctx, 9999, true); err == nil {
+		t.Error("Expected error for a missing user")
+	}
+}
+
+// TestPasswordHistory tests that replaced passwords are remembered for reuse checks
+func TestSetTemporaryPassword(t *testing.T) {
+	tmpFile := "test_temporary_password.db"
+	defer os.Remove(tmpFile)
+
+	store, err := NewStore(tmpFile, "multi")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+
+	userID, err := store.CreateUser(ctx, "reset-user", "first-password", "", false, false)
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	// A temporary password works for sign-in but must be changed
+	if err := store.SetTemporaryPassword(ctx, userID, "temporary-password"); err != nil {
+		t.Fatalf("SetTemporaryPassword failed: %v", err)
+	}
+	user, err := store.ValidateCredentials(ctx, "reset-user", "temporary-password")
+	if err != nil {
+		t.Fatalf("Expected the temporary password to be valid: %v", err)
+	}
+	if !user.MustChangePassword {
+		t.Error("Expected must_change_password to be set")
+	}
+
+	// Changing it clears the flag
+	if err := store.UpdatePassword(ctx, userID, "chosen-password"); err != nil {
+		t.Fatalf("UpdatePassword failed: %v", err)
+	}
+	user, err = store.GetUserByID(ctx, userID)
+	if err != nil {
+		t.Fatalf("GetUserByID failed: %v", err)
+	}
+	if user.MustChangePassword {
+		t.Error("Expected must_change_password to be cleared")
+	}
+}
+
+func TestPasswordHistory(t *testing.T) {
+	tmpFile := "test_password_history.db"
+	defer os.Remove(tmpFile)
+
+	store, err := NewStore(tmpFile, "multi")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+
+	userID, err := store.CreateUser(ctx, "history-user", "first-password", "", false, false)
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	for _, password := range []string{"second-password", "third-password"} {
+		if err := store.UpdatePassword(ctx, userID, password); err != nil {
+			t.Fatalf("UpdatePassword failed: %v", err)
+		}
+	}
+
+	tests := []struct {
+		password string
+		n        int
+		want     bool
+	}{
+		{"third-password", 1, true},   // current password
+		{"second-password", 1, false}, // only the current password is checked
+		{"second-password", 2, true},
+		{"first-password", 2, false},
+		{"first-password", 3, true},
+		{"never-used", 24, false},
+		{"third-password", 0, false}, // history check disabled
+	}
+	for _, tt := range tests {
+		got, err := store.IsRecentPassword(ctx, userID, tt.password, tt.n)
+		if err != nil {
+			t.Fatalf("IsRecentPassword failed: %v", err)
+		}
+		if got != tt.want {
+			t.Errorf("IsRecentPassword(%q, %d) = %v, want %v", tt.password, tt.n, got, tt.want)
+		}
+	}
+
+	// History is trimmed to MaxPasswordHistory entries
+	for i := 0; i < MaxPasswordHistory+2; i++ {
+		if err := store.UpdatePassword(ctx, userID, fmt.Sprintf("password-%d", i)); err != nil {
+			t.Fatalf("UpdatePassword failed: %v", err)
+		}
+	}
+	var count int
+	if err := store.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM password_history WHERE user_id = ?`, userID).Scan(&count); err != nil {
+		t.Fatalf("Failed to count history: %v", err)
+	}
+	if count != MaxPasswordHistory {
+		t.Errorf("Expected %d history entries, got %d", MaxPasswordHistory, count)
+	}
+}
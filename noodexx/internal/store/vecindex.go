@@ -0,0 +1,381 @@
+package store
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+	"os"
+	"strings"
+	"sync"
+)
+
+// vectorSnapshotMagic identifies a vector index snapshot file and its format version
+// Version 1 snapshots, written before the index held ANN lists, still load without them
+const (
+	vectorSnapshotMagic   = "NDXVEC02"
+	vectorSnapshotMagicV1 = "NDXVEC01"
+)
+
+// vectorRepairBatchSize is the number of embeddings loaded per query when repairing the index
+const vectorRepairBatchSize = 500
+
+// errBadSnapshot is returned when a snapshot file is truncated or fails its checksum
+var errBadSnapshot = errors.New("vector index snapshot is corrupt")
+
+// vectorIndex is an in-memory cache of chunk embeddings keyed by chunk ID, and the ANN
+// lists built over them (see ann.go). Chunk embeddings are never updated in place, so an
+// entry stays valid until its chunk is deleted
+type vectorIndex struct {
+	mu      sync.RWMutex
+	catchUp sync.Mutex // Held while indexNewChunks reads new chunks
+	path    string
+	vectors map[int64][]float32
+	ann     annLists
+}
+
+// get returns the cached embedding for a chunk
+func (v *vectorIndex) get(id int64) ([]float32, bool) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	vec, ok := v.vectors[id]
+	return vec, ok
+}
+
+// put caches the embedding for a chunk
+func (v *vectorIndex) put(id int64, vec []float32) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.vectors[id] = vec
+}
+
+// size returns the number of cached embeddings
+func (v *vectorIndex) size() int {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return len(v.vectors)
+}
+
+// VectorIndexReport describes the state of the vector index after loading or repair
+type VectorIndexReport struct {
+	Loaded   int   // Embeddings read from the snapshot
+	Added    int   // Embeddings loaded from the chunks table because they were missing
+	Removed  int   // Stale entries dropped because their chunks no longer exist
+	Size     int   // Embeddings held after the operation
+	Lists    int   // ANN lists held after the operation, 0 until RebuildANNIndex builds them
+	Snapshot error // Why the snapshot could not be used, if it was missing or corrupt
+}
+
+// OpenVectorIndex enables the in-memory vector index backed by a snapshot file at path.
+// The snapshot is memory-mapped and decoded when present; a missing or corrupt snapshot
+// starts an empty index instead of failing. Call RepairVectorIndex afterwards to reconcile
+// the index with the chunks table. Until then, searches load missing embeddings on demand.
+// ann controls when searches go through the ANN lists the snapshot holds or
+// RebuildANNIndex builds
+func (s *Store) OpenVectorIndex(path string, ann ANNOptions) (*VectorIndexReport, error) {
+	if ann.Probes <= 0 {
+		ann.Probes = annDefaultProbes
+	}
+	index := &vectorIndex{path: path, vectors: make(map[int64][]float32), ann: annLists{opts: ann}}
+	report := &VectorIndexReport{}
+
+	data, release, err := mapSnapshotFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to open vector index snapshot: %w", err)
+		}
+		report.Snapshot = err
+	} else {
+		err = decodeVectorSnapshot(data, index)
+		release()
+		if err != nil {
+			// Start over rather than trust a partially decoded snapshot
+			index.vectors = make(map[int64][]float32)
+			index.ann = annLists{opts: ann}
+			report.Snapshot = err
+		}
+	}
+
+	report.Loaded = len(index.vectors)
+	report.Size = report.Loaded
+	report.Lists = len(index.ann.lists)
+	s.vindex = index
+	return report, nil
+}
+
+// RepairVectorIndex reconciles the vector index with the chunks table, dropping entries
+// and ANN list IDs for deleted chunks, loading embeddings for chunks the index does not
+// hold yet and adding chunks newer than the ANN lists to them
+func (s *Store) RepairVectorIndex(ctx context.Context) (*VectorIndexReport, error) {
+	index := s.vindex
+	if index == nil {
+		return nil, fmt.Errorf("vector index is not enabled")
+	}
+
+	rows, err := s.db.QueryContext(ctx, "SELECT id FROM chunks")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list chunk ids: %w", err)
+	}
+	live := make(map[int64]bool)
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan chunk id: %w", err)
+		}
+		live[id] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating chunk ids: %w", err)
+	}
+
+	report := &VectorIndexReport{}
+
+	var missing []int64
+	index.mu.Lock()
+	for id := range index.vectors {
+		if !live[id] {
+			delete(index.vectors, id)
+			report.Removed++
+		}
+	}
+	for id := range live {
+		if _, ok := index.vectors[id]; !ok {
+			missing = append(missing, id)
+		}
+	}
+	index.ann.drop(live)
+	index.mu.Unlock()
+
+	for start := 0; start < len(missing); start += vectorRepairBatchSize {
+		end := start + vectorRepairBatchSize
+		if end > len(missing) {
+			end = len(missing)
+		}
+		loaded, err := s.loadEmbeddings(ctx, missing[start:end])
+		if err != nil {
+			return nil, err
+		}
+		report.Added += loaded
+	}
+	if err := s.indexNewChunks(ctx); err != nil {
+		return nil, err
+	}
+
+	index.mu.RLock()
+	report.Size, report.Lists = len(index.vectors), len(index.ann.lists)
+	index.mu.RUnlock()
+	return report, nil
+}
+
+// SnapshotVectorIndex writes the vector index to its snapshot file
+// The snapshot is written to a temporary file and renamed into place so a crash
+// mid-write never leaves a truncated snapshot behind
+func (s *Store) SnapshotVectorIndex() error {
+	index := s.vindex
+	if index == nil {
+		return nil
+	}
+
+	tmpPath := index.path + ".tmp"
+	f, err := os.Create(tmpPath)
+	if err != nil {
+		return fmt.Errorf("failed to create vector index snapshot: %w", err)
+	}
+
+	index.mu.RLock()
+	err = encodeVectorSnapshot(f, index)
+	index.mu.RUnlock()
+
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write vector index snapshot: %w", err)
+	}
+
+	if err := os.Rename(tmpPath, index.path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to replace vector index snapshot: %w", err)
+	}
+	return nil
+}
+
+// indexNewChunks reads the chunks saved since the ANN lists last looked, caching their
+// embeddings and adding those of the indexed dimension to the lists. It runs after each
+// write that saves chunks and is the one place writes reach the vector index; deletes
+// need nothing, since searches no longer find deleted chunks and repair drops them. When
+// another call is already catching up, it returns at once and leaves the new chunks to
+// that one or the next. Until then, searches read chunks newer than the lists in full
+// and load their embeddings on demand, so none is ever missed
+func (s *Store) indexNewChunks(ctx context.Context) error {
+	index := s.vindex
+	if index == nil || !index.catchUp.TryLock() {
+		return nil
+	}
+	defer index.catchUp.Unlock()
+
+	index.mu.RLock()
+	dim, lastID := index.ann.dim, index.ann.lastID
+	index.mu.RUnlock()
+	if dim == 0 {
+		return nil
+	}
+
+	return s.scanEmbeddings(ctx, 0, lastID, math.MaxInt64, index.add)
+}
+
+// add caches the embeddings of new chunks and puts those of the indexed dimension into
+// the ANN lists of their nearest centroids
+func (v *vectorIndex) add(ids []int64, vecs [][]float32) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	for i, id := range ids {
+		v.vectors[id] = vecs[i]
+		v.ann.add(id, vecs[i])
+	}
+}
+
+// fillEmbeddings sets the embedding of each chunk in page from the vector index,
+// loading any embeddings the index does not hold yet from the chunks table
+func (s *Store) fillEmbeddings(ctx context.Context, page []Chunk) error {
+	var missing []int64
+	for _, c := range page {
+		if _, ok := s.vindex.get(c.ID); !ok {
+			missing = append(missing, c.ID)
+		}
+	}
+	if len(missing) > 0 {
+		if _, err := s.loadEmbeddings(ctx, missing); err != nil {
+			return err
+		}
+	}
+
+	for i := range page {
+		page[i].Embedding, _ = s.vindex.get(page[i].ID)
+	}
+	return nil
+}
+
+// loadEmbeddings reads the embeddings of the given chunks into the vector index
+// and returns how many were found
+func (s *Store) loadEmbeddings(ctx context.Context, ids []int64) (int, error) {
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(ids)), ",")
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+
+	rows, err := s.reader().QueryContext(ctx, "SELECT id, embedding FROM chunks WHERE id IN ("+placeholders+")", args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load embeddings: %w", err)
+	}
+	defer rows.Close()
+
+	loaded := 0
+	for rows.Next() {
+		var id int64
+		var embeddingBytes []byte
+		if err := rows.Scan(&id, &embeddingBytes); err != nil {
+			return 0, fmt.Errorf("failed to scan embedding: %w", err)
+		}
+		s.vindex.put(id, deserializeEmbedding(embeddingBytes))
+		loaded++
+	}
+
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("error iterating embeddings: %w", err)
+	}
+	return loaded, nil
+}
+
+// encodeVectorSnapshot writes the index in the snapshot format: magic, entry count, then
+// per entry the chunk ID, dimension count and little-endian float32 values, then the ANN
+// lists (see encodeANNLists), followed by a CRC-32 of everything before it
+func encodeVectorSnapshot(w io.Writer, index *vectorIndex) error {
+	checksum := crc32.NewIEEE()
+	bw := bufio.NewWriter(io.MultiWriter(w, checksum))
+
+	if _, err := bw.WriteString(vectorSnapshotMagic); err != nil {
+		return err
+	}
+	if err := binary.Write(bw, binary.LittleEndian, uint64(len(index.vectors))); err != nil {
+		return err
+	}
+	for id, vec := range index.vectors {
+		if err := binary.Write(bw, binary.LittleEndian, id); err != nil {
+			return err
+		}
+		if err := binary.Write(bw, binary.LittleEndian, uint32(len(vec))); err != nil {
+			return err
+		}
+		if _, err := bw.Write(serializeEmbedding(vec)); err != nil {
+			return err
+		}
+	}
+	if err := encodeANNLists(bw, &index.ann); err != nil {
+		return err
+	}
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+
+	return binary.Write(w, binary.LittleEndian, checksum.Sum32())
+}
+
+// decodeVectorSnapshot reads a snapshot produced by encodeVectorSnapshot into index
+func decodeVectorSnapshot(data []byte, index *vectorIndex) error {
+	if len(data) < len(vectorSnapshotMagic)+8+4 {
+		return errBadSnapshot
+	}
+	magic := string(data[:len(vectorSnapshotMagic)])
+	if magic != vectorSnapshotMagic && magic != vectorSnapshotMagicV1 {
+		return errBadSnapshot
+	}
+
+	body := data[:len(data)-4]
+	if crc32.ChecksumIEEE(body) != binary.LittleEndian.Uint32(data[len(data)-4:]) {
+		return errBadSnapshot
+	}
+
+	pos := len(vectorSnapshotMagic)
+	count := binary.LittleEndian.Uint64(body[pos:])
+	pos += 8
+
+	for i := uint64(0); i < count; i++ {
+		if len(body)-pos < 12 {
+			return errBadSnapshot
+		}
+		id := int64(binary.LittleEndian.Uint64(body[pos:]))
+		dims := int(binary.LittleEndian.Uint32(body[pos+8:]))
+		pos += 12
+
+		if dims < 0 || len(body)-pos < dims*4 {
+			return errBadSnapshot
+		}
+		index.vectors[id] = deserializeEmbedding(body[pos : pos+dims*4])
+		pos += dims * 4
+	}
+
+	if magic == vectorSnapshotMagic {
+		n, err := decodeANNLists(body[pos:], &index.ann)
+		if err != nil {
+			return err
+		}
+		pos += n
+	}
+
+	if pos != len(body) {
+		return errBadSnapshot
+	}
+	return nil
+}
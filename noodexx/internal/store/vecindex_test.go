@@ -0,0 +1,172 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"hash/crc32"
+	"os"
+	"testing"
+)
+
+// TestVectorIndexWarmStart tests snapshotting, reloading and repairing the vector index
+func TestVectorIndexWarmStart(t *testing.T) {
+	tmpFile := "test_vecindex.db"
+	snapshot := "test_vecindex.vecidx"
+	defer os.Remove(tmpFile)
+	defer os.Remove(snapshot)
+
+	store, err := NewStore(tmpFile, "multi")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+
+	ctx := context.Background()
+
+	userID, err := store.CreateUser(ctx, "vecuser", "password", "vec@test.com", false, false)
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	if err := store.SaveChunk(ctx, userID, "a.txt", "alpha", []float32{1, 0, 0}, nil, ""); err != nil {
+		t.Fatalf("Failed to save chunk: %v", err)
+	}
+	if err := store.SaveChunk(ctx, userID, "b.txt", "beta", []float32{0, 1, 0}, nil, ""); err != nil {
+		t.Fatalf("Failed to save chunk: %v", err)
+	}
+
+	report, err := store.OpenVectorIndex(snapshot, ANNOptions{})
+	if err != nil {
+		t.Fatalf("OpenVectorIndex failed: %v", err)
+	}
+	if report.Snapshot == nil || report.Loaded != 0 {
+		t.Errorf("Expected a cold start without a snapshot, got %+v", report)
+	}
+
+	// Searches load missing embeddings on demand and still rank correctly
+	results, err := store.SearchByUser(ctx, userID, []float32{0, 1, 0}, "", 1)
+	if err != nil {
+		t.Fatalf("SearchByUser failed: %v", err)
+	}
+	if len(results) != 1 || results[0].Text != "beta" {
+		t.Errorf("Expected beta as the best match, got %+v", results)
+	}
+	if store.vindex.size() != 2 {
+		t.Errorf("Expected 2 cached embeddings after search, got %d", store.vindex.size())
+	}
+
+	if err := store.SnapshotVectorIndex(); err != nil {
+		t.Fatalf("SnapshotVectorIndex failed: %v", err)
+	}
+
+	// Change the library behind the snapshot's back
+	if err := store.DeleteChunksBySource(ctx, userID, "a.txt"); err != nil {
+		t.Fatalf("Failed to delete source: %v", err)
+	}
+	if err := store.SaveChunk(ctx, userID, "c.txt", "gamma", []float32{0, 0, 1}, nil, ""); err != nil {
+		t.Fatalf("Failed to save chunk: %v", err)
+	}
+	store.Close()
+
+	store, err = NewStore(tmpFile, "multi")
+	if err != nil {
+		t.Fatalf("Failed to reopen store: %v", err)
+	}
+	defer store.Close()
+
+	report, err = store.OpenVectorIndex(snapshot, ANNOptions{})
+	if err != nil {
+		t.Fatalf("OpenVectorIndex failed: %v", err)
+	}
+	if report.Snapshot != nil || report.Loaded != 2 {
+		t.Errorf("Expected 2 embeddings from the snapshot, got %+v", report)
+	}
+
+	report, err = store.RepairVectorIndex(ctx)
+	if err != nil {
+		t.Fatalf("RepairVectorIndex failed: %v", err)
+	}
+	if report.Added != 1 || report.Removed != 1 || report.Size != 2 {
+		t.Errorf("Expected one added and one removed embedding, got %+v", report)
+	}
+
+	results, err = store.SearchByUser(ctx, userID, []float32{0, 0, 1}, "", 1)
+	if err != nil {
+		t.Fatalf("SearchByUser failed: %v", err)
+	}
+	if len(results) != 1 || results[0].Text != "gamma" {
+		t.Errorf("Expected gamma as the best match, got %+v", results)
+	}
+}
+
+// TestVectorIndexCorruptSnapshot tests that a damaged snapshot is ignored rather than trusted
+func TestVectorIndexCorruptSnapshot(t *testing.T) {
+	tmpFile := "test_vecindex_corrupt.db"
+	snapshot := "test_vecindex_corrupt.vecidx"
+	defer os.Remove(tmpFile)
+	defer os.Remove(snapshot)
+
+	store, err := NewStore(tmpFile, "multi")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	if _, err := store.OpenVectorIndex(snapshot, ANNOptions{}); err != nil {
+		t.Fatalf("OpenVectorIndex failed: %v", err)
+	}
+	store.vindex.put(1, []float32{1, 2, 3})
+	if err := store.SnapshotVectorIndex(); err != nil {
+		t.Fatalf("SnapshotVectorIndex failed: %v", err)
+	}
+
+	// Flip a byte inside the entry so the checksum no longer matches
+	data, err := os.ReadFile(snapshot)
+	if err != nil {
+		t.Fatalf("Failed to read snapshot: %v", err)
+	}
+	data[len(vectorSnapshotMagic)+10] ^= 0xff
+	if err := os.WriteFile(snapshot, data, 0644); err != nil {
+		t.Fatalf("Failed to write snapshot: %v", err)
+	}
+
+	report, err := store.OpenVectorIndex(snapshot, ANNOptions{})
+	if err != nil {
+		t.Fatalf("OpenVectorIndex failed: %v", err)
+	}
+	if report.Snapshot != errBadSnapshot || report.Loaded != 0 {
+		t.Errorf("Expected the corrupt snapshot to be rejected, got %+v", report)
+	}
+}
+
+// TestVectorIndexSnapshotV1 tests that a snapshot written before the index held ANN lists still loads
+func TestVectorIndexSnapshotV1(t *testing.T) {
+	tmpFile := "test_vecindex_v1.db"
+	snapshot := "test_vecindex_v1.vecidx"
+	defer os.Remove(tmpFile)
+	defer os.Remove(snapshot)
+
+	store, err := NewStore(tmpFile, "multi")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	var buf bytes.Buffer
+	buf.WriteString(vectorSnapshotMagicV1)
+	binary.Write(&buf, binary.LittleEndian, uint64(1))
+	binary.Write(&buf, binary.LittleEndian, int64(1))
+	binary.Write(&buf, binary.LittleEndian, uint32(3))
+	buf.Write(serializeEmbedding([]float32{1, 2, 3}))
+	binary.Write(&buf, binary.LittleEndian, crc32.ChecksumIEEE(buf.Bytes()))
+	if err := os.WriteFile(snapshot, buf.Bytes(), 0644); err != nil {
+		t.Fatalf("Failed to write snapshot: %v", err)
+	}
+
+	report, err := store.OpenVectorIndex(snapshot, ANNOptions{})
+	if err != nil {
+		t.Fatalf("OpenVectorIndex failed: %v", err)
+	}
+	if report.Snapshot != nil || report.Loaded != 1 || report.Lists != 0 {
+		t.Errorf("Expected the embedding loaded without ANN lists, got %+v", report)
+	}
+}
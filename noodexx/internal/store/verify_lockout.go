@@ -29,17 +29,17 @@ func main() {
 	fmt.Println("  - Called after successful login")
 	fmt.Println()
 
-	fmt.Println("✓ IsAccountLocked(ctx, username) - Checks if account is locked")
-	fmt.Println("  - Counts failed attempts in last 15 minutes")
-	fmt.Println("  - Returns (true, expirationTime) if >= 5 attempts")
-	fmt.Println("  - Returns (false, zero) if < 5 attempts")
-	fmt.Println("  - Lockout expires 15 minutes after 5th attempt")
+	fmt.Println("✓ IsAccountLocked(ctx, username, threshold, window) - Checks if account is locked")
+	fmt.Println("  - Counts failed attempts within the window (auth.lockout_duration_minutes)")
+	fmt.Println("  - Returns (true, expirationTime) if >= threshold attempts (auth.lockout_threshold)")
+	fmt.Println("  - Returns (false, zero) if < threshold attempts")
+	fmt.Println("  - Lockout expires one window after the attempt that reached the threshold")
 	fmt.Println()
 
 	fmt.Println("Implementation matches DataStore interface:")
 	fmt.Println("  RecordFailedLogin(ctx context.Context, username string) error")
 	fmt.Println("  ClearFailedLogins(ctx context.Context, username string) error")
-	fmt.Println("  IsAccountLocked(ctx context.Context, username string) (bool, time.Time)")
+	fmt.Println("  IsAccountLocked(ctx context.Context, username string, threshold int, window time.Duration) (bool, time.Time)")
 	fmt.Println()
 
 	fmt.Println("Requirements satisfied:")
@@ -0,0 +1,134 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// GetDefaultVisibility returns the visibility of documents the user ingests
+// without choosing one, "private" unless they have set another
+func (s *Store) GetDefaultVisibility(ctx context.Context, userID int64) (string, error) {
+	var visibility string
+	err := s.db.QueryRowContext(ctx, `SELECT COALESCE(default_visibility, 'private') FROM users WHERE id = ?`, userID).Scan(&visibility)
+	if err == sql.ErrNoRows {
+		return "private", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get default visibility: %w", err)
+	}
+	return visibility, nil
+}
+
+// SetDefaultVisibility sets the visibility of documents the user ingests
+// without choosing one: "private", "shared" or "public"
+func (s *Store) SetDefaultVisibility(ctx context.Context, userID int64, visibility string) error {
+	result, err := s.db.ExecContext(ctx, `UPDATE users SET default_visibility = ? WHERE id = ?`, visibility, userID)
+	if err != nil {
+		return fmt.Errorf("failed to set default visibility: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("user not found: %d", userID)
+	}
+	return nil
+}
+
+// SetSourceVisibility sets the visibility of a user's document and every one of its chunks
+func (s *Store) SetSourceVisibility(ctx context.Context, userID int64, source, visibility string) error {
+	return setSourceVisibility(ctx, s.db, userID, source, visibility)
+}
+
+// setSourceVisibility updates a document and its chunks using the given
+// connection or transaction. New chunks inherit the document's visibility
+func setSourceVisibility(ctx context.Context, ex execer, userID int64, source, visibility string) error {
+	query := `UPDATE documents SET visibility = ?, updated_at = CURRENT_TIMESTAMP WHERE user_id = ? AND source = ?`
+	if _, err := ex.ExecContext(ctx, query, visibility, userID, source); err != nil {
+		return fmt.Errorf("failed to set document visibility: %w", err)
+	}
+	if _, err := ex.ExecContext(ctx, `UPDATE chunks SET visibility = ? WHERE user_id = ? AND source = ?`, visibility, userID, source); err != nil {
+		return fmt.Errorf("failed to set document visibility: %w", err)
+	}
+	return nil
+}
+
+// SetDocumentsVisibility sets the visibility of several of a user's documents
+// and their chunks in one transaction. IDs of documents the user does not own
+// are skipped; it returns the number of documents updated
+func (s *Store) SetDocumentsVisibility(ctx context.Context, userID int64, documentIDs []int64, visibility string) (int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	updated := 0
+	for _, id := range documentIDs {
+		result, err := tx.ExecContext(ctx, `UPDATE documents SET visibility = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ? AND user_id = ?`, visibility, id, userID)
+		if err != nil {
+			return 0, fmt.Errorf("failed to set document visibility: %w", err)
+		}
+		if n, _ := result.RowsAffected(); n == 0 {
+			continue
+		}
+		if _, err := tx.ExecContext(ctx, `UPDATE chunks SET visibility = ? WHERE document_id = ?`, visibility, id); err != nil {
+			return 0, fmt.Errorf("failed to set chunk visibility: %w", err)
+		}
+		updated++
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return updated, nil
+}
+
+// RepairChunkVisibility gives every chunk whose visibility differs from its
+// document's the document's visibility, returning the number of chunks repaired
+func (s *Store) RepairChunkVisibility(ctx context.Context) (int64, error) {
+	return repairChunkVisibility(ctx, s.db)
+}
+
+// repairChunkVisibility repairs mismatched chunks using the given connection or transaction
+func repairChunkVisibility(ctx context.Context, ex execer) (int64, error) {
+	result, err := ex.ExecContext(ctx, `
+		UPDATE chunks SET visibility = (SELECT d.visibility FROM documents d WHERE d.id = chunks.document_id)
+		WHERE document_id IS NOT NULL
+			AND visibility IS NOT (SELECT d.visibility FROM documents d WHERE d.id = chunks.document_id)
+	`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to repair chunk visibility: %w", err)
+	}
+	n, _ := result.RowsAffected()
+	return n, nil
+}
+
+// CountChunkVisibility returns how many of chunkIDs have each visibility level.
+// IDs of chunks that no longer exist are not counted
+func (s *Store) CountChunkVisibility(ctx context.Context, chunkIDs []int64) (map[string]int, error) {
+	counts := make(map[string]int)
+	if len(chunkIDs) == 0 {
+		return counts, nil
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(chunkIDs)), ",")
+	args := make([]interface{}, len(chunkIDs))
+	for i, id := range chunkIDs {
+		args[i] = id
+	}
+	rows, err := s.db.QueryContext(ctx, `SELECT visibility, COUNT(*) FROM chunks WHERE id IN (`+placeholders+`) GROUP BY visibility`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count chunk visibility: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var visibility string
+		var n int
+		if err := rows.Scan(&visibility, &n); err != nil {
+			return nil, fmt.Errorf("failed to scan chunk visibility: %w", err)
+		}
+		counts[visibility] = n
+	}
+	return counts, rows.Err()
+}
@@ -0,0 +1,210 @@
+package store
+
+import (
+	"context"
+	"testing"
+)
+
+// TestDefaultVisibility tests the per-user default visibility and setting the
+// visibility of a document's chunks as part of a unit of work
+func TestDefaultVisibility(t *testing.T) {
+	store, err := NewStore(t.TempDir()+"/test_visibility.db", "multi")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+
+	alice, _ := store.CreateUser(ctx, "alice", "password123", "alice@example.com", false, false)
+	bob, _ := store.CreateUser(ctx, "bob", "password123", "bob@example.com", false, false)
+
+	if v, err := store.GetDefaultVisibility(ctx, alice); err != nil || v != "private" {
+		t.Errorf("Expected private by default, got %q (%v)", v, err)
+	}
+	if err := store.SetDefaultVisibility(ctx, alice, "public"); err != nil {
+		t.Fatalf("SetDefaultVisibility failed: %v", err)
+	}
+	if v, _ := store.GetDefaultVisibility(ctx, alice); v != "public" {
+		t.Errorf("Expected public, got %q", v)
+	}
+	if err := store.SetDefaultVisibility(ctx, alice, "everyone"); err == nil {
+		t.Error("Expected an error for an unknown visibility")
+	}
+	if err := store.SetDefaultVisibility(ctx, 9999, "public"); err == nil {
+		t.Error("Expected an error for an unknown user")
+	}
+
+	embedding := []float32{0.1, 0.2, 0.3}
+	err = store.WithTx(ctx, func(tx StoreTx) error {
+		if err := tx.SaveChunk(ctx, alice, "team.md", "Team handbook", embedding, nil, ""); err != nil {
+			return err
+		}
+		return tx.SetSourceVisibility(ctx, alice, "team.md", "public")
+	})
+	if err != nil {
+		t.Fatalf("WithTx failed: %v", err)
+	}
+	store.SaveChunk(ctx, alice, "diary.md", "Private thoughts", embedding, nil, "")
+
+	results, err := store.SearchByUser(ctx, bob, embedding, "", 10)
+	if err != nil {
+		t.Fatalf("SearchByUser failed: %v", err)
+	}
+	if len(results) != 1 || results[0].Source != "team.md" {
+		t.Errorf("Expected bob to find only the public document, got %+v", results)
+	}
+}
+
+// TestDocumentVisibility tests that chunks inherit their document's
+// visibility, bulk updates and repairing chunks that drifted from it
+func TestDocumentVisibility(t *testing.T) {
+	store, err := NewStore(t.TempDir()+"/test_visibility.db", "multi")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+
+	alice, _ := store.CreateUser(ctx, "alice", "password123", "alice@example.com", false, false)
+	bob, _ := store.CreateUser(ctx, "bob", "password123", "bob@example.com", false, false)
+
+	embedding := []float32{0.1, 0.2, 0.3}
+	store.SaveChunk(ctx, alice, "team.md", "Team handbook", embedding, nil, "")
+	if err := store.SetSourceVisibility(ctx, alice, "team.md", "public"); err != nil {
+		t.Fatalf("SetSourceVisibility failed: %v", err)
+	}
+	store.SaveChunk(ctx, alice, "team.md", "Team handbook, part two", embedding, nil, "")
+	store.SaveChunk(ctx, alice, "diary.md", "Private thoughts", embedding, nil, "")
+	store.SaveChunk(ctx, bob, "bob.md", "Bob's notes", embedding, nil, "")
+
+	results, _ := store.SearchByUser(ctx, bob, embedding, "", 10)
+	if len(results) != 3 {
+		t.Errorf("Expected both team.md chunks public and bob's own, got %+v", results)
+	}
+
+	ids := map[string]int64{}
+	for _, userID := range []int64{alice, bob} {
+		entries, _ := store.LibraryByUser(ctx, userID)
+		for _, e := range entries {
+			ids[e.Source] = e.DocumentID
+		}
+	}
+	if doc, _ := store.GetDocument(ctx, alice, ids["team.md"]); doc == nil || doc.Visibility != "public" {
+		t.Errorf("Expected team.md public, got %+v", doc)
+	}
+
+	// Documents of other users are skipped
+	n, err := store.SetDocumentsVisibility(ctx, alice, []int64{ids["team.md"], ids["diary.md"], ids["bob.md"]}, "shared")
+	if err != nil || n != 2 {
+		t.Fatalf("Expected two documents updated, got %d (%v)", n, err)
+	}
+	if doc, _ := store.GetDocument(ctx, bob, ids["bob.md"]); doc.Visibility != "private" {
+		t.Errorf("Expected bob's document unchanged, got %q", doc.Visibility)
+	}
+	var shared int
+	store.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM chunks WHERE user_id = ? AND visibility = 'shared'`, alice).Scan(&shared)
+	if shared != 3 {
+		t.Errorf("Expected all three of alice's chunks shared, got %d", shared)
+	}
+	if _, err := store.SetDocumentsVisibility(ctx, alice, []int64{ids["team.md"]}, "everyone"); err == nil {
+		t.Error("Expected an error for an unknown visibility")
+	}
+
+	// A chunk updated on its own is put back in line with its document
+	store.db.ExecContext(ctx, `UPDATE chunks SET visibility = 'public' WHERE source = 'diary.md'`)
+	if n, err := store.RepairChunkVisibility(ctx); err != nil || n != 1 {
+		t.Fatalf("Expected one chunk repaired, got %d (%v)", n, err)
+	}
+	if n, _ := store.RepairChunkVisibility(ctx); n != 0 {
+		t.Errorf("Expected nothing left to repair, got %d", n)
+	}
+
+	// Chunks are counted by visibility; IDs of missing chunks are not counted
+	var chunkIDs []int64
+	rows, _ := store.db.QueryContext(ctx, `SELECT id FROM chunks`)
+	for rows.Next() {
+		var id int64
+		rows.Scan(&id)
+		chunkIDs = append(chunkIDs, id)
+	}
+	rows.Close()
+	counts, err := store.CountChunkVisibility(ctx, append(chunkIDs, 9999))
+	if err != nil || len(counts) != 2 || counts["shared"] != 3 || counts["private"] != 1 {
+		t.Errorf("Expected 3 shared and 1 private chunk, got %v (%v)", counts, err)
+	}
+
+	// Re-ingesting replaces the chunks; the document keeps the visibility set
+	// above and the new chunks inherit it, while a new document gets the default
+	err = store.WithTx(ctx, func(tx StoreTx) error {
+		for _, source := range []string{"team.md", "faq.md"} {
+			if err := tx.DeleteChunksBySource(ctx, alice, source); err != nil {
+				return err
+			}
+			if err := tx.CreateDocument(ctx, alice, source, "public"); err != nil {
+				return err
+			}
+			if err := tx.SaveChunk(ctx, alice, source, "New "+source, embedding, nil, ""); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WithTx failed: %v", err)
+	}
+	if doc, _ := store.GetDocument(ctx, alice, ids["team.md"]); doc.Visibility != "shared" {
+		t.Errorf("Expected the re-ingested document to stay shared, got %q", doc.Visibility)
+	}
+	rows, _ = store.db.QueryContext(ctx, `SELECT source, visibility FROM chunks WHERE user_id = ? AND source IN ('team.md', 'faq.md')`, alice)
+	defer rows.Close()
+	for rows.Next() {
+		var source, visibility string
+		rows.Scan(&source, &visibility)
+		if want := map[string]string{"team.md": "shared", "faq.md": "public"}[source]; visibility != want {
+			t.Errorf("Expected the %s chunk %s, got %q", source, want, visibility)
+		}
+	}
+}
+
+// TestAddVisibilityToDocuments tests that documents take the most restrictive
+// visibility of their chunks when the column is added
+func TestAddVisibilityToDocuments(t *testing.T) {
+	store, err := NewStore(t.TempDir()+"/test_visibility.db", "multi")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+
+	alice, _ := store.CreateUser(ctx, "alice", "password123", "alice@example.com", false, false)
+	embedding := []float32{0.1, 0.2, 0.3}
+	for _, source := range []string{"mixed.md", "mixed.md", "faq.md"} {
+		store.SaveChunk(ctx, alice, source, "text", embedding, nil, "")
+	}
+	store.db.ExecContext(ctx, `UPDATE chunks SET visibility = 'public' WHERE source = 'faq.md' OR id = (SELECT MIN(id) FROM chunks)`)
+	store.db.ExecContext(ctx, `UPDATE chunks SET visibility = 'shared' WHERE source = 'mixed.md' AND visibility = 'private'`)
+	if _, err := store.db.ExecContext(ctx, `ALTER TABLE documents DROP COLUMN visibility`); err != nil {
+		t.Fatalf("Failed to drop column: %v", err)
+	}
+
+	if err := store.runMigrations(ctx); err != nil {
+		t.Fatalf("runMigrations failed: %v", err)
+	}
+
+	rows, err := store.db.QueryContext(ctx, `SELECT d.source, d.visibility, c.visibility FROM chunks c JOIN documents d ON d.id = c.document_id`)
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	defer rows.Close()
+	want := map[string]string{"mixed.md": "shared", "faq.md": "public"}
+	for rows.Next() {
+		var source, document, chunk string
+		rows.Scan(&source, &document, &chunk)
+		if document != want[source] || chunk != document {
+			t.Errorf("%s: expected %s, got document %s and chunk %s", source, want[source], document, chunk)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// defaultSearchPageSize is the number of chunk rows read per page during vector search
+const defaultSearchPageSize = 500
+
+// Options controls SQLite tuning for a Store
+type Options struct {
+	Synchronous    string // PRAGMA synchronous: "OFF", "NORMAL", "FULL" or "EXTRA"
+	CacheSizeKB    int    // PRAGMA cache_size in KiB per connection (0 keeps the SQLite default)
+	SearchPageSize int    // Rows read per page during vector search
+	WritePoolSize  int    // Connections on the primary handle, which every write goes through (0 = 25)
+	ReadPoolSize   int    // Read-only connections for search and library queries (0 = they use the primary handle)
+}
+
+// DefaultOptions returns the tuning used when no configuration is supplied
+func DefaultOptions() Options {
+	return Options{
+		Synchronous:    "NORMAL",
+		CacheSizeKB:    0,
+		SearchPageSize: defaultSearchPageSize,
+	}
+}
+
+// validate checks the options before they are interpolated into the DSN
+func (o Options) validate() error {
+	switch strings.ToUpper(o.Synchronous) {
+	case "", "OFF", "NORMAL", "FULL", "EXTRA":
+	default:
+		return fmt.Errorf("invalid synchronous mode: %s (must be OFF, NORMAL, FULL, or EXTRA)", o.Synchronous)
+	}
+	if o.CacheSizeKB < 0 {
+		return fmt.Errorf("invalid cache size: %d (must not be negative)", o.CacheSizeKB)
+	}
+	if o.SearchPageSize < 0 {
+		return fmt.Errorf("invalid search page size: %d (must not be negative)", o.SearchPageSize)
+	}
+	if o.WritePoolSize < 0 || o.ReadPoolSize < 0 {
+		return fmt.Errorf("invalid pool sizes: %d write, %d read (must not be negative)", o.WritePoolSize, o.ReadPoolSize)
+	}
+	return nil
+}
+
+// dsnParams builds the connection string pragmas for the options
+func (o Options) dsnParams() string {
+	params := "?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)"
+	if o.Synchronous != "" {
+		params += "&_pragma=synchronous(" + strings.ToUpper(o.Synchronous) + ")"
+	}
+	if o.CacheSizeKB > 0 {
+		// A negative cache_size is interpreted by SQLite as KiB rather than pages
+		params += fmt.Sprintf("&_pragma=cache_size(-%d)", o.CacheSizeKB)
+	}
+	return params
+}
+
+// CheckpointResult reports the outcome of a WAL checkpoint
+type CheckpointResult struct {
+	Busy         bool // True if the checkpoint could not complete because of active readers or writers
+	LogFrames    int  // Frames in the WAL before the checkpoint
+	Checkpointed int  // Frames copied back into the database file
+}
+
+// Checkpoint runs a TRUNCATE checkpoint, copying the WAL into the database file and
+// truncating it to zero bytes so it cannot grow unbounded under heavy ingestion
+func (s *Store) Checkpoint(ctx context.Context) (*CheckpointResult, error) {
+	var busy int
+	var result CheckpointResult
+	err := s.db.QueryRowContext(ctx, "PRAGMA wal_checkpoint(TRUNCATE)").Scan(&busy, &result.LogFrames, &result.Checkpointed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to checkpoint WAL: %w", err)
+	}
+	result.Busy = busy != 0
+	return &result, nil
+}
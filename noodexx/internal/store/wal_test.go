@@ -0,0 +1,130 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+)
+
+// TestSearchAcrossPages tests that paginated search reads score every chunk
+func TestSearchAcrossPages(t *testing.T) {
+	tmpFile := "test_search_pages.db"
+	defer os.Remove(tmpFile)
+
+	opts := DefaultOptions()
+	opts.SearchPageSize = 2
+
+	store, err := NewStoreWithOptions(tmpFile, "multi", opts)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+
+	userID, err := store.CreateUser(ctx, "pager", "password", "pager@test.com", false, false)
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	// The best match is saved last so it lands on the final, partial page
+	for i := 0; i < 4; i++ {
+		source := fmt.Sprintf("doc%d.txt", i)
+		if err := store.SaveChunk(ctx, userID, source, "text", []float32{0, 1, 0}, nil, ""); err != nil {
+			t.Fatalf("Failed to save chunk: %v", err)
+		}
+	}
+	if err := store.SaveChunk(ctx, userID, "best.txt", "text", []float32{1, 0, 0}, nil, ""); err != nil {
+		t.Fatalf("Failed to save chunk: %v", err)
+	}
+
+	results, err := store.SearchByUser(ctx, userID, []float32{1, 0, 0}, "", 10)
+	if err != nil {
+		t.Fatalf("SearchByUser failed: %v", err)
+	}
+	if len(results) != 5 {
+		t.Fatalf("Expected 5 results across pages, got %d", len(results))
+	}
+	if results[0].Source != "best.txt" {
+		t.Errorf("Expected best.txt first, got %s", results[0].Source)
+	}
+
+	results, err = store.Search(ctx, []float32{1, 0, 0}, 10)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(results) != 5 {
+		t.Errorf("Expected 5 results across pages, got %d", len(results))
+	}
+}
+
+// TestCheckpoint tests that a TRUNCATE checkpoint completes on an idle database
+func TestCheckpoint(t *testing.T) {
+	tmpFile := "test_checkpoint.db"
+	defer os.Remove(tmpFile)
+	defer os.Remove(tmpFile + "-wal")
+	defer os.Remove(tmpFile + "-shm")
+
+	store, err := NewStore(tmpFile, "multi")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+
+	if err := store.SaveChunk(ctx, 1, "doc.txt", "text", []float32{1, 0, 0}, nil, ""); err != nil {
+		t.Fatalf("Failed to save chunk: %v", err)
+	}
+
+	result, err := store.Checkpoint(ctx)
+	if err != nil {
+		t.Fatalf("Checkpoint failed: %v", err)
+	}
+	if result.Busy {
+		t.Errorf("Expected checkpoint to complete on an idle database, got %+v", result)
+	}
+
+	info, err := os.Stat(tmpFile + "-wal")
+	if err == nil && info.Size() != 0 {
+		t.Errorf("Expected WAL to be truncated, got %d bytes", info.Size())
+	}
+}
+
+// TestNewStoreWithOptionsValidation tests that invalid tuning options are rejected
+func TestNewStoreWithOptionsValidation(t *testing.T) {
+	tmpFile := "test_store_options.db"
+	defer os.Remove(tmpFile)
+
+	opts := DefaultOptions()
+	opts.Synchronous = "sometimes"
+	if _, err := NewStoreWithOptions(tmpFile, "multi", opts); err == nil {
+		t.Error("Expected error for invalid synchronous mode")
+	}
+
+	opts = DefaultOptions()
+	opts.Synchronous = "full"
+	opts.CacheSizeKB = 4096
+	store, err := NewStoreWithOptions(tmpFile, "multi", opts)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	var synchronous int
+	if err := store.db.QueryRow("PRAGMA synchronous").Scan(&synchronous); err != nil {
+		t.Fatalf("Failed to read synchronous pragma: %v", err)
+	}
+	if synchronous != 2 { // FULL
+		t.Errorf("Expected synchronous FULL (2), got %d", synchronous)
+	}
+
+	var cacheSize int
+	if err := store.db.QueryRow("PRAGMA cache_size").Scan(&cacheSize); err != nil {
+		t.Fatalf("Failed to read cache_size pragma: %v", err)
+	}
+	if cacheSize != -4096 {
+		t.Errorf("Expected cache_size -4096, got %d", cacheSize)
+	}
+}
@@ -0,0 +1,242 @@
+// Package telemetry sends an anonymous usage report when an admin opts in. The
+// report holds only coarse counters: the Noodexx version, the operating
+// system, the configured provider types and the library size as a bucket. It
+// never contains user names, document names, content, hostnames, addresses or
+// an installation ID, so reports cannot be linked to each other or to anyone.
+//
+// Telemetry is off unless the config enables it, and NOODEXX_TELEMETRY=off or
+// DO_NOT_TRACK=1 turns it off whatever the config says. Preview returns the
+// exact bytes that would be sent, so admins can check them first.
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SchemaVersion is bumped whenever a field is added to or removed from Report
+const SchemaVersion = 1
+
+// ErrDisabled is returned by Send when telemetry is off
+var ErrDisabled = errors.New("telemetry is disabled")
+
+// Report is everything a telemetry report contains
+type Report struct {
+	SchemaVersion int    `json:"schema_version"`
+	Version       string `json:"version"`        // Noodexx version
+	OS            string `json:"os"`             // e.g. "linux"
+	Arch          string `json:"arch"`           // e.g. "amd64"
+	UserMode      string `json:"user_mode"`      // "single" or "multi"
+	LocalProvider string `json:"local_provider"` // Provider type, e.g. "ollama", or "none"
+	CloudProvider string `json:"cloud_provider"` // Provider type, e.g. "openai", or "none"
+	LibrarySize   string `json:"library_size"`   // Document count bucket, e.g. "11-100"
+}
+
+// Counter counts the documents in the library, across all users
+type Counter interface {
+	CountDocuments(ctx context.Context) (int, error)
+}
+
+// Options configures a Reporter
+type Options struct {
+	Enabled       bool   // The admin opted in
+	Endpoint      string // URL the report is POSTed to
+	Version       string
+	UserMode      string
+	LocalProvider string
+	CloudProvider string
+	Timeout       time.Duration // Timeout of one send, 10 seconds when zero
+}
+
+// Status describes the reporter for the admin API
+type Status struct {
+	Enabled     bool
+	DisabledBy  string // Why telemetry is off: "config", "NOODEXX_TELEMETRY" or "DO_NOT_TRACK"
+	Endpoint    string
+	LastSent    time.Time
+	LastError   string
+	ReportsSent int64
+}
+
+// Reporter builds and sends telemetry reports
+type Reporter struct {
+	opts       Options
+	counter    Counter
+	client     *http.Client
+	disabledBy string
+
+	mu          sync.Mutex
+	lastSent    time.Time
+	lastError   string
+	reportsSent int64
+}
+
+// New creates a reporter. The environment's off switches are read here and
+// win over opts.Enabled
+func New(opts Options, counter Counter) *Reporter {
+	if opts.Timeout <= 0 {
+		opts.Timeout = 10 * time.Second
+	}
+	r := &Reporter{
+		opts:    opts,
+		counter: counter,
+		client:  &http.Client{Timeout: opts.Timeout},
+	}
+	if switchedOff, name := EnvDisabled(); switchedOff {
+		r.disabledBy = name
+	} else if !opts.Enabled {
+		r.disabledBy = "config"
+	}
+	return r
+}
+
+// EnvDisabled reports whether the environment turns telemetry off, and which
+// variable does: NOODEXX_TELEMETRY set to off, false or 0, or DO_NOT_TRACK set
+// to anything but 0 or false
+func EnvDisabled() (bool, string) {
+	switch strings.ToLower(strings.TrimSpace(os.Getenv("NOODEXX_TELEMETRY"))) {
+	case "off", "false", "0", "no":
+		return true, "NOODEXX_TELEMETRY"
+	}
+	switch strings.ToLower(strings.TrimSpace(os.Getenv("DO_NOT_TRACK"))) {
+	case "", "0", "false":
+	default:
+		return true, "DO_NOT_TRACK"
+	}
+	return false, ""
+}
+
+// Enabled reports whether reports are sent
+func (r *Reporter) Enabled() bool {
+	return r.disabledBy == ""
+}
+
+// Build assembles the current report
+func (r *Reporter) Build(ctx context.Context) (Report, error) {
+	documents, err := r.counter.CountDocuments(ctx)
+	if err != nil {
+		return Report{}, fmt.Errorf("failed to count documents: %w", err)
+	}
+	return Report{
+		SchemaVersion: SchemaVersion,
+		Version:       r.opts.Version,
+		OS:            runtime.GOOS,
+		Arch:          runtime.GOARCH,
+		UserMode:      r.opts.UserMode,
+		LocalProvider: providerType(r.opts.LocalProvider),
+		CloudProvider: providerType(r.opts.CloudProvider),
+		LibrarySize:   SizeBucket(documents),
+	}, nil
+}
+
+// Preview returns the request body Send would post now, whether or not
+// telemetry is enabled
+func (r *Reporter) Preview(ctx context.Context) ([]byte, error) {
+	report, err := r.Build(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(report, "", "  ")
+}
+
+// Send posts the current report to the endpoint. It returns ErrDisabled
+// without building a report or opening a connection when telemetry is off
+func (r *Reporter) Send(ctx context.Context) error {
+	if !r.Enabled() {
+		return ErrDisabled
+	}
+	err := r.send(ctx)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err != nil {
+		r.lastError = err.Error()
+		return err
+	}
+	r.lastError = ""
+	r.lastSent = time.Now()
+	r.reportsSent++
+	return nil
+}
+
+func (r *Reporter) send(ctx context.Context) error {
+	body, err := r.Preview(ctx)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.opts.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create telemetry request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "noodexx-telemetry/"+r.opts.Version)
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send telemetry: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("telemetry endpoint returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Status returns a snapshot of the reporter
+func (r *Reporter) Status() Status {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return Status{
+		Enabled:     r.Enabled(),
+		DisabledBy:  r.disabledBy,
+		Endpoint:    r.opts.Endpoint,
+		LastSent:    r.lastSent,
+		LastError:   r.lastError,
+		ReportsSent: r.reportsSent,
+	}
+}
+
+// SizeBucket rounds a document count up to an order of magnitude, so the
+// report shows the scale of a library but not its size
+func SizeBucket(documents int) string {
+	switch {
+	case documents <= 0:
+		return "0"
+	case documents <= 10:
+		return "1-10"
+	case documents <= 100:
+		return "11-100"
+	case documents <= 1000:
+		return "101-1000"
+	case documents <= 10000:
+		return "1001-10000"
+	default:
+		return "10001+"
+	}
+}
+
+// providerType reports a configured provider type, keeping only known values
+// so a misconfigured type cannot leak anything else
+func providerType(t string) string {
+	switch t {
+	case "ollama", "builtin", "openai", "anthropic":
+		return t
+	case "":
+		return "none"
+	default:
+		return "other"
+	}
+}
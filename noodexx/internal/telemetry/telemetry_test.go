@@ -0,0 +1,143 @@
+package telemetry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+type fixedCounter int
+
+func (c fixedCounter) CountDocuments(ctx context.Context) (int, error) {
+	return int(c), nil
+}
+
+func testOptions(endpoint string) Options {
+	return Options{
+		Enabled:       true,
+		Endpoint:      endpoint,
+		Version:       "1.0.0",
+		UserMode:      "multi",
+		LocalProvider: "ollama",
+		CloudProvider: "my-custom-proxy",
+	}
+}
+
+func TestSendPostsThePreview(t *testing.T) {
+	t.Setenv("NOODEXX_TELEMETRY", "")
+	t.Setenv("DO_NOT_TRACK", "")
+
+	var received []byte
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received, _ = io.ReadAll(r.Body)
+	}))
+	defer ts.Close()
+
+	r := New(testOptions(ts.URL), fixedCounter(42))
+	preview, err := r.Preview(context.Background())
+	if err != nil {
+		t.Fatalf("Preview failed: %v", err)
+	}
+	if err := r.Send(context.Background()); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	if string(received) != string(preview) {
+		t.Errorf("Expected the preview to be sent exactly, got %s, want %s", received, preview)
+	}
+
+	var report map[string]interface{}
+	if err := json.Unmarshal(received, &report); err != nil {
+		t.Fatalf("Failed to decode report: %v", err)
+	}
+	want := map[string]interface{}{
+		"schema_version": float64(SchemaVersion),
+		"version":        "1.0.0",
+		"os":             runtime.GOOS,
+		"arch":           runtime.GOARCH,
+		"user_mode":      "multi",
+		"local_provider": "ollama",
+		"cloud_provider": "other",
+		"library_size":   "11-100",
+	}
+	if len(report) != len(want) {
+		t.Errorf("Expected exactly %d fields, got %v", len(want), report)
+	}
+	for k, v := range want {
+		if report[k] != v {
+			t.Errorf("Expected %s=%v, got %v", k, v, report[k])
+		}
+	}
+	if status := r.Status(); status.ReportsSent != 1 || status.LastSent.IsZero() || status.LastError != "" {
+		t.Errorf("Expected one report sent, got %+v", status)
+	}
+}
+
+func TestSendFailure(t *testing.T) {
+	t.Setenv("NOODEXX_TELEMETRY", "")
+	t.Setenv("DO_NOT_TRACK", "")
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	r := New(testOptions(ts.URL), fixedCounter(0))
+	if err := r.Send(context.Background()); err == nil || !strings.Contains(err.Error(), "503") {
+		t.Errorf("Expected the status in the error, got %v", err)
+	}
+	if status := r.Status(); status.ReportsSent != 0 || !strings.Contains(status.LastError, "503") {
+		t.Errorf("Expected the failure in the status, got %+v", status)
+	}
+}
+
+func TestOffSwitches(t *testing.T) {
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+	}))
+	defer ts.Close()
+
+	for _, tc := range []struct {
+		enabled          bool
+		telemetry, track string
+		disabledBy       string
+	}{
+		{false, "", "", "config"},
+		{true, "off", "", "NOODEXX_TELEMETRY"},
+		{true, "", "1", "DO_NOT_TRACK"},
+	} {
+		t.Setenv("NOODEXX_TELEMETRY", tc.telemetry)
+		t.Setenv("DO_NOT_TRACK", tc.track)
+		opts := testOptions(ts.URL)
+		opts.Enabled = tc.enabled
+
+		r := New(opts, fixedCounter(1))
+		if err := r.Send(context.Background()); !errors.Is(err, ErrDisabled) {
+			t.Errorf("Expected ErrDisabled, got %v", err)
+		}
+		if status := r.Status(); status.Enabled || status.DisabledBy != tc.disabledBy {
+			t.Errorf("Expected telemetry disabled by %s, got %+v", tc.disabledBy, status)
+		}
+		// The preview still works, so admins can see what enabling would send
+		if _, err := r.Preview(context.Background()); err != nil {
+			t.Errorf("Preview failed: %v", err)
+		}
+	}
+	if requests != 0 {
+		t.Errorf("Expected no requests while disabled, got %d", requests)
+	}
+}
+
+func TestSizeBucket(t *testing.T) {
+	for n, want := range map[int]string{0: "0", 1: "1-10", 10: "1-10", 11: "11-100", 1000: "101-1000", 5000: "1001-10000", 10001: "10001+"} {
+		if got := SizeBucket(n); got != want {
+			t.Errorf("SizeBucket(%d) = %s, want %s", n, got, want)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package transcripts
+
+import (
+	"context"
+	"io"
+	"noodexx/internal/auth"
+	"noodexx/internal/llm"
+	"time"
+)
+
+// provider wraps an llm.Provider and archives every request made through it
+type provider struct {
+	next       llm.Provider
+	chatModel  string
+	embedModel string
+	archive    *Archive
+}
+
+// Wrap returns a provider that archives the requests made through p.
+// Local providers are returned unchanged: nothing sent to them leaves the
+// machine. The user is taken from the request context when present
+func Wrap(p llm.Provider, chatModel, embedModel string, archive *Archive) llm.Provider {
+	if p == nil || archive == nil || p.IsLocal() {
+		return p
+	}
+	return &provider{next: p, chatModel: chatModel, embedModel: embedModel, archive: archive}
+}
+
+// Embed implements llm.Provider
+func (p *provider) Embed(ctx context.Context, text string) ([]float32, error) {
+	start := time.Now()
+	vec, err := p.next.Embed(ctx, text)
+	if !p.archive.embeddings {
+		return vec, err
+	}
+
+	payload := Payload{Input: text, Dimensions: len(vec)}
+	if err != nil {
+		payload.Error = err.Error()
+	}
+	p.record(ctx, OperationEmbed, p.embedModel, start, payload)
+	return vec, err
+}
+
+// Stream implements llm.Provider
+func (p *provider) Stream(ctx context.Context, messages []llm.Message, w io.Writer) (string, error) {
+	return p.StreamWithOptions(ctx, messages, llm.GenerationOptions{}, w)
+}
+
+// StreamWithOptions implements llm.Provider
+func (p *provider) StreamWithOptions(ctx context.Context, messages []llm.Message, opts llm.GenerationOptions, w io.Writer) (string, error) {
+	start := time.Now()
+	response, err := p.next.StreamWithOptions(ctx, messages, opts, w)
+
+	payload := Payload{
+		Messages:    messages,
+		Temperature: opts.Temperature,
+		TopP:        opts.TopP,
+		MaxTokens:   opts.MaxTokens,
+		Response:    response,
+	}
+	if err != nil {
+		payload.Error = err.Error()
+	}
+	p.record(ctx, OperationChat, p.chatModel, start, payload)
+	return response, err
+}
+
+// Name implements llm.Provider
+func (p *provider) Name() string {
+	return p.next.Name()
+}
+
+// IsLocal implements llm.Provider
+func (p *provider) IsLocal() bool {
+	return p.next.IsLocal()
+}
+
+// record archives a request even when the client that made it has gone away
+func (p *provider) record(ctx context.Context, operation, model string, start time.Time, payload Payload) {
+	userID, _ := auth.GetUserID(ctx)
+	p.archive.Record(context.WithoutCancel(ctx), Entry{
+		Time:      start,
+		UserID:    userID,
+		Provider:  p.next.Name(),
+		Model:     model,
+		Operation: operation,
+	}, payload)
+}
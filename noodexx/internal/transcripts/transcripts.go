@@ -0,0 +1,210 @@
+// Package transcripts archives what is exchanged with cloud LLM providers, for
+// users who must be able to show a regulator exactly what left the building.
+//
+// Each request is archived with the messages exactly as the provider received
+// them, after anything Noodexx removed or replaced, and the raw response. The
+// payload is sealed with AES-256-GCM under a key kept outside the database, so
+// a copy of the database alone does not reveal it. Only the user, provider,
+// model, operation and time are stored in the clear, for filtering and
+// retention.
+package transcripts
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"noodexx/internal/llm"
+	"noodexx/internal/logging"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Operation names recorded in entries
+const (
+	OperationEmbed = "embed"
+	OperationChat  = "chat"
+)
+
+// keySize is the length of an AES-256 key in bytes
+const keySize = 32
+
+// Entry is an archived request with its payload sealed
+type Entry struct {
+	ID        int64
+	Time      time.Time
+	UserID    int64 // 0 for requests made outside a user's request, such as scheduled jobs
+	Provider  string
+	Model     string
+	Operation string
+	KeyID     string // Identifies the key that sealed the payload
+	Sealed    []byte // Nonce followed by the encrypted payload
+}
+
+// Payload is what was sent to the provider and what came back
+type Payload struct {
+	Messages    []llm.Message `json:"messages,omitempty"`
+	Input       string        `json:"input,omitempty"` // Text embedded, for embedding requests
+	Temperature *float64      `json:"temperature,omitempty"`
+	TopP        *float64      `json:"top_p,omitempty"`
+	MaxTokens   int           `json:"max_tokens,omitempty"`
+	Response    string        `json:"response,omitempty"`
+	Dimensions  int           `json:"dimensions,omitempty"` // Size of the returned embedding, which is not kept
+	Error       string        `json:"error,omitempty"`
+}
+
+// Store persists sealed entries
+type Store interface {
+	SaveTranscript(ctx context.Context, e Entry) error
+}
+
+// Key seals and opens payloads
+type Key struct {
+	aead cipher.AEAD
+	id   string
+}
+
+// LoadKey reads the hex-encoded key in path, creating the file with a new
+// random key, readable only by its owner, if it does not exist.
+// Losing the file makes the archive unreadable
+func LoadKey(path string) (*Key, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		raw := make([]byte, keySize)
+		if _, err := rand.Read(raw); err != nil {
+			return nil, fmt.Errorf("failed to generate transcript key: %w", err)
+		}
+		if dir := filepath.Dir(path); dir != "" {
+			if err := os.MkdirAll(dir, 0700); err != nil {
+				return nil, fmt.Errorf("failed to create transcript key directory: %w", err)
+			}
+		}
+		data = []byte(hex.EncodeToString(raw) + "\n")
+		if err := os.WriteFile(path, data, 0600); err != nil {
+			return nil, fmt.Errorf("failed to write transcript key: %w", err)
+		}
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read transcript key: %w", err)
+	}
+
+	raw, err := hex.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil || len(raw) != keySize {
+		return nil, fmt.Errorf("transcript key in %s must be %d hex-encoded bytes", path, keySize)
+	}
+	return NewKey(raw)
+}
+
+// NewKey creates a key from 32 raw bytes
+func NewKey(raw []byte) (*Key, error) {
+	block, err := aes.NewCipher(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid transcript key: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("invalid transcript key: %w", err)
+	}
+	sum := sha256.Sum256(raw)
+	return &Key{aead: aead, id: hex.EncodeToString(sum[:])[:16]}, nil
+}
+
+// ID identifies the key without revealing it
+func (k *Key) ID() string {
+	return k.id
+}
+
+// seal encrypts plaintext under a random nonce, bound to the entry's metadata
+// so a sealed payload cannot be moved to another entry
+func (k *Key) seal(plaintext, metadata []byte) ([]byte, error) {
+	nonce := make([]byte, k.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return k.aead.Seal(nonce, nonce, plaintext, metadata), nil
+}
+
+// open decrypts a payload sealed by seal
+func (k *Key) open(sealed, metadata []byte) ([]byte, error) {
+	size := k.aead.NonceSize()
+	if len(sealed) < size {
+		return nil, fmt.Errorf("sealed payload too short")
+	}
+	return k.aead.Open(nil, sealed[:size], sealed[size:], metadata)
+}
+
+// metadata is the clear-text part of an entry authenticated with its payload
+func (e Entry) metadata() []byte {
+	return []byte(fmt.Sprintf("%d\x00%s\x00%s\x00%s\x00%d", e.UserID, e.Provider, e.Model, e.Operation, e.Time.UnixNano()))
+}
+
+// Archive seals payloads and saves them
+type Archive struct {
+	key        *Key
+	store      Store
+	embeddings bool
+	logger     *logging.Logger
+}
+
+// New creates an archive saving entries sealed with key to store.
+// Embedding requests are only archived when embeddings is set, since every
+// ingested chunk is one
+func New(key *Key, store Store, embeddings bool, logger *logging.Logger) *Archive {
+	return &Archive{key: key, store: store, embeddings: embeddings, logger: logger}
+}
+
+// Record seals payload and saves it as e. Failures are logged, and do not
+// fail the request the entry describes
+func (a *Archive) Record(ctx context.Context, e Entry, payload Payload) error {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	// Times round-trip through the database in microseconds at best
+	e.Time = e.Time.UTC().Truncate(time.Microsecond)
+
+	plaintext, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode transcript: %w", err)
+	}
+	e.KeyID = a.key.ID()
+	if e.Sealed, err = a.key.seal(plaintext, e.metadata()); err != nil {
+		return err
+	}
+	if err := a.store.SaveTranscript(ctx, e); err != nil {
+		a.logger.WithFields(map[string]interface{}{
+			"provider":  e.Provider,
+			"operation": e.Operation,
+			"user_id":   e.UserID,
+			"error":     err.Error(),
+		}).Error("failed to archive provider transcript")
+		return err
+	}
+	return nil
+}
+
+// Open decrypts an archived entry's payload
+func (a *Archive) Open(e Entry) (Payload, error) {
+	var payload Payload
+	if e.KeyID != a.key.ID() {
+		return payload, fmt.Errorf("transcript %d was sealed with another key (%s)", e.ID, e.KeyID)
+	}
+	plaintext, err := a.key.open(e.Sealed, Entry{
+		UserID:    e.UserID,
+		Provider:  e.Provider,
+		Model:     e.Model,
+		Operation: e.Operation,
+		Time:      e.Time.UTC().Truncate(time.Microsecond),
+	}.metadata())
+	if err != nil {
+		return payload, fmt.Errorf("failed to decrypt transcript %d: %w", e.ID, err)
+	}
+	if err := json.Unmarshal(plaintext, &payload); err != nil {
+		return payload, fmt.Errorf("failed to decode transcript %d: %w", e.ID, err)
+	}
+	return payload, nil
+}
@@ -0,0 +1,156 @@
+package transcripts
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"noodexx/internal/auth"
+	"noodexx/internal/llm"
+	"noodexx/internal/logging"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeProvider streams a fixed answer
+type fakeProvider struct {
+	local bool
+	err   error
+}
+
+func (f *fakeProvider) Embed(ctx context.Context, text string) ([]float32, error) {
+	return []float32{1, 2, 3}, nil
+}
+
+func (f *fakeProvider) Stream(ctx context.Context, messages []llm.Message, w io.Writer) (string, error) {
+	return f.StreamWithOptions(ctx, messages, llm.GenerationOptions{}, w)
+}
+
+func (f *fakeProvider) StreamWithOptions(ctx context.Context, messages []llm.Message, opts llm.GenerationOptions, w io.Writer) (string, error) {
+	if f.err != nil {
+		return "", f.err
+	}
+	answer := "The answer"
+	w.Write([]byte(answer))
+	return answer, nil
+}
+
+func (f *fakeProvider) Name() string  { return "fake" }
+func (f *fakeProvider) IsLocal() bool { return f.local }
+
+// memoryStore keeps saved entries
+type memoryStore struct {
+	entries []Entry
+}
+
+func (m *memoryStore) SaveTranscript(ctx context.Context, e Entry) error {
+	m.entries = append(m.entries, e)
+	return nil
+}
+
+func testArchive(t *testing.T, embeddings bool) (*Archive, *memoryStore) {
+	t.Helper()
+	key, err := LoadKey(filepath.Join(t.TempDir(), "keys", "transcripts.key"))
+	if err != nil {
+		t.Fatalf("LoadKey failed: %v", err)
+	}
+	store := &memoryStore{}
+	return New(key, store, embeddings, logging.NewLogger("test", logging.ERROR, io.Discard)), store
+}
+
+func TestLoadKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "transcripts.key")
+	first, err := LoadKey(path)
+	if err != nil {
+		t.Fatalf("LoadKey failed: %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("Expected a key file readable only by its owner, got %v (%v)", info, err)
+	}
+	second, err := LoadKey(path)
+	if err != nil || second.ID() != first.ID() {
+		t.Errorf("Expected the same key loaded again, got %v (%v)", second, err)
+	}
+
+	os.WriteFile(path, []byte("not a key"), 0600)
+	if _, err := LoadKey(path); err == nil {
+		t.Error("Expected an error for a malformed key")
+	}
+}
+
+func TestWrapArchivesCloudRequests(t *testing.T) {
+	archive, store := testArchive(t, false)
+	p := Wrap(&fakeProvider{}, "gpt-4o", "text-embedding-3-small", archive)
+
+	ctx := context.WithValue(context.Background(), auth.UserIDKey, int64(7))
+	temperature := 0.2
+	messages := []llm.Message{{Role: "system", Content: "Be brief"}, {Role: "user", Content: "What is [REDACTED]'s salary?"}}
+	var out bytes.Buffer
+	if _, err := p.StreamWithOptions(ctx, messages, llm.GenerationOptions{Temperature: &temperature, MaxTokens: 200}, &out); err != nil {
+		t.Fatalf("Stream failed: %v", err)
+	}
+	if out.String() != "The answer" {
+		t.Errorf("Expected the answer streamed through, got %q", out.String())
+	}
+	// Embeddings are not archived unless asked for
+	p.Embed(ctx, "a chunk")
+
+	if len(store.entries) != 1 {
+		t.Fatalf("Expected 1 archived request, got %d", len(store.entries))
+	}
+	e := store.entries[0]
+	if e.UserID != 7 || e.Provider != "fake" || e.Model != "gpt-4o" || e.Operation != OperationChat || e.KeyID == "" {
+		t.Errorf("Unexpected entry: %+v", e)
+	}
+	if bytes.Contains(e.Sealed, []byte("salary")) || bytes.Contains(e.Sealed, []byte("The answer")) {
+		t.Error("Expected the payload encrypted")
+	}
+
+	payload, err := archive.Open(e)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	if len(payload.Messages) != 2 || payload.Messages[1].Content != "What is [REDACTED]'s salary?" || payload.Response != "The answer" {
+		t.Errorf("Expected the exact messages and response, got %+v", payload)
+	}
+	if payload.Temperature == nil || *payload.Temperature != 0.2 || payload.MaxTokens != 200 {
+		t.Errorf("Expected the generation options, got %+v", payload)
+	}
+
+	// A payload moved to another entry does not open
+	tampered := e
+	tampered.UserID = 8
+	if _, err := archive.Open(tampered); err == nil {
+		t.Error("Expected an error for a payload moved to another user")
+	}
+	other, _ := testArchive(t, false)
+	if _, err := other.Open(e); err == nil || !strings.Contains(err.Error(), "another key") {
+		t.Errorf("Expected an error for another key, got %v", err)
+	}
+}
+
+func TestWrapSkipsLocalAndRecordsErrors(t *testing.T) {
+	archive, store := testArchive(t, true)
+	local := &fakeProvider{local: true}
+	if Wrap(local, "llama3.2", "nomic-embed-text", archive) != llm.Provider(local) {
+		t.Error("Expected a local provider returned unchanged")
+	}
+
+	p := Wrap(&fakeProvider{err: errors.New("rate limited")}, "gpt-4o", "text-embedding-3-small", archive)
+	p.Stream(context.Background(), []llm.Message{{Role: "user", Content: "hi"}}, io.Discard)
+	p.Embed(context.Background(), "a chunk")
+	if len(store.entries) != 2 {
+		t.Fatalf("Expected the failed chat and the embedding archived, got %d", len(store.entries))
+	}
+	chat, _ := archive.Open(store.entries[0])
+	embed, _ := archive.Open(store.entries[1])
+	if chat.Error != "rate limited" || store.entries[0].UserID != 0 {
+		t.Errorf("Expected the error archived, got %+v", chat)
+	}
+	if embed.Input != "a chunk" || embed.Dimensions != 3 || store.entries[1].Model != "text-embedding-3-small" {
+		t.Errorf("Expected the embedded text archived, got %+v", embed)
+	}
+}
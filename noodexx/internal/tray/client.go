@@ -0,0 +1,159 @@
+// Package tray puts Noodexx in the desktop's system tray, for people running
+// it on their own machine in single-user mode. The menu opens the web UI,
+// switches between local and cloud AI, pauses folder watching and shows what
+// the watcher is ingesting. It drives the local server through the HTTP API
+// like any other client. The menu itself needs a desktop toolkit and is only
+// built with the tray build tag (go build -tags tray).
+package tray
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// WatcherStatus is the folder watcher's activity, as reported by GET /api/watcher
+type WatcherStatus struct {
+	Paused     bool       `json:"paused"`
+	Pending    int        `json:"pending"`
+	Ingesting  int        `json:"ingesting"`
+	Ingested   int64      `json:"ingested"`
+	Failed     int64      `json:"failed"`
+	LastIngest *time.Time `json:"last_ingest,omitempty"`
+}
+
+// State is what the tray menu shows
+type State struct {
+	Mode     string         // "local" or "cloud"
+	Provider string         // Name of the active provider
+	Watcher  *WatcherStatus // nil when the watcher cannot be controlled from this instance
+}
+
+// Client calls the local Noodexx server
+type Client struct {
+	baseURL string
+	http    *http.Client
+}
+
+// NewClient creates a client for the server at baseURL, e.g. http://127.0.0.1:8080
+func NewClient(baseURL string) *Client {
+	return &Client{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		http:    &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// State fetches the AI mode and the watcher status
+func (c *Client) State(ctx context.Context) (State, error) {
+	var privacy struct {
+		Mode     string `json:"mode"`
+		Provider string `json:"provider"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/api/privacy-toggle", nil, &privacy); err != nil {
+		return State{}, err
+	}
+
+	var watcher struct {
+		Available bool          `json:"available"`
+		Status    WatcherStatus `json:"status"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/api/watcher", nil, &watcher); err != nil {
+		return State{}, err
+	}
+
+	state := State{Mode: privacy.Mode, Provider: privacy.Provider}
+	if watcher.Available {
+		state.Watcher = &watcher.Status
+	}
+	return state, nil
+}
+
+// SetMode switches answers to the "local" or "cloud" AI
+func (c *Client) SetMode(ctx context.Context, mode string) error {
+	return c.do(ctx, http.MethodPost, "/api/privacy-toggle", map[string]string{"mode": mode}, nil)
+}
+
+// SetWatcherPaused pauses or resumes folder watching
+func (c *Client) SetWatcherPaused(ctx context.Context, paused bool) error {
+	path := "/api/watcher/resume"
+	if paused {
+		path = "/api/watcher/pause"
+	}
+	return c.do(ctx, http.MethodPost, path, nil, nil)
+}
+
+// do sends a request and decodes a successful response into out, when not nil
+func (c *Client) do(ctx context.Context, method, path string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("noodexx is not reachable: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var apiErr struct {
+			Message string `json:"message"`
+		}
+		json.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&apiErr)
+		if apiErr.Message == "" {
+			apiErr.Message = resp.Status
+		}
+		return fmt.Errorf("%s %s failed: %s", method, path, apiErr.Message)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode %s response: %w", path, err)
+	}
+	return nil
+}
+
+// StatusLine summarizes the watcher for the menu
+func StatusLine(watcher *WatcherStatus) string {
+	switch {
+	case watcher == nil:
+		return "Folder watching runs on another instance"
+	case watcher.Paused && watcher.Pending > 0:
+		return fmt.Sprintf("Paused, %s waiting", plural(watcher.Pending, "change"))
+	case watcher.Paused:
+		return "Paused"
+	case watcher.Ingesting > 0:
+		return fmt.Sprintf("Ingesting %s", plural(watcher.Ingesting, "file"))
+	case watcher.Ingested == 0 && watcher.Failed == 0:
+		return "Watching, nothing ingested yet"
+	case watcher.Failed > 0:
+		return fmt.Sprintf("Watching, %s ingested, %s", plural(int(watcher.Ingested), "file"), plural(int(watcher.Failed), "failure"))
+	default:
+		return fmt.Sprintf("Watching, %s ingested", plural(int(watcher.Ingested), "file"))
+	}
+}
+
+func plural(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
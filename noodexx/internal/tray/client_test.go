@@ -0,0 +1,103 @@
+package tray
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestClientStateAndActions(t *testing.T) {
+	mode := "local"
+	paused := false
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/privacy-toggle", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "mode": mode, "provider": "ollama"})
+	})
+	mux.HandleFunc("POST /api/privacy-toggle", func(w http.ResponseWriter, r *http.Request) {
+		var req struct{ Mode string }
+		json.NewDecoder(r.Body).Decode(&req)
+		mode = req.Mode
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": true})
+	})
+	mux.HandleFunc("GET /api/watcher", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success":   true,
+			"available": true,
+			"status":    map[string]interface{}{"paused": paused, "ingested": 4},
+		})
+	})
+	mux.HandleFunc("POST /api/watcher/pause", func(w http.ResponseWriter, r *http.Request) {
+		paused = true
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": true})
+	})
+	mux.HandleFunc("POST /api/watcher/resume", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": false, "message": "Forbidden: admin access required"})
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	ctx := context.Background()
+	client := NewClient(ts.URL + "/")
+
+	state, err := client.State(ctx)
+	if err != nil {
+		t.Fatalf("State failed: %v", err)
+	}
+	if state.Mode != "local" || state.Provider != "ollama" || state.Watcher == nil || state.Watcher.Ingested != 4 {
+		t.Errorf("Unexpected state: %+v", state)
+	}
+
+	if err := client.SetMode(ctx, "cloud"); err != nil || mode != "cloud" {
+		t.Errorf("Expected the mode switched to cloud, got %s, %v", mode, err)
+	}
+	if err := client.SetWatcherPaused(ctx, true); err != nil || !paused {
+		t.Errorf("Expected the watcher paused, got %v, %v", paused, err)
+	}
+
+	err = client.SetWatcherPaused(ctx, false)
+	if err == nil || !strings.Contains(err.Error(), "admin access required") {
+		t.Errorf("Expected the API error message, got %v", err)
+	}
+}
+
+func TestStatusLine(t *testing.T) {
+	tests := []struct {
+		status *WatcherStatus
+		want   string
+	}{
+		{nil, "Folder watching runs on another instance"},
+		{&WatcherStatus{Paused: true, Pending: 3}, "Paused, 3 changes waiting"},
+		{&WatcherStatus{Paused: true}, "Paused"},
+		{&WatcherStatus{Ingesting: 1, Ingested: 9}, "Ingesting 1 file"},
+		{&WatcherStatus{}, "Watching, nothing ingested yet"},
+		{&WatcherStatus{Ingested: 12, Failed: 1}, "Watching, 12 files ingested, 1 failure"},
+		{&WatcherStatus{Ingested: 1}, "Watching, 1 file ingested"},
+	}
+	for _, tt := range tests {
+		if got := StatusLine(tt.status); got != tt.want {
+			t.Errorf("StatusLine(%+v) = %q, want %q", tt.status, got, tt.want)
+		}
+	}
+}
+
+func TestPNGToICO(t *testing.T) {
+	ico := pngToICO(iconPNG, 32)
+
+	if binary.LittleEndian.Uint16(ico[2:]) != 1 || binary.LittleEndian.Uint16(ico[4:]) != 1 {
+		t.Errorf("Expected an icon with one image, got header %v", ico[:6])
+	}
+	if ico[6] != 32 || ico[7] != 32 {
+		t.Errorf("Expected a 32x32 entry, got %dx%d", ico[6], ico[7])
+	}
+	if size := binary.LittleEndian.Uint32(ico[14:]); int(size) != len(iconPNG) {
+		t.Errorf("Expected image size %d, got %d", len(iconPNG), size)
+	}
+	if offset := binary.LittleEndian.Uint32(ico[18:]); offset != 22 || string(ico[23:26]) != "PNG" {
+		t.Errorf("Expected the PNG right after the directory, got offset %d", offset)
+	}
+}
@@ -0,0 +1,38 @@
+package tray
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/binary"
+	"runtime"
+)
+
+//go:embed icon.png
+var iconPNG []byte
+
+// Icon returns the tray icon in the format the platform's tray takes: ICO on
+// Windows, PNG elsewhere
+func Icon() []byte {
+	if runtime.GOOS == "windows" {
+		return pngToICO(iconPNG, 32)
+	}
+	return iconPNG
+}
+
+// pngToICO wraps a square PNG of the given size in an ICO container, which
+// Windows reads since Vista
+func pngToICO(png []byte, size int) []byte {
+	var buf bytes.Buffer
+	// ICONDIR: reserved, type 1 (icon), one image
+	binary.Write(&buf, binary.LittleEndian, [3]uint16{0, 1, 1})
+	// ICONDIRENTRY: width and height (0 means 256), no palette, 1 plane, 32 bpp
+	dim := byte(size)
+	if size >= 256 {
+		dim = 0
+	}
+	buf.Write([]byte{dim, dim, 0, 0})
+	binary.Write(&buf, binary.LittleEndian, [2]uint16{1, 32})
+	binary.Write(&buf, binary.LittleEndian, [2]uint32{uint32(len(png)), 6 + 16})
+	buf.Write(png)
+	return buf.Bytes()
+}
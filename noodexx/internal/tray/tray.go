@@ -0,0 +1,127 @@
+//go:build tray
+
+package tray
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"time"
+
+	"fyne.io/systray"
+)
+
+// refreshInterval is how often the menu re-reads the server's state
+const refreshInterval = 3 * time.Second
+
+// Run shows the tray icon until the user picks Quit or ctx is cancelled, then
+// calls quit. It must be called from the main goroutine
+func Run(ctx context.Context, client *Client, uiURL string, quit func()) {
+	systray.Run(func() { onReady(ctx, client, uiURL) }, quit)
+}
+
+func onReady(ctx context.Context, client *Client, uiURL string) {
+	systray.SetIcon(Icon())
+	systray.SetTooltip("Noodexx")
+
+	mOpen := systray.AddMenuItem("Open Noodexx", "Open the web interface")
+	systray.AddSeparator()
+	mLocal := systray.AddMenuItemCheckbox("Use local AI", "Answer with the local provider; off uses the cloud provider", false)
+	mPause := systray.AddMenuItemCheckbox("Pause folder watching", "Hold changes in watched folders until resumed", false)
+	mStatus := systray.AddMenuItem("Connecting...", "Folder watcher status")
+	mStatus.Disable()
+	systray.AddSeparator()
+	mQuit := systray.AddMenuItem("Quit Noodexx", "Stop the server and remove the tray icon")
+
+	var state State
+	refresh := func() {
+		callCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+		current, err := client.State(callCtx)
+		if err != nil {
+			mStatus.SetTitle("Server not responding")
+			systray.SetTooltip(fmt.Sprintf("Noodexx: %v", err))
+			return
+		}
+		state = current
+		if state.Mode == "local" {
+			mLocal.Check()
+		} else {
+			mLocal.Uncheck()
+		}
+		if state.Watcher == nil {
+			mPause.Disable()
+		} else {
+			mPause.Enable()
+			if state.Watcher.Paused {
+				mPause.Check()
+			} else {
+				mPause.Uncheck()
+			}
+		}
+		line := StatusLine(state.Watcher)
+		mStatus.SetTitle(line)
+		systray.SetTooltip(fmt.Sprintf("Noodexx (%s): %s", state.Provider, line))
+	}
+	// act runs a quick action, shows a failure in the status line, then
+	// re-reads the state so the menu matches the server
+	act := func(action func(context.Context) error) {
+		callCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		defer cancel()
+		if err := action(callCtx); err != nil {
+			mStatus.SetTitle(fmt.Sprintf("Failed: %v", err))
+			return
+		}
+		refresh()
+	}
+
+	go func() {
+		refresh()
+		ticker := time.NewTicker(refreshInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				systray.Quit()
+				return
+			case <-ticker.C:
+				refresh()
+			case <-mOpen.ClickedCh:
+				if err := openBrowser(uiURL); err != nil {
+					mStatus.SetTitle(fmt.Sprintf("Failed to open browser: %v", err))
+				}
+			case <-mLocal.ClickedCh:
+				mode := "local"
+				if state.Mode == "local" {
+					mode = "cloud"
+				}
+				act(func(ctx context.Context) error { return client.SetMode(ctx, mode) })
+			case <-mPause.ClickedCh:
+				paused := state.Watcher != nil && !state.Watcher.Paused
+				act(func(ctx context.Context) error { return client.SetWatcherPaused(ctx, paused) })
+			case <-mQuit.ClickedCh:
+				systray.Quit()
+				return
+			}
+		}
+	}()
+}
+
+// openBrowser opens url in the default browser
+func openBrowser(url string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
+	case "darwin":
+		cmd = exec.Command("open", url)
+	default:
+		cmd = exec.Command("xdg-open", url)
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	go cmd.Wait()
+	return nil
+}
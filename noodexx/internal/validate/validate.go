@@ -0,0 +1,209 @@
+// Package validate holds the input rules shared by the API handlers, so a field
+// such as a username or email is checked the same way wherever it is accepted
+// and every failure is reported per field.
+package validate
+
+import (
+	"fmt"
+	"math"
+	"net/mail"
+	"net/url"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Limits on accepted input
+const (
+	MinUsernameLength = 3
+	MaxUsernameLength = 32
+	MinPasswordLength = 8
+	MaxPasswordLength = 256 // bcrypt ignores bytes past 72; this only bounds request size
+	MaxEmailLength    = 254
+	MaxSourceLength   = 512
+	MaxURLLength      = 2048
+	MaxTags           = 32
+	MaxTagLength      = 64
+)
+
+// FieldError is a rule broken by one input field
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Error returns the message
+func (e FieldError) Error() string {
+	return e.Message
+}
+
+// Errors is every rule broken by a request, in the order they were checked
+type Errors []FieldError
+
+// Error returns the messages joined with "; "
+func (e Errors) Error() string {
+	messages := make([]string, len(e))
+	for i, fe := range e {
+		messages[i] = fe.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Validator collects the field errors of one request
+type Validator struct {
+	errs Errors
+}
+
+// New creates an empty validator
+func New() *Validator {
+	return &Validator{}
+}
+
+// Check records err against field; a nil err is ignored
+// Only the first error per field is kept
+func (v *Validator) Check(field string, err error) {
+	if err == nil {
+		return
+	}
+	for _, fe := range v.errs {
+		if fe.Field == field {
+			return
+		}
+	}
+	v.errs = append(v.errs, FieldError{Field: field, Message: err.Error()})
+}
+
+// Required records an error when value is empty or only whitespace
+func (v *Validator) Required(field, label, value string) {
+	if strings.TrimSpace(value) == "" {
+		v.Check(field, fmt.Errorf("%s is required", label))
+	}
+}
+
+// Err returns the collected errors as Errors, or nil if there were none
+func (v *Validator) Err() error {
+	if len(v.errs) == 0 {
+		return nil
+	}
+	return v.errs
+}
+
+// Username checks length and that only letters, numbers, underscores and dashes are used
+func Username(s string) error {
+	if len(s) < MinUsernameLength || len(s) > MaxUsernameLength {
+		return fmt.Errorf("Username must be between %d and %d characters", MinUsernameLength, MaxUsernameLength)
+	}
+	for _, c := range s {
+		if !((c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') || c == '_' || c == '-') {
+			return fmt.Errorf("Username can only contain letters, numbers, underscores, and dashes")
+		}
+	}
+	return nil
+}
+
+// Email checks for a bare address (no display name) whose domain contains a dot
+func Email(s string) error {
+	if len(s) > MaxEmailLength {
+		return fmt.Errorf("Email must be at most %d characters", MaxEmailLength)
+	}
+	addr, err := mail.ParseAddress(s)
+	if err != nil || addr.Address != s || addr.Name != "" {
+		return fmt.Errorf("Invalid email format")
+	}
+	at := strings.LastIndex(s, "@")
+	if domain := s[at+1:]; !strings.Contains(domain, ".") || strings.HasPrefix(domain, ".") || strings.HasSuffix(domain, ".") {
+		return fmt.Errorf("Invalid email format")
+	}
+	return nil
+}
+
+// Password checks the length bounds of a new password
+func Password(s string) error {
+	if len(s) < MinPasswordLength {
+		return fmt.Errorf("Password must be at least %d characters", MinPasswordLength)
+	}
+	if len(s) > MaxPasswordLength {
+		return fmt.Errorf("Password must be at most %d characters", MaxPasswordLength)
+	}
+	return nil
+}
+
+// URL checks for an absolute http or https URL with a host
+func URL(s string) error {
+	if len(s) > MaxURLLength {
+		return fmt.Errorf("URL must be at most %d characters", MaxURLLength)
+	}
+	u, err := url.Parse(s)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("URL must be an absolute http or https URL")
+	}
+	return nil
+}
+
+// Source checks a document source name: bounded length, valid UTF-8 and no control characters
+func Source(s string) error {
+	if len(s) > MaxSourceLength {
+		return fmt.Errorf("Source must be at most %d characters", MaxSourceLength)
+	}
+	if !utf8.ValidString(s) || strings.IndexFunc(s, unicode.IsControl) >= 0 {
+		return fmt.Errorf("Source must not contain control characters")
+	}
+	return nil
+}
+
+// Tags checks the number and length of document tags
+func Tags(tags []string) error {
+	if len(tags) > MaxTags {
+		return fmt.Errorf("At most %d tags are allowed", MaxTags)
+	}
+	for _, tag := range tags {
+		if len(tag) > MaxTagLength {
+			return fmt.Errorf("Tags must be at most %d characters", MaxTagLength)
+		}
+		if strings.ContainsAny(tag, ",\n") {
+			return fmt.Errorf("Tags must not contain commas or line breaks")
+		}
+	}
+	return nil
+}
+
+// Visibilities lists who can search a document: its owner only, also the users
+// it is shared with, or everyone
+var Visibilities = []string{"private", "shared", "public"}
+
+// Visibility checks a document visibility; empty leaves the user's default
+func Visibility(s string) error {
+	if s == "" {
+		return nil
+	}
+	return OneOf("Visibility", s, Visibilities...)
+}
+
+// OneOf checks that value is one of allowed
+func OneOf(label, value string, allowed ...string) error {
+	for _, a := range allowed {
+		if value == a {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s must be one of %s", label, strings.Join(allowed, ", "))
+}
+
+// IntRange parses s as an integer between min and max inclusive
+func IntRange(label, s string, min, max int) (int, error) {
+	n, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil || n < min || n > max {
+		return 0, fmt.Errorf("%s must be a whole number between %d and %d", label, min, max)
+	}
+	return n, nil
+}
+
+// FloatRange parses s as a number between min and max inclusive
+func FloatRange(label, s string, min, max float64) (float64, error) {
+	f, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil || math.IsNaN(f) || f < min || f > max {
+		return 0, fmt.Errorf("%s must be a number between %g and %g", label, min, max)
+	}
+	return f, nil
+}
@@ -0,0 +1,108 @@
+package validate
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		wantErr bool
+	}{
+		{"valid username", Username("alice_01"), false},
+		{"short username", Username("al"), true},
+		{"username with space", Username("alice smith"), true},
+		{"valid email", Email("alice@example.com"), false},
+		{"email without dot in domain", Email("alice@localhost"), true},
+		{"email with display name", Email("Alice <alice@example.com>"), true},
+		{"email with trailing dot", Email("alice@example."), true},
+		{"valid password", Password("correct horse"), false},
+		{"short password", Password("short"), true},
+		{"long password", Password(strings.Repeat("x", MaxPasswordLength+1)), true},
+		{"valid url", URL("https://example.com/page"), false},
+		{"relative url", URL("/page"), true},
+		{"file url", URL("file:///etc/passwd"), true},
+		{"valid source", Source("notes/meeting.md"), false},
+		{"source with newline", Source("notes\nmeeting.md"), true},
+		{"long source", Source(strings.Repeat("a", MaxSourceLength+1)), true},
+		{"valid tags", Tags([]string{"work", "q3"}), false},
+		{"tag with comma", Tags([]string{"a,b"}), true},
+		{"too many tags", Tags(make([]string, MaxTags+1)), true},
+		{"allowed value", OneOf("Mode", "off", "strict", "normal", "off"), false},
+		{"unknown value", OneOf("Mode", "loose", "strict", "normal", "off"), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if (tt.err != nil) != tt.wantErr {
+				t.Errorf("Expected error=%v, got %v", tt.wantErr, tt.err)
+			}
+		})
+	}
+}
+
+func TestIntRange(t *testing.T) {
+	if n, err := IntRange("Size", " 12 ", 1, 100); err != nil || n != 12 {
+		t.Errorf("Expected 12, got %d (%v)", n, err)
+	}
+	for _, s := range []string{"0", "101", "ten", ""} {
+		if _, err := IntRange("Size", s, 1, 100); err == nil {
+			t.Errorf("Expected error for %q", s)
+		}
+	}
+}
+
+func TestVisibility(t *testing.T) {
+	for _, s := range []string{"", "private", "shared", "public"} {
+		if err := Visibility(s); err != nil {
+			t.Errorf("Expected %q to be valid, got %v", s, err)
+		}
+	}
+	if err := Visibility("Public"); err == nil {
+		t.Error("Expected error for a visibility in the wrong case")
+	}
+}
+
+func TestFloatRange(t *testing.T) {
+	if f, err := FloatRange("Score", " 0.25 ", 0, 1); err != nil || f != 0.25 {
+		t.Errorf("Expected 0.25, got %v (%v)", f, err)
+	}
+	for _, s := range []string{"-0.1", "1.5", "NaN", "high", ""} {
+		if _, err := FloatRange("Score", s, 0, 1); err == nil {
+			t.Errorf("Expected error for %q", s)
+		}
+	}
+}
+
+func TestValidator(t *testing.T) {
+	v := New()
+	v.Required("username", "Username", "  ")
+	v.Check("username", Username("x"))
+	v.Check("password", Password("short"))
+	v.Check("email", nil)
+
+	err := v.Err()
+	var fields Errors
+	if !errors.As(err, &fields) {
+		t.Fatalf("Expected Errors, got %T", err)
+	}
+	if len(fields) != 2 {
+		t.Fatalf("Expected one error per field, got %+v", fields)
+	}
+	if fields[0].Field != "username" || fields[0].Message != "Username is required" {
+		t.Errorf("Expected the first username error to be kept, got %+v", fields[0])
+	}
+	if fields[1].Field != "password" {
+		t.Errorf("Expected password error second, got %+v", fields[1])
+	}
+	if !strings.Contains(err.Error(), "; ") {
+		t.Errorf("Expected joined messages, got %q", err.Error())
+	}
+
+	if err := New().Err(); err != nil {
+		t.Errorf("Expected nil error from empty validator, got %v", err)
+	}
+}
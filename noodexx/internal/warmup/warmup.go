@@ -0,0 +1,226 @@
+// Package warmup keeps the local provider's models loaded. Ollama loads a model
+// on first use and unloads it after a few idle minutes, so the first answer
+// after a break can take tens of seconds. A Warmer loads the models at startup
+// and pings them periodically while people are using Noodexx; once nobody has
+// chatted for the idle window it stops pinging and lets them unload.
+package warmup
+
+import (
+	"context"
+	"errors"
+	"noodexx/internal/llm"
+	"noodexx/internal/logging"
+	"sync"
+	"time"
+)
+
+// ErrUnsupported is returned when the local provider has no models to warm
+var ErrUnsupported = errors.New("local provider does not load models on demand")
+
+// State describes whether the local models are loaded
+type State string
+
+const (
+	StateCold        State = "cold"        // Not loaded, or unloaded since the last use
+	StateWarming     State = "warming"     // Warm-up or ping in progress
+	StateWarm        State = "warm"        // Loaded recently enough to still be in memory
+	StateFailed      State = "failed"      // The last warm-up or ping failed
+	StateUnsupported State = "unsupported" // The local provider has nothing to warm
+)
+
+// Source returns the current local provider's warmer, or nil if it has none
+// It is called for every warm-up so provider reloads are picked up
+type Source func() llm.Warmer
+
+// Options configures a Warmer
+type Options struct {
+	PingInterval time.Duration // Time between keep-alive pings
+	IdleWindow   time.Duration // Stop pinging after this long without activity
+	UnloadAfter  time.Duration // How long the backend keeps an unused model loaded
+}
+
+// Status is a snapshot of the warmer
+type Status struct {
+	State        State
+	KeepAlive    bool          // Keep-alive pinging is running
+	Paused       bool          // Pinging is paused because nobody chatted within the idle window
+	LastLoaded   time.Time     // Last warm-up, ping or request that used the models
+	LastActivity time.Time     // Last request that used the models
+	LastLatency  time.Duration // Duration of the last warm-up or ping
+	LastError    string
+	Pings        int64
+}
+
+// Warmer loads the local models ahead of use and keeps them loaded while active
+type Warmer struct {
+	source Source
+	opts   Options
+	logger *logging.Logger
+	now    func() time.Time
+
+	mu           sync.Mutex
+	keepAlive    bool
+	warming      bool
+	unsupported  bool
+	lastLoaded   time.Time
+	lastActivity time.Time
+	lastLatency  time.Duration
+	lastError    string
+	lastErrorAt  time.Time
+	pings        int64
+}
+
+// New creates a warmer; startup counts as activity, so pinging runs for at
+// least one idle window after Noodexx starts
+func New(source Source, opts Options, logger *logging.Logger) *Warmer {
+	if opts.PingInterval <= 0 {
+		opts.PingInterval = 2 * time.Minute
+	}
+	if opts.IdleWindow <= 0 {
+		opts.IdleWindow = 30 * time.Minute
+	}
+	if opts.UnloadAfter <= 0 {
+		opts.UnloadAfter = 5 * time.Minute
+	}
+	return &Warmer{
+		source:       source,
+		opts:         opts,
+		logger:       logger,
+		now:          time.Now,
+		lastActivity: time.Now(),
+	}
+}
+
+// WarmUp loads the local models and waits until they are ready
+func (w *Warmer) WarmUp(ctx context.Context) error {
+	return w.warm(ctx, false)
+}
+
+// Touch records that a request used the local models, which also keeps them loaded
+func (w *Warmer) Touch() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	now := w.now()
+	w.lastActivity = now
+	w.lastLoaded = now
+}
+
+// Run pings the local models every PingInterval until ctx is cancelled
+// A ping is skipped when the models were used in the last half interval or
+// when nobody has used them within the idle window
+func (w *Warmer) Run(ctx context.Context) {
+	w.mu.Lock()
+	w.keepAlive = true
+	w.mu.Unlock()
+	defer func() {
+		w.mu.Lock()
+		w.keepAlive = false
+		w.mu.Unlock()
+	}()
+
+	ticker := time.NewTicker(w.opts.PingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if w.shouldPing() {
+				w.warm(ctx, true)
+			}
+		}
+	}
+}
+
+// Status returns a snapshot of the warmer
+func (w *Warmer) Status() Status {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	now := w.now()
+	status := Status{
+		KeepAlive:    w.keepAlive,
+		Paused:       w.keepAlive && w.idle(now),
+		LastLoaded:   w.lastLoaded,
+		LastActivity: w.lastActivity,
+		LastLatency:  w.lastLatency,
+		LastError:    w.lastError,
+		Pings:        w.pings,
+	}
+	switch {
+	case w.warming:
+		status.State = StateWarming
+	case w.unsupported:
+		status.State = StateUnsupported
+	case !w.lastErrorAt.IsZero() && w.lastErrorAt.After(w.lastLoaded):
+		status.State = StateFailed
+	case !w.lastLoaded.IsZero() && now.Sub(w.lastLoaded) < w.opts.UnloadAfter:
+		status.State = StateWarm
+	default:
+		status.State = StateCold
+	}
+	return status
+}
+
+// shouldPing reports whether a keep-alive ping is due
+func (w *Warmer) shouldPing() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	now := w.now()
+	if w.warming || w.idle(now) {
+		return false
+	}
+	return w.lastLoaded.IsZero() || now.Sub(w.lastLoaded) >= w.opts.PingInterval/2
+}
+
+// idle reports whether nobody has used the models within the idle window
+// The caller must hold w.mu
+func (w *Warmer) idle(now time.Time) bool {
+	return now.Sub(w.lastActivity) >= w.opts.IdleWindow
+}
+
+// warm loads the models once and records the outcome; ping marks it as a keep-alive
+func (w *Warmer) warm(ctx context.Context, ping bool) error {
+	warmer := w.source()
+
+	w.mu.Lock()
+	if warmer == nil {
+		w.unsupported = true
+		w.mu.Unlock()
+		return ErrUnsupported
+	}
+	w.unsupported = false
+	w.warming = true
+	w.mu.Unlock()
+
+	start := time.Now()
+	err := warmer.WarmUp(ctx)
+	latency := time.Since(start)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.warming = false
+	w.lastLatency = latency
+	if ping {
+		w.pings++
+	}
+	if err != nil {
+		w.lastError = err.Error()
+		w.lastErrorAt = w.now()
+		w.logger.WithFields(map[string]interface{}{
+			"ping":       ping,
+			"error":      err.Error(),
+			"latency_ms": latency.Milliseconds(),
+		}).Warn("model warm-up failed")
+		return err
+	}
+	w.lastError = ""
+	w.lastLoaded = w.now()
+	w.logger.WithFields(map[string]interface{}{
+		"ping":       ping,
+		"latency_ms": latency.Milliseconds(),
+	}).Debug("model warm-up completed")
+	return nil
+}
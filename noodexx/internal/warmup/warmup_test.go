@@ -0,0 +1,113 @@
+package warmup
+
+import (
+	"context"
+	"errors"
+	"io"
+	"noodexx/internal/llm"
+	"noodexx/internal/logging"
+	"testing"
+	"time"
+)
+
+// fakeWarmer counts warm-ups and fails with err when set
+type fakeWarmer struct {
+	calls int
+	err   error
+}
+
+func (f *fakeWarmer) WarmUp(ctx context.Context) error {
+	f.calls++
+	return f.err
+}
+
+// newTestWarmer returns a warmer over model with a controllable clock
+func newTestWarmer(model *fakeWarmer, clock *time.Time) *Warmer {
+	logger := logging.NewLogger("test", logging.ERROR, io.Discard)
+	w := New(func() llm.Warmer { return model }, Options{
+		PingInterval: 2 * time.Minute,
+		IdleWindow:   30 * time.Minute,
+		UnloadAfter:  5 * time.Minute,
+	}, logger)
+	w.now = func() time.Time { return *clock }
+	w.lastActivity = *clock
+	return w
+}
+
+// TestWarmUpState tests the state reported before, after and long after a warm-up
+func TestWarmUpState(t *testing.T) {
+	clock := time.Unix(1000, 0)
+	model := &fakeWarmer{}
+	w := newTestWarmer(model, &clock)
+
+	if state := w.Status().State; state != StateCold {
+		t.Errorf("Expected cold before warm-up, got %s", state)
+	}
+	if err := w.WarmUp(context.Background()); err != nil || model.calls != 1 {
+		t.Fatalf("WarmUp() error = %v, calls = %d", err, model.calls)
+	}
+	if state := w.Status().State; state != StateWarm {
+		t.Errorf("Expected warm after warm-up, got %s", state)
+	}
+
+	clock = clock.Add(6 * time.Minute)
+	if state := w.Status().State; state != StateCold {
+		t.Errorf("Expected cold once the backend would have unloaded the model, got %s", state)
+	}
+
+	model.err = errors.New("connection refused")
+	if err := w.WarmUp(context.Background()); err == nil {
+		t.Fatal("Expected the warm-up error")
+	}
+	if status := w.Status(); status.State != StateFailed || status.LastError != "connection refused" {
+		t.Errorf("Expected a failed state with the error, got %+v", status)
+	}
+}
+
+// TestShouldPing tests that pings skip recently used models and stop after the idle window
+func TestShouldPing(t *testing.T) {
+	clock := time.Unix(1000, 0)
+	w := newTestWarmer(&fakeWarmer{}, &clock)
+
+	if !w.shouldPing() {
+		t.Error("Expected a ping for models never loaded")
+	}
+
+	w.Touch()
+	clock = clock.Add(30 * time.Second)
+	if w.shouldPing() {
+		t.Error("Expected no ping right after a request used the models")
+	}
+
+	clock = clock.Add(2 * time.Minute)
+	if !w.shouldPing() {
+		t.Error("Expected a ping once the models have been unused for half an interval")
+	}
+
+	clock = clock.Add(31 * time.Minute)
+	if w.shouldPing() {
+		t.Error("Expected no ping after the idle window")
+	}
+	w.keepAlive = true
+	if !w.Status().Paused {
+		t.Error("Expected keep-alive to report as paused after the idle window")
+	}
+
+	w.Touch()
+	if w.Status().Paused {
+		t.Error("Expected activity to resume keep-alive")
+	}
+}
+
+// TestUnsupported tests that providers without on-demand models are reported as such
+func TestUnsupported(t *testing.T) {
+	logger := logging.NewLogger("test", logging.ERROR, io.Discard)
+	w := New(func() llm.Warmer { return nil }, Options{}, logger)
+
+	if err := w.WarmUp(context.Background()); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("Expected ErrUnsupported, got %v", err)
+	}
+	if state := w.Status().State; state != StateUnsupported {
+		t.Errorf("Expected unsupported state, got %s", state)
+	}
+}
@@ -0,0 +1,24 @@
+//go:build windows
+
+package watcher
+
+import (
+	"errors"
+
+	"golang.org/x/sys/windows"
+)
+
+// fileLocked reports whether another process holds a file open, as sync
+// clients do while writing it: opening it without sharing fails then
+func fileLocked(path string) bool {
+	name, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return false
+	}
+	handle, err := windows.CreateFile(name, windows.GENERIC_READ, 0, nil, windows.OPEN_EXISTING, windows.FILE_ATTRIBUTE_NORMAL, 0)
+	if err != nil {
+		return errors.Is(err, windows.ERROR_SHARING_VIOLATION) || errors.Is(err, windows.ERROR_LOCK_VIOLATION)
+	}
+	windows.CloseHandle(handle)
+	return false
+}
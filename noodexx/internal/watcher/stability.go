@@ -0,0 +1,294 @@
+package watcher
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// tickInterval is how often settling files are checked and polled folders scanned
+const tickInterval = 500 * time.Millisecond
+
+// Tuning controls how the watcher decides a changed file is ready to ingest.
+// Files synced by Dropbox or OneDrive, or copied to a network share, are
+// written over seconds, and ingesting them on the first event reads them half
+// written
+type Tuning struct {
+	StableFor    time.Duration // Size and modification time must hold this long; 0 ingests on the first event
+	MaxWait      time.Duration // A file still changing or locked after this long is ingested anyway; 0 waits as long as it changes
+	ReadRetries  int           // Retries of a failed read, such as of a file a sync client holds open
+	RetryDelay   time.Duration // Delay before the first retry, doubled after each
+	PollInterval time.Duration // Scan the folder this often instead of relying on change events, which network mounts often don't deliver; 0 uses events
+}
+
+// merge fills the zero fields of t from defaults
+func (t Tuning) merge(defaults Tuning) Tuning {
+	if t.StableFor == 0 {
+		t.StableFor = defaults.StableFor
+	}
+	if t.MaxWait == 0 {
+		t.MaxWait = defaults.MaxWait
+	}
+	if t.ReadRetries == 0 {
+		t.ReadRetries = defaults.ReadRetries
+	}
+	if t.RetryDelay == 0 {
+		t.RetryDelay = defaults.RetryDelay
+	}
+	if t.PollInterval == 0 {
+		t.PollInterval = defaults.PollInterval
+	}
+	return t
+}
+
+// fileState is what a file's size and modification time were when last seen
+type fileState struct {
+	size    int64
+	modTime time.Time
+}
+
+// settlingFile is a changed file waiting until it stops changing
+type settlingFile struct {
+	folder    string
+	userID    int64
+	state     fileState
+	firstSeen time.Time // First change of this run of changes
+	stableAt  time.Time // When the size and modification time last changed
+}
+
+// folderPoll is a folder scanned for changes instead of watched for events
+type folderPoll struct {
+	interval time.Duration
+	next     time.Time
+	files    map[string]fileState
+}
+
+// SetTuning sets how changed files are judged ready to ingest, with overrides
+// per folder path whose zero fields keep the defaults. Call it before Start
+func (w *Watcher) SetTuning(defaults Tuning, folders map[string]Tuning) {
+	w.tuning = defaults
+	w.folderTuning = make(map[string]Tuning, len(folders))
+	for path, t := range folders {
+		w.folderTuning[filepath.Clean(path)] = t.merge(defaults)
+	}
+}
+
+// tuningFor returns the tuning of a watched folder
+func (w *Watcher) tuningFor(folder string) Tuning {
+	if t, ok := w.folderTuning[filepath.Clean(folder)]; ok {
+		return t
+	}
+	return w.tuning
+}
+
+// statFile returns a file's size and modification time
+func statFile(path string) (fileState, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileState{}, err
+	}
+	return fileState{size: info.Size(), modTime: info.ModTime()}, nil
+}
+
+// ingestWhenStable ingests a changed file now, or once it has stopped changing
+// when its folder asks for stability
+func (w *Watcher) ingestWhenStable(ctx context.Context, path, folder string, userID int64) {
+	if w.tuningFor(folder).StableFor <= 0 {
+		w.ingestFile(ctx, path, folder, userID)
+		return
+	}
+	state, err := statFile(path)
+	if err != nil {
+		return
+	}
+
+	now := time.Now()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.settling == nil {
+		w.settling = make(map[string]*settlingFile)
+	}
+	if f, ok := w.settling[path]; ok {
+		if f.state != state {
+			f.state, f.stableAt = state, now
+		}
+		return
+	}
+	w.settling[path] = &settlingFile{folder: folder, userID: userID, state: state, firstSeen: now, stableAt: now}
+}
+
+// forgetSettling stops waiting for a file, such as one deleted while settling
+func (w *Watcher) forgetSettling(path string) {
+	w.mu.Lock()
+	delete(w.settling, path)
+	w.mu.Unlock()
+}
+
+// checkSettling ingests the settling files that have stopped changing and
+// are not locked, and those that waited longer than their folder's MaxWait.
+// While paused they keep settling and are ingested after Resume
+func (w *Watcher) checkSettling(ctx context.Context, now time.Time) {
+	type ready struct {
+		path, folder string
+		userID       int64
+	}
+	var ingest []ready
+
+	w.mu.Lock()
+	if w.paused {
+		w.mu.Unlock()
+		return
+	}
+	for path, f := range w.settling {
+		state, err := statFile(path)
+		if err != nil {
+			delete(w.settling, path)
+			continue
+		}
+		tuning := w.tuningFor(f.folder)
+		timedOut := tuning.MaxWait > 0 && now.Sub(f.firstSeen) >= tuning.MaxWait
+		if state != f.state {
+			f.state, f.stableAt = state, now
+			if !timedOut {
+				continue
+			}
+		}
+		if !timedOut && (now.Sub(f.stableAt) < tuning.StableFor || fileLocked(path)) {
+			continue
+		}
+		if timedOut {
+			w.logger.WithFields(map[string]interface{}{
+				"file_path": path,
+				"waited":    now.Sub(f.firstSeen).String(),
+			}).Warn("file did not settle, ingesting anyway")
+		}
+		delete(w.settling, path)
+		ingest = append(ingest, ready{path: path, folder: f.folder, userID: f.userID})
+	}
+	w.mu.Unlock()
+
+	for _, r := range ingest {
+		w.ingestFile(ctx, r.path, r.folder, r.userID)
+	}
+}
+
+// startPolling scans a folder for its current files, so only later changes
+// are reported
+func (w *Watcher) startPolling(folder string, interval time.Duration) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.polls == nil {
+		w.polls = make(map[string]*folderPoll)
+	}
+	w.polls[folder] = &folderPoll{
+		interval: interval,
+		next:     time.Now().Add(interval),
+		files:    scanFolder(folder),
+	}
+}
+
+// scanFolder returns the files directly in a folder, as fsnotify watches them
+func scanFolder(folder string) map[string]fileState {
+	entries, err := os.ReadDir(folder)
+	if err != nil {
+		return nil
+	}
+	files := make(map[string]fileState, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		files[filepath.Join(folder, entry.Name())] = fileState{size: info.Size(), modTime: info.ModTime()}
+	}
+	return files
+}
+
+// pollFolders scans the polled folders that are due and returns the changes
+// found as the events fsnotify would have sent
+func (w *Watcher) pollFolders(now time.Time) []fsnotify.Event {
+	w.mu.Lock()
+	due := make(map[string]*folderPoll)
+	for folder, p := range w.polls {
+		if !now.Before(p.next) {
+			p.next = now.Add(p.interval)
+			due[folder] = p
+		}
+	}
+	w.mu.Unlock()
+
+	var events []fsnotify.Event
+	for folder, p := range due {
+		files := scanFolder(folder)
+		if files == nil {
+			// An unreachable share keeps its last listing rather than
+			// reporting every file deleted
+			w.logger.WithContext("folder_path", folder).Warn("failed to scan polled folder")
+			continue
+		}
+		for path, state := range files {
+			old, seen := p.files[path]
+			switch {
+			case !seen:
+				events = append(events, fsnotify.Event{Name: path, Op: fsnotify.Create})
+			case old != state:
+				events = append(events, fsnotify.Event{Name: path, Op: fsnotify.Write})
+			}
+		}
+		for path := range p.files {
+			if _, ok := files[path]; !ok {
+				events = append(events, fsnotify.Event{Name: path, Op: fsnotify.Remove})
+			}
+		}
+		w.mu.Lock()
+		p.files = files
+		w.mu.Unlock()
+	}
+	return events
+}
+
+// tick checks the settling files and scans the polled folders that are due
+func (w *Watcher) tick(ctx context.Context, now time.Time) {
+	for _, event := range w.pollFolders(now) {
+		if w.hold(event) {
+			continue
+		}
+		w.handleEvent(ctx, event)
+	}
+	w.checkSettling(ctx, now)
+}
+
+// readFile reads a file, retrying failures other than its absence, which on
+// network mounts and synced folders are often transient
+func (w *Watcher) readFile(ctx context.Context, path string, tuning Tuning) ([]byte, error) {
+	delay := tuning.RetryDelay
+	if delay <= 0 {
+		delay = time.Second
+	}
+	for attempt := 0; ; attempt++ {
+		content, err := os.ReadFile(path)
+		if err == nil || os.IsNotExist(err) || attempt >= tuning.ReadRetries {
+			return content, err
+		}
+		w.logger.WithFields(map[string]interface{}{
+			"file_path": path,
+			"attempt":   attempt + 1,
+			"error":     err.Error(),
+		}).Debug("read failed, retrying")
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		}
+		delay *= 2
+	}
+}
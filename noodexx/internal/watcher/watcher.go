@@ -4,10 +4,13 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"noodexx/internal/events"
+	"noodexx/internal/ingest"
 	"noodexx/internal/logging"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/fsnotify/fsnotify"
@@ -23,18 +26,67 @@ type Watcher struct {
 	maxSize     int64
 	logger      *logging.Logger
 	folderUsers map[string]int64 // Maps folder path to user_id
+
+	quarantineAfter int                // Failed attempts before a file is no longer retried, 0 for the default
+	onQuarantine    QuarantineNotifier // Called when a file is quarantined, may be nil
+	events          *events.Bus        // Told about deleted files; nil when nothing listens
+
+	resumed chan struct{} // Wakes the event loop to process changes held while paused
+
+	tuning       Tuning            // When changed files are ready to ingest
+	folderTuning map[string]Tuning // Overrides per folder path
+
+	mu         sync.Mutex
+	paused     bool
+	pending    map[string]fsnotify.Event // Latest change per file while paused
+	settling   map[string]*settlingFile  // Changed files waiting to stop changing
+	polls      map[string]*folderPoll    // Folders scanned instead of watched for events
+	ingesting  int
+	ingested   int64
+	failed     int64
+	lastIngest time.Time
 }
 
+// Status is a snapshot of the watcher's ingestion activity since startup
+type Status struct {
+	Paused     bool
+	Pending    int       // Changed files held back while paused
+	Settling   int       // Changed files waiting to stop changing
+	Ingesting  int       // Files being ingested now
+	Ingested   int64     // Files ingested
+	Failed     int64     // Failed ingest attempts
+	LastIngest time.Time // Last successful ingest, zero if none
+}
+
+// DefaultQuarantineAfter is how many times in a row a file may fail to ingest
+// before the watcher stops retrying it
+const DefaultQuarantineAfter = 3
+
+// QuarantineNotifier is told when a file in a user's watched folder is quarantined
+type QuarantineNotifier func(userID int64, folder, path, errMsg string)
+
 // Ingester interface for processing files
 type Ingester interface {
 	IngestText(ctx context.Context, userID int64, source, text string, tags []string) error
 }
 
+// FileExtractor is implemented by ingesters that convert files with external
+// extractors; ok is false when none handles the file, which is then ingested
+// as text
+type FileExtractor interface {
+	ExtractFile(ctx context.Context, filename, mimeType string, data []byte) (text string, tags []string, ok bool, err error)
+}
+
 // Store interface for folder management
 type Store interface {
 	AddWatchedFolder(ctx context.Context, userID int64, path string) error
 	GetWatchedFolders(ctx context.Context) ([]WatchedFolder, error)
 	DeleteSource(ctx context.Context, source string) error
+
+	// Ingestion failures, so files that keep failing are quarantined
+	RecordIngestFailure(ctx context.Context, userID int64, folder, path, errMsg string) (int, error)
+	GetIngestFailureAttempts(ctx context.Context, path string) (int, error)
+	ClearIngestFailure(ctx context.Context, path string) error
 }
 
 // WatchedFolder represents a monitored directory
@@ -63,11 +115,148 @@ func NewWatcher(ingester Ingester, store Store, privacyMode bool, logger *loggin
 		maxSize:     10 * 1024 * 1024, // 10MB
 		logger:      logger,
 		folderUsers: make(map[string]int64),
+		resumed:     make(chan struct{}, 1),
+		pending:     make(map[string]fsnotify.Event),
 	}, nil
 }
 
+// SetQuarantine sets how many failed attempts quarantine a file, and who to tell
+// when one is; n of 0 or less keeps the default
+func (w *Watcher) SetQuarantine(n int, notify QuarantineNotifier) {
+	w.quarantineAfter = n
+	w.onQuarantine = notify
+}
+
+// SetEventBus publishes a document.deleted event when a watched file is deleted
+func (w *Watcher) SetEventBus(bus *events.Bus) {
+	w.events = bus
+}
+
+// AllowExtensions watches files with more extensions, such as those external
+// extractors are registered for. Call it before Start
+func (w *Watcher) AllowExtensions(exts ...string) {
+	for _, ext := range exts {
+		ext = strings.ToLower(ext)
+		allowed := false
+		for _, allowedExt := range w.allowedExts {
+			if ext == allowedExt {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			w.allowedExts = append(w.allowedExts, ext)
+		}
+	}
+}
+
+// QuarantineLimit returns how many failed attempts quarantine a file
+func (w *Watcher) QuarantineLimit() int {
+	if w.quarantineAfter <= 0 {
+		return DefaultQuarantineAfter
+	}
+	return w.quarantineAfter
+}
+
+// Pause stops ingesting changes; changes made while paused are kept, latest
+// per file, and processed on Resume
+func (w *Watcher) Pause() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.paused {
+		w.paused = true
+		w.logger.Info("folder watcher paused")
+	}
+}
+
+// Resume ingests the changes held while paused and continues watching
+func (w *Watcher) Resume() {
+	w.mu.Lock()
+	wasPaused := w.paused
+	w.paused = false
+	w.mu.Unlock()
+	if !wasPaused {
+		return
+	}
+	w.logger.Info("folder watcher resumed")
+	select {
+	case w.resumed <- struct{}{}:
+	default:
+	}
+}
+
+// Status returns a snapshot of the watcher
+func (w *Watcher) Status() Status {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return Status{
+		Paused:     w.paused,
+		Pending:    len(w.pending),
+		Settling:   len(w.settling),
+		Ingesting:  w.ingesting,
+		Ingested:   w.ingested,
+		Failed:     w.failed,
+		LastIngest: w.lastIngest,
+	}
+}
+
+// hold keeps a change for later when the watcher is paused
+func (w *Watcher) hold(event fsnotify.Event) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.paused {
+		return false
+	}
+	if w.pending == nil {
+		w.pending = make(map[string]fsnotify.Event)
+	}
+	w.pending[event.Name] = event
+	return true
+}
+
+// processPending handles the changes held while paused
+func (w *Watcher) processPending(ctx context.Context) {
+	w.mu.Lock()
+	if w.paused {
+		w.mu.Unlock()
+		return
+	}
+	pending := w.pending
+	w.pending = make(map[string]fsnotify.Event)
+	w.mu.Unlock()
+
+	if len(pending) > 0 {
+		w.logger.WithContext("count", len(pending)).Debug("processing changes held while paused")
+	}
+	for _, event := range pending {
+		w.handleEvent(ctx, event)
+	}
+}
+
 // Start begins watching configured folders and starts event loop
 func (w *Watcher) Start(ctx context.Context) error {
+	if err := w.watchFolders(ctx); err != nil {
+		return err
+	}
+
+	// Start event loop in goroutine
+	go w.eventLoop(ctx)
+	return nil
+}
+
+// Run watches like Start but blocks until ctx is cancelled and the watcher has
+// stopped watching, after which it can be run again. Instances sharing a
+// database use it to hand the watcher over from one to the next
+func (w *Watcher) Run(ctx context.Context) error {
+	if err := w.watchFolders(ctx); err != nil {
+		return err
+	}
+	w.eventLoop(ctx)
+	return nil
+}
+
+// watchFolders adds every active folder in the database to the fsnotify watcher
+func (w *Watcher) watchFolders(ctx context.Context) error {
 	w.logger.Debug("starting file watcher")
 
 	// Load watched folders from database (all users)
@@ -91,7 +280,7 @@ func (w *Watcher) Start(ctx context.Context) error {
 			continue
 		}
 
-		if err := w.fsWatcher.Add(folder.Path); err != nil {
+		if err := w.watchFolder(folder.Path); err != nil {
 			w.logger.WithFields(map[string]interface{}{
 				"folder_path": folder.Path,
 				"error":       err.Error(),
@@ -108,28 +297,48 @@ func (w *Watcher) Start(ctx context.Context) error {
 		}).Debug("watching folder")
 	}
 
-	// Start event loop in goroutine
-	go w.eventLoop(ctx)
-
 	w.logger.WithContext("folder_count", len(folders)).Debug("file watcher started")
 	return nil
 }
 
+// watchFolder watches a folder for changes, by scanning it when its tuning
+// asks for polling and with fsnotify otherwise
+func (w *Watcher) watchFolder(path string) error {
+	if interval := w.tuningFor(path).PollInterval; interval > 0 {
+		w.startPolling(path, interval)
+		return nil
+	}
+	return w.fsWatcher.Add(path)
+}
+
 // eventLoop processes filesystem events
 func (w *Watcher) eventLoop(ctx context.Context) {
+	ticker := time.NewTicker(tickInterval)
+	defer ticker.Stop()
+
 	for {
 		select {
 		case <-ctx.Done():
-			w.fsWatcher.Close()
+			// Keep the fsnotify watcher open so the watcher can run again
+			w.unwatchFolders()
 			return
 
+		case now := <-ticker.C:
+			w.tick(ctx, now)
+
 		case event, ok := <-w.fsWatcher.Events:
 			if !ok {
 				return
 			}
 
+			if w.hold(event) {
+				continue
+			}
 			w.handleEvent(ctx, event)
 
+		case <-w.resumed:
+			w.processPending(ctx)
+
 		case err, ok := <-w.fsWatcher.Errors:
 			if !ok {
 				return
@@ -139,6 +348,19 @@ func (w *Watcher) eventLoop(ctx context.Context) {
 	}
 }
 
+// unwatchFolders stops watching every folder
+func (w *Watcher) unwatchFolders() {
+	for path := range w.folderUsers {
+		w.fsWatcher.Remove(path)
+		delete(w.folderUsers, path)
+	}
+	w.mu.Lock()
+	w.polls = nil
+	w.settling = nil
+	w.mu.Unlock()
+	w.logger.Debug("file watcher stopped")
+}
+
 // handleEvent processes create/modify/delete events
 func (w *Watcher) handleEvent(ctx context.Context, event fsnotify.Event) {
 	logger := w.logger.WithFields(map[string]interface{}{
@@ -152,7 +374,7 @@ func (w *Watcher) handleEvent(ctx context.Context, event fsnotify.Event) {
 	}
 
 	// Determine which folder this file belongs to
-	userID := w.getUserIDForFile(event.Name)
+	folder, userID := w.folderForFile(event.Name)
 	if userID == 0 {
 		logger.Warn("file does not belong to any watched folder")
 		return
@@ -161,14 +383,15 @@ func (w *Watcher) handleEvent(ctx context.Context, event fsnotify.Event) {
 	switch {
 	case event.Op&fsnotify.Create == fsnotify.Create:
 		logger.Debug("file created")
-		w.ingestFile(ctx, event.Name, userID)
+		w.ingestWhenStable(ctx, event.Name, folder, userID)
 
 	case event.Op&fsnotify.Write == fsnotify.Write:
 		logger.Debug("file modified")
-		w.ingestFile(ctx, event.Name, userID)
+		w.ingestWhenStable(ctx, event.Name, folder, userID)
 
 	case event.Op&fsnotify.Remove == fsnotify.Remove:
 		logger.Debug("file deleted")
+		w.forgetSettling(event.Name)
 		w.deleteFile(ctx, event.Name)
 	}
 }
@@ -242,7 +465,7 @@ func (w *Watcher) AddFolder(ctx context.Context, userID int64, path string) erro
 		return err
 	}
 
-	if err := w.fsWatcher.Add(path); err != nil {
+	if err := w.watchFolder(path); err != nil {
 		logger.WithContext("error", err.Error()).Error("failed to add folder to watcher")
 		return fmt.Errorf("failed to add folder to watcher: %w", err)
 	}
@@ -250,6 +473,9 @@ func (w *Watcher) AddFolder(ctx context.Context, userID int64, path string) erro
 	if err := w.store.AddWatchedFolder(ctx, userID, path); err != nil {
 		// Remove from fsnotify if database save fails
 		w.fsWatcher.Remove(path)
+		w.mu.Lock()
+		delete(w.polls, path)
+		w.mu.Unlock()
 		logger.WithContext("error", err.Error()).Error("failed to save watched folder")
 		return fmt.Errorf("failed to save watched folder: %w", err)
 	}
@@ -262,27 +488,117 @@ func (w *Watcher) AddFolder(ctx context.Context, userID int64, path string) erro
 }
 
 // ingestFile processes a file by reading it and calling ingester
-func (w *Watcher) ingestFile(ctx context.Context, path string, userID int64) {
+// Failures are recorded; once a file has failed quarantineAfter times in a row
+// it is skipped until it is retried (see Retry)
+func (w *Watcher) ingestFile(ctx context.Context, path, folder string, userID int64) {
 	logger := w.logger.WithContext("file_path", path)
 
-	// Read file content
-	content, err := os.ReadFile(path)
+	attempts, err := w.store.GetIngestFailureAttempts(ctx, path)
+	if err != nil {
+		logger.WithContext("error", err.Error()).Warn("failed to read ingest failures")
+	}
+	if attempts >= w.QuarantineLimit() {
+		logger.WithContext("attempts", attempts).Debug("skipping quarantined file")
+		return
+	}
+
+	w.mu.Lock()
+	w.ingesting++
+	w.mu.Unlock()
+	err = w.ingest(ctx, path, userID)
+	w.mu.Lock()
+	w.ingesting--
+	if err != nil {
+		w.failed++
+	} else {
+		w.ingested++
+		w.lastIngest = time.Now()
+	}
+	w.mu.Unlock()
+
 	if err != nil {
-		logger.WithContext("error", err.Error()).Error("failed to read file")
+		logger.WithContext("error", err.Error()).Error("failed to ingest file")
+		w.recordFailure(ctx, path, folder, userID, err)
 		return
 	}
 
-	// Use file path as source
+	if attempts > 0 {
+		if err := w.store.ClearIngestFailure(ctx, path); err != nil {
+			logger.WithContext("error", err.Error()).Warn("failed to clear ingest failures")
+		}
+	}
+	logger.Debug("file ingested successfully")
+}
+
+// ingest reads a file and passes its text to the ingester
+func (w *Watcher) ingest(ctx context.Context, path string, userID int64) error {
+	// Read file content, retrying failures a sync client or network mount
+	// may cause
+	folder, _ := w.folderForFile(path)
+	content, err := w.readFile(ctx, path, w.tuningFor(folder))
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+
+	// Use file path as source, decoding the text from the encoding it was saved in
 	tags := []string{"auto-ingested"}
+	text, encoding := ingest.DecodeText(content)
+
+	// Convert formats an external extractor is registered for
+	if extractor, ok := w.ingester.(FileExtractor); ok {
+		extracted, extractedTags, ok, err := extractor.ExtractFile(ctx, path, "", content)
+		if err != nil {
+			return fmt.Errorf("failed to extract file: %w", err)
+		}
+		if ok {
+			text, encoding = extracted, ingest.EncodingUTF8
+			tags = append(tags, extractedTags...)
+		}
+	}
 
 	// Ingest the text with the folder's user_id
-	if err := w.ingester.IngestText(ctx, userID, path, string(content), tags); err != nil {
-		logger.WithContext("error", err.Error()).Error("failed to ingest file")
-	} else {
-		logger.Debug("file ingested successfully")
+	return w.ingester.IngestText(ingest.WithEncoding(ctx, encoding), userID, path, text, tags)
+}
+
+// recordFailure counts a failed ingest and quarantines the file when it has
+// failed too often
+func (w *Watcher) recordFailure(ctx context.Context, path, folder string, userID int64, ingestErr error) {
+	logger := w.logger.WithContext("file_path", path)
+
+	attempts, err := w.store.RecordIngestFailure(ctx, userID, folder, path, ingestErr.Error())
+	if err != nil {
+		logger.WithContext("error", err.Error()).Error("failed to record ingest failure")
+		return
+	}
+	if attempts != w.QuarantineLimit() {
+		return
+	}
+
+	logger.WithContext("attempts", attempts).Warn("file quarantined after repeated ingest failures")
+	if w.onQuarantine != nil {
+		w.onQuarantine(userID, folder, path, ingestErr.Error())
 	}
 }
 
+// Retry ingests a file again, taking it out of quarantine
+// The file must be in one of userID's watched folders; a failure is recorded
+// as the first of a new run of attempts
+func (w *Watcher) Retry(ctx context.Context, userID int64, path string) error {
+	folder, owner := w.folderForFile(path)
+	if owner == 0 || owner != userID {
+		return fmt.Errorf("file is not in a watched folder: %s", path)
+	}
+
+	if err := w.store.ClearIngestFailure(ctx, path); err != nil {
+		return err
+	}
+	if err := w.ingest(ctx, path, userID); err != nil {
+		w.recordFailure(ctx, path, folder, userID, err)
+		return err
+	}
+	return nil
+}
+
 // deleteFile removes chunks for a deleted file
 func (w *Watcher) deleteFile(ctx context.Context, path string) {
 	logger := w.logger.WithContext("file_path", path)
@@ -291,16 +607,33 @@ func (w *Watcher) deleteFile(ctx context.Context, path string) {
 		logger.WithContext("error", err.Error()).Error("failed to delete chunks")
 	} else {
 		logger.Debug("chunks deleted successfully")
+		_, userID := w.folderForFile(path)
+		w.events.Publish(ctx, events.Event{
+			Topic:  events.DocumentDeleted,
+			UserID: userID,
+			Data:   map[string]interface{}{"source": path},
+		})
+	}
+
+	// A deleted file is no longer quarantined
+	if err := w.store.ClearIngestFailure(ctx, path); err != nil {
+		logger.WithContext("error", err.Error()).Warn("failed to clear ingest failures")
 	}
 }
 
 // getUserIDForFile determines which user owns the folder containing this file
 func (w *Watcher) getUserIDForFile(filePath string) int64 {
+	_, userID := w.folderForFile(filePath)
+	return userID
+}
+
+// folderForFile finds the watched folder containing this file and its owner
+func (w *Watcher) folderForFile(filePath string) (string, int64) {
 	// Check each watched folder to see if this file is within it
 	for folderPath, userID := range w.folderUsers {
 		if strings.HasPrefix(filePath, folderPath) {
-			return userID
+			return folderPath, userID
 		}
 	}
-	return 0 // No matching folder found
+	return "", 0 // No matching folder found
 }
@@ -2,17 +2,26 @@ package watcher
 
 import (
 	"context"
+	"errors"
 	"noodexx/internal/logging"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
+
+	"github.com/fsnotify/fsnotify"
 )
 
 // mockIngester for testing
 type mockIngester struct {
 	ingestedFiles map[int64][]string // userID -> list of file paths
+	err           error              // Returned instead of ingesting when set
 }
 
 func (m *mockIngester) IngestText(ctx context.Context, userID int64, source, text string, tags []string) error {
+	if m.err != nil {
+		return m.err
+	}
 	if m.ingestedFiles == nil {
 		m.ingestedFiles = make(map[int64][]string)
 	}
@@ -22,7 +31,8 @@ func (m *mockIngester) IngestText(ctx context.Context, userID int64, source, tex
 
 // mockStore for testing
 type mockStore struct {
-	folders []WatchedFolder
+	folders  []WatchedFolder
+	failures map[string]int // path -> failed attempts
 }
 
 func (m *mockStore) AddWatchedFolder(ctx context.Context, userID int64, path string) error {
@@ -43,6 +53,23 @@ func (m *mockStore) DeleteSource(ctx context.Context, source string) error {
 	return nil
 }
 
+func (m *mockStore) RecordIngestFailure(ctx context.Context, userID int64, folder, path, errMsg string) (int, error) {
+	if m.failures == nil {
+		m.failures = make(map[string]int)
+	}
+	m.failures[path]++
+	return m.failures[path], nil
+}
+
+func (m *mockStore) GetIngestFailureAttempts(ctx context.Context, path string) (int, error) {
+	return m.failures[path], nil
+}
+
+func (m *mockStore) ClearIngestFailure(ctx context.Context, path string) error {
+	delete(m.failures, path)
+	return nil
+}
+
 // mockLogger for testing
 type mockLogger struct {
 	logging.Logger
@@ -209,3 +236,329 @@ func TestWatcherLoadsAllUserFolders(t *testing.T) {
 		t.Errorf("Expected /tmp/user3 to belong to user 3")
 	}
 }
+
+func TestIngestFailureQuarantine(t *testing.T) {
+	ctx := context.Background()
+	store := &mockStore{}
+	ingester := &mockIngester{err: errors.New("extraction failed")}
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "broken.txt")
+	if err := os.WriteFile(path, []byte("text"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	var notified []string
+	w := &Watcher{
+		ingester:    ingester,
+		store:       store,
+		logger:      newMockLogger(),
+		folderUsers: map[string]int64{dir: 7},
+	}
+	w.SetQuarantine(2, func(userID int64, folder, file, errMsg string) {
+		if userID != 7 || folder != dir || errMsg != "extraction failed" {
+			t.Errorf("Unexpected notification: user %d, folder %s, error %q", userID, folder, errMsg)
+		}
+		notified = append(notified, file)
+	})
+
+	// Two failures quarantine the file; later events leave it alone
+	for i := 0; i < 4; i++ {
+		w.ingestFile(ctx, path, dir, 7)
+	}
+	if store.failures[path] != 2 {
+		t.Errorf("Expected 2 recorded attempts, got %d", store.failures[path])
+	}
+	if len(notified) != 1 || notified[0] != path {
+		t.Errorf("Expected one quarantine notification, got %v", notified)
+	}
+
+	// Only the folder's owner can retry it
+	if err := w.Retry(ctx, 8, path); err == nil {
+		t.Error("Expected a retry by another user to fail")
+	}
+
+	// A successful retry takes it out of quarantine
+	ingester.err = nil
+	if err := w.Retry(ctx, 7, path); err != nil {
+		t.Fatalf("Retry failed: %v", err)
+	}
+	if _, ok := store.failures[path]; ok {
+		t.Error("Expected the failure record to be cleared")
+	}
+	if len(ingester.ingestedFiles[7]) != 1 {
+		t.Errorf("Expected the file to be ingested, got %v", ingester.ingestedFiles)
+	}
+}
+
+func TestRunStopsAndRunsAgain(t *testing.T) {
+	dir := t.TempDir()
+	store := &mockStore{folders: []WatchedFolder{{ID: 1, UserID: 7, Path: dir, Active: true}}}
+	w, err := NewWatcher(&mockIngester{}, store, false, newMockLogger())
+	if err != nil {
+		t.Fatalf("NewWatcher failed: %v", err)
+	}
+
+	// Each run watches the folders until it is cancelled, as when another
+	// instance takes over the watcher and later hands it back
+	for i := 0; i < 2; i++ {
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error, 1)
+		go func() { done <- w.Run(ctx) }()
+
+		deadline := time.Now().Add(time.Second)
+		for len(w.fsWatcher.WatchList()) == 0 {
+			if time.Now().After(deadline) {
+				t.Fatalf("Run %d: expected the folder to be watched", i+1)
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+
+		cancel()
+		if err := <-done; err != nil {
+			t.Fatalf("Run %d failed: %v", i+1, err)
+		}
+		if len(w.folderUsers) != 0 || len(w.fsWatcher.WatchList()) != 0 {
+			t.Errorf("Run %d: expected no folders watched after stopping", i+1)
+		}
+	}
+}
+
+func TestPauseHoldsChangesUntilResume(t *testing.T) {
+	ctx := context.Background()
+	ingester := &mockIngester{}
+	w, err := NewWatcher(ingester, &mockStore{}, false, newMockLogger())
+	if err != nil {
+		t.Fatalf("NewWatcher failed: %v", err)
+	}
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "notes.txt")
+	if err := os.WriteFile(path, []byte("text"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	w.folderUsers[dir] = 7
+
+	// Changes while paused are held, the latest per file
+	w.Pause()
+	for _, op := range []fsnotify.Op{fsnotify.Create, fsnotify.Write} {
+		if !w.hold(fsnotify.Event{Name: path, Op: op}) {
+			t.Fatal("Expected the change to be held while paused")
+		}
+	}
+	if status := w.Status(); !status.Paused || status.Pending != 1 {
+		t.Errorf("Expected paused with 1 pending change, got %+v", status)
+	}
+	if len(ingester.ingestedFiles) != 0 {
+		t.Errorf("Expected nothing ingested while paused, got %v", ingester.ingestedFiles)
+	}
+
+	// Resuming processes them
+	w.Resume()
+	<-w.resumed
+	w.processPending(ctx)
+	status := w.Status()
+	if status.Paused || status.Pending != 0 || status.Ingested != 1 || status.LastIngest.IsZero() {
+		t.Errorf("Expected the held change ingested after resume, got %+v", status)
+	}
+	if len(ingester.ingestedFiles[7]) != 1 {
+		t.Errorf("Expected the file ingested once, got %v", ingester.ingestedFiles)
+	}
+	if w.hold(fsnotify.Event{Name: path, Op: fsnotify.Write}) {
+		t.Error("Expected changes not to be held after resume")
+	}
+}
+
+func TestChangedFileWaitsToSettle(t *testing.T) {
+	ctx := context.Background()
+	ingester := &mockIngester{}
+	w, err := NewWatcher(ingester, &mockStore{}, false, newMockLogger())
+	if err != nil {
+		t.Fatalf("NewWatcher failed: %v", err)
+	}
+	dir := t.TempDir()
+	w.folderUsers[dir] = 7
+	w.SetTuning(Tuning{StableFor: 2 * time.Second, MaxWait: time.Minute}, nil)
+
+	path := filepath.Join(dir, "synced.txt")
+	if err := os.WriteFile(path, []byte("half"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	w.handleEvent(ctx, fsnotify.Event{Name: path, Op: fsnotify.Create})
+	if status := w.Status(); status.Settling != 1 || len(ingester.ingestedFiles) != 0 {
+		t.Fatalf("Expected the file settling, got %+v", status)
+	}
+
+	// Still being written: the wait starts over
+	start := time.Now()
+	if err := os.WriteFile(path, []byte("half written"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	w.checkSettling(ctx, start.Add(time.Second))
+	w.checkSettling(ctx, start.Add(2500*time.Millisecond))
+	if len(ingester.ingestedFiles) != 0 {
+		t.Fatalf("Expected a changing file not to be ingested, got %v", ingester.ingestedFiles)
+	}
+
+	// Unchanged for StableFor: ingested once
+	w.checkSettling(ctx, start.Add(3500*time.Millisecond))
+	if len(ingester.ingestedFiles[7]) != 1 || w.Status().Settling != 0 {
+		t.Errorf("Expected the settled file ingested, got %v", ingester.ingestedFiles)
+	}
+
+	// A file deleted while settling is forgotten
+	w.handleEvent(ctx, fsnotify.Event{Name: path, Op: fsnotify.Write})
+	os.Remove(path)
+	w.handleEvent(ctx, fsnotify.Event{Name: path, Op: fsnotify.Remove})
+	if w.Status().Settling != 0 {
+		t.Error("Expected a deleted file to stop settling")
+	}
+}
+
+func TestFileThatNeverSettlesIsIngestedAfterMaxWait(t *testing.T) {
+	ctx := context.Background()
+	ingester := &mockIngester{}
+	w, err := NewWatcher(ingester, &mockStore{}, false, newMockLogger())
+	if err != nil {
+		t.Fatalf("NewWatcher failed: %v", err)
+	}
+	dir := t.TempDir()
+	w.folderUsers[dir] = 7
+	w.SetTuning(Tuning{StableFor: time.Hour, MaxWait: time.Minute}, nil)
+
+	path := filepath.Join(dir, "growing.txt")
+	if err := os.WriteFile(path, []byte("text"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	w.handleEvent(ctx, fsnotify.Event{Name: path, Op: fsnotify.Create})
+	w.checkSettling(ctx, time.Now().Add(30*time.Second))
+	if len(ingester.ingestedFiles) != 0 {
+		t.Fatal("Expected the file to keep waiting before MaxWait")
+	}
+	w.checkSettling(ctx, time.Now().Add(2*time.Minute))
+	if len(ingester.ingestedFiles[7]) != 1 {
+		t.Errorf("Expected the file ingested after MaxWait, got %v", ingester.ingestedFiles)
+	}
+}
+
+func TestReadFileRetries(t *testing.T) {
+	w := &Watcher{logger: newMockLogger()}
+	ctx := context.Background()
+	tuning := Tuning{ReadRetries: 2, RetryDelay: time.Millisecond}
+
+	// A directory can't be read; every retry fails
+	start := time.Now()
+	if _, err := w.readFile(ctx, t.TempDir(), tuning); err == nil {
+		t.Fatal("Expected reading a directory to fail")
+	}
+	if elapsed := time.Since(start); elapsed < 3*time.Millisecond {
+		t.Errorf("Expected 2 retries 1ms then 2ms apart, took %v", elapsed)
+	}
+
+	// A missing file is not retried
+	tuning.RetryDelay = time.Hour
+	if _, err := w.readFile(ctx, filepath.Join(t.TempDir(), "gone.txt"), tuning); !os.IsNotExist(err) {
+		t.Errorf("Expected a not-exist error without retrying, got %v", err)
+	}
+}
+
+func TestPolledFolder(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "existing.txt")
+	if err := os.WriteFile(existing, []byte("text"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	ingester := &mockIngester{}
+	store := &mockStore{folders: []WatchedFolder{{ID: 1, UserID: 7, Path: dir, Active: true}}}
+	w, err := NewWatcher(ingester, store, false, newMockLogger())
+	if err != nil {
+		t.Fatalf("NewWatcher failed: %v", err)
+	}
+	w.SetTuning(Tuning{}, map[string]Tuning{dir: {PollInterval: time.Minute}})
+	if err := w.watchFolders(ctx); err != nil {
+		t.Fatalf("watchFolders failed: %v", err)
+	}
+	if len(w.fsWatcher.WatchList()) != 0 || w.folderUsers[dir] != 7 {
+		t.Fatalf("Expected the folder polled instead of watched, watching %v", w.fsWatcher.WatchList())
+	}
+
+	// Files there when polling starts are not reported
+	added := filepath.Join(dir, "added.txt")
+	if err := os.WriteFile(added, []byte("new"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	w.tick(ctx, time.Now())
+	if len(ingester.ingestedFiles) != 0 {
+		t.Fatal("Expected no scan before the poll interval")
+	}
+	w.tick(ctx, time.Now().Add(2*time.Minute))
+	if files := ingester.ingestedFiles[7]; len(files) != 1 || files[0] != added {
+		t.Errorf("Expected only the added file ingested, got %v", ingester.ingestedFiles)
+	}
+
+	os.Remove(added)
+	events := w.pollFolders(time.Now().Add(4 * time.Minute))
+	if len(events) != 1 || events[0].Name != added || events[0].Op != fsnotify.Remove {
+		t.Errorf("Expected a remove event for the deleted file, got %v", events)
+	}
+
+	w.unwatchFolders()
+	if len(w.polls) != 0 {
+		t.Error("Expected polling to stop")
+	}
+}
+
+// extractingIngester converts .docx files, as an ingester with an external
+// extractor registered for them does
+type extractingIngester struct {
+	mockIngester
+	texts map[string]string // source -> text ingested
+}
+
+func (m *extractingIngester) ExtractFile(ctx context.Context, filename, mimeType string, data []byte) (string, []string, bool, error) {
+	if filepath.Ext(filename) != ".docx" {
+		return "", nil, false, nil
+	}
+	return "converted " + string(data), []string{"docx"}, true, nil
+}
+
+func (m *extractingIngester) IngestText(ctx context.Context, userID int64, source, text string, tags []string) error {
+	if m.texts == nil {
+		m.texts = make(map[string]string)
+	}
+	m.texts[source] = text
+	return m.mockIngester.IngestText(ctx, userID, source, text, tags)
+}
+
+func TestExtractedFormats(t *testing.T) {
+	ctx := context.Background()
+	ingester := &extractingIngester{}
+	w, err := NewWatcher(ingester, &mockStore{}, false, newMockLogger())
+	if err != nil {
+		t.Fatalf("NewWatcher failed: %v", err)
+	}
+	dir := t.TempDir()
+	w.folderUsers[dir] = 7
+
+	docx := filepath.Join(dir, "report.docx")
+	notes := filepath.Join(dir, "notes.txt")
+	for path, content := range map[string]string{docx: "report", notes: "notes"} {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+
+	// Extensions are only watched once allowed
+	w.handleEvent(ctx, fsnotify.Event{Name: docx, Op: fsnotify.Create})
+	if len(ingester.texts) != 0 {
+		t.Fatal("Expected .docx ignored before it is allowed")
+	}
+	w.AllowExtensions(".DOCX", ".txt")
+	w.handleEvent(ctx, fsnotify.Event{Name: docx, Op: fsnotify.Create})
+	w.handleEvent(ctx, fsnotify.Event{Name: notes, Op: fsnotify.Create})
+	if ingester.texts[docx] != "converted report" || ingester.texts[notes] != "notes" {
+		t.Errorf("Expected the .docx extracted and the text file read as is, got %v", ingester.texts)
+	}
+}
@@ -0,0 +1,116 @@
+package websearch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// braveEndpoint is the Brave Search web API
+const braveEndpoint = "https://api.search.brave.com/res/v1/web/search"
+
+// searxng queries a SearXNG instance through its JSON API
+// The instance must have the json format enabled in settings.yml
+type searxng struct {
+	endpoint string
+	client   *http.Client
+}
+
+func (e *searxng) Name() string { return "searxng" }
+
+func (e *searxng) Search(ctx context.Context, query string, limit int) ([]Result, error) {
+	params := url.Values{}
+	params.Set("q", query)
+	params.Set("format", "json")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, e.endpoint+"/search?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Accept", "application/json")
+
+	var body struct {
+		Results []struct {
+			Title   string `json:"title"`
+			URL     string `json:"url"`
+			Content string `json:"content"`
+		} `json:"results"`
+	}
+	if err := doJSON(e.client, req, &body); err != nil {
+		return nil, err
+	}
+
+	var results []Result
+	for _, r := range body.Results {
+		if len(results) == limit {
+			break
+		}
+		results = append(results, Result{Title: r.Title, URL: r.URL, Snippet: r.Content})
+	}
+	return results, nil
+}
+
+// brave queries the Brave Search API
+type brave struct {
+	endpoint string
+	apiKey   string
+	client   *http.Client
+}
+
+func (e *brave) Name() string { return "brave" }
+
+func (e *brave) Search(ctx context.Context, query string, limit int) ([]Result, error) {
+	params := url.Values{}
+	params.Set("q", query)
+	params.Set("count", strconv.Itoa(limit))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, e.endpoint+"?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("X-Subscription-Token", e.apiKey)
+
+	var body struct {
+		Web struct {
+			Results []struct {
+				Title       string `json:"title"`
+				URL         string `json:"url"`
+				Description string `json:"description"`
+			} `json:"results"`
+		} `json:"web"`
+	}
+	if err := doJSON(e.client, req, &body); err != nil {
+		return nil, err
+	}
+
+	var results []Result
+	for _, r := range body.Web.Results {
+		if len(results) == limit {
+			break
+		}
+		results = append(results, Result{Title: r.Title, URL: r.URL, Snippet: r.Description})
+	}
+	return results, nil
+}
+
+// doJSON sends a request and decodes a JSON response
+func doJSON(client *http.Client, req *http.Request, out interface{}) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("search engine returned status %d", resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode search response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,190 @@
+// Package websearch queries a web search engine and extracts the text of the top results
+// so they can be added to a prompt as web sources.
+package websearch
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"noodexx/internal/logging"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-shiori/go-readability"
+)
+
+// maxPageBytes caps how much of a result page is downloaded
+const maxPageBytes = 2 << 20
+
+// userAgent identifies noodexx to search engines and result pages
+const userAgent = "noodexx-websearch/1.0"
+
+// Result is a single search result
+// Text holds the extracted page text, or the engine's snippet if the page could not be fetched
+type Result struct {
+	Title   string
+	URL     string
+	Snippet string
+	Text    string
+}
+
+// Engine is a web search API
+type Engine interface {
+	// Search returns up to limit results for query
+	Search(ctx context.Context, query string, limit int) ([]Result, error)
+	// Name returns the engine name (e.g., "searxng", "brave")
+	Name() string
+}
+
+// Options configures a Client
+type Options struct {
+	Engine            string // "searxng" or "brave"
+	Endpoint          string
+	APIKey            string
+	MaxResults        int
+	MaxCharsPerResult int
+	Timeout           time.Duration
+}
+
+// Client searches the web and fetches the top results
+type Client struct {
+	engine     Engine
+	httpClient *http.Client
+	maxResults int
+	maxChars   int
+	logger     *logging.Logger
+}
+
+// New creates a client for the configured engine
+func New(opts Options, logger *logging.Logger) (*Client, error) {
+	if opts.Timeout <= 0 {
+		opts.Timeout = 10 * time.Second
+	}
+	if opts.MaxResults <= 0 {
+		opts.MaxResults = 3
+	}
+	httpClient := &http.Client{Timeout: opts.Timeout}
+
+	var engine Engine
+	switch opts.Engine {
+	case "searxng":
+		if opts.Endpoint == "" {
+			return nil, fmt.Errorf("searxng endpoint is required")
+		}
+		engine = &searxng{endpoint: strings.TrimRight(opts.Endpoint, "/"), client: httpClient}
+	case "brave":
+		if opts.APIKey == "" {
+			return nil, fmt.Errorf("brave API key is required")
+		}
+		endpoint := opts.Endpoint
+		if endpoint == "" {
+			endpoint = braveEndpoint
+		}
+		engine = &brave{endpoint: endpoint, apiKey: opts.APIKey, client: httpClient}
+	default:
+		return nil, fmt.Errorf("unknown web search engine: %s", opts.Engine)
+	}
+
+	return NewWithEngine(engine, httpClient, opts.MaxResults, opts.MaxCharsPerResult, logger), nil
+}
+
+// NewWithEngine creates a client around an existing engine
+func NewWithEngine(engine Engine, httpClient *http.Client, maxResults, maxChars int, logger *logging.Logger) *Client {
+	return &Client{
+		engine:     engine,
+		httpClient: httpClient,
+		maxResults: maxResults,
+		maxChars:   maxChars,
+		logger:     logger,
+	}
+}
+
+// Search runs the query and fetches the top results in parallel
+// Pages that cannot be fetched or parsed fall back to the engine's snippet
+func (c *Client) Search(ctx context.Context, query string) ([]Result, error) {
+	logger := c.logger.WithFields(map[string]interface{}{
+		"engine": c.engine.Name(),
+	})
+	logger.Debug("starting web search")
+
+	results, err := c.engine.Search(ctx, query, c.maxResults)
+	if err != nil {
+		logger.WithContext("error", err.Error()).Error("web search failed")
+		return nil, fmt.Errorf("web search failed: %w", err)
+	}
+	if len(results) > c.maxResults {
+		results = results[:c.maxResults]
+	}
+
+	var wg sync.WaitGroup
+	for i := range results {
+		wg.Add(1)
+		go func(r *Result) {
+			defer wg.Done()
+			text, err := c.fetch(ctx, r.URL)
+			if err != nil {
+				logger.WithFields(map[string]interface{}{
+					"url":   r.URL,
+					"error": err.Error(),
+				}).Debug("failed to fetch result, using snippet")
+				text = r.Snippet
+			}
+			r.Text = truncate(strings.TrimSpace(text), c.maxChars)
+		}(&results[i])
+	}
+	wg.Wait()
+
+	logger.WithContext("count", len(results)).Debug("web search completed")
+	return results, nil
+}
+
+// fetch downloads a result page and extracts its readable text
+func (c *Client) fetch(ctx context.Context, pageURL string) (string, error) {
+	parsedURL, err := url.Parse(pageURL)
+	if err != nil || (parsedURL.Scheme != "http" && parsedURL.Scheme != "https") {
+		return "", fmt.Errorf("unsupported URL: %s", pageURL)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, pageURL, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("User-Agent", userAgent)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "" && !strings.Contains(ct, "html") {
+		return "", fmt.Errorf("unsupported content type %s", ct)
+	}
+
+	article, err := readability.FromReader(io.LimitReader(resp.Body, maxPageBytes), parsedURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse HTML: %w", err)
+	}
+	if strings.TrimSpace(article.TextContent) == "" {
+		return "", fmt.Errorf("page has no readable text")
+	}
+	return article.TextContent, nil
+}
+
+// truncate shortens text to at most max characters (0 means no limit)
+func truncate(text string, max int) string {
+	if max <= 0 {
+		return text
+	}
+	runes := []rune(text)
+	if len(runes) <= max {
+		return text
+	}
+	return string(runes[:max]) + "…"
+}
@@ -0,0 +1,74 @@
+package websearch
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"noodexx/internal/logging"
+	"strings"
+	"testing"
+)
+
+// TestSearchFetchesResults tests that result pages are extracted, with the snippet as a fallback
+func TestSearchFetchesResults(t *testing.T) {
+	var server *httptest.Server
+	mux := http.NewServeMux()
+	mux.HandleFunc("/search", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("q") != "go readability" || r.URL.Query().Get("format") != "json" {
+			t.Errorf("Unexpected search query: %s", r.URL.RawQuery)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"results": []map[string]string{
+				{"title": "Article", "url": server.URL + "/article", "content": "article snippet"},
+				{"title": "Missing", "url": server.URL + "/missing", "content": "missing snippet"},
+				{"title": "Extra", "url": server.URL + "/extra", "content": "extra snippet"},
+			},
+		})
+	})
+	mux.HandleFunc("/article", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><head><title>Article</title></head><body><article><h1>Article</h1><p>` +
+			strings.Repeat("Readable article text about extraction. ", 20) + `</p></article></body></html>`))
+	})
+	server = httptest.NewServer(mux)
+	defer server.Close()
+
+	client, err := New(Options{Engine: "searxng", Endpoint: server.URL, MaxResults: 2, MaxCharsPerResult: 100},
+		logging.NewLogger("websearch", logging.ERROR, io.Discard))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	results, err := client.Search(context.Background(), "go readability")
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 results, got %d", len(results))
+	}
+	if !strings.Contains(results[0].Text, "Readable article text") {
+		t.Errorf("Expected extracted page text, got %q", results[0].Text)
+	}
+	if len([]rune(results[0].Text)) > 101 {
+		t.Errorf("Expected text truncated to 100 characters, got %d", len([]rune(results[0].Text)))
+	}
+	if results[1].Text != "missing snippet" {
+		t.Errorf("Expected snippet fallback, got %q", results[1].Text)
+	}
+}
+
+// TestNewValidatesEngine tests engine configuration errors
+func TestNewValidatesEngine(t *testing.T) {
+	logger := logging.NewLogger("websearch", logging.ERROR, io.Discard)
+	for _, opts := range []Options{
+		{Engine: "searxng"},
+		{Engine: "brave"},
+		{Engine: "unknown", Endpoint: "http://localhost"},
+	} {
+		if _, err := New(opts, logger); err == nil {
+			t.Errorf("Expected error for %+v", opts)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package wirelog
+
+import (
+	"context"
+	"io"
+	"noodexx/internal/auth"
+	"noodexx/internal/llm"
+	"strings"
+	"time"
+)
+
+// provider wraps an llm.Provider and records every request to a Log
+type provider struct {
+	next       llm.Provider
+	chatModel  string
+	embedModel string
+	log        *Log
+}
+
+// Wrap returns a provider that records Embed and Stream calls made through p
+// The user is taken from the request context when present
+func Wrap(p llm.Provider, chatModel, embedModel string, log *Log) llm.Provider {
+	if p == nil || log == nil {
+		return p
+	}
+	return &provider{next: p, chatModel: chatModel, embedModel: embedModel, log: log}
+}
+
+// Embed implements llm.Provider
+func (p *provider) Embed(ctx context.Context, text string) ([]float32, error) {
+	start := time.Now()
+	vec, err := p.next.Embed(ctx, text)
+
+	entry := p.newEntry(ctx, OperationEmbed, p.embedModel, text, start, err)
+	entry.Dimensions = len(vec)
+	if p.log.LogsContent(entry.UserID) {
+		entry.Prompt = text
+	}
+	p.log.Record(entry)
+
+	return vec, err
+}
+
+// Stream implements llm.Provider
+func (p *provider) Stream(ctx context.Context, messages []llm.Message, w io.Writer) (string, error) {
+	return p.StreamWithOptions(ctx, messages, llm.GenerationOptions{}, w)
+}
+
+// StreamWithOptions implements llm.Provider
+func (p *provider) StreamWithOptions(ctx context.Context, messages []llm.Message, opts llm.GenerationOptions, w io.Writer) (string, error) {
+	start := time.Now()
+	counter := &countingWriter{w: w}
+	response, err := p.next.StreamWithOptions(ctx, messages, opts, counter)
+
+	parts := make([]string, len(messages))
+	for i, m := range messages {
+		parts[i] = m.Role + ": " + m.Content
+	}
+	prompt := strings.Join(parts, "\n")
+
+	entry := p.newEntry(ctx, OperationChat, p.chatModel, prompt, start, err)
+	entry.Messages = len(messages)
+	entry.ResponseChars = counter.chars
+	entry.ResponseTokens = EstimateTokens(counter.chars)
+	if p.log.LogsContent(entry.UserID) {
+		entry.Prompt = prompt
+		entry.Response = response
+	}
+	p.log.Record(entry)
+
+	return response, err
+}
+
+// Name implements llm.Provider
+func (p *provider) Name() string {
+	return p.next.Name()
+}
+
+// IsLocal implements llm.Provider
+func (p *provider) IsLocal() bool {
+	return p.next.IsLocal()
+}
+
+// newEntry fills in the fields common to every operation
+func (p *provider) newEntry(ctx context.Context, operation, model, prompt string, start time.Time, err error) Entry {
+	userID, _ := auth.GetUserID(ctx)
+	promptChars := len([]rune(prompt))
+
+	entry := Entry{
+		Time:         start,
+		Provider:     p.next.Name(),
+		Model:        model,
+		Operation:    operation,
+		UserID:       userID,
+		PromptHash:   HashPrompt(prompt),
+		PromptChars:  promptChars,
+		PromptTokens: EstimateTokens(promptChars),
+		LatencyMS:    time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	return entry
+}
+
+// countingWriter counts the characters streamed to the client
+type countingWriter struct {
+	w     io.Writer
+	chars int
+}
+
+func (c *countingWriter) Write(b []byte) (int, error) {
+	n, err := c.w.Write(b)
+	c.chars += len([]rune(string(b[:n])))
+	return n, err
+}
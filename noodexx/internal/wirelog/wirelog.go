@@ -0,0 +1,234 @@
+// Package wirelog records metadata about requests made to LLM providers.
+//
+// The wire log is opt-in and written to its own rotating file, separate from the
+// application log. By default it never contains prompt or response text: prompts are
+// identified by a truncated SHA-256 hash and sizes are recorded as character counts
+// and estimated token counts. Raw content is only logged for users explicitly listed
+// in Options.ContentUserIDs.
+package wirelog
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"noodexx/internal/logging"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Operation names recorded in entries
+const (
+	OperationEmbed = "embed"
+	OperationChat  = "chat"
+)
+
+// promptHashLength is the number of hex characters of the prompt hash that are kept
+const promptHashLength = 16
+
+// Entry is a single provider request
+type Entry struct {
+	Time           time.Time `json:"time"`
+	Provider       string    `json:"provider"`
+	Model          string    `json:"model"`
+	Operation      string    `json:"operation"`
+	UserID         int64     `json:"user_id,omitempty"`
+	Messages       int       `json:"messages,omitempty"`
+	PromptHash     string    `json:"prompt_hash"`
+	PromptChars    int       `json:"prompt_chars"`
+	PromptTokens   int       `json:"prompt_tokens"`   // Estimated from character count
+	ResponseChars  int       `json:"response_chars"`  // Characters streamed back (0 for embeddings)
+	ResponseTokens int       `json:"response_tokens"` // Estimated from character count
+	Dimensions     int       `json:"dimensions,omitempty"`
+	LatencyMS      int64     `json:"latency_ms"`
+	Error          string    `json:"error,omitempty"`
+	Prompt         string    `json:"prompt,omitempty"`   // Only for users with content logging enabled
+	Response       string    `json:"response,omitempty"` // Only for users with content logging enabled
+}
+
+// Options configures a wire log
+type Options struct {
+	Path           string  // Log file path
+	MaxSizeMB      int     // Size at which the file is rotated
+	MaxBackups     int     // Rotated files to keep
+	ContentUserIDs []int64 // Users whose prompts and responses are logged verbatim
+}
+
+// Filter selects entries returned by Query
+// Zero values match everything
+type Filter struct {
+	Provider   string
+	Model      string
+	Operation  string
+	UserID     int64
+	ErrorsOnly bool
+	Since      time.Time
+	Limit      int
+}
+
+// Log writes entries as JSON lines to a rotating file
+type Log struct {
+	mu           sync.Mutex
+	path         string
+	maxBackups   int
+	writer       *logging.FileWriter
+	contentUsers map[int64]bool
+}
+
+// New opens the wire log file
+func New(opts Options) (*Log, error) {
+	if opts.Path == "" {
+		return nil, fmt.Errorf("wire log path is required")
+	}
+
+	writer, err := logging.NewFileWriter(opts.Path, opts.MaxSizeMB, opts.MaxBackups)
+	if err != nil {
+		return nil, err
+	}
+
+	contentUsers := make(map[int64]bool)
+	for _, id := range opts.ContentUserIDs {
+		contentUsers[id] = true
+	}
+
+	return &Log{
+		path:         opts.Path,
+		maxBackups:   opts.MaxBackups,
+		writer:       writer,
+		contentUsers: contentUsers,
+	}, nil
+}
+
+// LogsContent reports whether raw prompts and responses are logged for a user
+func (l *Log) LogsContent(userID int64) bool {
+	return userID != 0 && l.contentUsers[userID]
+}
+
+// Record appends an entry to the log
+func (l *Log) Record(entry Entry) error {
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode wire log entry: %w", err)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, err := l.writer.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write wire log entry: %w", err)
+	}
+	return nil
+}
+
+// Query returns matching entries from the current file and its rotated backups, newest first
+func (l *Log) Query(filter Filter) ([]Entry, error) {
+	l.mu.Lock()
+	err := l.writer.Flush()
+	l.mu.Unlock()
+	if err != nil {
+		return nil, fmt.Errorf("failed to flush wire log: %w", err)
+	}
+
+	// Oldest backup first so entries are read in chronological order
+	paths := make([]string, 0, l.maxBackups+1)
+	for i := l.maxBackups; i >= 1; i-- {
+		paths = append(paths, fmt.Sprintf("%s.%d", l.path, i))
+	}
+	paths = append(paths, l.path)
+
+	var entries []Entry
+	for _, path := range paths {
+		fileEntries, err := readEntries(path, filter)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, fileEntries...)
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Time.After(entries[j].Time)
+	})
+	if filter.Limit > 0 && len(entries) > filter.Limit {
+		entries = entries[:filter.Limit]
+	}
+	return entries, nil
+}
+
+// Close flushes and closes the log file
+func (l *Log) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.writer.Close()
+}
+
+// readEntries reads the entries of one log file that match filter
+// Missing files and malformed lines are skipped
+func readEntries(path string, filter Filter) ([]Entry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to open wire log: %w", err)
+	}
+	defer f.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue
+		}
+		if filter.matches(entry) {
+			entries = append(entries, entry)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read wire log: %w", err)
+	}
+	return entries, nil
+}
+
+// matches reports whether an entry passes the filter
+func (f Filter) matches(e Entry) bool {
+	if f.Provider != "" && !strings.EqualFold(f.Provider, e.Provider) {
+		return false
+	}
+	if f.Model != "" && f.Model != e.Model {
+		return false
+	}
+	if f.Operation != "" && f.Operation != e.Operation {
+		return false
+	}
+	if f.UserID != 0 && f.UserID != e.UserID {
+		return false
+	}
+	if f.ErrorsOnly && e.Error == "" {
+		return false
+	}
+	if !f.Since.IsZero() && e.Time.Before(f.Since) {
+		return false
+	}
+	return true
+}
+
+// HashPrompt returns a truncated SHA-256 hash identifying prompt text without revealing it
+func HashPrompt(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:])[:promptHashLength]
+}
+
+// EstimateTokens approximates the token count of text at about four characters per token
+func EstimateTokens(chars int) int {
+	return (chars + 3) / 4
+}
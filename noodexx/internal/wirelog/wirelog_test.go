@@ -0,0 +1,134 @@
+package wirelog
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"noodexx/internal/auth"
+	"noodexx/internal/llm"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeProvider streams a fixed answer and fails embedding on demand
+type fakeProvider struct {
+	embedErr error
+}
+
+func (f *fakeProvider) Embed(ctx context.Context, text string) ([]float32, error) {
+	if f.embedErr != nil {
+		return nil, f.embedErr
+	}
+	return []float32{1, 2, 3}, nil
+}
+
+func (f *fakeProvider) Stream(ctx context.Context, messages []llm.Message, w io.Writer) (string, error) {
+	answer := "The secret answer"
+	w.Write([]byte(answer))
+	return answer, nil
+}
+
+func (f *fakeProvider) StreamWithOptions(ctx context.Context, messages []llm.Message, opts llm.GenerationOptions, w io.Writer) (string, error) {
+	return f.Stream(ctx, messages, w)
+}
+
+func (f *fakeProvider) Name() string  { return "fake" }
+func (f *fakeProvider) IsLocal() bool { return true }
+
+// TestWrapRedactsContent tests that content is only logged for opted-in users
+func TestWrapRedactsContent(t *testing.T) {
+	path := "test_wire.log"
+	defer os.Remove(path)
+
+	log, err := New(Options{Path: path, MaxSizeMB: 1, MaxBackups: 1, ContentUserIDs: []int64{2}})
+	if err != nil {
+		t.Fatalf("Failed to create wire log: %v", err)
+	}
+	defer log.Close()
+
+	fake := &fakeProvider{}
+	p := Wrap(fake, "chat-model", "embed-model", log)
+	messages := []llm.Message{{Role: "user", Content: "my private question"}}
+
+	// User 1 is not opted in: only metadata is recorded
+	ctx := context.WithValue(context.Background(), auth.UserIDKey, int64(1))
+	var out bytes.Buffer
+	if _, err := p.Stream(ctx, messages, &out); err != nil {
+		t.Fatalf("Stream failed: %v", err)
+	}
+	if out.String() != "The secret answer" {
+		t.Errorf("Expected the answer to reach the client, got %q", out.String())
+	}
+
+	// User 2 is opted in to content logging
+	ctx = context.WithValue(context.Background(), auth.UserIDKey, int64(2))
+	if _, err := p.Stream(ctx, messages, io.Discard); err != nil {
+		t.Fatalf("Stream failed: %v", err)
+	}
+
+	fake.embedErr = errors.New("model not found")
+	if _, err := p.Embed(ctx, "embed me"); err == nil {
+		t.Fatal("Expected embed error to be returned")
+	}
+
+	data, err := os.ReadFile(path)
+	if err == nil && strings.Count(string(data), "my private question") > 1 {
+		t.Errorf("Expected prompt text to be logged only for the opted-in user")
+	}
+
+	entries, err := log.Query(Filter{Operation: OperationChat, UserID: 1})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("Expected 1 chat entry for user 1, got %d", len(entries))
+	}
+	e := entries[0]
+	if e.Prompt != "" || e.Response != "" {
+		t.Errorf("Expected content to be redacted, got %+v", e)
+	}
+	if e.Provider != "fake" || e.Model != "chat-model" || e.Messages != 1 {
+		t.Errorf("Unexpected metadata: %+v", e)
+	}
+	if e.PromptHash != HashPrompt("user: my private question") || len(e.PromptHash) != promptHashLength {
+		t.Errorf("Unexpected prompt hash: %s", e.PromptHash)
+	}
+	if e.ResponseChars != len("The secret answer") || e.ResponseTokens == 0 {
+		t.Errorf("Expected response size to be recorded, got %+v", e)
+	}
+
+	entries, err = log.Query(Filter{UserID: 2, Operation: OperationChat})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Response != "The secret answer" {
+		t.Errorf("Expected content for opted-in user, got %+v", entries)
+	}
+
+	entries, err = log.Query(Filter{ErrorsOnly: true})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Operation != OperationEmbed || entries[0].Model != "embed-model" || entries[0].Error != "model not found" {
+		t.Errorf("Expected the failed embedding, got %+v", entries)
+	}
+
+	// Newest first with limit; since excludes older entries
+	entries, err = log.Query(Filter{Limit: 1})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Operation != OperationEmbed {
+		t.Errorf("Expected the newest entry, got %+v", entries)
+	}
+	entries, err = log.Query(Filter{Since: time.Now().Add(time.Hour)})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("Expected no entries after since, got %d", len(entries))
+	}
+}
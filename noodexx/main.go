@@ -2,30 +2,53 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"io"
 	"log"
+	"net"
 	"net/http"
 	"os"
-	"os/signal"
-	"syscall"
+	"path/filepath"
+	"runtime"
 	"time"
 
 	"noodexx/internal/api"
 	"noodexx/internal/auth"
+	"noodexx/internal/cluster"
 	"noodexx/internal/config"
+	"noodexx/internal/embedpool"
+	"noodexx/internal/events"
+	"noodexx/internal/extractors"
+	"noodexx/internal/fairqueue"
+	"noodexx/internal/flags"
 	"noodexx/internal/ingest"
+	"noodexx/internal/llm"
 	"noodexx/internal/logging"
 	providerpkg "noodexx/internal/provider"
+	"noodexx/internal/pwpolicy"
 	"noodexx/internal/rag"
+	"noodexx/internal/readiness"
+	"noodexx/internal/replay"
+	"noodexx/internal/scheduler"
+	"noodexx/internal/service"
 	"noodexx/internal/skills"
 	"noodexx/internal/store"
+	"noodexx/internal/telemetry"
+	"noodexx/internal/transcripts"
 	"noodexx/internal/uistyle"
+	"noodexx/internal/warmup"
 	"noodexx/internal/watcher"
+	"noodexx/internal/websearch"
+	"noodexx/internal/wirelog"
 )
 
 const version = "1.0.0"
 
+// summaryRefreshBatch is how many stale summaries the refresh job regenerates per run
+const summaryRefreshBatch = 20
+
 // maskAPIKey masks an API key for display, showing only first 8 and last 4 characters
 func maskAPIKey(key string) string {
 	if key == "" {
@@ -37,8 +60,9 @@ func maskAPIKey(key string) string {
 	return key[:8] + "..." + key[len(key)-4:]
 }
 
-// initializeLogging creates and configures the logger based on configuration
-func initializeLogging(cfg *config.Config) (*logging.Logger, io.Writer, error) {
+// initializeLogging creates and configures the logger based on configuration;
+// console receives the output that normally goes to stdout
+func initializeLogging(cfg *config.Config, console io.Writer) (*logging.Logger, io.Writer, error) {
 	var writer io.Writer
 
 	if cfg.Logging.DebugEnabled {
@@ -52,14 +76,14 @@ func initializeLogging(cfg *config.Config) (*logging.Logger, io.Writer, error) {
 			// Log error to stderr and fall back to console-only
 			fmt.Fprintf(os.Stderr, "[ERROR] Failed to create debug log file: %v\n", err)
 			fmt.Fprintf(os.Stderr, "[INFO] Falling back to console-only logging\n")
-			writer = os.Stdout
+			writer = console
 		} else {
 			// Create multi-writer for dual output (console + file)
-			writer = logging.NewMultiWriter(os.Stdout, fileWriter, true)
+			writer = logging.NewMultiWriter(console, fileWriter, true)
 		}
 	} else {
 		// Debug disabled: console-only
-		writer = os.Stdout
+		writer = console
 	}
 
 	// Parse log level and create logger
@@ -69,13 +93,15 @@ func initializeLogging(cfg *config.Config) (*logging.Logger, io.Writer, error) {
 
 // initAuthProvider initializes the authentication provider based on configuration
 func initAuthProvider(authStore auth.Store, cfg *config.Config, logger *logging.Logger) auth.Provider {
-	authProvider, err := auth.GetProvider(
-		cfg.Auth.Provider,
-		authStore,
-		cfg.Auth.SessionExpiryDays,
-		cfg.Auth.LockoutThreshold,
-		cfg.Auth.LockoutDurationMinutes,
-	)
+	authProvider, err := auth.NewProvider(cfg.Auth.Provider, auth.ProviderOptions{
+		Store:                  authStore,
+		SessionExpiryDays:      cfg.Auth.SessionExpiryDays,
+		RememberMeDays:         cfg.Auth.RememberMeDays,
+		MaxSessionDays:         cfg.Auth.MaxSessionDays,
+		LockoutThreshold:       cfg.Auth.LockoutThreshold,
+		LockoutDurationMinutes: cfg.Auth.LockoutDurationMinutes,
+		Settings:               cfg.Auth.Settings,
+	})
 	if err != nil {
 		logger.Error("Failed to initialize auth provider: %v", err)
 		log.Fatalf("Failed to initialize auth provider: %v", err)
@@ -84,12 +110,98 @@ func initAuthProvider(authStore auth.Store, cfg *config.Config, logger *logging.
 	return authProvider
 }
 
+// watcherTuning converts the watcher settings into the watcher's defaults and
+// per-folder overrides
+func watcherTuning(cfg config.WatcherConfig) (watcher.Tuning, map[string]watcher.Tuning) {
+	seconds := func(n int) time.Duration { return time.Duration(n) * time.Second }
+	defaults := watcher.Tuning{
+		StableFor:   seconds(cfg.StableSeconds),
+		MaxWait:     seconds(cfg.MaxWaitSeconds),
+		ReadRetries: cfg.ReadRetries,
+		RetryDelay:  time.Second,
+	}
+	folders := make(map[string]watcher.Tuning, len(cfg.Folders))
+	for _, folder := range cfg.Folders {
+		folders[folder.Path] = watcher.Tuning{
+			StableFor:    seconds(folder.StableSeconds),
+			MaxWait:      seconds(folder.MaxWaitSeconds),
+			ReadRetries:  folder.ReadRetries,
+			PollInterval: seconds(folder.PollSeconds),
+		}
+	}
+	return defaults, folders
+}
+
+// rebuildANNIndex rebuilds the ANN lists of the vector index, logs their shape and writes
+// the vector index snapshot
+func rebuildANNIndex(ctx context.Context, st *store.Store, logger *logging.Logger) error {
+	report, err := st.RebuildANNIndex(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to rebuild ANN index: %w", err)
+	}
+	if report.Dimension == 0 {
+		logger.Info("ANN index not built, %d chunks is below database.ann_min_chunks", report.Chunks)
+	} else {
+		logger.Info("ANN index ready (%d chunks of %d dimensions in %d lists)", report.Size, report.Dimension, report.Lists)
+	}
+	return st.SnapshotVectorIndex()
+}
+
 func main() {
+	serviceCommand := flag.String("service", "", "Service command: install, uninstall, unit (print the systemd unit) or run")
+	workDir := flag.String("workdir", "", "Directory holding config.json and the database (default: current directory)")
+	trayMode := flag.Bool("tray", false, "Show a system tray icon with quick actions (needs a build with -tags tray)")
+	flag.Parse()
+	if *trayMode && *serviceCommand != "" {
+		log.Fatalf("--tray and --service cannot be combined")
+	}
+
+	// Service managers start Noodexx in their own directory, so installed
+	// services pass the directory they were installed from
+	if *workDir != "" {
+		if err := os.Chdir(*workDir); err != nil {
+			log.Fatalf("Failed to change to working directory: %v", err)
+		}
+	}
+
+	// Config subcommands check or describe the config file without starting Noodexx
+	if flag.Arg(0) == "config" {
+		os.Exit(runConfigCommand(flag.Args()[1:], os.Stdout, os.Stderr))
+	}
+
 	// Load configuration
 	cfg, err := config.Load("config.json")
 	if err != nil {
 		log.Fatalf("Failed to load config: %v", err)
 	}
+
+	// Console output goes to the service log file when run as a service
+	console := io.Writer(os.Stdout)
+	switch *serviceCommand {
+	case "":
+	case "install", "uninstall", "unit":
+		if err := controlService(*serviceCommand, cfg); err != nil {
+			log.Fatalf("Failed to %s service: %v", *serviceCommand, err)
+		}
+		return
+	case "run":
+		logFile := cfg.Service.LogFile
+		if logFile == "" && runtime.GOOS == "windows" {
+			// Windows services have no console
+			logFile = "noodexx-service.log"
+		}
+		if logFile != "" {
+			fileWriter, err := logging.NewFileWriter(logFile, cfg.Logging.MaxSizeMB, cfg.Logging.MaxBackups)
+			if err != nil {
+				log.Fatalf("Failed to open service log file: %v", err)
+			}
+			defer fileWriter.Close()
+			console = fileWriter
+			log.SetOutput(fileWriter)
+		}
+	default:
+		log.Fatalf("Unknown service command %q (use install, uninstall, unit or run)", *serviceCommand)
+	}
 	log.Printf("=== Configuration Loaded ===")
 	log.Printf("User Mode: %s", cfg.UserMode)
 	log.Printf("Auth Provider: %s", cfg.Auth.Provider)
@@ -109,6 +221,11 @@ func main() {
 			log.Printf("  Endpoint: %s", cfg.LocalProvider.OllamaEndpoint)
 			log.Printf("  Chat Model: %s", cfg.LocalProvider.OllamaChatModel)
 			log.Printf("  Embed Model: %s", cfg.LocalProvider.OllamaEmbedModel)
+		} else if cfg.LocalProvider.Type == "builtin" {
+			log.Printf("  Embed Model: %s", cfg.LocalProvider.BuiltinModelPath)
+			if cfg.LocalProvider.BuiltinRerankerPath != "" {
+				log.Printf("  Reranker Model: %s", cfg.LocalProvider.BuiltinRerankerPath)
+			}
 		}
 	} else {
 		log.Printf("Local Provider: Not configured")
@@ -122,7 +239,9 @@ func main() {
 			log.Printf("  API Key: %s", maskAPIKey(cfg.CloudProvider.OpenAIKey))
 		} else if cfg.CloudProvider.Type == "anthropic" {
 			log.Printf("  Chat Model: %s", cfg.CloudProvider.AnthropicChatModel)
-			log.Printf("  Embed Model: %s", cfg.CloudProvider.AnthropicEmbedModel)
+			if source, err := cfg.CloudProvider.EmbeddingSource(cfg.LocalProvider.Type != ""); err == nil {
+				log.Printf("  Embeddings: %s fallback", source)
+			}
 			log.Printf("  API Key: %s", maskAPIKey(cfg.CloudProvider.AnthropicKey))
 		}
 	} else {
@@ -138,21 +257,142 @@ func main() {
 	log.Printf("Cloud RAG Policy: %s", cfg.Privacy.CloudRAGPolicy)
 	log.Printf("=============================")
 
+	// In tray mode the menu's Quit stops the server as well
+	if *trayMode {
+		if err := runTray(cfg, console); err != nil {
+			log.Fatalf("Noodexx failed: %v", err)
+		}
+		return
+	}
+
+	// The service manager, Ctrl-C or SIGTERM stops the server gracefully
+	if err := service.Run(cfg.Service.Name, func(ctx context.Context, ready func()) error {
+		return run(ctx, cfg, console, ready)
+	}); err != nil {
+		log.Fatalf("Noodexx failed: %v", err)
+	}
+}
+
+// controlService installs or uninstalls the service, or prints its systemd unit
+func controlService(command string, cfg *config.Config) error {
+	if command == "uninstall" {
+		if err := service.Uninstall(cfg.Service.Name); err != nil {
+			return err
+		}
+		log.Printf("Service %s uninstalled", cfg.Service.Name)
+		return nil
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to locate the noodexx binary: %w", err)
+	}
+	if resolved, err := filepath.EvalSymlinks(executable); err == nil {
+		executable = resolved
+	}
+	workDir, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get working directory: %w", err)
+	}
+	opts := service.Options{
+		Name:        cfg.Service.Name,
+		DisplayName: cfg.Service.DisplayName,
+		Description: cfg.Service.Description,
+		Executable:  executable,
+		Args:        []string{"--service", "run", "--workdir", workDir},
+		WorkDir:     workDir,
+		User:        cfg.Service.User,
+	}
+
+	if command == "unit" {
+		fmt.Print(service.SystemdUnit(opts))
+		return nil
+	}
+	if err := service.Install(opts); err != nil {
+		return err
+	}
+	log.Printf("Service %s installed; it starts at boot, or start it now with the system service manager", cfg.Service.Name)
+	return nil
+}
+
+// run starts Noodexx and serves until ctx is cancelled, then shuts down
+// gracefully; ready is called once the server listens
+func run(ctx context.Context, cfg *config.Config, console io.Writer, ready func()) error {
 	// Initialize logger
-	logger, logWriter, err := initializeLogging(cfg)
+	logger, logWriter, err := initializeLogging(cfg, console)
 	if err != nil {
 		log.Fatalf("Failed to initialize logging: %v", err)
 	}
 	logger.Info("Starting Noodexx v%s...", version)
 
 	// Initialize store with migrations
-	st, err := store.NewStore("noodexx.db", cfg.UserMode)
+	st, err := store.NewStoreWithOptions("noodexx.db", cfg.UserMode, store.Options{
+		Synchronous:    cfg.Database.Synchronous,
+		CacheSizeKB:    cfg.Database.CacheSizeKB,
+		SearchPageSize: cfg.Database.SearchPageSize,
+		WritePoolSize:  cfg.Database.WritePoolSize,
+		ReadPoolSize:   cfg.Database.ReadPoolSize,
+	})
 	if err != nil {
 		logger.Error("Failed to initialize store: %v", err)
 		os.Exit(1)
 	}
 	defer st.Close()
 	logger.Info("Database initialized")
+	if cfg.Database.ReadPoolSize > 0 {
+		logger.Info("Search and library queries use %d read-only connections", cfg.Database.ReadPoolSize)
+	}
+
+	// Warm start the vector index from its snapshot, then reconcile it with the
+	// chunks table in the background; searches load missing embeddings on demand.
+	// Large libraries are searched through the index's ANN lists instead of a full
+	// table scan. Without lists in the snapshot they are built after the repair;
+	// searches scan the table until then
+	if cfg.Database.VectorIndexPath != "" {
+		report, err := st.OpenVectorIndex(cfg.Database.VectorIndexPath, store.ANNOptions{
+			MinChunks: cfg.Database.ANNMinChunks,
+			Probes:    cfg.Database.ANNProbes,
+		})
+		if err != nil {
+			logger.Error("Failed to open vector index: %v", err)
+			os.Exit(1)
+		}
+		if report.Snapshot != nil {
+			logger.Info("Vector index snapshot not used (%v), building from database", report.Snapshot)
+		} else {
+			logger.Info("Vector index loaded from snapshot (%d embeddings, %d ANN lists)", report.Loaded, report.Lists)
+		}
+
+		go func() {
+			repair, err := st.RepairVectorIndex(context.Background())
+			if err != nil {
+				logger.Error("Failed to repair vector index: %v", err)
+				return
+			}
+			logger.Info("Vector index ready (%d embeddings, %d added, %d removed)", repair.Size, repair.Added, repair.Removed)
+			if repair.Lists == 0 {
+				if err := rebuildANNIndex(context.Background(), st, logger); err != nil {
+					logger.Error("%v", err)
+				}
+			}
+		}()
+	}
+
+	// Instances sharing the database coordinate through it: background jobs and
+	// the folder watcher run on one instance at a time, and WebSocket events
+	// reach every instance's clients
+	var clusterNode *cluster.Node
+	clusterCtx, stopCluster := context.WithCancel(context.Background())
+	defer stopCluster()
+	if cfg.Cluster.Enabled {
+		clusterLogger := logging.NewLogger("cluster", logging.ParseLevel(cfg.Logging.Level), logWriter)
+		clusterNode = cluster.New(&clusterStoreAdapter{store: st}, cluster.Options{
+			NodeID:       cfg.Cluster.NodeID,
+			LockTTL:      time.Duration(cfg.Cluster.LockTTLSeconds) * time.Second,
+			PollInterval: time.Duration(cfg.Cluster.PollIntervalMS) * time.Millisecond,
+		}, clusterLogger)
+		logger.Info("Cluster mode enabled (node %s)", clusterNode.ID())
+	}
 
 	// Initialize dual provider manager and RAG policy enforcer
 	dualProviderManager, err := providerpkg.NewDualProviderManager(cfg, logger)
@@ -163,6 +403,56 @@ func main() {
 	ragEnforcer := rag.NewRAGPolicyEnforcer(cfg, logger)
 	logger.Info("Dual provider manager initialized")
 
+	// Keep provider requests that fail, redacted, for replay when enabled. This
+	// wraps the providers first so transcripts and the wire log see the same requests
+	var replayLog *replay.Log
+	if cfg.Replay.Enabled {
+		replayLogger := logging.NewLogger("replay", logging.ParseLevel(cfg.Logging.Level), logWriter)
+		replayLog, err = replay.New(&replayStoreAdapter{store: st}, cfg.Replay.RedactPatterns, replayLogger)
+		if err != nil {
+			logger.Error("Failed to set up failed request replay: %v", err)
+			os.Exit(1)
+		}
+		dualProviderManager.SetReplay(replayLog)
+		logger.Info("Failed provider requests are kept for replay (retention %d days)", cfg.Replay.RetentionDays)
+	}
+
+	// Archive what is sent to cloud providers, encrypted, when enabled. This
+	// wraps the providers first so the wire log sees the same requests
+	var transcriptArchive *transcripts.Archive
+	if cfg.Transcripts.Enabled {
+		key, err := transcripts.LoadKey(cfg.Transcripts.KeyFile)
+		if err != nil {
+			logger.Error("Failed to load transcript key: %v", err)
+			os.Exit(1)
+		}
+		transcriptLogger := logging.NewLogger("transcripts", logging.ParseLevel(cfg.Logging.Level), logWriter)
+		transcriptArchive = transcripts.New(key, &transcriptStoreAdapter{store: st}, cfg.Transcripts.IncludeEmbeddings, transcriptLogger)
+		dualProviderManager.SetTranscripts(transcriptArchive)
+		logger.Warn("Cloud provider transcripts are archived (key %s, retention %d days); keep %s safe", key.ID(), cfg.Transcripts.RetentionDays, cfg.Transcripts.KeyFile)
+	}
+
+	// Record provider requests to the wire log when enabled
+	var wireLog *wirelog.Log
+	if cfg.WireLog.Enabled {
+		wireLog, err = wirelog.New(wirelog.Options{
+			Path:           cfg.WireLog.File,
+			MaxSizeMB:      cfg.WireLog.MaxSizeMB,
+			MaxBackups:     cfg.WireLog.MaxBackups,
+			ContentUserIDs: cfg.WireLog.LogContentUserIDs,
+		})
+		if err != nil {
+			logger.Error("Failed to open provider wire log: %v", err)
+			os.Exit(1)
+		}
+		defer wireLog.Close()
+		dualProviderManager.SetWireLog(wireLog)
+		logger.Info("Provider wire log enabled: %s", cfg.WireLog.File)
+		if len(cfg.WireLog.LogContentUserIDs) > 0 {
+			logger.Warn("Provider wire log records raw prompts and responses for %d users", len(cfg.WireLog.LogContentUserIDs))
+		}
+	}
+
 	// Display provider initialization status
 	if dualProviderManager.GetCloudProvider() == nil && cfg.CloudProvider.Type != "" {
 		log.Printf("⚠️  Cloud provider configured but not available (check API key configuration)")
@@ -197,17 +487,101 @@ func main() {
 		}
 	}
 
+	// Subsystems publish what happened, such as a finished ingestion, to the
+	// event bus, and the API server, webhooks and skills subscribe
+	eventsLogger := logging.NewLogger("events", logging.ParseLevel(cfg.Logging.Level), logWriter)
+	eventBus := events.New(eventsLogger)
+	for _, hook := range cfg.Webhooks {
+		webhook := events.NewWebhook(hook.URL, hook.Secret, eventsLogger)
+		topics := events.Topics
+		if len(hook.Topics) > 0 {
+			topics = nil
+			for _, topic := range hook.Topics {
+				topics = append(topics, events.Topic(topic))
+			}
+		}
+		for _, topic := range topics {
+			eventBus.SubscribeAsync(topic, "webhook "+hook.URL, webhook.Handle)
+		}
+	}
+
 	// Initialize RAG components
-	chunker := rag.NewChunker(500, 50)
+	chunker := rag.NewChunker(cfg.Guardrails.ChunkSize, cfg.Guardrails.ChunkOverlap)
 	ragLogger := logging.NewLogger("rag", logging.ParseLevel(cfg.Logging.Level), logWriter)
 	searcher := rag.NewSearcher(&storeAdapter{store: st}, ragLogger)
+	if cfg.Search.Diversify {
+		searcher.SetMMR(cfg.Search.MMRLambda)
+	}
 	logger.Info("RAG components initialized")
 
 	// Initialize ingester
 	ingestLogger := logging.NewLogger("ingest", logging.ParseLevel(cfg.Logging.Level), logWriter)
-	ingester := ingest.NewIngester(&providerAdapter{provider: provider}, st, chunker, false, cfg.Guardrails.AutoSummarize, ingestLogger)
+	var ingestProvider ingest.LLMProvider = &providerAdapter{provider: provider}
+
+	// Spread ingestion embedding across a pool of Ollama instances serving the local embedding model
+	var embeddingPool *embedpool.Pool
+	if cfg.EmbeddingPool.Enabled {
+		if !provider.IsLocal() {
+			logger.Warn("Embedding pool disabled: ingestion uses the cloud provider, whose embeddings the pool cannot produce")
+		} else {
+			model := cfg.EmbeddingPool.Model
+			if model == "" {
+				model = cfg.LocalProvider.OllamaEmbedModel
+			}
+			poolLogger := logging.NewLogger("embedpool", logging.ParseLevel(cfg.Logging.Level), logWriter)
+			embeddingPool, err = embedpool.NewOllama(cfg.EmbeddingPool.Endpoints, model, embedpool.Options{
+				Concurrency:      cfg.EmbeddingPool.Concurrency,
+				MaxAttempts:      cfg.EmbeddingPool.MaxAttempts,
+				FailureThreshold: cfg.EmbeddingPool.FailureThreshold,
+				Cooldown:         time.Duration(cfg.EmbeddingPool.CooldownSeconds) * time.Second,
+				Events:           eventBus,
+			}, poolLogger)
+			if err != nil {
+				logger.Error("Failed to initialize embedding pool: %v", err)
+				os.Exit(1)
+			}
+			ingestProvider = &pooledProviderAdapter{providerAdapter: providerAdapter{provider: provider}, pool: embeddingPool}
+			logger.Info("Embedding pool initialized with %d endpoints (%s)", len(cfg.EmbeddingPool.Endpoints), model)
+		}
+	}
+
+	// Chunks record the model that embedded them so search can skip vectors from another model
+	ingestEmbedModel := dualProviderManager.GetActiveEmbedModel()
+	if embeddingPool != nil {
+		ingestEmbedModel = cfg.EmbeddingPool.Model
+		if ingestEmbedModel == "" {
+			ingestEmbedModel = cfg.LocalProvider.OllamaEmbedModel
+		}
+	}
+	st.SetEmbeddingModel(ingestEmbedModel)
+	// Chunks get a second embedding of their headings, for terse questions that match a section title
+	st.SetTitleWeight(cfg.Search.TitleWeight)
+	st.SetPinBoost(cfg.Search.PinBoost)
+	// Library search ranks by embedding, keyword or both, as configured
+	st.SetSearchDefaults(store.SearchOptions{Mode: cfg.Search.Mode, Fusion: cfg.Search.Fusion, KeywordWeight: cfg.Search.KeywordWeight})
+
+	ingester := ingest.NewIngester(ingestProvider, &ingestStoreAdapter{store: st}, chunker, false, cfg.Guardrails.AutoSummarize, ingestLogger)
+	// The ingester keeps the provider active at startup; summaries are attributed to its chat model
+	ingester.SetSummaryModel(dualProviderManager.GetActiveModel())
+	ingester.SetSourceLimits(cfg.Guardrails.MaxSourceChunks, cfg.Guardrails.MaxSourceChars)
+	ingester.SetTitleEmbeddings(cfg.Search.TitleWeight > 0)
+	ingester.SetEventBus(eventBus)
 	logger.Info("Ingester initialized")
 
+	// External extractors convert the file types they are registered for
+	extractorsLogger := logging.NewLogger("extractors", logging.ParseLevel(cfg.Logging.Level), logWriter)
+	extractorRegistry, err := extractors.Load(cfg.Extractors.Dir, extractorsLogger)
+	if err != nil {
+		logger.Warn("Failed to load extractors: %v", err)
+	} else {
+		extractorRegistry.SetLimits(extractors.Limits{
+			MaxRuntime:     time.Duration(cfg.Extractors.MaxRuntimeSeconds) * time.Second,
+			MaxOutputBytes: cfg.Extractors.MaxOutputMB * 1024 * 1024,
+		})
+		ingester.SetExtractor(extractorRegistry)
+		logger.Info("Loaded %d extractors", len(extractorRegistry.Extractors()))
+	}
+
 	// Initialize skills with store adapter for user-scoped loading
 	skillsLogger := logging.NewLogger("skills", logging.ParseLevel(cfg.Logging.Level), logWriter)
 	skillsStoreAdapter := &skillsStoreAdapter{store: st}
@@ -219,6 +593,14 @@ func main() {
 		logger.Info("Loaded %d skills", len(loadedSkills))
 	}
 	skillsExecutor := skills.NewExecutor(false, skillsLogger)
+	skillsExecutor.SetLimits(skills.Limits{
+		MaxRuntime:     time.Duration(cfg.Skills.MaxRuntimeSeconds) * time.Second,
+		MaxConcurrent:  cfg.Skills.MaxConcurrent,
+		MaxPerUser:     cfg.Skills.MaxPerUser,
+		MaxOutputBytes: cfg.Skills.MaxOutputKB * 1024,
+		QueueTimeout:   time.Duration(cfg.Skills.QueueTimeoutSeconds) * time.Second,
+	})
+	skillsExecutor.SetAuditor(st)
 
 	// Initialize folder watcher with adapter
 	watcherLogger := logging.NewLogger("watcher", logging.ParseLevel(cfg.Logging.Level), logWriter)
@@ -228,7 +610,13 @@ func main() {
 		logger.Error("Failed to initialize watcher: %v", err)
 		os.Exit(1)
 	}
-	ctx := context.Background()
+	w.SetEventBus(eventBus)
+	// Changed files are ingested once they stop changing, so sync clients and
+	// network copies are not read half written
+	w.SetTuning(watcherTuning(cfg.Watcher))
+	if extractorRegistry != nil {
+		w.AllowExtensions(extractorRegistry.Extensions()...)
+	}
 
 	// Get local-default user for backward compatibility with config-based folders
 	localDefaultUser, err := st.GetUserByUsername(ctx, "local-default")
@@ -237,14 +625,18 @@ func main() {
 	} else {
 		// Add folders from config to local-default user
 		for _, folder := range cfg.Folders {
-			if err := w.AddFolder(ctx, localDefaultUser.ID, folder); err != nil {
+			addFolder := w.AddFolder
+			if clusterNode != nil {
+				// Only the instance running the watcher watches the folder
+				addFolder = watcherStore.AddWatchedFolder
+			}
+			if err := addFolder(ctx, localDefaultUser.ID, folder); err != nil {
 				logger.Warn("Failed to add watched folder %s: %v", folder, err)
 			} else {
 				logger.Info("Watching folder: %s", folder)
 			}
 		}
 	}
-	go w.Start(ctx)
 
 	// Initialize API server with adapters
 	apiConfig := &api.ServerConfig{
@@ -269,9 +661,10 @@ func main() {
 
 	// Initialize auth provider
 	authLogger := logging.NewLogger("auth", logging.ParseLevel(cfg.Logging.Level), logWriter)
-	authStoreAdapter := &authStoreAdapter{store: st}
+	authStoreAdapter := &authStoreAdapter{store: st, events: eventBus}
+	baseAuthProvider := initAuthProvider(authStoreAdapter, cfg, authLogger)
 	authProvider := &apiAuthProviderAdapter{
-		provider: initAuthProvider(authStoreAdapter, cfg, authLogger),
+		provider: baseAuthProvider,
 	}
 
 	// Create adapters for the new components (using already initialized dualProviderManager and ragEnforcer)
@@ -305,66 +698,558 @@ func main() {
 		os.Exit(1)
 	}
 	logger.Info("API server initialized")
+	apiServer.SetEventBus(eventBus)
+
+	// Apply organization branding and template/static overrides
+	branding := api.Branding{
+		AppName:      cfg.Branding.AppName,
+		LogoPath:     cfg.Branding.LogoPath,
+		PrimaryColor: cfg.Branding.PrimaryColor,
+		FooterText:   cfg.Branding.FooterText,
+	}
+	if err := apiServer.SetBranding(branding, cfg.Branding.OverrideDir); err != nil {
+		logger.Error("Failed to apply branding overrides: %v", err)
+		os.Exit(1)
+	}
+	if cfg.Branding.OverrideDir != "" {
+		logger.Info("Branding overrides loaded from %s", cfg.Branding.OverrideDir)
+	}
+
+	if wireLog != nil {
+		apiServer.SetWireLog(&apiWireLogAdapter{log: wireLog})
+	}
+
+	if replayLog != nil {
+		apiServer.SetReplays(&apiReplayAdapter{
+			store:         st,
+			log:           replayLog,
+			providers:     dualProviderManager,
+			retentionDays: cfg.Replay.RetentionDays,
+		})
+	}
+	if transcriptArchive != nil {
+		apiServer.SetTranscripts(&apiTranscriptsAdapter{
+			store:         st,
+			archive:       transcriptArchive,
+			retentionDays: cfg.Transcripts.RetentionDays,
+			embeddings:    cfg.Transcripts.IncludeEmbeddings,
+		})
+	}
+
+	// The builtin provider's cross-encoder reorders library results before they reach the prompt
+	if cfg.LocalProvider.Type == "builtin" && cfg.LocalProvider.BuiltinRerankerPath != "" {
+		apiServer.SetReranker(&apiRerankerAdapter{manager: dualProviderManager})
+		logger.Info("Reranking enabled with builtin reranker model")
+	}
+
+	apiServer.SetSearchMode(cfg.Search.Mode)
+
+	// Maximal Marginal Relevance keeps near-identical passages from filling the prompt
+	if cfg.Search.Diversify {
+		apiServer.SetDiversity(cfg.Search.MMRLambda)
+		logger.Info("Search diversity enabled (lambda %.2f)", cfg.Search.MMRLambda)
+	}
+
+	// Feature flags follow the config unless an admin overrides them for a user
+	apiServer.SetFeatureFlags(flags.New(cfg.Features, st))
+	if len(cfg.Features) > 0 {
+		logger.Info("Feature flags configured: %d", len(cfg.Features))
+	}
+
+	if embeddingPool != nil {
+		apiServer.SetEmbeddingPool(&apiEmbeddingPoolAdapter{pool: embeddingPool})
+	}
+
+	// Answer generation is admitted fairly across users
+	apiServer.SetProviderQueue(&apiProviderQueueAdapter{queue: fairqueue.New(fairqueue.Options{
+		MaxActive:  cfg.ProviderQueue.MaxConcurrent,
+		MaxPerUser: cfg.ProviderQueue.MaxPerUser,
+	})}, time.Duration(cfg.ProviderQueue.KeepAliveSeconds)*time.Second)
+	apiServer.SetStreamKeepAlive(time.Duration(cfg.Server.KeepAliveSeconds) * time.Second)
+
+	// Load the local models ahead of the first chat and keep them loaded while in use
+	if cfg.ModelWarmup.WarmOnStartup || cfg.ModelWarmup.KeepAlive {
+		warmupLogger := logging.NewLogger("warmup", logging.ParseLevel(cfg.Logging.Level), logWriter)
+		modelWarmer := warmup.New(func() llm.Warmer {
+			return dualProviderManager.GetLocalWarmer()
+		}, warmup.Options{
+			PingInterval: time.Duration(cfg.ModelWarmup.PingIntervalSeconds) * time.Second,
+			IdleWindow:   time.Duration(cfg.ModelWarmup.IdleWindowMinutes) * time.Minute,
+		}, warmupLogger)
+		apiServer.SetModelWarmer(&apiModelWarmerAdapter{warmer: modelWarmer})
+
+		if cfg.ModelWarmup.WarmOnStartup {
+			go func() {
+				start := time.Now()
+				if err := modelWarmer.WarmUp(context.Background()); errors.Is(err, warmup.ErrUnsupported) {
+					logger.Info("Model warm-up skipped: %v", err)
+				} else if err != nil {
+					logger.Warn("Model warm-up failed: %v", err)
+				} else {
+					logger.Info("Local models warmed up in %v", time.Since(start).Round(time.Millisecond))
+				}
+			}()
+		}
+		if cfg.ModelWarmup.KeepAlive {
+			go modelWarmer.Run(context.Background())
+			logger.Info("Model keep-alive enabled (ping every %ds, idle window %d minutes)", cfg.ModelWarmup.PingIntervalSeconds, cfg.ModelWarmup.IdleWindowMinutes)
+		}
+	}
+
+	// "Remember me" cookies last as long as the sessions behind them
+	apiServer.SetRememberMeDays(cfg.Auth.RememberMeDays)
+
+	// Password policy for registration, password changes and admin-created users
+	passwordPolicy, err := pwpolicy.New(cfg.Auth.PasswordPolicy.Options())
+	if err != nil {
+		logger.Error("Invalid password policy: %v", err)
+		log.Fatalf("Invalid password policy: %v", err)
+	}
+	apiServer.SetPasswordPolicy(passwordPolicy)
+
+	// The admin lockout API reports the policy the auth provider applies
+	apiServer.SetLockoutPolicy(api.LockoutPolicy{
+		Threshold: cfg.Auth.LockoutThreshold,
+		Duration:  time.Duration(cfg.Auth.LockoutDurationMinutes) * time.Minute,
+	})
+
+	// Bound per-user and per-request generation options by the guardrails
+	apiServer.SetGenerationLimits(api.GenerationLimits{
+		MaxTemperature: cfg.Guardrails.MaxTemperature,
+		MaxTokens:      cfg.Guardrails.MaxOutputTokens,
+	})
+	apiServer.SetDocQABudget(cfg.Guardrails.DocQATokenBudget)
+	apiServer.SetHistoryWindow(cfg.Guardrails.HistoryMessages)
+
+	// Estimate what questions to paid providers cost, and confirm expensive ones
+	prices := make(map[string]api.ModelPrice, len(cfg.Costs.Prices))
+	for model, price := range cfg.Costs.Prices {
+		prices[model] = api.ModelPrice{InputPerMillion: price.InputPerMillion, OutputPerMillion: price.OutputPerMillion}
+	}
+	apiServer.SetCostPolicy(api.CostPolicy{
+		ConfirmAboveUSD:  cfg.Costs.ConfirmAboveUSD,
+		CompletionTokens: cfg.Costs.CompletionTokens,
+		Prices:           prices,
+	})
+
+	// Watched files that keep failing to ingest are quarantined and reported to
+	// the folder's owner; the folder errors API retries them
+	w.SetQuarantine(cfg.Guardrails.QuarantineAfter, func(userID int64, folder, path, errMsg string) {
+		apiServer.NotifyUser(context.Background(), userID, "quarantine",
+			fmt.Sprintf("File '%s' failed to ingest and was quarantined: %s", filepath.Base(path), errMsg),
+			map[string]interface{}{"folder": folder, "path": path})
+	})
+	if extractorRegistry != nil {
+		apiServer.SetExtractors(&apiExtractorsAdapter{registry: extractorRegistry})
+	}
+	apiServer.SetSummaryRegenerator(ingester)
+	apiServer.SetRechunker(ingester)
+	watcherDone := make(chan struct{})
+	if clusterNode != nil {
+		// One instance at a time runs the watcher and retries files for the others
+		retrier := &clusterFolderRetrier{node: clusterNode, watcher: w}
+		apiServer.SetFolderRetrier(retrier)
+		go func() {
+			defer close(watcherDone)
+			clusterNode.Lead(clusterCtx, "watcher", func(ctx context.Context) {
+				go retrier.work(ctx)
+				if err := w.Run(ctx); err != nil {
+					logger.Error("Failed to run folder watcher: %v", err)
+				}
+			})
+		}()
+
+		apiServer.SetEventPublisher(clusterNode)
+		clusterNode.Subscribe(api.WebSocketEventKind, apiServer.RelayEvent)
+		go clusterNode.Run(clusterCtx)
+	} else {
+		close(watcherDone)
+		apiServer.SetFolderRetrier(w)
+		apiServer.SetWatcherControl(&apiWatcherAdapter{watcher: w})
+		go w.Start(ctx)
+	}
+
+	// Web search adds live results to answers in cloud mode or when a user opts in
+	if cfg.WebSearch.Enabled {
+		webSearchLogger := logging.NewLogger("websearch", logging.ParseLevel(cfg.Logging.Level), logWriter)
+		webSearchClient, err := websearch.New(websearch.Options{
+			Engine:            cfg.WebSearch.Engine,
+			Endpoint:          cfg.WebSearch.Endpoint,
+			APIKey:            cfg.WebSearch.APIKey,
+			MaxResults:        cfg.WebSearch.MaxResults,
+			MaxCharsPerResult: cfg.WebSearch.MaxCharsPerResult,
+			Timeout:           time.Duration(cfg.WebSearch.TimeoutSeconds) * time.Second,
+		}, webSearchLogger)
+		if err != nil {
+			logger.Error("Failed to initialize web search: %v", err)
+			os.Exit(1)
+		}
+		apiServer.SetWebSearch(&apiWebSearchAdapter{client: webSearchClient}, api.WebSearchOptions{
+			AutoInCloudMode: cfg.WebSearch.AutoInCloudMode,
+			IngestResults:   cfg.WebSearch.IngestResults,
+		})
+		logger.Info("Web search enabled (%s)", cfg.WebSearch.Engine)
+	}
+
+	// Background jobs run on the scheduler, which keeps their pauses and run
+	// times in the database; the config can replace their schedules
+	schedulerLogger := logging.NewLogger("scheduler", logging.ParseLevel(cfg.Logging.Level), logWriter)
+	schedulerOptions := scheduler.Options{
+		Store:  &schedulerStoreAdapter{store: st},
+		Jitter: time.Duration(cfg.Scheduler.JitterSeconds) * time.Second,
+		OnRun: func(status scheduler.Status) {
+			// Admins subscribed to the jobs topic follow runs as they happen
+			apiServer.NotifyJob(toAPIScheduledJob(status))
+		},
+	}
+	if clusterNode != nil {
+		schedulerOptions.Locker = clusterNode
+	}
+	jobScheduler := scheduler.New(schedulerOptions, schedulerLogger)
+	addJob := func(job scheduler.Job) {
+		if spec, ok := cfg.Scheduler.Jobs[job.Name]; ok {
+			job.Schedule = spec
+		}
+		if err := jobScheduler.Add(job); err != nil {
+			logger.Error("Failed to schedule job: %v", err)
+		}
+	}
+
+	addJob(scheduler.Job{
+		Name:        "token_cleanup",
+		Description: "Delete expired session tokens",
+		Schedule:    "@hourly",
+		Run:         st.CleanupExpiredTokens,
+	})
+
+	if transcriptArchive != nil && cfg.Transcripts.RetentionDays > 0 {
+		addJob(scheduler.Job{
+			Name:        "transcript_retention",
+			Description: fmt.Sprintf("Delete archived provider transcripts older than %d days", cfg.Transcripts.RetentionDays),
+			Schedule:    "@daily",
+			Run: func(ctx context.Context) error {
+				removed, err := st.DeleteTranscriptsBefore(ctx, time.Now().AddDate(0, 0, -cfg.Transcripts.RetentionDays))
+				if err != nil {
+					return err
+				}
+				if removed > 0 {
+					logger.Info("Deleted %d archived provider transcripts past retention", removed)
+				}
+				return nil
+			},
+		})
+	}
+
+	if replayLog != nil {
+		addJob(scheduler.Job{
+			Name:        "failed_request_retention",
+			Description: fmt.Sprintf("Delete failed provider requests older than %d days", cfg.Replay.RetentionDays),
+			Schedule:    "@daily",
+			Run: func(ctx context.Context) error {
+				removed, err := st.DeleteFailedRequestsBefore(ctx, time.Now().AddDate(0, 0, -cfg.Replay.RetentionDays))
+				if err != nil {
+					return err
+				}
+				if removed > 0 {
+					logger.Info("Deleted %d failed provider requests past retention", removed)
+				}
+				return nil
+			},
+		})
+	}
+
+	addJob(scheduler.Job{
+		Name:        "notification_prune",
+		Description: "Delete read notifications older than 30 days",
+		Schedule:    "@daily",
+		Run: func(ctx context.Context) error {
+			removed, err := st.DeleteReadNotificationsBefore(ctx, time.Now().AddDate(0, 0, -30))
+			if err != nil {
+				return err
+			}
+			if removed > 0 {
+				logger.Debug("Deleted %d old notifications", removed)
+			}
+			return nil
+		},
+	})
+
+	addJob(scheduler.Job{
+		Name:        "visibility_repair",
+		Description: "Give chunks that differ from their document its visibility",
+		Schedule:    "@daily",
+		Run: func(ctx context.Context) error {
+			repaired, err := st.RepairChunkVisibility(ctx)
+			if err != nil {
+				return err
+			}
+			if repaired > 0 {
+				logger.Warn("Repaired the visibility of %d chunks that differed from their document", repaired)
+			}
+			return nil
+		},
+	})
+
+	if cfg.Database.CheckpointIntervalMinutes > 0 {
+		addJob(scheduler.Job{
+			Name:        "wal_checkpoint",
+			Description: "Checkpoint and truncate the database write-ahead log",
+			Schedule:    fmt.Sprintf("@every %dm", cfg.Database.CheckpointIntervalMinutes),
+			Run: func(ctx context.Context) error {
+				result, err := st.Checkpoint(ctx)
+				if err != nil {
+					return err
+				}
+				if result.Busy {
+					logger.Warn("WAL checkpoint incomplete, database busy (%d of %d frames checkpointed)", result.Checkpointed, result.LogFrames)
+				} else {
+					logger.Debug("WAL checkpointed (%d frames)", result.Checkpointed)
+				}
+				return nil
+			},
+		})
+	}
+
+	if cfg.Database.VectorIndexPath != "" && cfg.Database.VectorSnapshotMinutes > 0 {
+		addJob(scheduler.Job{
+			Name:        "vector_snapshot",
+			Description: "Repair the vector index and write its snapshot",
+			Schedule:    fmt.Sprintf("@every %dm", cfg.Database.VectorSnapshotMinutes),
+			Local:       true, // Each instance keeps its own index
+			Run: func(ctx context.Context) error {
+				if _, err := st.RepairVectorIndex(ctx); err != nil {
+					return fmt.Errorf("failed to repair vector index: %w", err)
+				}
+				return st.SnapshotVectorIndex()
+			},
+		})
+	}
+
+	if cfg.Database.VectorIndexPath != "" && cfg.Database.ANNRebuildHours > 0 {
+		addJob(scheduler.Job{
+			Name:        "ann_rebuild",
+			Description: "Rebuild the ANN lists of the vector index and write its snapshot",
+			Schedule:    fmt.Sprintf("@every %dh", cfg.Database.ANNRebuildHours),
+			Local:       true, // Each instance keeps its own index
+			Run: func(ctx context.Context) error {
+				return rebuildANNIndex(ctx, st, logger)
+			},
+		})
+	}
+
+	if cfg.Guardrails.AutoSummarize && cfg.Guardrails.SummaryRefreshMinutes > 0 {
+		addJob(scheduler.Job{
+			Name:        "summary_refresh",
+			Description: fmt.Sprintf("Regenerate up to %d stale document summaries", summaryRefreshBatch),
+			Schedule:    fmt.Sprintf("@every %dm", cfg.Guardrails.SummaryRefreshMinutes),
+			Run: func(ctx context.Context) error {
+				states, err := st.GetSummaryStates(ctx, 0, true)
+				if err != nil {
+					return fmt.Errorf("failed to check summaries: %w", err)
+				}
+				// Regenerate a bounded batch per run so a model switch does not
+				// tie up the provider for long
+				refreshed := 0
+				for _, state := range states {
+					if refreshed == summaryRefreshBatch {
+						break
+					}
+					if state.StaleReason(ingester.SummaryModel()) == "" {
+						continue
+					}
+					if err := ingester.RegenerateSummary(ctx, state.UserID, state.Source); err != nil {
+						logger.Warn("Failed to regenerate summary of %s: %v", state.Source, err)
+					}
+					refreshed++
+				}
+				if refreshed > 0 {
+					logger.Debug("Regenerated %d stale summaries", refreshed)
+				}
+				return nil
+			},
+		})
+	}
+
+	addJob(scheduler.Job{
+		Name:        "session_compaction",
+		Description: fmt.Sprintf("Summarize older messages of sessions idle for %d minutes", cfg.Guardrails.CompactIdleMinutes),
+		Schedule:    "@every 15m",
+		Run: func(ctx context.Context) error {
+			return apiServer.CompactIdleSessions(ctx, time.Duration(cfg.Guardrails.CompactIdleMinutes)*time.Minute)
+		},
+	})
+
+	addJob(scheduler.Job{
+		Name:        "saved_search_embeddings",
+		Description: "Embed saved search queries that are new or were embedded with another model",
+		Schedule:    "@every 15m",
+		Run: func(ctx context.Context) error {
+			refreshed, err := apiServer.RefreshSavedSearches(ctx)
+			if refreshed > 0 {
+				logger.Info("Embedded %d saved search queries", refreshed)
+			}
+			return err
+		},
+	})
+	// Warm saved searches at startup too, in case the embedding model changed
+	// while the server was down
+	go func() {
+		if _, err := apiServer.RefreshSavedSearches(context.Background()); err != nil {
+			logger.Warn("Failed to warm saved searches: %v", err)
+		}
+	}()
+
+	if clusterNode != nil {
+		addJob(scheduler.Job{
+			Name:        "cluster_prune",
+			Description: "Delete old cluster events and finished cluster tasks",
+			Schedule:    "@hourly",
+			Run:         clusterNode.Prune,
+		})
+	}
+
+	// Anonymous usage reports are sent only when an admin opts in and no
+	// environment off switch is set; the admin API previews them either way
+	telemetryReporter := telemetry.New(telemetry.Options{
+		Enabled:       cfg.Telemetry.Enabled,
+		Endpoint:      cfg.Telemetry.Endpoint,
+		Version:       version,
+		UserMode:      cfg.UserMode,
+		LocalProvider: cfg.LocalProvider.Type,
+		CloudProvider: cfg.CloudProvider.Type,
+	}, st)
+	apiServer.SetTelemetry(&apiTelemetryAdapter{reporter: telemetryReporter})
+	if telemetryReporter.Enabled() {
+		addJob(scheduler.Job{
+			Name:        "telemetry",
+			Description: "Send the anonymous usage report",
+			Schedule:    "@daily",
+			Run:         telemetryReporter.Send,
+		})
+		logger.Info("Anonymous telemetry enabled (%s)", cfg.Telemetry.Endpoint)
+	} else if cfg.Telemetry.Enabled {
+		logger.Info("Anonymous telemetry turned off by %s", telemetryReporter.Status().DisabledBy)
+	}
+
+	scheduled := make(map[string]bool)
+	for _, job := range jobScheduler.Jobs() {
+		scheduled[job.Name] = true
+	}
+	for name := range cfg.Scheduler.Jobs {
+		if !scheduled[name] {
+			logger.Warn("Scheduler config names job %s, which is unknown or disabled", name)
+		}
+	}
+
+	schedulerCtx, stopScheduler := context.WithCancel(context.Background())
+	defer stopScheduler()
+	jobScheduler.Start(schedulerCtx)
+	apiServer.SetJobScheduler(&apiJobSchedulerAdapter{scheduler: jobScheduler})
 
 	// Register routes
 	mux := http.NewServeMux()
 	apiServer.RegisterRoutes(mux)
 
 	// Apply authentication middleware
-	authMiddleware := auth.AuthMiddleware(authStoreAdapter, cfg.UserMode)
-	handler := authMiddleware(mux)
+	authMiddleware := auth.AuthMiddlewareWithProvider(authStoreAdapter, cfg.UserMode, baseAuthProvider)
+
+	// Cap request bodies before they reach authentication or the handlers
+	bodyLimits := api.DefaultBodyLimits(int64(cfg.Server.MaxBodyKB)<<10, int64(cfg.Guardrails.MaxFileSizeMB)<<20)
+	handler := api.BodyLimitMiddleware(bodyLimits)(authMiddleware(mux))
+
+	// Answer 503 until the database and the local provider respond; on boot
+	// Noodexx often starts before Ollama does
+	startupChecks := []readiness.Check{{Name: "database", Required: true, Probe: st.Ping}}
+	if pinger := dualProviderManager.GetLocalPinger(); pinger != nil {
+		startupChecks = append(startupChecks, readiness.Check{Name: cfg.LocalProvider.Type, Probe: pinger.Ping})
+	}
+	startupLogger := logging.NewLogger("startup", logging.ParseLevel(cfg.Logging.Level), logWriter)
+	startupGate := readiness.New(startupChecks, readiness.Options{
+		MaxWait:    time.Duration(cfg.Startup.MaxWaitSeconds) * time.Second,
+		MaxBackoff: time.Duration(cfg.Startup.MaxBackoffSeconds) * time.Second,
+	}, startupLogger)
+	handler = api.StartupMiddleware(&apiStartupGateAdapter{gate: startupGate})(handler)
+
+	// Streamed answers outlast the write timeout of other routes; the deadline
+	// of each response is set from its route
+	writeTimeouts := api.DefaultWriteTimeouts(time.Duration(cfg.Server.WriteTimeoutSeconds)*time.Second, time.Duration(cfg.Server.StreamTimeoutSeconds)*time.Second)
+	for route, seconds := range cfg.Server.RouteTimeouts {
+		writeTimeouts.Routes[route] = time.Duration(seconds) * time.Second
+	}
+	handler = api.WriteTimeoutMiddleware(writeTimeouts)(handler)
 
 	// Create HTTP server
 	addr := fmt.Sprintf("%s:%d", cfg.Server.BindAddress, cfg.Server.Port)
 	server := &http.Server{
 		Addr:         addr,
 		Handler:      handler,
-		ReadTimeout:  15 * time.Second,
-		WriteTimeout: 15 * time.Second,
-		IdleTimeout:  60 * time.Second,
+		ReadTimeout: time.Duration(cfg.Server.ReadTimeoutSeconds) * time.Second,
+		IdleTimeout: time.Duration(cfg.Server.IdleTimeoutSeconds) * time.Second,
+		// Write deadlines are set per route by WriteTimeoutMiddleware
 	}
 
-	// Start server in goroutine
+	// Listen before reporting ready, so the service manager only sees a
+	// started service once requests are accepted
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		logger.Error("Failed to listen on %s: %v", addr, err)
+		return fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+	serveErr := make(chan error, 2)
 	go func() {
 		log.Printf("Server listening on http://%s", addr)
 		log.Printf("Press Ctrl-C to quit")
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
 			logger.Error("Server error: %v", err)
+			serveErr <- err
 		}
 	}()
+	ready()
 
-	// Start background job for token cleanup
+	// Serve the starting page while waiting; Noodexx stops if the database never answers
 	go func() {
-		ticker := time.NewTicker(1 * time.Hour)
-		defer ticker.Stop()
-
-		logger.Info("Token cleanup job started (runs every hour)")
-
-		for range ticker.C {
-			ctx := context.Background()
-			if err := st.CleanupExpiredTokens(ctx); err != nil {
-				logger.Error("Failed to cleanup expired tokens: %v", err)
-			} else {
-				logger.Debug("Expired tokens cleaned up")
-			}
+		if err := startupGate.Wait(ctx); err != nil {
+			logger.Error("Startup failed: %v", err)
+			serveErr <- err
 		}
 	}()
 
 	// Graceful shutdown handling
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
-	<-sigChan
+	var runErr error
+	select {
+	case <-ctx.Done():
+	case runErr = <-serveErr:
+	}
 
 	// Log and display shutdown message
-	shutdownMsg := "User exit request made, shutting down..."
+	shutdownMsg := "Stop requested, shutting down..."
 	log.Println(shutdownMsg)
 	logger.Info(shutdownMsg)
-	
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.Server.ShutdownTimeoutSeconds)*time.Second)
 	defer cancel()
-	server.Shutdown(ctx)
+	server.Shutdown(shutdownCtx)
+
+	// Let running jobs finish before the final snapshot
+	stopScheduler()
+	jobScheduler.Wait()
+
+	// Deliver the events already published, such as to webhooks
+	eventBus.Close()
+
+	// Hand the watcher over to another instance
+	stopCluster()
+	<-watcherDone
+
+	// Persist the vector index so the next start is warm
+	if cfg.Database.VectorIndexPath != "" {
+		if err := st.SnapshotVectorIndex(); err != nil {
+			logger.Error("Failed to snapshot vector index: %v", err)
+		}
+	}
 	
 	finalMsg := "Noodexx stopped"
 	log.Println(finalMsg)
 	logger.Info(finalMsg)
+	return runErr
 }
@@ -19,8 +19,13 @@ QUERY=$(echo "$INPUT" | jq -r '.query // ""')
 # Get default location from settings, fallback to San Francisco
 DEFAULT_LOCATION=$(echo "$INPUT" | jq -r '.settings.default_location // "San Francisco"')
 
+# Location passed as a structured argument (see input_schema in skill.json)
+CONTEXT_LOCATION=$(echo "$INPUT" | jq -r '.context.location // ""')
+
 # Try to extract location from query, otherwise use default
-if [ -n "$QUERY" ]; then
+if [ -n "$CONTEXT_LOCATION" ]; then
+    LOCATION="$CONTEXT_LOCATION"
+elif [ -n "$QUERY" ]; then
     # Simple extraction: look for "in <location>" or "for <location>"
     LOCATION=$(echo "$QUERY" | grep -oP '(?:in|for)\s+\K[A-Za-z\s]+' | head -1 | xargs)
     if [ -z "$LOCATION" ]; then
@@ -0,0 +1,54 @@
+//go:build tray
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+
+	"noodexx/internal/config"
+	"noodexx/internal/tray"
+)
+
+// runTray serves in the background and shows the tray menu on the main
+// goroutine, which desktop toolkits require; quitting from the menu, Ctrl-C
+// or SIGTERM shuts the server down gracefully
+func runTray(cfg *config.Config, console io.Writer) error {
+	if cfg.UserMode != "single" {
+		return fmt.Errorf("tray mode needs user_mode \"single\"; in multi-user mode people sign in through the browser")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	ready := make(chan struct{})
+	done := make(chan error, 1)
+	go func() {
+		done <- run(ctx, cfg, console, func() { close(ready) })
+	}()
+	select {
+	case <-ready:
+	case err := <-done:
+		return err
+	}
+
+	baseURL := localURL(cfg)
+	tray.Run(ctx, tray.NewClient(baseURL), baseURL, stop)
+	stop()
+	return <-done
+}
+
+// localURL is the address the tray menu reaches the server and the browser at
+func localURL(cfg *config.Config) string {
+	host := cfg.Server.BindAddress
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		host = "127.0.0.1"
+	}
+	return fmt.Sprintf("http://%s", net.JoinHostPort(host, strconv.Itoa(cfg.Server.Port)))
+}
@@ -0,0 +1,15 @@
+//go:build !tray
+
+package main
+
+import (
+	"errors"
+	"io"
+
+	"noodexx/internal/config"
+)
+
+// runTray reports that this binary was built without the tray menu
+func runTray(cfg *config.Config, console io.Writer) error {
+	return errors.New("this build has no tray support; rebuild with: go build -tags tray")
+}
@@ -85,6 +85,21 @@ function escapeHtml(text) {
     return div.innerHTML;
 }
 
+/**
+ * Read the message of an API error response
+ * @param {Response} response - Failed fetch response
+ * @param {string} fallback - Message when the body has none
+ * @returns {Promise<string>} Error message
+ */
+async function responseErrorMessage(response, fallback) {
+    try {
+        const data = await response.json();
+        return data.message || data.error || fallback;
+    } catch (e) {
+        return fallback;
+    }
+}
+
 // ============================================================================
 // Command Palette
 // Requirements: 18.1-18.5
@@ -391,6 +406,84 @@ function setSidebarCollapsed(collapsed) {
     localStorage.setItem('sidebarCollapsed', collapsed);
 }
 
+// ============================================================================
+// Notifications
+// Sidebar unread badge and list, loaded from /api/notifications and kept up to
+// date by the notification messages handled in base.html's WebSocket script
+// ============================================================================
+
+/**
+ * Alpine component for the sidebar notification list
+ * @returns {Object} Component state and methods
+ */
+function notificationCenter() {
+    return {
+        open: false,
+        unread: 0,
+        items: [],
+
+        init() {
+            this.load();
+            window.addEventListener('notification', (e) => {
+                this.items = [e.detail.notification, ...this.items].slice(0, 20);
+                this.unread = e.detail.unread;
+            });
+            window.addEventListener('notifications-read', (e) => {
+                this.unread = e.detail.unread;
+                if (this.unread === 0) {
+                    this.items.forEach(item => item.read = true);
+                }
+            });
+        },
+
+        async load() {
+            try {
+                const response = await fetch('/api/notifications?limit=20');
+                if (!response.ok) return;
+                const data = await response.json();
+                this.items = data.notifications;
+                this.unread = data.unread;
+            } catch (e) {
+                console.error('Failed to load notifications:', e);
+            }
+        },
+
+        toggle() {
+            this.open = !this.open;
+            if (this.open) this.load();
+        },
+
+        /**
+         * Mark notifications read
+         * @param {number[]} [ids] - Notification IDs; all of them when omitted
+         */
+        async markRead(ids) {
+            try {
+                const response = await fetch('/api/notifications/read', {
+                    method: 'POST',
+                    headers: { 'Content-Type': 'application/json' },
+                    body: JSON.stringify({ ids: ids || [] })
+                });
+                if (!response.ok) {
+                    showToast(await responseErrorMessage(response, 'Failed to mark notifications read'), 'error');
+                    return;
+                }
+                const data = await response.json();
+                this.unread = data.unread;
+                this.items.forEach(item => {
+                    if (!ids || ids.includes(item.id)) item.read = true;
+                });
+            } catch (e) {
+                console.error('Failed to mark notifications read:', e);
+            }
+        },
+
+        formatTime(timestamp) {
+            return new Date(timestamp).toLocaleString();
+        }
+    };
+}
+
 // ============================================================================
 // Markdown Rendering Helper
 // Note: Server-side rendering is preferred, this is a placeholder for client-side needs
@@ -430,3 +523,5 @@ window.toggleCommandPalette = toggleCommandPalette;
 window.isSidebarCollapsed = isSidebarCollapsed;
 window.setSidebarCollapsed = setSidebarCollapsed;
 window.renderMarkdown = renderMarkdown;
+window.responseErrorMessage = responseErrorMessage;
+window.notificationCenter = notificationCenter;